@@ -43,7 +43,9 @@ config :serviceradar_core, Oban,
        {System.get_env("TRACE_SUMMARIES_REFRESH_CRON") || "*/2 * * * *",
         ServiceRadar.Jobs.RefreshTraceSummariesWorker, queue: :maintenance},
        {"*/15 * * * *", ServiceRadar.Jobs.ReapStalePeriodicJobsWorker, queue: :maintenance},
-       {"17 3 * * *", ServiceRadar.Observability.DataRetentionWorker, queue: :maintenance}
+       {"17 3 * * *", ServiceRadar.Observability.DataRetentionWorker, queue: :maintenance},
+       {"*/30 * * * *", ServiceRadar.Inventory.DeviceLifecycleWorker, queue: :maintenance},
+       {"*/5 * * * *", ServiceRadar.Infrastructure.GatewayGroupWorker, queue: :maintenance}
      ]}
   ],
   peer: Oban.Peers.Database
@@ -56,6 +58,8 @@ config :serviceradar_core, ServiceRadar.Observability.NetflowSecurityRefreshWork
   cache_ttl_seconds: 86_400,
   threat_candidate_limit: 10_000
 
+config :serviceradar_core, ServiceRadar.Observability.MetricDerivation, rules: []
+config :serviceradar_core, ServiceRadar.EventWriter.ConfigEnrichment, rules: []
 config :serviceradar_core, ServiceRadar.Observability.ThreatIntelOTXSyncWorker, []
 config :serviceradar_core, ServiceRadar.Observability.ThreatIntelRawPayloadStore, []
 
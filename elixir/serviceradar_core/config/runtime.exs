@@ -663,6 +663,169 @@ if config_env() == :prod do
     jetstream_replicas: parse_int_env.("SERVICERADAR_OTX_RAW_REPLICAS", 1),
     jetstream_storage: otx_raw_storage
 
+  # Derived metric rules as a JSON list of {"name", "expression", "unit"} objects.
+  case System.get_env("SERVICERADAR_METRIC_DERIVATIONS") do
+    nil ->
+      :ok
+
+    raw ->
+      case Jason.decode(raw) do
+        {:ok, rules} when is_list(rules) ->
+          config :serviceradar_core, ServiceRadar.Observability.MetricDerivation, rules: rules
+
+        _ ->
+          IO.warn("SERVICERADAR_METRIC_DERIVATIONS must be a JSON list of rules; ignoring")
+      end
+  end
+
+  # Custom DIRE identifier types as a JSON list of {"name", "source", "scope"}
+  # objects (see CustomIdentifierTypes).
+  case System.get_env("SERVICERADAR_CUSTOM_IDENTIFIER_TYPES") do
+    nil ->
+      :ok
+
+    raw ->
+      case Jason.decode(raw) do
+        {:ok, types} when is_list(types) ->
+          config :serviceradar_core, ServiceRadar.Inventory.CustomIdentifierTypes, types: types
+
+        _ ->
+          IO.warn("SERVICERADAR_CUSTOM_IDENTIFIER_TYPES must be a JSON list of types; ignoring")
+      end
+  end
+
+  # SRQL stream-export jobs as a JSON list of job objects (see StreamExport).
+  case System.get_env("SERVICERADAR_STREAM_EXPORTS") do
+    nil ->
+      :ok
+
+    raw ->
+      case Jason.decode(raw) do
+        {:ok, jobs} when is_list(jobs) ->
+          config :serviceradar_core, ServiceRadar.Observability.StreamExport, jobs: jobs
+
+        _ ->
+          IO.warn("SERVICERADAR_STREAM_EXPORTS must be a JSON list of jobs; ignoring")
+      end
+  end
+
+  # Event desired-state enrichment as a JSON list of {"match", "keys"} objects.
+  case System.get_env("SERVICERADAR_EVENT_CONFIG_ENRICHMENT") do
+    nil ->
+      :ok
+
+    raw ->
+      case Jason.decode(raw) do
+        {:ok, rules} when is_list(rules) ->
+          config :serviceradar_core, ServiceRadar.EventWriter.ConfigEnrichment,
+            rules: rules,
+            cache_ttl_ms: parse_int_env.("SERVICERADAR_EVENT_CONFIG_CACHE_TTL_MS", 60_000)
+
+        _ ->
+          IO.warn("SERVICERADAR_EVENT_CONFIG_ENRICHMENT must be a JSON list of rules; ignoring")
+      end
+  end
+
+  # Per-source confidence overrides as a JSON object, e.g. {"netbox": 9, "armis": 6}.
+  case System.get_env("SERVICERADAR_SOURCE_CONFIDENCE_OVERRIDES") do
+    nil ->
+      :ok
+
+    raw ->
+      case Jason.decode(raw) do
+        {:ok, overrides} when is_map(overrides) ->
+          {valid, invalid} =
+            Enum.split_with(overrides, fn {_source, value} -> value in 1..10 end)
+
+          if invalid != [] do
+            IO.warn(
+              "SERVICERADAR_SOURCE_CONFIDENCE_OVERRIDES values must be integers 1-10; " <>
+                "ignoring #{inspect(invalid)}"
+            )
+          end
+
+          config :serviceradar_core, ServiceRadar.Inventory.SourceConfidence,
+            overrides: Map.new(valid)
+
+        _ ->
+          IO.warn("SERVICERADAR_SOURCE_CONFIDENCE_OVERRIDES must be a JSON object; ignoring")
+      end
+  end
+
+  # Ingest-side device update validation (see DeviceUpdateValidator). Checks and
+  # extra sources are comma separated, e.g. "device_id,ip,source,partition".
+  parse_list_env = fn name ->
+    case System.get_env(name) do
+      nil -> nil
+      raw -> raw |> String.split(",", trim: true) |> Enum.map(&String.trim/1)
+    end
+  end
+
+  device_update_validator_opts =
+    Enum.reject(
+      [
+        enabled: parse_bool.("SERVICERADAR_DEVICE_UPDATE_VALIDATION", true),
+        checks: parse_list_env.("SERVICERADAR_DEVICE_UPDATE_CHECKS"),
+        extra_sources: parse_list_env.("SERVICERADAR_DEVICE_UPDATE_EXTRA_SOURCES")
+      ],
+      fn {_key, value} -> is_nil(value) end
+    )
+
+  config :serviceradar_core,
+         ServiceRadar.Inventory.DeviceUpdateValidator,
+         device_update_validator_opts
+
+  # GeoIP location of device IPs at ingestion (see DeviceGeoEnrichment). Defaults
+  # to the GeoLite2 City database downloaded into GEOLITE_MMDB_DIR.
+  config :serviceradar_core, ServiceRadar.Inventory.DeviceGeoEnrichment,
+    enabled: parse_bool.("SERVICERADAR_DEVICE_GEOIP_ENABLED", false),
+    database_path:
+      System.get_env("SERVICERADAR_DEVICE_GEOIP_DB") ||
+        Path.join(geolite_dir, "GeoLite2-City.mmdb")
+
+  # Order and toggles of the device enrichment stages (see EnrichmentPipeline),
+  # as comma-separated stage names.
+  enrichment_stage_names = fn var ->
+    case System.get_env(var) do
+      nil -> nil
+      raw -> raw |> String.split(",", trim: true) |> Enum.map(&String.trim/1)
+    end
+  end
+
+  enrichment_pipeline_opts =
+    [
+      order: enrichment_stage_names.("SERVICERADAR_ENRICHMENT_ORDER"),
+      disabled: enrichment_stage_names.("SERVICERADAR_ENRICHMENT_DISABLED")
+    ]
+    |> Enum.reject(fn {_key, value} -> is_nil(value) end)
+
+  if enrichment_pipeline_opts != [] do
+    config :serviceradar_core,
+           ServiceRadar.Inventory.EnrichmentPipeline,
+           enrichment_pipeline_opts
+  end
+
+  # Delete-safety threshold for dry-run sync plans (see SyncPlan)
+  config :serviceradar_core, ServiceRadar.Inventory.SyncPlan,
+    max_tombstone_percent: parse_int_env.("SERVICERADAR_SYNC_MAX_TOMBSTONE_PERCENT", 25)
+
+  # Optional review webhook for identity merge events (see MergeEvents).
+  identity_merge_webhook_headers =
+    case System.get_env("SERVICERADAR_IDENTITY_MERGE_WEBHOOK_TOKEN") do
+      token when is_binary(token) and token != "" -> %{"authorization" => "Bearer #{token}"}
+      _ -> %{}
+    end
+
+  config :serviceradar_core, ServiceRadar.Inventory.MergeEvents,
+    webhook_url: System.get_env("SERVICERADAR_IDENTITY_MERGE_WEBHOOK_URL"),
+    webhook_headers: identity_merge_webhook_headers
+
+  # Agent clock skew detection (see ClockSkew); action is warn, correct or reject.
+  config :serviceradar_core, ServiceRadar.ClockSkew,
+    max_skew_seconds: parse_int_env.("SERVICERADAR_CLOCK_SKEW_MAX_SECONDS", 300),
+    action: System.get_env("SERVICERADAR_CLOCK_SKEW_ACTION", "warn"),
+    event_interval_seconds: parse_int_env.("SERVICERADAR_CLOCK_SKEW_EVENT_INTERVAL_SECONDS", 3600)
+
   config :serviceradar_core, ServiceRadar.Repo, repo_opts
   config :serviceradar_core, :age_graph_name, age_graph_name
   config :serviceradar_core, :platform_sync_component_id, platform_sync_component_id
@@ -780,7 +943,9 @@ if config_env() == :prod do
          {System.get_env("TRACE_SUMMARIES_REFRESH_CRON") || "*/2 * * * *", ServiceRadar.Jobs.RefreshTraceSummariesWorker,
           queue: :maintenance},
          {"*/15 * * * *", ServiceRadar.Jobs.ReapStalePeriodicJobsWorker, queue: :maintenance},
-         {"17 3 * * *", ServiceRadar.Observability.DataRetentionWorker, queue: :maintenance}
+         {"17 3 * * *", ServiceRadar.Observability.DataRetentionWorker, queue: :maintenance},
+         {"*/30 * * * *", ServiceRadar.Inventory.DeviceLifecycleWorker, queue: :maintenance},
+         {"*/5 * * * *", ServiceRadar.Infrastructure.GatewayGroupWorker, queue: :maintenance}
        ]}
     ],
     peer: Oban.Peers.Database
@@ -545,7 +545,13 @@ defmodule ServiceRadar.AgentConfig.Compilers.SNMPCompiler do
         "name" => Map.get(oid, "name"),
         "data_type" => to_string(Map.get(oid, "data_type", "gauge")),
         "scale" => Map.get(oid, "scale", 1.0),
-        "delta" => Map.get(oid, "delta", false)
+        "divisor" => Map.get(oid, "divisor", 1.0),
+        "unit" => Map.get(oid, "unit"),
+        "delta" => Map.get(oid, "delta", false),
+        "adaptive" => Map.get(oid, "adaptive", false),
+        "adaptive_min_interval_seconds" => Map.get(oid, "adaptive_min_interval_seconds"),
+        "adaptive_max_interval_seconds" => Map.get(oid, "adaptive_max_interval_seconds"),
+        "adaptive_change_threshold" => Map.get(oid, "adaptive_change_threshold")
       }
     end)
     |> sort_oids()
@@ -605,7 +611,13 @@ defmodule ServiceRadar.AgentConfig.Compilers.SNMPCompiler do
       "name" => oid.name,
       "data_type" => to_string(oid.data_type),
       "scale" => oid.scale || 1.0,
-      "delta" => oid.delta || false
+      "divisor" => oid.divisor || 1.0,
+      "unit" => oid.unit,
+      "delta" => oid.delta || false,
+      "adaptive" => oid.adaptive || false,
+      "adaptive_min_interval_seconds" => oid.adaptive_min_interval_seconds,
+      "adaptive_max_interval_seconds" => oid.adaptive_max_interval_seconds,
+      "adaptive_change_threshold" => oid.adaptive_change_threshold
     }
   end
 
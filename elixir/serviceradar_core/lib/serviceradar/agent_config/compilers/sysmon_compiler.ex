@@ -25,6 +25,7 @@ defmodule ServiceRadar.AgentConfig.Compilers.SysmonCompiler do
         "collect_cpu" => true,
         "collect_memory" => true,
         "collect_disk" => true,
+        "collect_disk_io" => true,
         "collect_network" => false,
         "collect_processes" => false,
         "disk_paths" => [],
@@ -122,6 +123,7 @@ defmodule ServiceRadar.AgentConfig.Compilers.SysmonCompiler do
       "collect_cpu" => profile.collect_cpu,
       "collect_memory" => profile.collect_memory,
       "collect_disk" => profile.collect_disk,
+      "collect_disk_io" => profile.collect_disk_io,
       "collect_network" => profile.collect_network,
       "collect_processes" => profile.collect_processes,
       "disk_paths" => profile.disk_paths,
@@ -144,6 +146,7 @@ defmodule ServiceRadar.AgentConfig.Compilers.SysmonCompiler do
       "collect_cpu" => false,
       "collect_memory" => false,
       "collect_disk" => false,
+      "collect_disk_io" => false,
       "collect_network" => false,
       "collect_processes" => false,
       "disk_paths" => [],
@@ -58,6 +58,12 @@ defmodule ServiceRadar.Application do
     ensure_started(:ash_state_machine)
     ensure_started(:ssl)
 
+    # Operator-defined identifier types must be known before DIRE resolves devices
+    ServiceRadar.Inventory.CustomIdentifierTypes.register!()
+
+    # Enrichment stages run on every ingested update, so resolve them once
+    ServiceRadar.Inventory.EnrichmentPipeline.register!()
+
     children =
       [
         # Encryption vault for AshCloak (must start before repo for encrypted field access)
@@ -101,12 +107,25 @@ defmodule ServiceRadar.Application do
         # Event batcher for high-frequency NATS events
         event_batcher_child(),
 
+        # Derived metric rules and the previous samples used for rates
+        ServiceRadar.Observability.MetricDerivation,
+
+        # SRQL result exports to Kafka (no children unless jobs are configured)
+        ServiceRadar.Observability.StreamExport,
+
         # Task supervisor for sync ingestion work
         sync_ingestor_task_supervisor_child(),
 
         # Sync ingestion queue/coalescer
         sync_ingestor_queue_child(),
 
+        # Optional GeoIP location of device IPs at ingestion
+        device_geo_enrichment_child(),
+
+        # Optional coalescing of agent heartbeat writes from status pushes
+        agent_heartbeat_batcher_child(),
+        degraded_mode_child(),
+
         # Horde registries (always started for registration support)
         registry_children(),
 
@@ -241,6 +260,24 @@ defmodule ServiceRadar.Application do
     ServiceRadar.Inventory.SyncIngestorQueue
   end
 
+  defp device_geo_enrichment_child do
+    if ServiceRadar.Inventory.DeviceGeoEnrichment.enabled?() do
+      ServiceRadar.Inventory.DeviceGeoEnrichment
+    end
+  end
+
+  defp agent_heartbeat_batcher_child do
+    if repo_enabled?() and ServiceRadar.Edge.AgentHeartbeatBatcher.enabled?() do
+      ServiceRadar.Edge.AgentHeartbeatBatcher
+    end
+  end
+
+  defp degraded_mode_child do
+    if repo_enabled?() and ServiceRadar.Infrastructure.DegradedMode.enabled?() do
+      ServiceRadar.Infrastructure.DegradedMode
+    end
+  end
+
   defp registry_children do
     if Application.get_env(:serviceradar_core, :registries_enabled, true) do
       # ProcessRegistry provides Horde registry + DynamicSupervisor as child_specs
@@ -0,0 +1,77 @@
+defmodule ServiceRadar.CheckerResultSchema do
+  @moduledoc """
+  Schema versioning for checker result payloads (`StatusResponse.message`,
+  `ResultsResponse.data`).
+
+  Version 1 is the legacy, unversioned payload. Later versions carry a
+  top-level `"schema_version"` field. Core advertises the highest version it
+  accepts to agents through `result_schema_version` in the agent config, and
+  agents never stamp a newer version than that, so payloads from newer
+  checkers stay readable. Payloads claiming a version this core does not know
+  are rejected instead of being misread.
+  """
+
+  @legacy_version 1
+  @max_supported_version 2
+  @version_key "schema_version"
+
+  @doc "Highest payload schema version this core accepts."
+  @spec max_supported_version() :: pos_integer()
+  def max_supported_version, do: @max_supported_version
+
+  @doc "Agent config_json key used to advertise `max_supported_version/0`."
+  @spec config_key() :: String.t()
+  def config_key, do: "result_schema_version"
+
+  @doc """
+  Decodes a checker payload and returns its schema version together with the
+  payload without the version field. Non-JSON and non-object payloads are
+  legacy payloads and are returned as-is.
+  """
+  @spec parse(binary() | map() | nil) ::
+          {:ok, pos_integer(), term()}
+          | {:error, {:unsupported_schema_version | :invalid_schema_version, term()}}
+  def parse(message) when is_binary(message) do
+    case Jason.decode(message) do
+      {:ok, %{} = decoded} -> parse(decoded)
+      {:ok, other} -> {:ok, @legacy_version, other}
+      {:error, _} -> {:ok, @legacy_version, message}
+    end
+  end
+
+  def parse(%{@version_key => version} = payload)
+      when is_integer(version) and version >= @legacy_version and
+             version <= @max_supported_version do
+    {:ok, version, Map.delete(payload, @version_key)}
+  end
+
+  def parse(%{@version_key => version}) when is_integer(version) and version > @max_supported_version do
+    {:error, {:unsupported_schema_version, version}}
+  end
+
+  def parse(%{@version_key => version}), do: {:error, {:invalid_schema_version, version}}
+
+  def parse(payload), do: {:ok, @legacy_version, payload}
+
+  @doc """
+  Returns `:ok` when the payload's schema version is supported. Binary
+  payloads without a version field are accepted without being decoded, since
+  result payloads can be large.
+  """
+  @spec check(binary() | map() | nil) :: :ok | {:error, term()}
+  def check(message) when is_binary(message) do
+    case :binary.match(message, ~s("#{@version_key}")) do
+      :nomatch -> :ok
+      _ -> check_parsed(message)
+    end
+  end
+
+  def check(message), do: check_parsed(message)
+
+  defp check_parsed(message) do
+    case parse(message) do
+      {:ok, _version, _payload} -> :ok
+      {:error, _} = error -> error
+    end
+  end
+end
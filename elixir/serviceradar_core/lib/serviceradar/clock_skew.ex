@@ -0,0 +1,279 @@
+defmodule ServiceRadar.ClockSkew do
+  @moduledoc """
+  Detects agents whose clocks disagree with ServiceRadar's.
+
+  Every status report carries the agent's own `agent_timestamp`, which the
+  ingestors prefer when ordering time-series data. `check/3` compares it to
+  the time the report was received: the gateway's receive `timestamp` when
+  present (so reports replayed from the gateway buffer are not mistaken for
+  skew), otherwise the core's clock. A report whose skew exceeds
+  `max_skew_seconds` in either direction is handled according to `action`:
+
+    * `:warn` - log it and keep the agent's timestamp (default)
+    * `:correct` - log it and replace the agent's timestamp with the receive
+      time, in the same unit
+    * `:reject` - log it and drop the report
+
+  An empty or zero `agent_timestamp` is removed in every mode, so consumers
+  fall back to the receive time instead of the Unix epoch.
+
+  `ServiceRadar.StatusHandler` also records a `component.clock_skew` OCSF
+  event for a skewed agent, at most once per `event_interval_seconds`, so
+  operators can fix its NTP configuration. Configure with:
+
+      config :serviceradar_core, ServiceRadar.ClockSkew,
+        max_skew_seconds: 120,
+        action: :correct,
+        event_interval_seconds: 3600
+
+  or at runtime with `SERVICERADAR_CLOCK_SKEW_MAX_SECONDS`,
+  `SERVICERADAR_CLOCK_SKEW_ACTION` and
+  `SERVICERADAR_CLOCK_SKEW_EVENT_INTERVAL_SECONDS`.
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Events.PubSub, as: EventsPubSub
+  alias ServiceRadar.Monitoring.OcsfEvent
+
+  @actions [:warn, :correct, :reject]
+  @default_max_skew_seconds 300
+  @default_event_interval_seconds 3600
+
+  @log_name "component.clock_skew"
+  @provider "serviceradar.clock_skew"
+
+  @type action :: :warn | :correct | :reject
+
+  @type config :: %{
+          max_skew_seconds: pos_integer(),
+          action: action(),
+          event_interval_seconds: non_neg_integer()
+        }
+
+  @type finding :: %{
+          agent_id: String.t() | nil,
+          gateway_id: String.t() | nil,
+          partition: String.t() | nil,
+          skew_seconds: float(),
+          max_skew_seconds: pos_integer(),
+          action: action()
+        }
+
+  @doc "OCSF log name of clock skew events."
+  @spec log_name() :: String.t()
+  def log_name, do: @log_name
+
+  @doc """
+  Returns the effective configuration. Invalid values fall back to the
+  defaults (#{@default_max_skew_seconds}s, `:warn`, #{@default_event_interval_seconds}s).
+  """
+  @spec config() :: config()
+  def config do
+    opts = Application.get_env(:serviceradar_core, __MODULE__, [])
+
+    %{
+      max_skew_seconds:
+        positive_integer(Keyword.get(opts, :max_skew_seconds), @default_max_skew_seconds),
+      action: parse_action(Keyword.get(opts, :action)),
+      event_interval_seconds:
+        non_negative_integer(
+          Keyword.get(opts, :event_interval_seconds),
+          @default_event_interval_seconds
+        )
+    }
+  end
+
+  @doc """
+  Checks a status report's `agent_timestamp` against its receive time.
+
+  `now` is the core's receive time in Unix milliseconds. Returns the status to
+  process (with the timestamp corrected or dropped as configured) and the
+  finding when the skew exceeded the threshold, or `{:reject, finding}`.
+  """
+  @spec check(map(), integer(), config()) ::
+          {:ok, map(), finding() | nil} | {:reject, finding()}
+  def check(status, now_ms \\ System.os_time(:millisecond), config \\ config())
+
+  def check(%{agent_timestamp: raw} = status, now_ms, config) do
+    case to_unix_ms(raw) do
+      {:ok, reported_ms, unit} ->
+        received_ms = received_ms(status, now_ms)
+        skew_seconds = (reported_ms - received_ms) / 1000
+
+        if abs(skew_seconds) > config.max_skew_seconds do
+          handle_skew(status, finding(status, skew_seconds, config), received_ms, unit)
+        else
+          {:ok, status, nil}
+        end
+
+      :error ->
+        {:ok, Map.delete(status, :agent_timestamp), nil}
+    end
+  end
+
+  def check(status, _now_ms, _config), do: {:ok, status, nil}
+
+  @doc """
+  Records a `component.clock_skew` OCSF event for a finding and broadcasts it.
+
+  Options:
+    - `:actor` - actor to record the event as (default: system actor)
+    - `:record_event` - 2-arity function used instead of creating the event
+  """
+  @spec record_event(finding(), keyword()) :: :ok | {:error, term()}
+  def record_event(finding, opts \\ []) do
+    actor = Keyword.get(opts, :actor, SystemActor.system(:clock_skew))
+    record_event = Keyword.get(opts, :record_event, &create_event/2)
+
+    with {:ok, event} <- record_event.(event_attrs(finding), actor) do
+      _ = EventsPubSub.broadcast_event(event)
+      :ok
+    end
+  rescue
+    error -> {:error, error}
+  end
+
+  @doc """
+  Converts a report timestamp to Unix milliseconds. Integers in seconds,
+  milliseconds or nanoseconds (told apart by magnitude), numeric strings and
+  ISO8601 strings are accepted; empty and zero timestamps are not.
+  """
+  @spec to_unix_ms(term()) :: {:ok, integer(), :second | :millisecond | :nanosecond} | :error
+  def to_unix_ms(value) when is_integer(value) and value > 1_000_000_000_000_000,
+    do: {:ok, div(value, 1_000_000), :nanosecond}
+
+  def to_unix_ms(value) when is_integer(value) and value > 1_000_000_000_000,
+    do: {:ok, value, :millisecond}
+
+  def to_unix_ms(value) when is_integer(value) and value > 0, do: {:ok, value * 1000, :second}
+
+  def to_unix_ms(%DateTime{} = value), do: {:ok, DateTime.to_unix(value, :millisecond), :second}
+
+  def to_unix_ms(value) when is_binary(value) do
+    value = String.trim(value)
+
+    case Integer.parse(value) do
+      {integer, ""} ->
+        to_unix_ms(integer)
+
+      _ ->
+        case DateTime.from_iso8601(value) do
+          {:ok, datetime, _offset} -> to_unix_ms(datetime)
+          {:error, _} -> :error
+        end
+    end
+  end
+
+  def to_unix_ms(_value), do: :error
+
+  defp event_attrs(finding) do
+    metadata =
+      finding
+      |> Map.update!(:action, &Atom.to_string/1)
+      |> Map.new(fn {key, value} -> {Atom.to_string(key), value} end)
+
+    %{
+      time: DateTime.truncate(DateTime.utc_now(), :microsecond),
+      class_uid: 1008,
+      category_uid: 1,
+      type_uid: 100_801,
+      activity_id: 1,
+      activity_name: "Clock Skew",
+      severity_id: 3,
+      severity: "Medium",
+      message: event_message(finding),
+      status_id: 2,
+      status: "Failure",
+      status_code: "clock_skew_exceeded",
+      status_detail: "clock skew #{format_skew(finding.skew_seconds)}",
+      metadata: metadata,
+      observables:
+        Enum.reject(
+          [
+            observable(finding.agent_id, "Agent ID"),
+            observable(finding.gateway_id, "Gateway ID")
+          ],
+          &is_nil/1
+        ),
+      actor: %{"app_name" => "serviceradar.core", "process" => "status_handler"},
+      device: %{},
+      src_endpoint: %{},
+      dst_endpoint: %{},
+      log_name: @log_name,
+      log_provider: @provider,
+      log_level: "warning",
+      log_version: "clock_skew.v1",
+      unmapped: %{"clock_skew" => metadata},
+      raw_data: Jason.encode!(metadata)
+    }
+  end
+
+  defp handle_skew(_status, %{action: :reject} = finding, _received_ms, _unit),
+    do: {:reject, finding}
+
+  defp handle_skew(status, %{action: :correct} = finding, received_ms, unit) do
+    {:ok, %{status | agent_timestamp: from_unix_ms(received_ms, unit, status.agent_timestamp)},
+     finding}
+  end
+
+  defp handle_skew(status, finding, _received_ms, _unit), do: {:ok, status, finding}
+
+  defp received_ms(status, now_ms) do
+    case to_unix_ms(Map.get(status, :timestamp)) do
+      {:ok, received_ms, _unit} -> received_ms
+      :error -> now_ms
+    end
+  end
+
+  defp from_unix_ms(ms, _unit, %DateTime{}), do: DateTime.from_unix!(ms, :millisecond)
+  defp from_unix_ms(ms, :nanosecond, _original), do: ms * 1_000_000
+  defp from_unix_ms(ms, :millisecond, _original), do: ms
+  defp from_unix_ms(ms, :second, original) when is_binary(original), do: to_string(div(ms, 1000))
+  defp from_unix_ms(ms, :second, _original), do: div(ms, 1000)
+
+  defp finding(status, skew_seconds, config) do
+    %{
+      agent_id: Map.get(status, :agent_id),
+      gateway_id: Map.get(status, :gateway_id),
+      partition: Map.get(status, :partition),
+      skew_seconds: Float.round(skew_seconds, 3),
+      max_skew_seconds: config.max_skew_seconds,
+      action: config.action
+    }
+  end
+
+  defp event_message(finding) do
+    "Agent #{finding.agent_id || "unknown"} clock is #{format_skew(finding.skew_seconds)} " <>
+      "(limit #{finding.max_skew_seconds}s); check its NTP configuration"
+  end
+
+  defp format_skew(seconds) when seconds >= 0, do: "#{seconds}s ahead"
+  defp format_skew(seconds), do: "#{-seconds}s behind"
+
+  defp observable(value, name) when is_binary(value) and value != "",
+    do: %{"name" => name, "type" => "string", "value" => value}
+
+  defp observable(_value, _name), do: nil
+
+  defp create_event(attrs, actor) do
+    Ash.create(OcsfEvent, attrs, action: :record, actor: actor, domain: ServiceRadar.Monitoring)
+  end
+
+  defp parse_action(action) when action in @actions, do: action
+
+  defp parse_action(action) when is_binary(action) do
+    case String.downcase(String.trim(action)) do
+      "correct" -> :correct
+      "reject" -> :reject
+      _ -> :warn
+    end
+  end
+
+  defp parse_action(_action), do: :warn
+
+  defp positive_integer(value, _default) when is_integer(value) and value > 0, do: value
+  defp positive_integer(_value, default), do: default
+
+  defp non_negative_integer(value, _default) when is_integer(value) and value >= 0, do: value
+  defp non_negative_integer(_value, default), do: default
+end
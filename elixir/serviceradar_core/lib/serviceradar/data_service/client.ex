@@ -29,6 +29,11 @@ defmodule ServiceRadar.DataService.Client do
   - `DATASVC_RECONNECT_BASE_MS` - base reconnect backoff in ms (default: 1000)
   - `DATASVC_RECONNECT_MAX_MS` - max reconnect backoff in ms (default: 30000)
   - `DATASVC_SERVER_NAME` - TLS server name for SNI (default: "datasvc.serviceradar")
+  - `DATASVC_PRIMARY_HOST` - primary region's datasvc, read when the local copy
+    of a key lags behind a required revision (optional)
+  - `DATASVC_PRIMARY_PORT` - primary datasvc port (default: `DATASVC_PORT`)
+  - `DATASVC_REVISION_WAIT_MS` - how long a revision-bound read waits for the
+    local copy to catch up (default: 2000)
 
   ## Usage
 
@@ -40,12 +45,23 @@ defmodule ServiceRadar.DataService.Client do
 
       # Delete a value
       :ok = ServiceRadar.DataService.Client.delete("sync/sources/123")
+
+  ## Read-your-writes
+
+  In multi-region deployments a value written in one region reaches the others
+  through replication. Pass the revision of a write as `:min_revision` to read
+  it back, or anything newer, from any region (see
+  `ServiceRadar.DataService.ConsistentRead`):
+
+      {:ok, revision} = Client.put_with_revision("config/x", value)
+      {:ok, value} = Client.get("config/x", min_revision: revision)
   """
 
   use GenServer
 
   alias GRPC.Client.Adapters.Gun
   alias Proto.KVService.Stub
+  alias ServiceRadar.DataService.ConsistentRead
 
   require Logger
 
@@ -55,6 +71,7 @@ defmodule ServiceRadar.DataService.Client do
   @default_connect_timeout 5_000
   @default_reconnect_base 1_000
   @default_reconnect_max 30_000
+  @default_revision_wait 2_000
 
   # Client API
 
@@ -70,6 +87,16 @@ defmodule ServiceRadar.DataService.Client do
     safe_call({:put, key, value, opts}, opts[:timeout] || @default_timeout)
   end
 
+  @doc """
+  Put a key-value pair and return the revision it was stored at, for callers
+  that need readers to see this write (see `:min_revision` on `get/2`).
+  """
+  @spec put_with_revision(String.t(), binary(), keyword()) ::
+          {:ok, non_neg_integer()} | {:error, term()}
+  def put_with_revision(key, value, opts \\ []) do
+    safe_call({:put_with_revision, key, value, opts}, opts[:timeout] || @default_timeout)
+  end
+
   @doc """
   Returns true if the datasvc channel is connected and alive.
   """
@@ -133,19 +160,57 @@ defmodule ServiceRadar.DataService.Client do
 
   @doc """
   Get a value from the KV store.
+
+  With `:min_revision`, only a value at that revision or newer is returned;
+  see `get_with_revision/2`.
   """
   @spec get(String.t(), keyword()) :: {:ok, binary()} | {:error, :not_found | term()}
   def get(key, opts \\ []) do
-    safe_call({:get, key, opts}, opts[:timeout] || @default_timeout)
+    if opts[:min_revision] do
+      with {:ok, value, _revision} <- get_with_revision(key, opts), do: {:ok, value}
+    else
+      safe_call({:get, key, opts}, opts[:timeout] || @default_timeout)
+    end
   end
 
   @doc """
   Get a value and revision from the KV store.
+
+  Options:
+    - `:min_revision` - wait up to `:revision_wait_ms` for the local datasvc to
+      reach this revision, then read from the primary datasvc if one is
+      configured. Fails with `{:error, {:revision_unavailable, min, seen}}`
+      when neither has it.
+    - `:revision_wait_ms` - overrides `DATASVC_REVISION_WAIT_MS`
   """
   @spec get_with_revision(String.t(), keyword()) ::
           {:ok, binary(), non_neg_integer()} | {:error, :not_found | term()}
   def get_with_revision(key, opts \\ []) do
-    safe_call({:get_with_revision, key, opts}, opts[:timeout] || @default_timeout)
+    case Keyword.pop(opts, :min_revision) do
+      {nil, opts} ->
+        safe_call({:get_with_revision, key, opts}, opts[:timeout] || @default_timeout)
+
+      {min_revision, opts} ->
+        config = build_config(opts)
+
+        ConsistentRead.fetch(key, min_revision,
+          fetch: &get_with_revision(&1, opts),
+          fetch_primary: primary_fetcher(config, opts),
+          revision_wait_ms: opts[:revision_wait_ms] || config.revision_wait_ms
+        )
+    end
+  end
+
+  @doc """
+  Get a value and revision directly from the primary datasvc.
+  """
+  @spec get_from_primary(String.t(), keyword()) ::
+          {:ok, binary(), non_neg_integer()} | {:error, :no_primary | :not_found | term()}
+  def get_from_primary(key, opts \\ []) do
+    case primary_fetcher(build_config(opts), opts) do
+      nil -> {:error, :no_primary}
+      fetch -> fetch.(key)
+    end
   end
 
   @doc """
@@ -164,6 +229,33 @@ defmodule ServiceRadar.DataService.Client do
     safe_call({:list_keys, prefix, opts}, opts[:timeout] || @default_timeout)
   end
 
+  @doc """
+  Delete every key under a prefix and return the deleted keys.
+
+  Stops at the first failed delete. An empty prefix is refused so a caller
+  cannot wipe the whole bucket by accident.
+  """
+  @spec delete_prefix(String.t(), keyword()) :: {:ok, [String.t()]} | {:error, term()}
+  def delete_prefix(prefix, opts \\ [])
+
+  def delete_prefix("", _opts), do: {:error, :empty_prefix}
+
+  def delete_prefix(prefix, opts) when is_binary(prefix) do
+    with {:ok, keys} <- list_keys(prefix, opts) do
+      keys
+      |> Enum.reduce_while({:ok, []}, fn key, {:ok, deleted} ->
+        case delete(key, opts) do
+          :ok -> {:cont, {:ok, [key | deleted]}}
+          {:error, _reason} = error -> {:halt, error}
+        end
+      end)
+      |> case do
+        {:ok, deleted} -> {:ok, Enum.reverse(deleted)}
+        error -> error
+      end
+    end
+  end
+
   @doc """
   Put multiple key-value pairs atomically.
   """
@@ -327,6 +419,18 @@ defmodule ServiceRadar.DataService.Client do
     {:reply, result, state}
   end
 
+  def handle_call({:put_with_revision, key, value, opts}, _from, state) do
+    {result, state} =
+      call_with_channel(state, fn channel ->
+        with :ok <- do_put(channel, key, value, opts),
+             {:ok, _value, revision} <- do_get_with_revision(channel, key, opts) do
+          {:ok, revision}
+        end
+      end)
+
+    {:reply, result, state}
+  end
+
   def handle_call({:get, key, opts}, _from, state) do
     {result, state} = call_with_channel(state, &do_get(&1, key, opts))
     {:reply, result, state}
@@ -415,10 +519,34 @@ defmodule ServiceRadar.DataService.Client do
           :server_name,
           "DATASVC_SERVER_NAME",
           "datasvc.serviceradar"
+        ),
+      primary_host: config_value(opts, app_config, :primary_host, "DATASVC_PRIMARY_HOST"),
+      primary_port:
+        config_value_int(opts, app_config, :primary_port, "DATASVC_PRIMARY_PORT", nil),
+      revision_wait_ms:
+        config_value_int(
+          opts,
+          app_config,
+          :revision_wait_ms,
+          "DATASVC_REVISION_WAIT_MS",
+          @default_revision_wait
         )
     }
   end
 
+  defp primary_fetcher(%{primary_host: nil}, _opts), do: nil
+
+  defp primary_fetcher(config, opts) do
+    connect_opts =
+      opts
+      |> Keyword.put(:host, config.primary_host)
+      |> Keyword.put(:port, config.primary_port || config.port)
+
+    fn key ->
+      with_direct_channel(&do_get_with_revision(&1, key, opts), connect_opts)
+    end
+  end
+
   defp config_value(opts, app_config, key, env_key, default \\ nil) do
     opts[key] || get_env(env_key) || app_config[key] || default
   end
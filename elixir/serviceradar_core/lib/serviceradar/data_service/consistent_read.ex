@@ -0,0 +1,156 @@
+defmodule ServiceRadar.DataService.ConsistentRead do
+  @moduledoc """
+  Revision-aware KV reads for multi-region deployments.
+
+  A config write lands in one region's datasvc and reaches the others through
+  JetStream replication, so a reader in another region can briefly see an
+  older value. A caller that knows the revision it needs (for example the
+  revision returned by `ServiceRadar.DataService.Client.put_with_revision/3`)
+  passes it as `:min_revision`, and the read:
+
+  1. returns the local value once its revision is at least `min_revision`,
+     polling the local datasvc for up to `:revision_wait_ms`;
+  2. then reads from the primary datasvc (`DATASVC_PRIMARY_HOST`), if one is
+     configured;
+  3. otherwise fails with `{:error, {:revision_unavailable, min, seen}}`.
+
+  A key the local datasvc does not have yet counts as revision 0, so a newly
+  created key is waited for like any other.
+
+  Every revision-bound read emits `[:serviceradar, :datasvc, :consistent_read]`
+  with the time spent waiting (`wait_ms`) and how many revisions the local
+  copy was behind when the read started (`revisions_behind`), tagged with the
+  `outcome`: `:local`, `:waited`, `:primary`, `:unavailable` or `:error`.
+  """
+
+  require Logger
+
+  @default_wait_ms 2_000
+  @initial_poll_ms 25
+  @max_poll_ms 250
+
+  @telemetry_event [:serviceradar, :datasvc, :consistent_read]
+
+  @doc "Telemetry event emitted for every revision-bound read."
+  @spec telemetry_event() :: [atom()]
+  def telemetry_event, do: @telemetry_event
+
+  @doc """
+  Reads `key` at `min_revision` or newer.
+
+  Options:
+    - `:fetch` - reads the key from the local datasvc (required)
+    - `:fetch_primary` - reads the key from the primary datasvc, or nil when
+      there is no primary
+    - `:revision_wait_ms` - how long to wait for the local copy to catch up
+      (default: #{@default_wait_ms})
+  """
+  @spec fetch(String.t(), non_neg_integer(), keyword()) ::
+          {:ok, binary(), non_neg_integer()}
+          | {:error, :not_found | {:revision_unavailable, non_neg_integer(), non_neg_integer()}}
+          | {:error, term()}
+  def fetch(key, min_revision, opts)
+      when is_binary(key) and is_integer(min_revision) and min_revision >= 0 do
+    fetch_local = Keyword.fetch!(opts, :fetch)
+    wait_ms = Keyword.get(opts, :revision_wait_ms) || @default_wait_ms
+    started = System.monotonic_time(:millisecond)
+
+    case local_revision(fetch_local, key, min_revision) do
+      {:ok, value, revision} ->
+        emit(:local, started, 0, key)
+        {:ok, value, revision}
+
+      {:behind, revision} ->
+        behind = min_revision - revision
+        deadline = started + wait_ms
+
+        key
+        |> await(fetch_local, min_revision, deadline, @initial_poll_ms, revision)
+        |> finish(key, min_revision, behind, started, opts)
+
+      {:error, reason} ->
+        emit(:error, started, 0, key)
+        {:error, reason}
+    end
+  end
+
+  defp local_revision(fetch_local, key, min_revision) do
+    case fetch_local.(key) do
+      {:ok, value, revision} when revision >= min_revision -> {:ok, value, revision}
+      {:ok, _value, revision} -> {:behind, revision}
+      {:error, :not_found} -> {:behind, 0}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp await(key, fetch_local, min_revision, deadline, poll_ms, seen) do
+    remaining = deadline - System.monotonic_time(:millisecond)
+
+    if remaining <= 0 do
+      {:timeout, seen}
+    else
+      Process.sleep(min(poll_ms, remaining))
+
+      case local_revision(fetch_local, key, min_revision) do
+        {:behind, revision} ->
+          next_poll_ms = min(poll_ms * 2, @max_poll_ms)
+          await(key, fetch_local, min_revision, deadline, next_poll_ms, max(seen, revision))
+
+        result ->
+          result
+      end
+    end
+  end
+
+  defp finish({:ok, value, revision}, key, _min, behind, started, _opts) do
+    emit(:waited, started, behind, key)
+    {:ok, value, revision}
+  end
+
+  defp finish({:timeout, seen}, key, min_revision, behind, started, opts) do
+    case Keyword.get(opts, :fetch_primary) do
+      nil ->
+        unavailable(key, min_revision, seen, behind, started)
+
+      fetch_primary ->
+        case fetch_primary.(key) do
+          {:ok, value, revision} when revision >= min_revision ->
+            emit(:primary, started, behind, key)
+            {:ok, value, revision}
+
+          {:ok, _value, revision} ->
+            unavailable(key, min_revision, max(seen, revision), behind, started)
+
+          {:error, :not_found} ->
+            emit(:primary, started, behind, key)
+            {:error, :not_found}
+
+          {:error, reason} ->
+            Logger.warning(
+              "DataService primary read of #{key} failed: #{inspect(reason)}, " <>
+                "local copy at revision #{seen} of #{min_revision}"
+            )
+
+            unavailable(key, min_revision, seen, behind, started)
+        end
+    end
+  end
+
+  defp finish({:error, reason}, key, _min, behind, started, _opts) do
+    emit(:error, started, behind, key)
+    {:error, reason}
+  end
+
+  defp unavailable(key, min_revision, seen, behind, started) do
+    emit(:unavailable, started, behind, key)
+    {:error, {:revision_unavailable, min_revision, seen}}
+  end
+
+  defp emit(outcome, started, behind, key) do
+    :telemetry.execute(
+      @telemetry_event,
+      %{wait_ms: System.monotonic_time(:millisecond) - started, revisions_behind: behind},
+      %{outcome: outcome, key: key}
+    )
+  end
+end
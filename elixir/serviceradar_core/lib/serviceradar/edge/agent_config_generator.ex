@@ -33,6 +33,7 @@ defmodule ServiceRadar.Edge.AgentConfigGenerator do
   alias ServiceRadar.AgentConfig.Compilers.SysmonCompiler
   alias ServiceRadar.AgentConfig.ConfigServer
   alias ServiceRadar.AgentRegistry
+  alias ServiceRadar.CheckerResultSchema
   alias ServiceRadar.Edge.SNMPProtoMapper
   alias ServiceRadar.Infrastructure.Agent
   alias ServiceRadar.Integrations.SyncConfigGenerator
@@ -193,11 +194,24 @@ defmodule ServiceRadar.Edge.AgentConfigGenerator do
         port: check.port || 0,
         path: check.path || "",
         method: check.method || "",
-        settings: check.settings || %{}
+        settings: proto_settings(check.settings)
       }
     end)
   end
 
+  # Proto settings are a string map. Nested values (such as a composite
+  # check's sub-check list) are sent as JSON for the agent to decode.
+  defp proto_settings(nil), do: %{}
+
+  defp proto_settings(settings) when is_map(settings) do
+    Map.new(settings, fn {key, value} -> {to_string(key), proto_setting_value(value)} end)
+  end
+
+  defp proto_setting_value(value) when is_binary(value), do: value
+  defp proto_setting_value(value) when is_map(value) or is_list(value), do: Jason.encode!(value)
+  defp proto_setting_value(nil), do: ""
+  defp proto_setting_value(value), do: to_string(value)
+
   @doc """
   Converts a generated config map into an AgentConfigResponse proto struct.
   """
@@ -663,6 +677,7 @@ defmodule ServiceRadar.Edge.AgentConfigGenerator do
       sync_payload
       |> Map.put("sweep", sweep_config)
       |> Map.put("mapper", mapper_config)
+      |> Map.put(CheckerResultSchema.config_key(), CheckerResultSchema.max_supported_version())
 
     # Compute version hash from all config components
     config_version =
@@ -727,6 +742,8 @@ defmodule ServiceRadar.Edge.AgentConfigGenerator do
   defp atom_to_check_type(:grpc), do: "grpc"
   defp atom_to_check_type(:dns), do: "dns"
   defp atom_to_check_type(:mtr), do: "mtr"
+  defp atom_to_check_type(:composite), do: "composite"
+  defp atom_to_check_type(:transaction), do: "transaction"
   defp atom_to_check_type(:custom), do: "custom"
   defp atom_to_check_type(other) when is_atom(other), do: Atom.to_string(other)
   defp atom_to_check_type(other) when is_binary(other), do: other
@@ -1068,6 +1085,7 @@ defmodule ServiceRadar.Edge.AgentConfigGenerator do
       collect_cpu: Map.get(config, "collect_cpu", true),
       collect_memory: Map.get(config, "collect_memory", true),
       collect_disk: Map.get(config, "collect_disk", true),
+      collect_disk_io: Map.get(config, "collect_disk_io", true),
       collect_network: Map.get(config, "collect_network", false),
       collect_processes: Map.get(config, "collect_processes", false),
       disk_paths: Map.get(config, "disk_paths", []),
@@ -1148,7 +1166,13 @@ defmodule ServiceRadar.Edge.AgentConfigGenerator do
       name: Map.get(oid, "name", "") || "",
       data_type: SNMPProtoMapper.data_type(Map.get(oid, "data_type")),
       scale: Map.get(oid, "scale", 1.0) || 1.0,
-      delta: Map.get(oid, "delta", false) || false
+      delta: Map.get(oid, "delta", false) || false,
+      divisor: Map.get(oid, "divisor", 1.0) || 1.0,
+      unit: Map.get(oid, "unit", "") || "",
+      adaptive: Map.get(oid, "adaptive", false) || false,
+      adaptive_min_interval_seconds: Map.get(oid, "adaptive_min_interval_seconds") || 0,
+      adaptive_max_interval_seconds: Map.get(oid, "adaptive_max_interval_seconds") || 0,
+      adaptive_change_threshold: Map.get(oid, "adaptive_change_threshold") || 0.0
     }
   end
 
@@ -12,11 +12,13 @@ defmodule ServiceRadar.Edge.AgentGatewaySync do
   alias Ash.Error.Invalid
   alias Ash.Error.Query.NotFound
   alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Edge.AgentHeartbeatBatcher
   alias ServiceRadar.Edge.AgentReleaseManager
   alias ServiceRadar.Edge.AgentReleaseTarget
   alias ServiceRadar.Edge.OnboardingPackage
   alias ServiceRadar.Edge.ReleaseArtifactDelivery
   alias ServiceRadar.Infrastructure.Agent
+  alias ServiceRadar.Infrastructure.DegradedMode
   alias ServiceRadar.Inventory.Device
   alias ServiceRadar.Inventory.IdentityReconciler
 
@@ -77,8 +79,74 @@ defmodule ServiceRadar.Edge.AgentGatewaySync do
     end
   end
 
+  @doc """
+  Heartbeats an agent's record. With `AgentHeartbeatBatcher` running,
+  steady-state heartbeats are queued and written with its next flush. With
+  `ServiceRadar.Infrastructure.DegradedMode` running, a heartbeat the
+  database cannot take is buffered until it recovers.
+  """
   @spec heartbeat_agent(String.t(), map()) :: :ok | {:error, term()}
   def heartbeat_agent(agent_id, attrs) do
+    case AgentHeartbeatBatcher.batch(agent_id, attrs) do
+      :queued ->
+        :ok
+
+      :write ->
+        result =
+          DegradedMode.report({:agent_heartbeat, agent_id}, fn ->
+            write_heartbeat(agent_id, attrs)
+          end)
+
+        AgentHeartbeatBatcher.written(agent_id, attrs, result)
+        result
+    end
+  end
+
+  @doc """
+  Writes a batch of `{agent_id, attrs}` heartbeats. Connected, healthy
+  agents sharing the same heartbeat attributes are updated with one bulk
+  update; any other agent is heartbeated on its own, which restores or
+  creates its record. Returns the outcome per agent.
+  """
+  @spec heartbeat_agents([{String.t(), map()}]) :: %{String.t() => :ok | {:error, term()}}
+  def heartbeat_agents(entries) do
+    actor = SystemActor.system(:gateway_sync)
+    attrs_by_agent = Map.new(entries)
+
+    entries
+    |> Enum.group_by(fn {_agent_id, attrs} -> heartbeat_attrs(attrs) end, &elem(&1, 0))
+    |> Enum.flat_map(fn {heartbeat_attrs, agent_ids} ->
+      updated = bulk_heartbeat(agent_ids, heartbeat_attrs, actor)
+
+      Enum.map(agent_ids, fn agent_id ->
+        if MapSet.member?(updated, agent_id),
+          do: {agent_id, :ok},
+          else: {agent_id, write_heartbeat(agent_id, Map.fetch!(attrs_by_agent, agent_id))}
+      end)
+    end)
+    |> Map.new()
+  end
+
+  defp bulk_heartbeat(agent_ids, heartbeat_attrs, actor) do
+    Agent
+    |> Ash.Query.filter(uid in ^agent_ids and status == :connected and is_healthy == true)
+    |> Ash.bulk_update(:heartbeat, heartbeat_attrs,
+      actor: actor,
+      strategy: [:atomic, :stream],
+      return_records?: true,
+      return_errors?: true
+    )
+    |> case do
+      %Ash.BulkResult{status: :success, records: records} ->
+        MapSet.new(records || [], & &1.uid)
+
+      %Ash.BulkResult{records: records, errors: errors} ->
+        Logger.warning("Bulk agent heartbeat failed: #{inspect(List.first(errors || []))}")
+        MapSet.new(records || [], & &1.uid)
+    end
+  end
+
+  defp write_heartbeat(agent_id, attrs) do
     # DB connection's search_path determines the schema
     actor = SystemActor.system(:gateway_sync)
 
@@ -634,14 +702,9 @@ defmodule ServiceRadar.Edge.AgentGatewaySync do
       restore_connected_agent(agent, actor)
     end
 
-    heartbeat_attrs =
-      attrs
-      |> Map.take([:capabilities, :is_healthy, :config_source])
-      |> compact_attrs()
-
     # DB connection's search_path determines the schema
     agent
-    |> Ash.Changeset.for_update(:heartbeat, heartbeat_attrs)
+    |> Ash.Changeset.for_update(:heartbeat, heartbeat_attrs(attrs))
     |> Ash.update(actor: actor)
     |> case do
       {:ok, _} -> :ok
@@ -649,6 +712,12 @@ defmodule ServiceRadar.Edge.AgentGatewaySync do
     end
   end
 
+  defp heartbeat_attrs(attrs) do
+    attrs
+    |> Map.take([:capabilities, :is_healthy, :config_source])
+    |> compact_attrs()
+  end
+
   defp restore_connected_agent(agent, actor) do
     attrs =
       agent
@@ -0,0 +1,209 @@
+defmodule ServiceRadar.Edge.AgentHeartbeatBatcher do
+  @moduledoc """
+  Coalesces the agent status writes of the status report path.
+
+  Every status push an agent sends through agent-gateway heartbeats the
+  agent's record (`ServiceRadar.Edge.AgentGatewaySync.heartbeat_agent/2`),
+  which at scale is a stream of small writes. With batching enabled,
+  steady-state heartbeats are held here instead and written together, with
+  one bulk update per flush, whenever `max_batch_size` agents are pending or
+  every `flush_interval_ms`. Only the latest heartbeat of each agent is kept,
+  so `last_seen_time` lags by at most one flush interval.
+
+  Heartbeats that change an agent's state are never held; the caller writes
+  them immediately so state changes and their alerts are not delayed:
+
+    * the first heartbeat of an agent since this node started
+    * a change of the reported health (`is_healthy`)
+    * the first heartbeat after the agent left the connected state, as
+      announced by a health event (the agent reconnecting)
+
+  A health event taking an agent out of the connected state also drops its
+  pending heartbeat, so a late heartbeat cannot bring it back.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Edge.AgentHeartbeatBatcher,
+        enabled: true,
+        flush_interval_ms: 5_000,
+        max_batch_size: 500
+  """
+
+  use GenServer
+
+  alias ServiceRadar.Edge.AgentGatewaySync
+  alias ServiceRadar.Infrastructure.HealthPubSub
+
+  require Logger
+
+  @default_flush_interval_ms 5_000
+  @default_max_batch_size 500
+
+  @type entry :: {String.t(), map()}
+  @type write_fn :: ([entry()] -> %{String.t() => :ok | {:error, term()}})
+
+  @doc "Whether batching is enabled in the application config."
+  @spec enabled?() :: boolean()
+  def enabled?, do: Keyword.get(config(), :enabled, false) == true
+
+  @doc """
+  Starts the batcher. Options override the application config:
+
+    * `:flush_interval_ms` - how often pending heartbeats are written
+    * `:max_batch_size` - pending agents that trigger an early flush
+    * `:write_fn` - writes a batch of `{agent_id, attrs}` entries, returning
+      the outcome per agent (default `AgentGatewaySync.heartbeat_agents/1`)
+    * `:name` - registered name (default `#{inspect(__MODULE__)}`)
+  """
+  def start_link(opts \\ []) do
+    GenServer.start_link(__MODULE__, opts, name: Keyword.get(opts, :name, __MODULE__))
+  end
+
+  @doc """
+  Offers a heartbeat for batching. Returns `:queued` when it will be written
+  with the next flush, or `:write` when the caller must write it now (and
+  then report the outcome with `written/4`). Also returns `:write` when the
+  batcher is not running.
+  """
+  @spec batch(String.t(), map(), GenServer.server()) :: :queued | :write
+  def batch(agent_id, attrs, server \\ __MODULE__) do
+    if running?(server), do: GenServer.call(server, {:batch, agent_id, attrs}), else: :write
+  catch
+    :exit, _reason -> :write
+  end
+
+  @doc "Records the outcome of a heartbeat the caller wrote itself."
+  @spec written(String.t(), map(), :ok | {:error, term()}, GenServer.server()) :: :ok
+  def written(agent_id, attrs, result, server \\ __MODULE__) do
+    if running?(server), do: GenServer.cast(server, {:written, agent_id, attrs, result})
+    :ok
+  end
+
+  @doc "Writes all pending heartbeats now."
+  @spec flush(GenServer.server()) :: :ok
+  def flush(server \\ __MODULE__), do: GenServer.call(server, :flush)
+
+  defp running?(server) when is_pid(server), do: Process.alive?(server)
+  defp running?(server) when is_atom(server), do: Process.whereis(server) != nil
+  defp running?(_server), do: true
+
+  @impl true
+  def init(opts) do
+    opts = Keyword.merge(config(), opts)
+
+    if Process.whereis(ServiceRadar.PubSub) do
+      Phoenix.PubSub.subscribe(ServiceRadar.PubSub, HealthPubSub.topic())
+    end
+
+    state = %{
+      # agent_id => last written is_healthy, or :down after leaving :connected
+      known: %{},
+      pending: %{},
+      write_fn: Keyword.get(opts, :write_fn, &AgentGatewaySync.heartbeat_agents/1),
+      flush_interval_ms: Keyword.get(opts, :flush_interval_ms, @default_flush_interval_ms),
+      max_batch_size: Keyword.get(opts, :max_batch_size, @default_max_batch_size)
+    }
+
+    schedule_flush(state)
+    {:ok, state}
+  end
+
+  @impl true
+  def handle_call({:batch, agent_id, attrs}, _from, state) do
+    if steady?(state.known, agent_id, attrs) do
+      state = %{state | pending: Map.put(state.pending, agent_id, attrs)}
+
+      state =
+        if map_size(state.pending) >= state.max_batch_size,
+          do: do_flush(state, :size),
+          else: state
+
+      {:reply, :queued, state}
+    else
+      {:reply, :write, %{state | pending: Map.delete(state.pending, agent_id)}}
+    end
+  end
+
+  def handle_call(:flush, _from, state) do
+    {:reply, :ok, do_flush(state, :manual)}
+  end
+
+  @impl true
+  def handle_cast({:written, agent_id, attrs, result}, state) do
+    {:noreply, %{state | known: remember(state.known, agent_id, attrs, result)}}
+  end
+
+  @impl true
+  def handle_info(:flush, state) do
+    schedule_flush(state)
+    {:noreply, do_flush(state, :interval)}
+  end
+
+  def handle_info({:health_event, %{entity_type: :agent, entity_id: agent_id} = event}, state)
+      when is_binary(agent_id) do
+    if Map.get(event, :new_state) == :connected do
+      {:noreply, state}
+    else
+      {:noreply,
+       %{
+         state
+         | known: Map.put(state.known, agent_id, :down),
+           pending: Map.delete(state.pending, agent_id)
+       }}
+    end
+  end
+
+  def handle_info(_message, state), do: {:noreply, state}
+
+  defp steady?(known, agent_id, attrs) do
+    case Map.fetch(known, agent_id) do
+      {:ok, healthy} when is_boolean(healthy) -> Map.get(attrs, :is_healthy, healthy) == healthy
+      _ -> false
+    end
+  end
+
+  defp remember(known, agent_id, attrs, :ok),
+    do: Map.put(known, agent_id, Map.get(attrs, :is_healthy, true))
+
+  defp remember(known, agent_id, _attrs, _result), do: Map.delete(known, agent_id)
+
+  defp do_flush(%{pending: pending} = state, _reason) when map_size(pending) == 0, do: state
+
+  defp do_flush(state, reason) do
+    entries = Map.to_list(state.pending)
+    started_at = System.monotonic_time()
+
+    results =
+      try do
+        state.write_fn.(entries)
+      rescue
+        e ->
+          Logger.warning("Agent heartbeat flush failed: #{Exception.message(e)}")
+          %{}
+      end
+
+    known =
+      Enum.reduce(entries, state.known, fn {agent_id, attrs}, known ->
+        remember(known, agent_id, attrs, Map.get(results, agent_id, {:error, :not_written}))
+      end)
+
+    :telemetry.execute(
+      [:serviceradar, :core, :agent_heartbeats, :flush],
+      %{
+        count: length(entries),
+        duration_ms:
+          System.convert_time_unit(System.monotonic_time() - started_at, :native, :millisecond)
+      },
+      %{reason: reason}
+    )
+
+    %{state | known: known, pending: %{}}
+  end
+
+  defp schedule_flush(%{flush_interval_ms: interval}) when is_integer(interval) and interval > 0,
+    do: Process.send_after(self(), :flush, interval)
+
+  defp schedule_flush(_state), do: :ok
+
+  defp config, do: Application.get_env(:serviceradar_core, __MODULE__, [])
+end
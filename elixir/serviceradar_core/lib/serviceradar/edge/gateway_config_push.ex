@@ -0,0 +1,477 @@
+defmodule ServiceRadar.Edge.GatewayConfigPush do
+  @moduledoc """
+  Applies field-level patches to the KV-stored configuration of many
+  gateways at once, with a dry-run preview, an audit log entry and
+  per-gateway config version history.
+
+  Gateway configs live at `config/gateways/<gateway_id>.json`. A patch names
+  dotted paths into that JSON object (`"poll_interval"`,
+  `"security.mode"`); every field not named is left untouched. `gateway_id`
+  and `partition` cannot be patched.
+
+  Each write appends a version under
+  `config/history/gateways/<gateway_id>/<version>.json`, with a `latest` key
+  holding the current version number.
+
+  ## Usage
+
+      GatewayConfigPush.push(
+        %{
+          gateway_ids: ["gw-east"],
+          patch: %{set: %{"poll_interval" => "10s"}, unset: ["max_retries"]},
+          dry_run: true
+        },
+        "ops@example.com"
+      )
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.DataService.Client
+  alias ServiceRadar.Infrastructure.Gateway
+
+  require Logger
+
+  @config_prefix "config/gateways/"
+  @history_prefix "config/history/gateways/"
+  @protected_fields ~w(gateway_id partition)
+
+  @skip_not_found "config not found"
+  @skip_out_of_scope "outside partition scope"
+  @skip_no_change "already matches patch"
+
+  @type patch :: %{optional(:set) => %{String.t() => term()}, optional(:unset) => [String.t()]}
+
+  @type request :: %{
+          optional(:gateway_ids) => [String.t()],
+          optional(:all) => boolean(),
+          optional(:partition) => String.t() | nil,
+          optional(:dry_run) => boolean(),
+          optional(:reason) => String.t() | nil,
+          required(:patch) => patch()
+        }
+
+  @type gateway_result :: %{
+          gateway_id: String.t(),
+          key: String.t() | nil,
+          partition: String.t() | nil,
+          changed: [String.t()],
+          before: map() | nil,
+          after: map() | nil,
+          version: pos_integer() | nil,
+          skipped: String.t() | nil,
+          error: String.t() | nil
+        }
+
+  @type result :: %{
+          change_id: String.t(),
+          dry_run: boolean(),
+          applied: non_neg_integer(),
+          skipped: non_neg_integer(),
+          failed: non_neg_integer(),
+          results: [gateway_result()]
+        }
+
+  @doc """
+  Applies the request's patch to every selected gateway, or only previews
+  the result when `dry_run` is set. Per-gateway failures are reported in the
+  result rather than aborting the remaining gateways.
+
+  `gateway_ids` and `all` are mutually exclusive; with `all` every
+  registered gateway is selected. With `partition`, selected gateways whose
+  config names another partition are skipped.
+
+  ## Options
+
+    - `:kv` - KV client module (default: `ServiceRadar.DataService.Client`)
+    - `:list_gateways` - `fn -> {:ok, [gateway_id]} end` used for `all`
+      (default: reads `ServiceRadar.Infrastructure.Gateway`)
+    - `:audit` - `fn entry -> :ok end` (default: logs the entry)
+    - `:now` - reference time (default: `DateTime.utc_now/0`)
+  """
+  @spec push(request(), String.t() | nil, keyword()) :: {:ok, result()} | {:error, String.t()}
+  def push(request, actor, opts \\ []) do
+    patch = Map.get(request, :patch) || %{}
+    now = Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+    dry_run = Map.get(request, :dry_run) == true
+
+    with :ok <- validate_patch(patch),
+         {:ok, targets} <- targets(request, opts) do
+      change_id = "cfg-#{DateTime.to_unix(now, :nanosecond)}"
+
+      context = %{
+        kv: Keyword.get(opts, :kv, Client),
+        partition: blank_to_nil(Map.get(request, :partition)),
+        patch: patch,
+        dry_run: dry_run,
+        actor: actor,
+        reason: Map.get(request, :reason),
+        change_id: change_id,
+        now: now
+      }
+
+      results = Enum.map(targets, &push_one(context, &1))
+
+      result = %{
+        change_id: change_id,
+        dry_run: dry_run,
+        applied: Enum.count(results, &applied?/1),
+        skipped: Enum.count(results, &(is_nil(&1.error) and not is_nil(&1.skipped))),
+        failed: Enum.count(results, &(not is_nil(&1.error))),
+        results: results
+      }
+
+      unless dry_run, do: audit(context, result, opts)
+
+      {:ok, result}
+    end
+  end
+
+  @doc """
+  Returns a stored version of a gateway's config, or `{:error, :not_found}`.
+  """
+  @spec version(String.t(), pos_integer(), keyword()) :: {:ok, map()} | {:error, term()}
+  def version(gateway_id, version, opts \\ []) do
+    kv = Keyword.get(opts, :kv, Client)
+
+    with {:ok, raw} <- kv.get(history_key(gateway_id, "#{version}.json"), []) do
+      Jason.decode(raw)
+    end
+  end
+
+  @doc "The KV key holding a gateway's config."
+  @spec config_key(String.t()) :: String.t()
+  def config_key(gateway_id), do: @config_prefix <> gateway_id <> ".json"
+
+  defp push_one(context, gateway_id) do
+    base = %{
+      gateway_id: gateway_id,
+      key: nil,
+      partition: nil,
+      changed: [],
+      before: nil,
+      after: nil,
+      version: nil,
+      skipped: nil,
+      error: nil
+    }
+
+    if String.contains?(gateway_id, ["/", ".."]) do
+      %{base | error: "invalid gateway id"}
+    else
+      key = config_key(gateway_id)
+      patch_gateway(context, %{base | key: key})
+    end
+  end
+
+  defp patch_gateway(context, result) do
+    case load_config(context.kv, result.key) do
+      {:error, :not_found} ->
+        %{result | skipped: @skip_not_found}
+
+      {:error, reason} ->
+        %{result | error: reason}
+
+      {:ok, before} ->
+        partition = Map.get(before, "partition")
+        result = %{result | partition: partition}
+
+        if context.partition && partition != context.partition do
+          %{result | skipped: @skip_out_of_scope}
+        else
+          apply_and_write(context, result, before)
+        end
+    end
+  end
+
+  defp apply_and_write(context, result, before) do
+    case apply_patch(context.patch, before) do
+      {:error, reason} ->
+        %{result | error: reason}
+
+      {:ok, _after, []} ->
+        %{result | before: before, skipped: @skip_no_change}
+
+      {:ok, updated, changed} ->
+        result = %{result | before: before, after: updated, changed: changed}
+
+        if context.dry_run, do: result, else: write(context, result)
+    end
+  end
+
+  defp write(context, result) do
+    data = Jason.encode!(result.after, pretty: true)
+
+    case context.kv.put(result.key, data, []) do
+      :ok ->
+        case append_version(context, result) do
+          {:ok, version} ->
+            %{result | version: version}
+
+          {:error, reason} ->
+            %{result | error: "config applied but version history failed: #{inspect(reason)}"}
+        end
+
+      {:error, reason} ->
+        %{result | error: "write config: #{inspect(reason)}"}
+    end
+  end
+
+  defp append_version(context, result) do
+    latest_key = history_key(result.gateway_id, "latest")
+
+    with {:ok, latest} <- latest_version(context.kv, latest_key) do
+      version = latest + 1
+
+      entry =
+        Jason.encode!(%{
+          version: version,
+          gateway_id: result.gateway_id,
+          key: result.key,
+          change_id: context.change_id,
+          actor: context.actor,
+          reason: context.reason,
+          changed: result.changed,
+          config: result.after,
+          at: DateTime.to_iso8601(context.now)
+        })
+
+      with :ok <- context.kv.put(history_key(result.gateway_id, "#{version}.json"), entry, []),
+           :ok <- context.kv.put(latest_key, Integer.to_string(version), []) do
+        {:ok, version}
+      end
+    end
+  end
+
+  defp latest_version(kv, key) do
+    case kv.get(key, []) do
+      {:ok, raw} ->
+        case Integer.parse(String.trim(raw)) do
+          {latest, ""} -> {:ok, latest}
+          _ -> {:error, "invalid latest version #{inspect(raw)}"}
+        end
+
+      {:error, :not_found} ->
+        {:ok, 0}
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  defp load_config(kv, key) do
+    case kv.get(key, []) do
+      {:ok, raw} when is_binary(raw) ->
+        if String.trim(raw) == "" do
+          {:error, :not_found}
+        else
+          decode_config(raw)
+        end
+
+      {:error, :not_found} ->
+        {:error, :not_found}
+
+      {:error, reason} ->
+        {:error, "read config: #{inspect(reason)}"}
+    end
+  end
+
+  defp decode_config(raw) do
+    case Jason.decode(raw) do
+      {:ok, config} when is_map(config) -> {:ok, config}
+      _ -> {:error, "stored config is not a JSON object"}
+    end
+  end
+
+  defp validate_patch(patch) do
+    set = Map.get(patch, :set) || %{}
+    unset = Map.get(patch, :unset) || []
+
+    cond do
+      not is_map(set) or not is_list(unset) ->
+        {:error, "invalid patch"}
+
+      map_size(set) == 0 and unset == [] ->
+        {:error, "patch changes no fields"}
+
+      true ->
+        with :ok <- validate_fields(Map.keys(set) ++ unset) do
+          case Enum.find(unset, &Map.has_key?(set, &1)) do
+            nil -> :ok
+            field -> {:error, "invalid patch: #{field} is both set and unset"}
+          end
+        end
+    end
+  end
+
+  defp validate_fields(fields) do
+    Enum.reduce_while(fields, :ok, fn field, :ok ->
+      case validate_field(field) do
+        :ok -> {:cont, :ok}
+        error -> {:halt, error}
+      end
+    end)
+  end
+
+  # Identity and scope cannot be rewritten in bulk.
+  defp validate_field(field) when is_binary(field) do
+    [root | _] = parts = String.split(field, ".")
+
+    cond do
+      Enum.any?(parts, &(String.trim(&1) == "")) ->
+        {:error, "invalid patch: empty path segment in #{inspect(field)}"}
+
+      root in @protected_fields ->
+        {:error, "field cannot be patched: #{field}"}
+
+      true ->
+        :ok
+    end
+  end
+
+  defp validate_field(field), do: {:error, "invalid patch: field #{inspect(field)}"}
+
+  # Parents sort before their children, so "a" is set before "a.b".
+  defp apply_patch(patch, config) do
+    set = Map.get(patch, :set) || %{}
+
+    result =
+      set
+      |> Enum.sort_by(fn {field, _value} -> field end)
+      |> Enum.reduce_while({:ok, config, []}, fn {field, value}, {:ok, acc, changed} ->
+        case set_path(acc, String.split(field, "."), value) do
+          {:ok, updated, ^value} -> {:cont, {:ok, updated, changed}}
+          {:ok, updated, _previous} -> {:cont, {:ok, updated, [field | changed]}}
+          :error -> {:halt, {:error, "invalid patch: #{field}: path crosses a non-object"}}
+        end
+      end)
+
+    with {:ok, updated, changed} <- result do
+      {updated, changed} =
+        (Map.get(patch, :unset) || [])
+        |> Enum.reduce({updated, changed}, fn field, {acc, changed} ->
+          case unset_path(acc, String.split(field, ".")) do
+            {:ok, acc} -> {acc, [field | changed]}
+            :unchanged -> {acc, changed}
+          end
+        end)
+
+      {:ok, updated, Enum.sort(changed)}
+    end
+  end
+
+  # Returns the previous value, or a unique reference when there was none.
+  defp set_path(map, [key], value) do
+    {:ok, Map.put(map, key, value), Map.get(map, key, make_ref())}
+  end
+
+  defp set_path(map, [key | rest], value) do
+    case Map.get(map, key) do
+      nil ->
+        {:ok, child, previous} = set_path(%{}, rest, value)
+        {:ok, Map.put(map, key, child), previous}
+
+      child when is_map(child) and not is_struct(child) ->
+        with {:ok, child, previous} <- set_path(child, rest, value) do
+          {:ok, Map.put(map, key, child), previous}
+        end
+
+      _other ->
+        :error
+    end
+  end
+
+  defp unset_path(map, [key]) do
+    if Map.has_key?(map, key), do: {:ok, Map.delete(map, key)}, else: :unchanged
+  end
+
+  defp unset_path(map, [key | rest]) do
+    case Map.get(map, key) do
+      child when is_map(child) and not is_struct(child) ->
+        with {:ok, child} <- unset_path(child, rest) do
+          {:ok, Map.put(map, key, child)}
+        end
+
+      _other ->
+        :unchanged
+    end
+  end
+
+  defp targets(request, opts) do
+    ids = Map.get(request, :gateway_ids) || []
+
+    listed =
+      cond do
+        Map.get(request, :all) == true and ids != [] ->
+          {:error, "invalid patch: gateway_ids and all are mutually exclusive"}
+
+        Map.get(request, :all) == true ->
+          Keyword.get(opts, :list_gateways, &list_gateway_ids/0).()
+
+        true ->
+          {:ok, ids}
+      end
+
+    with {:ok, ids} <- listed do
+      targets =
+        ids
+        |> Enum.filter(&is_binary/1)
+        |> Enum.map(&String.trim/1)
+        |> Enum.reject(&(&1 == ""))
+        |> Enum.uniq()
+        |> Enum.sort()
+
+      if targets == [], do: {:error, "no gateways selected"}, else: {:ok, targets}
+    end
+  end
+
+  defp list_gateway_ids do
+    actor = SystemActor.system(:gateway_config_push)
+
+    case Ash.read(Gateway, actor: actor) do
+      {:ok, gateways} -> {:ok, Enum.map(gateways, & &1.id)}
+      {:error, reason} -> {:error, "list gateways: #{inspect(reason)}"}
+    end
+  end
+
+  defp audit(context, result, opts) do
+    entry = %{
+      change_id: context.change_id,
+      actor: context.actor,
+      action: "gateway_config.bulk_push",
+      partition: context.partition,
+      reason: context.reason,
+      patch: context.patch,
+      gateways: result.results |> Enum.filter(&applied?/1) |> Enum.map(& &1.gateway_id),
+      failed: result.failed,
+      at: context.now
+    }
+
+    Keyword.get(opts, :audit, &log_audit/1).(entry)
+  end
+
+  defp log_audit(entry) do
+    Logger.info("Applied bulk gateway config change",
+      audit_action: entry.action,
+      change_id: entry.change_id,
+      actor: entry.actor,
+      partition: entry.partition,
+      reason: entry.reason,
+      patch: Jason.encode!(entry.patch),
+      gateways: Enum.join(entry.gateways, ","),
+      failed: entry.failed
+    )
+  end
+
+  defp applied?(result), do: is_nil(result.error) and is_nil(result.skipped)
+
+  defp history_key(gateway_id, name), do: @history_prefix <> gateway_id <> "/" <> name
+
+  defp blank_to_nil(value) when is_binary(value) do
+    case String.trim(value) do
+      "" -> nil
+      trimmed -> trimmed
+    end
+  end
+
+  defp blank_to_nil(_value), do: nil
+end
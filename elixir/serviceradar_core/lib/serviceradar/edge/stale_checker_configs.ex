@@ -0,0 +1,164 @@
+defmodule ServiceRadar.Edge.StaleCheckerConfigs do
+  @moduledoc """
+  Finds and retires checker configs left in datasvc KV by agents that are gone.
+
+  Checker configs live under `agents/<agent_id>/checkers/`. When an agent is
+  decommissioned nothing removes them. An agent's keys are stale when the agent
+  is no longer registered, or when it has not reported within the window
+  (`:stale_after_seconds`, default 7 days).
+
+  `retire/1` previews by default. Pass `dry_run: false` to delete the stale
+  keys with `ServiceRadar.DataService.Client.delete_prefix/2`.
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.DataService.Client
+  alias ServiceRadar.Infrastructure.Agent
+
+  require Ash.Query
+  require Logger
+
+  @agents_prefix "agents/"
+  @checkers_segment "checkers"
+  @default_stale_after_seconds 7 * 24 * 60 * 60
+
+  @type stale_agent :: %{
+          agent_id: String.t(),
+          last_seen: DateTime.t() | nil,
+          keys: [String.t()]
+        }
+
+  @type result :: %{
+          dry_run: boolean(),
+          cutoff: DateTime.t(),
+          agents: [stale_agent()],
+          key_count: non_neg_integer(),
+          deleted_keys: [String.t()]
+        }
+
+  @doc """
+  Lists stale checker configs and, unless `dry_run` is true, deletes them.
+
+  Options:
+    - `:dry_run` - only report what would be deleted (default: true)
+    - `:stale_after_seconds` - how long an agent may be silent (default: 7 days)
+    - `:now` - reference time (default: `DateTime.utc_now/0`)
+    - `:kv` - KV client module (default: `ServiceRadar.DataService.Client`)
+    - `:last_seen` - `fn agent_ids -> {:ok, %{agent_id => DateTime.t() | nil}}`
+      (default: reads `ServiceRadar.Infrastructure.Agent`)
+  """
+  @spec retire(keyword()) :: {:ok, result()} | {:error, term()}
+  def retire(opts \\ []) do
+    dry_run = Keyword.get(opts, :dry_run, true)
+    kv = Keyword.get(opts, :kv, Client)
+    cutoff = cutoff(opts)
+
+    with {:ok, keys} <- kv.list_keys(@agents_prefix, []),
+         keys_by_agent = group_checker_keys(keys),
+         {:ok, last_seen} <- fetch_last_seen(opts, Map.keys(keys_by_agent)),
+         agents = stale_agents(keys_by_agent, last_seen, cutoff),
+         {:ok, deleted} <- maybe_delete(kv, agents, dry_run) do
+      {:ok,
+       %{
+         dry_run: dry_run,
+         cutoff: cutoff,
+         agents: agents,
+         key_count: agents |> Enum.map(&length(&1.keys)) |> Enum.sum(),
+         deleted_keys: deleted
+       }}
+    end
+  end
+
+  @doc """
+  Groups `agents/<agent_id>/checkers/...` keys by agent id, ignoring other keys.
+  """
+  @spec group_checker_keys([String.t()]) :: %{String.t() => [String.t()]}
+  def group_checker_keys(keys) do
+    keys
+    |> Enum.flat_map(fn key ->
+      case String.split(key, "/", parts: 4) do
+        ["agents", agent_id, @checkers_segment, _rest] when agent_id != "" -> [{agent_id, key}]
+        _ -> []
+      end
+    end)
+    |> Enum.group_by(&elem(&1, 0), &elem(&1, 1))
+    |> Map.new(fn {agent_id, agent_keys} -> {agent_id, Enum.sort(agent_keys)} end)
+  end
+
+  @doc """
+  Returns the agents whose keys are stale: unknown agents, agents that never
+  reported and agents last seen before `cutoff`. Sorted by agent id.
+  """
+  @spec stale_agents(
+          %{String.t() => [String.t()]},
+          %{String.t() => DateTime.t() | nil},
+          DateTime.t()
+        ) :: [stale_agent()]
+  def stale_agents(keys_by_agent, last_seen, cutoff) do
+    keys_by_agent
+    |> Enum.flat_map(fn {agent_id, keys} ->
+      seen = Map.get(last_seen, agent_id)
+
+      if stale?(seen, cutoff) do
+        [%{agent_id: agent_id, last_seen: seen, keys: keys}]
+      else
+        []
+      end
+    end)
+    |> Enum.sort_by(& &1.agent_id)
+  end
+
+  defp stale?(nil, _cutoff), do: true
+  defp stale?(%DateTime{} = seen, cutoff), do: DateTime.compare(seen, cutoff) == :lt
+
+  defp cutoff(opts) do
+    now = Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+    stale_after = Keyword.get(opts, :stale_after_seconds, @default_stale_after_seconds)
+
+    DateTime.add(now, -stale_after, :second)
+  end
+
+  defp fetch_last_seen(_opts, []), do: {:ok, %{}}
+
+  defp fetch_last_seen(opts, agent_ids) do
+    case Keyword.get(opts, :last_seen) do
+      fun when is_function(fun, 1) -> fun.(agent_ids)
+      nil -> load_last_seen(agent_ids)
+    end
+  end
+
+  defp load_last_seen(agent_ids) do
+    actor = SystemActor.system(:stale_checker_configs)
+
+    Agent
+    |> Ash.Query.for_read(:read, %{}, actor: actor)
+    |> Ash.Query.filter(uid in ^agent_ids)
+    |> Ash.Query.select([:uid, :last_seen_time])
+    |> Ash.read(actor: actor)
+    |> case do
+      {:ok, agents} -> {:ok, Map.new(agents, &{&1.uid, &1.last_seen_time})}
+      {:error, _reason} = error -> error
+    end
+  end
+
+  defp maybe_delete(_kv, _agents, true), do: {:ok, []}
+
+  defp maybe_delete(kv, agents, false) do
+    Enum.reduce_while(agents, {:ok, []}, fn %{agent_id: agent_id}, {:ok, deleted} ->
+      prefix = @agents_prefix <> agent_id <> "/" <> @checkers_segment <> "/"
+
+      case kv.delete_prefix(prefix, []) do
+        {:ok, keys} ->
+          Logger.info("Retired #{length(keys)} stale checker config(s) for agent #{agent_id}")
+          {:cont, {:ok, deleted ++ keys}}
+
+        {:error, reason} = error ->
+          Logger.warning(
+            "Failed to retire checker configs for agent #{agent_id}: #{inspect(reason)}"
+          )
+
+          {:halt, error}
+      end
+    end)
+  end
+end
@@ -0,0 +1,385 @@
+defmodule ServiceRadar.EventWriter.ConfigEnrichment do
+  @moduledoc """
+  Attaches the configured ("desired") state from the KV store to OCSF events
+  as they are written, so an event records what was expected alongside what
+  happened.
+
+  Each rule selects events by field values and names the KV keys to read for
+  them. The values are stored on the event under `unmapped["desired_state"]`.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.EventWriter.ConfigEnrichment,
+        cache_ttl_ms: 60_000,
+        rules: [
+          %{
+            match: %{"log_provider" => "sweep"},
+            keys: [
+              %{
+                key: "agents/{{unmapped.agent_id}}/checkers/sweep/sweep.json",
+                fields: ["interval", "timeout"],
+                as: "sweep"
+              }
+            ]
+          },
+          %{match: %{"unmapped.action" => "sync*"}, keys: ["config/sync.json"]}
+        ]
+
+  Match paths name an event column (`log_name`, `log_provider`, `class_uid`,
+  `type_uid`, ...) or a dotted path into one of its JSON maps
+  (`unmapped.action`, `metadata.product.name`). Every condition must hold; a
+  string ending in `*` matches by prefix. KV keys may reference the same
+  paths as `{{path}}` placeholders, and a key whose placeholders do not
+  resolve is skipped for that event.
+
+  JSON values are decoded and, when `fields` is given, reduced to those top
+  level fields; other values are attached as strings. Values are cached in
+  ETS for `cache_ttl_ms`, and each key is read at most once per batch, so
+  enrichment does not cost a KV round trip per event. Failed reads are cached
+  briefly and leave the event unenriched.
+  """
+
+  use GenServer
+
+  alias ServiceRadar.DataService.Client, as: DataServiceClient
+
+  require Logger
+
+  @table :event_config_enrichment
+  @config_key :__config__
+  @default_cache_ttl_ms 60_000
+  @error_ttl_ms 5_000
+  @desired_state_key "desired_state"
+  @placeholder ~r/\{\{\s*([A-Za-z0-9_.]+)\s*\}\}/
+
+  @columns ~w(log_name log_provider log_level log_version class_uid category_uid type_uid
+              activity_id activity_name severity_id severity status_id status status_code
+              message)a
+
+  @maps ~w(unmapped metadata actor device src_endpoint dst_endpoint)a
+
+  @column_names Map.new(@columns, &{Atom.to_string(&1), &1})
+  @map_names Map.new(@maps, &{Atom.to_string(&1), &1})
+
+  @type key_spec :: %{key: String.t(), fields: [String.t()] | nil, as: String.t() | nil}
+  @type rule :: %{match: [{[String.t()], term()}], keys: [key_spec()]}
+
+  ## Client API
+
+  def start_link(opts \\ []) do
+    GenServer.start_link(__MODULE__, opts, name: Keyword.get(opts, :name, __MODULE__))
+  end
+
+  @doc """
+  Validates rule definitions, returning the compiled rules and the rejected
+  ones with the reason each was rejected.
+  """
+  @spec compile_rules([map()]) :: {[rule()], [{term(), String.t()}]}
+  def compile_rules(definitions) when is_list(definitions) do
+    {valid, invalid} =
+      Enum.reduce(definitions, {[], []}, fn definition, {valid, invalid} ->
+        case compile_rule(definition) do
+          {:ok, rule} -> {[rule | valid], invalid}
+          {:error, reason} -> {valid, [{definition, reason} | invalid]}
+        end
+      end)
+
+    {Enum.reverse(valid), Enum.reverse(invalid)}
+  end
+
+  def compile_rules(_definitions), do: {[], [{nil, "rules must be a list"}]}
+
+  @doc """
+  Enriches a batch of event rows.
+
+  Returns the rows unchanged when the enrichment process is not running or no
+  rules are configured.
+  """
+  @spec enrich([map()], keyword()) :: [map()]
+  def enrich(rows, opts \\ []) when is_list(rows) do
+    table = Keyword.get(opts, :table, @table)
+
+    case stored_config(table) do
+      %{rules: [_ | _]} = config when rows != [] -> enrich_rows(rows, table, config)
+      _ -> rows
+    end
+  end
+
+  @doc """
+  Returns the desired state for a single event row given the compiled rules
+  and a KV lookup function, without caching.
+  """
+  @spec desired_state(map(), [rule()], (String.t() -> {:ok, term()} | :error)) :: map()
+  def desired_state(row, rules, lookup) when is_function(lookup, 1) do
+    rules
+    |> Enum.filter(&matches?(&1, row))
+    |> Enum.flat_map(& &1.keys)
+    |> Enum.reduce(%{}, fn spec, acc ->
+      with {:ok, key} <- render_key(spec.key, row),
+           {:ok, value} <- lookup.(key) do
+        Map.put(acc, spec.as || key, select_fields(value, spec.fields))
+      else
+        _ -> acc
+      end
+    end)
+  end
+
+  ## Server Callbacks
+
+  @impl true
+  def init(opts) do
+    table = Keyword.get(opts, :table, @table)
+    :ets.new(table, [:named_table, :public, :set, read_concurrency: true])
+
+    app_config = Application.get_env(:serviceradar_core, __MODULE__, [])
+    definitions = Keyword.get_lazy(opts, :rules, fn -> Keyword.get(app_config, :rules, []) end)
+
+    {rules, invalid} = compile_rules(definitions)
+
+    Enum.each(invalid, fn {definition, reason} ->
+      Logger.error("Rejected event config enrichment rule #{inspect(definition)}: #{reason}")
+    end)
+
+    default_ttl = Keyword.get(app_config, :cache_ttl_ms, @default_cache_ttl_ms)
+
+    config = %{
+      rules: rules,
+      ttl_ms: Keyword.get(opts, :cache_ttl_ms, default_ttl),
+      fetch: Keyword.get(opts, :fetch, &DataServiceClient.get/1)
+    }
+
+    :ets.insert(table, {@config_key, config})
+
+    if rules != [] do
+      Logger.info("Loaded #{length(rules)} event config enrichment rule(s)")
+    end
+
+    {:ok, %{table: table}}
+  end
+
+  ## Private Functions
+
+  defp compile_rule(definition) when is_map(definition) do
+    with {:ok, match} <- compile_match(fetch(definition, :match)),
+         {:ok, keys} <- compile_keys(fetch(definition, :keys)) do
+      {:ok, %{match: match, keys: keys}}
+    end
+  end
+
+  defp compile_rule(_definition), do: {:error, "rule must be a map"}
+
+  defp compile_match(match) when is_map(match) and map_size(match) > 0 do
+    Enum.reduce_while(match, {:ok, []}, fn {path, expected}, {:ok, acc} ->
+      case parse_path(path) do
+        {:ok, segments} -> {:cont, {:ok, [{segments, expected} | acc]}}
+        :error -> {:halt, {:error, "invalid match path #{inspect(path)}"}}
+      end
+    end)
+  end
+
+  defp compile_match(_match), do: {:error, "match must be a non-empty map"}
+
+  defp compile_keys(keys) when is_list(keys) and keys != [] do
+    Enum.reduce_while(keys, {:ok, []}, fn key, {:ok, acc} ->
+      case compile_key(key) do
+        {:ok, spec} -> {:cont, {:ok, acc ++ [spec]}}
+        {:error, _} = error -> {:halt, error}
+      end
+    end)
+  end
+
+  defp compile_keys(_keys), do: {:error, "keys must be a non-empty list"}
+
+  defp compile_key(key) when is_binary(key), do: compile_key(%{key: key})
+
+  defp compile_key(spec) when is_map(spec) do
+    key = fetch(spec, :key)
+    fields = fetch(spec, :fields)
+    as = fetch(spec, :as)
+
+    cond do
+      not is_binary(key) or key == "" ->
+        {:error, "key must be a non-empty string"}
+
+      not placeholders_valid?(key) ->
+        {:error, "key #{inspect(key)} references an unknown field"}
+
+      not (is_nil(fields) or (is_list(fields) and Enum.all?(fields, &is_binary/1))) ->
+        {:error, "fields must be a list of strings"}
+
+      not (is_nil(as) or is_binary(as)) ->
+        {:error, "as must be a string"}
+
+      true ->
+        {:ok, %{key: key, fields: fields, as: as}}
+    end
+  end
+
+  defp compile_key(_spec), do: {:error, "key must be a string or a map"}
+
+  defp placeholders_valid?(key) do
+    @placeholder
+    |> Regex.scan(key, capture: :all_but_first)
+    |> Enum.all?(fn [path] -> parse_path(path) != :error end)
+  end
+
+  defp parse_path(path) when is_atom(path) and not is_nil(path),
+    do: parse_path(Atom.to_string(path))
+
+  defp parse_path(path) when is_binary(path) do
+    case String.split(path, ".") do
+      [column] when is_map_key(@column_names, column) ->
+        {:ok, [column]}
+
+      [map | [_ | _] = rest] when is_map_key(@map_names, map) ->
+        if Enum.all?(rest, &(&1 != "")), do: {:ok, [map | rest]}, else: :error
+
+      _ ->
+        :error
+    end
+  end
+
+  defp parse_path(_path), do: :error
+
+  defp fetch(map, key), do: Map.get(map, key, Map.get(map, Atom.to_string(key)))
+
+  defp stored_config(table) do
+    case :ets.whereis(table) do
+      :undefined ->
+        nil
+
+      _ref ->
+        case :ets.lookup(table, @config_key) do
+          [{@config_key, config}] -> config
+          [] -> nil
+        end
+    end
+  end
+
+  defp enrich_rows(rows, table, config) do
+    now = System.monotonic_time(:millisecond)
+
+    values =
+      rows
+      |> Enum.flat_map(&wanted_keys(&1, config.rules))
+      |> Enum.uniq()
+      |> Map.new(&{&1, cached_lookup(table, &1, config, now)})
+
+    Enum.map(rows, fn row ->
+      case desired_state(row, config.rules, &Map.fetch!(values, &1)) do
+        state when map_size(state) == 0 -> row
+        state -> put_desired_state(row, state)
+      end
+    end)
+  end
+
+  defp wanted_keys(row, rules) do
+    for rule <- rules,
+        matches?(rule, row),
+        spec <- rule.keys,
+        {:ok, key} <- [render_key(spec.key, row)],
+        do: key
+  end
+
+  defp cached_lookup(table, key, config, now) do
+    case :ets.lookup(table, {:kv, key}) do
+      [{_, result, expires_at}] when expires_at > now ->
+        result
+
+      _ ->
+        {result, ttl} = read_kv(key, config)
+        :ets.insert(table, {{:kv, key}, result, now + ttl})
+        result
+    end
+  end
+
+  defp read_kv(key, config) do
+    case config.fetch.(key) do
+      {:ok, raw} when is_binary(raw) ->
+        {{:ok, decode(raw)}, config.ttl_ms}
+
+      {:error, :not_found} ->
+        {:error, config.ttl_ms}
+
+      {:error, reason} ->
+        Logger.debug("Event config enrichment could not read #{key}: #{inspect(reason)}")
+        {:error, @error_ttl_ms}
+    end
+  end
+
+  defp decode(raw) do
+    case Jason.decode(raw) do
+      {:ok, value} -> value
+      {:error, _} -> raw
+    end
+  end
+
+  defp matches?(%{match: match}, row) do
+    Enum.all?(match, fn {path, expected} -> value_matches?(resolve(row, path), expected) end)
+  end
+
+  defp value_matches?(nil, _expected), do: false
+
+  defp value_matches?(value, expected) when is_binary(value) and is_binary(expected) do
+    case String.split_at(expected, -1) do
+      {prefix, "*"} -> String.starts_with?(value, prefix)
+      _ -> value == expected
+    end
+  end
+
+  defp value_matches?(value, expected) when is_number(value) and is_binary(expected),
+    do: to_string(value) == expected
+
+  defp value_matches?(value, expected), do: value == expected
+
+  defp render_key(template, row) do
+    @placeholder
+    |> Regex.scan(template)
+    |> Enum.reduce_while({:ok, template}, fn [placeholder, path], {:ok, key} ->
+      case placeholder_value(row, path) do
+        nil -> {:halt, :error}
+        value -> {:cont, {:ok, String.replace(key, placeholder, value)}}
+      end
+    end)
+  end
+
+  defp placeholder_value(row, path) do
+    {:ok, segments} = parse_path(path)
+
+    case resolve(row, segments) do
+      value when is_binary(value) and value != "" -> value
+      value when is_integer(value) -> Integer.to_string(value)
+      _ -> nil
+    end
+  end
+
+  defp resolve(row, [column]), do: Map.get(row, Map.fetch!(@column_names, column))
+
+  defp resolve(row, [map | rest]),
+    do: get_in_json(Map.get(row, Map.fetch!(@map_names, map)), rest)
+
+  defp get_in_json(value, []), do: value
+  defp get_in_json(map, [key | rest]) when is_map(map), do: get_in_json(Map.get(map, key), rest)
+  defp get_in_json(_value, _path), do: nil
+
+  defp select_fields(value, fields) when is_map(value) and is_list(fields),
+    do: Map.take(value, fields)
+
+  defp select_fields(value, _fields), do: value
+
+  defp put_desired_state(row, state) do
+    unmapped =
+      case Map.get(row, :unmapped) do
+        map when is_map(map) -> map
+        _ -> %{}
+      end
+
+    existing =
+      case Map.get(unmapped, @desired_state_key) do
+        map when is_map(map) -> map
+        _ -> %{}
+      end
+
+    Map.put(row, :unmapped, Map.put(unmapped, @desired_state_key, Map.merge(existing, state)))
+  end
+end
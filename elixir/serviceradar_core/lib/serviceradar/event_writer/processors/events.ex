@@ -4,11 +4,14 @@ defmodule ServiceRadar.EventWriter.Processors.Events do
 
   Parses JSON events from NATS and inserts them into the `ocsf_events`
   hypertable using the OCSF Event Log Activity schema (class_uid: 1008).
+  Configured events are enriched with their desired state from KV first
+  (see `ServiceRadar.EventWriter.ConfigEnrichment`).
   """
 
   @behaviour ServiceRadar.EventWriter.Processor
 
   alias ServiceRadar.Events.PubSub, as: EventsPubSub
+  alias ServiceRadar.EventWriter.ConfigEnrichment
   alias ServiceRadar.EventWriter.FieldParser
   alias ServiceRadar.Observability.StatefulAlertEngine
 
@@ -52,6 +55,7 @@ defmodule ServiceRadar.EventWriter.Processors.Events do
     messages
     |> Enum.map(&parse_message/1)
     |> Enum.reject(&is_nil/1)
+    |> ConfigEnrichment.enrich()
   end
 
   defp insert_event_rows(rows) do
@@ -49,6 +49,7 @@ defmodule ServiceRadar.EventWriter.Processors.Sweep do
   alias ServiceRadar.EventWriter.FieldParser
   alias ServiceRadar.EventWriter.OCSF
   alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceHealth
   alias ServiceRadar.Repo
   alias ServiceRadar.SweepJobs.SweepResultsIngestor
 
@@ -178,9 +179,25 @@ defmodule ServiceRadar.EventWriter.Processors.Sweep do
   end
 
   defp update_availability(results, device_map, timestamp, actor) do
-    restore_deleted_devices(device_uids_from_results(results, device_map), actor)
+    device_uids = device_uids_from_results(results, device_map)
+
+    restore_deleted_devices(device_uids, actor)
     update_available_devices(results, device_map, timestamp)
     update_unavailable_devices(results, device_map, timestamp)
+    refresh_health_rollups(device_uids)
+  end
+
+  # Parents roll up the availability just written for their children.
+  defp refresh_health_rollups(device_uids) do
+    if device_uids != [] and DeviceHealth.enabled?() do
+      case DeviceHealth.devices_changed(device_uids) do
+        {:ok, _rollups} ->
+          :ok
+
+        {:error, reason} ->
+          Logger.warning("SweepProcessor: device health rollup failed: #{inspect(reason)}")
+      end
+    end
   end
 
   defp device_uids_from_results(results, device_map) do
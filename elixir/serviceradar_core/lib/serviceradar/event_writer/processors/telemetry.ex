@@ -52,6 +52,7 @@ defmodule ServiceRadar.EventWriter.Processors.Telemetry do
   @behaviour ServiceRadar.EventWriter.Processor
 
   alias ServiceRadar.EventWriter.FieldParser
+  alias ServiceRadar.Observability.MetricDerivation
   alias ServiceRadar.Observability.TimeseriesSeriesKey
 
   require Logger
@@ -93,6 +94,7 @@ defmodule ServiceRadar.EventWriter.Processors.Telemetry do
     messages
     |> Enum.map(&parse_message/1)
     |> Enum.reject(&is_nil/1)
+    |> MetricDerivation.append_derived()
   end
 
   defp insert_telemetry_rows(rows) do
@@ -12,7 +12,8 @@ defmodule ServiceRadar.EventWriter.Supervisor do
 
   ## Children
 
-  1. `EventWriter.Broadway` - The main Broadway pipeline for message processing
+  1. `EventWriter.ConfigEnrichment` - KV desired-state rules and their read cache
+  2. `EventWriter.Broadway` - The main Broadway pipeline for message processing
   """
 
   use Supervisor
@@ -38,6 +39,7 @@ defmodule ServiceRadar.EventWriter.Supervisor do
     )
 
     children = [
+      ServiceRadar.EventWriter.ConfigEnrichment,
       {ServiceRadar.EventWriter.Pipeline, config}
     ]
 
@@ -0,0 +1,162 @@
+defmodule ServiceRadar.Events.Stream do
+  @moduledoc """
+  Filtering, resumption and encoding for streaming OCSF events as
+  server-sent events.
+
+  Live events come from `ServiceRadar.Events.PubSub`, the same broadcast the
+  event LiveViews subscribe to. Each event is sent with an SSE `id` of
+  `<time in microseconds>:<event id>`; a client that reconnects with that id
+  as `Last-Event-ID` is first sent the stored events recorded after it
+  (`backlog/3`), read with the client's actor so event read policies apply.
+
+  Filters:
+    - `:log_names` - only events with one of these log names (default all)
+    - `:partition` - only events of this partition: named in the event
+      metadata (`partition_id` or `partition`), or concerning a device with an
+      identifier in the partition
+  """
+
+  alias ServiceRadar.Monitoring.EventReplay
+  alias ServiceRadar.Repo
+
+  @event_name "ocsf_event"
+  @backlog_limit 500
+
+  @device_partition_sql """
+  SELECT 1 FROM platform.device_identifiers
+  WHERE device_id = $1 AND partition = $2
+  LIMIT 1
+  """
+
+  @type filter :: %{log_names: [String.t()], partition: String.t() | nil}
+
+  @doc "Builds a filter from its options; blank values match all events."
+  @spec filter(keyword()) :: filter()
+  def filter(opts) do
+    %{
+      log_names:
+        opts
+        |> Keyword.get(:log_names, [])
+        |> List.wrap()
+        |> Enum.map(&String.trim/1)
+        |> Enum.reject(&(&1 == "")),
+      partition:
+        case Keyword.get(opts, :partition) do
+          value when is_binary(value) -> if String.trim(value) == "", do: nil, else: value
+          _ -> nil
+        end
+    }
+  end
+
+  @doc "Whether a broadcast event passes `filter`."
+  @spec matches?(map(), filter()) :: boolean()
+  def matches?(event, filter) do
+    log_name_matches?(event, filter.log_names) and partition_matches?(event, filter.partition)
+  end
+
+  @doc """
+  The stored events after `last_event_id` that pass `filter`, oldest first.
+  An empty or unparsable id yields no backlog.
+  """
+  @spec backlog(String.t() | nil, filter(), term()) :: {:ok, [map()]} | {:error, term()}
+  def backlog(last_event_id, filter, actor) do
+    case parse_event_id(last_event_id) do
+      {:ok, {time, id}} ->
+        %{
+          from: time,
+          to: DateTime.add(DateTime.utc_now(), 1, :second),
+          log_names: filter.log_names,
+          partition: filter.partition,
+          limit: @backlog_limit
+        }
+        |> EventReplay.select(actor: actor)
+        |> case do
+          {:ok, events} ->
+            {:ok, Enum.reject(events, &(event_id(&1) == id))}
+
+          {:error, reason} ->
+            {:error, reason}
+        end
+
+      :error ->
+        {:ok, []}
+    end
+  end
+
+  @doc "Parses an SSE event id of `event_id/1`."
+  @spec parse_event_id(String.t() | nil) :: {:ok, {DateTime.t(), String.t()}} | :error
+  def parse_event_id(value) when is_binary(value) do
+    with [micros, id] <- String.split(String.trim(value), ":", parts: 2),
+         {micros, ""} <- Integer.parse(micros),
+         {:ok, time} <- DateTime.from_unix(micros, :microsecond) do
+      {:ok, {time, id}}
+    else
+      _ -> :error
+    end
+  end
+
+  def parse_event_id(_value), do: :error
+
+  @doc "Encodes an event as an SSE frame."
+  @spec encode(map()) :: iodata()
+  def encode(event) do
+    data =
+      event
+      |> Map.take([
+        :id,
+        :time,
+        :class_uid,
+        :type_uid,
+        :activity_name,
+        :severity_id,
+        :severity,
+        :message,
+        :status,
+        :log_name,
+        :log_provider,
+        :device,
+        :metadata
+      ])
+      |> Map.update(:id, nil, &event_id/1)
+      |> Jason.encode!()
+
+    ["id: ", sse_id(event), "\nevent: ", @event_name, "\ndata: ", data, "\n\n"]
+  end
+
+  @doc "SSE comment that keeps idle connections open."
+  @spec keepalive() :: iodata()
+  def keepalive, do: ": keepalive\n\n"
+
+  defp sse_id(event) do
+    case Map.get(event, :time) do
+      %DateTime{} = time -> "#{DateTime.to_unix(time, :microsecond)}:#{event_id(event)}"
+      _ -> "0:#{event_id(event)}"
+    end
+  end
+
+  defp event_id(%{id: id}), do: event_id(id)
+  defp event_id(<<_::128>> = id), do: Ecto.UUID.load!(id)
+  defp event_id(nil), do: ""
+  defp event_id(id) when is_binary(id), do: id
+  defp event_id(id), do: to_string(id)
+
+  defp log_name_matches?(_event, []), do: true
+  defp log_name_matches?(event, log_names), do: Map.get(event, :log_name) in log_names
+
+  defp partition_matches?(_event, nil), do: true
+
+  defp partition_matches?(event, partition) do
+    metadata = Map.get(event, :metadata) || %{}
+
+    case Map.get(metadata, "partition_id") || Map.get(metadata, "partition") do
+      nil -> device_in_partition?(Map.get(event, :device), partition)
+      value -> value == partition
+    end
+  end
+
+  defp device_in_partition?(%{"uid" => uid}, partition) when is_binary(uid) do
+    match?({:ok, %{num_rows: 1}}, Repo.query(@device_partition_sql, [uid, partition]))
+  end
+
+  defp device_in_partition?(_device, _partition), do: false
+end
@@ -18,6 +18,8 @@ defmodule ServiceRadar.Identity.ApiToken do
   - Original token is only shown once at creation time
   - Tokens can be revoked at any time
   - Optional expiration dates are supported
+  - Optional inactivity expiry: a token unused for longer than
+    `inactivity_timeout_seconds` is disabled until it is re-enabled
   """
 
   use Ash.Resource,
@@ -27,8 +29,16 @@ defmodule ServiceRadar.Identity.ApiToken do
 
   alias ServiceRadar.Identity.AccessCredentialChanges
 
-  @token_create_fields [:name, :description, :scope, :expires_at, :metadata, :user_id]
-  @token_update_fields [:name, :description, :expires_at, :metadata]
+  @token_create_fields [
+    :name,
+    :description,
+    :scope,
+    :expires_at,
+    :inactivity_timeout_seconds,
+    :metadata,
+    :user_id
+  ]
+  @token_update_fields [:name, :description, :expires_at, :inactivity_timeout_seconds, :metadata]
   @token_usage_fields [:last_used_ip]
   @token_self_manage_actions [:update, :record_use, :revoke, :disable, :enable]
 
@@ -125,9 +135,19 @@ defmodule ServiceRadar.Identity.ApiToken do
       change set_attribute(:enabled, false)
     end
 
+    update :disable_inactive do
+      description "Disable this token after it went unused past its inactivity timeout"
+      change set_attribute(:enabled, false)
+      change set_attribute(:disabled_reason, "inactivity")
+    end
+
     update :enable do
       description "Enable this token"
       change set_attribute(:enabled, true)
+      change set_attribute(:disabled_reason, nil)
+      # Restart the inactivity window so a reactivated token is not
+      # immediately disabled again.
+      change atomic_update(:last_used_at, expr(now()))
     end
   end
 
@@ -221,6 +241,17 @@ defmodule ServiceRadar.Identity.ApiToken do
       description "Number of times token has been used"
     end
 
+    attribute :inactivity_timeout_seconds, :integer do
+      public? true
+      constraints min: 0
+      description "Disable the token if unused for this many seconds (nil or 0 = never)"
+    end
+
+    attribute :disabled_reason, :string do
+      public? true
+      description "Why the token was disabled (e.g. inactivity)"
+    end
+
     attribute :revoked_at, :utc_datetime do
       public? true
       description "When token was revoked"
@@ -289,6 +320,7 @@ defmodule ServiceRadar.Identity.ApiToken do
                 cond do
                   not is_nil(revoked_at) -> "revoked"
                   not is_nil(expires_at) and expires_at <= now() -> "expired"
+                  enabled == false and disabled_reason == "inactivity" -> "inactive"
                   enabled == false -> "disabled"
                   true -> "active"
                 end
@@ -300,9 +332,33 @@ defmodule ServiceRadar.Identity.ApiToken do
                 cond do
                   not is_nil(revoked_at) -> "red"
                   not is_nil(expires_at) and expires_at <= now() -> "orange"
+                  enabled == false and disabled_reason == "inactivity" -> "orange"
                   enabled == false -> "gray"
                   true -> "green"
                 end
               )
   end
+
+  @doc """
+  Returns true when the token has an inactivity timeout and has gone unused
+  for at least that long, counting from creation if it was never used.
+  """
+  def inactive?(token, now \\ DateTime.utc_now())
+
+  def inactive?(%{inactivity_timeout_seconds: timeout} = token, now)
+      when is_integer(timeout) and timeout > 0 do
+    case last_activity(token) do
+      nil -> false
+      last -> DateTime.diff(now, last, :second) >= timeout
+    end
+  end
+
+  def inactive?(_token, _now), do: false
+
+  defp last_activity(%{last_used_at: nil, created_at: created_at}), do: created_at
+  defp last_activity(%{last_used_at: last_used_at, created_at: nil}), do: last_used_at
+
+  defp last_activity(%{last_used_at: last_used_at, created_at: created_at}) do
+    if DateTime.after?(last_used_at, created_at), do: last_used_at, else: created_at
+  end
 end
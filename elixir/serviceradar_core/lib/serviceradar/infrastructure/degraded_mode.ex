@@ -0,0 +1,318 @@
+defmodule ServiceRadar.Infrastructure.DegradedMode do
+  @moduledoc """
+  Keeps the core accepting status reports and serving last-known state while
+  CNPG is unreachable.
+
+  Without degraded mode a database outage fails every agent status report
+  and every read. With it enabled:
+
+    * a report whose write fails because the database is unreachable is
+      buffered and accepted. Once degraded, later reports go straight to the
+      buffer. Only the latest report per key is kept, so the buffer holds
+      at most one entry per agent.
+    * reads fall back to the last value read successfully for the same key.
+      `state/1` tells callers the data is stale and since when.
+    * every `recover_interval_ms` the buffered reports are flushed, at most
+      `flush_batch_size` per pass. Degraded mode ends once the buffer has
+      drained and the database answers again.
+
+  The buffer lives in memory: reports still buffered when the node stops
+  are lost. Agents report again on their next interval.
+
+  Degraded mode is opt-in:
+
+      config :serviceradar_core, ServiceRadar.Infrastructure.DegradedMode,
+        enabled: true,
+        recover_interval_ms: 5_000,
+        flush_batch_size: 500,
+        max_buffered: 50_000
+  """
+
+  use GenServer
+
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @default_recover_interval_ms 5_000
+  @default_flush_batch_size 500
+  @default_max_buffered 50_000
+
+  @unavailable_postgres_codes [
+    :admin_shutdown,
+    :cannot_connect_now,
+    :crash_shutdown,
+    :too_many_connections
+  ]
+
+  @type state_info :: %{
+          degraded: boolean(),
+          since: DateTime.t() | nil,
+          buffered: non_neg_integer()
+        }
+
+  @doc "Whether degraded mode is enabled in the application config."
+  @spec enabled?() :: boolean()
+  def enabled?, do: Keyword.get(config(), :enabled, false) == true
+
+  @doc """
+  Starts degraded mode. Options override the application config:
+
+    * `:recover_interval_ms` - how often buffered reports are flushed
+    * `:flush_batch_size` - buffered reports written per flush pass
+    * `:max_buffered` - reports buffered before new ones are refused
+    * `:probe` - `fn -> {:ok, _} | {:error, _} end` checking the database is
+      back once the buffer has drained (default `SELECT 1`)
+    * `:name` - registered name (default `#{inspect(__MODULE__)}`)
+  """
+  def start_link(opts \\ []) do
+    GenServer.start_link(__MODULE__, opts, name: Keyword.get(opts, :name, __MODULE__))
+  end
+
+  @doc """
+  Writes a report with `write`. When the database is unreachable the report
+  is buffered under `key`, replacing any report buffered for that key, and
+  `:ok` is returned. Other results are returned as they are. Without a
+  running server `write` is simply called.
+  """
+  @spec report(term(), (-> term()), GenServer.server()) :: term()
+  def report(key, write, server \\ __MODULE__) when is_function(write, 0) do
+    if running?(server) and degraded?(server) do
+      buffer(server, key, write, nil)
+    else
+      case write.() do
+        {:error, reason} = error ->
+          if running?(server) and unavailable?(reason),
+            do: buffer(server, key, write, reason),
+            else: error
+
+        result ->
+          result
+      end
+    end
+  end
+
+  @doc """
+  Reads with `read`, which returns `{:ok, value}` or `{:error, reason}`.
+  Successful reads are remembered under `key`. While degraded, or when the
+  read fails because the database is unreachable, the remembered value is
+  returned instead; check `state/1` to flag it as stale. Without a
+  remembered value the read is attempted anyway.
+  """
+  @spec read(term(), (-> {:ok, term()} | {:error, term()}), GenServer.server()) ::
+          {:ok, term()} | {:error, term()}
+  def read(key, read, server \\ __MODULE__) when is_function(read, 0) do
+    cached = if running?(server), do: cached(server, key), else: :error
+
+    case {degraded?(server), cached} do
+      {true, {:ok, value}} -> {:ok, value}
+      _ -> read_through(server, key, read, cached)
+    end
+  end
+
+  @doc "Whether reads are currently served from last-known state."
+  @spec degraded?(GenServer.server()) :: boolean()
+  def degraded?(server \\ __MODULE__), do: state(server).degraded
+
+  @doc "Whether degraded mode is in effect, since when, and the backlog."
+  @spec state(GenServer.server()) :: state_info()
+  def state(server \\ __MODULE__) do
+    if running?(server),
+      do: GenServer.call(server, :state),
+      else: %{degraded: false, since: nil, buffered: 0}
+  catch
+    :exit, _reason -> %{degraded: false, since: nil, buffered: 0}
+  end
+
+  @doc "Flushes buffered reports now. Returns the resulting state."
+  @spec recover(GenServer.server()) :: state_info()
+  def recover(server \\ __MODULE__), do: GenServer.call(server, :recover)
+
+  @doc """
+  Whether an error means the database is unreachable, as opposed to the
+  write itself being rejected. Looks through Ash error wrappers.
+  """
+  @spec unavailable?(term()) :: boolean()
+  def unavailable?(%DBConnection.ConnectionError{}), do: true
+
+  def unavailable?(%Postgrex.Error{postgres: %{code: code}}),
+    do: code in @unavailable_postgres_codes
+
+  def unavailable?(%{errors: errors}) when is_list(errors), do: Enum.any?(errors, &unavailable?/1)
+  def unavailable?(%{error: error}), do: unavailable?(error)
+  def unavailable?(_reason), do: false
+
+  @impl true
+  def init(opts) do
+    opts = Keyword.merge(config(), opts)
+
+    state = %{
+      recover_interval_ms: Keyword.get(opts, :recover_interval_ms, @default_recover_interval_ms),
+      flush_batch_size: Keyword.get(opts, :flush_batch_size, @default_flush_batch_size),
+      max_buffered: Keyword.get(opts, :max_buffered, @default_max_buffered),
+      probe: Keyword.get(opts, :probe, &probe_repo/0),
+      # key => {seq, write}; seq keeps the flush in arrival order
+      buffer: %{},
+      seq: 0,
+      cache: %{},
+      since: nil
+    }
+
+    schedule_recover(state)
+    {:ok, state}
+  end
+
+  @impl true
+  def handle_call(:state, _from, state), do: {:reply, info(state), state}
+
+  def handle_call({:cached, key}, _from, state), do: {:reply, Map.fetch(state.cache, key), state}
+
+  def handle_call({:buffer, key, write, reason}, _from, state) do
+    if map_size(state.buffer) >= state.max_buffered and not Map.has_key?(state.buffer, key) do
+      {:reply, {:error, :degraded_buffer_full}, state}
+    else
+      state = enter(state, reason)
+      buffer = Map.put(state.buffer, key, {state.seq, write})
+      {:reply, :ok, %{state | buffer: buffer, seq: state.seq + 1}}
+    end
+  end
+
+  def handle_call(:recover, _from, state) do
+    state = flush(state)
+    {:reply, info(state), state}
+  end
+
+  @impl true
+  def handle_cast({:cache, key, value}, state) do
+    {:noreply, %{state | cache: Map.put(state.cache, key, value)}}
+  end
+
+  def handle_cast({:unavailable, reason}, state), do: {:noreply, enter(state, reason)}
+
+  @impl true
+  def handle_info(:recover, state) do
+    state = flush(state)
+    schedule_recover(state)
+    {:noreply, state}
+  end
+
+  def handle_info(:flush, state), do: {:noreply, flush(state)}
+
+  def handle_info(_msg, state), do: {:noreply, state}
+
+  defp read_through(server, key, read, cached) do
+    case read.() do
+      {:ok, value} = ok ->
+        if running?(server), do: GenServer.cast(server, {:cache, key, value})
+        ok
+
+      {:error, reason} = error ->
+        if running?(server) and unavailable?(reason) do
+          GenServer.cast(server, {:unavailable, reason})
+
+          case cached do
+            {:ok, value} -> {:ok, value}
+            :error -> error
+          end
+        else
+          error
+        end
+    end
+  end
+
+  defp buffer(server, key, write, reason) do
+    GenServer.call(server, {:buffer, key, write, reason})
+  catch
+    :exit, _reason -> {:error, reason || :degraded_mode_unavailable}
+  end
+
+  defp cached(server, key) do
+    GenServer.call(server, {:cached, key})
+  catch
+    :exit, _reason -> :error
+  end
+
+  defp flush(%{since: nil} = state), do: state
+
+  defp flush(state) do
+    {batch, rest} =
+      state.buffer
+      |> Enum.sort_by(fn {_key, {seq, _write}} -> seq end)
+      |> Enum.split(state.flush_batch_size)
+
+    {flushed, pending} = write_batch(batch)
+    buffer = Map.new(pending ++ rest)
+    state = %{state | buffer: buffer}
+
+    cond do
+      flushed > 0 and map_size(buffer) > 0 ->
+        # More to flush and the database is answering: keep going without
+        # blocking callers for the whole backlog.
+        send(self(), :flush)
+        state
+
+      map_size(buffer) == 0 and probe(state) ->
+        Logger.info(
+          "Database reachable again; leaving degraded mode after " <>
+            "#{DateTime.diff(DateTime.utc_now(), state.since, :second)}s"
+        )
+
+        %{state | since: nil}
+
+      true ->
+        state
+    end
+  end
+
+  # Writes until the database is unreachable again; the rest stays buffered.
+  defp write_batch(batch) do
+    Enum.reduce_while(batch, {0, batch}, fn {key, {_seq, write}}, {flushed, pending} ->
+      [_ | rest] = pending
+
+      case write.() do
+        {:error, reason} ->
+          if unavailable?(reason) do
+            {:halt, {flushed, pending}}
+          else
+            Logger.warning("Dropping buffered report #{inspect(key)}: #{inspect(reason)}")
+            {:cont, {flushed + 1, rest}}
+          end
+
+        _ok ->
+          {:cont, {flushed + 1, rest}}
+      end
+    end)
+  end
+
+  defp probe(state) do
+    case state.probe.() do
+      {:ok, _result} -> true
+      _error -> false
+    end
+  end
+
+  defp enter(%{since: nil} = state, reason) do
+    Logger.warning(
+      "Database unreachable; entering degraded mode with stale reads: #{inspect(reason)}"
+    )
+
+    %{state | since: DateTime.utc_now()}
+  end
+
+  defp enter(state, _reason), do: state
+
+  defp info(state) do
+    %{degraded: not is_nil(state.since), since: state.since, buffered: map_size(state.buffer)}
+  end
+
+  defp schedule_recover(state) do
+    Process.send_after(self(), :recover, state.recover_interval_ms)
+  end
+
+  defp probe_repo, do: Repo.query("SELECT 1", [])
+
+  defp running?(server) when is_atom(server), do: Process.whereis(server) != nil
+  defp running?(_server), do: true
+
+  defp config, do: Application.get_env(:serviceradar_core, __MODULE__, [])
+end
@@ -0,0 +1,119 @@
+defmodule ServiceRadar.Infrastructure.GatewayFlapDamping do
+  @moduledoc """
+  Hysteresis for gateway health transitions.
+
+  The StateMonitor sees each gateway once per check interval as either stale
+  (no report within the heartbeat timeout) or fresh. Without damping a single
+  late report turns into a down event that clears on the next check. This
+  module tracks consecutive observations per gateway and decides when a
+  transition is warranted:
+
+  - `:down` once the gateway has been stale for `down_after_misses`
+    consecutive checks or for `down_grace_period` milliseconds, whichever
+    comes first. A threshold of `0` disables that criterion; with both
+    disabled the first miss is enough.
+  - `:recovered` once an unhealthy gateway has reported continuously for
+    `recovery_stable_period` milliseconds.
+
+  Defaults come from the StateMonitor configuration. A gateway can override
+  them through its metadata:
+
+      %{"flap_damping" => %{
+        "down_after_misses" => 5,
+        "down_grace_seconds" => 300,
+        "recovery_stable_seconds" => 120
+      }}
+  """
+
+  @type thresholds :: %{
+          down_after_misses: non_neg_integer(),
+          down_grace_period: non_neg_integer(),
+          recovery_stable_period: non_neg_integer()
+        }
+
+  @type entry :: %{
+          misses: non_neg_integer(),
+          stale_since: integer() | nil,
+          fresh_since: integer() | nil
+        }
+
+  @type action :: :down | :recovered | :hold
+
+  @metadata_key "flap_damping"
+
+  @doc """
+  Merges a gateway's metadata overrides over the default thresholds.
+  """
+  @spec thresholds(thresholds(), map() | nil) :: thresholds()
+  def thresholds(defaults, metadata) when is_map(metadata) do
+    case Map.get(metadata, @metadata_key) || Map.get(metadata, :flap_damping) do
+      overrides when is_map(overrides) ->
+        %{
+          down_after_misses:
+            override(overrides, :down_after_misses, 1, defaults.down_after_misses),
+          down_grace_period:
+            override(overrides, :down_grace_seconds, 1_000, defaults.down_grace_period),
+          recovery_stable_period:
+            override(overrides, :recovery_stable_seconds, 1_000, defaults.recovery_stable_period)
+        }
+
+      _ ->
+        defaults
+    end
+  end
+
+  def thresholds(defaults, _metadata), do: defaults
+
+  @doc """
+  Records one observation and returns the action it warrants with the updated
+  entry. A `nil` entry means the gateway is healthy and reporting; it is what
+  `observe/5` returns once there is nothing left to track.
+  """
+  @spec observe(entry() | nil, boolean(), boolean(), integer(), thresholds()) ::
+          {action(), entry() | nil}
+  def observe(entry, stale?, healthy?, now, thresholds)
+
+  def observe(entry, true, _healthy?, now, thresholds) do
+    entry = entry || new_entry()
+
+    entry = %{
+      entry
+      | misses: entry.misses + 1,
+        stale_since: entry.stale_since || now,
+        fresh_since: nil
+    }
+
+    if down?(entry, now, thresholds), do: {:down, entry}, else: {:hold, entry}
+  end
+
+  def observe(_entry, false, true, _now, _thresholds), do: {:hold, nil}
+
+  def observe(entry, false, false, now, thresholds) do
+    entry = entry || new_entry()
+    entry = %{entry | misses: 0, stale_since: nil, fresh_since: entry.fresh_since || now}
+
+    if now - entry.fresh_since >= thresholds.recovery_stable_period do
+      {:recovered, nil}
+    else
+      {:hold, entry}
+    end
+  end
+
+  defp new_entry, do: %{misses: 0, stale_since: nil, fresh_since: nil}
+
+  defp down?(entry, now, %{down_after_misses: misses, down_grace_period: grace}) do
+    cond do
+      misses == 0 and grace == 0 -> true
+      misses > 0 and entry.misses >= misses -> true
+      grace > 0 and now - entry.stale_since >= grace -> true
+      true -> false
+    end
+  end
+
+  defp override(overrides, key, scale, default) do
+    case Map.get(overrides, Atom.to_string(key), Map.get(overrides, key)) do
+      value when is_integer(value) and value >= 0 -> value * scale
+      _ -> default
+    end
+  end
+end
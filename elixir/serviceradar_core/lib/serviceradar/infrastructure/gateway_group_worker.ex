@@ -0,0 +1,29 @@
+defmodule ServiceRadar.Infrastructure.GatewayGroupWorker do
+  @moduledoc """
+  Oban cron worker that writes configured gateway group assignments into
+  gateway metadata through
+  `ServiceRadar.Infrastructure.GatewayGroups.sync_configured/1`, so gateways
+  registered after startup pick up their group.
+  """
+
+  use Oban.Worker,
+    queue: :maintenance,
+    max_attempts: 1,
+    unique: [period: 300, states: [:available, :scheduled, :executing, :retryable]]
+
+  alias ServiceRadar.Infrastructure.GatewayGroups
+
+  require Logger
+
+  @impl Oban.Worker
+  def perform(%Oban.Job{}) do
+    case GatewayGroups.sync_configured() do
+      {:ok, _count} ->
+        :ok
+
+      {:error, reason} ->
+        Logger.warning("GatewayGroupWorker: group sync failed: #{inspect(reason)}")
+        {:error, reason}
+    end
+  end
+end
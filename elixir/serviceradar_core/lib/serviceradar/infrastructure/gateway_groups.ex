@@ -0,0 +1,233 @@
+defmodule ServiceRadar.Infrastructure.GatewayGroups do
+  @moduledoc """
+  Site/tier groups for gateways and per-group health rollups.
+
+  A gateway's group lives in its metadata under `"group"`, the same field
+  SRQL filters on (`in:gateways group:site-nyc`). Gateways without one fall
+  into the default group, `"ungrouped"`. Groups are set through `assign/2`
+  or from configuration, which `sync_configured/1` writes into metadata:
+
+      config :serviceradar_core, ServiceRadar.Infrastructure.GatewayGroups,
+        assignments: %{"gateway-nyc-1" => "site-nyc"}
+
+  A gateway counts as `down` when it is unhealthy or offline, `degraded`
+  while it is degraded, recovering, draining or in maintenance, and `up`
+  otherwise.
+  """
+
+  alias ServiceRadar.Infrastructure.DegradedMode
+  alias ServiceRadar.Repo
+
+  @default_group "ungrouped"
+  @metadata_key "group"
+  @degraded_statuses ~w(degraded recovering draining maintenance)
+  @down_statuses ~w(offline inactive)
+
+  @type gateway :: %{
+          gateway_id: String.t(),
+          status: String.t() | atom() | nil,
+          is_healthy: boolean() | nil,
+          metadata: map() | nil
+        }
+
+  @type summary :: %{
+          group: String.t(),
+          total: non_neg_integer(),
+          up: non_neg_integer(),
+          down: non_neg_integer(),
+          degraded: non_neg_integer(),
+          gateways: [String.t()]
+        }
+
+  @gateways_sql """
+  SELECT gateway_id, status, is_healthy, metadata
+  FROM platform.gateways
+  """
+
+  @assign_sql """
+  UPDATE platform.gateways
+  SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('group', $2::text),
+      updated_at = now()
+  WHERE gateway_id = $1
+  """
+
+  @clear_sql """
+  UPDATE platform.gateways
+  SET metadata = COALESCE(metadata, '{}'::jsonb) - 'group',
+      updated_at = now()
+  WHERE gateway_id = $1
+  """
+
+  @doc "The group gateways without one belong to."
+  @spec default_group() :: String.t()
+  def default_group, do: @default_group
+
+  @doc """
+  Returns the group a gateway belongs to: its metadata group, else the
+  default group.
+  """
+  @spec group_for(gateway()) :: String.t()
+  def group_for(gateway) do
+    case gateway |> Map.get(:metadata) |> metadata_group() do
+      nil -> @default_group
+      group -> group
+    end
+  end
+
+  @doc "Classifies a gateway as `:up`, `:down` or `:degraded`."
+  @spec state_of(gateway()) :: :up | :down | :degraded
+  def state_of(gateway) do
+    status = gateway |> Map.get(:status) |> normalize_status()
+
+    cond do
+      Map.get(gateway, :is_healthy) != true or status in @down_statuses -> :down
+      status in @degraded_statuses -> :degraded
+      true -> :up
+    end
+  end
+
+  @doc """
+  Summarizes gateways per group, sorted by group name. Gateway ids within a
+  summary are sorted too.
+  """
+  @spec summarize([gateway()]) :: [summary()]
+  def summarize(gateways) do
+    gateways
+    |> Enum.group_by(&group_for/1)
+    |> Enum.map(fn {group, members} ->
+      states = Enum.frequencies_by(members, &state_of/1)
+
+      %{
+        group: group,
+        total: length(members),
+        up: Map.get(states, :up, 0),
+        down: Map.get(states, :down, 0),
+        degraded: Map.get(states, :degraded, 0),
+        gateways: members |> Enum.map(& &1.gateway_id) |> Enum.sort()
+      }
+    end)
+    |> Enum.sort_by(& &1.group)
+  end
+
+  @doc """
+  Reads every gateway and returns the per-group rollup.
+
+  ## Options
+
+    - `:group` - only return this group's summary
+    - `:load` - `fn -> {:ok, [gateway]} end` used instead of reading
+      `platform.gateways`
+
+  While `ServiceRadar.Infrastructure.DegradedMode` is degraded the rollup is
+  built from the last gateways read.
+  """
+  @spec rollup(keyword()) :: {:ok, [summary()]} | {:error, term()}
+  def rollup(opts \\ []) do
+    load = Keyword.get(opts, :load, fn -> DegradedMode.read(:gateways, &load_gateways/0) end)
+
+    with {:ok, gateways} <- load.() do
+      summaries = summarize(gateways)
+
+      case blank_to_nil(Keyword.get(opts, :group)) do
+        nil -> {:ok, summaries}
+        group -> {:ok, Enum.filter(summaries, &(&1.group == group))}
+      end
+    end
+  end
+
+  @doc """
+  Assigns a gateway to a group by writing its metadata. A `nil` or blank
+  group clears the assignment.
+
+  ## Options
+
+    - `:store` - `fn gateway_id, group_or_nil -> :ok | {:error, reason} end`
+      used instead of updating `platform.gateways`
+  """
+  @spec assign(String.t(), String.t() | nil, keyword()) :: :ok | {:error, term()}
+  def assign(gateway_id, group, opts \\ []) do
+    store = Keyword.get(opts, :store, &store_group/2)
+
+    case blank_to_nil(gateway_id) do
+      nil -> {:error, "gateway_id is required"}
+      gateway_id -> store.(gateway_id, blank_to_nil(group))
+    end
+  end
+
+  @doc """
+  Writes the configured assignments into gateway metadata. Returns the
+  number of gateways updated.
+  """
+  @spec sync_configured(keyword()) :: {:ok, non_neg_integer()} | {:error, term()}
+  def sync_configured(opts \\ []) do
+    assignments = Keyword.get(opts, :assignments) || config(:assignments, %{})
+
+    Enum.reduce_while(assignments, {:ok, 0}, fn {gateway_id, group}, {:ok, count} ->
+      case assign(to_string(gateway_id), to_string(group), opts) do
+        :ok -> {:cont, {:ok, count + 1}}
+        {:error, :not_found} -> {:cont, {:ok, count}}
+        {:error, reason} -> {:halt, {:error, reason}}
+      end
+    end)
+  end
+
+  defp load_gateways do
+    case Repo.query(@gateways_sql, []) do
+      {:ok, %{rows: rows}} ->
+        {:ok,
+         Enum.map(rows, fn [gateway_id, status, is_healthy, metadata] ->
+           %{
+             gateway_id: gateway_id,
+             status: status,
+             is_healthy: is_healthy,
+             metadata: metadata || %{}
+           }
+         end)}
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  defp store_group(gateway_id, group) do
+    result =
+      case group do
+        nil -> Repo.query(@clear_sql, [gateway_id])
+        group -> Repo.query(@assign_sql, [gateway_id, group])
+      end
+
+    case result do
+      {:ok, %{num_rows: 0}} -> {:error, :not_found}
+      {:ok, _} -> :ok
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp metadata_group(metadata) when is_map(metadata) do
+    metadata
+    |> Map.get(@metadata_key, Map.get(metadata, :group))
+    |> blank_to_nil()
+  end
+
+  defp metadata_group(_metadata), do: nil
+
+  defp normalize_status(nil), do: nil
+  defp normalize_status(status) when is_atom(status), do: Atom.to_string(status)
+  defp normalize_status(status) when is_binary(status), do: String.downcase(status)
+  defp normalize_status(_status), do: nil
+
+  defp blank_to_nil(value) when is_binary(value) do
+    case String.trim(value) do
+      "" -> nil
+      trimmed -> trimmed
+    end
+  end
+
+  defp blank_to_nil(_value), do: nil
+
+  defp config(key, default) do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
@@ -3,7 +3,9 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
   GenServer that monitors infrastructure components and triggers state transitions.
 
   Periodically checks:
-  - Gateways for heartbeat timeouts (last_seen)
+  - Gateways for heartbeat timeouts (last_seen), damped by
+    `ServiceRadar.Infrastructure.GatewayFlapDamping` so brief blips do not
+    emit down events and recoveries must be stable before they clear
   - Agents for reachability (last_seen_time)
   - Checkers for consecutive failures
 
@@ -19,7 +21,16 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
         # Agent heartbeat timeout (default: 5 minutes)
         agent_timeout: 300_000,
         # Consecutive failures before marking checker as failing
-        checker_failure_threshold: 3
+        checker_failure_threshold: 3,
+        # Consecutive missed checks before a gateway goes down (0 disables)
+        gateway_down_after_misses: 2,
+        # Or how long a gateway may stay stale before it goes down (0 disables)
+        gateway_down_grace_period: 0,
+        # How long a recovered gateway must keep reporting before it is healthy
+        gateway_recovery_stable_period: 60_000
+
+  Gateways can override the damping thresholds through their metadata; see
+  `ServiceRadar.Infrastructure.GatewayFlapDamping`.
   """
 
   use GenServer
@@ -28,6 +39,7 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
   alias ServiceRadar.Infrastructure.Agent
   alias ServiceRadar.Infrastructure.Checker
   alias ServiceRadar.Infrastructure.Gateway
+  alias ServiceRadar.Infrastructure.GatewayFlapDamping
 
   require Logger
 
@@ -35,14 +47,19 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
   @default_gateway_timeout to_timeout(minute: 2)
   @default_agent_timeout to_timeout(minute: 5)
   @default_checker_failure_threshold 3
+  @default_gateway_down_after_misses 2
+  @default_gateway_down_grace_period 0
+  @default_gateway_recovery_stable_period to_timeout(minute: 1)
 
   defstruct [
     :check_interval,
     :gateway_timeout,
     :agent_timeout,
     :checker_failure_threshold,
+    :gateway_damping_thresholds,
     :last_check,
-    :check_timer
+    :check_timer,
+    gateway_damping: %{}
   ]
 
   # ============================================================================
@@ -98,6 +115,18 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
       agent_timeout: Keyword.get(merged_opts, :agent_timeout, @default_agent_timeout),
       checker_failure_threshold:
         Keyword.get(merged_opts, :checker_failure_threshold, @default_checker_failure_threshold),
+      gateway_damping_thresholds: %{
+        down_after_misses:
+          Keyword.get(merged_opts, :gateway_down_after_misses, @default_gateway_down_after_misses),
+        down_grace_period:
+          Keyword.get(merged_opts, :gateway_down_grace_period, @default_gateway_down_grace_period),
+        recovery_stable_period:
+          Keyword.get(
+            merged_opts,
+            :gateway_recovery_stable_period,
+            @default_gateway_recovery_stable_period
+          )
+      },
       last_check: nil
     }
 
@@ -116,6 +145,8 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
       gateway_timeout: state.gateway_timeout,
       agent_timeout: state.agent_timeout,
       checker_failure_threshold: state.checker_failure_threshold,
+      gateway_damping_thresholds: state.gateway_damping_thresholds,
+      damped_gateways: map_size(state.gateway_damping),
       last_check: state.last_check,
       node: node()
     }
@@ -125,13 +156,13 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
 
   @impl true
   def handle_cast(:check_now, state) do
-    run_health_checks(state)
+    state = run_health_checks(state)
     {:noreply, %{state | last_check: DateTime.utc_now()}}
   end
 
   @impl true
   def handle_info(:run_checks, state) do
-    run_health_checks(state)
+    state = run_health_checks(state)
 
     # Schedule next check
     timer = schedule_check(state.check_interval)
@@ -166,7 +197,8 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
       Task.async(fn -> check_checkers(state, actor) end)
     ]
 
-    results = Task.await_many(tasks, to_timeout(second: 30))
+    [{gateways_checked, gateway_damping}, agents_checked, checkers_checked] =
+      Task.await_many(tasks, to_timeout(second: 30))
 
     duration = System.monotonic_time(:millisecond) - start_time
 
@@ -174,36 +206,72 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
       [:serviceradar, :infrastructure, :state_monitor, :check_completed],
       %{duration: duration},
       %{
-        gateways_checked: Enum.at(results, 0),
-        agents_checked: Enum.at(results, 1),
-        checkers_checked: Enum.at(results, 2)
+        gateways_checked: gateways_checked,
+        agents_checked: agents_checked,
+        checkers_checked: checkers_checked
       }
     )
 
     Logger.debug("Health checks completed", duration_ms: duration)
+
+    %{state | gateway_damping: gateway_damping}
   end
 
   defp check_gateways(state, actor) do
-    timeout_threshold = DateTime.add(DateTime.utc_now(), -state.gateway_timeout, :millisecond)
+    now = DateTime.utc_now()
+    timeout_threshold = DateTime.add(now, -state.gateway_timeout, :millisecond)
+    now_ms = DateTime.to_unix(now, :millisecond)
+
+    case list_monitored_gateways(actor) do
+      {:ok, gateways} ->
+        damping =
+          Enum.reduce(gateways, %{}, fn gateway, acc ->
+            case damp_gateway(gateway, timeout_threshold, now_ms, state, actor) do
+              nil -> acc
+              entry -> Map.put(acc, gateway.id, entry)
+            end
+          end)
+
+        {length(gateways), damping}
 
-    run_check(
-      fn -> list_stale_gateways(timeout_threshold, actor) end,
-      fn gateway -> handle_stale_gateway(gateway, actor) end,
-      "gateways"
-    )
+      {:error, reason} ->
+        Logger.error("Failed to check gateways", reason: inspect(reason))
+        {0, state.gateway_damping}
+    end
   end
 
-  defp list_stale_gateways(timeout_threshold, actor) do
+  defp list_monitored_gateways(actor) do
     require Ash.Query
 
     Gateway
-    |> Ash.Query.filter(
-      status in [:healthy, :degraded] and
-        (is_nil(last_seen) or last_seen < ^timeout_threshold)
-    )
+    |> Ash.Query.filter(status in [:healthy, :degraded, :offline, :recovering])
     |> Ash.read(actor: actor)
   end
 
+  defp damp_gateway(gateway, timeout_threshold, now_ms, state, actor) do
+    stale? =
+      is_nil(gateway.last_seen) or DateTime.compare(gateway.last_seen, timeout_threshold) == :lt
+
+    thresholds =
+      GatewayFlapDamping.thresholds(state.gateway_damping_thresholds, gateway.metadata)
+
+    {action, entry} =
+      state.gateway_damping
+      |> Map.get(gateway.id)
+      |> GatewayFlapDamping.observe(stale?, gateway.status == :healthy, now_ms, thresholds)
+
+    case action do
+      :down -> handle_stale_gateway(gateway, actor)
+      :recovered -> handle_recovered_gateway(gateway, actor)
+      :hold -> :ok
+    end
+
+    entry
+  end
+
+  defp handle_stale_gateway(%{status: status}, _actor) when status in [:offline, :recovering],
+    do: :ok
+
   defp handle_stale_gateway(gateway, actor) do
     Logger.info("Gateway heartbeat timeout, transitioning to degraded/offline",
       gateway_id: gateway.id
@@ -222,6 +290,27 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
     )
   end
 
+  defp handle_recovered_gateway(gateway, actor) do
+    Logger.info("Gateway reporting steadily again, restoring health", gateway_id: gateway.id)
+
+    if gateway.status == :offline do
+      case update_resource(gateway, :recover, %{}, actor) do
+        {:ok, recovering} ->
+          transition_resource(recovering, :restore_health, %{}, actor, "gateway",
+            gateway_id: gateway.id
+          )
+
+        {:error, reason} ->
+          Logger.error("Failed to transition gateway",
+            gateway_id: gateway.id,
+            reason: inspect(reason)
+          )
+      end
+    else
+      transition_resource(gateway, :restore_health, %{}, actor, "gateway", gateway_id: gateway.id)
+    end
+  end
+
   defp check_agents(state, actor) do
     timeout_threshold = DateTime.add(DateTime.utc_now(), -state.agent_timeout, :millisecond)
 
@@ -299,12 +388,7 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
   end
 
   defp transition_resource(resource, action, params, actor, resource_name, metadata) do
-    result =
-      resource
-      |> Ash.Changeset.for_update(action, params, actor: actor)
-      |> Ash.update()
-
-    case result do
+    case update_resource(resource, action, params, actor) do
       {:ok, _updated_resource} ->
         :ok
 
@@ -315,4 +399,10 @@ defmodule ServiceRadar.Infrastructure.StateMonitor do
         )
     end
   end
+
+  defp update_resource(resource, action, params, actor) do
+    resource
+    |> Ash.Changeset.for_update(action, params, actor: actor)
+    |> Ash.update()
+  end
 end
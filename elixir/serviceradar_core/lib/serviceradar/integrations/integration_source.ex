@@ -212,6 +212,15 @@ defmodule ServiceRadar.Integrations.IntegrationSource do
       change {PublishSyncLog, stage: :finished}
     end
 
+    update :record_dry_run do
+      description "Record the reconcile plan from a dry-run sync"
+
+      argument :plan, :map, allow_nil?: false
+
+      change set_attribute(:last_dry_run_plan, arg(:plan))
+      change set_attribute(:last_dry_run_at, &DateTime.utc_now/0)
+    end
+
     update :northbound_start do
       description "Mark northbound Armis update execution as running"
 
@@ -503,6 +512,16 @@ defmodule ServiceRadar.Integrations.IntegrationSource do
       description "Total sync attempts"
     end
 
+    attribute :last_dry_run_at, :utc_datetime do
+      public? true
+      description "Last dry-run sync time"
+    end
+
+    attribute :last_dry_run_plan, :map do
+      public? true
+      description "Changes the last dry-run sync would have made, grouped by action"
+    end
+
     attribute :northbound_last_run_at, :utc_datetime do
       public? true
       description "Last northbound update run time"
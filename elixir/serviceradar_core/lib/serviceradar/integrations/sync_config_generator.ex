@@ -98,6 +98,7 @@ defmodule ServiceRadar.Integrations.SyncConfigGenerator do
       "custom_field" => first_custom_field(source.custom_fields),
       "batch_size" => get_setting(source.settings, "batch_size"),
       "insecure_skip_verify" => get_setting(source.settings, "insecure_skip_verify"),
+      "dry_run" => get_setting(source.settings, "dry_run"),
       "sync_service_id" => to_string(source.id)
     })
   end
@@ -40,6 +40,10 @@ defmodule ServiceRadar.Inventory do
     resource ServiceRadar.Inventory.DeviceGroup
     resource ServiceRadar.Inventory.DeviceIdentifier
     resource ServiceRadar.Inventory.MergeAudit
+    resource ServiceRadar.Inventory.DeviceFieldConflict
+    resource ServiceRadar.Inventory.DeviceTagRule
+    resource ServiceRadar.Inventory.ScheduledDeviceOperation
+    resource ServiceRadar.Inventory.QuarantinedDeviceUpdate
     resource ServiceRadar.Inventory.DeviceCleanupSettings
   end
 
@@ -0,0 +1,45 @@
+defmodule ServiceRadar.Inventory.Changes.ApplyDeviceTagRules do
+  @moduledoc """
+  Re-evaluates device tag rules against the updated device, so a change in
+  e.g. risk score or type adds or removes rule-applied tags right away.
+
+  Atomic bulk updates (such as bulk tag edits) skip evaluation; rule changes
+  are re-synced by `ServiceRadar.Inventory.DeviceTagResyncWorker`.
+  """
+
+  use Ash.Resource.Change
+
+  alias ServiceRadar.Inventory.DeviceTagger
+
+  @matched_fields [:ip, :type, :vendor_name, :os, :risk_score]
+
+  @impl true
+  def change(changeset, _opts, _context) do
+    Ash.Changeset.before_action(changeset, &apply_rules/1)
+  end
+
+  @impl true
+  def atomic(_changeset, _opts, _context), do: :ok
+
+  defp apply_rules(changeset) do
+    rules = DeviceTagger.load_rules()
+    previous_auto_tags = changeset.data.auto_tags || %{}
+
+    if rules == [] and previous_auto_tags == %{} do
+      changeset
+    else
+      device = Map.new(@matched_fields, &{&1, Ash.Changeset.get_attribute(changeset, &1)})
+
+      {tags, auto_tags} =
+        DeviceTagger.retag(
+          Ash.Changeset.get_attribute(changeset, :tags),
+          previous_auto_tags,
+          DeviceTagger.evaluate(device, rules)
+        )
+
+      changeset
+      |> Ash.Changeset.force_change_attribute(:tags, tags)
+      |> Ash.Changeset.force_change_attribute(:auto_tags, auto_tags)
+    end
+  end
+end
@@ -0,0 +1,30 @@
+defmodule ServiceRadar.Inventory.Changes.ResyncDeviceTags do
+  @moduledoc """
+  Schedules a re-evaluation of every device's tags after a tag rule changes.
+  """
+
+  use Ash.Resource.Change
+
+  alias ServiceRadar.Changes.AfterAction
+  alias ServiceRadar.Inventory.DeviceTagResyncWorker
+
+  require Logger
+
+  @impl true
+  def change(changeset, _opts, _context) do
+    AfterAction.after_action(changeset, fn _record -> schedule_resync() end)
+  end
+
+  defp schedule_resync do
+    case DeviceTagResyncWorker.enqueue() do
+      {:ok, _job} ->
+        :ok
+
+      {:error, :oban_unavailable} ->
+        Logger.debug("Device tag resync deferred (Oban unavailable)")
+
+      {:error, reason} ->
+        Logger.warning("Failed to schedule device tag resync: #{inspect(reason)}")
+    end
+  end
+end
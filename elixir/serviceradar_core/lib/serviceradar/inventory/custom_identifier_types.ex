@@ -0,0 +1,202 @@
+defmodule ServiceRadar.Inventory.CustomIdentifierTypes do
+  @moduledoc """
+  Operator-defined strong identifier types for DIRE
+  (`ServiceRadar.Inventory.IdentityReconciler`).
+
+  Some environments have strong identifiers DIRE does not know about, such as
+  asset tags or serial numbers from a CMDB. Declaring them as custom identifier
+  types makes DIRE look devices up by them, register them in
+  `device_identifiers`, and merge devices that share them, exactly like the
+  built-in non-MAC strong identifiers. Custom types rank after
+  `netbox_device_id` and before `mac`, in the order they are declared.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Inventory.CustomIdentifierTypes,
+        types: [
+          %{name: "asset_tag"},
+          %{name: "cmdb_serial", source: "cmdb_serial_number", scope: "global"}
+        ]
+
+  or at runtime with `SERVICERADAR_CUSTOM_IDENTIFIER_TYPES` as a JSON list.
+
+    * `name` - the identifier type stored in `device_identifiers`: lowercase
+      letters, digits and underscores, and not a built-in type
+    * `source` - the device update metadata key holding the value (default:
+      `name`)
+    * `scope` - `partition` (default) when a value identifies one device per
+      partition, like the built-in identifiers; `global` when it identifies one
+      device across all partitions
+
+  Types are validated and registered when the application starts; invalid
+  ones are logged and skipped.
+  """
+
+  require Logger
+
+  @types_key {__MODULE__, :types}
+  @builtin_types [:agent_id, :armis_device_id, :integration_id, :netbox_device_id, :mac, :ip]
+  @name_pattern ~r/^[a-z][a-z0-9_]{0,62}$/
+  @global_partition "*"
+
+  @type scope :: :partition | :global
+  @type t :: %{name: atom(), source: String.t(), scope: scope()}
+
+  @doc "Built-in identifier types, which custom types cannot redefine."
+  @spec builtin_types() :: [atom()]
+  def builtin_types, do: @builtin_types
+
+  @doc """
+  Validates and registers the configured custom types, replacing any
+  registered before. Returns the registered types.
+  """
+  @spec register!() :: [t()]
+  def register! do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(:types, [])
+    |> register()
+  end
+
+  @doc "Validates and registers `definitions`. Returns the registered types."
+  @spec register([map()]) :: [t()]
+  def register(definitions) do
+    {types, invalid} = compile_types(definitions)
+
+    Enum.each(invalid, fn {label, reason} ->
+      Logger.warning("Ignoring custom identifier type #{inspect(label)}: #{reason}")
+    end)
+
+    :persistent_term.put(@types_key, types)
+    types
+  end
+
+  @doc """
+  Validates type definitions, returning the compiled types and the rejected
+  ones with the reason each was rejected.
+  """
+  @spec compile_types([map()]) :: {[t()], [{term(), String.t()}]}
+  def compile_types(definitions) when is_list(definitions) do
+    {valid, invalid} =
+      Enum.reduce(definitions, {[], []}, fn definition, {valid, invalid} ->
+        case compile_type(definition, valid) do
+          {:ok, type} -> {[type | valid], invalid}
+          {:error, reason} -> {valid, [{field(definition, :name), reason} | invalid]}
+        end
+      end)
+
+    {Enum.reverse(valid), Enum.reverse(invalid)}
+  end
+
+  def compile_types(_definitions), do: {[], [{nil, "types must be a list"}]}
+
+  @doc "The registered custom types, in priority order."
+  @spec types() :: [t()]
+  def types, do: :persistent_term.get(@types_key, [])
+
+  @doc "The names of the registered custom types, in priority order."
+  @spec names() :: [atom()]
+  def names, do: Enum.map(types(), & &1.name)
+
+  @doc "Whether `type` is a built-in or registered custom identifier type."
+  @spec known_type?(term()) :: boolean()
+  def known_type?(type) when is_atom(type), do: type in @builtin_types or type in names()
+
+  def known_type?(type) when is_binary(type) do
+    Enum.any?(@builtin_types ++ names(), &(Atom.to_string(&1) == type))
+  end
+
+  def known_type?(_type), do: false
+
+  @doc """
+  Reads the custom identifier values from device update metadata, keyed by
+  type name. Blank values are omitted.
+  """
+  @spec extract(map() | nil) :: %{optional(atom()) => String.t()}
+  def extract(metadata) when is_map(metadata) do
+    Enum.reduce(types(), %{}, fn %{name: name, source: source}, acc ->
+      case metadata[source] do
+        value when is_binary(value) ->
+          case String.trim(value) do
+            "" -> acc
+            trimmed -> Map.put(acc, name, trimmed)
+          end
+
+        value when is_integer(value) ->
+          Map.put(acc, name, Integer.to_string(value))
+
+        _ ->
+          acc
+      end
+    end)
+  end
+
+  def extract(_metadata), do: %{}
+
+  @doc """
+  The partition an identifier of `type` is stored and looked up under:
+  `partition` for partition-scoped types, a shared partition for global ones.
+  """
+  @spec partition(atom(), String.t()) :: String.t()
+  def partition(type, partition) do
+    if Enum.any?(types(), &(&1.name == type and &1.scope == :global)) do
+      @global_partition
+    else
+      partition
+    end
+  end
+
+  defp compile_type(definition, compiled) when is_map(definition) do
+    with {:ok, name} <- parse_name(field(definition, :name), compiled),
+         {:ok, source} <- parse_source(field(definition, :source), name),
+         {:ok, scope} <- parse_scope(field(definition, :scope)) do
+      {:ok, %{name: name, source: source, scope: scope}}
+    end
+  end
+
+  defp compile_type(_definition, _compiled), do: {:error, "must be a map"}
+
+  defp parse_name(nil, _compiled), do: {:error, "name is required"}
+
+  defp parse_name(name, compiled) when is_binary(name) or is_atom(name) do
+    name = to_string(name)
+
+    cond do
+      not Regex.match?(@name_pattern, name) ->
+        {:error, "name must be lowercase letters, digits and underscores"}
+
+      Enum.any?(@builtin_types, &(Atom.to_string(&1) == name)) ->
+        {:error, "name is a built-in identifier type"}
+
+      Enum.any?(compiled, &(Atom.to_string(&1.name) == name)) ->
+        {:error, "name is declared more than once"}
+
+      true ->
+        # Bounded by configuration, so creating the atom is safe.
+        {:ok, String.to_atom(name)}
+    end
+  end
+
+  defp parse_name(_name, _compiled), do: {:error, "name is required"}
+
+  defp parse_source(nil, name), do: {:ok, Atom.to_string(name)}
+
+  defp parse_source(source, _name) when is_binary(source) do
+    case String.trim(source) do
+      "" -> {:error, "source must not be blank"}
+      trimmed -> {:ok, trimmed}
+    end
+  end
+
+  defp parse_source(_source, _name), do: {:error, "source must be a metadata key"}
+
+  defp parse_scope(scope) when scope in [nil, :partition, "partition"], do: {:ok, :partition}
+  defp parse_scope(scope) when scope in [:global, "global"], do: {:ok, :global}
+  defp parse_scope(_scope), do: {:error, "scope must be partition or global"}
+
+  defp field(definition, key) when is_map(definition) do
+    Map.get(definition, key, Map.get(definition, Atom.to_string(key)))
+  end
+
+  defp field(_definition, _key), do: nil
+end
@@ -132,8 +132,10 @@ defmodule ServiceRadar.Inventory.Device do
     :metadata
   ]
   @group_fields [:group_id]
+  @owner_fields [:owner_uid, :owner_type]
   @availability_fields [:is_available]
   @soft_delete_fields [:deleted_reason, :deleted_by]
+  @archive_fields [:archived_reason]
 
   postgres do
     table "ocsf_devices"
@@ -158,7 +160,10 @@ defmodule ServiceRadar.Inventory.Device do
     define :get_by_mac, action: :by_mac, args: [:mac, :include_deleted]
     define :soft_delete, action: :soft_delete, args: [:deleted_reason, :deleted_by]
     define :restore, action: :restore
+    define :archive, action: :archive
+    define :unarchive, action: :unarchive
     define :bulk_soft_delete, action: :bulk_soft_delete, args: [:device_uids, :deleted_reason]
+    define :assign_owner, action: :assign_owner
   end
 
   actions do
@@ -172,7 +177,18 @@ defmodule ServiceRadar.Inventory.Device do
         default false
       end
 
-      filter expr(is_nil(deleted_at) or ^arg(:include_deleted))
+      argument :include_archived, :boolean do
+        allow_nil? true
+        default false
+      end
+
+      # Callers that include deleted devices (ingest lookups, cleanup) see
+      # archived ones too.
+      filter expr(
+               (is_nil(deleted_at) or ^arg(:include_deleted)) and
+                 (is_nil(archived_at) or ^arg(:include_archived) or ^arg(:include_deleted))
+             )
+
       pagination keyset?: true, default_limit: 5000
     end
 
@@ -184,8 +200,16 @@ defmodule ServiceRadar.Inventory.Device do
         default false
       end
 
+      argument :include_archived, :boolean do
+        allow_nil? true
+        default false
+      end
+
       get? true
-      filter expr(uid == ^arg(:uid) and (is_nil(deleted_at) or ^arg(:include_deleted)))
+      filter expr(
+               uid == ^arg(:uid) and (is_nil(deleted_at) or ^arg(:include_deleted)) and
+                 (is_nil(archived_at) or ^arg(:include_archived) or ^arg(:include_deleted))
+             )
     end
 
     read :by_ip do
@@ -196,7 +220,15 @@ defmodule ServiceRadar.Inventory.Device do
         default false
       end
 
-      filter expr(ip == ^arg(:ip) and (is_nil(deleted_at) or ^arg(:include_deleted)))
+      argument :include_archived, :boolean do
+        allow_nil? true
+        default false
+      end
+
+      filter expr(
+               ip == ^arg(:ip) and (is_nil(deleted_at) or ^arg(:include_deleted)) and
+                 (is_nil(archived_at) or ^arg(:include_archived) or ^arg(:include_deleted))
+             )
     end
 
     read :by_mac do
@@ -207,7 +239,15 @@ defmodule ServiceRadar.Inventory.Device do
         default false
       end
 
-      filter expr(mac == ^arg(:mac) and (is_nil(deleted_at) or ^arg(:include_deleted)))
+      argument :include_archived, :boolean do
+        allow_nil? true
+        default false
+      end
+
+      filter expr(
+               mac == ^arg(:mac) and (is_nil(deleted_at) or ^arg(:include_deleted)) and
+                 (is_nil(archived_at) or ^arg(:include_archived) or ^arg(:include_deleted))
+             )
     end
 
     read :by_gateway do
@@ -218,19 +258,26 @@ defmodule ServiceRadar.Inventory.Device do
         default false
       end
 
+      argument :include_archived, :boolean do
+        allow_nil? true
+        default false
+      end
+
       filter expr(
-               gateway_id == ^arg(:gateway_id) and (is_nil(deleted_at) or ^arg(:include_deleted))
+               gateway_id == ^arg(:gateway_id) and
+                 (is_nil(deleted_at) or ^arg(:include_deleted)) and
+                 (is_nil(archived_at) or ^arg(:include_archived) or ^arg(:include_deleted))
              )
     end
 
     read :available do
       description "Devices currently available"
-      filter expr(is_available == true and is_nil(deleted_at))
+      filter expr(is_available == true and is_nil(deleted_at) and is_nil(archived_at))
     end
 
     read :recently_seen do
       description "Devices seen in the last hour"
-      filter expr(last_seen_time > ago(1, :hour) and is_nil(deleted_at))
+      filter expr(last_seen_time > ago(1, :hour) and is_nil(deleted_at) and is_nil(archived_at))
     end
 
     create :create do
@@ -244,12 +291,15 @@ defmodule ServiceRadar.Inventory.Device do
         |> Ash.Changeset.change_new_attribute(:last_seen_time, now)
         |> Ash.Changeset.change_new_attribute(:created_time, now)
       end
+
+      change ServiceRadar.Inventory.Changes.ApplyDeviceTagRules
     end
 
     update :update do
       accept @device_update_fields
 
       change set_attribute(:modified_time, &DateTime.utc_now/0)
+      change ServiceRadar.Inventory.Changes.ApplyDeviceTagRules
       validate ServiceRadar.Inventory.Validations.AgentManaged
     end
 
@@ -268,6 +318,23 @@ defmodule ServiceRadar.Inventory.Device do
       change set_attribute(:modified_time, &DateTime.utc_now/0)
     end
 
+    update :assign_owner do
+      description "Assign (or clear) the owner accountable for a device"
+      accept @owner_fields
+      require_atomic? false
+
+      change fn changeset, context ->
+        assigned_by = actor_identifier(Map.get(context, :actor))
+
+        changeset
+        |> Ash.Changeset.force_change_attribute(:owner_assigned_at, DateTime.utc_now())
+        |> Ash.Changeset.force_change_attribute(:owner_assigned_by, assigned_by)
+        |> Ash.Changeset.force_change_attribute(:modified_time, DateTime.utc_now())
+      end
+
+      validate ServiceRadar.Inventory.Validations.OwnerAssignment
+    end
+
     update :touch do
       description "Update last_seen_time without other changes"
       change set_attribute(:last_seen_time, &DateTime.utc_now/0)
@@ -292,6 +359,40 @@ defmodule ServiceRadar.Inventory.Device do
       change set_attribute(:modified_time, &DateTime.utc_now/0)
     end
 
+    update :archive do
+      description "Hide a device from default queries while keeping its history"
+      accept @archive_fields
+      require_atomic? false
+
+      # Archiving a soft-deleted device moves it out of the purge queue and
+      # keeps its deletion reason unless a new one is given.
+      change fn changeset, context ->
+        reason =
+          Ash.Changeset.get_attribute(changeset, :archived_reason) ||
+            changeset.data.deleted_reason
+
+        changeset
+        |> Ash.Changeset.force_change_attribute(:archived_reason, reason)
+        |> Ash.Changeset.force_change_attribute(:archived_at, DateTime.utc_now())
+        |> Ash.Changeset.force_change_attribute(
+          :archived_by,
+          actor_identifier(Map.get(context, :actor))
+        )
+        |> Ash.Changeset.force_change_attribute(:deleted_at, nil)
+        |> Ash.Changeset.force_change_attribute(:deleted_by, nil)
+        |> Ash.Changeset.force_change_attribute(:deleted_reason, nil)
+        |> Ash.Changeset.force_change_attribute(:modified_time, DateTime.utc_now())
+      end
+    end
+
+    update :unarchive do
+      description "Restore an archived device to active inventory"
+      change set_attribute(:archived_at, nil)
+      change set_attribute(:archived_by, nil)
+      change set_attribute(:archived_reason, nil)
+      change set_attribute(:modified_time, &DateTime.utc_now/0)
+    end
+
     action :bulk_soft_delete do
       argument :device_uids, {:array, :string}, allow_nil?: false
       argument :deleted_reason, :string
@@ -604,12 +705,33 @@ defmodule ServiceRadar.Inventory.Device do
       description "Optional reason for device deletion"
     end
 
+    attribute :archived_at, :utc_datetime_usec do
+      public? true
+      description "When the device was archived (hidden from default queries)"
+    end
+
+    attribute :archived_by, :string do
+      public? true
+      description "Actor identifier that archived the device"
+    end
+
+    attribute :archived_reason, :string do
+      public? true
+      description "Optional reason for archiving the device"
+    end
+
     attribute :tags, :map do
       default %{}
       public? true
       description "User-defined tags (key/value map)"
     end
 
+    attribute :auto_tags, :map do
+      default %{}
+      public? true
+      description "Subset of tags applied by device tag rules"
+    end
+
     attribute :is_available, :boolean do
       default true
       public? true
@@ -622,6 +744,33 @@ defmodule ServiceRadar.Inventory.Device do
       description "Additional metadata"
     end
 
+    attribute :fingerprint, :string do
+      public? true
+      description "Content hash of the last sync update applied (see DeviceFingerprint)"
+    end
+
+    # Ownership assignment
+    attribute :owner_uid, :string do
+      public? true
+      description "User or team accountable for this device"
+    end
+
+    attribute :owner_type, :atom do
+      public? true
+      constraints one_of: [:user, :team]
+      description "Whether owner_uid identifies a user or a team"
+    end
+
+    attribute :owner_assigned_at, :utc_datetime_usec do
+      public? true
+      description "When the owner was last assigned"
+    end
+
+    attribute :owner_assigned_by, :string do
+      public? true
+      description "Actor (or discovery source) that last assigned the owner"
+    end
+
     # Group assignment
     attribute :group_id, :uuid do
       public? true
@@ -0,0 +1,270 @@
+defmodule ServiceRadar.Inventory.DeviceArchival do
+  @moduledoc """
+  Archives devices instead of deleting them, and restores them.
+
+  An archived device (`archived_at` set) keeps its record, identifiers and
+  history but is hidden from default device reads, device search and SRQL;
+  `in:devices include_archived:true` shows it again. Archival survives
+  re-discovery: ingest keeps the record current, but the device stays archived
+  until it is restored. Archiving a soft-deleted device takes it out of the
+  cleanup worker's purge queue.
+
+  Every archive and restore is written to the audit log and recorded as a
+  `device.archival` OCSF event. `ServiceRadar.Inventory.DeviceCleanupWorker`
+  archives expired soft-deleted devices instead of purging them when the
+  device cleanup settings have `archive_expired` set.
+
+  ## Usage
+
+      DeviceArchival.archive(["sr:abc", "sr:def"], actor: scope.user, reason: "site closed")
+      DeviceArchival.restore("sr:abc", actor: scope.user)
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Events.AuditWriter
+  alias ServiceRadar.Events.PubSub, as: EventsPubSub
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Monitoring.OcsfEvent
+
+  require Ash.Query
+  require Logger
+
+  @max_devices 1000
+  @log_name "device.archival"
+  @provider "serviceradar.inventory"
+
+  @type result :: %{
+          required(:unchanged) => [String.t()],
+          required(:skipped) => [String.t()],
+          optional(:archived) => [String.t()],
+          optional(:restored) => [String.t()]
+        }
+
+  @doc "OCSF log name of archival events."
+  @spec log_name() :: String.t()
+  def log_name, do: @log_name
+
+  @doc """
+  Archives one or more devices, including soft-deleted ones.
+
+  Returns the device UIDs that were archived, were already archived, or were
+  skipped because they do not exist.
+
+  Options:
+    - `:actor` - actor performing the archival (required)
+    - `:reason` - why the devices are archived (default: the deletion reason
+      of a soft-deleted device)
+    - `:record_event` - 2-arity function used instead of creating the event
+  """
+  @spec archive([String.t()] | String.t(), keyword()) :: {:ok, result()} | {:error, term()}
+  def archive(device_uids, opts \\ []) do
+    attrs = %{archived_reason: normalize_reason(Keyword.get(opts, :reason))}
+
+    change(device_uids, :archive, attrs, &is_nil(&1.archived_at), :archived, opts)
+  end
+
+  @doc """
+  Restores one or more archived devices to active inventory.
+
+  Returns the device UIDs that were restored, were not archived, or were
+  skipped because they do not exist. Accepts the same options as `archive/2`
+  except `:reason`.
+  """
+  @spec restore([String.t()] | String.t(), keyword()) :: {:ok, result()} | {:error, term()}
+  def restore(device_uids, opts \\ []) do
+    change(device_uids, :unarchive, %{}, &(not is_nil(&1.archived_at)), :restored, opts)
+  end
+
+  @doc """
+  Archives already-loaded devices in bulk, for the cleanup worker. Writes one
+  audit entry and one event for the batch rather than one per device.
+  """
+  @spec archive_all([Device.t()], String.t(), keyword()) ::
+          {:ok, non_neg_integer()} | {:error, term()}
+  def archive_all(devices, reason, opts \\ [])
+
+  def archive_all([], _reason, _opts), do: {:ok, 0}
+
+  def archive_all(devices, reason, opts) do
+    actor = Keyword.get(opts, :actor, SystemActor.system(:device_archival))
+
+    result =
+      Ash.bulk_update(devices, :archive, %{archived_reason: reason},
+        actor: actor,
+        strategy: [:stream],
+        return_errors?: true,
+        return_records?: false
+      )
+
+    case result do
+      %Ash.BulkResult{status: :success} ->
+        uids = Enum.map(devices, & &1.uid)
+        record(:archived, uids, reason, actor, opts)
+        {:ok, length(uids)}
+
+      %Ash.BulkResult{errors: errors} ->
+        {:error, List.first(errors || []) || :bulk_archive_failed}
+    end
+  end
+
+  defp change(device_uids, action, attrs, pending?, key, opts) do
+    actor = Keyword.get(opts, :actor)
+
+    with {:ok, uids} <- normalize_uids(device_uids),
+         {:ok, devices} <- read_devices(uids, actor) do
+      found = MapSet.new(devices, & &1.uid)
+      skipped = Enum.reject(uids, &MapSet.member?(found, &1))
+      {pending, unchanged} = Enum.split_with(devices, pending?)
+
+      case update_devices(pending, action, attrs, actor) do
+        :ok ->
+          changed = Enum.map(pending, & &1.uid)
+          record(key, changed, Map.get(attrs, :archived_reason), actor, opts)
+
+          {:ok,
+           %{key => changed, unchanged: Enum.map(unchanged, & &1.uid), skipped: skipped}}
+
+        {:error, reason} ->
+          {:error, reason}
+      end
+    end
+  end
+
+  defp normalize_uids(device_uids) when is_binary(device_uids), do: normalize_uids([device_uids])
+
+  defp normalize_uids(device_uids) when is_list(device_uids) do
+    uids =
+      device_uids
+      |> Enum.filter(&is_binary/1)
+      |> Enum.map(&String.trim/1)
+      |> Enum.reject(&(&1 == ""))
+      |> Enum.uniq()
+
+    cond do
+      uids == [] -> {:error, :invalid_device_uids}
+      length(uids) > @max_devices -> {:error, {:too_many_devices, @max_devices}}
+      true -> {:ok, uids}
+    end
+  end
+
+  defp normalize_uids(_device_uids), do: {:error, :invalid_device_uids}
+
+  defp normalize_reason(reason) when is_binary(reason) do
+    case String.trim(reason) do
+      "" -> nil
+      reason -> reason
+    end
+  end
+
+  defp normalize_reason(_reason), do: nil
+
+  defp read_devices(uids, actor) do
+    Device
+    |> Ash.Query.for_read(:read, %{include_deleted: true, include_archived: true})
+    |> Ash.Query.filter(uid in ^uids)
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+  end
+
+  defp update_devices(devices, action, attrs, actor) do
+    Enum.reduce_while(devices, :ok, fn device, :ok ->
+      device
+      |> Ash.Changeset.for_update(action, attrs, actor: actor)
+      |> Ash.update()
+      |> case do
+        {:ok, _updated} -> {:cont, :ok}
+        {:error, reason} -> {:halt, {:error, reason}}
+      end
+    end)
+  end
+
+  defp record(_key, [], _reason, _actor, _opts), do: :ok
+
+  defp record(key, uids, reason, actor, opts) do
+    action = if key == :archived, do: :archive, else: :restore
+
+    AuditWriter.write_async(
+      action: action,
+      resource_type: "device",
+      resource_id: audit_resource_id(uids),
+      actor: actor,
+      message: "#{length(uids)} device(s) #{key}",
+      details: %{device_uids: uids, reason: reason}
+    )
+
+    record_event = Keyword.get(opts, :record_event, &create_event/2)
+
+    with {:ok, event} <- record_event.(event_attrs(key, uids, reason, actor), actor) do
+      _ = EventsPubSub.broadcast_event(event)
+      :ok
+    end
+  rescue
+    error ->
+      Logger.warning("Failed to record device archival event: #{inspect(error)}")
+      :ok
+  end
+
+  defp audit_resource_id([uid]), do: uid
+  defp audit_resource_id(_uids), do: "bulk"
+
+  defp event_attrs(key, uids, reason, actor) do
+    metadata = %{
+      "action" => Atom.to_string(key),
+      "device_uids" => uids,
+      "count" => length(uids),
+      "reason" => reason,
+      "actor" => actor_identifier(actor)
+    }
+
+    %{
+      time: DateTime.truncate(DateTime.utc_now(), :microsecond),
+      class_uid: 1008,
+      category_uid: 1,
+      type_uid: 100_803,
+      activity_id: 3,
+      activity_name: if(key == :archived, do: "Archive", else: "Restore"),
+      severity_id: 1,
+      severity: "Informational",
+      message: event_message(key, uids, reason),
+      status_id: 1,
+      status: "Success",
+      status_code: "device_#{key}",
+      metadata: metadata,
+      observables: Enum.map(Enum.take(uids, 100), &observable/1),
+      actor: %{"app_name" => "serviceradar.core", "user" => actor_identifier(actor)},
+      device: device_object(uids),
+      src_endpoint: %{},
+      dst_endpoint: %{},
+      log_name: @log_name,
+      log_provider: @provider,
+      log_level: "info",
+      log_version: "device_archival.v1",
+      unmapped: %{"device_archival" => metadata},
+      raw_data: Jason.encode!(metadata)
+    }
+  end
+
+  defp event_message(key, [uid], reason), do: with_reason("Device #{uid} #{key}", reason)
+
+  defp event_message(key, uids, reason),
+    do: with_reason("#{length(uids)} devices #{key}", reason)
+
+  defp with_reason(message, reason) when is_binary(reason), do: "#{message}: #{reason}"
+  defp with_reason(message, _reason), do: message
+
+  defp device_object([uid]), do: %{"uid" => uid}
+  defp device_object(_uids), do: %{}
+
+  defp observable(uid), do: %{"name" => "Device UID", "type" => "string", "value" => uid}
+
+  defp actor_identifier(actor) when is_map(actor) do
+    Map.get(actor, :id) || Map.get(actor, :email)
+  end
+
+  defp actor_identifier(_actor), do: nil
+
+  defp create_event(attrs, actor) do
+    Ash.create(OcsfEvent, attrs, action: :record, actor: actor, domain: ServiceRadar.Monitoring)
+  end
+end
@@ -3,7 +3,8 @@ defmodule ServiceRadar.Inventory.DeviceCleanupSettings do
   Instance-level settings for device cleanup retention.
 
   This resource stores the retention window and schedule used by the
-  device cleanup worker to purge tombstoned devices.
+  device cleanup worker to purge tombstoned devices, and whether expired
+  tombstones are archived instead of purged.
   """
 
   use Ash.Resource,
@@ -12,7 +13,13 @@ defmodule ServiceRadar.Inventory.DeviceCleanupSettings do
     authorizers: [Ash.Policy.Authorizer],
     extensions: [AshJsonApi.Resource]
 
-  @settings_fields [:retention_days, :cleanup_interval_minutes, :batch_size, :enabled]
+  @settings_fields [
+    :retention_days,
+    :cleanup_interval_minutes,
+    :batch_size,
+    :enabled,
+    :archive_expired
+  ]
 
   postgres do
     table "device_cleanup_settings"
@@ -120,6 +127,13 @@ defmodule ServiceRadar.Inventory.DeviceCleanupSettings do
       description "Whether device cleanup scheduling is enabled"
     end
 
+    attribute :archive_expired, :boolean do
+      allow_nil? false
+      default false
+      public? true
+      description "Archive expired soft-deleted devices instead of purging them"
+    end
+
     timestamps()
   end
 end
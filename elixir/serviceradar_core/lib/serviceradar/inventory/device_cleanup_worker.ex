@@ -1,6 +1,10 @@
 defmodule ServiceRadar.Inventory.DeviceCleanupWorker do
   @moduledoc """
   Oban worker that purges soft-deleted devices after a retention period.
+
+  When the cleanup settings have `archive_expired` set, expired devices are
+  archived through `ServiceRadar.Inventory.DeviceArchival` instead, keeping
+  their history.
   """
 
   use Oban.Worker,
@@ -13,6 +17,7 @@ defmodule ServiceRadar.Inventory.DeviceCleanupWorker do
   alias ServiceRadar.Actors.SystemActor
   alias ServiceRadar.Ash.Page
   alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceArchival
   alias ServiceRadar.Inventory.DeviceCleanupSettings
   alias ServiceRadar.Repo
   alias ServiceRadar.SweepJobs.ObanSupport
@@ -74,10 +79,11 @@ defmodule ServiceRadar.Inventory.DeviceCleanupWorker do
         "DeviceCleanupWorker: Starting cleanup - deleted devices older than #{retention_days} days"
       )
 
-      stats = purge_deleted_devices(cutoff, batch_size, actor)
+      stats = purge_deleted_devices(cutoff, batch_size, settings.archive_expired, actor)
 
       Logger.info("DeviceCleanupWorker: Completed cleanup",
         deleted: stats.deleted,
+        archived: stats.archived,
         errors: stats.errors
       )
 
@@ -131,16 +137,19 @@ defmodule ServiceRadar.Inventory.DeviceCleanupWorker do
           retention_days: @default_retention_days,
           cleanup_interval_minutes: @default_cleanup_interval_minutes,
           batch_size: @default_batch_size,
-          enabled: true
+          enabled: true,
+          archive_expired: false
         }
     end
   end
 
-  defp purge_deleted_devices(cutoff, batch_size, actor) do
-    do_purge(cutoff, batch_size, actor, %{deleted: 0, errors: 0})
+  defp purge_deleted_devices(cutoff, batch_size, archive?, actor) do
+    do_purge(cutoff, batch_size, archive?, actor, %{deleted: 0, archived: 0, errors: 0})
   end
 
-  defp do_purge(cutoff, batch_size, actor, stats) do
+  # Archived devices drop out of the batch query (archiving clears
+  # deleted_at), so both paths make progress batch by batch.
+  defp do_purge(cutoff, batch_size, archive?, actor, stats) do
     query =
       Device
       |> Ash.Query.for_read(:read, %{include_deleted: true})
@@ -152,10 +161,10 @@ defmodule ServiceRadar.Inventory.DeviceCleanupWorker do
         stats
 
       {:ok, records} ->
-        {updated, deleted_count} = hard_delete_records(stats, records)
+        {updated, removed_count} = remove_records(stats, records, archive?, actor)
 
-        if deleted_count > 0 do
-          do_purge(cutoff, batch_size, actor, updated)
+        if removed_count > 0 do
+          do_purge(cutoff, batch_size, archive?, actor, updated)
         else
           updated
         end
@@ -169,6 +178,19 @@ defmodule ServiceRadar.Inventory.DeviceCleanupWorker do
     end
   end
 
+  defp remove_records(stats, records, true, actor) do
+    case DeviceArchival.archive_all(records, "retention expired", actor: actor) do
+      {:ok, count} ->
+        {%{stats | archived: stats.archived + count}, count}
+
+      {:error, reason} ->
+        Logger.warning("DeviceCleanupWorker: archive failures", reason: inspect(reason))
+        {%{stats | errors: stats.errors + 1}, 0}
+    end
+  end
+
+  defp remove_records(stats, records, false, _actor), do: hard_delete_records(stats, records)
+
   defp hard_delete_records(stats, records) do
     uids = Enum.map(records, & &1.uid)
 
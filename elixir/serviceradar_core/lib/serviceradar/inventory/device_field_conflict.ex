@@ -0,0 +1,145 @@
+defmodule ServiceRadar.Inventory.DeviceFieldConflict do
+  @moduledoc """
+  A disagreement between discovery sources about one device field.
+
+  Conflicts are recorded by `ServiceRadar.Inventory.FieldConflictDetector`
+  while `ServiceRadar.Inventory.SyncIngestor` merges updates. The device keeps
+  the value chosen by source confidence (`winning_source`/`winning_value`);
+  the conflict only makes the disagreement visible. `source_values` maps each
+  disagreeing source to the value it reported.
+
+  There is at most one open conflict per device and field. Further
+  disagreements update it; once resolved, a new disagreement opens a new one.
+  """
+
+  use Ash.Resource,
+    domain: ServiceRadar.Inventory,
+    data_layer: AshPostgres.DataLayer,
+    authorizers: [Ash.Policy.Authorizer]
+
+  postgres do
+    table "device_field_conflicts"
+    repo ServiceRadar.Repo
+    schema "platform"
+    migrate? false
+  end
+
+  code_interface do
+    define :list_open, action: :open
+    define :get_by_device, action: :by_device, args: [:device_id]
+    define :resolve, action: :resolve
+  end
+
+  actions do
+    defaults [:read]
+
+    read :open do
+      description "Open conflicts, most recently seen first"
+      filter expr(status == :open)
+
+      prepare fn query, _context ->
+        Ash.Query.sort(query, last_seen_at: :desc)
+      end
+
+      pagination offset?: true, required?: false, default_limit: 100
+    end
+
+    read :by_device do
+      description "All conflicts recorded for a device"
+      argument :device_id, :string, allow_nil?: false
+      filter expr(device_id == ^arg(:device_id))
+
+      prepare fn query, _context ->
+        Ash.Query.sort(query, last_seen_at: :desc)
+      end
+    end
+
+    update :resolve do
+      description "Mark a conflict as reviewed"
+      require_atomic? false
+      argument :resolved_by, :string
+      argument :resolution_note, :string
+
+      validate attribute_equals(:status, :open), message: "conflict is already resolved"
+
+      change set_attribute(:status, :resolved)
+      change set_attribute(:resolved_at, &DateTime.utc_now/0)
+      change set_attribute(:resolved_by, arg(:resolved_by))
+      change set_attribute(:resolution_note, arg(:resolution_note))
+    end
+  end
+
+  policies do
+    import ServiceRadar.Policies
+
+    system_bypass()
+    read_viewer_plus()
+    operator_action(:resolve)
+  end
+
+  attributes do
+    uuid_primary_key :id
+
+    attribute :device_id, :string do
+      allow_nil? false
+      public? true
+      description "Device the sources disagree about"
+    end
+
+    attribute :field, :string do
+      allow_nil? false
+      public? true
+      description "Device field in conflict (hostname, type, vendor_name, model)"
+    end
+
+    attribute :source_values, :map do
+      default %{}
+      public? true
+      description "Value reported by each disagreeing source"
+    end
+
+    attribute :winning_source, :string do
+      public? true
+      description "Source whose value the device kept"
+    end
+
+    attribute :winning_value, :string do
+      public? true
+      description "Value the device kept"
+    end
+
+    attribute :status, :atom do
+      allow_nil? false
+      default :open
+      public? true
+      constraints one_of: [:open, :resolved]
+    end
+
+    attribute :occurrences, :integer do
+      allow_nil? false
+      default 1
+      public? true
+      description "Number of sync batches in which the sources disagreed"
+    end
+
+    attribute :first_seen_at, :utc_datetime_usec do
+      public? true
+    end
+
+    attribute :last_seen_at, :utc_datetime_usec do
+      public? true
+    end
+
+    attribute :resolved_at, :utc_datetime_usec do
+      public? true
+    end
+
+    attribute :resolved_by, :string do
+      public? true
+    end
+
+    attribute :resolution_note, :string do
+      public? true
+    end
+  end
+end
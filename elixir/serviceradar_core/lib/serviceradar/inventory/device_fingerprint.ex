@@ -0,0 +1,188 @@
+defmodule ServiceRadar.Inventory.DeviceFingerprint do
+  @moduledoc """
+  Stable content hash of a device's significant fields, used to tell whether
+  an update actually changes a device.
+
+  The sync ingestor stores the fingerprint of the last update it applied in
+  `ocsf_devices.fingerprint`. With `skip_unchanged` enabled, when the next
+  update for an active device has the same fingerprint, the device is only
+  marked as seen (`last_seen_time`) instead of being rewritten, and
+  per-device follow-up work such as tag rules is skipped. Checking costs one
+  extra SELECT of the stored fingerprints per ingest batch, so it is off by
+  default:
+
+      config :serviceradar_core, ServiceRadar.Inventory.DeviceFingerprint,
+        skip_unchanged: true
+
+  Volatile values never take part: timestamps are not fingerprint fields, and
+  the metadata keys in `volatile_metadata_keys/0` are dropped. Maps are hashed
+  independent of key order, and `discovery_sources` independent of order and
+  duplicates.
+
+  The fields hashed default to `default_fields/0` and can be configured:
+
+      config :serviceradar_core, ServiceRadar.Inventory.DeviceFingerprint,
+        fields: [:ip, :mac, :hostname, :vendor_name, :model, :metadata]
+
+  Changing the field set changes every fingerprint, so the first sync after
+  the change rewrites each device once.
+  """
+
+  import Ecto.Query, only: [from: 2]
+
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Repo
+
+  @default_fields [
+    :ip,
+    :mac,
+    :hostname,
+    :name,
+    :type,
+    :type_id,
+    :vendor_name,
+    :model,
+    :os,
+    :hw_info,
+    :owner,
+    :is_available,
+    :metadata,
+    :tags,
+    :discovery_sources
+  ]
+
+  @volatile_metadata_keys ~w(last_seen last_seen_time timestamp sync_run_id)
+
+  @unordered_fields [:discovery_sources]
+
+  @doc "Fields hashed when none are configured."
+  @spec default_fields() :: [atom()]
+  def default_fields, do: @default_fields
+
+  @doc "Metadata keys left out of the fingerprint."
+  @spec volatile_metadata_keys() :: [String.t()]
+  def volatile_metadata_keys, do: @volatile_metadata_keys
+
+  @doc "The configured fingerprint fields."
+  @spec fields() :: [atom()]
+  def fields do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(:fields, @default_fields)
+  end
+
+  @doc "Whether sync skips rewriting devices whose fingerprint is unchanged."
+  @spec skip_unchanged?() :: boolean()
+  def skip_unchanged? do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(:skip_unchanged, false)
+    |> Kernel.==(true)
+  end
+
+  @doc """
+  Stamps each record with its `:fingerprint` and splits off the records
+  whose stored fingerprint is the same. Returns `{changed, unchanged_uids}`.
+  Records whose uid is in `rewrite_uids` always count as changed.
+
+  When skipping is disabled no fingerprints are loaded and every record is
+  changed.
+
+  Options: `:skip_unchanged` (defaults to `skip_unchanged?/0`), `:fields`
+  (defaults to `fields/0`) and `:load`, the stored-fingerprint loader
+  (defaults to `load_existing/1`).
+  """
+  @spec split_unchanged([map()], [String.t()], keyword()) :: {[map()], [String.t()]}
+  def split_unchanged(records, rewrite_uids, opts \\ []) do
+    fields = Keyword.get_lazy(opts, :fields, &fields/0)
+    records = Enum.map(records, &Map.put(&1, :fingerprint, compute(&1, fields)))
+
+    if Keyword.get_lazy(opts, :skip_unchanged, &skip_unchanged?/0) do
+      load = Keyword.get(opts, :load, &load_existing/1)
+      rewrite_uids = MapSet.new(rewrite_uids)
+      stored = records |> Enum.map(& &1.uid) |> load.()
+
+      {unchanged, changed} =
+        Enum.split_with(records, fn record ->
+          Map.get(stored, record.uid) == record.fingerprint and
+            not MapSet.member?(rewrite_uids, record.uid)
+        end)
+
+      {changed, Enum.map(unchanged, & &1.uid)}
+    else
+      {records, []}
+    end
+  end
+
+  @doc """
+  Returns the fingerprint of `device` (a map or struct with atom keys) over
+  `fields`, as a lowercase hex SHA-256 digest. Missing fields hash as nil.
+  """
+  @spec compute(map(), [atom()]) :: String.t()
+  def compute(device, fields \\ fields()) when is_map(device) do
+    fields
+    |> Enum.uniq()
+    |> Enum.sort()
+    |> Enum.map(fn field -> {field, canonical(field, Map.get(device, field))} end)
+    |> :erlang.term_to_binary()
+    |> then(&:crypto.hash(:sha256, &1))
+    |> Base.encode16(case: :lower)
+  end
+
+  @doc "Whether `device` differs from the stored `fingerprint` (nil always differs)."
+  @spec changed?(map(), String.t() | nil, [atom()]) :: boolean()
+  def changed?(device, fingerprint, fields \\ fields())
+  def changed?(_device, nil, _fields), do: true
+  def changed?(device, fingerprint, fields), do: compute(device, fields) != fingerprint
+
+  @doc """
+  Loads the stored fingerprints of the active devices among `uids`. Devices
+  without a fingerprint and soft-deleted devices are left out, so they always
+  count as changed.
+  """
+  @spec load_existing([String.t()]) :: %{String.t() => String.t()}
+  def load_existing([]), do: %{}
+
+  def load_existing(uids) do
+    from(d in Device,
+      where: d.uid in ^uids and is_nil(d.deleted_at) and not is_nil(d.fingerprint),
+      select: {d.uid, d.fingerprint}
+    )
+    |> Repo.all()
+    |> Map.new()
+  end
+
+  defp canonical(:metadata, value) when is_map(value) do
+    value
+    |> Map.drop(@volatile_metadata_keys)
+    |> canonical_value()
+  end
+
+  defp canonical(field, value) when field in @unordered_fields and is_list(value) do
+    value
+    |> Enum.map(&canonical_value/1)
+    |> Enum.uniq()
+    |> Enum.sort()
+  end
+
+  defp canonical(_field, value), do: canonical_value(value)
+
+  # Keys are compared as strings so atom- and string-keyed maps hash alike.
+  defp canonical_value(%DateTime{} = value), do: DateTime.to_iso8601(value)
+
+  defp canonical_value(value) when is_struct(value),
+    do: value |> Map.from_struct() |> canonical_value()
+
+  defp canonical_value(value) when is_map(value) do
+    value
+    |> Enum.map(fn {key, item} -> {to_string(key), canonical_value(item)} end)
+    |> Enum.sort()
+  end
+
+  defp canonical_value(value) when is_list(value), do: Enum.map(value, &canonical_value/1)
+
+  defp canonical_value(value) when is_atom(value) and value not in [nil, true, false],
+    do: Atom.to_string(value)
+
+  defp canonical_value(value), do: value
+end
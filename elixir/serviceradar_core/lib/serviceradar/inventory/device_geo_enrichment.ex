@@ -0,0 +1,275 @@
+defmodule ServiceRadar.Inventory.DeviceGeoEnrichment do
+  @moduledoc """
+  Optional geolocation of devices at ingestion.
+
+  When enabled, `ServiceRadar.Inventory.SyncIngestor` resolves each public
+  device IP against a local MaxMind-format (MMDB) database and attaches the
+  result to the device metadata:
+
+    * `geo_country_iso2`, `geo_country`
+    * `geo_region`, `geo_city`
+    * `geo_latitude`, `geo_longitude`
+
+  Private, loopback and link-local addresses are skipped (see
+  `ServiceRadar.Observability.GeoIP.private_ip?/1`), as are IPs the database
+  has no location for.
+
+  The database is read once into memory. Every `:reload_check_ms` the file is
+  checked and reloaded when its modification time or size changed, so a
+  GeoLite update (or any replacement dropped in place) is picked up without a
+  restart. Lookups are cached per IP for `:cache_ttl_ms`; the cache is cleared
+  on reload and when it grows past `:cache_max_entries`.
+
+      config :serviceradar_core, ServiceRadar.Inventory.DeviceGeoEnrichment,
+        enabled: true,
+        database_path: "/var/lib/serviceradar/geoip/GeoLite2-City.mmdb"
+
+  or at runtime with `SERVICERADAR_DEVICE_GEOIP_ENABLED` and
+  `SERVICERADAR_DEVICE_GEOIP_DB`.
+  """
+
+  use GenServer
+
+  alias ServiceRadar.Observability.GeoIP
+
+  require Logger
+
+  @default_reload_check_ms to_timeout(minute: 1)
+  @default_cache_ttl_ms to_timeout(hour: 6)
+  @default_cache_max_entries 100_000
+
+  @metadata_keys [
+    {:country_iso2, "geo_country_iso2"},
+    {:country_name, "geo_country"},
+    {:region, "geo_region"},
+    {:city, "geo_city"},
+    {:latitude, "geo_latitude"},
+    {:longitude, "geo_longitude"}
+  ]
+
+  @typedoc "Reads the database at a path into a function that resolves IP tuples."
+  @type loader :: (Path.t() -> {:ok, reader()} | {:error, term()})
+  @type reader :: (:inet.ip_address() -> {:ok, map() | nil} | {:error, term()})
+
+  ## Client API
+
+  @doc """
+  Starts the enrichment server.
+
+  Options (defaulting to the application config):
+    - `:database_path` - the MMDB file (required)
+    - `:reload_check_ms` - how often to check the file for changes
+    - `:cache_ttl_ms` - how long a lookup stays cached
+    - `:cache_max_entries` - cache size at which it is cleared
+    - `:load` - a `t:loader/0`, for tests
+    - `:name` - the server name (default: `#{inspect(__MODULE__)}`)
+  """
+  def start_link(opts \\ []) do
+    opts = Keyword.merge(Application.get_env(:serviceradar_core, __MODULE__, []), opts)
+    GenServer.start_link(__MODULE__, opts, name: Keyword.get(opts, :name, __MODULE__))
+  end
+
+  @doc "Whether the enrichment is configured to run."
+  @spec enabled?() :: boolean()
+  def enabled? do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(:enabled, false)
+  end
+
+  @doc """
+  Resolves `ip` to a geolocation map (see `ServiceRadar.Observability.GeoIP`).
+
+  Returns `:skip` for private or invalid IPs, IPs without a location, and when
+  no database is loaded.
+  """
+  @spec lookup(String.t() | nil, atom()) :: {:ok, map()} | :skip
+  def lookup(ip, server \\ __MODULE__)
+
+  def lookup(ip, server) when is_binary(ip) and is_atom(server) do
+    with {:ok, reader} <- loaded_reader(server),
+         {:ok, tuple} <- public_ip(ip) do
+      cached(server, ip, fn -> resolve(reader, tuple) end)
+    end
+  end
+
+  def lookup(_ip, _server), do: :skip
+
+  @doc """
+  Adds the geolocation of `ip` to a device metadata map, leaving it unchanged
+  when the IP cannot be located.
+  """
+  @spec put_metadata(map(), String.t() | nil, atom()) :: map()
+  def put_metadata(metadata, ip, server \\ __MODULE__) when is_map(metadata) do
+    case lookup(ip, server) do
+      {:ok, geo} ->
+        Enum.reduce(@metadata_keys, metadata, fn {field, key}, acc ->
+          case Map.get(geo, field) do
+            nil -> acc
+            value -> Map.put(acc, key, value)
+          end
+        end)
+
+      :skip ->
+        metadata
+    end
+  end
+
+  @doc "Reloads the database now, regardless of whether the file changed."
+  @spec reload(atom()) :: :ok | {:error, term()}
+  def reload(server \\ __MODULE__) do
+    GenServer.call(server, :reload)
+  end
+
+  ## Server Callbacks
+
+  @impl true
+  def init(opts) do
+    name = Keyword.get(opts, :name, __MODULE__)
+    :ets.new(name, [:named_table, :set, :public, read_concurrency: true])
+
+    state = %{
+      name: name,
+      path: Keyword.fetch!(opts, :database_path),
+      load: Keyword.get(opts, :load, &load_mmdb/1),
+      reload_check_ms: Keyword.get(opts, :reload_check_ms, @default_reload_check_ms),
+      cache_ttl_ms: Keyword.get(opts, :cache_ttl_ms, @default_cache_ttl_ms),
+      cache_max_entries: Keyword.get(opts, :cache_max_entries, @default_cache_max_entries),
+      file_version: nil
+    }
+
+    cache_limits = {state.cache_ttl_ms, state.cache_max_entries}
+    :persistent_term.put({__MODULE__, name, :cache}, cache_limits)
+    schedule_check(state)
+
+    {:ok, state, {:continue, :load}}
+  end
+
+  @impl true
+  def handle_continue(:load, state) do
+    if is_nil(file_version(state.path)) do
+      Logger.warning("DeviceGeoEnrichment: GeoIP database #{state.path} not found, waiting")
+    end
+
+    {:noreply, maybe_reload(state)}
+  end
+
+  @impl true
+  def handle_call(:reload, _from, state) do
+    case load_database(%{state | file_version: nil}) do
+      {:ok, state} -> {:reply, :ok, state}
+      {:error, reason} -> {:reply, {:error, reason}, state}
+    end
+  end
+
+  @impl true
+  def handle_info(:check_database, state) do
+    schedule_check(state)
+    {:noreply, maybe_reload(state)}
+  end
+
+  ## Private Functions
+
+  defp maybe_reload(state) do
+    if file_version(state.path) == state.file_version do
+      state
+    else
+      case load_database(state) do
+        {:ok, state} ->
+          state
+
+        # Remember the version so a broken file is not reloaded on every check
+        {:error, _reason} ->
+          %{state | file_version: file_version(state.path)}
+      end
+    end
+  end
+
+  defp load_database(state) do
+    version = file_version(state.path)
+
+    case state.load.(state.path) do
+      {:ok, reader} ->
+        :persistent_term.put({__MODULE__, state.name, :reader}, reader)
+        :ets.delete_all_objects(state.name)
+        Logger.info("DeviceGeoEnrichment: loaded GeoIP database #{state.path}")
+        {:ok, %{state | file_version: version}}
+
+      {:error, reason} ->
+        Logger.warning(
+          "DeviceGeoEnrichment: failed to load GeoIP database #{state.path}: #{inspect(reason)}"
+        )
+
+        {:error, reason}
+    end
+  end
+
+  defp file_version(path) do
+    case File.stat(path, time: :posix) do
+      {:ok, %File.Stat{mtime: mtime, size: size}} -> {mtime, size}
+      {:error, _} -> nil
+    end
+  end
+
+  defp schedule_check(state) do
+    Process.send_after(self(), :check_database, state.reload_check_ms)
+  end
+
+  defp load_mmdb(path) do
+    with {:ok, contents} <- File.read(path),
+         {:ok, meta, tree, data} <- MMDB2Decoder.parse_database(contents) do
+      {:ok, fn ip -> MMDB2Decoder.lookup(ip, meta, tree, data) end}
+    end
+  end
+
+  defp loaded_reader(server) do
+    case :persistent_term.get({__MODULE__, server, :reader}, nil) do
+      nil -> :skip
+      reader -> {:ok, reader}
+    end
+  end
+
+  defp public_ip(ip) do
+    case :inet.parse_address(String.to_charlist(String.trim(ip))) do
+      {:ok, tuple} -> if GeoIP.private_ip?(tuple), do: :skip, else: {:ok, tuple}
+      {:error, _} -> :skip
+    end
+  end
+
+  defp cached(server, ip, resolve) do
+    {ttl_ms, max_entries} = :persistent_term.get({__MODULE__, server, :cache})
+    now = System.monotonic_time(:millisecond)
+
+    case :ets.lookup(server, ip) do
+      [{^ip, result, cached_at}] when now - cached_at < ttl_ms ->
+        result
+
+      _ ->
+        result = resolve.()
+
+        if :ets.info(server, :size) >= max_entries do
+          :ets.delete_all_objects(server)
+        end
+
+        :ets.insert(server, {ip, result, now})
+        result
+    end
+  end
+
+  defp resolve(reader, tuple) do
+    case reader.(tuple) do
+      {:ok, %{} = record} ->
+        geo = GeoIP.normalize_record(record)
+        if located?(geo), do: {:ok, geo}, else: :skip
+
+      _ ->
+        :skip
+    end
+  rescue
+    _ -> :skip
+  end
+
+  defp located?(geo) do
+    Enum.any?(@metadata_keys, fn {field, _key} -> Map.has_key?(geo, field) end)
+  end
+end
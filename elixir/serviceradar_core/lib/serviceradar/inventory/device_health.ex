@@ -0,0 +1,337 @@
+defmodule ServiceRadar.Inventory.DeviceHealth do
+  @moduledoc """
+  Rolls the health of child devices (the VMs on a host, the line cards in a
+  chassis) up to their parents over `ServiceRadar.Inventory.DeviceRelationships`.
+
+  A device's own health is `healthy` when it is available and `unhealthy`
+  otherwise. Its rolled-up health is the worse of its own and its children's
+  rolled-up health combined by the policy:
+
+    * `worst_child` (default) - the worst state of any child
+    * `percentage` - `unhealthy` when at least `unhealthy_percent` of the
+      children are not healthy, `degraded` when at least `degraded_percent`
+
+  `ServiceRadar.Inventory.SyncIngestor` and the sweep processor call
+  `devices_changed/2` when devices are written or change availability; the
+  rollup of each affected parent is stored in the `health_rollup`,
+  `health_rollup_mode`, `health_rollup_children` and
+  `health_rollup_updated_at` metadata of `platform.ocsf_devices`, which the
+  SRQL `health_rollup` field reads (devices without children fall back to
+  their own availability).
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Inventory.DeviceHealth,
+        enabled: true,
+        policy: %{
+          mode: "percentage",
+          degraded_percent: 25,
+          unhealthy_percent: 50,
+          depth: 3,
+          types: ["runs_on", "part_of"]
+        }
+  """
+
+  alias ServiceRadar.Inventory.DeviceRelationships
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @states ~w(healthy degraded unhealthy)
+  @modes ~w(worst_child percentage)
+  @default_degraded_percent 25
+  @default_unhealthy_percent 50
+  @default_depth 3
+
+  @availability_sql """
+  SELECT uid, coalesce(is_available, false)
+  FROM platform.ocsf_devices
+  WHERE uid = ANY($1)
+  """
+
+  @merge_metadata_sql """
+  UPDATE platform.ocsf_devices
+  SET metadata = coalesce(metadata, '{}'::jsonb) || $2::jsonb
+  WHERE uid = $1
+  """
+
+  @type state :: String.t()
+
+  @type policy :: %{
+          mode: String.t(),
+          degraded_percent: number(),
+          unhealthy_percent: number(),
+          depth: pos_integer(),
+          types: [String.t()]
+        }
+
+  @type rollup :: %{
+          device_id: String.t(),
+          mode: String.t(),
+          own: state(),
+          state: state(),
+          children: %{state() => non_neg_integer()}
+        }
+
+  @doc "Whether health rollups are enabled with a valid policy."
+  @spec enabled?() :: boolean()
+  def enabled? do
+    config(:enabled, false) == true and match?({:ok, _}, policy())
+  end
+
+  @doc "The configured policy, validated."
+  @spec policy() :: {:ok, policy()} | {:error, String.t()}
+  def policy, do: validate_policy(config(:policy, %{}))
+
+  @doc "Fills the policy defaults and validates it."
+  @spec validate_policy(map() | keyword()) :: {:ok, policy()} | {:error, String.t()}
+  def validate_policy(policy) do
+    policy = Map.new(policy)
+    mode = policy |> field(:mode) |> Kernel.||("worst_child") |> to_string() |> String.downcase()
+    degraded = field(policy, :degraded_percent) || @default_degraded_percent
+    unhealthy = field(policy, :unhealthy_percent) || @default_unhealthy_percent
+    depth = field(policy, :depth) || @default_depth
+
+    cond do
+      mode not in @modes ->
+        {:error, "unknown health rollup mode #{inspect(mode)}"}
+
+      not (is_number(degraded) and is_number(unhealthy) and degraded > 0 and
+               degraded <= unhealthy and unhealthy <= 100) ->
+        {:error,
+         "need 0 < degraded_percent (#{inspect(degraded)}) <= " <>
+           "unhealthy_percent (#{inspect(unhealthy)}) <= 100"}
+
+      not (is_integer(depth) and depth in 1..DeviceRelationships.max_depth()) ->
+        {:error, "depth must be 1-#{DeviceRelationships.max_depth()}"}
+
+      true ->
+        with {:ok, types} <- validate_types(List.wrap(field(policy, :types))) do
+          {:ok,
+           %{
+             mode: mode,
+             degraded_percent: degraded,
+             unhealthy_percent: unhealthy,
+             depth: depth,
+             types: types
+           }}
+        end
+    end
+  end
+
+  @doc "Combines children's states under `policy`; no children is healthy."
+  @spec combine(policy(), [state()]) :: state()
+  def combine(_policy, []), do: "healthy"
+
+  def combine(%{mode: "worst_child"}, states), do: Enum.reduce(states, "healthy", &worse/2)
+
+  def combine(%{mode: "percentage"} = policy, states) do
+    impaired = Enum.count(states, &(&1 != "healthy"))
+    percent = 100 * impaired / length(states)
+
+    cond do
+      percent >= policy.unhealthy_percent -> "unhealthy"
+      percent >= policy.degraded_percent -> "degraded"
+      true -> "healthy"
+    end
+  end
+
+  @doc """
+  Computes a device's rolled-up health. Children's states are themselves
+  rolled up from their own children, down to the policy depth; children
+  that are not stored devices are ignored.
+
+  Options:
+    - `:policy` - policy to apply (default configured)
+    - `:load` - passed to `DeviceRelationships.traverse/2`
+    - `:availability` - `fn device_ids -> {:ok, %{uid => boolean}} end`
+      used instead of reading `ocsf_devices`
+  """
+  @spec rollup(String.t(), keyword()) :: {:ok, rollup()} | {:error, term()}
+  def rollup(device_id, opts \\ []) do
+    with {:ok, policy} <- opts |> Keyword.get_lazy(:policy, &policy/0) |> ensure_policy(),
+         {:ok, graph} <-
+           DeviceRelationships.traverse(
+             device_id,
+             [direction: "incoming", depth: policy.depth, types: policy.types] ++
+               Keyword.take(opts, [:load])
+           ),
+         {:ok, availability} <- load_availability(graph.devices, opts),
+         {:ok, available} <- fetch_availability(availability, graph.device_id) do
+      children = children_of(graph.relationships, availability)
+      eval = %{policy: policy, children: children, availability: availability}
+
+      states =
+        children
+        |> Map.get(graph.device_id, [])
+        |> Enum.map(&state(eval, &1, MapSet.new([graph.device_id])))
+
+      own = own_state(available)
+
+      {:ok,
+       %{
+         device_id: graph.device_id,
+         mode: policy.mode,
+         own: own,
+         state: worse(own, combine(policy, states)),
+         children: Enum.frequencies(states)
+       }}
+    end
+  end
+
+  @doc """
+  Recomputes and stores the rollup of every device whose health depends on
+  `device_ids`: the devices themselves and their ancestors up to the policy
+  depth. Only devices with children are stored. Returns the stored rollups.
+
+  Options as for `rollup/2`, plus `:store` -
+  `fn device_id, metadata -> :ok | {:error, reason} end` used instead of
+  writing `ocsf_devices`.
+  """
+  @spec devices_changed([String.t()], keyword()) :: {:ok, [rollup()]} | {:error, term()}
+  def devices_changed(device_ids, opts \\ []) do
+    with {:ok, policy} <- opts |> Keyword.get_lazy(:policy, &policy/0) |> ensure_policy(),
+         {:ok, affected} <- ancestors(device_ids, policy, opts) do
+      opts = Keyword.put(opts, :policy, policy)
+      store = Keyword.get(opts, :store, &store_rollup/2)
+      now = DateTime.to_iso8601(DateTime.utc_now())
+
+      rollups =
+        Enum.flat_map(affected, fn device_id ->
+          with {:ok, rollup} <- rollup(device_id, opts),
+               children when children > 0 <- rollup.children |> Map.values() |> Enum.sum(),
+               :ok <- store.(device_id, rollup_metadata(rollup, children, now)) do
+            [rollup]
+          else
+            {:error, :not_found} ->
+              []
+
+            {:error, reason} ->
+              Logger.warning("Device health rollup failed for #{device_id}: #{inspect(reason)}")
+              []
+
+            _no_children ->
+              []
+          end
+        end)
+
+      {:ok, rollups}
+    end
+  end
+
+  defp ancestors(device_ids, policy, opts) do
+    device_ids
+    |> Enum.filter(&(is_binary(&1) and String.trim(&1) != ""))
+    |> Enum.uniq()
+    |> Enum.reduce_while({:ok, MapSet.new()}, fn device_id, {:ok, acc} ->
+      case DeviceRelationships.traverse(
+             device_id,
+             [direction: "outgoing", depth: policy.depth, types: policy.types] ++
+               Keyword.take(opts, [:load])
+           ) do
+        {:ok, graph} -> {:cont, {:ok, Enum.into(graph.devices, acc)}}
+        {:error, reason} -> {:halt, {:error, reason}}
+      end
+    end)
+    |> case do
+      {:ok, affected} -> {:ok, Enum.sort(affected)}
+      error -> error
+    end
+  end
+
+  # A device reached again through a cycle contributes only its own state.
+  defp state(eval, device_id, visiting) do
+    own = own_state(Map.get(eval.availability, device_id, false))
+
+    if MapSet.member?(visiting, device_id) do
+      own
+    else
+      visiting = MapSet.put(visiting, device_id)
+
+      states =
+        eval.children
+        |> Map.get(device_id, [])
+        |> Enum.map(&state(eval, &1, visiting))
+
+      worse(own, combine(eval.policy, states))
+    end
+  end
+
+  defp children_of(relationships, availability) do
+    relationships
+    |> Enum.filter(fn edge ->
+      Map.has_key?(availability, edge.source_device_id) and
+        edge.source_device_id != edge.target_device_id
+    end)
+    |> Enum.group_by(& &1.target_device_id, & &1.source_device_id)
+    |> Map.new(fn {parent, children} -> {parent, children |> Enum.uniq() |> Enum.sort()} end)
+  end
+
+  defp load_availability(device_ids, opts) do
+    case Keyword.get(opts, :availability) do
+      nil -> read_availability(device_ids)
+      load -> load.(device_ids)
+    end
+  end
+
+  defp read_availability(device_ids) do
+    case Repo.query(@availability_sql, [device_ids]) do
+      {:ok, %{rows: rows}} -> {:ok, Map.new(rows, fn [uid, available] -> {uid, available} end)}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp fetch_availability(availability, device_id) do
+    case Map.fetch(availability, device_id) do
+      {:ok, available} -> {:ok, available}
+      :error -> {:error, :not_found}
+    end
+  end
+
+  defp store_rollup(device_id, metadata) do
+    case Repo.query(@merge_metadata_sql, [device_id, metadata]) do
+      {:ok, %{num_rows: 1}} -> :ok
+      {:ok, _} -> {:error, :not_found}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp rollup_metadata(rollup, children, now) do
+    %{
+      "health_rollup" => rollup.state,
+      "health_rollup_mode" => rollup.mode,
+      "health_rollup_children" => Integer.to_string(children),
+      "health_rollup_updated_at" => now
+    }
+  end
+
+  defp ensure_policy({:ok, policy}), do: {:ok, policy}
+  defp ensure_policy({:error, reason}), do: {:error, reason}
+  defp ensure_policy(policy), do: validate_policy(policy)
+
+  defp own_state(true), do: "healthy"
+  defp own_state(_available), do: "unhealthy"
+
+  defp worse(a, b) do
+    if Enum.find_index(@states, &(&1 == b)) > Enum.find_index(@states, &(&1 == a)),
+      do: b,
+      else: a
+  end
+
+  defp validate_types(types) do
+    Enum.reduce_while(types, {:ok, []}, fn type, {:ok, acc} ->
+      case DeviceRelationships.parse_type(type) do
+        {:ok, type} -> {:cont, {:ok, acc ++ [type]}}
+        {:error, reason} -> {:halt, {:error, reason}}
+      end
+    end)
+  end
+
+  defp field(map, key), do: Map.get(map, key) || Map.get(map, Atom.to_string(key))
+
+  defp config(key, default) do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
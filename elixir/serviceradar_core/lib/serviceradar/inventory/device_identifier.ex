@@ -14,6 +14,10 @@ defmodule ServiceRadar.Inventory.DeviceIdentifier do
   - `netbox_device_id` - NetBox device ID
   - `mac` - MAC address (normalized uppercase, no separators)
 
+  Operator-defined strong identifiers (see
+  `ServiceRadar.Inventory.CustomIdentifierTypes`) rank between `netbox_device_id`
+  and `mac`.
+
   Weak identifier:
   - `ip` - IP address (only used when no strong identifiers present)
 
@@ -29,7 +33,6 @@ defmodule ServiceRadar.Inventory.DeviceIdentifier do
     data_layer: AshPostgres.DataLayer,
     authorizers: [Ash.Policy.Authorizer]
 
-  @identifier_types [:agent_id, :armis_device_id, :integration_id, :netbox_device_id, :mac, :ip]
   @confidence_levels [:strong, :medium, :weak]
   @register_fields [
     :device_id,
@@ -111,6 +114,8 @@ defmodule ServiceRadar.Inventory.DeviceIdentifier do
 
       accept @register_fields
 
+      validate ServiceRadar.Inventory.Validations.IdentifierType
+
       change fn changeset, _context ->
         now = DateTime.utc_now()
 
@@ -146,6 +151,8 @@ defmodule ServiceRadar.Inventory.DeviceIdentifier do
       upsert_identity :unique_identifier
       upsert_fields [:device_id, :last_seen, :confidence, :source, :verified, :metadata]
 
+      validate ServiceRadar.Inventory.Validations.IdentifierType
+
       change fn changeset, _context ->
         now = DateTime.utc_now()
 
@@ -176,8 +183,7 @@ defmodule ServiceRadar.Inventory.DeviceIdentifier do
     attribute :identifier_type, :atom do
       allow_nil? false
       public? true
-      constraints one_of: @identifier_types
-      description "Type of identifier (armis_device_id, mac, netbox_device_id, etc.)"
+      description "Type of identifier (armis_device_id, mac, netbox_device_id, or a custom type)"
     end
 
     attribute :identifier_value, :string do
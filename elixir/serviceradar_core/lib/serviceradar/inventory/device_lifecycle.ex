@@ -0,0 +1,455 @@
+defmodule ServiceRadar.Inventory.DeviceLifecycle do
+  @moduledoc """
+  Explicit device lifecycle (discovered → active → stale → decommissioned)
+  with enforced transitions.
+
+  The state is kept in the `lifecycle_state` metadata of
+  `platform.ocsf_devices`, next to `lifecycle_changed_at`,
+  `lifecycle_changed_by` and `lifecycle_reason`; the SRQL
+  `lifecycle_state` field reads it, and devices recorded before lifecycle
+  tracking count as `active`.
+
+    * `ServiceRadar.Inventory.SyncIngestor` stamps the state onto each device
+      it writes (`stamp/2`): new devices start as `discovered`, discovered
+      and stale devices become `active` once seen available, and
+      decommissioned devices stay decommissioned whatever the source reports.
+    * `ServiceRadar.Inventory.DeviceLifecycleWorker` marks discovered and
+      active devices `stale` when they have not been seen for `stale_after`
+      (`reconcile_stale/1`).
+    * Operators move devices with `transition/3`; only a manual transition
+      may reactivate a decommissioned device.
+
+  Every transition is recorded as a `device.lifecycle` OCSF event.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Inventory.DeviceLifecycle,
+        enabled: true,
+        stale_after: to_timeout(hour: 24)
+
+  Manual transitions work whether or not `enabled` is set; it only controls
+  ingestion and stale reconciliation.
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Events.PubSub, as: EventsPubSub
+  alias ServiceRadar.Monitoring.OcsfEvent
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @default_state "active"
+  @default_stale_after to_timeout(hour: 24)
+  @system_actor "system"
+  @log_name "device.lifecycle"
+  @provider "serviceradar.inventory"
+
+  # Discovered is only ever an initial state, and leaving decommissioned is
+  # reserved for manual reactivation.
+  @transitions %{
+    "discovered" => ~w(active stale decommissioned),
+    "active" => ~w(stale decommissioned),
+    "stale" => ~w(active decommissioned),
+    "decommissioned" => ~w(active)
+  }
+
+  @states_sql """
+  SELECT uid, coalesce(metadata->>'lifecycle_state', '')
+  FROM platform.ocsf_devices
+  WHERE uid = ANY($1)
+  """
+
+  @merge_metadata_sql """
+  UPDATE platform.ocsf_devices
+  SET metadata = coalesce(metadata, '{}'::jsonb) || $2::jsonb
+  WHERE uid = $1
+  """
+
+  @mark_stale_sql """
+  WITH candidates AS (
+    SELECT uid, coalesce(metadata->>'lifecycle_state', 'active') AS from_state, last_seen_time
+    FROM platform.ocsf_devices
+    WHERE deleted_at IS NULL
+      AND last_seen_time < $1
+      AND coalesce(metadata->>'lifecycle_state', 'active') IN ('discovered', 'active')
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+  )
+  UPDATE platform.ocsf_devices d
+  SET metadata = coalesce(d.metadata, '{}'::jsonb) || jsonb_build_object(
+    'lifecycle_state', 'stale',
+    'lifecycle_changed_at', $2::text,
+    'lifecycle_changed_by', 'system',
+    'lifecycle_reason',
+    'not seen since ' || to_char(c.last_seen_time, 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+  )
+  FROM candidates c
+  WHERE d.uid = c.uid
+  RETURNING d.uid, c.from_state, c.last_seen_time
+  """
+
+  @stale_batch_size 1_000
+
+  @type transition :: %{
+          device_id: String.t(),
+          from: String.t() | nil,
+          to: String.t(),
+          manual: boolean(),
+          actor: String.t(),
+          reason: String.t() | nil,
+          timestamp: DateTime.t()
+        }
+
+  @doc "Whether ingestion and stale reconciliation track lifecycle state."
+  @spec enabled?() :: boolean()
+  def enabled?, do: config(:enabled, false) == true
+
+  @doc "How long a device may go unseen before it is marked stale, in ms."
+  @spec stale_after() :: pos_integer()
+  def stale_after do
+    case config(:stale_after, @default_stale_after) do
+      value when is_integer(value) and value > 0 -> value
+      _ -> @default_stale_after
+    end
+  end
+
+  @doc "The lifecycle states."
+  @spec states() :: [String.t()]
+  def states, do: ~w(discovered active stale decommissioned)
+
+  @doc "Validates and normalizes a state name."
+  @spec parse_state(term()) :: {:ok, String.t()} | {:error, String.t()}
+  def parse_state(state) when is_binary(state) do
+    state = state |> String.trim() |> String.downcase()
+
+    if Map.has_key?(@transitions, state),
+      do: {:ok, state},
+      else: {:error, "invalid lifecycle state #{inspect(state)}"}
+  end
+
+  def parse_state(state), do: {:error, "invalid lifecycle state #{inspect(state)}"}
+
+  @doc "The state recorded in device metadata, `active` when missing or unknown."
+  @spec state_from_metadata(map() | nil) :: String.t()
+  def state_from_metadata(metadata) do
+    case parse_state(Map.get(metadata || %{}, "lifecycle_state")) do
+      {:ok, state} -> state
+      {:error, _} -> @default_state
+    end
+  end
+
+  @doc """
+  Checks `from` may move to `to`. Manual transitions are those requested by
+  an operator; only they may reactivate a decommissioned device.
+  """
+  @spec validate_transition(String.t(), String.t(), boolean()) ::
+          :ok | {:error, {:conflict, String.t()}}
+  def validate_transition(state, state, _manual?),
+    do: {:error, {:conflict, "device already #{state}"}}
+
+  def validate_transition("decommissioned", _to, false),
+    do: {:error, {:conflict, "decommissioned devices require explicit reactivation"}}
+
+  def validate_transition(from, to, _manual?) do
+    if to in Map.get(@transitions, from, []),
+      do: :ok,
+      else: {:error, {:conflict, "invalid lifecycle transition #{from} -> #{to}"}}
+  end
+
+  @doc """
+  The state a device moves to when ingestion sees it. `current` is nil for
+  devices that have never been stored.
+  """
+  @spec observe(String.t() | nil, boolean()) :: String.t()
+  def observe(nil, _available?), do: "discovered"
+  def observe(current, false), do: current
+  def observe(current, _available?) when current in ["active", "decommissioned"], do: current
+  def observe(_current, true), do: "active"
+
+  @doc """
+  Stamps the lifecycle state onto device records (maps with `uid`,
+  `is_available` and `metadata`) before they are written. Returns the
+  stamped records and the transitions they make; a transition also stamps
+  `lifecycle_changed_at` and `lifecycle_changed_by`.
+
+  Options:
+    - `:load` - `fn uids -> {:ok, %{uid => state}} end` used instead of
+      reading `ocsf_devices` (states of devices without one are `""`)
+    - `:now` - transition time (default now)
+  """
+  @spec stamp([map()], keyword()) :: {:ok, [map()], [transition()]} | {:error, term()}
+  def stamp(records, opts \\ []) do
+    load = Keyword.get(opts, :load, &load_states/1)
+    now = Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+
+    with {:ok, stored} <- records |> Enum.map(& &1.uid) |> Enum.uniq() |> load.() do
+      {records, transitions} =
+        Enum.map_reduce(records, [], fn record, transitions ->
+          current = stored |> Map.get(record.uid) |> stored_state()
+          next = observe(current, record.is_available == true)
+          metadata = Map.put(record.metadata || %{}, "lifecycle_state", next)
+
+          if current == next do
+            {%{record | metadata: metadata}, transitions}
+          else
+            transition = system_transition(record.uid, current, next, nil, now)
+            metadata = Map.merge(metadata, changed_metadata(transition))
+            {%{record | metadata: metadata}, [transition | transitions]}
+          end
+        end)
+
+      {:ok, records, Enum.reverse(transitions)}
+    end
+  end
+
+  @doc """
+  Marks discovered and active devices not seen for `stale_after` stale and
+  records their transitions.
+
+  Options: `:stale_after` (ms, default configured), `:now`, `:record_event`.
+  """
+  @spec reconcile_stale(keyword()) :: {:ok, [transition()]} | {:error, term()}
+  def reconcile_stale(opts \\ []) do
+    now = Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+    stale_after = Keyword.get_lazy(opts, :stale_after, &stale_after/0)
+    # last_seen_time is a timestamp without time zone.
+    cutoff = now |> DateTime.add(-stale_after, :millisecond) |> DateTime.to_naive()
+
+    case mark_stale(cutoff, now, []) do
+      {:ok, transitions} ->
+        record(transitions, opts)
+        {:ok, transitions}
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  @doc """
+  Applies an operator-requested state change and records it.
+
+  Options:
+    - `:actor` - actor making the change (required)
+    - `:reason` - why the device moves
+    - `:load`, `:store` - `fn uids -> {:ok, %{uid => state}} end` and
+      `fn uid, metadata -> :ok | {:error, reason} end` used instead of
+      `ocsf_devices`
+    - `:record_event` - 2-arity function used instead of creating the event
+  """
+  @spec transition(String.t(), String.t(), keyword()) :: {:ok, transition()} | {:error, term()}
+  def transition(device_id, state, opts) do
+    load = Keyword.get(opts, :load, &load_states/1)
+    store = Keyword.get(opts, :store, &store_metadata/2)
+
+    with {:ok, actor} <- actor_name(Keyword.get(opts, :actor)),
+         {:ok, to} <- parse_state(state),
+         {:ok, stored} <- load.([device_id]),
+         {:ok, raw} <- fetch_state(stored, device_id),
+         from = stored_state(raw) || @default_state,
+         :ok <- validate_transition(from, to, true) do
+      transition = %{
+        device_id: device_id,
+        from: from,
+        to: to,
+        manual: true,
+        actor: actor,
+        reason: blank_to_nil(Keyword.get(opts, :reason)),
+        timestamp: Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+      }
+
+      with :ok <- store.(device_id, transition_metadata(transition)) do
+        record([transition], opts)
+        {:ok, transition}
+      end
+    end
+  end
+
+  @doc "Records transitions as `device.lifecycle` OCSF events."
+  @spec record([transition()], keyword()) :: :ok
+  def record(transitions, opts \\ []) do
+    actor = SystemActor.system(:device_lifecycle)
+    record_event = Keyword.get(opts, :record_event, &create_event/2)
+
+    Enum.each(transitions, fn transition ->
+      case record_event.(event_attrs(transition), actor) do
+        {:ok, event} ->
+          _ = EventsPubSub.broadcast_event(event)
+
+        {:error, reason} ->
+          Logger.warning(
+            "Failed to record lifecycle event for #{transition.device_id}: #{inspect(reason)}"
+          )
+      end
+    end)
+  rescue
+    error ->
+      Logger.warning("Failed to record device lifecycle events: #{inspect(error)}")
+      :ok
+  end
+
+  defp mark_stale(cutoff, now, acc) do
+    case Repo.query(@mark_stale_sql, [cutoff, DateTime.to_iso8601(now), @stale_batch_size]) do
+      {:ok, %{rows: rows}} ->
+        transitions =
+          Enum.map(rows, fn [uid, from, last_seen] ->
+            reason = "not seen since #{DateTime.to_iso8601(to_datetime(last_seen))}"
+            system_transition(uid, from, "stale", reason, now)
+          end)
+
+        if length(rows) == @stale_batch_size,
+          do: mark_stale(cutoff, now, acc ++ transitions),
+          else: {:ok, acc ++ transitions}
+
+      {:error, reason} ->
+        if acc == [], do: {:error, reason}, else: {:ok, acc}
+    end
+  end
+
+  defp system_transition(device_id, from, to, reason, now) do
+    %{
+      device_id: device_id,
+      from: from,
+      to: to,
+      manual: false,
+      actor: @system_actor,
+      reason: reason,
+      timestamp: now
+    }
+  end
+
+  defp changed_metadata(transition) do
+    %{
+      "lifecycle_changed_at" => DateTime.to_iso8601(transition.timestamp),
+      "lifecycle_changed_by" => transition.actor
+    }
+  end
+
+  defp transition_metadata(transition) do
+    metadata =
+      transition
+      |> changed_metadata()
+      |> Map.put("lifecycle_state", transition.to)
+
+    if transition.reason,
+      do: Map.put(metadata, "lifecycle_reason", transition.reason),
+      else: metadata
+  end
+
+  # nil for devices that were never stored; stored devices without a state
+  # are active.
+  defp stored_state(nil), do: nil
+  defp stored_state(raw), do: state_from_metadata(%{"lifecycle_state" => raw})
+
+  defp fetch_state(stored, device_id) do
+    case Map.fetch(stored, device_id) do
+      {:ok, raw} -> {:ok, raw}
+      :error -> {:error, :not_found}
+    end
+  end
+
+  defp load_states(uids) do
+    case Repo.query(@states_sql, [uids]) do
+      {:ok, %{rows: rows}} -> {:ok, Map.new(rows, fn [uid, state] -> {uid, state} end)}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp store_metadata(device_id, metadata) do
+    case Repo.query(@merge_metadata_sql, [device_id, metadata]) do
+      {:ok, %{num_rows: 1}} -> :ok
+      {:ok, _} -> {:error, :not_found}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp actor_name(actor) when is_binary(actor) do
+    case String.trim(actor) do
+      "" -> {:error, "manual lifecycle transitions require an actor"}
+      actor -> {:ok, actor}
+    end
+  end
+
+  defp actor_name(actor) when is_map(actor) do
+    case Map.get(actor, :email) || Map.get(actor, :id) do
+      nil -> {:error, "manual lifecycle transitions require an actor"}
+      name -> {:ok, to_string(name)}
+    end
+  end
+
+  defp actor_name(_actor), do: {:error, "manual lifecycle transitions require an actor"}
+
+  defp event_attrs(transition) do
+    metadata = %{
+      "device_id" => transition.device_id,
+      "from_state" => transition.from,
+      "to_state" => transition.to,
+      "manual" => transition.manual,
+      "actor" => transition.actor,
+      "reason" => transition.reason
+    }
+
+    %{
+      time: DateTime.truncate(transition.timestamp, :microsecond),
+      class_uid: 1008,
+      category_uid: 1,
+      type_uid: 100_803,
+      activity_id: 3,
+      activity_name: "Lifecycle",
+      severity_id: 1,
+      severity: "Informational",
+      message: event_message(transition),
+      status_id: 1,
+      status: "Success",
+      status_code: "device_#{transition.to}",
+      metadata: metadata,
+      observables: [
+        %{"name" => "Device UID", "type" => "string", "value" => transition.device_id}
+      ],
+      actor: %{"app_name" => "serviceradar.core", "user" => transition.actor},
+      device: %{"uid" => transition.device_id},
+      src_endpoint: %{},
+      dst_endpoint: %{},
+      log_name: @log_name,
+      log_provider: @provider,
+      log_level: "info",
+      log_version: "device_lifecycle.v1",
+      unmapped: %{"device_lifecycle" => metadata},
+      raw_data: Jason.encode!(metadata)
+    }
+  end
+
+  defp event_message(%{from: nil} = transition),
+    do: with_reason("Device #{transition.device_id} #{transition.to}", transition.reason)
+
+  defp event_message(transition) do
+    with_reason(
+      "Device #{transition.device_id} #{transition.from} -> #{transition.to}",
+      transition.reason
+    )
+  end
+
+  defp with_reason(message, reason) when is_binary(reason), do: "#{message}: #{reason}"
+  defp with_reason(message, _reason), do: message
+
+  defp to_datetime(%DateTime{} = value), do: value
+  defp to_datetime(%NaiveDateTime{} = value), do: DateTime.from_naive!(value, "Etc/UTC")
+
+  defp blank_to_nil(value) when is_binary(value) do
+    case String.trim(value) do
+      "" -> nil
+      value -> value
+    end
+  end
+
+  defp blank_to_nil(_value), do: nil
+
+  defp create_event(attrs, actor) do
+    Ash.create(OcsfEvent, attrs, action: :record, actor: actor, domain: ServiceRadar.Monitoring)
+  end
+
+  defp config(key, default) do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
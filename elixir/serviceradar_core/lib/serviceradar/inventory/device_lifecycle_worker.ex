@@ -0,0 +1,37 @@
+defmodule ServiceRadar.Inventory.DeviceLifecycleWorker do
+  @moduledoc """
+  Oban cron worker that marks devices not seen for the configured
+  `stale_after` as stale through
+  `ServiceRadar.Inventory.DeviceLifecycle.reconcile_stale/1`. Does nothing
+  unless lifecycle tracking is enabled.
+  """
+
+  use Oban.Worker,
+    queue: :maintenance,
+    max_attempts: 1,
+    unique: [period: 300, states: [:available, :scheduled, :executing, :retryable]]
+
+  alias ServiceRadar.Inventory.DeviceLifecycle
+
+  require Logger
+
+  @impl Oban.Worker
+  def perform(%Oban.Job{}) do
+    if DeviceLifecycle.enabled?() do
+      case DeviceLifecycle.reconcile_stale() do
+        {:ok, []} ->
+          :ok
+
+        {:ok, transitions} ->
+          Logger.info("DeviceLifecycleWorker: #{length(transitions)} device(s) marked stale")
+          :ok
+
+        {:error, reason} ->
+          Logger.warning("DeviceLifecycleWorker: stale reconciliation failed: #{inspect(reason)}")
+          {:error, reason}
+      end
+    else
+      :ok
+    end
+  end
+end
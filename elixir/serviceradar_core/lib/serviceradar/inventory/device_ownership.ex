@@ -0,0 +1,189 @@
+defmodule ServiceRadar.Inventory.DeviceOwnership do
+  @moduledoc """
+  Assigns devices to the user or team accountable for them.
+
+  Ownership lives on `ServiceRadar.Inventory.Device` (`owner_uid`,
+  `owner_type`) and is queryable through SRQL (`in:devices owner:team-netops`).
+  Every change is written to the audit log with the previous and new owner.
+
+  ## Partition scope
+
+  When a `:partition` is given, only devices with an identifier in that
+  partition are assigned; the rest are reported as skipped. Devices in other
+  tenants are never visible to the actor in the first place (schema isolation),
+  so an assignment cannot cross tenants either way.
+
+  ## Usage
+
+      DeviceOwnership.assign(["sr:abc", "sr:def"], %{uid: "team-netops", type: :team},
+        actor: scope.user, partition: "default")
+
+      # Clear ownership
+      DeviceOwnership.assign("sr:abc", nil, actor: scope.user)
+  """
+
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Events.AuditWriter
+  alias ServiceRadar.Inventory.Device
+
+  require Ash.Query
+
+  @max_devices 1000
+  @owner_types %{"user" => :user, "team" => :team}
+
+  @type owner :: %{uid: String.t(), type: :user | :team} | nil
+
+  @type result :: %{
+          assigned: [String.t()],
+          unchanged: [String.t()],
+          skipped: [String.t()]
+        }
+
+  @doc """
+  Assigns (or, with a `nil` owner, clears) the owner of one or more devices.
+
+  Returns the device UIDs that were assigned, already had that owner, or were
+  skipped because they do not exist or fall outside the requested partition.
+
+  Options:
+    - `:actor` - actor performing the assignment (required)
+    - `:partition` - only assign devices with an identifier in this partition
+  """
+  @spec assign([String.t()] | String.t(), owner() | map(), keyword()) ::
+          {:ok, result()} | {:error, term()}
+  def assign(device_uids, owner, opts \\ [])
+
+  def assign(device_uid, owner, opts) when is_binary(device_uid),
+    do: assign([device_uid], owner, opts)
+
+  def assign(device_uids, owner, opts) when is_list(device_uids) do
+    actor = Keyword.get(opts, :actor)
+
+    with {:ok, uids} <- normalize_uids(device_uids),
+         {:ok, owner} <- normalize_owner(owner),
+         {:ok, devices} <- read_devices(uids, Keyword.get(opts, :partition), actor) do
+      found = MapSet.new(devices, & &1.uid)
+      skipped = Enum.reject(uids, &MapSet.member?(found, &1))
+
+      {changed, unchanged} = Enum.split_with(devices, &owner_changed?(&1, owner))
+
+      case update_devices(changed, owner, actor, Keyword.get(opts, :partition)) do
+        :ok ->
+          {:ok,
+           %{
+             assigned: Enum.map(changed, & &1.uid),
+             unchanged: Enum.map(unchanged, & &1.uid),
+             skipped: skipped
+           }}
+
+        {:error, reason} ->
+          {:error, reason}
+      end
+    end
+  end
+
+  def assign(_device_uids, _owner, _opts), do: {:error, :invalid_device_uids}
+
+  @doc """
+  Normalizes an owner given as a map with `uid`/`type` keys (atom or string).
+  `nil` clears ownership.
+  """
+  @spec normalize_owner(term()) :: {:ok, owner()} | {:error, :invalid_owner}
+  def normalize_owner(nil), do: {:ok, nil}
+
+  def normalize_owner(owner) when is_map(owner) do
+    uid = Map.get(owner, :uid) || Map.get(owner, "uid")
+    type = Map.get(owner, :type) || Map.get(owner, "type")
+
+    with uid when is_binary(uid) <- uid,
+         uid when uid != "" <- String.trim(uid),
+         {:ok, type} <- normalize_type(type) do
+      {:ok, %{uid: uid, type: type}}
+    else
+      _ -> {:error, :invalid_owner}
+    end
+  end
+
+  def normalize_owner(_owner), do: {:error, :invalid_owner}
+
+  defp normalize_type(type) when type in [:user, :team], do: {:ok, type}
+
+  defp normalize_type(type) when is_binary(type) do
+    case Map.fetch(@owner_types, type |> String.trim() |> String.downcase()) do
+      {:ok, type} -> {:ok, type}
+      :error -> {:error, :invalid_owner}
+    end
+  end
+
+  defp normalize_type(_type), do: {:error, :invalid_owner}
+
+  defp normalize_uids(device_uids) do
+    uids =
+      device_uids
+      |> Enum.filter(&is_binary/1)
+      |> Enum.map(&String.trim/1)
+      |> Enum.reject(&(&1 == ""))
+      |> Enum.uniq()
+
+    cond do
+      uids == [] -> {:error, :invalid_device_uids}
+      length(uids) > @max_devices -> {:error, {:too_many_devices, @max_devices}}
+      true -> {:ok, uids}
+    end
+  end
+
+  defp read_devices(uids, partition, actor) do
+    Device
+    |> Ash.Query.filter(uid in ^uids)
+    |> scope_to_partition(partition)
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+  end
+
+  defp scope_to_partition(query, partition) when is_binary(partition) and partition != "" do
+    Ash.Query.filter(query, exists(identifiers, partition == ^partition))
+  end
+
+  defp scope_to_partition(query, _partition), do: query
+
+  defp owner_changed?(device, nil), do: not is_nil(device.owner_uid)
+
+  defp owner_changed?(device, %{uid: uid, type: type}),
+    do: device.owner_uid != uid or device.owner_type != type
+
+  defp update_devices(devices, owner, actor, partition) do
+    attrs =
+      case owner do
+        nil -> %{owner_uid: nil, owner_type: nil}
+        %{uid: uid, type: type} -> %{owner_uid: uid, owner_type: type}
+      end
+
+    Enum.reduce_while(devices, :ok, fn device, :ok ->
+      case Device.assign_owner(device, attrs, actor: actor) do
+        {:ok, updated} ->
+          audit(device, updated, actor, partition)
+          {:cont, :ok}
+
+        {:error, reason} ->
+          {:halt, {:error, reason}}
+      end
+    end)
+  end
+
+  defp audit(previous, updated, actor, partition) do
+    AuditWriter.write_async(
+      action: if(is_nil(updated.owner_uid), do: :unassign_owner, else: :assign_owner),
+      resource_type: "device",
+      resource_id: updated.uid,
+      resource_name: updated.hostname || updated.ip,
+      actor: actor,
+      details: %{
+        previous_owner_uid: previous.owner_uid,
+        previous_owner_type: previous.owner_type,
+        owner_uid: updated.owner_uid,
+        owner_type: updated.owner_type,
+        partition: partition
+      }
+    )
+  end
+end
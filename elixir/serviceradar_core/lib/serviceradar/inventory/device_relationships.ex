@@ -0,0 +1,442 @@
+defmodule ServiceRadar.Inventory.DeviceRelationships do
+  @moduledoc """
+  Typed, non-network relationships between devices, read as
+  "source <type> target":
+
+    * `runs_on` - a workload (e.g. a VM) runs on a host
+    * `member_of` - a device belongs to a cluster, stack or group device
+    * `part_of` - a component (e.g. a line card) is part of a chassis
+
+  `ServiceRadar.Inventory.SyncIngestor` derives the edges from integration
+  metadata (see `rules/0`) each time a batch of updates is ingested and stores
+  them in `platform.device_relationships`. A metadata field holds either the
+  target's device UID or source identifier (matched against the device
+  identifiers of the update's partition), or the target's IP address. Targets
+  that do not resolve to a device are skipped.
+
+  Each edge replaces the edge previously derived for the same source device,
+  type, discovery source and metadata field, so a VM that migrates to another
+  host is re-pointed instead of gaining a second host.
+
+  `traverse/2` walks the stored edges breadth first; the topology-aware alert
+  filters use it to find the devices below an alerting or maintained device.
+  """
+
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @types ~w(runs_on member_of part_of)
+  @directions ~w(outgoing incoming both)
+  @default_depth 1
+  @max_depth 5
+
+  @rules [
+    %{metadata_key: "host_device_id", type: "runs_on", target: :device},
+    %{metadata_key: "host_ip", type: "runs_on", target: :ip},
+    %{metadata_key: "hypervisor_ip", type: "runs_on", target: :ip},
+    %{metadata_key: "parent_device_id", type: "member_of", target: :device},
+    %{metadata_key: "parent_ip", type: "member_of", target: :ip},
+    %{metadata_key: "chassis_device_id", type: "part_of", target: :device}
+  ]
+
+  @upsert_sql """
+  WITH incoming AS (
+    SELECT *
+    FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::timestamptz[])
+      AS t(source_device_id, target_device_id, relation_type, discovery_source,
+        metadata_key, seen)
+  ),
+  superseded AS (
+    DELETE FROM platform.device_relationships r
+    USING incoming i
+    WHERE r.source_device_id = i.source_device_id
+      AND r.relation_type = i.relation_type
+      AND r.discovery_source = i.discovery_source
+      AND r.metadata_key = i.metadata_key
+      AND r.target_device_id <> i.target_device_id
+  )
+  INSERT INTO platform.device_relationships (
+    source_device_id, target_device_id, relation_type, discovery_source, metadata_key,
+    first_seen, last_seen
+  )
+  SELECT source_device_id, target_device_id, relation_type, discovery_source, metadata_key,
+    seen, seen
+  FROM incoming
+  ON CONFLICT (source_device_id, target_device_id, relation_type) DO UPDATE SET
+    discovery_source = EXCLUDED.discovery_source,
+    metadata_key = EXCLUDED.metadata_key,
+    last_seen = GREATEST(platform.device_relationships.last_seen, EXCLUDED.last_seen)
+  """
+
+  @list_sql """
+  SELECT source_device_id, target_device_id, relation_type, discovery_source, metadata_key,
+    first_seen, last_seen
+  FROM platform.device_relationships
+  WHERE source_device_id = ANY($1) OR target_device_id = ANY($1)
+  ORDER BY source_device_id, relation_type, target_device_id
+  """
+
+  @resolve_ips_sql """
+  SELECT ip, uid
+  FROM platform.ocsf_devices
+  WHERE ip = ANY($1) AND deleted_at IS NULL
+  """
+
+  @resolve_ids_sql """
+  SELECT d.uid, d.uid, NULL
+  FROM platform.ocsf_devices d
+  WHERE d.uid = ANY($1) AND d.deleted_at IS NULL
+  UNION ALL
+  SELECT i.identifier_value, i.device_id, COALESCE(i.partition, 'default')
+  FROM platform.device_identifiers i
+  WHERE i.identifier_value = ANY($1)
+  """
+
+  @type relationship :: %{
+          source_device_id: String.t(),
+          target_device_id: String.t(),
+          relation_type: String.t(),
+          discovery_source: String.t(),
+          metadata_key: String.t(),
+          first_seen: DateTime.t() | nil,
+          last_seen: DateTime.t() | nil
+        }
+
+  @type graph :: %{
+          device_id: String.t(),
+          direction: String.t(),
+          depth: pos_integer(),
+          devices: [String.t()],
+          relationships: [relationship()]
+        }
+
+  @doc "Relationship type names."
+  @spec types() :: [String.t()]
+  def types, do: @types
+
+  @doc "Metadata fields edges are derived from."
+  @spec rules() :: [map()]
+  def rules, do: @rules
+
+  @doc "Maximum number of hops `traverse/2` follows."
+  @spec max_depth() :: pos_integer()
+  def max_depth, do: @max_depth
+
+  @doc "Validates and normalizes a relationship type name."
+  @spec parse_type(term()) :: {:ok, String.t()} | {:error, String.t()}
+  def parse_type(raw) when is_binary(raw) or is_atom(raw) do
+    type = raw |> to_string() |> String.trim() |> String.downcase()
+
+    if type in @types,
+      do: {:ok, type},
+      else: {:error, "invalid relationship type: #{inspect(raw)}"}
+  end
+
+  def parse_type(raw), do: {:error, "invalid relationship type: #{inspect(raw)}"}
+
+  @doc """
+  Extracts unresolved edges from `{update, device_id}` pairs, as produced by
+  the sync ingestor. Empty values and self references are ignored; when
+  several updates yield an edge for the same device, type and field, the
+  latest wins.
+  """
+  @spec derive([{map(), String.t()}]) :: [map()]
+  def derive(resolved_updates) do
+    resolved_updates
+    |> Enum.flat_map(fn {update, device_id} -> update_edges(update, device_id) end)
+    |> Enum.reduce({%{}, []}, fn edge, {latest, order} ->
+      key = {edge.source_device_id, edge.metadata_key, edge.relation_type, edge.discovery_source}
+
+      case Map.fetch(latest, key) do
+        {:ok, current} ->
+          if DateTime.compare(current.seen, edge.seen) == :gt,
+            do: {latest, order},
+            else: {Map.put(latest, key, edge), order}
+
+        :error ->
+          {Map.put(latest, key, edge), [key | order]}
+      end
+    end)
+    |> then(fn {latest, order} ->
+      order |> Enum.reverse() |> Enum.map(&Map.fetch!(latest, &1))
+    end)
+  end
+
+  @doc """
+  Derives the edges of ingested updates, resolves their targets and stores
+  them. Returns the stored edges.
+  """
+  @spec record([{map(), String.t()}]) :: {:ok, [map()]} | {:error, term()}
+  def record(resolved_updates) do
+    case derive(resolved_updates) do
+      [] ->
+        {:ok, []}
+
+      edges ->
+        edges
+        |> resolve_targets()
+        |> upsert()
+    end
+  end
+
+  @doc "Returns every stored edge in which any of the devices is the source or target."
+  @spec list([String.t()]) :: {:ok, [relationship()]} | {:error, term()}
+  def list([]), do: {:ok, []}
+
+  def list(device_ids) when is_list(device_ids) do
+    case Repo.query(@list_sql, [device_ids]) do
+      {:ok, %{rows: rows}} -> {:ok, Enum.map(rows, &row_to_relationship/1)}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  @doc """
+  Walks relationships breadth first from `device_id`. Each edge and device is
+  reported once, so cycles terminate.
+
+  Options:
+    - `:direction` - `"outgoing"` follows edges the device is the source of
+      (a VM to its host), `"incoming"` edges pointing at it (a host to its
+      VMs), `"both"` (default) either way
+    - `:depth` - hops to follow, 1 to #{@max_depth} (default #{@default_depth})
+    - `:types` - only follow these relationship types (default all)
+    - `:load` - `fn device_ids -> {:ok, edges} | {:error, reason} end` used
+      instead of `list/1`
+  """
+  @spec traverse(String.t(), keyword()) :: {:ok, graph()} | {:error, term()}
+  def traverse(device_id, opts \\ []) do
+    with {:ok, device_id} <- require_device_id(device_id),
+         {:ok, direction} <- parse_direction(Keyword.get(opts, :direction)),
+         {:ok, depth} <- parse_depth(Keyword.get(opts, :depth)),
+         {:ok, types} <- parse_types(Keyword.get(opts, :types, [])),
+         {:ok, {devices, edges}} <-
+           walk([device_id], depth, direction, types, Keyword.get(opts, :load, &list/1)) do
+      {:ok,
+       %{
+         device_id: device_id,
+         direction: direction,
+         depth: depth,
+         devices: devices |> MapSet.put(device_id) |> Enum.sort(),
+         relationships: edges
+       }}
+    end
+  end
+
+  defp walk(frontier, depth, direction, types, load) do
+    Enum.reduce_while(1..depth, {:ok, {MapSet.new(frontier), [], MapSet.new(), frontier}}, fn
+      _hop, {:ok, {_visited, _edges, _seen, []}} = acc ->
+        {:halt, acc}
+
+      _hop, {:ok, {visited, edges, seen, frontier}} ->
+        case load.(frontier) do
+          {:ok, loaded} ->
+            {:cont, {:ok, expand(loaded, frontier, direction, types, {visited, edges, seen})}}
+
+          {:error, reason} ->
+            {:halt, {:error, reason}}
+        end
+    end)
+    |> case do
+      {:ok, {visited, edges, _seen, _frontier}} -> {:ok, {visited, Enum.reverse(edges)}}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp expand(loaded, frontier, direction, types, {visited, edges, seen}) do
+    in_frontier = MapSet.new(frontier)
+
+    {visited, edges, seen, next} =
+      Enum.reduce(loaded, {visited, edges, seen, []}, fn edge, {visited, edges, seen, next} ->
+        ends = followed_ends(edge, direction, in_frontier)
+
+        if ends == [] or (types != [] and edge.relation_type not in types) do
+          {visited, edges, seen, next}
+        else
+          key = {edge.relation_type, edge.source_device_id, edge.target_device_id}
+
+          {edges, seen} =
+            if MapSet.member?(seen, key),
+              do: {edges, seen},
+              else: {[edge | edges], MapSet.put(seen, key)}
+
+          new_ends = Enum.reject(ends, &MapSet.member?(visited, &1))
+          {Enum.into(new_ends, visited), edges, seen, next ++ new_ends}
+        end
+      end)
+
+    {visited, edges, seen, Enum.uniq(next)}
+  end
+
+  defp followed_ends(edge, direction, frontier) do
+    outgoing =
+      if direction != "incoming" and MapSet.member?(frontier, edge.source_device_id),
+        do: [edge.target_device_id],
+        else: []
+
+    incoming =
+      if direction != "outgoing" and MapSet.member?(frontier, edge.target_device_id),
+        do: [edge.source_device_id],
+        else: []
+
+    outgoing ++ incoming
+  end
+
+  defp update_edges(update, device_id) when is_binary(device_id) do
+    metadata = Map.get(update, :metadata) || %{}
+
+    Enum.flat_map(@rules, fn rule ->
+      case target(metadata, rule) do
+        nil ->
+          []
+
+        ^device_id ->
+          []
+
+        value ->
+          [
+            %{
+              source_device_id: device_id,
+              target: {rule.target, value, Map.get(update, :partition) || "default"},
+              relation_type: rule.type,
+              discovery_source: to_string(Map.get(update, :source) || "unknown"),
+              metadata_key: rule.metadata_key,
+              seen: Map.get(update, :timestamp) || DateTime.utc_now()
+            }
+          ]
+      end
+    end)
+  end
+
+  defp update_edges(_update, _device_id), do: []
+
+  defp target(metadata, %{metadata_key: key, target: kind}) do
+    value =
+      case Map.get(metadata, key) do
+        nil -> nil
+        value -> value |> to_string() |> String.trim()
+      end
+
+    cond do
+      value in [nil, ""] -> nil
+      kind == :ip and not valid_ip?(value) -> nil
+      true -> value
+    end
+  end
+
+  defp valid_ip?(value), do: match?({:ok, _}, :inet.parse_address(String.to_charlist(value)))
+
+  defp resolve_targets(edges) do
+    {ips, ids} =
+      Enum.reduce(edges, {[], []}, fn
+        %{target: {:ip, value, _}}, {ips, ids} -> {[value | ips], ids}
+        %{target: {:device, value, _}}, {ips, ids} -> {ips, [value | ids]}
+      end)
+
+    by_ip = lookup(@resolve_ips_sql, Enum.uniq(ips), fn [ip, uid] -> {ip, uid} end)
+
+    by_id =
+      @resolve_ids_sql
+      |> lookup(Enum.uniq(ids), fn [value, uid, partition] -> {{value, partition}, uid} end)
+
+    edges
+    |> Enum.flat_map(fn edge ->
+      case resolve_target(edge.target, by_ip, by_id) do
+        nil -> []
+        uid when uid == edge.source_device_id -> []
+        uid -> [edge |> Map.delete(:target) |> Map.put(:target_device_id, uid)]
+      end
+    end)
+    # Two fields naming the same target would otherwise update a row twice.
+    |> Enum.uniq_by(&{&1.source_device_id, &1.target_device_id, &1.relation_type})
+  end
+
+  defp resolve_target({:ip, ip, _partition}, by_ip, _by_id), do: Map.get(by_ip, ip)
+
+  defp resolve_target({:device, value, partition}, _by_ip, by_id) do
+    Map.get(by_id, {value, nil}) || Map.get(by_id, {value, partition})
+  end
+
+  defp lookup(_sql, [], _fun), do: %{}
+
+  defp lookup(sql, values, fun) do
+    case Repo.query(sql, [values]) do
+      {:ok, %{rows: rows}} ->
+        Map.new(rows, fun)
+
+      {:error, reason} ->
+        Logger.warning("DeviceRelationships: target lookup failed: #{inspect(reason)}")
+        %{}
+    end
+  end
+
+  defp upsert([]), do: {:ok, []}
+
+  defp upsert(edges) do
+    params = [
+      Enum.map(edges, & &1.source_device_id),
+      Enum.map(edges, & &1.target_device_id),
+      Enum.map(edges, & &1.relation_type),
+      Enum.map(edges, & &1.discovery_source),
+      Enum.map(edges, & &1.metadata_key),
+      Enum.map(edges, & &1.seen)
+    ]
+
+    case Repo.query(@upsert_sql, params) do
+      {:ok, _} -> {:ok, edges}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp row_to_relationship([source, target, type, origin, key, first_seen, last_seen]) do
+    %{
+      source_device_id: source,
+      target_device_id: target,
+      relation_type: type,
+      discovery_source: origin,
+      metadata_key: key,
+      first_seen: first_seen,
+      last_seen: last_seen
+    }
+  end
+
+  defp require_device_id(device_id) when is_binary(device_id) do
+    case String.trim(device_id) do
+      "" -> {:error, "device id is required"}
+      trimmed -> {:ok, trimmed}
+    end
+  end
+
+  defp require_device_id(_device_id), do: {:error, "device id is required"}
+
+  defp parse_direction(nil), do: {:ok, "both"}
+
+  defp parse_direction(raw) do
+    direction = raw |> to_string() |> String.trim() |> String.downcase()
+
+    cond do
+      direction == "" -> {:ok, "both"}
+      direction in @directions -> {:ok, direction}
+      true -> {:error, "invalid traversal direction: #{inspect(raw)}"}
+    end
+  end
+
+  defp parse_depth(nil), do: {:ok, @default_depth}
+  defp parse_depth(depth) when is_integer(depth) and depth in 1..@max_depth, do: {:ok, depth}
+  defp parse_depth(_depth), do: {:error, "depth must be 1-#{@max_depth}"}
+
+  defp parse_types(types) when is_list(types) do
+    Enum.reduce_while(types, {:ok, []}, fn raw, {:ok, acc} ->
+      case parse_type(raw) do
+        {:ok, type} -> {:cont, {:ok, [type | acc]}}
+        {:error, reason} -> {:halt, {:error, reason}}
+      end
+    end)
+    |> case do
+      {:ok, parsed} -> {:ok, Enum.reverse(parsed)}
+      error -> error
+    end
+  end
+
+  defp parse_types(_types), do: {:error, "types must be a list"}
+end
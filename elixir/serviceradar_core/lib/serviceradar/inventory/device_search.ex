@@ -0,0 +1,204 @@
+defmodule ServiceRadar.Inventory.DeviceSearch do
+  @moduledoc """
+  Typo-tolerant device search across hostnames, IPs and identifier values.
+
+  Matching uses `pg_trgm`: a value matches when it is trigram-similar to the
+  search term (`%`, so `web-prd-01` finds `web-prod-01`) or contains it
+  (`ILIKE`, so `10.20.3` finds `10.20.3.14`). Both are served by the GIN
+  trigram indexes on `ocsf_devices.hostname`, `ocsf_devices.ip` and
+  `device_identifiers.identifier_value`.
+
+  Each device is ranked by its best matching field:
+
+    * prefix match - 0.75 to 1.0 (an exact match scores 1.0)
+    * substring match - 0.5 to 0.75
+    * fuzzy match - the trigram similarity (0.3 to 1.0)
+
+  Ranked devices are then read through Ash with the caller's actor, so device
+  read policies still apply. With a `:partition`, only devices with an
+  identifier in that partition are returned.
+
+  ## Usage
+
+      DeviceSearch.search("web-prd", actor: scope.user, partition: "default", limit: 20)
+  """
+
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Repo
+
+  require Ash.Query
+
+  @default_limit 25
+  @max_limit 200
+  @min_term_length 3
+
+  @type match :: %{
+          device: Device.t(),
+          score: float(),
+          matched_field: :hostname | :ip | :identifier,
+          identifier_type: String.t() | nil,
+          matched_value: String.t(),
+          highlight: %{start: non_neg_integer(), length: pos_integer()} | nil
+        }
+
+  @search_sql """
+  WITH matches AS (
+    SELECT d.uid AS device_uid, 'hostname' AS field, NULL::text AS identifier_type, d.hostname AS value
+    FROM platform.ocsf_devices d
+    WHERE d.deleted_at IS NULL AND (d.hostname % $1 OR d.hostname ILIKE $2)
+    UNION ALL
+    SELECT d.uid, 'ip', NULL::text, d.ip
+    FROM platform.ocsf_devices d
+    WHERE d.deleted_at IS NULL AND (d.ip % $1 OR d.ip ILIKE $2)
+    UNION ALL
+    SELECT i.device_id, 'identifier', i.identifier_type, i.identifier_value
+    FROM platform.device_identifiers i
+    WHERE i.identifier_value % $1 OR i.identifier_value ILIKE $2
+  ),
+  scored AS (
+    SELECT m.*,
+      CASE
+        WHEN m.value ILIKE $3 THEN 0.75 + 0.25 * similarity(m.value, $1)
+        WHEN m.value ILIKE $2 THEN 0.5 + 0.25 * similarity(m.value, $1)
+        ELSE similarity(m.value, $1)
+      END::float8 AS score
+    FROM matches m
+  ),
+  best AS (
+    SELECT DISTINCT ON (s.device_uid) s.*
+    FROM scored s
+    JOIN platform.ocsf_devices d
+      ON d.uid = s.device_uid AND d.deleted_at IS NULL AND d.archived_at IS NULL
+    WHERE $4::text IS NULL
+      OR EXISTS (
+        SELECT 1 FROM platform.device_identifiers p
+        WHERE p.device_id = s.device_uid AND p.partition = $4
+      )
+    ORDER BY s.device_uid, s.score DESC, s.field
+  )
+  SELECT device_uid, field, identifier_type, value, score
+  FROM best
+  ORDER BY score DESC, device_uid
+  LIMIT $5
+  """
+
+  @doc """
+  Searches devices for `term`, best match first.
+
+  Options:
+    - `:actor` - actor the matching devices are read as (required)
+    - `:partition` - only return devices with an identifier in this partition
+    - `:limit` - maximum results (default #{@default_limit}, max #{@max_limit})
+  """
+  @spec search(String.t(), keyword()) :: {:ok, [match()]} | {:error, term()}
+  def search(term, opts \\ [])
+
+  def search(term, opts) when is_binary(term) do
+    term = String.trim(term)
+    limit = clamp_limit(Keyword.get(opts, :limit))
+    partition = blank_to_nil(Keyword.get(opts, :partition))
+
+    with :ok <- validate_term(term),
+         {:ok, ranked} <- rank(term, partition, limit),
+         {:ok, devices} <- read_devices(ranked, Keyword.get(opts, :actor)) do
+      {:ok,
+       Enum.flat_map(ranked, fn row ->
+         case Map.fetch(devices, row.uid) do
+           {:ok, device} -> [to_match(row, device, term)]
+           :error -> []
+         end
+       end)}
+    end
+  end
+
+  def search(_term, _opts), do: {:error, :invalid_query}
+
+  @doc """
+  Returns the position of `term` within `value` (case-insensitive), or `nil`
+  when the value only matched fuzzily.
+  """
+  @spec highlight(String.t(), String.t()) ::
+          %{start: non_neg_integer(), length: pos_integer()} | nil
+  def highlight(value, term) when is_binary(value) and is_binary(term) and term != "" do
+    case :binary.match(String.downcase(value, :ascii), String.downcase(term, :ascii)) do
+      {start, length} -> %{start: start, length: length}
+      :nomatch -> nil
+    end
+  end
+
+  def highlight(_value, _term), do: nil
+
+  defp validate_term(term) do
+    if String.length(term) >= @min_term_length,
+      do: :ok,
+      else: {:error, :query_too_short}
+  end
+
+  defp rank(term, partition, limit) do
+    pattern = escape_like(term)
+
+    case Repo.query(@search_sql, [term, "%#{pattern}%", "#{pattern}%", partition, limit]) do
+      {:ok, %{rows: rows}} ->
+        {:ok,
+         Enum.map(rows, fn [uid, field, identifier_type, value, score] ->
+           %{
+             uid: uid,
+             field: field_atom(field),
+             identifier_type: identifier_type,
+             value: value,
+             score: score
+           }
+         end)}
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  defp read_devices([], _actor), do: {:ok, %{}}
+
+  defp read_devices(ranked, actor) do
+    uids = Enum.map(ranked, & &1.uid)
+
+    Device
+    |> Ash.Query.filter(uid in ^uids)
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+    |> case do
+      {:ok, devices} -> {:ok, Map.new(devices, &{&1.uid, &1})}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp to_match(row, device, term) do
+    %{
+      device: device,
+      score: Float.round(row.score, 4),
+      matched_field: row.field,
+      identifier_type: row.identifier_type,
+      matched_value: row.value,
+      highlight: highlight(row.value, term)
+    }
+  end
+
+  defp clamp_limit(limit) when is_integer(limit), do: limit |> max(1) |> min(@max_limit)
+  defp clamp_limit(_limit), do: @default_limit
+
+  defp field_atom("hostname"), do: :hostname
+  defp field_atom("ip"), do: :ip
+  defp field_atom("identifier"), do: :identifier
+
+  defp escape_like(term) do
+    String.replace(term, ["\\", "%", "_"], fn char -> "\\" <> char end)
+  end
+
+  defp blank_to_nil(value) when is_binary(value) do
+    case String.trim(value) do
+      "" -> nil
+      trimmed -> trimmed
+    end
+  end
+
+  defp blank_to_nil(_value), do: nil
+end
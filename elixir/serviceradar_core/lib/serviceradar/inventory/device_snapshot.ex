@@ -0,0 +1,306 @@
+defmodule ServiceRadar.Inventory.DeviceSnapshot do
+  @moduledoc """
+  Reconstructs a device's state at a point in time from its history in
+  `platform.device_updates` and diffs two such snapshots.
+
+  A snapshot folds the latest update per discovery source observed at or
+  before the requested time, applied in observation order so the most recent
+  non-empty value of each field wins. Metadata values are compared as
+  strings; tags come from the comma-separated `armis_tags` and `tags`
+  metadata keys.
+
+  ## Usage
+
+      DeviceSnapshot.compare("sr:1234",
+        from: ~U[2026-10-01 00:00:00Z],
+        to: ~U[2026-10-17 00:00:00Z]
+      )
+  """
+
+  alias ServiceRadar.Repo
+
+  @tag_metadata_keys ~w(armis_tags tags)
+  @metadata_field_prefix "metadata."
+
+  @updates_sql """
+  SELECT DISTINCT ON (discovery_source)
+    observed_at, agent_id, gateway_id, partition, discovery_source,
+    COALESCE(ip, ''), mac, hostname, COALESCE(available, false), metadata
+  FROM platform.device_updates
+  WHERE device_id = $1
+    AND observed_at <= $2
+    AND ($3::text IS NULL OR partition = $3)
+  ORDER BY discovery_source, observed_at DESC
+  """
+
+  @type snapshot :: %{
+          device_id: String.t(),
+          partition: String.t() | nil,
+          at: DateTime.t(),
+          observed_at: DateTime.t() | nil,
+          ip: String.t() | nil,
+          mac: String.t() | nil,
+          hostname: String.t() | nil,
+          is_available: boolean(),
+          agent_id: String.t() | nil,
+          gateway_id: String.t() | nil,
+          discovery_sources: [String.t()],
+          metadata: %{String.t() => String.t()},
+          tags: [String.t()]
+        }
+
+  @type change :: %{
+          field: String.t(),
+          kind: :added | :removed | :changed,
+          before: String.t() | nil,
+          after: String.t() | nil
+        }
+
+  @type diff :: %{
+          device_id: String.t(),
+          partition: String.t() | nil,
+          from: snapshot(),
+          to: snapshot(),
+          changes: [change()],
+          tags_added: [String.t()],
+          tags_removed: [String.t()]
+        }
+
+  @doc """
+  Diffs a device's snapshots at `:from` and `:to`.
+
+  ## Options
+
+    - `:from`, `:to` - `DateTime`s to compare (required, `from` before `to`)
+    - `:partition` - only consider updates recorded in this partition
+    - `:load` - `fn device_id, partition, at -> {:ok, [update]} end` used
+      instead of reading `platform.device_updates`
+
+  Returns `{:error, :not_found}` when the device has no updates at `:to`.
+  """
+  @spec compare(String.t(), keyword()) :: {:ok, diff()} | {:error, term()}
+  def compare(device_id, opts) do
+    partition = blank_to_nil(Keyword.get(opts, :partition))
+
+    with {:ok, device_id} <- require_device_id(device_id),
+         {:ok, from, to} <- validate_range(Keyword.get(opts, :from), Keyword.get(opts, :to)),
+         {:ok, before} <- load(device_id, partition, from, opts),
+         {:ok, current} <- load(device_id, partition, to, opts) do
+      if exists?(current) do
+        diff = diff(before, current)
+        {:ok, if(partition, do: %{diff | partition: partition}, else: diff)}
+      else
+        {:error, :not_found}
+      end
+    end
+  end
+
+  @doc """
+  Reads and builds the snapshot of a device as of `at`. Options as for
+  `compare/2`.
+  """
+  @spec load(String.t(), String.t() | nil, DateTime.t(), keyword()) ::
+          {:ok, snapshot()} | {:error, term()}
+  def load(device_id, partition, at, opts \\ []) do
+    loader = Keyword.get(opts, :load, &load_updates/3)
+
+    with {:ok, updates} <- loader.(device_id, partition, at) do
+      {:ok, build(device_id, at, updates)}
+    end
+  end
+
+  @doc """
+  Folds per-source updates into a snapshot.
+  """
+  @spec build(String.t(), DateTime.t(), [map()]) :: snapshot()
+  def build(device_id, at, updates) do
+    initial = %{
+      device_id: device_id,
+      partition: nil,
+      at: at,
+      observed_at: nil,
+      ip: nil,
+      mac: nil,
+      hostname: nil,
+      is_available: false,
+      agent_id: nil,
+      gateway_id: nil,
+      discovery_sources: [],
+      metadata: %{},
+      tags: []
+    }
+
+    snapshot =
+      updates
+      |> Enum.reject(&is_nil/1)
+      |> Enum.sort_by(& &1.observed_at, DateTime)
+      |> Enum.reduce(initial, &apply_update/2)
+
+    %{
+      snapshot
+      | discovery_sources: snapshot.discovery_sources |> Enum.uniq() |> Enum.sort(),
+        tags: snapshot.metadata |> tag_set() |> Enum.sort()
+    }
+  end
+
+  @doc "Whether any update contributed to the snapshot."
+  @spec exists?(snapshot()) :: boolean()
+  def exists?(%{observed_at: observed_at}), do: not is_nil(observed_at)
+
+  @doc """
+  Reports field, metadata and tag differences between two snapshots.
+  Changes are ordered by field name; metadata keys are reported as
+  `"metadata.<key>"`.
+  """
+  @spec diff(snapshot(), snapshot()) :: diff()
+  def diff(before, current) do
+    changes =
+      (diff_maps(fields(before), fields(current), "") ++
+         diff_maps(before.metadata, current.metadata, @metadata_field_prefix))
+      |> Enum.sort_by(& &1.field)
+
+    before_tags = tag_set(before.metadata)
+    current_tags = tag_set(current.metadata)
+
+    %{
+      device_id: current.device_id,
+      partition: current.partition,
+      from: before,
+      to: current,
+      changes: changes,
+      tags_added: current_tags |> MapSet.difference(before_tags) |> Enum.sort(),
+      tags_removed: before_tags |> MapSet.difference(current_tags) |> Enum.sort()
+    }
+  end
+
+  defp apply_update(update, snapshot) do
+    sources =
+      case blank_to_nil(update[:discovery_source]) do
+        nil -> snapshot.discovery_sources
+        source -> [source | snapshot.discovery_sources]
+      end
+
+    %{
+      snapshot
+      | observed_at: update.observed_at,
+        is_available: update[:is_available] == true,
+        partition: present(update[:partition], snapshot.partition),
+        ip: present(update[:ip], snapshot.ip),
+        mac: present(update[:mac], snapshot.mac),
+        hostname: present(update[:hostname], snapshot.hostname),
+        agent_id: present(update[:agent_id], snapshot.agent_id),
+        gateway_id: present(update[:gateway_id], snapshot.gateway_id),
+        discovery_sources: sources,
+        metadata: Map.merge(snapshot.metadata, stringify_metadata(update[:metadata]))
+    }
+  end
+
+  defp fields(snapshot) do
+    if exists?(snapshot) do
+      %{
+        "ip" => snapshot.ip,
+        "mac" => snapshot.mac,
+        "hostname" => snapshot.hostname,
+        "is_available" => to_string(snapshot.is_available),
+        "agent_id" => snapshot.agent_id,
+        "gateway_id" => snapshot.gateway_id,
+        "discovery_sources" => Enum.join(snapshot.discovery_sources, ",")
+      }
+    else
+      %{}
+    end
+  end
+
+  # Empty values count as absent.
+  defp diff_maps(before, current, prefix) do
+    keys = before |> Map.keys() |> Enum.concat(Map.keys(current)) |> Enum.uniq()
+
+    Enum.flat_map(keys, fn key ->
+      case {blank_to_nil(before[key]), blank_to_nil(current[key])} do
+        {nil, nil} -> []
+        {nil, value} -> [change(prefix <> key, :added, nil, value)]
+        {value, nil} -> [change(prefix <> key, :removed, value, nil)]
+        {same, same} -> []
+        {old, new} -> [change(prefix <> key, :changed, old, new)]
+      end
+    end)
+  end
+
+  defp change(field, kind, before, current) do
+    %{field: field, kind: kind, before: before, after: current}
+  end
+
+  defp tag_set(metadata) do
+    @tag_metadata_keys
+    |> Enum.flat_map(fn key -> String.split(Map.get(metadata, key) || "", ",") end)
+    |> Enum.map(&(&1 |> String.trim() |> String.downcase()))
+    |> Enum.reject(&(&1 == ""))
+    |> MapSet.new()
+  end
+
+  defp stringify_metadata(metadata) when is_map(metadata) do
+    Map.new(metadata, fn
+      {key, value} when is_binary(value) -> {to_string(key), value}
+      {key, value} -> {to_string(key), Jason.encode!(value)}
+    end)
+  end
+
+  defp stringify_metadata(_metadata), do: %{}
+
+  defp load_updates(device_id, partition, at) do
+    case Repo.query(@updates_sql, [device_id, at, partition]) do
+      {:ok, %{rows: rows}} ->
+        {:ok,
+         Enum.map(rows, fn [observed_at, agent_id, gateway_id, partition, source | rest] ->
+           [ip, mac, hostname, available, metadata] = rest
+
+           %{
+             observed_at: observed_at,
+             agent_id: agent_id,
+             gateway_id: gateway_id,
+             partition: partition,
+             discovery_source: source,
+             ip: ip,
+             mac: mac,
+             hostname: hostname,
+             is_available: available,
+             metadata: metadata || %{}
+           }
+         end)}
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  defp require_device_id(device_id) do
+    case blank_to_nil(device_id) do
+      nil -> {:error, "device_id is required"}
+      device_id -> {:ok, device_id}
+    end
+  end
+
+  defp validate_range(%DateTime{} = from, %DateTime{} = to) do
+    if DateTime.compare(from, to) == :lt,
+      do: {:ok, from, to},
+      else: {:error, "from must be before to"}
+  end
+
+  defp validate_range(_from, _to), do: {:error, "from and to timestamps are required"}
+
+  defp present(value, fallback) do
+    case blank_to_nil(value) do
+      nil -> fallback
+      value -> value
+    end
+  end
+
+  defp blank_to_nil(value) when is_binary(value) do
+    case String.trim(value) do
+      "" -> nil
+      _ -> value
+    end
+  end
+
+  defp blank_to_nil(_value), do: nil
+end
@@ -0,0 +1,39 @@
+defmodule ServiceRadar.Inventory.DeviceTagResyncWorker do
+  @moduledoc """
+  Oban worker that re-applies device tag rules to every device after a rule
+  changes.
+
+  Jobs are unique while waiting, so a burst of rule edits results in a single
+  resync; an edit made while a resync is running queues one more.
+  """
+
+  use Oban.Worker,
+    queue: :maintenance,
+    max_attempts: 3,
+    unique: [period: :infinity, states: [:available, :scheduled]]
+
+  alias ServiceRadar.Inventory.DeviceTagger
+  alias ServiceRadar.SweepJobs.ObanSupport
+
+  require Logger
+
+  # Short delay so consecutive rule edits are coalesced into one resync.
+  @schedule_delay_seconds 5
+
+  @doc """
+  Enqueues a resync of all device tags.
+  """
+  @spec enqueue() :: {:ok, Oban.Job.t()} | {:error, term()}
+  def enqueue do
+    %{}
+    |> new(schedule_in: @schedule_delay_seconds)
+    |> ObanSupport.safe_insert()
+  end
+
+  @impl Oban.Worker
+  def perform(%Oban.Job{}) do
+    changed = DeviceTagger.resync_all()
+    Logger.info("DeviceTagResyncWorker: updated tags on #{changed} devices")
+    :ok
+  end
+end
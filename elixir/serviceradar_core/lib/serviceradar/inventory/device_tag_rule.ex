@@ -0,0 +1,177 @@
+defmodule ServiceRadar.Inventory.DeviceTagRule do
+  @moduledoc """
+  Rule that automatically tags devices matching its conditions.
+
+  Rules are evaluated by `ServiceRadar.Inventory.DeviceTagger` in ascending
+  `position` order when devices are ingested or updated, and every device is
+  re-evaluated when a rule changes. All configured conditions must match; a
+  rule without conditions matches every device. When two matching rules set
+  the same tag key, the earlier rule wins.
+
+  Tags applied by rules are tracked in the device's `auto_tags`, so changing
+  or removing a rule updates them without touching manually set tags.
+  """
+
+  use Ash.Resource,
+    domain: ServiceRadar.Inventory,
+    data_layer: AshPostgres.DataLayer,
+    authorizers: [Ash.Policy.Authorizer],
+    extensions: [AshJsonApi.Resource]
+
+  @rule_fields [
+    :name,
+    :description,
+    :enabled,
+    :position,
+    :cidrs,
+    :device_types,
+    :vendor_pattern,
+    :os_pattern,
+    :min_risk_score,
+    :tags
+  ]
+
+  postgres do
+    table "device_tag_rules"
+    repo ServiceRadar.Repo
+    schema "platform"
+    migrate? false
+  end
+
+  json_api do
+    type "device_tag_rule"
+
+    routes do
+      base "/device-tag-rules"
+
+      get :by_id
+      index :read
+      post :create
+      patch :update
+      delete :destroy
+    end
+  end
+
+  code_interface do
+    define :get, action: :by_id, args: [:id]
+    define :list, action: :read
+    define :list_enabled, action: :enabled
+    define :create, action: :create
+    define :update, action: :update
+    define :destroy, action: :destroy
+  end
+
+  actions do
+    defaults [:read]
+
+    read :by_id do
+      argument :id, :uuid, allow_nil?: false
+      get? true
+      filter expr(id == ^arg(:id))
+    end
+
+    read :enabled do
+      description "Enabled rules in evaluation order"
+      filter expr(enabled == true)
+
+      prepare fn query, _context ->
+        Ash.Query.sort(query, position: :asc, name: :asc)
+      end
+    end
+
+    create :create do
+      accept @rule_fields
+      validate ServiceRadar.Inventory.Validations.DeviceTagRule
+      change ServiceRadar.Inventory.Changes.ResyncDeviceTags
+    end
+
+    update :update do
+      accept @rule_fields
+      require_atomic? false
+      validate ServiceRadar.Inventory.Validations.DeviceTagRule
+      change ServiceRadar.Inventory.Changes.ResyncDeviceTags
+    end
+
+    destroy :destroy do
+      primary? true
+      require_atomic? false
+      change ServiceRadar.Inventory.Changes.ResyncDeviceTags
+    end
+  end
+
+  policies do
+    import ServiceRadar.Policies
+
+    system_bypass()
+    operator_action_type([:create, :update])
+    admin_action_type(:destroy)
+    read_viewer_plus()
+  end
+
+  attributes do
+    uuid_primary_key :id
+
+    attribute :name, :string do
+      allow_nil? false
+      public? true
+      description "Rule name"
+    end
+
+    attribute :description, :string do
+      public? true
+      description "What the rule is for"
+    end
+
+    attribute :enabled, :boolean do
+      default true
+      public? true
+      description "Whether the rule is active"
+    end
+
+    attribute :position, :integer do
+      default 0
+      public? true
+      description "Evaluation order (lower first; earlier rules win tag key collisions)"
+    end
+
+    attribute :cidrs, {:array, :string} do
+      default []
+      public? true
+      description "CIDR ranges the device IP must fall in (any)"
+    end
+
+    attribute :device_types, {:array, :string} do
+      default []
+      public? true
+      description "Device types to match (any, case-insensitive)"
+    end
+
+    attribute :vendor_pattern, :string do
+      public? true
+      description "Regex pattern for the device vendor/manufacturer"
+    end
+
+    attribute :os_pattern, :string do
+      public? true
+      description "Regex pattern for the device OS name or version"
+    end
+
+    attribute :min_risk_score, :integer do
+      public? true
+      description "Minimum device risk score"
+    end
+
+    attribute :tags, :map do
+      default %{}
+      public? true
+      description "Tags (key/value) applied to matching devices"
+    end
+
+    create_timestamp :inserted_at
+    update_timestamp :updated_at
+  end
+
+  identities do
+    identity :unique_name, [:name]
+  end
+end
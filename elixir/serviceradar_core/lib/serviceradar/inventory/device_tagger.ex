@@ -0,0 +1,198 @@
+defmodule ServiceRadar.Inventory.DeviceTagger do
+  @moduledoc """
+  Applies `ServiceRadar.Inventory.DeviceTagRule` definitions to devices.
+
+  Rule-applied tags live in the device's `tags` alongside manual ones (so they
+  are searchable the same way) and are also recorded in `auto_tags`. When the
+  rules are re-evaluated, tags whose value still matches `auto_tags` are
+  replaced by the new rule output, while manually set tags - including a
+  manual value for a key a rule also sets - are kept.
+
+  Rules are evaluated:
+
+    * for devices touched by `ServiceRadar.Inventory.SyncIngestor`
+    * when a device is updated through the `:update` action
+    * for every device when a rule is created, updated or destroyed
+      (`ServiceRadar.Inventory.DeviceTagResyncWorker`)
+  """
+
+  import Ecto.Query, only: [from: 2]
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceTagRule
+  alias ServiceRadar.Policies.NetworkAddressPolicy
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @default_batch_size 1_000
+  @device_fields [:uid, :ip, :type, :vendor_name, :os, :risk_score, :tags, :auto_tags]
+
+  @type tags :: %{String.t() => String.t()}
+
+  @doc "Loads the enabled rules in evaluation order."
+  @spec load_rules() :: [DeviceTagRule.t()]
+  def load_rules do
+    case DeviceTagRule.list_enabled(actor: SystemActor.system(:device_tagger)) do
+      {:ok, rules} ->
+        rules
+
+      {:error, reason} ->
+        Logger.warning("Device tag rule load failed: #{inspect(reason)}")
+        []
+    end
+  end
+
+  @doc """
+  Returns the tags the rules apply to `device`. Rules are taken in order, so
+  the first matching rule to set a key wins.
+  """
+  @spec evaluate(map(), [map()]) :: tags()
+  def evaluate(device, rules) do
+    rules
+    |> Enum.filter(&matches?(&1, device))
+    |> Enum.reduce(%{}, fn rule, acc -> Map.merge(stringify(rule.tags), acc) end)
+  end
+
+  @doc """
+  Replaces the previously rule-applied tags with `new_auto_tags`, keeping
+  manual tags. Returns `{tags, auto_tags}`.
+  """
+  @spec retag(map() | nil, map() | nil, tags()) :: {tags(), tags()}
+  def retag(tags, auto_tags, new_auto_tags) do
+    tags = stringify(tags)
+    auto_tags = stringify(auto_tags)
+
+    manual = Map.reject(tags, fn {key, value} -> Map.get(auto_tags, key) == value end)
+    applied = Map.drop(new_auto_tags, Map.keys(manual))
+
+    {Map.merge(applied, manual), applied}
+  end
+
+  @doc """
+  Re-evaluates the rules for the given device uids and persists any tag
+  changes. Returns the number of devices whose tags changed.
+  """
+  @spec apply_to_uids([String.t()], [map()]) :: non_neg_integer()
+  def apply_to_uids(uids, rules \\ load_rules())
+
+  def apply_to_uids([], _rules), do: 0
+
+  def apply_to_uids(uids, rules) do
+    from(d in Device,
+      where: d.uid in ^uids and is_nil(d.deleted_at),
+      select: map(d, ^@device_fields)
+    )
+    |> Repo.all()
+    |> apply_to_devices(rules)
+  end
+
+  @doc """
+  Re-evaluates the rules for every device, in batches of `batch_size`.
+  Returns the number of devices whose tags changed.
+  """
+  @spec resync_all(pos_integer()) :: non_neg_integer()
+  def resync_all(batch_size \\ @default_batch_size) do
+    resync_from(nil, load_rules(), batch_size, 0)
+  end
+
+  defp resync_from(after_uid, rules, batch_size, changed) do
+    devices =
+      from(d in Device,
+        where: is_nil(d.deleted_at),
+        order_by: [asc: d.uid],
+        limit: ^batch_size,
+        select: map(d, ^@device_fields)
+      )
+      |> after_uid_filter(after_uid)
+      |> Repo.all()
+
+    changed = changed + apply_to_devices(devices, rules)
+
+    if length(devices) < batch_size do
+      changed
+    else
+      resync_from(List.last(devices).uid, rules, batch_size, changed)
+    end
+  end
+
+  defp after_uid_filter(query, nil), do: query
+  defp after_uid_filter(query, uid), do: from(d in query, where: d.uid > ^uid)
+
+  defp apply_to_devices(devices, rules) do
+    Enum.count(devices, fn device ->
+      {tags, auto_tags} = retag(device.tags, device.auto_tags, evaluate(device, rules))
+
+      if tags == stringify(device.tags) and auto_tags == stringify(device.auto_tags) do
+        false
+      else
+        from(d in Device, where: d.uid == ^device.uid)
+        |> Repo.update_all(set: [tags: tags, auto_tags: auto_tags])
+
+        true
+      end
+    end)
+  end
+
+  defp matches?(rule, device) do
+    matches_cidrs?(rule.cidrs, Map.get(device, :ip)) and
+      matches_type?(rule.device_types, Map.get(device, :type)) and
+      matches_pattern?(rule.vendor_pattern, [Map.get(device, :vendor_name)]) and
+      matches_pattern?(rule.os_pattern, os_values(Map.get(device, :os))) and
+      matches_risk?(rule.min_risk_score, Map.get(device, :risk_score))
+  end
+
+  defp matches_cidrs?(cidrs, _ip) when cidrs in [nil, []], do: true
+
+  defp matches_cidrs?(cidrs, ip) when is_binary(ip) do
+    case :inet.parse_address(String.to_charlist(String.trim(ip))) do
+      {:ok, address} -> NetworkAddressPolicy.ip_in_any_cidr?(address, cidrs)
+      {:error, _} -> false
+    end
+  end
+
+  defp matches_cidrs?(_cidrs, _ip), do: false
+
+  defp matches_type?(types, _type) when types in [nil, []], do: true
+
+  defp matches_type?(types, type) when is_binary(type) do
+    type = String.downcase(type)
+    Enum.any?(types, &(String.downcase(&1) == type))
+  end
+
+  defp matches_type?(_types, _type), do: false
+
+  defp matches_pattern?(pattern, _values) when pattern in [nil, ""], do: true
+
+  defp matches_pattern?(pattern, values) do
+    case Regex.compile(pattern) do
+      {:ok, regex} -> Enum.any?(values, &(is_binary(&1) and Regex.match?(regex, &1)))
+      {:error, _} -> false
+    end
+  end
+
+  defp matches_risk?(nil, _score), do: true
+  defp matches_risk?(min, score) when is_integer(score), do: score >= min
+  defp matches_risk?(_min, _score), do: false
+
+  # Matched against the OS name, version, type and "name version" so patterns
+  # like "Windows Server 2019" work.
+  defp os_values(os) when is_map(os) do
+    os = stringify(os)
+    parts = Enum.map(["name", "version", "type"], &Map.get(os, &1))
+
+    full =
+      parts
+      |> Enum.take(2)
+      |> Enum.filter(&is_binary/1)
+      |> Enum.join(" ")
+
+    [full | parts]
+  end
+
+  defp os_values(_os), do: []
+
+  defp stringify(nil), do: %{}
+  defp stringify(map) when is_map(map), do: Map.new(map, fn {k, v} -> {to_string(k), v} end)
+end
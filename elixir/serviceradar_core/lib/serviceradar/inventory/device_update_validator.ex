@@ -0,0 +1,286 @@
+defmodule ServiceRadar.Inventory.DeviceUpdateValidator do
+  @moduledoc """
+  Ingest-side validation of device updates before
+  `ServiceRadar.Inventory.SyncIngestor` reconciles them.
+
+  Updates that fail a check are not ingested. They are written to
+  `ServiceRadar.Inventory.QuarantinedDeviceUpdate` with the reasons they were
+  rejected, and counted in the
+  `[:serviceradar, :inventory, :device_update, :quarantined]` telemetry event
+  (measurement `:count`, metadata `:reason` and `:source`).
+
+  ## Checks
+
+    * `:device_id` - the update has a `device_id`, or something DIRE can derive
+      one from (IP, MAC, an agent, Armis, NetBox or integration id, or a custom
+      identifier, see `ServiceRadar.Inventory.CustomIdentifierTypes`)
+    * `:ip` - a present `ip` is a valid IPv4 or IPv6 address
+    * `:source` - the `source` is a known discovery source: one of the
+      `ServiceRadar.Inventory.SourceConfidence` sources or `extra_sources`
+    * `:partition` - the update names a non-blank `partition` (without this
+      check, updates without one land in `"default"`)
+
+  Only `:device_id` and `:ip` are enabled by default, since several producers
+  omit the partition or report their own source names. Configure with:
+
+      config :serviceradar_core, ServiceRadar.Inventory.DeviceUpdateValidator,
+        enabled: true,
+        checks: [:device_id, :ip, :source, :partition],
+        extra_sources: ["wifi_map", "plugin_device_discovery"]
+
+  or at runtime with `SERVICERADAR_DEVICE_UPDATE_CHECKS` and
+  `SERVICERADAR_DEVICE_UPDATE_EXTRA_SOURCES` (comma separated).
+  """
+
+  alias ServiceRadar.Inventory.CustomIdentifierTypes
+  alias ServiceRadar.Inventory.QuarantinedDeviceUpdate
+  alias ServiceRadar.Inventory.SourceConfidence
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @checks [:device_id, :ip, :source, :partition]
+  @default_checks [:device_id, :ip]
+  @identity_metadata_keys ~w(agent_id armis_device_id netbox_device_id integration_id)
+
+  @type check :: :device_id | :ip | :source | :partition
+  @type reason ::
+          :not_a_map | :missing_device_id | :invalid_ip | :unknown_source | :missing_partition
+
+  @type config :: %{enabled: boolean(), checks: [check()], known_sources: MapSet.t()}
+
+  @doc "All supported checks."
+  @spec checks() :: [check()]
+  def checks, do: @checks
+
+  @doc """
+  Returns the effective configuration. Unsupported check names are ignored.
+  """
+  @spec config() :: config()
+  def config do
+    opts = Application.get_env(:serviceradar_core, __MODULE__, [])
+
+    checks =
+      opts
+      |> Keyword.get(:checks, @default_checks)
+      |> List.wrap()
+      |> Enum.flat_map(&parse_check/1)
+      |> Enum.uniq()
+
+    known_sources =
+      opts
+      |> Keyword.get(:extra_sources, [])
+      |> List.wrap()
+      |> Enum.map(&normalize_source/1)
+      |> Enum.concat(Map.keys(SourceConfidence.table()))
+      |> MapSet.new()
+
+    %{enabled: Keyword.get(opts, :enabled, true), checks: checks, known_sources: known_sources}
+  end
+
+  @doc """
+  Validates a single raw update. Returns every failed check, in check order.
+  """
+  @spec validate(term(), config()) :: :ok | {:error, [reason()]}
+  def validate(update, config \\ config())
+
+  def validate(update, %{checks: checks} = config) when is_map(update) do
+    case Enum.flat_map(checks, &run_check(&1, update, config)) do
+      [] -> :ok
+      reasons -> {:error, reasons}
+    end
+  end
+
+  def validate(_update, _config), do: {:error, [:not_a_map]}
+
+  @doc """
+  Splits raw updates into the ones that pass validation and the rejected ones
+  paired with their reasons. With validation disabled everything passes.
+  """
+  @spec split([term()], config()) :: {[map()], [{term(), [reason()]}]}
+  def split(updates, config \\ config())
+
+  def split(updates, %{enabled: false}), do: {updates, []}
+
+  def split(updates, config) do
+    {valid, rejected} =
+      Enum.reduce(updates, {[], []}, fn update, {valid, rejected} ->
+        case validate(update, config) do
+          :ok -> {[update | valid], rejected}
+          {:error, reasons} -> {valid, [{update, reasons} | rejected]}
+        end
+      end)
+
+    {Enum.reverse(valid), Enum.reverse(rejected)}
+  end
+
+  @doc """
+  Validates `updates`, quarantines the rejected ones and returns the rest.
+  """
+  @spec screen([term()], config()) :: [map()]
+  def screen(updates, config \\ config()) do
+    {valid, rejected} = split(updates, config)
+    _ = quarantine(rejected)
+    valid
+  end
+
+  @doc """
+  Writes rejected updates to the quarantine table and emits the quarantine
+  telemetry. Failures to write are logged; they never fail ingestion.
+  """
+  @spec quarantine([{term(), [reason()]}]) :: :ok | {:error, term()}
+  def quarantine([]), do: :ok
+
+  def quarantine(rejected) do
+    now = DateTime.utc_now()
+    rows = Enum.map(rejected, &quarantine_row(&1, now))
+
+    emit_quarantined(rows)
+
+    Logger.warning(
+      "DeviceUpdateValidator: quarantined #{length(rows)} malformed device updates " <>
+        "(#{summarize_reasons(rows)})"
+    )
+
+    Repo.insert_all(QuarantinedDeviceUpdate, rows)
+    :ok
+  rescue
+    e ->
+      Logger.warning("Failed to quarantine device updates: #{inspect(e)}")
+      {:error, e}
+  end
+
+  defp run_check(:device_id, update, _config) do
+    if has_identity?(update), do: [], else: [:missing_device_id]
+  end
+
+  defp run_check(:ip, update, _config) do
+    case string_field(update, "ip") do
+      nil -> []
+      ip -> if valid_ip?(ip), do: [], else: [:invalid_ip]
+    end
+  end
+
+  defp run_check(:source, update, %{known_sources: known_sources}) do
+    case string_field(update, "source") do
+      nil -> [:unknown_source]
+      source -> if normalize_source(source) in known_sources, do: [], else: [:unknown_source]
+    end
+  end
+
+  defp run_check(:partition, update, _config) do
+    if string_field(update, "partition"), do: [], else: [:missing_partition]
+  end
+
+  defp has_identity?(update) do
+    metadata =
+      case field(update, "metadata") do
+        metadata when is_map(metadata) -> metadata
+        _ -> %{}
+      end
+
+    Enum.any?(["device_id", "ip", "mac", "agent_id"], &string_field(update, &1)) or
+      Enum.any?(@identity_metadata_keys, &string_field(metadata, &1)) or
+      map_size(CustomIdentifierTypes.extract(metadata)) > 0
+  end
+
+  defp valid_ip?(ip) do
+    match?({:ok, _}, :inet.parse_strict_address(String.to_charlist(ip)))
+  end
+
+  defp quarantine_row({update, reasons}, now) do
+    payload = json_safe(update)
+
+    %{
+      id: Ecto.UUID.generate(),
+      reasons: Enum.map(reasons, &Atom.to_string/1),
+      source: string_field(update, "source"),
+      partition: string_field(update, "partition"),
+      device_id: string_field(update, "device_id"),
+      ip: string_field(update, "ip"),
+      payload: if(is_map(payload), do: payload, else: %{"value" => payload}),
+      quarantined_at: now
+    }
+  end
+
+  defp emit_quarantined(rows) do
+    rows
+    |> Enum.flat_map(fn row -> Enum.map(row.reasons, &{&1, row.source || "unknown"}) end)
+    |> Enum.frequencies()
+    |> Enum.each(fn {{reason, source}, count} ->
+      :telemetry.execute(
+        [:serviceradar, :inventory, :device_update, :quarantined],
+        %{count: count},
+        %{reason: reason, source: source}
+      )
+    end)
+  end
+
+  defp summarize_reasons(rows) do
+    rows
+    |> Enum.flat_map(& &1.reasons)
+    |> Enum.frequencies()
+    |> Enum.map_join(", ", fn {reason, count} -> "#{reason}: #{count}" end)
+  end
+
+  defp json_safe(%DateTime{} = value), do: DateTime.to_iso8601(value)
+  defp json_safe(%NaiveDateTime{} = value), do: NaiveDateTime.to_iso8601(value)
+  defp json_safe(%_{} = value), do: inspect(value)
+
+  defp json_safe(value) when is_map(value) do
+    Map.new(value, fn {key, val} -> {json_key(key), json_safe(val)} end)
+  end
+
+  defp json_safe(value) when is_list(value), do: Enum.map(value, &json_safe/1)
+  defp json_safe(value) when is_binary(value), do: safe_string(value)
+  defp json_safe(value) when is_number(value) or is_boolean(value) or is_nil(value), do: value
+  defp json_safe(value) when is_atom(value), do: Atom.to_string(value)
+  defp json_safe(value), do: inspect(value)
+
+  defp json_key(key) when is_binary(key), do: safe_string(key)
+  defp json_key(key) when is_atom(key), do: Atom.to_string(key)
+  defp json_key(key), do: inspect(key)
+
+  defp safe_string(value) do
+    if String.valid?(value), do: value, else: Base.encode64(value)
+  end
+
+  defp field(update, key) when is_map(update) do
+    case Map.fetch(update, key) do
+      {:ok, value} -> value
+      :error -> Map.get(update, String.to_existing_atom(key))
+    end
+  end
+
+  defp field(_update, _key), do: nil
+
+  defp string_field(update, key) do
+    case field(update, key) do
+      value when is_binary(value) ->
+        case String.trim(value) do
+          "" -> nil
+          trimmed -> trimmed
+        end
+
+      _ ->
+        nil
+    end
+  end
+
+  defp parse_check(check) when check in @checks, do: [check]
+
+  defp parse_check(check) when is_binary(check) do
+    case Enum.find(@checks, &(Atom.to_string(&1) == String.trim(check))) do
+      nil -> []
+      found -> [found]
+    end
+  end
+
+  defp parse_check(_check), do: []
+
+  defp normalize_source(source) when is_atom(source), do: normalize_source(Atom.to_string(source))
+  defp normalize_source(source) when is_binary(source),
+    do: source |> String.trim() |> String.downcase()
+
+  defp normalize_source(_source), do: ""
+end
@@ -0,0 +1,364 @@
+defmodule ServiceRadar.Inventory.DuplicateReport do
+  @moduledoc """
+  Reports probable duplicate devices within a partition without changing
+  them, so operators can review candidates before running identity merges.
+
+  Devices are grouped when they share a MAC address, share an identifier in
+  their metadata (`armis_device_id`, `serial_number`, ...), or have
+  identical or near-identical hostnames. Hostname similarity catches
+  candidates the identity reconciler never merges on its own. Each group
+  lists the evidence behind it. Groups with a shared MAC or identifier have
+  `high` confidence; groups held together by hostnames alone have `medium`
+  confidence.
+
+  A device belongs to a partition when it has an identifier there. Zero,
+  broadcast and locally administered (randomized) MACs, IP-address
+  hostnames and ServiceRadar service devices are ignored.
+
+  ## Usage
+
+      DuplicateReport.report(partition: "default", hostname_threshold: 0.9)
+  """
+
+  alias ServiceRadar.Inventory.IdentityReconciler
+  alias ServiceRadar.Repo
+
+  @default_hostname_threshold 0.85
+  @default_max_devices 50_000
+  @default_identifier_keys ~w(armis_device_id netbox_device_id serial_number integration_id)
+  @min_fuzzy_hostname_length 4
+  @hostname_block_prefix 3
+
+  @devices_sql """
+  SELECT d.uid, d.ip, d.mac, d.hostname, d.discovery_sources, d.last_seen_time, d.metadata
+  FROM platform.ocsf_devices d
+  WHERE d.deleted_at IS NULL
+    AND EXISTS (
+      SELECT 1
+      FROM platform.device_identifiers i
+      WHERE i.device_id = d.uid AND COALESCE(i.partition, 'default') = $1
+    )
+  ORDER BY d.uid
+  LIMIT $2
+  """
+
+  @type device :: %{
+          uid: String.t(),
+          ip: String.t() | nil,
+          mac: String.t() | nil,
+          hostname: String.t() | nil,
+          discovery_sources: [String.t()] | nil,
+          last_seen_time: DateTime.t() | nil,
+          metadata: map() | nil
+        }
+
+  @type evidence :: %{
+          type: :mac | :identifier | :hostname,
+          key: String.t() | nil,
+          value: String.t(),
+          device_ids: [String.t()],
+          similarity: float() | nil
+        }
+
+  @type group :: %{confidence: :high | :medium, devices: [device()], evidence: [evidence()]}
+
+  @type report :: %{
+          partition: String.t(),
+          scanned_devices: non_neg_integer(),
+          truncated: boolean(),
+          groups: [group()]
+        }
+
+  @doc "Identifier metadata keys compared when none are given."
+  @spec default_identifier_keys() :: [String.t()]
+  def default_identifier_keys, do: @default_identifier_keys
+
+  @doc """
+  Scans a partition's devices and reports probable duplicates.
+
+  Options:
+    - `:partition` - partition to scan (required)
+    - `:hostname_threshold` - minimum hostname similarity, 0-1 (default
+      #{@default_hostname_threshold}); 1 only groups identical hostnames
+    - `:identifier_keys` - metadata keys compared for shared identifiers
+    - `:max_devices` - maximum devices scanned (default #{@default_max_devices})
+    - `:load` - `fn partition, limit -> {:ok, [device]} end` used instead of
+      reading `ocsf_devices`
+  """
+  @spec report(keyword()) :: {:ok, report()} | {:error, term()}
+  def report(opts) do
+    max_devices = positive(Keyword.get(opts, :max_devices), @default_max_devices)
+    load = Keyword.get(opts, :load, &load_devices/2)
+
+    with {:ok, partition} <- require_partition(Keyword.get(opts, :partition)),
+         {:ok, devices} <- load.(partition, max_devices + 1),
+         {:ok, detected} <- detect(Enum.take(devices, max_devices), opts) do
+      {:ok,
+       Map.merge(detected, %{partition: partition, truncated: length(devices) > max_devices})}
+    end
+  end
+
+  @doc """
+  Groups probable duplicates among devices. Options as for `report/1`.
+  """
+  @spec detect([device()], keyword()) ::
+          {:ok, %{scanned_devices: non_neg_integer(), groups: [group()]}} | {:error, String.t()}
+  def detect(devices, opts \\ []) do
+    threshold = Keyword.get(opts, :hostname_threshold) || @default_hostname_threshold
+    keys = Keyword.get(opts, :identifier_keys) || @default_identifier_keys
+
+    if is_number(threshold) and threshold > 0 and threshold <= 1 do
+      devices =
+        Enum.reject(devices, fn device ->
+          device.uid in [nil, ""] or IdentityReconciler.service_device_id?(device.uid)
+        end)
+
+      evidence = mac_evidence(devices) ++ identifier_evidence(devices, keys)
+      evidence = evidence ++ hostname_evidence(devices, threshold)
+
+      {:ok, %{scanned_devices: length(devices), groups: groups(devices, evidence)}}
+    else
+      {:error, "hostname_threshold must be greater than 0 and at most 1"}
+    end
+  end
+
+  @doc """
+  Lowercases a hostname and drops its domain, so `SW-Core-01.example.com`
+  and `sw-core-01` compare equal. IP-address hostnames normalize to nil.
+  """
+  @spec normalize_hostname(String.t() | nil) :: String.t() | nil
+  def normalize_hostname(hostname) when is_binary(hostname) do
+    name = hostname |> String.trim() |> String.downcase()
+
+    cond do
+      name == "" -> nil
+      match?({:ok, _}, :inet.parse_strict_address(String.to_charlist(name))) -> nil
+      true -> name |> String.split(".", parts: 2) |> hd() |> trim_separators() |> blank_to_nil()
+    end
+  end
+
+  def normalize_hostname(_hostname), do: nil
+
+  @doc """
+  Hostname similarity: 1 minus the Levenshtein distance divided by the
+  length of the longer name.
+  """
+  @spec hostname_similarity(String.t(), String.t()) :: float()
+  def hostname_similarity(a, b) do
+    case max(String.length(a), String.length(b)) do
+      0 -> 1.0
+      longest -> 1 - levenshtein(a, b) / longest
+    end
+  end
+
+  defp mac_evidence(devices) do
+    devices
+    |> Enum.flat_map(fn device -> Enum.map(device_macs(device), &{&1, device.uid}) end)
+    |> shared(fn mac, uids -> evidence(:mac, nil, mac, uids) end)
+  end
+
+  defp identifier_evidence(devices, keys) do
+    Enum.flat_map(keys, fn key ->
+      devices
+      |> Enum.flat_map(fn device ->
+        case device.metadata |> Kernel.||(%{}) |> Map.get(key) |> identifier_value() do
+          nil -> []
+          value -> [{value, device.uid}]
+        end
+      end)
+      |> shared(fn value, uids -> evidence(:identifier, key, value, uids) end)
+    end)
+  end
+
+  # Fuzzy comparison only happens between hostnames sharing a short prefix,
+  # which keeps the scan roughly linear on large partitions.
+  defp hostname_evidence(devices, threshold) do
+    by_name =
+      devices
+      |> Enum.flat_map(fn device ->
+        case normalize_hostname(device.hostname) do
+          nil -> []
+          name -> [{name, device.uid}]
+        end
+      end)
+      |> Enum.group_by(&elem(&1, 0), &elem(&1, 1))
+
+    exact =
+      by_name
+      |> Enum.sort()
+      |> Enum.filter(fn {_name, uids} -> length(uids) > 1 end)
+      |> Enum.map(fn {name, uids} -> evidence(:hostname, nil, name, uids, 1.0) end)
+
+    exact ++ if(threshold < 1, do: fuzzy_hostname_evidence(by_name, threshold), else: [])
+  end
+
+  defp fuzzy_hostname_evidence(by_name, threshold) do
+    by_name
+    |> Map.keys()
+    |> Enum.filter(&(String.length(&1) >= @min_fuzzy_hostname_length))
+    |> Enum.sort()
+    |> Enum.group_by(&String.slice(&1, 0, @hostname_block_prefix))
+    |> Enum.sort()
+    |> Enum.flat_map(fn {_block, names} ->
+      for {a, i} <- Enum.with_index(names),
+          b <- Enum.drop(names, i + 1),
+          similarity <- [hostname_similarity(a, b)],
+          similarity >= threshold do
+        evidence(
+          :hostname,
+          nil,
+          "#{a} ~ #{b}",
+          by_name[a] ++ by_name[b],
+          Float.round(similarity, 2)
+        )
+      end
+    end)
+  end
+
+  defp shared(pairs, build) do
+    pairs
+    |> Enum.uniq()
+    |> Enum.group_by(&elem(&1, 0), &elem(&1, 1))
+    |> Enum.sort()
+    |> Enum.filter(fn {_value, uids} -> length(uids) > 1 end)
+    |> Enum.map(fn {value, uids} -> build.(value, uids) end)
+  end
+
+  defp evidence(type, key, value, uids, similarity \\ nil) do
+    %{type: type, key: key, value: value, device_ids: uids, similarity: similarity}
+  end
+
+  # Devices linked by any evidence, directly or through other devices, form
+  # one group.
+  defp groups(devices, evidence) do
+    root = Enum.reduce(evidence, %{}, &link(&2, &1.device_ids))
+    by_uid = Map.new(devices, &{&1.uid, &1})
+
+    members =
+      evidence
+      |> Enum.flat_map(& &1.device_ids)
+      |> Enum.uniq()
+      |> Enum.group_by(&find(root, &1))
+
+    evidence
+    |> Enum.group_by(&find(root, hd(&1.device_ids)))
+    |> Enum.map(fn {group_root, group_evidence} ->
+      uids = Map.fetch!(members, group_root)
+
+      %{
+        confidence:
+          if(Enum.any?(group_evidence, &(&1.type != :hostname)), do: :high, else: :medium),
+        devices: uids |> Enum.sort() |> Enum.map(&Map.fetch!(by_uid, &1)),
+        evidence: group_evidence
+      }
+    end)
+    |> Enum.sort_by(fn group ->
+      {if(group.confidence == :high, do: 0, else: 1), -length(group.devices),
+       hd(group.devices).uid}
+    end)
+  end
+
+  defp link(root, [first | rest]) do
+    Enum.reduce(rest, root, fn uid, root ->
+      a = find(root, first)
+      b = find(root, uid)
+      if a == b, do: root, else: Map.put(root, b, a)
+    end)
+  end
+
+  defp find(root, uid) do
+    case Map.get(root, uid) do
+      nil -> uid
+      parent -> find(root, parent)
+    end
+  end
+
+  # The mac column may hold a comma-separated list for multi-interface
+  # devices.
+  defp device_macs(%{mac: mac}) when is_binary(mac) do
+    mac
+    |> String.split(",")
+    |> Enum.flat_map(fn raw ->
+      hex = raw |> String.trim() |> String.replace([":", "-", "."], "") |> String.upcase()
+
+      with true <- String.match?(hex, ~r/\A[0-9A-F]{12}\z/),
+           {first, ""} <- Integer.parse(String.slice(hex, 0, 2), 16),
+           true <- Bitwise.band(first, 0x02) == 0 and hex != "000000000000" do
+        [hex |> String.graphemes() |> Enum.chunk_every(2) |> Enum.map_join(":", &Enum.join/1)]
+      else
+        _ -> []
+      end
+    end)
+    |> Enum.uniq()
+  end
+
+  defp device_macs(_device), do: []
+
+  defp identifier_value(nil), do: nil
+  # JSON numbers may decode as floats; 42.0 and "42" are the same identifier.
+  defp identifier_value(value) when is_float(value) and value == trunc(value),
+    do: value |> trunc() |> Integer.to_string()
+
+  defp identifier_value(value), do: value |> to_string() |> String.trim() |> blank_to_nil()
+
+  defp levenshtein(a, b) do
+    b = String.graphemes(b)
+
+    a
+    |> String.graphemes()
+    |> Enum.with_index(1)
+    |> Enum.reduce(Enum.to_list(0..length(b)), fn {char_a, i}, prev ->
+      prev
+      |> Enum.zip(tl(prev))
+      |> Enum.zip(b)
+      |> Enum.reduce([i], fn {{diagonal, above}, char_b}, [left | _] = row ->
+        cost = if char_a == char_b, do: 0, else: 1
+        [min(min(above + 1, left + 1), diagonal + cost) | row]
+      end)
+      |> Enum.reverse()
+    end)
+    |> List.last()
+  end
+
+  defp load_devices(partition, limit) do
+    case Repo.query(@devices_sql, [partition, limit]) do
+      {:ok, %{rows: rows}} ->
+        {:ok,
+         Enum.map(rows, fn [uid, ip, mac, hostname, sources, last_seen, metadata] ->
+           %{
+             uid: uid,
+             ip: ip,
+             mac: mac,
+             hostname: hostname,
+             discovery_sources: sources || [],
+             last_seen_time: last_seen,
+             metadata: metadata || %{}
+           }
+         end)}
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  defp require_partition(partition) do
+    case blank_to_nil(partition) do
+      nil -> {:error, "partition is required"}
+      partition -> {:ok, partition}
+    end
+  end
+
+  defp trim_separators(name), do: String.replace(name, ~r/\A[-_]+|[-_]+\z/, "")
+
+  defp positive(value, _default) when is_integer(value) and value > 0, do: value
+  defp positive(_value, default), do: default
+
+  defp blank_to_nil(value) when is_binary(value) do
+    case String.trim(value) do
+      "" -> nil
+      trimmed -> trimmed
+    end
+  end
+
+  defp blank_to_nil(_value), do: nil
+end
@@ -0,0 +1,150 @@
+defmodule ServiceRadar.Inventory.EnrichmentPipeline do
+  @moduledoc """
+  Ordered, configurable enrichment of device updates at ingestion.
+
+  `ServiceRadar.Inventory.SyncIngestor` runs every normalized update through
+  the enabled stages, in order, before resolving device identities. Each stage
+  is a module implementing `ServiceRadar.Inventory.EnrichmentStage`.
+
+  Built-in stages, in their default order:
+
+    * `:classification` - device enrichment rules (vendor, model, type)
+    * `:geoip` - GeoIP location of public IPs
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Inventory.EnrichmentPipeline,
+        order: [:classification, :risk, :geoip],
+        disabled: [:geoip],
+        plugins: [MyApp.RiskStage]
+
+  or at runtime with `SERVICERADAR_ENRICHMENT_ORDER` and
+  `SERVICERADAR_ENRICHMENT_DISABLED` as comma-separated stage names.
+
+    * `plugins` - extra stage modules, known by their `name/0`
+    * `order` - stage names in the order they run (default: the built-in
+      stages, then the plugins in the order listed)
+    * `disabled` - stage names to skip
+
+  Stages are validated and registered when the application starts. Unknown
+  stages, and stages ordered before an enabled stage they require, are logged
+  and skipped. A stage that raises is logged and leaves the update unchanged.
+  """
+
+  alias ServiceRadar.Inventory.EnrichmentStages
+
+  require Logger
+
+  @stages_key {__MODULE__, :stages}
+  @builtin_stages [EnrichmentStages.Classification, EnrichmentStages.GeoIP]
+
+  @type stage :: module()
+
+  @doc "Built-in stage modules, in their default order."
+  @spec builtin_stages() :: [stage()]
+  def builtin_stages, do: @builtin_stages
+
+  @doc """
+  Validates and registers the configured stages, replacing any registered
+  before. Returns the enabled stages in run order.
+  """
+  @spec register!() :: [stage()]
+  def register! do
+    stages =
+      :serviceradar_core
+      |> Application.get_env(__MODULE__, [])
+      |> compile()
+
+    :persistent_term.put(@stages_key, stages)
+    stages
+  end
+
+  @doc "The registered stages in run order, or the defaults when none are registered."
+  @spec stages() :: [stage()]
+  def stages do
+    case :persistent_term.get(@stages_key, nil) do
+      nil -> compile([])
+      stages -> stages
+    end
+  end
+
+  @doc """
+  Resolves `config` (see the module doc) to the enabled stage modules in run
+  order, logging every stage it skips.
+  """
+  @spec compile(keyword()) :: [stage()]
+  def compile(config) do
+    {stages, skipped} = resolve(config)
+
+    Enum.each(skipped, fn {name, reason} ->
+      Logger.warning("Skipping enrichment stage #{inspect(name)}: #{reason}")
+    end)
+
+    stages
+  end
+
+  @doc """
+  Like `compile/1`, but returns the skipped stages and the reason each was
+  skipped instead of logging them.
+  """
+  @spec resolve(keyword()) :: {[stage()], [{term(), String.t()}]}
+  def resolve(config) do
+    available = @builtin_stages ++ List.wrap(Keyword.get(config, :plugins, []))
+    by_name = Map.new(available, &{&1.name(), &1})
+    default_order = Enum.map(available, & &1.name())
+    order = config |> Keyword.get(:order, default_order) |> Enum.map(&to_name/1)
+    disabled = config |> Keyword.get(:disabled, []) |> Enum.map(&to_name/1)
+    enabled = Enum.reject(order, &(&1 in disabled))
+
+    {stages, skipped, _ran} =
+      Enum.reduce(enabled, {[], [], MapSet.new()}, fn name, {stages, skipped, ran} ->
+        case Map.fetch(by_name, name) do
+          :error ->
+            {stages, [{name, "unknown stage"} | skipped], ran}
+
+          {:ok, module} ->
+            case unmet_requirement(module, enabled, ran) do
+              nil ->
+                {[module | stages], skipped, MapSet.put(ran, name)}
+
+              required ->
+                reason = "requires #{inspect(required)}, which must run before it"
+                {stages, [{name, reason} | skipped], ran}
+            end
+        end
+      end)
+
+    {Enum.reverse(stages), Enum.reverse(skipped)}
+  end
+
+  # An enabled stage the module requires that has not run yet.
+  defp unmet_requirement(module, enabled, ran) do
+    Enum.find(module.requires(), &(&1 in enabled and not MapSet.member?(ran, &1)))
+  end
+
+  @doc "Runs each update through `stages` in order."
+  @spec run([map()], [stage()]) :: [map()]
+  def run(updates, stages \\ stages())
+
+  def run(updates, []), do: updates
+
+  def run(updates, stages) do
+    Enum.map(updates, fn update -> Enum.reduce(stages, update, &run_stage/2) end)
+  end
+
+  defp run_stage(stage, update) do
+    stage.enrich(update)
+  rescue
+    e ->
+      Logger.warning("EnrichmentPipeline: stage #{inspect(stage.name())} failed: #{inspect(e)}")
+      update
+  end
+
+  defp to_name(name) when is_atom(name), do: name
+
+  defp to_name(name) when is_binary(name) do
+    name |> String.trim() |> String.to_existing_atom()
+  rescue
+    ArgumentError -> name
+  end
+end
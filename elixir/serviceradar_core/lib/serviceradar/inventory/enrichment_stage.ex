@@ -0,0 +1,28 @@
+defmodule ServiceRadar.Inventory.EnrichmentStage do
+  @moduledoc """
+  Behaviour for a stage of the device enrichment pipeline
+  (`ServiceRadar.Inventory.EnrichmentPipeline`).
+
+  A stage receives one normalized device update from
+  `ServiceRadar.Inventory.SyncIngestor` and returns it enriched. The update is
+  a map with `:device_id`, `:ip`, `:mac`, `:hostname`, `:partition`,
+  `:metadata`, `:tags`, `:timestamp`, `:is_available` and `:source`, plus any
+  keys added by earlier stages. A stage must keep those keys and their types;
+  it usually adds metadata keys or a key of its own.
+
+  `c:requires/0` names the stages whose output the stage reads. The pipeline
+  refuses an order that runs a stage before an enabled stage it requires. A
+  required stage that is disabled is not an error: the stage runs without it.
+  """
+
+  @type update :: map()
+
+  @doc "Unique stage name used in the pipeline configuration."
+  @callback name() :: atom()
+
+  @doc "Stages that must run before this one when they are enabled."
+  @callback requires() :: [atom()]
+
+  @doc "Enriches one normalized device update."
+  @callback enrich(update()) :: update()
+end
@@ -0,0 +1,23 @@
+defmodule ServiceRadar.Inventory.EnrichmentStages.Classification do
+  @moduledoc """
+  Enrichment stage that matches the update against the device enrichment rules
+  (`ServiceRadar.Inventory.DeviceEnrichmentRules`).
+
+  Adds `:classification`, which the sync ingestor uses for vendor, model and
+  device type and records in the classification metadata keys. Without this
+  stage devices are typed from the update fields alone.
+  """
+
+  @behaviour ServiceRadar.Inventory.EnrichmentStage
+
+  alias ServiceRadar.Inventory.DeviceEnrichmentRules
+
+  @impl true
+  def name, do: :classification
+
+  @impl true
+  def requires, do: []
+
+  @impl true
+  def enrich(update), do: Map.put(update, :classification, DeviceEnrichmentRules.classify(update))
+end
@@ -0,0 +1,25 @@
+defmodule ServiceRadar.Inventory.EnrichmentStages.GeoIP do
+  @moduledoc """
+  Enrichment stage that adds the `geo_*` metadata keys for public device IPs
+  (`ServiceRadar.Inventory.DeviceGeoEnrichment`).
+
+  The lookup is a no-op while GeoIP enrichment is disabled or its database is
+  not loaded.
+  """
+
+  @behaviour ServiceRadar.Inventory.EnrichmentStage
+
+  alias ServiceRadar.Inventory.DeviceGeoEnrichment
+
+  @impl true
+  def name, do: :geoip
+
+  @impl true
+  def requires, do: []
+
+  @impl true
+  def enrich(update) do
+    metadata = DeviceGeoEnrichment.put_metadata(update.metadata || %{}, update.ip)
+    %{update | metadata: metadata}
+  end
+end
@@ -0,0 +1,234 @@
+defmodule ServiceRadar.Inventory.FieldConflictDetector do
+  @moduledoc """
+  Detects discovery sources that disagree about a device's descriptive fields
+  during sync reconciliation and records them as
+  `ServiceRadar.Inventory.DeviceFieldConflict` rows.
+
+  Detection never changes the merge outcome: the value chosen by
+  `ServiceRadar.Inventory.SourceConfidence` still applies. The conflict just
+  records which sources said what.
+
+  Two kinds of disagreement are caught:
+
+    * two sources reporting different values for the same device in one batch
+    * a source reporting a value different from the one already stored, when
+      the stored value came from another source. Devices do not track
+      per-field provenance, so the stored value is attributed to the most
+      confident source that reported the device before.
+
+  Values are compared case-insensitively after trimming; empty values never
+  conflict. Disable detection with:
+
+      config :serviceradar_core, ServiceRadar.Inventory.FieldConflictDetector,
+        enabled: false
+  """
+
+  import Ecto.Query, only: [from: 2]
+
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceFieldConflict
+  alias ServiceRadar.Inventory.SourceConfidence
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @fields [:hostname, :type, :vendor_name, :model]
+
+  @type observation :: %{
+          required(:uid) => String.t(),
+          required(:discovery_sources) => [String.t()],
+          optional(atom()) => term()
+        }
+
+  @type conflict :: %{
+          device_id: String.t(),
+          field: String.t(),
+          source_values: %{String.t() => String.t()},
+          winning_source: String.t(),
+          winning_value: String.t()
+        }
+
+  @doc "Fields checked for disagreements."
+  @spec fields() :: [atom()]
+  def fields, do: @fields
+
+  @spec enabled?() :: boolean()
+  def enabled? do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(:enabled, true)
+  end
+
+  @doc """
+  Returns the conflicts between the per-source `records` of one batch (in
+  arrival order, one per update, each carrying a single discovery source) and
+  the `existing` devices keyed by uid.
+
+  The winner of each conflict is the most confident source, with ties going
+  to the latest report, which matches how the sync merge picks values.
+  """
+  @spec detect([observation()], %{String.t() => observation()}, map()) :: [conflict()]
+  def detect(records, existing, confidence \\ SourceConfidence.table()) do
+    records
+    |> Enum.group_by(& &1.uid)
+    |> Enum.flat_map(fn {uid, device_records} ->
+      detect_device(uid, device_records, Map.get(existing, uid), confidence)
+    end)
+  end
+
+  @doc """
+  Loads the fields compared by `detect/3` for the given device uids.
+  """
+  @spec load_existing([String.t()]) :: %{String.t() => observation()}
+  def load_existing([]), do: %{}
+
+  def load_existing(uids) do
+    from(d in Device,
+      where: d.uid in ^uids,
+      select: map(d, [:uid, :discovery_sources, :hostname, :type, :vendor_name, :model])
+    )
+    |> Repo.all()
+    |> Map.new(&{&1.uid, &1})
+  end
+
+  @doc """
+  Opens conflicts, or updates the open conflict for the same device and field
+  with the latest values.
+  """
+  @spec record([conflict()]) :: :ok | {:error, term()}
+  def record([]), do: :ok
+
+  def record(conflicts) do
+    now = DateTime.utc_now()
+
+    rows =
+      Enum.map(conflicts, fn conflict ->
+        Map.merge(conflict, %{
+          id: Ecto.UUID.generate(),
+          status: :open,
+          occurrences: 1,
+          first_seen_at: now,
+          last_seen_at: now
+        })
+      end)
+
+    Repo.insert_all(DeviceFieldConflict, rows,
+      on_conflict: upsert_query(),
+      conflict_target: {:unsafe_fragment, "(device_id, field) WHERE status = 'open'"}
+    )
+
+    :ok
+  rescue
+    e ->
+      Logger.warning("Failed to record device field conflicts: #{inspect(e)}")
+      {:error, e}
+  end
+
+  defp upsert_query do
+    from(c in DeviceFieldConflict,
+      update: [
+        set: [
+          source_values:
+            fragment("COALESCE(?, '{}'::jsonb) || EXCLUDED.source_values", c.source_values),
+          winning_source: fragment("EXCLUDED.winning_source"),
+          winning_value: fragment("EXCLUDED.winning_value"),
+          last_seen_at: fragment("EXCLUDED.last_seen_at")
+        ],
+        inc: [occurrences: 1]
+      ]
+    )
+  end
+
+  defp detect_device(uid, records, existing, confidence) do
+    batch_sources = records |> Enum.flat_map(& &1.discovery_sources) |> MapSet.new()
+    stored_source = stored_value_source(existing, batch_sources, confidence)
+
+    Enum.flat_map(@fields, fn field ->
+      observations =
+        stored_observation(existing, stored_source, field) ++
+          Enum.flat_map(records, &record_observations(&1, field))
+
+      case conflict_for(observations, confidence) do
+        nil -> []
+        conflict -> [Map.merge(conflict, %{device_id: uid, field: Atom.to_string(field)})]
+      end
+    end)
+  end
+
+  # A source updating a value it reported itself is not a conflict, so the
+  # stored value is only compared when no source in this batch could own it.
+  defp stored_value_source(nil, _batch_sources, _confidence), do: nil
+
+  defp stored_value_source(existing, batch_sources, confidence) do
+    source =
+      (existing.discovery_sources || [])
+      |> Enum.reject(&(&1 in [nil, ""]))
+      |> Enum.max_by(&SourceConfidence.confidence(&1, confidence), fn -> nil end)
+
+    if source && not MapSet.member?(batch_sources, source), do: source
+  end
+
+  defp stored_observation(_existing, nil, _field), do: []
+
+  defp stored_observation(existing, source, field) do
+    case present(Map.get(existing, field)) do
+      nil -> []
+      value -> [{source, value}]
+    end
+  end
+
+  defp record_observations(record, field) do
+    case present(Map.get(record, field)) do
+      nil -> []
+      value -> Enum.map(record.discovery_sources, &{&1, value})
+    end
+  end
+
+  defp conflict_for(observations, confidence) do
+    # The latest value per source counts; a source changing its own mind is
+    # not a conflict.
+    by_source =
+      Enum.reduce(observations, %{}, fn {source, value}, acc -> Map.put(acc, source, value) end)
+
+    distinct = by_source |> Map.values() |> Enum.uniq_by(&normalize/1)
+
+    if length(distinct) > 1 do
+      {winning_source, winning_value} = winner(observations, by_source, confidence)
+
+      %{
+        source_values: by_source,
+        winning_source: winning_source,
+        winning_value: winning_value
+      }
+    end
+  end
+
+  defp winner(observations, by_source, confidence) do
+    # Order sources by their latest report so later reports win ties.
+    observations
+    |> Enum.map(&elem(&1, 0))
+    |> Enum.reverse()
+    |> Enum.uniq()
+    |> Enum.reverse()
+    |> Enum.reduce(nil, fn source, best ->
+      score = SourceConfidence.confidence(source, confidence)
+
+      case best do
+        {_best_source, best_score} when best_score > score -> best
+        _ -> {source, score}
+      end
+    end)
+    |> then(fn {source, _score} -> {source, Map.fetch!(by_source, source)} end)
+  end
+
+  defp present(value) when is_binary(value) do
+    case String.trim(value) do
+      "" -> nil
+      trimmed -> trimmed
+    end
+  end
+
+  defp present(_value), do: nil
+
+  defp normalize(value), do: String.downcase(value)
+end
@@ -8,7 +8,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
 
   ## Resolution Priority
 
-  1. Strong identifiers (Agent ID > Armis ID > Integration ID > NetBox ID > MAC)
+  1. Strong identifiers (Agent ID > Armis ID > Integration ID > NetBox ID > custom > MAC)
      - Hash to deterministic `sr:` UUID
   2. Existing `sr:` UUID in update
      - Preserve as-is
@@ -22,7 +22,9 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   2. `armis_device_id` - Armis platform device ID
   3. `integration_id` - Generic integration ID
   4. `netbox_device_id` - NetBox device ID
-  5. `mac` - MAC address (normalized)
+  5. Custom identifier types, in declared order (see
+     `ServiceRadar.Inventory.CustomIdentifierTypes`)
+  6. `mac` - MAC address (normalized)
 
   IP is a "weak" identifier only used when no strong identifiers are present.
   """
@@ -33,19 +35,21 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   alias ServiceRadar.Ash.Page
   alias ServiceRadar.Identity.DeviceAliasState
   alias ServiceRadar.Infrastructure.Agent
+  alias ServiceRadar.Inventory.CustomIdentifierTypes
   alias ServiceRadar.Inventory.Device
   alias ServiceRadar.Inventory.DeviceIdentifier
   alias ServiceRadar.Inventory.Interface
   alias ServiceRadar.Inventory.MergeAudit
+  alias ServiceRadar.Inventory.MergeEvents
   alias ServiceRadar.Monitoring.Alert
   alias ServiceRadar.Monitoring.ServiceCheck
 
   require Ash.Query
   require Logger
 
-  # Identifier types in priority order (lower index = higher priority)
-  @identifier_priority [:agent_id, :armis_device_id, :integration_id, :netbox_device_id, :mac]
-  @strong_non_mac_identifier_types [
+  # Built-in non-MAC strong identifier types in priority order (lower index =
+  # higher priority). Custom types rank after these and before MAC.
+  @builtin_non_mac_identifier_types [
     :agent_id,
     :armis_device_id,
     :integration_id,
@@ -54,6 +58,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   @provisional_promotion_required_repeat_count 2
 
   @type strong_identifiers :: %{
+          optional(:custom) => %{optional(atom()) => String.t()},
           agent_id: String.t() | nil,
           armis_id: String.t() | nil,
           integration_id: String.t() | nil,
@@ -131,6 +136,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
       armis_id: get_trimmed(metadata, "armis_device_id"),
       integration_id: get_integration_id(metadata),
       netbox_id: get_trimmed(metadata, "netbox_device_id"),
+      custom: CustomIdentifierTypes.extract(metadata),
       mac: normalize_mac(update[:mac]),
       ip: String.trim(update[:ip] || ""),
       partition: partition
@@ -181,11 +187,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   """
   @spec has_strong_identifier?(strong_identifiers()) :: boolean()
   def has_strong_identifier?(ids) do
-    ids_get(ids, :agent_id) != nil or
-      ids_get(ids, :armis_id) != nil or
-      ids_get(ids, :integration_id) != nil or
-      ids_get(ids, :netbox_id) != nil or
-      ids_get(ids, :mac) != nil
+    Enum.any?(identifier_priority(), &(get_identifier_value(ids, &1) != nil))
   end
 
   @doc """
@@ -193,14 +195,24 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   """
   @spec highest_priority_identifier(strong_identifiers()) :: {atom() | nil, String.t() | nil}
   def highest_priority_identifier(ids) do
-    cond do
-      ids_get(ids, :agent_id) != nil -> {:agent_id, ids_get(ids, :agent_id)}
-      ids_get(ids, :armis_id) != nil -> {:armis_device_id, ids_get(ids, :armis_id)}
-      ids_get(ids, :integration_id) != nil -> {:integration_id, ids_get(ids, :integration_id)}
-      ids_get(ids, :netbox_id) != nil -> {:netbox_device_id, ids_get(ids, :netbox_id)}
-      ids_get(ids, :mac) != nil -> {:mac, ids_get(ids, :mac)}
-      true -> {nil, nil}
-    end
+    Enum.find_value(identifier_priority(), {nil, nil}, fn id_type ->
+      case get_identifier_value(ids, id_type) do
+        nil -> nil
+        value -> {id_type, value}
+      end
+    end)
+  end
+
+  @doc """
+  Identifier types in priority order, including registered custom types.
+  """
+  @spec identifier_priority() :: [atom()]
+  def identifier_priority do
+    strong_non_mac_identifier_types() ++ [:mac]
+  end
+
+  defp strong_non_mac_identifier_types do
+    @builtin_non_mac_identifier_types ++ CustomIdentifierTypes.names()
   end
 
   @doc """
@@ -235,7 +247,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   defp get_identifier_value(ids, :integration_id), do: ids_get(ids, :integration_id)
   defp get_identifier_value(ids, :netbox_device_id), do: ids_get(ids, :netbox_id)
   defp get_identifier_value(ids, :mac), do: ids_get(ids, :mac)
-  defp get_identifier_value(_ids, _type), do: nil
+  defp get_identifier_value(ids, type), do: Map.get(ids_custom(ids), type)
 
   defp lookup_device_identifier(id_type, id_value, partition, actor) do
     query_opts = if actor, do: [actor: actor], else: []
@@ -408,6 +420,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
       |> maybe_add_seed("armis", ids_get(ids, :armis_id))
       |> maybe_add_seed("integration", ids_get(ids, :integration_id))
       |> maybe_add_seed("netbox", ids_get(ids, :netbox_id))
+      |> add_custom_seeds(ids)
       |> maybe_add_seed("mac", ids_get(ids, :mac))
 
     hash_input =
@@ -438,6 +451,14 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   defp maybe_add_seed(acc, _prefix, nil), do: acc
   defp maybe_add_seed(acc, prefix, value), do: acc ++ ["#{prefix}:#{value}"]
 
+  defp add_custom_seeds(acc, ids) do
+    custom = ids_custom(ids)
+
+    Enum.reduce(CustomIdentifierTypes.names(), acc, fn id_type, acc ->
+      maybe_add_seed(acc, "custom:#{id_type}", Map.get(custom, id_type))
+    end)
+  end
+
   defp return_random_uuid do
     "sr:" <> Ecto.UUID.generate()
   end
@@ -488,6 +509,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
         ids_get(ids, :netbox_id),
         partition
       )
+      |> add_custom_identifiers(canonical_id, ids, partition)
       |> maybe_add_identifier(canonical_id, :mac, ids_get(ids, :mac), partition)
 
     results =
@@ -557,7 +579,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   end
 
   defp current_non_mac_strong_types(ids) do
-    Enum.filter(@strong_non_mac_identifier_types, fn type ->
+    Enum.filter(strong_non_mac_identifier_types(), fn type ->
       present_id?(get_identifier_value(ids, type))
     end)
   end
@@ -566,7 +588,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
     query =
       DeviceIdentifier
       |> Ash.Query.for_read(:by_device, %{device_id: device_id})
-      |> Ash.Query.filter(identifier_type in ^@strong_non_mac_identifier_types)
+      |> Ash.Query.filter(identifier_type in ^strong_non_mac_identifier_types())
       |> maybe_filter_identifier_partition(partition)
 
     case Ash.read(query, actor: actor) do
@@ -704,6 +726,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
                 armis_id: ids_get(ids, :armis_id),
                 integration_id: ids_get(ids, :integration_id),
                 netbox_id: ids_get(ids, :netbox_id),
+                custom: ids_custom(ids),
                 mac: ids_get(ids, :mac)
               }
             }
@@ -729,6 +752,13 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   defp ids_get(ids, key) when is_map(ids), do: Map.get(ids, key)
   defp ids_get(_ids, _key), do: nil
 
+  defp ids_custom(ids) do
+    case ids_get(ids, :custom) do
+      %{} = custom -> custom
+      _ -> %{}
+    end
+  end
+
   defp ids_get_string(ids, key) do
     case ids_get(ids, key) do
       value when is_binary(value) -> value
@@ -807,10 +837,12 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   defp lookup_identifier_matches(ids, actor) do
     partition = ids_get_partition(ids)
 
-    Enum.reduce(@identifier_priority, %{}, fn id_type, acc ->
+    Enum.reduce(identifier_priority(), %{}, fn id_type, acc ->
+      id_partition = CustomIdentifierTypes.partition(id_type, partition)
+
       with id_value when not is_nil(id_value) <- get_identifier_value(ids, id_type),
            {:ok, device_id} when is_binary(device_id) and device_id != "" <-
-             lookup_device_identifier(id_type, id_value, partition, actor) do
+             lookup_device_identifier(id_type, id_value, id_partition, actor) do
         Map.put(acc, id_type, %{value: id_value, device_id: device_id})
       else
         _ -> acc
@@ -832,7 +864,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   end
 
   defp highest_priority_match(matches) do
-    Enum.find_value(@identifier_priority, fn id_type ->
+    Enum.find_value(identifier_priority(), fn id_type ->
       case Map.get(matches, id_type) do
         %{device_id: device_id} -> device_id
         _ -> nil
@@ -987,7 +1019,7 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
   defp build_identifier_index(actor) do
     query =
       DeviceIdentifier
-      |> Ash.Query.filter(identifier_type in ^@identifier_priority)
+      |> Ash.Query.filter(identifier_type in ^identifier_priority())
       |> Ash.Query.select([:device_id, :identifier_type, :identifier_value, :partition])
 
     query
@@ -1220,6 +1252,10 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
 
   @doc """
   Merge a duplicate device into a canonical device and reassign related records.
+
+  Every merge is recorded as an identity merge event (see
+  `ServiceRadar.Inventory.MergeEvents`); `:record_event` replaces how the
+  event is created.
   """
   @spec merge_devices(String.t(), String.t(), keyword()) :: :ok | {:error, term()}
   def merge_devices(from_device_id, to_device_id, opts \\ []) do
@@ -1246,6 +1282,8 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
         with {:ok, %Device{} = from_device} <-
                Device.get_by_uid(from_device_id, false, actor: actor),
              {:ok, %Device{}} <- Device.get_by_uid(to_device_id, false, actor: actor),
+             from_identifiers = device_identifiers(from_device_id, actor),
+             to_identifiers = device_identifiers(to_device_id, actor),
              :ok <- reassign_device_identifiers(from_device_id, to_device_id, actor),
              :ok <- reassign_service_checks(from_device_id, to_device_id, actor),
              :ok <- reassign_alerts(from_device_id, to_device_id, actor),
@@ -1264,12 +1302,19 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
                  actor: actor
                ),
              {:ok, _} <- Ash.destroy(from_device, actor: actor, action: :destroy) do
-          :ok
+          {:ok,
+           MergeEvents.build(from_device_id, to_device_id,
+             reason: reason,
+             details: details,
+             from_identifiers: from_identifiers,
+             to_identifiers: to_identifiers
+           )}
         end
       end)
       |> case do
-        {:ok, :ok} ->
+        {:ok, {:ok, merge}} ->
           emit_merge_executed_telemetry(reason, from_device_id, to_device_id)
+          record_merge_event(merge, actor, opts)
           :ok
 
         {:ok, other} ->
@@ -1283,6 +1328,27 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
     end
   end
 
+  defp device_identifiers(device_id, actor) do
+    case DeviceIdentifier
+         |> Ash.Query.for_read(:by_device, %{device_id: device_id})
+         |> Ash.read(actor: actor) do
+      {:ok, identifiers} -> identifiers
+      _ -> []
+    end
+  end
+
+  defp record_merge_event(merge, actor, opts) do
+    case MergeEvents.record(merge, [actor: actor] ++ Keyword.take(opts, [:record_event])) do
+      :ok ->
+        :ok
+
+      {:error, reason} ->
+        Logger.warning(
+          "Failed to record merge event for #{merge.canonical_device_id}: #{inspect(reason)}"
+        )
+    end
+  end
+
   defp emit_merge_executed_telemetry(reason, from_device_id, to_device_id) do
     :telemetry.execute(
       [:serviceradar, :identity_reconciler, :merge, :executed],
@@ -1598,6 +1664,13 @@ defmodule ServiceRadar.Inventory.IdentityReconciler do
     Enum.member?(existing_keys, {record.timestamp, record.interface_uid})
   end
 
+  defp add_custom_identifiers(acc, device_id, ids, partition) do
+    Enum.reduce(ids_custom(ids), acc, fn {id_type, id_value}, acc ->
+      id_partition = CustomIdentifierTypes.partition(id_type, partition)
+      maybe_add_identifier(acc, device_id, id_type, id_value, id_partition)
+    end)
+  end
+
   defp maybe_add_identifier(acc, _device_id, _id_type, nil, _partition), do: acc
 
   defp maybe_add_identifier(acc, device_id, :mac, id_value, partition) do
@@ -0,0 +1,325 @@
+defmodule ServiceRadar.Inventory.MergeEvents do
+  @moduledoc """
+  Records every device identity merge as an `identity.merge` OCSF event, so
+  operators can review what the identity reconciler merged and catch
+  erroneous merges, such as devices of different partitions collapsed into
+  one.
+
+  `ServiceRadar.Inventory.IdentityReconciler.merge_devices/3` records the
+  event once a merge has committed. The event carries the surviving
+  canonical device ID, the merged-away device IDs, the identifiers that
+  caused the merge and the partitions of both devices' identifiers; merges
+  that span more than one partition are flagged `cross_partition` and
+  recorded with medium severity.
+
+  The triggering identifiers are the ones the caller named in the merge
+  details (`identifiers`, or the conflicting IP of IP alias merges). Merges
+  without them, such as scheduled reconciliation, report the identifiers
+  both devices had in common.
+
+  With a webhook configured, each merge is also POSTed there as JSON for
+  review. The URL must pass the outbound URL policy (https, public host).
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Inventory.MergeEvents,
+        webhook_url: "https://review.example.com/identity-merges",
+        webhook_headers: %{"authorization" => "Bearer ..."}
+
+  or at runtime with `SERVICERADAR_IDENTITY_MERGE_WEBHOOK_URL` and
+  `SERVICERADAR_IDENTITY_MERGE_WEBHOOK_TOKEN`.
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Events.PubSub, as: EventsPubSub
+  alias ServiceRadar.Monitoring.OcsfEvent
+  alias ServiceRadar.Observability.OutboundFeedPolicy
+
+  require Logger
+
+  @log_name "identity.merge"
+  @provider "serviceradar.inventory"
+  @default_timeout 10_000
+  @ip_detail_keys [:alias_ip, :camera_ip, :ip]
+  # Keys of the reconciler's identifier details that differ from the
+  # identifier types stored in device_identifiers.
+  @detail_types %{"armis_id" => "armis_device_id", "netbox_id" => "netbox_device_id"}
+
+  @type identifier :: %{type: String.t(), value: String.t(), partition: String.t() | nil}
+
+  @type merge :: %{
+          canonical_device_id: String.t(),
+          merged_device_ids: [String.t()],
+          reason: String.t(),
+          identifiers: [identifier()],
+          partitions: [String.t()],
+          cross_partition: boolean()
+        }
+
+  @doc "OCSF log name of identity merge events."
+  @spec log_name() :: String.t()
+  def log_name, do: @log_name
+
+  @doc """
+  Describes the merge of `from_device_id` into `to_device_id`.
+
+  Options:
+    - `:reason` - merge reason (default `"identity_resolution"`)
+    - `:details` - merge details, as passed to `merge_devices/3`
+    - `:from_identifiers`, `:to_identifiers` - identifiers each device had
+      before the merge (`DeviceIdentifier` records or maps with
+      `identifier_type`, `identifier_value` and `partition`)
+  """
+  @spec build(String.t(), String.t(), keyword()) :: merge()
+  def build(from_device_id, to_device_id, opts \\ []) do
+    from_identifiers = Enum.map(Keyword.get(opts, :from_identifiers, []), &identifier/1)
+    to_identifiers = Enum.map(Keyword.get(opts, :to_identifiers, []), &identifier/1)
+    known = from_identifiers ++ to_identifiers
+
+    triggering =
+      case detail_identifiers(Keyword.get(opts, :details, %{})) do
+        [] -> shared_identifiers(from_identifiers, to_identifiers)
+        identifiers -> Enum.map(identifiers, &with_partition(&1, known))
+      end
+
+    partitions =
+      known
+      |> Enum.map(& &1.partition)
+      |> Enum.reject(&(&1 in [nil, ""]))
+      |> Enum.uniq()
+      |> Enum.sort()
+
+    %{
+      canonical_device_id: to_device_id,
+      merged_device_ids: [from_device_id],
+      reason: Keyword.get(opts, :reason, "identity_resolution"),
+      identifiers: Enum.uniq(triggering),
+      partitions: partitions,
+      cross_partition: length(partitions) > 1
+    }
+  end
+
+  @doc """
+  Records the OCSF event of a merge, broadcasts it and notifies the webhook,
+  if one is configured, in the background.
+
+  Options:
+    - `:actor` - actor to record the event as (default: system actor)
+    - `:record_event` - 2-arity function used instead of creating the event
+    - the options of `notify_webhook/2`
+  """
+  @spec record(merge(), keyword()) :: :ok | {:error, term()}
+  def record(merge, opts \\ []) do
+    actor = Keyword.get(opts, :actor, SystemActor.system(:identity_merge))
+    record_event = Keyword.get(opts, :record_event, &create_event/2)
+
+    if webhook_url(opts), do: Task.start(fn -> notify_webhook(merge, opts) end)
+
+    with {:ok, event} <- record_event.(event_attrs(merge), actor) do
+      _ = EventsPubSub.broadcast_event(event)
+      :ok
+    end
+  rescue
+    error -> {:error, error}
+  end
+
+  @doc """
+  POSTs a merge to the webhook as JSON. Returns `:skipped` when no webhook
+  is configured.
+
+  Options:
+    - `:webhook_url`, `:webhook_headers` - override the configuration
+    - `:validate_url` - URL policy check (default `OutboundFeedPolicy.validate/1`)
+    - `:http_post` - `fn url, req_opts -> {:ok, response} | {:error, reason} end`
+  """
+  @spec notify_webhook(merge(), keyword()) :: :ok | :skipped | {:error, String.t()}
+  def notify_webhook(merge, opts \\ []) do
+    case webhook_url(opts) do
+      nil -> :skipped
+      url -> post_webhook(url, merge, opts)
+    end
+  end
+
+  defp post_webhook(url, merge, opts) do
+    validate_url = Keyword.get(opts, :validate_url, &OutboundFeedPolicy.validate/1)
+    http_post = Keyword.get(opts, :http_post, &Req.post/2)
+    headers = Keyword.get_lazy(opts, :webhook_headers, fn -> config(:webhook_headers, %{}) end)
+    payload = Map.merge(%{event: @log_name, time: DateTime.to_iso8601(DateTime.utc_now())}, merge)
+
+    request_opts =
+      @default_timeout
+      |> OutboundFeedPolicy.req_opts()
+      |> Keyword.merge(json: payload, headers: headers)
+
+    with :ok <- validate_url.(url),
+         {:ok, %{status: status}} when status in 200..299 <- http_post.(url, request_opts) do
+      :ok
+    else
+      {:ok, %{status: status}} -> webhook_failed(url, "HTTP #{status}")
+      {:error, reason} -> webhook_failed(url, OutboundFeedPolicy.format_reason(reason))
+    end
+  end
+
+  defp webhook_failed(url, reason) do
+    Logger.warning(
+      "Identity merge webhook #{OutboundFeedPolicy.redact_url(url)} failed: #{reason}"
+    )
+
+    {:error, reason}
+  end
+
+  defp webhook_url(opts) do
+    case Keyword.get_lazy(opts, :webhook_url, fn -> config(:webhook_url) end) do
+      url when is_binary(url) and url != "" -> url
+      _ -> nil
+    end
+  end
+
+  defp identifier(record) do
+    %{
+      type: to_string(field(record, :identifier_type)),
+      value: to_string(field(record, :identifier_value)),
+      partition: field(record, :partition)
+    }
+  end
+
+  defp detail_identifiers(details) when is_map(details) do
+    listed =
+      case field(details, :identifiers) do
+        identifiers when is_list(identifiers) -> Enum.flat_map(identifiers, &listed_identifier/1)
+        identifiers when is_map(identifiers) -> identifier_map(identifiers)
+        _ -> []
+      end
+
+    ips =
+      Enum.flat_map(@ip_detail_keys, fn key ->
+        case field(details, key) do
+          ip when is_binary(ip) and ip != "" -> [%{type: "ip", value: ip}]
+          _ -> []
+        end
+      end)
+
+    listed ++ ips
+  end
+
+  defp detail_identifiers(_details), do: []
+
+  defp listed_identifier(identifier) when is_map(identifier) do
+    case {field(identifier, :type), field(identifier, :value)} do
+      {type, value} when not is_nil(type) and value not in [nil, ""] ->
+        [%{type: to_string(type), value: to_string(value)}]
+
+      _ ->
+        []
+    end
+  end
+
+  defp listed_identifier(_identifier), do: []
+
+  defp identifier_map(identifiers) do
+    Enum.flat_map(identifiers, fn
+      {key, custom} when key in [:custom, "custom"] and is_map(custom) ->
+        identifier_map(custom)
+
+      {type, value} when is_binary(value) and value != "" ->
+        type = to_string(type)
+        [%{type: Map.get(@detail_types, type, type), value: value}]
+
+      _ ->
+        []
+    end)
+  end
+
+  defp with_partition(identifier, known) do
+    partition =
+      Enum.find_value(known, fn %{type: type, value: value, partition: partition} ->
+        if type == identifier.type and value == identifier.value, do: partition
+      end)
+
+    Map.put(identifier, :partition, partition)
+  end
+
+  defp shared_identifiers(from_identifiers, to_identifiers) do
+    Enum.filter(from_identifiers, fn %{type: type, value: value} ->
+      Enum.any?(to_identifiers, &(&1.type == type and &1.value == value))
+    end)
+  end
+
+  defp event_attrs(merge) do
+    metadata = %{
+      "canonical_device_id" => merge.canonical_device_id,
+      "merged_device_ids" => merge.merged_device_ids,
+      "reason" => merge.reason,
+      "identifiers" =>
+        Enum.map(merge.identifiers, &Map.new(&1, fn {key, value} -> {to_string(key), value} end)),
+      "partitions" => merge.partitions,
+      "cross_partition" => merge.cross_partition
+    }
+
+    {severity_id, severity, log_level} =
+      if merge.cross_partition, do: {3, "Medium", "warning"}, else: {1, "Informational", "info"}
+
+    %{
+      time: DateTime.truncate(DateTime.utc_now(), :microsecond),
+      class_uid: 1008,
+      category_uid: 1,
+      type_uid: 100_803,
+      activity_id: 3,
+      activity_name: "Merge",
+      severity_id: severity_id,
+      severity: severity,
+      message: event_message(merge),
+      status_id: 1,
+      status: "Success",
+      status_code: "identity_merged",
+      metadata: metadata,
+      observables:
+        Enum.map([merge.canonical_device_id | merge.merged_device_ids], &device_observable/1) ++
+          Enum.map(merge.identifiers, &identifier_observable/1),
+      actor: %{"app_name" => "serviceradar.core", "process" => "identity_reconciler"},
+      device: %{"uid" => merge.canonical_device_id},
+      src_endpoint: %{},
+      dst_endpoint: %{},
+      log_name: @log_name,
+      log_provider: @provider,
+      log_level: log_level,
+      log_version: "identity_merge.v1",
+      unmapped: %{"identity_merge" => metadata},
+      raw_data: Jason.encode!(metadata)
+    }
+  end
+
+  defp event_message(merge) do
+    message =
+      "#{Enum.join(merge.merged_device_ids, ", ")} merged into " <>
+        "#{merge.canonical_device_id} (#{merge.reason})"
+
+    if merge.cross_partition do
+      "#{message} across partitions #{Enum.join(merge.partitions, ", ")}"
+    else
+      message
+    end
+  end
+
+  defp device_observable(uid), do: %{"name" => "Device UID", "type" => "string", "value" => uid}
+
+  defp identifier_observable(%{type: type, value: value}),
+    do: %{"name" => "Identifier #{type}", "type" => "string", "value" => value}
+
+  defp field(map, key) when is_map(map) do
+    case Map.get(map, key) do
+      nil -> Map.get(map, Atom.to_string(key))
+      value -> value
+    end
+  end
+
+  defp create_event(attrs, actor) do
+    Ash.create(OcsfEvent, attrs, action: :record, actor: actor, domain: ServiceRadar.Monitoring)
+  end
+
+  defp config(key, default \\ nil) do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
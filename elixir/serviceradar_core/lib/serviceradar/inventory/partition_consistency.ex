@@ -0,0 +1,208 @@
+defmodule ServiceRadar.Inventory.PartitionConsistency do
+  @moduledoc """
+  Flags devices whose partition assignment looks inconsistent with their
+  attributes, so likely misassignments (and cross-tenant merges) can be
+  reviewed. Nothing is corrected automatically.
+
+  A device's assigned partitions are the partitions of its identifiers. Its
+  inferred partitions are the enabled partitions whose `cidr_ranges` contain
+  the device IP. A device is flagged with:
+
+    * `:cidr_mismatch` - the IP falls in another partition's CIDR ranges and
+      none of the assigned partitions claims it
+    * `:multiple_partitions` - the device has identifiers in more than one
+      partition, which usually means devices from different tenants were
+      merged
+
+  Devices whose IP matches no partition CIDR are not inferred, so partitions
+  without `cidr_ranges` only contribute `:multiple_partitions` findings.
+  CIDR ranges that do not parse are skipped and listed in the report.
+
+  Flagged devices are read through Ash with the caller's actor, so device
+  read policies still apply.
+
+  ## Usage
+
+      PartitionConsistency.report(actor: scope.user, partition: "default", limit: 100)
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Infrastructure.Partition
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Repo
+  alias ServiceRadar.Types.Cidr
+
+  require Ash.Query
+
+  @default_limit 100
+  @max_limit 1_000
+
+  @type issue :: :cidr_mismatch | :multiple_partitions
+
+  @type finding :: %{
+          device: Device.t(),
+          issues: [issue()],
+          ip: String.t() | nil,
+          assigned_partitions: [String.t()],
+          inferred_partitions: [String.t()]
+        }
+
+  @type report :: %{
+          findings: [finding()],
+          partitions_checked: non_neg_integer(),
+          skipped_cidrs: [%{partition: String.t(), cidr: String.t()}]
+        }
+
+  @report_sql """
+  WITH partition_cidrs AS (
+    SELECT c.slug, c.cidr::inet AS cidr
+    FROM unnest($1::text[], $2::text[]) AS c(slug, cidr)
+  ),
+  assigned AS (
+    SELECT i.device_id, array_agg(DISTINCT COALESCE(i.partition, 'default')) AS partitions
+    FROM platform.device_identifiers i
+    GROUP BY i.device_id
+  ),
+  inferred AS (
+    SELECT d.uid, array_agg(DISTINCT pc.slug) AS partitions
+    FROM platform.ocsf_devices d
+    JOIN partition_cidrs pc ON platform.try_inet(d.ip) <<= pc.cidr
+    WHERE d.deleted_at IS NULL
+    GROUP BY d.uid
+  )
+  SELECT d.uid, d.ip, a.partitions, COALESCE(inf.partitions, ARRAY[]::text[])
+  FROM platform.ocsf_devices d
+  JOIN assigned a ON a.device_id = d.uid
+  LEFT JOIN inferred inf ON inf.uid = d.uid
+  WHERE d.deleted_at IS NULL
+    AND (
+      cardinality(a.partitions) > 1
+      OR (inf.partitions IS NOT NULL AND NOT (a.partitions && inf.partitions))
+    )
+    AND ($3::text IS NULL OR $3 = ANY(a.partitions) OR $3 = ANY(COALESCE(inf.partitions, '{}')))
+  ORDER BY d.uid
+  LIMIT $4
+  """
+
+  @doc """
+  Builds the partition consistency report.
+
+  Options:
+    - `:actor` - actor the flagged devices are read as (required)
+    - `:partition` - only report devices assigned to, or inferred for, this partition
+    - `:limit` - maximum findings (default #{@default_limit}, max #{@max_limit})
+  """
+  @spec report(keyword()) :: {:ok, report()} | {:error, term()}
+  def report(opts \\ []) do
+    limit = clamp_limit(Keyword.get(opts, :limit))
+    partition = blank_to_nil(Keyword.get(opts, :partition))
+
+    with {:ok, partitions} <- load_partitions(),
+         {cidrs, skipped} = partition_cidrs(partitions),
+         {:ok, rows} <- scan(cidrs, partition, limit),
+         {:ok, devices} <- read_devices(rows, Keyword.get(opts, :actor)) do
+      findings =
+        Enum.flat_map(rows, fn row ->
+          case Map.fetch(devices, row.uid) do
+            {:ok, device} -> [Map.put(row, :device, device)]
+            :error -> []
+          end
+        end)
+
+      {:ok,
+       %{
+         findings: Enum.map(findings, &Map.delete(&1, :uid)),
+         partitions_checked: length(partitions),
+         skipped_cidrs: skipped
+       }}
+    end
+  end
+
+  @doc """
+  Returns the issues for one device given its assigned and inferred partitions.
+  """
+  @spec issues([String.t()], [String.t()]) :: [issue()]
+  def issues(assigned, inferred) do
+    []
+    |> maybe_add(inferred != [] and Enum.all?(assigned, &(&1 not in inferred)), :cidr_mismatch)
+    |> maybe_add(length(Enum.uniq(assigned)) > 1, :multiple_partitions)
+    |> Enum.reverse()
+  end
+
+  defp maybe_add(issues, true, issue), do: [issue | issues]
+  defp maybe_add(issues, false, _issue), do: issues
+
+  defp load_partitions do
+    Partition
+    |> Ash.Query.for_read(:enabled)
+    |> Ash.read(actor: SystemActor.system(:partition_consistency))
+    |> Page.unwrap()
+  end
+
+  defp partition_cidrs(partitions) do
+    Enum.reduce(partitions, {[], []}, fn partition, acc ->
+      Enum.reduce(partition.cidr_ranges || [], acc, fn cidr, {valid, skipped} ->
+        case Ash.Type.cast_input(Cidr, cidr, []) do
+          {:ok, normalized} when is_binary(normalized) ->
+            {[{partition.slug, normalized} | valid], skipped}
+
+          _ ->
+            {valid, [%{partition: partition.slug, cidr: cidr} | skipped]}
+        end
+      end)
+    end)
+    |> then(fn {valid, skipped} -> {Enum.reverse(valid), Enum.reverse(skipped)} end)
+  end
+
+  defp scan(cidrs, partition, limit) do
+    {slugs, ranges} = Enum.unzip(cidrs)
+
+    case Repo.query(@report_sql, [slugs, ranges, partition, limit]) do
+      {:ok, %{rows: rows}} ->
+        {:ok,
+         Enum.map(rows, fn [uid, ip, assigned, inferred] ->
+           assigned = Enum.sort(assigned)
+           inferred = Enum.sort(inferred)
+
+           %{
+             uid: uid,
+             ip: ip,
+             assigned_partitions: assigned,
+             inferred_partitions: inferred,
+             issues: issues(assigned, inferred)
+           }
+         end)}
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  defp read_devices([], _actor), do: {:ok, %{}}
+
+  defp read_devices(rows, actor) do
+    uids = Enum.map(rows, & &1.uid)
+
+    Device
+    |> Ash.Query.filter(uid in ^uids)
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+    |> case do
+      {:ok, devices} -> {:ok, Map.new(devices, &{&1.uid, &1})}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp clamp_limit(limit) when is_integer(limit), do: limit |> max(1) |> min(@max_limit)
+  defp clamp_limit(_limit), do: @default_limit
+
+  defp blank_to_nil(value) when is_binary(value) do
+    case String.trim(value) do
+      "" -> nil
+      trimmed -> trimmed
+    end
+  end
+
+  defp blank_to_nil(_value), do: nil
+end
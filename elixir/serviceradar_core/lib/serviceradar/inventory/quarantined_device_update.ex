@@ -0,0 +1,101 @@
+defmodule ServiceRadar.Inventory.QuarantinedDeviceUpdate do
+  @moduledoc """
+  A device update rejected at ingest by
+  `ServiceRadar.Inventory.DeviceUpdateValidator`.
+
+  The original update is kept in `payload` (made JSON-safe) alongside the
+  validation `reasons`, so malformed updates can be inspected and traced back
+  to the producer that sent them. Quarantined updates are never ingested.
+  """
+
+  use Ash.Resource,
+    domain: ServiceRadar.Inventory,
+    data_layer: AshPostgres.DataLayer,
+    authorizers: [Ash.Policy.Authorizer]
+
+  require Ash.Query
+
+  postgres do
+    table "quarantined_device_updates"
+    repo ServiceRadar.Repo
+    schema "platform"
+    migrate? false
+  end
+
+  code_interface do
+    define :list_recent, action: :recent
+  end
+
+  actions do
+    defaults [:read, :destroy]
+
+    read :recent do
+      description "Quarantined updates, most recent first"
+      argument :reason, :string
+      argument :source, :string
+
+      filter expr(is_nil(^arg(:source)) or source == ^arg(:source))
+
+      prepare fn query, _context ->
+        query
+        |> filter_reason(Ash.Query.get_argument(query, :reason))
+        |> Ash.Query.sort(quarantined_at: :desc)
+      end
+
+      pagination offset?: true, required?: false, default_limit: 100
+    end
+  end
+
+  policies do
+    import ServiceRadar.Policies
+
+    system_bypass()
+    read_viewer_plus()
+    operator_action_type(:destroy)
+  end
+
+  attributes do
+    uuid_primary_key :id
+
+    attribute :reasons, {:array, :string} do
+      default []
+      public? true
+      description "Validation checks the update failed"
+    end
+
+    attribute :source, :string do
+      public? true
+      description "Discovery source reported by the update"
+    end
+
+    attribute :partition, :string do
+      public? true
+    end
+
+    attribute :device_id, :string do
+      public? true
+    end
+
+    attribute :ip, :string do
+      public? true
+    end
+
+    attribute :payload, :map do
+      default %{}
+      public? true
+      description "The update as it was received"
+    end
+
+    attribute :quarantined_at, :utc_datetime_usec do
+      allow_nil? false
+      public? true
+      default &DateTime.utc_now/0
+    end
+  end
+
+  defp filter_reason(query, reason) when is_binary(reason) and reason != "" do
+    Ash.Query.filter(query, fragment("? = ANY(?)", ^reason, reasons))
+  end
+
+  defp filter_reason(query, _reason), do: query
+end
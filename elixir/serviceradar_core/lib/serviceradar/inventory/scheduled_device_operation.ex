@@ -0,0 +1,210 @@
+defmodule ServiceRadar.Inventory.ScheduledDeviceOperation do
+  @moduledoc """
+  A bulk device operation deferred to a future time, such as decommissioning
+  a subnet or retagging devices during a maintenance window.
+
+  Operations target either explicit `device_uids` or every device in a `cidr`
+  (resolved when the operation runs), optionally limited to a `partition`.
+  They are executed by `ServiceRadar.Inventory.ScheduledDeviceOperations`
+  once `run_at` has passed, as the user who scheduled them: that user's
+  permissions are checked both when scheduling and again at execution.
+
+  ## Operations
+
+  - `:decommission` - soft-delete the devices; `params`: `%{"reason" => ...}`
+  - `:add_tags` - merge tags into the devices; `params`: `%{"tags" => %{...}}`
+  - `:remove_tags` - drop tag keys; `params`: `%{"keys" => [...]}`
+  """
+
+  use Ash.Resource,
+    domain: ServiceRadar.Inventory,
+    data_layer: AshPostgres.DataLayer,
+    authorizers: [Ash.Policy.Authorizer]
+
+  alias ServiceRadar.Policies.Checks.ActorHasPermission
+
+  @devices_view_check {ActorHasPermission, permission: "devices.view"}
+  @devices_bulk_edit_check {ActorHasPermission, permission: "devices.bulk_edit"}
+  @schedule_fields [:operation, :device_uids, :cidr, :partition, :params, :run_at]
+  @result_fields [:status, :started_at, :completed_at, :result, :error]
+
+  postgres do
+    table "scheduled_device_operations"
+    repo ServiceRadar.Repo
+    schema "platform"
+    migrate? false
+  end
+
+  code_interface do
+    define :schedule, action: :schedule
+    define :cancel, action: :cancel
+    define :list_pending, action: :pending
+    define :list_due, action: :due, args: [:now]
+  end
+
+  actions do
+    defaults [:read]
+
+    read :pending do
+      description "Operations waiting for their run time, soonest first"
+      filter expr(status == :scheduled)
+
+      prepare fn query, _context ->
+        Ash.Query.sort(query, run_at: :asc)
+      end
+    end
+
+    read :due do
+      description "Scheduled operations whose run time has passed"
+      argument :now, :utc_datetime_usec, allow_nil?: false
+      filter expr(status == :scheduled and run_at <= ^arg(:now))
+
+      prepare fn query, _context ->
+        Ash.Query.sort(query, run_at: :asc)
+      end
+    end
+
+    create :schedule do
+      accept @schedule_fields
+      validate ServiceRadar.Inventory.Validations.ScheduledDeviceOperation
+
+      change fn changeset, context ->
+        actor = Map.get(context, :actor)
+
+        changeset
+        |> Ash.Changeset.force_change_attribute(:requested_by_id, requester_id(actor))
+        |> Ash.Changeset.force_change_attribute(:requested_by, actor_label(actor))
+      end
+    end
+
+    update :cancel do
+      description "Cancel an operation that has not run yet"
+      require_atomic? false
+
+      validate attribute_equals(:status, :scheduled),
+        message: "only scheduled operations can be cancelled"
+
+      change fn changeset, context ->
+        changeset
+        |> Ash.Changeset.force_change_attribute(:status, :cancelled)
+        |> Ash.Changeset.force_change_attribute(:cancelled_at, DateTime.utc_now())
+        |> Ash.Changeset.force_change_attribute(
+          :cancelled_by,
+          actor_label(Map.get(context, :actor))
+        )
+      end
+    end
+
+    update :record_result do
+      description "Record execution progress and outcome (system only)"
+      accept @result_fields
+    end
+  end
+
+  policies do
+    import ServiceRadar.Policies
+
+    system_bypass()
+    read_with_permission(@devices_view_check)
+    action_with_permission([:schedule, :cancel], @devices_bulk_edit_check)
+  end
+
+  attributes do
+    uuid_primary_key :id
+
+    attribute :operation, :atom do
+      allow_nil? false
+      public? true
+      constraints one_of: [:decommission, :add_tags, :remove_tags]
+      description "Bulk operation to run"
+    end
+
+    attribute :device_uids, {:array, :string} do
+      default []
+      public? true
+      description "Devices to operate on (mutually exclusive with cidr)"
+    end
+
+    attribute :cidr, :string do
+      public? true
+      description "Operate on every device with an IP in this range, resolved at run time"
+    end
+
+    attribute :partition, :string do
+      public? true
+      description "Only operate on devices with an identifier in this partition"
+    end
+
+    attribute :params, :map do
+      default %{}
+      public? true
+      description "Operation parameters (reason, tags or tag keys)"
+    end
+
+    attribute :run_at, :utc_datetime_usec do
+      allow_nil? false
+      public? true
+      description "When the operation should run"
+    end
+
+    attribute :status, :atom do
+      allow_nil? false
+      default :scheduled
+      public? true
+      constraints one_of: [:scheduled, :running, :succeeded, :failed, :cancelled]
+    end
+
+    attribute :requested_by_id, :uuid do
+      public? true
+      description "User whose permissions the operation runs with"
+    end
+
+    attribute :requested_by, :string do
+      public? true
+    end
+
+    attribute :cancelled_at, :utc_datetime_usec do
+      public? true
+    end
+
+    attribute :cancelled_by, :string do
+      public? true
+    end
+
+    attribute :started_at, :utc_datetime_usec do
+      public? true
+    end
+
+    attribute :completed_at, :utc_datetime_usec do
+      public? true
+    end
+
+    attribute :result, :map do
+      default %{}
+      public? true
+      description "Outcome counts (matched, applied, skipped)"
+    end
+
+    attribute :error, :string do
+      public? true
+    end
+
+    create_timestamp :inserted_at
+    update_timestamp :updated_at
+  end
+
+  defp requester_id(%{role: :system}), do: nil
+
+  defp requester_id(actor) when is_map(actor) do
+    case Ecto.UUID.cast(Map.get(actor, :id)) do
+      {:ok, id} -> id
+      :error -> nil
+    end
+  end
+
+  defp requester_id(_actor), do: nil
+
+  defp actor_label(%{email: email}) when not is_nil(email), do: to_string(email)
+  defp actor_label(%{id: id}) when not is_nil(id), do: to_string(id)
+  defp actor_label(_actor), do: nil
+end
@@ -0,0 +1,27 @@
+defmodule ServiceRadar.Inventory.ScheduledDeviceOperationWorker do
+  @moduledoc """
+  Oban cron worker that runs scheduled bulk device operations once their run
+  time has passed. Scheduled every minute from the core runtime config.
+  """
+
+  use Oban.Worker,
+    queue: :maintenance,
+    max_attempts: 1,
+    unique: [period: 55, states: [:available, :scheduled, :executing]]
+
+  alias ServiceRadar.Inventory.ScheduledDeviceOperations
+
+  require Logger
+
+  @impl Oban.Worker
+  def perform(%Oban.Job{}) do
+    case ScheduledDeviceOperations.run_due() do
+      [] ->
+        :ok
+
+      operations ->
+        Logger.info("ScheduledDeviceOperationWorker: ran #{length(operations)} operation(s)")
+        :ok
+    end
+  end
+end
@@ -0,0 +1,275 @@
+defmodule ServiceRadar.Inventory.ScheduledDeviceOperations do
+  @moduledoc """
+  Schedules, cancels and executes deferred bulk device operations
+  (`ServiceRadar.Inventory.ScheduledDeviceOperation`).
+
+  `ServiceRadar.Inventory.ScheduledDeviceOperationWorker` calls `run_due/1`
+  every minute. Each due operation is claimed (so it runs at most once, even
+  with several schedulers), then executed as the user who scheduled it:
+
+    * the user must still be active and still hold the operation's permission
+    * targets are resolved at run time with that user as the actor, limited
+      to the operation's partition; uids that no longer match are skipped
+
+  The outcome (matched, applied and skipped counts, or the failure reason) is
+  stored on the operation and written to the audit log as an event.
+
+  ## Usage
+
+      ScheduledDeviceOperations.schedule(
+        %{operation: :decommission, cidr: "10.20.0.0/24", run_at: ~U[2026-11-01 02:00:00Z],
+          params: %{"reason" => "site closure"}, partition: "default"},
+        actor: scope.user
+      )
+  """
+
+  import Ash.Expr
+  import Ecto.Query, only: [from: 2]
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Events.AuditWriter
+  alias ServiceRadar.Identity.RBAC
+  alias ServiceRadar.Identity.User
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.ScheduledDeviceOperation
+  alias ServiceRadar.Repo
+
+  require Ash.Query
+  require Logger
+
+  @max_devices 10_000
+
+  @permissions %{
+    decommission: "devices.bulk_delete",
+    add_tags: "devices.bulk_edit",
+    remove_tags: "devices.bulk_edit"
+  }
+
+  @doc "RBAC permission required to schedule and to run `operation`."
+  @spec required_permission(atom()) :: String.t()
+  def required_permission(operation), do: Map.fetch!(@permissions, operation)
+
+  @doc """
+  Persists an operation to run at `run_at`. The actor must hold the
+  operation's permission.
+  """
+  @spec schedule(map(), keyword()) :: {:ok, ScheduledDeviceOperation.t()} | {:error, term()}
+  def schedule(attrs, opts), do: ScheduledDeviceOperation.schedule(attrs, opts)
+
+  @doc "Cancels an operation that has not started yet."
+  @spec cancel(ScheduledDeviceOperation.t(), keyword()) ::
+          {:ok, ScheduledDeviceOperation.t()} | {:error, term()}
+  def cancel(operation, opts), do: ScheduledDeviceOperation.cancel(operation, opts)
+
+  @doc """
+  Executes every scheduled operation whose run time is at or before `now`.
+  Returns the executed operations with their final status.
+  """
+  @spec run_due(DateTime.t()) :: [ScheduledDeviceOperation.t()]
+  def run_due(now \\ DateTime.utc_now()) do
+    case ScheduledDeviceOperation.list_due(now, actor: system_actor()) do
+      {:ok, operations} ->
+        operations
+        |> Enum.map(&execute/1)
+        |> Enum.flat_map(fn
+          {:ok, operation} -> [operation]
+          :skipped -> []
+        end)
+
+      {:error, reason} ->
+        Logger.warning("Failed to load due device operations: #{inspect(reason)}")
+        []
+    end
+  end
+
+  @doc """
+  Claims and runs one operation. Returns `:skipped` when another run already
+  claimed it or it was cancelled in the meantime.
+  """
+  @spec execute(ScheduledDeviceOperation.t()) :: {:ok, ScheduledDeviceOperation.t()} | :skipped
+  def execute(%ScheduledDeviceOperation{} = operation) do
+    if claim(operation) do
+      outcome = run(operation)
+      finish(operation, outcome)
+    else
+      :skipped
+    end
+  end
+
+  defp claim(operation) do
+    now = DateTime.utc_now()
+
+    {count, _} =
+      from(o in ScheduledDeviceOperation,
+        where: o.id == ^operation.id and o.status == ^:scheduled
+      )
+      |> Repo.update_all(set: [status: :running, started_at: now, updated_at: now])
+
+    count == 1
+  end
+
+  defp run(operation) do
+    with {:ok, actor} <- requesting_actor(operation),
+         :ok <- authorize(actor, operation.operation),
+         {:ok, uids} <- resolve_targets(operation, actor),
+         :ok <- apply_operation(operation, uids, actor) do
+      {:ok,
+       %{
+         "matched" => length(uids),
+         "applied" => length(uids),
+         "skipped" => max(length(operation.device_uids || []) - length(uids), 0)
+       }}
+    end
+  rescue
+    e ->
+      Logger.error("Scheduled device operation #{operation.id} crashed: #{inspect(e)}")
+      {:error, Exception.message(e)}
+  end
+
+  defp requesting_actor(%{requested_by_id: nil}), do: {:ok, system_actor()}
+
+  defp requesting_actor(%{requested_by_id: user_id}) do
+    case User.get_by_id(user_id, actor: system_actor()) do
+      {:ok, %User{status: :active} = user} -> {:ok, user}
+      _ -> {:error, "requesting user no longer exists or is inactive"}
+    end
+  end
+
+  defp authorize(actor, operation) do
+    cond do
+      SystemActor.system_actor?(actor) -> :ok
+      RBAC.has_permission?(actor, required_permission(operation)) -> :ok
+      true -> {:error, "requesting user no longer has #{required_permission(operation)}"}
+    end
+  end
+
+  defp resolve_targets(operation, actor) do
+    Device
+    |> Ash.Query.select([:uid])
+    |> target_filter(operation)
+    |> scope_to_partition(operation.partition)
+    |> Ash.Query.limit(@max_devices + 1)
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+    |> case do
+      {:ok, devices} when length(devices) > @max_devices ->
+        {:error, "more than #{@max_devices} devices match"}
+
+      {:ok, devices} ->
+        {:ok, Enum.map(devices, & &1.uid)}
+
+      {:error, reason} ->
+        {:error, error_message(reason)}
+    end
+  end
+
+  defp target_filter(query, %{cidr: cidr}) when is_binary(cidr) and cidr != "" do
+    Ash.Query.filter(query, fragment("NULLIF(?, '')::inet <<= (?)::inet", ip, ^cidr))
+  end
+
+  defp target_filter(query, %{device_uids: uids}), do: Ash.Query.filter(query, uid in ^uids)
+
+  defp scope_to_partition(query, partition) when is_binary(partition) and partition != "" do
+    Ash.Query.filter(query, exists(identifiers, partition == ^partition))
+  end
+
+  defp scope_to_partition(query, _partition), do: query
+
+  defp apply_operation(_operation, [], _actor), do: :ok
+
+  defp apply_operation(%{operation: :decommission} = operation, uids, actor) do
+    reason = param(operation.params, "reason") || "scheduled decommission"
+
+    case Device.bulk_soft_delete(uids, reason, actor: actor) do
+      {:error, reason} -> {:error, error_message(reason)}
+      _ok -> :ok
+    end
+  end
+
+  defp apply_operation(%{operation: :add_tags} = operation, uids, actor) do
+    tags = param(operation.params, "tags")
+
+    update_tags(
+      uids,
+      actor,
+      expr(fragment("COALESCE(?, '{}'::jsonb) || (?::jsonb)", ^ref(:tags), ^tags))
+    )
+  end
+
+  defp apply_operation(%{operation: :remove_tags} = operation, uids, actor) do
+    keys = param(operation.params, "keys")
+
+    update_tags(
+      uids,
+      actor,
+      expr(fragment("COALESCE(?, '{}'::jsonb) - (?::text[])", ^ref(:tags), ^keys))
+    )
+  end
+
+  defp update_tags(uids, actor, tags_expr) do
+    Device
+    |> Ash.Query.filter(uid in ^uids)
+    |> Ash.bulk_update(:update, %{},
+      actor: actor,
+      return_errors?: true,
+      return_records?: false,
+      atomic_update: %{tags: tags_expr}
+    )
+    |> case do
+      %Ash.BulkResult{status: :success} -> :ok
+      %Ash.BulkResult{errors: errors} -> {:error, error_message(List.first(errors || []))}
+    end
+  end
+
+  defp finish(operation, outcome) do
+    {status, result, error} =
+      case outcome do
+        {:ok, result} -> {:succeeded, result, nil}
+        {:error, reason} -> {:failed, %{}, error_message(reason)}
+      end
+
+    {:ok, finished} =
+      operation
+      |> Ash.Changeset.for_update(
+        :record_result,
+        %{status: status, result: result, error: error, completed_at: DateTime.utc_now()},
+        actor: system_actor()
+      )
+      |> Ash.update()
+
+    audit(finished)
+    {:ok, finished}
+  end
+
+  defp audit(operation) do
+    AuditWriter.write_async(
+      action: :scheduled_device_operation,
+      resource_type: "scheduled_device_operation",
+      resource_id: operation.id,
+      resource_name: Atom.to_string(operation.operation),
+      actor: %{id: operation.requested_by_id, email: operation.requested_by},
+      severity: if(operation.status == :failed, do: :medium, else: :informational),
+      message: "Scheduled #{operation.operation} #{operation.status}",
+      details: %{
+        operation: operation.operation,
+        status: operation.status,
+        run_at: operation.run_at,
+        cidr: operation.cidr,
+        partition: operation.partition,
+        requested_devices: length(operation.device_uids || []),
+        result: operation.result,
+        error: operation.error
+      }
+    )
+  end
+
+  defp param(params, key) when is_map(params), do: Map.get(params, key)
+  defp param(_params, _key), do: nil
+
+  defp error_message(reason) when is_binary(reason), do: reason
+  defp error_message(%{__exception__: true} = error), do: Exception.message(error)
+  defp error_message(reason), do: inspect(reason)
+
+  defp system_actor, do: SystemActor.system(:scheduled_device_operations)
+end
@@ -0,0 +1,146 @@
+defmodule ServiceRadar.Inventory.SourceConfidence do
+  @moduledoc """
+  Confidence (1-10) assigned to each discovery source when device fields
+  conflict.
+
+  The built-in values match `models.GetSourceConfidence` on the Go side.
+  Deployments that trust one source more than another can override them:
+
+      config :serviceradar_core, ServiceRadar.Inventory.SourceConfidence,
+        overrides: %{"netbox" => 9, "armis" => 6}
+
+  or at runtime with `SERVICERADAR_SOURCE_CONFIDENCE_OVERRIDES` set to a JSON
+  object. Overrides outside the 1-10 range are ignored with a warning.
+
+  `ServiceRadar.Inventory.SyncIngestor` consults this table when merging
+  updates: a descriptive field (hostname, name, type, vendor, model) is only
+  replaced by a source at least as confident as the most confident source that
+  has already reported the device. Less confident sources still fill gaps.
+  """
+
+  @min_confidence 1
+  @max_confidence 10
+  @unknown_confidence @min_confidence
+
+  @defaults %{
+    "snmp" => 9,
+    "mapper" => 8,
+    "integration" => 7,
+    "armis" => 7,
+    "netflow" => 6,
+    "sweep" => 5,
+    "sighting" => 5,
+    "self-reported" => 8,
+    "manual" => 10,
+    "netbox" => 7,
+    "sysmon" => 6,
+    "serviceradar" => 8
+  }
+
+  @doc "Built-in confidence per source, before overrides."
+  @spec defaults() :: %{String.t() => pos_integer()}
+  def defaults, do: @defaults
+
+  @doc """
+  Returns the effective confidence table: built-in defaults merged with the
+  valid configured overrides. Invalid overrides are dropped here; they are
+  reported once when the runtime configuration is loaded.
+  """
+  @spec table() :: %{String.t() => pos_integer()}
+  def table do
+    case validate_overrides(configured_overrides()) do
+      {:ok, overrides} -> Map.merge(@defaults, overrides)
+      {:error, _invalid, overrides} -> Map.merge(@defaults, overrides)
+    end
+  end
+
+  @doc "Returns the confidence for a single source. Unknown sources get #{@unknown_confidence}."
+  @spec confidence(String.t() | atom() | nil, map()) :: pos_integer()
+  def confidence(source, table \\ table())
+
+  def confidence(nil, _table), do: @unknown_confidence
+
+  def confidence(source, table) do
+    Map.get(table, normalize_source(source), @unknown_confidence)
+  end
+
+  @doc """
+  Returns the highest confidence among a list of sources, or 0 for an empty
+  list (a device no source has reported yet).
+  """
+  @spec max_confidence([String.t()] | nil, map()) :: non_neg_integer()
+  def max_confidence(sources, table \\ table())
+
+  def max_confidence(sources, table) when is_list(sources) and sources != [] do
+    sources
+    |> Enum.map(&confidence(&1, table))
+    |> Enum.max()
+  end
+
+  def max_confidence(_sources, _table), do: 0
+
+  @doc """
+  Ecto fragment for `INSERT ... ON CONFLICT DO UPDATE`: true when the sources
+  in `EXCLUDED.discovery_sources` are at least as confident as every source in
+  `existing_sources`. `confidence` is the JSON-encoded `table/0`; sources
+  missing from it count as #{@unknown_confidence}.
+  """
+  defmacro incoming_wins(confidence, existing_sources) do
+    quote do
+      fragment(
+        "(SELECT COALESCE(MAX(COALESCE((?::jsonb ->> src)::int, 1)), 1) FROM unnest(EXCLUDED.discovery_sources) AS src) >= (SELECT COALESCE(MAX(COALESCE((?::jsonb ->> src)::int, 1)), 0) FROM unnest(?) AS src)",
+        unquote(confidence),
+        unquote(confidence),
+        unquote(existing_sources)
+      )
+    end
+  end
+
+  @doc """
+  Validates a map of source => confidence overrides.
+
+  Returns `{:ok, overrides}` with normalized (lowercase, trimmed) source names,
+  or `{:error, invalid, valid}` listing the entries that were rejected
+  alongside the ones that can still be applied.
+  """
+  @spec validate_overrides(term()) ::
+          {:ok, map()} | {:error, [{term(), term()}], map()}
+  def validate_overrides(nil), do: {:ok, %{}}
+
+  def validate_overrides(overrides) when is_map(overrides) or is_list(overrides) do
+    {valid, invalid} =
+      Enum.reduce(overrides, {%{}, []}, fn {source, value}, {valid, invalid} ->
+        case {normalize_source(source), value} do
+          {"", _value} ->
+            {valid, [{source, value} | invalid]}
+
+          {name, value}
+          when is_integer(value) and value >= @min_confidence and value <= @max_confidence ->
+            {Map.put(valid, name, value), invalid}
+
+          _ ->
+            {valid, [{source, value} | invalid]}
+        end
+      end)
+
+    case invalid do
+      [] -> {:ok, valid}
+      _ -> {:error, Enum.reverse(invalid), valid}
+    end
+  end
+
+  def validate_overrides(other), do: {:error, [{:overrides, other}], %{}}
+
+  defp configured_overrides do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(:overrides)
+  end
+
+  defp normalize_source(source) when is_atom(source), do: normalize_source(Atom.to_string(source))
+
+  defp normalize_source(source) when is_binary(source),
+    do: source |> String.trim() |> String.downcase()
+
+  defp normalize_source(_source), do: ""
+end
@@ -14,13 +14,25 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
   alias ServiceRadar.Actors.SystemActor
   alias ServiceRadar.Identity.AliasEvents
   alias ServiceRadar.Identity.DeviceAliasState
+  alias ServiceRadar.Inventory.CustomIdentifierTypes
   alias ServiceRadar.Inventory.Device
-  alias ServiceRadar.Inventory.DeviceEnrichmentRules
+  alias ServiceRadar.Inventory.DeviceFingerprint
+  alias ServiceRadar.Inventory.DeviceHealth
   alias ServiceRadar.Inventory.DeviceIdentifier
+  alias ServiceRadar.Inventory.DeviceLifecycle
+  alias ServiceRadar.Inventory.DeviceRelationships
+  alias ServiceRadar.Inventory.DeviceTagger
+  alias ServiceRadar.Inventory.DeviceUpdateValidator
+  alias ServiceRadar.Inventory.EnrichmentPipeline
+  alias ServiceRadar.Inventory.FieldConflictDetector
   alias ServiceRadar.Inventory.IdentityReconciler
+  alias ServiceRadar.Inventory.SourceConfidence
+  alias ServiceRadar.Inventory.SyncPlan
+  alias ServiceRadar.Monitoring.RiskAlerts
   alias ServiceRadar.Repo
 
   require Logger
+  require SourceConfidence
 
   # Process in chunks to balance memory vs DB efficiency
   @batch_size 500
@@ -31,6 +43,7 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
     classification_confidence
     classification_reason
   )
+  @owner_assignment_fields [:owner_uid, :owner_type, :owner_assigned_at, :owner_assigned_by]
   @vendor_tokens [
     {"cisco", "Cisco"},
     {"juniper", "Juniper"},
@@ -54,7 +67,8 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
     # DB connection's search_path determines the schema
     actor = Keyword.get(opts, :actor, SystemActor.system(:sync_ingestor))
 
-    updates = List.wrap(updates)
+    # Malformed updates are quarantined here instead of being reconciled
+    updates = updates |> List.wrap() |> DeviceUpdateValidator.screen()
     total_count = length(updates)
     batch_concurrency = batch_concurrency()
 
@@ -107,14 +121,110 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
     maybe_refresh_inventory_rollups(result, total_count)
   end
 
+  @doc """
+  Plans what ingesting `updates` would change without writing anything.
+
+  Updates are screened, normalized and resolved to device ids exactly as
+  `ingest_updates/2` would, then compared against the stored devices (see
+  `ServiceRadar.Inventory.SyncPlan`). Rejected updates are counted, not
+  quarantined.
+
+  Options:
+    - `:sync_service_id` - the integration source the updates came from;
+      without it the plan has no tombstone candidates
+    - `:max_tombstone_percent` - overrides the delete-safety threshold
+  """
+  @spec plan_updates([map()], keyword()) :: {:ok, SyncPlan.t()} | {:error, term()}
+  def plan_updates(updates, opts \\ []) do
+    actor = Keyword.get(opts, :actor, SystemActor.system(:sync_ingestor))
+    {accepted, rejected} = updates |> List.wrap() |> DeviceUpdateValidator.split()
+
+    records =
+      accepted
+      |> Enum.chunk_every(@batch_size)
+      |> Enum.flat_map(fn batch ->
+        {_resolved, device_records, _identifiers, _conflicts} =
+          batch |> normalize_updates() |> resolve_updates(actor)
+
+        device_records
+      end)
+      |> merge_records_by_uid()
+
+    existing = load_planned_devices(Enum.map(records, & &1.uid))
+    owned = load_source_devices(Keyword.get(opts, :sync_service_id))
+
+    plan_opts =
+      opts
+      |> Keyword.take([:max_tombstone_percent])
+      |> Keyword.put(:rejected, length(rejected))
+
+    {:ok, SyncPlan.build(records, existing, owned, plan_opts)}
+  rescue
+    error ->
+      Logger.warning("SyncIngestor: planning sync updates failed: #{inspect(error)}")
+      {:error, error}
+  end
+
+  # Soft-deleted devices are included: the upsert would restore them.
+  defp load_planned_devices([]), do: %{}
+
+  defp load_planned_devices(uids) do
+    uids
+    |> Enum.chunk_every(@batch_size)
+    |> Enum.flat_map(fn chunk ->
+      from(d in Device,
+        where: d.uid in ^chunk,
+        select:
+          map(d, [
+            :uid,
+            :ip,
+            :mac,
+            :hostname,
+            :name,
+            :type,
+            :vendor_name,
+            :model,
+            :discovery_sources,
+            :deleted_at
+          ])
+      )
+      |> Repo.all()
+    end)
+    |> Map.new(&{&1.uid, &1})
+  end
+
+  defp load_source_devices(nil), do: []
+
+  defp load_source_devices(sync_service_id) do
+    from(d in Device,
+      where:
+        is_nil(d.deleted_at) and is_nil(d.archived_at) and
+          fragment("?->>'sync_service_id' = ?", d.metadata, ^sync_service_id),
+      select: map(d, [:uid, :ip, :hostname, :last_seen_time])
+    )
+    |> Repo.all()
+  end
+
   defp ingest_batch(updates, actor) do
     normalized_updates = normalize_updates(updates)
 
-    {resolved_updates, device_records, identifier_records} =
+    {resolved_updates, device_records, identifier_records, field_conflicts} =
       resolve_updates(normalized_updates, actor)
 
+    {device_records, lifecycle_transitions} = stamp_lifecycle(device_records)
+
+    {device_records, unchanged_uids} =
+      split_unchanged_devices(device_records, Enum.map(lifecycle_transitions, & &1.device_id))
+
+    stored_risk = load_stored_risk(resolved_updates)
+
     case upsert_devices(device_records) do
       {:ok, remap} ->
+        _ = touch_unchanged_devices(unchanged_uids)
+        _ = record_field_conflicts(field_conflicts, remap)
+        _ = apply_tag_rules(device_records, remap)
+        _ = record_lifecycle_transitions(lifecycle_transitions, remap)
+
         # An IP-conflict recovery may have rewritten device uids during the
         # device upsert. Apply the same mapping to identifier records and the
         # resolved-update tuples so downstream steps reference uids that
@@ -123,6 +233,9 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
         resolved_updates = apply_uid_remap_to_resolved_updates(resolved_updates, remap)
 
         identifier_result = upsert_identifiers(identifier_records)
+        _ = record_relationships(resolved_updates)
+        _ = refresh_health_rollups(resolved_updates)
+        _ = check_risk_alerts(stored_risk, resolved_updates)
 
         _ = maybe_process_alias_conflicts(:ok, resolved_updates, actor)
         alias_result = maybe_process_alias_updates(:ok, resolved_updates, actor)
@@ -161,6 +274,7 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
     updates
     |> Enum.map(&normalize_update/1)
     |> Enum.map(&enrich_alias_metadata/1)
+    |> EnrichmentPipeline.run()
   end
 
   defp resolve_updates(normalized_updates, _actor) do
@@ -186,10 +300,180 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
     resolved_updates = Enum.reverse(resolved_updates)
 
     timestamp = DateTime.truncate(DateTime.utc_now(), :second)
-    device_records = build_device_upsert_records(resolved_updates, timestamp)
+    source_records = build_device_records(resolved_updates, timestamp)
+    device_records = merge_records_by_uid(source_records)
     identifier_records = build_identifier_records(resolved_updates)
+    field_conflicts = detect_field_conflicts(source_records)
+
+    {resolved_updates, device_records, identifier_records, field_conflicts}
+  end
+
+  # Compared against the stored devices before the upsert overwrites them.
+  defp detect_field_conflicts(source_records) do
+    if FieldConflictDetector.enabled?() do
+      source_records
+      |> Enum.map(& &1.uid)
+      |> Enum.uniq()
+      |> FieldConflictDetector.load_existing()
+      |> then(&FieldConflictDetector.detect(source_records, &1))
+    else
+      []
+    end
+  rescue
+    e ->
+      Logger.warning("SyncIngestor: field conflict detection failed: #{inspect(e)}")
+      []
+  end
 
-    {resolved_updates, device_records, identifier_records}
+  defp record_field_conflicts([], _remap), do: :ok
+
+  defp record_field_conflicts(conflicts, remap) do
+    conflicts
+    |> Enum.map(fn conflict ->
+      %{conflict | device_id: Map.get(remap, conflict.device_id, conflict.device_id)}
+    end)
+    |> FieldConflictDetector.record()
+  end
+
+  defp apply_tag_rules(device_records, remap) do
+    case DeviceTagger.load_rules() do
+      [] ->
+        :ok
+
+      rules ->
+        device_records
+        |> Enum.map(&Map.get(remap, &1.uid, &1.uid))
+        |> Enum.uniq()
+        |> DeviceTagger.apply_to_uids(rules)
+    end
+  rescue
+    e ->
+      Logger.warning("SyncIngestor: device tag rules failed: #{inspect(e)}")
+      :error
+  end
+
+  defp record_relationships(resolved_updates) do
+    case DeviceRelationships.record(resolved_updates) do
+      {:ok, _edges} ->
+        :ok
+
+      {:error, reason} ->
+        Logger.warning("SyncIngestor: recording device relationships failed: #{inspect(reason)}")
+        :error
+    end
+  rescue
+    e ->
+      Logger.warning("SyncIngestor: recording device relationships failed: #{inspect(e)}")
+      :error
+  end
+
+  # Runs after the relationships are recorded so new children count.
+  defp refresh_health_rollups(resolved_updates) do
+    if DeviceHealth.enabled?() do
+      case resolved_updates |> Enum.map(&elem(&1, 1)) |> DeviceHealth.devices_changed() do
+        {:ok, _rollups} ->
+          :ok
+
+        {:error, reason} ->
+          Logger.warning("SyncIngestor: device health rollup failed: #{inspect(reason)}")
+          :error
+      end
+    else
+      :ok
+    end
+  rescue
+    e ->
+      Logger.warning("SyncIngestor: device health rollup failed: #{inspect(e)}")
+      :error
+  end
+
+  defp stamp_lifecycle(device_records) do
+    if DeviceLifecycle.enabled?() do
+      case DeviceLifecycle.stamp(device_records) do
+        {:ok, stamped, transitions} ->
+          {stamped, transitions}
+
+        {:error, reason} ->
+          Logger.warning("SyncIngestor: reading lifecycle states failed: #{inspect(reason)}")
+          {device_records, []}
+      end
+    else
+      {device_records, []}
+    end
+  rescue
+    e ->
+      Logger.warning("SyncIngestor: reading lifecycle states failed: #{inspect(e)}")
+      {device_records, []}
+  end
+
+  defp record_lifecycle_transitions([], _remap), do: :ok
+
+  defp record_lifecycle_transitions(transitions, remap) do
+    transitions
+    |> Enum.map(fn transition ->
+      %{transition | device_id: Map.get(remap, transition.device_id, transition.device_id)}
+    end)
+    |> DeviceLifecycle.record()
+  end
+
+  # Risk alerts compare against the risk stored before this batch, so it is
+  # read ahead of the device upsert.
+  defp load_stored_risk(resolved_updates) do
+    if RiskAlerts.enabled?() do
+      case resolved_updates |> Enum.map(&elem(&1, 1)) |> RiskAlerts.stored_levels() do
+        {:ok, levels} ->
+          levels
+
+        {:error, reason} ->
+          Logger.warning("SyncIngestor: reading stored risk failed: #{inspect(reason)}")
+          nil
+      end
+    end
+  rescue
+    e ->
+      Logger.warning("SyncIngestor: reading stored risk failed: #{inspect(e)}")
+      nil
+  end
+
+  defp check_risk_alerts(nil, _resolved_updates), do: :ok
+
+  defp check_risk_alerts(stored_risk, resolved_updates) do
+    _ = RiskAlerts.check(stored_risk, resolved_updates)
+    :ok
+  rescue
+    e ->
+      Logger.warning("SyncIngestor: checking risk alerts failed: #{inspect(e)}")
+      :error
+  end
+
+  # With skip_unchanged enabled, devices whose fingerprint matches the stored
+  # one would be rewritten with the same values, so they are only marked as
+  # seen. Devices changing lifecycle state are always rewritten: the state may
+  # have been changed since the fingerprint was stored.
+  defp split_unchanged_devices(records, rewrite_uids) do
+    DeviceFingerprint.split_unchanged(records, rewrite_uids)
+  rescue
+    e ->
+      Logger.warning("SyncIngestor: device fingerprint check failed: #{inspect(e)}")
+      {records, []}
+  end
+
+  defp touch_unchanged_devices([]), do: :ok
+
+  defp touch_unchanged_devices(uids) do
+    now = DateTime.truncate(DateTime.utc_now(), :second)
+
+    with_inventory_rollup_bypassed(fn ->
+      from(d in Device, where: d.uid in ^uids and is_nil(d.deleted_at))
+      |> Repo.update_all(set: [last_seen_time: now])
+    end)
+
+    Logger.debug("SyncIngestor: #{length(uids)} unchanged devices marked as seen")
+    :ok
+  rescue
+    e ->
+      Logger.warning("SyncIngestor: marking unchanged devices as seen failed: #{inspect(e)}")
+      :error
   end
 
   defp upsert_devices([]), do: {:ok, %{}}
@@ -232,11 +516,18 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
       |> maybe_add_id(:armis_device_id, ids.armis_id, partition)
       |> maybe_add_id(:integration_id, ids.integration_id, partition)
       |> maybe_add_id(:netbox_device_id, ids.netbox_id, partition)
+      |> add_custom_ids(ids)
       |> maybe_add_id_if(include_mac?, :mac, ids.mac, partition)
     end)
     |> Enum.uniq()
   end
 
+  defp add_custom_ids(acc, ids) do
+    Enum.reduce(ids.custom, acc, fn {type, value}, acc ->
+      maybe_add_id(acc, type, value, CustomIdentifierTypes.partition(type, ids.partition))
+    end)
+  end
+
   defp maybe_add_id(acc, _type, nil, _partition), do: acc
   defp maybe_add_id(acc, type, value, partition), do: [{type, value, partition} | acc]
   defp maybe_add_id_if(acc, false, _type, _value, _partition), do: acc
@@ -382,9 +673,18 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
       lookup_cached(:armis_device_id, ids.armis_id, ids.partition, existing_mappings) ||
       lookup_cached(:integration_id, ids.integration_id, ids.partition, existing_mappings) ||
       lookup_cached(:netbox_device_id, ids.netbox_id, ids.partition, existing_mappings) ||
+      cached_custom_device_id(ids, existing_mappings) ||
       lookup_cached(:mac, ids.mac, ids.partition, existing_mappings)
   end
 
+  # Custom identifier types, in their configured priority order
+  defp cached_custom_device_id(ids, existing_mappings) do
+    Enum.find_value(CustomIdentifierTypes.names(), fn type ->
+      partition = CustomIdentifierTypes.partition(type, ids.partition)
+      lookup_cached(type, Map.get(ids.custom, type), partition, existing_mappings)
+    end)
+  end
+
   defp existing_device_id(update, ids, ip_to_device) do
     cond do
       IdentityReconciler.serviceradar_uuid?(update.device_id) ->
@@ -404,18 +704,26 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
     Map.get(mappings, {type, value, partition})
   end
 
-  defp build_device_upsert_records(resolved_updates, timestamp) do
-    resolved_updates
-    |> Enum.reduce(%{}, fn {update, device_id}, acc ->
+  # One record per update, in arrival order; merge_records_by_uid/1 folds
+  # several sources reporting the same device so the more confident one wins
+  # conflicting fields.
+  defp build_device_records(resolved_updates, timestamp) do
+    Enum.map(resolved_updates, fn {update, device_id} ->
       source = if update.source in [nil, ""], do: "unknown", else: update.source
-      classification = DeviceEnrichmentRules.classify(update)
+      # Set by the :classification enrichment stage unless it is disabled
+      classification = Map.get(update, :classification, %{})
       vendor_name = infer_vendor_name(update, classification)
       model = infer_model(update, classification)
       {device_type, device_type_id} = infer_device_type(update, classification)
-      metadata = merge_classification_metadata(update.metadata || %{}, classification)
+
+      metadata =
+        (update.metadata || %{})
+        |> merge_classification_metadata(classification)
+
       owner = infer_owner(update, metadata)
+      {owner_uid, owner_type} = default_assigned_owner(metadata, owner)
 
-      record = %{
+      %{
         uid: device_id,
         ip: update.ip,
         mac: update.mac,
@@ -429,6 +737,10 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
         hw_info: infer_hw_info(metadata),
         is_available: update.is_available || false,
         owner: owner,
+        owner_uid: owner_uid,
+        owner_type: owner_type,
+        owner_assigned_at: if(owner_uid, do: timestamp),
+        owner_assigned_by: if(owner_uid, do: "source:#{source}"),
         metadata: metadata,
         tags: update.tags || %{},
         discovery_sources: [source],
@@ -437,15 +749,12 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
         created_time: timestamp,
         modified_time: timestamp
       }
-
-      Map.put(acc, device_id, record)
     end)
-    |> Map.values()
   end
 
   # DB connection's search_path determines the schema
   defp bulk_upsert_devices(records) do
-    update_query = device_upsert_update_query()
+    update_query = device_upsert_update_query(SourceConfidence.table())
     do_bulk_upsert_devices(records, update_query)
   rescue
     e ->
@@ -602,15 +911,17 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
   end
 
   defp merge_records_by_uid(records) do
+    confidence = SourceConfidence.table()
+
     records
     |> Enum.reduce(%{}, fn record, acc ->
       uid = Map.fetch!(record, :uid)
-      Map.update(acc, uid, record, &merge_device_records(&1, record))
+      Map.update(acc, uid, record, &merge_device_records(&1, record, confidence))
     end)
     |> Map.values()
   end
 
-  defp merge_device_records(existing, incoming) do
+  defp merge_device_records(existing, incoming, confidence) do
     merged_metadata =
       existing.metadata
       |> strip_classification_metadata()
@@ -623,16 +934,29 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
     merged_discovery_sources =
       merge_discovery_sources(existing.discovery_sources, incoming.discovery_sources)
 
+    # Descriptive fields come from the more confident source; ties go to the newer update.
+    {preferred, fallback} =
+      if SourceConfidence.max_confidence(incoming.discovery_sources, confidence) >=
+           SourceConfidence.max_confidence(existing.discovery_sources, confidence),
+         do: {incoming, existing},
+         else: {existing, incoming}
+
+    # The first update in a batch that names an owner wins; the fields move together.
+    owner_assignment =
+      if is_nil(existing.owner_uid),
+        do: Map.take(incoming, @owner_assignment_fields),
+        else: Map.take(existing, @owner_assignment_fields)
+
     %{
       existing
       | ip: prefer_non_empty(incoming.ip, existing.ip),
         mac: prefer_non_empty(incoming.mac, existing.mac),
-        hostname: prefer_non_empty(incoming.hostname, existing.hostname),
-        name: prefer_non_empty(incoming.name, existing.name),
-        type: prefer_non_empty(incoming.type, existing.type),
-        type_id: prefer_positive_int(incoming.type_id, existing.type_id),
-        vendor_name: prefer_non_empty(incoming.vendor_name, existing.vendor_name),
-        model: prefer_non_empty(incoming.model, existing.model),
+        hostname: prefer_non_empty(preferred.hostname, fallback.hostname),
+        name: prefer_non_empty(preferred.name, fallback.name),
+        type: prefer_non_empty(preferred.type, fallback.type),
+        type_id: prefer_positive_int(preferred.type_id, fallback.type_id),
+        vendor_name: prefer_non_empty(preferred.vendor_name, fallback.vendor_name),
+        model: prefer_non_empty(preferred.model, fallback.model),
         os: merged_os,
         hw_info: merged_hw_info,
         is_available: incoming.is_available,
@@ -645,6 +969,7 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
         created_time: prefer_non_nil(existing.created_time, incoming.created_time),
         modified_time: prefer_non_nil(incoming.modified_time, existing.modified_time)
     }
+    |> Map.merge(owner_assignment)
   end
 
   defp merge_discovery_sources(existing_sources, incoming_sources) do
@@ -674,26 +999,58 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
 
   defp ip_unique_conflict?(_), do: false
 
-  defp device_upsert_update_query do
+  defp device_upsert_update_query(confidence_table) do
+    confidence = Jason.encode!(confidence_table)
+
+    # Descriptive fields are only replaced by a source at least as confident as
+    # those that already reported the device; less confident sources fill gaps.
     from(d in Device,
       update: [
         set: [
           ip: fragment("COALESCE(EXCLUDED.ip, ?)", d.ip),
           mac: fragment("COALESCE(EXCLUDED.mac, ?)", d.mac),
-          hostname: fragment("COALESCE(EXCLUDED.hostname, ?)", d.hostname),
-          name: fragment("COALESCE(EXCLUDED.name, ?)", d.name),
+          hostname:
+            fragment(
+              "CASE WHEN ? THEN COALESCE(EXCLUDED.hostname, ?) ELSE COALESCE(?, EXCLUDED.hostname) END",
+              SourceConfidence.incoming_wins(^confidence, d.discovery_sources),
+              d.hostname,
+              d.hostname
+            ),
+          name:
+            fragment(
+              "CASE WHEN ? THEN COALESCE(EXCLUDED.name, ?) ELSE COALESCE(?, EXCLUDED.name) END",
+              SourceConfidence.incoming_wins(^confidence, d.discovery_sources),
+              d.name,
+              d.name
+            ),
           type:
             fragment(
-              "COALESCE(NULLIF(EXCLUDED.type, ''), ?)",
+              "CASE WHEN ? THEN COALESCE(NULLIF(EXCLUDED.type, ''), ?) ELSE COALESCE(NULLIF(?, ''), EXCLUDED.type) END",
+              SourceConfidence.incoming_wins(^confidence, d.discovery_sources),
+              d.type,
               d.type
             ),
           type_id:
             fragment(
-              "CASE WHEN EXCLUDED.type_id IS NOT NULL AND EXCLUDED.type_id > 0 THEN EXCLUDED.type_id ELSE ? END",
+              "CASE WHEN EXCLUDED.type_id IS NOT NULL AND EXCLUDED.type_id > 0 AND (? OR COALESCE(?, 0) <= 0) THEN EXCLUDED.type_id ELSE ? END",
+              SourceConfidence.incoming_wins(^confidence, d.discovery_sources),
+              d.type_id,
               d.type_id
             ),
-          vendor_name: fragment("COALESCE(EXCLUDED.vendor_name, ?)", d.vendor_name),
-          model: fragment("COALESCE(EXCLUDED.model, ?)", d.model),
+          vendor_name:
+            fragment(
+              "CASE WHEN ? THEN COALESCE(EXCLUDED.vendor_name, ?) ELSE COALESCE(?, EXCLUDED.vendor_name) END",
+              SourceConfidence.incoming_wins(^confidence, d.discovery_sources),
+              d.vendor_name,
+              d.vendor_name
+            ),
+          model:
+            fragment(
+              "CASE WHEN ? THEN COALESCE(EXCLUDED.model, ?) ELSE COALESCE(?, EXCLUDED.model) END",
+              SourceConfidence.incoming_wins(^confidence, d.discovery_sources),
+              d.model,
+              d.model
+            ),
           os:
             fragment(
               "COALESCE(?, '{}'::jsonb) || COALESCE(EXCLUDED.os, '{}'::jsonb)",
@@ -706,6 +1063,26 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
             ),
           is_available: fragment("COALESCE(EXCLUDED.is_available, ?)", d.is_available),
           owner: fragment("COALESCE(EXCLUDED.owner, ?)", d.owner),
+          # Source-derived ownership only fills in devices nobody has assigned yet.
+          owner_uid: fragment("COALESCE(?, EXCLUDED.owner_uid)", d.owner_uid),
+          owner_type:
+            fragment(
+              "CASE WHEN ? IS NULL THEN EXCLUDED.owner_type ELSE ? END",
+              d.owner_uid,
+              d.owner_type
+            ),
+          owner_assigned_at:
+            fragment(
+              "CASE WHEN ? IS NULL THEN EXCLUDED.owner_assigned_at ELSE ? END",
+              d.owner_uid,
+              d.owner_assigned_at
+            ),
+          owner_assigned_by:
+            fragment(
+              "CASE WHEN ? IS NULL THEN EXCLUDED.owner_assigned_by ELSE ? END",
+              d.owner_uid,
+              d.owner_assigned_by
+            ),
           metadata:
             fragment(
               "(COALESCE(?, '{}'::jsonb) - 'classification_source' - 'classification_rule_id' - 'classification_confidence' - 'classification_reason') || COALESCE(EXCLUDED.metadata, '{}'::jsonb)",
@@ -719,6 +1096,7 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
               "(SELECT array_agg(DISTINCT src) FROM unnest(array_cat(COALESCE(?, ARRAY[]::text[]), EXCLUDED.discovery_sources)) AS src WHERE src IS NOT NULL AND src <> '')",
               d.discovery_sources
             ),
+          fingerprint: fragment("EXCLUDED.fingerprint"),
           last_seen_time: fragment("EXCLUDED.last_seen_time"),
           modified_time: fragment("EXCLUDED.modified_time")
         ]
@@ -759,11 +1137,19 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
         ids.netbox_id,
         partition
       )
+      |> add_custom_identifier_records(update, device_id, ids)
       |> maybe_add_identifier_record_if(include_mac?, update, device_id, :mac, ids.mac, partition)
     end)
     |> Enum.uniq_by(fn r -> {r.identifier_type, r.identifier_value, r.partition} end)
   end
 
+  defp add_custom_identifier_records(acc, update, device_id, ids) do
+    Enum.reduce(ids.custom, acc, fn {type, value}, acc ->
+      partition = CustomIdentifierTypes.partition(type, ids.partition)
+      maybe_add_identifier_record(acc, update, device_id, type, value, partition)
+    end)
+  end
+
   defp maybe_add_identifier_record(acc, _update, _device_id, _type, nil, _partition), do: acc
 
   defp maybe_add_identifier_record(acc, update, device_id, type, value, partition) do
@@ -1158,6 +1544,19 @@ defmodule ServiceRadar.Inventory.SyncIngestor do
     end
   end
 
+  # Default assignment from source metadata: an explicit owner team, or the
+  # uid of the OCSF owner reported by the source (e.g. Armis or NetBox).
+  defp default_assigned_owner(metadata, owner) do
+    team = get_string(metadata, ["owner_team", :owner_team])
+    user = if is_map(owner), do: get_string(owner, ["uid", :uid])
+
+    cond do
+      team not in [nil, ""] -> {team, :team}
+      user not in [nil, ""] -> {user, :user}
+      true -> {nil, nil}
+    end
+  end
+
   defp vendor_from_sys_descr(nil), do: nil
 
   defp vendor_from_sys_descr(sys_descr) when is_binary(sys_descr) do
@@ -2,6 +2,11 @@ defmodule ServiceRadar.Inventory.SyncIngestorQueue do
   @moduledoc """
   Buffers sync result chunks and coalesces bursts before ingestion.
 
+  Chunks from a dry-run source (`dry_run` in their sync metadata) are never
+  ingested. They are buffered per source until the final chunk of the run
+  arrives, then planned with `ServiceRadar.Inventory.SyncIngestor.plan_updates/2`
+  and the plan is recorded on the integration source.
+
   In schema-agnostic mode, operates as a single queue since the DB schema
   is set by CNPG search_path credentials.
   """
@@ -33,14 +38,21 @@ defmodule ServiceRadar.Inventory.SyncIngestorQueue do
 
   @impl true
   def init(_opts) do
-    {:ok, %{queue: %Queue{}, inflight_ref: nil}}
+    {:ok, %{queue: %Queue{}, inflight_ref: nil, dry_runs: %{}}}
   end
 
   @impl true
   def handle_cast({:enqueue, message}, state) do
     case decode_results(message) do
       {:ok, updates} ->
-        {:noreply, enqueue_updates(state, updates)}
+        {dry_run, updates} = Enum.split_with(updates, &dry_run_update?/1)
+        state = collect_dry_run(state, dry_run)
+
+        if dry_run != [] and updates == [] do
+          {:noreply, state}
+        else
+          {:noreply, enqueue_updates(state, updates)}
+        end
 
       {:error, reason} ->
         Logger.warning("Sync results decode failed: #{inspect(reason)}")
@@ -98,6 +110,73 @@ defmodule ServiceRadar.Inventory.SyncIngestorQueue do
     end
   end
 
+  # Buffers a dry-run chunk until its run is complete, then plans the run.
+  defp collect_dry_run(state, []), do: state
+
+  defp collect_dry_run(state, updates) do
+    sync_meta = extract_sync_meta(updates)
+    sync_service_id = extract_sync_service_id(updates, sync_meta)
+
+    # A new run discards whatever an unfinished earlier run left behind
+    buffered =
+      if should_record_sync_start?(sync_meta),
+        do: [updates],
+        else: [updates | Map.get(state.dry_runs, sync_service_id, [])]
+
+    if should_record_sync_status?(sync_meta) do
+      buffered |> Enum.reverse() |> List.flatten() |> start_dry_run_plan(sync_service_id)
+      %{state | dry_runs: Map.delete(state.dry_runs, sync_service_id)}
+    else
+      %{state | dry_runs: Map.put(state.dry_runs, sync_service_id, buffered)}
+    end
+  end
+
+  defp start_dry_run_plan(updates, sync_service_id) do
+    case start_task(fn -> plan_dry_run(updates, sync_service_id) end) do
+      {:ok, _ref} ->
+        :ok
+
+      {:error, reason} ->
+        Logger.warning("Failed to start dry-run sync planning task: #{inspect(reason)}")
+    end
+  end
+
+  defp plan_dry_run(updates, sync_service_id) do
+    actor = SystemActor.system(:sync_ingestor)
+    opts = [actor: actor, sync_service_id: sync_service_id]
+
+    case sync_ingestor().plan_updates(updates, opts) do
+      {:ok, plan} ->
+        Logger.info(
+          "Dry-run sync for #{sync_service_id || "unknown source"}: #{inspect(plan.summary)}"
+        )
+
+        record_dry_run(sync_service_id, actor, plan)
+
+      {:error, reason} ->
+        Logger.warning("Dry-run sync planning failed: #{inspect(reason)}")
+    end
+  end
+
+  defp record_dry_run(sync_service_id, actor, plan) do
+    with_sync_service(sync_service_id, actor, fn source ->
+      attrs = %{plan: plan}
+      update_sync_source(source, actor, :record_dry_run, attrs, sync_service_id, "dry-run plan")
+    end)
+  rescue
+    error ->
+      Logger.warning("Error recording dry-run plan: #{inspect(error)}")
+  end
+
+  defp dry_run_update?(update) when is_map(update) do
+    case update["sync_meta"] || update[:sync_meta] do
+      %{} = meta -> get_bool(meta, ["dry_run", :dry_run])
+      _ -> false
+    end
+  end
+
+  defp dry_run_update?(_update), do: false
+
   defp maybe_schedule_flush(state, queue) do
     coalesce_ms = coalesce_window_ms()
 
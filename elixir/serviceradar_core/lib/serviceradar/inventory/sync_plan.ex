@@ -0,0 +1,203 @@
+defmodule ServiceRadar.Inventory.SyncPlan do
+  @moduledoc """
+  The inventory changes a sync run would make, computed without writing.
+
+  `ServiceRadar.Inventory.SyncIngestor.plan_updates/2` resolves a source's
+  updates exactly as an ingest would, then hands the resulting device records
+  to `build/4` along with the stored devices they resolved to and the active
+  devices the source last reported. The plan groups devices by action:
+
+    * `create` - resolved to a device that does not exist yet
+    * `update` - resolved to an existing device whose stored fields the
+      upsert would change, with the `from`/`to` value of each changed field;
+      `restore: true` marks soft-deleted devices the sync would bring back
+    * `tombstone` - active devices carrying the source's `sync_service_id`
+      that the run no longer returns
+    * `unchanged` - the number of existing devices the run leaves as they are
+
+  Field changes follow the upsert rules: IPs and MACs are replaced when
+  reported, and descriptive fields only by a source at least as confident as
+  those that already reported the device (see
+  `ServiceRadar.Inventory.SourceConfidence`).
+
+  ## Delete safety
+
+  A source that suddenly stops returning most of its devices is more likely
+  misconfigured or failing than reporting real removals. When the tombstone
+  candidates exceed `:max_tombstone_percent` of the source's active devices,
+  the plan trips the delete-safety threshold: `tombstone` is left empty and
+  the candidates are listed under `withheld_tombstones` instead.
+
+      config :serviceradar_core, ServiceRadar.Inventory.SyncPlan,
+        max_tombstone_percent: 25
+
+  or at runtime with `SERVICERADAR_SYNC_MAX_TOMBSTONE_PERCENT`.
+  """
+
+  alias ServiceRadar.Inventory.SourceConfidence
+
+  @default_max_tombstone_percent 25
+  @address_fields [:ip, :mac]
+  @descriptive_fields [:hostname, :name, :type, :vendor_name, :model]
+
+  @type t :: %{
+          create: [map()],
+          update: [map()],
+          tombstone: [map()],
+          withheld_tombstones: [map()],
+          unchanged: non_neg_integer(),
+          rejected: non_neg_integer(),
+          delete_safety: map(),
+          summary: map()
+        }
+
+  @doc "The configured delete-safety threshold, as a percentage of active devices."
+  @spec max_tombstone_percent() :: number()
+  def max_tombstone_percent do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(:max_tombstone_percent, @default_max_tombstone_percent)
+  end
+
+  @doc """
+  Builds the plan for the merged device `records` of a run.
+
+  `existing` maps device uids to the stored devices (soft-deleted ones
+  included) and `owned` lists the active devices the source reported before.
+
+  Options:
+    - `:max_tombstone_percent` - the delete-safety threshold (default: the
+      configured `max_tombstone_percent/0`)
+    - `:rejected` - updates the validator rejected, reported in the summary
+    - `:confidence` - the source confidence table (default: the effective
+      `SourceConfidence.table/0`)
+  """
+  @spec build([map()], %{String.t() => map()}, [map()], keyword()) :: t()
+  def build(records, existing, owned, opts \\ []) do
+    confidence = Keyword.get_lazy(opts, :confidence, &SourceConfidence.table/0)
+    max_percent = Keyword.get_lazy(opts, :max_tombstone_percent, &max_tombstone_percent/0)
+
+    {creates, updates, unchanged} =
+      Enum.reduce(records, {[], [], 0}, fn record, {creates, updates, unchanged} ->
+        case plan_record(record, Map.get(existing, record.uid), confidence) do
+          {:create, entry} -> {[entry | creates], updates, unchanged}
+          {:update, entry} -> {creates, [entry | updates], unchanged}
+          :unchanged -> {creates, updates, unchanged + 1}
+        end
+      end)
+
+    seen = MapSet.new(records, & &1.uid)
+    candidates = owned |> Enum.reject(&MapSet.member?(seen, &1.uid)) |> Enum.map(&tombstone/1)
+    delete_safety = delete_safety(length(candidates), length(owned), max_percent)
+
+    {tombstones, withheld} =
+      if delete_safety.tripped, do: {[], candidates}, else: {candidates, []}
+
+    plan = %{
+      create: Enum.sort_by(creates, & &1.device_id),
+      update: Enum.sort_by(updates, & &1.device_id),
+      tombstone: Enum.sort_by(tombstones, & &1.device_id),
+      withheld_tombstones: Enum.sort_by(withheld, & &1.device_id),
+      unchanged: unchanged,
+      rejected: Keyword.get(opts, :rejected, 0),
+      delete_safety: delete_safety
+    }
+
+    Map.put(plan, :summary, summary(plan))
+  end
+
+  defp plan_record(record, nil, _confidence) do
+    {:create,
+     %{
+       device_id: record.uid,
+       ip: record.ip,
+       mac: record.mac,
+       hostname: record.hostname,
+       type: record.type,
+       vendor_name: record.vendor_name,
+       sources: record.discovery_sources
+     }}
+  end
+
+  defp plan_record(record, stored, confidence) do
+    changes = field_changes(record, stored, confidence)
+    restore = not is_nil(Map.get(stored, :deleted_at))
+
+    if map_size(changes) == 0 and not restore do
+      :unchanged
+    else
+      {:update,
+       %{
+         device_id: record.uid,
+         ip: stored.ip,
+         hostname: stored.hostname,
+         changes: changes,
+         restore: restore
+       }}
+    end
+  end
+
+  defp field_changes(record, stored, confidence) do
+    incoming_wins =
+      SourceConfidence.max_confidence(record.discovery_sources, confidence) >=
+        SourceConfidence.max_confidence(stored.discovery_sources, confidence)
+
+    address_changes =
+      Enum.map(@address_fields, fn field ->
+        current = Map.get(stored, field)
+        {field, current, blank_to_nil(Map.get(record, field)) || current}
+      end)
+
+    descriptive_changes =
+      Enum.map(@descriptive_fields, fn field ->
+        current = Map.get(stored, field)
+        incoming = blank_to_nil(Map.get(record, field))
+
+        planned =
+          if incoming_wins,
+            do: incoming || current,
+            else: blank_to_nil(current) || incoming || current
+
+        {field, current, planned}
+      end)
+
+    (address_changes ++ descriptive_changes)
+    |> Enum.reject(fn {_field, from, to} -> from == to end)
+    |> Map.new(fn {field, from, to} -> {field, %{from: from, to: to}} end)
+  end
+
+  defp tombstone(device) do
+    %{
+      device_id: device.uid,
+      ip: device.ip,
+      hostname: device.hostname,
+      last_seen_time: device.last_seen_time
+    }
+  end
+
+  defp delete_safety(candidates, owned, max_percent) do
+    percent = if owned > 0, do: Float.round(candidates * 100 / owned, 1), else: 0.0
+
+    %{
+      max_percent: max_percent,
+      percent: percent,
+      candidates: candidates,
+      active_devices: owned,
+      tripped: percent > max_percent
+    }
+  end
+
+  defp summary(plan) do
+    %{
+      create: length(plan.create),
+      update: length(plan.update),
+      tombstone: length(plan.tombstone),
+      withheld_tombstones: length(plan.withheld_tombstones),
+      unchanged: plan.unchanged,
+      rejected: plan.rejected
+    }
+  end
+
+  defp blank_to_nil(""), do: nil
+  defp blank_to_nil(value), do: value
+end
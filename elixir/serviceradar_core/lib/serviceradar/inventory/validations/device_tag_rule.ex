@@ -0,0 +1,56 @@
+defmodule ServiceRadar.Inventory.Validations.DeviceTagRule do
+  @moduledoc """
+  Rejects tag rules that could never be evaluated: malformed CIDRs or regex
+  patterns, and tags that are not string keys with string values.
+  """
+
+  use Ash.Resource.Validation
+
+  alias ServiceRadar.Types.Cidr
+
+  @impl true
+  def validate(changeset, _opts, _context) do
+    with :ok <- validate_cidrs(Ash.Changeset.get_attribute(changeset, :cidrs)),
+         :ok <- validate_pattern(changeset, :vendor_pattern),
+         :ok <- validate_pattern(changeset, :os_pattern) do
+      validate_tags(Ash.Changeset.get_attribute(changeset, :tags))
+    end
+  end
+
+  defp validate_cidrs(cidrs) do
+    case Enum.reject(cidrs || [], &valid_cidr?/1) do
+      [] -> :ok
+      invalid -> {:error, field: :cidrs, message: "invalid CIDR: #{Enum.join(invalid, ", ")}"}
+    end
+  end
+
+  defp valid_cidr?(cidr) when is_binary(cidr) and cidr != "" do
+    match?({:ok, _}, Cidr.cast_input(cidr, []))
+  end
+
+  defp valid_cidr?(_cidr), do: false
+
+  defp validate_pattern(changeset, field) do
+    case Ash.Changeset.get_attribute(changeset, field) do
+      pattern when pattern in [nil, ""] ->
+        :ok
+
+      pattern ->
+        case Regex.compile(pattern) do
+          {:ok, _regex} -> :ok
+          {:error, _} -> {:error, field: field, message: "is not a valid regular expression"}
+        end
+    end
+  end
+
+  defp validate_tags(tags) when is_map(tags) and map_size(tags) > 0 do
+    if Enum.all?(tags, fn {key, value} -> tag_key?(key) and is_binary(value) end),
+      do: :ok,
+      else: {:error, field: :tags, message: "must map tag keys to string values"}
+  end
+
+  defp validate_tags(_tags), do: {:error, field: :tags, message: "must set at least one tag"}
+
+  defp tag_key?(key) when is_binary(key), do: String.trim(key) != ""
+  defp tag_key?(key), do: is_atom(key) and key not in [nil, true, false]
+end
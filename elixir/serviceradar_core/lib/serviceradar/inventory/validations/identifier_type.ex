@@ -0,0 +1,28 @@
+defmodule ServiceRadar.Inventory.Validations.IdentifierType do
+  @moduledoc """
+  Requires a built-in identifier type or a registered custom one (see
+  `ServiceRadar.Inventory.CustomIdentifierTypes`).
+  """
+
+  use Ash.Resource.Validation
+
+  alias ServiceRadar.Inventory.CustomIdentifierTypes
+
+  @impl true
+  def atomic(_changeset, _opts, _context), do: :ok
+
+  @impl true
+  def validate(changeset, _opts, _context) do
+    case Ash.Changeset.get_attribute(changeset, :identifier_type) do
+      nil ->
+        :ok
+
+      type ->
+        if CustomIdentifierTypes.known_type?(type) do
+          :ok
+        else
+          {:error, field: :identifier_type, message: "is not a known identifier type"}
+        end
+    end
+  end
+end
@@ -0,0 +1,31 @@
+defmodule ServiceRadar.Inventory.Validations.OwnerAssignment do
+  @moduledoc """
+  Requires an owner type whenever an owner is assigned, and no owner type
+  when the owner is cleared.
+  """
+
+  use Ash.Resource.Validation
+
+  @impl true
+  def atomic(_changeset, _opts, _context), do: :ok
+
+  @impl true
+  def validate(changeset, _opts, _context) do
+    owner_uid = Ash.Changeset.get_attribute(changeset, :owner_uid)
+    owner_type = Ash.Changeset.get_attribute(changeset, :owner_type)
+
+    cond do
+      is_binary(owner_uid) and String.trim(owner_uid) == "" ->
+        {:error, field: :owner_uid, message: "must not be blank"}
+
+      is_binary(owner_uid) and is_nil(owner_type) ->
+        {:error, field: :owner_type, message: "is required when an owner is assigned"}
+
+      is_nil(owner_uid) and not is_nil(owner_type) ->
+        {:error, field: :owner_uid, message: "is required when an owner type is set"}
+
+      true ->
+        :ok
+    end
+  end
+end
@@ -0,0 +1,103 @@
+defmodule ServiceRadar.Inventory.Validations.ScheduledDeviceOperation do
+  @moduledoc """
+  Validates a scheduled device operation: a future run time, exactly one
+  target (device uids or a CIDR), parameters matching the operation, and an
+  actor allowed to run that operation.
+  """
+
+  use Ash.Resource.Validation
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Identity.RBAC
+  alias ServiceRadar.Inventory.ScheduledDeviceOperations
+  alias ServiceRadar.Types.Cidr
+
+  @impl true
+  def validate(changeset, _opts, context) do
+    operation = Ash.Changeset.get_attribute(changeset, :operation)
+
+    with :ok <- validate_run_at(Ash.Changeset.get_attribute(changeset, :run_at)),
+         :ok <-
+           validate_target(
+             Ash.Changeset.get_attribute(changeset, :device_uids) || [],
+             Ash.Changeset.get_attribute(changeset, :cidr)
+           ),
+         :ok <- validate_params(operation, Ash.Changeset.get_attribute(changeset, :params)) do
+      validate_permission(operation, Map.get(context, :actor))
+    end
+  end
+
+  defp validate_run_at(%DateTime{} = run_at) do
+    if DateTime.after?(run_at, DateTime.utc_now()),
+      do: :ok,
+      else: {:error, field: :run_at, message: "must be in the future"}
+  end
+
+  defp validate_run_at(_run_at), do: :ok
+
+  defp validate_target([], cidr) when cidr in [nil, ""],
+    do: {:error, field: :device_uids, message: "device_uids or cidr is required"}
+
+  defp validate_target([_ | _], cidr) when cidr not in [nil, ""],
+    do: {:error, field: :cidr, message: "cannot be combined with device_uids"}
+
+  defp validate_target(uids, _cidr) when length(uids) > 10_000,
+    do: {:error, field: :device_uids, message: "at most 10000 devices per operation"}
+
+  defp validate_target([], cidr) do
+    case Cidr.cast_input(cidr, []) do
+      {:ok, _} -> :ok
+      _ -> {:error, field: :cidr, message: "is not a valid CIDR"}
+    end
+  end
+
+  defp validate_target(_uids, _cidr), do: :ok
+
+  defp validate_params(:add_tags, params) do
+    case param(params, "tags") do
+      %{} = tags when map_size(tags) > 0 ->
+        if Enum.all?(tags, fn {key, value} -> tag_key?(key) and is_binary(value) end),
+          do: :ok,
+          else: {:error, field: :params, message: "tags must map keys to string values"}
+
+      _ ->
+        {:error, field: :params, message: "add_tags requires a non-empty tags map"}
+    end
+  end
+
+  defp validate_params(:remove_tags, params) do
+    case param(params, "keys") do
+      [_ | _] = keys ->
+        if Enum.all?(keys, &is_binary/1),
+          do: :ok,
+          else: {:error, field: :params, message: "keys must be strings"}
+
+      _ ->
+        {:error, field: :params, message: "remove_tags requires a non-empty keys list"}
+    end
+  end
+
+  defp validate_params(_operation, _params), do: :ok
+
+  defp validate_permission(operation, actor) do
+    cond do
+      is_nil(operation) or SystemActor.system_actor?(actor) ->
+        :ok
+
+      RBAC.has_permission?(actor, ScheduledDeviceOperations.required_permission(operation)) ->
+        :ok
+
+      true ->
+        {:error, field: :operation, message: "not permitted for this user"}
+    end
+  end
+
+  defp tag_key?(key) when is_binary(key), do: String.trim(key) != ""
+  defp tag_key?(key), do: is_atom(key) and key not in [nil, true, false]
+
+  defp param(params, key) when is_map(params) do
+    Map.get(params, key) || Map.get(params, String.to_existing_atom(key))
+  end
+
+  defp param(_params, _key), do: nil
+end
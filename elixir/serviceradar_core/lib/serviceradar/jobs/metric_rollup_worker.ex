@@ -0,0 +1,291 @@
+defmodule ServiceRadar.Jobs.MetricRollupWorker do
+  @moduledoc """
+  Oban worker that maintains the summary resolutions SRQL reads for long
+  metric time windows.
+
+  - `:hourly` - the `*_metrics_hourly` continuous aggregates. TimescaleDB
+    refreshes them; this worker keeps their retention policy in line with the
+    configured retention.
+  - `:daily` - the `*_metrics_daily` tables. Each run re-aggregates the raw
+    metrics for the last `lookback_days` whole days (plus today) into
+    min/max/avg/count rows and prunes days past retention.
+
+  SRQL reads daily rollups for stats over 30 days or more and for downsampled
+  queries with whole-day buckets, hourly aggregates otherwise.
+
+  Configuration:
+
+      config :serviceradar_core, ServiceRadar.Jobs.MetricRollupWorker,
+        resolutions: [:hourly, :daily],
+        retention_days: %{hourly: 395, daily: 730},
+        lookback_days: 1
+  """
+
+  use Oban.Worker,
+    queue: :maintenance,
+    max_attempts: 3,
+    unique: [period: 3_600, states: [:available, :scheduled, :executing, :retryable]]
+
+  alias Ecto.Adapters.SQL
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @resolutions [:hourly, :daily]
+  @default_retention_days %{hourly: 395, daily: 730}
+  @default_lookback_days 1
+  @query_timeout_ms 300_000
+
+  @families %{
+    "cpu_metrics" => %{
+      dimensions: ~w(device_id host_id),
+      aggregates: [
+        {"avg_usage_percent", "AVG(usage_percent)::float8"},
+        {"max_usage_percent", "MAX(usage_percent)::float8"}
+      ]
+    },
+    "memory_metrics" => %{
+      dimensions: ~w(device_id host_id),
+      aggregates: [
+        {"avg_usage_percent", "AVG(usage_percent)::float8"},
+        {"max_usage_percent", "MAX(usage_percent)::float8"},
+        {"avg_used_bytes", "AVG(used_bytes)::float8"},
+        {"avg_available_bytes", "AVG(available_bytes)::float8"}
+      ]
+    },
+    "disk_metrics" => %{
+      dimensions: ~w(device_id host_id mount_point),
+      aggregates: [
+        {"avg_usage_percent", "AVG(usage_percent)::float8"},
+        {"max_usage_percent", "MAX(usage_percent)::float8"},
+        {"avg_used_bytes", "AVG(used_bytes)::float8"},
+        {"avg_available_bytes", "AVG(available_bytes)::float8"}
+      ]
+    },
+    "process_metrics" => %{
+      dimensions: ~w(device_id host_id name),
+      aggregates: [
+        {"avg_cpu_usage", "AVG(cpu_usage)::float8"},
+        {"max_cpu_usage", "MAX(cpu_usage)::float8"},
+        {"avg_memory_usage", "AVG(memory_usage)::float8"},
+        {"max_memory_usage", "MAX(memory_usage)::float8"}
+      ]
+    },
+    "timeseries_metrics" => %{
+      dimensions: ~w(device_id metric_type metric_name),
+      aggregates: [
+        {"avg_value", "AVG(value)::float8"},
+        {"min_value", "MIN(value)::float8"},
+        {"max_value", "MAX(value)::float8"}
+      ]
+    }
+  }
+
+  @retention_matches_sql """
+  SELECT (j.config ->> 'drop_after')::interval = make_interval(days => $2)
+  FROM timescaledb_information.jobs j
+  JOIN timescaledb_information.continuous_aggregates c
+    ON j.hypertable_schema = c.materialization_hypertable_schema
+   AND j.hypertable_name = c.materialization_hypertable_name
+  WHERE j.proc_name = 'policy_retention'
+    AND c.view_schema = 'platform'
+    AND c.view_name = $1
+  """
+
+  @doc "Metric families rolled up by this worker (raw table names)."
+  @spec families() :: [String.t()]
+  def families, do: @families |> Map.keys() |> Enum.sort()
+
+  def retention_matches_sql, do: @retention_matches_sql
+
+  def config do
+    app_config = Application.get_env(:serviceradar_core, __MODULE__, [])
+
+    %{
+      resolutions: normalize_resolutions(Keyword.get(app_config, :resolutions, @resolutions)),
+      retention_days:
+        Map.merge(
+          @default_retention_days,
+          normalize_retention(Keyword.get(app_config, :retention_days, %{}))
+        ),
+      lookback_days: Keyword.get(app_config, :lookback_days, @default_lookback_days)
+    }
+  end
+
+  @doc """
+  Returns the `[from, to)` window re-aggregated by a run at `now`: from the
+  start of the UTC day `lookback_days` ago up to `now`. Whole days are
+  recomputed so an upsert never replaces a day with a partial aggregate.
+  """
+  @spec rollup_window(DateTime.t(), non_neg_integer()) :: {DateTime.t(), DateTime.t()}
+  def rollup_window(now, lookback_days) do
+    day = now |> DateTime.to_date() |> Date.add(-lookback_days)
+    {DateTime.new!(day, ~T[00:00:00], "Etc/UTC"), now}
+  end
+
+  @doc "SQL that upserts daily rollups for a family from its raw table over `[$1, $2)`."
+  @spec rollup_sql(String.t()) :: String.t()
+  def rollup_sql(family) do
+    %{dimensions: dimensions, aggregates: aggregates} = Map.fetch!(@families, family)
+
+    dims = Enum.join(dimensions, ", ")
+    value_columns = Enum.map(aggregates, &elem(&1, 0))
+    group_by = Enum.map_join(1..(length(dimensions) + 1), ", ", &Integer.to_string/1)
+
+    select = Enum.map_join(aggregates, ",\n  ", fn {column, expr} -> "#{expr} AS #{column}" end)
+    updates = Enum.map_join(value_columns ++ ["sample_count"], ",\n  ", &"#{&1} = EXCLUDED.#{&1}")
+
+    """
+    INSERT INTO platform.#{family}_daily (bucket, #{dims}, #{Enum.join(value_columns, ", ")}, sample_count)
+    SELECT
+      date_trunc('day', timestamp, 'UTC') AS bucket,
+      #{dims},
+      #{select},
+      COUNT(*)::bigint AS sample_count
+    FROM platform.#{family}
+    WHERE timestamp >= $1 AND timestamp < $2
+    GROUP BY #{group_by}
+    ON CONFLICT (bucket, #{dims}) DO UPDATE SET
+      #{updates}
+    """
+  end
+
+  @doc "SQL that deletes daily rollups older than `$1` days."
+  @spec prune_sql(String.t()) :: String.t()
+  def prune_sql(family) do
+    "DELETE FROM platform.#{family}_daily WHERE bucket < NOW() - make_interval(days => $1)"
+  end
+
+  @impl Oban.Worker
+  def perform(_job) do
+    config = config()
+
+    results =
+      Enum.flat_map(config.resolutions, fn resolution ->
+        Enum.map(families(), &maintain(resolution, &1, config))
+      end)
+
+    case Enum.filter(results, &match?({:error, _}, &1)) do
+      [] ->
+        Logger.info("Metric rollups maintained", resolutions: config.resolutions)
+        :ok
+
+      [{:error, error} | _] ->
+        {:error, error}
+    end
+  end
+
+  @doc """
+  Re-aggregates raw metrics for one family into its daily rollup table over
+  `[from, to)`. Returns the number of daily rows written.
+  """
+  @spec rollup(String.t(), DateTime.t(), DateTime.t()) ::
+          {:ok, non_neg_integer()} | {:error, term()}
+  def rollup(family, from, to) do
+    case SQL.query(Repo, rollup_sql(family), [from, to], timeout: @query_timeout_ms) do
+      {:ok, %{num_rows: rows}} -> {:ok, rows}
+      {:error, error} -> {:error, error}
+    end
+  end
+
+  defp maintain(:daily, family, config) do
+    {from, to} = rollup_window(DateTime.utc_now(), config.lookback_days)
+    retention_days = config.retention_days.daily
+
+    with {:ok, written} <- rollup(family, from, to),
+         {:ok, %{num_rows: pruned}} <-
+           SQL.query(Repo, prune_sql(family), [retention_days], timeout: @query_timeout_ms) do
+      Logger.debug("Rolled up daily #{family}", written_rows: written, pruned_rows: pruned)
+      :ok
+    else
+      {:error, %Postgrex.Error{postgres: %{code: :undefined_table}}} ->
+        Logger.debug("#{family}_daily missing; skipping rollup")
+        :ok
+
+      {:error, error} ->
+        Logger.error("Failed to roll up daily #{family}: #{Exception.message(error)}")
+        {:error, error}
+    end
+  end
+
+  defp maintain(:hourly, family, config) do
+    view = "#{family}_hourly"
+    retention_days = config.retention_days.hourly
+
+    case SQL.query(Repo, @retention_matches_sql, [view, retention_days],
+           timeout: @query_timeout_ms
+         ) do
+      {:ok, %{rows: [[true]]}} ->
+        :ok
+
+      {:ok, _} ->
+        replace_retention_policy(view, retention_days)
+
+      {:error, error} ->
+        # Without TimescaleDB there is no hourly resolution to manage.
+        Logger.debug("Skipping hourly retention for #{view}: #{Exception.message(error)}")
+        :ok
+    end
+  end
+
+  defp replace_retention_policy(view, retention_days) do
+    regclass = "platform.#{view}"
+
+    with {:ok, _} <-
+           SQL.query(
+             Repo,
+             "SELECT remove_retention_policy($1::regclass, if_exists => true)",
+             [regclass],
+             timeout: @query_timeout_ms
+           ),
+         {:ok, _} <-
+           SQL.query(
+             Repo,
+             "SELECT add_retention_policy($1::regclass, make_interval(days => $2))",
+             [regclass, retention_days],
+             timeout: @query_timeout_ms
+           ) do
+      Logger.info("Updated #{view} retention", retention_days: retention_days)
+      :ok
+    else
+      {:error, error} ->
+        Logger.error("Failed to update #{view} retention: #{Exception.message(error)}")
+        {:error, error}
+    end
+  end
+
+  defp normalize_resolutions(resolutions) when is_list(resolutions) do
+    resolutions
+    |> Enum.map(&normalize_resolution/1)
+    |> Enum.filter(&(&1 in @resolutions))
+    |> Enum.uniq()
+  end
+
+  defp normalize_resolutions(_resolutions), do: @resolutions
+
+  defp normalize_resolution(resolution) when is_atom(resolution), do: resolution
+
+  defp normalize_resolution(resolution) when is_binary(resolution) do
+    case resolution |> String.trim() |> String.downcase() do
+      "hourly" -> :hourly
+      "daily" -> :daily
+      _ -> nil
+    end
+  end
+
+  defp normalize_resolution(_resolution), do: nil
+
+  defp normalize_retention(retention) when is_map(retention) or is_list(retention) do
+    Enum.reduce(retention, %{}, fn {resolution, days}, acc ->
+      case normalize_resolution(resolution) do
+        resolution when resolution in @resolutions and is_integer(days) and days > 0 ->
+          Map.put(acc, resolution, days)
+
+        _ ->
+          acc
+      end
+    end)
+  end
+
+  defp normalize_retention(_retention), do: %{}
+end
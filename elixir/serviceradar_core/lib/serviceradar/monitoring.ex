@@ -15,6 +15,7 @@ defmodule ServiceRadar.Monitoring do
   - `ServiceRadar.Monitoring.ServiceCheck` - Scheduled service checks
   - `ServiceRadar.Monitoring.Alert` - Alerts with state machine lifecycle
   - `ServiceRadar.Monitoring.OcsfEvent` - OCSF event log activity entries
+  - `ServiceRadar.Monitoring.MonitoredCertificate` - TLS certificates tracked for expiry
 
   ## Alert State Machine
 
@@ -42,6 +43,8 @@ defmodule ServiceRadar.Monitoring do
     resource ServiceRadar.Monitoring.ServiceCheck
     resource ServiceRadar.Monitoring.Alert
     resource ServiceRadar.Monitoring.OcsfEvent
+    resource ServiceRadar.Monitoring.MonitoredCertificate
+    resource ServiceRadar.Monitoring.InventoryCount
   end
 
   authorization do
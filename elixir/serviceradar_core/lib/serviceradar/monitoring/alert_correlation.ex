@@ -0,0 +1,383 @@
+defmodule ServiceRadar.Monitoring.AlertCorrelation do
+  @moduledoc """
+  Groups device alerts that share a root cause into one incident, so a failed
+  host pages once instead of once per VM running on it.
+
+  `ServiceRadar.Monitoring.AlertGenerator` passes each device alert matching a
+  rule to `correlate/2` before creating it. Upstream devices are found by
+  following `ServiceRadar.Inventory.DeviceRelationships` edges from the
+  alert's device (a VM `runs_on` its host); downstream devices by following
+  them backwards.
+
+    * When an upstream device has an active alert raised within the window,
+      or one already rooting an open incident, the new alert joins that
+      incident as a `downstream` member. The first such alert opens the
+      incident, tagging the upstream alert as the `root_cause`.
+    * When the new alert's device is upstream of active, uncorrelated alerts
+      raised within the window (the host alert arrived after its VMs'), it
+      opens an incident as the root cause with those alerts as members.
+
+  Opening an incident raises one incident alert through
+  `AlertGenerator.incident_opened/1`, which is notified; alerts joining the
+  incident are stored with `incident_id`, `incident_role` and
+  `root_cause_device_id` metadata but not notified themselves, and the
+  incident alert's `alert_count` follows its members. An incident stays open
+  while its root-cause alert is active.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Monitoring.AlertCorrelation,
+        enabled: true,
+        window: to_timeout(minute: 2),
+        rules: [
+          %{
+            name: "hosts",
+            source_types: [:device, :service_check],
+            severities: [:critical, :warning],
+            relationship_types: ["runs_on", "member_of"],
+            max_depth: 2
+          }
+        ]
+
+  Empty `source_types`, `severities` and `relationship_types` match all; the
+  first matching rule applies.
+  """
+
+  alias ServiceRadar.Inventory.DeviceRelationships
+  alias ServiceRadar.Monitoring.Alert
+  alias ServiceRadar.Monitoring.AlertGenerator
+
+  require Ash.Query
+  require Logger
+
+  @default_window to_timeout(minute: 2)
+  @default_depth 2
+
+  @incident_field "incident_id"
+  @role_field "incident_role"
+  @root_field "root_cause_device_id"
+  @count_field "alert_count"
+
+  @severity_order [:info, :warning, :critical, :emergency]
+
+  @type rule :: %{
+          name: String.t(),
+          source_types: [atom()],
+          severities: [atom()],
+          relationship_types: [String.t()],
+          max_depth: pos_integer()
+        }
+
+  @doc "Whether correlation is enabled and has valid rules."
+  @spec enabled?() :: boolean()
+  def enabled? do
+    config(:enabled, false) == true and match?({:ok, [_ | _]}, rules())
+  end
+
+  @doc "The configured rules, validated."
+  @spec rules() :: {:ok, [rule()]} | {:error, String.t()}
+  def rules, do: validate_rules(config(:rules, []))
+
+  @doc "The configured correlation window in milliseconds."
+  @spec window() :: pos_integer()
+  def window do
+    case config(:window, @default_window) do
+      value when is_integer(value) and value > 0 -> value
+      _ -> @default_window
+    end
+  end
+
+  @doc "Normalizes rules, checking their relationship types and depth."
+  @spec validate_rules([map() | keyword()]) :: {:ok, [rule()]} | {:error, String.t()}
+  def validate_rules(rules) when is_list(rules) do
+    rules
+    |> Enum.with_index()
+    |> Enum.reduce_while({:ok, []}, fn {rule, index}, {:ok, acc} ->
+      case validate_rule(Map.new(rule), index) do
+        {:ok, rule} -> {:cont, {:ok, [rule | acc]}}
+        {:error, reason} -> {:halt, {:error, reason}}
+      end
+    end)
+    |> case do
+      {:ok, rules} -> {:ok, Enum.reverse(rules)}
+      error -> error
+    end
+  end
+
+  def validate_rules(_rules), do: {:error, "rules must be a list"}
+
+  @doc "The first rule matching alert attributes, or nil."
+  @spec match_rule([rule()], map()) :: rule() | nil
+  def match_rule(rules, attrs) do
+    Enum.find(rules, fn rule ->
+      matches?(rule.source_types, Map.get(attrs, :source_type)) and
+        matches?(rule.severities, Map.get(attrs, :severity))
+    end)
+  end
+
+  @doc """
+  Correlates alert attributes with the active alerts around their device.
+
+  Returns `{:notify, attrs}` for an alert to create and notify as usual, or
+  `{:incident, attrs}` for an alert to create quietly, tagged as a member of
+  an incident.
+
+  Options: `:actor`, `:now`, and `:rules`/`:window` to override the
+  configuration.
+  """
+  @spec correlate(map(), keyword()) :: {:notify, map()} | {:incident, map()}
+  def correlate(attrs, opts \\ []) do
+    rules = Keyword.get_lazy(opts, :rules, &configured_rules/0)
+    device_uid = Map.get(attrs, :device_uid)
+
+    with true <- Keyword.has_key?(opts, :rules) or enabled?(),
+         true <- is_binary(device_uid),
+         false <- incident_alert?(attrs),
+         %{} = rule <- match_rule(rules, attrs) do
+      ctx = %{
+        actor: Keyword.get(opts, :actor),
+        now: Keyword.get_lazy(opts, :now, &DateTime.utc_now/0),
+        window: Keyword.get_lazy(opts, :window, &window/0),
+        rule: rule
+      }
+
+      join_upstream(attrs, device_uid, ctx)
+    else
+      _ -> {:notify, attrs}
+    end
+  end
+
+  defp join_upstream(attrs, device_uid, ctx) do
+    case related_alerts(device_uid, "outgoing", ctx) do
+      [] ->
+        adopt_downstream(attrs, device_uid, ctx)
+
+      upstream ->
+        # An open incident wins; otherwise the earliest failure is the
+        # probable root cause.
+        root_alert =
+          Enum.find(upstream, &incident_id/1) ||
+            Enum.min_by(upstream, & &1.triggered_at, DateTime)
+
+        case incident_id(root_alert) do
+          nil -> open_incident(root_alert, [], attrs, ctx)
+          incident_id -> join_incident(incident_id, root_alert, attrs, ctx)
+        end
+    end
+  end
+
+  defp adopt_downstream(attrs, device_uid, ctx) do
+    case related_alerts(device_uid, "incoming", ctx) |> Enum.reject(&incident_id/1) do
+      [] -> {:notify, attrs}
+      members -> open_incident(nil, members, attrs, ctx)
+    end
+  end
+
+  # Opens an incident rooted at `root_alert`, or at the new alert when nil.
+  defp open_incident(root_alert, members, attrs, ctx) do
+    incident_id = Ecto.UUID.generate()
+    root_device = if root_alert, do: root_alert.device_uid, else: attrs.device_uid
+    root_title = if root_alert, do: root_alert.title, else: attrs.title
+
+    {role, stored} =
+      if root_alert, do: {"downstream", [root_alert]}, else: {"root_cause", members}
+
+    Enum.each(stored, fn alert ->
+      member_role = if alert == root_alert, do: "root_cause", else: "downstream"
+      tag_alert(alert, incident_id, root_device, member_role, ctx.actor)
+    end)
+
+    count = length(stored) + 1
+
+    case AlertGenerator.incident_opened(
+           incident_id: incident_id,
+           root_device_uid: root_device,
+           root_title: root_title,
+           rule: ctx.rule.name,
+           alert_count: count,
+           severity: max_severity([attrs | stored]),
+           partition: get_in(attrs, [:metadata, "partition"]),
+           actor: ctx.actor
+         ) do
+      {:ok, _incident} ->
+        :ok
+
+      {:error, reason} ->
+        Logger.warning("Failed to open incident for #{root_device}: #{inspect(reason)}")
+    end
+
+    {:incident, tag_attrs(attrs, incident_id, root_device, role)}
+  end
+
+  defp join_incident(incident_id, root_alert, attrs, ctx) do
+    root_device = Map.get(root_alert.metadata, @root_field) || root_alert.device_uid
+
+    bump_incident(incident_id, ctx.actor)
+    {:incident, tag_attrs(attrs, incident_id, root_device, "downstream")}
+  end
+
+  defp bump_incident(incident_id, actor) do
+    Alert
+    |> Ash.Query.for_read(:active, %{}, actor: actor)
+    |> Ash.Query.filter(fragment("(? ->> ?) = ?", metadata, ^@incident_field, ^incident_id))
+    |> Ash.Query.filter(fragment("(? ->> ?) = 'incident'", metadata, ^@role_field))
+    |> Ash.read()
+    |> case do
+      {:ok, incidents} ->
+        Enum.each(incidents, fn incident ->
+          count = Map.get(incident.metadata || %{}, @count_field, 1) + 1
+          update_metadata(incident, Map.put(incident.metadata || %{}, @count_field, count), actor)
+        end)
+
+      {:error, reason} ->
+        Logger.warning("Failed to load incident #{incident_id}: #{inspect(reason)}")
+    end
+  end
+
+  # Active, non-incident alerts on the devices related to `device_uid` in
+  # `direction`: raised within the window, or rooting an open incident.
+  defp related_alerts(device_uid, direction, ctx) do
+    with {:ok, %{devices: devices}} <-
+           DeviceRelationships.traverse(device_uid,
+             direction: direction,
+             depth: ctx.rule.max_depth,
+             types: ctx.rule.relationship_types
+           ),
+         [_ | _] = related <- List.delete(devices, device_uid),
+         {:ok, alerts} <- active_alerts(related, ctx.actor) do
+      cutoff = DateTime.add(ctx.now, -ctx.window, :millisecond)
+
+      Enum.filter(alerts, fn alert ->
+        not incident_alert?(alert) and
+          (DateTime.compare(alert.triggered_at, cutoff) != :lt or
+             (direction == "outgoing" and role(alert) == "root_cause"))
+      end)
+    else
+      {:error, reason} ->
+        Logger.warning("Alert correlation failed for #{device_uid}: #{inspect(reason)}")
+        []
+
+      _ ->
+        []
+    end
+  end
+
+  defp active_alerts(device_ids, actor) do
+    Alert
+    |> Ash.Query.for_read(:active, %{}, actor: actor)
+    |> Ash.Query.filter(device_uid in ^device_ids)
+    |> Ash.read()
+  end
+
+  defp tag_alert(alert, incident_id, root_device, role, actor) do
+    metadata = tag_metadata(alert.metadata, incident_id, root_device, role)
+    update_metadata(alert, metadata, actor)
+  end
+
+  defp update_metadata(alert, metadata, actor) do
+    alert
+    |> Ash.Changeset.for_update(:update_metadata, %{metadata: metadata}, actor: actor)
+    |> Ash.update()
+    |> case do
+      {:ok, _} -> :ok
+      {:error, reason} -> Logger.warning("Failed to tag alert #{alert.id}: #{inspect(reason)}")
+    end
+  end
+
+  defp tag_attrs(attrs, incident_id, root_device, role) do
+    Map.put(
+      attrs,
+      :metadata,
+      tag_metadata(Map.get(attrs, :metadata), incident_id, root_device, role)
+    )
+  end
+
+  defp tag_metadata(metadata, incident_id, root_device, role) do
+    (metadata || %{})
+    |> Map.put(@incident_field, incident_id)
+    |> Map.put(@root_field, root_device)
+    |> Map.put(@role_field, role)
+  end
+
+  defp max_severity(alerts) do
+    alerts
+    |> Enum.map(&Map.get(&1, :severity))
+    |> Enum.max_by(fn severity -> Enum.find_index(@severity_order, &(&1 == severity)) || -1 end)
+  end
+
+  defp incident_id(%{metadata: metadata}), do: Map.get(metadata || %{}, @incident_field)
+
+  defp role(%{metadata: metadata}), do: Map.get(metadata || %{}, @role_field)
+
+  defp incident_alert?(alert_or_attrs) do
+    alert_or_attrs
+    |> Map.get(:metadata)
+    |> Kernel.||(%{})
+    |> Map.get(@role_field) == "incident"
+  end
+
+  defp matches?([], _value), do: true
+  defp matches?(values, value), do: value in values
+
+  defp validate_rule(rule, index) do
+    name = to_string(field(rule, :name) || "rule-#{index}")
+    depth = field(rule, :max_depth) || @default_depth
+    types = List.wrap(field(rule, :relationship_types))
+
+    with :ok <- validate_depth(name, depth),
+         {:ok, types} <- validate_types(name, types) do
+      {:ok,
+       %{
+         name: name,
+         source_types: rule |> field(:source_types) |> List.wrap() |> Enum.map(&to_atom/1),
+         severities: rule |> field(:severities) |> List.wrap() |> Enum.map(&to_atom/1),
+         relationship_types: types,
+         max_depth: depth
+       }}
+    end
+  end
+
+  defp validate_depth(name, depth) do
+    if is_integer(depth) and depth in 1..DeviceRelationships.max_depth() do
+      :ok
+    else
+      {:error, "rule #{name}: max_depth must be 1-#{DeviceRelationships.max_depth()}"}
+    end
+  end
+
+  defp validate_types(name, types) do
+    Enum.reduce_while(types, {:ok, []}, fn type, {:ok, acc} ->
+      case DeviceRelationships.parse_type(type) do
+        {:ok, type} -> {:cont, {:ok, acc ++ [type]}}
+        {:error, reason} -> {:halt, {:error, "rule #{name}: #{reason}"}}
+      end
+    end)
+  end
+
+  defp to_atom(value) when is_atom(value), do: value
+
+  defp to_atom(value) when is_binary(value) do
+    String.to_existing_atom(value)
+  rescue
+    ArgumentError -> value
+  end
+
+  defp field(rule, key), do: Map.get(rule, key) || Map.get(rule, Atom.to_string(key))
+
+  defp configured_rules do
+    case rules() do
+      {:ok, rules} ->
+        rules
+
+      {:error, reason} ->
+        Logger.warning("Ignoring invalid alert correlation rules: #{reason}")
+        []
+    end
+  end
+
+  defp config(key, default) do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
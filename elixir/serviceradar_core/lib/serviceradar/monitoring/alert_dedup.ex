@@ -0,0 +1,278 @@
+defmodule ServiceRadar.Monitoring.AlertDedup do
+  @moduledoc """
+  Collapses alerts that share a dedup key into one active alert.
+
+  `ServiceRadar.Monitoring.AlertGenerator` asks `check/2` before creating an
+  alert. The key is rendered from a template of `{{field}}` placeholders over
+  the alert attributes (`source_type`, `source_id`, `device_uid`,
+  `metric_name`, ...) or, for other names, its metadata. When an active alert
+  with the same key was last seen within the window, the new alert is not
+  created or notified; the active alert's `dedup_count` and
+  `dedup_last_seen` metadata are bumped instead. A recovery alert (such as
+  `service_recovered/1`) resolves the active alerts with its key, so the next
+  failure notifies again.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Monitoring.AlertDedup,
+        enabled: true,
+        key_template: "{{device_uid}}|{{check_type}}",
+        window: to_timeout(minute: 5)
+  """
+
+  alias ServiceRadar.Monitoring.Alert
+
+  require Ash.Query
+  require Logger
+
+  @default_key_template "{{source_type}}|{{source_id}}|{{metric_name}}"
+  @default_window to_timeout(minute: 5)
+
+  @key_field "dedup_key"
+  @count_field "dedup_count"
+  @last_seen_field "dedup_last_seen"
+
+  @placeholder ~r/\{\{\s*([A-Za-z0-9_]+)\s*\}\}/
+
+  @attribute_fields ~w(title severity source_type source_id event_id service_check_id
+                       device_uid agent_uid metric_name comparison)
+
+  @doc "Whether deduplication is enabled."
+  @spec enabled?() :: boolean()
+  def enabled?, do: config(:enabled, false) == true
+
+  @doc "The configured key template, validated."
+  @spec key_template() :: {:ok, String.t()} | {:error, String.t()}
+  def key_template, do: validate_template(config(:key_template, @default_key_template))
+
+  @doc "The configured dedup window in milliseconds."
+  @spec window() :: non_neg_integer()
+  def window do
+    case config(:window, @default_window) do
+      value when is_integer(value) and value > 0 -> value
+      _ -> @default_window
+    end
+  end
+
+  @doc """
+  Checks a template has at least one placeholder and no unbalanced braces.
+  """
+  @spec validate_template(term()) :: {:ok, String.t()} | {:error, String.t()}
+  def validate_template(template) when is_binary(template) do
+    stripped = Regex.replace(@placeholder, template, "")
+
+    cond do
+      not Regex.match?(@placeholder, template) ->
+        {:error, "dedup key template has no {{field}} placeholder"}
+
+      String.contains?(stripped, ["{{", "}}"]) ->
+        {:error, "dedup key template has an invalid placeholder"}
+
+      true ->
+        {:ok, template}
+    end
+  end
+
+  def validate_template(_template), do: {:error, "dedup key template must be a string"}
+
+  @doc """
+  Renders the dedup key of alert attributes. Placeholders naming an alert
+  attribute read it; others read the metadata. Missing values render empty.
+  """
+  @spec key(map(), String.t()) :: String.t()
+  def key(attrs, template) do
+    Regex.replace(@placeholder, template, fn _, field -> field_value(attrs, field) end)
+  end
+
+  @doc """
+  Whether an active alert's metadata was last seen within `window`
+  milliseconds of `now`.
+  """
+  @spec within_window?(map(), DateTime.t(), non_neg_integer()) :: boolean()
+  def within_window?(metadata, now, window) do
+    with value when is_binary(value) <- Map.get(metadata || %{}, @last_seen_field),
+         {:ok, last_seen, _} <- DateTime.from_iso8601(value) do
+      DateTime.diff(now, last_seen, :millisecond) <= window
+    else
+      _ -> false
+    end
+  end
+
+  @doc """
+  Decides whether alert attributes become a new alert.
+
+  Returns `{:ok, attrs}` with the dedup key and a count of one added to the
+  metadata when the alert should be created and notified, or
+  `{:duplicate, alert}` with the active alert it was folded into. With
+  `recovery: true` the active alerts with the key are resolved and the
+  recovery alert, which is not keyed itself, records in `dedup_count` how
+  many alerts they stood for.
+
+  Options: `:actor`, `:recovery`, `:now`, and `:template`/`:window` to
+  override the configuration.
+  """
+  @spec check(map(), keyword()) :: {:ok, map()} | {:duplicate, Alert.t()}
+  def check(attrs, opts \\ []) do
+    if Keyword.has_key?(opts, :template) or enabled?() do
+      do_check(attrs, opts)
+    else
+      {:ok, attrs}
+    end
+  end
+
+  defp do_check(attrs, opts) do
+    case Keyword.get_lazy(opts, :template, &configured_template/0) do
+      nil ->
+        {:ok, attrs}
+
+      template ->
+        key = key(attrs, template)
+        now = Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+        actor = Keyword.get(opts, :actor)
+
+        if Keyword.get(opts, :recovery, false) do
+          {:ok, put_recovered(attrs, resolve_active(key, actor))}
+        else
+          window = Keyword.get_lazy(opts, :window, &window/0)
+          fold_into_active(attrs, key, now, window, actor)
+        end
+    end
+  end
+
+  defp fold_into_active(attrs, key, now, window, actor) do
+    case latest_active(key, actor) do
+      %Alert{} = alert ->
+        if within_window?(alert.metadata, now, window) do
+          record_repeat(alert, attrs, key, now, actor)
+        else
+          {:ok, put_dedup(attrs, key, now)}
+        end
+
+      nil ->
+        {:ok, put_dedup(attrs, key, now)}
+    end
+  end
+
+  defp record_repeat(alert, attrs, key, now, actor) do
+    metadata =
+      (alert.metadata || %{})
+      |> Map.put(@count_field, dedup_count(alert.metadata) + 1)
+      |> Map.put(@last_seen_field, DateTime.to_iso8601(now))
+
+    alert
+    |> Ash.Changeset.for_update(:update_metadata, %{metadata: metadata}, actor: actor)
+    |> Ash.update()
+    |> case do
+      {:ok, updated} ->
+        {:duplicate, updated}
+
+      {:error, reason} ->
+        Logger.warning("Failed to record duplicate alert #{key}: #{inspect(reason)}")
+        {:ok, put_dedup(attrs, key, now)}
+    end
+  end
+
+  defp latest_active(key, actor) do
+    Alert
+    |> Ash.Query.for_read(:active, %{}, actor: actor)
+    |> Ash.Query.filter(fragment("(? ->> ?) = ?", metadata, ^@key_field, ^key))
+    |> Ash.Query.sort(triggered_at: :desc)
+    |> Ash.Query.limit(1)
+    |> Ash.read_one()
+    |> case do
+      {:ok, alert} ->
+        alert
+
+      {:error, reason} ->
+        Logger.warning("Failed to look up active alert #{key}: #{inspect(reason)}")
+        nil
+    end
+  end
+
+  defp resolve_active(key, actor) do
+    Alert
+    |> Ash.Query.for_read(:active, %{}, actor: actor)
+    |> Ash.Query.filter(fragment("(? ->> ?) = ?", metadata, ^@key_field, ^key))
+    |> Ash.read()
+    |> case do
+      {:ok, alerts} ->
+        Enum.reduce(alerts, 0, fn alert, count ->
+          resolve(alert, actor)
+          count + dedup_count(alert.metadata)
+        end)
+
+      {:error, reason} ->
+        Logger.warning("Failed to look up active alerts #{key}: #{inspect(reason)}")
+        0
+    end
+  end
+
+  defp resolve(alert, actor) do
+    alert
+    |> Ash.Changeset.for_update(
+      :resolve,
+      %{resolved_by: "system", resolution_note: "Recovered"},
+      actor: actor
+    )
+    |> Ash.update()
+    |> case do
+      {:ok, _} -> :ok
+      {:error, reason} -> Logger.warning("Failed to resolve alert: #{inspect(reason)}")
+    end
+  end
+
+  defp put_dedup(attrs, key, now) do
+    metadata =
+      (Map.get(attrs, :metadata) || %{})
+      |> Map.put(@key_field, key)
+      |> Map.put(@count_field, 1)
+      |> Map.put(@last_seen_field, DateTime.to_iso8601(now))
+
+    Map.put(attrs, :metadata, metadata)
+  end
+
+  # The recovery alert is not keyed, so the next failure is not folded into it.
+  defp put_recovered(attrs, 0), do: attrs
+
+  defp put_recovered(attrs, count) do
+    Map.update(attrs, :metadata, %{@count_field => count}, fn metadata ->
+      Map.put(metadata || %{}, @count_field, count)
+    end)
+  end
+
+  defp dedup_count(metadata) do
+    case Map.get(metadata || %{}, @count_field) do
+      count when is_integer(count) and count > 0 -> count
+      _ -> 1
+    end
+  end
+
+  defp field_value(attrs, field) when field in @attribute_fields do
+    attrs |> Map.get(String.to_existing_atom(field)) |> to_key_part()
+  end
+
+  defp field_value(attrs, field) do
+    attrs |> Map.get(:metadata, %{}) |> Kernel.||(%{}) |> Map.get(field) |> to_key_part()
+  end
+
+  defp to_key_part(nil), do: ""
+  defp to_key_part(value) when is_binary(value), do: value
+  defp to_key_part(value), do: to_string(value)
+
+  defp configured_template do
+    case key_template() do
+      {:ok, template} ->
+        template
+
+      {:error, reason} ->
+        Logger.warning("Alert dedup disabled: #{reason}")
+        nil
+    end
+  end
+
+  defp config(key, default) do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
@@ -7,6 +7,8 @@ defmodule ServiceRadar.Monitoring.AlertGenerator do
   - Device availability changes
   - Gateway/agent health issues
   - Metric threshold violations
+  - Device risk level increases
+  - Incidents of correlated device alerts
   - Stats anomalies
 
   ## Usage
@@ -30,6 +32,9 @@ defmodule ServiceRadar.Monitoring.AlertGenerator do
 
   alias ServiceRadar.Actors.SystemActor
   alias ServiceRadar.Monitoring.Alert
+  alias ServiceRadar.Monitoring.AlertCorrelation
+  alias ServiceRadar.Monitoring.AlertDedup
+  alias ServiceRadar.Monitoring.MaintenanceWindows
   alias ServiceRadar.Monitoring.WebhookNotifier
 
   require Logger
@@ -108,7 +113,7 @@ defmodule ServiceRadar.Monitoring.AlertGenerator do
       metadata: build_metadata(opts)
     }
 
-    create_alert_and_notify(attrs, opts)
+    create_alert_and_notify(attrs, Keyword.put(opts, :recovery, true))
   end
 
   @doc """
@@ -184,7 +189,7 @@ defmodule ServiceRadar.Monitoring.AlertGenerator do
       metadata: build_metadata(opts)
     }
 
-    create_alert_and_notify(attrs, opts)
+    create_alert_and_notify(attrs, Keyword.put(opts, :recovery, true))
   end
 
   @doc """
@@ -217,6 +222,8 @@ defmodule ServiceRadar.Monitoring.AlertGenerator do
   - `:threshold_value` - Threshold that was violated (required)
   - `:comparison` - How value compared (:greater_than, :less_than, etc.)
   - `:device_uid` - Device the metric belongs to
+  - `:title` - Alert title (default "Threshold Violation: <metric_name>")
+  - `:source_id` - Source ID (default the device UID)
   """
   @spec threshold_violation(keyword()) :: {:ok, Alert.t()} | {:error, term()}
   def threshold_violation(opts) do
@@ -234,11 +241,11 @@ defmodule ServiceRadar.Monitoring.AlertGenerator do
       end
 
     attrs = %{
-      title: "Threshold Violation: #{metric_name}",
+      title: Keyword.get(opts, :title, "Threshold Violation: #{metric_name}"),
       description: "#{metric_name} #{comparison_text} threshold: #{metric_value} vs #{threshold}",
       severity: Keyword.get(opts, :severity, :warning),
       source_type: :device,
-      source_id: Keyword.get(opts, :device_uid),
+      source_id: Keyword.get(opts, :source_id, Keyword.get(opts, :device_uid)),
       device_uid: Keyword.get(opts, :device_uid),
       metric_name: metric_name,
       metric_value: metric_value,
@@ -250,6 +257,179 @@ defmodule ServiceRadar.Monitoring.AlertGenerator do
     create_alert_and_notify(attrs, opts)
   end
 
+  @doc """
+  Generate alert for a device whose reported risk level rose past a risk
+  alert rule (see `ServiceRadar.Monitoring.RiskAlerts`).
+
+  ## Options
+
+  - `:device_uid` - Device UID (required)
+  - `:current_risk` - Risk level now (required)
+  - `:previous_risk` - Risk level before the update, nil if unknown
+  - `:threshold` - Threshold of the rule, 0 for delta-only rules
+  - `:source_id` - Source ID (default the device UID)
+  - `:severity` - Alert severity (default `:warning`)
+  - `:ip`, `:partition` - Device identity
+  - `:details` - Additional details
+  """
+  @spec device_risk_increased(keyword()) :: {:ok, Alert.t()} | {:error, term()}
+  def device_risk_increased(opts) do
+    device_uid = Keyword.fetch!(opts, :device_uid)
+    current = Keyword.fetch!(opts, :current_risk)
+    previous = Keyword.get(opts, :previous_risk)
+    threshold = Keyword.get(opts, :threshold, 0)
+
+    description =
+      if is_nil(previous) do
+        "Device #{device_uid} was first seen with risk level #{current}"
+      else
+        "Device #{device_uid} risk level rose from #{previous} to #{current}"
+      end
+
+    attrs = %{
+      title: "Device Risk Level Increased",
+      description: description,
+      severity: Keyword.get(opts, :severity, :warning),
+      source_type: :device,
+      source_id: Keyword.get(opts, :source_id, device_uid),
+      device_uid: device_uid,
+      metric_name: "risk_level",
+      metric_value: current,
+      threshold_value: if(threshold > 0, do: threshold),
+      comparison: :greater_than,
+      metadata: build_metadata(opts)
+    }
+
+    create_alert_and_notify(attrs, opts)
+  end
+
+  @doc """
+  Generate the alert announcing an incident: alerts on devices depending on
+  a failed device, grouped by `ServiceRadar.Monitoring.AlertCorrelation`.
+
+  ## Options
+
+  - `:incident_id` - Incident ID (required)
+  - `:root_device_uid` - Device that is the probable root cause (required)
+  - `:root_title` - Title of the root-cause alert
+  - `:rule` - Name of the correlation rule
+  - `:alert_count` - Alerts in the incident so far
+  - `:severity` - Alert severity (default `:critical`)
+  - `:partition` - Partition of the alerts
+  """
+  @spec incident_opened(keyword()) :: {:ok, Alert.t()} | {:error, term()}
+  def incident_opened(opts) do
+    incident_id = Keyword.fetch!(opts, :incident_id)
+    root = Keyword.fetch!(opts, :root_device_uid)
+    root_title = Keyword.get(opts, :root_title) || "Device Alert"
+
+    metadata =
+      opts
+      |> build_metadata()
+      |> Map.merge(%{
+        "incident_id" => incident_id,
+        "incident_role" => "incident",
+        "root_cause_device_id" => root,
+        "alert_count" => Keyword.get(opts, :alert_count, 1)
+      })
+      |> maybe_put("rule", Keyword.get(opts, :rule))
+
+    attrs = %{
+      title: "Incident: #{root_title} on #{root}",
+      description:
+        "Alerts on devices depending on #{root} were grouped into one incident; " <>
+          "#{root} is the probable root cause",
+      severity: Keyword.get(opts, :severity, :critical),
+      source_type: :system,
+      source_id: incident_id,
+      device_uid: root,
+      metadata: metadata
+    }
+
+    create_alert_and_notify(attrs, opts)
+  end
+
+  @doc """
+  Generate alert for a TLS certificate nearing or past expiry.
+
+  ## Options
+
+  - `:source_id` - Source ID identifying the certificate (required)
+  - `:name` - Endpoint or package the certificate belongs to (required)
+  - `:not_after` - When the certificate expires (required)
+  - `:days_remaining` - Whole days until expiry, negative once expired (required)
+  - `:severity` - Alert severity (default `:warning`)
+  - `:details` - Additional details
+  """
+  @spec certificate_expiring(keyword()) :: {:ok, Alert.t()} | {:error, term()}
+  def certificate_expiring(opts) do
+    name = Keyword.fetch!(opts, :name)
+    not_after = Keyword.fetch!(opts, :not_after)
+    days = Keyword.fetch!(opts, :days_remaining)
+
+    {title, description} =
+      if days < 0 do
+        {"Certificate Expired: #{name}",
+         "The certificate for #{name} expired at #{DateTime.to_iso8601(not_after)}"}
+      else
+        {"Certificate Expiring: #{name}",
+         "The certificate for #{name} expires in #{days} day(s), " <>
+           "at #{DateTime.to_iso8601(not_after)}"}
+      end
+
+    attrs = %{
+      title: title,
+      description: description,
+      severity: Keyword.get(opts, :severity, :warning),
+      source_type: :system,
+      source_id: Keyword.fetch!(opts, :source_id),
+      metadata: build_metadata(opts)
+    }
+
+    create_alert_and_notify(attrs, opts)
+  end
+
+  @doc """
+  Generate alert for a sudden change in the device count of a discovery
+  source or partition, which usually means an integration is broken.
+
+  ## Options
+
+  - `:source_id` - Source ID identifying the source or partition (required)
+  - `:dimension` - `:source` or `:partition` (required)
+  - `:key` - Source name or partition slug (required)
+  - `:previous_count` - Device count at the previous check (required)
+  - `:current_count` - Device count now (required)
+  - `:change_percent` - Signed change, in percent (required)
+  - `:severity` - Alert severity (default `:warning`)
+  - `:details` - Additional details
+  """
+  @spec inventory_count_changed(keyword()) :: {:ok, Alert.t()} | {:error, term()}
+  def inventory_count_changed(opts) do
+    dimension = Keyword.fetch!(opts, :dimension)
+    key = Keyword.fetch!(opts, :key)
+    previous = Keyword.fetch!(opts, :previous_count)
+    current = Keyword.fetch!(opts, :current_count)
+    change = Keyword.fetch!(opts, :change_percent)
+
+    verb = if current < previous, do: "Dropped", else: "Jumped"
+
+    attrs = %{
+      title: "Device Count #{verb}: #{dimension} #{key}",
+      description:
+        "Devices in #{dimension} #{key} went from #{previous} to #{current} " <>
+          "(#{format_percent(change)}) since the last check",
+      severity: Keyword.get(opts, :severity, :warning),
+      source_type: :system,
+      source_id: Keyword.fetch!(opts, :source_id),
+      metric_name: "device_count",
+      metric_value: current,
+      metadata: build_metadata(opts)
+    }
+
+    create_alert_and_notify(attrs, opts)
+  end
+
   @doc """
   Generate an alert from an OCSF event.
 
@@ -414,13 +594,42 @@ defmodule ServiceRadar.Monitoring.AlertGenerator do
     # DB connection's search_path determines the schema
     actor = Keyword.get(opts, :actor) || SystemActor.system(:alert_generator)
 
-    # Create the alert in the database
+    recovery? = Keyword.get(opts, :recovery, false)
+
+    case AlertDedup.check(attrs, actor: actor, recovery: recovery?) do
+      {:duplicate, alert} ->
+        {:ok, alert}
+
+      {:ok, attrs} ->
+        case MaintenanceWindows.suppress(attrs) do
+          # Alerts on devices under maintenance are recorded without paging.
+          {:suppressed, attrs} ->
+            create_alert(attrs, actor, Keyword.put(opts, :notify, false))
+
+          {:ok, attrs} when recovery? ->
+            create_alert(attrs, actor, opts)
+
+          {:ok, attrs} ->
+            attrs
+            |> AlertCorrelation.correlate(actor: actor)
+            |> create_correlated(actor, opts)
+        end
+    end
+  end
+
+  # Members of an incident are stored but only the incident is notified.
+  defp create_correlated({:notify, attrs}, actor, opts), do: create_alert(attrs, actor, opts)
+
+  defp create_correlated({:incident, attrs}, actor, opts),
+    do: create_alert(attrs, actor, Keyword.put(opts, :notify, false))
+
+  defp create_alert(attrs, actor, opts) do
     case Alert
          |> Ash.Changeset.for_create(:trigger, attrs, actor: actor)
          |> Ash.create() do
       {:ok, alert} ->
         # Also send webhook notification
-        send_webhook_notification(alert, opts)
+        if Keyword.get(opts, :notify, true), do: send_webhook_notification(alert, opts)
         {:ok, alert}
 
       {:error, error} ->
@@ -472,6 +681,9 @@ defmodule ServiceRadar.Monitoring.AlertGenerator do
     Map.merge(base_metadata, details)
   end
 
+  defp format_percent(change) when change > 0, do: "+#{Float.round(change * 1.0, 1)}%"
+  defp format_percent(change), do: "#{Float.round(change * 1.0, 1)}%"
+
   defp maybe_put(map, _key, nil), do: map
   defp maybe_put(map, key, value), do: Map.put(map, key, to_string(value))
 
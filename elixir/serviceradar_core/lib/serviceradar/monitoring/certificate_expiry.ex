@@ -0,0 +1,467 @@
+defmodule ServiceRadar.Monitoring.CertificateExpiry do
+  @moduledoc """
+  Tracks TLS certificate expiry and alerts before certificates lapse.
+
+  Each run records the leaf certificate of every configured endpoint and the
+  certificates ServiceRadar issued to edge onboarding and collector packages
+  as `ServiceRadar.Monitoring.MonitoredCertificate`s, then evaluates them. A
+  certificate is:
+
+    * `:ok` - more than `warning_days` from expiry
+    * `:warning` - within `warning_days`
+    * `:critical` - within `critical_days`
+    * `:expired` - past `not_after`
+
+  A certificate that is not `:ok` raises an alert (through
+  `ServiceRadar.Monitoring.AlertGenerator`, so webhooks are notified) unless
+  one is already open; the alert is resolved once the certificate is renewed.
+  Open alerts are found by their source ID,
+  `certificate_expiry:<source>:<target>`, so evaluation needs no state of its
+  own between runs.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Monitoring.CertificateExpiry,
+        endpoints: ["portal.example.com", "vault.internal:8200"],
+        warning_days: 30,
+        critical_days: 7,
+        timeout_ms: 5_000
+
+  or at runtime with `SERVICERADAR_CERT_EXPIRY_ENDPOINTS` (comma-separated),
+  `SERVICERADAR_CERT_EXPIRY_WARNING_DAYS` and
+  `SERVICERADAR_CERT_EXPIRY_CRITICAL_DAYS`. Endpoints are `host`, `host:port`
+  or an `https://` URL; the port defaults to 443. Endpoint certificates are
+  read without verification, so expired and self-signed certificates are
+  still recorded.
+
+  Synthetic transaction checks also report `cert_not_after` for HTTP steps
+  answered over TLS.
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Edge.CollectorPackage
+  alias ServiceRadar.Edge.Crypto
+  alias ServiceRadar.Edge.OnboardingPackage
+  alias ServiceRadar.Monitoring.Alert
+  alias ServiceRadar.Monitoring.AlertGenerator
+  alias ServiceRadar.Monitoring.MonitoredCertificate
+  alias ServiceRadar.SPIFFE
+
+  require Ash.Query
+  require Logger
+
+  @default_warning_days 30
+  @default_critical_days 7
+  @default_timeout_ms 5_000
+  @source_prefix "certificate_expiry:"
+  @common_name_oid {2, 5, 4, 3}
+  @issued_package_states [:issued, :delivered, :activated]
+
+  @type status :: :ok | :warning | :critical | :expired
+  @type summary :: %{
+          recorded: non_neg_integer(),
+          fired: non_neg_integer(),
+          resolved: non_neg_integer()
+        }
+
+  @doc "The configured thresholds, in days: `%{warning: days, critical: days}`."
+  @spec thresholds() :: %{warning: non_neg_integer(), critical: non_neg_integer()}
+  def thresholds do
+    %{
+      warning: config(:warning_days, @default_warning_days),
+      critical: config(:critical_days, @default_critical_days)
+    }
+  end
+
+  @doc """
+  Records every monitored certificate, then evaluates them.
+
+  Options are passed to `collect/1` and `evaluate/1`.
+  """
+  @spec run(keyword()) :: {:ok, summary()} | {:error, term()}
+  def run(opts \\ []) do
+    opts = Keyword.put_new_lazy(opts, :actor, fn -> SystemActor.system(:certificate_expiry) end)
+
+    with {:ok, recorded} <- collect(opts),
+         {:ok, result} <- evaluate(opts) do
+      {:ok, Map.put(result, :recorded, recorded)}
+    end
+  end
+
+  @doc """
+  Records the certificates of the configured endpoints and issued packages,
+  and forgets those of endpoints no longer configured and packages no longer
+  in use. An endpoint that cannot be reached is logged and keeps its last
+  recorded certificate.
+
+  Options:
+    - `:endpoints` - endpoints to probe (default: configured)
+    - `:actor` - actor certificates are read and written as (default system actor)
+  """
+  @spec collect(keyword()) :: {:ok, non_neg_integer()} | {:error, term()}
+  def collect(opts \\ []) do
+    actor = Keyword.get_lazy(opts, :actor, fn -> SystemActor.system(:certificate_expiry) end)
+    endpoints = opts |> Keyword.get_lazy(:endpoints, fn -> config(:endpoints, []) end)
+    endpoints = endpoints |> Enum.map(&normalize_endpoint/1) |> Enum.reject(&is_nil/1)
+
+    endpoint_results =
+      Enum.map(endpoints, fn {target, _host, _port} = endpoint ->
+        {target, record_endpoint(endpoint, actor: actor)}
+      end)
+
+    with {:ok, packages} <- issued_package_certificates(actor) do
+      package_results =
+        Enum.map(packages, fn {source, target, name, pem} ->
+          {target, record_pem(source, target, pem, name: name, actor: actor)}
+        end)
+
+      Enum.each(endpoint_results ++ package_results, fn
+        {_target, {:ok, _certificate}} ->
+          :ok
+
+        {target, {:error, reason}} ->
+          Logger.warning("Certificate expiry check failed for #{target}", reason: inspect(reason))
+      end)
+
+      keep = %{
+        endpoint: MapSet.new(endpoints, &elem(&1, 0)),
+        edge_package: package_targets(packages, :edge_package),
+        collector_package: package_targets(packages, :collector_package)
+      }
+
+      forget_stale(keep, actor)
+
+      {:ok, Enum.count(endpoint_results ++ package_results, &match?({_, {:ok, _}}, &1))}
+    end
+  end
+
+  @doc """
+  Reads the leaf certificate of `endpoint` and records it.
+
+  Options:
+    - `:timeout_ms` - connect timeout (default: configured, 5000)
+    - `:actor` - actor the certificate is written as
+  """
+  @spec record_endpoint(String.t() | {String.t(), String.t(), pos_integer()}, keyword()) ::
+          {:ok, MonitoredCertificate.t()} | {:error, term()}
+  def record_endpoint(endpoint, opts \\ [])
+
+  def record_endpoint({target, host, port}, opts) do
+    timeout =
+      Keyword.get_lazy(opts, :timeout_ms, fn -> config(:timeout_ms, @default_timeout_ms) end)
+
+    with {:ok, der} <- peer_certificate(host, port, timeout) do
+      record_der(:endpoint, target, der, Keyword.put_new(opts, :name, target))
+    end
+  end
+
+  def record_endpoint(endpoint, opts) do
+    case normalize_endpoint(endpoint) do
+      nil -> {:error, :invalid_endpoint}
+      normalized -> record_endpoint(normalized, opts)
+    end
+  end
+
+  @doc "Records the first certificate in `pem` for `source` and `target`."
+  @spec record_pem(atom(), String.t(), String.t(), keyword()) ::
+          {:ok, MonitoredCertificate.t()} | {:error, term()}
+  def record_pem(source, target, pem, opts \\ []) do
+    pem
+    |> :public_key.pem_decode()
+    |> Enum.find_value(fn
+      {:Certificate, der, _} -> der
+      _ -> nil
+    end)
+    |> case do
+      nil -> {:error, :no_certificate}
+      der -> record_der(source, target, der, opts)
+    end
+  rescue
+    error -> {:error, error}
+  end
+
+  @doc """
+  Records a DER-encoded certificate for `source` and `target`.
+
+  Options:
+    - `:name` - display name (default `target`)
+    - `:now` - check time (default now)
+    - `:actor` - actor the certificate is written as (default system actor)
+  """
+  @spec record_der(atom(), String.t(), binary(), keyword()) ::
+          {:ok, MonitoredCertificate.t()} | {:error, term()}
+  def record_der(source, target, der, opts \\ []) do
+    actor = Keyword.get_lazy(opts, :actor, fn -> SystemActor.system(:certificate_expiry) end)
+
+    with {:ok, details} <- describe(der) do
+      details
+      |> Map.merge(%{
+        source: source,
+        target: target,
+        name: Keyword.get(opts, :name, target),
+        last_checked_at: Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+      })
+      |> MonitoredCertificate.record(actor: actor)
+    end
+  end
+
+  @doc """
+  Reads the subject and issuer common names, serial number and validity of a
+  DER-encoded certificate.
+  """
+  @spec describe(binary()) :: {:ok, map()} | {:error, term()}
+  def describe(der) when is_binary(der) do
+    with {:ok, validity} <- SPIFFE.certificate_validity(der),
+         {:OTPCertificate, tbs, _sig_alg, _sig} <- :public_key.pkix_decode_cert(der, :otp) do
+      {:ok,
+       %{
+         subject: common_name(elem(tbs, 6)),
+         issuer: common_name(elem(tbs, 4)),
+         serial_number: serial_number(elem(tbs, 2)),
+         not_before: validity.not_before,
+         not_after: validity.not_after
+       }}
+    else
+      {:error, reason} -> {:error, reason}
+      other -> {:error, {:invalid_certificate, other}}
+    end
+  rescue
+    error -> {:error, {:invalid_certificate, error}}
+  end
+
+  @doc """
+  Raises alerts for certificates nearing or past expiry and resolves the
+  alerts of renewed ones.
+
+  Options:
+    - `:now` - evaluation time (default now)
+    - `:thresholds` - `%{warning: days, critical: days}` (default `thresholds/0`)
+    - `:actor` - actor alerts are read and written as (default system actor)
+  """
+  @spec evaluate(keyword()) ::
+          {:ok, %{fired: non_neg_integer(), resolved: non_neg_integer()}} | {:error, term()}
+  def evaluate(opts \\ []) do
+    now = Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+    thresholds = Keyword.get_lazy(opts, :thresholds, &thresholds/0)
+    actor = Keyword.get_lazy(opts, :actor, fn -> SystemActor.system(:certificate_expiry) end)
+
+    with {:ok, certificates} <- MonitoredCertificate.list(actor: actor) |> Page.unwrap(),
+         {:ok, open} <- open_alerts(actor) do
+      statuses =
+        Map.new(certificates, fn certificate ->
+          {alert_key(certificate), {certificate, status(certificate.not_after, now, thresholds)}}
+        end)
+
+      fired =
+        Enum.count(statuses, fn {key, {certificate, status}} ->
+          status != :ok and not Map.has_key?(open, key) and
+            fire(certificate, status, now, actor) == :ok
+        end)
+
+      resolved =
+        Enum.count(open, fn {key, alert} ->
+          match?({_certificate, :ok}, Map.get(statuses, key)) and
+            resolve(alert, elem(statuses[key], 0), actor) == :ok
+        end)
+
+      {:ok, %{fired: fired, resolved: resolved}}
+    end
+  end
+
+  @doc "Classifies a certificate expiring at `not_after` as of `now`."
+  @spec status(DateTime.t(), DateTime.t(), %{warning: number(), critical: number()}) :: status()
+  def status(not_after, now, %{warning: warning, critical: critical}) do
+    seconds = DateTime.diff(not_after, now)
+
+    cond do
+      seconds <= 0 -> :expired
+      seconds <= critical * 86_400 -> :critical
+      seconds <= warning * 86_400 -> :warning
+      true -> :ok
+    end
+  end
+
+  @doc "Whole days from `now` until `not_after`; negative once expired."
+  @spec days_remaining(DateTime.t(), DateTime.t()) :: integer()
+  def days_remaining(not_after, now) do
+    seconds = DateTime.diff(not_after, now)
+    if seconds < 0, do: -div(-seconds + 86_399, 86_400), else: div(seconds, 86_400)
+  end
+
+  @doc """
+  Parses an endpoint into `{target, host, port}`, where target is
+  `host:port`. Returns nil for unparseable endpoints.
+  """
+  @spec normalize_endpoint(String.t()) :: {String.t(), String.t(), pos_integer()} | nil
+  def normalize_endpoint(endpoint) when is_binary(endpoint) do
+    endpoint = String.trim(endpoint)
+    uri = if String.contains?(endpoint, "://"), do: endpoint, else: "//" <> endpoint
+
+    case URI.parse(uri) do
+      %URI{host: host, port: port} when is_binary(host) and host != "" ->
+        port = port || 443
+        host_part = if String.contains?(host, ":"), do: "[#{host}]", else: host
+        {"#{host_part}:#{port}", host, port}
+
+      _ ->
+        nil
+    end
+  end
+
+  def normalize_endpoint(_endpoint), do: nil
+
+  defp peer_certificate(host, port, timeout) do
+    ssl_opts = [verify: :verify_none, active: false] ++ server_name_opts(host)
+
+    case :ssl.connect(String.to_charlist(host), port, ssl_opts, timeout) do
+      {:ok, socket} ->
+        result = :ssl.peercert(socket)
+        _ = :ssl.close(socket)
+        result
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  defp server_name_opts(host) do
+    case :inet.parse_address(String.to_charlist(host)) do
+      {:ok, _address} -> [server_name_indication: :disable]
+      {:error, _} -> [server_name_indication: String.to_charlist(host)]
+    end
+  end
+
+  defp issued_package_certificates(actor) do
+    with {:ok, onboarding} <-
+           OnboardingPackage
+           |> Ash.Query.filter(status in ^@issued_package_states)
+           |> Ash.Query.filter(not is_nil(bundle_ciphertext))
+           |> Ash.read(actor: actor)
+           |> Page.unwrap(),
+         {:ok, collectors} <-
+           CollectorPackage
+           |> Ash.Query.for_read(:active)
+           |> Ash.Query.filter(not is_nil(tls_cert_pem))
+           |> Ash.read(actor: actor)
+           |> Page.unwrap() do
+      edge =
+        Enum.flat_map(onboarding, fn package ->
+          case Crypto.decrypt_safe(package.bundle_ciphertext) do
+            {:ok, pem} -> [{:edge_package, package.id, package.label, pem}]
+            {:error, _reason} -> []
+          end
+        end)
+
+      collector =
+        Enum.map(collectors, fn package ->
+          name = package.hostname || "#{package.collector_type} collector"
+          {:collector_package, package.id, name, package.tls_cert_pem}
+        end)
+
+      {:ok, edge ++ collector}
+    end
+  end
+
+  defp package_targets(packages, source) do
+    for {^source, target, _name, _pem} <- packages, into: MapSet.new(), do: target
+  end
+
+  defp forget_stale(keep, actor) do
+    case MonitoredCertificate.list(actor: actor) |> Page.unwrap() do
+      {:ok, certificates} ->
+        certificates
+        |> Enum.reject(&MapSet.member?(Map.fetch!(keep, &1.source), &1.target))
+        |> Enum.each(&Ash.destroy(&1, actor: actor))
+
+      {:error, reason} ->
+        Logger.warning("Failed to prune monitored certificates", reason: inspect(reason))
+    end
+  end
+
+  defp open_alerts(actor) do
+    Alert
+    |> Ash.Query.for_read(:active)
+    |> Ash.Query.filter(contains(source_id, ^@source_prefix))
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+    |> case do
+      {:ok, alerts} ->
+        {:ok, Map.new(alerts, &{String.replace_prefix(&1.source_id, @source_prefix, ""), &1})}
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  defp fire(certificate, status, now, actor) do
+    result =
+      AlertGenerator.certificate_expiring(
+        name: certificate.name || certificate.target,
+        not_after: certificate.not_after,
+        days_remaining: days_remaining(certificate.not_after, now),
+        severity: if(status == :warning, do: :warning, else: :critical),
+        source_id: @source_prefix <> alert_key(certificate),
+        actor: actor,
+        details: %{
+          "certificate_source" => to_string(certificate.source),
+          "certificate_target" => certificate.target,
+          "subject" => certificate.subject,
+          "issuer" => certificate.issuer,
+          "serial_number" => certificate.serial_number,
+          "not_after" => DateTime.to_iso8601(certificate.not_after),
+          "status" => to_string(status)
+        }
+      )
+
+    case result do
+      {:ok, _alert} -> :ok
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp resolve(alert, certificate, actor) do
+    alert
+    |> Ash.Changeset.for_update(:resolve, %{
+      resolved_by: "certificate_expiry",
+      resolution_note:
+        "Certificate renewed, now expires #{DateTime.to_iso8601(certificate.not_after)}"
+    })
+    |> Ash.update(actor: actor)
+    |> case do
+      {:ok, _alert} ->
+        :ok
+
+      {:error, reason} ->
+        Logger.warning("Failed to resolve certificate alert #{alert.id}", reason: inspect(reason))
+        {:error, reason}
+    end
+  end
+
+  defp alert_key(certificate), do: "#{certificate.source}:#{certificate.target}"
+
+  defp common_name({:rdnSequence, rdns}) do
+    rdns
+    |> List.flatten()
+    |> Enum.find_value(fn
+      {:AttributeTypeAndValue, @common_name_oid, value} -> attribute_string(value)
+      _ -> nil
+    end)
+  end
+
+  defp common_name(_name), do: nil
+
+  defp attribute_string({_type, value}), do: attribute_string(value)
+  defp attribute_string(value) when is_binary(value), do: value
+  defp attribute_string(value) when is_list(value), do: List.to_string(value)
+  defp attribute_string(_value), do: nil
+
+  defp serial_number(serial) when is_integer(serial), do: Integer.to_string(serial, 16)
+  defp serial_number(_serial), do: nil
+
+  defp config(key, default) do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
@@ -0,0 +1,31 @@
+defmodule ServiceRadar.Monitoring.CertificateExpiryWorker do
+  @moduledoc """
+  Oban cron worker that records monitored TLS certificates and alerts on
+  those nearing expiry. Scheduled hourly from the core runtime config.
+  """
+
+  use Oban.Worker,
+    queue: :monitoring,
+    max_attempts: 1,
+    unique: [period: 3_000, states: [:available, :scheduled, :executing]]
+
+  alias ServiceRadar.Monitoring.CertificateExpiry
+
+  require Logger
+
+  @impl Oban.Worker
+  def perform(%Oban.Job{}) do
+    case CertificateExpiry.run() do
+      {:ok, %{recorded: recorded, fired: fired, resolved: resolved}} ->
+        Logger.info(
+          "CertificateExpiryWorker: recorded #{recorded} certificate(s), " <>
+            "fired #{fired}, resolved #{resolved} alert(s)"
+        )
+
+        :ok
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+end
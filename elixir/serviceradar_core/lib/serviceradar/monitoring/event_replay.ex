@@ -0,0 +1,273 @@
+defmodule ServiceRadar.Monitoring.EventReplay do
+  @moduledoc """
+  Replays historical OCSF events (`ServiceRadar.Monitoring.OcsfEvent`) through
+  the webhook notification channels, for testing a new integration or
+  recovering from a channel outage.
+
+  Events are selected by time range, optionally narrowed by `log_name`,
+  `type_uid` and partition, and read with the caller's actor so event read
+  policies apply. Each event is sent as a `WebhookNotifier.Alert` that is
+  clearly marked as a replay:
+
+    * the title is prefixed with `[REPLAY]`
+    * the payload carries a `replay` object with the original event id and
+      time, when it was replayed and by whom
+
+  Replays bypass notifier cooldowns and are rate limited (`:rate` events per
+  second) so a large replay does not flood the receiver. Every replay is
+  written to the audit log.
+
+  ## Usage
+
+      EventReplay.replay(
+        %{from: ~U[2026-10-01 00:00:00Z], to: ~U[2026-10-02 00:00:00Z],
+          log_names: ["alert.rule.threshold"], partition: "default"},
+        actor: scope.user,
+        channel: "slack"
+      )
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Events.AuditWriter
+  alias ServiceRadar.Identity.RBAC
+  alias ServiceRadar.Monitoring.OcsfEvent
+  alias ServiceRadar.Monitoring.WebhookNotifier
+
+  require Ash.Query
+
+  @permission "observability.alerts.manage"
+  @default_limit 100
+  @max_limit 500
+  @default_rate 5
+  @max_rate 50
+  @max_window_days 31
+
+  @type filters :: %{
+          required(:from) => DateTime.t(),
+          required(:to) => DateTime.t(),
+          optional(:log_names) => [String.t()],
+          optional(:type_uids) => [integer()],
+          optional(:partition) => String.t() | nil,
+          optional(:limit) => pos_integer()
+        }
+
+  @type summary :: %{
+          selected: non_neg_integer(),
+          sent: non_neg_integer(),
+          failed: non_neg_integer(),
+          failed_event_ids: [String.t()]
+        }
+
+  @doc "RBAC permission required to replay events."
+  @spec required_permission() :: String.t()
+  def required_permission, do: @permission
+
+  @doc """
+  Returns the events matching `filters`, oldest first, without sending them.
+
+  Options:
+    - `:actor` - actor the events are read as (required)
+  """
+  @spec select(filters(), keyword()) :: {:ok, [OcsfEvent.t()]} | {:error, term()}
+  def select(filters, opts) do
+    with {:ok, filters} <- normalize_filters(filters) do
+      OcsfEvent
+      |> Ash.Query.filter(time >= ^filters.from and time < ^filters.to)
+      |> filter_log_names(filters.log_names)
+      |> filter_type_uids(filters.type_uids)
+      |> filter_partition(filters.partition)
+      |> Ash.Query.sort(time: :asc)
+      |> Ash.Query.limit(filters.limit)
+      |> Ash.read(actor: Keyword.get(opts, :actor))
+      |> Page.unwrap()
+    end
+  end
+
+  @doc """
+  Selects the events matching `filters` and re-sends them as replays.
+
+  Options:
+    - `:actor` - requesting actor; must hold `#{@permission}` (required)
+    - `:channel` - only send to the webhook with this name or URL
+    - `:rate` - events sent per second (default #{@default_rate}, max #{@max_rate})
+    - `:notifier` - notifier process (default: `ServiceRadar.Monitoring.WebhookNotifier`)
+  """
+  @spec replay(filters(), keyword()) :: {:ok, summary()} | {:error, term()}
+  def replay(filters, opts) do
+    actor = Keyword.get(opts, :actor)
+
+    with :ok <- authorize(actor),
+         {:ok, events} <- select(filters, opts) do
+      summary = send_events(events, actor, opts)
+      audit(actor, filters, Keyword.get(opts, :channel), summary)
+      {:ok, summary}
+    end
+  end
+
+  @doc """
+  Builds the replay alert for `event`. `replay` holds who requested the replay
+  and when.
+  """
+  @spec to_alert(OcsfEvent.t(), map()) :: WebhookNotifier.Alert.t()
+  def to_alert(event, replay) do
+    %WebhookNotifier.Alert{
+      level: severity_level(event.severity_id),
+      title: "[REPLAY] " <> (event.activity_name || event.log_name || "Event"),
+      message: event.message || "",
+      timestamp: DateTime.to_iso8601(replay.replayed_at),
+      gateway_id: "core",
+      service_name: event.log_name,
+      details: %{
+        "event_id" => event.id,
+        "class_uid" => event.class_uid,
+        "type_uid" => event.type_uid,
+        "severity" => event.severity,
+        "status" => event.status,
+        "device" => event.device || %{}
+      },
+      replay: %{
+        "replay" => true,
+        "original_event_id" => event.id,
+        "original_time" => DateTime.to_iso8601(event.time),
+        "replayed_at" => DateTime.to_iso8601(replay.replayed_at),
+        "requested_by" => replay.requested_by
+      }
+    }
+  end
+
+  defp send_events(events, actor, opts) do
+    replay = %{replayed_at: DateTime.utc_now(), requested_by: actor_label(actor)}
+    interval = div(1000, clamp_rate(Keyword.get(opts, :rate)))
+
+    notifier_opts =
+      [channel: Keyword.get(opts, :channel), server: Keyword.get(opts, :notifier)]
+      |> Enum.reject(fn {_key, value} -> is_nil(value) end)
+
+    failed =
+      events
+      |> Enum.with_index()
+      |> Enum.flat_map(fn {event, index} ->
+        if index > 0, do: Process.sleep(interval)
+
+        case WebhookNotifier.replay_alert(to_alert(event, replay), notifier_opts) do
+          :ok -> []
+          {:error, _reason} -> [event.id]
+        end
+      end)
+
+    %{
+      selected: length(events),
+      sent: length(events) - length(failed),
+      failed: length(failed),
+      failed_event_ids: failed
+    }
+  end
+
+  defp normalize_filters(filters) do
+    from = Map.get(filters, :from)
+    to = Map.get(filters, :to)
+
+    cond do
+      not match?(%DateTime{}, from) or not match?(%DateTime{}, to) ->
+        {:error, "from and to are required"}
+
+      DateTime.compare(from, to) != :lt ->
+        {:error, "from must be before to"}
+
+      DateTime.diff(to, from, :day) > @max_window_days ->
+        {:error, "time range must not exceed #{@max_window_days} days"}
+
+      true ->
+        {:ok,
+         %{
+           from: from,
+           to: to,
+           log_names: List.wrap(Map.get(filters, :log_names)),
+           type_uids: List.wrap(Map.get(filters, :type_uids)),
+           partition: blank_to_nil(Map.get(filters, :partition)),
+           limit: clamp_limit(Map.get(filters, :limit))
+         }}
+    end
+  end
+
+  defp filter_log_names(query, []), do: query
+  defp filter_log_names(query, names), do: Ash.Query.filter(query, log_name in ^names)
+
+  defp filter_type_uids(query, []), do: query
+  defp filter_type_uids(query, uids), do: Ash.Query.filter(query, type_uid in ^uids)
+
+  # An event belongs to a partition when its metadata names the partition or
+  # the device it concerns has an identifier in that partition.
+  defp filter_partition(query, nil), do: query
+
+  defp filter_partition(query, partition) do
+    Ash.Query.filter(
+      query,
+      fragment(
+        "(COALESCE(? ->> 'partition_id', ? ->> 'partition') = ? OR EXISTS (SELECT 1 FROM platform.device_identifiers i WHERE i.device_id = ? ->> 'uid' AND i.partition = ?))",
+        metadata,
+        metadata,
+        ^partition,
+        device,
+        ^partition
+      )
+    )
+  end
+
+  defp authorize(actor) do
+    cond do
+      is_nil(actor) -> {:error, :forbidden}
+      SystemActor.system_actor?(actor) -> :ok
+      RBAC.has_permission?(actor, @permission) -> :ok
+      true -> {:error, :forbidden}
+    end
+  end
+
+  defp audit(actor, filters, channel, summary) do
+    AuditWriter.write_async(
+      action: :event_replay,
+      resource_type: "ocsf_event",
+      resource_id: Ecto.UUID.generate(),
+      resource_name: channel || "all channels",
+      actor: actor,
+      severity: if(summary.failed > 0, do: :medium, else: :informational),
+      message: "Replayed #{summary.sent} of #{summary.selected} events",
+      details: %{
+        from: Map.get(filters, :from),
+        to: Map.get(filters, :to),
+        log_names: Map.get(filters, :log_names),
+        type_uids: Map.get(filters, :type_uids),
+        partition: Map.get(filters, :partition),
+        channel: channel,
+        selected: summary.selected,
+        sent: summary.sent,
+        failed: summary.failed
+      }
+    )
+  end
+
+  # OCSF severity_id: 1 informational, 2 low, 3 medium, 4 high, 5 critical, 6 fatal.
+  defp severity_level(severity_id) when is_integer(severity_id) and severity_id >= 4, do: :error
+  defp severity_level(3), do: :warning
+  defp severity_level(_severity_id), do: :info
+
+  defp actor_label(%{email: email}) when is_binary(email), do: email
+  defp actor_label(%{id: id}) when not is_nil(id), do: to_string(id)
+  defp actor_label(_actor), do: "system"
+
+  defp clamp_rate(rate) when is_integer(rate), do: rate |> max(1) |> min(@max_rate)
+  defp clamp_rate(_rate), do: @default_rate
+
+  defp clamp_limit(limit) when is_integer(limit), do: limit |> max(1) |> min(@max_limit)
+  defp clamp_limit(_limit), do: @default_limit
+
+  defp blank_to_nil(value) when is_binary(value) do
+    case String.trim(value) do
+      "" -> nil
+      trimmed -> trimmed
+    end
+  end
+
+  defp blank_to_nil(_value), do: nil
+end
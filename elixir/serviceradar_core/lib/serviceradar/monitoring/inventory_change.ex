@@ -0,0 +1,249 @@
+defmodule ServiceRadar.Monitoring.InventoryChange do
+  @moduledoc """
+  Alerts on sudden changes in inventory size, which usually mean an
+  integration is broken rather than that the network changed - for example a
+  sync returning an empty result and reconciliation deleting its devices.
+
+  Each run counts the devices of every discovery source (`discovery_sources`)
+  and every partition (device identifiers), compares each count with the one
+  recorded by the previous run (`ServiceRadar.Monitoring.InventoryCount`),
+  and raises an alert (through `ServiceRadar.Monitoring.AlertGenerator`, so
+  webhooks are notified) for each count that changed by more than the
+  threshold. The alert carries the old and new counts and the source or
+  partition. A source or partition that has no devices left counts as 0.
+
+  Changes are measured against the previous run only, so a single drop alerts
+  once; a count that stays low becomes the new baseline. Counts whose previous
+  value is below `min_count` are not alerted on, since small inventories
+  fluctuate by large percentages.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Monitoring.InventoryChange,
+        change_percent: 20,
+        min_count: 10,
+        overrides: %{"source:armis" => 10, "partition:lab" => 50}
+
+  or at runtime with `SERVICERADAR_INVENTORY_CHANGE_PERCENT` and
+  `SERVICERADAR_INVENTORY_CHANGE_MIN_COUNT`. `overrides` sets the change
+  percent for single sources or partitions, keyed `source:<name>` or
+  `partition:<slug>`.
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Monitoring.AlertGenerator
+  alias ServiceRadar.Monitoring.InventoryCount
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @default_change_percent 20
+  @default_min_count 10
+  @source_prefix "inventory_change:"
+
+  @source_counts_sql """
+  SELECT s.source, count(*)
+  FROM platform.ocsf_devices d
+  CROSS JOIN LATERAL unnest(COALESCE(d.discovery_sources, ARRAY[]::text[])) AS s(source)
+  WHERE d.deleted_at IS NULL
+  GROUP BY s.source
+  """
+
+  @partition_counts_sql """
+  SELECT COALESCE(i.partition, 'default'), count(DISTINCT i.device_id)
+  FROM platform.device_identifiers i
+  JOIN platform.ocsf_devices d ON d.uid = i.device_id
+  WHERE d.deleted_at IS NULL
+  GROUP BY 1
+  """
+
+  @type dimension :: :source | :partition
+  @type counts :: %{{dimension(), String.t()} => non_neg_integer()}
+  @type thresholds :: %{
+          change_percent: number(),
+          min_count: non_neg_integer(),
+          overrides: %{String.t() => number()}
+        }
+  @type change :: %{
+          dimension: dimension(),
+          key: String.t(),
+          previous_count: non_neg_integer(),
+          current_count: non_neg_integer(),
+          change_percent: float()
+        }
+
+  @doc "The configured thresholds."
+  @spec thresholds() :: thresholds()
+  def thresholds do
+    %{
+      change_percent: config(:change_percent, @default_change_percent),
+      min_count: config(:min_count, @default_min_count),
+      overrides: config(:overrides, %{})
+    }
+  end
+
+  @doc """
+  Counts the inventory, alerts on changes since the previous run and records
+  the new counts.
+
+  Options:
+    - `:now` - check time (default now)
+    - `:thresholds` - see `thresholds/0` (default configured)
+    - `:actor` - actor counts and alerts are written as (default system actor)
+  """
+  @spec run(keyword()) ::
+          {:ok, %{checked: non_neg_integer(), fired: non_neg_integer()}} | {:error, term()}
+  def run(opts \\ []) do
+    actor = Keyword.get_lazy(opts, :actor, fn -> SystemActor.system(:inventory_change) end)
+    now = Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+    opts = Keyword.merge(opts, actor: actor, now: now)
+
+    with {:ok, previous} <- previous_counts(actor),
+         {:ok, current} <- current_counts() do
+      fired = check(previous, current, opts)
+      record(previous, current, now, actor)
+      {:ok, %{checked: map_size(current), fired: length(fired)}}
+    end
+  end
+
+  @doc """
+  Raises an alert for each count that changed by more than the threshold
+  and returns the changes alerted on.
+
+  Options:
+    - `:thresholds` - see `thresholds/0` (default configured)
+    - `:fire` - `fn change, opts -> {:ok, alert} | {:error, reason} end`
+      (default raises an alert through `AlertGenerator`)
+  """
+  @spec check(counts(), counts(), keyword()) :: [change()]
+  def check(previous, current, opts \\ []) do
+    thresholds = Keyword.get_lazy(opts, :thresholds, &thresholds/0)
+    fire = Keyword.get(opts, :fire, &fire/2)
+
+    previous
+    |> changes(current, thresholds)
+    |> Enum.filter(fn change ->
+      case fire.(change, opts) do
+        {:ok, _alert} ->
+          true
+
+        {:error, reason} ->
+          Logger.warning("Failed to raise inventory change alert for #{label(change)}",
+            reason: inspect(reason)
+          )
+
+          false
+      end
+    end)
+  end
+
+  @doc """
+  Returns the counts that changed by more than their threshold between
+  `previous` and `current`. Keys missing from `current` count as 0; keys new
+  in `current` have no baseline and are skipped.
+  """
+  @spec changes(counts(), counts(), thresholds()) :: [change()]
+  def changes(previous, current, thresholds) do
+    previous
+    |> Enum.sort()
+    |> Enum.flat_map(fn {{dimension, key}, previous_count} ->
+      current_count = Map.get(current, {dimension, key}, 0)
+      limit = change_limit(thresholds, dimension, key)
+
+      if previous_count >= max(thresholds.min_count, 1) and current_count != previous_count do
+        percent = (current_count - previous_count) * 100 / previous_count
+
+        if abs(percent) > limit do
+          [
+            %{
+              dimension: dimension,
+              key: key,
+              previous_count: previous_count,
+              current_count: current_count,
+              change_percent: Float.round(percent, 1)
+            }
+          ]
+        else
+          []
+        end
+      else
+        []
+      end
+    end)
+  end
+
+  defp change_limit(%{change_percent: default} = thresholds, dimension, key) do
+    thresholds
+    |> Map.get(:overrides, %{})
+    |> Map.get("#{dimension}:#{key}", default)
+  end
+
+  defp previous_counts(actor) do
+    case InventoryCount.list(actor: actor) |> Page.unwrap() do
+      {:ok, counts} -> {:ok, Map.new(counts, &{{&1.dimension, &1.key}, &1.device_count})}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp current_counts do
+    with {:ok, %{rows: sources}} <- Repo.query(@source_counts_sql),
+         {:ok, %{rows: partitions}} <- Repo.query(@partition_counts_sql) do
+      {:ok,
+       Map.merge(
+         Map.new(sources, fn [source, count] -> {{:source, source}, count} end),
+         Map.new(partitions, fn [partition, count] -> {{:partition, partition}, count} end)
+       )}
+    end
+  end
+
+  # Keys that disappeared are recorded as 0 so a later recovery is measured
+  # from the drop, not from the original count.
+  defp record(previous, current, now, actor) do
+    previous
+    |> Map.new(fn {key, _count} -> {key, 0} end)
+    |> Map.merge(current)
+    |> Enum.each(fn {{dimension, key}, count} ->
+      %{dimension: dimension, key: key, device_count: count, checked_at: now}
+      |> InventoryCount.record(actor: actor)
+      |> case do
+        {:ok, _count} ->
+          :ok
+
+        {:error, reason} ->
+          Logger.warning("Failed to record inventory count for #{dimension} #{key}",
+            reason: inspect(reason)
+          )
+      end
+    end)
+  end
+
+  defp fire(change, opts) do
+    AlertGenerator.inventory_count_changed(
+      source_id: @source_prefix <> "#{change.dimension}:#{change.key}",
+      dimension: change.dimension,
+      key: change.key,
+      previous_count: change.previous_count,
+      current_count: change.current_count,
+      change_percent: change.change_percent,
+      severity: if(change.current_count < change.previous_count, do: :critical, else: :warning),
+      actor: Keyword.get(opts, :actor),
+      partition: if(change.dimension == :partition, do: change.key),
+      details: %{
+        "dimension" => to_string(change.dimension),
+        "key" => change.key,
+        "previous_count" => change.previous_count,
+        "current_count" => change.current_count,
+        "change_percent" => change.change_percent
+      }
+    )
+  end
+
+  defp label(change), do: "#{change.dimension} #{change.key}"
+
+  defp config(key, default) do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
@@ -0,0 +1,28 @@
+defmodule ServiceRadar.Monitoring.InventoryChangeWorker do
+  @moduledoc """
+  Oban cron worker that counts devices per discovery source and partition and
+  alerts on sudden changes. Scheduled every 15 minutes from the core runtime
+  config.
+  """
+
+  use Oban.Worker,
+    queue: :monitoring,
+    max_attempts: 1,
+    unique: [period: 600, states: [:available, :scheduled, :executing]]
+
+  alias ServiceRadar.Monitoring.InventoryChange
+
+  require Logger
+
+  @impl Oban.Worker
+  def perform(%Oban.Job{}) do
+    case InventoryChange.run() do
+      {:ok, %{checked: checked, fired: fired}} ->
+        Logger.info("InventoryChangeWorker: checked #{checked} count(s), fired #{fired} alert(s)")
+        :ok
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+end
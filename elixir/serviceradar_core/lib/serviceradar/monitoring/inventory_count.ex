@@ -0,0 +1,87 @@
+defmodule ServiceRadar.Monitoring.InventoryCount do
+  @moduledoc """
+  The device count of one discovery source or partition as of the last
+  inventory change check.
+
+  Counts are identified by their `dimension` and `key`:
+
+    * `source` - devices listing the discovery source (`armis`, `netbox`,
+      `sweep`, ...) in `discovery_sources`
+    * `partition` - devices with an identifier in the partition
+
+  `ServiceRadar.Monitoring.InventoryChange` compares each check against the
+  counts recorded by the previous one.
+  """
+
+  use Ash.Resource,
+    domain: ServiceRadar.Monitoring,
+    data_layer: AshPostgres.DataLayer,
+    authorizers: [Ash.Policy.Authorizer]
+
+  postgres do
+    table "inventory_counts"
+    repo ServiceRadar.Repo
+    schema "platform"
+    migrate? false
+  end
+
+  code_interface do
+    define :list, action: :read
+    define :record, action: :record
+  end
+
+  actions do
+    defaults [:read, :destroy]
+
+    create :record do
+      description "Records the current device count of a source or partition"
+      accept [:dimension, :key, :device_count, :checked_at]
+
+      upsert? true
+      upsert_identity :unique_key
+      upsert_fields [:device_count, :checked_at, :updated_at]
+    end
+  end
+
+  policies do
+    import ServiceRadar.Policies
+
+    system_bypass()
+    read_viewer_plus()
+  end
+
+  attributes do
+    uuid_primary_key :id
+
+    attribute :dimension, :atom do
+      allow_nil? false
+      public? true
+      constraints one_of: [:source, :partition]
+    end
+
+    attribute :key, :string do
+      allow_nil? false
+      public? true
+      description "Discovery source name or partition slug"
+    end
+
+    attribute :device_count, :integer do
+      allow_nil? false
+      public? true
+      constraints min: 0
+    end
+
+    attribute :checked_at, :utc_datetime_usec do
+      allow_nil? false
+      public? true
+      default &DateTime.utc_now/0
+    end
+
+    create_timestamp :inserted_at
+    update_timestamp :updated_at
+  end
+
+  identities do
+    identity :unique_key, [:dimension, :key]
+  end
+end
@@ -0,0 +1,233 @@
+defmodule ServiceRadar.Monitoring.MaintenanceWindows do
+  @moduledoc """
+  Maintenance windows that withhold alert notifications for a device and the
+  devices depending on it.
+
+  A device is covered by an active window on its own UID and by one on any
+  device it depends on, found by following
+  `ServiceRadar.Inventory.DeviceRelationships` edges from it (a VM `runs_on`
+  the host being patched) for up to `max_depth` hops.
+  `ServiceRadar.Monitoring.AlertGenerator` still stores alerts on covered
+  devices, tagged with `suppressed`, `maintenance_window` and
+  `maintenance_device_id` metadata, but does not notify or correlate them.
+
+  Windows come from the configuration and from `schedule/1`; scheduled windows
+  are kept in memory until they end or are cancelled.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Monitoring.MaintenanceWindows,
+        enabled: true,
+        max_depth: 2,
+        relationship_types: ["runs_on", "member_of"],
+        windows: [
+          %{
+            name: "core-switch-upgrade",
+            device_uid: "sr:7f1c...",
+            start: "2026-10-20T01:00:00Z",
+            end: "2026-10-20T03:00:00Z",
+            reason: "firmware upgrade"
+          }
+        ]
+
+  Empty `relationship_types` follows every type.
+  """
+
+  alias ServiceRadar.Inventory.DeviceRelationships
+
+  require Logger
+
+  @default_depth 2
+  @scheduled_key {__MODULE__, :scheduled}
+
+  @type window :: %{
+          name: String.t(),
+          device_uid: String.t(),
+          start: DateTime.t(),
+          end: DateTime.t(),
+          reason: String.t() | nil
+        }
+
+  @doc "Whether maintenance windows are enabled."
+  @spec enabled?() :: boolean()
+  def enabled?, do: config(:enabled, false) == true
+
+  @doc "Validates and normalizes a window; `start` and `end` may be ISO 8601."
+  @spec validate_window(map() | keyword()) :: {:ok, window()} | {:error, String.t()}
+  def validate_window(window) do
+    window = Map.new(window)
+    device_uid = window |> field(:device_uid) |> to_string() |> String.trim()
+
+    with {:ok, start} <- parse_time(window, :start),
+         {:ok, finish} <- parse_time(window, :end) do
+      name = field(window, :name) || "#{device_uid}@#{DateTime.to_iso8601(start)}"
+
+      cond do
+        device_uid == "" ->
+          {:error, "maintenance window #{name}: device_uid is required"}
+
+        DateTime.compare(finish, start) != :gt ->
+          {:error, "maintenance window #{name}: end must be after start"}
+
+        true ->
+          {:ok,
+           %{
+             name: to_string(name),
+             device_uid: device_uid,
+             start: start,
+             end: finish,
+             reason: field(window, :reason)
+           }}
+      end
+    end
+  end
+
+  @doc "Adds a window, replacing any scheduled window with the same name."
+  @spec schedule(map() | keyword()) :: {:ok, window()} | {:error, String.t()}
+  def schedule(window) do
+    with {:ok, window} <- validate_window(window) do
+      now = DateTime.utc_now()
+
+      scheduled()
+      |> Map.reject(fn {_name, scheduled} -> ended?(scheduled, now) end)
+      |> Map.put(window.name, window)
+      |> put_scheduled()
+
+      {:ok, window}
+    end
+  end
+
+  @doc "Removes a scheduled window, reporting whether it existed."
+  @spec cancel(String.t()) :: boolean()
+  def cancel(name) do
+    scheduled = scheduled()
+    put_scheduled(Map.delete(scheduled, name))
+    Map.has_key?(scheduled, name)
+  end
+
+  @doc "The configured and scheduled windows in effect at `now`, by name."
+  @spec active(DateTime.t()) :: [window()]
+  def active(now \\ DateTime.utc_now()) do
+    (configured_windows() ++ Map.values(scheduled()))
+    |> Enum.filter(&active?(&1, now))
+    |> Enum.sort_by(& &1.name)
+  end
+
+  @doc """
+  The active window covering `device_uid`, or nil.
+
+  Options:
+    - `:now` - time to check (default now)
+    - `:windows` - windows to check (default `active/1`)
+    - `:max_depth`, `:relationship_types` - dependency traversal (default
+      configured)
+    - `:load` - passed to `DeviceRelationships.traverse/2`
+  """
+  @spec covering(String.t() | nil, keyword()) :: window() | nil
+  def covering(device_uid, opts \\ [])
+
+  def covering(device_uid, opts) when is_binary(device_uid) do
+    now = Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+    windows = Keyword.get_lazy(opts, :windows, fn -> active(now) end)
+
+    case Enum.filter(windows, &active?(&1, now)) do
+      [] ->
+        nil
+
+      windows ->
+        Enum.find(windows, &(&1.device_uid == device_uid)) ||
+          covering_upstream(device_uid, windows, opts)
+    end
+  end
+
+  def covering(_device_uid, _opts), do: nil
+
+  @doc """
+  Tags alert attributes on a covered device as suppressed. Returns
+  `{:suppressed, attrs}` when a window covers the alert's device, otherwise
+  `{:ok, attrs}`. Options as for `covering/2`.
+  """
+  @spec suppress(map(), keyword()) :: {:ok, map()} | {:suppressed, map()}
+  def suppress(attrs, opts \\ []) do
+    with true <- Keyword.has_key?(opts, :windows) or enabled?(),
+         %{} = window <- covering(Map.get(attrs, :device_uid), opts) do
+      metadata =
+        (Map.get(attrs, :metadata) || %{})
+        |> Map.put("suppressed", true)
+        |> Map.put("maintenance_window", window.name)
+        |> Map.put("maintenance_device_id", window.device_uid)
+
+      {:suppressed, Map.put(attrs, :metadata, metadata)}
+    else
+      _ -> {:ok, attrs}
+    end
+  end
+
+  defp covering_upstream(device_uid, windows, opts) do
+    traverse_opts =
+      [
+        direction: "outgoing",
+        depth: Keyword.get_lazy(opts, :max_depth, fn -> config(:max_depth, @default_depth) end),
+        types:
+          Keyword.get_lazy(opts, :relationship_types, fn -> config(:relationship_types, []) end)
+      ] ++ Keyword.take(opts, [:load])
+
+    case DeviceRelationships.traverse(device_uid, traverse_opts) do
+      {:ok, %{devices: devices}} ->
+        Enum.find(windows, &(&1.device_uid in devices))
+
+      {:error, reason} ->
+        Logger.warning("Maintenance lookup failed for #{device_uid}: #{inspect(reason)}")
+        nil
+    end
+  end
+
+  defp active?(window, now) do
+    DateTime.compare(now, window.start) != :lt and DateTime.compare(now, window.end) == :lt
+  end
+
+  defp ended?(window, now), do: DateTime.compare(now, window.end) != :lt
+
+  defp parse_time(window, key) do
+    case field(window, key) do
+      %DateTime{} = value ->
+        {:ok, value}
+
+      value when is_binary(value) ->
+        case DateTime.from_iso8601(value) do
+          {:ok, datetime, _offset} -> {:ok, datetime}
+          {:error, _} -> {:error, "maintenance window: invalid #{key} #{inspect(value)}"}
+        end
+
+      value ->
+        {:error, "maintenance window: invalid #{key} #{inspect(value)}"}
+    end
+  end
+
+  defp configured_windows do
+    :windows
+    |> config([])
+    |> Enum.flat_map(fn window ->
+      case validate_window(window) do
+        {:ok, window} ->
+          [window]
+
+        {:error, reason} ->
+          Logger.warning("Ignoring #{reason}")
+          []
+      end
+    end)
+  end
+
+  defp scheduled, do: :persistent_term.get(@scheduled_key, %{})
+
+  defp put_scheduled(windows), do: :persistent_term.put(@scheduled_key, windows)
+
+  defp field(map, key), do: Map.get(map, key) || Map.get(map, Atom.to_string(key))
+
+  defp config(key, default) do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
@@ -0,0 +1,120 @@
+defmodule ServiceRadar.Monitoring.MonitoredCertificate do
+  @moduledoc """
+  A TLS certificate whose expiry ServiceRadar tracks.
+
+  Certificates are identified by their `source` and `target`:
+
+    * `endpoint` - a configured TLS endpoint, targeted as `host:port`
+    * `edge_package` - the certificate issued to an edge onboarding package,
+      targeted by package ID
+    * `collector_package` - the mTLS certificate issued to a collector
+      package, targeted by package ID
+
+  `ServiceRadar.Monitoring.CertificateExpiry` records them and alerts as they
+  approach `not_after`. They are queryable with SRQL as `in:certificates`.
+  """
+
+  use Ash.Resource,
+    domain: ServiceRadar.Monitoring,
+    data_layer: AshPostgres.DataLayer,
+    authorizers: [Ash.Policy.Authorizer]
+
+  @detail_fields [
+    :name,
+    :subject,
+    :issuer,
+    :serial_number,
+    :not_before,
+    :not_after,
+    :last_checked_at
+  ]
+
+  postgres do
+    table "monitored_certificates"
+    repo ServiceRadar.Repo
+    schema "platform"
+    migrate? false
+  end
+
+  code_interface do
+    define :list, action: :read
+    define :record, action: :record
+  end
+
+  actions do
+    defaults [:read, :destroy]
+
+    create :record do
+      description "Records a certificate, replacing what was known about its target"
+      accept [:source, :target | @detail_fields]
+
+      upsert? true
+      upsert_identity :unique_target
+      upsert_fields [:updated_at | @detail_fields]
+    end
+  end
+
+  policies do
+    import ServiceRadar.Policies
+
+    system_bypass()
+    read_viewer_plus()
+  end
+
+  attributes do
+    uuid_primary_key :id
+
+    attribute :source, :atom do
+      allow_nil? false
+      public? true
+      constraints one_of: [:endpoint, :edge_package, :collector_package]
+    end
+
+    attribute :target, :string do
+      allow_nil? false
+      public? true
+      description "host:port for endpoints, the package ID for packages"
+    end
+
+    attribute :name, :string do
+      public? true
+      description "Display name: the endpoint or package label"
+    end
+
+    attribute :subject, :string do
+      public? true
+      description "Subject common name"
+    end
+
+    attribute :issuer, :string do
+      public? true
+      description "Issuer common name"
+    end
+
+    attribute :serial_number, :string do
+      public? true
+    end
+
+    attribute :not_before, :utc_datetime_usec do
+      public? true
+    end
+
+    attribute :not_after, :utc_datetime_usec do
+      allow_nil? false
+      public? true
+    end
+
+    attribute :last_checked_at, :utc_datetime_usec do
+      allow_nil? false
+      public? true
+      default &DateTime.utc_now/0
+    end
+
+    create_timestamp :inserted_at
+    update_timestamp :updated_at
+  end
+
+  identities do
+    identity :unique_target, [:source, :target]
+  end
+end
@@ -0,0 +1,264 @@
+defmodule ServiceRadar.Monitoring.QuietHours do
+  @moduledoc """
+  Quiet-hours windows for `ServiceRadar.Monitoring.WebhookNotifier`.
+
+  During a window, non-critical alerts are held for a digest instead of
+  being sent; `:error` alerts (critical and emergency severities) are always
+  sent. The notifier sends one digest alert per window occurrence when it
+  ends.
+
+  A window is a daily period from `start` to `end` (`"HH:MM"` wall-clock
+  times); an `end` at or before `start` spans midnight. Optional fields
+  narrow it:
+
+    - `:timezone` - `"UTC"` (default), a fixed offset such as `"+02:00"`, or
+      a zone name such as `"Europe/Berlin"`, which needs a time zone database
+      (`config :elixir, :time_zone_database`)
+    - `:days` - weekdays the window starts on (`"mon"` .. `"sun"`)
+    - `:partitions` - alert partitions (the `partition` detail)
+    - `:levels` - held levels, `:info` and/or `:warning` (default both)
+  """
+
+  alias ServiceRadar.Monitoring.WebhookNotifier.Alert
+
+  @days %{
+    "mon" => 1,
+    "tue" => 2,
+    "wed" => 3,
+    "thu" => 4,
+    "fri" => 5,
+    "sat" => 6,
+    "sun" => 7
+  }
+  @quiet_levels [:info, :warning]
+  @max_digest_alerts 100
+
+  @type timezone :: {:offset, integer()} | {:zone, String.t()}
+
+  @type window :: %{
+          name: String.t(),
+          start: Time.t(),
+          end: Time.t(),
+          timezone: timezone(),
+          days: [1..7],
+          partitions: [String.t()],
+          levels: [atom()]
+        }
+
+  @doc "Validates and normalizes window configurations."
+  @spec parse_windows([map()]) :: {:ok, [window()]} | {:error, String.t()}
+  def parse_windows(configs) when is_list(configs) do
+    configs
+    |> Enum.with_index()
+    |> Enum.reduce_while({:ok, []}, fn {config, index}, {:ok, acc} ->
+      case parse_window(Map.new(config), index) do
+        {:ok, window} -> {:cont, {:ok, [window | acc]}}
+        {:error, reason} -> {:halt, {:error, reason}}
+      end
+    end)
+    |> case do
+      {:ok, windows} -> {:ok, Enum.reverse(windows)}
+      error -> error
+    end
+  end
+
+  def parse_windows(_configs), do: {:error, "quiet hours must be a list of windows"}
+
+  @doc """
+  Finds the window occurrence holding `alert` at `now`.
+
+  Returns `{:hold, key, ends_at}` where `key` identifies the occurrence's
+  digest, or `:send`.
+  """
+  @spec check([window()], Alert.t(), DateTime.t()) ::
+          {:hold, {String.t(), DateTime.t()}, DateTime.t()} | :send
+  def check(windows, %Alert{} = alert, now) do
+    partition = Map.get(alert.details || %{}, "partition")
+
+    Enum.find_value(windows, :send, fn window ->
+      with true <- alert.level in window.levels,
+           true <- window.partitions == [] or partition in window.partitions,
+           {start, ends_at} <- occurrence(window, now) do
+        {:hold, {window.name, start}, ends_at}
+      else
+        _ -> nil
+      end
+    end)
+  end
+
+  @doc """
+  Builds the digest alert for the alerts held during a window occurrence,
+  oldest first.
+  """
+  @spec digest({String.t(), DateTime.t()}, DateTime.t(), [Alert.t()]) :: Alert.t()
+  def digest({name, start}, ends_at, alerts) do
+    level = if Enum.any?(alerts, &(&1.level == :warning)), do: :warning, else: :info
+
+    %Alert{
+      level: level,
+      title: "Quiet Hours Digest: #{name}",
+      message:
+        "#{length(alerts)} alert(s) held during quiet hours " <>
+          "#{DateTime.to_iso8601(start)} - #{DateTime.to_iso8601(ends_at)}",
+      timestamp: DateTime.to_iso8601(ends_at),
+      gateway_id: "core",
+      details: %{
+        "window" => name,
+        "start" => DateTime.to_iso8601(start),
+        "end" => DateTime.to_iso8601(ends_at),
+        "count" => length(alerts),
+        "alerts" => alerts |> Enum.take(@max_digest_alerts) |> Enum.map(&digest_entry/1)
+      }
+    }
+  end
+
+  # The occurrence covering `now` started today or, spanning midnight,
+  # yesterday (local time).
+  defp occurrence(window, now) do
+    local = to_local(now, window.timezone)
+    date = NaiveDateTime.to_date(local)
+
+    Enum.find_value([date, Date.add(date, -1)], fn day ->
+      start = NaiveDateTime.new!(day, window.start)
+      finish = NaiveDateTime.new!(end_day(day, window), window.end)
+
+      if day_matches?(window, day) and NaiveDateTime.compare(local, start) != :lt and
+           NaiveDateTime.compare(local, finish) == :lt do
+        {to_utc(start, window.timezone), to_utc(finish, window.timezone)}
+      end
+    end)
+  end
+
+  defp end_day(day, window) do
+    if Time.compare(window.end, window.start) == :gt, do: day, else: Date.add(day, 1)
+  end
+
+  defp day_matches?(%{days: []}, _day), do: true
+  defp day_matches?(%{days: days}, day), do: Date.day_of_week(day) in days
+
+  defp to_local(now, {:offset, seconds}) do
+    now |> DateTime.to_naive() |> NaiveDateTime.add(seconds)
+  end
+
+  defp to_local(now, {:zone, zone}) do
+    now |> DateTime.shift_zone!(zone) |> DateTime.to_naive()
+  end
+
+  defp to_utc(naive, {:offset, seconds}) do
+    naive |> NaiveDateTime.add(-seconds) |> DateTime.from_naive!("Etc/UTC")
+  end
+
+  defp to_utc(naive, {:zone, zone}) do
+    case DateTime.from_naive(naive, zone) do
+      {:ok, datetime} -> DateTime.shift_zone!(datetime, "Etc/UTC")
+      {:ambiguous, first, _second} -> DateTime.shift_zone!(first, "Etc/UTC")
+      {:gap, _before, after_gap} -> DateTime.shift_zone!(after_gap, "Etc/UTC")
+    end
+  end
+
+  defp parse_window(config, index) do
+    name = field(config, :name) || "window-#{index}"
+
+    with {:ok, start} <- parse_clock(name, "start", field(config, :start)),
+         {:ok, finish} <- parse_clock(name, "end", field(config, :end)),
+         {:ok, timezone} <- parse_timezone(name, field(config, :timezone)),
+         {:ok, days} <- parse_days(name, field(config, :days) || []),
+         {:ok, levels} <- parse_levels(name, field(config, :levels) || @quiet_levels) do
+      {:ok,
+       %{
+         name: to_string(name),
+         start: start,
+         end: finish,
+         timezone: timezone,
+         days: days,
+         partitions: Enum.map(field(config, :partitions) || [], &to_string/1),
+         levels: levels
+       }}
+    end
+  end
+
+  defp parse_clock(name, label, value) when is_binary(value) do
+    with [hour, minute] <- String.split(value, ":"),
+         {hour, ""} <- Integer.parse(hour),
+         {minute, ""} <- Integer.parse(minute),
+         {:ok, time} <- Time.new(hour, minute, 0) do
+      {:ok, time}
+    else
+      _ -> {:error, "quiet window #{name}: #{label} must be HH:MM, got #{inspect(value)}"}
+    end
+  end
+
+  defp parse_clock(name, label, value) do
+    {:error, "quiet window #{name}: #{label} must be HH:MM, got #{inspect(value)}"}
+  end
+
+  defp parse_timezone(_name, value) when value in [nil, "", "UTC", "Etc/UTC"] do
+    {:ok, {:offset, 0}}
+  end
+
+  defp parse_timezone(name, <<sign, hours::binary-size(2), ":", minutes::binary-size(2)>>)
+       when sign in [?+, ?-] do
+    with {hours, ""} when hours <= 14 <- Integer.parse(hours),
+         {minutes, ""} when minutes < 60 <- Integer.parse(minutes) do
+      seconds = hours * 3600 + minutes * 60
+      {:ok, {:offset, if(sign == ?-, do: -seconds, else: seconds)}}
+    else
+      _ -> {:error, "quiet window #{name}: invalid timezone offset"}
+    end
+  end
+
+  defp parse_timezone(name, zone) when is_binary(zone) do
+    case DateTime.shift_zone(DateTime.utc_now(), zone) do
+      {:ok, _} ->
+        {:ok, {:zone, zone}}
+
+      {:error, :utc_only_time_zone_database} ->
+        {:error,
+         "quiet window #{name}: timezone #{zone} needs a time zone database; " <>
+           "use a UTC offset such as +02:00"}
+
+      {:error, _} ->
+        {:error, "quiet window #{name}: unknown timezone #{zone}"}
+    end
+  end
+
+  defp parse_timezone(name, zone) do
+    {:error, "quiet window #{name}: invalid timezone #{inspect(zone)}"}
+  end
+
+  defp parse_days(name, days) do
+    Enum.reduce_while(days, {:ok, []}, fn day, {:ok, acc} ->
+      case Map.fetch(@days, day |> to_string() |> String.downcase() |> String.slice(0, 3)) do
+        {:ok, number} -> {:cont, {:ok, [number | acc]}}
+        :error -> {:halt, {:error, "quiet window #{name}: unknown day #{inspect(day)}"}}
+      end
+    end)
+  end
+
+  defp parse_levels(name, levels) do
+    levels = Enum.map(levels, &normalize_level/1)
+
+    if levels != [] and Enum.all?(levels, &(&1 in @quiet_levels)) do
+      {:ok, levels}
+    else
+      {:error, "quiet window #{name}: levels must be info and/or warning"}
+    end
+  end
+
+  defp normalize_level(level) when is_atom(level), do: level
+  defp normalize_level("info"), do: :info
+  defp normalize_level("warning"), do: :warning
+  defp normalize_level(level), do: level
+
+  defp digest_entry(alert) do
+    %{
+      "level" => to_string(alert.level),
+      "title" => alert.title,
+      "message" => alert.message,
+      "timestamp" => alert.timestamp,
+      "gateway_id" => alert.gateway_id
+    }
+  end
+
+  defp field(config, key), do: Map.get(config, key) || Map.get(config, Atom.to_string(key))
+end
@@ -0,0 +1,352 @@
+defmodule ServiceRadar.Monitoring.RiskAlerts do
+  @moduledoc """
+  Alerts when the risk level a discovery source reports for a device rises
+  past a configured threshold or by more than a configured delta.
+
+  `ServiceRadar.Inventory.SyncIngestor` reads the risk stored for each
+  device of a batch before the batch is written and hands both to
+  `check/3` afterwards. The risk of an update is the first integer of its
+  `armis_risk_level` or `risk_level` metadata; the stored risk is read the
+  same way from the device metadata, falling back to `risk_score`.
+
+  Each rule may be scoped to a partition and to a tag (a key of the device
+  tags, or an entry of the comma-separated `armis_tags`/`tags` metadata);
+  the first matching rule applies. A rule fires when the risk rises to or
+  above its `threshold` from below it (critical), or rises by at least its
+  `delta` (warning). Alerts are raised through
+  `ServiceRadar.Monitoring.AlertGenerator`, so they are stored and sent to
+  the webhooks of `ServiceRadar.Monitoring.WebhookNotifier`, with the
+  previous and current risk and the device identity.
+
+  Devices without a stored risk only record a baseline unless
+  `fire_on_first_seen` is set.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Monitoring.RiskAlerts,
+        enabled: true,
+        fire_on_first_seen: false,
+        rules: [
+          %{partition: "prod", tag: "critical", threshold: 80},
+          %{delta: 30}
+        ]
+  """
+
+  alias ServiceRadar.Monitoring.AlertGenerator
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @risk_metadata_keys ["armis_risk_level", "risk_level"]
+  @tag_metadata_keys ["armis_tags", "tags"]
+  @source_prefix "device_risk:"
+
+  @stored_risk_sql """
+  SELECT uid, metadata->>'armis_risk_level', metadata->>'risk_level', risk_score
+  FROM platform.ocsf_devices
+  WHERE uid = ANY($1)
+  """
+
+  @type rule :: %{
+          partition: String.t() | nil,
+          tag: String.t() | nil,
+          threshold: non_neg_integer(),
+          delta: non_neg_integer()
+        }
+
+  @type change :: %{
+          device_id: String.t(),
+          previous_risk: integer() | nil,
+          current_risk: integer(),
+          rule: rule(),
+          update: map()
+        }
+
+  @doc "Whether risk alerts are enabled and have valid rules."
+  @spec enabled?() :: boolean()
+  def enabled? do
+    config(:enabled, false) == true and match?({:ok, [_ | _]}, rules())
+  end
+
+  @doc "The configured rules, validated."
+  @spec rules() :: {:ok, [rule()]} | {:error, String.t()}
+  def rules, do: validate_rules(config(:rules, []))
+
+  @doc """
+  Normalizes rules. Each needs a positive `threshold` or `delta`; neither may
+  be negative.
+  """
+  @spec validate_rules([map() | keyword()]) :: {:ok, [rule()]} | {:error, String.t()}
+  def validate_rules(rules) when is_list(rules) do
+    rules
+    |> Enum.with_index()
+    |> Enum.reduce_while({:ok, []}, fn {rule, index}, {:ok, acc} ->
+      case validate_rule(Map.new(rule)) do
+        {:ok, rule} -> {:cont, {:ok, [rule | acc]}}
+        {:error, reason} -> {:halt, {:error, "rule #{index}: #{reason}"}}
+      end
+    end)
+    |> case do
+      {:ok, rules} -> {:ok, Enum.reverse(rules)}
+      error -> error
+    end
+  end
+
+  def validate_rules(_rules), do: {:error, "rules must be a list"}
+
+  @doc "The risk level carried by device metadata, or nil."
+  @spec risk_level(map() | nil) :: integer() | nil
+  def risk_level(metadata) when is_map(metadata) do
+    Enum.find_value(@risk_metadata_keys, &parse_risk(Map.get(metadata, &1)))
+  end
+
+  def risk_level(_metadata), do: nil
+
+  @doc """
+  Reads the risk stored for `device_ids`, before a batch overwrites it.
+  Devices without a stored risk are left out.
+  """
+  @spec stored_levels([String.t()]) :: {:ok, %{String.t() => integer()}} | {:error, term()}
+  def stored_levels([]), do: {:ok, %{}}
+
+  def stored_levels(device_ids) when is_list(device_ids) do
+    case Repo.query(@stored_risk_sql, [Enum.uniq(device_ids)]) do
+      {:ok, %{rows: rows}} ->
+        {:ok,
+         Enum.reduce(rows, %{}, fn [uid | values], acc ->
+           case Enum.find_value(values, &parse_risk/1) do
+             nil -> acc
+             level -> Map.put(acc, uid, level)
+           end
+         end)}
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  @doc """
+  Compares the risk of each device in `resolved_updates` (`{update,
+  device_id}` pairs) with `previous` and raises an alert for each device that
+  crossed its rule. Returns the changes alerted on.
+
+  Options:
+    - `:rules` - rules to apply (default configured)
+    - `:fire_on_first_seen` - alert on devices without a previous risk
+      (default configured, false)
+    - `:fire` - `fn change -> {:ok, alert} | {:error, reason} end` (default
+      raises an alert through `AlertGenerator`)
+  """
+  @spec check(%{String.t() => integer()}, [{map(), String.t()}], keyword()) :: [change()]
+  def check(previous, resolved_updates, opts \\ []) do
+    rules = Keyword.get_lazy(opts, :rules, fn -> configured_rules() end)
+    first_seen? = Keyword.get(opts, :fire_on_first_seen, config(:fire_on_first_seen, false))
+    fire = Keyword.get(opts, :fire, &fire/1)
+
+    resolved_updates
+    |> latest_by_device()
+    |> Enum.flat_map(fn {device_id, update} ->
+      with current when is_integer(current) <- risk_level(field(update, :metadata)),
+           previous_risk = Map.get(previous, device_id),
+           true <- not is_nil(previous_risk) or first_seen?,
+           %{} = rule <- Enum.find(rules, &matches?(&1, update)),
+           true <- crossed?(rule, previous_risk || 0, current) do
+        [
+          %{
+            device_id: device_id,
+            previous_risk: previous_risk,
+            current_risk: current,
+            rule: rule,
+            update: update
+          }
+        ]
+      else
+        _ -> []
+      end
+    end)
+    |> Enum.filter(fn change ->
+      case fire.(change) do
+        {:ok, _alert} ->
+          true
+
+        {:error, reason} ->
+          Logger.warning("Failed to raise risk alert for device #{change.device_id}",
+            reason: inspect(reason)
+          )
+
+          false
+      end
+    end)
+  end
+
+  @doc """
+  Whether a risk change from `previous` to `current` crosses `rule`: it rose
+  to or above the threshold from below it, or rose by at least the delta.
+  """
+  @spec crossed?(rule(), integer(), integer()) :: boolean()
+  def crossed?(rule, previous, current) when current > previous do
+    (rule.threshold > 0 and previous < rule.threshold and current >= rule.threshold) or
+      (rule.delta > 0 and current - previous >= rule.delta)
+  end
+
+  def crossed?(_rule, _previous, _current), do: false
+
+  defp validate_rule(rule) do
+    threshold = Map.get(rule, :threshold, Map.get(rule, "threshold", 0))
+    delta = Map.get(rule, :delta, Map.get(rule, "delta", 0))
+
+    cond do
+      not is_integer(threshold) or not is_integer(delta) ->
+        {:error, "threshold and delta must be integers"}
+
+      threshold < 0 or delta < 0 ->
+        {:error, "threshold and delta must be non-negative"}
+
+      threshold == 0 and delta == 0 ->
+        {:error, "a threshold or delta is required"}
+
+      true ->
+        {:ok,
+         %{
+           partition: blank_to_nil(Map.get(rule, :partition, Map.get(rule, "partition"))),
+           tag: blank_to_nil(Map.get(rule, :tag, Map.get(rule, "tag"))),
+           threshold: threshold,
+           delta: delta
+         }}
+    end
+  end
+
+  defp configured_rules do
+    case rules() do
+      {:ok, rules} ->
+        rules
+
+      {:error, reason} ->
+        Logger.warning("Ignoring invalid risk alert rules: #{reason}")
+        []
+    end
+  end
+
+  defp latest_by_device(resolved_updates) do
+    resolved_updates
+    |> Enum.reduce(%{}, fn {update, device_id}, acc ->
+      Map.update(acc, device_id, update, fn existing ->
+        if newer?(update, existing), do: update, else: existing
+      end)
+    end)
+    |> Enum.sort_by(&elem(&1, 0))
+  end
+
+  defp newer?(update, existing) do
+    case {field(update, :timestamp), field(existing, :timestamp)} do
+      {%DateTime{} = a, %DateTime{} = b} -> DateTime.compare(a, b) != :lt
+      _ -> true
+    end
+  end
+
+  defp matches?(rule, update) do
+    partition_matches?(rule.partition, field(update, :partition)) and
+      tag_matches?(rule.tag, update)
+  end
+
+  defp partition_matches?(nil, _partition), do: true
+
+  defp partition_matches?(expected, partition) do
+    String.downcase(expected) == String.downcase(to_string(partition || "default"))
+  end
+
+  defp tag_matches?(nil, _update), do: true
+
+  defp tag_matches?(tag, update) do
+    String.downcase(tag) in device_tags(update)
+  end
+
+  defp device_tags(update) do
+    metadata = field(update, :metadata) || %{}
+
+    listed =
+      Enum.flat_map(@tag_metadata_keys, fn key ->
+        case Map.get(metadata, key) do
+          value when is_binary(value) -> String.split(value, ",")
+          _ -> []
+        end
+      end)
+
+    keyed =
+      case field(update, :tags) do
+        tags when is_map(tags) -> Enum.map(tags, fn {key, _value} -> to_string(key) end)
+        _ -> []
+      end
+
+    (listed ++ keyed)
+    |> Enum.map(&(&1 |> String.trim() |> String.downcase()))
+    |> Enum.reject(&(&1 == ""))
+  end
+
+  defp fire(change) do
+    update = change.update
+    metadata = field(update, :metadata) || %{}
+
+    AlertGenerator.device_risk_increased(
+      source_id: @source_prefix <> change.device_id,
+      device_uid: change.device_id,
+      previous_risk: change.previous_risk,
+      current_risk: change.current_risk,
+      threshold: change.rule.threshold,
+      severity: severity(change),
+      ip: field(update, :ip),
+      partition: field(update, :partition),
+      details:
+        %{
+          "device_id" => change.device_id,
+          "hostname" => field(update, :hostname),
+          "mac" => field(update, :mac),
+          "source" => field(update, :source) && to_string(field(update, :source)),
+          "previous_risk" => change.previous_risk,
+          "current_risk" => change.current_risk,
+          "threshold" => change.rule.threshold,
+          "delta" => change.rule.delta,
+          "tags" => Map.get(metadata, "armis_tags") || Map.get(metadata, "tags")
+        }
+        |> Enum.reject(fn {_key, value} -> is_nil(value) end)
+        |> Map.new()
+    )
+  end
+
+  defp severity(%{rule: rule, current_risk: current}) do
+    if rule.threshold > 0 and current >= rule.threshold, do: :critical, else: :warning
+  end
+
+  defp parse_risk(value) when is_integer(value), do: value
+
+  defp parse_risk(value) when is_binary(value) do
+    case Integer.parse(String.trim(value)) do
+      {level, ""} -> level
+      _ -> nil
+    end
+  end
+
+  defp parse_risk(_value), do: nil
+
+  defp blank_to_nil(value) when is_binary(value) do
+    if String.trim(value) == "", do: nil, else: String.trim(value)
+  end
+
+  defp blank_to_nil(value) when is_atom(value) and not is_nil(value), do: Atom.to_string(value)
+  defp blank_to_nil(_value), do: nil
+
+  defp field(map, key) when is_map(map) do
+    case Map.get(map, key) do
+      nil -> Map.get(map, Atom.to_string(key))
+      value -> value
+    end
+  end
+
+  defp field(_map, _key), do: nil
+
+  defp config(key, default) do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
@@ -274,7 +274,18 @@ defmodule ServiceRadar.Monitoring.ServiceCheck do
     attribute :check_type, :atom do
       allow_nil? false
       public? true
-      constraints one_of: [:ping, :http, :tcp, :snmp, :grpc, :dns, :mtr, :custom]
+      constraints one_of: [
+                    :ping,
+                    :http,
+                    :tcp,
+                    :snmp,
+                    :grpc,
+                    :dns,
+                    :mtr,
+                    :composite,
+                    :transaction,
+                    :custom
+                  ]
       description "Type of check to perform"
     end
 
@@ -4,9 +4,13 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
 
   Port of Go core's alerts/webhook.go. Supports:
   - Configurable webhook endpoints
-  - Custom headers and templates
+  - Custom headers and per-channel templates (see
+    `ServiceRadar.Monitoring.WebhookTemplates`)
   - Cooldown to prevent alert storms
+  - Quiet hours holding non-critical alerts for a digest (see
+    `ServiceRadar.Monitoring.QuietHours`)
   - Node/service state tracking
+  - Replaying historical events, marked as replays (see `replay_alert/2`)
 
   ## Configuration
 
@@ -16,19 +20,39 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
         enabled: true,
         webhooks: [
           %{
+            name: "slack",
+            channel: "slack",
             url: "https://hooks.slack.com/...",
+            cooldown: :timer.minutes(5)
+          },
+          %{
+            name: "pagerduty",
+            channel: "pagerduty",
+            url: "https://events.pagerduty.com/v2/enqueue",
+            vars: %{routing_key: "..."}
+          },
+          %{
+            url: "https://example.com/alerts",
             headers: [%{key: "Authorization", value: "Bearer token"}],
             cooldown: :timer.minutes(5),
             template: nil  # Use default JSON payload
-          },
+          }
+        ],
+        quiet_hours: [
           %{
-            url: "https://discord.com/api/webhooks/...",
-            headers: [],
-            cooldown: :timer.minutes(5),
-            template: nil
+            name: "overnight",
+            start: "22:00",
+            end: "07:00",
+            timezone: "+01:00",
+            partitions: ["lab"]
           }
         ]
 
+  Webhooks whose template fails to compile are logged and left out. Invalid
+  quiet hours are logged and ignored. Alerts held during a quiet window are
+  sent as one "Quiet Hours Digest" alert when the window ends; the pending
+  digests are kept in memory.
+
   ## Usage
 
       # Send an alert
@@ -45,6 +69,9 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
 
   use GenServer
 
+  alias ServiceRadar.Monitoring.QuietHours
+  alias ServiceRadar.Monitoring.WebhookTemplates
+
   require Logger
 
   @default_timeout to_timeout(second: 10)
@@ -65,7 +92,8 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
             timestamp: String.t(),
             gateway_id: String.t(),
             service_name: String.t() | nil,
-            details: map()
+            details: map(),
+            replay: map() | nil
           }
 
     defstruct [
@@ -75,6 +103,7 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
       :timestamp,
       :gateway_id,
       :service_name,
+      :replay,
       details: %{}
     ]
   end
@@ -83,18 +112,28 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
   defmodule WebhookConfig do
     @moduledoc false
     @type t :: %__MODULE__{
+            name: String.t() | nil,
             url: String.t(),
             headers: [%{key: String.t(), value: String.t()}],
             cooldown: non_neg_integer(),
+            channel: String.t() | nil,
             template: String.t() | nil,
+            content_type: String.t() | nil,
+            vars: map(),
+            format: WebhookTemplates.compiled() | nil,
             enabled: boolean()
           }
 
     defstruct [
+      :name,
       :url,
+      :format,
       headers: [],
       cooldown: to_timeout(minute: 5),
+      channel: nil,
       template: nil,
+      content_type: nil,
+      vars: %{},
       enabled: true
     ]
   end
@@ -105,6 +144,9 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
     defstruct [
       :webhooks,
       :http_client,
+      clock: &DateTime.utc_now/0,
+      quiet_windows: [],
+      digests: %{},
       last_alert_times: %{},
       node_down_states: %{},
       service_alert_states: %{}
@@ -117,7 +159,7 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
   Start the webhook notifier GenServer.
   """
   def start_link(opts \\ []) do
-    GenServer.start_link(__MODULE__, opts, name: __MODULE__)
+    GenServer.start_link(__MODULE__, opts, name: Keyword.get(opts, :name, __MODULE__))
   end
 
   @doc """
@@ -134,6 +176,25 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
       {:error, :not_running}
   end
 
+  @doc """
+  Re-send an alert built from a historical event.
+
+  Replays skip cooldown and duplicate suppression and do not affect them, so
+  live alerts are unchanged by a replay. Set `alert.replay` so receivers can
+  tell the alert apart from a live one.
+
+  Options:
+    - `:channel` - only send to the webhook with this name or URL
+    - `:server` - notifier process (default: `#{inspect(__MODULE__)}`)
+  """
+  @spec replay_alert(Alert.t(), keyword()) :: :ok | {:error, term()}
+  def replay_alert(%Alert{} = alert, opts \\ []) do
+    server = Keyword.get(opts, :server, __MODULE__)
+    GenServer.call(server, {:replay_alert, alert, Keyword.get(opts, :channel)}, @default_timeout)
+  catch
+    :exit, {:noproc, _} -> {:error, :not_running}
+  end
+
   @doc """
   Check if the notifier is enabled and has configured webhooks.
   """
@@ -181,20 +242,32 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
     config = Application.get_env(:serviceradar_core, __MODULE__, [])
 
     webhooks =
-      config
-      |> Keyword.get(:webhooks, [])
+      opts
+      |> Keyword.get_lazy(:webhooks, fn -> Keyword.get(config, :webhooks, []) end)
       |> Enum.map(&build_webhook_config/1)
       |> Enum.filter(& &1.enabled)
+      |> Enum.flat_map(&compile_format/1)
 
     http_client = Keyword.get(opts, :http_client, Req)
 
+    quiet_windows =
+      opts
+      |> Keyword.get_lazy(:quiet_hours, fn -> Keyword.get(config, :quiet_hours, []) end)
+      |> parse_quiet_hours()
+
     if Enum.empty?(webhooks) do
       Logger.info("WebhookNotifier started with no configured webhooks")
     else
       Logger.info("WebhookNotifier started with #{length(webhooks)} webhook(s)")
     end
 
-    {:ok, %State{webhooks: webhooks, http_client: http_client}}
+    {:ok,
+     %State{
+       webhooks: webhooks,
+       http_client: http_client,
+       quiet_windows: quiet_windows,
+       clock: Keyword.get(opts, :clock, &DateTime.utc_now/0)
+     }}
   end
 
   @impl true
@@ -202,8 +275,38 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
     if Enum.empty?(state.webhooks) do
       {:reply, {:error, :no_webhooks_configured}, state}
     else
-      {result, new_state} = do_send_alert(alert, state)
-      {:reply, result, new_state}
+      alert = ensure_timestamp(alert)
+
+      case QuietHours.check(state.quiet_windows, alert, state.clock.()) do
+        {:hold, key, ends_at} ->
+          {:reply, :ok, hold_alert(alert, key, ends_at, state)}
+
+        :send ->
+          {result, new_state} = do_send_alert(alert, state)
+          {:reply, result, new_state}
+      end
+    end
+  end
+
+  @impl true
+  def handle_call({:replay_alert, alert, channel}, _from, state) do
+    case select_webhooks(state.webhooks, channel) do
+      [] when is_nil(channel) ->
+        {:reply, {:error, :no_webhooks_configured}, state}
+
+      [] ->
+        {:reply, {:error, :unknown_channel}, state}
+
+      webhooks ->
+        alert = ensure_timestamp(alert)
+
+        results = Enum.map(webhooks, &deliver(alert, &1, state.http_client))
+
+        if Enum.any?(results, &(&1 == :ok)) do
+          {:reply, :ok, state}
+        else
+          {:reply, {:error, results}, state}
+        end
     end
   end
 
@@ -218,7 +321,9 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
       webhook_count: length(state.webhooks),
       node_down_count: map_size(state.node_down_states),
       service_alert_count: map_size(state.service_alert_states),
-      cooldown_entries: map_size(state.last_alert_times)
+      cooldown_entries: map_size(state.last_alert_times),
+      held_alert_count:
+        Enum.reduce(state.digests, 0, fn {_key, digest}, acc -> acc + length(digest.alerts) end)
     }
 
     {:reply, stats, state}
@@ -238,6 +343,19 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
     {:noreply, %{state | service_alert_states: new_states}}
   end
 
+  @impl true
+  def handle_info({:send_digest, key}, state) do
+    case Map.pop(state.digests, key) do
+      {nil, _digests} ->
+        {:noreply, state}
+
+      {digest, digests} ->
+        alert = QuietHours.digest(key, digest.ends_at, Enum.reverse(digest.alerts))
+        Enum.each(state.webhooks, &deliver(alert, &1, state.http_client))
+        {:noreply, %{state | digests: digests}}
+    end
+  end
+
   @impl true
   def handle_info(_msg, state) do
     {:noreply, state}
@@ -247,20 +365,61 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
 
   defp build_webhook_config(config) when is_map(config) do
     %WebhookConfig{
+      name: Map.get(config, :name) || Map.get(config, "name"),
       url: Map.get(config, :url) || Map.get(config, "url"),
       headers: Map.get(config, :headers, []) || Map.get(config, "headers", []),
       cooldown:
         Map.get(config, :cooldown, @default_cooldown) ||
           Map.get(config, "cooldown", @default_cooldown),
+      channel: Map.get(config, :channel) || Map.get(config, "channel"),
       template: Map.get(config, :template) || Map.get(config, "template"),
+      content_type: Map.get(config, :content_type) || Map.get(config, "content_type"),
+      vars: Map.get(config, :vars) || Map.get(config, "vars") || %{},
       enabled: Map.get(config, :enabled, true)
     }
   end
 
-  defp do_send_alert(alert, state) do
-    # Ensure timestamp is set
-    alert = ensure_timestamp(alert)
+  defp compile_format(webhook) do
+    case WebhookTemplates.compile(Map.from_struct(webhook)) do
+      {:ok, format} ->
+        [%{webhook | format: format}]
 
+      {:error, reason} ->
+        Logger.error("Ignoring webhook #{webhook.name || webhook.url}: #{reason}")
+        []
+    end
+  end
+
+  defp parse_quiet_hours(configs) do
+    case QuietHours.parse_windows(configs) do
+      {:ok, windows} ->
+        windows
+
+      {:error, reason} ->
+        Logger.error("Ignoring quiet hours: #{reason}")
+        []
+    end
+  end
+
+  # The first alert held in a window occurrence schedules its digest for the
+  # end of the occurrence.
+  defp hold_alert(alert, key, ends_at, state) do
+    digest =
+      case Map.get(state.digests, key) do
+        nil ->
+          delay = max(DateTime.diff(ends_at, state.clock.(), :millisecond), 0)
+          Process.send_after(self(), {:send_digest, key}, delay)
+          %{ends_at: ends_at, alerts: [alert]}
+
+        digest ->
+          %{digest | alerts: [alert | digest.alerts]}
+      end
+
+    Logger.debug("Holding alert '#{alert.title}' for the quiet hours digest")
+    %{state | digests: Map.put(state.digests, key, digest)}
+  end
+
+  defp do_send_alert(alert, state) do
     # Check for duplicate "Node Offline" alert
     state =
       if alert.title == "Node Offline" do
@@ -301,6 +460,12 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
     {:duplicate, state} -> {:ok, state}
   end
 
+  defp select_webhooks(webhooks, nil), do: webhooks
+
+  defp select_webhooks(webhooks, channel) do
+    Enum.filter(webhooks, &(&1.name == channel or &1.url == channel))
+  end
+
   defp ensure_timestamp(%Alert{timestamp: nil} = alert) do
     %{alert | timestamp: DateTime.to_iso8601(DateTime.utc_now())}
   end
@@ -313,8 +478,7 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
 
     case check_cooldown(alert_key, webhook.cooldown, state.last_alert_times) do
       :ok ->
-        payload = prepare_payload(alert, webhook.template)
-        do_http_post(webhook.url, payload, webhook.headers, state.http_client)
+        deliver(alert, webhook, state.http_client)
 
       {:error, :cooldown} ->
         Logger.debug(
@@ -343,41 +507,29 @@ defmodule ServiceRadar.Monitoring.WebhookNotifier do
     end
   end
 
-  defp prepare_payload(alert, nil) do
-    # Default JSON payload
-    %{
-      level: to_string(alert.level),
-      title: alert.title,
-      message: alert.message,
-      timestamp: alert.timestamp,
-      gateway_id: alert.gateway_id,
-      service_name: alert.service_name,
-      details: alert.details
-    }
-    |> Enum.reject(fn {_k, v} -> is_nil(v) end)
-    |> Map.new()
-  end
+  defp deliver(alert, webhook, http_client) do
+    case WebhookTemplates.render(webhook.format, alert) do
+      {:ok, payload} ->
+        do_http_post(webhook, payload, webhook.format.content_type, http_client)
 
-  defp prepare_payload(alert, template) when is_binary(template) do
-    # Apply EEx template
-    result = EEx.eval_string(template, alert: alert)
-    Jason.decode!(result)
-  rescue
-    e ->
-      Logger.error("Template evaluation failed: #{inspect(e)}")
-      prepare_payload(alert, nil)
+      {:error, reason} ->
+        Logger.error("Template evaluation failed: #{reason}")
+        payload = {:json, WebhookTemplates.default_payload(alert)}
+        do_http_post(webhook, payload, "application/json", http_client)
+    end
   end
 
-  defp do_http_post(url, payload, headers, http_client) do
+  defp do_http_post(webhook, {kind, payload}, content_type, http_client) do
     headers_map =
-      headers
+      webhook.headers
       |> Map.new(fn h -> {h[:key] || h["key"], h[:value] || h["value"]} end)
-      |> Map.put_new("content-type", "application/json")
+      |> Map.put_new("content-type", content_type)
+
+    request_body = if kind == :json, do: [json: payload], else: [body: payload]
 
-    case http_client.post(url,
-           json: payload,
-           headers: headers_map,
-           receive_timeout: @default_timeout
+    case http_client.post(
+           webhook.url,
+           request_body ++ [headers: headers_map, receive_timeout: @default_timeout]
          ) do
       {:ok, %{status: status}} when status >= 200 and status < 300 ->
         :ok
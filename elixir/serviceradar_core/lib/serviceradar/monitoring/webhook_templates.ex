@@ -0,0 +1,266 @@
+defmodule ServiceRadar.Monitoring.WebhookTemplates do
+  @moduledoc """
+  Per-channel message formats for `ServiceRadar.Monitoring.WebhookNotifier`.
+
+  Each webhook has a channel type that selects a default body template and
+  content type:
+
+    - `generic` (default) - the alert as JSON
+    - `slack` - Slack message with markdown blocks
+    - `pagerduty` - PagerDuty Events v2 event; needs `vars.routing_key`
+    - `email` - HTML body, for mail relays that accept HTTP
+
+  A webhook's own `template` replaces the channel default. Templates are EEx
+  over `alert` (a `WebhookNotifier.Alert`) and `vars` (the webhook's `vars`
+  map, string keys), with the helpers of `Helpers` imported, for example:
+
+      {"text": <%= json(alert.title <> ": " <> alert.message) %>}
+
+  `compile/1` parses the template and renders a sample alert with it, so a
+  template that fails to parse, raises, or produces invalid JSON for a JSON
+  content type is rejected when the configuration is loaded rather than
+  when an alert fires.
+  """
+
+  alias ServiceRadar.Monitoring.WebhookNotifier.Alert
+
+  @content_type_json "application/json"
+  @content_type_html "text/html; charset=utf-8"
+
+  @slack_template ~S"""
+  <% headline = "#{level_emoji(alert.level)} *#{alert.title}*" %>
+  <% context = [upcase(alert.level), "gateway #{alert.gateway_id || "-"}", alert.timestamp] %>
+  {
+    "text": <%= json(headline) %>,
+    "blocks": [
+      {"type": "section",
+       "text": {"type": "mrkdwn", "text": <%= json(headline <> "\n" <> alert.message) %>}},
+      {"type": "context",
+       "elements": [{"type": "mrkdwn", "text": <%= json(Enum.join(context, " | ")) %>}]}
+    ]
+  }
+  """
+
+  @pagerduty_template ~S"""
+  {
+    "routing_key": <%= json(vars["routing_key"]) %>,
+    "event_action": "trigger",
+    "dedup_key": <%= json(Enum.join([alert.gateway_id, alert.service_name, alert.title], "|")) %>,
+    "payload": {
+      "summary": <%= json(alert.title) %>,
+      "source": <%= json(alert.gateway_id || "serviceradar") %>,
+      "severity": <%= json(pagerduty_severity(alert.level)) %>,
+      "timestamp": <%= json(alert.timestamp) %>,
+      "custom_details": {
+        "message": <%= json(alert.message) %>,
+        "details": <%= json(alert.details) %>,
+        "replay": <%= json(alert.replay) %>
+      }
+    }
+  }
+  """
+
+  @email_template ~S"""
+  <html>
+  <body>
+  <h2>[<%= upcase(alert.level) %>] <%= html(alert.title) %></h2>
+  <p><%= html(alert.message) %></p>
+  <table>
+  <tr><th align="left">Time</th><td><%= html(alert.timestamp) %></td></tr>
+  <%= if alert.gateway_id do %>
+  <tr><th align="left">Gateway</th><td><%= html(alert.gateway_id) %></td></tr>
+  <% end %>
+  <%= if alert.service_name do %>
+  <tr><th align="left">Service</th><td><%= html(alert.service_name) %></td></tr>
+  <% end %>
+  <%= for {key, value} <- Enum.sort(alert.details || %{}) do %>
+  <tr><th align="left"><%= html(key) %></th><td><%= html(value) %></td></tr>
+  <% end %>
+  </table>
+  </body>
+  </html>
+  """
+
+  @channels %{
+    "generic" => {nil, @content_type_json},
+    "slack" => {@slack_template, @content_type_json},
+    "pagerduty" => {@pagerduty_template, @content_type_json},
+    "email" => {@email_template, @content_type_html}
+  }
+
+  @type compiled :: %{
+          channel: String.t(),
+          content_type: String.t(),
+          vars: %{String.t() => String.t()},
+          quoted: Macro.t() | nil
+        }
+
+  defmodule Helpers do
+    @moduledoc """
+    Functions available in webhook templates.
+    """
+
+    @doc "Encodes a value as a JSON literal."
+    def json(value), do: Jason.encode!(value)
+
+    @doc "Escapes a value for HTML."
+    def html(nil), do: ""
+
+    def html(value) when is_binary(value) do
+      value
+      |> String.replace("&", "&amp;")
+      |> String.replace("<", "&lt;")
+      |> String.replace(">", "&gt;")
+      |> String.replace("\"", "&quot;")
+      |> String.replace("'", "&#39;")
+    end
+
+    def html(value) when is_map(value) or is_list(value), do: html(Jason.encode!(value))
+    def html(value), do: html(to_string(value))
+
+    @doc "Upper-cases a string or atom."
+    def upcase(nil), do: ""
+    def upcase(value), do: value |> to_string() |> String.upcase()
+
+    @doc "Slack emoji for an alert level."
+    def level_emoji(:error), do: ":red_circle:"
+    def level_emoji(:warning), do: ":large_yellow_circle:"
+    def level_emoji(:info), do: ":large_blue_circle:"
+    def level_emoji(_level), do: ":white_circle:"
+
+    @doc "PagerDuty Events v2 severity for an alert level."
+    def pagerduty_severity(:error), do: "critical"
+    def pagerduty_severity(:warning), do: "warning"
+    def pagerduty_severity(_level), do: "info"
+  end
+
+  @doc "Supported channel types."
+  @spec channels() :: [String.t()]
+  def channels, do: @channels |> Map.keys() |> Enum.sort()
+
+  @doc """
+  Compiles the message format of a webhook from its `channel`, `template`,
+  `content_type` and `vars`, and checks it against a sample alert.
+  """
+  @spec compile(map()) :: {:ok, compiled()} | {:error, String.t()}
+  def compile(config) when is_map(config) do
+    channel = config |> Map.get(:channel) |> normalize_channel()
+    vars = config |> Map.get(:vars) |> normalize_vars()
+
+    with {:ok, {default_template, default_content_type}} <- channel_defaults(channel),
+         :ok <- require_routing_key(channel, vars),
+         {:ok, quoted} <- parse(Map.get(config, :template) || default_template) do
+      compiled = %{
+        channel: channel,
+        content_type: Map.get(config, :content_type) || default_content_type,
+        vars: vars,
+        quoted: quoted
+      }
+
+      case render(compiled, sample_alert()) do
+        {:ok, _payload} -> {:ok, compiled}
+        {:error, reason} -> {:error, "#{channel} template: #{reason}"}
+      end
+    end
+  end
+
+  @doc """
+  Renders an alert for delivery: `{:json, map}` for JSON content types,
+  `{:body, binary}` otherwise.
+  """
+  @spec render(compiled(), Alert.t()) ::
+          {:ok, {:json, map()} | {:body, String.t()}} | {:error, String.t()}
+  def render(%{quoted: nil}, alert), do: {:ok, {:json, default_payload(alert)}}
+
+  def render(%{quoted: quoted, vars: vars, content_type: content_type}, alert) do
+    {body, _binding} = Code.eval_quoted(quoted, alert: alert, vars: vars)
+
+    if json_content_type?(content_type) do
+      case Jason.decode(body) do
+        {:ok, payload} -> {:ok, {:json, payload}}
+        {:error, _} -> {:error, "does not render valid JSON"}
+      end
+    else
+      {:ok, {:body, body}}
+    end
+  rescue
+    error -> {:error, Exception.message(error)}
+  end
+
+  @doc "The default JSON payload of an alert."
+  @spec default_payload(Alert.t()) :: map()
+  def default_payload(alert) do
+    %{
+      level: to_string(alert.level),
+      title: alert.title,
+      message: alert.message,
+      timestamp: alert.timestamp,
+      gateway_id: alert.gateway_id,
+      service_name: alert.service_name,
+      details: alert.details,
+      replay: alert.replay
+    }
+    |> Enum.reject(fn {_k, v} -> is_nil(v) end)
+    |> Map.new()
+  end
+
+  defp parse(nil), do: {:ok, nil}
+
+  defp parse(source) when is_binary(source) do
+    if String.trim(source) == "" do
+      {:ok, nil}
+    else
+      quoted = EEx.compile_string(source)
+
+      {:ok,
+       quote do
+         import ServiceRadar.Monitoring.WebhookTemplates.Helpers
+         unquote(quoted)
+       end}
+    end
+  rescue
+    error -> {:error, "template does not parse: #{Exception.message(error)}"}
+  end
+
+  defp parse(_source), do: {:error, "template must be a string"}
+
+  defp channel_defaults(channel) do
+    case Map.fetch(@channels, channel) do
+      {:ok, defaults} -> {:ok, defaults}
+      :error -> {:error, "unknown notification channel #{inspect(channel)}"}
+    end
+  end
+
+  defp require_routing_key("pagerduty", vars) do
+    if String.trim(Map.get(vars, "routing_key", "")) == "",
+      do: {:error, "pagerduty channel requires vars.routing_key"},
+      else: :ok
+  end
+
+  defp require_routing_key(_channel, _vars), do: :ok
+
+  defp normalize_channel(nil), do: "generic"
+
+  defp normalize_channel(channel),
+    do: channel |> to_string() |> String.trim() |> String.downcase()
+
+  defp normalize_vars(vars) when is_map(vars),
+    do: Map.new(vars, fn {key, value} -> {to_string(key), to_string(value)} end)
+
+  defp normalize_vars(_vars), do: %{}
+
+  defp json_content_type?(content_type),
+    do: String.starts_with?(content_type, @content_type_json)
+
+  defp sample_alert do
+    %Alert{
+      level: :warning,
+      title: "Device Risk Level Increased",
+      message: "Device sr:sample risk level rose from 40 to 85",
+      timestamp: "2026-01-01T00:00:00Z",
+      gateway_id: "gateway-1",
+      service_name: "sample",
+      details: %{"device_uid" => "sr:sample", "previous_risk" => 40, "current_risk" => 85}
+    }
+  end
+end
@@ -0,0 +1,346 @@
+defmodule ServiceRadar.NetworkDiscovery.PathTrace do
+  @moduledoc """
+  Traces the discovered layer-2/layer-3 path between two devices.
+
+  Reads the canonical topology (`CANONICAL_TOPOLOGY` edges written by
+  `ServiceRadar.NetworkDiscovery.TopologyGraph`) from the AGE graph and runs a
+  breadth-first search over it, treating links as bidirectional. Each returned
+  path is an ordered list of hops from the source to the destination device:
+
+      %{
+        device_id: "sr:switch-1",
+        ingress_interface: %{if_index: 3, if_name: "ge-0/0/3"},
+        egress_interface: %{if_index: 7, if_name: "ge-0/0/7"},
+        link: %{protocol: "lldp", relation_type: "CONNECTS_TO", ...}
+      }
+
+  The source hop has no ingress interface and the destination hop has no
+  egress interface or link. `link` describes the edge to the next hop.
+
+  By default only the shortest path is returned. Pass `max_paths: n` to return
+  up to `n` paths, shortest first; alternatives are found by letting each
+  device be visited at most `n` times, so the search stays bounded on dense
+  meshes.
+  """
+
+  alias ServiceRadar.Graph
+
+  @default_max_hops 16
+  @max_paths_limit 16
+  @ingestor "mapper_topology_v1"
+
+  @type interface :: %{if_index: pos_integer() | nil, if_name: String.t() | nil}
+
+  @type hop :: %{
+          device_id: String.t(),
+          ingress_interface: interface() | nil,
+          egress_interface: interface() | nil,
+          link: map() | nil
+        }
+
+  @type path :: %{hop_count: non_neg_integer(), hops: [hop()]}
+
+  @type result :: %{
+          source_device_id: String.t(),
+          destination_device_id: String.t(),
+          paths: [path()]
+        }
+
+  @doc """
+  Traces the path between two device IDs using the canonical topology graph.
+
+  Options:
+    - `:max_paths` - number of paths to return, shortest first (default 1)
+    - `:max_hops` - longest path, in links, to consider (default #{@default_max_hops})
+
+  Returns `{:error, :no_path}` when the devices are not connected.
+  """
+  @spec trace(String.t(), String.t(), keyword()) :: {:ok, result()} | {:error, term()}
+  def trace(source_device_id, destination_device_id, opts \\ [])
+
+  def trace(source_device_id, destination_device_id, opts)
+      when is_binary(source_device_id) and is_binary(destination_device_id) do
+    with {:ok, edges} <- fetch_edges(opts) do
+      find_paths(edges, source_device_id, destination_device_id, opts)
+    end
+  end
+
+  def trace(_, _, _), do: {:error, :invalid_arguments}
+
+  @doc """
+  Finds paths between two devices over a list of canonical edges without
+  touching the graph. Edges are maps with `src_id`, `dst_id` and the interface
+  and evidence properties of a `CANONICAL_TOPOLOGY` relationship.
+  """
+  @spec find_paths([map()], String.t(), String.t(), keyword()) ::
+          {:ok, result()} | {:error, term()}
+  def find_paths(edges, source_device_id, destination_device_id, opts \\ [])
+
+  def find_paths(edges, source_device_id, destination_device_id, opts)
+      when is_list(edges) and is_binary(source_device_id) and
+             is_binary(destination_device_id) do
+    max_paths = bounded_option(opts, :max_paths, 1, @max_paths_limit)
+    max_hops = bounded_option(opts, :max_hops, @default_max_hops, nil)
+    adjacency = build_adjacency(edges)
+
+    routes =
+      if source_device_id == destination_device_id do
+        [[source_device_id]]
+      else
+        search(adjacency, source_device_id, destination_device_id, max_paths, max_hops)
+      end
+
+    case routes do
+      [] ->
+        {:error, :no_path}
+
+      routes ->
+        {:ok,
+         %{
+           source_device_id: source_device_id,
+           destination_device_id: destination_device_id,
+           paths: Enum.map(routes, &build_path(&1, adjacency))
+         }}
+    end
+  end
+
+  def find_paths(_, _, _, _), do: {:error, :invalid_arguments}
+
+  ## Search
+
+  defp search(adjacency, source, destination, max_paths, max_hops) do
+    queue = :queue.from_list([[source]])
+    bfs(queue, adjacency, destination, max_paths, max_hops, %{source => 1}, [])
+  end
+
+  # Queue entries are reversed paths. A device may be expanded up to
+  # `max_paths` times so that alternative paths of equal or longer length
+  # survive; with `max_paths: 1` this is a plain BFS.
+  defp bfs(_queue, _adjacency, _destination, max_paths, _max_hops, _visits, found)
+       when length(found) >= max_paths,
+       do: Enum.reverse(found)
+
+  defp bfs(queue, adjacency, destination, max_paths, max_hops, visits, found) do
+    case :queue.out(queue) do
+      {:empty, _} ->
+        Enum.reverse(found)
+
+      {{:value, [current | _] = path}, rest} ->
+        {queue, visits, found} =
+          adjacency
+          |> neighbors(current)
+          |> Enum.reduce({rest, visits, found}, fn neighbor, {queue, visits, found} ->
+            cond do
+              length(found) >= max_paths or neighbor in path ->
+                {queue, visits, found}
+
+              neighbor == destination ->
+                {queue, visits, [Enum.reverse([neighbor | path]) | found]}
+
+              length(path) >= max_hops or Map.get(visits, neighbor, 0) >= max_paths ->
+                {queue, visits, found}
+
+              true ->
+                {:queue.in([neighbor | path], queue),
+                 Map.update(visits, neighbor, 1, &(&1 + 1)), found}
+            end
+          end)
+
+        bfs(queue, adjacency, destination, max_paths, max_hops, visits, found)
+    end
+  end
+
+  defp neighbors(adjacency, device_id) do
+    adjacency
+    |> Map.get(device_id, %{})
+    |> Map.keys()
+    |> Enum.sort()
+  end
+
+  # Adjacency is `%{device_id => %{neighbor_id => edge}}`. When several edges
+  # join the same pair of devices, the most confident one describes the hop.
+  defp build_adjacency(edges) do
+    edges
+    |> Enum.flat_map(&normalize_edge/1)
+    |> Enum.reduce(%{}, fn edge, acc ->
+      acc
+      |> put_edge(edge.src_id, edge.dst_id, edge)
+      |> put_edge(edge.dst_id, edge.src_id, edge)
+    end)
+  end
+
+  defp put_edge(adjacency, from, to, edge) do
+    Map.update(adjacency, from, %{to => edge}, fn peers ->
+      Map.update(peers, to, edge, fn existing ->
+        if edge.confidence_score > existing.confidence_score, do: edge, else: existing
+      end)
+    end)
+  end
+
+  defp normalize_edge(edge) when is_map(edge) do
+    src_id = map_value(edge, :src_id)
+    dst_id = map_value(edge, :dst_id)
+
+    if is_binary(src_id) and is_binary(dst_id) and src_id != dst_id do
+      [
+        %{
+          src_id: src_id,
+          dst_id: dst_id,
+          src_interface:
+            interface(
+              map_value(edge, :local_if_index_ab) || map_value(edge, :local_if_index),
+              map_value(edge, :local_if_name_ab) || map_value(edge, :local_if_name)
+            ),
+          dst_interface:
+            interface(
+              map_value(edge, :local_if_index_ba) || map_value(edge, :neighbor_if_index),
+              map_value(edge, :local_if_name_ba) || map_value(edge, :neighbor_if_name)
+            ),
+          protocol: map_value(edge, :protocol),
+          relation_type: map_value(edge, :relation_type),
+          evidence_class: map_value(edge, :evidence_class),
+          confidence_tier: map_value(edge, :confidence_tier),
+          confidence_score: parse_score(map_value(edge, :confidence_score)),
+          last_observed_at: map_value(edge, :last_observed_at)
+        }
+      ]
+    else
+      []
+    end
+  end
+
+  defp normalize_edge(_edge), do: []
+
+  ## Result building
+
+  defp build_path(device_ids, adjacency) do
+    links =
+      device_ids
+      |> Enum.chunk_every(2, 1, :discard)
+      |> Enum.map(fn [from, to] -> oriented_link(adjacency[from][to], from) end)
+
+    hops =
+      device_ids
+      |> Enum.with_index()
+      |> Enum.map(fn {device_id, index} ->
+        incoming = if index > 0, do: Enum.at(links, index - 1)
+        outgoing = Enum.at(links, index)
+
+        %{
+          device_id: device_id,
+          ingress_interface: incoming && incoming.to_interface,
+          egress_interface: outgoing && outgoing.from_interface,
+          link: outgoing && outgoing.link
+        }
+      end)
+
+    %{hop_count: length(links), hops: hops}
+  end
+
+  # Orients the stored edge in the direction of travel.
+  defp oriented_link(edge, from) do
+    {from_interface, to_interface} =
+      if edge.src_id == from,
+        do: {edge.src_interface, edge.dst_interface},
+        else: {edge.dst_interface, edge.src_interface}
+
+    %{
+      from_interface: from_interface,
+      to_interface: to_interface,
+      link:
+        Map.take(edge, [
+          :protocol,
+          :relation_type,
+          :evidence_class,
+          :confidence_tier,
+          :confidence_score,
+          :last_observed_at
+        ])
+    }
+  end
+
+  defp interface(if_index, if_name) do
+    case {parse_ifindex(if_index), normalize_name(if_name)} do
+      {nil, nil} -> nil
+      {index, name} -> %{if_index: index, if_name: name}
+    end
+  end
+
+  ## Graph access
+
+  defp fetch_edges(opts) do
+    cypher = """
+    MATCH (a:Device)-[r:CANONICAL_TOPOLOGY]->(b:Device)
+    WHERE r.ingestor = '#{@ingestor}'
+      AND a.id IS NOT NULL
+      AND b.id IS NOT NULL
+    RETURN {
+      src_id: a.id,
+      dst_id: b.id,
+      protocol: r.protocol,
+      relation_type: r.relation_type,
+      evidence_class: r.evidence_class,
+      confidence_tier: r.confidence_tier,
+      confidence_score: r.confidence_score,
+      last_observed_at: r.last_observed_at,
+      local_if_index_ab: coalesce(r.local_if_index_ab, r.local_if_index),
+      local_if_name_ab: coalesce(r.local_if_name_ab, r.local_if_name),
+      local_if_index_ba: coalesce(r.local_if_index_ba, r.neighbor_if_index),
+      local_if_name_ba: coalesce(r.local_if_name_ba, r.neighbor_if_name)
+    }
+    """
+
+    case Graph.query(cypher, Keyword.take(opts, [:graph, :repo])) do
+      {:ok, rows} when is_list(rows) -> {:ok, rows}
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  ## Helpers
+
+  defp bounded_option(opts, key, default, limit) do
+    case Keyword.get(opts, key) do
+      value when is_integer(value) and value > 0 and is_nil(limit) -> value
+      value when is_integer(value) and value > 0 -> min(value, limit)
+      _ -> default
+    end
+  end
+
+  defp parse_ifindex(value) when is_integer(value) and value > 0, do: value
+
+  defp parse_ifindex(value) when is_binary(value) do
+    case Integer.parse(String.trim(value)) do
+      {parsed, _} when parsed > 0 -> parsed
+      _ -> nil
+    end
+  end
+
+  defp parse_ifindex(_), do: nil
+
+  defp normalize_name(value) when is_binary(value) do
+    case String.trim(value) do
+      "" -> nil
+      name -> name
+    end
+  end
+
+  defp normalize_name(_), do: nil
+
+  defp parse_score(value) when is_number(value), do: value
+
+  defp parse_score(value) when is_binary(value) do
+    case Float.parse(value) do
+      {parsed, _} -> parsed
+      :error -> 0
+    end
+  end
+
+  defp parse_score(_), do: 0
+
+  defp map_value(map, key) do
+    case Map.get(map, key) do
+      nil -> Map.get(map, to_string(key))
+      value -> value
+    end
+  end
+end
@@ -14,7 +14,8 @@ defmodule ServiceRadar.Observability do
   - `ServiceRadar.Observability.TimeseriesMetric` - Generic time-series metrics
   - `ServiceRadar.Observability.CpuMetric` - CPU utilization metrics
   - `ServiceRadar.Observability.MemoryMetric` - Memory usage metrics
-  - `ServiceRadar.Observability.DiskMetric` - Disk usage metrics
+  - `ServiceRadar.Observability.DiskMetric` - Disk usage and I/O metrics
+  - `ServiceRadar.Observability.NetworkMetric` - Network interface throughput metrics
   - `ServiceRadar.Observability.OtelTraceSummary` - OpenTelemetry trace summaries
 
   ## TimescaleDB Integration
@@ -44,6 +45,7 @@ defmodule ServiceRadar.Observability do
     resource ServiceRadar.Observability.StatefulAlertRuleTemplate
     resource ServiceRadar.Observability.StatefulAlertRuleState
     resource ServiceRadar.Observability.StatefulAlertRuleHistory
+    resource ServiceRadar.Observability.MetricAlertRule
     resource ServiceRadar.Observability.IpGeoEnrichmentCache
     resource ServiceRadar.Observability.IpRdnsCache
     resource ServiceRadar.Observability.IpIpinfoCache
@@ -57,6 +59,7 @@ defmodule ServiceRadar.Observability do
     resource ServiceRadar.Observability.NetflowPortAnomalyFlag
     resource ServiceRadar.Observability.NetflowSettings
     resource ServiceRadar.Observability.BmpSettings
+    resource ServiceRadar.Observability.PartitionRetentionPolicy
     resource ServiceRadar.Observability.NetflowLocalCidr
     resource ServiceRadar.Observability.NetflowAppClassificationRule
     resource ServiceRadar.Observability.NetflowExporterCache
@@ -77,6 +80,7 @@ defmodule ServiceRadar.Observability do
     resource ServiceRadar.Observability.MemoryMetricHourly
     resource ServiceRadar.Observability.DiskMetric
     resource ServiceRadar.Observability.DiskMetricHourly
+    resource ServiceRadar.Observability.NetworkMetric
     resource ServiceRadar.Observability.ProcessMetric
     resource ServiceRadar.Observability.ProcessMetricHourly
     resource ServiceRadar.Observability.TimeseriesMetricHourly
@@ -90,6 +94,8 @@ defmodule ServiceRadar.Observability do
     resource ServiceRadar.Observability.OtelMetric
     resource ServiceRadar.Observability.OtelTrace
     resource ServiceRadar.Observability.OtelTraceSummary
+    resource ServiceRadar.Observability.ScheduledReport
+    resource ServiceRadar.Observability.SRQLView
   end
 
   authorization do
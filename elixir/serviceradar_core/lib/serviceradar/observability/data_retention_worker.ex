@@ -1,6 +1,7 @@
 defmodule ServiceRadar.Observability.DataRetentionWorker do
   @moduledoc """
-  Recurring cleanup for high-volume observability tables that are not Timescale hypertables.
+  Recurring cleanup for high-volume observability tables that are not Timescale hypertables,
+  plus per-partition retention windows (`ServiceRadar.Observability.PartitionRetention`).
   """
 
   use Oban.Worker,
@@ -9,6 +10,7 @@ defmodule ServiceRadar.Observability.DataRetentionWorker do
     unique: [period: 3_600, states: [:available, :scheduled, :executing, :retryable]]
 
   alias Ecto.Adapters.SQL
+  alias ServiceRadar.Observability.PartitionRetention
   alias ServiceRadar.Repo
 
   require Logger
@@ -38,7 +40,8 @@ defmodule ServiceRadar.Observability.DataRetentionWorker do
         batch_size
       ),
       prune_inactive_dataset_snapshots("netflow_oui_dataset_snapshots", config, batch_size),
-      prune_mapper_topology_links(config, batch_size)
+      prune_mapper_topology_links(config, batch_size),
+      prune_partitions()
     ]
 
     deleted = Enum.sum(results)
@@ -142,6 +145,12 @@ defmodule ServiceRadar.Observability.DataRetentionWorker do
     )
   end
 
+  defp prune_partitions do
+    PartitionRetention.enforce()
+    |> Map.values()
+    |> Enum.sum()
+  end
+
   defp prune_by_timestamp(
          table_name,
          key_column,
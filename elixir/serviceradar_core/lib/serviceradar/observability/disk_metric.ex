@@ -1,6 +1,10 @@
 defmodule ServiceRadar.Observability.DiskMetric do
   @moduledoc """
-  Disk utilization metric resource.
+  Disk utilization and I/O metric resource.
+
+  The I/O columns hold the counters of the block device backing the mount and
+  the per-second rates the agent derived from them. They are null when disk
+  I/O collection is disabled or the platform has no counters for the device.
 
   Maps to the `disk_metrics` TimescaleDB hypertable. This table is managed by raw SQL
   migrations that match the Go schema exactly.
@@ -41,6 +45,14 @@ defmodule ServiceRadar.Observability.DiskMetric do
         :used_bytes,
         :available_bytes,
         :usage_percent,
+        :read_bytes,
+        :write_bytes,
+        :read_ops,
+        :write_ops,
+        :read_bytes_per_sec,
+        :write_bytes_per_sec,
+        :read_ops_per_sec,
+        :write_ops_per_sec,
         :device_id,
         :partition,
         :created_at
@@ -101,6 +113,46 @@ defmodule ServiceRadar.Observability.DiskMetric do
       description "Disk usage percentage"
     end
 
+    attribute :read_bytes, :integer do
+      public? true
+      description "Total bytes read from the block device"
+    end
+
+    attribute :write_bytes, :integer do
+      public? true
+      description "Total bytes written to the block device"
+    end
+
+    attribute :read_ops, :integer do
+      public? true
+      description "Total completed reads on the block device"
+    end
+
+    attribute :write_ops, :integer do
+      public? true
+      description "Total completed writes on the block device"
+    end
+
+    attribute :read_bytes_per_sec, :float do
+      public? true
+      description "Read throughput since the previous sample"
+    end
+
+    attribute :write_bytes_per_sec, :float do
+      public? true
+      description "Write throughput since the previous sample"
+    end
+
+    attribute :read_ops_per_sec, :float do
+      public? true
+      description "Read operations per second since the previous sample"
+    end
+
+    attribute :write_ops_per_sec, :float do
+      public? true
+      description "Write operations per second since the previous sample"
+    end
+
     attribute :device_id, :string do
       public? true
       description "Device identifier"
@@ -72,6 +72,45 @@ defmodule ServiceRadar.Observability.GeoIP do
 
   def lookup(_), do: {:error, :invalid_ip}
 
+  @doc """
+  Normalizes a raw MMDB record (string keys, as decoded by `MMDB2Decoder`) into
+  the same shape as `lookup/1`.
+  """
+  @spec normalize_record(map()) :: geo_asn()
+  def normalize_record(%{} = record) do
+    record
+    |> normalize_asn()
+    |> Map.merge(normalize_city(record))
+    |> Map.merge(normalize_country(record))
+  end
+
+  @doc """
+  Returns true for private, loopback, link-local and unspecified addresses,
+  which have no meaningful geolocation.
+  """
+  @spec private_ip?(String.t() | :inet.ip_address()) :: boolean()
+  def private_ip?(ip) when is_binary(ip) do
+    case parse_ip(ip) do
+      {:ok, tuple} -> private_ip?(tuple)
+      {:error, _} -> false
+    end
+  end
+
+  def private_ip?({10, _, _, _}), do: true
+  def private_ip?({127, _, _, _}), do: true
+  def private_ip?({169, 254, _, _}), do: true
+  def private_ip?({192, 168, _, _}), do: true
+  def private_ip?({172, b, _, _}) when b in 16..31, do: true
+  def private_ip?({0, _, _, _}), do: true
+  def private_ip?({_, _, _, _}), do: false
+
+  def private_ip?({0, 0, 0, 0, 0, 0, 0, 1}), do: true
+  # fc00::/7 (unique local addresses)
+  def private_ip?({a, _, _, _, _, _, _, _}) when a in 0xFC00..0xFDFF, do: true
+  # fe80::/10 (link-local)
+  def private_ip?({a, _, _, _, _, _, _, _}) when a in 0xFE80..0xFEBF, do: true
+  def private_ip?(_), do: false
+
   defp load_all_databases do
     with {:ok, _} <- ensure_started(:geolix),
          {:ok, _} <- ensure_started(:mmdb2_decoder) do
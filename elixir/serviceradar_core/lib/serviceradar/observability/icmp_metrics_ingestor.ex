@@ -21,6 +21,7 @@ defmodule ServiceRadar.Observability.IcmpMetricsIngestor do
   alias ServiceRadar.Actors.SystemActor
   alias ServiceRadar.EventWriter.FieldParser
   alias ServiceRadar.Identity.DeviceLookup
+  alias ServiceRadar.Observability.MetricDerivation
   alias ServiceRadar.Observability.TimeseriesMetric
   alias ServiceRadar.Observability.TimeseriesSeriesKey
 
@@ -37,6 +38,7 @@ defmodule ServiceRadar.Observability.IcmpMetricsIngestor do
       |> Enum.map(&build_metric_row(&1, status, actor, created_at))
       |> Enum.reject(&is_nil/1)
       |> TimeseriesSeriesKey.dedupe_rows()
+      |> MetricDerivation.append_derived()
 
     if Enum.empty?(rows) do
       :ok
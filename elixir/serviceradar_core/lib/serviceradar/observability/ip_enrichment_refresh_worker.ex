@@ -231,7 +231,7 @@ defmodule ServiceRadar.Observability.IpEnrichmentRefreshWorker do
   end
 
   defp geo_lookup(ip) when is_binary(ip) do
-    if private_ip?(ip) do
+    if GeoIP.private_ip?(ip) do
       {%{is_private: true}, "private", nil}
     else
       case GeoIP.lookup(ip) do
@@ -356,7 +356,7 @@ defmodule ServiceRadar.Observability.IpEnrichmentRefreshWorker do
   end
 
   defp ipinfo_enabled_for_ip?(%NetflowSettings{} = settings, ip) when is_binary(ip) do
-    settings.ipinfo_enabled and IpInfo.available?() and not private_ip?(ip)
+    settings.ipinfo_enabled and IpInfo.available?() and not GeoIP.private_ip?(ip)
   end
 
   defp upsert_ipinfo_cache(ip, attrs, err, actor, now, expires_at) when is_binary(ip) do
@@ -452,37 +452,4 @@ defmodule ServiceRadar.Observability.IpEnrichmentRefreshWorker do
       {:error, _} -> {:error, "invalid_ip"}
     end
   end
-
-  defp private_ip?(ip) when is_binary(ip) do
-    ip = normalize_ip_string(ip)
-
-    case :inet.parse_address(String.to_charlist(ip)) do
-      {:ok, tuple} -> private_ip_tuple?(tuple)
-      {:error, _} -> false
-    end
-  end
-
-  defp normalize_ip_string(ip) when is_binary(ip) do
-    ip
-    |> String.trim()
-    |> String.split("/", parts: 2)
-    |> List.first()
-    |> to_string()
-    |> String.trim()
-  end
-
-  defp private_ip_tuple?({10, _, _, _}), do: true
-  defp private_ip_tuple?({127, _, _, _}), do: true
-  defp private_ip_tuple?({169, 254, _, _}), do: true
-  defp private_ip_tuple?({192, 168, _, _}), do: true
-  defp private_ip_tuple?({172, b, _, _}) when b in 16..31, do: true
-  defp private_ip_tuple?({0, _, _, _}), do: true
-  defp private_ip_tuple?({_, _, _, _}), do: false
-
-  defp private_ip_tuple?({0, 0, 0, 0, 0, 0, 0, 1}), do: true
-  # fc00::/7 (unique local addresses)
-  defp private_ip_tuple?({a, _, _, _, _, _, _, _}) when a in 0xFC00..0xFDFF, do: true
-  # fe80::/10 (link-local)
-  defp private_ip_tuple?({a, _, _, _, _, _, _, _}) when a in 0xFE80..0xFEBF, do: true
-  defp private_ip_tuple?(_), do: false
 end
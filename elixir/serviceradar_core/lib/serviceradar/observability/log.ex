@@ -42,7 +42,10 @@ defmodule ServiceRadar.Observability.Log do
     :scope_version,
     :scope_attributes,
     :attributes,
-    :resource_attributes
+    :resource_attributes,
+    :device_id,
+    :device_partition,
+    :device_tags
   ]
 
   postgres do
@@ -216,6 +219,22 @@ defmodule ServiceRadar.Observability.Log do
       description "Resource attributes"
     end
 
+    # Device context (set by db-event-writer log enrichment)
+    attribute :device_id, :string do
+      public? true
+      description "Device the log's source host resolved to"
+    end
+
+    attribute :device_partition, :string do
+      public? true
+      description "Partition of the resolved device"
+    end
+
+    attribute :device_tags, :map do
+      public? true
+      description "Selected tags of the resolved device"
+    end
+
     # Timestamps
     create_timestamp :created_at
   end
@@ -0,0 +1,162 @@
+defmodule ServiceRadar.Observability.MetricAlertRule do
+  @moduledoc """
+  Declarative alert rule over a metrics SRQL query.
+
+  The rule's query selects metric samples (e.g. `in:cpu_metrics
+  partition:site-a`), `value_field` names the sampled column and `group_by`
+  the column samples are grouped by, usually `device_id`. A group fires when
+  its samples have compared true against `threshold` for `duration_seconds`,
+  and clears once its latest sample no longer does. See
+  `ServiceRadar.Observability.MetricAlertRules` for the evaluation.
+  """
+
+  use Ash.Resource,
+    domain: ServiceRadar.Observability,
+    data_layer: AshPostgres.DataLayer,
+    authorizers: [Ash.Policy.Authorizer]
+
+  @rule_fields [
+    :name,
+    :description,
+    :enabled,
+    :query,
+    :value_field,
+    :group_by,
+    :comparison,
+    :threshold,
+    :duration_seconds,
+    :severity
+  ]
+
+  postgres do
+    table "metric_alert_rules"
+    repo ServiceRadar.Repo
+    schema "platform"
+    migrate? false
+  end
+
+  code_interface do
+    define :list, action: :read
+    define :list_enabled, action: :enabled
+    define :get_by_id, action: :read, get_by: [:id]
+    define :create, action: :create
+    define :update, action: :update
+    define :destroy, action: :destroy
+  end
+
+  actions do
+    defaults [:read, :destroy]
+
+    read :enabled do
+      description "Rules the evaluator runs"
+      filter expr(enabled == true)
+    end
+
+    create :create do
+      accept @rule_fields
+      validate fn changeset, _context ->
+        validate_query(Ash.Changeset.get_attribute(changeset, :query))
+      end
+    end
+
+    update :update do
+      require_atomic? false
+      accept @rule_fields
+      validate fn changeset, _context ->
+        validate_query(Ash.Changeset.get_attribute(changeset, :query))
+      end
+    end
+  end
+
+  policies do
+    import ServiceRadar.Policies
+
+    system_bypass()
+    read_viewer_plus()
+    operator_action([:create, :update, :destroy])
+  end
+
+  attributes do
+    uuid_primary_key :id
+
+    attribute :name, :string do
+      allow_nil? false
+      public? true
+      constraints trim?: true, allow_empty?: false
+    end
+
+    attribute :description, :string do
+      public? true
+    end
+
+    attribute :enabled, :boolean do
+      allow_nil? false
+      default true
+      public? true
+    end
+
+    attribute :query, :string do
+      allow_nil? false
+      public? true
+      constraints trim?: true, allow_empty?: false
+      description "SRQL query selecting the metric samples, without time:"
+    end
+
+    attribute :value_field, :string do
+      allow_nil? false
+      public? true
+      constraints trim?: true, allow_empty?: false
+      description "Column holding the sampled value, e.g. usage_percent"
+    end
+
+    attribute :group_by, :string do
+      allow_nil? false
+      default "device_id"
+      public? true
+      constraints trim?: true, allow_empty?: false
+      description "Column samples are grouped by; each group alerts separately"
+    end
+
+    attribute :comparison, :atom do
+      allow_nil? false
+      default :gt
+      public? true
+      constraints one_of: [:gt, :gte, :lt, :lte]
+    end
+
+    attribute :threshold, :float do
+      allow_nil? false
+      public? true
+    end
+
+    attribute :duration_seconds, :integer do
+      allow_nil? false
+      default 300
+      public? true
+      constraints min: 0, max: 86_400
+      description "How long the condition must hold before the rule fires"
+    end
+
+    attribute :severity, :atom do
+      allow_nil? false
+      default :warning
+      public? true
+      constraints one_of: [:info, :warning, :critical, :emergency]
+    end
+
+    create_timestamp :inserted_at
+    update_timestamp :updated_at
+  end
+
+  identities do
+    identity :unique_name, [:name]
+  end
+
+  defp validate_query(query) do
+    if is_binary(query) and Regex.match?(~r/(^|\s)time:/, query) do
+      {:error, field: :query, message: "must not set time:, the evaluator adds its own window"}
+    else
+      :ok
+    end
+  end
+end
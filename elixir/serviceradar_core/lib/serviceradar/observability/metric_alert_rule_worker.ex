@@ -0,0 +1,30 @@
+defmodule ServiceRadar.Observability.MetricAlertRuleWorker do
+  @moduledoc """
+  Oban cron worker that evaluates enabled metric alert rules. Scheduled every
+  minute from the core runtime config.
+  """
+
+  use Oban.Worker,
+    queue: :monitoring,
+    max_attempts: 1,
+    unique: [period: 55, states: [:available, :scheduled, :executing]]
+
+  alias ServiceRadar.Observability.MetricAlertRules
+
+  require Logger
+
+  @impl Oban.Worker
+  def perform(%Oban.Job{}) do
+    case MetricAlertRules.run_all() do
+      {:ok, %{fired: 0, resolved: 0}} ->
+        :ok
+
+      {:ok, %{fired: fired, resolved: resolved}} ->
+        Logger.info("MetricAlertRuleWorker: fired #{fired}, resolved #{resolved} alert(s)")
+        :ok
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+end
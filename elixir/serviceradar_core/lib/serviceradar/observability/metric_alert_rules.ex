@@ -0,0 +1,274 @@
+defmodule ServiceRadar.Observability.MetricAlertRules do
+  @moduledoc """
+  Evaluates metric alert rules (`ServiceRadar.Observability.MetricAlertRule`).
+
+  Each run reads the rule's SRQL query over the last `duration_seconds` plus
+  a two-minute margin, groups the samples by `group_by` and compares each
+  sample's `value_field` against the threshold. A group is:
+
+    * `:firing` - its samples have breached the threshold without a break for
+      at least `duration_seconds`
+    * `:pending` - its latest samples breach, but not for long enough yet
+    * `:ok` - its latest sample does not breach
+
+  A firing group without an open alert raises one (through
+  `ServiceRadar.Monitoring.AlertGenerator`, so webhooks are notified); an
+  open alert whose group is back to `:ok` is resolved. A group with no
+  samples in the window keeps its alert open, since missing data is not a
+  recovery. Open alerts are found by their source ID,
+  `metric_alert_rule:<rule id>:<group>`, so evaluation needs no state of its
+  own between runs.
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Monitoring.Alert
+  alias ServiceRadar.Monitoring.AlertGenerator
+  alias ServiceRadar.Observability.MetricAlertRule
+  alias ServiceRadar.Observability.SRQLRunner
+
+  require Ash.Query
+  require Logger
+
+  @time_field "timestamp"
+  @sample_margin_seconds 120
+  @page_size 1_000
+  @max_samples 20_000
+
+  @type group_state :: %{
+          state: :ok | :pending | :firing,
+          value: float(),
+          since: DateTime.t() | nil
+        }
+
+  @type summary :: %{fired: non_neg_integer(), resolved: non_neg_integer()}
+
+  @doc """
+  Evaluates every enabled rule. A rule that fails to evaluate is logged and
+  skipped.
+
+  Options are passed to `run_rule/2`.
+  """
+  @spec run_all(keyword()) :: {:ok, summary()} | {:error, term()}
+  def run_all(opts \\ []) do
+    opts = Keyword.put_new_lazy(opts, :actor, fn -> SystemActor.system(:metric_alert_rules) end)
+
+    with {:ok, rules} <- MetricAlertRule.list_enabled(actor: opts[:actor]) do
+      {:ok,
+       Enum.reduce(rules, %{fired: 0, resolved: 0}, fn rule, acc ->
+         case run_rule(rule, opts) do
+           {:ok, result} ->
+             %{fired: acc.fired + result.fired, resolved: acc.resolved + result.resolved}
+
+           {:error, reason} ->
+             Logger.warning("Metric alert rule #{rule.name} failed to evaluate",
+               reason: inspect(reason)
+             )
+
+             acc
+         end
+       end)}
+    end
+  end
+
+  @doc """
+  Evaluates one rule, raising alerts for newly firing groups and resolving
+  the alerts of recovered ones.
+
+  Options:
+    - `:now` - evaluation time (default now)
+    - `:actor` - actor alerts are read and written as (default system actor)
+    - `:runner_opts` - extra options for `SRQLRunner.query_page/2`
+  """
+  @spec run_rule(MetricAlertRule.t(), keyword()) :: {:ok, summary()} | {:error, term()}
+  def run_rule(rule, opts \\ []) do
+    now = Keyword.get_lazy(opts, :now, &DateTime.utc_now/0)
+    actor = Keyword.get_lazy(opts, :actor, fn -> SystemActor.system(:metric_alert_rules) end)
+
+    with {:ok, rows} <- fetch_samples(rule, now, Keyword.get(opts, :runner_opts, [])),
+         {:ok, open} <- open_alerts(rule, actor) do
+      groups = evaluate(rule, rows, now)
+
+      fired =
+        Enum.count(groups, fn {group, group_state} ->
+          group_state.state == :firing and not Map.has_key?(open, group) and
+            fire(rule, group, group_state, actor) == :ok
+        end)
+
+      resolved =
+        Enum.count(open, fn {group, alert} ->
+          match?(%{state: :ok}, Map.get(groups, group)) and
+            resolve(rule, alert, groups[group], actor) == :ok
+        end)
+
+      {:ok, %{fired: fired, resolved: resolved}}
+    end
+  end
+
+  @doc """
+  Computes the state of every group in `rows` at `now`. Rows without a
+  group, timestamp or numeric value are ignored.
+  """
+  @spec evaluate(MetricAlertRule.t(), [map()], DateTime.t()) :: %{
+          optional(String.t()) => group_state()
+        }
+  def evaluate(rule, rows, now) do
+    rows
+    |> Enum.flat_map(&sample(rule, &1))
+    |> Enum.group_by(&elem(&1, 0), &{elem(&1, 1), elem(&1, 2)})
+    |> Map.new(fn {group, samples} -> {group, group_state(rule, samples, now)} end)
+  end
+
+  defp group_state(rule, samples, now) do
+    [{_time, latest} | _] = sorted = Enum.sort_by(samples, &elem(&1, 0), {:desc, DateTime})
+    streak = Enum.take_while(sorted, fn {_time, value} -> breaches?(rule, value) end)
+
+    case List.last(streak) do
+      nil ->
+        %{state: :ok, value: latest, since: nil}
+
+      {since, _value} ->
+        state = if DateTime.diff(now, since) >= rule.duration_seconds, do: :firing, else: :pending
+        %{state: state, value: latest, since: since}
+    end
+  end
+
+  defp breaches?(%{comparison: :gt, threshold: threshold}, value), do: value > threshold
+  defp breaches?(%{comparison: :gte, threshold: threshold}, value), do: value >= threshold
+  defp breaches?(%{comparison: :lt, threshold: threshold}, value), do: value < threshold
+  defp breaches?(%{comparison: :lte, threshold: threshold}, value), do: value <= threshold
+
+  defp sample(rule, row) do
+    with group when not is_nil(group) <- Map.get(row, rule.group_by),
+         %DateTime{} = time <- to_datetime(Map.get(row, @time_field)),
+         value when is_float(value) <- to_float(Map.get(row, rule.value_field)) do
+      [{to_string(group), time, value}]
+    else
+      _ -> []
+    end
+  end
+
+  defp to_datetime(%DateTime{} = time), do: time
+  defp to_datetime(%NaiveDateTime{} = time), do: DateTime.from_naive!(time, "Etc/UTC")
+
+  defp to_datetime(time) when is_binary(time) do
+    case DateTime.from_iso8601(time) do
+      {:ok, datetime, _offset} -> datetime
+      _ -> nil
+    end
+  end
+
+  defp to_datetime(_time), do: nil
+
+  defp to_float(value) when is_float(value), do: value
+  defp to_float(value) when is_integer(value), do: value / 1
+  defp to_float(%Decimal{} = value), do: Decimal.to_float(value)
+  defp to_float(_value), do: nil
+
+  defp fetch_samples(rule, now, runner_opts) do
+    from = DateTime.add(now, -(rule.duration_seconds + @sample_margin_seconds), :second)
+
+    query =
+      "#{rule.query} time:[#{DateTime.to_iso8601(from)},#{DateTime.to_iso8601(now)}]"
+
+    fetch_pages(rule, query, runner_opts, nil, [])
+  end
+
+  defp fetch_pages(rule, query, runner_opts, cursor, acc) do
+    opts =
+      runner_opts
+      |> Keyword.put(:limit, @page_size)
+      |> maybe_put_cursor(cursor)
+
+    case SRQLRunner.query_page(query, opts) do
+      {:ok, %{rows: rows, next_cursor: next_cursor}} ->
+        acc = acc ++ rows
+
+        cond do
+          not is_binary(next_cursor) ->
+            {:ok, acc}
+
+          length(acc) >= @max_samples ->
+            Logger.warning("Metric alert rule #{rule.name} matched over #{@max_samples} samples")
+            {:ok, acc}
+
+          true ->
+            fetch_pages(rule, query, runner_opts, next_cursor, acc)
+        end
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  defp maybe_put_cursor(opts, nil), do: opts
+  defp maybe_put_cursor(opts, cursor), do: Keyword.merge(opts, cursor: cursor, direction: "next")
+
+  defp open_alerts(rule, actor) do
+    prefix = source_prefix(rule)
+
+    Alert
+    |> Ash.Query.for_read(:active)
+    |> Ash.Query.filter(contains(source_id, ^prefix))
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+    |> case do
+      {:ok, alerts} ->
+        {:ok, Map.new(alerts, &{String.replace_prefix(&1.source_id, prefix, ""), &1})}
+
+      {:error, reason} ->
+        {:error, reason}
+    end
+  end
+
+  defp fire(rule, group, group_state, actor) do
+    result =
+      AlertGenerator.threshold_violation(
+        title: "#{rule.name}: #{group}",
+        metric_name: rule.value_field,
+        metric_value: group_state.value,
+        threshold_value: rule.threshold,
+        comparison: alert_comparison(rule.comparison),
+        severity: rule.severity,
+        device_uid: if(rule.group_by == "device_id", do: group),
+        source_id: source_prefix(rule) <> group,
+        actor: actor,
+        details: %{
+          "metric_alert_rule_id" => rule.id,
+          "metric_alert_rule" => rule.name,
+          "group_by" => rule.group_by,
+          "group" => group,
+          "query" => rule.query,
+          "duration_seconds" => rule.duration_seconds,
+          "breaching_since" => DateTime.to_iso8601(group_state.since)
+        }
+      )
+
+    case result do
+      {:ok, _alert} -> :ok
+      {:error, reason} -> {:error, reason}
+    end
+  end
+
+  defp resolve(rule, alert, group_state, actor) do
+    alert
+    |> Ash.Changeset.for_update(:resolve, %{
+      resolved_by: "metric_alert_rule",
+      resolution_note: "#{rule.value_field} recovered to #{group_state.value}"
+    })
+    |> Ash.update(actor: actor)
+    |> case do
+      {:ok, _alert} ->
+        :ok
+
+      {:error, reason} ->
+        Logger.warning("Failed to resolve metric alert #{alert.id}", reason: inspect(reason))
+        {:error, reason}
+    end
+  end
+
+  defp alert_comparison(comparison) when comparison in [:gt, :gte], do: :greater_than
+  defp alert_comparison(comparison) when comparison in [:lt, :lte], do: :less_than
+
+  defp source_prefix(rule), do: "metric_alert_rule:#{rule.id}:"
+end
@@ -0,0 +1,339 @@
+defmodule ServiceRadar.Observability.MetricDerivation do
+  @moduledoc """
+  Computes derived metrics from raw samples as they are ingested.
+
+  Each rule names a new metric and an arithmetic expression over existing
+  metric names (see `ServiceRadar.Observability.MetricDerivation.Expression`).
+  Rules are evaluated per device, and per interface for interface metrics,
+  against the rows of each ingest batch. The results are appended to the batch
+  as `metric_type: "derived"` rows, so they land in `timeseries_metrics` and
+  can be queried with SRQL like any other metric.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Observability.MetricDerivation,
+        rules: [
+          %{name: "disk_fill_rate", expression: "rate(disk_used_bytes)", unit: "bytes/s"},
+          %{name: "memory_headroom", expression: "memory_total_bytes - memory_used_bytes",
+            unit: "bytes"}
+        ]
+
+  Expressions are validated when the rules are loaded; invalid rules are
+  logged and skipped. A rule is evaluated only when every metric it references
+  is present in the batch for that device. `rate/1` and `delta/1` compare
+  against the previous sample of the metric, which this process keeps in ETS.
+  """
+
+  use GenServer
+
+  alias ServiceRadar.Observability.MetricDerivation.Expression
+  alias ServiceRadar.Observability.TimeseriesSeriesKey
+
+  require Logger
+
+  @table :metric_derivation_samples
+  @rules_key :__rules__
+  @metric_type "derived"
+  @name_pattern ~r/^[A-Za-z_][A-Za-z0-9_.:]*$/
+
+  @type rule :: %{
+          name: String.t(),
+          expression: String.t(),
+          ast: Expression.t(),
+          refs: [Expression.metric_ref()],
+          unit: String.t() | nil
+        }
+
+  @type samples :: %{optional(term()) => {DateTime.t(), float()}}
+
+  ## Client API
+
+  def start_link(opts \\ []) do
+    GenServer.start_link(__MODULE__, opts, name: Keyword.get(opts, :name, __MODULE__))
+  end
+
+  @doc """
+  Validates rule definitions, returning the compiled rules and the rejected
+  ones with the reason each was rejected.
+  """
+  @spec compile_rules([map()]) :: {[rule()], [{term(), String.t()}]}
+  def compile_rules(definitions) when is_list(definitions) do
+    {valid, invalid} =
+      Enum.reduce(definitions, {[], []}, fn definition, {valid, invalid} ->
+        case compile_rule(definition, valid) do
+          {:ok, rule} -> {[rule | valid], invalid}
+          {:error, reason} -> {valid, [{rule_label(definition), reason} | invalid]}
+        end
+      end)
+
+    {Enum.reverse(valid), Enum.reverse(invalid)}
+  end
+
+  def compile_rules(_definitions), do: {[], [{nil, "rules must be a list"}]}
+
+  @doc """
+  Appends derived metric rows to a batch of timeseries rows.
+
+  Returns the rows unchanged when the engine is not running or no rules are
+  configured.
+  """
+  @spec append_derived([map()], keyword()) :: [map()]
+  def append_derived(rows, opts \\ []) when is_list(rows) do
+    table = Keyword.get(opts, :table, @table)
+
+    with [_ | _] <- rows,
+         [_ | _] = rules <- stored_rules(table) do
+      {derived, updates} = derive(rows, rules, &lookup_sample(table, &1))
+      :ets.insert(table, Map.to_list(updates))
+      rows ++ derived
+    else
+      _ -> rows
+    end
+  end
+
+  @doc """
+  Evaluates rules against a batch without side effects.
+
+  `previous` returns the last `{timestamp, value}` recorded for a
+  `{device_key, metric_name}` pair, or `nil`. Returns the derived rows and the
+  samples to remember for the next batch.
+  """
+  @spec derive([map()], [rule()], (term() -> {DateTime.t(), float()} | nil)) ::
+          {[map()], samples()}
+  def derive(rows, rules, previous) when is_function(previous, 1) do
+    tracked = tracked_metrics(rules)
+
+    rows
+    |> Enum.reject(&derived_row?/1)
+    |> Enum.group_by(&device_key/1)
+    |> Map.delete(nil)
+    |> Enum.reduce({[], %{}}, fn {key, device_rows}, {derived, updates} ->
+      current = latest_samples(device_rows)
+
+      rows_for_device =
+        Enum.flat_map(rules, fn rule ->
+          evaluate_rule(rule, key, current, previous)
+        end)
+
+      {rows_for_device ++ derived, remember(updates, key, current, tracked)}
+    end)
+  end
+
+  ## Server Callbacks
+
+  @impl true
+  def init(opts) do
+    table = Keyword.get(opts, :table, @table)
+    :ets.new(table, [:named_table, :public, :set, read_concurrency: true])
+
+    definitions =
+      Keyword.get_lazy(opts, :rules, fn ->
+        :serviceradar_core
+        |> Application.get_env(__MODULE__, [])
+        |> Keyword.get(:rules, [])
+      end)
+
+    {rules, invalid} = compile_rules(definitions)
+
+    Enum.each(invalid, fn {name, reason} ->
+      Logger.error("Rejected metric derivation rule #{inspect(name)}: #{reason}")
+    end)
+
+    :ets.insert(table, {@rules_key, rules})
+
+    if rules != [] do
+      Logger.info("Loaded #{length(rules)} metric derivation rule(s)")
+    end
+
+    {:ok, %{table: table}}
+  end
+
+  ## Private Functions
+
+  defp compile_rule(definition, compiled) when is_map(definition) do
+    name = fetch(definition, :name)
+    expression = fetch(definition, :expression)
+    unit = fetch(definition, :unit)
+
+    cond do
+      not is_binary(name) or not Regex.match?(@name_pattern, name) ->
+        {:error, "name must be a metric identifier"}
+
+      Enum.any?(compiled, &(&1.name == name)) ->
+        {:error, "duplicate rule name"}
+
+      not is_binary(expression) ->
+        {:error, "expression is required"}
+
+      not (is_nil(unit) or is_binary(unit)) ->
+        {:error, "unit must be a string"}
+
+      true ->
+        with {:ok, ast} <- Expression.parse(expression),
+             refs = Expression.references(ast),
+             :ok <- validate_refs(name, refs) do
+          {:ok, %{name: name, expression: expression, ast: ast, refs: refs, unit: unit}}
+        end
+    end
+  end
+
+  defp compile_rule(_definition, _compiled), do: {:error, "rule must be a map"}
+
+  defp validate_refs(_name, []), do: {:error, "expression must reference at least one metric"}
+
+  defp validate_refs(name, refs) do
+    if Enum.any?(refs, fn {_kind, metric} -> metric == name end) do
+      {:error, "expression must not reference the derived metric itself"}
+    else
+      :ok
+    end
+  end
+
+  defp rule_label(definition) when is_map(definition), do: fetch(definition, :name)
+  defp rule_label(definition), do: definition
+
+  defp fetch(map, key), do: Map.get(map, key, Map.get(map, Atom.to_string(key)))
+
+  defp stored_rules(table) do
+    case :ets.whereis(table) do
+      :undefined ->
+        []
+
+      _ref ->
+        case :ets.lookup(table, @rules_key) do
+          [{@rules_key, rules}] -> rules
+          [] -> []
+        end
+    end
+  end
+
+  defp lookup_sample(table, key) do
+    case :ets.lookup(table, key) do
+      [{^key, sample}] -> sample
+      [] -> nil
+    end
+  end
+
+  defp tracked_metrics(rules) do
+    for %{refs: refs} <- rules,
+        {kind, metric} <- refs,
+        kind in [:rate, :delta],
+        into: MapSet.new(),
+        do: metric
+  end
+
+  defp derived_row?(row), do: Map.get(row, :metric_type) == @metric_type
+
+  defp device_key(row) do
+    case Map.get(row, :device_id) || Map.get(row, :target_device_ip) do
+      device when is_binary(device) and device != "" ->
+        {Map.get(row, :partition), device, Map.get(row, :if_index)}
+
+      _ ->
+        nil
+    end
+  end
+
+  # Latest row per metric name for one device.
+  defp latest_samples(rows) do
+    Enum.reduce(rows, %{}, fn row, acc ->
+      name = Map.get(row, :metric_name)
+      value = Map.get(row, :value)
+
+      if is_binary(name) and is_number(value) do
+        Map.update(acc, name, row, fn existing ->
+          if newer?(row, existing), do: row, else: existing
+        end)
+      else
+        acc
+      end
+    end)
+  end
+
+  defp newer?(row, existing) do
+    case {Map.get(row, :timestamp), Map.get(existing, :timestamp)} do
+      {%DateTime{} = a, %DateTime{} = b} -> DateTime.compare(a, b) != :lt
+      _ -> true
+    end
+  end
+
+  defp evaluate_rule(rule, key, current, previous) do
+    if Enum.all?(rule.refs, fn {_kind, metric} -> Map.has_key?(current, metric) end) do
+      resolve = &resolve_ref(&1, key, current, previous)
+
+      case Expression.evaluate(rule.ast, resolve) do
+        {:ok, value} -> [build_row(rule, value, current)]
+        :error -> []
+      end
+    else
+      []
+    end
+  end
+
+  defp resolve_ref({:metric, metric}, _key, current, _previous),
+    do: current |> Map.fetch!(metric) |> Map.get(:value)
+
+  defp resolve_ref({kind, metric}, key, current, previous) when kind in [:rate, :delta] do
+    row = Map.fetch!(current, metric)
+
+    with {%DateTime{} = prev_ts, prev_value} <- previous.({key, metric}),
+         %DateTime{} = ts <- Map.get(row, :timestamp),
+         elapsed when elapsed > 0 <- DateTime.diff(ts, prev_ts, :microsecond),
+         change when change >= 0 <- row.value - prev_value do
+      if kind == :rate, do: change * 1_000_000 / elapsed, else: change
+    else
+      _ -> nil
+    end
+  end
+
+  defp build_row(rule, value, current) do
+    source =
+      rule.refs
+      |> Enum.map(fn {_kind, metric} -> Map.fetch!(current, metric) end)
+      |> Enum.max_by(&Map.get(&1, :timestamp), &timestamp_sorter/2)
+
+    row =
+      source
+      |> Map.take([
+        :timestamp,
+        :gateway_id,
+        :agent_id,
+        :device_id,
+        :partition,
+        :target_device_ip,
+        :if_index,
+        :created_at
+      ])
+      |> Map.merge(%{
+        metric_name: rule.name,
+        metric_type: @metric_type,
+        value: value,
+        unit: rule.unit,
+        tags: derived_tags(source),
+        scale: nil,
+        is_delta: false,
+        metadata: %{"expression" => rule.expression}
+      })
+
+    Map.put(row, :series_key, TimeseriesSeriesKey.build(row))
+  end
+
+  defp timestamp_sorter(%DateTime{} = a, %DateTime{} = b), do: DateTime.compare(a, b) != :lt
+  defp timestamp_sorter(_a, _b), do: true
+
+  # Keep identifying tags (e.g. interface_uid) so derived series line up with
+  # their sources, but drop the source metric name.
+  defp derived_tags(%{tags: tags}) when is_map(tags), do: Map.drop(tags, ["metric", :metric])
+  defp derived_tags(_source), do: %{}
+
+  defp remember(updates, key, current, tracked) do
+    Enum.reduce(current, updates, fn {metric, row}, acc ->
+      with true <- MapSet.member?(tracked, metric),
+           %DateTime{} = ts <- Map.get(row, :timestamp) do
+        Map.put(acc, {key, metric}, {ts, row.value / 1})
+      else
+        _ -> acc
+      end
+    end)
+  end
+end
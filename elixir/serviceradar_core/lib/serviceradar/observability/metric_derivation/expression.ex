@@ -0,0 +1,287 @@
+defmodule ServiceRadar.Observability.MetricDerivation.Expression do
+  @moduledoc """
+  Arithmetic expressions over named metrics, used by metric derivation rules.
+
+  Expressions are parsed into a small AST and evaluated by walking it; nothing
+  is ever compiled or passed to `Code`. The grammar is:
+
+      expr    := term (("+" | "-") term)*
+      term    := unary (("*" | "/") unary)*
+      unary   := "-" unary | primary
+      primary := number | metric | call | "(" expr ")"
+      call    := rate(metric) | delta(metric)
+               | abs(expr) | min(expr, expr) | max(expr, expr)
+
+  Metric names start with a letter or underscore and may contain letters,
+  digits, `_`, `.` and `:` (for example `disk.used_bytes` or `ifHCInOctets`).
+
+  `rate(m)` is the per-second change of `m` since its previous sample and
+  `delta(m)` the raw difference. Both need a previous sample; a counter that
+  went backwards is treated as a reset and yields no value.
+  """
+
+  @max_length 512
+  @max_depth 32
+  @metric_functions %{"rate" => :rate, "delta" => :delta}
+  @numeric_functions %{"abs" => {:abs, 1}, "min" => {:min, 2}, "max" => {:max, 2}}
+
+  @type metric_ref :: {:metric, String.t()} | {:rate, String.t()} | {:delta, String.t()}
+  @type t ::
+          {:number, float()}
+          | metric_ref()
+          | {:neg, t()}
+          | {:op, :+ | :- | :* | :/, t(), t()}
+          | {:call, :abs | :min | :max, [t()]}
+
+  @doc """
+  Parses an expression string, returning `{:ok, ast}` or `{:error, reason}`.
+  """
+  @spec parse(String.t()) :: {:ok, t()} | {:error, String.t()}
+  def parse(source) when is_binary(source) do
+    source = String.trim(source)
+
+    cond do
+      source == "" ->
+        {:error, "expression is empty"}
+
+      byte_size(source) > @max_length ->
+        {:error, "expression exceeds #{@max_length} characters"}
+
+      true ->
+        with {:ok, tokens} <- tokenize(source, []),
+             {:ok, ast, []} <- parse_expr(tokens, 0) do
+          {:ok, ast}
+        else
+          {:ok, _ast, [token | _]} -> {:error, "unexpected #{describe(token)}"}
+          {:error, _reason} = error -> error
+        end
+    end
+  end
+
+  def parse(_source), do: {:error, "expression must be a string"}
+
+  @doc """
+  Returns the metric references in an expression, without duplicates.
+  """
+  @spec references(t()) :: [metric_ref()]
+  def references(ast) do
+    ast
+    |> collect_refs([])
+    |> Enum.reverse()
+    |> Enum.uniq()
+  end
+
+  @doc """
+  Evaluates an expression. `resolve` maps each metric reference to a number,
+  or `nil` when it has no value; any unresolved reference, division by zero,
+  or arithmetic overflow yields `:error`.
+  """
+  @spec evaluate(t(), (metric_ref() -> number() | nil)) :: {:ok, float()} | :error
+  def evaluate(ast, resolve) when is_function(resolve, 1) do
+    case eval(ast, resolve) do
+      {:ok, value} when is_float(value) -> {:ok, value}
+      _ -> :error
+    end
+  catch
+    :error, _ -> :error
+  end
+
+  ## Tokenizer
+
+  defp tokenize(<<>>, acc), do: {:ok, Enum.reverse(acc)}
+
+  defp tokenize(<<c, rest::binary>>, acc) when c in [?\s, ?\t, ?\n, ?\r],
+    do: tokenize(rest, acc)
+
+  defp tokenize(<<?+, rest::binary>>, acc), do: tokenize(rest, [{:op, :+} | acc])
+  defp tokenize(<<?-, rest::binary>>, acc), do: tokenize(rest, [{:op, :-} | acc])
+  defp tokenize(<<?*, rest::binary>>, acc), do: tokenize(rest, [{:op, :*} | acc])
+  defp tokenize(<<?/, rest::binary>>, acc), do: tokenize(rest, [{:op, :/} | acc])
+  defp tokenize(<<?(, rest::binary>>, acc), do: tokenize(rest, [:lparen | acc])
+  defp tokenize(<<?), rest::binary>>, acc), do: tokenize(rest, [:rparen | acc])
+  defp tokenize(<<?,, rest::binary>>, acc), do: tokenize(rest, [:comma | acc])
+
+  defp tokenize(<<c, _::binary>> = source, acc) when c in ?0..?9 or c == ?. do
+    {literal, rest} = take_while(source, &number_char?/1)
+
+    case Float.parse(literal) do
+      {value, ""} -> tokenize(rest, [{:number, value} | acc])
+      _ -> {:error, "invalid number #{inspect(literal)}"}
+    end
+  end
+
+  defp tokenize(<<c, _::binary>> = source, acc)
+       when c in ?a..?z or c in ?A..?Z or c == ?_ do
+    {name, rest} = take_while(source, &ident_char?/1)
+    tokenize(rest, [{:ident, name} | acc])
+  end
+
+  defp tokenize(<<c::utf8, _::binary>>, _acc),
+    do: {:error, "unexpected character #{inspect(<<c::utf8>>)}"}
+
+  defp tokenize(_source, _acc), do: {:error, "expression is not valid UTF-8"}
+
+  defp take_while(source, fun) do
+    size =
+      source
+      |> :binary.bin_to_list()
+      |> Enum.take_while(fun)
+      |> length()
+
+    <<taken::binary-size(size), rest::binary>> = source
+    {taken, rest}
+  end
+
+  defp number_char?(c), do: c in ?0..?9 or c == ?.
+
+  defp ident_char?(c), do: c in ?a..?z or c in ?A..?Z or c in ?0..?9 or c in [?_, ?., ?:]
+
+  ## Parser
+
+  defp parse_expr(_tokens, depth) when depth > @max_depth,
+    do: {:error, "expression nests deeper than #{@max_depth} levels"}
+
+  defp parse_expr(tokens, depth) do
+    with {:ok, left, rest} <- parse_term(tokens, depth) do
+      parse_expr_tail(left, rest, depth)
+    end
+  end
+
+  defp parse_expr_tail(left, [{:op, op} | rest], depth) when op in [:+, :-] do
+    with {:ok, right, rest} <- parse_term(rest, depth) do
+      parse_expr_tail({:op, op, left, right}, rest, depth)
+    end
+  end
+
+  defp parse_expr_tail(left, rest, _depth), do: {:ok, left, rest}
+
+  defp parse_term(tokens, depth) do
+    with {:ok, left, rest} <- parse_unary(tokens, depth) do
+      parse_term_tail(left, rest, depth)
+    end
+  end
+
+  defp parse_term_tail(left, [{:op, op} | rest], depth) when op in [:*, :/] do
+    with {:ok, right, rest} <- parse_unary(rest, depth) do
+      parse_term_tail({:op, op, left, right}, rest, depth)
+    end
+  end
+
+  defp parse_term_tail(left, rest, _depth), do: {:ok, left, rest}
+
+  defp parse_unary([{:op, :-} | rest], depth) do
+    with {:ok, operand, rest} <- parse_unary(rest, depth + 1) do
+      {:ok, {:neg, operand}, rest}
+    end
+  end
+
+  defp parse_unary(tokens, depth), do: parse_primary(tokens, depth)
+
+  defp parse_primary([{:number, value} | rest], _depth), do: {:ok, {:number, value}, rest}
+
+  defp parse_primary([{:ident, name}, :lparen | rest], depth), do: parse_call(name, rest, depth)
+
+  defp parse_primary([{:ident, name} | rest], _depth), do: {:ok, {:metric, name}, rest}
+
+  defp parse_primary([:lparen | rest], depth) do
+    case parse_expr(rest, depth + 1) do
+      {:ok, inner, [:rparen | rest]} -> {:ok, inner, rest}
+      {:ok, _inner, _rest} -> {:error, "missing closing parenthesis"}
+      error -> error
+    end
+  end
+
+  defp parse_primary([token | _], _depth), do: {:error, "unexpected #{describe(token)}"}
+  defp parse_primary([], _depth), do: {:error, "unexpected end of expression"}
+
+  defp parse_call(name, tokens, depth) do
+    cond do
+      Map.has_key?(@metric_functions, name) -> parse_metric_call(name, tokens)
+      Map.has_key?(@numeric_functions, name) -> parse_numeric_call(name, tokens, depth)
+      true -> {:error, "unknown function #{name}()"}
+    end
+  end
+
+  defp parse_metric_call(name, [{:ident, metric}, :rparen | rest]),
+    do: {:ok, {Map.fetch!(@metric_functions, name), metric}, rest}
+
+  defp parse_metric_call(name, _tokens), do: {:error, "#{name}() takes a single metric name"}
+
+  defp parse_numeric_call(name, tokens, depth) do
+    {fun, arity} = Map.fetch!(@numeric_functions, name)
+
+    with {:ok, args, rest} <- parse_args(tokens, depth + 1, []) do
+      if length(args) == arity do
+        {:ok, {:call, fun, args}, rest}
+      else
+        {:error, "#{name}() takes #{arity} argument(s)"}
+      end
+    end
+  end
+
+  defp parse_args(tokens, depth, acc) do
+    case parse_expr(tokens, depth) do
+      {:ok, arg, [:comma | rest]} -> parse_args(rest, depth, [arg | acc])
+      {:ok, arg, [:rparen | rest]} -> {:ok, Enum.reverse([arg | acc]), rest}
+      {:ok, _arg, _rest} -> {:error, "missing closing parenthesis"}
+      error -> error
+    end
+  end
+
+  defp describe({:op, op}), do: "operator #{op}"
+  defp describe({:number, value}), do: "number #{value}"
+  defp describe({:ident, name}), do: "identifier #{name}"
+  defp describe(:lparen), do: "\"(\""
+  defp describe(:rparen), do: "\")\""
+  defp describe(:comma), do: "\",\""
+
+  ## Evaluation
+
+  defp eval({:number, value}, _resolve), do: {:ok, value}
+
+  defp eval({kind, _name} = ref, resolve) when kind in [:metric, :rate, :delta] do
+    case resolve.(ref) do
+      value when is_number(value) -> {:ok, value / 1}
+      _ -> :error
+    end
+  end
+
+  defp eval({:neg, operand}, resolve) do
+    with {:ok, value} <- eval(operand, resolve), do: {:ok, -value}
+  end
+
+  defp eval({:op, op, left, right}, resolve) do
+    with {:ok, l} <- eval(left, resolve),
+         {:ok, r} <- eval(right, resolve) do
+      apply_op(op, l, r)
+    end
+  end
+
+  defp eval({:call, :abs, [arg]}, resolve) do
+    with {:ok, value} <- eval(arg, resolve), do: {:ok, abs(value)}
+  end
+
+  defp eval({:call, fun, [a, b]}, resolve) when fun in [:min, :max] do
+    with {:ok, x} <- eval(a, resolve),
+         {:ok, y} <- eval(b, resolve) do
+      if fun == :min, do: {:ok, min(x, y)}, else: {:ok, max(x, y)}
+    end
+  end
+
+  defp apply_op(:+, l, r), do: {:ok, l + r}
+  defp apply_op(:-, l, r), do: {:ok, l - r}
+  defp apply_op(:*, l, r), do: {:ok, l * r}
+  defp apply_op(:/, _l, r) when r == 0, do: :error
+  defp apply_op(:/, l, r), do: {:ok, l / r}
+
+  defp collect_refs({kind, _name} = ref, acc) when kind in [:metric, :rate, :delta],
+    do: [ref | acc]
+
+  defp collect_refs({:neg, operand}, acc), do: collect_refs(operand, acc)
+
+  defp collect_refs({:op, _op, left, right}, acc),
+    do: collect_refs(right, collect_refs(left, acc))
+
+  defp collect_refs({:call, _fun, args}, acc), do: Enum.reduce(args, acc, &collect_refs/2)
+  defp collect_refs({:number, _value}, acc), do: acc
+end
@@ -0,0 +1,165 @@
+defmodule ServiceRadar.Observability.NetworkMetric do
+  @moduledoc """
+  Network interface throughput metric resource.
+
+  Maps to the `network_metrics` TimescaleDB hypertable, populated from the
+  per-interface counters reported by sysmon. Rate columns are computed by the
+  agent from consecutive samples and are null for an interface's first sample
+  or after its counters reset.
+  """
+
+  use ServiceRadar.Observability.RawMetricResource,
+    table: "network_metrics",
+    type: "network_metric",
+    route: "/network_metrics"
+
+  actions do
+    defaults [:read]
+
+    read :by_device do
+      argument :device_id, :string, allow_nil?: false
+      filter expr(device_id == ^arg(:device_id))
+    end
+
+    read :by_interface do
+      argument :interface_name, :string, allow_nil?: false
+      filter expr(interface_name == ^arg(:interface_name))
+    end
+
+    read :recent do
+      description "Metrics from the last 24 hours"
+      filter expr(timestamp > ago(24, :hour))
+    end
+
+    create :create do
+      accept [
+        :timestamp,
+        :gateway_id,
+        :agent_id,
+        :host_id,
+        :interface_name,
+        :bytes_sent,
+        :bytes_recv,
+        :packets_sent,
+        :packets_recv,
+        :errors_in,
+        :errors_out,
+        :drops_in,
+        :drops_out,
+        :bytes_sent_per_sec,
+        :bytes_recv_per_sec,
+        :packets_sent_per_sec,
+        :packets_recv_per_sec,
+        :device_id,
+        :partition,
+        :created_at
+      ]
+    end
+  end
+
+  attributes do
+    # TimescaleDB hypertable - no traditional PK
+    attribute :timestamp, :utc_datetime_usec do
+      allow_nil? false
+      public? true
+      description "When the metric was recorded"
+    end
+
+    attribute :gateway_id, :string do
+      public? true
+      description "Gateway that collected this metric"
+    end
+
+    attribute :agent_id, :string do
+      public? true
+      description "Agent ID"
+    end
+
+    attribute :host_id, :string do
+      public? true
+      description "Host identifier"
+    end
+
+    attribute :interface_name, :string do
+      allow_nil? false
+      public? true
+      description "Network interface name"
+    end
+
+    attribute :bytes_sent, :integer do
+      public? true
+      description "Total bytes transmitted"
+    end
+
+    attribute :bytes_recv, :integer do
+      public? true
+      description "Total bytes received"
+    end
+
+    attribute :packets_sent, :integer do
+      public? true
+      description "Total packets transmitted"
+    end
+
+    attribute :packets_recv, :integer do
+      public? true
+      description "Total packets received"
+    end
+
+    attribute :errors_in, :integer do
+      public? true
+      description "Total receive errors"
+    end
+
+    attribute :errors_out, :integer do
+      public? true
+      description "Total transmit errors"
+    end
+
+    attribute :drops_in, :integer do
+      public? true
+      description "Total dropped incoming packets"
+    end
+
+    attribute :drops_out, :integer do
+      public? true
+      description "Total dropped outgoing packets"
+    end
+
+    attribute :bytes_sent_per_sec, :float do
+      public? true
+      description "Transmit throughput since the previous sample"
+    end
+
+    attribute :bytes_recv_per_sec, :float do
+      public? true
+      description "Receive throughput since the previous sample"
+    end
+
+    attribute :packets_sent_per_sec, :float do
+      public? true
+      description "Transmitted packets per second since the previous sample"
+    end
+
+    attribute :packets_recv_per_sec, :float do
+      public? true
+      description "Received packets per second since the previous sample"
+    end
+
+    attribute :device_id, :string do
+      public? true
+      description "Device identifier"
+    end
+
+    attribute :partition, :string do
+      public? true
+      description "Partition"
+    end
+
+    attribute :created_at, :utc_datetime_usec do
+      allow_nil? false
+      public? true
+      description "When the record was created"
+    end
+  end
+end
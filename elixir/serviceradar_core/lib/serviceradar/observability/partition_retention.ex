@@ -0,0 +1,115 @@
+defmodule ServiceRadar.Observability.PartitionRetention do
+  @moduledoc """
+  Enforces per-partition retention windows
+  (`ServiceRadar.Observability.PartitionRetentionPolicy`).
+
+  For every enabled policy, rows of that partition older than its window are
+  deleted from each partition-scoped table; other partitions are untouched.
+  Tables keep their TimescaleDB retention policy as an upper bound, so windows
+  are validated against a global maximum that should not exceed it:
+
+      config :serviceradar_core, ServiceRadar.Observability.PartitionRetention,
+        max_retention_days: 90
+
+  `ServiceRadar.Observability.DataRetentionWorker` runs `enforce/1` daily.
+  """
+
+  alias Ecto.Adapters.SQL
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Observability.PartitionRetentionPolicy
+  alias ServiceRadar.Repo
+
+  require Logger
+
+  @default_max_retention_days 90
+  @query_timeout_ms 120_000
+
+  # Partition-scoped tables and their time column.
+  @tables [
+    {"cpu_metrics", "timestamp"},
+    {"cpu_cluster_metrics", "timestamp"},
+    {"memory_metrics", "timestamp"},
+    {"disk_metrics", "timestamp"},
+    {"process_metrics", "timestamp"},
+    {"network_metrics", "timestamp"},
+    {"timeseries_metrics", "timestamp"},
+    {"service_status", "timestamp"},
+    {"mtr_traces", "time"}
+  ]
+
+  @doc "Largest retention window a partition may configure, in days."
+  @spec max_retention_days() :: pos_integer()
+  def max_retention_days do
+    :serviceradar_core
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(:max_retention_days, @default_max_retention_days)
+  end
+
+  @doc "Tables covered by partition retention, with their time column."
+  @spec tables() :: [{String.t(), String.t()}]
+  def tables, do: @tables
+
+  @doc """
+  Deletes expired rows for every enabled policy. Returns the number of rows
+  deleted per partition.
+
+  Options:
+    - `:now` - reference time for the windows (default: now)
+  """
+  @spec enforce(keyword()) :: %{String.t() => non_neg_integer()}
+  def enforce(opts \\ []) do
+    now = Keyword.get(opts, :now, DateTime.utc_now())
+
+    case PartitionRetentionPolicy.list_enabled(actor: SystemActor.system(:partition_retention)) do
+      {:ok, policies} ->
+        Map.new(policies, fn policy -> {policy.partition, prune_partition(policy, now)} end)
+
+      {:error, reason} ->
+        Logger.warning("Failed to load partition retention policies: #{inspect(reason)}")
+        %{}
+    end
+  end
+
+  defp prune_partition(policy, now) do
+    days = min(policy.retention_days, max_retention_days())
+    cutoff = DateTime.add(now, -days * 86_400, :second)
+
+    Enum.reduce(@tables, 0, fn {table, time_column}, deleted ->
+      deleted + prune_table(table, time_column, policy.partition, cutoff, days)
+    end)
+  end
+
+  defp prune_table(table, time_column, partition, cutoff, days) do
+    sql = """
+    DELETE FROM platform.#{table}
+    WHERE partition = $1 AND #{time_column} < $2
+    """
+
+    case SQL.query(Repo, sql, [partition, cutoff], timeout: @query_timeout_ms) do
+      {:ok, %{num_rows: deleted}} ->
+        if deleted > 0 do
+          Logger.info("Pruned partition data",
+            table: table,
+            partition: partition,
+            deleted_rows: deleted,
+            retention_days: days
+          )
+        end
+
+        deleted
+
+      {:error, %Postgrex.Error{postgres: %{code: code}}}
+      when code in [:undefined_table, :undefined_column] ->
+        0
+
+      {:error, error} ->
+        Logger.warning("Failed to prune partition data",
+          table: table,
+          partition: partition,
+          reason: Exception.message(error)
+        )
+
+        0
+    end
+  end
+end
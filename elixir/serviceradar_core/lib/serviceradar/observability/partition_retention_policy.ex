@@ -0,0 +1,114 @@
+defmodule ServiceRadar.Observability.PartitionRetentionPolicy do
+  @moduledoc """
+  Retention window for one partition's observability data.
+
+  Partitions with a policy keep their partition-scoped metrics, service
+  status and MTR traces for `retention_days` (see
+  `ServiceRadar.Observability.PartitionRetention` for the tables covered);
+  partitions without one keep the table-level retention. Windows are limited
+  to the global maximum, `PartitionRetention.max_retention_days/0`.
+  """
+
+  use Ash.Resource,
+    domain: ServiceRadar.Observability,
+    data_layer: AshPostgres.DataLayer,
+    authorizers: [Ash.Policy.Authorizer]
+
+  alias ServiceRadar.Observability.PartitionRetention
+
+  @policy_fields [:partition, :retention_days, :enabled]
+
+  postgres do
+    table "partition_retention_policies"
+    repo ServiceRadar.Repo
+    schema "platform"
+    migrate? false
+  end
+
+  code_interface do
+    define :list_enabled, action: :enabled
+    define :get_by_partition, action: :by_partition, args: [:partition], get?: true
+    define :create, action: :create
+    define :update, action: :update
+    define :destroy, action: :destroy
+  end
+
+  actions do
+    defaults [:read, :destroy]
+
+    read :enabled do
+      description "Policies the retention job enforces"
+      filter expr(enabled == true)
+    end
+
+    read :by_partition do
+      argument :partition, :string, allow_nil?: false
+      filter expr(partition == ^arg(:partition))
+    end
+
+    create :create do
+      accept @policy_fields
+      validate fn changeset, _context ->
+        validate_retention_days(Ash.Changeset.get_attribute(changeset, :retention_days))
+      end
+    end
+
+    update :update do
+      require_atomic? false
+      accept [:retention_days, :enabled]
+      validate fn changeset, _context ->
+        validate_retention_days(Ash.Changeset.get_attribute(changeset, :retention_days))
+      end
+    end
+  end
+
+  policies do
+    import ServiceRadar.Policies
+
+    system_bypass()
+    read_operator_plus()
+    admin_action([:create, :update, :destroy])
+  end
+
+  attributes do
+    uuid_primary_key :id
+
+    attribute :partition, :string do
+      allow_nil? false
+      public? true
+      constraints trim?: true, allow_empty?: false
+      description "Partition the window applies to"
+    end
+
+    attribute :retention_days, :integer do
+      allow_nil? false
+      public? true
+      constraints min: 1
+      description "Days of data to keep, up to the global maximum"
+    end
+
+    attribute :enabled, :boolean do
+      allow_nil? false
+      default true
+      public? true
+    end
+
+    create_timestamp :inserted_at
+    update_timestamp :updated_at
+  end
+
+  identities do
+    identity :unique_partition, [:partition]
+  end
+
+  defp validate_retention_days(days) do
+    max = PartitionRetention.max_retention_days()
+
+    if is_integer(days) and days > max do
+      {:error,
+       field: :retention_days, message: "must not exceed the global maximum of #{max} days"}
+    else
+      :ok
+    end
+  end
+end
@@ -9,6 +9,7 @@ defmodule ServiceRadar.Observability.PluginResultIngestor do
   alias ServiceRadar.Camera.InventoryIngestor
   alias ServiceRadar.EventWriter.FieldParser
   alias ServiceRadar.Inventory.DeviceDiscoveryIngestor
+  alias ServiceRadar.Observability.MetricDerivation
   alias ServiceRadar.Observability.ServiceIdentity
   alias ServiceRadar.Observability.ServiceStatus
   alias ServiceRadar.Observability.ThreatIntelPluginIngestor
@@ -149,6 +150,7 @@ defmodule ServiceRadar.Observability.PluginResultIngestor do
       |> Enum.map(&build_metric_row(&1, payload, status, observed_at, created_at))
       |> Enum.reject(&is_nil/1)
       |> TimeseriesSeriesKey.dedupe_rows()
+      |> MetricDerivation.append_derived()
 
     if Enum.empty?(rows) do
       :ok
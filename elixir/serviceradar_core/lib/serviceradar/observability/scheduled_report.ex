@@ -0,0 +1,237 @@
+defmodule ServiceRadar.Observability.ScheduledReport do
+  @moduledoc """
+  A saved SRQL query delivered on a schedule, such as a weekly CSV of
+  high-risk devices emailed to a compliance team.
+
+  Reports are run by `ServiceRadar.Observability.ScheduledReports` whenever
+  `next_run_at` has passed, as the user who created them: that user must
+  still hold `analytics.manage_queries` and the view permission of the
+  queried entity. `next_run_at` is computed from the `cron` expression (UTC)
+  whenever the report is created, updated or run.
+
+  ## Formats
+
+  - `:csv` - one row per result, one column per result field
+  - `:json` - an array of result objects
+  - `:pdf` - a plain tabular document
+
+  ## Delivery
+
+  - `:email` - attached to a mail; `target`: `%{"recipients" => [...]}`
+  - `:webhook` - POSTed as the request body;
+    `target`: `%{"url" => ..., "headers" => %{...}}`
+  - `:object_store` - uploaded to the datasvc object store; `target`: `%{"prefix" => ...}`
+  """
+
+  use Ash.Resource,
+    domain: ServiceRadar.Observability,
+    data_layer: AshPostgres.DataLayer,
+    authorizers: [Ash.Policy.Authorizer]
+
+  alias ServiceRadar.Observability.ScheduledReports
+  alias ServiceRadar.Policies.Checks.ActorHasPermission
+
+  @analytics_view_check {ActorHasPermission, permission: "analytics.view"}
+  @manage_queries_check {ActorHasPermission, permission: "analytics.manage_queries"}
+  @report_fields [
+    :name,
+    :description,
+    :query,
+    :cron,
+    :format,
+    :delivery,
+    :target,
+    :partition,
+    :enabled
+  ]
+  @run_fields [:next_run_at, :last_run_at, :last_status, :last_error, :last_row_count]
+
+  postgres do
+    table "scheduled_reports"
+    repo ServiceRadar.Repo
+    schema "platform"
+    migrate? false
+  end
+
+  code_interface do
+    define :get_by_id, action: :read, get_by: [:id]
+    define :create, action: :create
+    define :update, action: :update
+    define :destroy, action: :destroy
+    define :list_due, action: :due, args: [:now]
+  end
+
+  actions do
+    defaults [:read, :destroy]
+
+    read :due do
+      description "Enabled reports whose next run time has passed"
+      argument :now, :utc_datetime_usec, allow_nil?: false
+      filter expr(enabled == true and not is_nil(next_run_at) and next_run_at <= ^arg(:now))
+
+      prepare fn query, _context ->
+        Ash.Query.sort(query, next_run_at: :asc)
+      end
+    end
+
+    create :create do
+      accept @report_fields
+      validate ServiceRadar.Observability.Validations.ScheduledReport
+
+      change fn changeset, context ->
+        actor = Map.get(context, :actor)
+
+        changeset
+        |> Ash.Changeset.force_change_attribute(:requested_by_id, requester_id(actor))
+        |> Ash.Changeset.force_change_attribute(:requested_by, actor_label(actor))
+        |> schedule_next_run()
+      end
+    end
+
+    update :update do
+      accept @report_fields
+      require_atomic? false
+      validate ServiceRadar.Observability.Validations.ScheduledReport
+
+      change fn changeset, _context ->
+        schedule_next_run(changeset)
+      end
+    end
+
+    update :record_run do
+      description "Record the outcome of a run and the next run time (system only)"
+      accept @run_fields
+    end
+  end
+
+  policies do
+    import ServiceRadar.Policies
+
+    system_bypass()
+    read_with_permission(@analytics_view_check)
+    action_with_permission([:create, :update, :destroy], @manage_queries_check)
+  end
+
+  attributes do
+    uuid_primary_key :id
+
+    attribute :name, :string do
+      allow_nil? false
+      public? true
+      constraints min_length: 1, max_length: 120
+    end
+
+    attribute :description, :string do
+      public? true
+    end
+
+    attribute :query, :string do
+      allow_nil? false
+      public? true
+      description "SRQL query whose results make up the report"
+    end
+
+    attribute :cron, :string do
+      allow_nil? false
+      public? true
+      description "Cron expression (UTC) for when the report runs"
+    end
+
+    attribute :format, :atom do
+      allow_nil? false
+      default :csv
+      public? true
+      constraints one_of: [:csv, :json, :pdf]
+    end
+
+    attribute :delivery, :atom do
+      allow_nil? false
+      public? true
+      constraints one_of: [:email, :webhook, :object_store]
+    end
+
+    attribute :target, :map do
+      default %{}
+      public? true
+      description "Delivery target (recipients, webhook url and headers, or object key prefix)"
+    end
+
+    attribute :partition, :string do
+      public? true
+      description "Only report results from this partition"
+    end
+
+    attribute :enabled, :boolean do
+      allow_nil? false
+      default true
+      public? true
+    end
+
+    attribute :requested_by_id, :uuid do
+      public? true
+      description "User whose permissions the report runs with"
+    end
+
+    attribute :requested_by, :string do
+      public? true
+    end
+
+    attribute :next_run_at, :utc_datetime_usec do
+      public? true
+    end
+
+    attribute :last_run_at, :utc_datetime_usec do
+      public? true
+    end
+
+    attribute :last_status, :atom do
+      public? true
+      constraints one_of: [:succeeded, :failed]
+    end
+
+    attribute :last_error, :string do
+      public? true
+    end
+
+    attribute :last_row_count, :integer do
+      public? true
+    end
+
+    create_timestamp :inserted_at
+    update_timestamp :updated_at
+  end
+
+  identities do
+    identity :unique_name, [:name]
+  end
+
+  defp schedule_next_run(changeset) do
+    enabled = Ash.Changeset.get_attribute(changeset, :enabled)
+    cron = Ash.Changeset.get_attribute(changeset, :cron)
+
+    next_run_at =
+      if enabled != false and is_binary(cron) do
+        case ScheduledReports.next_run_at(cron, DateTime.utc_now()) do
+          {:ok, next_run_at} -> next_run_at
+          {:error, _reason} -> nil
+        end
+      end
+
+    Ash.Changeset.force_change_attribute(changeset, :next_run_at, next_run_at)
+  end
+
+  defp requester_id(%{role: :system}), do: nil
+
+  defp requester_id(actor) when is_map(actor) do
+    case Ecto.UUID.cast(Map.get(actor, :id)) do
+      {:ok, id} -> id
+      :error -> nil
+    end
+  end
+
+  defp requester_id(_actor), do: nil
+
+  defp actor_label(%{email: email}) when not is_nil(email), do: to_string(email)
+  defp actor_label(%{id: id}) when not is_nil(id), do: to_string(id)
+  defp actor_label(_actor), do: nil
+end
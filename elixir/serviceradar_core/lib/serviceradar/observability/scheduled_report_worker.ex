@@ -0,0 +1,27 @@
+defmodule ServiceRadar.Observability.ScheduledReportWorker do
+  @moduledoc """
+  Oban cron worker that runs scheduled SRQL reports once their next run time
+  has passed. Scheduled every minute from the core runtime config.
+  """
+
+  use Oban.Worker,
+    queue: :maintenance,
+    max_attempts: 1,
+    unique: [period: 55, states: [:available, :scheduled, :executing]]
+
+  alias ServiceRadar.Observability.ScheduledReports
+
+  require Logger
+
+  @impl Oban.Worker
+  def perform(%Oban.Job{}) do
+    case ScheduledReports.run_due() do
+      [] ->
+        :ok
+
+      reports ->
+        Logger.info("ScheduledReportWorker: ran #{length(reports)} report(s)")
+        :ok
+    end
+  end
+end
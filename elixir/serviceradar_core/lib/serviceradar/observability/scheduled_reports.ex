@@ -0,0 +1,350 @@
+defmodule ServiceRadar.Observability.ScheduledReports do
+  @moduledoc """
+  Creates and runs scheduled SRQL reports
+  (`ServiceRadar.Observability.ScheduledReport`).
+
+  `ServiceRadar.Observability.ScheduledReportWorker` calls `run_due/1` every
+  minute. Each due report is claimed by advancing its `next_run_at` (so a
+  run happens at most once per slot, even with several schedulers; missed
+  slots are skipped, not caught up), then run as the user who created it:
+
+    * the user must still be active and still hold `analytics.manage_queries`
+      and the view permission of the queried entity
+    * results are limited to the report's partition, when set
+    * at most 10,000 rows are reported; larger results fail the run
+
+  The rows are rendered by `ScheduledReports.Renderer` and delivered by
+  `ScheduledReports.Delivery`. The outcome is stored on the report and
+  written to the audit log as an event.
+
+  ## Usage
+
+      ScheduledReports.create(
+        %{name: "weekly-high-risk", query: "in:devices risk_level:high",
+          cron: "0 6 * * 1", format: :csv, delivery: :email,
+          target: %{"recipients" => ["compliance@example.com"]}},
+        actor: scope.user
+      )
+  """
+
+  import Ecto.Query, only: [from: 2]
+
+  alias Oban.Cron.Expression
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Events.AuditWriter
+  alias ServiceRadar.Identity.RBAC
+  alias ServiceRadar.Identity.User
+  alias ServiceRadar.Inventory.DeviceIdentifier
+  alias ServiceRadar.Observability.ScheduledReport
+  alias ServiceRadar.Observability.ScheduledReports.Delivery
+  alias ServiceRadar.Observability.ScheduledReports.Renderer
+  alias ServiceRadar.Observability.SRQLRunner
+  alias ServiceRadar.Repo
+
+  require Ash.Query
+  require Logger
+
+  @max_rows 10_000
+  @manage_permission "analytics.manage_queries"
+
+  @view_permissions %{
+    "devices" => "devices.view",
+    "device_updates" => "devices.view",
+    "interfaces" => "devices.view",
+    "services" => "services.view",
+    "logs" => "observability.logs.view",
+    "traces" => "observability.traces.view",
+    "otel_traces" => "observability.traces.view",
+    "events" => "observability.events.view",
+    "flows" => "observability.netflow.view",
+    "alerts" => "observability.alerts.view",
+    "cpu_metrics" => "observability.metrics.view",
+    "memory_metrics" => "observability.metrics.view",
+    "disk_metrics" => "observability.metrics.view",
+    "process_metrics" => "observability.metrics.view",
+    "network_metrics" => "observability.metrics.view",
+    "timeseries_metrics" => "observability.metrics.view",
+    "otel_metrics" => "observability.metrics.view"
+  }
+
+  # Entities whose SRQL filters accept partition:; devices are scoped through
+  # their identifiers instead.
+  @partition_filter_entities ~w(services logs device_updates cpu_metrics memory_metrics
+                                disk_metrics process_metrics network_metrics timeseries_metrics)
+
+  @doc "Returns the entity named by a query's `in:` clause, or nil."
+  @spec entity(String.t()) :: String.t() | nil
+  def entity(query) when is_binary(query) do
+    case Regex.run(~r/(?:^|\s)in:([A-Za-z_]+)/, query) do
+      [_, entity] -> String.downcase(entity)
+      nil -> nil
+    end
+  end
+
+  @doc """
+  RBAC permission required to see an entity's rows. Entities without a
+  dedicated permission fall back to `analytics.view`.
+  """
+  @spec required_permission(String.t()) :: String.t()
+  def required_permission(entity), do: Map.get(@view_permissions, entity, "analytics.view")
+
+  @doc "Whether reports on `entity` can be limited to a partition."
+  @spec partition_scoped?(String.t()) :: boolean()
+  def partition_scoped?(entity), do: entity == "devices" or partition_filter?(entity)
+
+  @doc "Whether `entity` queries accept a `partition:` filter."
+  @spec partition_filter?(String.t()) :: boolean()
+  def partition_filter?(entity), do: entity in @partition_filter_entities
+
+  @doc """
+  Returns the first time strictly after `after` (to the minute) matching the
+  cron expression, evaluated in UTC.
+  """
+  @spec next_run_at(String.t(), DateTime.t()) :: {:ok, DateTime.t()} | {:error, String.t()}
+  def next_run_at(cron, %DateTime{} = after) when is_binary(cron) do
+    with {:ok, expr} <- parse_cron(cron),
+         %DateTime{} = next <- Expression.next_at(expr, DateTime.shift_zone!(after, "Etc/UTC")) do
+      {:ok, next}
+    else
+      {:error, _} = error -> error
+      _ -> {:error, "cron expression never matches"}
+    end
+  end
+
+  defp parse_cron(cron) do
+    case Expression.parse(String.trim(cron)) do
+      {:ok, expr} -> {:ok, expr}
+      {:error, error} -> {:error, "invalid cron expression: #{Exception.message(error)}"}
+    end
+  end
+
+  @doc "Persists a report. The actor must be allowed to view the queried entity."
+  @spec create(map(), keyword()) :: {:ok, ScheduledReport.t()} | {:error, term()}
+  def create(attrs, opts), do: ScheduledReport.create(attrs, opts)
+
+  @doc "Updates a report, recomputing its next run time."
+  @spec update(ScheduledReport.t(), map(), keyword()) ::
+          {:ok, ScheduledReport.t()} | {:error, term()}
+  def update(report, attrs, opts), do: ScheduledReport.update(report, attrs, opts)
+
+  @doc """
+  Runs every enabled report whose next run time is at or before `now`.
+  Returns the reports that ran, with their recorded outcome.
+  """
+  @spec run_due(DateTime.t(), keyword()) :: [ScheduledReport.t()]
+  def run_due(now \\ DateTime.utc_now(), opts \\ []) do
+    case ScheduledReport.list_due(now, actor: system_actor()) do
+      {:ok, reports} ->
+        reports
+        |> Enum.map(&execute(&1, Keyword.put(opts, :now, now)))
+        |> Enum.flat_map(fn
+          {:ok, report} -> [report]
+          :skipped -> []
+        end)
+
+      {:error, reason} ->
+        Logger.warning("Failed to load due scheduled reports: #{inspect(reason)}")
+        []
+    end
+  end
+
+  @doc """
+  Claims and runs one report, recording the outcome. Returns `:skipped` when
+  another scheduler already claimed this run.
+  """
+  @spec execute(ScheduledReport.t(), keyword()) :: {:ok, ScheduledReport.t()} | :skipped
+  def execute(%ScheduledReport{} = report, opts \\ []) do
+    now = Keyword.get(opts, :now, DateTime.utc_now())
+
+    case claim(report, now) do
+      {:ok, next_run_at} ->
+        outcome = run(report, opts)
+        finish(report, outcome, now, next_run_at)
+
+      :skipped ->
+        :skipped
+    end
+  end
+
+  defp claim(report, now) do
+    next_run_at =
+      case next_run_at(report.cron, now) do
+        {:ok, next} -> next
+        {:error, _reason} -> nil
+      end
+
+    {count, _} =
+      from(r in ScheduledReport,
+        where: r.id == ^report.id and r.enabled and r.next_run_at == ^report.next_run_at
+      )
+      |> Repo.update_all(set: [next_run_at: next_run_at, last_run_at: now, updated_at: now])
+
+    if count == 1, do: {:ok, next_run_at}, else: :skipped
+  end
+
+  @doc """
+  Runs the report's query and delivers the rendered output, without touching
+  its schedule. Returns the row count and where the output was delivered.
+
+  `opts` may carry `:translate_fn` and `:query_fn` for the SRQL runner, and
+  the delivery overrides accepted by `ScheduledReports.Delivery.deliver/3`.
+  """
+  @spec run(ScheduledReport.t(), keyword()) ::
+          {:ok, %{rows: non_neg_integer(), location: String.t()}} | {:error, term()}
+  def run(%ScheduledReport{} = report, opts \\ []) do
+    entity = entity(report.query)
+
+    with {:ok, actor} <- requesting_actor(report),
+         :ok <- authorize(actor, entity),
+         {:ok, rows} <- fetch_rows(report, entity, actor, opts),
+         {:ok, output} <- Renderer.render(rows, report.format, report_name(report)),
+         {:ok, location} <- Delivery.deliver(report, output, opts) do
+      {:ok, %{rows: length(rows), location: location}}
+    end
+  rescue
+    e ->
+      Logger.error("Scheduled report #{report.id} crashed: #{inspect(e)}")
+      {:error, Exception.message(e)}
+  end
+
+  defp requesting_actor(%{requested_by_id: nil}), do: {:ok, system_actor()}
+
+  defp requesting_actor(%{requested_by_id: user_id}) do
+    case User.get_by_id(user_id, actor: system_actor()) do
+      {:ok, %User{status: :active} = user} -> {:ok, user}
+      _ -> {:error, "requesting user no longer exists or is inactive"}
+    end
+  end
+
+  defp authorize(_actor, nil), do: {:error, "query must name its entity with in:<entity>"}
+
+  defp authorize(actor, entity) do
+    cond do
+      SystemActor.system_actor?(actor) ->
+        :ok
+
+      not RBAC.has_permission?(actor, @manage_permission) ->
+        {:error, "requesting user no longer has #{@manage_permission}"}
+
+      not RBAC.has_permission?(actor, required_permission(entity)) ->
+        {:error, "requesting user no longer has #{required_permission(entity)}"}
+
+      true ->
+        :ok
+    end
+  end
+
+  defp fetch_rows(report, entity, actor, opts) do
+    runner_opts =
+      opts
+      |> Keyword.take([:translate_fn, :query_fn])
+      |> Keyword.put(:limit, @max_rows + 1)
+
+    report.query
+    |> scoped_query(entity, report.partition)
+    |> SRQLRunner.query(runner_opts)
+    |> case do
+      {:ok, rows} when length(rows) > @max_rows ->
+        {:error, "query returned more than #{@max_rows} rows"}
+
+      {:ok, rows} ->
+        scope_devices(rows, entity, report.partition, actor)
+
+      {:error, reason} ->
+        {:error, error_message(reason)}
+    end
+  end
+
+  defp scoped_query(query, entity, partition)
+       when is_binary(partition) and partition != "" and entity in @partition_filter_entities do
+    ~s(#{query} partition:"#{String.replace(partition, "\"", "")}")
+  end
+
+  defp scoped_query(query, _entity, _partition), do: query
+
+  defp scope_devices(rows, "devices", partition, actor)
+       when is_binary(partition) and partition != "" do
+    uids = rows |> Enum.map(&Map.get(&1, "uid")) |> Enum.filter(&is_binary/1)
+
+    DeviceIdentifier
+    |> Ash.Query.filter(partition == ^partition and device_id in ^uids)
+    |> Ash.Query.select([:device_id])
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+    |> case do
+      {:ok, identifiers} ->
+        in_partition = MapSet.new(identifiers, & &1.device_id)
+        {:ok, Enum.filter(rows, &MapSet.member?(in_partition, Map.get(&1, "uid")))}
+
+      {:error, reason} ->
+        {:error, error_message(reason)}
+    end
+  end
+
+  defp scope_devices(rows, _entity, _partition, _actor), do: {:ok, rows}
+
+  defp finish(report, outcome, now, next_run_at) do
+    {status, row_count, error} =
+      case outcome do
+        {:ok, %{rows: rows}} -> {:succeeded, rows, nil}
+        {:error, reason} -> {:failed, nil, error_message(reason)}
+      end
+
+    {:ok, finished} =
+      report
+      |> Ash.Changeset.for_update(
+        :record_run,
+        %{
+          next_run_at: next_run_at,
+          last_run_at: now,
+          last_status: status,
+          last_error: error,
+          last_row_count: row_count
+        },
+        actor: system_actor()
+      )
+      |> Ash.update()
+
+    audit(finished, outcome)
+    {:ok, finished}
+  end
+
+  defp audit(report, outcome) do
+    location =
+      case outcome do
+        {:ok, %{location: location}} -> location
+        _ -> nil
+      end
+
+    AuditWriter.write_async(
+      action: :scheduled_report_run,
+      resource_type: "scheduled_report",
+      resource_id: report.id,
+      resource_name: report.name,
+      actor: %{id: report.requested_by_id, email: report.requested_by},
+      severity: if(report.last_status == :failed, do: :medium, else: :informational),
+      message: "Scheduled report #{report.name} #{report.last_status}",
+      details: %{
+        query: report.query,
+        format: report.format,
+        delivery: report.delivery,
+        partition: report.partition,
+        status: report.last_status,
+        rows: report.last_row_count,
+        location: location,
+        error: report.last_error,
+        next_run_at: report.next_run_at
+      }
+    )
+  end
+
+  defp report_name(%{name: name}) when is_binary(name) and name != "", do: name
+  defp report_name(_report), do: "report"
+
+  defp error_message(reason) when is_binary(reason), do: reason
+  defp error_message(%{__exception__: true} = error), do: Exception.message(error)
+  defp error_message(reason), do: inspect(reason)
+
+  defp system_actor, do: SystemActor.system(:scheduled_reports)
+end
@@ -0,0 +1,141 @@
+defmodule ServiceRadar.Observability.ScheduledReports.Delivery do
+  @moduledoc """
+  Delivers a rendered scheduled report to its target.
+
+  - `:email` mails the report as an attachment through `ServiceRadar.Mailer`
+  - `:webhook` POSTs the report as the request body; the URL must pass the
+    outbound URL policy (https, public host)
+  - `:object_store` uploads the report to the datasvc object store under
+    `<prefix>/<report name>/<run time>.<ext>`
+
+  Each returns a description of where the report went, for the run event.
+  """
+
+  import Swoosh.Email
+
+  alias ServiceRadar.Observability.OutboundFeedPolicy
+  alias ServiceRadar.Sync.Client, as: SyncClient
+
+  @from {"ServiceRadar", "noreply@serviceradar.cloud"}
+  @default_prefix "reports"
+  @default_timeout 30_000
+
+  @type opts :: [
+          mailer: module(),
+          validate_url: (String.t() -> :ok | {:error, term()}),
+          http_post: (String.t(), keyword() -> {:ok, Req.Response.t()} | {:error, term()}),
+          upload_object: (Proto.ObjectMetadata.t(), binary(), keyword() ->
+                            {:ok, term()} | {:error, term()}),
+          now: DateTime.t()
+        ]
+
+  @doc "Delivers `output` as configured on `report`."
+  @spec deliver(map(), ServiceRadar.Observability.ScheduledReports.Renderer.output(), opts()) ::
+          {:ok, String.t()} | {:error, term()}
+  def deliver(%{delivery: :email} = report, output, opts) do
+    recipients = List.wrap(param(report.target, :recipients))
+    mailer = Keyword.get(opts, :mailer, ServiceRadar.Mailer)
+
+    email =
+      new()
+      |> to(recipients)
+      |> from(@from)
+      |> subject("ServiceRadar report: #{report.name}")
+      |> text_body("""
+      The scheduled report "#{report.name}" is attached.
+
+      Query: #{report.query}
+      """)
+      |> attachment(
+        Swoosh.Attachment.new({:data, output.body},
+          filename: output.filename,
+          content_type: output.content_type
+        )
+      )
+
+    case mailer.deliver(email) do
+      {:ok, _} -> {:ok, "email:" <> Enum.join(recipients, ",")}
+      {:error, reason} -> {:error, "email delivery failed: #{inspect(reason)}"}
+    end
+  end
+
+  def deliver(%{delivery: :webhook} = report, output, opts) do
+    url = param(report.target, :url)
+    validate_url = Keyword.get(opts, :validate_url, &OutboundFeedPolicy.validate/1)
+    http_post = Keyword.get(opts, :http_post, &Req.post/2)
+
+    headers =
+      (param(report.target, :headers) || %{})
+      |> Map.new(fn {key, value} -> {String.downcase(to_string(key)), to_string(value)} end)
+      |> Map.put("content-type", output.content_type)
+      |> Map.put("content-disposition", ~s(attachment; filename="#{output.filename}"))
+
+    request_opts =
+      @default_timeout
+      |> OutboundFeedPolicy.req_opts()
+      |> Keyword.merge(body: output.body, headers: headers)
+
+    with {:url, url} when is_binary(url) <- {:url, url},
+         :ok <- validate_url.(url),
+         {:ok, %{status: status}} when status in 200..299 <- http_post.(url, request_opts) do
+      {:ok, "webhook:" <> OutboundFeedPolicy.redact_url(url)}
+    else
+      {:url, _} -> {:error, "webhook target has no url"}
+      {:ok, %{status: status}} -> {:error, "webhook returned HTTP #{status}"}
+      {:error, reason} -> {:error, "webhook failed: #{OutboundFeedPolicy.format_reason(reason)}"}
+    end
+  end
+
+  def deliver(%{delivery: :object_store} = report, output, opts) do
+    upload_object = Keyword.get(opts, :upload_object, &default_upload_object/3)
+    now = Keyword.get(opts, :now, DateTime.utc_now())
+    key = object_key(report, output, now)
+
+    metadata = %Proto.ObjectMetadata{
+      key: key,
+      content_type: output.content_type,
+      sha256: :sha256 |> :crypto.hash(output.body) |> Base.encode16(case: :lower),
+      total_size: byte_size(output.body),
+      attributes: %{
+        "scheduled_report_id" => to_string(report.id),
+        "scheduled_report_name" => report.name,
+        "file_name" => output.filename
+      }
+    }
+
+    case upload_object.(metadata, output.body, timeout: @default_timeout) do
+      {:ok, _response} -> {:ok, "object_store:" <> key}
+      {:error, reason} -> {:error, "object store upload failed: #{inspect(reason)}"}
+    end
+  end
+
+  def deliver(report, _output, _opts),
+    do: {:error, "unsupported report delivery: #{inspect(Map.get(report, :delivery))}"}
+
+  defp object_key(report, output, now) do
+    prefix =
+      case param(report.target, :prefix) do
+        prefix when is_binary(prefix) and prefix != "" -> String.trim(prefix, "/")
+        _ -> @default_prefix
+      end
+
+    stamp = now |> DateTime.truncate(:second) |> DateTime.to_iso8601(:basic)
+    name = Path.rootname(output.filename)
+    Enum.join([prefix, name, stamp <> Path.extname(output.filename)], "/")
+  end
+
+  defp default_upload_object(metadata, data, opts) do
+    timeout = Keyword.get(opts, :timeout, @default_timeout)
+
+    ServiceRadar.DataService.Client.with_channel(
+      fn channel -> SyncClient.upload_object(channel, metadata, data, timeout: timeout) end,
+      timeout: timeout
+    )
+  end
+
+  defp param(params, key) when is_map(params) do
+    Map.get(params, Atom.to_string(key)) || Map.get(params, key)
+  end
+
+  defp param(_params, _key), do: nil
+end
@@ -0,0 +1,216 @@
+defmodule ServiceRadar.Observability.ScheduledReports.Renderer do
+  @moduledoc """
+  Renders SRQL result rows as a scheduled report document.
+
+  Columns are the union of the rows' keys, sorted by name. Values are
+  flattened for output: timestamps as ISO 8601, addresses as text, and maps
+  or lists as JSON.
+
+  PDF output is a plain monospaced table in landscape US Letter, with cells
+  truncated to 32 characters; it needs no external renderer.
+  """
+
+  @type output :: %{body: binary(), content_type: String.t(), filename: String.t()}
+
+  @pdf_cell_width 32
+  @pdf_font_size 8
+  @pdf_leading 10
+  @pdf_page_width 792
+  @pdf_page_height 612
+  @pdf_margin 36
+  @pdf_max_line 150
+
+  @doc "Renders `rows` in `format`, naming the file after the report."
+  @spec render([map()], :csv | :json | :pdf, String.t()) :: {:ok, output()} | {:error, term()}
+  def render(rows, format, name) when is_list(rows) do
+    columns = columns(rows)
+    basename = slug(name)
+
+    case format do
+      :csv ->
+        {:ok, output(csv(columns, flatten_rows(rows)), "text/csv", basename <> ".csv")}
+
+      :json ->
+        body = rows |> Enum.map(&normalize_json/1) |> Jason.encode_to_iodata!()
+        {:ok, output(body, "application/json", basename <> ".json")}
+
+      :pdf ->
+        body = pdf(name, columns, flatten_rows(rows))
+        {:ok, output(body, "application/pdf", basename <> ".pdf")}
+
+      other ->
+        {:error, "unsupported report format: #{inspect(other)}"}
+    end
+  end
+
+  defp output(body, content_type, filename),
+    do: %{body: IO.iodata_to_binary(body), content_type: content_type, filename: filename}
+
+  defp columns(rows) do
+    rows
+    |> Enum.flat_map(&Map.keys/1)
+    |> Enum.map(&to_string/1)
+    |> Enum.uniq()
+    |> Enum.sort()
+  end
+
+  defp flatten_rows(rows) do
+    Enum.map(rows, fn row ->
+      Map.new(row, fn {key, value} -> {to_string(key), flatten(value)} end)
+    end)
+  end
+
+  defp flatten(nil), do: nil
+  defp flatten(value) when is_binary(value) or is_number(value) or is_boolean(value), do: value
+  defp flatten(%DateTime{} = value), do: DateTime.to_iso8601(value)
+  defp flatten(%NaiveDateTime{} = value), do: NaiveDateTime.to_iso8601(value)
+  defp flatten(%Date{} = value), do: Date.to_iso8601(value)
+  defp flatten(%Decimal{} = value), do: Decimal.to_string(value)
+
+  defp flatten(%Postgrex.INET{address: address, netmask: netmask}) do
+    ip = address |> :inet.ntoa() |> to_string()
+    if netmask in [nil, 32, 128], do: ip, else: "#{ip}/#{netmask}"
+  end
+
+  defp flatten(value) when is_atom(value), do: Atom.to_string(value)
+
+  defp flatten(value) when is_map(value) or is_list(value) do
+    value |> normalize_json() |> Jason.encode!()
+  end
+
+  defp flatten(value), do: inspect(value)
+
+  defp normalize_json(value) when is_map(value) and not is_struct(value),
+    do: Map.new(value, fn {key, inner} -> {to_string(key), normalize_json(inner)} end)
+
+  defp normalize_json(value) when is_list(value), do: Enum.map(value, &normalize_json/1)
+  defp normalize_json(value), do: flatten(value)
+
+  defp csv(columns, rows) do
+    lines = [Enum.map(columns, &csv_cell/1) | Enum.map(rows, &csv_line(&1, columns))]
+    Enum.map(lines, &[Enum.intersperse(&1, ","), "\r\n"])
+  end
+
+  defp csv_line(row, columns), do: Enum.map(columns, &csv_cell(Map.get(row, &1)))
+
+  defp csv_cell(nil), do: ""
+
+  defp csv_cell(value) do
+    text = to_string(value)
+
+    if String.contains?(text, [",", "\"", "\r", "\n"]),
+      do: [?", String.replace(text, "\"", "\"\""), ?"],
+      else: text
+  end
+
+  defp pdf(name, columns, rows) do
+    header = table_line(columns)
+    generated = DateTime.utc_now() |> DateTime.truncate(:second) |> DateTime.to_iso8601()
+
+    rule = String.duplicate("-", String.length(header))
+
+    lines =
+      [name, "Generated #{generated}", "", header, rule] ++
+        Enum.map(rows, fn row -> table_line(Enum.map(columns, &pdf_cell(Map.get(row, &1)))) end)
+
+    lines_per_page = div(@pdf_page_height - 2 * @pdf_margin, @pdf_leading)
+
+    lines
+    |> Enum.map(&String.slice(&1, 0, @pdf_max_line))
+    |> Enum.chunk_every(lines_per_page)
+    |> pdf_document()
+  end
+
+  defp table_line(cells) do
+    cells
+    |> Enum.map(fn cell ->
+      cell |> String.slice(0, @pdf_cell_width) |> String.pad_trailing(@pdf_cell_width)
+    end)
+    |> Enum.join(" ")
+    |> String.trim_trailing()
+  end
+
+  defp pdf_cell(nil), do: ""
+  defp pdf_cell(value), do: to_string(value)
+
+  # Objects: 1 catalog, 2 page tree, 3 font, then a page and its content
+  # stream for each page.
+  defp pdf_document(pages) do
+    page_ids = Enum.map(0..(length(pages) - 1)//1, &(4 + 2 * &1))
+    kids = Enum.map_join(page_ids, " ", &"#{&1} 0 R")
+
+    objects =
+      [
+        "<< /Type /Catalog /Pages 2 0 R >>",
+        "<< /Type /Pages /Kids [#{kids}] /Count #{length(pages)} >>",
+        "<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>"
+      ] ++
+        Enum.flat_map(Enum.zip(page_ids, pages), fn {page_id, lines} ->
+          stream = pdf_page_stream(lines)
+
+          [
+            "<< /Type /Page /Parent 2 0 R " <>
+              "/MediaBox [0 0 #{@pdf_page_width} #{@pdf_page_height}] " <>
+              "/Resources << /Font << /F1 3 0 R >> >> /Contents #{page_id + 1} 0 R >>",
+            "<< /Length #{byte_size(stream)} >>\nstream\n#{stream}\nendstream"
+          ]
+        end)
+
+    pdf_with_xref(objects)
+  end
+
+  defp pdf_page_stream(lines) do
+    top = @pdf_page_height - @pdf_margin - @pdf_font_size
+
+    text = Enum.map_join(lines, "\n", fn line -> "(#{pdf_escape(line)}) Tj T*" end)
+
+    "BT /F1 #{@pdf_font_size} Tf #{@pdf_leading} TL #{@pdf_margin} #{top} Td\n#{text}\nET"
+  end
+
+  defp pdf_with_xref(objects) do
+    header = "%PDF-1.4\n"
+
+    {body, offsets, _} =
+      objects
+      |> Enum.with_index(1)
+      |> Enum.reduce({[], [], byte_size(header)}, fn {object, id}, {body, offsets, offset} ->
+        chunk = "#{id} 0 obj\n#{object}\nendobj\n"
+        {[body, chunk], [offset | offsets], offset + byte_size(chunk)}
+      end)
+
+    xref_offset = IO.iodata_length([header, body])
+    count = length(objects) + 1
+
+    entries =
+      offsets
+      |> Enum.reverse()
+      |> Enum.map(&(String.pad_leading(Integer.to_string(&1), 10, "0") <> " 00000 n \n"))
+
+    [
+      header,
+      body,
+      "xref\n0 #{count}\n0000000000 65535 f \n",
+      entries,
+      "trailer\n<< /Size #{count} /Root 1 0 R >>\nstartxref\n#{xref_offset}\n%%EOF\n"
+    ]
+  end
+
+  # Courier in the standard encoding covers printable ASCII only.
+  defp pdf_escape(text) do
+    text
+    |> String.replace(~r/[^\x20-\x7E]/u, "?")
+    |> String.replace("\\", "\\\\")
+    |> String.replace("(", "\\(")
+    |> String.replace(")", "\\)")
+  end
+
+  defp slug(name) do
+    slug =
+      name
+      |> String.downcase()
+      |> String.replace(~r/[^a-z0-9._-]+/, "-")
+      |> String.trim("-")
+
+    if slug == "", do: "report", else: slug
+  end
+end
@@ -22,6 +22,7 @@ defmodule ServiceRadar.Observability.SnmpMetricsIngestor do
   alias ServiceRadar.Actors.SystemActor
   alias ServiceRadar.EventWriter.FieldParser
   alias ServiceRadar.Identity.DeviceLookup
+  alias ServiceRadar.Observability.MetricDerivation
   alias ServiceRadar.Observability.TimeseriesMetric
   alias ServiceRadar.Observability.TimeseriesSeriesKey
 
@@ -38,6 +39,7 @@ defmodule ServiceRadar.Observability.SnmpMetricsIngestor do
       |> Enum.map(&build_metric_row(&1, status, actor, created_at))
       |> Enum.reject(&is_nil/1)
       |> TimeseriesSeriesKey.dedupe_rows()
+      |> MetricDerivation.append_derived()
 
     if rows == [] do
       :ok
@@ -0,0 +1,128 @@
+defmodule ServiceRadar.Observability.SRQLView do
+  @moduledoc """
+  A named SRQL query that can itself be queried like an entity, such as
+  `linux_high_risk` for `in:devices risk_level:high os:%linux%`.
+
+  `ServiceRadar.Observability.SRQLViews.expand/2` inlines the definition
+  whenever a query selects `in:<name>`, so further filters, sorting and
+  stats apply on top of the view. A view can be built on another view, but
+  never on itself, directly or through other views.
+
+  Views with a `partition` only return rows from that partition. Reading
+  views needs `analytics.view`; creating or changing them needs
+  `analytics.manage_queries` and the view permission of the entity the view
+  is built on, which is also required to query it.
+  """
+
+  use Ash.Resource,
+    domain: ServiceRadar.Observability,
+    data_layer: AshPostgres.DataLayer,
+    authorizers: [Ash.Policy.Authorizer]
+
+  alias ServiceRadar.Policies.Checks.ActorHasPermission
+
+  @analytics_view_check {ActorHasPermission, permission: "analytics.view"}
+  @manage_queries_check {ActorHasPermission, permission: "analytics.manage_queries"}
+  @view_fields [:name, :description, :query, :partition]
+
+  postgres do
+    table "srql_views"
+    repo ServiceRadar.Repo
+    schema "platform"
+    migrate? false
+  end
+
+  code_interface do
+    define :get_by_id, action: :read, get_by: [:id]
+    define :get_by_name, action: :read, get_by: [:name]
+    define :create, action: :create
+    define :update, action: :update
+    define :destroy, action: :destroy
+  end
+
+  actions do
+    defaults [:read, :destroy]
+
+    create :create do
+      accept @view_fields
+      validate ServiceRadar.Observability.Validations.SRQLView
+
+      change fn changeset, context ->
+        actor = Map.get(context, :actor)
+
+        changeset
+        |> downcase_name()
+        |> Ash.Changeset.force_change_attribute(:created_by, actor_label(actor))
+      end
+    end
+
+    update :update do
+      accept @view_fields
+      require_atomic? false
+      validate ServiceRadar.Observability.Validations.SRQLView
+
+      change fn changeset, _context ->
+        downcase_name(changeset)
+      end
+    end
+  end
+
+  policies do
+    import ServiceRadar.Policies
+
+    system_bypass()
+    read_with_permission(@analytics_view_check)
+    action_with_permission([:create, :update, :destroy], @manage_queries_check)
+  end
+
+  attributes do
+    uuid_primary_key :id
+
+    attribute :name, :string do
+      allow_nil? false
+      public? true
+      constraints min_length: 1, max_length: 63
+      description "Name to query the view by (in:<name>)"
+    end
+
+    attribute :description, :string do
+      public? true
+    end
+
+    attribute :query, :string do
+      allow_nil? false
+      public? true
+      description "SRQL query the view stands for"
+    end
+
+    attribute :partition, :string do
+      public? true
+      description "Only return rows from this partition"
+    end
+
+    attribute :created_by, :string do
+      public? true
+    end
+
+    create_timestamp :inserted_at
+    update_timestamp :updated_at
+  end
+
+  identities do
+    identity :unique_name, [:name]
+  end
+
+  defp downcase_name(changeset) do
+    case Ash.Changeset.get_attribute(changeset, :name) do
+      name when is_binary(name) ->
+        Ash.Changeset.force_change_attribute(changeset, :name, String.downcase(name))
+
+      _ ->
+        changeset
+    end
+  end
+
+  defp actor_label(%{email: email}) when not is_nil(email), do: to_string(email)
+  defp actor_label(%{id: id}) when not is_nil(id), do: to_string(id)
+  defp actor_label(_actor), do: nil
+end
@@ -0,0 +1,229 @@
+defmodule ServiceRadar.Observability.SRQLViews do
+  @moduledoc """
+  Inlines SRQL views (`ServiceRadar.Observability.SRQLView`) into queries
+  before they are translated.
+
+  A view is a saved query that can be queried like an entity. `expand/2`
+  replaces the top-level `in:<view>` of a query with the view's definition,
+  so the rest of the query narrows the view's results:
+
+      # view "linux_high_risk": in:devices risk_level:high os:%linux%
+      in:linux_high_risk hostname:%web% sort:hostname:asc
+      # => in:devices risk_level:high os:%linux% hostname:%web% sort:hostname:asc
+
+    * a view may be defined over another view, at most 5 levels deep;
+      definitions that lead back to a view already being expanded are
+      rejected
+    * a view scoped to a partition adds a `partition:` filter
+    * querying a view needs the view permission of the entity it is built on
+    * `in:` targets inside subqueries are not expanded
+
+  Names of built-in entities always refer to the entity, never to a view.
+  """
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Identity.RBAC
+  alias ServiceRadar.Observability.ScheduledReports
+  alias ServiceRadar.Observability.SRQLView
+
+  @max_depth 5
+  @name_pattern ~r/^[A-Za-z][A-Za-z0-9_]*$/
+
+  # Entity names and aliases accepted by the SRQL parser (`parse_entity` in
+  # rust/srql/src/parser.rs). Keep in sync so views cannot shadow them.
+  @entities ~w(
+    agents agent ocsf_agents devices device device_inventory device_graph devicegraph graph
+    graph_cypher graphcypher cypher device_updates device_update updates interfaces interface
+    discovered_interfaces events activity bmp_events bmp_event bmp_routing_events
+    field_survey_sessions fieldsurvey_sessions survey_sessions field_survey_rasters
+    fieldsurvey_rasters survey_coverage_rasters survey_rasters field_survey_artifacts
+    fieldsurvey_artifacts survey_room_artifacts survey_artifacts field_survey_rf_observations
+    fieldsurvey_rf_observations survey_rf_observations field_survey_pose_samples
+    fieldsurvey_pose_samples survey_pose_samples field_survey_rf_pose_matches
+    fieldsurvey_rf_pose_matches survey_rf_pose_matches field_survey_spectrum_observations
+    fieldsurvey_spectrum_observations survey_spectrum_observations wifi_sites wifi_site_map
+    wifi_map_sites wifi_site_snapshots wifi_snapshots wifi_aps wifi_access_points
+    wifi_ap_observations wifi_controllers wifi_wlcs wifi_controller_observations
+    wifi_radius_groups wifi_radius_group_observations wifi_fleet_history wifi_history
+    wifi_site_references wifi_airport_references wifi_references logs services service gateways
+    gateway otel_metrics metrics rperf_metrics rperf cpu_metrics cpu memory_metrics memory
+    disk_metrics disk network_metrics network process_metrics processes timeseries_metrics
+    timeseries snmp_metrics snmp otel_trace_summaries trace_summaries traces_summaries
+    otel_traces traces trace_spans flows flow network_activity alerts alert certificates
+    certificate certs cert_expiry
+  )
+
+  @doc "Whether `name` is a built-in SRQL entity (or one of its aliases)."
+  @spec builtin_entity?(String.t()) :: boolean()
+  def builtin_entity?(name) when is_binary(name), do: String.downcase(name) in @entities
+
+  @doc """
+  Inlines the views referenced by `query`.
+
+  Options:
+
+    * `:actor` - reads the views and must hold the view permission of the
+      entity they are built on
+    * `:resolve_fn` - looks up a view by name, returning `{:ok, view}` or
+      `:error`; defaults to reading `SRQLView` as the actor
+
+  Queries that do not reference a view are returned unchanged. Unknown
+  names are left for the translator to reject.
+  """
+  @spec expand(String.t(), keyword()) :: {:ok, String.t()} | {:error, String.t()}
+  def expand(query, opts \\ []) when is_binary(query) do
+    actor = Keyword.get(opts, :actor)
+    resolve_fn = Keyword.get(opts, :resolve_fn, &load_view(&1, actor))
+
+    case inline(query, resolve_fn, []) do
+      {:ok, ^query} -> {:ok, query}
+      {:ok, expanded} -> authorize(actor, ScheduledReports.entity(expanded), expanded)
+      {:error, _} = error -> error
+    end
+  end
+
+  @doc """
+  Checks that `query` can define the view `name`: it must select from an
+  entity or another view without leading back to `name`. Returns the entity
+  the view is built on.
+  """
+  @spec check_definition(String.t(), String.t(), keyword()) ::
+          {:ok, String.t()} | {:error, String.t()}
+  def check_definition(name, query, opts \\ []) when is_binary(name) and is_binary(query) do
+    name = String.downcase(name)
+    actor = Keyword.get(opts, :actor)
+    resolve_fn = Keyword.get(opts, :resolve_fn, &load_view(&1, actor))
+
+    resolve = fn
+      ^name -> {:ok, %{query: query, partition: nil}}
+      other -> resolve_fn.(other)
+    end
+
+    with :ok <- check_target(query),
+         {:ok, expanded} <- inline("in:" <> name, resolve, []) do
+      entity = ScheduledReports.entity(expanded)
+
+      if is_binary(entity) and builtin_entity?(entity),
+        do: {:ok, entity},
+        else: {:error, "must select from an entity or an existing view"}
+    end
+  end
+
+  defp check_target(query) do
+    if String.starts_with?(String.trim(query), "in:"),
+      do: :ok,
+      else: {:error, "must start with in:<entity>"}
+  end
+
+  defp inline(query, resolve_fn, path) do
+    case view_reference(query) do
+      {name, start, length} ->
+        inline_view(query, name, {start, length}, resolve_fn, path)
+
+      nil ->
+        {:ok, query}
+    end
+  end
+
+  defp inline_view(query, name, span, resolve_fn, path) do
+    cond do
+      name in path ->
+        chain = [name | path] |> Enum.reverse() |> Enum.join(" -> ")
+        {:error, "recursive view definition: #{chain}"}
+
+      length(path) >= @max_depth ->
+        {:error, "views can be nested at most #{@max_depth} levels deep"}
+
+      true ->
+        case resolve_fn.(name) do
+          {:ok, view} ->
+            view.query
+            |> String.trim()
+            |> inline(resolve_fn, [name | path])
+            |> case do
+              {:ok, definition} -> {:ok, splice(query, span, scope(definition, view))}
+              {:error, _} = error -> error
+            end
+
+          :error ->
+            {:ok, query}
+        end
+    end
+  end
+
+  defp view_reference(query) do
+    query
+    |> token_spans()
+    |> Enum.find(fn {start, length} ->
+      query |> binary_part(start, length) |> String.starts_with?("in:")
+    end)
+    |> case do
+      {start, length} ->
+        "in:" <> name = binary_part(query, start, length)
+
+        if Regex.match?(@name_pattern, name) and not builtin_entity?(name),
+          do: {String.downcase(name), start, length}
+
+      nil ->
+        nil
+    end
+  end
+
+  # Splits a query into the {start, length} of its whitespace-separated
+  # tokens. Quoted strings and parenthesised lists (including subqueries)
+  # stay inside their token.
+  defp token_spans(query), do: token_spans(query, 0, nil, 0, false, [])
+
+  defp token_spans(<<>>, pos, start, _depth, _quoted?, acc),
+    do: Enum.reverse(add_span(acc, start, pos))
+
+  defp token_spans(<<?", rest::binary>>, pos, start, depth, quoted?, acc),
+    do: token_spans(rest, pos + 1, start || pos, depth, not quoted?, acc)
+
+  defp token_spans(<<c, rest::binary>>, pos, start, depth, false, acc) when c in [?(, ?)] do
+    depth = if c == ?(, do: depth + 1, else: max(depth - 1, 0)
+    token_spans(rest, pos + 1, start || pos, depth, false, acc)
+  end
+
+  defp token_spans(<<c, rest::binary>>, pos, start, 0, false, acc) when c in ~c" \t\r\n",
+    do: token_spans(rest, pos + 1, nil, 0, false, add_span(acc, start, pos))
+
+  defp token_spans(<<_c, rest::binary>>, pos, start, depth, quoted?, acc),
+    do: token_spans(rest, pos + 1, start || pos, depth, quoted?, acc)
+
+  defp add_span(acc, nil, _pos), do: acc
+  defp add_span(acc, start, pos), do: [{start, pos - start} | acc]
+
+  defp splice(query, {start, length}, definition) do
+    binary_part(query, 0, start) <>
+      definition <> binary_part(query, start + length, byte_size(query) - start - length)
+  end
+
+  defp scope(definition, %{partition: partition})
+       when is_binary(partition) and partition != "" do
+    ~s(#{definition} partition:"#{String.replace(partition, "\"", "")}")
+  end
+
+  defp scope(definition, _view), do: definition
+
+  defp authorize(actor, entity, expanded) do
+    cond do
+      SystemActor.system_actor?(actor) ->
+        {:ok, expanded}
+
+      is_binary(entity) and not is_nil(actor) and
+          RBAC.has_permission?(actor, ScheduledReports.required_permission(entity)) ->
+        {:ok, expanded}
+
+      true ->
+        {:error, "not permitted to view #{entity || "the view's entity"}"}
+    end
+  end
+
+  defp load_view(name, actor) do
+    case SRQLView.get_by_name(name, actor: actor) do
+      {:ok, %SRQLView{} = view} -> {:ok, view}
+      _ -> :error
+    end
+  end
+end
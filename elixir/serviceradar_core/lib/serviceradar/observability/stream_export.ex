@@ -0,0 +1,293 @@
+defmodule ServiceRadar.Observability.StreamExport do
+  @moduledoc """
+  Publishes continuous SRQL query results to Kafka.
+
+  Each stream-export job runs an SRQL query on a poll interval over the time
+  window since its previous run and publishes every result row to a topic,
+  one message per row. The message value is the row encoded as JSON; the key
+  is the value of the job's `key_field` (a column name, or a dotted path into
+  a JSON column such as `device.uid`), or empty when no key field is set.
+
+  ## Configuration
+
+      config :serviceradar_core, ServiceRadar.Observability.StreamExport,
+        jobs: [
+          %{
+            name: "critical-events",
+            query: "in:events severity_id:>=4",
+            topic: "serviceradar.events",
+            key_field: "device.uid",
+            brokers: ["kafka-1:9093", "kafka-2:9093"],
+            sasl: %{mechanism: "scram_sha_512", username: "sr", password: "secret"},
+            tls: %{cacertfile: "/etc/serviceradar/certs/kafka-ca.pem"},
+            poll_interval_ms: 5_000
+          }
+        ]
+
+  The query must not set `time:`; each run adds the window it covers. Delivery
+  is at least once: a batch that fails is retried in full after reconnecting,
+  and rows stamped exactly on a window boundary can be published twice.
+
+  Invalid jobs are logged and skipped.
+  """
+
+  use Supervisor
+
+  alias ServiceRadar.Observability.StreamExport.BrodProducer
+  alias ServiceRadar.Observability.StreamExport.KafkaSink
+
+  require Logger
+
+  @name_pattern ~r/^[A-Za-z0-9][A-Za-z0-9_.-]*$/
+  @sasl_mechanisms %{
+    "plain" => :plain,
+    "scram_sha_256" => :scram_sha_256,
+    "scram_sha_512" => :scram_sha_512
+  }
+  @default_poll_interval_ms 5_000
+  @min_poll_interval_ms 100
+  @default_page_size 500
+  @max_page_size 1_000
+
+  @type job :: %{
+          name: String.t(),
+          query: String.t(),
+          topic: String.t(),
+          key_field: [String.t()] | nil,
+          brokers: [{String.t(), :inet.port_number()}],
+          client_id: String.t(),
+          sasl: %{mechanism: atom(), username: String.t(), password: String.t()} | nil,
+          tls: boolean() | keyword(),
+          poll_interval_ms: pos_integer(),
+          page_size: pos_integer(),
+          lookback_seconds: non_neg_integer(),
+          producer: module(),
+          producer_opts: keyword()
+        }
+
+  def start_link(opts \\ []) do
+    Supervisor.start_link(__MODULE__, opts, name: Keyword.get(opts, :name, __MODULE__))
+  end
+
+  @doc """
+  Validates job definitions, returning the parsed jobs and the rejected ones
+  with the reason each was rejected. Disabled jobs are dropped.
+  """
+  @spec parse_jobs([map()]) :: {[job()], [{term(), String.t()}]}
+  def parse_jobs(definitions) when is_list(definitions) do
+    {valid, invalid} =
+      definitions
+      |> Enum.reject(&(is_map(&1) and fetch(&1, :enabled) == false))
+      |> Enum.reduce({[], []}, fn definition, {valid, invalid} ->
+        case parse_job(definition, valid) do
+          {:ok, job} -> {[job | valid], invalid}
+          {:error, reason} -> {valid, [{job_label(definition), reason} | invalid]}
+        end
+      end)
+
+    {Enum.reverse(valid), Enum.reverse(invalid)}
+  end
+
+  def parse_jobs(_definitions), do: {[], [{nil, "jobs must be a list"}]}
+
+  @impl true
+  def init(opts) do
+    definitions =
+      Keyword.get_lazy(opts, :jobs, fn ->
+        :serviceradar_core
+        |> Application.get_env(__MODULE__, [])
+        |> Keyword.get(:jobs, [])
+      end)
+
+    {jobs, invalid} = parse_jobs(definitions)
+
+    Enum.each(invalid, fn {name, reason} ->
+      Logger.error("Rejected stream export job #{inspect(name)}: #{reason}")
+    end)
+
+    if jobs != [] do
+      Logger.info("Starting #{length(jobs)} stream export job(s)")
+    end
+
+    children =
+      Enum.map(jobs, fn job ->
+        Supervisor.child_spec({KafkaSink, job: job}, id: {KafkaSink, job.name})
+      end)
+
+    Supervisor.init(children, strategy: :one_for_one)
+  end
+
+  defp parse_job(definition, parsed) when is_map(definition) do
+    name = fetch(definition, :name)
+    query = fetch(definition, :query)
+    topic = fetch(definition, :topic)
+
+    cond do
+      not is_binary(name) or not Regex.match?(@name_pattern, name) ->
+        {:error, "name must be letters, digits, '.', '_' or '-'"}
+
+      Enum.any?(parsed, &(&1.name == name)) ->
+        {:error, "duplicate job name"}
+
+      not is_binary(query) or String.trim(query) == "" ->
+        {:error, "query is required"}
+
+      Regex.match?(~r/(^|\s)time:/, query) ->
+        {:error, "query must not set time:, the export adds its own window"}
+
+      not is_binary(topic) or String.trim(topic) == "" ->
+        {:error, "topic is required"}
+
+      true ->
+        with {:ok, key_field} <- parse_key_field(fetch(definition, :key_field)),
+             {:ok, brokers} <- parse_brokers(fetch(definition, :brokers)),
+             {:ok, sasl} <- parse_sasl(fetch(definition, :sasl)),
+             {:ok, tls} <- parse_tls(fetch(definition, :tls)),
+             {:ok, poll_interval_ms} <-
+               parse_integer(
+                 fetch(definition, :poll_interval_ms),
+                 @default_poll_interval_ms,
+                 @min_poll_interval_ms,
+                 nil,
+                 "poll_interval_ms"
+               ),
+             {:ok, page_size} <-
+               parse_integer(
+                 fetch(definition, :page_size),
+                 @default_page_size,
+                 1,
+                 @max_page_size,
+                 "page_size"
+               ),
+             {:ok, lookback_seconds} <-
+               parse_integer(fetch(definition, :lookback_seconds), 0, 0, nil, "lookback_seconds"),
+             {:ok, producer} <- parse_producer(fetch(definition, :producer)) do
+          {:ok,
+           %{
+             name: name,
+             query: String.trim(query),
+             topic: String.trim(topic),
+             key_field: key_field,
+             brokers: brokers,
+             client_id: client_id(fetch(definition, :client_id), name),
+             sasl: sasl,
+             tls: tls,
+             poll_interval_ms: poll_interval_ms,
+             page_size: page_size,
+             lookback_seconds: lookback_seconds,
+             producer: producer,
+             producer_opts: List.wrap(fetch(definition, :producer_opts))
+           }}
+        end
+    end
+  end
+
+  defp parse_job(_definition, _parsed), do: {:error, "job must be a map"}
+
+  defp parse_key_field(nil), do: {:ok, nil}
+  defp parse_key_field(""), do: {:ok, nil}
+
+  defp parse_key_field(field) when is_binary(field) do
+    path = String.split(field, ".")
+
+    if Enum.any?(path, &(&1 == "")),
+      do: {:error, "key_field must be a column name or dotted path"},
+      else: {:ok, path}
+  end
+
+  defp parse_key_field(_field), do: {:error, "key_field must be a string"}
+
+  defp parse_brokers([_ | _] = brokers) do
+    Enum.reduce_while(brokers, {:ok, []}, fn broker, {:ok, acc} ->
+      case parse_broker(broker) do
+        {:ok, endpoint} -> {:cont, {:ok, acc ++ [endpoint]}}
+        :error -> {:halt, {:error, "invalid broker #{inspect(broker)}, expected host:port"}}
+      end
+    end)
+  end
+
+  defp parse_brokers(_brokers), do: {:error, "brokers must be a non-empty list of host:port"}
+
+  defp parse_broker(broker) when is_binary(broker) do
+    with [host, port] when host != "" <- String.split(broker, ":", parts: 2),
+         {port, ""} when port in 1..65_535 <- Integer.parse(port) do
+      {:ok, {host, port}}
+    else
+      _ -> :error
+    end
+  end
+
+  defp parse_broker(_broker), do: :error
+
+  defp parse_sasl(nil), do: {:ok, nil}
+
+  defp parse_sasl(sasl) when is_map(sasl) do
+    mechanism = fetch(sasl, :mechanism)
+    username = fetch(sasl, :username)
+    password = fetch(sasl, :password)
+
+    case Map.fetch(@sasl_mechanisms, mechanism_key(mechanism)) do
+      {:ok, mechanism} when is_binary(username) and is_binary(password) ->
+        {:ok, %{mechanism: mechanism, username: username, password: password}}
+
+      {:ok, _mechanism} ->
+        {:error, "sasl requires username and password"}
+
+      :error ->
+        {:error, "sasl mechanism must be plain, scram_sha_256 or scram_sha_512"}
+    end
+  end
+
+  defp parse_sasl(_sasl), do: {:error, "sasl must be a map"}
+
+  defp mechanism_key(mechanism) when is_atom(mechanism) or is_binary(mechanism),
+    do: mechanism |> to_string() |> String.downcase()
+
+  defp mechanism_key(_mechanism), do: nil
+
+  defp parse_tls(nil), do: {:ok, false}
+  defp parse_tls(enabled) when is_boolean(enabled), do: {:ok, enabled}
+
+  defp parse_tls(tls) when is_map(tls) do
+    verify = if fetch(tls, :verify) == false, do: :verify_none, else: :verify_peer
+
+    files =
+      for key <- [:cacertfile, :certfile, :keyfile],
+          path = fetch(tls, key),
+          is_binary(path),
+          do: {key, String.to_charlist(path)}
+
+    {:ok, [verify: verify] ++ files}
+  end
+
+  defp parse_tls(_tls), do: {:error, "tls must be a boolean or a map"}
+
+  defp parse_integer(nil, default, _min, _max, _field), do: {:ok, default}
+
+  defp parse_integer(value, _default, min, max, field) when is_integer(value) do
+    if value >= min and (is_nil(max) or value <= max),
+      do: {:ok, value},
+      else: {:error, "#{field} must be between #{min} and #{max || "unbounded"}"}
+  end
+
+  defp parse_integer(_value, _default, _min, _max, field),
+    do: {:error, "#{field} must be an integer"}
+
+  defp client_id(client_id, _name) when is_binary(client_id) and client_id != "", do: client_id
+  defp client_id(_client_id, name), do: "serviceradar-#{name}"
+
+  defp parse_producer(nil), do: {:ok, BrodProducer}
+
+  defp parse_producer(module) when is_atom(module) do
+    if Code.ensure_loaded?(module) and function_exported?(module, :produce, 3),
+      do: {:ok, module},
+      else: {:error, "producer #{inspect(module)} does not implement StreamExport.Producer"}
+  end
+
+  defp parse_producer(_module), do: {:error, "producer must be a module"}
+
+  defp job_label(definition) when is_map(definition), do: fetch(definition, :name)
+  defp job_label(definition), do: definition
+
+  defp fetch(map, key), do: Map.get(map, key, Map.get(map, Atom.to_string(key)))
+end
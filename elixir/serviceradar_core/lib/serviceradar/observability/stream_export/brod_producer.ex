@@ -0,0 +1,64 @@
+defmodule ServiceRadar.Observability.StreamExport.BrodProducer do
+  @moduledoc """
+  Kafka producer backed by `:brod`.
+
+  `:brod` is an optional dependency: add `{:brod, "~> 4.0"}` to the release
+  to publish to Kafka. Without it `connect/1` returns
+  `{:error, :kafka_client_unavailable}` and the job keeps retrying.
+
+  Messages are partitioned by a hash of their key and published with
+  `produce_sync`, so a batch is acknowledged before the next one is sent.
+  """
+
+  @behaviour ServiceRadar.Observability.StreamExport.Producer
+
+  @impl true
+  def connect(config) do
+    if Code.ensure_loaded?(:brod) do
+      client = String.to_atom("stream_export_" <> config.client_id)
+
+      client_config =
+        [
+          auto_start_producers: true,
+          default_producer_config: [],
+          reconnect_cool_down_seconds: 5,
+          client_id: config.client_id
+        ] ++ ssl_config(config.tls) ++ sasl_config(config.sasl)
+
+      case apply(:brod, :start_client, [config.brokers, client, client_config]) do
+        :ok -> {:ok, client}
+        {:error, {:already_started, _pid}} -> {:ok, client}
+        {:error, reason} -> {:error, reason}
+      end
+    else
+      {:error, :kafka_client_unavailable}
+    end
+  end
+
+  @impl true
+  def produce(client, topic, messages) do
+    Enum.reduce_while(messages, :ok, fn %{key: key, value: value}, :ok ->
+      case apply(:brod, :produce_sync, [client, topic, :hash, key, value]) do
+        :ok -> {:cont, :ok}
+        {:error, reason} -> {:halt, {:error, reason}}
+      end
+    end)
+  end
+
+  @impl true
+  def close(client) do
+    apply(:brod, :stop_client, [client])
+    :ok
+  catch
+    :exit, _reason -> :ok
+  end
+
+  defp ssl_config(false), do: []
+  defp ssl_config(true), do: [ssl: true]
+  defp ssl_config(opts) when is_list(opts), do: [ssl: opts]
+
+  defp sasl_config(nil), do: []
+
+  defp sasl_config(%{mechanism: mechanism, username: username, password: password}),
+    do: [sasl: {mechanism, username, password}]
+end
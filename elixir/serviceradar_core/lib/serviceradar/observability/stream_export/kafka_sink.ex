@@ -0,0 +1,230 @@
+defmodule ServiceRadar.Observability.StreamExport.KafkaSink do
+  @moduledoc """
+  Runs one stream-export job: polls its SRQL query and publishes the rows.
+
+  Each poll covers the window from the previous watermark up to now. The
+  window is read one page at a time and a page is only fetched once the
+  previous one was acknowledged by the producer, so a slow or unavailable
+  broker holds the export back instead of buffering rows in memory. The
+  watermark only advances once every page of a window was published.
+
+  When connecting or publishing fails, the connection is closed and the same
+  page is retried after an exponential backoff.
+  """
+
+  use GenServer
+
+  alias ServiceRadar.Backoff
+  alias ServiceRadar.Observability.SRQLRunner
+  alias ServiceRadar.Types.Cidr
+
+  require Logger
+
+  @type stats :: %{
+          connected: boolean(),
+          published: non_neg_integer(),
+          failures: non_neg_integer(),
+          watermark: DateTime.t()
+        }
+
+  def start_link(opts) do
+    job = Keyword.fetch!(opts, :job)
+    name = Keyword.get(opts, :name, via(job.name))
+    GenServer.start_link(__MODULE__, opts, name: name)
+  end
+
+  @doc """
+  Returns publishing counters for a running job.
+  """
+  @spec stats(GenServer.server()) :: stats()
+  def stats(server), do: GenServer.call(server, :stats)
+
+  @doc """
+  Encodes a result row as a message: the value of `key_field` as the key and
+  the row as JSON. Values JSON cannot represent (inet addresses, binary
+  UUIDs) are converted to strings.
+  """
+  @spec encode_message(map(), [String.t()] | nil) :: %{key: binary(), value: binary()}
+  def encode_message(row, key_field) when is_map(row) do
+    row = normalize(row)
+    %{key: encode_key(row, key_field), value: Jason.encode!(row)}
+  end
+
+  @doc """
+  Returns the job query restricted to the window `[from, to]`.
+  """
+  @spec window_query(String.t(), DateTime.t(), DateTime.t()) :: String.t()
+  def window_query(query, from, to) do
+    "#{query} time:[#{DateTime.to_iso8601(from)},#{DateTime.to_iso8601(to)}]"
+  end
+
+  @impl true
+  def init(opts) do
+    job = Keyword.fetch!(opts, :job)
+    now = DateTime.utc_now()
+
+    state = %{
+      job: job,
+      runner_opts: Keyword.get(opts, :runner_opts, []),
+      conn: nil,
+      watermark: DateTime.add(now, -job.lookback_seconds, :second),
+      window: nil,
+      pending: nil,
+      backoff: Backoff.new(Keyword.get(opts, :backoff, [])),
+      published: 0,
+      failures: 0
+    }
+
+    Process.flag(:trap_exit, true)
+    send(self(), :poll)
+    {:ok, state}
+  end
+
+  @impl true
+  def handle_call(:stats, _from, state) do
+    {:reply,
+     %{
+       connected: not is_nil(state.conn),
+       published: state.published,
+       failures: state.failures,
+       watermark: state.watermark
+     }, state}
+  end
+
+  @impl true
+  def handle_info(:poll, state), do: {:noreply, poll(state)}
+  def handle_info(_message, state), do: {:noreply, state}
+
+  @impl true
+  def terminate(_reason, %{conn: nil}), do: :ok
+  def terminate(_reason, %{job: job, conn: conn}), do: job.producer.close(conn)
+
+  defp poll(%{conn: nil, job: job} = state) do
+    case job.producer.connect(producer_config(job)) do
+      {:ok, conn} ->
+        Logger.info("Stream export #{job.name} connected")
+        poll(%{state | conn: conn})
+
+      {:error, reason} ->
+        retry_later(state, "connect", reason)
+    end
+  end
+
+  defp poll(%{pending: nil} = state) do
+    window = state.window || %{from: state.watermark, to: DateTime.utc_now(), cursor: nil}
+
+    case fetch_page(state, window) do
+      {:ok, %{rows: []}} ->
+        finish_window(state, window)
+
+      {:ok, %{rows: rows, next_cursor: next_cursor}} ->
+        messages = Enum.map(rows, &encode_message(&1, state.job.key_field))
+        poll(%{state | window: window, pending: %{messages: messages, next_cursor: next_cursor}})
+
+      {:error, reason} ->
+        retry_later(%{state | window: window}, "query", reason)
+    end
+  end
+
+  defp poll(%{pending: pending, job: job} = state) do
+    case job.producer.produce(state.conn, job.topic, pending.messages) do
+      :ok ->
+        state = %{
+          state
+          | pending: nil,
+            published: state.published + length(pending.messages),
+            backoff: Backoff.reset(state.backoff)
+        }
+
+        if is_binary(pending.next_cursor) do
+          send(self(), :poll)
+          %{state | window: %{state.window | cursor: pending.next_cursor}}
+        else
+          finish_window(state, state.window)
+        end
+
+      {:error, reason} ->
+        job.producer.close(state.conn)
+        retry_later(%{state | conn: nil}, "publish", reason)
+    end
+  end
+
+  defp finish_window(state, window) do
+    Process.send_after(self(), :poll, state.job.poll_interval_ms)
+    %{state | watermark: window.to, window: nil, backoff: Backoff.reset(state.backoff)}
+  end
+
+  defp retry_later(state, step, reason) do
+    {delay_ms, backoff} = Backoff.next(state.backoff)
+
+    Logger.warning("Stream export #{state.job.name} #{step} failed, retrying",
+      reason: inspect(reason),
+      retry_in_ms: delay_ms
+    )
+
+    Process.send_after(self(), :poll, delay_ms)
+    %{state | backoff: backoff, failures: state.failures + 1}
+  end
+
+  defp fetch_page(state, window) do
+    opts =
+      state.runner_opts
+      |> Keyword.put(:limit, state.job.page_size)
+      |> maybe_put_cursor(window.cursor)
+
+    state.job.query
+    |> window_query(window.from, window.to)
+    |> SRQLRunner.query_page(opts)
+  end
+
+  defp maybe_put_cursor(opts, nil), do: opts
+  defp maybe_put_cursor(opts, cursor), do: Keyword.merge(opts, cursor: cursor, direction: "next")
+
+  defp producer_config(job) do
+    Map.take(job, [:brokers, :client_id, :sasl, :tls, :producer_opts])
+  end
+
+  defp encode_key(_row, nil), do: ""
+
+  defp encode_key(row, path) do
+    case key_value(row, path) do
+      nil -> ""
+      value when is_binary(value) -> value
+      value when is_number(value) or is_boolean(value) -> to_string(value)
+      value -> Jason.encode!(value)
+    end
+  end
+
+  defp key_value(value, []), do: value
+  defp key_value(map, [key | rest]) when is_map(map), do: key_value(Map.get(map, key), rest)
+  defp key_value(_value, _path), do: nil
+
+  defp normalize(%Postgrex.INET{} = inet) do
+    {:ok, value} = Cidr.cast_stored(inet, [])
+    value
+  end
+
+  defp normalize(%module{} = value)
+       when module in [DateTime, NaiveDateTime, Date, Time, Decimal],
+       do: value
+
+  defp normalize(%_{} = struct), do: inspect(struct)
+
+  defp normalize(map) when is_map(map) do
+    Map.new(map, fn {key, value} -> {to_string(key), normalize(value)} end)
+  end
+
+  defp normalize(list) when is_list(list), do: Enum.map(list, &normalize/1)
+
+  defp normalize(binary) when is_binary(binary) do
+    cond do
+      String.valid?(binary) -> binary
+      byte_size(binary) == 16 -> binary |> Ecto.UUID.load() |> elem(1)
+      true -> Base.encode64(binary)
+    end
+  end
+
+  defp normalize(value), do: value
+
+  defp via(name), do: {:via, Registry, {ServiceRadar.LocalRegistry, {:stream_export, name}}}
+end
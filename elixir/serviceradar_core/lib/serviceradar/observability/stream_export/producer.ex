@@ -0,0 +1,37 @@
+defmodule ServiceRadar.Observability.StreamExport.Producer do
+  @moduledoc """
+  Behaviour for the message producer a stream-export job publishes through.
+
+  `ServiceRadar.Observability.StreamExport.BrodProducer` is the Kafka
+  implementation. Tests and other transports can supply their own module with
+  the job's `:producer` option.
+  """
+
+  @type conn :: term()
+
+  @type message :: %{key: binary(), value: binary()}
+
+  @type config :: %{
+          brokers: [{String.t(), :inet.port_number()}],
+          client_id: String.t(),
+          sasl: %{mechanism: atom(), username: String.t(), password: String.t()} | nil,
+          tls: boolean() | keyword(),
+          producer_opts: keyword()
+        }
+
+  @doc """
+  Connects to the brokers.
+  """
+  @callback connect(config()) :: {:ok, conn()} | {:error, term()}
+
+  @doc """
+  Publishes a batch of messages to `topic`. Must only return `:ok` once the
+  brokers acknowledged every message; on error the whole batch is retried.
+  """
+  @callback produce(conn(), topic :: String.t(), [message()]) :: :ok | {:error, term()}
+
+  @doc """
+  Closes the connection. Called before reconnecting and on shutdown.
+  """
+  @callback close(conn()) :: :ok
+end
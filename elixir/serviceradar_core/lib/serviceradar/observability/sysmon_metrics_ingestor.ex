@@ -12,6 +12,7 @@ defmodule ServiceRadar.Observability.SysmonMetricsIngestor do
   alias ServiceRadar.Observability.CpuMetric
   alias ServiceRadar.Observability.DiskMetric
   alias ServiceRadar.Observability.MemoryMetric
+  alias ServiceRadar.Observability.NetworkMetric
   alias ServiceRadar.Observability.ProcessMetric
   alias ServiceRadar.Repo
 
@@ -19,14 +20,40 @@ defmodule ServiceRadar.Observability.SysmonMetricsIngestor do
 
   @default_bulk_create_chunk_size 1_000
 
+  @disk_io_counters [:read_bytes, :write_bytes, :read_ops, :write_ops]
+
+  @disk_io_rates [
+    :read_bytes_per_sec,
+    :write_bytes_per_sec,
+    :read_ops_per_sec,
+    :write_ops_per_sec
+  ]
+
+  @network_counters [
+    :bytes_sent,
+    :bytes_recv,
+    :packets_sent,
+    :packets_recv,
+    :errors_in,
+    :errors_out,
+    :drops_in,
+    :drops_out
+  ]
+
+  @network_rates [
+    :bytes_sent_per_sec,
+    :bytes_recv_per_sec,
+    :packets_sent_per_sec,
+    :packets_recv_per_sec
+  ]
+
   @spec ingest(map(), map()) :: :ok | {:error, term()}
   def ingest(payload, status) when is_map(payload) and is_map(status) do
     # DB connection's search_path determines the schema
     actor = SystemActor.system(:sysmon_metrics_ingestor)
 
-    with {:ok, sample} <- extract_sample(payload),
-         {:ok, context} <- build_context(sample, status, actor) do
-      metrics = build_metrics(sample, context)
+    with {:ok, samples} <- extract_samples(payload),
+         {:ok, metrics} <- build_sample_metrics(samples, status, actor) do
       persist_metrics(metrics, actor)
     end
   end
@@ -52,17 +79,40 @@ defmodule ServiceRadar.Observability.SysmonMetricsIngestor do
       cpu_clusters: build_cluster_records(fetch_list(sample, "clusters"), base),
       memory: build_memory_records(fetch_map(sample, "memory"), base),
       disks: build_disk_records(fetch_list(sample, "disks"), base),
+      network: build_network_records(fetch_list(sample, "network"), base),
       processes: build_process_records(fetch_list(sample, "processes"), base)
     }
   end
 
-  defp extract_sample(payload) do
+  # Agents that batch flushes send the newest sample in "status" and the older
+  # ones in "samples", so every sample in the flush lands in one bulk write.
+  @doc false
+  def extract_samples(payload) when is_map(payload) do
     case fetch_map(payload, "status") do
       nil -> {:error, :missing_status}
-      sample -> {:ok, sample}
+      sample -> {:ok, Enum.filter(fetch_list(payload, "samples"), &is_map/1) ++ [sample]}
     end
   end
 
+  defp build_sample_metrics(samples, status, actor) do
+    Enum.reduce_while(samples, {:ok, nil}, fn sample, {:ok, acc} ->
+      case build_context(sample, status, actor) do
+        {:ok, context} ->
+          metrics = build_metrics(sample, context)
+          {:cont, {:ok, merge_metrics(acc, metrics)}}
+
+        {:error, reason} ->
+          {:halt, {:error, reason}}
+      end
+    end)
+  end
+
+  defp merge_metrics(nil, metrics), do: metrics
+
+  defp merge_metrics(acc, metrics) do
+    Map.merge(acc, metrics, fn _kind, left, right -> left ++ right end)
+  end
+
   defp build_context(sample, status, actor) do
     gateway_id = status[:gateway_id]
     agent_id = status[:agent_id] || fetch_string(sample, "agent_id")
@@ -178,6 +228,7 @@ defmodule ServiceRadar.Observability.SysmonMetricsIngestor do
           |> Map.put(:used_bytes, used_bytes)
           |> Map.put(:available_bytes, available_bytes)
           |> Map.put(:usage_percent, usage_percent)
+          |> Map.merge(disk_io_fields(fetch_map(disk, "io")))
 
         [record | acc]
       else
@@ -187,6 +238,41 @@ defmodule ServiceRadar.Observability.SysmonMetricsIngestor do
     |> Enum.reverse()
   end
 
+  # Disk I/O is reported per block device under "io"; mounts without counters
+  # keep the I/O columns null.
+  defp disk_io_fields(nil), do: %{}
+
+  defp disk_io_fields(io) do
+    Map.merge(
+      numeric_fields(io, @disk_io_counters, &parse_integer/1),
+      numeric_fields(io, @disk_io_rates, &parse_float/1)
+    )
+  end
+
+  defp build_network_records(interfaces, base) do
+    interfaces
+    |> Enum.reduce([], fn interface, acc ->
+      case fetch_string(interface, "interface") do
+        nil ->
+          acc
+
+        name ->
+          record =
+            base
+            |> Map.put(:interface_name, name)
+            |> Map.merge(numeric_fields(interface, @network_counters, &parse_integer/1))
+            |> Map.merge(numeric_fields(interface, @network_rates, &parse_float/1))
+
+          [record | acc]
+      end
+    end)
+    |> Enum.reverse()
+  end
+
+  defp numeric_fields(map, keys, parser) do
+    Map.new(keys, fn key -> {key, parser.(fetch_value(map, Atom.to_string(key)))} end)
+  end
+
   defp build_process_records(processes, base) do
     processes
     |> Enum.reduce([], fn process, acc ->
@@ -217,6 +303,7 @@ defmodule ServiceRadar.Observability.SysmonMetricsIngestor do
       insert_bulk(metrics.cpu_clusters, CpuClusterMetric, actor),
       insert_bulk(metrics.memory, MemoryMetric, actor),
       insert_bulk(metrics.disks, DiskMetric, actor),
+      insert_bulk(metrics.network, NetworkMetric, actor),
       insert_bulk(metrics.processes, ProcessMetric, actor)
     ]
 
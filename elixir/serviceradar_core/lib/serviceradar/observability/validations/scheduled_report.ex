@@ -0,0 +1,136 @@
+defmodule ServiceRadar.Observability.Validations.ScheduledReport do
+  @moduledoc """
+  Validates a scheduled report: an SRQL query naming its entity, a cron
+  expression, a delivery target matching the delivery method, a partition
+  scope the entity supports, and an actor allowed to view the entity.
+  """
+
+  use Ash.Resource.Validation
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Identity.RBAC
+  alias ServiceRadar.Observability.ScheduledReports
+
+  @max_recipients 50
+
+  @impl true
+  def validate(changeset, _opts, context) do
+    query = Ash.Changeset.get_attribute(changeset, :query)
+    entity = if is_binary(query), do: ScheduledReports.entity(query)
+
+    with :ok <- validate_query(query, entity),
+         :ok <- validate_cron(Ash.Changeset.get_attribute(changeset, :cron)),
+         :ok <-
+           validate_target(
+             Ash.Changeset.get_attribute(changeset, :delivery),
+             Ash.Changeset.get_attribute(changeset, :target)
+           ),
+         :ok <- validate_partition(entity, Ash.Changeset.get_attribute(changeset, :partition)) do
+      validate_permission(entity, Map.get(context, :actor))
+    end
+  end
+
+  defp validate_query(query, _entity) when not is_binary(query) or query == "",
+    do: {:error, field: :query, message: "is required"}
+
+  defp validate_query(_query, nil),
+    do: {:error, field: :query, message: "must name its entity with in:<entity>"}
+
+  defp validate_query(_query, _entity), do: :ok
+
+  defp validate_cron(cron) when is_binary(cron) do
+    case ScheduledReports.next_run_at(cron, DateTime.utc_now()) do
+      {:ok, _next_run_at} -> :ok
+      {:error, reason} -> {:error, field: :cron, message: reason}
+    end
+  end
+
+  defp validate_cron(_cron), do: {:error, field: :cron, message: "is required"}
+
+  defp validate_target(:email, target) do
+    case param(target, :recipients) do
+      [_ | _] = recipients when length(recipients) <= @max_recipients ->
+        if Enum.all?(recipients, &email?/1),
+          do: :ok,
+          else: {:error, field: :target, message: "recipients must be email addresses"}
+
+      [_ | _] ->
+        {:error, field: :target, message: "at most #{@max_recipients} recipients"}
+
+      _ ->
+        {:error, field: :target, message: "email delivery requires a recipients list"}
+    end
+  end
+
+  defp validate_target(:webhook, target) do
+    with url when is_binary(url) <- param(target, :url),
+         %URI{scheme: "https", host: host} when is_binary(host) and host != "" <- URI.parse(url),
+         :ok <- validate_headers(param(target, :headers)) do
+      :ok
+    else
+      {:error, _} = error -> error
+      _ -> {:error, field: :target, message: "webhook delivery requires an https url"}
+    end
+  end
+
+  defp validate_target(:object_store, target) do
+    case param(target, :prefix) do
+      nil ->
+        :ok
+
+      prefix when is_binary(prefix) ->
+        if String.contains?(prefix, ".."),
+          do: {:error, field: :target, message: "prefix must not contain '..'"},
+          else: :ok
+
+      _ ->
+        {:error, field: :target, message: "prefix must be a string"}
+    end
+  end
+
+  defp validate_target(_delivery, _target), do: :ok
+
+  defp validate_headers(nil), do: :ok
+
+  defp validate_headers(headers) when is_map(headers) do
+    if Enum.all?(headers, fn {key, value} -> is_binary(key) and is_binary(value) end),
+      do: :ok,
+      else: {:error, field: :target, message: "headers must map names to string values"}
+  end
+
+  defp validate_headers(_headers),
+    do: {:error, field: :target, message: "headers must map names to string values"}
+
+  defp validate_partition(_entity, partition) when partition in [nil, ""], do: :ok
+  defp validate_partition(nil, _partition), do: :ok
+
+  defp validate_partition(entity, _partition) do
+    if ScheduledReports.partition_scoped?(entity) do
+      :ok
+    else
+      {:error, field: :partition, message: "#{entity} reports cannot be scoped to a partition"}
+    end
+  end
+
+  defp validate_permission(entity, actor) do
+    cond do
+      is_nil(entity) or SystemActor.system_actor?(actor) ->
+        :ok
+
+      RBAC.has_permission?(actor, ScheduledReports.required_permission(entity)) ->
+        :ok
+
+      true ->
+        {:error, field: :query, message: "not permitted to view #{entity}"}
+    end
+  end
+
+  defp email?(value) when is_binary(value), do: Regex.match?(~r/^[^\s@]+@[^\s@]+$/, value)
+  defp email?(_value), do: false
+
+  defp param(params, key) when is_map(params) do
+    Map.get(params, Atom.to_string(key)) || Map.get(params, key)
+  end
+
+  defp param(_params, _key), do: nil
+end
@@ -0,0 +1,76 @@
+defmodule ServiceRadar.Observability.Validations.SRQLView do
+  @moduledoc """
+  Validates an SRQL view: a name that does not shadow a built-in entity, a
+  definition that resolves to an entity without recursing into itself, a
+  partition scope the entity supports, and an actor allowed to view the
+  entity.
+  """
+
+  use Ash.Resource.Validation
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Identity.RBAC
+  alias ServiceRadar.Observability.ScheduledReports
+  alias ServiceRadar.Observability.SRQLViews
+
+  @impl true
+  def validate(changeset, _opts, context) do
+    name = Ash.Changeset.get_attribute(changeset, :name)
+    query = Ash.Changeset.get_attribute(changeset, :query)
+    actor = Map.get(context, :actor)
+
+    with :ok <- validate_name(name),
+         {:ok, entity} <- validate_query(name, query, actor),
+         :ok <- validate_partition(entity, Ash.Changeset.get_attribute(changeset, :partition)) do
+      validate_permission(entity, actor)
+    end
+  end
+
+  defp validate_name(name) when is_binary(name) do
+    cond do
+      not Regex.match?(~r/^[A-Za-z][A-Za-z0-9_]*$/, name) ->
+        {:error,
+         field: :name, message: "must start with a letter and contain only letters, digits and _"}
+
+      SRQLViews.builtin_entity?(name) ->
+        {:error, field: :name, message: "is the name of a built-in entity"}
+
+      true ->
+        :ok
+    end
+  end
+
+  defp validate_name(_name), do: {:error, field: :name, message: "is required"}
+
+  defp validate_query(name, query, actor) when is_binary(query) and query != "" do
+    case SRQLViews.check_definition(name, query, actor: actor) do
+      {:ok, entity} -> {:ok, entity}
+      {:error, reason} -> {:error, field: :query, message: reason}
+    end
+  end
+
+  defp validate_query(_name, _query, _actor), do: {:error, field: :query, message: "is required"}
+
+  defp validate_partition(_entity, partition) when partition in [nil, ""], do: :ok
+
+  defp validate_partition(entity, _partition) do
+    if ScheduledReports.partition_filter?(entity) do
+      :ok
+    else
+      {:error, field: :partition, message: "#{entity} views cannot be scoped to a partition"}
+    end
+  end
+
+  defp validate_permission(entity, actor) do
+    cond do
+      SystemActor.system_actor?(actor) ->
+        :ok
+
+      RBAC.has_permission?(actor, ScheduledReports.required_permission(entity)) ->
+        :ok
+
+      true ->
+        {:error, field: :query, message: "not permitted to view #{entity}"}
+    end
+  end
+end
@@ -164,6 +164,55 @@ defmodule Proto.ListKeysResponse do
   field :keys, 1, repeated: true, type: :string
 end
 
+defmodule Proto.HistoryRequest do
+  @moduledoc false
+
+  use Protobuf, syntax: :proto3, protoc_gen_elixir_version: "0.13.0"
+
+  field :key, 1, type: :string
+  field :limit, 2, type: :uint32
+end
+
+defmodule Proto.KeyRevision do
+  @moduledoc false
+
+  use Protobuf, syntax: :proto3, protoc_gen_elixir_version: "0.13.0"
+
+  field :revision, 1, type: :uint64
+  field :created_unix_nano, 2, type: :int64, json_name: "createdUnixNano"
+  field :operation, 3, type: :string
+  field :value, 4, type: :bytes
+end
+
+defmodule Proto.HistoryResponse do
+  @moduledoc false
+
+  use Protobuf, syntax: :proto3, protoc_gen_elixir_version: "0.13.0"
+
+  field :revisions, 1, repeated: true, type: Proto.KeyRevision
+  field :truncated, 2, type: :bool
+end
+
+defmodule Proto.GetRevisionRequest do
+  @moduledoc false
+
+  use Protobuf, syntax: :proto3, protoc_gen_elixir_version: "0.13.0"
+
+  field :key, 1, type: :string
+  field :revision, 2, type: :uint64
+end
+
+defmodule Proto.GetRevisionResponse do
+  @moduledoc false
+
+  use Protobuf, syntax: :proto3, protoc_gen_elixir_version: "0.13.0"
+
+  field :value, 1, type: :bytes
+  field :found, 2, type: :bool
+  field :revision, 3, type: :uint64
+  field :created_unix_nano, 4, type: :int64, json_name: "createdUnixNano"
+end
+
 defmodule Proto.KVService.Service do
   @moduledoc false
 
@@ -188,6 +237,10 @@ defmodule Proto.KVService.Service do
   rpc(:Info, Proto.InfoRequest, Proto.InfoResponse)
 
   rpc(:ListKeys, Proto.ListKeysRequest, Proto.ListKeysResponse)
+
+  rpc(:History, Proto.HistoryRequest, Proto.HistoryResponse)
+
+  rpc(:GetRevision, Proto.GetRevisionRequest, Proto.GetRevisionResponse)
 end
 
 defmodule Proto.KVService.Stub do
@@ -666,6 +666,7 @@ defmodule Monitoring.SysmonConfig do
   field(:collect_processes, 7, type: :bool, json_name: "collectProcesses")
   field(:disk_paths, 8, repeated: true, type: :string, json_name: "diskPaths")
   field(:disk_exclude_paths, 14, repeated: true, type: :string, json_name: "diskExcludePaths")
+  field(:collect_disk_io, 15, type: :bool, json_name: "collectDiskIo")
   field(:thresholds, 10, repeated: true, type: Monitoring.SysmonConfig.ThresholdsEntry, map: true)
   field(:profile_id, 11, type: :string, json_name: "profileId")
   field(:profile_name, 12, type: :string, json_name: "profileName")
@@ -787,6 +788,21 @@ defmodule Monitoring.SNMPOIDConfig do
   field(:data_type, 3, type: Monitoring.SNMPDataType, json_name: "dataType", enum: true)
   field(:scale, 4, type: :double)
   field(:delta, 5, type: :bool)
+  field(:divisor, 6, type: :double)
+  field(:unit, 7, type: :string)
+  field(:adaptive, 8, type: :bool)
+
+  field(:adaptive_min_interval_seconds, 9,
+    type: :uint32,
+    json_name: "adaptiveMinIntervalSeconds"
+  )
+
+  field(:adaptive_max_interval_seconds, 10,
+    type: :uint32,
+    json_name: "adaptiveMaxIntervalSeconds"
+  )
+
+  field(:adaptive_change_threshold, 11, type: :double, json_name: "adaptiveChangeThreshold")
 end
 
 defmodule Monitoring.MtrMplsLabel do
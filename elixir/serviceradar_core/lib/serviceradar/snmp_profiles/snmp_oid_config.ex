@@ -12,6 +12,7 @@ defmodule ServiceRadar.SNMPProfiles.SNMPOIDConfig do
   - `data_type`: Expected data type (counter, gauge, boolean, bytes, string, float, timeticks)
   - `scale`: Scale factor to apply to the value (default 1.0)
   - `delta`: Whether to calculate rate of change between samples
+  - `adaptive`: Whether to adapt the poll interval to how much the value changes
 
   ## Data Types
 
@@ -29,6 +30,14 @@ defmodule ServiceRadar.SNMPProfiles.SNMPOIDConfig do
   samples rather than the absolute value. This is useful for bandwidth metrics
   (bytes/sec) rather than total bytes.
 
+  ## Adaptive Polling
+
+  With `adaptive: true` the agent doubles the OID's poll interval each time a
+  sample changes by no more than `adaptive_change_threshold` (relative, default
+  0.05) and halves it when the change is larger, staying between
+  `adaptive_min_interval_seconds` (default: the profile poll interval) and
+  `adaptive_max_interval_seconds` (default: 10x the minimum).
+
   ## Usage
 
       SNMPOIDConfig
@@ -47,7 +56,19 @@ defmodule ServiceRadar.SNMPProfiles.SNMPOIDConfig do
     data_layer: AshPostgres.DataLayer,
     authorizers: [Ash.Policy.Authorizer]
 
-  @oid_fields [:oid, :name, :data_type, :scale, :delta]
+  @oid_fields [
+    :oid,
+    :name,
+    :data_type,
+    :scale,
+    :divisor,
+    :unit,
+    :delta,
+    :adaptive,
+    :adaptive_min_interval_seconds,
+    :adaptive_max_interval_seconds,
+    :adaptive_change_threshold
+  ]
 
   postgres do
     table "snmp_oid_configs"
@@ -121,6 +142,19 @@ defmodule ServiceRadar.SNMPProfiles.SNMPOIDConfig do
       description "Scale factor to apply to the value"
     end
 
+    attribute :divisor, :float do
+      allow_nil? false
+      default 1.0
+      public? true
+      constraints greater_than: 0.0
+      description "Divisor to apply to the value after scaling (e.g., 10 for tenths of a degree)"
+    end
+
+    attribute :unit, :string do
+      public? true
+      description "Unit of the emitted value (e.g., 'celsius', 'bits')"
+    end
+
     attribute :delta, :boolean do
       allow_nil? false
       default false
@@ -128,6 +162,31 @@ defmodule ServiceRadar.SNMPProfiles.SNMPOIDConfig do
       description "Whether to calculate rate of change between samples"
     end
 
+    attribute :adaptive, :boolean do
+      allow_nil? false
+      default false
+      public? true
+      description "Whether to adapt the poll interval to how much the value changes"
+    end
+
+    attribute :adaptive_min_interval_seconds, :integer do
+      public? true
+      constraints min: 1
+      description "Shortest adaptive poll interval (default: the profile poll interval)"
+    end
+
+    attribute :adaptive_max_interval_seconds, :integer do
+      public? true
+      constraints min: 1
+      description "Longest adaptive poll interval (default: 10x the minimum)"
+    end
+
+    attribute :adaptive_change_threshold, :float do
+      public? true
+      constraints min: 0.0
+      description "Relative change between samples that counts as significant (e.g., 0.05)"
+    end
+
     timestamps()
   end
 
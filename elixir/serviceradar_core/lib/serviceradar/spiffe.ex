@@ -268,6 +268,19 @@ defmodule ServiceRadar.SPIFFE do
     end
   end
 
+  @doc """
+  Returns the validity window of a DER-encoded certificate.
+  """
+  @spec certificate_validity(binary()) ::
+          {:ok, %{not_before: DateTime.t(), not_after: DateTime.t()}} | {:error, term()}
+  def certificate_validity(der) when is_binary(der) do
+    with {:ok, validity} <- decode_validity(der),
+         {:ok, not_before} <- parse_asn1_time(elem(validity, 1)),
+         {:ok, not_after} <- parse_asn1_time(elem(validity, 2)) do
+      {:ok, %{not_before: not_before, not_after: not_after}}
+    end
+  end
+
   @doc """
   Monitors certificate files for rotation and returns when they change.
 
@@ -2,11 +2,14 @@ defmodule ServiceRadar.StatusHandler do
   @moduledoc """
   Handles service status updates forwarded from agent-gateway.
 
-  Results payloads are routed to ResultsRouter when available.
+  Results payloads are routed to ResultsRouter when available. Each update's
+  agent timestamp is checked for clock skew first (see `ServiceRadar.ClockSkew`).
   """
 
   use GenServer
 
+  alias ServiceRadar.CheckerResultSchema
+  alias ServiceRadar.ClockSkew
   alias ServiceRadar.Inventory.SyncIngestorQueue
   alias ServiceRadar.ResultsRouter
 
@@ -33,9 +36,24 @@ defmodule ServiceRadar.StatusHandler do
         "service=#{service_name}"
     )
 
-    case process(status) do
-      :ok ->
-        :ok
+    {checked, state} = check_clock_skew(status, state)
+
+    with {:ok, status} <- checked,
+         :ok <- CheckerResultSchema.check(status[:message]),
+         :ok <- process(status) do
+      :ok
+    else
+      {:error, {:clock_skew, skew_seconds}} ->
+        Logger.warning(
+          "Dropping #{service_type} status from #{service_name}: agent clock skew " <>
+            "#{skew_seconds}s exceeds the configured limit"
+        )
+
+      {:error, {:unsupported_schema_version, version}} ->
+        Logger.warning(
+          "Dropping #{service_type} result from #{service_name}: schema version #{version} " <>
+            "is newer than supported version #{CheckerResultSchema.max_supported_version()}"
+        )
 
       {:error, reason} ->
         Logger.warning("Status update processing failed: #{inspect(reason)}")
@@ -44,6 +62,44 @@ defmodule ServiceRadar.StatusHandler do
     {:noreply, state}
   end
 
+  defp check_clock_skew(status, state) do
+    config = ClockSkew.config()
+
+    case ClockSkew.check(status, System.os_time(:millisecond), config) do
+      {:ok, status, nil} ->
+        {{:ok, status}, state}
+
+      {:ok, status, finding} ->
+        {{:ok, status}, report_clock_skew(finding, config, state)}
+
+      {:reject, finding} ->
+        {{:error, {:clock_skew, finding.skew_seconds}}, report_clock_skew(finding, config, state)}
+    end
+  end
+
+  # Skewed agents keep reporting, so the event is throttled per agent while
+  # every skewed report is still logged.
+  defp report_clock_skew(finding, config, state) do
+    Logger.warning(
+      "Clock skew of #{finding.skew_seconds}s from agent #{finding.agent_id || "unknown"} " <>
+        "(gateway #{finding.gateway_id || "unknown"}) exceeds #{finding.max_skew_seconds}s; " <>
+        "action=#{finding.action}"
+    )
+
+    key = {finding.agent_id, finding.gateway_id}
+    now = System.monotonic_time(:second)
+    last_events = Map.get(state, :clock_skew_events, %{})
+
+    case Map.fetch(last_events, key) do
+      {:ok, last} when now - last < config.event_interval_seconds ->
+        state
+
+      _ ->
+        Task.start(fn -> ClockSkew.record_event(finding) end)
+        Map.put(state, :clock_skew_events, Map.put(last_events, key, now))
+    end
+  end
+
   defp process(%{source: source} = status)
        when source in [
               "results",
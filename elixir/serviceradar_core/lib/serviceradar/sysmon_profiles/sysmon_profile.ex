@@ -14,6 +14,7 @@ defmodule ServiceRadar.SysmonProfiles.SysmonProfile do
   - `collect_cpu`: Enable CPU metrics collection
   - `collect_memory`: Enable memory metrics collection
   - `collect_disk`: Enable disk metrics collection
+  - `collect_disk_io`: Attach block device I/O counters and rates to disk metrics
   - `collect_network`: Enable network interface metrics collection
   - `collect_processes`: Enable process metrics collection (can be resource-intensive)
   - `disk_paths`: Specific paths to monitor (empty means all mounted filesystems)
@@ -82,6 +83,7 @@ defmodule ServiceRadar.SysmonProfiles.SysmonProfile do
         :collect_cpu,
         :collect_memory,
         :collect_disk,
+        :collect_disk_io,
         :collect_network,
         :collect_processes,
         :disk_paths,
@@ -103,6 +105,7 @@ defmodule ServiceRadar.SysmonProfiles.SysmonProfile do
         :collect_cpu,
         :collect_memory,
         :collect_disk,
+        :collect_disk_io,
         :collect_network,
         :collect_processes,
         :disk_paths,
@@ -193,6 +196,13 @@ defmodule ServiceRadar.SysmonProfiles.SysmonProfile do
       description "Enable disk metrics collection"
     end
 
+    attribute :collect_disk_io, :boolean do
+      allow_nil? false
+      public? true
+      default true
+      description "Attach block device I/O counters and rates to disk metrics"
+    end
+
     attribute :collect_network, :boolean do
       allow_nil? false
       public? true
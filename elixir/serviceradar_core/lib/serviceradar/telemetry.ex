@@ -295,6 +295,27 @@ defmodule ServiceRadar.Telemetry do
         description: "Number of processes in registry"
       ),
 
+      # Ingest metrics
+      sum("serviceradar.inventory.device_update.quarantined.count",
+        tags: [:reason, :source],
+        description: "Number of device updates rejected at ingest and quarantined"
+      ),
+
+      # Datasvc KV replication metrics
+      counter("serviceradar.datasvc.consistent_read.count",
+        tags: [:outcome],
+        description: "Number of KV reads that required a minimum revision"
+      ),
+      distribution("serviceradar.datasvc.consistent_read.wait_ms",
+        tags: [:outcome],
+        unit: :millisecond,
+        reporter_options: [buckets: [0, 10, 25, 50, 100, 250, 500, 1_000, 2_500, 5_000]],
+        description: "Time spent waiting for the local KV to reach a required revision"
+      ),
+      last_value("serviceradar.datasvc.consistent_read.revisions_behind",
+        description: "Revisions the local KV was behind when a revision-bound read started"
+      ),
+
       # SPIFFE/TLS metrics
       counter("serviceradar.spiffe.verification.success.count",
         description: "Number of successful SPIFFE ID verifications"
@@ -0,0 +1,34 @@
+defmodule ServiceRadar.Repo.Migrations.CreateDeviceRelationships do
+  @moduledoc """
+  Adds storage for typed, non-network device relationships (runs_on,
+  member_of, part_of) derived from integration metadata.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.device_relationships (
+      source_device_id TEXT        NOT NULL,
+      target_device_id TEXT        NOT NULL,
+      relation_type    TEXT        NOT NULL,
+      discovery_source TEXT        NOT NULL DEFAULT 'unknown',
+      metadata_key     TEXT        NOT NULL DEFAULT '',
+      first_seen       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      last_seen        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      PRIMARY KEY (source_device_id, target_device_id, relation_type)
+    )
+    """)
+
+    execute(
+      "CREATE INDEX IF NOT EXISTS idx_device_relationships_target ON #{prefix() || "platform"}.device_relationships (target_device_id)"
+    )
+  end
+
+  def down do
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.idx_device_relationships_target")
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.device_relationships")
+  end
+end
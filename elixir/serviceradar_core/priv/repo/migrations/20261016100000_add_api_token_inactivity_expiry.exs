@@ -0,0 +1,27 @@
+defmodule ServiceRadar.Repo.Migrations.AddApiTokenInactivityExpiry do
+  @moduledoc """
+  Adds optional per-token inactivity expiry to api_tokens. A token unused for
+  longer than inactivity_timeout_seconds is disabled with
+  disabled_reason = 'inactivity' until it is re-enabled.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.api_tokens
+      ADD COLUMN IF NOT EXISTS inactivity_timeout_seconds integer,
+      ADD COLUMN IF NOT EXISTS disabled_reason text
+    """)
+  end
+
+  def down do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.api_tokens
+      DROP COLUMN IF EXISTS inactivity_timeout_seconds,
+      DROP COLUMN IF EXISTS disabled_reason
+    """)
+  end
+end
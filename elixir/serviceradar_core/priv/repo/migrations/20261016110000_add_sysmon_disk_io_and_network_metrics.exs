@@ -0,0 +1,155 @@
+defmodule ServiceRadar.Repo.Migrations.AddSysmonDiskIoAndNetworkMetrics do
+  @moduledoc """
+  Stores sysmon disk I/O and network throughput.
+
+  - Adds block device I/O counters and rates to disk_metrics.
+  - Creates the network_metrics hypertable for per-interface counters and
+    rates, with the same 7 day retention as the other sysmon tables.
+  - Adds collect_disk_io to sysmon_profiles.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.disk_metrics
+      ADD COLUMN IF NOT EXISTS read_bytes BIGINT,
+      ADD COLUMN IF NOT EXISTS write_bytes BIGINT,
+      ADD COLUMN IF NOT EXISTS read_ops BIGINT,
+      ADD COLUMN IF NOT EXISTS write_ops BIGINT,
+      ADD COLUMN IF NOT EXISTS read_bytes_per_sec FLOAT8,
+      ADD COLUMN IF NOT EXISTS write_bytes_per_sec FLOAT8,
+      ADD COLUMN IF NOT EXISTS read_ops_per_sec FLOAT8,
+      ADD COLUMN IF NOT EXISTS write_ops_per_sec FLOAT8
+    """)
+
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.network_metrics (
+      timestamp            TIMESTAMPTZ NOT NULL,
+      gateway_id           TEXT        NOT NULL DEFAULT '',
+      agent_id             TEXT,
+      host_id              TEXT,
+      interface_name       TEXT        NOT NULL,
+      bytes_sent           BIGINT,
+      bytes_recv           BIGINT,
+      packets_sent         BIGINT,
+      packets_recv         BIGINT,
+      errors_in            BIGINT,
+      errors_out           BIGINT,
+      drops_in             BIGINT,
+      drops_out            BIGINT,
+      bytes_sent_per_sec   FLOAT8,
+      bytes_recv_per_sec   FLOAT8,
+      packets_sent_per_sec FLOAT8,
+      packets_recv_per_sec FLOAT8,
+      device_id            TEXT,
+      partition            TEXT,
+      created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      PRIMARY KEY (timestamp, gateway_id, interface_name)
+    )
+    """)
+
+    execute("""
+    CREATE INDEX IF NOT EXISTS idx_network_metrics_device_time
+    ON #{prefix() || "platform"}.network_metrics (device_id, timestamp DESC)
+    """)
+
+    maybe_create_hypertable("network_metrics", "timestamp")
+    maybe_add_retention_policy("network_metrics", "7 days")
+
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.sysmon_profiles
+      ADD COLUMN IF NOT EXISTS collect_disk_io BOOLEAN NOT NULL DEFAULT TRUE
+    """)
+  end
+
+  def down do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.sysmon_profiles
+      DROP COLUMN IF EXISTS collect_disk_io
+    """)
+
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.network_metrics")
+
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.disk_metrics
+      DROP COLUMN IF EXISTS read_bytes,
+      DROP COLUMN IF EXISTS write_bytes,
+      DROP COLUMN IF EXISTS read_ops,
+      DROP COLUMN IF EXISTS write_ops,
+      DROP COLUMN IF EXISTS read_bytes_per_sec,
+      DROP COLUMN IF EXISTS write_bytes_per_sec,
+      DROP COLUMN IF EXISTS read_ops_per_sec,
+      DROP COLUMN IF EXISTS write_ops_per_sec
+    """)
+  end
+
+  defp maybe_create_hypertable(table_name, time_column) do
+    execute("""
+    DO $$
+    DECLARE
+      ts_schema text;
+    BEGIN
+      SELECT n.nspname
+      INTO ts_schema
+      FROM pg_extension e
+      JOIN pg_namespace n ON n.oid = e.extnamespace
+      WHERE e.extname = 'timescaledb';
+
+      IF ts_schema IS NOT NULL THEN
+        IF NOT EXISTS (
+          SELECT 1 FROM timescaledb_information.hypertables
+          WHERE hypertable_name = '#{table_name}'
+            AND hypertable_schema = '#{prefix() || "platform"}'
+        ) THEN
+          EXECUTE format(
+            'SELECT %I.create_hypertable(%L::regclass, %L::name, migrate_data => true, if_not_exists => true)',
+            ts_schema,
+            '#{prefix() || "platform"}.#{table_name}',
+            '#{time_column}'
+          );
+        END IF;
+      END IF;
+    EXCEPTION
+      WHEN others THEN
+        RAISE NOTICE 'Could not create hypertable for #{table_name}: %', SQLERRM;
+    END;
+    $$;
+    """)
+  end
+
+  defp maybe_add_retention_policy(table_name, interval) do
+    execute("""
+    DO $$
+    DECLARE
+      ts_schema text;
+    BEGIN
+      SELECT n.nspname
+      INTO ts_schema
+      FROM pg_extension e
+      JOIN pg_namespace n ON n.oid = e.extnamespace
+      WHERE e.extname = 'timescaledb';
+
+      IF ts_schema IS NOT NULL
+         AND EXISTS (
+           SELECT 1
+           FROM timescaledb_information.hypertables
+           WHERE hypertable_schema = '#{prefix() || "platform"}'
+             AND hypertable_name = '#{table_name}'
+         ) THEN
+        EXECUTE format(
+          'SELECT %I.add_retention_policy(%L::regclass, INTERVAL ''#{interval}'', if_not_exists => true)',
+          ts_schema,
+          '#{prefix() || "platform"}.#{table_name}'
+        );
+      END IF;
+    EXCEPTION
+      WHEN others THEN
+        RAISE NOTICE 'Could not add retention policy for #{table_name}: %', SQLERRM;
+    END;
+    $$;
+    """)
+  end
+end
@@ -0,0 +1,26 @@
+defmodule ServiceRadar.Repo.Migrations.AddSnmpOidDivisorAndUnit do
+  @moduledoc """
+  Adds divisor and unit to snmp_oid_configs so agents can emit SNMP values
+  in the desired unit.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.snmp_oid_configs
+      ADD COLUMN IF NOT EXISTS divisor FLOAT8 NOT NULL DEFAULT 1.0,
+      ADD COLUMN IF NOT EXISTS unit TEXT
+    """)
+  end
+
+  def down do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.snmp_oid_configs
+      DROP COLUMN IF EXISTS divisor,
+      DROP COLUMN IF EXISTS unit
+    """)
+  end
+end
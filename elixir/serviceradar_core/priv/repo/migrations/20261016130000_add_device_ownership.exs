@@ -0,0 +1,40 @@
+defmodule ServiceRadar.Repo.Migrations.AddDeviceOwnership do
+  @moduledoc """
+  Adds assigned ownership to ocsf_devices.
+
+  - owner_uid / owner_type identify the user or team accountable for a device.
+  - owner_assigned_at / owner_assigned_by record the last assignment.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.ocsf_devices
+      ADD COLUMN IF NOT EXISTS owner_uid TEXT,
+      ADD COLUMN IF NOT EXISTS owner_type TEXT,
+      ADD COLUMN IF NOT EXISTS owner_assigned_at TIMESTAMPTZ,
+      ADD COLUMN IF NOT EXISTS owner_assigned_by TEXT
+    """)
+
+    execute("""
+    CREATE INDEX IF NOT EXISTS ocsf_devices_owner_uid_idx
+      ON #{prefix() || "platform"}.ocsf_devices (owner_uid)
+      WHERE owner_uid IS NOT NULL
+    """)
+  end
+
+  def down do
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.ocsf_devices_owner_uid_idx")
+
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.ocsf_devices
+      DROP COLUMN IF EXISTS owner_uid,
+      DROP COLUMN IF EXISTS owner_type,
+      DROP COLUMN IF EXISTS owner_assigned_at,
+      DROP COLUMN IF EXISTS owner_assigned_by
+    """)
+  end
+end
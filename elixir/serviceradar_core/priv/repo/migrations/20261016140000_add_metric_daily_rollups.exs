@@ -0,0 +1,208 @@
+defmodule ServiceRadar.Repo.Migrations.AddMetricDailyRollups do
+  @moduledoc """
+  Creates daily rollup tables for SRQL metric entities.
+
+  The tables mirror the columns of the hourly continuous aggregates so SRQL can
+  serve long time windows from either resolution. They are kept current by
+  `ServiceRadar.Jobs.MetricRollupWorker`; this migration backfills them from
+  the hourly aggregates that already exist.
+  """
+
+  use Ecto.Migration
+
+  @disable_ddl_transaction true
+  @disable_migration_lock true
+
+  @cpu_table "platform.cpu_metrics_daily"
+  @memory_table "platform.memory_metrics_daily"
+  @disk_table "platform.disk_metrics_daily"
+  @process_table "platform.process_metrics_daily"
+  @timeseries_table "platform.timeseries_metrics_daily"
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{@cpu_table} (
+      bucket            TIMESTAMPTZ NOT NULL,
+      device_id         TEXT,
+      host_id           TEXT,
+      avg_usage_percent FLOAT8,
+      max_usage_percent FLOAT8,
+      sample_count      BIGINT      NOT NULL DEFAULT 0
+    )
+    """)
+
+    execute("""
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_cpu_metrics_daily_bucket_device_host
+    ON #{@cpu_table} (bucket DESC, device_id, host_id) NULLS NOT DISTINCT
+    """)
+
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{@memory_table} (
+      bucket              TIMESTAMPTZ NOT NULL,
+      device_id           TEXT,
+      host_id             TEXT,
+      avg_usage_percent   FLOAT8,
+      max_usage_percent   FLOAT8,
+      avg_used_bytes      FLOAT8,
+      avg_available_bytes FLOAT8,
+      sample_count        BIGINT      NOT NULL DEFAULT 0
+    )
+    """)
+
+    execute("""
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_memory_metrics_daily_bucket_device_host
+    ON #{@memory_table} (bucket DESC, device_id, host_id) NULLS NOT DISTINCT
+    """)
+
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{@disk_table} (
+      bucket              TIMESTAMPTZ NOT NULL,
+      device_id           TEXT,
+      host_id             TEXT,
+      mount_point         TEXT,
+      avg_usage_percent   FLOAT8,
+      max_usage_percent   FLOAT8,
+      avg_used_bytes      FLOAT8,
+      avg_available_bytes FLOAT8,
+      sample_count        BIGINT      NOT NULL DEFAULT 0
+    )
+    """)
+
+    execute("""
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_disk_metrics_daily_bucket_device_host_mount
+    ON #{@disk_table} (bucket DESC, device_id, host_id, mount_point) NULLS NOT DISTINCT
+    """)
+
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{@process_table} (
+      bucket           TIMESTAMPTZ NOT NULL,
+      device_id        TEXT,
+      host_id          TEXT,
+      name             TEXT,
+      avg_cpu_usage    FLOAT8,
+      max_cpu_usage    FLOAT8,
+      avg_memory_usage FLOAT8,
+      max_memory_usage FLOAT8,
+      sample_count     BIGINT      NOT NULL DEFAULT 0
+    )
+    """)
+
+    execute("""
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_process_metrics_daily_bucket_device_host_name
+    ON #{@process_table} (bucket DESC, device_id, host_id, name) NULLS NOT DISTINCT
+    """)
+
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{@timeseries_table} (
+      bucket       TIMESTAMPTZ NOT NULL,
+      device_id    TEXT,
+      metric_type  TEXT,
+      metric_name  TEXT,
+      avg_value    FLOAT8,
+      min_value    FLOAT8,
+      max_value    FLOAT8,
+      sample_count BIGINT      NOT NULL DEFAULT 0
+    )
+    """)
+
+    execute("""
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_timeseries_metrics_daily_bucket_device_type_name
+    ON #{@timeseries_table} (bucket DESC, device_id, metric_type, metric_name) NULLS NOT DISTINCT
+    """)
+
+    backfill(
+      "platform.cpu_metrics_hourly",
+      @cpu_table,
+      ~w(device_id host_id),
+      """
+      SUM(avg_usage_percent * sample_count) / NULLIF(SUM(sample_count), 0),
+      MAX(max_usage_percent),
+      """,
+      ~w(avg_usage_percent max_usage_percent)
+    )
+
+    backfill(
+      "platform.memory_metrics_hourly",
+      @memory_table,
+      ~w(device_id host_id),
+      """
+      SUM(avg_usage_percent * sample_count) / NULLIF(SUM(sample_count), 0),
+      MAX(max_usage_percent),
+      SUM(avg_used_bytes * sample_count) / NULLIF(SUM(sample_count), 0),
+      SUM(avg_available_bytes * sample_count) / NULLIF(SUM(sample_count), 0),
+      """,
+      ~w(avg_usage_percent max_usage_percent avg_used_bytes avg_available_bytes)
+    )
+
+    backfill(
+      "platform.disk_metrics_hourly",
+      @disk_table,
+      ~w(device_id host_id mount_point),
+      """
+      SUM(avg_usage_percent * sample_count) / NULLIF(SUM(sample_count), 0),
+      MAX(max_usage_percent),
+      SUM(avg_used_bytes * sample_count) / NULLIF(SUM(sample_count), 0),
+      SUM(avg_available_bytes * sample_count) / NULLIF(SUM(sample_count), 0),
+      """,
+      ~w(avg_usage_percent max_usage_percent avg_used_bytes avg_available_bytes)
+    )
+
+    backfill(
+      "platform.process_metrics_hourly",
+      @process_table,
+      ~w(device_id host_id name),
+      """
+      SUM(avg_cpu_usage * sample_count) / NULLIF(SUM(sample_count), 0),
+      MAX(max_cpu_usage),
+      SUM(avg_memory_usage * sample_count) / NULLIF(SUM(sample_count), 0),
+      MAX(max_memory_usage),
+      """,
+      ~w(avg_cpu_usage max_cpu_usage avg_memory_usage max_memory_usage)
+    )
+
+    backfill(
+      "platform.timeseries_metrics_hourly",
+      @timeseries_table,
+      ~w(device_id metric_type metric_name),
+      """
+      SUM(avg_value * sample_count) / NULLIF(SUM(sample_count), 0),
+      MIN(min_value),
+      MAX(max_value),
+      """,
+      ~w(avg_value min_value max_value)
+    )
+  end
+
+  def down do
+    execute("DROP TABLE IF EXISTS #{@timeseries_table}")
+    execute("DROP TABLE IF EXISTS #{@process_table}")
+    execute("DROP TABLE IF EXISTS #{@disk_table}")
+    execute("DROP TABLE IF EXISTS #{@memory_table}")
+    execute("DROP TABLE IF EXISTS #{@cpu_table}")
+  end
+
+  # Hourly averages are weighted by their sample counts so the daily average
+  # matches one computed over the raw rows.
+  defp backfill(source, target, dimensions, aggregates, value_columns) do
+    dims = Enum.join(dimensions, ", ")
+    values = Enum.join(value_columns, ", ")
+
+    execute("""
+    DO $$
+    BEGIN
+      IF to_regclass('#{source}') IS NOT NULL THEN
+        INSERT INTO #{target} (bucket, #{dims}, #{values}, sample_count)
+        SELECT
+          date_trunc('day', bucket, 'UTC') AS bucket,
+          #{dims},
+          #{aggregates}
+          SUM(sample_count)::bigint
+        FROM #{source}
+        GROUP BY 1, #{dims}
+        ON CONFLICT DO NOTHING;
+      END IF;
+    END;
+    $$;
+    """)
+  end
+end
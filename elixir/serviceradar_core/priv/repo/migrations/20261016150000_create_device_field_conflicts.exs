@@ -0,0 +1,50 @@
+defmodule ServiceRadar.Repo.Migrations.CreateDeviceFieldConflicts do
+  @moduledoc """
+  Adds storage for disagreements between discovery sources about a device
+  field (hostname, type, vendor, model), so operators can review them.
+
+  At most one open conflict exists per device and field; repeated
+  disagreements update it.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.device_field_conflicts (
+      id              UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+      device_id       TEXT        NOT NULL,
+      field           TEXT        NOT NULL,
+      source_values   JSONB       NOT NULL DEFAULT '{}'::jsonb,
+      winning_source  TEXT,
+      winning_value   TEXT,
+      status          TEXT        NOT NULL DEFAULT 'open',
+      occurrences     INTEGER     NOT NULL DEFAULT 1,
+      first_seen_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      last_seen_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      resolved_at     TIMESTAMPTZ,
+      resolved_by     TEXT,
+      resolution_note TEXT
+    )
+    """)
+
+    execute(
+      "CREATE UNIQUE INDEX IF NOT EXISTS idx_device_field_conflicts_open ON #{prefix() || "platform"}.device_field_conflicts (device_id, field) WHERE status = 'open'"
+    )
+
+    execute(
+      "CREATE INDEX IF NOT EXISTS idx_device_field_conflicts_last_seen ON #{prefix() || "platform"}.device_field_conflicts (last_seen_at DESC)"
+    )
+  end
+
+  def down do
+    execute(
+      "DROP INDEX IF EXISTS #{prefix() || "platform"}.idx_device_field_conflicts_last_seen"
+    )
+
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.idx_device_field_conflicts_open")
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.device_field_conflicts")
+  end
+end
@@ -0,0 +1,56 @@
+defmodule ServiceRadar.Repo.Migrations.CreateDeviceTagRules do
+  @moduledoc """
+  Adds automatic device tagging rules.
+
+  - device_tag_rules holds ordered rules that match devices on CIDR, type,
+    vendor, OS and risk score and apply a set of tags.
+  - ocsf_devices.auto_tags records which tags were applied by rules, so rule
+    changes can re-sync them without touching manually set tags.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.device_tag_rules (
+      id             UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+      name           TEXT        NOT NULL,
+      description    TEXT,
+      enabled        BOOLEAN     NOT NULL DEFAULT TRUE,
+      position       INTEGER     NOT NULL DEFAULT 0,
+      cidrs          TEXT[]      NOT NULL DEFAULT ARRAY[]::text[],
+      device_types   TEXT[]      NOT NULL DEFAULT ARRAY[]::text[],
+      vendor_pattern TEXT,
+      os_pattern     TEXT,
+      min_risk_score INTEGER,
+      tags           JSONB       NOT NULL DEFAULT '{}'::jsonb,
+      inserted_at    TIMESTAMP   NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+      updated_at     TIMESTAMP   NOT NULL DEFAULT (now() AT TIME ZONE 'utc')
+    )
+    """)
+
+    execute(
+      "CREATE UNIQUE INDEX IF NOT EXISTS device_tag_rules_unique_name_index ON #{prefix() || "platform"}.device_tag_rules (name)"
+    )
+
+    execute(
+      "CREATE INDEX IF NOT EXISTS device_tag_rules_position_idx ON #{prefix() || "platform"}.device_tag_rules (position) WHERE enabled"
+    )
+
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.ocsf_devices
+      ADD COLUMN IF NOT EXISTS auto_tags JSONB NOT NULL DEFAULT '{}'::jsonb
+    """)
+  end
+
+  def down do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.ocsf_devices
+      DROP COLUMN IF EXISTS auto_tags
+    """)
+
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.device_tag_rules")
+  end
+end
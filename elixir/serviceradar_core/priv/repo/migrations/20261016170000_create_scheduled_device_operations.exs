@@ -0,0 +1,49 @@
+defmodule ServiceRadar.Repo.Migrations.CreateScheduledDeviceOperations do
+  @moduledoc """
+  Adds scheduled (deferred) bulk device operations.
+
+  Each row stores the operation spec, its run time, the user who scheduled it
+  (whose permissions are re-checked at execution) and the outcome.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.scheduled_device_operations (
+      id                UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+      operation         TEXT        NOT NULL,
+      device_uids       TEXT[]      NOT NULL DEFAULT ARRAY[]::text[],
+      cidr              TEXT,
+      partition         TEXT,
+      params            JSONB       NOT NULL DEFAULT '{}'::jsonb,
+      run_at            TIMESTAMPTZ NOT NULL,
+      status            TEXT        NOT NULL DEFAULT 'scheduled',
+      requested_by_id   UUID,
+      requested_by      TEXT,
+      cancelled_at      TIMESTAMPTZ,
+      cancelled_by      TEXT,
+      started_at        TIMESTAMPTZ,
+      completed_at      TIMESTAMPTZ,
+      result            JSONB       NOT NULL DEFAULT '{}'::jsonb,
+      error             TEXT,
+      inserted_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    )
+    """)
+
+    execute(
+      "CREATE INDEX IF NOT EXISTS idx_scheduled_device_operations_due ON #{prefix() || "platform"}.scheduled_device_operations (run_at) WHERE status = 'scheduled'"
+    )
+  end
+
+  def down do
+    execute(
+      "DROP INDEX IF EXISTS #{prefix() || "platform"}.idx_scheduled_device_operations_due"
+    )
+
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.scheduled_device_operations")
+  end
+end
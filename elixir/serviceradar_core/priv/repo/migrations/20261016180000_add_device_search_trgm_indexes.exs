@@ -0,0 +1,32 @@
+defmodule ServiceRadar.Repo.Migrations.AddDeviceSearchTrgmIndexes do
+  @moduledoc """
+  Adds trigram indexes backing fuzzy device search
+  (`ServiceRadar.Inventory.DeviceSearch`) on device hostnames, IPs and
+  identifier values. The GIN trigram indexes serve both the similarity
+  operator (`%`) and the substring `ILIKE` used for partial matches.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute(
+      "CREATE INDEX IF NOT EXISTS ocsf_devices_hostname_trgm_idx ON #{prefix() || "platform"}.ocsf_devices USING GIN (hostname gin_trgm_ops)"
+    )
+
+    execute(
+      "CREATE INDEX IF NOT EXISTS ocsf_devices_ip_trgm_idx ON #{prefix() || "platform"}.ocsf_devices USING GIN (ip gin_trgm_ops)"
+    )
+
+    execute(
+      "CREATE INDEX IF NOT EXISTS device_identifiers_value_trgm_idx ON #{prefix() || "platform"}.device_identifiers USING GIN (identifier_value gin_trgm_ops)"
+    )
+  end
+
+  def down do
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.device_identifiers_value_trgm_idx")
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.ocsf_devices_ip_trgm_idx")
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.ocsf_devices_hostname_trgm_idx")
+  end
+end
@@ -0,0 +1,37 @@
+defmodule ServiceRadar.Repo.Migrations.CreatePartitionRetentionPolicies do
+  @moduledoc """
+  Adds per-partition retention windows for partition-scoped observability data.
+
+  `ServiceRadar.Observability.DataRetentionWorker` deletes rows of each
+  partition that are older than that partition's window.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.partition_retention_policies (
+      id              UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+      partition       TEXT        NOT NULL,
+      retention_days  INTEGER     NOT NULL,
+      enabled         BOOLEAN     NOT NULL DEFAULT TRUE,
+      inserted_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    )
+    """)
+
+    execute(
+      "CREATE UNIQUE INDEX IF NOT EXISTS partition_retention_policies_unique_partition_index ON #{prefix() || "platform"}.partition_retention_policies (partition)"
+    )
+  end
+
+  def down do
+    execute(
+      "DROP INDEX IF EXISTS #{prefix() || "platform"}.partition_retention_policies_unique_partition_index"
+    )
+
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.partition_retention_policies")
+  end
+end
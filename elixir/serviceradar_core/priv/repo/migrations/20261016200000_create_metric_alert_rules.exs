@@ -0,0 +1,45 @@
+defmodule ServiceRadar.Repo.Migrations.CreateMetricAlertRules do
+  @moduledoc """
+  Adds metric alert rules: SRQL metric conditions that raise an alert once
+  they hold for a configured duration.
+
+  `ServiceRadar.Observability.MetricAlertRuleWorker` evaluates enabled rules
+  every minute.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.metric_alert_rules (
+      id                UUID             PRIMARY KEY DEFAULT gen_random_uuid(),
+      name              TEXT             NOT NULL,
+      description       TEXT,
+      enabled           BOOLEAN          NOT NULL DEFAULT TRUE,
+      query             TEXT             NOT NULL,
+      value_field       TEXT             NOT NULL,
+      group_by          TEXT             NOT NULL DEFAULT 'device_id',
+      comparison        TEXT             NOT NULL DEFAULT 'gt',
+      threshold         DOUBLE PRECISION NOT NULL,
+      duration_seconds  INTEGER          NOT NULL DEFAULT 300,
+      severity          TEXT             NOT NULL DEFAULT 'warning',
+      inserted_at       TIMESTAMPTZ      NOT NULL DEFAULT NOW(),
+      updated_at        TIMESTAMPTZ      NOT NULL DEFAULT NOW()
+    )
+    """)
+
+    execute(
+      "CREATE UNIQUE INDEX IF NOT EXISTS metric_alert_rules_unique_name_index ON #{prefix() || "platform"}.metric_alert_rules (name)"
+    )
+  end
+
+  def down do
+    execute(
+      "DROP INDEX IF EXISTS #{prefix() || "platform"}.metric_alert_rules_unique_name_index"
+    )
+
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.metric_alert_rules")
+  end
+end
@@ -0,0 +1,44 @@
+defmodule ServiceRadar.Repo.Migrations.CreateQuarantinedDeviceUpdates do
+  @moduledoc """
+  Adds the dead-letter table for device updates rejected at ingest.
+
+  Each row keeps the original update payload and the validation reasons, so
+  malformed updates can be inspected and traced back to their source.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.quarantined_device_updates (
+      id                UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+      reasons           TEXT[]      NOT NULL DEFAULT ARRAY[]::text[],
+      source            TEXT,
+      partition         TEXT,
+      device_id         TEXT,
+      ip                TEXT,
+      payload           JSONB       NOT NULL DEFAULT '{}'::jsonb,
+      quarantined_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    )
+    """)
+
+    execute(
+      "CREATE INDEX IF NOT EXISTS idx_quarantined_device_updates_time ON #{prefix() || "platform"}.quarantined_device_updates (quarantined_at DESC)"
+    )
+
+    execute(
+      "CREATE INDEX IF NOT EXISTS idx_quarantined_device_updates_source ON #{prefix() || "platform"}.quarantined_device_updates (source, quarantined_at DESC)"
+    )
+  end
+
+  def down do
+    execute(
+      "DROP INDEX IF EXISTS #{prefix() || "platform"}.idx_quarantined_device_updates_source"
+    )
+
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.idx_quarantined_device_updates_time")
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.quarantined_device_updates")
+  end
+end
@@ -0,0 +1,50 @@
+defmodule ServiceRadar.Repo.Migrations.AddDeviceArchival do
+  @moduledoc """
+  Adds device archival.
+
+  - archived_at / archived_by / archived_reason on ocsf_devices record when,
+    by whom and why a device was archived.
+  - device_cleanup_settings.archive_expired makes the cleanup worker archive
+    expired soft-deleted devices instead of purging them.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.ocsf_devices
+      ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ,
+      ADD COLUMN IF NOT EXISTS archived_by TEXT,
+      ADD COLUMN IF NOT EXISTS archived_reason TEXT
+    """)
+
+    execute("""
+    CREATE INDEX IF NOT EXISTS ocsf_devices_archived_at_idx
+      ON #{prefix() || "platform"}.ocsf_devices (archived_at)
+      WHERE archived_at IS NOT NULL
+    """)
+
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.device_cleanup_settings
+      ADD COLUMN IF NOT EXISTS archive_expired BOOLEAN NOT NULL DEFAULT FALSE
+    """)
+  end
+
+  def down do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.device_cleanup_settings
+      DROP COLUMN IF EXISTS archive_expired
+    """)
+
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.ocsf_devices_archived_at_idx")
+
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.ocsf_devices
+      DROP COLUMN IF EXISTS archived_at,
+      DROP COLUMN IF EXISTS archived_by,
+      DROP COLUMN IF EXISTS archived_reason
+    """)
+  end
+end
@@ -0,0 +1,30 @@
+defmodule ServiceRadar.Repo.Migrations.AddSnmpOidAdaptivePolling do
+  @moduledoc """
+  Adds adaptive polling settings to snmp_oid_configs so agents can poll stable
+  OIDs less often and volatile ones more often.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.snmp_oid_configs
+      ADD COLUMN IF NOT EXISTS adaptive BOOLEAN NOT NULL DEFAULT FALSE,
+      ADD COLUMN IF NOT EXISTS adaptive_min_interval_seconds INTEGER,
+      ADD COLUMN IF NOT EXISTS adaptive_max_interval_seconds INTEGER,
+      ADD COLUMN IF NOT EXISTS adaptive_change_threshold FLOAT8
+    """)
+  end
+
+  def down do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.snmp_oid_configs
+      DROP COLUMN IF EXISTS adaptive,
+      DROP COLUMN IF EXISTS adaptive_min_interval_seconds,
+      DROP COLUMN IF EXISTS adaptive_max_interval_seconds,
+      DROP COLUMN IF EXISTS adaptive_change_threshold
+    """)
+  end
+end
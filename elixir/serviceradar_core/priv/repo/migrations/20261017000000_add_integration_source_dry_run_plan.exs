@@ -0,0 +1,26 @@
+defmodule ServiceRadar.Repo.Migrations.AddIntegrationSourceDryRunPlan do
+  @moduledoc """
+  Adds the last dry-run reconcile plan to integration_sources so operators can
+  review what a sync would change before letting it write.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.integration_sources
+      ADD COLUMN IF NOT EXISTS last_dry_run_at TIMESTAMP(0) WITHOUT TIME ZONE,
+      ADD COLUMN IF NOT EXISTS last_dry_run_plan JSONB
+    """)
+  end
+
+  def down do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.integration_sources
+      DROP COLUMN IF EXISTS last_dry_run_at,
+      DROP COLUMN IF EXISTS last_dry_run_plan
+    """)
+  end
+end
@@ -0,0 +1,52 @@
+defmodule ServiceRadar.Repo.Migrations.CreateMonitoredCertificates do
+  @moduledoc """
+  Adds monitored certificates: the TLS certificates of configured endpoints
+  and issued edge packages, with their expiry, for SRQL (`in:certificates`)
+  and expiry alerting.
+
+  `ServiceRadar.Monitoring.CertificateExpiryWorker` refreshes them hourly.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.monitored_certificates (
+      id               UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+      source           TEXT        NOT NULL,
+      target           TEXT        NOT NULL,
+      name             TEXT,
+      subject          TEXT,
+      issuer           TEXT,
+      serial_number    TEXT,
+      not_before       TIMESTAMPTZ,
+      not_after        TIMESTAMPTZ NOT NULL,
+      last_checked_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      inserted_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    )
+    """)
+
+    execute(
+      "CREATE UNIQUE INDEX IF NOT EXISTS monitored_certificates_unique_target_index ON #{prefix() || "platform"}.monitored_certificates (source, target)"
+    )
+
+    execute(
+      "CREATE INDEX IF NOT EXISTS monitored_certificates_not_after_index ON #{prefix() || "platform"}.monitored_certificates (not_after)"
+    )
+  end
+
+  def down do
+    execute(
+      "DROP INDEX IF EXISTS #{prefix() || "platform"}.monitored_certificates_not_after_index"
+    )
+
+    execute(
+      "DROP INDEX IF EXISTS #{prefix() || "platform"}.monitored_certificates_unique_target_index"
+    )
+
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.monitored_certificates")
+  end
+end
@@ -0,0 +1,40 @@
+defmodule ServiceRadar.Repo.Migrations.AddLogDeviceContext do
+  @moduledoc """
+  Adds device context to logs.
+
+  - device_id / device_partition identify the device a log's source host
+    resolved to when db-event-writer log enrichment is enabled.
+  - device_tags holds the device metadata keys selected for enrichment, so
+    logs can be filtered by device tag without joining devices.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.logs
+      ADD COLUMN IF NOT EXISTS device_id TEXT,
+      ADD COLUMN IF NOT EXISTS device_partition TEXT,
+      ADD COLUMN IF NOT EXISTS device_tags JSONB
+    """)
+
+    execute("""
+    CREATE INDEX IF NOT EXISTS logs_device_id_timestamp_idx
+      ON #{prefix() || "platform"}.logs (device_id, timestamp DESC)
+      WHERE device_id IS NOT NULL
+    """)
+  end
+
+  def down do
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.logs_device_id_timestamp_idx")
+
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.logs
+      DROP COLUMN IF EXISTS device_id,
+      DROP COLUMN IF EXISTS device_partition,
+      DROP COLUMN IF EXISTS device_tags
+    """)
+  end
+end
@@ -0,0 +1,53 @@
+defmodule ServiceRadar.Repo.Migrations.CreateScheduledReports do
+  @moduledoc """
+  Adds scheduled SRQL reports.
+
+  Each row stores a saved SRQL query, its cron schedule, output format and
+  delivery target, the user whose permissions it runs with, the next run
+  time and the outcome of the last run.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.scheduled_reports (
+      id                UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+      name              TEXT        NOT NULL,
+      description       TEXT,
+      query             TEXT        NOT NULL,
+      cron              TEXT        NOT NULL,
+      format            TEXT        NOT NULL DEFAULT 'csv',
+      delivery          TEXT        NOT NULL,
+      target            JSONB       NOT NULL DEFAULT '{}'::jsonb,
+      partition         TEXT,
+      enabled           BOOLEAN     NOT NULL DEFAULT TRUE,
+      requested_by_id   UUID,
+      requested_by      TEXT,
+      next_run_at       TIMESTAMPTZ,
+      last_run_at       TIMESTAMPTZ,
+      last_status       TEXT,
+      last_error        TEXT,
+      last_row_count    INTEGER,
+      inserted_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    )
+    """)
+
+    execute(
+      "CREATE UNIQUE INDEX IF NOT EXISTS scheduled_reports_unique_name_index ON #{prefix() || "platform"}.scheduled_reports (name)"
+    )
+
+    execute(
+      "CREATE INDEX IF NOT EXISTS idx_scheduled_reports_due ON #{prefix() || "platform"}.scheduled_reports (next_run_at) WHERE enabled"
+    )
+  end
+
+  def down do
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.idx_scheduled_reports_due")
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.scheduled_reports_unique_name_index")
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.scheduled_reports")
+  end
+end
@@ -0,0 +1,36 @@
+defmodule ServiceRadar.Repo.Migrations.CreateInventoryCounts do
+  @moduledoc """
+  Adds inventory counts: the device count of each discovery source and
+  partition as of the last inventory change check, so the next check can
+  alert on sudden changes.
+
+  `ServiceRadar.Monitoring.InventoryChangeWorker` refreshes them.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.inventory_counts (
+      id               UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+      dimension        TEXT        NOT NULL,
+      key              TEXT        NOT NULL,
+      device_count     INTEGER     NOT NULL,
+      checked_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      inserted_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    )
+    """)
+
+    execute(
+      "CREATE UNIQUE INDEX IF NOT EXISTS inventory_counts_unique_key_index ON #{prefix() || "platform"}.inventory_counts (dimension, key)"
+    )
+  end
+
+  def down do
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.inventory_counts_unique_key_index")
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.inventory_counts")
+  end
+end
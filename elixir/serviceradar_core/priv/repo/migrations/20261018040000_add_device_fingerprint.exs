@@ -0,0 +1,24 @@
+defmodule ServiceRadar.Repo.Migrations.AddDeviceFingerprint do
+  @moduledoc """
+  Adds ocsf_devices.fingerprint, the content hash of the last sync update
+  applied to a device (see ServiceRadar.Inventory.DeviceFingerprint).
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.ocsf_devices
+      ADD COLUMN IF NOT EXISTS fingerprint TEXT
+    """)
+  end
+
+  def down do
+    execute("""
+    ALTER TABLE #{prefix() || "platform"}.ocsf_devices
+      DROP COLUMN IF EXISTS fingerprint
+    """)
+  end
+end
@@ -0,0 +1,33 @@
+defmodule ServiceRadar.Repo.Migrations.CreateSrqlViews do
+  @moduledoc """
+  Adds SRQL views: named queries that can be queried like an entity.
+
+  This migration is idempotent - safe to run multiple times.
+  """
+
+  use Ecto.Migration
+
+  def up do
+    execute("""
+    CREATE TABLE IF NOT EXISTS #{prefix() || "platform"}.srql_views (
+      id           UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+      name         TEXT        NOT NULL,
+      description  TEXT,
+      query        TEXT        NOT NULL,
+      partition    TEXT,
+      created_by   TEXT,
+      inserted_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    )
+    """)
+
+    execute(
+      "CREATE UNIQUE INDEX IF NOT EXISTS srql_views_unique_name_index ON #{prefix() || "platform"}.srql_views (name)"
+    )
+  end
+
+  def down do
+    execute("DROP INDEX IF EXISTS #{prefix() || "platform"}.srql_views_unique_name_index")
+    execute("DROP TABLE IF EXISTS #{prefix() || "platform"}.srql_views")
+  end
+end
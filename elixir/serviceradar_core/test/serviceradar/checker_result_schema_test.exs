@@ -0,0 +1,34 @@
+defmodule ServiceRadar.CheckerResultSchemaTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.CheckerResultSchema
+
+  @legacy ~s({"available":true,"response_time":1200,"host":"10.0.0.1"})
+  @versioned ~s({"available":true,"response_time":1200,"host":"10.0.0.1","schema_version":2})
+
+  test "parses legacy and versioned payloads to the same fields" do
+    assert {:ok, 1, legacy} = CheckerResultSchema.parse(@legacy)
+    assert {:ok, 2, versioned} = CheckerResultSchema.parse(@versioned)
+
+    assert legacy == versioned
+    refute Map.has_key?(versioned, "schema_version")
+  end
+
+  test "treats non-object payloads as legacy" do
+    assert {:ok, 1, [%{"device_id" => "dev-1"}]} =
+             CheckerResultSchema.parse(~s([{"device_id":"dev-1"}]))
+
+    assert {:ok, 1, "command received"} = CheckerResultSchema.parse("command received")
+  end
+
+  test "rejects versions newer than supported" do
+    assert {:error, {:unsupported_schema_version, 3}} =
+             CheckerResultSchema.check(~s({"available":true,"schema_version":3}))
+
+    assert {:error, {:invalid_schema_version, "two"}} =
+             CheckerResultSchema.check(~s({"schema_version":"two"}))
+
+    assert :ok = CheckerResultSchema.check(@legacy)
+    assert :ok = CheckerResultSchema.check(@versioned)
+  end
+end
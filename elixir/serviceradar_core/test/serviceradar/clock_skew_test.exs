@@ -0,0 +1,113 @@
+defmodule ServiceRadar.ClockSkewTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.ClockSkew
+
+  @now_ms 1_760_000_000_000
+  @config %{max_skew_seconds: 60, action: :warn, event_interval_seconds: 3600}
+
+  defp status(agent_timestamp, extra \\ %{}) do
+    Map.merge(
+      %{
+        agent_id: "agent-1",
+        gateway_id: "gateway-1",
+        partition: "default",
+        service_name: "ping",
+        agent_timestamp: agent_timestamp
+      },
+      extra
+    )
+  end
+
+  test "accepts reports within the allowed skew" do
+    status = status(div(@now_ms, 1000) - 30)
+    assert {:ok, ^status, nil} = ClockSkew.check(status, @now_ms, @config)
+
+    status = status((@now_ms + 45_000) * 1_000_000)
+    assert {:ok, ^status, nil} = ClockSkew.check(status, @now_ms, @config)
+  end
+
+  test "flags reports beyond the allowed skew and keeps them in warn mode" do
+    status = status(div(@now_ms, 1000) + 600)
+
+    assert {:ok, ^status, finding} = ClockSkew.check(status, @now_ms, @config)
+    assert finding.agent_id == "agent-1"
+    assert finding.gateway_id == "gateway-1"
+    assert finding.skew_seconds == 600.0
+    assert finding.max_skew_seconds == 60
+    assert finding.action == :warn
+
+    assert {:ok, _status, %{skew_seconds: -120.0}} =
+             ClockSkew.check(status(div(@now_ms, 1000) - 120), @now_ms, @config)
+  end
+
+  test "measures skew against the gateway receive time when present" do
+    received = div(@now_ms, 1000) - 3600
+    status = status(received + 5, %{timestamp: received})
+
+    assert {:ok, ^status, nil} = ClockSkew.check(status, @now_ms, @config)
+  end
+
+  test "corrects the timestamp to the receive time in the original unit" do
+    config = %{@config | action: :correct}
+
+    assert {:ok, %{agent_timestamp: corrected}, %{action: :correct}} =
+             ClockSkew.check(status(div(@now_ms, 1000) + 600), @now_ms, config)
+
+    assert corrected == div(@now_ms, 1000)
+
+    assert {:ok, %{agent_timestamp: corrected_ns}, _finding} =
+             ClockSkew.check(status((@now_ms - 900_000) * 1_000_000), @now_ms, config)
+
+    assert corrected_ns == @now_ms * 1_000_000
+  end
+
+  test "rejects skewed reports in reject mode" do
+    config = %{@config | action: :reject}
+
+    assert {:reject, %{skew_seconds: 600.0, action: :reject}} =
+             ClockSkew.check(status(div(@now_ms, 1000) + 600), @now_ms, config)
+  end
+
+  test "drops empty timestamps so consumers fall back to the receive time" do
+    for empty <- [0, "", "   ", nil, "garbage"] do
+      assert {:ok, checked, nil} = ClockSkew.check(status(empty), @now_ms, @config)
+      refute Map.has_key?(checked, :agent_timestamp)
+    end
+
+    assert {:ok, %{service_name: "ping"}, nil} =
+             ClockSkew.check(%{service_name: "ping"}, @now_ms, @config)
+  end
+
+  test "records an OCSF event for a finding" do
+    test_pid = self()
+
+    finding = %{
+      agent_id: "agent-1",
+      gateway_id: "gateway-1",
+      partition: "default",
+      skew_seconds: -420.5,
+      max_skew_seconds: 60,
+      action: :warn
+    }
+
+    record_event = fn attrs, _actor ->
+      send(test_pid, {:event, attrs})
+      {:error, :not_persisted}
+    end
+
+    assert {:error, :not_persisted} = ClockSkew.record_event(finding, record_event: record_event)
+    assert_received {:event, attrs}
+
+    assert attrs.log_name == ClockSkew.log_name()
+    assert attrs.status_code == "clock_skew_exceeded"
+    assert attrs.message =~ "420.5s behind"
+    assert attrs.metadata["agent_id"] == "agent-1"
+    assert attrs.metadata["action"] == "warn"
+  end
+
+  test "config falls back to the defaults" do
+    assert %{max_skew_seconds: 300, action: :warn, event_interval_seconds: 3600} =
+             ClockSkew.config()
+  end
+end
@@ -8,4 +8,10 @@ defmodule ServiceRadar.DataService.ClientTest do
 
     assert {:error, :not_started} = Client.get_channel(timeout: 10)
   end
+
+  test "revision-bound reads return local errors without waiting" do
+    refute Process.whereis(Client)
+
+    assert {:error, :not_started} = Client.get("config/x", min_revision: 3, timeout: 10)
+  end
 end
@@ -0,0 +1,99 @@
+defmodule ServiceRadar.DataService.ConsistentReadTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.DataService.ConsistentRead
+
+  setup do
+    test_pid = self()
+    handler = "consistent-read-test-#{inspect(test_pid)}"
+
+    :telemetry.attach(
+      handler,
+      ConsistentRead.telemetry_event(),
+      fn _event, measurements, metadata, _config ->
+        send(test_pid, {:consistent_read, measurements, metadata})
+      end,
+      nil
+    )
+
+    on_exit(fn -> :telemetry.detach(handler) end)
+    :ok
+  end
+
+  # A local replica that is `lag` polls behind: the first `lag` reads return
+  # the old revision, later reads the replicated one.
+  defp lagging_replica(lag, old, new) do
+    {:ok, replica} = Agent.start_link(fn -> 0 end)
+
+    fn _key ->
+      reads = Agent.get_and_update(replica, &{&1, &1 + 1})
+      if reads < lag, do: old, else: new
+    end
+  end
+
+  test "returns the local value when it is already at the required revision" do
+    fetch = fn "config/a" -> {:ok, "v7", 7} end
+
+    assert {:ok, "v7", 7} = ConsistentRead.fetch("config/a", 5, fetch: fetch)
+    assert_received {:consistent_read, %{revisions_behind: 0}, %{outcome: :local}}
+  end
+
+  test "waits for the required revision to replicate before returning" do
+    fetch = lagging_replica(3, {:ok, "old", 4}, {:ok, "new", 6})
+
+    assert {:ok, "new", 6} =
+             ConsistentRead.fetch("config/a", 6, fetch: fetch, revision_wait_ms: 5_000)
+
+    assert_received {:consistent_read, %{revisions_behind: 2}, %{outcome: :waited}}
+  end
+
+  test "waits for a key that has not replicated at all yet" do
+    fetch = lagging_replica(2, {:error, :not_found}, {:ok, "created", 3})
+
+    assert {:ok, "created", 3} =
+             ConsistentRead.fetch("config/b", 3, fetch: fetch, revision_wait_ms: 5_000)
+
+    assert_received {:consistent_read, %{revisions_behind: 3}, %{outcome: :waited}}
+  end
+
+  test "reads from the primary when the local copy does not catch up in time" do
+    fetch = fn _key -> {:ok, "old", 4} end
+    fetch_primary = fn "config/a" -> {:ok, "new", 9} end
+
+    assert {:ok, "new", 9} =
+             ConsistentRead.fetch("config/a", 9,
+               fetch: fetch,
+               fetch_primary: fetch_primary,
+               revision_wait_ms: 50
+             )
+
+    assert_received {:consistent_read, %{wait_ms: wait_ms, revisions_behind: 5},
+                     %{outcome: :primary}}
+
+    assert wait_ms >= 50
+  end
+
+  test "fails when neither the local copy nor the primary has the revision" do
+    fetch = fn _key -> {:ok, "old", 4} end
+
+    assert {:error, {:revision_unavailable, 9, 4}} =
+             ConsistentRead.fetch("config/a", 9, fetch: fetch, revision_wait_ms: 30)
+
+    assert {:error, {:revision_unavailable, 9, 5}} =
+             ConsistentRead.fetch("config/a", 9,
+               fetch: fetch,
+               fetch_primary: fn _key -> {:ok, "older", 5} end,
+               revision_wait_ms: 30
+             )
+
+    assert_received {:consistent_read, _, %{outcome: :unavailable}}
+  end
+
+  test "returns local read errors without waiting" do
+    fetch = fn _key -> {:error, :timeout} end
+
+    assert {:error, :timeout} = ConsistentRead.fetch("config/a", 1, fetch: fetch)
+    assert_received {:consistent_read, %{wait_ms: wait_ms}, %{outcome: :error}}
+    assert wait_ms < 1_000
+  end
+end
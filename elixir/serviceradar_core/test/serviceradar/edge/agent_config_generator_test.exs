@@ -212,6 +212,34 @@ defmodule ServiceRadar.Edge.AgentConfigGeneratorTest do
       assert proto_check.method == ""
       assert proto_check.settings == %{}
     end
+
+    test "encodes nested composite settings as JSON strings" do
+      sub_checks = [
+        %{"name" => "edge", "type" => "icmp", "target" => "10.0.0.1"},
+        %{"name" => "core", "type" => "icmp", "target" => "10.0.0.2"}
+      ]
+
+      check = %{
+        check_id: "789",
+        check_type: "composite",
+        name: "Site reachability",
+        enabled: true,
+        interval_sec: 60,
+        timeout_sec: 10,
+        target: "site-a",
+        port: nil,
+        path: nil,
+        method: nil,
+        settings: %{"checks" => sub_checks, "policy" => "quorum", "quorum" => 1}
+      }
+
+      [proto_check] = AgentConfigGenerator.to_proto_checks([check])
+
+      assert proto_check.check_type == "composite"
+      assert Jason.decode!(proto_check.settings["checks"]) == sub_checks
+      assert proto_check.settings["policy"] == "quorum"
+      assert proto_check.settings["quorum"] == "1"
+    end
   end
 
   describe "version hash stability" do
@@ -0,0 +1,116 @@
+defmodule ServiceRadar.Edge.AgentHeartbeatBatcherTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Edge.AgentHeartbeatBatcher
+
+  defp start_batcher(opts \\ []) do
+    test_pid = self()
+
+    write_fn = fn entries ->
+      send(test_pid, {:written, Enum.sort(entries)})
+      Map.new(entries, fn {agent_id, _attrs} -> {agent_id, :ok} end)
+    end
+
+    opts =
+      Keyword.merge(
+        [name: nil, write_fn: write_fn, flush_interval_ms: 0, max_batch_size: 100],
+        opts
+      )
+
+    start_supervised!(Supervisor.child_spec({AgentHeartbeatBatcher, opts}, id: make_ref()))
+  end
+
+  # Writes the first heartbeat directly, as AgentGatewaySync does.
+  defp connect(batcher, agent_id, attrs \\ %{}) do
+    assert :write = AgentHeartbeatBatcher.batch(agent_id, attrs, batcher)
+    AgentHeartbeatBatcher.written(agent_id, attrs, :ok, batcher)
+  end
+
+  test "writes steady-state heartbeats together, keeping the latest per agent" do
+    batcher = start_batcher()
+    connect(batcher, "agent-1")
+    connect(batcher, "agent-2")
+
+    assert :queued = AgentHeartbeatBatcher.batch("agent-1", %{config_source: :local}, batcher)
+    assert :queued = AgentHeartbeatBatcher.batch("agent-2", %{}, batcher)
+    assert :queued = AgentHeartbeatBatcher.batch("agent-1", %{config_source: :remote}, batcher)
+    refute_received {:written, _}
+
+    :ok = AgentHeartbeatBatcher.flush(batcher)
+
+    assert_received {:written, [{"agent-1", %{config_source: :remote}}, {"agent-2", %{}}]}
+
+    :ok = AgentHeartbeatBatcher.flush(batcher)
+    refute_received {:written, _}
+  end
+
+  test "flushes when the batch is full or the interval passes" do
+    batcher = start_batcher(max_batch_size: 2)
+    connect(batcher, "agent-1")
+    connect(batcher, "agent-2")
+
+    assert :queued = AgentHeartbeatBatcher.batch("agent-1", %{}, batcher)
+    refute_received {:written, _}
+    assert :queued = AgentHeartbeatBatcher.batch("agent-2", %{}, batcher)
+    assert_received {:written, [{"agent-1", %{}}, {"agent-2", %{}}]}
+
+    batcher = start_batcher(flush_interval_ms: 10)
+    connect(batcher, "agent-3")
+
+    assert :queued = AgentHeartbeatBatcher.batch("agent-3", %{}, batcher)
+    assert_receive {:written, [{"agent-3", %{}}]}, 1_000
+  end
+
+  test "does not hold heartbeats that change the agent's state" do
+    batcher = start_batcher()
+
+    # Unknown agents are written immediately.
+    assert :write = AgentHeartbeatBatcher.batch("agent-1", %{}, batcher)
+    AgentHeartbeatBatcher.written("agent-1", %{}, :ok, batcher)
+
+    # A pending heartbeat is dropped when the health changes.
+    assert :queued = AgentHeartbeatBatcher.batch("agent-1", %{}, batcher)
+    assert :write = AgentHeartbeatBatcher.batch("agent-1", %{is_healthy: false}, batcher)
+    AgentHeartbeatBatcher.written("agent-1", %{is_healthy: false}, :ok, batcher)
+
+    assert :queued = AgentHeartbeatBatcher.batch("agent-1", %{is_healthy: false}, batcher)
+    assert :write = AgentHeartbeatBatcher.batch("agent-1", %{is_healthy: true}, batcher)
+
+    :ok = AgentHeartbeatBatcher.flush(batcher)
+    refute_received {:written, _}
+  end
+
+  test "writes the first heartbeat after the agent disconnected immediately" do
+    batcher = start_batcher()
+    connect(batcher, "agent-1")
+
+    assert :queued = AgentHeartbeatBatcher.batch("agent-1", %{}, batcher)
+
+    send(
+      batcher,
+      {:health_event, %{entity_type: :agent, entity_id: "agent-1", new_state: :disconnected}}
+    )
+
+    # The pending heartbeat is dropped and the reconnect is not batched.
+    assert :write = AgentHeartbeatBatcher.batch("agent-1", %{}, batcher)
+    :ok = AgentHeartbeatBatcher.flush(batcher)
+    refute_received {:written, _}
+  end
+
+  test "forgets agents whose batched write failed" do
+    batcher =
+      start_batcher(
+        write_fn: fn entries -> Map.new(entries, fn {id, _} -> {id, {:error, :stale}} end) end
+      )
+
+    connect(batcher, "agent-1")
+    assert :queued = AgentHeartbeatBatcher.batch("agent-1", %{}, batcher)
+    :ok = AgentHeartbeatBatcher.flush(batcher)
+
+    assert :write = AgentHeartbeatBatcher.batch("agent-1", %{}, batcher)
+  end
+
+  test "asks callers to write when the batcher is not running" do
+    assert :write = AgentHeartbeatBatcher.batch("agent-1", %{}, :not_running_batcher)
+  end
+end
@@ -0,0 +1,140 @@
+defmodule ServiceRadar.Edge.GatewayConfigPushTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Edge.GatewayConfigPush
+
+  defmodule KVStub do
+    @moduledoc false
+
+    def get(key, _opts) do
+      case Map.fetch(Process.get(:kv, %{}), key) do
+        {:ok, value} -> {:ok, value}
+        :error -> {:error, :not_found}
+      end
+    end
+
+    def put(key, value, _opts) do
+      send(self(), {:put, key})
+      Process.put(:kv, Map.put(Process.get(:kv, %{}), key, value))
+      :ok
+    end
+  end
+
+  @east_config %{
+    "gateway_id" => "gw-east",
+    "partition" => "east",
+    "poll_interval" => "30s",
+    "listen_addr" => ":50053",
+    "agents" => %{"a1" => %{"address" => "10.0.0.1:50051"}},
+    "security" => %{"mode" => "mtls", "cert_dir" => "/etc/serviceradar/certs"},
+    "max_retries" => 3
+  }
+
+  @west_config ~s({"gateway_id":"gw-west","partition":"west","poll_interval":"30s"})
+
+  setup do
+    Process.put(:kv, %{
+      "config/gateways/gw-east.json" => Jason.encode!(@east_config),
+      "config/gateways/gw-east-2.json" =>
+        ~s({"gateway_id":"gw-east-2","partition":"east","poll_interval":"5m"}),
+      "config/gateways/gw-west.json" => @west_config
+    })
+
+    test_pid = self()
+
+    opts = [
+      kv: KVStub,
+      audit: fn entry -> send(test_pid, {:audit, entry}) end,
+      now: ~U[2026-10-17 12:00:00Z]
+    ]
+
+    {:ok, opts: opts}
+  end
+
+  defp stored(key), do: Jason.decode!(Map.fetch!(Process.get(:kv), key))
+
+  test "dry run previews affected gateways without writing", %{opts: opts} do
+    list = fn -> {:ok, ["gw-east", "gw-east-2", "gw-west", "gw-gone"]} end
+
+    request = %{
+      all: true,
+      partition: "east",
+      dry_run: true,
+      patch: %{set: %{"poll_interval" => "5m"}}
+    }
+
+    assert {:ok, result} =
+             GatewayConfigPush.push(request, "ops@example.com", [list_gateways: list] ++ opts)
+
+    assert %{dry_run: true, applied: 1, skipped: 3, failed: 0} = result
+    refute_received {:put, _}
+    refute_received {:audit, _}
+
+    by_id = Map.new(result.results, &{&1.gateway_id, &1})
+    assert by_id["gw-east-2"].skipped == "already matches patch"
+    assert by_id["gw-west"].skipped == "outside partition scope"
+    assert by_id["gw-gone"].skipped == "config not found"
+
+    preview = by_id["gw-east"]
+    assert preview.key == "config/gateways/gw-east.json"
+    assert preview.changed == ["poll_interval"]
+    assert preview.before["poll_interval"] == "30s"
+    assert preview.after["poll_interval"] == "5m"
+  end
+
+  test "patches only the named fields and records a version", %{opts: opts} do
+    request = %{
+      gateway_ids: ["gw-east", " gw-east "],
+      reason: "tighten polling",
+      patch: %{
+        set: %{"poll_interval" => "10s", "security.mode" => "spiffe"},
+        unset: ["max_retries"]
+      }
+    }
+
+    assert {:ok, %{applied: 1, results: [gateway]} = result} =
+             GatewayConfigPush.push(request, "ops@example.com", opts)
+
+    assert gateway.changed == ["max_retries", "poll_interval", "security.mode"]
+    assert gateway.version == 1
+
+    expected =
+      @east_config
+      |> Map.put("poll_interval", "10s")
+      |> put_in(["security", "mode"], "spiffe")
+      |> Map.delete("max_retries")
+
+    assert stored("config/gateways/gw-east.json") == expected
+    assert Map.fetch!(Process.get(:kv), "config/gateways/gw-west.json") == @west_config
+
+    assert {:ok, version} = GatewayConfigPush.version("gw-east", 1, opts)
+    assert version["change_id"] == result.change_id
+    assert version["actor"] == "ops@example.com"
+    assert Map.fetch!(Process.get(:kv), "config/history/gateways/gw-east/latest") == "1"
+
+    assert_received {:audit, %{action: "gateway_config.bulk_push", gateways: ["gw-east"]}}
+  end
+
+  test "rejects protected fields and invalid patches", %{opts: opts} do
+    for patch <- [
+          %{},
+          %{set: %{"partition" => "west"}},
+          %{unset: ["gateway_id"]},
+          %{set: %{"security..mode" => "x"}},
+          %{set: %{"poll_interval" => "1m"}, unset: ["poll_interval"]}
+        ] do
+      request = %{gateway_ids: ["gw-east"], patch: patch}
+      assert {:error, _} = GatewayConfigPush.push(request, nil, opts)
+    end
+
+    assert {:error, "no gateways selected"} =
+             GatewayConfigPush.push(%{gateway_ids: [" "], patch: %{unset: ["x"]}}, nil, opts)
+
+    assert {:ok, %{failed: 1}} =
+             GatewayConfigPush.push(
+               %{gateway_ids: ["gw-east"], patch: %{set: %{"poll_interval.value" => 1}}},
+               nil,
+               opts
+             )
+  end
+end
@@ -0,0 +1,135 @@
+defmodule ServiceRadar.Edge.StaleCheckerConfigsTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Edge.StaleCheckerConfigs
+
+  @now ~U[2026-10-16 12:00:00Z]
+
+  defmodule KVStub do
+    @moduledoc false
+
+    def list_keys("agents/", _opts) do
+      send(self(), :list_keys)
+
+      {:ok,
+       [
+         "agents/active/checkers/sysmon/sysmon.json",
+         "agents/gone/checkers/snmp/snmp.json",
+         "agents/gone/checkers/sweep/sweep.json",
+         "agents/gone/config.json",
+         "agents/silent/checkers/trapd/trapd.json",
+         "templates/checkers/snmp.json"
+       ]}
+    end
+
+    def delete_prefix(prefix, _opts) do
+      send(self(), {:delete_prefix, prefix})
+
+      case prefix do
+        "agents/gone/checkers/" ->
+          {:ok,
+           [
+             "agents/gone/checkers/snmp/snmp.json",
+             "agents/gone/checkers/sweep/sweep.json"
+           ]}
+
+        "agents/silent/checkers/" ->
+          {:ok, ["agents/silent/checkers/trapd/trapd.json"]}
+      end
+    end
+  end
+
+  defp last_seen(agent_ids) do
+    send(self(), {:last_seen, Enum.sort(agent_ids)})
+
+    {:ok,
+     %{
+       "active" => DateTime.add(@now, -3600, :second),
+       "silent" => DateTime.add(@now, -30 * 86_400, :second)
+     }}
+  end
+
+  defp opts(extra) do
+    Keyword.merge([kv: KVStub, last_seen: &last_seen/1, now: @now], extra)
+  end
+
+  describe "group_checker_keys/1" do
+    test "groups checker keys by agent and ignores other keys" do
+      grouped =
+        StaleCheckerConfigs.group_checker_keys([
+          "agents/a/checkers/snmp/snmp.json",
+          "agents/a/checkers/rperf/rperf.json",
+          "agents/a/config.json",
+          "agents//checkers/x.json",
+          "agents/b/checkers/sysmon/sysmon.json",
+          "config/sync.json"
+        ])
+
+      assert grouped == %{
+               "a" => [
+                 "agents/a/checkers/rperf/rperf.json",
+                 "agents/a/checkers/snmp/snmp.json"
+               ],
+               "b" => ["agents/b/checkers/sysmon/sysmon.json"]
+             }
+    end
+  end
+
+  describe "stale_agents/3" do
+    test "flags unknown agents and agents last seen before the cutoff" do
+      cutoff = DateTime.add(@now, -86_400, :second)
+
+      keys_by_agent = %{
+        "recent" => ["agents/recent/checkers/a.json"],
+        "old" => ["agents/old/checkers/a.json"],
+        "unknown" => ["agents/unknown/checkers/a.json"],
+        "never" => ["agents/never/checkers/a.json"]
+      }
+
+      last_seen = %{
+        "recent" => DateTime.add(@now, -60, :second),
+        "old" => DateTime.add(cutoff, -1, :second),
+        "never" => nil
+      }
+
+      assert keys_by_agent
+             |> StaleCheckerConfigs.stale_agents(last_seen, cutoff)
+             |> Enum.map(& &1.agent_id) == ["never", "old", "unknown"]
+    end
+  end
+
+  describe "retire/1" do
+    test "dry run previews stale keys without deleting them" do
+      assert {:ok, result} = StaleCheckerConfigs.retire(opts(stale_after_seconds: 7 * 86_400))
+
+      assert result.dry_run
+      assert result.cutoff == DateTime.add(@now, -7 * 86_400, :second)
+      assert result.key_count == 3
+      assert result.deleted_keys == []
+
+      assert [
+               %{agent_id: "gone", last_seen: nil, keys: gone_keys},
+               %{agent_id: "silent", keys: ["agents/silent/checkers/trapd/trapd.json"]}
+             ] = result.agents
+
+      assert gone_keys == [
+               "agents/gone/checkers/snmp/snmp.json",
+               "agents/gone/checkers/sweep/sweep.json"
+             ]
+
+      assert_received {:last_seen, ["active", "gone", "silent"]}
+      refute_received {:delete_prefix, _}
+    end
+
+    test "deletes each stale agent's checker prefix when not a dry run" do
+      assert {:ok, result} = StaleCheckerConfigs.retire(opts(dry_run: false))
+
+      refute result.dry_run
+      assert length(result.deleted_keys) == 3
+
+      assert_received {:delete_prefix, "agents/gone/checkers/"}
+      assert_received {:delete_prefix, "agents/silent/checkers/"}
+      refute_received {:delete_prefix, "agents/active/checkers/"}
+    end
+  end
+end
@@ -0,0 +1,150 @@
+defmodule ServiceRadar.EventWriter.ConfigEnrichmentTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.EventWriter.ConfigEnrichment
+
+  @sweep_key "agents/agent-1/checkers/sweep/sweep.json"
+
+  @sweep_rule %{
+    "match" => %{"log_provider" => "sweep", "unmapped.action" => "discovery*"},
+    "keys" => [
+      %{
+        "key" => "agents/{{unmapped.agent_id}}/checkers/sweep/sweep.json",
+        "fields" => ["interval", "timeout"],
+        "as" => "sweep"
+      }
+    ]
+  }
+
+  defp event(overrides \\ %{}) do
+    Map.merge(
+      %{
+        id: Ecto.UUID.bingenerate(),
+        class_uid: 1008,
+        type_uid: 100_801,
+        log_name: "events.ocsf.processed",
+        log_provider: "sweep",
+        message: "sweep discovered 12 hosts",
+        unmapped: %{"action" => "discovery_completed", "agent_id" => "agent-1"}
+      },
+      overrides
+    )
+  end
+
+  defp sweep_config do
+    Jason.encode!(%{
+      "interval" => "5m",
+      "timeout" => "30s",
+      "networks" => ["10.0.0.0/24"]
+    })
+  end
+
+  defp start_enrichment(rules, fetch) do
+    table = :"config_enrichment_test_#{System.unique_integer([:positive])}"
+    start_supervised!({ConfigEnrichment, name: nil, table: table, rules: rules, fetch: fetch})
+    table
+  end
+
+  defp counting_fetch(values) do
+    counter = :counters.new(1, [])
+
+    fetch = fn key ->
+      :counters.add(counter, 1, 1)
+
+      case Map.fetch(values, key) do
+        {:ok, value} -> {:ok, value}
+        :error -> {:error, :not_found}
+      end
+    end
+
+    {fetch, fn -> :counters.get(counter, 1) end}
+  end
+
+  describe "compile_rules/1" do
+    test "rejects rules with unknown fields or missing keys" do
+      {rules, invalid} =
+        ConfigEnrichment.compile_rules([
+          @sweep_rule,
+          %{"match" => %{"hostname" => "x"}, "keys" => ["config/sync.json"]},
+          %{"match" => %{"log_provider" => "sync"}, "keys" => []},
+          %{"match" => %{"log_provider" => "sync"}, "keys" => ["config/{{nope}}.json"]},
+          %{"match" => %{}, "keys" => ["config/sync.json"]},
+          "not a rule"
+        ])
+
+      assert length(rules) == 1
+      assert length(invalid) == 5
+    end
+  end
+
+  describe "enrich/2" do
+    test "attaches the configured sweep interval to a discovery event" do
+      {fetch, _calls} = counting_fetch(%{@sweep_key => sweep_config()})
+      table = start_enrichment([@sweep_rule], fetch)
+
+      [enriched] = ConfigEnrichment.enrich([event()], table: table)
+
+      assert enriched.unmapped["desired_state"] == %{
+               "sweep" => %{"interval" => "5m", "timeout" => "30s"}
+             }
+
+      assert enriched.unmapped["agent_id"] == "agent-1"
+    end
+
+    test "leaves events that match no rule untouched" do
+      {fetch, calls} = counting_fetch(%{@sweep_key => sweep_config()})
+      table = start_enrichment([@sweep_rule], fetch)
+
+      rows = [
+        event(%{log_provider: "mapper"}),
+        event(%{unmapped: %{"action" => "discovery_completed"}})
+      ]
+
+      assert ConfigEnrichment.enrich(rows, table: table) == rows
+      assert calls.() == 0
+    end
+
+    test "reads each key once and serves later batches from the cache" do
+      {fetch, calls} = counting_fetch(%{@sweep_key => sweep_config()})
+      table = start_enrichment([@sweep_rule], fetch)
+
+      batch = [event(), event(), event()]
+
+      assert Enum.all?(ConfigEnrichment.enrich(batch, table: table), fn row ->
+               row.unmapped["desired_state"]["sweep"]["interval"] == "5m"
+             end)
+
+      ConfigEnrichment.enrich([event()], table: table)
+
+      assert calls.() == 1
+    end
+
+    test "keeps the whole value when no fields are selected and skips missing keys" do
+      rules = [
+        %{
+          "match" => %{"type_uid" => 100_801},
+          "keys" => ["config/sync.json", "config/missing.json"]
+        }
+      ]
+
+      {fetch, _calls} = counting_fetch(%{"config/sync.json" => ~s({"poll_interval":"30m"})})
+      table = start_enrichment(rules, fetch)
+
+      [enriched] =
+        ConfigEnrichment.enrich(
+          [event(%{unmapped: %{"desired_state" => %{"prior" => true}}})],
+          table: table
+        )
+
+      assert enriched.unmapped["desired_state"] == %{
+               "prior" => true,
+               "config/sync.json" => %{"poll_interval" => "30m"}
+             }
+    end
+
+    test "returns rows unchanged when the enrichment process is not running" do
+      rows = [event()]
+      assert ConfigEnrichment.enrich(rows, table: :config_enrichment_missing) == rows
+    end
+  end
+end
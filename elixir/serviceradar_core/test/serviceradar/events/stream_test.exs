@@ -0,0 +1,41 @@
+defmodule ServiceRadar.Events.StreamTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Events.Stream
+
+  @event %{
+    id: "5f1c7f3e-3a44-4f59-9d43-3e0f6d1f4b11",
+    time: ~U[2026-10-01 12:00:00.123456Z],
+    log_name: "alert.rule.threshold",
+    severity_id: 4,
+    message: "cpu above 90%",
+    metadata: %{"partition" => "tenant-a"}
+  }
+
+  test "filters by log name and metadata partition" do
+    assert Stream.matches?(@event, Stream.filter([]))
+    assert Stream.matches?(@event, Stream.filter(log_names: ["alert.rule.threshold"]))
+    refute Stream.matches?(@event, Stream.filter(log_names: ["identity.merge"]))
+    assert Stream.matches?(@event, Stream.filter(partition: "tenant-a"))
+    refute Stream.matches?(@event, Stream.filter(partition: "tenant-b"))
+  end
+
+  test "encodes an SSE frame whose id resumes after the event" do
+    frame = @event |> Stream.encode() |> IO.iodata_to_binary()
+
+    assert ["id: " <> id, "event: ocsf_event", "data: " <> data, "", ""] =
+             String.split(frame, "\n")
+
+    assert {:ok, {time, event_id}} = Stream.parse_event_id(id)
+    assert time == @event.time
+    assert event_id == @event.id
+    assert %{"message" => "cpu above 90%", "log_name" => "alert.rule.threshold"} =
+             Jason.decode!(data)
+  end
+
+  test "ignores unparsable resume ids" do
+    assert Stream.parse_event_id("not-an-id") == :error
+    assert Stream.parse_event_id(nil) == :error
+    assert {:ok, []} = Stream.backlog("garbage", Stream.filter([]), nil)
+  end
+end
@@ -0,0 +1,64 @@
+defmodule ServiceRadar.Identity.ApiTokenTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Identity.ApiToken
+
+  @now ~U[2026-10-17 12:00:00Z]
+
+  defp token(attrs) do
+    struct(
+      ApiToken,
+      Map.merge(
+        %{
+          inactivity_timeout_seconds: 3600,
+          created_at: DateTime.add(@now, -7200, :second),
+          last_used_at: nil
+        },
+        attrs
+      )
+    )
+  end
+
+  describe "inactive?/2" do
+    test "is false without an inactivity timeout" do
+      refute ApiToken.inactive?(token(%{inactivity_timeout_seconds: nil}), @now)
+      refute ApiToken.inactive?(token(%{inactivity_timeout_seconds: 0}), @now)
+    end
+
+    test "counts from creation when the token was never used" do
+      assert ApiToken.inactive?(token(%{}), @now)
+
+      refute ApiToken.inactive?(
+               token(%{created_at: DateTime.add(@now, -1800, :second)}),
+               @now
+             )
+    end
+
+    test "counts from the last use" do
+      refute ApiToken.inactive?(
+               token(%{last_used_at: DateTime.add(@now, -600, :second)}),
+               @now
+             )
+
+      assert ApiToken.inactive?(
+               token(%{last_used_at: DateTime.add(@now, -3600, :second)}),
+               @now
+             )
+    end
+
+    test "uses the later of creation and last use" do
+      # A last use before creation can come from restored or imported rows.
+      token =
+        token(%{
+          created_at: DateTime.add(@now, -600, :second),
+          last_used_at: DateTime.add(@now, -7200, :second)
+        })
+
+      refute ApiToken.inactive?(token, @now)
+    end
+
+    test "is false when there is no activity to count from" do
+      refute ApiToken.inactive?(token(%{created_at: nil}), @now)
+    end
+  end
+end
@@ -0,0 +1,93 @@
+defmodule ServiceRadar.Infrastructure.DegradedModeTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Infrastructure.DegradedMode
+
+  @outage %DBConnection.ConnectionError{message: "connection refused"}
+
+  # Simulates CNPG: `up?` decides whether writes and reads reach it.
+  setup do
+    {:ok, db} = Agent.start_link(fn -> %{up?: true, written: [], gateways: []} end)
+
+    probe = fn ->
+      if Agent.get(db, & &1.up?), do: {:ok, :pong}, else: {:error, @outage}
+    end
+
+    name = :"degraded_mode_#{System.unique_integer([:positive])}"
+
+    start_supervised!(
+      {DegradedMode, name: name, recover_interval_ms: 60_000, flush_batch_size: 2, probe: probe}
+    )
+
+    {:ok, db: db, server: name}
+  end
+
+  defp set_up(db, up?), do: Agent.update(db, &%{&1 | up?: up?})
+
+  defp write(db, report) do
+    fn ->
+      Agent.get_and_update(db, fn state ->
+        if state.up?,
+          do: {:ok, %{state | written: state.written ++ [report]}},
+          else: {{:error, %Ash.Error.Unknown{errors: [@outage]}}, state}
+      end)
+    end
+  end
+
+  defp read(db) do
+    fn ->
+      Agent.get(db, fn state ->
+        if state.up?, do: {:ok, state.gateways}, else: {:error, @outage}
+      end)
+    end
+  end
+
+  test "buffers reports during an outage and flushes them", %{db: db, server: server} do
+    assert :ok = DegradedMode.report({:agent, "a1"}, write(db, {"a1", 1}), server)
+    refute DegradedMode.degraded?(server)
+
+    set_up(db, false)
+    assert :ok = DegradedMode.report({:agent, "a1"}, write(db, {"a1", 2}), server)
+    assert :ok = DegradedMode.report({:agent, "a2"}, write(db, {"a2", 1}), server)
+    assert :ok = DegradedMode.report({:agent, "a1"}, write(db, {"a1", 3}), server)
+    assert :ok = DegradedMode.report({:agent, "a3"}, write(db, {"a3", 1}), server)
+
+    assert %{degraded: true, buffered: 3, since: %DateTime{}} = DegradedMode.state(server)
+    assert %{degraded: true, buffered: 3} = DegradedMode.recover(server)
+
+    set_up(db, true)
+    DegradedMode.recover(server)
+
+    # The flush continues in batches of two until the buffer drains.
+    assert %{degraded: false, buffered: 0} = DegradedMode.state(server)
+
+    # Only the latest report per key is flushed, in the order they arrived.
+    assert Agent.get(db, & &1.written) == [{"a1", 1}, {"a2", 1}, {"a1", 3}, {"a3", 1}]
+  end
+
+  test "serves the last-known read while degraded", %{db: db, server: server} do
+    Agent.update(db, &%{&1 | gateways: ["gw-1"]})
+    assert {:ok, ["gw-1"]} = DegradedMode.read(:gateways, read(db), server)
+
+    set_up(db, false)
+    assert {:ok, ["gw-1"]} = DegradedMode.read(:gateways, read(db), server)
+    assert DegradedMode.degraded?(server)
+    assert {:error, @outage} = DegradedMode.read(:other, read(db), server)
+
+    Agent.update(db, &%{&1 | up?: true, gateways: ["gw-1", "gw-2"]})
+    assert %{degraded: false} = DegradedMode.recover(server)
+    assert {:ok, ["gw-1", "gw-2"]} = DegradedMode.read(:gateways, read(db), server)
+  end
+
+  test "passes other errors through", %{server: server} do
+    rejected = fn -> {:error, :invalid} end
+
+    assert {:error, :invalid} = DegradedMode.report({:agent, "a1"}, rejected, server)
+    refute DegradedMode.degraded?(server)
+  end
+
+  test "unavailable?/1 looks through error wrappers" do
+    assert DegradedMode.unavailable?(%Ash.Error.Unknown{errors: [%{error: @outage}]})
+    refute DegradedMode.unavailable?(%Ash.Error.Unknown{errors: [:boom]})
+  end
+end
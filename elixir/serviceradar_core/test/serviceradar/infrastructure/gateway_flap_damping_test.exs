@@ -0,0 +1,73 @@
+defmodule ServiceRadar.Infrastructure.GatewayFlapDampingTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Infrastructure.GatewayFlapDamping
+
+  @thresholds %{down_after_misses: 3, down_grace_period: 0, recovery_stable_period: 60_000}
+  @interval 30_000
+
+  # Feeds {stale?, healthy?} observations one check interval apart and
+  # returns the actions taken.
+  defp run(observations, thresholds \\ @thresholds) do
+    {actions, _entry} =
+      observations
+      |> Enum.with_index()
+      |> Enum.map_reduce(nil, fn {{stale?, healthy?}, index}, entry ->
+        GatewayFlapDamping.observe(entry, stale?, healthy?, index * @interval, thresholds)
+      end)
+
+    actions
+  end
+
+  test "a flapping gateway does not go down until it misses enough checks in a row" do
+    flapping = [{true, true}, {false, true}, {true, true}, {true, true}, {false, true}]
+    assert run(flapping) == [:hold, :hold, :hold, :hold, :hold]
+
+    assert run([{true, true}, {true, true}, {true, true}]) == [:hold, :hold, :down]
+  end
+
+  test "the grace period alone can take a gateway down" do
+    thresholds = %{@thresholds | down_after_misses: 0, down_grace_period: 60_000}
+
+    assert run([{true, true}, {true, true}, {true, true}], thresholds) == [:hold, :hold, :down]
+    assert run([{true, true}, {false, true}, {true, true}], thresholds) == [:hold, :hold, :hold]
+  end
+
+  test "disabling both criteria takes a gateway down on the first miss" do
+    thresholds = %{@thresholds | down_after_misses: 0, down_grace_period: 0}
+    assert run([{true, true}], thresholds) == [:down]
+  end
+
+  test "recovery must stay stable before it clears" do
+    # Unhealthy gateway reports again, blips, then reports steadily.
+    observations = [
+      {false, false},
+      {false, false},
+      {true, false},
+      {false, false},
+      {false, false},
+      {false, false}
+    ]
+
+    assert run(observations) == [:hold, :hold, :hold, :hold, :hold, :recovered]
+  end
+
+  test "healthy gateways that keep reporting are not tracked" do
+    assert {:hold, nil} = GatewayFlapDamping.observe(nil, false, true, 0, @thresholds)
+  end
+
+  test "gateway metadata overrides the default thresholds" do
+    metadata = %{
+      "flap_damping" => %{"down_after_misses" => 5, "recovery_stable_seconds" => 10}
+    }
+
+    assert GatewayFlapDamping.thresholds(@thresholds, metadata) == %{
+             down_after_misses: 5,
+             down_grace_period: 0,
+             recovery_stable_period: 10_000
+           }
+
+    assert GatewayFlapDamping.thresholds(@thresholds, %{}) == @thresholds
+    assert GatewayFlapDamping.thresholds(@thresholds, nil) == @thresholds
+  end
+end
@@ -0,0 +1,82 @@
+defmodule ServiceRadar.Infrastructure.GatewayGroupsTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Infrastructure.GatewayGroups
+
+  defp gateway(id, status, healthy?, group \\ nil) do
+    metadata = if group, do: %{"group" => group}, else: %{}
+    %{gateway_id: id, status: status, is_healthy: healthy?, metadata: metadata}
+  end
+
+  describe "state_of/1" do
+    test "classifies gateways by health and status" do
+      assert GatewayGroups.state_of(gateway("gw", :healthy, true)) == :up
+      assert GatewayGroups.state_of(gateway("gw", "recovering", true)) == :degraded
+      assert GatewayGroups.state_of(gateway("gw", :degraded, true)) == :degraded
+      assert GatewayGroups.state_of(gateway("gw", :healthy, false)) == :down
+      assert GatewayGroups.state_of(gateway("gw", :offline, true)) == :down
+    end
+  end
+
+  describe "rollup/1" do
+    setup do
+      gateways = [
+        gateway("gw-nyc-2", :healthy, true, "site-nyc"),
+        gateway("gw-nyc-1", :degraded, true, "site-nyc"),
+        gateway("gw-lon-1", :offline, false, "site-lon"),
+        gateway("gw-lab", :healthy, true, " ")
+      ]
+
+      {:ok, load: fn -> {:ok, gateways} end}
+    end
+
+    test "summarizes each group sorted by name", %{load: load} do
+      assert {:ok, [lon, nyc, ungrouped]} = GatewayGroups.rollup(load: load)
+
+      assert %{group: "site-lon", total: 1, down: 1, up: 0} = lon
+
+      assert %{group: "site-nyc", total: 2, up: 1, degraded: 1, down: 0} = nyc
+      assert nyc.gateways == ["gw-nyc-1", "gw-nyc-2"]
+
+      assert %{group: "ungrouped", gateways: ["gw-lab"]} = ungrouped
+    end
+
+    test "filters to one group", %{load: load} do
+      assert {:ok, [%{group: "site-nyc"}]} = GatewayGroups.rollup(group: "site-nyc", load: load)
+      assert {:ok, []} = GatewayGroups.rollup(group: "site-sfo", load: load)
+    end
+  end
+
+  describe "sync_configured/1" do
+    test "writes assignments and skips unknown gateways" do
+      test_pid = self()
+
+      store = fn
+        "gw-missing", _group ->
+          {:error, :not_found}
+
+        gateway_id, group ->
+          send(test_pid, {:stored, gateway_id, group})
+          :ok
+      end
+
+      assignments = %{"gw-nyc-1" => "site-nyc", "gw-missing" => "site-lon"}
+
+      assert {:ok, 1} = GatewayGroups.sync_configured(assignments: assignments, store: store)
+      assert_received {:stored, "gw-nyc-1", "site-nyc"}
+    end
+
+    test "assign/3 clears blank groups" do
+      test_pid = self()
+
+      store = fn gateway_id, group ->
+        send(test_pid, {:stored, gateway_id, group})
+        :ok
+      end
+
+      assert :ok = GatewayGroups.assign("gw-nyc-1", "", store: store)
+      assert_received {:stored, "gw-nyc-1", nil}
+      assert {:error, _} = GatewayGroups.assign(" ", "site-nyc", store: store)
+    end
+  end
+end
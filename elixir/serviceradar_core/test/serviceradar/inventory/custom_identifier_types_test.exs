@@ -0,0 +1,208 @@
+defmodule ServiceRadar.Inventory.CustomIdentifierTypesTest do
+  @moduledoc """
+  Coverage for operator-defined identifier types and how DIRE resolves and
+  merges devices by them.
+  """
+
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Inventory.CustomIdentifierTypes
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceIdentifier
+  alias ServiceRadar.Inventory.IdentityReconciler
+  alias ServiceRadar.Inventory.MergeAudit
+  alias ServiceRadar.TestSupport
+
+  setup do
+    CustomIdentifierTypes.register([
+      %{"name" => "asset_tag"},
+      %{"name" => "cmdb_serial", "source" => "serial_number", "scope" => "global"}
+    ])
+
+    on_exit(fn -> CustomIdentifierTypes.register([]) end)
+    :ok
+  end
+
+  describe "compile_types/1" do
+    test "rejects invalid declarations with a reason" do
+      {valid, invalid} =
+        CustomIdentifierTypes.compile_types([
+          %{name: "rack_tag", source: "rack", scope: :partition},
+          %{name: "mac"},
+          %{name: "Asset Tag"},
+          %{name: "rack_tag"},
+          %{name: "bin_id", scope: "region"},
+          %{source: "orphan"},
+          "asset_tag"
+        ])
+
+      assert valid == [%{name: :rack_tag, source: "rack", scope: :partition}]
+
+      assert invalid == [
+               {"mac", "name is a built-in identifier type"},
+               {"Asset Tag", "name must be lowercase letters, digits and underscores"},
+               {"rack_tag", "name is declared more than once"},
+               {"bin_id", "scope must be partition or global"},
+               {nil, "name is required"},
+               {nil, "must be a map"}
+             ]
+    end
+
+    test "skips invalid declarations when registering" do
+      types = CustomIdentifierTypes.register([%{name: "asset_tag"}, %{name: "ip"}])
+
+      assert types == [%{name: :asset_tag, source: "asset_tag", scope: :partition}]
+      assert CustomIdentifierTypes.names() == [:asset_tag]
+    end
+  end
+
+  describe "identity extraction" do
+    test "reads custom identifiers from update metadata" do
+      ids =
+        IdentityReconciler.extract_strong_identifiers(%{
+          device_id: nil,
+          ip: "10.40.0.1",
+          mac: "aa:bb:cc:dd:ee:01",
+          partition: "site-a",
+          metadata: %{"asset_tag" => " AT-1001 ", "serial_number" => "SN-77"}
+        })
+
+      assert ids.custom == %{asset_tag: "AT-1001", cmdb_serial: "SN-77"}
+      assert IdentityReconciler.has_strong_identifier?(%{ids | mac: nil})
+
+      # Custom types rank after the built-in non-MAC identifiers and before MAC
+      assert {:asset_tag, "AT-1001"} = IdentityReconciler.highest_priority_identifier(ids)
+
+      assert IdentityReconciler.identifier_priority() ==
+               [
+                 :agent_id,
+                 :armis_device_id,
+                 :integration_id,
+                 :netbox_device_id,
+                 :asset_tag,
+                 :cmdb_serial,
+                 :mac
+               ]
+    end
+
+    test "scopes global types across partitions" do
+      assert CustomIdentifierTypes.partition(:asset_tag, "site-a") == "site-a"
+      assert CustomIdentifierTypes.partition(:cmdb_serial, "site-a") == "*"
+      assert CustomIdentifierTypes.partition(:mac, "site-a") == "site-a"
+    end
+
+    test "includes custom identifiers in the deterministic device id" do
+      base = %{device_id: nil, ip: "", mac: nil, partition: "default", metadata: %{}}
+
+      tagged = %{base | metadata: %{"asset_tag" => "AT-1"}}
+      retagged = %{base | metadata: %{"asset_tag" => "AT-2"}}
+
+      id = IdentityReconciler.generate_deterministic_device_id(ids(tagged))
+
+      assert id == IdentityReconciler.generate_deterministic_device_id(ids(tagged))
+      refute id == IdentityReconciler.generate_deterministic_device_id(ids(retagged))
+    end
+  end
+
+  describe "device resolution" do
+    @describetag :integration
+
+    setup do
+      TestSupport.start_core!()
+      {:ok, actor: SystemActor.system(:custom_identifier_types_test)}
+    end
+
+    test "a shared custom identifier merges devices", %{actor: actor} do
+      asset_tag = "AT-#{System.unique_integer([:positive])}"
+      netbox_id = "netbox-#{System.unique_integer([:positive])}"
+
+      {:ok, device_a} = create_device(actor, "custom-a")
+      {:ok, device_b} = create_device(actor, "custom-b")
+
+      assert {:ok, _} = register_identifier(actor, device_a.uid, :asset_tag, asset_tag)
+      assert {:ok, _} = register_identifier(actor, device_b.uid, :netbox_device_id, netbox_id)
+
+      ids =
+        ids(%{
+          device_id: device_b.uid,
+          ip: "",
+          mac: nil,
+          partition: "default",
+          metadata: %{"asset_tag" => asset_tag, "netbox_device_id" => netbox_id}
+        })
+
+      assert :ok = IdentityReconciler.register_identifiers(device_b.uid, ids, actor: actor)
+
+      assert {:error, _} = Device.get_by_uid(device_a.uid, false, actor: actor)
+      assert {:ok, _} = Device.get_by_uid(device_b.uid, false, actor: actor)
+
+      assert {:ok, [identifier]} = lookup_identifier(actor, :asset_tag, asset_tag, "default")
+      assert identifier.device_id == device_b.uid
+
+      assert {:ok, [audit | _]} = MergeAudit.get_merged_to(device_a.uid, actor: actor)
+      assert audit.to_device_id == device_b.uid
+      assert audit.reason == "identifier_conflict"
+    end
+
+    test "a global custom identifier resolves the device from any partition", %{actor: actor} do
+      serial = "SN-#{System.unique_integer([:positive])}"
+      {:ok, device} = create_device(actor, "custom-global")
+
+      update = %{
+        device_id: nil,
+        ip: "10.41.0.#{:rand.uniform(200)}",
+        mac: nil,
+        partition: "site-a",
+        metadata: %{"serial_number" => serial}
+      }
+
+      assert :ok = IdentityReconciler.register_identifiers(device.uid, ids(update), actor: actor)
+      assert {:ok, [_]} = lookup_identifier(actor, :cmdb_serial, serial, "*")
+
+      elsewhere = %{update | partition: "site-b", ip: "10.42.0.#{:rand.uniform(200)}"}
+      assert {:ok, device.uid} == IdentityReconciler.resolve_device_id(elsewhere, actor: actor)
+    end
+
+    test "unregistered identifier types are rejected", %{actor: actor} do
+      {:ok, device} = create_device(actor, "custom-unknown")
+
+      assert {:error, %Ash.Error.Invalid{}} =
+               register_identifier(actor, device.uid, :unknown_tag, "value")
+    end
+  end
+
+  defp ids(update), do: IdentityReconciler.extract_strong_identifiers(update)
+
+  defp create_device(actor, hostname) do
+    Device
+    |> Ash.Changeset.for_create(:create, %{
+      uid: "sr:" <> Ecto.UUID.generate(),
+      hostname: hostname,
+      ip: "10.40.#{:rand.uniform(200)}.#{:rand.uniform(200)}"
+    })
+    |> Ash.create(actor: actor)
+  end
+
+  defp register_identifier(actor, device_id, type, value) do
+    DeviceIdentifier
+    |> Ash.Changeset.for_create(:register, %{
+      device_id: device_id,
+      identifier_type: type,
+      identifier_value: value,
+      partition: "default",
+      source: "test"
+    })
+    |> Ash.create(actor: actor)
+  end
+
+  defp lookup_identifier(actor, type, value, partition) do
+    DeviceIdentifier
+    |> Ash.Query.for_read(:lookup, %{
+      identifier_type: type,
+      identifier_value: value,
+      partition: partition
+    })
+    |> Ash.read(actor: actor)
+  end
+end
@@ -0,0 +1,182 @@
+defmodule ServiceRadar.Inventory.DeviceArchivalTest do
+  use ExUnit.Case, async: false
+
+  import Ecto.Query
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceArchival
+  alias ServiceRadar.Inventory.DeviceCleanupSettings
+  alias ServiceRadar.Inventory.DeviceCleanupWorker
+  alias ServiceRadar.Repo
+  alias ServiceRadar.TestSupport
+
+  require Ash.Query
+
+  @moduletag :integration
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    test_pid = self()
+
+    record_event = fn attrs, _actor ->
+      send(test_pid, {:archival_event, attrs})
+      {:ok, attrs}
+    end
+
+    {:ok, actor: SystemActor.system(:device_archival_test), record_event: record_event}
+  end
+
+  test "archived devices are excluded from default reads", %{actor: actor} = ctx do
+    {:ok, device} = create_device(actor)
+
+    assert {:ok, %{archived: [uid], unchanged: [], skipped: []}} =
+             DeviceArchival.archive(device.uid,
+               actor: actor,
+               reason: "site closed",
+               record_event: ctx.record_event
+             )
+
+    assert uid == device.uid
+    assert {:ok, []} = read_device(actor, uid, %{})
+    assert {:error, _} = Device.get_by_uid(uid, false, actor: actor)
+
+    assert {:ok, [archived]} = read_device(actor, uid, %{include_archived: true})
+    assert archived.archived_at
+    assert archived.archived_by == "system:device_archival_test"
+    assert archived.archived_reason == "site closed"
+
+    assert_received {:archival_event, event}
+    assert event.status_code == "device_archived"
+    assert event.log_name == DeviceArchival.log_name()
+    assert event.device == %{"uid" => uid}
+  end
+
+  test "archiving twice reports the device as unchanged", %{actor: actor} = ctx do
+    {:ok, device} = create_device(actor)
+    opts = [actor: actor, record_event: ctx.record_event]
+
+    assert {:ok, %{archived: [_]}} = DeviceArchival.archive(device.uid, opts)
+    assert_received {:archival_event, _}
+
+    assert {:ok, %{archived: [], unchanged: [uid]}} = DeviceArchival.archive(device.uid, opts)
+    assert uid == device.uid
+    refute_received {:archival_event, _}
+  end
+
+  test "restore returns an archived device to default reads", %{actor: actor} = ctx do
+    {:ok, device} = create_device(actor)
+    opts = [actor: actor, record_event: ctx.record_event]
+
+    assert {:ok, %{archived: [_]}} = DeviceArchival.archive(device.uid, opts)
+    assert {:ok, %{restored: [uid], skipped: []}} = DeviceArchival.restore(device.uid, opts)
+
+    assert {:ok, [restored]} = read_device(actor, uid, %{})
+    assert is_nil(restored.archived_at)
+    assert is_nil(restored.archived_by)
+    assert is_nil(restored.archived_reason)
+
+    assert_received {:archival_event, %{status_code: "device_archived"}}
+    assert_received {:archival_event, %{status_code: "device_restored"}}
+  end
+
+  test "archiving a soft-deleted device keeps its deletion reason", %{actor: actor} = ctx do
+    {:ok, device} = create_device(actor)
+
+    {:ok, _} =
+      device
+      |> Ash.Changeset.for_update(:soft_delete, %{deleted_reason: "decommissioned"},
+        actor: actor
+      )
+      |> Ash.update()
+
+    assert {:ok, %{archived: [uid]}} =
+             DeviceArchival.archive(device.uid, actor: actor, record_event: ctx.record_event)
+
+    assert {:ok, [archived]} = read_device(actor, uid, %{include_archived: true})
+    assert is_nil(archived.deleted_at)
+    assert archived.archived_reason == "decommissioned"
+  end
+
+  test "unknown devices are skipped", %{actor: actor} = ctx do
+    assert {:ok, %{archived: [], skipped: ["device-missing"]}} =
+             DeviceArchival.archive("device-missing",
+               actor: actor,
+               record_event: ctx.record_event
+             )
+
+    assert {:error, :invalid_device_uids} = DeviceArchival.archive([], actor: actor)
+  end
+
+  test "cleanup worker archives expired devices when configured", %{actor: actor} do
+    {:ok, _settings} = ensure_cleanup_settings(actor, %{retention_days: 1, archive_expired: true})
+    on_exit(fn -> ensure_cleanup_settings(actor, %{archive_expired: false}) end)
+
+    {:ok, device} = create_device(actor)
+
+    {:ok, _} =
+      device
+      |> Ash.Changeset.for_update(:soft_delete, %{deleted_reason: "stale"}, actor: actor)
+      |> Ash.update()
+
+    expired = DateTime.add(DateTime.utc_now(), -2 * 86_400, :second)
+
+    Repo.update_all(
+      from(d in "ocsf_devices",
+        where: d.uid == ^device.uid,
+        update: [set: [deleted_at: ^expired]]
+      ),
+      [],
+      prefix: "platform"
+    )
+
+    assert :ok = DeviceCleanupWorker.perform(struct(Oban.Job, args: %{"manual" => true}))
+
+    assert {:ok, [archived]} = read_device(actor, device.uid, %{include_archived: true})
+    assert archived.archived_at
+    assert archived.archived_reason == "retention expired"
+    assert is_nil(archived.deleted_at)
+  end
+
+  defp create_device(actor) do
+    uid = "device-#{System.unique_integer([:positive])}"
+    seed = :erlang.phash2(uid, 62_500)
+
+    Device
+    |> Ash.Changeset.for_create(:create, %{
+      uid: uid,
+      ip: "10.43.#{rem(seed, 250) + 1}.#{rem(div(seed, 250), 250) + 1}",
+      hostname: "archival-#{uid}"
+    })
+    |> Ash.create(actor: actor)
+  end
+
+  defp read_device(actor, uid, args) do
+    Device
+    |> Ash.Query.for_read(:read, args)
+    |> Ash.Query.filter(uid == ^uid)
+    |> Ash.read(actor: actor)
+    |> case do
+      {:ok, %Ash.Page.Keyset{results: results}} -> {:ok, results}
+      other -> other
+    end
+  end
+
+  defp ensure_cleanup_settings(actor, attrs) do
+    case DeviceCleanupSettings.get_settings(actor: actor) do
+      {:ok, %DeviceCleanupSettings{} = settings} ->
+        settings
+        |> Ash.Changeset.for_update(:update, attrs)
+        |> Ash.update(actor: actor)
+
+      _ ->
+        DeviceCleanupSettings
+        |> Ash.Changeset.for_create(:create, attrs)
+        |> Ash.create(actor: actor)
+    end
+  end
+end
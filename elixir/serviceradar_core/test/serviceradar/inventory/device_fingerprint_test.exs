@@ -0,0 +1,136 @@
+defmodule ServiceRadar.Inventory.DeviceFingerprintTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.DeviceFingerprint
+
+  @fields DeviceFingerprint.default_fields()
+
+  defp device(overrides \\ %{}) do
+    Map.merge(
+      %{
+        uid: "sr:dev-1",
+        ip: "10.0.0.1",
+        mac: "00:11:22:33:44:55",
+        hostname: "core-sw-1",
+        vendor_name: "Cisco",
+        is_available: true,
+        metadata: %{"armis_device_id" => "42", "last_seen" => "2026-10-16T10:00:00Z"},
+        tags: %{"site" => "east", "role" => "core"},
+        discovery_sources: ["armis", "netbox"],
+        last_seen_time: ~U[2026-10-16 10:00:00Z],
+        modified_time: ~U[2026-10-16 10:00:00Z]
+      },
+      overrides
+    )
+  end
+
+  describe "compute/2" do
+    test "is stable across volatile fields and ordering" do
+      fingerprint = DeviceFingerprint.compute(device(), @fields)
+
+      assert fingerprint =~ ~r/\A[0-9a-f]{64}\z/
+
+      assert DeviceFingerprint.compute(
+               device(%{
+                 last_seen_time: ~U[2026-10-17 08:30:00Z],
+                 modified_time: ~U[2026-10-17 08:30:00Z],
+                 metadata: %{"last_seen" => "2026-10-17T08:30:00Z", "armis_device_id" => "42"},
+                 tags: %{"role" => "core", "site" => "east"},
+                 discovery_sources: ["netbox", "armis", "armis"]
+               }),
+               @fields
+             ) == fingerprint
+    end
+
+    test "changes when a significant field changes" do
+      fingerprint = DeviceFingerprint.compute(device(), @fields)
+
+      for overrides <- [
+            %{hostname: "core-sw-2"},
+            %{ip: "10.0.0.2"},
+            %{is_available: false},
+            %{metadata: %{"armis_device_id" => "43"}},
+            %{tags: %{"site" => "west", "role" => "core"}},
+            %{discovery_sources: ["armis"]}
+          ] do
+        refute DeviceFingerprint.compute(device(overrides), @fields) == fingerprint,
+               "expected #{inspect(overrides)} to change the fingerprint"
+      end
+    end
+
+    test "only hashes the configured fields" do
+      fields = [:ip, :mac]
+      fingerprint = DeviceFingerprint.compute(device(), fields)
+
+      assert DeviceFingerprint.compute(device(%{hostname: "renamed"}), fields) == fingerprint
+      refute DeviceFingerprint.compute(device(%{mac: "66:77:88:99:aa:bb"}), fields) == fingerprint
+    end
+
+    test "hashes atom and string map keys alike" do
+      assert DeviceFingerprint.compute(device(%{tags: %{site: "east", role: "core"}}), @fields) ==
+               DeviceFingerprint.compute(device(), @fields)
+    end
+  end
+
+  describe "changed?/3" do
+    test "compares against the stored fingerprint" do
+      stored = DeviceFingerprint.compute(device(), @fields)
+
+      seen_again = device(%{last_seen_time: DateTime.utc_now()})
+
+      refute DeviceFingerprint.changed?(seen_again, stored, @fields)
+      assert DeviceFingerprint.changed?(device(%{model: "C9300"}), stored, @fields)
+    end
+
+    test "treats devices without a fingerprint as changed" do
+      assert DeviceFingerprint.changed?(device(), nil, @fields)
+    end
+  end
+
+  describe "split_unchanged/3" do
+    setup do
+      unchanged = device()
+      changed = device(%{uid: "sr:dev-2", hostname: "edge-1"})
+      transitioning = device(%{uid: "sr:dev-3"})
+
+      stored = %{
+        "sr:dev-1" => DeviceFingerprint.compute(unchanged, @fields),
+        "sr:dev-2" => DeviceFingerprint.compute(device(%{uid: "sr:dev-2"}), @fields),
+        "sr:dev-3" => DeviceFingerprint.compute(transitioning, @fields)
+      }
+
+      %{records: [unchanged, changed, transitioning], stored: stored}
+    end
+
+    test "writes every record without loading fingerprints when disabled", %{records: records} do
+      load = fn _uids -> flunk("stored fingerprints must not be loaded when disabled") end
+
+      {write, unchanged_uids} =
+        DeviceFingerprint.split_unchanged(records, [],
+          skip_unchanged: false,
+          fields: @fields,
+          load: load
+        )
+
+      assert Enum.map(write, & &1.uid) == ["sr:dev-1", "sr:dev-2", "sr:dev-3"]
+      assert Enum.all?(write, &is_binary(&1.fingerprint))
+      assert unchanged_uids == []
+    end
+
+    test "skips unchanged records when enabled", %{records: records, stored: stored} do
+      {write, unchanged_uids} =
+        DeviceFingerprint.split_unchanged(records, ["sr:dev-3"],
+          skip_unchanged: true,
+          fields: @fields,
+          load: fn _uids -> stored end
+        )
+
+      assert Enum.map(write, & &1.uid) == ["sr:dev-2", "sr:dev-3"]
+      assert unchanged_uids == ["sr:dev-1"]
+    end
+
+    test "is disabled by default" do
+      refute DeviceFingerprint.skip_unchanged?()
+    end
+  end
+end
@@ -0,0 +1,134 @@
+defmodule ServiceRadar.Inventory.DeviceGeoEnrichmentTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.DeviceGeoEnrichment
+
+  @moduletag :tmp_dir
+
+  # Raw MMDB City records, keyed by the contents of the database file so a
+  # rewritten file resolves differently.
+  defp record("v1", {8, 8, 8, 8}) do
+    %{
+      "city" => %{"names" => %{"en" => "Mountain View"}},
+      "country" => %{"iso_code" => "US", "names" => %{"en" => "United States"}},
+      "location" => %{"latitude" => 37.386, "longitude" => -122.0838},
+      "subdivisions" => [%{"names" => %{"en" => "California"}}]
+    }
+  end
+
+  defp record("v2-moved", {8, 8, 8, 8}) do
+    %{
+      "country" => %{"iso_code" => "CA", "names" => %{"en" => "Canada"}},
+      "location" => %{"latitude" => 43.6532, "longitude" => -79.3832}
+    }
+  end
+
+  defp record(_contents, _ip), do: nil
+
+  defp start_enrichment(%{tmp_dir: tmp_dir}, opts \\ []) do
+    test_pid = self()
+    path = Path.join(tmp_dir, "GeoLite2-City.mmdb")
+    File.write!(path, "v1")
+
+    load = fn path ->
+      contents = File.read!(path)
+
+      {:ok,
+       fn ip ->
+         send(test_pid, {:geoip_lookup, ip})
+         {:ok, record(contents, ip)}
+       end}
+    end
+
+    name = :"device_geo_enrichment_#{System.unique_integer([:positive])}"
+
+    start_supervised!(
+      {DeviceGeoEnrichment, Keyword.merge([name: name, database_path: path, load: load], opts)}
+    )
+
+    # Wait for the initial load
+    :sys.get_state(name)
+    {name, path}
+  end
+
+  test "attaches the location of a public IP to device metadata", ctx do
+    {name, _path} = start_enrichment(ctx)
+
+    metadata = DeviceGeoEnrichment.put_metadata(%{"source" => "armis"}, "8.8.8.8", name)
+
+    assert metadata == %{
+             "source" => "armis",
+             "geo_country_iso2" => "US",
+             "geo_country" => "United States",
+             "geo_region" => "California",
+             "geo_city" => "Mountain View",
+             "geo_latitude" => 37.386,
+             "geo_longitude" => -122.0838
+           }
+
+    assert_received {:geoip_lookup, {8, 8, 8, 8}}
+
+    # The second lookup is served from the cache
+    assert {:ok, %{country_iso2: "US"}} = DeviceGeoEnrichment.lookup("8.8.8.8", name)
+    refute_received {:geoip_lookup, _}
+  end
+
+  test "skips private IPs without a lookup", ctx do
+    {name, _path} = start_enrichment(ctx)
+
+    for ip <- ["10.0.0.5", "192.168.1.1", "172.16.4.2", "127.0.0.1", "fe80::1", "fd00::1"] do
+      assert DeviceGeoEnrichment.lookup(ip, name) == :skip
+    end
+
+    assert DeviceGeoEnrichment.put_metadata(%{}, "10.0.0.5", name) == %{}
+    assert DeviceGeoEnrichment.put_metadata(%{}, nil, name) == %{}
+    refute_received {:geoip_lookup, _}
+  end
+
+  test "skips public IPs the database has no location for", ctx do
+    {name, _path} = start_enrichment(ctx)
+
+    assert DeviceGeoEnrichment.lookup("1.1.1.1", name) == :skip
+    assert DeviceGeoEnrichment.lookup("not-an-ip", name) == :skip
+  end
+
+  test "reloads the database when the file changes", ctx do
+    {name, path} = start_enrichment(ctx, reload_check_ms: 10)
+
+    assert {:ok, %{country_iso2: "US"}} = DeviceGeoEnrichment.lookup("8.8.8.8", name)
+
+    File.write!(path, "v2-moved")
+
+    assert eventually(fn ->
+             DeviceGeoEnrichment.lookup("8.8.8.8", name) ==
+               {:ok,
+                %{
+                  country_iso2: "CA",
+                  country_name: "Canada",
+                  latitude: 43.6532,
+                  longitude: -79.3832
+                }}
+           end)
+  end
+
+  test "reports nothing until a database is loaded", ctx do
+    name = :"device_geo_enrichment_#{System.unique_integer([:positive])}"
+    missing = Path.join(ctx.tmp_dir, "missing.mmdb")
+
+    start_supervised!({DeviceGeoEnrichment, name: name, database_path: missing})
+    :sys.get_state(name)
+
+    assert DeviceGeoEnrichment.lookup("8.8.8.8", name) == :skip
+    assert {:error, :enoent} = DeviceGeoEnrichment.reload(name)
+  end
+
+  defp eventually(fun, attempts \\ 50) do
+    cond do
+      fun.() -> true
+      attempts == 0 -> false
+      true ->
+        Process.sleep(10)
+        eventually(fun, attempts - 1)
+    end
+  end
+end
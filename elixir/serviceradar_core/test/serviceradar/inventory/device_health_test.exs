@@ -0,0 +1,159 @@
+defmodule ServiceRadar.Inventory.DeviceHealthTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.DeviceHealth
+
+  defp edge(source, type, target) do
+    %{source_device_id: source, target_device_id: target, relation_type: type}
+  end
+
+  defp loader(edges) do
+    fn device_ids ->
+      {:ok,
+       Enum.filter(edges, fn edge ->
+         edge.source_device_id in device_ids or edge.target_device_id in device_ids
+       end)}
+    end
+  end
+
+  defp availability(states) do
+    fn device_ids -> {:ok, Map.take(states, device_ids)} end
+  end
+
+  # A host with four VMs, one of which hosts a nested VM.
+  defp fixture(available) do
+    edges = [
+      edge("sr:vm-1", "runs_on", "sr:host"),
+      edge("sr:vm-2", "runs_on", "sr:host"),
+      edge("sr:vm-3", "runs_on", "sr:host"),
+      edge("sr:vm-4", "runs_on", "sr:host"),
+      edge("sr:nested", "runs_on", "sr:vm-1")
+    ]
+
+    states =
+      Map.merge(
+        %{
+          "sr:host" => true,
+          "sr:vm-1" => true,
+          "sr:vm-2" => true,
+          "sr:vm-3" => true,
+          "sr:vm-4" => true,
+          "sr:nested" => true
+        },
+        available
+      )
+
+    [load: loader(edges), availability: availability(states)]
+  end
+
+  defp policy(attrs) do
+    {:ok, policy} = DeviceHealth.validate_policy(attrs)
+    policy
+  end
+
+  describe "validate_policy/1" do
+    test "fills defaults" do
+      assert {:ok, policy} = DeviceHealth.validate_policy(%{})
+      assert policy.mode == "worst_child"
+      assert policy.degraded_percent == 25
+      assert policy.unhealthy_percent == 50
+      assert policy.depth == 3
+      assert policy.types == []
+    end
+
+    test "rejects unknown modes, inverted thresholds and bad depths" do
+      assert {:error, _} = DeviceHealth.validate_policy(%{mode: "average"})
+
+      assert {:error, _} =
+               DeviceHealth.validate_policy(%{degraded_percent: 60, unhealthy_percent: 40})
+
+      assert {:error, _} = DeviceHealth.validate_policy(%{depth: 0})
+      assert {:error, _} = DeviceHealth.validate_policy(%{types: ["bogus"]})
+    end
+  end
+
+  describe "combine/2" do
+    test "worst_child takes the worst state" do
+      policy = policy(%{mode: "worst_child"})
+
+      assert DeviceHealth.combine(policy, []) == "healthy"
+      assert DeviceHealth.combine(policy, ["healthy", "degraded"]) == "degraded"
+      assert DeviceHealth.combine(policy, ["degraded", "unhealthy"]) == "unhealthy"
+    end
+
+    test "percentage compares the share of impaired children" do
+      policy = policy(%{mode: "percentage", degraded_percent: 25, unhealthy_percent: 50})
+
+      assert DeviceHealth.combine(policy, ~w(healthy healthy healthy healthy)) == "healthy"
+      assert DeviceHealth.combine(policy, ~w(unhealthy healthy healthy healthy)) == "degraded"
+      assert DeviceHealth.combine(policy, ~w(unhealthy degraded healthy healthy)) == "unhealthy"
+    end
+  end
+
+  describe "rollup/2" do
+    test "worst_child marks the host unhealthy for one unhealthy VM" do
+      opts = fixture(%{"sr:vm-2" => false})
+
+      assert {:ok, rollup} =
+               DeviceHealth.rollup("sr:host", [policy: %{mode: "worst_child"}] ++ opts)
+
+      assert rollup.own == "healthy"
+      assert rollup.state == "unhealthy"
+      assert rollup.children == %{"healthy" => 3, "unhealthy" => 1}
+    end
+
+    test "percentage degrades the host below the unhealthy threshold" do
+      opts = fixture(%{"sr:vm-2" => false})
+
+      assert {:ok, rollup} =
+               DeviceHealth.rollup("sr:host", [policy: %{mode: "percentage"}] ++ opts)
+
+      assert rollup.state == "degraded"
+    end
+
+    test "children roll up their own children first" do
+      opts = fixture(%{"sr:nested" => false, "sr:vm-3" => false})
+
+      assert {:ok, rollup} =
+               DeviceHealth.rollup("sr:host", [policy: %{mode: "percentage"}] ++ opts)
+
+      assert rollup.children == %{"healthy" => 2, "unhealthy" => 2}
+      assert rollup.state == "unhealthy"
+    end
+
+    test "an unavailable device is unhealthy whatever its children" do
+      opts = fixture(%{"sr:host" => false})
+
+      assert {:ok, %{own: "unhealthy", state: "unhealthy"}} =
+               DeviceHealth.rollup("sr:host", [policy: %{}] ++ opts)
+    end
+
+    test "unknown devices are not found" do
+      assert {:error, :not_found} =
+               DeviceHealth.rollup("sr:missing", [policy: %{}] ++ fixture(%{}))
+    end
+  end
+
+  describe "devices_changed/2" do
+    test "stores the rollup of every ancestor with children" do
+      test_pid = self()
+
+      store = fn device_id, metadata ->
+        send(test_pid, {:stored, device_id, metadata})
+        :ok
+      end
+
+      opts = [policy: %{mode: "worst_child"}, store: store] ++ fixture(%{"sr:nested" => false})
+
+      assert {:ok, rollups} = DeviceHealth.devices_changed(["sr:nested"], opts)
+      assert rollups |> Enum.map(& &1.device_id) |> Enum.sort() == ["sr:host", "sr:vm-1"]
+
+      assert_received {:stored, "sr:vm-1", %{"health_rollup" => "unhealthy"}}
+
+      assert_received {:stored, "sr:host",
+                       %{"health_rollup" => "unhealthy", "health_rollup_children" => "4"}}
+
+      refute_received {:stored, "sr:nested", _}
+    end
+  end
+end
@@ -0,0 +1,134 @@
+defmodule ServiceRadar.Inventory.DeviceLifecycleTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.DeviceLifecycle
+
+  @now ~U[2026-10-17 12:00:00Z]
+
+  defp loader(states), do: fn uids -> {:ok, Map.take(states, uids)} end
+
+  defp record(uid, available?) do
+    %{uid: uid, is_available: available?, metadata: %{"source" => "armis"}}
+  end
+
+  defp recorder(test_pid) do
+    fn attrs, _actor ->
+      send(test_pid, {:event, attrs})
+      {:error, :not_recorded}
+    end
+  end
+
+  describe "validate_transition/3" do
+    test "allows the lifecycle edges" do
+      assert :ok = DeviceLifecycle.validate_transition("discovered", "active", false)
+      assert :ok = DeviceLifecycle.validate_transition("active", "stale", false)
+      assert :ok = DeviceLifecycle.validate_transition("stale", "decommissioned", false)
+    end
+
+    test "rejects moving back to discovered and staying put" do
+      assert {:error, {:conflict, _}} =
+               DeviceLifecycle.validate_transition("active", "discovered", true)
+
+      assert {:error, {:conflict, _}} =
+               DeviceLifecycle.validate_transition("stale", "stale", true)
+    end
+
+    test "only manual transitions reactivate decommissioned devices" do
+      assert {:error, {:conflict, _}} =
+               DeviceLifecycle.validate_transition("decommissioned", "active", false)
+
+      assert :ok = DeviceLifecycle.validate_transition("decommissioned", "active", true)
+    end
+  end
+
+  describe "state_from_metadata/1" do
+    test "defaults missing and unknown states to active" do
+      assert DeviceLifecycle.state_from_metadata(nil) == "active"
+      assert DeviceLifecycle.state_from_metadata(%{"lifecycle_state" => "bogus"}) == "active"
+      assert DeviceLifecycle.state_from_metadata(%{"lifecycle_state" => "Stale"}) == "stale"
+    end
+  end
+
+  describe "stamp/2" do
+    test "new devices start discovered and known devices follow availability" do
+      records = [
+        record("sr:new", true),
+        record("sr:stale", true),
+        record("sr:gone", false),
+        record("sr:legacy", true),
+        record("sr:retired", true)
+      ]
+
+      states = %{
+        "sr:stale" => "stale",
+        "sr:gone" => "active",
+        "sr:legacy" => "",
+        "sr:retired" => "decommissioned"
+      }
+
+      assert {:ok, stamped, transitions} =
+               DeviceLifecycle.stamp(records, load: loader(states), now: @now)
+
+      assert Enum.map(stamped, & &1.metadata["lifecycle_state"]) ==
+               ~w(discovered active active active decommissioned)
+
+      assert [
+               %{device_id: "sr:new", from: nil, to: "discovered", manual: false},
+               %{device_id: "sr:stale", from: "stale", to: "active", actor: "system"}
+             ] = transitions
+
+      [new, stale, gone | _] = stamped
+      assert new.metadata["lifecycle_changed_at"] == DateTime.to_iso8601(@now)
+      assert stale.metadata["lifecycle_changed_by"] == "system"
+      refute Map.has_key?(gone.metadata, "lifecycle_changed_at")
+      assert gone.metadata["source"] == "armis"
+    end
+  end
+
+  describe "transition/3" do
+    test "records a manual reactivation with the actor and reason" do
+      test_pid = self()
+
+      store = fn uid, metadata ->
+        send(test_pid, {:stored, uid, metadata})
+        :ok
+      end
+
+      assert {:ok, transition} =
+               DeviceLifecycle.transition("sr:retired", "active",
+                 actor: %{email: "ops@example.com"},
+                 reason: "back in service",
+                 load: loader(%{"sr:retired" => "decommissioned"}),
+                 store: store,
+                 record_event: recorder(test_pid),
+                 now: @now
+               )
+
+      assert %{from: "decommissioned", to: "active", manual: true} = transition
+
+      assert_received {:stored, "sr:retired",
+                       %{
+                         "lifecycle_state" => "active",
+                         "lifecycle_changed_by" => "ops@example.com",
+                         "lifecycle_reason" => "back in service"
+                       }}
+
+      assert_received {:event, %{log_name: "device.lifecycle", metadata: %{"manual" => true}}}
+    end
+
+    test "requires an actor, a valid state and a known device" do
+      opts = [load: loader(%{"sr:a" => "active"}), store: fn _, _ -> :ok end]
+
+      assert {:error, _} = DeviceLifecycle.transition("sr:a", "stale", opts)
+
+      assert {:error, _} =
+               DeviceLifecycle.transition("sr:a", "gone", [actor: "ops"] ++ opts)
+
+      assert {:error, :not_found} =
+               DeviceLifecycle.transition("sr:b", "stale", [actor: "ops"] ++ opts)
+
+      assert {:error, {:conflict, _}} =
+               DeviceLifecycle.transition("sr:a", "discovered", [actor: "ops"] ++ opts)
+    end
+  end
+end
@@ -0,0 +1,172 @@
+defmodule ServiceRadar.Inventory.DeviceOwnershipTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceIdentifier
+  alias ServiceRadar.Inventory.DeviceOwnership
+  alias ServiceRadar.Inventory.SyncIngestor
+  alias ServiceRadar.TestSupport
+
+  require Ash.Query
+
+  @moduletag :integration
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    actor = SystemActor.system(:device_ownership_test)
+    {:ok, actor: actor}
+  end
+
+  test "assigns, reassigns and clears a device owner", %{actor: actor} do
+    {:ok, device} = create_device(actor)
+    team = %{uid: "team-netops", type: :team}
+
+    assert {:ok, %{assigned: [uid], unchanged: [], skipped: []}} =
+             DeviceOwnership.assign(device.uid, team, actor: actor)
+
+    assert uid == device.uid
+
+    assert {:ok, [assigned]} = read_device(actor, device.uid)
+    assert assigned.owner_uid == "team-netops"
+    assert assigned.owner_type == :team
+    assert assigned.owner_assigned_at
+    assert assigned.owner_assigned_by
+
+    assert {:ok, %{assigned: [], unchanged: [_]}} =
+             DeviceOwnership.assign(device.uid, %{"uid" => "team-netops", "type" => "team"},
+               actor: actor
+             )
+
+    assert {:ok, %{assigned: [_]}} =
+             DeviceOwnership.assign(device.uid, %{uid: "alice", type: :user}, actor: actor)
+
+    assert {:ok, [reassigned]} = read_device(actor, device.uid)
+    assert reassigned.owner_uid == "alice"
+    assert reassigned.owner_type == :user
+
+    assert {:ok, %{assigned: [_]}} = DeviceOwnership.assign(device.uid, nil, actor: actor)
+
+    assert {:ok, [cleared]} = read_device(actor, device.uid)
+    assert is_nil(cleared.owner_uid)
+    assert is_nil(cleared.owner_type)
+  end
+
+  test "bulk assignment reports missing devices and honours the partition", %{actor: actor} do
+    {:ok, in_partition} = create_device(actor)
+    {:ok, other_partition} = create_device(actor)
+    {:ok, _} = register_identifier(actor, in_partition.uid, "default")
+    {:ok, _} = register_identifier(actor, other_partition.uid, "remote-site")
+
+    missing = unique_uid()
+    owner = %{uid: "team-edge", type: :team}
+
+    assert {:ok, result} =
+             DeviceOwnership.assign([in_partition.uid, other_partition.uid, missing], owner,
+               actor: actor,
+               partition: "default"
+             )
+
+    assert result.assigned == [in_partition.uid]
+    assert Enum.sort(result.skipped) == Enum.sort([other_partition.uid, missing])
+
+    assert {:ok, owned} =
+             Device
+             |> Ash.Query.filter(owner_uid == "team-edge")
+             |> Ash.read(actor: actor)
+             |> Page.unwrap()
+
+    owned_uids = Enum.map(owned, & &1.uid)
+    assert in_partition.uid in owned_uids
+    refute other_partition.uid in owned_uids
+  end
+
+  test "rejects invalid owners", %{actor: actor} do
+    {:ok, device} = create_device(actor)
+
+    assert {:error, :invalid_owner} =
+             DeviceOwnership.assign(device.uid, %{uid: " ", type: :team}, actor: actor)
+
+    assert {:error, :invalid_owner} =
+             DeviceOwnership.assign(device.uid, %{uid: "bob", type: "group"}, actor: actor)
+
+    assert {:error, :invalid_device_uids} = DeviceOwnership.assign([], nil, actor: actor)
+  end
+
+  test "sync sources set a default owner without overriding assignments", %{actor: actor} do
+    ip = unique_ip()
+
+    update = %{
+      "ip" => ip,
+      "hostname" => "owned-#{System.unique_integer([:positive])}",
+      "source" => "netbox",
+      "metadata" => %{"owner_team" => "team-facilities"}
+    }
+
+    assert :ok = SyncIngestor.ingest_updates([update], actor: actor)
+
+    assert {:ok, [device]} = read_device_by_ip(actor, ip)
+    assert device.owner_uid == "team-facilities"
+    assert device.owner_type == :team
+    assert device.owner_assigned_by == "source:netbox"
+
+    assert {:ok, _} =
+             DeviceOwnership.assign(device.uid, %{uid: "team-netops", type: :team},
+               actor: actor
+             )
+
+    assert :ok = SyncIngestor.ingest_updates([update], actor: actor)
+
+    assert {:ok, [device]} = read_device_by_ip(actor, ip)
+    assert device.owner_uid == "team-netops"
+  end
+
+  defp create_device(actor) do
+    uid = unique_uid()
+
+    Device
+    |> Ash.Changeset.for_create(:create, %{uid: uid, ip: unique_ip(), hostname: "device-#{uid}"})
+    |> Ash.create(actor: actor)
+  end
+
+  defp register_identifier(actor, device_id, partition) do
+    DeviceIdentifier
+    |> Ash.Changeset.for_create(:upsert, %{
+      device_id: device_id,
+      identifier_type: :netbox_device_id,
+      identifier_value: "nb-#{System.unique_integer([:positive])}",
+      partition: partition,
+      confidence: :strong,
+      source: "device_ownership_test"
+    })
+    |> Ash.create(actor: actor)
+  end
+
+  defp read_device(actor, uid) do
+    Device
+    |> Ash.Query.filter(uid == ^uid)
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+  end
+
+  defp read_device_by_ip(actor, ip) do
+    Device
+    |> Ash.Query.filter(ip == ^ip)
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+  end
+
+  defp unique_uid do
+    "device-#{System.unique_integer([:positive])}"
+  end
+
+  defp unique_ip do
+    seed = System.unique_integer([:positive, :monotonic])
+    "10.43.#{rem(seed, 250) + 1}.#{rem(div(seed, 250), 250) + 1}"
+  end
+end
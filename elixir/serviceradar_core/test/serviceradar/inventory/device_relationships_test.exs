@@ -0,0 +1,101 @@
+defmodule ServiceRadar.Inventory.DeviceRelationshipsTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.DeviceRelationships
+
+  defp update(metadata, opts \\ []) do
+    %{
+      metadata: metadata,
+      partition: Keyword.get(opts, :partition, "default"),
+      source: Keyword.get(opts, :source, "armis"),
+      timestamp: Keyword.get(opts, :timestamp, ~U[2026-10-01 12:00:00Z])
+    }
+  end
+
+  defp edge(source, type, target) do
+    %{source_device_id: source, target_device_id: target, relation_type: type}
+  end
+
+  defp loader(edges) do
+    fn device_ids ->
+      {:ok,
+       Enum.filter(edges, fn edge ->
+         edge.source_device_id in device_ids or edge.target_device_id in device_ids
+       end)}
+    end
+  end
+
+  describe "derive/1" do
+    test "derives a VM to host edge from sync metadata" do
+      assert [edge] = DeviceRelationships.derive([{update(%{"host_ip" => "10.0.0.1"}), "sr:vm"}])
+
+      assert edge.source_device_id == "sr:vm"
+      assert edge.target == {:ip, "10.0.0.1", "default"}
+      assert edge.relation_type == "runs_on"
+      assert edge.discovery_source == "armis"
+      assert edge.metadata_key == "host_ip"
+    end
+
+    test "ignores empty values, invalid IPs and self references" do
+      updates = [
+        {update(%{"host_ip" => "not-an-ip", "parent_device_id" => ""}), "sr:a"},
+        {update(%{"chassis_device_id" => "sr:b"}), "sr:b"}
+      ]
+
+      assert DeviceRelationships.derive(updates) == []
+    end
+
+    test "keeps the latest edge for the same device, type and field" do
+      updates = [
+        {update(%{"host_device_id" => "host-2"}, timestamp: ~U[2026-10-01 13:00:00Z]), "sr:vm"},
+        {update(%{"host_device_id" => "host-1"}, timestamp: ~U[2026-10-01 12:00:00Z]), "sr:vm"}
+      ]
+
+      assert [%{target: {:device, "host-2", "default"}}] = DeviceRelationships.derive(updates)
+    end
+  end
+
+  describe "traverse/2" do
+    setup do
+      edges = [
+        edge("sr:vm-1", "runs_on", "sr:host"),
+        edge("sr:vm-2", "runs_on", "sr:host"),
+        edge("sr:host", "member_of", "sr:cluster"),
+        edge("sr:cluster", "member_of", "sr:host")
+      ]
+
+      {:ok, load: loader(edges)}
+    end
+
+    test "follows incoming edges to the dependents of a device", %{load: load} do
+      assert {:ok, graph} =
+               DeviceRelationships.traverse("sr:host",
+                 direction: "incoming",
+                 types: ["runs_on"],
+                 load: load
+               )
+
+      assert graph.devices == ["sr:host", "sr:vm-1", "sr:vm-2"]
+      assert length(graph.relationships) == 2
+    end
+
+    test "follows outgoing edges over several hops and stops on cycles", %{load: load} do
+      assert {:ok, graph} =
+               DeviceRelationships.traverse("sr:vm-1",
+                 direction: "outgoing",
+                 depth: 5,
+                 load: load
+               )
+
+      assert graph.devices == ["sr:cluster", "sr:host", "sr:vm-1"]
+      assert length(graph.relationships) == 3
+    end
+
+    test "rejects invalid options", %{load: load} do
+      assert {:error, _} = DeviceRelationships.traverse("", load: load)
+      assert {:error, _} = DeviceRelationships.traverse("sr:host", depth: 9, load: load)
+      assert {:error, _} = DeviceRelationships.traverse("sr:host", direction: "up", load: load)
+      assert {:error, _} = DeviceRelationships.traverse("sr:host", types: ["peer_of"], load: load)
+    end
+  end
+end
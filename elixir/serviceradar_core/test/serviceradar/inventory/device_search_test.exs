@@ -0,0 +1,114 @@
+defmodule ServiceRadar.Inventory.DeviceSearchTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceIdentifier
+  alias ServiceRadar.Inventory.DeviceSearch
+  alias ServiceRadar.TestSupport
+
+  @moduletag :integration
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    actor = SystemActor.system(:device_search_test)
+    {:ok, actor: actor, suffix: "q#{System.unique_integer([:positive])}"}
+  end
+
+  test "matches hostnames with typos", %{actor: actor, suffix: suffix} do
+    {:ok, device} = create_device(actor, "web-prod-#{suffix}", unique_ip())
+
+    assert {:ok, results} = DeviceSearch.search("web-prd-#{suffix}", actor: actor)
+
+    assert %{matched_field: :hostname, highlight: nil} =
+             Enum.find(results, &(&1.device.uid == device.uid))
+  end
+
+  test "matches partial IPs and highlights the match", %{actor: actor} do
+    octet = rem(System.unique_integer([:positive]), 250) + 1
+    {:ok, device} = create_device(actor, "ip-host-#{octet}", "10.48.#{octet}.77")
+
+    assert {:ok, results} = DeviceSearch.search("10.48.#{octet}.", actor: actor)
+    match = Enum.find(results, &(&1.device.uid == device.uid))
+
+    assert match.matched_field == :ip
+    assert match.matched_value == "10.48.#{octet}.77"
+    assert match.highlight == %{start: 0, length: byte_size("10.48.#{octet}.")}
+  end
+
+  test "ranks exact, prefix, substring and fuzzy matches in order", %{
+    actor: actor,
+    suffix: suffix
+  } do
+    {:ok, fuzzy} = create_device(actor, "cor-sw1tch-#{suffix}", unique_ip())
+    {:ok, substring} = create_device(actor, "dc1-core-switch-#{suffix}", unique_ip())
+    {:ok, prefix} = create_device(actor, "core-switch-#{suffix}-backup", unique_ip())
+    {:ok, exact} = create_device(actor, "core-switch-#{suffix}", unique_ip())
+
+    assert {:ok, results} = DeviceSearch.search("core-switch-#{suffix}", actor: actor)
+
+    ranked =
+      results
+      |> Enum.map(& &1.device.uid)
+      |> Enum.filter(&(&1 in [fuzzy.uid, substring.uid, prefix.uid, exact.uid]))
+
+    assert ranked == [exact.uid, prefix.uid, substring.uid, fuzzy.uid]
+    assert hd(results).score == 1.0
+  end
+
+  test "matches identifier values and respects the partition", %{actor: actor, suffix: suffix} do
+    {:ok, in_partition} = create_device(actor, "ident-a-#{suffix}", unique_ip())
+    {:ok, other_partition} = create_device(actor, "ident-b-#{suffix}", unique_ip())
+    {:ok, _} = register_identifier(actor, in_partition.uid, "nb-#{suffix}-1", "default")
+    {:ok, _} = register_identifier(actor, other_partition.uid, "nb-#{suffix}-2", "remote-site")
+
+    assert {:ok, results} = DeviceSearch.search("nb-#{suffix}", actor: actor)
+    uids = Enum.map(results, & &1.device.uid)
+    assert in_partition.uid in uids
+    assert other_partition.uid in uids
+
+    assert {:ok, scoped} =
+             DeviceSearch.search("nb-#{suffix}", actor: actor, partition: "default")
+
+    assert [%{matched_field: :identifier, identifier_type: "netbox_device_id"} = match] =
+             Enum.filter(scoped, &(&1.device.uid in [in_partition.uid, other_partition.uid]))
+
+    assert match.device.uid == in_partition.uid
+  end
+
+  test "rejects queries that are too short", %{actor: actor} do
+    assert {:error, :query_too_short} = DeviceSearch.search(" ab ", actor: actor)
+  end
+
+  defp create_device(actor, hostname, ip) do
+    Device
+    |> Ash.Changeset.for_create(:create, %{
+      uid: "device-#{System.unique_integer([:positive])}",
+      hostname: hostname,
+      ip: ip
+    })
+    |> Ash.create(actor: actor)
+  end
+
+  defp register_identifier(actor, device_id, value, partition) do
+    DeviceIdentifier
+    |> Ash.Changeset.for_create(:upsert, %{
+      device_id: device_id,
+      identifier_type: :netbox_device_id,
+      identifier_value: value,
+      partition: partition,
+      confidence: :strong,
+      source: "device_search_test"
+    })
+    |> Ash.create(actor: actor)
+  end
+
+  defp unique_ip do
+    seed = System.unique_integer([:positive, :monotonic])
+    "10.49.#{rem(seed, 250) + 1}.#{rem(div(seed, 250), 250) + 1}"
+  end
+end
@@ -0,0 +1,112 @@
+defmodule ServiceRadar.Inventory.DeviceSnapshotTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.DeviceSnapshot
+
+  @base ~U[2026-03-01 12:00:00Z]
+  @device "default:10.0.0.5"
+
+  defp at(hours), do: DateTime.add(@base, round(hours * 3600), :second)
+
+  defp update(device_id, partition, source, hours, attrs) do
+    Map.merge(
+      %{
+        device_id: device_id,
+        partition: partition,
+        discovery_source: source,
+        observed_at: at(hours),
+        ip: "10.0.0.5",
+        hostname: nil,
+        is_available: false,
+        metadata: %{}
+      },
+      Map.new(attrs)
+    )
+  end
+
+  defp updates do
+    [
+      update(@device, "default", "armis", 0,
+        hostname: "core-sw-1",
+        is_available: true,
+        metadata: %{"armis_tags" => "pci,critical", "os" => "ios 15.1"}
+      ),
+      update(@device, "default", "sweep", 1, is_available: true),
+      update(@device, "default", "armis", 48,
+        hostname: "core-sw-01",
+        is_available: true,
+        metadata: %{"armis_tags" => "pci", "os" => "ios 15.1", "site" => "nyc-1"}
+      ),
+      update("lab:10.0.0.5", "lab", "armis", 1, hostname: "lab-host")
+    ]
+  end
+
+  # Mirrors the device_updates query: the latest row per source at or before
+  # the requested time, scoped to the partition when one is given.
+  defp load(device_id, partition, as_of) do
+    {:ok,
+     updates()
+     |> Enum.filter(fn update ->
+       update.device_id == device_id and DateTime.compare(update.observed_at, as_of) != :gt and
+         (is_nil(partition) or update.partition == partition)
+     end)
+     |> Enum.group_by(& &1.discovery_source)
+     |> Enum.map(fn {_source, rows} -> Enum.max_by(rows, & &1.observed_at, DateTime) end)}
+  end
+
+  defp change(diff, field), do: Enum.find(diff.changes, &(&1.field == field))
+
+  describe "compare/2" do
+    test "reports field, metadata and tag changes" do
+      assert {:ok, diff} =
+               DeviceSnapshot.compare(@device,
+                 partition: "default",
+                 from: at(2),
+                 to: at(72),
+                 load: &load/3
+               )
+
+      assert change(diff, "hostname") ==
+               %{field: "hostname", kind: :changed, before: "core-sw-1", after: "core-sw-01"}
+
+      assert %{kind: :added, after: "nyc-1"} = change(diff, "metadata.site")
+
+      assert %{kind: :changed, before: "pci,critical", after: "pci"} =
+               change(diff, "metadata.armis_tags")
+
+      assert diff.tags_added == []
+      assert diff.tags_removed == ["critical"]
+      assert length(diff.changes) == 3
+    end
+
+    test "treats a missing earlier snapshot as additions" do
+      assert {:ok, diff} =
+               DeviceSnapshot.compare(@device, from: at(-1), to: at(0.5), load: &load/3)
+
+      refute DeviceSnapshot.exists?(diff.from)
+      assert %{kind: :added} = change(diff, "ip")
+      assert diff.tags_added == ["critical", "pci"]
+    end
+
+    test "respects the partition scope" do
+      assert {:error, :not_found} =
+               DeviceSnapshot.compare(@device,
+                 partition: "lab",
+                 from: at(0),
+                 to: at(72),
+                 load: &load/3
+               )
+    end
+
+    test "validates the request" do
+      assert {:error, "device_id is required"} =
+               DeviceSnapshot.compare(" ", from: at(0), to: at(1), load: &load/3)
+
+      assert {:error, "from and to timestamps are required"} =
+               DeviceSnapshot.compare(@device, to: at(0), load: &load/3)
+
+      assert {:error, "from must be before to"} =
+               DeviceSnapshot.compare(@device, from: at(0), to: at(0), load: &load/3)
+    end
+  end
+end
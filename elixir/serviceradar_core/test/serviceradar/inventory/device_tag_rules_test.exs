@@ -0,0 +1,120 @@
+defmodule ServiceRadar.Inventory.DeviceTagRulesTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceTagger
+  alias ServiceRadar.Inventory.DeviceTagRule
+  alias ServiceRadar.Inventory.SyncIngestor
+  alias ServiceRadar.TestSupport
+
+  require Ash.Query
+
+  @moduletag :integration
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    actor = SystemActor.system(:device_tag_rules_test)
+
+    on_exit(fn ->
+      {:ok, rules} = DeviceTagRule.list(actor: actor)
+      Enum.each(rules, &DeviceTagRule.destroy!(&1, actor: actor))
+    end)
+
+    {:ok, actor: actor}
+  end
+
+  test "a CIDR rule tags ingested devices and follows rule changes", %{actor: actor} do
+    subnet = "10.77.#{rem(System.unique_integer([:positive]), 250)}"
+    ip = "#{subnet}.10"
+
+    {:ok, rule} =
+      DeviceTagRule.create(
+        %{name: "hq-subnet-#{subnet}", cidrs: ["#{subnet}.0/24"], tags: %{"site" => "hq"}},
+        actor: actor
+      )
+
+    assert :ok =
+             SyncIngestor.ingest_updates(
+               [%{"ip" => ip, "hostname" => "tagged-host", "source" => "netbox"}],
+               actor: actor
+             )
+
+    {:ok, [device]} =
+      Device
+      |> Ash.Query.filter(ip == ^ip)
+      |> Ash.read(actor: actor)
+      |> Page.unwrap()
+
+    assert device.tags == %{"site" => "hq"}
+    assert device.auto_tags == %{"site" => "hq"}
+
+    {:ok, device} =
+      device
+      |> Ash.Changeset.for_update(:update, %{tags: Map.put(device.tags, "owner", "netops")})
+      |> Ash.update(actor: actor)
+
+    {:ok, _rule} = DeviceTagRule.update(rule, %{tags: %{"site" => "branch"}}, actor: actor)
+    assert DeviceTagger.resync_all() >= 1
+
+    {:ok, device} = Device.get_by_uid(device.uid, false, actor: actor)
+    assert device.tags == %{"site" => "branch", "owner" => "netops"}
+    assert device.auto_tags == %{"site" => "branch"}
+
+    :ok = DeviceTagRule.destroy(rule, actor: actor)
+    DeviceTagger.resync_all()
+
+    {:ok, device} = Device.get_by_uid(device.uid, false, actor: actor)
+    assert device.tags == %{"owner" => "netops"}
+    assert device.auto_tags == %{}
+  end
+
+  test "a risk threshold rule follows device risk changes", %{actor: actor} do
+    {:ok, _rule} =
+      DeviceTagRule.create(
+        %{name: "high-risk", min_risk_score: 70, tags: %{"risk" => "high"}},
+        actor: actor
+      )
+
+    uid = "tag-rule-#{System.unique_integer([:positive])}"
+
+    {:ok, device} =
+      Device
+      |> Ash.Changeset.for_create(:create, %{uid: uid, risk_score: 10})
+      |> Ash.create(actor: actor)
+
+    {:ok, device} = update_risk(device, 85, actor)
+    assert device.tags == %{"risk" => "high"}
+
+    {:ok, device} = update_risk(device, 20, actor)
+    assert device.tags == %{}
+    assert device.auto_tags == %{}
+  end
+
+  test "rejects invalid CIDRs and patterns", %{actor: actor} do
+    assert {:error, _} =
+             DeviceTagRule.create(
+               %{name: "bad-cidr", cidrs: ["10.0.0.0/99"], tags: %{"a" => "b"}},
+               actor: actor
+             )
+
+    assert {:error, _} =
+             DeviceTagRule.create(
+               %{name: "bad-pattern", vendor_pattern: "(", tags: %{"a" => "b"}},
+               actor: actor
+             )
+
+    assert {:error, _} = DeviceTagRule.create(%{name: "no-tags"}, actor: actor)
+  end
+
+  defp update_risk(device, score, actor) do
+    device
+    |> Ash.Changeset.for_update(:update, %{risk_score: score})
+    |> Ash.update(actor: actor)
+  end
+end
@@ -0,0 +1,86 @@
+defmodule ServiceRadar.Inventory.DeviceTaggerTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.DeviceTagger
+
+  defp rule(attrs) do
+    Map.merge(
+      %{
+        cidrs: [],
+        device_types: [],
+        vendor_pattern: nil,
+        os_pattern: nil,
+        min_risk_score: nil,
+        tags: %{}
+      },
+      attrs
+    )
+  end
+
+  describe "evaluate/2" do
+    test "matches devices inside a CIDR" do
+      rules = [rule(%{cidrs: ["10.20.0.0/16"], tags: %{"site" => "hq"}})]
+
+      assert DeviceTagger.evaluate(%{ip: "10.20.4.7"}, rules) == %{"site" => "hq"}
+      assert DeviceTagger.evaluate(%{ip: "10.21.4.7"}, rules) == %{}
+      assert DeviceTagger.evaluate(%{ip: nil}, rules) == %{}
+    end
+
+    test "matches devices at or above a risk threshold" do
+      rules = [rule(%{min_risk_score: 70, tags: %{"risk" => "high"}})]
+
+      assert DeviceTagger.evaluate(%{risk_score: 70}, rules) == %{"risk" => "high"}
+      assert DeviceTagger.evaluate(%{risk_score: 69}, rules) == %{}
+      assert DeviceTagger.evaluate(%{risk_score: nil}, rules) == %{}
+    end
+
+    test "requires every condition and matches type, vendor and OS" do
+      rules = [
+        rule(%{
+          device_types: ["Server"],
+          vendor_pattern: "(?i)dell",
+          os_pattern: "Windows Server 2019",
+          tags: %{"patch-group" => "win2019"}
+        })
+      ]
+
+      device = %{
+        type: "server",
+        vendor_name: "Dell Inc.",
+        os: %{"name" => "Windows Server", "version" => "2019"}
+      }
+
+      assert DeviceTagger.evaluate(device, rules) == %{"patch-group" => "win2019"}
+      assert DeviceTagger.evaluate(%{device | vendor_name: "HP"}, rules) == %{}
+    end
+
+    test "earlier rules win tag key collisions" do
+      rules = [
+        rule(%{cidrs: ["10.0.0.0/8"], tags: %{"zone" => "internal"}}),
+        rule(%{tags: %{"zone" => "default", "managed" => "yes"}})
+      ]
+
+      assert DeviceTagger.evaluate(%{ip: "10.1.1.1"}, rules) == %{
+               "zone" => "internal",
+               "managed" => "yes"
+             }
+    end
+  end
+
+  describe "retag/3" do
+    test "replaces rule-applied tags and keeps manual ones" do
+      tags = %{"site" => "hq", "owner" => "netops"}
+      auto_tags = %{"site" => "hq"}
+
+      assert DeviceTagger.retag(tags, auto_tags, %{"site" => "branch"}) ==
+               {%{"site" => "branch", "owner" => "netops"}, %{"site" => "branch"}}
+
+      assert DeviceTagger.retag(tags, auto_tags, %{}) == {%{"owner" => "netops"}, %{}}
+    end
+
+    test "a manual value for a rule key is not overwritten" do
+      assert DeviceTagger.retag(%{site: "lab"}, %{}, %{"site" => "hq"}) ==
+               {%{"site" => "lab"}, %{}}
+    end
+  end
+end
@@ -0,0 +1,86 @@
+defmodule ServiceRadar.Inventory.DeviceUpdateQuarantineTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.QuarantinedDeviceUpdate
+  alias ServiceRadar.Inventory.SyncIngestor
+  alias ServiceRadar.TestSupport
+
+  require Ash.Query
+
+  @moduletag :integration
+
+  @event [:serviceradar, :inventory, :device_update, :quarantined]
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    actor = SystemActor.system(:device_update_quarantine_test)
+    handler = "device-update-quarantine-test-#{System.unique_integer([:positive])}"
+    test_pid = self()
+
+    :telemetry.attach(
+      handler,
+      @event,
+      fn _event, measurements, metadata, _config ->
+        send(test_pid, {:quarantined, measurements, metadata})
+      end,
+      nil
+    )
+
+    on_exit(fn -> :telemetry.detach(handler) end)
+    {:ok, actor: actor}
+  end
+
+  test "quarantines malformed updates and ingests the valid ones", %{actor: actor} do
+    suffix = System.unique_integer([:positive])
+    source = "quarantine-test-#{suffix}"
+    valid_ip = "10.78.#{rem(suffix, 250)}.#{rem(div(suffix, 250), 250) + 1}"
+    bad_ip = "10.78.#{rem(suffix, 250)}.300"
+
+    updates = [
+      %{"ip" => valid_ip, "hostname" => "valid-#{suffix}", "source" => source},
+      %{"ip" => bad_ip, "hostname" => "bad-ip-#{suffix}", "source" => source},
+      %{"hostname" => "no-identity-#{suffix}", "source" => source}
+    ]
+
+    assert :ok = SyncIngestor.ingest_updates(updates, actor: actor)
+
+    assert {:ok, [device]} = read_devices(actor, valid_ip)
+    assert device.hostname == "valid-#{suffix}"
+    assert {:ok, []} = read_devices(actor, bad_ip)
+
+    assert {:ok, quarantined} = read_quarantined(actor, %{source: source})
+    reasons = quarantined |> Enum.map(& &1.reasons) |> Enum.sort()
+    assert reasons == [["invalid_ip"], ["missing_device_id"]]
+
+    bad = Enum.find(quarantined, &(&1.reasons == ["invalid_ip"]))
+    assert bad.ip == bad_ip
+    assert bad.payload["hostname"] == "bad-ip-#{suffix}"
+
+    assert {:ok, [only_bad]} = read_quarantined(actor, %{source: source, reason: "invalid_ip"})
+    assert only_bad.id == bad.id
+
+    assert_received {:quarantined, %{count: 1}, %{reason: "invalid_ip", source: ^source}}
+    assert_received {:quarantined, %{count: 1}, %{reason: "missing_device_id", source: ^source}}
+  end
+
+  defp read_quarantined(actor, filters) do
+    QuarantinedDeviceUpdate
+    |> Ash.Query.for_read(:recent, filters)
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+  end
+
+  defp read_devices(actor, ip) do
+    Device
+    |> Ash.Query.filter(ip == ^ip)
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+  end
+end
@@ -0,0 +1,68 @@
+defmodule ServiceRadar.Inventory.DeviceUpdateValidatorTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.DeviceUpdateValidator
+
+  @all_checks %{
+    enabled: true,
+    checks: [:device_id, :ip, :source, :partition],
+    known_sources: MapSet.new(["armis", "netbox"])
+  }
+
+  @valid %{
+    "device_id" => "default:10.0.0.1",
+    "ip" => "10.0.0.1",
+    "source" => "armis",
+    "partition" => "default"
+  }
+
+  test "accepts a well-formed update" do
+    assert :ok = DeviceUpdateValidator.validate(@valid, @all_checks)
+    assert :ok = DeviceUpdateValidator.validate(%{@valid | "ip" => "fd00::1"}, @all_checks)
+  end
+
+  test "accepts atom keys and a derivable device id" do
+    update = %{ip: "10.0.0.2", source: "NetBox", partition: "site-a"}
+    assert :ok = DeviceUpdateValidator.validate(update, @all_checks)
+
+    update = %{"metadata" => %{"armis_device_id" => "42"}, "source" => "armis"}
+    assert :ok = DeviceUpdateValidator.validate(Map.put(update, "partition", "p"), @all_checks)
+  end
+
+  test "reports every failed check" do
+    update = %{"ip" => "10.0.0.256", "source" => "spreadsheet", "partition" => " "}
+
+    assert {:error, [:invalid_ip, :unknown_source, :missing_partition]} =
+             DeviceUpdateValidator.validate(update, @all_checks)
+
+    assert {:error, [:missing_device_id, :unknown_source, :missing_partition]} =
+             DeviceUpdateValidator.validate(%{"hostname" => "orphan"}, @all_checks)
+
+    assert {:error, [:not_a_map]} = DeviceUpdateValidator.validate("junk", @all_checks)
+  end
+
+  test "only runs the configured checks" do
+    config = %{@all_checks | checks: [:device_id, :ip]}
+    assert :ok = DeviceUpdateValidator.validate(%{"ip" => "10.0.0.3"}, config)
+
+    assert {:error, [:invalid_ip]} =
+             DeviceUpdateValidator.validate(%{"ip" => "not-an-ip"}, config)
+  end
+
+  test "split keeps valid updates in order and pairs rejects with reasons" do
+    bad = %{"ip" => "10.0.0.999", "source" => "armis", "partition" => "default"}
+    other = %{@valid | "device_id" => "default:10.0.0.4", "ip" => "10.0.0.4"}
+
+    assert {[@valid, ^other], [{^bad, [:invalid_ip]}]} =
+             DeviceUpdateValidator.split([@valid, bad, other], @all_checks)
+
+    assert {[^bad], []} = DeviceUpdateValidator.split([bad], %{@all_checks | enabled: false})
+  end
+
+  test "config falls back to the default checks and known sources" do
+    config = DeviceUpdateValidator.config()
+
+    assert config.checks == [:device_id, :ip]
+    assert "netbox" in config.known_sources
+  end
+end
@@ -0,0 +1,121 @@
+defmodule ServiceRadar.Inventory.DuplicateReportTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.DuplicateReport
+
+  defp device(uid, attrs) do
+    Map.merge(
+      %{
+        uid: uid,
+        ip: nil,
+        mac: nil,
+        hostname: nil,
+        discovery_sources: [],
+        last_seen_time: nil,
+        metadata: %{}
+      },
+      Map.new(attrs)
+    )
+  end
+
+  defp uids(group), do: Enum.map(group.devices, & &1.uid)
+
+  describe "detect/2" do
+    test "groups devices sharing a MAC" do
+      devices = [
+        device("sr:1", mac: "00:1a:2b:3c:4d:5e"),
+        device("sr:2", mac: "00-1A-2B-3C-4D-5E, 00:1a:2b:3c:4d:60"),
+        device("sr:3", mac: "00:1a:2b:3c:4d:60"),
+        device("sr:4", mac: "00:00:00:00:00:00"),
+        device("sr:5", mac: "00:00:00:00:00:00"),
+        device("sr:6", mac: "da:a1:19:00:00:01"),
+        device("sr:7", mac: "da:a1:19:00:00:01"),
+        device("serviceradar:agent:a1", mac: "00:1a:2b:3c:4d:5e")
+      ]
+
+      assert {:ok, report} = DuplicateReport.detect(devices)
+      assert report.scanned_devices == 7
+
+      # Zero and randomized MACs are not evidence.
+      assert [group] = report.groups
+      assert group.confidence == :high
+      assert uids(group) == ["sr:1", "sr:2", "sr:3"]
+
+      assert [
+               %{type: :mac, value: "00:1A:2B:3C:4D:5E", device_ids: ["sr:1", "sr:2"]},
+               %{type: :mac, value: "00:1A:2B:3C:4D:60"}
+             ] = group.evidence
+    end
+
+    test "groups identical and near-identical hostnames" do
+      devices = [
+        device("sr:1", hostname: "SW-Core-01.example.com"),
+        device("sr:2", hostname: "sw-core-01"),
+        device("sr:3", hostname: "sw-core-1"),
+        device("sr:4", hostname: "sw-edge-07"),
+        device("sr:5", hostname: "printer-lobby"),
+        device("sr:6", hostname: "10.0.0.6"),
+        device("sr:7", hostname: "10.0.0.6")
+      ]
+
+      assert {:ok, %{groups: [group]}} = DuplicateReport.detect(devices)
+      assert group.confidence == :medium
+      assert uids(group) == ["sr:1", "sr:2", "sr:3"]
+
+      assert [
+               %{type: :hostname, value: "sw-core-01", similarity: 1.0},
+               %{type: :hostname, value: "sw-core-01 ~ sw-core-1", similarity: similarity}
+             ] = group.evidence
+
+      assert similarity >= 0.85
+
+      assert {:ok, %{groups: [strict]}} = DuplicateReport.detect(devices, hostname_threshold: 1)
+      assert uids(strict) == ["sr:1", "sr:2"]
+    end
+
+    test "shared identifiers raise a hostname group to high confidence" do
+      devices = [
+        device("sr:1", hostname: "db-01", metadata: %{"armis_device_id" => 42.0}),
+        device("sr:2", hostname: "db-01.corp", metadata: %{"armis_device_id" => "42"}),
+        device("sr:3", metadata: %{"armis_device_id" => "43"})
+      ]
+
+      assert {:ok, %{groups: [group]}} = DuplicateReport.detect(devices)
+      assert group.confidence == :high
+
+      assert [
+               %{type: :identifier, key: "armis_device_id", value: "42"},
+               %{type: :hostname, value: "db-01"}
+             ] = group.evidence
+    end
+
+    test "rejects thresholds outside (0, 1]" do
+      assert {:error, _} = DuplicateReport.detect([], hostname_threshold: 1.5)
+    end
+  end
+
+  describe "report/1" do
+    test "requires a partition and reports truncation" do
+      test_pid = self()
+
+      load = fn partition, limit ->
+        send(test_pid, {:loaded, partition, limit})
+
+        {:ok,
+         [
+           device("sr:1", mac: "00:1a:2b:3c:4d:5e"),
+           device("sr:2", mac: "00:1a:2b:3c:4d:5e"),
+           device("sr:3", mac: "00:1a:2b:3c:4d:5e")
+         ]}
+      end
+
+      assert {:error, "partition is required"} = DuplicateReport.report(load: load)
+
+      assert {:ok, report} = DuplicateReport.report(partition: "east", max_devices: 2, load: load)
+      assert_received {:loaded, "east", 3}
+      assert report.partition == "east"
+      assert report.truncated
+      assert [%{devices: [_, _]}] = report.groups
+    end
+  end
+end
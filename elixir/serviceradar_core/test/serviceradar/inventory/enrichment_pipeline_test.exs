@@ -0,0 +1,135 @@
+defmodule ServiceRadar.Inventory.EnrichmentPipelineTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.EnrichmentPipeline
+  alias ServiceRadar.Inventory.EnrichmentStages
+
+  defmodule PartitionStage do
+    @moduledoc false
+    @behaviour ServiceRadar.Inventory.EnrichmentStage
+
+    def name, do: :cidr_partition
+    def requires, do: []
+
+    def enrich(update) do
+      trail = Map.get(update.metadata, "trail", []) ++ ["cidr_partition"]
+
+      %{update | partition: "lab", metadata: Map.put(update.metadata, "trail", trail)}
+    end
+  end
+
+  defmodule TagStage do
+    @moduledoc false
+    @behaviour ServiceRadar.Inventory.EnrichmentStage
+
+    def name, do: :tags
+    def requires, do: [:cidr_partition]
+
+    def enrich(update) do
+      trail = Map.get(update.metadata, "trail", []) ++ ["tags"]
+
+      %{
+        update
+        | tags: %{"partition" => update.partition},
+          metadata: Map.put(update.metadata, "trail", trail)
+      }
+    end
+  end
+
+  defmodule FailingStage do
+    @moduledoc false
+    @behaviour ServiceRadar.Inventory.EnrichmentStage
+
+    def name, do: :failing
+    def requires, do: []
+    def enrich(_update), do: raise("boom")
+  end
+
+  @plugins [PartitionStage, TagStage, FailingStage]
+
+  defp update do
+    %{ip: "10.0.0.1", partition: "default", metadata: %{}, tags: %{}, source: "armis"}
+  end
+
+  describe "resolve/1" do
+    test "defaults to the built-in stages followed by plugins" do
+      assert {stages, []} = EnrichmentPipeline.resolve(plugins: [PartitionStage, TagStage])
+
+      assert stages == [
+               EnrichmentStages.Classification,
+               EnrichmentStages.GeoIP,
+               PartitionStage,
+               TagStage
+             ]
+    end
+
+    test "follows the configured order and skips disabled stages" do
+      assert {stages, []} =
+               EnrichmentPipeline.resolve(
+                 plugins: @plugins,
+                 order: ["cidr_partition", "geoip", "tags", "classification"],
+                 disabled: [:geoip]
+               )
+
+      assert stages == [PartitionStage, TagStage, EnrichmentStages.Classification]
+    end
+
+    test "skips a stage ordered before an enabled stage it requires" do
+      assert {[PartitionStage], [{:tags, reason}]} =
+               EnrichmentPipeline.resolve(plugins: @plugins, order: [:tags, :cidr_partition])
+
+      assert reason =~ ":cidr_partition"
+    end
+
+    test "runs a stage whose required stage is disabled" do
+      assert {[TagStage], []} =
+               EnrichmentPipeline.resolve(
+                 plugins: @plugins,
+                 order: [:cidr_partition, :tags],
+                 disabled: [:cidr_partition]
+               )
+    end
+
+    test "skips unknown stages" do
+      assert {[PartitionStage], [{"no_such_enricher", "unknown stage"}]} =
+               EnrichmentPipeline.resolve(
+                 plugins: @plugins,
+                 order: ["no_such_enricher", :cidr_partition]
+               )
+    end
+  end
+
+  describe "run/2" do
+    test "applies stages in order so later stages see earlier output" do
+      [enriched] = EnrichmentPipeline.run([update()], [PartitionStage, TagStage])
+
+      assert enriched.metadata["trail"] == ["cidr_partition", "tags"]
+      assert enriched.tags == %{"partition" => "lab"}
+    end
+
+    test "a disabled stage leaves the update untouched by it" do
+      {stages, []} =
+        EnrichmentPipeline.resolve(
+          plugins: @plugins,
+          order: [:cidr_partition, :tags],
+          disabled: [:cidr_partition]
+        )
+
+      [enriched] = EnrichmentPipeline.run([update()], stages)
+
+      assert enriched.partition == "default"
+      assert enriched.metadata["trail"] == ["tags"]
+      assert enriched.tags == %{"partition" => "default"}
+    end
+
+    test "a failing stage leaves the update unchanged and later stages still run" do
+      [enriched] = EnrichmentPipeline.run([update()], [FailingStage, PartitionStage])
+
+      assert enriched.metadata["trail"] == ["cidr_partition"]
+    end
+
+    test "no stages returns the updates as they are" do
+      assert EnrichmentPipeline.run([update()], []) == [update()]
+    end
+  end
+end
@@ -0,0 +1,76 @@
+defmodule ServiceRadar.Inventory.FieldConflictDetectorTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.FieldConflictDetector
+  alias ServiceRadar.Inventory.SourceConfidence
+
+  @confidence SourceConfidence.defaults()
+
+  defp record(source, fields) do
+    Map.merge(%{uid: "sr:dev-1", discovery_sources: [source]}, fields)
+  end
+
+  test "reports sources that disagree within one batch" do
+    records = [
+      record("netbox", %{hostname: "netbox-name"}),
+      record("armis", %{hostname: "armis-name"})
+    ]
+
+    assert [conflict] = FieldConflictDetector.detect(records, %{}, @confidence)
+
+    assert conflict == %{
+             device_id: "sr:dev-1",
+             field: "hostname",
+             source_values: %{"netbox" => "netbox-name", "armis" => "armis-name"},
+             # Equal confidence: the later report wins, as in the merge.
+             winning_source: "armis",
+             winning_value: "armis-name"
+           }
+  end
+
+  test "the more confident source wins regardless of order" do
+    records = [
+      record("snmp", %{model: "C9300"}),
+      record("sweep", %{model: "Unknown"})
+    ]
+
+    assert [%{winning_source: "snmp", winning_value: "C9300"}] =
+             FieldConflictDetector.detect(records, %{}, @confidence)
+  end
+
+  test "ignores case, whitespace and empty values" do
+    records = [
+      record("netbox", %{hostname: "Core-SW1", vendor_name: ""}),
+      record("armis", %{hostname: " core-sw1 ", vendor_name: "Cisco"})
+    ]
+
+    assert FieldConflictDetector.detect(records, %{}, @confidence) == []
+  end
+
+  test "compares against the stored value reported by another source" do
+    existing = %{
+      "sr:dev-1" => %{uid: "sr:dev-1", discovery_sources: ["netbox"], hostname: "netbox-name"}
+    }
+
+    assert [%{source_values: values}] =
+             FieldConflictDetector.detect(
+               [record("armis", %{hostname: "armis-name"})],
+               existing,
+               @confidence
+             )
+
+    assert values == %{"netbox" => "netbox-name", "armis" => "armis-name"}
+  end
+
+  test "a source changing its own stored value is not a conflict" do
+    existing = %{
+      "sr:dev-1" => %{uid: "sr:dev-1", discovery_sources: ["armis"], hostname: "old-name"}
+    }
+
+    assert FieldConflictDetector.detect(
+             [record("armis", %{hostname: "new-name"})],
+             existing,
+             @confidence
+           ) == []
+  end
+end
@@ -7,6 +7,7 @@ defmodule ServiceRadar.Inventory.IdentityReconcilerMergeTest do
 
   alias ServiceRadar.Actors.SystemActor
   alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceIdentifier
   alias ServiceRadar.Inventory.IdentityReconciler
   alias ServiceRadar.Inventory.Interface
   alias ServiceRadar.TestSupport
@@ -105,6 +106,40 @@ defmodule ServiceRadar.Inventory.IdentityReconcilerMergeTest do
     assert telemetry_metadata.manual_override == true
   end
 
+  test "merge records an identity merge event with the triggering identifier", %{actor: actor} do
+    from_uid = "sr:" <> Ecto.UUID.generate()
+    to_uid = "sr:" <> Ecto.UUID.generate()
+    mac = :sha256 |> :crypto.hash(from_uid) |> binary_part(0, 6) |> Base.encode16()
+
+    assert {:ok, _from_device} = create_device(actor, from_uid, "merge-from-event")
+    assert {:ok, _to_device} = create_device(actor, to_uid, "merge-to-event")
+    assert {:ok, _} = register_identifier(actor, from_uid, :mac, mac)
+
+    test_pid = self()
+
+    record_event = fn attrs, _actor ->
+      send(test_pid, {:merge_event, attrs})
+      {:ok, attrs}
+    end
+
+    assert :ok =
+             IdentityReconciler.merge_devices(from_uid, to_uid,
+               actor: actor,
+               reason: "identifier_conflict",
+               details: %{identifiers: [%{type: :mac, value: mac}]},
+               record_event: record_event
+             )
+
+    assert_receive {:merge_event, event}
+    assert event.metadata["canonical_device_id"] == to_uid
+    assert event.metadata["merged_device_ids"] == [from_uid]
+    assert event.metadata["reason"] == "identifier_conflict"
+
+    assert event.metadata["identifiers"] == [
+             %{"type" => "mac", "value" => mac, "partition" => "default"}
+           ]
+  end
+
   defp create_device(actor, uid, hostname) do
     attrs = %{
       uid: uid,
@@ -122,6 +157,20 @@ defmodule ServiceRadar.Inventory.IdentityReconcilerMergeTest do
     "10.#{a}.#{b}.#{max(c, 1)}"
   end
 
+  defp register_identifier(actor, device_id, type, value) do
+    attrs = %{
+      device_id: device_id,
+      identifier_type: type,
+      identifier_value: value,
+      partition: "default",
+      source: "test"
+    }
+
+    DeviceIdentifier
+    |> Ash.Changeset.for_create(:register, attrs)
+    |> Ash.create(actor: actor)
+  end
+
   defp create_interface(actor, device_id, timestamp, interface_uid, if_index, if_name) do
     attrs = %{
       timestamp: timestamp,
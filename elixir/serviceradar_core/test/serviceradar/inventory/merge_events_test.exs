@@ -0,0 +1,166 @@
+defmodule ServiceRadar.Inventory.MergeEventsTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.MergeEvents
+
+  defp identifier(type, value, partition) do
+    %{identifier_type: type, identifier_value: value, partition: partition}
+  end
+
+  defp record(merge) do
+    test_pid = self()
+
+    record_event = fn attrs, _actor ->
+      send(test_pid, {:merge_event, attrs})
+      {:ok, attrs}
+    end
+
+    assert :ok = MergeEvents.record(merge, record_event: record_event, webhook_url: nil)
+    assert_received {:merge_event, event}
+    event
+  end
+
+  describe "build/3" do
+    test "reports the canonical and merged-away IDs and the triggering identifiers" do
+      merge =
+        MergeEvents.build("sr:old", "sr:canonical",
+          reason: "identifier_conflict",
+          details: %{identifiers: [%{type: :armis_device_id, value: "armis-1", device_id: "x"}]},
+          from_identifiers: [identifier(:armis_device_id, "armis-1", "default")],
+          to_identifiers: [identifier(:mac, "00AABBCCDDEE", "default")]
+        )
+
+      assert merge == %{
+               canonical_device_id: "sr:canonical",
+               merged_device_ids: ["sr:old"],
+               reason: "identifier_conflict",
+               identifiers: [%{type: "armis_device_id", value: "armis-1", partition: "default"}],
+               partitions: ["default"],
+               cross_partition: false
+             }
+    end
+
+    test "reads identifier maps and conflicting IPs from the merge details" do
+      merge =
+        MergeEvents.build("sr:old", "sr:canonical",
+          details: %{
+            identifiers: %{agent_id: nil, armis_id: "armis-1", custom: %{serial: "SN1"}},
+            alias_ip: "10.0.0.5"
+          }
+        )
+
+      assert merge.identifiers == [
+               %{type: "armis_device_id", value: "armis-1", partition: nil},
+               %{type: "serial", value: "SN1", partition: nil},
+               %{type: "ip", value: "10.0.0.5", partition: nil}
+             ]
+    end
+
+    test "falls back to the identifiers both devices shared" do
+      merge =
+        MergeEvents.build("sr:old", "sr:canonical",
+          reason: "identifier_backfill",
+          details: %{source: "scheduled_reconciliation"},
+          from_identifiers: [
+            identifier(:mac, "00AABBCCDDEE", "tenant-a"),
+            identifier(:netbox_device_id, "42", "tenant-a")
+          ],
+          to_identifiers: [identifier(:mac, "00AABBCCDDEE", "tenant-b")]
+        )
+
+      assert merge.identifiers == [%{type: "mac", value: "00AABBCCDDEE", partition: "tenant-a"}]
+      assert merge.partitions == ["tenant-a", "tenant-b"]
+      assert merge.cross_partition
+    end
+  end
+
+  describe "record/2" do
+    test "records an identity merge event" do
+      event =
+        "sr:old"
+        |> MergeEvents.build("sr:canonical",
+          reason: "identifier_conflict",
+          details: %{identifiers: [%{type: :mac, value: "00AABBCCDDEE"}]},
+          from_identifiers: [identifier(:mac, "00AABBCCDDEE", "default")]
+        )
+        |> record()
+
+      assert event.log_name == MergeEvents.log_name()
+      assert event.status_code == "identity_merged"
+      assert event.severity == "Informational"
+      assert event.device == %{"uid" => "sr:canonical"}
+      assert event.message == "sr:old merged into sr:canonical (identifier_conflict)"
+
+      assert event.metadata["canonical_device_id"] == "sr:canonical"
+      assert event.metadata["merged_device_ids"] == ["sr:old"]
+
+      assert event.metadata["identifiers"] == [
+               %{"type" => "mac", "value" => "00AABBCCDDEE", "partition" => "default"}
+             ]
+
+      assert %{"name" => "Identifier mac", "value" => "00AABBCCDDEE"} =
+               List.last(event.observables)
+    end
+
+    test "raises the severity of merges across partitions" do
+      event =
+        "sr:old"
+        |> MergeEvents.build("sr:canonical",
+          from_identifiers: [identifier(:mac, "00AABBCCDDEE", "tenant-a")],
+          to_identifiers: [identifier(:mac, "00AABBCCDDEE", "tenant-b")]
+        )
+        |> record()
+
+      assert event.severity == "Medium"
+      assert event.metadata["cross_partition"]
+      assert event.message =~ "across partitions tenant-a, tenant-b"
+    end
+  end
+
+  describe "notify_webhook/2" do
+    setup do
+      {:ok, merge: MergeEvents.build("sr:old", "sr:canonical", details: %{ip: "10.0.0.5"})}
+    end
+
+    test "posts the merge as JSON", %{merge: merge} do
+      test_pid = self()
+
+      http_post = fn url, opts ->
+        send(test_pid, {:posted, url, opts})
+        {:ok, %{status: 202}}
+      end
+
+      assert :ok =
+               MergeEvents.notify_webhook(merge,
+                 webhook_url: "https://review.example.com/merges",
+                 webhook_headers: %{"authorization" => "Bearer token"},
+                 validate_url: fn _url -> :ok end,
+                 http_post: http_post
+               )
+
+      assert_received {:posted, "https://review.example.com/merges", opts}
+      assert opts[:headers] == %{"authorization" => "Bearer token"}
+      assert opts[:json].event == "identity.merge"
+      assert opts[:json].canonical_device_id == "sr:canonical"
+      assert opts[:json].merged_device_ids == ["sr:old"]
+      assert opts[:json].identifiers == [%{type: "ip", value: "10.0.0.5", partition: nil}]
+    end
+
+    test "reports failures and skips without a webhook", %{merge: merge} do
+      assert {:error, "HTTP 500"} =
+               MergeEvents.notify_webhook(merge,
+                 webhook_url: "https://review.example.com/merges",
+                 validate_url: fn _url -> :ok end,
+                 http_post: fn _url, _opts -> {:ok, %{status: 500}} end
+               )
+
+      assert {:error, "feed URL must use https"} =
+               MergeEvents.notify_webhook(merge,
+                 webhook_url: "http://review.example.com/merges",
+                 validate_url: fn _url -> {:error, :disallowed_scheme} end
+               )
+
+      assert :skipped = MergeEvents.notify_webhook(merge, webhook_url: nil)
+    end
+  end
+end
@@ -0,0 +1,109 @@
+defmodule ServiceRadar.Inventory.PartitionConsistencyTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Infrastructure.Partition
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceIdentifier
+  alias ServiceRadar.Inventory.PartitionConsistency
+  alias ServiceRadar.TestSupport
+
+  @moduletag :integration
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    actor = SystemActor.system(:partition_consistency_test)
+    seed = System.unique_integer([:positive])
+    octet = rem(seed, 200) + 20
+
+    {:ok, site_a} = create_partition(actor, "site-a-#{seed}", ["10.#{octet}.1.0/24"])
+    {:ok, site_b} = create_partition(actor, "site-b-#{seed}", ["10.#{octet}.2.0/24", "bogus"])
+
+    {:ok, actor: actor, octet: octet, site_a: site_a.slug, site_b: site_b.slug}
+  end
+
+  test "flags a device whose IP belongs to another partition", ctx do
+    {:ok, device} = create_device(ctx.actor, "10.#{ctx.octet}.1.15")
+    {:ok, _} = register_identifier(ctx.actor, device.uid, ctx.site_b)
+
+    assert {:ok, report} = report(ctx, ctx.site_a)
+    assert %{} = finding = find(report, device.uid)
+
+    assert finding.issues == [:cidr_mismatch]
+    assert finding.assigned_partitions == [ctx.site_b]
+    assert ctx.site_a in finding.inferred_partitions
+    assert finding.ip == "10.#{ctx.octet}.1.15"
+    assert %{partition: ctx.site_b, cidr: "bogus"} in report.skipped_cidrs
+  end
+
+  test "flags a device with identifiers in several partitions", ctx do
+    {:ok, device} = create_device(ctx.actor, "10.#{ctx.octet}.1.16")
+    {:ok, _} = register_identifier(ctx.actor, device.uid, ctx.site_a)
+    {:ok, _} = register_identifier(ctx.actor, device.uid, ctx.site_b)
+
+    assert {:ok, report} = report(ctx, ctx.site_a)
+    assert %{issues: [:multiple_partitions]} = find(report, device.uid)
+  end
+
+  test "does not flag consistent assignments", ctx do
+    {:ok, in_cidr} = create_device(ctx.actor, "10.#{ctx.octet}.2.20")
+    {:ok, _} = register_identifier(ctx.actor, in_cidr.uid, ctx.site_b)
+
+    {:ok, outside_cidrs} = create_device(ctx.actor, "10.#{ctx.octet}.9.20")
+    {:ok, _} = register_identifier(ctx.actor, outside_cidrs.uid, ctx.site_a)
+
+    assert {:ok, report_a} = report(ctx, ctx.site_a)
+    assert {:ok, report_b} = report(ctx, ctx.site_b)
+
+    for report <- [report_a, report_b], uid <- [in_cidr.uid, outside_cidrs.uid] do
+      refute find(report, uid)
+    end
+  end
+
+  test "issues/2 classifies assignments" do
+    assert PartitionConsistency.issues(["a"], ["a"]) == []
+    assert PartitionConsistency.issues(["a"], []) == []
+    assert PartitionConsistency.issues(["b"], ["a"]) == [:cidr_mismatch]
+    assert PartitionConsistency.issues(["a", "b"], ["a"]) == [:multiple_partitions]
+
+    assert PartitionConsistency.issues(["b", "c"], ["a"]) ==
+             [:cidr_mismatch, :multiple_partitions]
+  end
+
+  defp report(ctx, partition) do
+    PartitionConsistency.report(actor: ctx.actor, partition: partition, limit: 1_000)
+  end
+
+  defp find(report, uid), do: Enum.find(report.findings, &(&1.device.uid == uid))
+
+  defp create_partition(actor, slug, cidrs) do
+    Partition
+    |> Ash.Changeset.for_create(:create, %{name: slug, slug: slug, cidr_ranges: cidrs})
+    |> Ash.create(actor: actor)
+  end
+
+  defp create_device(actor, ip) do
+    uid = "device-#{System.unique_integer([:positive])}"
+
+    Device
+    |> Ash.Changeset.for_create(:create, %{uid: uid, ip: ip, hostname: uid})
+    |> Ash.create(actor: actor)
+  end
+
+  defp register_identifier(actor, device_id, partition) do
+    DeviceIdentifier
+    |> Ash.Changeset.for_create(:upsert, %{
+      device_id: device_id,
+      identifier_type: :netbox_device_id,
+      identifier_value: "nb-#{System.unique_integer([:positive])}",
+      partition: partition,
+      confidence: :strong,
+      source: "partition_consistency_test"
+    })
+    |> Ash.create(actor: actor)
+  end
+end
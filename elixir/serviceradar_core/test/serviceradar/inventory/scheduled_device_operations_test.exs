@@ -0,0 +1,195 @@
+defmodule ServiceRadar.Inventory.ScheduledDeviceOperationsTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceIdentifier
+  alias ServiceRadar.Inventory.ScheduledDeviceOperation
+  alias ServiceRadar.Inventory.ScheduledDeviceOperations
+  alias ServiceRadar.TestSupport
+
+  require Ash.Query
+
+  @moduletag :integration
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    actor = SystemActor.system(:scheduled_device_operations_test)
+    {:ok, actor: actor}
+  end
+
+  test "persists the operation and does not run it before its time", %{actor: actor} do
+    {:ok, device} = create_device(actor)
+
+    assert {:ok, operation} =
+             schedule(actor, %{
+               operation: :add_tags,
+               device_uids: [device.uid],
+               params: %{"tags" => %{"window" => "nightly"}}
+             })
+
+    assert operation.status == :scheduled
+    assert {:ok, pending} = ScheduledDeviceOperation.list_pending(actor: actor)
+    assert Enum.any?(pending, &(&1.id == operation.id))
+
+    executed = ScheduledDeviceOperations.run_due(DateTime.utc_now())
+    refute Enum.any?(executed, &(&1.id == operation.id))
+
+    assert {:ok, [unchanged]} = read_device(actor, device.uid)
+    refute Map.has_key?(unchanged.tags || %{}, "window")
+  end
+
+  test "runs add_tags once its time has passed and records the outcome", %{actor: actor} do
+    {:ok, device} = create_device(actor)
+
+    {:ok, operation} =
+      schedule(actor, %{
+        operation: :add_tags,
+        device_uids: [device.uid, "device-missing-#{System.unique_integer([:positive])}"],
+        params: %{"tags" => %{"window" => "nightly"}}
+      })
+
+    executed = ScheduledDeviceOperations.run_due(in_future(3600))
+    assert %ScheduledDeviceOperation{} = done = Enum.find(executed, &(&1.id == operation.id))
+
+    assert done.status == :succeeded
+    assert done.started_at
+    assert done.completed_at
+    assert done.result["matched"] == 1
+    assert done.result["skipped"] == 1
+
+    assert {:ok, [tagged]} = read_device(actor, device.uid)
+    assert tagged.tags["window"] == "nightly"
+
+    refute Enum.any?(ScheduledDeviceOperations.run_due(in_future(3600)), &(&1.id == done.id))
+  end
+
+  test "decommissions devices in a CIDR, limited to the partition", %{actor: actor} do
+    octet = rem(System.unique_integer([:positive]), 250) + 1
+    {:ok, in_partition} = create_device(actor, "10.47.#{octet}.10")
+    {:ok, other_partition} = create_device(actor, "10.47.#{octet}.11")
+    {:ok, _} = register_identifier(actor, in_partition.uid, "default")
+    {:ok, _} = register_identifier(actor, other_partition.uid, "remote-site")
+
+    {:ok, operation} =
+      schedule(actor, %{
+        operation: :decommission,
+        cidr: "10.47.#{octet}.0/24",
+        partition: "default",
+        params: %{"reason" => "site closure"}
+      })
+
+    executed = ScheduledDeviceOperations.run_due(in_future(3600))
+    assert %{status: :succeeded} = Enum.find(executed, &(&1.id == operation.id))
+
+    assert {:ok, []} = read_device(actor, in_partition.uid)
+    assert {:ok, [_]} = read_device(actor, other_partition.uid)
+  end
+
+  test "cancelled operations are not executed", %{actor: actor} do
+    {:ok, device} = create_device(actor)
+
+    {:ok, operation} =
+      schedule(actor, %{
+        operation: :decommission,
+        device_uids: [device.uid],
+        params: %{"reason" => "retired"}
+      })
+
+    assert {:ok, cancelled} = ScheduledDeviceOperations.cancel(operation, actor: actor)
+    assert cancelled.status == :cancelled
+    assert cancelled.cancelled_at
+
+    refute Enum.any?(ScheduledDeviceOperations.run_due(in_future(3600)), &(&1.id == operation.id))
+    assert {:ok, [_]} = read_device(actor, device.uid)
+
+    assert {:error, %Ash.Error.Invalid{}} =
+             ScheduledDeviceOperations.cancel(cancelled, actor: actor)
+  end
+
+  test "rejects invalid schedules", %{actor: actor} do
+    assert {:error, %Ash.Error.Invalid{}} =
+             ScheduledDeviceOperations.schedule(
+               %{operation: :decommission, device_uids: ["x"], run_at: in_future(-60)},
+               actor: actor
+             )
+
+    assert {:error, %Ash.Error.Invalid{}} =
+             schedule(actor, %{operation: :decommission})
+
+    assert {:error, %Ash.Error.Invalid{}} =
+             schedule(actor, %{operation: :decommission, cidr: "not-a-cidr"})
+
+    assert {:error, %Ash.Error.Invalid{}} =
+             schedule(actor, %{operation: :add_tags, device_uids: ["x"], params: %{}})
+  end
+
+  test "requires the operation's permission when scheduling" do
+    editor = %{
+      id: Ecto.UUID.generate(),
+      email: "editor@example.com",
+      role: :operator,
+      permissions: ["devices.view", "devices.bulk_edit"]
+    }
+
+    assert {:ok, _} =
+             schedule(editor, %{
+               operation: :add_tags,
+               device_uids: ["device-x"],
+               params: %{"tags" => %{"k" => "v"}}
+             })
+
+    assert {:error, _} =
+             schedule(editor, %{
+               operation: :decommission,
+               device_uids: ["device-x"],
+               params: %{"reason" => "nope"}
+             })
+  end
+
+  defp schedule(actor, attrs) do
+    attrs
+    |> Map.put_new(:run_at, in_future(60))
+    |> ScheduledDeviceOperations.schedule(actor: actor)
+  end
+
+  defp in_future(seconds), do: DateTime.add(DateTime.utc_now(), seconds, :second)
+
+  defp create_device(actor, ip \\ nil) do
+    uid = "device-#{System.unique_integer([:positive])}"
+
+    Device
+    |> Ash.Changeset.for_create(:create, %{uid: uid, ip: ip || unique_ip(), hostname: uid})
+    |> Ash.create(actor: actor)
+  end
+
+  defp register_identifier(actor, device_id, partition) do
+    DeviceIdentifier
+    |> Ash.Changeset.for_create(:upsert, %{
+      device_id: device_id,
+      identifier_type: :netbox_device_id,
+      identifier_value: "nb-#{System.unique_integer([:positive])}",
+      partition: partition,
+      confidence: :strong,
+      source: "scheduled_device_operations_test"
+    })
+    |> Ash.create(actor: actor)
+  end
+
+  defp read_device(actor, uid) do
+    Device
+    |> Ash.Query.filter(uid == ^uid)
+    |> Ash.read(actor: actor)
+    |> Page.unwrap()
+  end
+
+  defp unique_ip do
+    seed = System.unique_integer([:positive, :monotonic])
+    "10.46.#{rem(seed, 250) + 1}.#{rem(div(seed, 250), 250) + 1}"
+  end
+end
@@ -0,0 +1,55 @@
+defmodule ServiceRadar.Inventory.SourceConfidenceTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Inventory.SourceConfidence
+
+  setup do
+    previous = Application.get_env(:serviceradar_core, SourceConfidence)
+
+    on_exit(fn ->
+      if previous,
+        do: Application.put_env(:serviceradar_core, SourceConfidence, previous),
+        else: Application.delete_env(:serviceradar_core, SourceConfidence)
+    end)
+
+    Application.delete_env(:serviceradar_core, SourceConfidence)
+    :ok
+  end
+
+  test "uses the built-in defaults without overrides" do
+    assert SourceConfidence.confidence("netbox") == 7
+    assert SourceConfidence.confidence("armis") == 7
+    assert SourceConfidence.confidence(:snmp) == 9
+    assert SourceConfidence.confidence("unknown-source") == 1
+    assert SourceConfidence.table() == SourceConfidence.defaults()
+  end
+
+  test "configured overrides replace the defaults" do
+    Application.put_env(:serviceradar_core, SourceConfidence,
+      overrides: %{"NetBox" => 9, "armis" => 4}
+    )
+
+    assert SourceConfidence.confidence("netbox") == 9
+    assert SourceConfidence.confidence("armis") == 4
+    assert SourceConfidence.confidence("sweep") == 5
+    assert SourceConfidence.max_confidence(["armis", "netbox"]) == 9
+    assert SourceConfidence.max_confidence([]) == 0
+  end
+
+  test "rejects overrides outside the 1-10 range and keeps the valid ones" do
+    assert {:error, invalid, valid} =
+             SourceConfidence.validate_overrides(%{
+               "netbox" => 9,
+               "armis" => 11,
+               "sweep" => 0,
+               "snmp" => "high",
+               " " => 5
+             })
+
+    assert valid == %{"netbox" => 9}
+    assert length(invalid) == 4
+
+    Application.put_env(:serviceradar_core, SourceConfidence, overrides: %{"armis" => 42})
+    assert SourceConfidence.confidence("armis") == 7
+  end
+end
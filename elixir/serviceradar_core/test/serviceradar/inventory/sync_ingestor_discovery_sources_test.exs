@@ -12,8 +12,10 @@ defmodule ServiceRadar.Inventory.SyncIngestorDiscoverySourcesTest do
   alias ServiceRadar.Ash.Page
   alias ServiceRadar.Camera.InventoryIngestor
   alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceFieldConflict
   alias ServiceRadar.Inventory.DeviceIdentifier
   alias ServiceRadar.Inventory.IdentityReconciler
+  alias ServiceRadar.Inventory.SourceConfidence
   alias ServiceRadar.Inventory.SyncIngestor
 
   require Ash.Query
@@ -450,6 +452,135 @@ defmodule ServiceRadar.Inventory.SyncIngestorDiscoverySourcesTest do
     end
   end
 
+  describe "source confidence overrides" do
+    setup do
+      previous = Application.get_env(:serviceradar_core, SourceConfidence)
+
+      on_exit(fn ->
+        if previous,
+          do: Application.put_env(:serviceradar_core, SourceConfidence, previous),
+          else: Application.delete_env(:serviceradar_core, SourceConfidence)
+      end)
+
+      :ok
+    end
+
+    test "an equally confident later source replaces conflicting fields by default", %{
+      actor: actor
+    } do
+      Application.delete_env(:serviceradar_core, SourceConfidence)
+
+      device = ingest_conflicting_sources(actor)
+
+      assert device.hostname == "armis-name"
+      assert device.vendor_name == "Armis Vendor"
+    end
+
+    test "a more trusted source keeps its field values when overridden", %{actor: actor} do
+      Application.put_env(:serviceradar_core, SourceConfidence, overrides: %{"netbox" => 9})
+
+      device = ingest_conflicting_sources(actor)
+
+      assert device.hostname == "netbox-name"
+      assert device.vendor_name == "NetBox Vendor"
+      # The less trusted source still fills fields NetBox left empty.
+      assert device.model == "Armis Model"
+      assert Enum.sort(device.discovery_sources) == ["armis", "netbox"]
+    end
+
+    test "overrides also decide conflicts within a single batch", %{actor: actor} do
+      Application.put_env(:serviceradar_core, SourceConfidence, overrides: %{"netbox" => 9})
+
+      ip = "10.0.12.#{unique_octet()}"
+      mac = unique_mac()
+      {netbox_update, armis_update} = conflicting_updates(ip, mac)
+
+      assert :ok = SyncIngestor.ingest_updates([netbox_update, armis_update], actor: actor)
+
+      {:ok, [device]} =
+        Device
+        |> Ash.Query.filter(ip == ^ip and is_nil(deleted_at))
+        |> Ash.read(actor: actor)
+        |> Page.unwrap()
+
+      assert device.hostname == "netbox-name"
+    end
+  end
+
+  describe "field conflicts" do
+    test "records sources that disagree about a device field", %{actor: actor} do
+      Application.delete_env(:serviceradar_core, SourceConfidence)
+
+      device = ingest_conflicting_sources(actor)
+
+      {:ok, conflicts} = DeviceFieldConflict.get_by_device(device.uid, actor: actor)
+      by_field = Map.new(conflicts, &{&1.field, &1})
+
+      assert Enum.sort(Map.keys(by_field)) == ["hostname", "vendor_name"]
+
+      hostname = by_field["hostname"]
+      assert hostname.status == :open
+      assert hostname.source_values == %{"netbox" => "netbox-name", "armis" => "armis-name"}
+      assert hostname.winning_source == "armis"
+      assert hostname.winning_value == "armis-name"
+
+      assert {:ok, resolved} =
+               DeviceFieldConflict.resolve(
+                 hostname,
+                 %{resolved_by: "test", resolution_note: "armis is right"},
+                 actor: actor
+               )
+
+      assert resolved.status == :resolved
+      assert resolved.resolved_at
+      assert {:error, _} = DeviceFieldConflict.resolve(resolved, %{}, actor: actor)
+    end
+  end
+
+  defp ingest_conflicting_sources(actor) do
+    ip = "10.0.13.#{unique_octet()}"
+    mac = unique_mac()
+    {netbox_update, armis_update} = conflicting_updates(ip, mac)
+
+    assert :ok = SyncIngestor.ingest_updates([netbox_update], actor: actor)
+    assert :ok = SyncIngestor.ingest_updates([armis_update], actor: actor)
+
+    {:ok, [device]} =
+      Device
+      |> Ash.Query.filter(ip == ^ip and is_nil(deleted_at))
+      |> Ash.read(actor: actor)
+      |> Page.unwrap()
+
+    device
+  end
+
+  defp conflicting_updates(ip, mac) do
+    netbox_update = %{
+      "ip" => ip,
+      "mac" => mac,
+      "hostname" => "netbox-name",
+      "source" => "netbox",
+      "metadata" => %{
+        "netbox_device_id" => "netbox-#{System.unique_integer([:positive])}",
+        "vendor_name" => "NetBox Vendor"
+      }
+    }
+
+    armis_update = %{
+      "ip" => ip,
+      "mac" => mac,
+      "hostname" => "armis-name",
+      "source" => "armis",
+      "metadata" => %{
+        "armis_device_id" => "armis-#{System.unique_integer([:positive])}",
+        "vendor_name" => "Armis Vendor",
+        "model" => "Armis Model"
+      }
+    }
+
+    {netbox_update, armis_update}
+  end
+
   defp unique_octet do
     rem(System.unique_integer([:positive]), 200) + 10
   end
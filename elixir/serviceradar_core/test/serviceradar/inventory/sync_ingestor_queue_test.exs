@@ -26,6 +26,14 @@ defmodule ServiceRadar.Inventory.SyncIngestorQueueTest do
 
       :ok
     end
+
+    def plan_updates(updates, _opts) do
+      if pid = Application.get_env(:serviceradar_core, :sync_ingestor_test_pid) do
+        send(pid, {:plan_started, updates})
+      end
+
+      {:ok, %{summary: %{}}}
+    end
   end
 
   setup do
@@ -85,6 +93,33 @@ defmodule ServiceRadar.Inventory.SyncIngestorQueueTest do
     assert_receive {:ingest_started, _updates}, 2_000
   end
 
+  test "plans dry-run chunks once the run completes instead of ingesting them" do
+    Application.put_env(:serviceradar_core, :sync_ingestor_coalesce_ms, 10)
+
+    chunk = fn device_id, index, final? ->
+      meta = %{
+        "dry_run" => true,
+        "chunk_index" => index,
+        "total_chunks" => 2,
+        "is_final" => final?
+      }
+
+      %{"device_id" => device_id, "sync_meta" => meta}
+    end
+
+    first = chunk.("dev-1", 0, false)
+    last = chunk.("dev-2", 1, true)
+
+    SyncIngestorQueue.enqueue(Jason.encode!([first]))
+    refute_receive {:plan_started, _updates}, 100
+
+    SyncIngestorQueue.enqueue(Jason.encode!([last]))
+
+    assert_receive {:plan_started, updates}, 500
+    assert [^first, ^last] = updates
+    refute_receive {:ingest_started, _updates}, 100
+  end
+
   defp restore_env(key, nil), do: Application.delete_env(:serviceradar_core, key)
   defp restore_env(key, value), do: Application.put_env(:serviceradar_core, key, value)
 
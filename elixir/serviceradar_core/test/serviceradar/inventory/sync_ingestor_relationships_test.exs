@@ -0,0 +1,95 @@
+defmodule ServiceRadar.Inventory.SyncIngestorRelationshipsTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceRelationships
+  alias ServiceRadar.Inventory.SyncIngestor
+
+  require Ash.Query
+
+  @moduletag :integration
+
+  setup_all do
+    ServiceRadar.TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    octet = rem(System.unique_integer([:positive]), 200) + 10
+    {:ok, actor: SystemActor.system(:sync_ingestor_relationships_test), octet: octet}
+  end
+
+  test "records a VM to host relationship from sync metadata", %{actor: actor, octet: octet} do
+    host_ip = "10.0.40.#{octet}"
+    vm_ip = "10.0.41.#{octet}"
+
+    host = %{"ip" => host_ip, "hostname" => "esx-#{octet}", "source" => "armis"}
+
+    vm = %{
+      "ip" => vm_ip,
+      "hostname" => "vm-#{octet}",
+      "source" => "armis",
+      "metadata" => %{"host_ip" => host_ip}
+    }
+
+    assert :ok = SyncIngestor.ingest_updates([host], actor: actor)
+    assert :ok = SyncIngestor.ingest_updates([vm], actor: actor)
+
+    host_uid = device_uid!(actor, host_ip)
+    vm_uid = device_uid!(actor, vm_ip)
+
+    assert {:ok, graph} = DeviceRelationships.traverse(vm_uid, direction: "outgoing")
+    assert graph.devices == Enum.sort([host_uid, vm_uid])
+
+    assert [
+             %{
+               source_device_id: ^vm_uid,
+               target_device_id: ^host_uid,
+               relation_type: "runs_on",
+               discovery_source: "armis",
+               metadata_key: "host_ip"
+             }
+           ] = graph.relationships
+
+    assert {:ok, %{devices: dependents}} =
+             DeviceRelationships.traverse(host_uid, direction: "incoming", types: ["runs_on"])
+
+    assert vm_uid in dependents
+  end
+
+  test "re-points the edge when the VM moves to another host", %{actor: actor, octet: octet} do
+    old_host_ip = "10.0.42.#{octet}"
+    new_host_ip = "10.0.43.#{octet}"
+    vm_ip = "10.0.44.#{octet}"
+
+    hosts =
+      for ip <- [old_host_ip, new_host_ip],
+          do: %{"ip" => ip, "hostname" => "esx-#{ip}", "source" => "armis"}
+
+    vm = fn host_ip ->
+      %{"ip" => vm_ip, "source" => "armis", "metadata" => %{"host_ip" => host_ip}}
+    end
+
+    assert :ok = SyncIngestor.ingest_updates(hosts, actor: actor)
+    assert :ok = SyncIngestor.ingest_updates([vm.(old_host_ip)], actor: actor)
+    assert :ok = SyncIngestor.ingest_updates([vm.(new_host_ip)], actor: actor)
+
+    vm_uid = device_uid!(actor, vm_ip)
+    new_host_uid = device_uid!(actor, new_host_ip)
+
+    assert {:ok, [%{target_device_id: ^new_host_uid}]} =
+             DeviceRelationships.list([vm_uid])
+  end
+
+  defp device_uid!(actor, ip) do
+    {:ok, [device]} =
+      Device
+      |> Ash.Query.filter(ip == ^ip)
+      |> Ash.read(actor: actor)
+      |> Page.unwrap()
+
+    device.uid
+  end
+end
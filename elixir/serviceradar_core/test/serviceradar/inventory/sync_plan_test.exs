@@ -0,0 +1,110 @@
+defmodule ServiceRadar.Inventory.SyncPlanTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Inventory.SourceConfidence
+  alias ServiceRadar.Inventory.SyncPlan
+
+  @seen_at ~U[2026-10-01 12:00:00Z]
+  @opts [confidence: SourceConfidence.defaults(), max_tombstone_percent: 50]
+
+  defp record(uid, attrs \\ %{}) do
+    Map.merge(
+      %{
+        uid: uid,
+        ip: "10.0.0.1",
+        mac: nil,
+        hostname: nil,
+        name: "10.0.0.1",
+        type: nil,
+        vendor_name: nil,
+        model: nil,
+        discovery_sources: ["armis"]
+      },
+      attrs
+    )
+  end
+
+  defp stored(uid, attrs) do
+    uid |> record(attrs) |> Map.put_new(:deleted_at, nil)
+  end
+
+  defp owned(uid), do: %{uid: uid, ip: "10.0.9.9", hostname: uid, last_seen_time: @seen_at}
+
+  test "groups creates, updates and tombstones with per-device detail" do
+    records = [
+      record("sr:new", %{ip: "10.0.0.5", hostname: "cam-5"}),
+      record("sr:renamed", %{ip: "10.0.0.6", hostname: "db-02", name: "db-02"}),
+      record("sr:same", %{ip: "10.0.0.7"}),
+      record("sr:deleted", %{ip: "10.0.0.8"})
+    ]
+
+    existing = %{
+      "sr:renamed" => stored("sr:renamed", %{ip: "10.0.0.60", hostname: "db-01", name: "db-01"}),
+      "sr:same" => stored("sr:same", %{ip: "10.0.0.7"}),
+      "sr:deleted" => stored("sr:deleted", %{ip: "10.0.0.8", deleted_at: @seen_at})
+    }
+
+    source_devices = Enum.map(["sr:renamed", "sr:same", "sr:deleted", "sr:gone"], &owned/1)
+
+    plan = SyncPlan.build(records, existing, source_devices, @opts)
+
+    assert [%{device_id: "sr:new", ip: "10.0.0.5", hostname: "cam-5", sources: ["armis"]}] =
+             plan.create
+
+    assert [deleted, renamed] = plan.update
+
+    assert renamed.device_id == "sr:renamed"
+    refute renamed.restore
+
+    assert renamed.changes == %{
+             ip: %{from: "10.0.0.60", to: "10.0.0.6"},
+             hostname: %{from: "db-01", to: "db-02"},
+             name: %{from: "db-01", to: "db-02"}
+           }
+
+    assert deleted.device_id == "sr:deleted"
+    assert deleted.restore
+    assert deleted.changes == %{}
+
+    assert [%{device_id: "sr:gone", last_seen_time: @seen_at}] = plan.tombstone
+    assert plan.withheld_tombstones == []
+
+    assert plan.summary == %{
+             create: 1,
+             update: 2,
+             tombstone: 1,
+             withheld_tombstones: 0,
+             unchanged: 1,
+             rejected: 0
+           }
+
+    assert %{percent: 25.0, max_percent: 50, tripped: false} = plan.delete_safety
+  end
+
+  test "a less confident source only fills empty fields" do
+    records = [record("sr:switch", %{hostname: "sweep-name", vendor_name: "Cisco"})]
+
+    existing = %{
+      "sr:switch" =>
+        stored("sr:switch", %{hostname: "core-sw", discovery_sources: ["snmp"], vendor_name: nil})
+    }
+
+    plan = SyncPlan.build(records, existing, [], @opts)
+
+    assert [%{changes: changes}] = plan.update
+    assert changes == %{vendor_name: %{from: nil, to: "Cisco"}}
+  end
+
+  test "withholds tombstones when the delete-safety threshold trips" do
+    records = [record("sr:kept")]
+    existing = %{"sr:kept" => stored("sr:kept", %{})}
+    source_devices = Enum.map(["sr:kept", "sr:gone-1", "sr:gone-2"], &owned/1)
+
+    plan = SyncPlan.build(records, existing, source_devices, @opts)
+
+    assert plan.tombstone == []
+    assert Enum.map(plan.withheld_tombstones, & &1.device_id) == ["sr:gone-1", "sr:gone-2"]
+    assert %{percent: 66.7, candidates: 2, active_devices: 3, tripped: true} = plan.delete_safety
+    assert plan.summary.withheld_tombstones == 2
+  end
+end
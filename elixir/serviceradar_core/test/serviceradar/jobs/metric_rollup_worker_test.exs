@@ -0,0 +1,156 @@
+defmodule ServiceRadar.Jobs.MetricRollupWorkerTest do
+  use ExUnit.Case, async: false
+
+  alias Ecto.Adapters.SQL
+  alias ServiceRadar.Jobs.MetricRollupWorker
+  alias ServiceRadar.Repo
+  alias ServiceRadar.TestSupport
+
+  describe "config/0" do
+    setup do
+      original = Application.get_env(:serviceradar_core, MetricRollupWorker)
+
+      on_exit(fn ->
+        if is_nil(original) do
+          Application.delete_env(:serviceradar_core, MetricRollupWorker)
+        else
+          Application.put_env(:serviceradar_core, MetricRollupWorker, original)
+        end
+      end)
+
+      :ok
+    end
+
+    test "uses defaults when runtime config is absent" do
+      Application.delete_env(:serviceradar_core, MetricRollupWorker)
+
+      assert MetricRollupWorker.config() == %{
+               resolutions: [:hourly, :daily],
+               retention_days: %{hourly: 395, daily: 730},
+               lookback_days: 1
+             }
+    end
+
+    test "normalizes configured resolutions and retention" do
+      Application.put_env(:serviceradar_core, MetricRollupWorker,
+        resolutions: ["daily", " Daily ", "weekly"],
+        retention_days: %{"daily" => 90, hourly: 0},
+        lookback_days: 3
+      )
+
+      assert MetricRollupWorker.config() == %{
+               resolutions: [:daily],
+               retention_days: %{hourly: 395, daily: 90},
+               lookback_days: 3
+             }
+    end
+  end
+
+  describe "rollup_window/2" do
+    test "starts at the beginning of the UTC day lookback_days ago" do
+      now = ~U[2026-03-10 14:25:00Z]
+
+      assert MetricRollupWorker.rollup_window(now, 1) == {~U[2026-03-09 00:00:00Z], now}
+      assert MetricRollupWorker.rollup_window(now, 0) == {~U[2026-03-10 00:00:00Z], now}
+    end
+  end
+
+  describe "rollup_sql/1" do
+    test "aggregates raw rows into whole UTC days and upserts" do
+      sql = MetricRollupWorker.rollup_sql("timeseries_metrics")
+
+      assert sql =~ "INSERT INTO platform.timeseries_metrics_daily"
+      assert sql =~ "date_trunc('day', timestamp, 'UTC') AS bucket"
+      assert sql =~ "AVG(value)::float8 AS avg_value"
+      assert sql =~ "MIN(value)::float8 AS min_value"
+      assert sql =~ "MAX(value)::float8 AS max_value"
+      assert sql =~ "COUNT(*)::bigint AS sample_count"
+      assert sql =~ "FROM platform.timeseries_metrics\n"
+      assert sql =~ "GROUP BY 1, 2, 3, 4"
+      assert sql =~ "ON CONFLICT (bucket, device_id, metric_type, metric_name) DO UPDATE"
+    end
+
+    test "covers every metric family" do
+      assert MetricRollupWorker.families() == [
+               "cpu_metrics",
+               "disk_metrics",
+               "memory_metrics",
+               "process_metrics",
+               "timeseries_metrics"
+             ]
+
+      for family <- MetricRollupWorker.families() do
+        assert MetricRollupWorker.rollup_sql(family) =~ "INSERT INTO platform.#{family}_daily"
+      end
+    end
+  end
+
+  describe "rollup/3" do
+    @describetag :integration
+
+    setup do
+      TestSupport.start_core!()
+    end
+
+    test "computes min, max, avg and count per device and day" do
+      device_id = "rollup-#{System.unique_integer([:positive])}"
+
+      samples = [
+        {~U[2020-01-01 00:10:00Z], 10.0},
+        {~U[2020-01-01 06:00:00Z], 20.0},
+        {~U[2020-01-01 23:59:00Z], 60.0},
+        {~U[2020-01-02 01:00:00Z], 5.0}
+      ]
+
+      for {timestamp, value} <- samples do
+        SQL.query!(
+          Repo,
+          """
+          INSERT INTO platform.timeseries_metrics
+            (timestamp, gateway_id, metric_name, metric_type, device_id, value)
+          VALUES ($1, $2, 'ifInOctets', 'snmp', $3, $4)
+          """,
+          [timestamp, "gw-#{System.unique_integer([:positive])}", device_id, value]
+        )
+      end
+
+      assert {:ok, 2} =
+               MetricRollupWorker.rollup(
+                 "timeseries_metrics",
+                 ~U[2020-01-01 00:00:00Z],
+                 ~U[2020-01-03 00:00:00Z]
+               )
+
+      assert [first_day, second_day] = read_daily(device_id)
+
+      assert first_day == [~U[2020-01-01 00:00:00.000000Z], 30.0, 10.0, 60.0, 3]
+      assert second_day == [~U[2020-01-02 00:00:00.000000Z], 5.0, 5.0, 5.0, 1]
+
+      # Re-running over the same window replaces rather than duplicates rows.
+      assert {:ok, 2} =
+               MetricRollupWorker.rollup(
+                 "timeseries_metrics",
+                 ~U[2020-01-01 00:00:00Z],
+                 ~U[2020-01-03 00:00:00Z]
+               )
+
+      assert length(read_daily(device_id)) == 2
+    end
+  end
+
+  defp read_daily(device_id) do
+    %{rows: rows} =
+      SQL.query!(
+        Repo,
+        """
+        SELECT bucket, avg_value, min_value, max_value, sample_count
+        FROM platform.timeseries_metrics_daily
+        WHERE device_id = $1
+        ORDER BY bucket
+        """,
+        [device_id]
+      )
+
+    rows
+  end
+end
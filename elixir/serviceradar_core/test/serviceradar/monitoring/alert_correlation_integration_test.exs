@@ -0,0 +1,117 @@
+defmodule ServiceRadar.Monitoring.AlertCorrelationIntegrationTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Ash.Page
+  alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.SyncIngestor
+  alias ServiceRadar.Monitoring.Alert
+  alias ServiceRadar.Monitoring.AlertCorrelation
+  alias ServiceRadar.Monitoring.AlertGenerator
+  alias ServiceRadar.TestSupport
+
+  require Ash.Query
+
+  @moduletag :integration
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    previous = Application.get_env(:serviceradar_core, AlertCorrelation)
+
+    Application.put_env(:serviceradar_core, AlertCorrelation,
+      enabled: true,
+      rules: [%{name: "hosts", source_types: [:device], relationship_types: ["runs_on"]}]
+    )
+
+    on_exit(fn ->
+      if previous,
+        do: Application.put_env(:serviceradar_core, AlertCorrelation, previous),
+        else: Application.delete_env(:serviceradar_core, AlertCorrelation)
+    end)
+
+    actor = SystemActor.system(:alert_correlation_test)
+    octet = rem(System.unique_integer([:positive]), 200) + 10
+    host_ip = "10.0.50.#{octet}"
+
+    vms =
+      for subnet <- [51, 52] do
+        %{
+          "ip" => "10.0.#{subnet}.#{octet}",
+          "source" => "armis",
+          "metadata" => %{"host_ip" => host_ip}
+        }
+      end
+
+    host = %{"ip" => host_ip, "hostname" => "esx-#{octet}", "source" => "armis"}
+
+    assert :ok = SyncIngestor.ingest_updates([host], actor: actor)
+    assert :ok = SyncIngestor.ingest_updates(vms, actor: actor)
+
+    {:ok,
+     actor: actor,
+     host: device_uid!(actor, host_ip),
+     vms: Enum.map(vms, &device_uid!(actor, &1["ip"]))}
+  end
+
+  test "a host failure correlates the alerts of its VMs into one incident", ctx do
+    assert {:ok, host_alert} = offline(ctx, ctx.host)
+    vm_alerts = for vm <- ctx.vms, do: elem(offline(ctx, vm), 1)
+
+    [incident] = incidents(ctx)
+    incident_id = incident.metadata["incident_id"]
+
+    assert incident.metadata["root_cause_device_id"] == ctx.host
+    assert incident.metadata["alert_count"] == 3
+
+    for alert <- vm_alerts do
+      assert alert.metadata["incident_id"] == incident_id
+      assert alert.metadata["incident_role"] == "downstream"
+      assert alert.metadata["root_cause_device_id"] == ctx.host
+    end
+
+    host_alert = Ash.get!(Alert, host_alert.id, actor: ctx.actor)
+    assert host_alert.metadata["incident_id"] == incident_id
+    assert host_alert.metadata["incident_role"] == "root_cause"
+  end
+
+  test "a host alert after its VMs' adopts them into an incident", ctx do
+    [first_vm | _] = ctx.vms
+    assert {:ok, vm_alert} = offline(ctx, first_vm)
+    refute Map.has_key?(vm_alert.metadata, "incident_id")
+
+    assert {:ok, host_alert} = offline(ctx, ctx.host)
+    assert host_alert.metadata["incident_role"] == "root_cause"
+
+    [incident] = incidents(ctx)
+    assert incident.metadata["alert_count"] == 2
+
+    vm_alert = Ash.get!(Alert, vm_alert.id, actor: ctx.actor)
+    assert vm_alert.metadata["incident_id"] == incident.metadata["incident_id"]
+    assert vm_alert.metadata["incident_role"] == "downstream"
+  end
+
+  defp offline(ctx, device_uid) do
+    AlertGenerator.device_offline(device_uid: device_uid, actor: ctx.actor)
+  end
+
+  defp incidents(ctx) do
+    Alert
+    |> Ash.Query.for_read(:active, %{}, actor: ctx.actor)
+    |> Ash.Query.filter(device_uid == ^ctx.host and source_type == :system)
+    |> Ash.read!()
+  end
+
+  defp device_uid!(actor, ip) do
+    {:ok, [device]} =
+      Device
+      |> Ash.Query.filter(ip == ^ip)
+      |> Ash.read(actor: actor)
+      |> Page.unwrap()
+
+    device.uid
+  end
+end
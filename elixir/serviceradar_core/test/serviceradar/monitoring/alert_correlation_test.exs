@@ -0,0 +1,44 @@
+defmodule ServiceRadar.Monitoring.AlertCorrelationTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Monitoring.AlertCorrelation
+
+  test "validates rules" do
+    assert {:ok, [rule]} =
+             AlertCorrelation.validate_rules([
+               %{"source_types" => ["device"], "relationship_types" => ["runs_on"]}
+             ])
+
+    assert rule.name == "rule-0"
+    assert rule.source_types == [:device]
+    assert rule.max_depth == 2
+
+    assert {:error, "rule hosts: invalid relationship type" <> _} =
+             AlertCorrelation.validate_rules([%{name: "hosts", relationship_types: ["uplink"]}])
+
+    assert {:error, "rule rule-0: max_depth must be" <> _} =
+             AlertCorrelation.validate_rules([%{max_depth: 9}])
+  end
+
+  test "the first rule matching the source type and severity applies" do
+    {:ok, rules} =
+      AlertCorrelation.validate_rules([
+        %{name: "critical-devices", source_types: [:device], severities: [:critical]},
+        %{name: "checks", source_types: [:service_check]}
+      ])
+
+    assert %{name: "critical-devices"} =
+             AlertCorrelation.match_rule(rules, %{source_type: :device, severity: :critical})
+
+    assert %{name: "checks"} =
+             AlertCorrelation.match_rule(rules, %{source_type: :service_check, severity: :info})
+
+    assert nil == AlertCorrelation.match_rule(rules, %{source_type: :device, severity: :info})
+  end
+
+  test "alerts without a device or matching rule are notified unchanged" do
+    attrs = %{source_type: :gateway, severity: :critical, device_uid: nil}
+    assert {:notify, ^attrs} = AlertCorrelation.correlate(attrs, rules: [])
+    assert {:notify, ^attrs} = AlertCorrelation.correlate(attrs)
+  end
+end
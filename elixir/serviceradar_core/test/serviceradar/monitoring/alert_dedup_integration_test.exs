@@ -0,0 +1,93 @@
+defmodule ServiceRadar.Monitoring.AlertDedupIntegrationTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Monitoring.Alert
+  alias ServiceRadar.Monitoring.AlertDedup
+  alias ServiceRadar.Monitoring.AlertGenerator
+  alias ServiceRadar.TestSupport
+
+  @moduletag :integration
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    previous = Application.get_env(:serviceradar_core, AlertDedup)
+
+    Application.put_env(:serviceradar_core, AlertDedup,
+      enabled: true,
+      key_template: "{{device_uid}}|{{check_type}}",
+      window: to_timeout(minute: 5)
+    )
+
+    on_exit(fn ->
+      if previous,
+        do: Application.put_env(:serviceradar_core, AlertDedup, previous),
+        else: Application.delete_env(:serviceradar_core, AlertDedup)
+    end)
+
+    actor = SystemActor.system(:alert_dedup_test)
+    {:ok, actor: actor, device: "sr:dedup-#{System.unique_integer([:positive])}"}
+  end
+
+  test "alerts sharing a key within the window fold into one active alert", ctx do
+    assert {:ok, first} = service_down(ctx, "check-1", "http")
+    assert {:ok, second} = service_down(ctx, "check-2", "http")
+    assert {:ok, third} = service_down(ctx, "check-3", "http")
+
+    assert second.id == first.id
+    assert third.id == first.id
+    assert third.metadata["dedup_count"] == 3
+    assert third.metadata["dedup_key"] == "#{ctx.device}|http"
+    assert [_] = active(ctx)
+  end
+
+  test "alerts with different keys stay independent", ctx do
+    assert {:ok, http} = service_down(ctx, "check-1", "http")
+    assert {:ok, icmp} = service_down(ctx, "check-2", "icmp")
+
+    refute http.id == icmp.id
+    assert length(active(ctx)) == 2
+  end
+
+  test "a recovery clears the active alert so the next failure notifies", ctx do
+    assert {:ok, first} = service_down(ctx, "check-1", "http")
+    assert {:ok, _} = service_down(ctx, "check-1", "http")
+
+    assert {:ok, recovered} =
+             AlertGenerator.service_recovered(
+               service_check_id: "check-1",
+               device_uid: ctx.device,
+               details: %{"check_type" => "http"},
+               actor: ctx.actor
+             )
+
+    assert recovered.metadata["dedup_count"] == 2
+    refute Map.has_key?(recovered.metadata, "dedup_key")
+    assert Ash.get!(Alert, first.id, actor: ctx.actor).status == :resolved
+
+    assert {:ok, next} = service_down(ctx, "check-1", "http")
+    refute next.id == first.id
+    assert next.metadata["dedup_count"] == 1
+  end
+
+  defp service_down(ctx, check_id, check_type) do
+    AlertGenerator.service_down(
+      service_check_id: check_id,
+      service_name: "web",
+      device_uid: ctx.device,
+      details: %{"check_type" => check_type},
+      actor: ctx.actor
+    )
+  end
+
+  defp active(ctx) do
+    Alert
+    |> Ash.Query.for_read(:active, %{}, actor: ctx.actor)
+    |> Ash.read!()
+    |> Enum.filter(&(&1.device_uid == ctx.device))
+  end
+end
@@ -0,0 +1,42 @@
+defmodule ServiceRadar.Monitoring.AlertDedupTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Monitoring.AlertDedup
+
+  @attrs %{
+    source_type: :service_check,
+    source_id: "check-1",
+    device_uid: "sr:web-1",
+    metric_name: nil,
+    metadata: %{"check_type" => "http"}
+  }
+
+  test "renders keys from alert attributes and metadata" do
+    assert AlertDedup.key(@attrs, "{{source_type}}|{{source_id}}|{{metric_name}}") ==
+             "service_check|check-1|"
+
+    assert AlertDedup.key(@attrs, "{{ device_uid }}/{{check_type}}") == "sr:web-1/http"
+    assert AlertDedup.key(%{@attrs | device_uid: "sr:web-2"}, "{{device_uid}}") == "sr:web-2"
+  end
+
+  test "rejects templates without placeholders or with broken ones" do
+    assert {:ok, _} = AlertDedup.validate_template("{{device_uid}}|{{check_type}}")
+    assert {:error, _} = AlertDedup.validate_template("static")
+    assert {:error, _} = AlertDedup.validate_template("{{device_uid}}|{{check type}}")
+    assert {:error, _} = AlertDedup.validate_template(nil)
+  end
+
+  test "an active alert counts within the window of its last sighting" do
+    now = ~U[2026-10-01 12:00:00Z]
+    metadata = %{"dedup_last_seen" => "2026-10-01T11:57:00Z"}
+    window = to_timeout(minute: 5)
+
+    assert AlertDedup.within_window?(metadata, now, window)
+    refute AlertDedup.within_window?(metadata, DateTime.add(now, 3, :minute), window)
+    refute AlertDedup.within_window?(%{}, now, window)
+  end
+
+  test "passes alerts through when disabled" do
+    assert AlertDedup.check(@attrs) == {:ok, @attrs}
+  end
+end
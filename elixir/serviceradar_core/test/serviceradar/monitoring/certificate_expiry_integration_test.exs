@@ -0,0 +1,87 @@
+defmodule ServiceRadar.Monitoring.CertificateExpiryIntegrationTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Monitoring.Alert
+  alias ServiceRadar.Monitoring.CertificateExpiry
+  alias ServiceRadar.TestSupport
+
+  require Ash.Query
+
+  @moduletag :integration
+
+  @thresholds %{warning: 30, critical: 7}
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    actor = SystemActor.system(:certificate_expiry_test)
+    target = "portal-#{System.unique_integer([:positive])}.example.com:443"
+
+    {:ok, actor: actor, target: target}
+  end
+
+  test "records an endpoint's expiry and alerts within the window", ctx do
+    %{actor: actor, target: target} = ctx
+    now = DateTime.utc_now()
+
+    assert {:ok, certificate} =
+             CertificateExpiry.record_der(:endpoint, target, certificate(20), actor: actor)
+
+    assert certificate.source == :endpoint
+    assert certificate.subject == "portal.example.com"
+    assert DateTime.to_date(certificate.not_after) == Date.add(Date.utc_today(), 20)
+
+    # Twenty days out, inside the 30 day window: fires once.
+    assert {:ok, %{fired: fired}} = evaluate(actor, now)
+    assert fired >= 1
+    assert {:ok, %{fired: 0}} = evaluate(actor, now)
+
+    assert [alert] = open_alerts(target, actor)
+    assert alert.severity == :warning
+    assert alert.title =~ "Certificate Expiring"
+    assert alert.metadata["certificate_target"] == target
+
+    # Renewed: resolved.
+    assert {:ok, _certificate} =
+             CertificateExpiry.record_der(:endpoint, target, certificate(365), actor: actor)
+
+    assert {:ok, %{resolved: resolved}} = evaluate(actor, now)
+    assert resolved >= 1
+    assert [] = open_alerts(target, actor)
+    assert {:ok, %{status: :resolved}} = Ash.get(Alert, alert.id, actor: actor)
+  end
+
+  test "certificates outside the window do not alert", %{actor: actor, target: target} do
+    assert {:ok, _certificate} =
+             CertificateExpiry.record_der(:endpoint, target, certificate(90), actor: actor)
+
+    assert {:ok, _result} = evaluate(actor, DateTime.utc_now())
+    assert [] = open_alerts(target, actor)
+  end
+
+  defp evaluate(actor, now) do
+    CertificateExpiry.evaluate(now: now, thresholds: @thresholds, actor: actor)
+  end
+
+  defp open_alerts(target, actor) do
+    Alert
+    |> Ash.Query.for_read(:active)
+    |> Ash.Query.filter(source_id == ^"certificate_expiry:endpoint:#{target}")
+    |> Ash.read!(actor: actor)
+  end
+
+  defp certificate(days) do
+    today = Date.utc_today()
+
+    %{cert: der} =
+      :public_key.pkix_test_root_cert(~c"portal.example.com",
+        validity: {Date.to_erl(Date.add(today, -1)), Date.to_erl(Date.add(today, days))}
+      )
+
+    der
+  end
+end
@@ -0,0 +1,74 @@
+defmodule ServiceRadar.Monitoring.CertificateExpiryTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Monitoring.CertificateExpiry
+
+  @now ~U[2026-10-16 12:00:00Z]
+  @thresholds %{warning: 30, critical: 7}
+
+  describe "status/3" do
+    test "classifies by the time left before not_after" do
+      assert CertificateExpiry.status(days_from_now(90), @now, @thresholds) == :ok
+      assert CertificateExpiry.status(days_from_now(30), @now, @thresholds) == :warning
+      assert CertificateExpiry.status(days_from_now(12), @now, @thresholds) == :warning
+      assert CertificateExpiry.status(days_from_now(7), @now, @thresholds) == :critical
+      assert CertificateExpiry.status(@now, @now, @thresholds) == :expired
+      assert CertificateExpiry.status(days_from_now(-3), @now, @thresholds) == :expired
+    end
+  end
+
+  describe "days_remaining/2" do
+    test "counts whole days, negative once expired" do
+      assert CertificateExpiry.days_remaining(days_from_now(12), @now) == 12
+      assert CertificateExpiry.days_remaining(DateTime.add(@now, 3_600, :second), @now) == 0
+      assert CertificateExpiry.days_remaining(DateTime.add(@now, -3_600, :second), @now) == -1
+    end
+  end
+
+  describe "normalize_endpoint/1" do
+    test "defaults the port to 443" do
+      assert CertificateExpiry.normalize_endpoint("portal.example.com") ==
+               {"portal.example.com:443", "portal.example.com", 443}
+
+      assert CertificateExpiry.normalize_endpoint(" vault.internal:8200 ") ==
+               {"vault.internal:8200", "vault.internal", 8200}
+    end
+
+    test "accepts URLs and IPv6 literals" do
+      assert CertificateExpiry.normalize_endpoint("https://portal.example.com/login") ==
+               {"portal.example.com:443", "portal.example.com", 443}
+
+      assert CertificateExpiry.normalize_endpoint("[2001:db8::1]:8443") ==
+               {"[2001:db8::1]:8443", "2001:db8::1", 8443}
+    end
+
+    test "rejects endpoints without a host" do
+      assert CertificateExpiry.normalize_endpoint("") == nil
+      assert CertificateExpiry.normalize_endpoint(":443") == nil
+    end
+  end
+
+  describe "describe/1" do
+    test "reads the names, serial number and validity" do
+      today = Date.utc_today()
+      expires = Date.add(today, 20)
+
+      %{cert: der} =
+        :public_key.pkix_test_root_cert(~c"portal.example.com",
+          validity: {Date.to_erl(Date.add(today, -1)), Date.to_erl(expires)}
+        )
+
+      assert {:ok, details} = CertificateExpiry.describe(der)
+      assert details.subject == "portal.example.com"
+      assert details.issuer == "portal.example.com"
+      assert is_binary(details.serial_number)
+      assert DateTime.to_date(details.not_after) == expires
+    end
+
+    test "rejects data that is not a certificate" do
+      assert {:error, _reason} = CertificateExpiry.describe("not a certificate")
+    end
+  end
+
+  defp days_from_now(days), do: DateTime.add(@now, days * 86_400, :second)
+end
@@ -0,0 +1,150 @@
+defmodule ServiceRadar.Monitoring.EventReplayTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Monitoring.EventReplay
+  alias ServiceRadar.Monitoring.OcsfEvent
+  alias ServiceRadar.Monitoring.WebhookNotifier
+  alias ServiceRadar.TestSupport
+
+  @moduletag :integration
+
+  defmodule CaptureClient do
+    @moduledoc false
+
+    def post(url, opts) do
+      send(:event_replay_test, {:webhook, url, Keyword.fetch!(opts, :json)})
+      {:ok, %{status: 200, body: ""}}
+    end
+  end
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    Process.register(self(), :event_replay_test)
+
+    notifier =
+      start_supervised!(
+        {WebhookNotifier,
+         name: nil,
+         http_client: CaptureClient,
+         webhooks: [
+           %{name: "capture", url: "http://capture.test/hook", cooldown: 60_000},
+           %{name: "other", url: "http://other.test/hook", cooldown: 60_000}
+         ]}
+      )
+
+    actor = SystemActor.system(:event_replay_test)
+    log_name = "replay.test.#{System.unique_integer([:positive])}"
+    from = DateTime.add(DateTime.utc_now(), -7200, :second)
+
+    {:ok, actor: actor, notifier: notifier, log_name: log_name, from: from}
+  end
+
+  test "selects events by time range, type and partition", ctx do
+    inside = record_event(ctx, 60, %{"partition" => "default"})
+    _other_partition = record_event(ctx, 120, %{"partition" => "remote-site"})
+    _other_type = record_event(%{ctx | log_name: ctx.log_name <> ".other"}, 180, %{})
+    _too_late = record_event(ctx, 3600, %{"partition" => "default"})
+
+    filters = %{
+      from: ctx.from,
+      to: DateTime.add(ctx.from, 1800, :second),
+      log_names: [ctx.log_name],
+      partition: "default"
+    }
+
+    assert {:ok, [event]} = EventReplay.select(filters, actor: ctx.actor)
+    assert event.id == inside.id
+
+    assert {:ok, events} =
+             EventReplay.select(%{filters | partition: nil}, actor: ctx.actor)
+
+    assert length(events) == 2
+  end
+
+  test "delivers events marked as replays through the chosen channel", ctx do
+    event = record_event(ctx, 60, %{})
+
+    filters = %{
+      from: ctx.from,
+      to: DateTime.add(ctx.from, 600, :second),
+      log_names: [ctx.log_name]
+    }
+
+    opts = [actor: ctx.actor, notifier: ctx.notifier, channel: "capture", rate: 50]
+
+    assert {:ok, %{selected: 1, sent: 1, failed: 0}} = EventReplay.replay(filters, opts)
+    assert_receive {:webhook, "http://capture.test/hook", payload}
+    refute_receive {:webhook, "http://other.test/hook", _}
+
+    assert "[REPLAY] " <> _ = payload.title
+    assert payload.replay["replay"] == true
+    assert payload.replay["original_event_id"] == event.id
+    assert payload.replay["original_time"] == DateTime.to_iso8601(event.time)
+    assert payload.replay["replayed_at"]
+    assert payload.details["event_id"] == event.id
+
+    # Replays bypass the notifier cooldown, so a second replay is delivered too.
+    assert {:ok, %{sent: 1}} = EventReplay.replay(filters, opts)
+    assert_receive {:webhook, "http://capture.test/hook", _}
+  end
+
+  test "rejects unknown channels and invalid time ranges", ctx do
+    record_event(ctx, 60, %{})
+    filters = %{from: ctx.from, to: DateTime.add(ctx.from, 600, :second)}
+
+    assert {:ok, %{selected: selected, failed: selected}} =
+             EventReplay.replay(Map.put(filters, :log_names, [ctx.log_name]),
+               actor: ctx.actor,
+               notifier: ctx.notifier,
+               channel: "missing",
+               rate: 50
+             )
+
+    assert selected == 1
+    refute_receive {:webhook, _, _}
+
+    assert {:error, _} =
+             EventReplay.select(%{filters | to: ctx.from, from: filters.to}, actor: ctx.actor)
+
+    assert {:error, _} = EventReplay.select(%{to: ctx.from}, actor: ctx.actor)
+  end
+
+  test "requires the replay permission", ctx do
+    viewer = %{
+      id: Ecto.UUID.generate(),
+      email: "viewer@example.com",
+      role: :viewer,
+      permissions: ["observability.events.view"]
+    }
+
+    filters = %{from: ctx.from, to: DateTime.utc_now()}
+
+    assert {:error, :forbidden} =
+             EventReplay.replay(filters, actor: viewer, notifier: ctx.notifier)
+
+    refute_receive {:webhook, _, _}
+  end
+
+  defp record_event(ctx, offset_seconds, metadata) do
+    OcsfEvent
+    |> Ash.Changeset.for_create(:record, %{
+      time: DateTime.add(ctx.from, offset_seconds, :second),
+      class_uid: 1008,
+      category_uid: 1,
+      type_uid: 100_801,
+      activity_id: 1,
+      activity_name: "Threshold Breached",
+      severity_id: 4,
+      severity: "High",
+      message: "cpu above 90%",
+      log_name: ctx.log_name,
+      metadata: metadata
+    })
+    |> Ash.create!(actor: ctx.actor)
+  end
+end
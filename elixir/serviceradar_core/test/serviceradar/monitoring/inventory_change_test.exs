@@ -0,0 +1,87 @@
+defmodule ServiceRadar.Monitoring.InventoryChangeTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Monitoring.InventoryChange
+
+  @thresholds %{change_percent: 20, min_count: 10, overrides: %{}}
+
+  describe "check/3" do
+    test "alerts when a source drops by more than the threshold" do
+      previous = %{{:source, "armis"} => 1_200, {:partition, "default"} => 1_500}
+      current = %{{:source, "armis"} => 300, {:partition, "default"} => 1_450}
+
+      assert [change] = check(previous, current)
+
+      assert change == %{
+               dimension: :source,
+               key: "armis",
+               previous_count: 1_200,
+               current_count: 300,
+               change_percent: -75.0
+             }
+
+      assert_received {:fired, ^change}
+    end
+
+    test "stays silent for normal fluctuation" do
+      previous = %{{:source, "netbox"} => 400, {:partition, "default"} => 1_000}
+      current = %{{:source, "netbox"} => 430, {:partition, "default"} => 850}
+
+      assert check(previous, current) == []
+      refute_received {:fired, _}
+    end
+
+    test "does not report changes that failed to alert" do
+      previous = %{{:source, "armis"} => 100}
+
+      assert InventoryChange.check(previous, %{},
+               thresholds: @thresholds,
+               fire: fn _change, _opts -> {:error, :unavailable} end
+             ) == []
+    end
+  end
+
+  describe "changes/3" do
+    test "counts a source with no devices left as 0" do
+      assert [%{key: "sweep", current_count: 0, change_percent: -100.0}] =
+               InventoryChange.changes(%{{:source, "sweep"} => 50}, %{}, @thresholds)
+    end
+
+    test "alerts on growth as well as drops" do
+      assert [%{key: "lab", change_percent: 150.0}] =
+               InventoryChange.changes(
+                 %{{:partition, "lab"} => 40},
+                 %{{:partition, "lab"} => 100},
+                 @thresholds
+               )
+    end
+
+    test "skips small baselines and new keys" do
+      previous = %{{:source, "mapper"} => 5}
+      current = %{{:source, "mapper"} => 0, {:source, "armis"} => 900}
+
+      assert InventoryChange.changes(previous, current, @thresholds) == []
+    end
+
+    test "applies per-source overrides" do
+      previous = %{{:source, "armis"} => 1_000, {:source, "netbox"} => 1_000}
+      current = %{{:source, "armis"} => 900, {:source, "netbox"} => 900}
+      thresholds = %{@thresholds | overrides: %{"source:armis" => 5}}
+
+      assert [%{key: "armis", change_percent: -10.0}] =
+               InventoryChange.changes(previous, current, thresholds)
+    end
+  end
+
+  defp check(previous, current) do
+    test_pid = self()
+
+    InventoryChange.check(previous, current,
+      thresholds: @thresholds,
+      fire: fn change, _opts ->
+        send(test_pid, {:fired, change})
+        {:ok, %{id: "alert"}}
+      end
+    )
+  end
+end
@@ -0,0 +1,87 @@
+defmodule ServiceRadar.Monitoring.MaintenanceWindowsTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Monitoring.MaintenanceWindows
+
+  @now ~U[2026-10-20 02:00:00Z]
+
+  # Two VMs run on host-1 and one on host-2; both hosts are members of stack-1.
+  @edges [
+    {"vm-1", "host-1", "runs_on"},
+    {"vm-2", "host-1", "runs_on"},
+    {"vm-3", "host-2", "runs_on"},
+    {"host-1", "stack-1", "member_of"},
+    {"host-2", "stack-1", "member_of"}
+  ]
+
+  defp load(ids) do
+    {:ok,
+     for {source, target, type} <- @edges, source in ids or target in ids do
+       %{source_device_id: source, target_device_id: target, relation_type: type}
+     end}
+  end
+
+  defp window!(device_uid) do
+    {:ok, window} =
+      MaintenanceWindows.validate_window(%{
+        device_uid: device_uid,
+        start: "2026-10-20T01:00:00Z",
+        end: "2026-10-20T03:00:00Z"
+      })
+
+    window
+  end
+
+  defp opts(windows, extra \\ []) do
+    [windows: windows, now: @now, load: &load/1] ++ extra
+  end
+
+  test "a parent window suppresses its children but not a sibling's" do
+    windows = [window!("host-1")]
+
+    assert {:suppressed, attrs} =
+             MaintenanceWindows.suppress(%{device_uid: "vm-1", metadata: %{}}, opts(windows))
+
+    assert attrs.metadata["suppressed"] == true
+    assert attrs.metadata["maintenance_device_id"] == "host-1"
+    assert attrs.metadata["maintenance_window"] == "host-1@2026-10-20T01:00:00Z"
+
+    assert {:suppressed, _} = MaintenanceWindows.suppress(%{device_uid: "host-1"}, opts(windows))
+    assert {:ok, _} = MaintenanceWindows.suppress(%{device_uid: "vm-3"}, opts(windows))
+    assert {:ok, _} = MaintenanceWindows.suppress(%{device_uid: "host-2"}, opts(windows))
+  end
+
+  test "the traversal depth bounds the covered subtree" do
+    windows = [window!("stack-1")]
+
+    assert MaintenanceWindows.covering("host-2", opts(windows, max_depth: 1))
+    refute MaintenanceWindows.covering("vm-3", opts(windows, max_depth: 1))
+    assert MaintenanceWindows.covering("vm-3", opts(windows, max_depth: 2))
+    refute MaintenanceWindows.covering("vm-3", opts(windows, relationship_types: ["runs_on"]))
+  end
+
+  test "windows only apply between start and end" do
+    windows = [window!("host-1")]
+    later = DateTime.add(@now, 1, :hour)
+
+    refute MaintenanceWindows.covering("vm-1", opts(windows, now: later))
+  end
+
+  test "schedules and cancels windows" do
+    assert {:error, "maintenance window" <> _} =
+             MaintenanceWindows.schedule(%{device_uid: "host-1", start: @now, end: @now})
+
+    assert {:ok, window} =
+             MaintenanceWindows.schedule(%{
+               name: "patching",
+               device_uid: "host-1",
+               start: DateTime.add(@now, -60, :second),
+               end: DateTime.add(DateTime.utc_now(), 3600, :second)
+             })
+
+    assert window in MaintenanceWindows.active(DateTime.utc_now())
+    assert MaintenanceWindows.cancel("patching")
+    refute MaintenanceWindows.cancel("patching")
+    refute window in MaintenanceWindows.active(DateTime.utc_now())
+  end
+end
@@ -0,0 +1,117 @@
+defmodule ServiceRadar.Monitoring.QuietHoursTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Monitoring.QuietHours
+  alias ServiceRadar.Monitoring.WebhookNotifier
+  alias ServiceRadar.Monitoring.WebhookNotifier.Alert
+
+  defmodule CaptureClient do
+    @moduledoc false
+
+    def post(url, opts) do
+      send(:quiet_hours_test, {:webhook, url, opts})
+      {:ok, %{status: 200, body: ""}}
+    end
+  end
+
+  @overnight %{name: "overnight", start: "22:00", end: "07:00", timezone: "+02:00"}
+
+  defp alert(level, attrs \\ []) do
+    struct!(
+      %Alert{
+        level: level,
+        title: "Service Down: web",
+        message: "web is not responding",
+        gateway_id: "gateway-1",
+        details: %{"partition" => "lab"}
+      },
+      attrs
+    )
+  end
+
+  defp windows!(configs) do
+    {:ok, windows} = QuietHours.parse_windows(configs)
+    windows
+  end
+
+  describe "check/3" do
+    test "holds non-critical alerts inside a window in its timezone" do
+      windows = windows!([@overnight])
+
+      # 01:30 at +02:00, in the occurrence that started at 22:00 the day before.
+      assert {:hold, {"overnight", start}, ends_at} =
+               QuietHours.check(windows, alert(:warning), ~U[2026-10-01 23:30:00Z])
+
+      assert start == ~U[2026-10-01 20:00:00Z]
+      assert ends_at == ~U[2026-10-02 05:00:00Z]
+
+      assert :send = QuietHours.check(windows, alert(:warning), ~U[2026-10-01 12:00:00Z])
+    end
+
+    test "always sends critical alerts" do
+      windows = windows!([@overnight])
+      assert :send = QuietHours.check(windows, alert(:error), ~U[2026-10-01 23:30:00Z])
+    end
+
+    test "narrows windows by partition, level and start day" do
+      windows =
+        windows!([
+          Map.merge(@overnight, %{partitions: ["prod"], levels: ["info"], days: ["thu"]})
+        ])
+
+      now = ~U[2026-10-01 23:30:00Z]
+      in_prod = %{"partition" => "prod"}
+
+      assert {:hold, _, _} = QuietHours.check(windows, alert(:info, details: in_prod), now)
+      assert :send = QuietHours.check(windows, alert(:info), now)
+      assert :send = QuietHours.check(windows, alert(:warning, details: in_prod), now)
+
+      # 2026-10-02 is a Friday.
+      friday = ~U[2026-10-02 23:30:00Z]
+      assert :send = QuietHours.check(windows, alert(:info, details: in_prod), friday)
+    end
+
+    test "rejects invalid windows" do
+      assert {:error, _} = QuietHours.parse_windows([%{start: "25:00", end: "07:00"}])
+      assert {:error, _} = QuietHours.parse_windows([%{@overnight | timezone: "+2"}])
+      assert {:error, _} = QuietHours.parse_windows([Map.put(@overnight, :levels, [:error])])
+      assert {:error, _} = QuietHours.parse_windows([Map.put(@overnight, :days, ["someday"])])
+    end
+  end
+
+  describe "WebhookNotifier" do
+    setup do
+      Process.register(self(), :quiet_hours_test)
+      :ok
+    end
+
+    test "holds alerts, pages critical ones and sends the digest at window end" do
+      notifier =
+        start_supervised!(
+          {WebhookNotifier,
+           name: nil,
+           http_client: CaptureClient,
+           webhooks: [%{name: "hook", url: "http://hook.test/", cooldown: 0}],
+           quiet_hours: [@overnight],
+           # 50ms before the window ends at 07:00 +02:00.
+           clock: fn -> ~U[2026-10-02 04:59:59.950Z] end}
+        )
+
+      assert :ok = GenServer.call(notifier, {:send_alert, alert(:warning)})
+      assert :ok = GenServer.call(notifier, {:send_alert, alert(:info, title: "Disk Low")})
+      refute_received {:webhook, _, _}
+
+      assert :ok = GenServer.call(notifier, {:send_alert, alert(:error, title: "Node Offline")})
+      assert_received {:webhook, _, opts}
+      assert opts[:json].title == "Node Offline"
+
+      assert_receive {:webhook, _, opts}, 1_000
+      digest = opts[:json]
+      assert digest.title == "Quiet Hours Digest: overnight"
+      assert digest.details["count"] == 2
+
+      assert Enum.map(digest.details["alerts"], & &1["title"]) ==
+               ["Service Down: web", "Disk Low"]
+    end
+  end
+end
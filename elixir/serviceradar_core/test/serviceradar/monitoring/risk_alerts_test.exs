@@ -0,0 +1,112 @@
+defmodule ServiceRadar.Monitoring.RiskAlertsTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Monitoring.RiskAlerts
+
+  @device_id "sr:10.0.0.5"
+
+  defp update(risk, opts \\ []) do
+    metadata =
+      case Keyword.get(opts, :tags) do
+        nil -> %{"armis_risk_level" => risk}
+        tags -> %{"armis_risk_level" => risk, "armis_tags" => tags}
+      end
+
+    %{
+      ip: "10.0.0.5",
+      hostname: "db-1",
+      partition: Keyword.get(opts, :partition, "default"),
+      source: "armis",
+      metadata: metadata,
+      tags: %{},
+      timestamp: Keyword.get(opts, :timestamp, ~U[2026-10-01 12:00:00Z])
+    }
+  end
+
+  defp check(previous, updates, rules, opts \\ []) do
+    test_pid = self()
+    {:ok, rules} = RiskAlerts.validate_rules(rules)
+
+    fire = fn change ->
+      send(test_pid, {:fired, change})
+      {:ok, change}
+    end
+
+    RiskAlerts.check(
+      previous,
+      Enum.map(updates, &{&1, @device_id}),
+      Keyword.merge([rules: rules, fire_on_first_seen: false, fire: fire], opts)
+    )
+  end
+
+  describe "check/3" do
+    test "alerts when the risk crosses the threshold" do
+      assert [change] = check(%{@device_id => 3}, [update("8")], [%{threshold: 7}])
+
+      assert change.device_id == @device_id
+      assert change.previous_risk == 3
+      assert change.current_risk == 8
+      assert_received {:fired, ^change}
+    end
+
+    test "stays silent below the threshold or when already above it" do
+      rules = [%{threshold: 7}]
+
+      assert check(%{@device_id => 1}, [update("6")], rules) == []
+      assert check(%{@device_id => 8}, [update("9")], rules) == []
+      refute_received {:fired, _}
+    end
+
+    test "applies the rule of the partition and tag" do
+      rules = [%{partition: "tenant-a", tag: "critical", delta: 2}]
+      tenant_a = update("4", partition: "tenant-a", tags: "Critical,Server")
+      tenant_b = update("9", partition: "tenant-b", tags: "critical")
+      untagged = update("9", partition: "tenant-a", tags: "server")
+
+      assert [_change] = check(%{@device_id => 2}, [tenant_a], rules)
+      assert check(%{@device_id => 1}, [tenant_b], rules) == []
+      assert check(%{@device_id => 1}, [untagged], rules) == []
+    end
+
+    test "only records a baseline for devices seen for the first time" do
+      assert check(%{}, [update("9")], [%{threshold: 7}]) == []
+
+      assert [%{previous_risk: nil, current_risk: 9}] =
+               check(%{}, [update("9")], [%{threshold: 7}], fire_on_first_seen: true)
+    end
+
+    test "uses the latest update of a device in the batch" do
+      updates = [
+        update("9", timestamp: ~U[2026-10-01 12:00:00Z]),
+        update("2", timestamp: ~U[2026-10-01 13:00:00Z])
+      ]
+
+      assert check(%{@device_id => 1}, updates, [%{threshold: 7}]) == []
+    end
+
+    test "does not report changes that failed to alert" do
+      {:ok, rules} = RiskAlerts.validate_rules([%{threshold: 7}])
+
+      assert RiskAlerts.check(%{@device_id => 1}, [{update("9"), @device_id}],
+               rules: rules,
+               fire: fn _change -> {:error, :unavailable} end
+             ) == []
+    end
+  end
+
+  describe "validate_rules/1" do
+    test "requires a non-negative threshold or delta" do
+      assert {:error, "rule 0: a threshold or delta is required"} =
+               RiskAlerts.validate_rules([%{partition: "default"}])
+
+      assert {:error, "rule 1: threshold and delta must be non-negative"} =
+               RiskAlerts.validate_rules([[delta: 1], [threshold: -1]])
+    end
+  end
+
+  test "risk_level/1 reads the first integer risk key" do
+    assert RiskAlerts.risk_level(%{"armis_risk_level" => " 7 ", "risk_level" => "2"}) == 7
+    assert RiskAlerts.risk_level(%{"armis_risk_level" => "high", "risk_level" => "2"}) == 2
+    assert RiskAlerts.risk_level(%{}) == nil
+  end
+end
@@ -0,0 +1,131 @@
+defmodule ServiceRadar.Monitoring.WebhookTemplatesTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Monitoring.WebhookNotifier
+  alias ServiceRadar.Monitoring.WebhookTemplates
+
+  defmodule CaptureClient do
+    @moduledoc false
+
+    def post(url, opts) do
+      send(:webhook_templates_test, {:webhook, url, opts})
+      {:ok, %{status: 200, body: ""}}
+    end
+  end
+
+  defp alert do
+    %WebhookNotifier.Alert{
+      level: :error,
+      title: "Device Risk Level Increased",
+      message: "Device sr:db-1 risk level rose from 3 to 8",
+      timestamp: "2026-10-01T12:00:00Z",
+      gateway_id: "gateway-1",
+      details: %{"device_uid" => "sr:db-1", "note" => "<b>check</b>"}
+    }
+  end
+
+  defp render!(config) do
+    {:ok, format} = WebhookTemplates.compile(config)
+    {:ok, payload} = WebhookTemplates.render(format, alert())
+    {format, payload}
+  end
+
+  describe "channel defaults" do
+    test "generic sends the alert as JSON" do
+      assert {_format, {:json, payload}} = render!(%{})
+      assert payload.title == "Device Risk Level Increased"
+      assert payload.details["device_uid"] == "sr:db-1"
+    end
+
+    test "slack renders markdown blocks" do
+      assert {_format, {:json, payload}} = render!(%{channel: "Slack"})
+
+      assert payload["text"] == ":red_circle: *Device Risk Level Increased*"
+      assert [section, context] = payload["blocks"]
+      assert section["text"]["text"] =~ "rose from 3 to 8"
+      assert hd(context["elements"])["text"] == "ERROR | gateway gateway-1 | 2026-10-01T12:00:00Z"
+    end
+
+    test "pagerduty renders an Events v2 trigger" do
+      assert {_format, {:json, payload}} =
+               render!(%{channel: "pagerduty", vars: %{routing_key: "rk-1"}})
+
+      assert payload["routing_key"] == "rk-1"
+      assert payload["event_action"] == "trigger"
+      assert payload["payload"]["severity"] == "critical"
+      assert payload["payload"]["source"] == "gateway-1"
+      assert payload["payload"]["custom_details"]["details"]["device_uid"] == "sr:db-1"
+    end
+
+    test "email renders escaped HTML" do
+      assert {format, {:body, body}} = render!(%{channel: "email"})
+
+      assert format.content_type =~ "text/html"
+      assert body =~ "<h2>[ERROR] Device Risk Level Increased</h2>"
+      assert body =~ "&lt;b&gt;check&lt;/b&gt;"
+    end
+  end
+
+  describe "compile/1" do
+    test "renders a custom template over the alert and vars" do
+      template = ~S|{"msg": <%= json(alert.title <> " @ " <> vars["team"]) %>}|
+
+      assert {_format, {:json, %{"msg" => "Device Risk Level Increased @ noc"}}} =
+               render!(%{channel: "slack", template: template, vars: %{"team" => "noc"}})
+    end
+
+    test "rejects templates that fail to parse, raise or render invalid JSON" do
+      assert {:error, "template does not parse: " <> _} =
+               WebhookTemplates.compile(%{template: ~S|{"a": <%= json(alert.title) |})
+
+      assert {:error, "generic template: " <> _} =
+               WebhookTemplates.compile(%{template: ~S|<%= alert.missing_field %>|})
+
+      assert {:error, "generic template: does not render valid JSON"} =
+               WebhookTemplates.compile(%{template: ~S|{"a": <%= alert.title %>}|})
+    end
+
+    test "rejects unknown channels and pagerduty without a routing key" do
+      assert {:error, "unknown notification channel \"teams\""} =
+               WebhookTemplates.compile(%{channel: "teams"})
+
+      assert {:error, "pagerduty channel requires vars.routing_key"} =
+               WebhookTemplates.compile(%{channel: "pagerduty"})
+    end
+  end
+
+  describe "WebhookNotifier" do
+    setup do
+      Process.register(self(), :webhook_templates_test)
+      :ok
+    end
+
+    test "formats alerts and replays per channel and skips invalid webhooks" do
+      notifier =
+        start_supervised!(
+          {WebhookNotifier,
+           name: nil,
+           http_client: CaptureClient,
+           webhooks: [
+             %{name: "slack", channel: "slack", url: "http://slack.test/hook"},
+             %{name: "mail", channel: "email", url: "http://mail.test/hook"},
+             %{name: "broken", url: "http://broken.test/hook", template: "<%= nope"}
+           ]}
+        )
+
+      replay = %{alert() | replay: %{"replay" => true}}
+
+      assert :ok = WebhookNotifier.replay_alert(replay, server: notifier, channel: "slack")
+      assert_receive {:webhook, "http://slack.test/hook", opts}
+      assert opts[:json]["text"] =~ "Device Risk Level Increased"
+
+      assert :ok = WebhookNotifier.replay_alert(replay, server: notifier, channel: "mail")
+      assert_receive {:webhook, "http://mail.test/hook", opts}
+      assert opts[:body] =~ "<html>"
+      assert opts[:headers]["content-type"] =~ "text/html"
+
+      assert {:error, :unknown_channel} =
+               WebhookNotifier.replay_alert(replay, server: notifier, channel: "broken")
+    end
+  end
+end
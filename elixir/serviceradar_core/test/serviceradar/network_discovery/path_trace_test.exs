@@ -0,0 +1,107 @@
+defmodule ServiceRadar.NetworkDiscovery.PathTraceTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.NetworkDiscovery.PathTrace
+
+  defp edge(src, dst, src_if, dst_if, overrides \\ %{}) do
+    Map.merge(
+      %{
+        "src_id" => src,
+        "dst_id" => dst,
+        "protocol" => "lldp",
+        "relation_type" => "CONNECTS_TO",
+        "evidence_class" => "direct-physical",
+        "confidence_tier" => "high",
+        "confidence_score" => 90,
+        "local_if_index_ab" => src_if,
+        "local_if_name_ab" => "eth#{src_if}",
+        "local_if_index_ba" => dst_if,
+        "local_if_name_ba" => "eth#{dst_if}"
+      },
+      overrides
+    )
+  end
+
+  test "traces a linear path with oriented interfaces" do
+    # Stored edges are canonical (src_id <= dst_id), so the middle link is
+    # traversed against its stored direction.
+    edges = [
+      edge("sr:a", "sr:b", 1, 2),
+      edge("sr:b", "sr:c", 3, 4)
+    ]
+
+    assert {:ok, result} = PathTrace.find_paths(edges, "sr:c", "sr:a")
+    assert [%{hop_count: 2, hops: hops}] = result.paths
+
+    assert Enum.map(hops, & &1.device_id) == ["sr:c", "sr:b", "sr:a"]
+
+    [source, middle, destination] = hops
+
+    assert source.ingress_interface == nil
+    assert source.egress_interface == %{if_index: 4, if_name: "eth4"}
+    assert source.link.protocol == "lldp"
+
+    assert middle.ingress_interface == %{if_index: 3, if_name: "eth3"}
+    assert middle.egress_interface == %{if_index: 2, if_name: "eth2"}
+
+    assert destination.ingress_interface == %{if_index: 1, if_name: "eth1"}
+    assert destination.egress_interface == nil
+    assert destination.link == nil
+  end
+
+  test "returns no_path when the devices are not connected" do
+    edges = [
+      edge("sr:a", "sr:b", 1, 2),
+      edge("sr:c", "sr:d", 3, 4)
+    ]
+
+    assert {:error, :no_path} = PathTrace.find_paths(edges, "sr:a", "sr:d")
+    assert {:error, :no_path} = PathTrace.find_paths(edges, "sr:a", "sr:unknown")
+  end
+
+  test "respects max_hops" do
+    edges = [
+      edge("sr:a", "sr:b", 1, 2),
+      edge("sr:b", "sr:c", 3, 4),
+      edge("sr:c", "sr:d", 5, 6)
+    ]
+
+    assert {:error, :no_path} = PathTrace.find_paths(edges, "sr:a", "sr:d", max_hops: 2)
+
+    assert {:ok, %{paths: [%{hop_count: 3}]}} =
+             PathTrace.find_paths(edges, "sr:a", "sr:d", max_hops: 3)
+  end
+
+  test "returns the shortest of multiple paths, or all of them up to a cap" do
+    # a - b - c - e and a - d - e: two routes to e of different length.
+    edges = [
+      edge("sr:a", "sr:b", 1, 2),
+      edge("sr:b", "sr:c", 3, 4),
+      edge("sr:c", "sr:e", 5, 6),
+      edge("sr:a", "sr:d", 7, 8),
+      edge("sr:d", "sr:e", 9, 10)
+    ]
+
+    assert {:ok, %{paths: [shortest]}} = PathTrace.find_paths(edges, "sr:a", "sr:e")
+    assert shortest.hop_count == 2
+    assert Enum.map(shortest.hops, & &1.device_id) == ["sr:a", "sr:d", "sr:e"]
+
+    assert {:ok, %{paths: paths}} = PathTrace.find_paths(edges, "sr:a", "sr:e", max_paths: 5)
+
+    assert Enum.map(paths, fn path -> Enum.map(path.hops, & &1.device_id) end) == [
+             ["sr:a", "sr:d", "sr:e"],
+             ["sr:a", "sr:b", "sr:c", "sr:e"]
+           ]
+  end
+
+  test "prefers the most confident edge between the same pair of devices" do
+    edges = [
+      edge("sr:a", "sr:b", 1, 2, %{"protocol" => "snmp-l2", "confidence_score" => 40}),
+      edge("sr:a", "sr:b", 11, 12, %{"confidence_score" => 95})
+    ]
+
+    assert {:ok, %{paths: [%{hops: [source, _]}]}} = PathTrace.find_paths(edges, "sr:a", "sr:b")
+    assert source.egress_interface == %{if_index: 11, if_name: "eth11"}
+    assert source.link.protocol == "lldp"
+  end
+end
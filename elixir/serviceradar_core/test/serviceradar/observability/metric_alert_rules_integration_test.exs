@@ -0,0 +1,121 @@
+defmodule ServiceRadar.Observability.MetricAlertRulesIntegrationTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Monitoring.Alert
+  alias ServiceRadar.Observability.MetricAlertRule
+  alias ServiceRadar.Observability.MetricAlertRules
+  alias ServiceRadar.TestSupport
+
+  require Ash.Query
+
+  @moduletag :integration
+
+  @now ~U[2026-10-16 12:00:00Z]
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    actor = SystemActor.system(:metric_alert_rules_test)
+
+    {:ok, rule} =
+      MetricAlertRule.create(
+        %{
+          name: "cpu-high-#{System.unique_integer([:positive])}",
+          query: "in:cpu_metrics partition:default",
+          value_field: "usage_percent",
+          comparison: :gt,
+          threshold: 90.0,
+          duration_seconds: 300,
+          severity: :critical
+        },
+        actor: actor
+      )
+
+    {:ok, actor: actor, rule: rule, device: "sr:cpu-#{System.unique_integer([:positive])}"}
+  end
+
+  test "fires after the sustained duration and clears on recovery", ctx do
+    %{actor: actor, rule: rule, device: device} = ctx
+
+    # Breaching for two minutes: not yet.
+    assert {:ok, %{fired: 0}} =
+             run(rule, actor, samples(device, [{-400, 40.0}, {-120, 95.0}, {0, 96.0}]))
+
+    assert [] = open_alerts(rule, actor)
+
+    # Breaching for six minutes: fires once.
+    rows = samples(device, [{-400, 40.0}, {-360, 95.0}, {-120, 95.0}, {0, 96.0}])
+    assert {:ok, %{fired: 1}} = run(rule, actor, rows)
+    assert {:ok, %{fired: 0}} = run(rule, actor, rows)
+
+    assert [alert] = open_alerts(rule, actor)
+    assert alert.device_uid == device
+    assert alert.severity == :critical
+    assert alert.metric_value == 96.0
+    assert alert.metadata["metric_alert_rule_id"] == rule.id
+
+    # No samples: the alert stays open.
+    assert {:ok, %{resolved: 0}} = run(rule, actor, [])
+
+    # Latest sample below the threshold: resolved.
+    assert {:ok, %{resolved: 1}} = run(rule, actor, samples(device, [{-120, 95.0}, {0, 42.0}]))
+    assert [] = open_alerts(rule, actor)
+    assert {:ok, %{status: :resolved}} = Ash.get(Alert, alert.id, actor: actor)
+  end
+
+  test "rejects queries that set their own time window", %{actor: actor} do
+    assert {:error, %Ash.Error.Invalid{}} =
+             MetricAlertRule.create(
+               %{
+                 name: "bad-#{System.unique_integer([:positive])}",
+                 query: "in:cpu_metrics time:last_1h",
+                 value_field: "usage_percent",
+                 threshold: 90.0
+               },
+               actor: actor
+             )
+  end
+
+  defp run(rule, actor, rows) do
+    translate_fn = fn "in:cpu_metrics partition:default time:[" <> _, _limit, nil, _dir, _mode ->
+      {:ok, Jason.encode!(%{"sql" => "select samples", "params" => []})}
+    end
+
+    query_fn = fn "select samples", [] ->
+      columns = ["device_id", "timestamp", "usage_percent"]
+
+      {:ok,
+       %Postgrex.Result{
+         columns: columns,
+         rows: Enum.map(rows, fn row -> Enum.map(columns, &Map.get(row, &1)) end)
+       }}
+    end
+
+    MetricAlertRules.run_rule(rule,
+      now: @now,
+      actor: actor,
+      runner_opts: [translate_fn: translate_fn, query_fn: query_fn]
+    )
+  end
+
+  defp open_alerts(rule, actor) do
+    Alert
+    |> Ash.Query.for_read(:active)
+    |> Ash.Query.filter(contains(source_id, ^"metric_alert_rule:#{rule.id}:"))
+    |> Ash.read!(actor: actor)
+  end
+
+  defp samples(device, points) do
+    Enum.map(points, fn {offset, value} ->
+      %{
+        "device_id" => device,
+        "timestamp" => DateTime.add(@now, offset, :second),
+        "usage_percent" => value
+      }
+    end)
+  end
+end
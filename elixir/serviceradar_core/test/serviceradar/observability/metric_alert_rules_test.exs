@@ -0,0 +1,72 @@
+defmodule ServiceRadar.Observability.MetricAlertRulesTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Observability.MetricAlertRules
+
+  @now ~U[2026-10-16 12:00:00Z]
+
+  describe "evaluate/3" do
+    setup do
+      {:ok, rule: rule_map(duration_seconds: 300)}
+    end
+
+    test "is pending until the condition has held for the duration", %{rule: rule} do
+      rows = samples("dev-1", [{-600, 40.0}, {-240, 95.0}, {-120, 96.0}, {0, 97.0}])
+
+      assert %{"dev-1" => %{state: :pending, value: 97.0, since: since}} =
+               MetricAlertRules.evaluate(rule, rows, @now)
+
+      assert since == DateTime.add(@now, -240, :second)
+    end
+
+    test "fires once the condition held for the whole duration", %{rule: rule} do
+      rows = samples("dev-1", [{-420, 40.0}, {-360, 91.0}, {-180, 95.0}, {0, 93.0}])
+
+      assert %{"dev-1" => %{state: :firing, value: 93.0}} =
+               MetricAlertRules.evaluate(rule, rows, @now)
+    end
+
+    test "a single recovered sample breaks the streak", %{rule: rule} do
+      rows = samples("dev-1", [{-420, 95.0}, {-360, 95.0}, {-180, 50.0}, {0, 93.0}])
+      assert %{"dev-1" => %{state: :pending}} = MetricAlertRules.evaluate(rule, rows, @now)
+
+      rows = samples("dev-1", [{-420, 95.0}, {-60, 95.0}, {0, 80.0}])
+      assert %{"dev-1" => %{state: :ok, value: 80.0}} =
+               MetricAlertRules.evaluate(rule, rows, @now)
+    end
+
+    test "groups samples and honours the comparison" do
+      rule = rule_map(comparison: :lte, threshold: 10.0, duration_seconds: 0)
+
+      rows =
+        samples("dev-1", [{0, 5}]) ++
+          samples("dev-2", [{0, 50.0}]) ++ [%{"device_id" => nil, "timestamp" => @now}]
+
+      assert %{"dev-1" => %{state: :firing}, "dev-2" => %{state: :ok}} =
+               MetricAlertRules.evaluate(rule, rows, @now)
+    end
+  end
+
+  defp rule_map(overrides) do
+    Map.merge(
+      %{
+        value_field: "value",
+        group_by: "device_id",
+        comparison: :gt,
+        threshold: 90.0,
+        duration_seconds: 300
+      },
+      Map.new(overrides)
+    )
+  end
+
+  defp samples(device, points) do
+    Enum.map(points, fn {offset, value} ->
+      %{
+        "device_id" => device,
+        "timestamp" => DateTime.add(@now, offset, :second),
+        "value" => value
+      }
+    end)
+  end
+end
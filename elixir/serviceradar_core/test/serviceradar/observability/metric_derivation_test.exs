@@ -0,0 +1,177 @@
+defmodule ServiceRadar.Observability.MetricDerivationTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Observability.MetricDerivation
+  alias ServiceRadar.Observability.MetricDerivation.Expression
+
+  @t0 ~U[2026-01-01 00:00:00.000000Z]
+
+  defp row(metric, value, timestamp, overrides \\ %{}) do
+    Map.merge(
+      %{
+        timestamp: timestamp,
+        gateway_id: "gw-1",
+        agent_id: "agent-1",
+        metric_name: metric,
+        metric_type: "sysmon",
+        device_id: "sr:device-1",
+        value: value,
+        unit: "bytes",
+        tags: %{"metric" => metric, "mount" => "/"},
+        partition: "default",
+        target_device_ip: "10.0.0.5",
+        if_index: nil,
+        created_at: timestamp
+      },
+      overrides
+    )
+  end
+
+  defp compile!(definitions) do
+    {rules, []} = MetricDerivation.compile_rules(definitions)
+    rules
+  end
+
+  describe "Expression.parse/1" do
+    test "respects operator precedence and parentheses" do
+      {:ok, ast} = Expression.parse("a + b * 2 - (c - 1) / 4")
+
+      values = %{"a" => 1, "b" => 3, "c" => 9}
+      assert {:ok, 5.0} = Expression.evaluate(ast, fn {:metric, m} -> values[m] end)
+    end
+
+    test "collects metric references" do
+      {:ok, ast} = Expression.parse("max(rate(ifHCInOctets), 0) * 8 + disk.used_bytes")
+
+      assert Expression.references(ast) == [
+               {:rate, "ifHCInOctets"},
+               {:metric, "disk.used_bytes"}
+             ]
+    end
+
+    test "rejects invalid expressions" do
+      assert {:error, _} = Expression.parse("")
+      assert {:error, _} = Expression.parse("a +")
+      assert {:error, _} = Expression.parse("(a + b")
+      assert {:error, _} = Expression.parse("a b")
+      assert {:error, "unknown function system()"} = Expression.parse("system(a)")
+      assert {:error, _} = Expression.parse("rate(a + b)")
+      assert {:error, _} = Expression.parse("min(a)")
+      assert {:error, _} = Expression.parse("File.read!(\"/etc/passwd\")")
+      assert {:error, _} = Expression.parse("a; b")
+      assert {:error, _} = Expression.parse(String.duplicate("(", 40) <> "a" <> String.duplicate(")", 40))
+    end
+
+    test "division by zero and missing values do not evaluate" do
+      {:ok, ast} = Expression.parse("a / b")
+
+      assert :error = Expression.evaluate(ast, fn {:metric, m} -> %{"a" => 1, "b" => 0}[m] end)
+      assert :error = Expression.evaluate(ast, fn _ -> nil end)
+    end
+  end
+
+  describe "compile_rules/1" do
+    test "rejects rules with invalid expressions or names" do
+      {rules, invalid} =
+        MetricDerivation.compile_rules([
+          %{"name" => "memory_headroom", "expression" => "memory_total - memory_used"},
+          %{"name" => "broken", "expression" => "memory_total -"},
+          %{"name" => "bad name", "expression" => "memory_total"},
+          %{"name" => "constant", "expression" => "1 + 2"},
+          %{"name" => "loop", "expression" => "loop * 2"},
+          %{"name" => "memory_headroom", "expression" => "memory_total"},
+          "not a rule"
+        ])
+
+      assert [%{name: "memory_headroom"}] = rules
+
+      assert Enum.map(invalid, &elem(&1, 0)) == [
+               "broken",
+               "bad name",
+               "constant",
+               "loop",
+               "memory_headroom",
+               "not a rule"
+             ]
+    end
+  end
+
+  describe "derive/3" do
+    test "computes a derived rate from consecutive batches" do
+      rules =
+        compile!([%{name: "disk_fill_rate", expression: "rate(disk_used_bytes)", unit: "bytes/s"}])
+
+      first = [row("disk_used_bytes", 1_000, @t0)]
+
+      assert {[], samples} = MetricDerivation.derive(first, rules, fn _ -> nil end)
+
+      second = [row("disk_used_bytes", 7_000, DateTime.add(@t0, 60, :second))]
+
+      assert {[derived], _samples} = MetricDerivation.derive(second, rules, &Map.get(samples, &1))
+
+      assert derived.metric_name == "disk_fill_rate"
+      assert derived.metric_type == "derived"
+      assert derived.value == 100.0
+      assert derived.unit == "bytes/s"
+      assert derived.device_id == "sr:device-1"
+      assert derived.timestamp == DateTime.add(@t0, 60, :second)
+      assert derived.tags == %{"mount" => "/"}
+      assert derived.metadata == %{"expression" => "rate(disk_used_bytes)"}
+      assert is_binary(derived.series_key)
+    end
+
+    test "skips rates across counter resets" do
+      rules = compile!([%{name: "in_bps", expression: "rate(ifHCInOctets) * 8"}])
+      previous = fn _ -> {@t0, 5_000.0} end
+
+      rows = [row("ifHCInOctets", 10, DateTime.add(@t0, 10, :second))]
+
+      assert {[], _} = MetricDerivation.derive(rows, rules, previous)
+    end
+
+    test "evaluates arithmetic per device" do
+      rules = compile!([%{name: "memory_headroom", expression: "memory_total - memory_used"}])
+
+      rows = [
+        row("memory_total", 16, @t0),
+        row("memory_used", 10, @t0),
+        row("memory_total", 8, @t0, %{device_id: "sr:device-2"}),
+        row("memory_used", 2, @t0, %{device_id: "sr:device-2"}),
+        row("memory_total", 4, @t0, %{device_id: "sr:device-3"})
+      ]
+
+      {derived, _} = MetricDerivation.derive(rows, rules, fn _ -> nil end)
+
+      assert derived |> Enum.map(&{&1.device_id, &1.value}) |> Enum.sort() == [
+               {"sr:device-1", 6.0},
+               {"sr:device-2", 6.0}
+             ]
+    end
+  end
+
+  describe "append_derived/2" do
+    test "appends derived rows using samples from earlier batches" do
+      table = :"metric_derivation_test_#{System.unique_integer([:positive])}"
+
+      start_supervised!(
+        {MetricDerivation,
+         name: nil,
+         table: table,
+         rules: [%{"name" => "disk_fill_rate", "expression" => "rate(disk_used_bytes)"}]}
+      )
+
+      first = [row("disk_used_bytes", 0, @t0)]
+      assert MetricDerivation.append_derived(first, table: table) == first
+
+      second = [row("disk_used_bytes", 500, DateTime.add(@t0, 5, :second))]
+
+      assert [_, %{metric_name: "disk_fill_rate", value: 100.0}] =
+               MetricDerivation.append_derived(second, table: table)
+    end
+
+    test "returns rows unchanged when the engine is not running" do
+      rows = [row("disk_used_bytes", 1, @t0)]
+      assert MetricDerivation.append_derived(rows, table: :metric_derivation_missing) == rows
+    end
+  end
+end
@@ -0,0 +1,106 @@
+defmodule ServiceRadar.Observability.PartitionRetentionTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Observability.PartitionRetention
+  alias ServiceRadar.Observability.PartitionRetentionPolicy
+  alias ServiceRadar.Repo
+  alias ServiceRadar.TestSupport
+
+  @moduletag :integration
+
+  setup_all do
+    TestSupport.start_core!()
+    :ok
+  end
+
+  setup do
+    actor = SystemActor.system(:partition_retention_test)
+    suffix = System.unique_integer([:positive])
+    {:ok, actor: actor, suffix: suffix}
+  end
+
+  test "deletes each partition's data according to its own window", %{
+    actor: actor,
+    suffix: suffix
+  } do
+    now = DateTime.utc_now()
+    short = "retention-short-#{suffix}"
+    long = "retention-long-#{suffix}"
+    unmanaged = "retention-none-#{suffix}"
+
+    {:ok, _} = create_policy(actor, short, 7)
+    {:ok, _} = create_policy(actor, long, 90)
+
+    for partition <- [short, long, unmanaged], age_days <- [1, 30] do
+      insert_cpu_metric(partition, DateTime.add(now, -age_days * 86_400, :second))
+    end
+
+    deleted = PartitionRetention.enforce(now: now)
+
+    assert deleted[short] == 1
+    assert deleted[long] == 0
+    refute Map.has_key?(deleted, unmanaged)
+
+    assert cpu_metric_ages(short, now) == [1]
+    assert cpu_metric_ages(long, now) == [1, 30]
+    assert cpu_metric_ages(unmanaged, now) == [1, 30]
+  end
+
+  test "disabled policies are not enforced", %{actor: actor, suffix: suffix} do
+    now = DateTime.utc_now()
+    partition = "retention-disabled-#{suffix}"
+
+    {:ok, policy} = create_policy(actor, partition, 7)
+
+    {:ok, _} = PartitionRetentionPolicy.update(policy, %{enabled: false}, actor: actor)
+    insert_cpu_metric(partition, DateTime.add(now, -30 * 86_400, :second))
+
+    refute Map.has_key?(PartitionRetention.enforce(now: now), partition)
+    assert cpu_metric_ages(partition, now) == [30]
+  end
+
+  test "rejects windows above the global maximum", %{actor: actor, suffix: suffix} do
+    max = PartitionRetention.max_retention_days()
+    partition = "retention-max-#{suffix}"
+
+    assert {:error, %Ash.Error.Invalid{}} = create_policy(actor, partition, max + 1)
+    assert {:ok, policy} = create_policy(actor, partition, max)
+
+    assert {:error, %Ash.Error.Invalid{}} =
+             PartitionRetentionPolicy.update(policy, %{retention_days: max + 1}, actor: actor)
+
+    # One policy per partition.
+    assert {:error, %Ash.Error.Invalid{}} = create_policy(actor, partition, 7)
+  end
+
+  defp create_policy(actor, partition, days) do
+    PartitionRetentionPolicy.create(%{partition: partition, retention_days: days}, actor: actor)
+  end
+
+  defp insert_cpu_metric(partition, timestamp) do
+    Repo.query!(
+      """
+      INSERT INTO platform.cpu_metrics
+        (timestamp, gateway_id, device_id, partition, usage_percent, created_at)
+      VALUES ($1, 'gateway-retention-test', $2, $3, 12.5, NOW())
+      """,
+      [timestamp, "device-#{partition}", partition]
+    )
+  end
+
+  defp cpu_metric_ages(partition, now) do
+    %{rows: rows} =
+      Repo.query!(
+        """
+        SELECT round(extract(epoch FROM ($2::timestamptz - timestamp)) / 86400)::int
+        FROM platform.cpu_metrics
+        WHERE partition = $1
+        ORDER BY timestamp DESC
+        """,
+        [partition, now]
+      )
+
+    List.flatten(rows)
+  end
+end
@@ -0,0 +1,130 @@
+defmodule ServiceRadar.Observability.ScheduledReportsTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Observability.ScheduledReport
+  alias ServiceRadar.Observability.ScheduledReports
+  alias ServiceRadar.Observability.ScheduledReports.Renderer
+
+  describe "next_run_at/2" do
+    test "returns the next matching time after the given one" do
+      # Wednesday noon -> next Monday 06:00
+      assert {:ok, ~U[2026-10-19 06:00:00Z]} =
+               ScheduledReports.next_run_at("0 6 * * 1", ~U[2026-10-14 12:00:00Z])
+
+      assert {:ok, ~U[2026-10-14 12:15:00Z]} =
+               ScheduledReports.next_run_at("*/15 * * * *", ~U[2026-10-14 12:00:00Z])
+    end
+
+    test "never returns the given time itself" do
+      assert {:ok, ~U[2026-10-20 06:00:00Z]} =
+               ScheduledReports.next_run_at("0 6 * * *", ~U[2026-10-19 06:00:00Z])
+    end
+
+    test "rejects invalid expressions" do
+      assert {:error, "invalid cron expression" <> _} =
+               ScheduledReports.next_run_at("61 * * * *", ~U[2026-10-14 12:00:00Z])
+
+      assert {:error, _} = ScheduledReports.next_run_at("not cron", ~U[2026-10-14 12:00:00Z])
+    end
+  end
+
+  describe "Renderer.render/3" do
+    @rows [
+      %{"uid" => "sr:1", "hostname" => "core, east", "risk" => "high"},
+      %{"uid" => "sr:2", "hostname" => "edge \"b\"", "tags" => %{"site" => "a"}}
+    ]
+
+    test "renders CSV with the union of columns and quoted cells" do
+      assert {:ok, %{body: body, content_type: "text/csv", filename: "weekly-risk.csv"}} =
+               Renderer.render(@rows, :csv, "Weekly Risk")
+
+      assert body ==
+               "hostname,risk,tags,uid\r\n" <>
+                 "\"core, east\",high,,sr:1\r\n" <>
+                 "\"edge \"\"b\"\"\",,\"{\"\"site\"\":\"\"a\"\"}\",sr:2\r\n"
+    end
+
+    test "renders JSON" do
+      assert {:ok, %{body: body, content_type: "application/json"}} =
+               Renderer.render(@rows, :json, "weekly")
+
+      assert Jason.decode!(body) == @rows
+    end
+
+    test "renders a PDF document" do
+      assert {:ok, %{body: body, content_type: "application/pdf", filename: "weekly.pdf"}} =
+               Renderer.render(@rows, :pdf, "weekly")
+
+      assert "%PDF-1.4\n" <> _ = body
+      assert body =~ "(core, east"
+      assert String.ends_with?(body, "%%EOF\n")
+    end
+  end
+
+  describe "run/2" do
+    test "runs the query in the report's partition and delivers the output" do
+      test_pid = self()
+
+      report = %ScheduledReport{
+        id: Ecto.UUID.generate(),
+        name: "error logs",
+        query: "in:logs severity_text:error",
+        cron: "0 * * * *",
+        format: :csv,
+        delivery: :webhook,
+        target: %{"url" => "https://reports.example.com/hook", "headers" => %{"X-Key" => "k"}},
+        partition: "site-a",
+        requested_by_id: nil
+      }
+
+      translate_fn = fn query, 10_001, nil, nil, nil ->
+        send(test_pid, {:translated, query})
+        {:ok, Jason.encode!(%{"sql" => "select 1", "params" => []})}
+      end
+
+      query_fn = fn "select 1", [] ->
+        {:ok, %Postgrex.Result{columns: ["message", "partition"], rows: [["boom", "site-a"]]}}
+      end
+
+      http_post = fn url, opts ->
+        send(test_pid, {:posted, url, opts})
+        {:ok, %Req.Response{status: 202}}
+      end
+
+      assert {:ok, %{rows: 1, location: "webhook:" <> _}} =
+               ScheduledReports.run(report,
+                 translate_fn: translate_fn,
+                 query_fn: query_fn,
+                 validate_url: fn _url -> :ok end,
+                 http_post: http_post
+               )
+
+      assert_received {:translated, ~s(in:logs severity_text:error partition:"site-a")}
+      assert_received {:posted, "https://reports.example.com/hook", opts}
+      assert opts[:body] == "message,partition\r\nboom,site-a\r\n"
+      assert opts[:headers]["content-type"] == "text/csv"
+      assert opts[:headers]["x-key"] == "k"
+    end
+
+    test "fails when the query returns too many rows" do
+      report = %ScheduledReport{
+        id: Ecto.UUID.generate(),
+        name: "all logs",
+        query: "in:logs",
+        format: :json,
+        delivery: :webhook,
+        target: %{"url" => "https://reports.example.com/hook"},
+        requested_by_id: nil
+      }
+
+      rows = for i <- 1..10_001, do: [i]
+
+      assert {:error, "query returned more than 10000 rows"} =
+               ScheduledReports.run(report,
+                 translate_fn: fn _, _, _, _, _ -> {:ok, ~s({"sql":"select 1","params":[]})} end,
+                 query_fn: fn _, _ -> {:ok, %Postgrex.Result{columns: ["n"], rows: rows}} end,
+                 http_post: fn _, _ -> flunk("should not deliver") end
+               )
+    end
+  end
+end
@@ -0,0 +1,124 @@
+defmodule ServiceRadar.Observability.SRQLViewsTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Observability.SRQLViews
+
+  @actor SystemActor.system(:srql_views_test)
+
+  defp resolver(views) do
+    fn name ->
+      case Map.fetch(views, name) do
+        {:ok, %{} = view} -> {:ok, Map.put_new(view, :partition, nil)}
+        {:ok, query} -> {:ok, %{query: query, partition: nil}}
+        :error -> :error
+      end
+    end
+  end
+
+  defp expand(query, views) do
+    SRQLViews.expand(query, actor: @actor, resolve_fn: resolver(views))
+  end
+
+  describe "expand/2" do
+    test "inlines a view and keeps the additional filters" do
+      views = %{"linux_high_risk" => "in:devices risk_level:high os:%linux%"}
+
+      expanded = "in:devices risk_level:high os:%linux% hostname:%web% sort:hostname:asc"
+
+      assert {:ok, ^expanded} =
+               expand("in:linux_high_risk hostname:%web% sort:hostname:asc", views)
+
+      assert {:ok, "EXPLAIN in:devices risk_level:high os:%linux% time:last_24h"} =
+               expand("EXPLAIN in:Linux_High_Risk time:last_24h", views)
+    end
+
+    test "inlines views built on other views" do
+      views = %{
+        "critical_logs" => "in:logs severity_text:critical",
+        "critical_core_logs" => ~s(in:critical_logs service_name:"core")
+      }
+
+      assert {:ok, ~s(in:logs severity_text:critical service_name:"core" time:last_1h)} =
+               expand("in:critical_core_logs time:last_1h", views)
+    end
+
+    test "scopes partitioned views to their partition" do
+      views = %{"dc1_logs" => %{query: "in:logs", partition: "dc1"}}
+
+      assert {:ok, ~s(in:logs partition:"dc1" severity_text:error)} =
+               expand("in:dc1_logs severity_text:error", views)
+    end
+
+    test "leaves entities, subqueries and unknown names alone" do
+      views = %{"devices" => "in:logs", "events" => "in:logs", "noisy" => "in:logs"}
+
+      for query <- [
+            "in:devices hostname:web",
+            "in:devices device_id:(in:events severity:Critical select:device_id)",
+            ~s(in:logs message:"login in:noisy"),
+            "in:unknown_view"
+          ] do
+        assert {:ok, ^query} = expand(query, views)
+      end
+    end
+
+    test "rejects recursive view definitions" do
+      assert {:error, "recursive view definition: loop -> loop"} =
+               expand("in:loop", %{"loop" => "in:loop hostname:a"})
+
+      views = %{"a" => "in:b", "b" => "in:c host:x", "c" => "in:a"}
+
+      assert {:error, "recursive view definition: a -> b -> c -> a"} =
+               expand("in:a hostname:web", views)
+    end
+
+    test "rejects views nested too deeply" do
+      views = Map.new(1..6, fn n -> {"v#{n}", "in:v#{n + 1}"} end)
+
+      assert {:error, "views can be nested at most 5 levels deep"} = expand("in:v1", views)
+    end
+
+    test "requires an actor allowed to view the entity" do
+      assert {:error, "not permitted to view devices"} =
+               SRQLViews.expand("in:mine", resolve_fn: resolver(%{"mine" => "in:devices"}))
+    end
+  end
+
+  describe "check_definition/3" do
+    test "returns the entity a view is built on" do
+      resolve_fn = resolver(%{"critical_logs" => "in:logs severity_text:critical"})
+
+      assert {:ok, "logs"} =
+               SRQLViews.check_definition("core_errors", "in:critical_logs service:core",
+                 resolve_fn: resolve_fn
+               )
+    end
+
+    test "rejects definitions that lead back to the view" do
+      resolve_fn = resolver(%{"b" => "in:a hostname:web"})
+
+      assert {:error, "recursive view definition: a -> b -> a"} =
+               SRQLViews.check_definition("a", "in:b", resolve_fn: resolve_fn)
+
+      assert {:error, "recursive view definition: a -> a"} =
+               SRQLViews.check_definition("A", "in:a", resolve_fn: resolve_fn)
+    end
+
+    test "rejects definitions without a known entity" do
+      resolve_fn = resolver(%{})
+
+      assert {:error, "must select from an entity or an existing view"} =
+               SRQLViews.check_definition("a", "in:missing", resolve_fn: resolve_fn)
+
+      assert {:error, "must start with in:<entity>"} =
+               SRQLViews.check_definition("a", "EXPLAIN in:devices", resolve_fn: resolve_fn)
+    end
+  end
+
+  test "builtin_entity?/1 matches entity names and aliases" do
+    assert SRQLViews.builtin_entity?("devices")
+    assert SRQLViews.builtin_entity?("Cert_Expiry")
+    refute SRQLViews.builtin_entity?("linux_high_risk")
+  end
+end
@@ -0,0 +1,207 @@
+defmodule ServiceRadar.Observability.StreamExportTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Observability.StreamExport
+  alias ServiceRadar.Observability.StreamExport.KafkaSink
+
+  defmodule MockProducer do
+    @moduledoc false
+    @behaviour ServiceRadar.Observability.StreamExport.Producer
+
+    @impl true
+    def connect(%{producer_opts: opts} = config) do
+      send(opts[:test_pid], {:connect, config})
+      {:ok, opts}
+    end
+
+    @impl true
+    def produce(opts, topic, messages) do
+      failures = opts[:failures]
+
+      if failures && Agent.get_and_update(failures, &{&1 > 0, max(&1 - 1, 0)}) do
+        send(opts[:test_pid], {:produce_failed, topic, messages})
+        {:error, :leader_not_available}
+      else
+        send(opts[:test_pid], {:produced, topic, messages})
+        :ok
+      end
+    end
+
+    @impl true
+    def close(opts) do
+      send(opts[:test_pid], :closed)
+      :ok
+    end
+  end
+
+  describe "parse_jobs/1" do
+    test "parses a JSON job definition and applies defaults" do
+      assert {[job], []} =
+               StreamExport.parse_jobs([
+                 %{
+                   "name" => "critical-events",
+                   "query" => "in:events severity_id:>=4",
+                   "topic" => "serviceradar.events",
+                   "key_field" => "device.uid",
+                   "brokers" => ["kafka-1:9093", "kafka-2:9093"],
+                   "sasl" => %{
+                     "mechanism" => "SCRAM_SHA_512",
+                     "username" => "sr",
+                     "password" => "secret"
+                   },
+                   "tls" => %{"cacertfile" => "/etc/kafka-ca.pem"}
+                 }
+               ])
+
+      assert job.key_field == ["device", "uid"]
+      assert job.brokers == [{"kafka-1", 9093}, {"kafka-2", 9093}]
+      assert job.sasl == %{mechanism: :scram_sha_512, username: "sr", password: "secret"}
+      assert job.tls == [verify: :verify_peer, cacertfile: ~c"/etc/kafka-ca.pem"]
+      assert job.client_id == "serviceradar-critical-events"
+      assert job.poll_interval_ms == 5_000
+      assert job.page_size == 500
+      assert job.producer == StreamExport.BrodProducer
+    end
+
+    test "rejects invalid jobs and skips disabled ones" do
+      base = %{name: "a", query: "in:events", topic: "t", brokers: ["k:9092"]}
+
+      {valid, invalid} =
+        StreamExport.parse_jobs([
+          base,
+          base,
+          %{base | name: "b", query: "in:events time:last_1h"},
+          Map.merge(base, %{name: "c", brokers: ["kafka"]}),
+          Map.merge(base, %{name: "d", sasl: %{mechanism: "gssapi"}}),
+          Map.merge(base, %{name: "e", page_size: 5_000}),
+          Map.merge(base, %{name: "f", enabled: false}),
+          Map.merge(base, %{name: "g", producer: NotAProducer})
+        ])
+
+      assert Enum.map(valid, & &1.name) == ["a"]
+
+      assert [
+               {"a", "duplicate job name"},
+               {"b", "query must not set time:" <> _},
+               {"c", "invalid broker" <> _},
+               {"d", "sasl mechanism" <> _},
+               {"e", "page_size" <> _},
+               {"g", "producer" <> _}
+             ] = invalid
+    end
+  end
+
+  describe "encode_message/2" do
+    test "keys by the configured field and encodes the row as JSON" do
+      uuid = "ffffffff-0000-4000-8000-000000000001"
+      {:ok, binary_uuid} = Ecto.UUID.dump(uuid)
+
+      row = %{
+        "id" => binary_uuid,
+        "device" => %{"uid" => "sr:device-1", "ip" => "10.0.0.1"},
+        "src_ip" => %Postgrex.INET{address: {10, 0, 0, 1}, netmask: 32},
+        "time" => ~U[2026-10-16 12:00:00Z],
+        "severity_id" => 4
+      }
+
+      message = KafkaSink.encode_message(row, ["device", "uid"])
+
+      assert message.key == "sr:device-1"
+
+      assert Jason.decode!(message.value) == %{
+               "id" => uuid,
+               "device" => %{"uid" => "sr:device-1", "ip" => "10.0.0.1"},
+               "src_ip" => "10.0.0.1",
+               "time" => "2026-10-16T12:00:00Z",
+               "severity_id" => 4
+             }
+    end
+
+    test "uses an empty key when the field is unset or missing" do
+      assert %{key: ""} = KafkaSink.encode_message(%{"a" => 1}, nil)
+      assert %{key: ""} = KafkaSink.encode_message(%{"a" => 1}, ["device", "uid"])
+      assert %{key: ""} = KafkaSink.encode_message(%{"device" => "x"}, ["device", "uid"])
+      assert %{key: "7"} = KafkaSink.encode_message(%{"a" => 7}, ["a"])
+    end
+  end
+
+  describe "KafkaSink" do
+    test "publishes every page of the window with the configured key" do
+      start_sink(key_field: "uid")
+
+      assert_receive {:connect, %{brokers: [{"kafka", 9092}], client_id: "serviceradar-test"}}
+
+      assert_receive {:translate, "in:devices time:[" <> _, nil}
+      assert_receive {:produced, "devices", [first, second]}
+      assert_receive {:translate, _query, "cursor-2"}
+      assert_receive {:produced, "devices", [third]}
+
+      assert Enum.map([first, second, third], & &1.key) == ["dev-1", "dev-2", "dev-3"]
+      assert Jason.decode!(first.value) == %{"uid" => "dev-1", "hostname" => "router-1"}
+    end
+
+    test "reconnects and retries the same page when publishing fails" do
+      {:ok, failures} = Agent.start_link(fn -> 1 end)
+      sink = start_sink(key_field: "uid", failures: failures)
+
+      assert_receive {:produce_failed, "devices", failed}
+      assert_receive :closed
+      assert_receive {:connect, _config}
+      assert_receive {:connect, _config}
+      assert_receive {:produced, "devices", ^failed}
+      assert_receive {:produced, "devices", [_third]}
+
+      assert %{published: 3, failures: 1, connected: true} = KafkaSink.stats(sink)
+    end
+  end
+
+  defp start_sink(opts) do
+    test_pid = self()
+
+    {[job], []} =
+      StreamExport.parse_jobs([
+        %{
+          name: "test",
+          query: "in:devices",
+          topic: "devices",
+          key_field: opts[:key_field],
+          brokers: ["kafka:9092"],
+          page_size: 2,
+          poll_interval_ms: 60_000,
+          producer: MockProducer,
+          producer_opts: [test_pid: test_pid, failures: opts[:failures]]
+        }
+      ])
+
+    translate_fn = fn query, 2, cursor, _direction, _mode ->
+      send(test_pid, {:translate, query, cursor})
+
+      {:ok,
+       Jason.encode!(%{
+         "sql" => "page #{cursor || "cursor-1"}",
+         "params" => [],
+         "pagination" => %{"limit" => 2, "next_cursor" => "cursor-2"}
+       })}
+    end
+
+    query_fn = fn
+      "page cursor-1", [] ->
+        {:ok,
+         %Postgrex.Result{
+           columns: ["uid", "hostname"],
+           rows: [["dev-1", "router-1"], ["dev-2", "router-2"]]
+         }}
+
+      "page cursor-2", [] ->
+        {:ok, %Postgrex.Result{columns: ["uid", "hostname"], rows: [["dev-3", "router-3"]]}}
+    end
+
+    start_supervised!(
+      {KafkaSink,
+       job: job,
+       name: nil,
+       backoff: [base_ms: 10, jitter: 0],
+       runner_opts: [translate_fn: translate_fn, query_fn: query_fn]}
+    )
+  end
+end
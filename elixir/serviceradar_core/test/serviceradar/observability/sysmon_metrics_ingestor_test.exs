@@ -20,7 +20,38 @@ defmodule ServiceRadar.Observability.SysmonMetricsIngestorTest do
         }
       ],
       "clusters" => [%{"name" => "ECPU", "frequency_hz" => 2_000_000}],
-      "disks" => [%{"mount_point" => "/", "used_bytes" => 50, "total_bytes" => 100}],
+      "disks" => [
+        %{
+          "mount_point" => "/",
+          "device_name" => "sda1",
+          "used_bytes" => 50,
+          "total_bytes" => 100,
+          "io" => %{
+            "read_bytes" => 4_096,
+            "write_bytes" => 8_192,
+            "read_ops" => 10,
+            "write_ops" => 20,
+            "read_bytes_per_sec" => 512,
+            "write_bytes_per_sec" => 1024.5
+          }
+        },
+        %{"mount_point" => "/data", "used_bytes" => 1, "total_bytes" => 2}
+      ],
+      "network" => [
+        %{
+          "interface" => "eth0",
+          "bytes_sent" => 1_000,
+          "bytes_recv" => 2_000,
+          "packets_sent" => 10,
+          "packets_recv" => 20,
+          "errors_in" => 0,
+          "errors_out" => 0,
+          "drops_in" => 1,
+          "drops_out" => 0,
+          "bytes_recv_per_sec" => 250.0
+        },
+        %{"bytes_sent" => 5}
+      ],
       "memory" => %{"used_bytes" => 80, "total_bytes" => 100},
       "processes" => [
         %{
@@ -66,18 +97,69 @@ defmodule ServiceRadar.Observability.SysmonMetricsIngestorTest do
     assert [
              %{
                mount_point: "/",
+               device_name: "sda1",
                total_bytes: 100,
                used_bytes: 50,
                available_bytes: 50,
-               usage_percent: 50.0
-             }
+               usage_percent: 50.0,
+               read_bytes: 4_096,
+               write_bytes: 8_192,
+               read_ops: 10,
+               write_ops: 20,
+               read_bytes_per_sec: 512.0,
+               write_bytes_per_sec: 1024.5,
+               read_ops_per_sec: nil
+             },
+             data_disk
            ] =
              metrics.disks
 
+    refute Map.has_key?(data_disk, :read_bytes)
+
+    assert [
+             %{
+               interface_name: "eth0",
+               bytes_sent: 1_000,
+               bytes_recv: 2_000,
+               drops_in: 1,
+               bytes_recv_per_sec: 250.0,
+               bytes_sent_per_sec: nil,
+               device_id: "device-1"
+             }
+           ] = metrics.network
+
     assert [%{pid: 123, name: "nginx", cpu_usage: 1.1, memory_usage: 2_048, status: "Running"}] =
              metrics.processes
   end
 
+  test "extracts every sample from a batched flush, oldest first" do
+    payload = %{
+      "status" => %{"timestamp" => "2026-10-17T12:00:10Z"},
+      "samples" => [
+        %{"timestamp" => "2026-10-17T12:00:00Z"},
+        "not a sample",
+        %{"timestamp" => "2026-10-17T12:00:05Z"}
+      ]
+    }
+
+    assert {:ok, samples} = SysmonMetricsIngestor.extract_samples(payload)
+
+    assert Enum.map(samples, & &1["timestamp"]) == [
+             "2026-10-17T12:00:00Z",
+             "2026-10-17T12:00:05Z",
+             "2026-10-17T12:00:10Z"
+           ]
+  end
+
+  test "extracts the single sample of an unbatched flush" do
+    payload = %{"status" => %{"timestamp" => "2026-10-17T12:00:00Z"}}
+
+    assert {:ok, [%{"timestamp" => "2026-10-17T12:00:00Z"}]} =
+             SysmonMetricsIngestor.extract_samples(payload)
+
+    assert {:error, :missing_status} = SysmonMetricsIngestor.extract_samples(%{"samples" => []})
+  end
+
   test "extracts corrupted index names from nested Ash errors" do
     errors = [
       %{
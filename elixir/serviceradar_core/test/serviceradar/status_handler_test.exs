@@ -45,4 +45,38 @@ defmodule ServiceRadar.StatusHandlerTest do
     assert {:noreply, %{}} = StatusHandler.handle_cast({:status_update, status}, %{})
     assert_receive {:forwarded, ^status}
   end
+  test "drops skewed updates in reject mode and remembers the reported agent" do
+    previous = Application.get_env(:serviceradar_core, ServiceRadar.ClockSkew)
+    Application.put_env(:serviceradar_core, ServiceRadar.ClockSkew, action: :reject)
+
+    on_exit(fn ->
+      if previous,
+        do: Application.put_env(:serviceradar_core, ServiceRadar.ClockSkew, previous),
+        else: Application.delete_env(:serviceradar_core, ServiceRadar.ClockSkew)
+    end)
+
+    parent = self()
+
+    router_pid =
+      spawn(fn ->
+        receive do
+          {:"$gen_cast", {:results_update, status}} -> send(parent, {:forwarded, status})
+        end
+      end)
+
+    Process.register(router_pid, ServiceRadar.ResultsRouter)
+
+    status = %{
+      source: "results",
+      service_type: "sync",
+      agent_id: "agent-skewed",
+      gateway_id: "gateway-1",
+      agent_timestamp: System.os_time(:second) + 3600,
+      message: Jason.encode!([%{"device_id" => "dev-1"}])
+    }
+
+    assert {:noreply, state} = StatusHandler.handle_cast({:status_update, status}, %{})
+    assert Map.has_key?(state.clock_skew_events, {"agent-skewed", "gateway-1"})
+    refute_receive {:forwarded, _status}
+  end
 end
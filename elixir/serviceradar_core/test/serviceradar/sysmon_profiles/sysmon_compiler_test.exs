@@ -45,6 +45,7 @@ defmodule ServiceRadar.AgentConfig.Compilers.SysmonCompilerTest do
       assert config["collect_cpu"] == false
       assert config["collect_memory"] == false
       assert config["collect_disk"] == false
+      assert config["collect_disk_io"] == false
       assert config["collect_network"] == false
       assert config["collect_processes"] == false
       assert config["disk_paths"] == []
@@ -4,7 +4,13 @@ alias Geolix.Adapter.MMDB2
 alias Oban.Plugins.Cron
 alias ServiceRadar.EventWriter.Processors.CausalSignals
 alias ServiceRadar.EventWriter.Processors.Flows
+alias ServiceRadar.Inventory.ScheduledDeviceOperationWorker
 alias ServiceRadar.Jobs.AlertsRetentionWorker
+alias ServiceRadar.Jobs.MetricRollupWorker
+alias ServiceRadar.Monitoring.CertificateExpiryWorker
+alias ServiceRadar.Monitoring.InventoryChangeWorker
+alias ServiceRadar.Observability.MetricAlertRuleWorker
+alias ServiceRadar.Observability.ScheduledReportWorker
 
 parse_int_env = fn env_name, default ->
   case System.get_env(env_name) do
@@ -305,6 +311,28 @@ config :serviceradar_core, :spiffe,
 config :serviceradar_core,
   mapper_topology_edge_stale_minutes: parse_int_env.("SERVICERADAR_MAPPER_TOPOLOGY_EDGE_STALE_MINUTES", 180)
 
+cert_expiry_endpoints =
+  "SERVICERADAR_CERT_EXPIRY_ENDPOINTS"
+  |> System.get_env("")
+  |> String.split(",", trim: true)
+  |> Enum.map(&String.trim/1)
+  |> Enum.reject(&(&1 == ""))
+
+config :serviceradar_core, ServiceRadar.Monitoring.CertificateExpiry,
+  endpoints: cert_expiry_endpoints,
+  warning_days: parse_int_env.("SERVICERADAR_CERT_EXPIRY_WARNING_DAYS", 30),
+  critical_days: parse_int_env.("SERVICERADAR_CERT_EXPIRY_CRITICAL_DAYS", 7),
+  timeout_ms: parse_int_env.("SERVICERADAR_CERT_EXPIRY_TIMEOUT_MS", 5_000)
+
+config :serviceradar_core, ServiceRadar.Monitoring.InventoryChange,
+  change_percent: parse_int_env.("SERVICERADAR_INVENTORY_CHANGE_PERCENT", 20),
+  min_count: parse_int_env.("SERVICERADAR_INVENTORY_CHANGE_MIN_COUNT", 10)
+
+config :serviceradar_core, ServiceRadar.Edge.AgentHeartbeatBatcher,
+  enabled: System.get_env("SERVICERADAR_AGENT_HEARTBEAT_BATCHING", "false") in ~w(true 1 yes),
+  flush_interval_ms: parse_int_env.("SERVICERADAR_AGENT_HEARTBEAT_FLUSH_INTERVAL_MS", 5_000),
+  max_batch_size: parse_int_env.("SERVICERADAR_AGENT_HEARTBEAT_MAX_BATCH", 500)
+
 if config_env() == :prod do
   cloak_key =
     case System.get_env("CLOAK_KEY") do
@@ -607,6 +635,16 @@ if config_env() == :prod do
   alerts_retention_batch_size = parse_int_env.("ALERT_RETENTION_BATCH_SIZE", 10_000)
   alerts_retention_max_batches = parse_int_env.("ALERT_RETENTION_MAX_BATCHES", 100)
 
+  metric_rollup_resolutions =
+    "METRIC_ROLLUP_RESOLUTIONS"
+    |> System.get_env("hourly,daily")
+    |> String.split(",", trim: true)
+    |> Enum.map(&String.trim/1)
+
+  metric_rollup_hourly_retention_days = parse_int_env.("METRIC_ROLLUP_HOURLY_RETENTION_DAYS", 395)
+  metric_rollup_daily_retention_days = parse_int_env.("METRIC_ROLLUP_DAILY_RETENTION_DAYS", 730)
+  metric_rollup_lookback_days = parse_int_env.("METRIC_ROLLUP_LOOKBACK_DAYS", 1)
+
   # Enable AshOban scheduler - core-elx is the only service that should run schedulers
   ash_oban_scheduler_enabled =
     System.get_env("SERVICERADAR_ASH_OBAN_SCHEDULER_ENABLED", "true") in ~w(true 1 yes)
@@ -657,7 +695,13 @@ if config_env() == :prod do
     {System.get_env("TRACE_SUMMARIES_REFRESH_CRON") || "*/2 * * * *", ServiceRadar.Jobs.RefreshTraceSummariesWorker,
      queue: :maintenance},
     {"*/2 * * * *", ServiceRadar.Jobs.RefreshLogsSeverityStatsWorker, queue: :maintenance},
-    {System.get_env("ALERT_RETENTION_CRON") || "15 * * * *", AlertsRetentionWorker, queue: :maintenance}
+    {System.get_env("ALERT_RETENTION_CRON") || "15 * * * *", AlertsRetentionWorker, queue: :maintenance},
+    {System.get_env("METRIC_ROLLUP_CRON") || "25 * * * *", MetricRollupWorker, queue: :maintenance},
+    {"* * * * *", ScheduledDeviceOperationWorker, queue: :maintenance},
+    {"* * * * *", ScheduledReportWorker, queue: :maintenance},
+    {"* * * * *", MetricAlertRuleWorker, queue: :monitoring},
+    {System.get_env("CERT_EXPIRY_CRON") || "5 * * * *", CertificateExpiryWorker, queue: :monitoring},
+    {System.get_env("INVENTORY_CHANGE_CRON") || "*/15 * * * *", InventoryChangeWorker, queue: :monitoring}
   ]
 
   add_cron_entries = fn config, entries ->
@@ -691,6 +735,14 @@ if config_env() == :prod do
     batch_size: alerts_retention_batch_size,
     max_batches: alerts_retention_max_batches
 
+  config :serviceradar_core, MetricRollupWorker,
+    resolutions: metric_rollup_resolutions,
+    retention_days: %{
+      hourly: metric_rollup_hourly_retention_days,
+      daily: metric_rollup_daily_retention_days
+    },
+    lookback_days: metric_rollup_lookback_days
+
   config :serviceradar_core, Oban, if(oban_enabled, do: oban_config, else: false)
   config :serviceradar_core, ServiceRadar.ControlRepo, control_repo_opts
   config :serviceradar_core, ServiceRadar.Repo, repo_opts
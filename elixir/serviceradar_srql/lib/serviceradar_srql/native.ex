@@ -36,6 +36,12 @@ defmodule ServiceRadarSRQL.Native do
   """
   def encode_arrow_json(_columns, _rows_json), do: :erlang.nif_error(:nif_not_loaded)
 
+  @doc """
+  Encode a JSON SRQL query response as the `serviceradar.srql.v1.QueryResponse`
+  protobuf message.
+  """
+  def encode_query_response_protobuf(_response_json), do: :erlang.nif_error(:nif_not_loaded)
+
   @doc """
   Decode an Arancini Cap'n Proto update payload into JSON.
   """
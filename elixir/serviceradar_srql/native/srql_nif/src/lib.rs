@@ -137,6 +137,30 @@ fn encode_arrow_json_impl<'a>(
     Ok(Binary::from_owned(out, env))
 }
 
+/// Encode a JSON query response as the SRQL `QueryResponse` protobuf message
+/// (see `rust/srql/proto/query.proto`).
+#[rustler::nif(schedule = "DirtyCpu")]
+fn encode_query_response_protobuf<'a>(env: Env<'a>, response_json: String) -> Term<'a> {
+    match encode_query_response_protobuf_impl(env, &response_json) {
+        Ok(binary) => (atoms::ok(), binary).encode(env),
+        Err(err) => (atoms::error(), err).encode(env),
+    }
+}
+
+fn encode_query_response_protobuf_impl<'a>(
+    env: Env<'a>,
+    response_json: &str,
+) -> Result<Binary<'a>, String> {
+    let response: Value = serde_json::from_str(response_json)
+        .map_err(|err| format!("invalid query response JSON: {err}"))?;
+    let payload = srql::encoding::encode_protobuf_json(&response)?;
+
+    let mut out = OwnedBinary::new(payload.len()).ok_or("failed to allocate protobuf payload")?;
+    out.as_mut_slice().copy_from_slice(&payload);
+
+    Ok(Binary::from_owned(out, env))
+}
+
 fn infer_column_kind(column: &str, rows: &[Value]) -> ColumnKind {
     let mut kind: Option<ColumnKind> = None;
 
@@ -32,4 +32,21 @@ defmodule ServiceRadarSRQL.NativeTest do
     assert {:error, reason} = Native.encode_arrow_json(["site_code"], "{")
     assert reason =~ "invalid rows JSON"
   end
+
+  test "encodes a query response as protobuf" do
+    response = Jason.encode!(%{"results" => [], "truncated" => true, "total_available" => 3})
+
+    # pagination (2) is always present; truncated (5) and total_available (6)
+    # are varints.
+    assert {:ok, <<0x12, 0x00, 0x28, 0x01, 0x30, 0x03>>} =
+             Native.encode_query_response_protobuf(response)
+  end
+
+  test "rejects query responses that are not JSON objects" do
+    assert {:error, reason} = Native.encode_query_response_protobuf("[]")
+    assert reason =~ "JSON object"
+
+    assert {:error, reason} = Native.encode_query_response_protobuf("{")
+    assert reason =~ "invalid query response JSON"
+  end
 end
@@ -203,6 +203,11 @@ config :serviceradar_web_ng, :plugin_verification,
   trusted_github_repositories: [],
   trusted_upload_signing_keys: %{}
 
+config :serviceradar_web_ng, :query_quota,
+  queries_per_minute: 600,
+  max_concurrent: 8,
+  partition_overrides: %{}
+
 config :serviceradar_web_ng, :saml_assertion_max_validity_seconds, 300
 
 config :serviceradar_web_ng, :scopes,
@@ -455,6 +455,50 @@ god_view_runtime_graph_auto_refresh =
     value -> value
   end
 
+query_quota_overrides =
+  case System.get_env("SERVICERADAR_QUERY_QUOTA_OVERRIDES") do
+    value when value in [nil, ""] ->
+      %{}
+
+    value ->
+      case Jason.decode(value) do
+        {:ok, overrides} when is_map(overrides) ->
+          overrides
+
+        _ ->
+          Logger.warning("Ignoring invalid SERVICERADAR_QUERY_QUOTA_OVERRIDES; expected a JSON object")
+          %{}
+      end
+  end
+
+config :serviceradar_web_ng, :query_quota,
+  queries_per_minute: parse_int_env.("SERVICERADAR_QUERY_QUOTA_PER_MINUTE", 600),
+  max_concurrent: parse_int_env.("SERVICERADAR_QUERY_QUOTA_MAX_CONCURRENT", 8),
+  partition_overrides: query_quota_overrides
+
+# Federated deployments: a JSON object of partition ID to the database URL
+# holding that partition's data. SRQL queries scoped to a listed partition
+# run there; unscoped queries fan out to every backend.
+srql_partition_backends =
+  case System.get_env("SERVICERADAR_SRQL_PARTITION_BACKENDS") do
+    value when value in [nil, ""] ->
+      %{}
+
+    value ->
+      case Jason.decode(value) do
+        {:ok, backends} when is_map(backends) ->
+          backends
+
+        _ ->
+          Logger.warning("Ignoring invalid SERVICERADAR_SRQL_PARTITION_BACKENDS; expected a JSON object")
+          %{}
+      end
+  end
+
+config :serviceradar_web_ng, :srql_partition_backends,
+  backends: srql_partition_backends,
+  fan_out_max_rows: parse_int_env.("SERVICERADAR_SRQL_FAN_OUT_MAX_ROWS", 1_000)
+
 camera_relay_browser_stream_timeout_ms =
   case to_int.(System.get_env("CAMERA_RELAY_BROWSER_STREAM_TIMEOUT_MS", "86400000")) do
     value when is_integer(value) and value > 0 -> value
@@ -54,6 +54,7 @@ defmodule ServiceRadarWebNG.Application do
       pubsub_children ++
         base_children ++
         field_survey_adbc_children() ++
+        ServiceRadarWebNG.SRQLPartitionRepo.child_specs() ++
         [
           ServiceRadarWebNG.FieldSurveyStreamLimiter,
           {Task.Supervisor, name: ServiceRadarWebNG.TaskSupervisor}
@@ -0,0 +1,79 @@
+defmodule ServiceRadarWebNG.SparseFields do
+  @moduledoc """
+  Sparse fieldsets for API responses: a `fields` parameter naming the fields
+  a client wants back, such as `fields=uid,hostname,ip`.
+
+  `fields` is a comma-separated string or a list of strings. Field names are
+  identifiers (letters, digits and underscores); order and duplicates are
+  ignored. A missing or empty `fields` means all fields.
+  """
+
+  @max_fields 64
+  @field_pattern ~r/\A[A-Za-z_][A-Za-z0-9_]*\z/
+
+  @type fields :: [String.t()] | nil
+
+  @doc """
+  Parses a `fields` parameter.
+
+  Options:
+    - `:allowed` - the fields the response has; other names are rejected
+  """
+  @spec parse(term(), keyword()) :: {:ok, fields()} | {:error, String.t()}
+  def parse(value, opts \\ [])
+
+  def parse(value, _opts) when value in [nil, "", []], do: {:ok, nil}
+
+  def parse(value, opts) when is_binary(value) do
+    value
+    |> String.split(",")
+    |> parse(opts)
+  end
+
+  def parse(value, opts) when is_list(value) do
+    if Enum.all?(value, &is_binary/1),
+      do: parse_names(value, opts),
+      else: {:error, "fields must be strings"}
+  end
+
+  def parse(_value, _opts), do: {:error, "fields must be a comma-separated list"}
+
+  @doc """
+  Restricts a response map to `fields`; nil keeps every field. Values other
+  than maps are returned as they are.
+  """
+  @spec take(term(), fields()) :: term()
+  def take(map, fields) when is_map(map) and is_list(fields), do: Map.take(map, fields)
+  def take(value, _fields), do: value
+
+  defp parse_names(names, opts) do
+    fields =
+      names
+      |> Enum.map(&String.trim/1)
+      |> Enum.reject(&(&1 == ""))
+      |> Enum.uniq()
+
+    cond do
+      fields == [] ->
+        {:ok, nil}
+
+      length(fields) > @max_fields ->
+        {:error, "at most #{@max_fields} fields can be requested"}
+
+      invalid = Enum.find(fields, &(not Regex.match?(@field_pattern, &1))) ->
+        {:error, "invalid field: #{String.slice(invalid, 0, 64)}"}
+
+      true ->
+        validate_allowed(fields, Keyword.get(opts, :allowed))
+    end
+  end
+
+  defp validate_allowed(fields, nil), do: {:ok, fields}
+
+  defp validate_allowed(fields, allowed) do
+    case Enum.reject(fields, &(&1 in allowed)) do
+      [] -> {:ok, fields}
+      unknown -> {:error, "unknown fields: #{Enum.join(unknown, ", ")}"}
+    end
+  end
+end
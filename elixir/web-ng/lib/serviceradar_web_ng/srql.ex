@@ -12,13 +12,13 @@ defmodule ServiceRadarWebNG.SRQL do
     deps: [ServiceRadarWebNG],
     exports: :all
 
-  alias ServiceRadar.Repo
+  alias ServiceRadar.Observability.SRQLViews
+  alias ServiceRadarWebNG.SparseFields
   alias ServiceRadarWebNG.SRQL.Native
+  alias ServiceRadarWebNG.SRQL.PartitionRouter
 
   require Logger
 
-  Module.register_attribute(__MODULE__, :sobelow_skip, accumulate: true)
-
   @impl true
   def query(query, opts \\ %{}) when is_binary(query) do
     query_request(%{
@@ -26,7 +26,10 @@ defmodule ServiceRadarWebNG.SRQL do
       "limit" => Map.get(opts, :limit),
       "cursor" => Map.get(opts, :cursor),
       "direction" => Map.get(opts, :direction),
-      "mode" => Map.get(opts, :mode)
+      "mode" => Map.get(opts, :mode),
+      "fields" => Map.get(opts, :fields),
+      "actor" => scope_actor(opts),
+      "partition" => Map.get(opts, :partition)
     })
   end
 
@@ -37,7 +40,9 @@ defmodule ServiceRadarWebNG.SRQL do
     direction = Map.get(opts, :direction)
     mode = Map.get(opts, :mode)
 
-    with {:ok, translation} <- translate(query, limit, cursor, direction, mode),
+    with {:ok, query} <- SRQLViews.expand(query, actor: scope_actor(opts)),
+         {:ok, translation} <- translate(query, limit, cursor, direction, mode),
+         translation = Map.put(translation, "_partition", Map.get(opts, :partition)),
          {:ok, result} <- execute_translation_raw(translation),
          {:ok, payload} <- encode_result_arrow(result) do
       {:ok,
@@ -50,24 +55,74 @@ defmodule ServiceRadarWebNG.SRQL do
     end
   end
 
+  @doc """
+  Runs a query request: `query` plus optional `limit`, `cursor`, `direction`
+  and `mode`.
+
+  `fields` (a comma-separated string or a list) restricts each result row to
+  the named columns. The translated SQL is then wrapped to select only those
+  columns; when a field is not a column of the query (such as the `uid` alias
+  derived from `device_id`), the full query runs and the rows are trimmed
+  instead. Fields that are neither are rejected.
+
+  Queries selecting a saved view (`in:<view>`) are expanded with the view's
+  definition first, as the request's `actor`.
+
+  When a result cap is configured (see `result_cap/0`), a request asking for
+  more rows than the cap gets the first capped rows. If more rows matched,
+  the response carries `"truncated" => true` and `"total_available"`, the
+  row count the request would have returned, and `pagination.next_cursor`
+  continues from the cap. Requests with `"uncapped" => true` bypass the cap.
+
+  `partition` is the caller's partition scope. The query runs on the backend
+  holding that partition; unscoped queries fan out across backends when any
+  are configured (see `ServiceRadarWebNG.SRQL.PartitionRouter`).
+  """
   @impl true
   def query_request(%{} = request) do
-    case normalize_request(request) do
-      {:ok, query, limit, cursor, direction, mode} ->
-        execute_query(query, limit, cursor, direction, mode)
-
-      {:error, reason} ->
-        {:error, reason}
+    with {:ok, query, limit, cursor, direction, mode} <- normalize_request(request),
+         {:ok, fields} <- SparseFields.parse(request_fields(request)) do
+      cap = if request_uncapped?(request), do: nil, else: result_cap()
+      page = {limit, cursor, direction, mode}
+      scope = %{actor: request_actor(request), cap: cap, partition: request_partition(request)}
+      execute_query(query, page, fields, scope)
     end
   end
 
-  defp execute_query(query, limit, cursor, direction, mode) do
+  @doc """
+  The configured cap on rows returned by one query, or `nil` when uncapped.
+  The cap is opt-in:
+
+      config :serviceradar_web_ng, :srql_result_cap,
+        max_rows: 10_000,
+        admin_api_key: "..."
+
+  Query API requests sending `x-srql-admin-key` matching `admin_api_key`
+  bypass the cap.
+  """
+  @spec result_cap() :: pos_integer() | nil
+  def result_cap do
+    cap =
+      :serviceradar_web_ng
+      |> Application.get_env(:srql_result_cap, [])
+      |> Keyword.get(:max_rows)
+
+    if is_integer(cap) and cap > 0, do: cap
+  end
+
+  defp execute_query(query, {limit, cursor, direction, mode}, fields, %{cap: cap} = scope) do
     entity = extract_entity(query)
     start_time = System.monotonic_time()
 
     result =
-      with {:ok, translation} <- translate(query, limit, cursor, direction, mode) do
-        execute_translation(Map.put(translation, "_query", query))
+      with {:ok, query} <- SRQLViews.expand(query, actor: scope.actor),
+           {:ok, translation} <- translate(query, limit, cursor, direction, mode),
+           translation = Map.put(translation, "_partition", scope.partition),
+           {:ok, capped, uncapped} <- cap_translation(translation, query, cursor, direction, mode, cap) do
+        capped
+        |> Map.merge(%{"_query" => query, "_partition" => scope.partition})
+        |> execute_translation(fields)
+        |> mark_truncation(uncapped, cap)
       end
 
     status = if match?({:ok, _}, result), do: :ok, else: :error
@@ -117,14 +172,68 @@ defmodule ServiceRadarWebNG.SRQL do
     end
   end
 
-  defp execute_translation(%{"sql" => sql} = translation) when is_binary(sql) do
+  # Translates again with the cap as the limit when the request asks for more
+  # rows, so the cursor continues from the cap. The uncapped translation is
+  # kept to count the rows the request would have returned.
+  defp cap_translation(translation, query, cursor, direction, mode, cap) do
+    limit = pagination_limit(translation)
+
+    if is_integer(cap) and is_integer(limit) and limit > cap and !Map.get(translation, "explain") do
+      with {:ok, capped} <- translate(query, cap, cursor, direction, mode) do
+        {:ok, capped, translation}
+      end
+    else
+      {:ok, translation, nil}
+    end
+  end
+
+  # Counting runs only when the capped page came back full. When the count
+  # fails the response is flagged as truncated with an unknown total.
+  defp mark_truncation({:ok, %{"results" => results} = response}, %{} = uncapped, cap)
+       when length(results) >= cap do
+    case count_translation(uncapped) do
+      {:ok, total} ->
+        {:ok, Map.merge(response, %{"truncated" => total > cap, "total_available" => total})}
+
+      {:error, reason} ->
+        Logger.warning("Failed to count truncated SRQL results: #{inspect(reason)}")
+        {:ok, Map.merge(response, %{"truncated" => true, "total_available" => nil})}
+    end
+  end
+
+  defp mark_truncation({:ok, response}, %{}, _cap), do: {:ok, Map.put(response, "truncated", false)}
+  defp mark_truncation(result, _uncapped, _cap), do: result
+
+  defp count_translation(%{"sql" => sql} = translation) when is_binary(sql) do
+    with {:ok, params} <- decode_params(Map.get(translation, "params", [])),
+         {:ok, %Postgrex.Result{rows: [[total]]}} <-
+           run_sql(count_sql(sql), params, translation, merge: :sum) do
+      {:ok, total}
+    end
+  end
+
+  defp count_translation(_translation), do: {:error, :invalid_srql_translation}
+
+  @doc """
+  Wraps translated SQL to count the rows it returns.
+  """
+  @spec count_sql(String.t()) :: String.t()
+  def count_sql(sql) do
+    inner = sql |> String.trim() |> String.trim_trailing(";")
+
+    "SELECT COUNT(*) FROM (#{inner}) AS srql_capped"
+  end
+
+  defp execute_translation(%{"sql" => sql} = translation, fields) when is_binary(sql) do
     translation
     |> Map.get("params", [])
     |> decode_params()
     |> case do
       {:ok, params} ->
-        with {:ok, result} <- run_sql(sql, params) do
-          {:ok, build_response(translation, result)}
+        with {:ok, result, narrowed?} <- run_fields_sql(sql, params, translation, fields) do
+          translation
+          |> build_response(result)
+          |> select_fields(fields, result, narrowed?)
         end
 
       {:error, reason} ->
@@ -132,16 +241,66 @@ defmodule ServiceRadarWebNG.SRQL do
     end
   end
 
-  defp execute_translation(_translation) do
+  defp execute_translation(_translation, _fields) do
     {:error, :invalid_srql_translation}
   end
 
+  defp run_fields_sql(sql, params, translation, fields) do
+    if is_nil(fields) or Map.get(translation, "explain") do
+      with {:ok, result} <- run_sql(sql, params, translation), do: {:ok, result, false}
+    else
+      case run_sql(narrow_sql(sql, fields), params, translation) do
+        {:ok, result} ->
+          {:ok, result, true}
+
+        {:error, %Postgrex.Error{postgres: %{code: :undefined_column}}} ->
+          with {:ok, result} <- run_sql(sql, params, translation), do: {:ok, result, false}
+
+        {:error, reason} ->
+          {:error, reason}
+      end
+    end
+  end
+
+  @doc """
+  Wraps translated SQL so it selects only `fields`. Field names must already
+  be validated identifiers (see `ServiceRadarWebNG.SparseFields.parse/2`), so
+  quoting them is enough.
+  """
+  @spec narrow_sql(String.t(), [String.t()]) :: String.t()
+  def narrow_sql(sql, fields) do
+    columns = Enum.map_join(fields, ", ", &~s("#{&1}"))
+    inner = sql |> String.trim() |> String.trim_trailing(";")
+
+    "SELECT #{columns} FROM (#{inner}) AS srql_fields"
+  end
+
+  defp select_fields(response, nil, _result, _narrowed?), do: {:ok, response}
+  defp select_fields(response, _fields, _result, true), do: {:ok, response}
+
+  defp select_fields(%{"results" => results} = response, fields, result, false) do
+    available =
+      case results do
+        [%{} | _] -> results |> Enum.flat_map(&Map.keys/1) |> Enum.uniq()
+        _ -> result.columns || []
+      end
+
+    case fields -- available do
+      [] ->
+        rows = Enum.map(results, &SparseFields.take(&1, fields))
+        {:ok, Map.put(response, "results", rows)}
+
+      unknown ->
+        {:error, "unknown fields: #{Enum.join(unknown, ", ")}"}
+    end
+  end
+
   defp execute_translation_raw(%{"sql" => sql} = translation) when is_binary(sql) do
     translation
     |> Map.get("params", [])
     |> decode_params()
     |> case do
-      {:ok, params} -> run_sql(sql, params)
+      {:ok, params} -> run_sql(sql, params, translation)
       {:error, reason} -> {:error, reason}
     end
   end
@@ -150,10 +309,9 @@ defmodule ServiceRadarWebNG.SRQL do
     {:error, :invalid_srql_translation}
   end
 
-  @sobelow_skip ["SQL.Query"]
-  defp run_sql(sql, params) do
+  defp run_sql(sql, params, translation, opts \\ []) do
     with :ok <- ensure_read_only_sql(sql) do
-      Ecto.Adapters.SQL.query(Repo, sql, params)
+      PartitionRouter.query(sql, params, Map.get(translation, "_partition"), opts)
     end
   end
 
@@ -423,6 +581,25 @@ defmodule ServiceRadarWebNG.SRQL do
     {:error, "missing required field: query"}
   end
 
+  defp request_fields(%{"fields" => fields}), do: fields
+  defp request_fields(%{fields: fields}), do: fields
+  defp request_fields(_request), do: nil
+
+  defp request_uncapped?(%{"uncapped" => uncapped}), do: uncapped == true
+  defp request_uncapped?(%{uncapped: uncapped}), do: uncapped == true
+  defp request_uncapped?(_request), do: false
+
+  defp request_actor(%{"actor" => actor}), do: actor
+  defp request_actor(%{actor: actor}), do: actor
+  defp request_actor(_request), do: nil
+
+  defp request_partition(%{"partition" => partition}), do: partition
+  defp request_partition(%{partition: partition}), do: partition
+  defp request_partition(_request), do: nil
+
+  defp scope_actor(%{scope: %{user: user}}), do: user
+  defp scope_actor(_opts), do: nil
+
   defp parse_limit(nil), do: nil
   defp parse_limit(limit) when is_integer(limit), do: limit
 
@@ -9,4 +9,5 @@ defmodule ServiceRadarWebNG.SRQL.Native do
   defdelegate translate(query, limit, cursor, direction, mode), to: ServiceRadarSRQL.Native
   defdelegate parse_ast(query), to: ServiceRadarSRQL.Native
   defdelegate encode_arrow_json(columns, rows_json), to: ServiceRadarSRQL.Native
+  defdelegate encode_query_response_protobuf(response_json), to: ServiceRadarSRQL.Native
 end
@@ -0,0 +1,68 @@
+defmodule ServiceRadarWebNG.SRQLPartitionRepo do
+  @moduledoc """
+  Read-only repo for the partition backends SRQL queries are routed to in a
+  federated deployment (see `ServiceRadarWebNG.SRQL.PartitionRouter`).
+
+  One instance runs per backend configured under `:srql_partition_backends`,
+  registered as `name_for/1` of its partition. A backend is a database URL or
+  a keyword list of repo options.
+  """
+
+  use Ecto.Repo,
+    otp_app: :serviceradar_web_ng,
+    adapter: Ecto.Adapters.Postgres,
+    read_only: true
+
+  Module.register_attribute(__MODULE__, :sobelow_skip, accumulate: true)
+
+  @doc """
+  Child specs for the configured partition backends.
+  """
+  @spec child_specs() :: [Supervisor.child_spec()]
+  def child_specs do
+    Enum.map(backends(), fn {partition, backend} ->
+      opts =
+        backend
+        |> backend_opts()
+        |> Keyword.put(:name, name_for(partition))
+
+      Supervisor.child_spec({__MODULE__, opts}, id: {__MODULE__, partition})
+    end)
+  end
+
+  @doc """
+  The configured backends as a map of partition to backend.
+  """
+  @spec backends() :: %{String.t() => String.t() | keyword()}
+  def backends do
+    :serviceradar_web_ng
+    |> Application.get_env(:srql_partition_backends, [])
+    |> Keyword.get(:backends, %{})
+    |> Map.new(fn {partition, backend} -> {to_string(partition), backend} end)
+  end
+
+  @doc """
+  The registered name of a partition's backend. Only called for configured
+  partitions, so the atoms are bounded by the config.
+  """
+  @sobelow_skip ["DOS.StringToAtom"]
+  @spec name_for(String.t()) :: atom()
+  def name_for(partition), do: String.to_atom("#{__MODULE__}.#{partition}")
+
+  @doc """
+  Runs `sql` on the backend registered as `name`.
+  """
+  @spec query_backend(atom(), String.t(), list()) :: {:ok, Postgrex.Result.t()} | {:error, term()}
+  def query_backend(name, sql, params) do
+    previous = put_dynamic_repo(name)
+
+    try do
+      query(sql, params)
+    after
+      put_dynamic_repo(previous)
+    end
+  end
+
+  defp backend_opts(url) when is_binary(url), do: [url: url]
+  defp backend_opts(opts) when is_list(opts), do: opts
+end
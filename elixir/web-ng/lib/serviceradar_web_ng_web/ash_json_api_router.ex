@@ -9,6 +9,10 @@ defmodule ServiceRadarWebNGWeb.AshJsonApiRouter do
   ### Inventory Domain
   - GET /api/v2/devices - List devices
   - GET /api/v2/devices/:uid - Get device by UID
+  - GET /api/v2/device-tag-rules - List device tag rules
+  - POST /api/v2/device-tag-rules - Create device tag rule
+  - PATCH /api/v2/device-tag-rules/:id - Update device tag rule
+  - DELETE /api/v2/device-tag-rules/:id - Delete device tag rule
 
   ### Infrastructure Domain
   - GET /api/v2/gateways - List gateways
@@ -22,6 +22,7 @@ defmodule ServiceRadarWebNGWeb.Auth.OIDCClient do
   require Logger
 
   @discovery_suffix "/.well-known/openid-configuration"
+  @clock_skew_seconds 60
 
   @doc """
   Generates the authorization URL for initiating OIDC login.
@@ -113,6 +114,43 @@ defmodule ServiceRadarWebNGWeb.Auth.OIDCClient do
     end
   end
 
+  @doc """
+  Verifies an ID token presented as an API bearer token.
+
+  Validates the signature, issuer, audience, expiration and not-before the
+  same way as `verify_id_token/2`, allowing #{@clock_skew_seconds}s of clock
+  skew. There is no nonce to check outside the login flow. When the token's
+  key is not in the cached JWKS the keys are fetched again once, so tokens
+  signed with a rotated key are accepted; that refetch happens at most once
+  a minute.
+
+  Returns `{:ok, claims}` on success.
+  """
+  def verify_bearer_token(token, opts \\ []) do
+    now = Keyword.get(opts, :now, System.system_time(:second))
+
+    with {:ok, config} <- get_config(),
+         {:ok, metadata} <- fetch_discovery_metadata(config.discovery_url),
+         {:ok, claims} <- verify_with_jwks(token, metadata["jwks_uri"]) do
+      cond do
+        claims["iss"] != metadata["issuer"] ->
+          {:error, :invalid_issuer}
+
+        claims["aud"] != config.client_id and config.client_id not in List.wrap(claims["aud"]) ->
+          {:error, :invalid_audience}
+
+        not is_integer(claims["exp"]) or claims["exp"] + @clock_skew_seconds < now ->
+          {:error, :token_expired}
+
+        is_integer(claims["nbf"]) and claims["nbf"] - @clock_skew_seconds > now ->
+          {:error, :token_not_yet_valid}
+
+        true ->
+          {:ok, claims}
+      end
+    end
+  end
+
   @doc """
   Extracts user attributes from ID token claims using configured mappings.
 
@@ -201,6 +239,37 @@ defmodule ServiceRadarWebNGWeb.Auth.OIDCClient do
     end
   end
 
+  defp verify_with_jwks(token, jwks_uri) do
+    with {:ok, jwks} <- fetch_jwks(jwks_uri) do
+      case decode_and_verify_jwt(token, jwks) do
+        {:error, :key_not_found} = error ->
+          case refetch_jwks(jwks_uri) do
+            {:ok, jwks} -> decode_and_verify_jwt(token, jwks)
+            :throttled -> error
+            {:error, _reason} = fetch_error -> fetch_error
+          end
+
+        result ->
+          result
+      end
+    end
+  end
+
+  # Unknown key ids refetch the JWKS at most once a minute so tokens with
+  # made-up kids cannot hammer the provider.
+  defp refetch_jwks(jwks_uri) do
+    refetched_key = "oidc_jwks_refetched:#{jwks_uri}"
+
+    case ConfigCache.get_cached(refetched_key) do
+      {:ok, _at} ->
+        :throttled
+
+      :miss ->
+        ConfigCache.put_cached(refetched_key, true, ttl: to_timeout(minute: 1))
+        fetch_jwks_uncached(jwks_uri, "oidc_jwks:#{jwks_uri}")
+    end
+  end
+
   defp ensure_discovery_suffix(discovery_url) do
     if String.ends_with?(discovery_url, @discovery_suffix) do
       discovery_url
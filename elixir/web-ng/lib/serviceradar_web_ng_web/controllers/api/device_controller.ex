@@ -5,6 +5,20 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
   use ServiceRadarWebNGWeb, :controller
 
   alias ServiceRadar.Inventory.Device
+  alias ServiceRadar.Inventory.DeviceArchival
+  alias ServiceRadar.Inventory.DeviceFieldConflict
+  alias ServiceRadar.Inventory.DeviceHealth
+  alias ServiceRadar.Inventory.DeviceLifecycle
+  alias ServiceRadar.Inventory.DeviceOwnership
+  alias ServiceRadar.Inventory.DeviceRelationships
+  alias ServiceRadar.Inventory.DeviceSearch
+  alias ServiceRadar.Inventory.DeviceSnapshot
+  alias ServiceRadar.Inventory.DuplicateReport
+  alias ServiceRadar.Inventory.PartitionConsistency
+  alias ServiceRadar.Inventory.QuarantinedDeviceUpdate
+  alias ServiceRadarWebNG.Accounts.Scope
+  alias ServiceRadarWebNG.RBAC
+  alias ServiceRadarWebNG.SparseFields
 
   require Ash.Query
 
@@ -12,13 +26,33 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
   @max_limit 500
   @max_offset 100_000
 
+  # Response fields of `device_to_map/1`, for `fields=`. Each is read from
+  # the device attribute of the same name, except the aliases below.
+  @device_fields ~w(uid type_id type name hostname ip mac uid_alt vendor_name model domain zone
+                    subnet_uid vlan_uid region first_seen_time last_seen_time first_seen last_seen
+                    created_time modified_time risk_level_id risk_level risk_score is_managed
+                    is_compliant is_trusted os hw_info network_interfaces owner org groups
+                    agent_list gateway_id agent_id discovery_sources is_available archived_at
+                    archived_reason metadata)
+  @device_field_aliases %{"first_seen" => :first_seen_time, "last_seen" => :last_seen_time}
+
+  @doc """
+  Lists devices, most recently seen first.
+
+  Query params:
+  - limit, offset or page: pagination (default 100, max 500)
+  - search, status, gateway_id, device_type: filters
+  - include_archived: also list archived devices
+  - fields: comma-separated response fields (default: all); only those
+    columns are read
+  """
   def index(conn, params) do
     case parse_index_params(params) do
       {:ok, opts} ->
         devices = list_devices(conn, opts)
 
         json(conn, %{
-          "data" => Enum.map(devices, &device_to_map/1),
+          "data" => Enum.map(devices, &(&1 |> device_to_map() |> SparseFields.take(opts.fields))),
           "pagination" => build_pagination(devices, opts)
         })
 
@@ -29,26 +63,30 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
     end
   end
 
-  def show(conn, %{"uid" => uid}) do
-    case parse_uid(uid) do
-      {:ok, parsed_uid} ->
-        scope = get_scope(conn)
-
-        case Device.get_by_uid(parsed_uid, false, scope: scope) do
-          {:ok, device} ->
-            json(conn, %{"data" => device_to_map(device)})
-
-          {:error, %Ash.Error.Query.NotFound{}} ->
-            conn
-            |> put_status(:not_found)
-            |> json(%{"error" => "device not found"})
-
-          {:error, _} ->
-            conn
-            |> put_status(:not_found)
-            |> json(%{"error" => "device not found"})
-        end
-
+  @doc """
+  Shows a device. Accepts `fields` like `index/2`.
+  """
+  def show(conn, %{"uid" => uid} = params) do
+    with {:ok, parsed_uid} <- parse_uid(uid),
+         {:ok, fields} <- parse_fields(params) do
+      scope = get_scope(conn)
+      query = Device |> Ash.Query.new() |> maybe_select_fields(fields)
+
+      case Device.get_by_uid(parsed_uid, false, scope: scope, query: query) do
+        {:ok, device} ->
+          json(conn, %{"data" => device |> device_to_map() |> SparseFields.take(fields)})
+
+        {:error, %Ash.Error.Query.NotFound{}} ->
+          conn
+          |> put_status(:not_found)
+          |> json(%{"error" => "device not found"})
+
+        {:error, _} ->
+          conn
+          |> put_status(:not_found)
+          |> json(%{"error" => "device not found"})
+      end
+    else
       {:error, reason} ->
         conn
         |> put_status(:bad_request)
@@ -62,6 +100,444 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
     |> json(%{"error" => "missing required path param: uid"})
   end
 
+  @doc """
+  Lists the relationships of a device derived from sync metadata, such as
+  the host a VM runs on or the chassis a blade is part of.
+
+  Query params:
+  - direction: outgoing (what the device runs on or belongs to), incoming
+    (what runs on or belongs to it) or both (default)
+  - depth: hops to follow (default 1, max 5)
+  - types: comma-separated relationship types (runs_on, member_of, part_of)
+  """
+  def relationships(conn, %{"uid" => uid} = params) do
+    with {:ok, parsed_uid} <- parse_uid(uid),
+         {:ok, opts} <- parse_relationship_params(params),
+         {:ok, _device} <- fetch_device(conn, parsed_uid),
+         {:ok, graph} <- DeviceRelationships.traverse(parsed_uid, opts) do
+      json(conn, %{"data" => relationship_graph_to_map(graph)})
+    else
+      {:error, :not_found} ->
+        conn
+        |> put_status(:not_found)
+        |> json(%{"error" => "device not found"})
+
+      {:error, reason} when is_binary(reason) ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => reason})
+
+      {:error, _reason} ->
+        conn
+        |> put_status(:internal_server_error)
+        |> json(%{"error" => "failed to load relationships"})
+    end
+  end
+
+  @doc """
+  Returns a device's health rolled up from the devices that run on or are
+  part of it, using the configured `ServiceRadar.Inventory.DeviceHealth`
+  policy. `children` counts the children by rolled-up state.
+  """
+  def health(conn, %{"uid" => uid}) do
+    with {:ok, parsed_uid} <- parse_uid(uid),
+         {:ok, _device} <- fetch_device(conn, parsed_uid),
+         {:ok, rollup} <- DeviceHealth.rollup(parsed_uid) do
+      json(conn, %{
+        "data" => %{
+          "device_id" => rollup.device_id,
+          "mode" => rollup.mode,
+          "own" => rollup.own,
+          "state" => rollup.state,
+          "children" => rollup.children
+        }
+      })
+    else
+      {:error, :not_found} ->
+        conn
+        |> put_status(:not_found)
+        |> json(%{"error" => "device not found"})
+
+      {:error, reason} when is_binary(reason) ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => reason})
+
+      {:error, _reason} ->
+        conn
+        |> put_status(:internal_server_error)
+        |> json(%{"error" => "failed to load device health"})
+    end
+  end
+
+  @doc """
+  Diffs a device's state at two points in time, rebuilt from its device
+  update history. Takes ISO8601 `from` and `to` and an optional `partition`.
+  """
+  def snapshot_diff(conn, %{"uid" => uid} = params) do
+    with {:ok, parsed_uid} <- parse_uid(uid),
+         {:ok, from} <- parse_optional_datetime(Map.get(params, "from")),
+         {:ok, to} <- parse_optional_datetime(Map.get(params, "to")),
+         {:ok, partition} <- parse_optional_string(Map.get(params, "partition")),
+         {:ok, _device} <- fetch_device(conn, parsed_uid),
+         {:ok, diff} <-
+           DeviceSnapshot.compare(parsed_uid, from: from, to: to, partition: partition) do
+      json(conn, %{"data" => diff})
+    else
+      {:error, :not_found} ->
+        conn
+        |> put_status(:not_found)
+        |> json(%{"error" => "device has no updates in the requested range"})
+
+      {:error, reason} when is_binary(reason) ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => reason})
+
+      {:error, _reason} ->
+        conn
+        |> put_status(:internal_server_error)
+        |> json(%{"error" => "failed to compare device snapshots"})
+    end
+  end
+
+  @doc """
+  Typo-tolerant search across device hostnames, IPs and identifiers, best
+  match first.
+
+  Query params:
+  - q: search term (at least 3 characters)
+  - partition: only devices with an identifier in this partition
+  - limit: max results (default 25, max 200)
+
+  Each result carries its relevance `score`, the `matched_field` and
+  `matched_value`, and a `highlight` with the position of the term in the
+  value (null when the value only matched fuzzily).
+  """
+  def search(conn, params) do
+    with {:ok, term} <- parse_optional_string(Map.get(params, "q")),
+         {:ok, partition} <- parse_optional_string(Map.get(params, "partition")),
+         {:ok, limit} <- parse_limit(Map.get(params, "limit"), 25),
+         {:ok, matches} <-
+           DeviceSearch.search(term || "",
+             actor: get_actor(conn),
+             partition: partition,
+             limit: limit
+           ) do
+      json(conn, %{"data" => Enum.map(matches, &search_match_to_map/1)})
+    else
+      {:error, :query_too_short} ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => "q must be at least 3 characters"})
+
+      {:error, reason} when is_binary(reason) ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => reason})
+
+      {:error, _reason} ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => "device search failed"})
+    end
+  end
+
+  @doc """
+  Reports probable duplicate devices in a partition: devices sharing a MAC or
+  a source identifier, or with near-identical hostnames, grouped with the
+  evidence. Read-only; nothing is merged.
+
+  Query params:
+  - partition: partition to scan (required)
+  - hostname_threshold: minimum hostname similarity, 0-1 (default 0.85)
+  """
+  def duplicates(conn, params) do
+    with :ok <- require_permission(conn, "devices.view"),
+         {:ok, partition} <- parse_optional_string(Map.get(params, "partition")),
+         {:ok, threshold} <- parse_threshold(Map.get(params, "hostname_threshold")),
+         {:ok, report} <-
+           DuplicateReport.report(partition: partition, hostname_threshold: threshold) do
+      json(conn, %{
+        "data" => Enum.map(report.groups, &duplicate_group_to_map/1),
+        "partition" => report.partition,
+        "scanned_devices" => report.scanned_devices,
+        "truncated" => report.truncated
+      })
+    else
+      {:error, :forbidden} ->
+        ownership_error(conn, :forbidden)
+
+      {:error, reason} when is_binary(reason) ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => reason})
+
+      {:error, _reason} ->
+        conn
+        |> put_status(:internal_server_error)
+        |> json(%{"error" => "duplicate report failed"})
+    end
+  end
+
+  @doc """
+  Reports devices whose partition assignment looks inconsistent: an IP inside
+  another partition's CIDR ranges, or identifiers spread over several
+  partitions. Findings are for review only; nothing is reassigned.
+
+  Query params:
+  - partition: only devices assigned to, or inferred for, this partition
+  - limit: max findings (default 100, max 500)
+  """
+  def partition_consistency(conn, params) do
+    with {:ok, partition} <- parse_optional_string(Map.get(params, "partition")),
+         {:ok, limit} <- parse_limit(Map.get(params, "limit"), @default_limit),
+         {:ok, report} <-
+           PartitionConsistency.report(
+             actor: get_actor(conn),
+             partition: partition,
+             limit: limit
+           ) do
+      json(conn, %{
+        "data" => Enum.map(report.findings, &partition_finding_to_map/1),
+        "partitions_checked" => report.partitions_checked,
+        "skipped_cidrs" => report.skipped_cidrs
+      })
+    else
+      {:error, reason} when is_binary(reason) ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => reason})
+
+      {:error, _reason} ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => "partition consistency report failed"})
+    end
+  end
+
+  @doc """
+  Assigns (or clears) the owner of a single device.
+
+  Body: `{"owner": {"uid": "team-netops", "type": "team"}}`, or
+  `{"owner": null}` to clear ownership.
+  """
+  def assign_owner(conn, %{"uid" => uid} = params) do
+    with :ok <- require_permission(conn, "devices.update"),
+         {:ok, parsed_uid} <- parse_uid(uid),
+         {:ok, owner} <- fetch_owner(params) do
+      case DeviceOwnership.assign(parsed_uid, owner, actor: get_actor(conn)) do
+        {:ok, %{skipped: [_ | _]}} ->
+          conn
+          |> put_status(:not_found)
+          |> json(%{"error" => "device not found"})
+
+        {:ok, result} ->
+          json(conn, %{"data" => ownership_result_to_map(result, owner)})
+
+        {:error, reason} ->
+          ownership_error(conn, reason)
+      end
+    else
+      {:error, reason} -> ownership_error(conn, reason)
+    end
+  end
+
+  @doc """
+  Assigns (or clears) the owner of many devices at once.
+
+  Body: `{"device_uids": [...], "owner": {...} | null, "partition": "default"}`.
+  With a `partition`, devices outside it are reported as skipped.
+  """
+  def bulk_assign_owner(conn, params) do
+    with :ok <- require_permission(conn, "devices.update"),
+         {:ok, device_uids} <- fetch_device_uids(params),
+         {:ok, owner} <- fetch_owner(params),
+         {:ok, partition} <- parse_optional_string(Map.get(params, "partition")) do
+      case DeviceOwnership.assign(device_uids, owner,
+             actor: get_actor(conn),
+             partition: partition
+           ) do
+        {:ok, result} ->
+          json(conn, %{"data" => ownership_result_to_map(result, owner)})
+
+        {:error, reason} ->
+          ownership_error(conn, reason)
+      end
+    else
+      {:error, reason} -> ownership_error(conn, reason)
+    end
+  end
+
+  @doc """
+  Archives a device: it is hidden from default device listings and SRQL but
+  keeps its history, and can be restored later.
+
+  Body (optional): `{"reason": "site decommissioned"}`.
+  """
+  def archive(conn, %{"uid" => uid} = params) do
+    with :ok <- require_permission(conn, "devices.update"),
+         {:ok, parsed_uid} <- parse_uid(uid),
+         {:ok, reason} <- parse_optional_string(Map.get(params, "reason")) do
+      parsed_uid
+      |> DeviceArchival.archive(actor: get_actor(conn), reason: reason)
+      |> archival_response(conn)
+    else
+      {:error, reason} -> archival_error(conn, reason)
+    end
+  end
+
+  @doc """
+  Restores an archived device to active inventory.
+  """
+  def unarchive(conn, %{"uid" => uid}) do
+    with :ok <- require_permission(conn, "devices.update"),
+         {:ok, parsed_uid} <- parse_uid(uid) do
+      parsed_uid
+      |> DeviceArchival.restore(actor: get_actor(conn))
+      |> archival_response(conn)
+    else
+      {:error, reason} -> archival_error(conn, reason)
+    end
+  end
+
+  @doc """
+  Moves a device to another lifecycle state (discovered, active, stale,
+  decommissioned). Only this manual path can reactivate a decommissioned
+  device.
+
+  Body: `{"state": "decommissioned", "reason": "returned to vendor"}`.
+  """
+  def lifecycle(conn, %{"uid" => uid} = params) do
+    with :ok <- require_permission(conn, "devices.update"),
+         {:ok, parsed_uid} <- parse_uid(uid),
+         {:ok, reason} <- parse_optional_string(Map.get(params, "reason")),
+         {:ok, _device} <- fetch_device(conn, parsed_uid),
+         {:ok, transition} <-
+           DeviceLifecycle.transition(parsed_uid, Map.get(params, "state"),
+             actor: get_actor(conn),
+             reason: reason
+           ) do
+      json(conn, %{"data" => lifecycle_transition_to_map(transition)})
+    else
+      {:error, :forbidden} ->
+        ownership_error(conn, :forbidden)
+
+      {:error, :not_found} ->
+        conn
+        |> put_status(:not_found)
+        |> json(%{"error" => "device not found"})
+
+      {:error, {:conflict, reason}} ->
+        conn
+        |> put_status(:conflict)
+        |> json(%{"error" => reason})
+
+      {:error, reason} when is_binary(reason) ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => reason})
+
+      {:error, _reason} ->
+        conn
+        |> put_status(:internal_server_error)
+        |> json(%{"error" => "failed to apply lifecycle transition"})
+    end
+  end
+
+  @doc """
+  Lists conflicts where discovery sources disagree about a device field,
+  most recently seen first.
+
+  Query params:
+  - status: open (default), resolved or all
+  - device_uid: only conflicts for this device
+  - limit / offset: pagination (default 100, max 500)
+  """
+  def conflicts(conn, params) do
+    with {:ok, limit} <- parse_limit(Map.get(params, "limit"), @default_limit),
+         {:ok, offset} <- parse_offset(params, limit),
+         {:ok, status} <- parse_conflict_status(Map.get(params, "status")),
+         {:ok, device_uid} <- parse_optional_uid(Map.get(params, "device_uid")) do
+      conflicts =
+        DeviceFieldConflict
+        |> Ash.Query.sort(last_seen_at: :desc)
+        |> Ash.Query.limit(limit)
+        |> Ash.Query.offset(offset)
+        |> maybe_filter_conflict_status(status)
+        |> maybe_filter_conflict_device(device_uid)
+        |> Ash.read!(scope: get_scope(conn))
+
+      json(conn, %{
+        "data" => Enum.map(conflicts, &conflict_to_map/1),
+        "pagination" => %{"limit" => limit, "offset" => offset, "count" => length(conflicts)}
+      })
+    else
+      {:error, reason} ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => reason})
+    end
+  end
+
+  @doc """
+  Marks a field conflict as reviewed. The device keeps its current value.
+
+  Body: `{"note": "NetBox is authoritative for this site"}` (optional).
+  """
+  def resolve_conflict(conn, %{"id" => id} = params) do
+    actor = get_actor(conn)
+
+    with :ok <- require_permission(conn, "devices.update"),
+         {:ok, id} <- parse_conflict_id(id),
+         {:ok, note} <- parse_optional_string(Map.get(params, "note")),
+         {:ok, conflict} <- Ash.get(DeviceFieldConflict, id, actor: actor),
+         {:ok, resolved} <-
+           DeviceFieldConflict.resolve(
+             conflict,
+             %{resolved_by: actor_label(actor), resolution_note: note},
+             actor: actor
+           ) do
+      json(conn, %{"data" => conflict_to_map(resolved)})
+    else
+      {:error, reason} -> conflict_error(conn, reason)
+    end
+  end
+
+  @doc """
+  Lists device updates that were rejected at ingest and quarantined, most
+  recent first.
+
+  Query params:
+  - reason: only updates that failed this check (missing_device_id,
+    invalid_ip, unknown_source, missing_partition, not_a_map)
+  - source: only updates reported by this source
+  - limit / offset: pagination (default 100, max 500)
+  """
+  def quarantine(conn, params) do
+    with {:ok, limit} <- parse_limit(Map.get(params, "limit"), @default_limit),
+         {:ok, offset} <- parse_offset(params, limit),
+         {:ok, reason} <- parse_optional_string(Map.get(params, "reason")),
+         {:ok, source} <- parse_optional_string(Map.get(params, "source")) do
+      updates =
+        QuarantinedDeviceUpdate
+        |> Ash.Query.for_read(:recent, %{reason: reason, source: source})
+        |> Ash.Query.limit(limit)
+        |> Ash.Query.offset(offset)
+        |> Ash.read!(scope: get_scope(conn))
+
+      json(conn, %{
+        "data" => Enum.map(updates, &quarantined_update_to_map/1),
+        "pagination" => %{"limit" => limit, "offset" => offset, "count" => length(updates)}
+      })
+    else
+      {:error, reason} ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{"error" => reason})
+    end
+  end
+
   @doc """
   Export devices in OCSF v1.7.0 Device object format.
   Supports filtering by type_id, time range, and pagination.
@@ -224,6 +700,8 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
       "hw_info" => device.hw_info,
       "network_interfaces" => device.network_interfaces,
       "owner" => device.owner,
+      "owner_uid" => device.owner_uid,
+      "owner_type" => device.owner_type,
       "org" => device.org,
       "groups" => device.groups,
       "agent_list" => device.agent_list
@@ -234,6 +712,7 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
     scope = get_scope(conn)
 
     Device
+    |> Ash.Query.for_read(:read, %{include_archived: opts.include_archived})
     |> Ash.Query.sort(last_seen_time: :desc)
     |> Ash.Query.limit(opts.limit)
     |> Ash.Query.offset(opts.offset)
@@ -241,20 +720,188 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
     |> maybe_filter_status(opts.status)
     |> maybe_filter_gateway_id(opts.gateway_id)
     |> maybe_filter_device_type(opts.device_type)
+    |> maybe_select_fields(opts.fields)
     |> Ash.read!(scope: scope)
   end
 
+  defp fetch_device(conn, uid) do
+    case Device.get_by_uid(uid, false, scope: get_scope(conn)) do
+      {:ok, device} -> {:ok, device}
+      {:error, _} -> {:error, :not_found}
+    end
+  end
+
+  defp parse_relationship_params(params) do
+    with {:ok, depth} <- parse_relationship_depth(Map.get(params, "depth")),
+         {:ok, direction} <- parse_optional_string(Map.get(params, "direction")) do
+      types =
+        params
+        |> Map.get("types", "")
+        |> to_string()
+        |> String.split(",", trim: true)
+        |> Enum.map(&String.trim/1)
+        |> Enum.reject(&(&1 == ""))
+
+      {:ok, [direction: direction, depth: depth, types: types]}
+    end
+  end
+
+  defp parse_relationship_depth(nil), do: {:ok, nil}
+  defp parse_relationship_depth(""), do: {:ok, nil}
+
+  defp parse_relationship_depth(depth) when is_binary(depth) do
+    case Integer.parse(String.trim(depth)) do
+      {value, ""} -> {:ok, value}
+      _ -> {:error, "invalid depth"}
+    end
+  end
+
+  defp parse_relationship_depth(_depth), do: {:error, "invalid depth"}
+
+  defp relationship_graph_to_map(graph) do
+    %{
+      "device_id" => graph.device_id,
+      "direction" => graph.direction,
+      "depth" => graph.depth,
+      "devices" => graph.devices,
+      "relationships" =>
+        Enum.map(graph.relationships, fn edge ->
+          %{
+            "source_device_id" => edge.source_device_id,
+            "target_device_id" => edge.target_device_id,
+            "relation_type" => edge.relation_type,
+            "discovery_source" => Map.get(edge, :discovery_source),
+            "metadata_key" => Map.get(edge, :metadata_key),
+            "first_seen" => Map.get(edge, :first_seen),
+            "last_seen" => Map.get(edge, :last_seen)
+          }
+        end)
+    }
+  end
+
   defp get_scope(conn) do
     conn.assigns[:current_scope]
   end
 
+  defp get_actor(conn) do
+    case get_scope(conn) do
+      %Scope{user: user} when not is_nil(user) -> conn.assigns[:ash_actor] || user
+      _ -> nil
+    end
+  end
+
+  defp require_permission(conn, permission) do
+    if RBAC.can?(get_scope(conn), permission), do: :ok, else: {:error, :forbidden}
+  end
+
+  defp fetch_owner(params) do
+    case Map.fetch(params, "owner") do
+      {:ok, owner} -> DeviceOwnership.normalize_owner(owner)
+      :error -> {:error, "missing required field: owner"}
+    end
+  end
+
+  defp fetch_device_uids(%{"device_uids" => uids}) when is_list(uids) do
+    Enum.reduce_while(uids, {:ok, []}, fn uid, {:ok, acc} ->
+      case parse_uid(uid) do
+        {:ok, parsed} -> {:cont, {:ok, [parsed | acc]}}
+        {:error, reason} -> {:halt, {:error, reason}}
+      end
+    end)
+    |> case do
+      {:ok, []} -> {:error, "device_uids must not be empty"}
+      {:ok, parsed} -> {:ok, Enum.reverse(parsed)}
+      error -> error
+    end
+  end
+
+  defp fetch_device_uids(_params), do: {:error, "device_uids must be a list"}
+
+  defp ownership_result_to_map(result, owner) do
+    %{
+      "owner" => owner && %{"uid" => owner.uid, "type" => Atom.to_string(owner.type)},
+      "assigned" => result.assigned,
+      "unchanged" => result.unchanged,
+      "skipped" => result.skipped
+    }
+  end
+
+  defp ownership_error(conn, :forbidden) do
+    conn
+    |> put_status(:forbidden)
+    |> json(%{"error" => "forbidden"})
+  end
+
+  defp ownership_error(conn, %Ash.Error.Forbidden{}), do: ownership_error(conn, :forbidden)
+
+  defp ownership_error(conn, reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => ownership_error_message(reason)})
+  end
+
+  defp ownership_error_message(reason) when is_binary(reason), do: reason
+  defp ownership_error_message(:invalid_owner), do: "owner must have a uid and a type of user or team"
+  defp ownership_error_message(:invalid_device_uids), do: "invalid device_uids"
+
+  defp ownership_error_message({:too_many_devices, max}),
+    do: "at most #{max} devices can be assigned at once"
+
+  defp ownership_error_message(_reason), do: "owner assignment failed"
+
+  defp lifecycle_transition_to_map(transition) do
+    %{
+      "device_id" => transition.device_id,
+      "from" => transition.from,
+      "to" => transition.to,
+      "manual" => transition.manual,
+      "actor" => transition.actor,
+      "reason" => transition.reason,
+      "timestamp" => DateTime.to_iso8601(transition.timestamp)
+    }
+  end
+
+  defp archival_response({:ok, %{skipped: [_ | _]}}, conn) do
+    conn
+    |> put_status(:not_found)
+    |> json(%{"error" => "device not found"})
+  end
+
+  defp archival_response({:ok, result}, conn) do
+    json(conn, %{
+      "data" => %{
+        "archived" => Map.get(result, :archived, []),
+        "restored" => Map.get(result, :restored, []),
+        "unchanged" => result.unchanged
+      }
+    })
+  end
+
+  defp archival_response({:error, reason}, conn), do: archival_error(conn, reason)
+
+  defp archival_error(conn, :forbidden), do: ownership_error(conn, :forbidden)
+  defp archival_error(conn, %Ash.Error.Forbidden{}), do: ownership_error(conn, :forbidden)
+
+  defp archival_error(conn, reason) when is_binary(reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => reason})
+  end
+
+  defp archival_error(conn, _reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => "device archival failed"})
+  end
+
   defp parse_index_params(params) when is_map(params) do
     with {:ok, limit} <- parse_limit(Map.get(params, "limit"), @default_limit),
          {:ok, offset} <- parse_offset(params, limit),
          {:ok, search} <- parse_optional_string(Map.get(params, "search")),
          {:ok, status} <- parse_status(Map.get(params, "status")),
          {:ok, gateway_id} <- parse_optional_string(Map.get(params, "gateway_id")),
-         {:ok, device_type} <- parse_optional_string(Map.get(params, "device_type")) do
+         {:ok, device_type} <- parse_optional_string(Map.get(params, "device_type")),
+         {:ok, fields} <- parse_fields(params) do
       {:ok,
        %{
          limit: limit,
@@ -262,13 +909,32 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
          search: search,
          status: status,
          gateway_id: gateway_id,
-         device_type: device_type
+         device_type: device_type,
+         include_archived: Map.get(params, "include_archived") in [true, "true", "1"],
+         fields: fields
        }}
     end
   end
 
   defp parse_index_params(_), do: {:error, "invalid query params"}
 
+  defp parse_fields(params) do
+    SparseFields.parse(Map.get(params, "fields"), allowed: @device_fields)
+  end
+
+  # The primary key is always read; sorting and filtering do not need the
+  # columns they use to be selected.
+  defp maybe_select_fields(query, nil), do: query
+
+  defp maybe_select_fields(query, fields) do
+    attributes =
+      Enum.map(fields, fn field ->
+        Map.get_lazy(@device_field_aliases, field, fn -> String.to_existing_atom(field) end)
+      end)
+
+    Ash.Query.select(query, attributes)
+  end
+
   defp parse_limit(nil, default), do: {:ok, default}
   defp parse_limit("", default), do: {:ok, default}
 
@@ -329,6 +995,17 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
 
   defp parse_page(_), do: {:error, "invalid page"}
 
+  defp parse_threshold(value) when value in [nil, ""], do: {:ok, nil}
+
+  defp parse_threshold(value) when is_binary(value) do
+    case Float.parse(String.trim(value)) do
+      {threshold, ""} -> {:ok, threshold}
+      _ -> {:error, "invalid hostname_threshold"}
+    end
+  end
+
+  defp parse_threshold(_value), do: {:error, "invalid hostname_threshold"}
+
   defp parse_optional_string(nil), do: {:ok, nil}
 
   defp parse_optional_string(value) when is_binary(value) do
@@ -344,6 +1021,97 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
   defp parse_optional_string(value) when is_atom(value), do: {:ok, Atom.to_string(value)}
   defp parse_optional_string(_), do: {:error, "invalid string param"}
 
+  defp parse_conflict_status(value) when value in [nil, "", "open"], do: {:ok, :open}
+  defp parse_conflict_status("resolved"), do: {:ok, :resolved}
+  defp parse_conflict_status("all"), do: {:ok, nil}
+  defp parse_conflict_status(_value), do: {:error, "status must be open, resolved or all"}
+
+  defp parse_conflict_id(value) do
+    case Ecto.UUID.cast(value) do
+      {:ok, id} -> {:ok, id}
+      :error -> {:error, "invalid conflict id"}
+    end
+  end
+
+  defp parse_optional_uid(value) when value in [nil, ""], do: {:ok, nil}
+  defp parse_optional_uid(value), do: parse_uid(value)
+
+  defp maybe_filter_conflict_status(query, nil), do: query
+
+  defp maybe_filter_conflict_status(query, status),
+    do: Ash.Query.filter(query, status == ^status)
+
+  defp maybe_filter_conflict_device(query, nil), do: query
+
+  defp maybe_filter_conflict_device(query, device_uid),
+    do: Ash.Query.filter(query, device_id == ^device_uid)
+
+  defp conflict_to_map(conflict) do
+    %{
+      "id" => conflict.id,
+      "device_uid" => conflict.device_id,
+      "field" => conflict.field,
+      "source_values" => conflict.source_values,
+      "winning_source" => conflict.winning_source,
+      "winning_value" => conflict.winning_value,
+      "status" => conflict.status,
+      "occurrences" => conflict.occurrences,
+      "first_seen_at" => conflict.first_seen_at,
+      "last_seen_at" => conflict.last_seen_at,
+      "resolved_at" => conflict.resolved_at,
+      "resolved_by" => conflict.resolved_by,
+      "resolution_note" => conflict.resolution_note
+    }
+  end
+
+  defp quarantined_update_to_map(update) do
+    %{
+      "id" => update.id,
+      "reasons" => update.reasons,
+      "source" => update.source,
+      "partition" => update.partition,
+      "device_id" => update.device_id,
+      "ip" => update.ip,
+      "payload" => update.payload,
+      "quarantined_at" => update.quarantined_at
+    }
+  end
+
+  defp actor_label(%{email: email}) when not is_nil(email), do: to_string(email)
+  defp actor_label(%{id: id}) when not is_nil(id), do: to_string(id)
+  defp actor_label(_actor), do: nil
+
+  defp conflict_error(conn, :forbidden), do: ownership_error(conn, :forbidden)
+  defp conflict_error(conn, %Ash.Error.Forbidden{}), do: ownership_error(conn, :forbidden)
+
+  defp conflict_error(conn, %Ash.Error.Invalid{errors: errors} = error) do
+    if Enum.any?(errors, &match?(%Ash.Error.Query.NotFound{}, &1)) do
+      conflict_error(conn, %Ash.Error.Query.NotFound{})
+    else
+      conn
+      |> put_status(:conflict)
+      |> json(%{"error" => Exception.message(error)})
+    end
+  end
+
+  defp conflict_error(conn, %Ash.Error.Query.NotFound{}) do
+    conn
+    |> put_status(:not_found)
+    |> json(%{"error" => "conflict not found"})
+  end
+
+  defp conflict_error(conn, reason) when is_binary(reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => reason})
+  end
+
+  defp conflict_error(conn, _reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => "conflict update failed"})
+  end
+
   defp parse_status(nil), do: {:ok, nil}
   defp parse_status(""), do: {:ok, nil}
 
@@ -421,6 +1189,55 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
     }
   end
 
+  defp duplicate_group_to_map(group) do
+    %{
+      "confidence" => Atom.to_string(group.confidence),
+      "devices" =>
+        Enum.map(group.devices, fn device ->
+          %{
+            "uid" => device.uid,
+            "ip" => device.ip,
+            "mac" => device.mac,
+            "hostname" => device.hostname,
+            "discovery_sources" => device.discovery_sources,
+            "last_seen" => normalize_value(device.last_seen_time)
+          }
+        end),
+      "evidence" =>
+        Enum.map(group.evidence, fn evidence ->
+          %{
+            "type" => Atom.to_string(evidence.type),
+            "key" => evidence.key,
+            "value" => evidence.value,
+            "device_ids" => evidence.device_ids,
+            "similarity" => evidence.similarity
+          }
+        end)
+    }
+  end
+
+  defp partition_finding_to_map(finding) do
+    %{
+      "device" => device_to_map(finding.device),
+      "issues" => finding.issues,
+      "ip" => finding.ip,
+      "assigned_partitions" => finding.assigned_partitions,
+      "inferred_partitions" => finding.inferred_partitions
+    }
+  end
+
+  defp search_match_to_map(match) do
+    %{
+      "device" => device_to_map(match.device),
+      "score" => match.score,
+      "matched_field" => match.matched_field,
+      "identifier_type" => match.identifier_type,
+      "matched_value" => match.matched_value,
+      "highlight" =>
+        match.highlight && %{"start" => match.highlight.start, "length" => match.highlight.length}
+    }
+  end
+
   defp device_to_map(device) do
     %{
       # Primary identifier (OCSF uid)
@@ -468,6 +1285,9 @@ defmodule ServiceRadarWebNGWeb.Api.DeviceController do
       "agent_id" => device.agent_id,
       "discovery_sources" => device.discovery_sources,
       "is_available" => device.is_available,
+      "lifecycle_state" => DeviceLifecycle.state_from_metadata(device.metadata),
+      "archived_at" => normalize_value(device.archived_at),
+      "archived_reason" => device.archived_reason,
       "metadata" => device.metadata
     }
   end
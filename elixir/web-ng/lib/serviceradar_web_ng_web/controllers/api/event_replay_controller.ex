@@ -0,0 +1,111 @@
+defmodule ServiceRadarWebNGWeb.Api.EventReplayController do
+  @moduledoc """
+  API for replaying historical events through the webhook notification
+  channels. Replayed notifications are marked as replays.
+  """
+
+  use ServiceRadarWebNGWeb, :controller
+
+  alias ServiceRadar.Monitoring.EventReplay
+  alias ServiceRadarWebNG.Accounts.Scope
+  alias ServiceRadarWebNG.RBAC
+
+  @doc """
+  Replays the matching events and returns how many were sent.
+
+  Body:
+
+      {"from": "2026-10-01T00:00:00Z",
+       "to": "2026-10-02T00:00:00Z",
+       "log_names": ["alert.rule.threshold"],
+       "type_uids": [100801],
+       "partition": "default",
+       "channel": "slack",
+       "limit": 100,
+       "rate": 5}
+
+  Only `from` and `to` are required. Without `channel`, events are sent to
+  every configured webhook.
+  """
+  def create(conn, params) do
+    with :ok <- require_permission(conn, EventReplay.required_permission()),
+         {:ok, from} <- parse_time(params, "from"),
+         {:ok, to} <- parse_time(params, "to"),
+         {:ok, log_names} <- parse_list(params, "log_names", &is_binary/1),
+         {:ok, type_uids} <- parse_list(params, "type_uids", &is_integer/1),
+         {:ok, summary} <-
+           EventReplay.replay(
+             %{
+               from: from,
+               to: to,
+               log_names: log_names,
+               type_uids: type_uids,
+               partition: Map.get(params, "partition"),
+               limit: Map.get(params, "limit")
+             },
+             actor: get_actor(conn),
+             channel: Map.get(params, "channel"),
+             rate: Map.get(params, "rate")
+           ) do
+      json(conn, %{"data" => summary})
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  defp parse_time(params, key) do
+    case Map.get(params, key) do
+      value when is_binary(value) ->
+        case DateTime.from_iso8601(value) do
+          {:ok, time, _offset} -> {:ok, time}
+          {:error, _} -> {:error, "#{key} must be an ISO8601 timestamp"}
+        end
+
+      _ ->
+        {:error, "missing required field: #{key}"}
+    end
+  end
+
+  defp parse_list(params, key, valid?) do
+    values = List.wrap(Map.get(params, key))
+
+    if Enum.all?(values, valid?),
+      do: {:ok, values},
+      else: {:error, "invalid #{key}"}
+  end
+
+  defp get_scope(conn) do
+    conn.assigns[:current_scope]
+  end
+
+  defp get_actor(conn) do
+    case get_scope(conn) do
+      %Scope{user: user} when not is_nil(user) -> conn.assigns[:ash_actor] || user
+      _ -> nil
+    end
+  end
+
+  defp require_permission(conn, permission) do
+    if RBAC.can?(get_scope(conn), permission), do: :ok, else: {:error, :forbidden}
+  end
+
+  defp error(conn, :forbidden) do
+    conn
+    |> put_status(:forbidden)
+    |> json(%{"error" => "forbidden"})
+  end
+
+  defp error(conn, %Ash.Error.Forbidden{}), do: error(conn, :forbidden)
+
+  defp error(conn, reason) when is_binary(reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => reason})
+  end
+
+  defp error(conn, _reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => "event replay failed"})
+  end
+end
@@ -0,0 +1,126 @@
+defmodule ServiceRadarWebNGWeb.Api.EventStreamController do
+  @moduledoc """
+  Streams new OCSF events as server-sent events, for clients that cannot use
+  the LiveView/WebSocket path. Events come from the same
+  `ServiceRadar.Events.PubSub` broadcast the event pages subscribe to.
+  """
+
+  use ServiceRadarWebNGWeb, :controller
+
+  alias ServiceRadar.Events.PubSub, as: EventsPubSub
+  alias ServiceRadar.Events.Stream, as: EventStream
+  alias ServiceRadar.Monitoring.OcsfEvent
+  alias ServiceRadarWebNG.Accounts.Scope
+
+  require Logger
+
+  @default_keepalive to_timeout(second: 15)
+  @default_max_duration to_timeout(minute: 30)
+
+  @doc """
+  Opens the event stream.
+
+  Query params:
+  - type: comma-separated event log names (default all)
+  - partition: only events of this partition
+  - last_event_id: resume after this event; the `Last-Event-ID` header
+    takes precedence
+
+  Each event is sent as `event: ocsf_event` with a JSON `data` line. The
+  stream closes after 30 minutes; clients reconnect with `Last-Event-ID`
+  and are first sent the stored events they missed.
+  """
+  def stream(conn, params) do
+    actor = get_actor(conn)
+
+    if actor && Ash.can?({OcsfEvent, :read}, actor) do
+      filter =
+        EventStream.filter(
+          log_names: params |> Map.get("type", "") |> to_string() |> String.split(","),
+          partition: Map.get(params, "partition")
+        )
+
+      last_event_id =
+        conn |> get_req_header("last-event-id") |> List.first() ||
+          Map.get(params, "last_event_id")
+
+      :ok = Phoenix.PubSub.subscribe(ServiceRadar.PubSub, EventsPubSub.topic())
+
+      conn
+      |> put_resp_content_type("text/event-stream")
+      |> put_resp_header("cache-control", "no-cache")
+      |> put_resp_header("x-accel-buffering", "no")
+      |> send_chunked(200)
+      |> send_backlog(last_event_id, filter, actor)
+      |> stream_events(filter, deadline())
+    else
+      conn
+      |> put_status(:forbidden)
+      |> json(%{"error" => "forbidden"})
+    end
+  end
+
+  defp send_backlog(conn, last_event_id, filter, actor) do
+    case EventStream.backlog(last_event_id, filter, actor) do
+      {:ok, events} ->
+        Enum.reduce_while(events, conn, fn event, conn ->
+          send_frame(conn, EventStream.encode(event))
+        end)
+
+      {:error, reason} ->
+        Logger.warning("Event stream backlog failed: #{inspect(reason)}")
+        conn
+    end
+  end
+
+  # Stored events and live events may overlap right after a resume; clients
+  # see the same SSE id twice at most.
+  defp stream_events(%Plug.Conn{halted: true} = conn, _filter, _deadline), do: conn
+
+  defp stream_events(conn, filter, deadline) do
+    timeout = min(config(:keepalive, @default_keepalive), deadline - now())
+
+    if timeout <= 0 do
+      conn
+    else
+      receive do
+        {:ocsf_event, event} ->
+          if EventStream.matches?(event, filter) do
+            conn |> send_frame(EventStream.encode(event)) |> continue(filter, deadline)
+          else
+            stream_events(conn, filter, deadline)
+          end
+      after
+        timeout ->
+          conn |> send_frame(EventStream.keepalive()) |> continue(filter, deadline)
+      end
+    end
+  end
+
+  defp continue({:cont, conn}, filter, deadline), do: stream_events(conn, filter, deadline)
+  defp continue({:halt, conn}, _filter, _deadline), do: conn
+
+  defp send_frame(conn, frame) do
+    case chunk(conn, frame) do
+      {:ok, conn} -> {:cont, conn}
+      {:error, _reason} -> {:halt, halt(conn)}
+    end
+  end
+
+  defp deadline, do: now() + config(:max_duration, @default_max_duration)
+
+  defp now, do: System.monotonic_time(:millisecond)
+
+  defp get_actor(conn) do
+    case conn.assigns[:current_scope] do
+      %Scope{user: user} when not is_nil(user) -> conn.assigns[:ash_actor] || user
+      _ -> nil
+    end
+  end
+
+  defp config(key, default) do
+    :serviceradar_web_ng
+    |> Application.get_env(__MODULE__, [])
+    |> Keyword.get(key, default)
+  end
+end
@@ -0,0 +1,87 @@
+defmodule ServiceRadarWebNGWeb.Api.GatewayConfigController do
+  @moduledoc """
+  Admin API for bulk gateway config changes.
+
+  `POST /api/admin/gateways/config/bulk` applies a field-level patch to the
+  selected gateways' KV configs. With `dry_run` set it previews the affected
+  gateways and their resulting config without writing anything.
+
+      {
+        "gateway_ids": ["gw-east"],
+        "partition": "east",
+        "patch": {"set": {"poll_interval": "10s"}, "unset": ["max_retries"]},
+        "dry_run": true,
+        "reason": "tighten polling"
+      }
+  """
+
+  use ServiceRadarWebNGWeb, :controller
+
+  alias ServiceRadar.Edge.GatewayConfigPush
+  alias ServiceRadarWebNG.Accounts.Scope
+  alias ServiceRadarWebNG.RBAC
+
+  action_fallback(ServiceRadarWebNGWeb.Api.FallbackController)
+
+  @doc """
+  POST /api/admin/gateways/config/bulk
+  """
+  def bulk(conn, params) do
+    with {:ok, user} <- require_authenticated(conn),
+         :ok <- require_permission(conn, "settings.edge.manage"),
+         {:ok, request} <- bulk_request(params),
+         {:ok, result} <- GatewayConfigPush.push(request, actor_name(user)) do
+      json(conn, %{data: result})
+    else
+      {:error, message} when is_binary(message) ->
+        conn |> put_status(:bad_request) |> json(%{error: "invalid_request", message: message})
+
+      {:error, other} ->
+        {:error, other}
+    end
+  end
+
+  defp bulk_request(%{"patch" => patch} = params) when is_map(patch) do
+    with {:ok, set} <- optional(patch, "set", &is_map/1, %{}),
+         {:ok, unset} <- optional(patch, "unset", &is_list/1, []),
+         {:ok, gateway_ids} <- optional(params, "gateway_ids", &is_list/1, []) do
+      {:ok,
+       %{
+         gateway_ids: gateway_ids,
+         all: Map.get(params, "all") in [true, "true"],
+         partition: Map.get(params, "partition"),
+         dry_run: Map.get(params, "dry_run") in [true, "true"],
+         reason: Map.get(params, "reason"),
+         patch: %{set: set, unset: unset}
+       }}
+    end
+  end
+
+  defp bulk_request(_params), do: {:error, "patch is required"}
+
+  defp optional(map, key, valid?, default) do
+    case Map.get(map, key) do
+      nil -> {:ok, default}
+      value -> if valid?.(value), do: {:ok, value}, else: {:error, "invalid #{key}"}
+    end
+  end
+
+  defp actor_name(user) do
+    case Map.get(user, :email) do
+      nil -> to_string(Map.get(user, :id))
+      email -> to_string(email)
+    end
+  end
+
+  defp require_authenticated(conn) do
+    case conn.assigns[:current_scope] do
+      %Scope{user: user} when not is_nil(user) -> {:ok, user}
+      _ -> {:error, :unauthorized}
+    end
+  end
+
+  defp require_permission(conn, permission) when is_binary(permission) do
+    scope = conn.assigns[:current_scope]
+    if RBAC.can?(scope, permission), do: :ok, else: {:error, :forbidden}
+  end
+end
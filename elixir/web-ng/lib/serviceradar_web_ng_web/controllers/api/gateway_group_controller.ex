@@ -0,0 +1,90 @@
+defmodule ServiceRadarWebNGWeb.Api.GatewayGroupController do
+  @moduledoc """
+  API for gateway site/tier groups.
+
+  `GET /api/admin/gateway-groups` returns the per-group health rollup,
+  optionally for one `group`. While the core is in degraded mode the rollup
+  is built from last-known gateway state and flagged `stale`.
+
+  `PUT /api/admin/gateways/:gateway_id/group` assigns a gateway to a group;
+  an empty or missing `group` clears the assignment.
+  """
+
+  use ServiceRadarWebNGWeb, :controller
+
+  alias ServiceRadar.Infrastructure.DegradedMode
+  alias ServiceRadar.Infrastructure.GatewayGroups
+  alias ServiceRadarWebNG.Accounts.Scope
+  alias ServiceRadarWebNG.RBAC
+
+  action_fallback(ServiceRadarWebNGWeb.Api.FallbackController)
+
+  @doc """
+  GET /api/admin/gateway-groups
+  """
+  def index(conn, params) do
+    with :ok <- require_authenticated(conn),
+         :ok <- require_permission(conn, "settings.view"),
+         {:ok, summaries} <- GatewayGroups.rollup(group: Map.get(params, "group")) do
+      degraded = DegradedMode.state()
+
+      conn
+      |> put_stale_headers(degraded)
+      |> json(%{
+        data: %{groups: summaries, stale: degraded.degraded, stale_since: degraded.since}
+      })
+    end
+  end
+
+  @doc """
+  PUT /api/admin/gateways/:gateway_id/group
+  """
+  def assign(conn, %{"gateway_id" => gateway_id} = params) do
+    group = Map.get(params, "group")
+
+    with :ok <- require_authenticated(conn),
+         :ok <- require_permission(conn, "settings.edge.manage"),
+         :ok <- validate_group(group),
+         :ok <- GatewayGroups.assign(gateway_id, group) do
+      json(conn, %{
+        data: %{
+          gateway_id: gateway_id,
+          group: GatewayGroups.group_for(%{metadata: %{"group" => group}})
+        }
+      })
+    else
+      {:error, :invalid_request, message} ->
+        conn |> put_status(:bad_request) |> json(%{error: "invalid_request", message: message})
+
+      {:error, message} when is_binary(message) ->
+        conn |> put_status(:bad_request) |> json(%{error: "invalid_request", message: message})
+
+      {:error, other} ->
+        {:error, other}
+    end
+  end
+
+  defp put_stale_headers(conn, %{degraded: true, since: since}) do
+    conn
+    |> put_resp_header("x-serviceradar-stale", "true")
+    |> put_resp_header("x-serviceradar-stale-since", DateTime.to_iso8601(since))
+  end
+
+  defp put_stale_headers(conn, _state), do: conn
+
+  defp validate_group(nil), do: :ok
+  defp validate_group(group) when is_binary(group), do: :ok
+  defp validate_group(_group), do: {:error, :invalid_request, "group must be a string"}
+
+  defp require_authenticated(conn) do
+    case conn.assigns[:current_scope] do
+      %Scope{user: user} when not is_nil(user) -> :ok
+      _ -> {:error, :unauthorized}
+    end
+  end
+
+  defp require_permission(conn, permission) when is_binary(permission) do
+    scope = conn.assigns[:current_scope]
+    if RBAC.can?(scope, permission), do: :ok, else: {:error, :forbidden}
+  end
+end
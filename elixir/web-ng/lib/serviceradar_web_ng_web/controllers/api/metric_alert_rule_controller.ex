@@ -0,0 +1,140 @@
+defmodule ServiceRadarWebNGWeb.Api.MetricAlertRuleController do
+  @moduledoc """
+  API for metric alert rules: SRQL metric conditions that raise an alert
+  once they hold for a configured duration.
+  """
+
+  use ServiceRadarWebNGWeb, :controller
+
+  alias ServiceRadar.Observability.MetricAlertRule
+  alias ServiceRadarWebNG.Accounts.Scope
+
+  require Ash.Query
+
+  @rule_params ~w(name description enabled query value_field group_by comparison threshold
+                  duration_seconds severity)
+
+  @doc """
+  Lists metric alert rules by name.
+  """
+  def index(conn, _params) do
+    rules =
+      MetricAlertRule
+      |> Ash.Query.sort(name: :asc)
+      |> Ash.read!(scope: get_scope(conn))
+
+    json(conn, %{"data" => Enum.map(rules, &rule_to_map/1)})
+  end
+
+  @doc """
+  Creates a rule.
+
+  Body:
+
+      {"name": "cpu-high-site-a",
+       "query": "in:cpu_metrics partition:site-a",
+       "value_field": "usage_percent",
+       "group_by": "device_id",
+       "comparison": "gt" | "gte" | "lt" | "lte",
+       "threshold": 90,
+       "duration_seconds": 300,
+       "severity": "info" | "warning" | "critical" | "emergency"}
+  """
+  def create(conn, params) do
+    case MetricAlertRule.create(Map.take(params, @rule_params), actor: get_actor(conn)) do
+      {:ok, rule} ->
+        conn
+        |> put_status(:created)
+        |> json(%{"data" => rule_to_map(rule)})
+
+      {:error, reason} ->
+        error(conn, reason)
+    end
+  end
+
+  @doc """
+  Updates a rule. Accepts the same fields as `create/2`.
+  """
+  def update(conn, %{"id" => id} = params) do
+    actor = get_actor(conn)
+
+    attrs = Map.take(params, @rule_params)
+
+    with {:ok, rule} <- MetricAlertRule.get_by_id(id, actor: actor),
+         {:ok, rule} <- MetricAlertRule.update(rule, attrs, actor: actor) do
+      json(conn, %{"data" => rule_to_map(rule)})
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  @doc """
+  Deletes a rule. Alerts it raised are left as they are.
+  """
+  def delete(conn, %{"id" => id}) do
+    actor = get_actor(conn)
+
+    with {:ok, rule} <- MetricAlertRule.get_by_id(id, actor: actor),
+         :ok <- MetricAlertRule.destroy(rule, actor: actor) do
+      send_resp(conn, :no_content, "")
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  defp rule_to_map(rule) do
+    %{
+      "id" => rule.id,
+      "name" => rule.name,
+      "description" => rule.description,
+      "enabled" => rule.enabled,
+      "query" => rule.query,
+      "value_field" => rule.value_field,
+      "group_by" => rule.group_by,
+      "comparison" => rule.comparison,
+      "threshold" => rule.threshold,
+      "duration_seconds" => rule.duration_seconds,
+      "severity" => rule.severity,
+      "updated_at" => rule.updated_at
+    }
+  end
+
+  defp get_scope(conn) do
+    conn.assigns[:current_scope]
+  end
+
+  defp get_actor(conn) do
+    case get_scope(conn) do
+      %Scope{user: user} when not is_nil(user) -> conn.assigns[:ash_actor] || user
+      _ -> nil
+    end
+  end
+
+  defp error(conn, %Ash.Error.Forbidden{}) do
+    conn
+    |> put_status(:forbidden)
+    |> json(%{"error" => "forbidden"})
+  end
+
+  defp error(conn, %Ash.Error.Invalid{errors: errors} = invalid) do
+    if Enum.any?(errors, &match?(%Ash.Error.Query.NotFound{}, &1)) do
+      error(conn, %Ash.Error.Query.NotFound{})
+    else
+      conn
+      |> put_status(:unprocessable_entity)
+      |> json(%{"error" => Exception.message(invalid)})
+    end
+  end
+
+  defp error(conn, %Ash.Error.Query.NotFound{}) do
+    conn
+    |> put_status(:not_found)
+    |> json(%{"error" => "metric alert rule not found"})
+  end
+
+  defp error(conn, _reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => "metric alert rule request failed"})
+  end
+end
@@ -0,0 +1,114 @@
+defmodule ServiceRadarWebNGWeb.Api.PartitionRetentionController do
+  @moduledoc """
+  API for per-partition data retention windows.
+  """
+
+  use ServiceRadarWebNGWeb, :controller
+
+  alias ServiceRadar.Observability.PartitionRetention
+  alias ServiceRadar.Observability.PartitionRetentionPolicy
+  alias ServiceRadarWebNG.Accounts.Scope
+
+  require Ash.Query
+
+  @doc """
+  Lists the configured partition windows and the global maximum.
+  """
+  def index(conn, _params) do
+    policies =
+      PartitionRetentionPolicy
+      |> Ash.Query.sort(partition: :asc)
+      |> Ash.read!(scope: get_scope(conn))
+
+    json(conn, %{
+      "data" => Enum.map(policies, &policy_to_map/1),
+      "max_retention_days" => PartitionRetention.max_retention_days()
+    })
+  end
+
+  @doc """
+  Creates or updates the window for a partition.
+
+  Body: `{"retention_days": 90, "enabled": true}`
+  """
+  def upsert(conn, %{"partition" => partition} = params) do
+    actor = get_actor(conn)
+    attrs = Map.take(params, ["retention_days", "enabled"])
+
+    result =
+      case PartitionRetentionPolicy.get_by_partition(partition, actor: actor) do
+        {:ok, policy} ->
+          PartitionRetentionPolicy.update(policy, attrs, actor: actor)
+
+        {:error, _} ->
+          PartitionRetentionPolicy.create(Map.put(attrs, "partition", partition), actor: actor)
+      end
+
+    case result do
+      {:ok, policy} -> json(conn, %{"data" => policy_to_map(policy)})
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  @doc """
+  Removes a partition's window; the partition falls back to table-level retention.
+  """
+  def delete(conn, %{"partition" => partition}) do
+    actor = get_actor(conn)
+
+    with {:ok, policy} <- PartitionRetentionPolicy.get_by_partition(partition, actor: actor),
+         :ok <- PartitionRetentionPolicy.destroy(policy, actor: actor) do
+      send_resp(conn, :no_content, "")
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  defp policy_to_map(policy) do
+    %{
+      "partition" => policy.partition,
+      "retention_days" => policy.retention_days,
+      "enabled" => policy.enabled,
+      "updated_at" => policy.updated_at
+    }
+  end
+
+  defp get_scope(conn) do
+    conn.assigns[:current_scope]
+  end
+
+  defp get_actor(conn) do
+    case get_scope(conn) do
+      %Scope{user: user} when not is_nil(user) -> conn.assigns[:ash_actor] || user
+      _ -> nil
+    end
+  end
+
+  defp error(conn, %Ash.Error.Forbidden{}) do
+    conn
+    |> put_status(:forbidden)
+    |> json(%{"error" => "forbidden"})
+  end
+
+  defp error(conn, %Ash.Error.Invalid{errors: errors} = invalid) do
+    if Enum.any?(errors, &match?(%Ash.Error.Query.NotFound{}, &1)) do
+      error(conn, %Ash.Error.Query.NotFound{})
+    else
+      conn
+      |> put_status(:unprocessable_entity)
+      |> json(%{"error" => Exception.message(invalid)})
+    end
+  end
+
+  defp error(conn, %Ash.Error.Query.NotFound{}) do
+    conn
+    |> put_status(:not_found)
+    |> json(%{"error" => "no retention policy for partition"})
+  end
+
+  defp error(conn, _reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => "retention policy request failed"})
+  end
+end
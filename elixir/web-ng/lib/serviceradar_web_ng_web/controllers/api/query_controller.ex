@@ -1,14 +1,42 @@
 defmodule ServiceRadarWebNGWeb.Api.QueryController do
   use ServiceRadarWebNGWeb, :controller
 
+  alias ServiceRadarWebNG.SRQL.Native
+  alias ServiceRadarWebNGWeb.QueryQuota
+
+  @protobuf_content_type "application/x-protobuf"
+
   def execute(conn, params) do
+    partition_id = conn.assigns[:current_partition_id]
+
+    case QueryQuota.acquire(partition_id) do
+      :ok ->
+        try do
+          run_query(conn, params)
+        after
+          QueryQuota.release(partition_id)
+        end
+
+      {:error, reason, retry_after} ->
+        quota_exceeded(conn, reason, retry_after)
+    end
+  end
+
+  defp run_query(conn, params) do
     # Get actor from current_scope for Ash policy enforcement
     actor = get_actor(conn)
-    params_with_actor = Map.put(params, "actor", actor)
+
+    # The partition scope comes from authentication, never from the request
+    # body, since it picks the backend the query runs on.
+    params_with_actor =
+      params
+      |> Map.put("actor", actor)
+      |> Map.put("uncapped", admin_key_request?(conn))
+      |> Map.put("partition", conn.assigns[:current_partition_id])
 
     case srql_module().query_request(params_with_actor) do
       {:ok, response} ->
-        json(conn, response)
+        respond(conn, response)
 
       {:error, reason} ->
         conn
@@ -17,6 +45,53 @@ defmodule ServiceRadarWebNGWeb.Api.QueryController do
     end
   end
 
+  # Clients asking for protobuf get the same srql.QueryResponse message the
+  # standalone SRQL server sends; everything else, errors included, is JSON.
+  defp respond(conn, response) do
+    if protobuf_accepted?(conn) do
+      case Native.encode_query_response_protobuf(Jason.encode!(response)) do
+        {:ok, payload} ->
+          conn
+          |> put_resp_content_type(@protobuf_content_type, nil)
+          |> send_resp(200, payload)
+
+        {:error, reason} ->
+          conn
+          |> put_status(:internal_server_error)
+          |> json(%{"error" => to_string(reason)})
+      end
+    else
+      json(conn, response)
+    end
+  end
+
+  defp protobuf_accepted?(conn) do
+    conn
+    |> get_req_header("accept")
+    |> Enum.flat_map(&String.split(&1, ","))
+    |> Enum.any?(fn media_type ->
+      media_type
+      |> String.split(";", parts: 2)
+      |> hd()
+      |> String.trim()
+      |> String.downcase()
+      |> Kernel.==(@protobuf_content_type)
+    end)
+  end
+
+  defp quota_exceeded(conn, reason, retry_after) do
+    message =
+      case reason do
+        :rate_limited -> "query rate limit exceeded for partition"
+        :concurrency_limited -> "too many concurrent queries for partition"
+      end
+
+    conn
+    |> put_resp_header("retry-after", to_string(retry_after))
+    |> put_status(:too_many_requests)
+    |> json(%{"error" => message, "retry_after" => retry_after})
+  end
+
   # Extract actor (user) from connection for Ash policy enforcement
   defp get_actor(conn) do
     case conn.assigns do
@@ -25,6 +100,22 @@ defmodule ServiceRadarWebNGWeb.Api.QueryController do
     end
   end
 
+  # Requests presenting the configured SRQL admin key bypass the result cap.
+  defp admin_key_request?(conn) do
+    expected =
+      :serviceradar_web_ng
+      |> Application.get_env(:srql_result_cap, [])
+      |> Keyword.get(:admin_api_key)
+
+    case {expected, get_req_header(conn, "x-srql-admin-key")} do
+      {expected, [provided | _]} when is_binary(expected) and expected != "" ->
+        Plug.Crypto.secure_compare(String.trim(provided), expected)
+
+      _ ->
+        false
+    end
+  end
+
   defp srql_module do
     Application.get_env(:serviceradar_web_ng, :srql_module, ServiceRadarWebNG.SRQL)
   end
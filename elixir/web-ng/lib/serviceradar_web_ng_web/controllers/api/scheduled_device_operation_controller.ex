@@ -0,0 +1,227 @@
+defmodule ServiceRadarWebNGWeb.Api.ScheduledDeviceOperationController do
+  @moduledoc """
+  API for scheduling bulk device operations for a later maintenance window,
+  listing them and cancelling them before they run.
+  """
+
+  use ServiceRadarWebNGWeb, :controller
+
+  alias ServiceRadar.Inventory.ScheduledDeviceOperation
+  alias ServiceRadar.Inventory.ScheduledDeviceOperations
+  alias ServiceRadarWebNG.Accounts.Scope
+  alias ServiceRadarWebNG.RBAC
+
+  require Ash.Query
+
+  @default_limit 100
+  @max_limit 500
+  @operations %{
+    "decommission" => :decommission,
+    "add_tags" => :add_tags,
+    "remove_tags" => :remove_tags
+  }
+  @statuses %{
+    "scheduled" => :scheduled,
+    "running" => :running,
+    "succeeded" => :succeeded,
+    "failed" => :failed,
+    "cancelled" => :cancelled
+  }
+
+  @doc """
+  Lists scheduled operations, soonest run time first.
+
+  Query params:
+  - status: scheduled, running, succeeded, failed, cancelled (default: all)
+  - limit: max results (default 100, max 500)
+  """
+  def index(conn, params) do
+    with {:ok, status} <- parse_status(Map.get(params, "status")),
+         {:ok, limit} <- parse_limit(Map.get(params, "limit")) do
+      operations =
+        ScheduledDeviceOperation
+        |> Ash.Query.sort(run_at: :asc)
+        |> Ash.Query.limit(limit)
+        |> maybe_filter_status(status)
+        |> Ash.read!(scope: get_scope(conn))
+
+      json(conn, %{"data" => Enum.map(operations, &operation_to_map/1)})
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  @doc """
+  Schedules an operation.
+
+  Body:
+
+      {"operation": "decommission" | "add_tags" | "remove_tags",
+       "run_at": "2026-11-01T02:00:00Z",
+       "device_uids": [...] | "cidr": "10.20.0.0/24",
+       "partition": "default",
+       "params": {"reason": "..."} | {"tags": {...}} | {"keys": [...]}}
+  """
+  def create(conn, params) do
+    with :ok <- require_permission(conn, "devices.bulk_edit"),
+         {:ok, operation} <- parse_operation(Map.get(params, "operation")),
+         :ok <- require_permission(conn, ScheduledDeviceOperations.required_permission(operation)),
+         {:ok, run_at} <- parse_run_at(Map.get(params, "run_at")),
+         {:ok, scheduled} <-
+           ScheduledDeviceOperations.schedule(
+             %{
+               operation: operation,
+               run_at: run_at,
+               device_uids: List.wrap(Map.get(params, "device_uids")),
+               cidr: Map.get(params, "cidr"),
+               partition: Map.get(params, "partition"),
+               params: Map.get(params, "params") || %{}
+             },
+             actor: get_actor(conn)
+           ) do
+      conn
+      |> put_status(:created)
+      |> json(%{"data" => operation_to_map(scheduled)})
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  @doc """
+  Cancels an operation that has not started yet.
+  """
+  def cancel(conn, %{"id" => id}) do
+    actor = get_actor(conn)
+
+    with :ok <- require_permission(conn, "devices.bulk_edit"),
+         {:ok, id} <- parse_id(id),
+         {:ok, operation} <- Ash.get(ScheduledDeviceOperation, id, actor: actor),
+         {:ok, cancelled} <- ScheduledDeviceOperations.cancel(operation, actor: actor) do
+      json(conn, %{"data" => operation_to_map(cancelled)})
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  defp parse_operation(value) when is_binary(value) do
+    case Map.fetch(@operations, String.downcase(String.trim(value))) do
+      {:ok, operation} -> {:ok, operation}
+      :error -> {:error, "operation must be decommission, add_tags or remove_tags"}
+    end
+  end
+
+  defp parse_operation(_value), do: {:error, "missing required field: operation"}
+
+  defp parse_run_at(value) when is_binary(value) do
+    case DateTime.from_iso8601(value) do
+      {:ok, run_at, _offset} -> {:ok, run_at}
+      {:error, _} -> {:error, "run_at must be an ISO8601 timestamp"}
+    end
+  end
+
+  defp parse_run_at(_value), do: {:error, "missing required field: run_at"}
+
+  defp parse_status(value) when value in [nil, "", "all"], do: {:ok, nil}
+
+  defp parse_status(value) when is_binary(value) do
+    case Map.fetch(@statuses, value) do
+      {:ok, status} -> {:ok, status}
+      :error -> {:error, "invalid status: #{value}"}
+    end
+  end
+
+  defp parse_status(_value), do: {:error, "invalid status"}
+
+  defp parse_limit(nil), do: {:ok, @default_limit}
+  defp parse_limit(""), do: {:ok, @default_limit}
+
+  defp parse_limit(value) when is_binary(value) do
+    case Integer.parse(value) do
+      {limit, ""} when limit > 0 -> {:ok, min(limit, @max_limit)}
+      _ -> {:error, "invalid limit"}
+    end
+  end
+
+  defp parse_limit(_value), do: {:error, "invalid limit"}
+
+  defp parse_id(value) do
+    case Ecto.UUID.cast(value) do
+      {:ok, id} -> {:ok, id}
+      :error -> {:error, "invalid operation id"}
+    end
+  end
+
+  defp maybe_filter_status(query, nil), do: query
+  defp maybe_filter_status(query, status), do: Ash.Query.filter(query, status == ^status)
+
+  defp operation_to_map(operation) do
+    %{
+      "id" => operation.id,
+      "operation" => operation.operation,
+      "status" => operation.status,
+      "run_at" => operation.run_at,
+      "device_uids" => operation.device_uids,
+      "cidr" => operation.cidr,
+      "partition" => operation.partition,
+      "params" => operation.params,
+      "requested_by" => operation.requested_by,
+      "cancelled_at" => operation.cancelled_at,
+      "cancelled_by" => operation.cancelled_by,
+      "started_at" => operation.started_at,
+      "completed_at" => operation.completed_at,
+      "result" => operation.result,
+      "error" => operation.error
+    }
+  end
+
+  defp get_scope(conn) do
+    conn.assigns[:current_scope]
+  end
+
+  defp get_actor(conn) do
+    case get_scope(conn) do
+      %Scope{user: user} when not is_nil(user) -> conn.assigns[:ash_actor] || user
+      _ -> nil
+    end
+  end
+
+  defp require_permission(conn, permission) do
+    if RBAC.can?(get_scope(conn), permission), do: :ok, else: {:error, :forbidden}
+  end
+
+  defp error(conn, :forbidden) do
+    conn
+    |> put_status(:forbidden)
+    |> json(%{"error" => "forbidden"})
+  end
+
+  defp error(conn, %Ash.Error.Forbidden{}), do: error(conn, :forbidden)
+
+  defp error(conn, %Ash.Error.Query.NotFound{}) do
+    conn
+    |> put_status(:not_found)
+    |> json(%{"error" => "operation not found"})
+  end
+
+  defp error(conn, %Ash.Error.Invalid{errors: errors} = invalid) do
+    if Enum.any?(errors, &match?(%Ash.Error.Query.NotFound{}, &1)) do
+      error(conn, %Ash.Error.Query.NotFound{})
+    else
+      conn
+      |> put_status(:unprocessable_entity)
+      |> json(%{"error" => Exception.message(invalid)})
+    end
+  end
+
+  defp error(conn, reason) when is_binary(reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => reason})
+  end
+
+  defp error(conn, _reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => "scheduled operation request failed"})
+  end
+end
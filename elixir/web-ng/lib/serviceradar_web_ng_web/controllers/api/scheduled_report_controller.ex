@@ -0,0 +1,143 @@
+defmodule ServiceRadarWebNGWeb.Api.ScheduledReportController do
+  @moduledoc """
+  API for scheduled reports: SRQL queries run on a cron schedule and
+  delivered as CSV, JSON or PDF by email, webhook or object storage.
+  """
+
+  use ServiceRadarWebNGWeb, :controller
+
+  alias ServiceRadar.Observability.ScheduledReport
+  alias ServiceRadar.Observability.ScheduledReports
+  alias ServiceRadarWebNG.Accounts.Scope
+
+  require Ash.Query
+
+  @report_params ~w(name description query cron format delivery target partition enabled)
+
+  @doc """
+  Lists scheduled reports by name.
+  """
+  def index(conn, _params) do
+    reports =
+      ScheduledReport
+      |> Ash.Query.sort(name: :asc)
+      |> Ash.read!(scope: get_scope(conn))
+
+    json(conn, %{"data" => Enum.map(reports, &report_to_map/1)})
+  end
+
+  @doc """
+  Creates a report. It runs with the creating user's permissions.
+
+  Body:
+
+      {"name": "weekly-high-risk",
+       "query": "in:devices risk_level:high",
+       "cron": "0 6 * * 1",
+       "format": "csv" | "json" | "pdf",
+       "delivery": "email" | "webhook" | "object_store",
+       "target": {"recipients": [...]} | {"url": "https://..."} | {"prefix": "..."},
+       "partition": "default"}
+  """
+  def create(conn, params) do
+    case ScheduledReports.create(Map.take(params, @report_params), actor: get_actor(conn)) do
+      {:ok, report} ->
+        conn
+        |> put_status(:created)
+        |> json(%{"data" => report_to_map(report)})
+
+      {:error, reason} ->
+        error(conn, reason)
+    end
+  end
+
+  @doc """
+  Updates a report. Accepts the same fields as `create/2`.
+  """
+  def update(conn, %{"id" => id} = params) do
+    actor = get_actor(conn)
+
+    with {:ok, report} <- ScheduledReport.get_by_id(id, actor: actor),
+         {:ok, report} <-
+           ScheduledReports.update(report, Map.take(params, @report_params), actor: actor) do
+      json(conn, %{"data" => report_to_map(report)})
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  @doc """
+  Deletes a report.
+  """
+  def delete(conn, %{"id" => id}) do
+    actor = get_actor(conn)
+
+    with {:ok, report} <- ScheduledReport.get_by_id(id, actor: actor),
+         :ok <- ScheduledReport.destroy(report, actor: actor) do
+      send_resp(conn, :no_content, "")
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  defp report_to_map(report) do
+    %{
+      "id" => report.id,
+      "name" => report.name,
+      "description" => report.description,
+      "query" => report.query,
+      "cron" => report.cron,
+      "format" => report.format,
+      "delivery" => report.delivery,
+      "target" => report.target,
+      "partition" => report.partition,
+      "enabled" => report.enabled,
+      "requested_by" => report.requested_by,
+      "next_run_at" => report.next_run_at,
+      "last_run_at" => report.last_run_at,
+      "last_status" => report.last_status,
+      "last_error" => report.last_error,
+      "last_row_count" => report.last_row_count,
+      "updated_at" => report.updated_at
+    }
+  end
+
+  defp get_scope(conn) do
+    conn.assigns[:current_scope]
+  end
+
+  defp get_actor(conn) do
+    case get_scope(conn) do
+      %Scope{user: user} when not is_nil(user) -> conn.assigns[:ash_actor] || user
+      _ -> nil
+    end
+  end
+
+  defp error(conn, %Ash.Error.Forbidden{}) do
+    conn
+    |> put_status(:forbidden)
+    |> json(%{"error" => "forbidden"})
+  end
+
+  defp error(conn, %Ash.Error.Invalid{errors: errors} = invalid) do
+    if Enum.any?(errors, &match?(%Ash.Error.Query.NotFound{}, &1)) do
+      error(conn, %Ash.Error.Query.NotFound{})
+    else
+      conn
+      |> put_status(:unprocessable_entity)
+      |> json(%{"error" => Exception.message(invalid)})
+    end
+  end
+
+  defp error(conn, %Ash.Error.Query.NotFound{}) do
+    conn
+    |> put_status(:not_found)
+    |> json(%{"error" => "scheduled report not found"})
+  end
+
+  defp error(conn, _reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => "scheduled report request failed"})
+  end
+end
@@ -0,0 +1,128 @@
+defmodule ServiceRadarWebNGWeb.Api.SRQLViewController do
+  @moduledoc """
+  API for SRQL views: named queries that can be queried like an entity with
+  `in:<name>`.
+  """
+
+  use ServiceRadarWebNGWeb, :controller
+
+  alias ServiceRadar.Observability.SRQLView
+  alias ServiceRadarWebNG.Accounts.Scope
+
+  require Ash.Query
+
+  @view_params ~w(name description query partition)
+
+  @doc """
+  Lists views by name.
+  """
+  def index(conn, _params) do
+    views =
+      SRQLView
+      |> Ash.Query.sort(name: :asc)
+      |> Ash.read!(scope: get_scope(conn))
+
+    json(conn, %{"data" => Enum.map(views, &view_to_map/1)})
+  end
+
+  @doc """
+  Creates a view.
+
+  Body:
+
+      {"name": "linux_high_risk",
+       "query": "in:devices risk_level:high os:%linux%",
+       "description": "...",
+       "partition": "default"}
+  """
+  def create(conn, params) do
+    case SRQLView.create(Map.take(params, @view_params), actor: get_actor(conn)) do
+      {:ok, view} ->
+        conn
+        |> put_status(:created)
+        |> json(%{"data" => view_to_map(view)})
+
+      {:error, reason} ->
+        error(conn, reason)
+    end
+  end
+
+  @doc """
+  Updates a view. Accepts the same fields as `create/2`.
+  """
+  def update(conn, %{"id" => id} = params) do
+    actor = get_actor(conn)
+
+    with {:ok, view} <- SRQLView.get_by_id(id, actor: actor),
+         {:ok, view} <- SRQLView.update(view, Map.take(params, @view_params), actor: actor) do
+      json(conn, %{"data" => view_to_map(view)})
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  @doc """
+  Deletes a view. Views built on it stop resolving.
+  """
+  def delete(conn, %{"id" => id}) do
+    actor = get_actor(conn)
+
+    with {:ok, view} <- SRQLView.get_by_id(id, actor: actor),
+         :ok <- SRQLView.destroy(view, actor: actor) do
+      send_resp(conn, :no_content, "")
+    else
+      {:error, reason} -> error(conn, reason)
+    end
+  end
+
+  defp view_to_map(view) do
+    %{
+      "id" => view.id,
+      "name" => view.name,
+      "description" => view.description,
+      "query" => view.query,
+      "partition" => view.partition,
+      "created_by" => view.created_by,
+      "updated_at" => view.updated_at
+    }
+  end
+
+  defp get_scope(conn) do
+    conn.assigns[:current_scope]
+  end
+
+  defp get_actor(conn) do
+    case get_scope(conn) do
+      %Scope{user: user} when not is_nil(user) -> conn.assigns[:ash_actor] || user
+      _ -> nil
+    end
+  end
+
+  defp error(conn, %Ash.Error.Forbidden{}) do
+    conn
+    |> put_status(:forbidden)
+    |> json(%{"error" => "forbidden"})
+  end
+
+  defp error(conn, %Ash.Error.Invalid{errors: errors} = invalid) do
+    if Enum.any?(errors, &match?(%Ash.Error.Query.NotFound{}, &1)) do
+      error(conn, %Ash.Error.Query.NotFound{})
+    else
+      conn
+      |> put_status(:unprocessable_entity)
+      |> json(%{"error" => Exception.message(invalid)})
+    end
+  end
+
+  defp error(conn, %Ash.Error.Query.NotFound{}) do
+    conn
+    |> put_status(:not_found)
+    |> json(%{"error" => "view not found"})
+  end
+
+  defp error(conn, _reason) do
+    conn
+    |> put_status(:bad_request)
+    |> json(%{"error" => "view request failed"})
+  end
+end
@@ -0,0 +1,116 @@
+defmodule ServiceRadarWebNGWeb.Api.StaleCheckerConfigController do
+  @moduledoc """
+  Admin API for retiring KV checker configs left behind by removed agents.
+
+  `GET` previews the stale `agents/<id>/checkers/*` keys. `POST .../retire`
+  deletes them, or previews only when `dry_run` is true.
+  """
+
+  use ServiceRadarWebNGWeb, :controller
+
+  alias ServiceRadar.Edge.StaleCheckerConfigs
+  alias ServiceRadarWebNG.Accounts.Scope
+  alias ServiceRadarWebNG.RBAC
+
+  action_fallback(ServiceRadarWebNGWeb.Api.FallbackController)
+
+  @doc """
+  GET /api/admin/kv/stale-checker-configs
+  """
+  def index(conn, params) do
+    run(conn, params, true)
+  end
+
+  @doc """
+  POST /api/admin/kv/stale-checker-configs/retire
+  """
+  def retire(conn, params) do
+    run(conn, params, normalize_boolean(Map.get(params, "dry_run"), false))
+  end
+
+  defp run(conn, params, dry_run) do
+    with :ok <- require_authenticated(conn),
+         :ok <- require_permission(conn, "settings.edge.manage"),
+         {:ok, opts} <- retire_opts(params, dry_run),
+         {:ok, result} <- stale_checker_configs().retire(opts) do
+      json(conn, %{data: result_json(result)})
+    else
+      {:error, :invalid_request, message} ->
+        conn |> put_status(:bad_request) |> json(%{error: "invalid_request", message: message})
+
+      {:error, %GRPC.RPCError{} = error} ->
+        conn
+        |> put_status(:bad_gateway)
+        |> json(%{error: "kv_unavailable", message: GRPC.RPCError.message(error)})
+
+      {:error, other} ->
+        {:error, other}
+    end
+  end
+
+  defp retire_opts(params, dry_run) do
+    case Map.get(params, "stale_after_seconds") do
+      nil ->
+        {:ok, [dry_run: dry_run]}
+
+      value ->
+        case parse_positive_integer(value) do
+          {:ok, seconds} ->
+            {:ok, [dry_run: dry_run, stale_after_seconds: seconds]}
+
+          :error ->
+            {:error, :invalid_request, "stale_after_seconds must be a positive integer"}
+        end
+    end
+  end
+
+  defp parse_positive_integer(value) when is_integer(value) and value > 0, do: {:ok, value}
+
+  defp parse_positive_integer(value) when is_binary(value) do
+    case Integer.parse(String.trim(value)) do
+      {int, ""} when int > 0 -> {:ok, int}
+      _ -> :error
+    end
+  end
+
+  defp parse_positive_integer(_value), do: :error
+
+  defp normalize_boolean(true, _default), do: true
+  defp normalize_boolean(false, _default), do: false
+  defp normalize_boolean("true", _default), do: true
+  defp normalize_boolean("false", _default), do: false
+  defp normalize_boolean(_value, default), do: default
+
+  defp result_json(result) do
+    %{
+      dry_run: result.dry_run,
+      cutoff: DateTime.to_iso8601(result.cutoff),
+      key_count: result.key_count,
+      deleted_count: length(result.deleted_keys),
+      agents:
+        Enum.map(result.agents, fn agent ->
+          %{
+            agent_id: agent.agent_id,
+            last_seen: agent.last_seen && DateTime.to_iso8601(agent.last_seen),
+            keys: agent.keys
+          }
+        end)
+    }
+  end
+
+  defp stale_checker_configs do
+    Application.get_env(:serviceradar_web_ng, :stale_checker_configs, StaleCheckerConfigs)
+  end
+
+  defp require_authenticated(conn) do
+    case conn.assigns[:current_scope] do
+      %Scope{user: user} when not is_nil(user) -> :ok
+      _ -> {:error, :unauthorized}
+    end
+  end
+
+  defp require_permission(conn, permission) when is_binary(permission) do
+    scope = conn.assigns[:current_scope]
+    if RBAC.can?(scope, permission), do: :ok, else: {:error, :forbidden}
+  end
+end
@@ -5,6 +5,7 @@ defmodule ServiceRadarWebNGWeb.Api.TopologyController do
 
   use ServiceRadarWebNGWeb, :controller
 
+  alias ServiceRadar.NetworkDiscovery.PathTrace
   alias ServiceRadar.NetworkDiscovery.RouteAnalyzer
   alias ServiceRadarWebNG.Accounts.Scope
   alias ServiceRadarWebNG.RBAC
@@ -58,6 +59,76 @@ defmodule ServiceRadarWebNGWeb.Api.TopologyController do
     end
   end
 
+  @doc """
+  POST /api/admin/topology/path-trace
+
+  Traces the discovered topology path between two devices.
+
+  Body:
+    - source_device_id (string, required)
+    - destination_device_id (string, required)
+    - max_paths (integer, optional, default 1) - return up to this many paths, shortest first
+    - max_hops (integer, optional)
+  """
+  def path_trace(conn, params) do
+    with :ok <- require_authenticated(conn),
+         :ok <- require_permission(conn, "devices.view"),
+         {:ok, request} <- normalize_path_trace_request(params),
+         {:ok, result} <-
+           PathTrace.trace(
+             request.source_device_id,
+             request.destination_device_id,
+             max_paths: request.max_paths,
+             max_hops: request.max_hops
+           ) do
+      json(conn, %{result: result})
+    else
+      {:error, :invalid_request, message} ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{error: "invalid_request", message: message})
+
+      {:error, :no_path} ->
+        conn
+        |> put_status(:not_found)
+        |> json(%{error: "no_path", message: "no topology path between the devices"})
+
+      {:error, reason} when reason in [:unauthorized, :forbidden] ->
+        {:error, reason}
+
+      {:error, reason} ->
+        conn
+        |> put_status(:bad_request)
+        |> json(%{error: "path_trace_failed", reason: inspect(reason)})
+    end
+  end
+
+  defp normalize_path_trace_request(params) when is_map(params) do
+    source_device_id = params |> Map.get("source_device_id") |> normalize_non_empty_string()
+
+    destination_device_id =
+      params |> Map.get("destination_device_id") |> normalize_non_empty_string()
+
+    cond do
+      is_nil(source_device_id) ->
+        {:error, :invalid_request, "source_device_id is required"}
+
+      is_nil(destination_device_id) ->
+        {:error, :invalid_request, "destination_device_id is required"}
+
+      true ->
+        {:ok,
+         %{
+           source_device_id: source_device_id,
+           destination_device_id: destination_device_id,
+           max_paths: normalize_positive_integer(Map.get(params, "max_paths"), 1),
+           max_hops: normalize_max_hops(Map.get(params, "max_hops"))
+         }}
+    end
+  end
+
+  defp normalize_path_trace_request(_), do: {:error, :invalid_request, "request body is required"}
+
   defp normalize_route_analysis_request(params) when is_map(params) do
     source_device_id =
       params
@@ -100,16 +171,19 @@ defmodule ServiceRadarWebNGWeb.Api.TopologyController do
 
   defp normalize_non_empty_string(_), do: nil
 
-  defp normalize_max_hops(value) when is_integer(value) and value > 0, do: value
+  defp normalize_max_hops(value), do: normalize_positive_integer(value, 16)
+
+  defp normalize_positive_integer(value, _default) when is_integer(value) and value > 0,
+    do: value
 
-  defp normalize_max_hops(value) when is_binary(value) do
+  defp normalize_positive_integer(value, default) when is_binary(value) do
     case Integer.parse(String.trim(value)) do
       {parsed, ""} when parsed > 0 -> parsed
-      _ -> 16
+      _ -> default
     end
   end
 
-  defp normalize_max_hops(_), do: 16
+  defp normalize_positive_integer(_value, default), do: default
 
   defp route_analysis_json(result) when is_map(result) do
     result
@@ -8392,6 +8392,7 @@ defmodule ServiceRadarWebNGWeb.DeviceLive.Show do
               <.collection_badge enabled={@profile.collect_cpu} label="CPU" />
               <.collection_badge enabled={@profile.collect_memory} label="Memory" />
               <.collection_badge enabled={@profile.collect_disk} label="Disk" />
+              <.collection_badge enabled={@profile.collect_disk_io} label="Disk I/O" />
               <.collection_badge enabled={@profile.collect_network} label="Network" />
               <.collection_badge enabled={@profile.collect_processes} label="Processes" />
             </div>
@@ -464,6 +464,9 @@ defmodule ServiceRadarWebNGWeb.Settings.SysmonProfilesLive.Index do
                       Memory
                     </.ui_badge>
                     <.ui_badge :if={profile.collect_disk} variant="ghost" size="xs">Disk</.ui_badge>
+                    <.ui_badge :if={profile.collect_disk_io} variant="ghost" size="xs">
+                      Disk I/O
+                    </.ui_badge>
                     <.ui_badge :if={profile.collect_network} variant="ghost" size="xs">
                       Network
                     </.ui_badge>
@@ -767,7 +770,7 @@ defmodule ServiceRadarWebNGWeb.Settings.SysmonProfilesLive.Index do
             Metric Collectors
           </h3>
 
-          <div class="grid grid-cols-2 md:grid-cols-5 gap-4">
+          <div class="grid grid-cols-2 md:grid-cols-6 gap-4">
             <label class="flex items-center gap-2 cursor-pointer">
               <.input
                 type="checkbox"
@@ -792,6 +795,14 @@ defmodule ServiceRadarWebNGWeb.Settings.SysmonProfilesLive.Index do
               />
               <span class="label-text">Disk</span>
             </label>
+            <label class="flex items-center gap-2 cursor-pointer">
+              <.input
+                type="checkbox"
+                field={@form[:collect_disk_io]}
+                class="checkbox checkbox-primary checkbox-sm"
+              />
+              <span class="label-text">Disk I/O</span>
+            </label>
             <label class="flex items-center gap-2 cursor-pointer">
               <.input
                 type="checkbox"
@@ -153,6 +153,9 @@ defmodule ServiceRadarWebNGWeb.OpenAPI.AdminSpec do
       "/api/admin/topology/route-analysis" => %{
         "post" => op("Analyze topology route", "Topology", body: "AnyObject", response: "AnyObject")
       },
+      "/api/admin/topology/path-trace" => %{
+        "post" => op("Trace topology path between devices", "Topology", body: "AnyObject", response: "AnyObject")
+      },
       "/api/admin/edge-packages/defaults" => %{
         "get" => op("Get edge package defaults", "Edge", response: "AnyObject")
       },
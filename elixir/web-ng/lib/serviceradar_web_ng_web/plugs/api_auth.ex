@@ -7,7 +7,8 @@ defmodule ServiceRadarWebNGWeb.Plugs.ApiAuth do
   perform additional routing.
 
   Checks for authentication in the following order:
-  1. `Authorization: Bearer <token>` header (Guardian JWT session token or OAuth2 access token)
+  1. `Authorization: Bearer <token>` header (Guardian JWT session token, OAuth2 access
+     token, or OIDC ID token)
   2. `X-API-Key: <key>` header (Ash API token or legacy static key)
   3. `ws_token` query parameter for FieldSurvey WebSocket stream handshakes only
 
@@ -23,11 +24,22 @@ defmodule ServiceRadarWebNGWeb.Plugs.ApiAuth do
   - `:oauth_client_id` - The client UUID
   - `:oauth_token_scope` - The granted scopes as a space-separated string
 
+  ## OIDC ID Tokens
+
+  When OIDC SSO is enabled, a bearer token that is not a Guardian token is
+  verified as an ID token from the configured identity provider: signature
+  against the provider's JWKS, issuer, audience (the OIDC client id),
+  expiration and not-before. The user is then found or provisioned exactly
+  as on an OIDC login, so the configured role mappings apply to the token's
+  claims.
+
   ## Ash API Tokens
 
   API tokens created via the ServiceRadar.Identity.ApiToken resource are
   validated by hashing the provided token and comparing against stored hashes.
-  Tokens have scopes (read, write, admin) that determine permissions.
+  Tokens have scopes (read, write, admin) that determine permissions. A token
+  with an inactivity timeout that has gone unused past it is disabled and the
+  request is rejected.
 
   ## Legacy Configuration
 
@@ -43,9 +55,13 @@ defmodule ServiceRadarWebNGWeb.Plugs.ApiAuth do
 
   alias Ash.PlugHelpers
   alias ServiceRadar.Actors.SystemActor
+  alias ServiceRadar.Identity.ApiToken
   alias ServiceRadar.Identity.RBAC
   alias ServiceRadarWebNG.Accounts.Scope
   alias ServiceRadarWebNG.Auth.Guardian
+  alias ServiceRadarWebNGWeb.Auth.OIDCClient
+  alias ServiceRadarWebNGWeb.Auth.OIDCStrategy
+  alias ServiceRadarWebNGWeb.Auth.SSOProvisioning
   alias ServiceRadarWebNGWeb.ClientIP
 
   require Logger
@@ -132,8 +148,45 @@ defmodule ServiceRadarWebNGWeb.Plugs.ApiAuth do
   defp normalize_token(_token), do: nil
 
   defp validate_bearer_token(conn, token) do
-    # Validate Guardian JWT (user session tokens or API tokens)
-    validate_guardian_jwt(conn, token)
+    # Validate Guardian JWT (user session tokens or API tokens), then fall back
+    # to an ID token issued by the configured OIDC provider.
+    with {:error, :unauthorized} <- validate_guardian_jwt(conn, token) do
+      if OIDCStrategy.enabled?(),
+        do: validate_oidc_id_token(conn, token),
+        else: {:error, :unauthorized}
+    end
+  end
+
+  @doc """
+  Resolves the user for an ID token issued by the configured OIDC provider,
+  provisioning the user and applying role mappings as an OIDC login does.
+  """
+  @spec oidc_id_token_user(String.t()) :: {:ok, struct()} | {:error, term()}
+  def oidc_id_token_user(token) do
+    actor = SystemActor.system(:oidc_auth)
+
+    with {:ok, claims} <- OIDCClient.verify_bearer_token(token),
+         {:ok, user_info} <- OIDCClient.extract_user_info(claims) do
+      SSOProvisioning.find_or_create_user(user_info, claims, :oidc, actor)
+    end
+  end
+
+  defp validate_oidc_id_token(conn, token) do
+    case oidc_id_token_user(token) do
+      {:ok, user} ->
+        scope = Scope.for_user(user)
+
+        conn =
+          conn
+          |> assign_scope(scope, user)
+          |> assign(:oidc_id_token_auth, true)
+
+        {:ok, conn}
+
+      {:error, reason} ->
+        Logger.debug("OIDC ID token validation failed: #{inspect(reason)}")
+        {:error, :unauthorized}
+    end
   end
 
   defp validate_guardian_jwt(conn, token) do
@@ -191,6 +244,9 @@ defmodule ServiceRadarWebNGWeb.Plugs.ApiAuth do
       {:ok, conn} ->
         {:ok, conn}
 
+      {:error, :unauthorized} ->
+        {:error, :unauthorized}
+
       {:error, :not_found} ->
         # Fall back to legacy static API keys
         validate_legacy_api_key(conn, key)
@@ -204,26 +260,47 @@ defmodule ServiceRadarWebNGWeb.Plugs.ApiAuth do
 
     case find_api_token(token_hash, token_prefix) do
       {:ok, api_token} ->
-        # Record the usage
-        record_token_usage(api_token, conn)
-
-        # Create scope with the token's user
-        user = api_token.user
-        scope = Scope.for_user(user)
-
-        conn =
-          conn
-          |> assign_scope(scope, user)
-          |> assign(:api_token, api_token)
-          |> assign(:api_token_scope, api_token.scope)
-
-        {:ok, conn}
+        if ApiToken.inactive?(api_token) do
+          disable_inactive_token(api_token)
+          {:error, :unauthorized}
+        else
+          authorize_api_token(conn, api_token)
+        end
 
       {:error, :not_found} ->
         {:error, :not_found}
     end
   end
 
+  defp authorize_api_token(conn, api_token) do
+    # Record the usage
+    record_token_usage(api_token, conn)
+
+    # Create scope with the token's user
+    user = api_token.user
+    scope = Scope.for_user(user)
+
+    conn =
+      conn
+      |> assign_scope(scope, user)
+      |> assign(:api_token, api_token)
+      |> assign(:api_token_scope, api_token.scope)
+
+    {:ok, conn}
+  end
+
+  # The token went unused past its inactivity timeout: disable it so it stays
+  # rejected until an owner or admin re-enables it.
+  defp disable_inactive_token(api_token) do
+    actor = SystemActor.system(:api_auth)
+
+    Logger.warning("Disabling API token #{api_token.token_prefix} after inactivity")
+
+    api_token
+    |> Ash.Changeset.for_update(:disable_inactive, %{})
+    |> Ash.update(actor: actor, authorize?: false)
+  end
+
   defp record_token_usage(api_token, conn) do
     client_ip = ClientIP.get(conn)
     actor = SystemActor.system(:api_auth)
@@ -264,7 +341,7 @@ defmodule ServiceRadarWebNGWeb.Plugs.ApiAuth do
     require Ash.Query
 
     query =
-      ServiceRadar.Identity.ApiToken
+      ApiToken
       |> Ash.Query.filter(
         token_prefix == ^token_prefix and
           token_hash == ^token_hash and
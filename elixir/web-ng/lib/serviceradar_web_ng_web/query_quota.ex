@@ -0,0 +1,245 @@
+defmodule ServiceRadarWebNGWeb.QueryQuota do
+  @moduledoc """
+  Per-partition rate and concurrency quotas for the query API.
+
+  One partition's heavy dashboard usage should not starve the others, so each
+  partition gets its own budget of queries per minute and concurrent queries.
+  Requests without a partition scope share the `"default"` bucket.
+
+  ## Configuration
+
+      config :serviceradar_web_ng, :query_quota,
+        queries_per_minute: 600,
+        max_concurrent: 8,
+        partition_overrides: %{
+          "2f7c..." => %{queries_per_minute: 120, max_concurrent: 2}
+        }
+
+  A limit of `0` disables that check. Overrides replace the global value only
+  for the keys they set.
+
+  ## Usage
+
+      case QueryQuota.acquire(partition_id) do
+        :ok ->
+          try do
+            run_query()
+          after
+            QueryQuota.release(partition_id)
+          end
+
+        {:error, reason, retry_after} ->
+          reject(reason, retry_after)
+      end
+
+  Every decision is counted per partition (see `usage/2`) and emitted as a
+  `[:serviceradar, :query_quota, :check]` telemetry event.
+  """
+
+  use GenServer
+
+  @table :query_quota
+  @default_partition "default"
+  @window_seconds 60
+  @cleanup_interval to_timeout(minute: 1)
+
+  @default_queries_per_minute 600
+  @default_max_concurrent 8
+
+  @type reason :: :rate_limited | :concurrency_limited
+  @type limits :: %{queries_per_minute: non_neg_integer(), max_concurrent: non_neg_integer()}
+
+  ## Client API
+
+  def start_link(opts \\ []) do
+    GenServer.start_link(__MODULE__, opts, name: Keyword.get(opts, :name, __MODULE__))
+  end
+
+  @doc """
+  Reserves one query slot for the partition.
+
+  Returns `:ok` when the query may run, in which case the caller must call
+  `release/2` once it finishes, or `{:error, reason, retry_after_seconds}`.
+
+  ## Options
+
+  - `:limits` - Limits to enforce instead of the configured ones
+  - `:now` - Current time in seconds (for tests)
+  - `:table` - ETS table (for tests)
+  """
+  @spec acquire(String.t() | nil, keyword()) :: :ok | {:error, reason(), pos_integer()}
+  def acquire(partition_id, opts \\ []) do
+    table = Keyword.get(opts, :table, @table)
+    partition = partition_key(partition_id)
+    limits = Keyword.get_lazy(opts, :limits, fn -> limits_for(partition) end)
+    max_concurrent = limits.max_concurrent
+    now = Keyword.get_lazy(opts, :now, fn -> System.system_time(:second) end)
+
+    result =
+      with :ok <- reserve_concurrency(table, partition, max_concurrent),
+           :ok <- consume_rate(table, partition, limits.queries_per_minute, now) do
+        :ok
+      else
+        {:error, :rate_limited, _retry_after} = error when max_concurrent > 0 ->
+          release(partition_id, table: table)
+          error
+
+        error ->
+          error
+      end
+
+    record(table, partition, result)
+    result
+  end
+
+  @doc """
+  Returns a concurrency slot taken by a successful `acquire/2`.
+  """
+  @spec release(String.t() | nil, keyword()) :: :ok
+  def release(partition_id, opts \\ []) do
+    table = Keyword.get(opts, :table, @table)
+    key = {:in_flight, partition_key(partition_id)}
+
+    :ets.update_counter(table, key, {2, -1, 0, 0}, {key, 0})
+    :ok
+  end
+
+  @doc """
+  Returns the usage counters for one partition.
+  """
+  @spec usage(String.t() | nil, keyword()) :: map()
+  def usage(partition_id, opts \\ []) do
+    table = Keyword.get(opts, :table, @table)
+    partition = partition_key(partition_id)
+
+    %{
+      allowed: lookup(table, {:allowed, partition}),
+      rate_limited: lookup(table, {:rate_limited, partition}),
+      concurrency_limited: lookup(table, {:concurrency_limited, partition}),
+      in_flight: lookup(table, {:in_flight, partition})
+    }
+  end
+
+  @doc """
+  Resolves the limits for a partition: the global defaults merged with any
+  per-partition override.
+  """
+  @spec limits_for(String.t() | nil) :: limits()
+  def limits_for(partition_id) do
+    config = Application.get_env(:serviceradar_web_ng, :query_quota, [])
+
+    defaults = %{
+      queries_per_minute:
+        non_negative(Keyword.get(config, :queries_per_minute), @default_queries_per_minute),
+      max_concurrent: non_negative(Keyword.get(config, :max_concurrent), @default_max_concurrent)
+    }
+
+    config
+    |> Keyword.get(:partition_overrides, %{})
+    |> Map.get(partition_key(partition_id), %{})
+    |> Enum.reduce(defaults, fn {key, value}, acc ->
+      case normalize_limit_key(key) do
+        nil -> acc
+        key -> Map.put(acc, key, non_negative(value, Map.fetch!(acc, key)))
+      end
+    end)
+  end
+
+  ## Server Callbacks
+
+  @impl true
+  def init(opts) do
+    table = Keyword.get(opts, :table, @table)
+    :ets.new(table, [:named_table, :public, :set, write_concurrency: true])
+
+    schedule_cleanup()
+
+    {:ok, %{table: table}}
+  end
+
+  @impl true
+  def handle_info(:cleanup, %{table: table} = state) do
+    cleanup_expired_windows(table, current_window(System.system_time(:second)))
+    schedule_cleanup()
+    {:noreply, state}
+  end
+
+  ## Private Functions
+
+  defp reserve_concurrency(_table, _partition, 0), do: :ok
+
+  defp reserve_concurrency(table, partition, max_concurrent) do
+    key = {:in_flight, partition}
+
+    if :ets.update_counter(table, key, {2, 1}, {key, 0}) > max_concurrent do
+      :ets.update_counter(table, key, {2, -1, 0, 0})
+      {:error, :concurrency_limited, 1}
+    else
+      :ok
+    end
+  end
+
+  defp consume_rate(_table, _partition, 0, _now), do: :ok
+
+  defp consume_rate(table, partition, queries_per_minute, now) do
+    key = {:window, partition, current_window(now)}
+
+    if :ets.update_counter(table, key, {2, 1}, {key, 0}) > queries_per_minute do
+      {:error, :rate_limited, max(1, @window_seconds - rem(now, @window_seconds))}
+    else
+      :ok
+    end
+  end
+
+  defp record(table, partition, result) do
+    outcome =
+      case result do
+        :ok -> :allowed
+        {:error, reason, _retry_after} -> reason
+      end
+
+    key = {outcome, partition}
+    :ets.update_counter(table, key, {2, 1}, {key, 0})
+
+    :telemetry.execute(
+      [:serviceradar, :query_quota, :check],
+      %{count: 1},
+      %{partition: partition, result: outcome}
+    )
+  end
+
+  defp lookup(table, key) do
+    case :ets.lookup(table, key) do
+      [{^key, value}] -> value
+      [] -> 0
+    end
+  end
+
+  defp current_window(now), do: div(now, @window_seconds)
+
+  defp cleanup_expired_windows(table, window) do
+    :ets.select_delete(table, [
+      {{{:window, :_, :"$1"}, :_}, [{:<, :"$1", window}], [true]}
+    ])
+  end
+
+  defp schedule_cleanup do
+    Process.send_after(self(), :cleanup, @cleanup_interval)
+  end
+
+  defp partition_key(partition_id) when is_binary(partition_id) and partition_id != "",
+    do: partition_id
+
+  defp partition_key(_partition_id), do: @default_partition
+
+  defp normalize_limit_key(key) when key in [:queries_per_minute, "queries_per_minute"],
+    do: :queries_per_minute
+
+  defp normalize_limit_key(key) when key in [:max_concurrent, "max_concurrent"],
+    do: :max_concurrent
+
+  defp normalize_limit_key(_key), do: nil
+
+  defp non_negative(value, _default) when is_integer(value) and value >= 0, do: value
+  defp non_negative(_value, default), do: default
+end
@@ -89,6 +89,17 @@ defmodule ServiceRadarWebNGWeb.Router do
     plug(:require_authenticated_user_api)
   end
 
+  # Same as :api_auth, for endpoints that answer with something other than
+  # JSON, such as server-sent event streams and protobuf query responses.
+  pipeline :api_stream_auth do
+    plug(:fetch_session)
+    plug(:skip_csrf_protection_for_bearer_auth)
+    plug(:protect_from_forgery)
+    plug(:fetch_current_scope_for_user)
+    plug(:set_ash_actor)
+    plug(:require_authenticated_user_api)
+  end
+
   # API authentication for CLI/external tools (API key or bearer token)
   pipeline :api_key_auth do
     plug(:accepts, ["json"])
@@ -167,14 +178,53 @@ defmodule ServiceRadarWebNGWeb.Router do
     get("/camera-relay-sessions/:id/stream", CameraRelayStreamController, :connect)
   end
 
+  scope "/api", ServiceRadarWebNGWeb.Api do
+    pipe_through(:api_stream_auth)
+
+    get("/events/stream", EventStreamController, :stream)
+    post("/query", QueryController, :execute)
+  end
+
   # Other scopes may use custom stacks.
   scope "/api", ServiceRadarWebNGWeb.Api do
     pipe_through(:api_auth)
 
-    post("/query", QueryController, :execute)
+    get("/query/views", SRQLViewController, :index)
+    post("/query/views", SRQLViewController, :create)
+    patch("/query/views/:id", SRQLViewController, :update)
+    delete("/query/views/:id", SRQLViewController, :delete)
     get("/devices", DeviceController, :index)
     get("/devices/ocsf/export", DeviceController, :ocsf_export)
+    post("/devices/ownership", DeviceController, :bulk_assign_owner)
+    get("/devices/search", DeviceController, :search)
+    get("/devices/partition-consistency", DeviceController, :partition_consistency)
+    get("/devices/duplicates", DeviceController, :duplicates)
+    get("/devices/conflicts", DeviceController, :conflicts)
+    post("/devices/conflicts/:id/resolve", DeviceController, :resolve_conflict)
+    get("/devices/quarantine", DeviceController, :quarantine)
+    get("/devices/scheduled-operations", ScheduledDeviceOperationController, :index)
+    post("/devices/scheduled-operations", ScheduledDeviceOperationController, :create)
+    post("/devices/scheduled-operations/:id/cancel", ScheduledDeviceOperationController, :cancel)
     get("/devices/:uid", DeviceController, :show)
+    get("/devices/:uid/relationships", DeviceController, :relationships)
+    get("/devices/:uid/health", DeviceController, :health)
+    get("/devices/:uid/snapshots/diff", DeviceController, :snapshot_diff)
+    put("/devices/:uid/owner", DeviceController, :assign_owner)
+    post("/devices/:uid/archive", DeviceController, :archive)
+    post("/devices/:uid/unarchive", DeviceController, :unarchive)
+    post("/devices/:uid/lifecycle", DeviceController, :lifecycle)
+    post("/events/replay", EventReplayController, :create)
+    get("/retention/partitions", PartitionRetentionController, :index)
+    put("/retention/partitions/:partition", PartitionRetentionController, :upsert)
+    delete("/retention/partitions/:partition", PartitionRetentionController, :delete)
+    get("/metric-alert-rules", MetricAlertRuleController, :index)
+    post("/metric-alert-rules", MetricAlertRuleController, :create)
+    patch("/metric-alert-rules/:id", MetricAlertRuleController, :update)
+    delete("/metric-alert-rules/:id", MetricAlertRuleController, :delete)
+    get("/reports/scheduled", ScheduledReportController, :index)
+    post("/reports/scheduled", ScheduledReportController, :create)
+    patch("/reports/scheduled/:id", ScheduledReportController, :update)
+    delete("/reports/scheduled/:id", ScheduledReportController, :delete)
     post("/camera-relay-sessions", CameraRelaySessionController, :create)
     get("/camera-relay-sessions/:id", CameraRelaySessionController, :show)
     post("/camera-relay-sessions/:id/close", CameraRelaySessionController, :close)
@@ -237,6 +287,14 @@ defmodule ServiceRadarWebNGWeb.Router do
     post("/camera-analysis-workers/:id/disable", CameraAnalysisWorkerController, :disable)
 
     post("/topology/route-analysis", TopologyController, :route_analysis)
+    post("/topology/path-trace", TopologyController, :path_trace)
+
+    get("/kv/stale-checker-configs", StaleCheckerConfigController, :index)
+    post("/kv/stale-checker-configs/retire", StaleCheckerConfigController, :retire)
+
+    get("/gateway-groups", GatewayGroupController, :index)
+    put("/gateways/:gateway_id/group", GatewayGroupController, :assign)
+    post("/gateways/config/bulk", GatewayConfigController, :bulk)
   end
 
   # Edge onboarding admin API (API key or bearer token auth)
@@ -7,6 +7,7 @@ defmodule ServiceRadarWebNGWeb.Runtime do
       ServiceRadarWebNGWeb.Telemetry,
       ServiceRadarWebNGWeb.Auth.ConfigCache,
       ServiceRadarWebNGWeb.Auth.RateLimiter,
+      ServiceRadarWebNGWeb.QueryQuota,
       ServiceRadarWebNG.Auth.TokenRevocation,
       ServiceRadarWebNGWeb.Endpoint
     ]
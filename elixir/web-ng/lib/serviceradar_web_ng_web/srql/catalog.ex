@@ -117,14 +117,20 @@ defmodule ServiceRadarWebNGWeb.SRQL.Catalog do
         "vendor_name",
         "discovery_sources",
         "tags",
-        "include_deleted"
+        "owner",
+        "owner_type",
+        "archived",
+        "include_deleted",
+        "include_archived"
       ],
       boolean_fields: [
         "is_available",
         "is_managed",
         "is_compliant",
         "is_trusted",
-        "include_deleted"
+        "archived",
+        "include_deleted",
+        "include_archived"
       ],
       # Fields backed by array columns - builder will always use list syntax for these
       array_fields: ["discovery_sources", "tags"],
@@ -869,6 +875,21 @@ defmodule ServiceRadarWebNGWeb.SRQL.Catalog do
         "device_name"
       ]
     },
+    %{
+      id: "network_metrics",
+      label: "Network Metrics",
+      route: "/dashboard",
+      default_time: "last_24h",
+      default_sort_field: "timestamp",
+      default_sort_dir: "desc",
+      default_filter_field: "interface_name",
+      filter_fields: ["gateway_id", "agent_id", "host_id", "uid", "partition", "interface_name"],
+      downsample: true,
+      default_bucket: "5m",
+      default_agg: "avg",
+      default_series_field: "interface_name",
+      series_fields: ["uid", "host_id", "gateway_id", "agent_id", "partition", "interface_name"]
+    },
     %{
       id: "process_metrics",
       label: "Process Metrics",
@@ -111,6 +111,12 @@ defmodule ServiceRadarWebNGWeb.Telemetry do
         description: "Count of SRQL queries"
       ),
 
+      # Query Quota Metrics
+      counter("serviceradar.query_quota.check.count",
+        tags: [:partition, :result],
+        description: "Count of query quota decisions by partition and result"
+      ),
+
       # API Error Metrics
       counter("serviceradar.api.error.count",
         tags: [:status, :path, :method],
@@ -17,6 +17,8 @@ defmodule ServiceRadarWebNGWeb.UserAuth do
   alias ServiceRadarWebNG.Accounts.Scope
   alias ServiceRadarWebNG.Auth.Guardian
   alias ServiceRadarWebNG.Auth.TokenRevocation
+  alias ServiceRadarWebNGWeb.Auth.OIDCStrategy
+  alias ServiceRadarWebNGWeb.Plugs.ApiAuth
 
   require Logger
 
@@ -294,7 +296,19 @@ defmodule ServiceRadarWebNGWeb.UserAuth do
         assign(conn, :current_scope, create_scope(user))
 
       {:error, reason} ->
-        log_session_failure(conn, reason)
+        authenticate_with_oidc_id_token(conn, token, reason)
+    end
+  end
+
+  # With OIDC SSO enabled, API clients may present an ID token from the
+  # identity provider instead of a ServiceRadar token.
+  defp authenticate_with_oidc_id_token(conn, token, guardian_reason) do
+    with true <- OIDCStrategy.enabled?(),
+         {:ok, user} <- ApiAuth.oidc_id_token_user(token) do
+      assign(conn, :current_scope, create_scope(user))
+    else
+      _ ->
+        log_session_failure(conn, guardian_reason)
         assign(conn, :current_scope, Scope.for_user(nil))
     end
   end
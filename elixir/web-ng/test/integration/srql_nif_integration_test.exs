@@ -151,7 +151,7 @@ if System.get_env("SRQL_INTEGRATION") == "1" do
         end
       end)
 
-      {:ok, gateway_id: gateway_id}
+      {:ok, gateway_id: gateway_id, pg: conn}
     end
 
     test "translates SRQL gateways query via NIF", %{gateway_id: gateway_id} do
@@ -181,6 +181,63 @@ if System.get_env("SRQL_INTEGRATION") == "1" do
       assert gateway["agent_count"] == 7
       assert gateway["checker_count"] == 3
     end
+
+    describe "result cap" do
+      setup %{pg: conn} do
+        prefix = "srql-cap-" <> Ecto.UUID.generate()
+        gateway_ids = Enum.map(1..3, &"#{prefix}-#{&1}")
+        Enum.each(gateway_ids, &PostgrexHelpers.insert_gateway(conn, &1))
+
+        previous = Application.get_env(:serviceradar_web_ng, :srql_result_cap)
+        Application.put_env(:serviceradar_web_ng, :srql_result_cap, max_rows: 2)
+
+        on_exit(fn ->
+          if is_nil(previous),
+            do: Application.delete_env(:serviceradar_web_ng, :srql_result_cap),
+            else: Application.put_env(:serviceradar_web_ng, :srql_result_cap, previous)
+
+          if Process.alive?(conn) do
+            try do
+              Enum.each(gateway_ids, &PostgrexHelpers.delete_gateway(conn, &1))
+            catch
+              :exit, _ -> :ok
+            end
+          end
+        end)
+
+        query =
+          "in:gateways gateway_id:(#{Enum.join(gateway_ids, ",")}) sort:gateway_id:asc limit:10"
+
+        {:ok, query: query}
+      end
+
+      test "returns the capped rows and flags truncation", %{query: query} do
+        assert {:ok, response} = ServiceRadarWebNG.SRQL.query_request(%{"query" => query})
+
+        assert length(response["results"]) == 2
+        assert response["truncated"] == true
+        assert response["total_available"] == 3
+        assert response["pagination"]["limit"] == 2
+        assert is_binary(response["pagination"]["next_cursor"])
+      end
+
+      test "leaves requests within the cap alone", %{query: query} do
+        query = String.replace(query, "limit:10", "limit:2")
+
+        assert {:ok, response} = ServiceRadarWebNG.SRQL.query_request(%{"query" => query})
+
+        assert length(response["results"]) == 2
+        refute Map.has_key?(response, "truncated")
+      end
+
+      test "uncapped requests bypass the cap", %{query: query} do
+        assert {:ok, response} =
+                 ServiceRadarWebNG.SRQL.query_request(%{"query" => query, "uncapped" => true})
+
+        assert length(response["results"]) == 3
+        refute Map.has_key?(response, "truncated")
+      end
+    end
   end
 else
   defmodule ServiceRadarWebNG.SRQLNifIntegrationTest do
@@ -188,6 +188,141 @@ defmodule ServiceRadarWebNGWeb.Auth.OIDCClientTest do
     end
   end
 
+  describe "verify_bearer_token/2" do
+    setup do
+      put_oidc_settings(%{
+        is_enabled: true,
+        mode: :active_sso,
+        provider_type: :oidc,
+        oidc_client_id: "client-id",
+        oidc_client_secret_encrypted: "client-secret",
+        oidc_discovery_url: "https://idp.example.com",
+        oidc_scopes: "openid email profile"
+      })
+
+      ConfigCache.put_cached(
+        "oidc_metadata:https://idp.example.com",
+        %{
+          "issuer" => "https://idp.example.com",
+          "authorization_endpoint" => "https://idp.example.com/authorize",
+          "token_endpoint" => "https://idp.example.com/token",
+          "jwks_uri" => "https://idp.example.com/jwks"
+        },
+        ttl: to_timeout(minute: 5)
+      )
+
+      key = JOSE.JWK.generate_key({:rsa, 2048})
+      {_fields, public} = JOSE.JWK.to_public_map(key)
+
+      ConfigCache.put_cached(
+        "oidc_jwks:https://idp.example.com/jwks",
+        [Map.merge(public, %{"kid" => "key-1", "alg" => "RS256"})],
+        ttl: to_timeout(minute: 5)
+      )
+
+      %{key: key, now: System.system_time(:second)}
+    end
+
+    test "accepts a valid ID token", %{key: key, now: now} do
+      token = sign(key, "key-1", id_token_claims(now))
+
+      assert {:ok, claims} = OIDCClient.verify_bearer_token(token, now: now)
+      assert claims["sub"] == "oidc|12345"
+    end
+
+    test "accepts an audience list containing the client id", %{key: key, now: now} do
+      token = sign(key, "key-1", id_token_claims(now, %{"aud" => ["other", "client-id"]}))
+
+      assert {:ok, _claims} = OIDCClient.verify_bearer_token(token, now: now)
+    end
+
+    test "rejects a token from another issuer", %{key: key, now: now} do
+      token = sign(key, "key-1", id_token_claims(now, %{"iss" => "https://evil.example.com"}))
+
+      assert {:error, :invalid_issuer} = OIDCClient.verify_bearer_token(token, now: now)
+    end
+
+    test "rejects a token for another audience", %{key: key, now: now} do
+      token = sign(key, "key-1", id_token_claims(now, %{"aud" => "other-client"}))
+
+      assert {:error, :invalid_audience} = OIDCClient.verify_bearer_token(token, now: now)
+    end
+
+    test "rejects an expired token beyond the clock skew", %{key: key, now: now} do
+      token = sign(key, "key-1", id_token_claims(now, %{"exp" => now - 120}))
+
+      assert {:error, :token_expired} = OIDCClient.verify_bearer_token(token, now: now)
+    end
+
+    test "tolerates expiry within the clock skew", %{key: key, now: now} do
+      token = sign(key, "key-1", id_token_claims(now, %{"exp" => now - 30}))
+
+      assert {:ok, _claims} = OIDCClient.verify_bearer_token(token, now: now)
+    end
+
+    test "rejects a token without an expiry", %{key: key, now: now} do
+      token = sign(key, "key-1", Map.delete(id_token_claims(now), "exp"))
+
+      assert {:error, :token_expired} = OIDCClient.verify_bearer_token(token, now: now)
+    end
+
+    test "rejects a token that is not valid yet", %{key: key, now: now} do
+      token = sign(key, "key-1", id_token_claims(now, %{"nbf" => now + 300}))
+
+      assert {:error, :token_not_yet_valid} = OIDCClient.verify_bearer_token(token, now: now)
+    end
+
+    test "rejects a token signed with another key", %{now: now} do
+      other = JOSE.JWK.generate_key({:rsa, 2048})
+      token = sign(other, "key-1", id_token_claims(now))
+
+      assert {:error, :invalid_signature} = OIDCClient.verify_bearer_token(token, now: now)
+    end
+
+    test "refetches the JWKS for unknown keys at most once a minute", %{
+      key: key,
+      now: now
+    } do
+      ConfigCache.put_cached("oidc_jwks_refetched:https://idp.example.com/jwks", true,
+        ttl: to_timeout(minute: 1)
+      )
+
+      token = sign(key, "key-2", id_token_claims(now))
+
+      assert {:error, :key_not_found} = OIDCClient.verify_bearer_token(token, now: now)
+    end
+
+    test "fails when OIDC is not configured", %{key: key, now: now} do
+      put_oidc_settings(%{is_enabled: false, mode: :active_sso, provider_type: :oidc})
+      token = sign(key, "key-1", id_token_claims(now))
+
+      assert {:error, :oidc_not_configured} = OIDCClient.verify_bearer_token(token, now: now)
+    end
+  end
+
+  defp id_token_claims(now, overrides \\ %{}) do
+    Map.merge(
+      %{
+        "iss" => "https://idp.example.com",
+        "aud" => "client-id",
+        "sub" => "oidc|12345",
+        "email" => "user@example.com",
+        "iat" => now,
+        "exp" => now + 300
+      },
+      overrides
+    )
+  end
+
+  defp sign(key, kid, claims) do
+    {_fields, token} =
+      key
+      |> JOSE.JWT.sign(%{"alg" => "RS256", "kid" => kid}, claims)
+      |> JOSE.JWS.compact()
+
+    token
+  end
+
   defp maybe_start_config_cache do
     case Process.whereis(ConfigCache) do
       nil -> start_supervised!({ConfigCache, ttl_ms: 60_000})
@@ -0,0 +1,33 @@
+defmodule ServiceRadarWebNGWeb.Api.DeviceControllerFieldsTest do
+  use ServiceRadarWebNGWeb.ConnCase, async: true
+  use ServiceRadarWebNG.AshTestHelpers
+
+  setup :register_and_log_in_api_user
+
+  setup do
+    %{device: device_fixture(%{hostname: "fields-host", ip: "10.20.0.5"})}
+  end
+
+  test "show returns only the requested fields", %{conn: conn, device: device} do
+    conn = get(conn, ~p"/api/devices/#{device.uid}?fields=uid,hostname,last_seen")
+
+    assert %{"data" => data} = json_response(conn, 200)
+    assert Map.keys(data) == ["hostname", "last_seen", "uid"]
+    assert data["uid"] == device.uid
+    assert data["hostname"] == "fields-host"
+    assert is_binary(data["last_seen"])
+  end
+
+  test "index narrows every device", %{conn: conn, device: device} do
+    conn = get(conn, ~p"/api/devices?search=fields-host&fields=uid,ip")
+
+    assert %{"data" => [row]} = json_response(conn, 200)
+    assert row == %{"uid" => device.uid, "ip" => "10.20.0.5"}
+  end
+
+  test "rejects fields devices do not have", %{conn: conn} do
+    conn = get(conn, ~p"/api/devices?fields=uid,password")
+
+    assert json_response(conn, 400) == %{"error" => "unknown fields: password"}
+  end
+end
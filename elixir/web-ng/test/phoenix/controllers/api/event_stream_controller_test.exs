@@ -0,0 +1,106 @@
+defmodule ServiceRadarWebNGWeb.Api.EventStreamControllerTest do
+  use ServiceRadarWebNGWeb.ConnCase, async: false
+
+  import ServiceRadarWebNG.AshTestHelpers, only: [admin_user_fixture: 0, system_actor: 0]
+
+  alias ServiceRadar.Events.PubSub, as: EventsPubSub
+  alias ServiceRadar.Monitoring.OcsfEvent
+  alias ServiceRadarWebNG.Auth.Guardian
+  alias ServiceRadarWebNGWeb.Api.EventStreamController
+
+  setup %{conn: conn} do
+    previous = Application.get_env(:serviceradar_web_ng, EventStreamController)
+
+    Application.put_env(:serviceradar_web_ng, EventStreamController,
+      max_duration: 400,
+      keepalive: 100
+    )
+
+    on_exit(fn ->
+      if previous,
+        do: Application.put_env(:serviceradar_web_ng, EventStreamController, previous),
+        else: Application.delete_env(:serviceradar_web_ng, EventStreamController)
+    end)
+
+    {:ok, token, _claims} = Guardian.create_access_token(admin_user_fixture())
+
+    conn =
+      conn
+      |> put_req_header("authorization", "Bearer #{token}")
+      |> put_req_header("accept", "text/event-stream")
+
+    log_name = "sse.test.#{System.unique_integer([:positive])}"
+    {:ok, conn: conn, log_name: log_name}
+  end
+
+  test "a new event appears on an open stream", %{conn: conn, log_name: log_name} do
+    broadcast_later([
+      event(log_name <> ".other", "filtered out"),
+      event(log_name, "disk almost full")
+    ])
+
+    conn = get(conn, ~p"/api/events/stream?type=#{log_name}")
+
+    assert conn.status == 200
+    assert get_resp_header(conn, "content-type") |> hd() =~ "text/event-stream"
+    assert conn.resp_body =~ "event: ocsf_event"
+    assert conn.resp_body =~ "disk almost full"
+    refute conn.resp_body =~ "filtered out"
+    assert conn.resp_body =~ ": keepalive"
+  end
+
+  test "resumes after Last-Event-ID with the stored events", %{conn: conn, log_name: log_name} do
+    now = DateTime.utc_now()
+    first = record_event(log_name, "first", DateTime.add(now, -60, :second))
+    _second = record_event(log_name, "second", DateTime.add(now, -30, :second))
+
+    last_event_id = "#{DateTime.to_unix(first.time, :microsecond)}:#{first.id}"
+
+    conn =
+      conn
+      |> put_req_header("last-event-id", last_event_id)
+      |> get(~p"/api/events/stream?type=#{log_name}")
+
+    assert conn.resp_body =~ ~s("message":"second")
+    refute conn.resp_body =~ ~s("message":"first")
+  end
+
+  test "requires authentication" do
+    conn = get(build_conn(), ~p"/api/events/stream")
+    assert conn.status == 401
+  end
+
+  defp broadcast_later(events) do
+    spawn(fn ->
+      Process.sleep(100)
+      Enum.each(events, &EventsPubSub.broadcast_event/1)
+    end)
+  end
+
+  defp event(log_name, message) do
+    %{
+      id: Ecto.UUID.generate(),
+      time: DateTime.utc_now(),
+      log_name: log_name,
+      severity_id: 3,
+      message: message,
+      metadata: %{}
+    }
+  end
+
+  defp record_event(log_name, message, time) do
+    OcsfEvent
+    |> Ash.Changeset.for_create(:record, %{
+      time: time,
+      class_uid: 1008,
+      category_uid: 1,
+      type_uid: 100_801,
+      activity_id: 1,
+      severity_id: 3,
+      message: message,
+      log_name: log_name,
+      metadata: %{}
+    })
+    |> Ash.create!(actor: system_actor())
+  end
+end
@@ -0,0 +1,108 @@
+defmodule ServiceRadarWebNGWeb.Api.QueryControllerTest do
+  use ServiceRadarWebNGWeb.ConnCase, async: false
+
+  alias ServiceRadarWebNG.AshTestHelpers
+  alias ServiceRadarWebNG.Auth.Guardian
+  alias ServiceRadarWebNG.SRQL.Native
+
+  defmodule FakeSRQL do
+    @moduledoc false
+
+    def query_request(request) do
+      send(self(), {:srql_request, request})
+      {:ok, %{"results" => [], "pagination" => %{"limit" => 10}}}
+    end
+  end
+
+  setup %{conn: conn} do
+    previous_srql_module = Application.get_env(:serviceradar_web_ng, :srql_module)
+    previous_cap = Application.get_env(:serviceradar_web_ng, :srql_result_cap)
+
+    Application.put_env(:serviceradar_web_ng, :srql_module, FakeSRQL)
+
+    Application.put_env(:serviceradar_web_ng, :srql_result_cap,
+      max_rows: 100,
+      admin_api_key: "srql-admin-key"
+    )
+
+    on_exit(fn ->
+      restore_env(:srql_module, previous_srql_module)
+      restore_env(:srql_result_cap, previous_cap)
+    end)
+
+    {:ok, token, _claims} = Guardian.create_access_token(AshTestHelpers.admin_user_fixture())
+
+    {:ok, conn: put_req_header(conn, "authorization", "Bearer #{token}")}
+  end
+
+  describe "POST /api/query result cap" do
+    test "caps ordinary requests", %{conn: conn} do
+      conn = post(conn, ~p"/api/query", %{"query" => "in:devices limit:500"})
+
+      assert json_response(conn, 200)
+      assert_received {:srql_request, %{"uncapped" => false}}
+    end
+
+    test "ignores uncapped set by the client", %{conn: conn} do
+      conn = post(conn, ~p"/api/query", %{"query" => "in:devices", "uncapped" => true})
+
+      assert json_response(conn, 200)
+      assert_received {:srql_request, %{"uncapped" => false}}
+    end
+
+    test "lifts the cap for the admin key", %{conn: conn} do
+      conn =
+        conn
+        |> put_req_header("x-srql-admin-key", "srql-admin-key")
+        |> post(~p"/api/query", %{"query" => "in:devices limit:500"})
+
+      assert json_response(conn, 200)
+      assert_received {:srql_request, %{"uncapped" => true}}
+    end
+
+    test "keeps the cap for a wrong admin key", %{conn: conn} do
+      conn =
+        conn
+        |> put_req_header("x-srql-admin-key", "srql-admin-kez")
+        |> post(~p"/api/query", %{"query" => "in:devices limit:500"})
+
+      assert json_response(conn, 200)
+      assert_received {:srql_request, %{"uncapped" => false}}
+    end
+  end
+
+  describe "POST /api/query partition scope" do
+    test "ignores a partition set by the client", %{conn: conn} do
+      conn = post(conn, ~p"/api/query", %{"query" => "in:devices", "partition" => "other-partition"})
+
+      assert json_response(conn, 200)
+      assert_received {:srql_request, %{"partition" => nil}}
+    end
+  end
+
+  describe "POST /api/query response encoding" do
+    test "answers with JSON by default", %{conn: conn} do
+      conn = post(conn, ~p"/api/query", %{"query" => "in:devices"})
+
+      assert %{"results" => []} = json_response(conn, 200)
+    end
+
+    test "answers with protobuf when the client accepts it", %{conn: conn} do
+      conn =
+        conn
+        |> put_req_header("accept", "application/json;q=0.5, Application/X-Protobuf")
+        |> post(~p"/api/query", %{"query" => "in:devices"})
+
+      {:ok, expected} =
+        Native.encode_query_response_protobuf(
+          Jason.encode!(%{"results" => [], "pagination" => %{"limit" => 10}})
+        )
+
+      assert response(conn, 200) == expected
+      assert get_resp_header(conn, "content-type") == ["application/x-protobuf"]
+    end
+  end
+
+  defp restore_env(key, nil), do: Application.delete_env(:serviceradar_web_ng, key)
+  defp restore_env(key, value), do: Application.put_env(:serviceradar_web_ng, key, value)
+end
@@ -0,0 +1,107 @@
+defmodule ServiceRadarWebNGWeb.Api.StaleCheckerConfigControllerTest do
+  use ServiceRadarWebNGWeb.ConnCase, async: false
+
+  import ServiceRadarWebNG.AshTestHelpers,
+    only: [admin_user_fixture: 0, viewer_user_fixture: 0]
+
+  alias ServiceRadarWebNG.Auth.Guardian
+
+  defmodule StaleCheckerConfigsStub do
+    @moduledoc false
+
+    def retire(opts) do
+      test_pid = Application.get_env(:serviceradar_web_ng, :stale_checker_configs_test_pid)
+      send(test_pid, {:retire, opts})
+
+      dry_run = Keyword.fetch!(opts, :dry_run)
+      keys = ["agents/gone/checkers/snmp/snmp.json"]
+
+      {:ok,
+       %{
+         dry_run: dry_run,
+         cutoff: ~U[2026-10-09 12:00:00Z],
+         agents: [%{agent_id: "gone", last_seen: nil, keys: keys}],
+         key_count: 1,
+         deleted_keys: if(dry_run, do: [], else: keys)
+       }}
+    end
+  end
+
+  setup %{conn: conn} do
+    previous_module = Application.get_env(:serviceradar_web_ng, :stale_checker_configs)
+    previous_pid = Application.get_env(:serviceradar_web_ng, :stale_checker_configs_test_pid)
+
+    Application.put_env(:serviceradar_web_ng, :stale_checker_configs, StaleCheckerConfigsStub)
+    Application.put_env(:serviceradar_web_ng, :stale_checker_configs_test_pid, self())
+
+    on_exit(fn ->
+      restore_env(:stale_checker_configs, previous_module)
+      restore_env(:stale_checker_configs_test_pid, previous_pid)
+    end)
+
+    user = admin_user_fixture()
+    {:ok, token, _claims} = Guardian.create_access_token(user)
+    conn = Plug.Conn.put_req_header(conn, "authorization", "Bearer #{token}")
+
+    %{conn: conn}
+  end
+
+  describe "GET /api/admin/kv/stale-checker-configs" do
+    test "previews stale keys as a dry run", %{conn: conn} do
+      conn = get(conn, ~p"/api/admin/kv/stale-checker-configs?stale_after_seconds=3600")
+      body = json_response(conn, 200)
+
+      assert body["data"]["dry_run"] == true
+      assert body["data"]["deleted_count"] == 0
+      assert [%{"agent_id" => "gone", "last_seen" => nil}] = body["data"]["agents"]
+
+      assert_receive {:retire, opts}
+      assert opts[:dry_run] == true
+      assert opts[:stale_after_seconds] == 3600
+    end
+
+    test "rejects an invalid window", %{conn: conn} do
+      conn = get(conn, ~p"/api/admin/kv/stale-checker-configs?stale_after_seconds=soon")
+
+      assert json_response(conn, 400)["error"] == "invalid_request"
+      refute_receive {:retire, _opts}
+    end
+
+    test "rejects users without settings.edge.manage" do
+      viewer = viewer_user_fixture()
+      {:ok, token, _claims} = Guardian.create_access_token(viewer)
+
+      conn =
+        build_conn()
+        |> Plug.Conn.put_req_header("authorization", "Bearer #{token}")
+        |> get(~p"/api/admin/kv/stale-checker-configs")
+
+      assert conn.status == 403
+      refute_receive {:retire, _opts}
+    end
+  end
+
+  describe "POST /api/admin/kv/stale-checker-configs/retire" do
+    test "deletes stale keys", %{conn: conn} do
+      conn = post(conn, ~p"/api/admin/kv/stale-checker-configs/retire", %{})
+      body = json_response(conn, 200)
+
+      assert body["data"]["dry_run"] == false
+      assert body["data"]["deleted_count"] == 1
+
+      assert_receive {:retire, opts}
+      assert opts[:dry_run] == false
+    end
+
+    test "honours dry_run", %{conn: conn} do
+      conn = post(conn, ~p"/api/admin/kv/stale-checker-configs/retire", %{"dry_run" => true})
+
+      assert json_response(conn, 200)["data"]["dry_run"] == true
+      assert_receive {:retire, opts}
+      assert opts[:dry_run] == true
+    end
+  end
+
+  defp restore_env(key, nil), do: Application.delete_env(:serviceradar_web_ng, key)
+  defp restore_env(key, value), do: Application.put_env(:serviceradar_web_ng, key, value)
+end
@@ -87,4 +87,24 @@ defmodule ServiceRadarWebNGWeb.Api.TopologyControllerTest do
       assert conn.status == 403
     end
   end
+
+  describe "POST /api/admin/topology/path-trace" do
+    test "returns 400 when destination_device_id is missing", %{conn: conn} do
+      conn = post(conn, ~p"/api/admin/topology/path-trace", %{"source_device_id" => "sr:router-a"})
+      body = json_response(conn, 400)
+
+      assert body["error"] == "invalid_request"
+      assert body["message"] =~ "destination_device_id"
+    end
+
+    test "returns 400 when source_device_id is blank", %{conn: conn} do
+      params = %{"source_device_id" => " ", "destination_device_id" => "sr:router-b"}
+
+      conn = post(conn, ~p"/api/admin/topology/path-trace", params)
+      body = json_response(conn, 400)
+
+      assert body["error"] == "invalid_request"
+      assert body["message"] =~ "source_device_id"
+    end
+  end
 end
@@ -0,0 +1,90 @@
+defmodule ServiceRadarWebNGWeb.Plugs.ApiAuthTest do
+  use ServiceRadarWebNGWeb.ConnCase, async: false
+
+  alias ServiceRadar.Identity.ApiToken
+  alias ServiceRadar.Repo
+  alias ServiceRadarWebNG.AshTestHelpers
+  alias ServiceRadarWebNGWeb.Plugs.ApiAuth
+
+  describe "API token inactivity" do
+    setup do
+      user = AshTestHelpers.admin_user_fixture()
+      raw_token = "srk_" <> Base.encode64(:crypto.strong_rand_bytes(32))
+
+      token =
+        AshTestHelpers.api_token_fixture(user, %{
+          token: raw_token,
+          inactivity_timeout_seconds: 3600
+        })
+
+      {:ok, user: user, raw_token: raw_token, token: token}
+    end
+
+    test "accepts a token used within its inactivity timeout", %{
+      conn: conn,
+      raw_token: raw_token,
+      token: token
+    } do
+      conn = call_with_api_key(conn, raw_token)
+
+      refute conn.halted
+      assert conn.assigns.api_token.id == token.id
+      assert %{enabled: true, disabled_reason: nil} = reload(token)
+    end
+
+    test "disables a token left unused past its inactivity timeout", %{
+      conn: conn,
+      raw_token: raw_token,
+      token: token
+    } do
+      age_token(token, 7200)
+
+      conn = call_with_api_key(conn, raw_token)
+
+      assert conn.halted
+      assert conn.status == 401
+      assert %{enabled: false, disabled_reason: "inactivity"} = reload(token)
+    end
+
+    test "keeps rejecting a disabled token until it is re-enabled", %{
+      raw_token: raw_token,
+      token: token
+    } do
+      age_token(token, 7200)
+
+      assert call_with_api_key(build_conn(), raw_token).status == 401
+      assert call_with_api_key(build_conn(), raw_token).status == 401
+
+      token
+      |> reload()
+      |> Ash.Changeset.for_update(:enable, %{})
+      |> Ash.update!(actor: AshTestHelpers.system_actor(), authorize?: false)
+
+      conn = call_with_api_key(build_conn(), raw_token)
+
+      refute conn.halted
+      assert %{enabled: true, disabled_reason: nil} = reload(token)
+    end
+  end
+
+  defp call_with_api_key(conn, raw_token) do
+    conn
+    |> put_req_header("x-api-key", raw_token)
+    |> ApiAuth.call([])
+  end
+
+  defp age_token(token, seconds) do
+    Repo.query!(
+      """
+      UPDATE platform.api_tokens
+      SET created_at = now() - make_interval(secs => $2), last_used_at = NULL
+      WHERE id = $1
+      """,
+      [Ecto.UUID.dump!(token.id), seconds]
+    )
+  end
+
+  defp reload(token) do
+    Ash.get!(ApiToken, token.id, actor: AshTestHelpers.system_actor(), authorize?: false)
+  end
+end
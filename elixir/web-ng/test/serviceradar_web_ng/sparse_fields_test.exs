@@ -0,0 +1,52 @@
+defmodule ServiceRadarWebNG.SparseFieldsTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadarWebNG.SparseFields
+  alias ServiceRadarWebNG.SRQL
+
+  describe "parse/2" do
+    test "accepts comma-separated strings and lists" do
+      assert SparseFields.parse(" uid, hostname ,,uid") == {:ok, ["uid", "hostname"]}
+      assert SparseFields.parse(["ip", "mac"]) == {:ok, ["ip", "mac"]}
+    end
+
+    test "treats a missing or empty value as all fields" do
+      assert SparseFields.parse(nil) == {:ok, nil}
+      assert SparseFields.parse("") == {:ok, nil}
+      assert SparseFields.parse(" , ") == {:ok, nil}
+    end
+
+    test "rejects names that are not identifiers" do
+      assert {:error, "invalid field: " <> _} = SparseFields.parse(~s(uid,"x" from y))
+      assert {:error, _} = SparseFields.parse([1, 2])
+      assert {:error, _} = SparseFields.parse(%{"uid" => true})
+    end
+
+    test "validates against the allowed fields" do
+      assert SparseFields.parse("uid,ip", allowed: ~w(uid ip mac)) == {:ok, ["uid", "ip"]}
+
+      assert SparseFields.parse("uid,serial,owner_id", allowed: ~w(uid ip)) ==
+               {:error, "unknown fields: serial, owner_id"}
+    end
+  end
+
+  describe "take/2" do
+    test "narrows maps and leaves other values alone" do
+      row = %{"uid" => "sr:1", "hostname" => "core", "ip" => "10.0.0.1"}
+
+      assert SparseFields.take(row, ["uid", "ip"]) == %{"uid" => "sr:1", "ip" => "10.0.0.1"}
+      assert SparseFields.take(row, nil) == row
+      assert SparseFields.take(42, ["uid"]) == 42
+    end
+  end
+
+  describe "SRQL.narrow_sql/2" do
+    test "selects only the requested columns of the translated query" do
+      sql = "SELECT * FROM ocsf_devices WHERE hostname = $1 ORDER BY last_seen_time DESC LIMIT 10;"
+
+      assert SRQL.narrow_sql(sql, ["uid", "hostname"]) ==
+               ~s|SELECT "uid", "hostname" FROM (SELECT * FROM ocsf_devices WHERE hostname = $1 | <>
+                 ~s|ORDER BY last_seen_time DESC LIMIT 10) AS srql_fields|
+    end
+  end
+end
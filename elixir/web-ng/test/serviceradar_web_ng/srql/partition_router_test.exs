@@ -0,0 +1,79 @@
+defmodule ServiceRadarWebNG.SRQL.PartitionRouterTest do
+  use ExUnit.Case, async: true
+
+  alias ServiceRadar.Repo
+  alias ServiceRadarWebNG.SRQL.PartitionRouter
+
+  @config %{
+    backends: %{"partition-a" => :backend_a, "partition-b" => :backend_b},
+    fan_out_max_rows: 3,
+    fan_out_timeout: 1_000
+  }
+
+  defp result(rows), do: %Postgrex.Result{columns: ["uid"], rows: rows, num_rows: length(rows)}
+
+  defp runner(rows_by_target) do
+    test = self()
+
+    fn target, sql, _params ->
+      send(test, {:ran, target, sql})
+
+      case Map.fetch!(rows_by_target, target) do
+        {:error, reason} -> {:error, reason}
+        rows -> {:ok, result(rows)}
+      end
+    end
+  end
+
+  describe "single-partition routing" do
+    test "runs everything on the primary repo without backends" do
+      run = runner(%{Repo => [["d1"]]})
+      config = %{@config | backends: %{}}
+
+      assert {:ok, %{rows: [["d1"]]}} = PartitionRouter.query("SELECT 1", [], nil, config: config, run: run)
+      assert_received {:ran, Repo, "SELECT 1"}
+      refute_received {:ran, _, _}
+    end
+
+    test "runs a scoped query on its partition's backend only" do
+      run = runner(%{backend_b: [["d2"]]})
+
+      assert {:ok, %{rows: [["d2"]]}} = PartitionRouter.query("SELECT 1", [], "partition-b", config: @config, run: run)
+      assert_received {:ran, :backend_b, _}
+      refute_received {:ran, _, _}
+    end
+
+    test "runs a partition without a backend on the primary repo" do
+      assert PartitionRouter.targets("partition-z", @config) == {:single, Repo}
+    end
+  end
+
+  describe "unscoped fan-out" do
+    test "merges the rows of every backend" do
+      run = runner(%{Repo => [["d0"]], backend_a: [["d1"]], backend_b: [["d2"]]})
+
+      assert {:ok, %{rows: rows, num_rows: 3}} = PartitionRouter.query("SELECT 1", [], nil, config: @config, run: run)
+      assert rows == [["d0"], ["d1"], ["d2"]]
+    end
+
+    test "rejects merged results over the row limit" do
+      run = runner(%{Repo => [["d0"], ["d0b"]], backend_a: [["d1"]], backend_b: [["d2"]]})
+
+      assert PartitionRouter.query("SELECT 1", [], nil, config: @config, run: run) ==
+               {:error, :fan_out_result_too_large}
+    end
+
+    test "adds up counts" do
+      run = runner(%{Repo => [[2]], backend_a: [[5]], backend_b: [[0]]})
+
+      assert {:ok, %{rows: [[7]]}} =
+               PartitionRouter.query("SELECT COUNT(*)", [], "", config: @config, run: run, merge: :sum)
+    end
+
+    test "fails when a backend fails" do
+      run = runner(%{Repo => [["d0"]], backend_a: {:error, :econnrefused}, backend_b: [["d2"]]})
+
+      assert PartitionRouter.query("SELECT 1", [], nil, config: @config, run: run) == {:error, :econnrefused}
+    end
+  end
+end
@@ -0,0 +1,87 @@
+defmodule ServiceRadarWebNGWeb.QueryQuotaTest do
+  use ExUnit.Case, async: false
+
+  alias ServiceRadarWebNGWeb.QueryQuota
+
+  @busy "8d1b5f5e-0a43-4c59-9b4b-2f1f4b0c2a11"
+  @quiet "c3e0f4d2-6f0e-4b1a-8a63-1f5d7a9c0b22"
+
+  setup do
+    table = :"query_quota_test_#{System.unique_integer([:positive])}"
+    start_supervised!({QueryQuota, name: table, table: table})
+    %{table: table}
+  end
+
+  test "a partition over its rate limit is rejected while another is unaffected", %{table: table} do
+    limits = %{queries_per_minute: 3, max_concurrent: 0}
+    now = 1_800_000_010
+
+    for _ <- 1..3 do
+      assert :ok = QueryQuota.acquire(@busy, table: table, limits: limits, now: now)
+    end
+
+    assert {:error, :rate_limited, 50} =
+             QueryQuota.acquire(@busy, table: table, limits: limits, now: now)
+
+    assert :ok = QueryQuota.acquire(@quiet, table: table, limits: limits, now: now)
+
+    assert %{allowed: 3, rate_limited: 1} = QueryQuota.usage(@busy, table: table)
+    assert %{allowed: 1, rate_limited: 0} = QueryQuota.usage(@quiet, table: table)
+
+    assert :ok = QueryQuota.acquire(@busy, table: table, limits: limits, now: now + 60)
+  end
+
+  test "concurrent queries are capped per partition and freed on release", %{table: table} do
+    limits = %{queries_per_minute: 0, max_concurrent: 2}
+
+    assert :ok = QueryQuota.acquire(@busy, table: table, limits: limits)
+    assert :ok = QueryQuota.acquire(@busy, table: table, limits: limits)
+
+    assert {:error, :concurrency_limited, 1} =
+             QueryQuota.acquire(@busy, table: table, limits: limits)
+
+    assert :ok = QueryQuota.acquire(@quiet, table: table, limits: limits)
+    assert %{in_flight: 2, concurrency_limited: 1} = QueryQuota.usage(@busy, table: table)
+
+    QueryQuota.release(@busy, table: table)
+    assert :ok = QueryQuota.acquire(@busy, table: table, limits: limits)
+  end
+
+  test "rate rejections do not leak concurrency slots", %{table: table} do
+    limits = %{queries_per_minute: 1, max_concurrent: 5}
+    now = 1_800_000_000
+
+    assert :ok = QueryQuota.acquire(nil, table: table, limits: limits, now: now)
+    QueryQuota.release(nil, table: table)
+
+    assert {:error, :rate_limited, _} =
+             QueryQuota.acquire(nil, table: table, limits: limits, now: now)
+
+    assert %{in_flight: 0} = QueryQuota.usage(nil, table: table)
+  end
+
+  describe "limits_for/1" do
+    setup do
+      previous = Application.get_env(:serviceradar_web_ng, :query_quota)
+
+      Application.put_env(:serviceradar_web_ng, :query_quota,
+        queries_per_minute: 100,
+        max_concurrent: 4,
+        partition_overrides: %{@busy => %{"queries_per_minute" => 10}}
+      )
+
+      on_exit(fn ->
+        if previous do
+          Application.put_env(:serviceradar_web_ng, :query_quota, previous)
+        else
+          Application.delete_env(:serviceradar_web_ng, :query_quota)
+        end
+      end)
+    end
+
+    test "merges partition overrides over the global limits" do
+      assert QueryQuota.limits_for(@busy) == %{queries_per_minute: 10, max_concurrent: 4}
+      assert QueryQuota.limits_for(@quiet) == %{queries_per_minute: 100, max_concurrent: 4}
+    end
+  end
+end
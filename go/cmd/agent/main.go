@@ -48,6 +48,7 @@ var Version = "dev"
 var errConfigFileMissing = errors.New("config file not found")
 var errConfigTrailingData = errors.New("config has trailing data")
 var errShutdownTimeout = errors.New("shutdown timed out")
+var errSelfTestFailed = errors.New("self-test failed")
 
 func main() {
 	if err := run(); err != nil {
@@ -58,6 +59,7 @@ func main() {
 func run() error {
 	// Parse command line flags
 	configPath := flag.String("config", "/etc/serviceradar/agent.json", "Path to agent config file")
+	selfTest := flag.Bool("self-test", false, "Run each configured check and a KV read once, print the report and exit")
 	flag.Parse()
 
 	// Setup a context we can use for loading the config and running the server
@@ -100,9 +102,64 @@ func run() error {
 		return agentgateway.ErrGatewayAddrRequired
 	}
 
+	if *selfTest {
+		return runSelfTest(ctx, server, cfg, agentLogger)
+	}
+
 	return runPushMode(ctx, server, cfg, agentLogger)
 }
 
+// runSelfTest connects to the gateway to learn the configured checks, runs
+// them once alongside a KV read, and prints the JSON report to stdout.
+func runSelfTest(ctx context.Context, server *agent.Server, cfg *agent.ServerConfig, log logger.Logger) error {
+	const selfTestTimeout = 2 * time.Minute
+
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	gatewayClient := agentgateway.NewGatewayClient(cfg.GatewayAddr, cfg.GatewaySecurity, log)
+	defer func() {
+		if err := gatewayClient.Disconnect(); err != nil {
+			log.Warn().Err(err).Msg("Error disconnecting from gateway")
+		}
+	}()
+
+	pushLoop := agent.NewPushLoop(server, gatewayClient, 0, log)
+
+	gatewayResult := agent.SelfTestResult{
+		Component: agent.SelfTestComponentGateway,
+		Name:      "gateway",
+		Target:    cfg.GatewayAddr,
+	}
+
+	start := time.Now()
+	err := gatewayClient.Connect(ctx)
+	if err == nil {
+		err = pushLoop.RefreshChecks(ctx)
+	}
+	gatewayResult.LatencyMs = time.Since(start).Milliseconds()
+	gatewayResult.Passed = err == nil
+	if err != nil {
+		gatewayResult.Error = err.Error()
+	}
+
+	report := pushLoop.RunSelfTest(ctx)
+	report.Results = append([]agent.SelfTestResult{gatewayResult}, report.Results...)
+	report.Passed = report.Passed && gatewayResult.Passed
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("failed to write self-test report: %w", err)
+	}
+
+	if !report.Passed {
+		return fmt.Errorf("%w: %d component(s) failed", errSelfTestFailed, len(report.Failed()))
+	}
+
+	return nil
+}
+
 // loadConfig loads agent configuration from file, falling back to embedded defaults.
 func loadConfig(configPath string) (*agent.ServerConfig, error) {
 	var cfg agent.ServerConfig
@@ -148,6 +205,9 @@ func runPushMode(ctx context.Context, server *agent.Server, cfg *agent.ServerCon
 
 	// Create gateway client (PushLoop handles connect/enroll/config polling)
 	gatewayClient := agentgateway.NewGatewayClient(cfg.GatewayAddr, cfg.GatewaySecurity, log)
+	if cfg.GatewayDiagnostics != nil && cfg.GatewayDiagnostics.Enabled {
+		gatewayClient.EnableDiagnostics(*cfg.GatewayDiagnostics)
+	}
 	defer func() {
 		if err := gatewayClient.Disconnect(); err != nil {
 			log.Warn().Err(err).Msg("Error disconnecting from gateway")
@@ -77,6 +77,14 @@ func dispatchCommand(cfg *cli.CmdConfig) error {
 		return cli.RunNatsBootstrap(cfg)
 	case "admin":
 		return dispatchAdminCommand(cfg)
+	case "doctor":
+		return cli.RunDoctor(cfg)
+	case "config-export":
+		return cli.RunConfigExport(cfg)
+	case "config-import":
+		return cli.RunConfigImport(cfg)
+	case "tail":
+		return cli.RunTail(cfg)
 	default:
 		return runBcryptMode(cfg)
 	}
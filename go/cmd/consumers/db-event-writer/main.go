@@ -140,6 +140,11 @@ func run() int {
 		Security: cfg.Security,
 	}
 
+	// Report NOT_SERVING until CNPG answers a ping.
+	if pinger, ok := dbService.(interface{ Ping(context.Context) error }); ok {
+		opts.ReadinessChecks = []lifecycle.ReadinessCheck{{Name: "cnpg", Check: pinger.Ping}}
+	}
+
 	if err := lifecycle.RunServer(ctx, opts); err != nil {
 		log.Printf("Server failed: %v", err)
 		return 1
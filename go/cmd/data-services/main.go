@@ -36,6 +36,9 @@ import (
 //go:embed config.json
 var defaultConfig []byte
 
+// readinessProbeKey is read (and normally missing) to confirm the KV bucket is reachable.
+const readinessProbeKey = "serviceradar/readiness-probe"
+
 func main() {
 	configPath := flag.String("config", "/etc/serviceradar/datasvc.json", "Path to config file")
 	flag.Parse()
@@ -131,6 +134,16 @@ func main() {
 		EnableHealthCheck: true,
 		Security:          cfg.Security,
 		DisableTelemetry:  true,
+		// Hold off serving KV requests until the NATS KV bucket answers a read.
+		ReadinessChecks: []lifecycle.ReadinessCheck{
+			{
+				Name: "nats-kv",
+				Check: func(ctx context.Context) error {
+					_, _, err := server.Store().Get(ctx, readinessProbeKey)
+					return err
+				},
+			},
+		},
 		RegisterGRPCServices: []lifecycle.GRPCServiceRegistrar{
 			func(srv *ggrpc.Server) error {
 				proto.RegisterKVServiceServer(srv, server)
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+// checkerPanicLimit is the number of consecutive panics after which a
+// checker is disabled.
+const checkerPanicLimit = 3
+
+var (
+	errCheckerPanicked = errors.New("checker panicked")
+	errCheckerDisabled = errors.New("checker disabled after repeated panics")
+)
+
+// checkerBreaker isolates one checker's invocations. A panic is recovered,
+// logged with its stack and reported as a failed run instead of taking down
+// the agent; after checkerPanicLimit consecutive panics the breaker opens and
+// the checker is skipped until it is reconfigured.
+type checkerBreaker struct {
+	kind  string
+	id    string
+	limit int
+	log   logger.Logger
+
+	mu          sync.Mutex
+	consecutive int
+	disabled    bool
+}
+
+func newCheckerBreaker(kind, id string, log logger.Logger) *checkerBreaker {
+	return &checkerBreaker{kind: kind, id: id, limit: checkerPanicLimit, log: log}
+}
+
+// Disabled reports whether the checker has been disabled by repeated panics.
+func (b *checkerBreaker) Disabled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.disabled
+}
+
+// run invokes fn. A panic is returned as an error wrapping
+// errCheckerPanicked; the panic that opens the breaker also wraps
+// errCheckerDisabled. A run that completes resets the consecutive count.
+func (b *checkerBreaker) run(fn func()) (err error) {
+	defer func() {
+		recovered := recover()
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if recovered == nil {
+			b.consecutive = 0
+
+			return
+		}
+
+		b.consecutive++
+		err = fmt.Errorf("%w: %v", errCheckerPanicked, recovered)
+
+		b.log.Error().
+			Str("checker_kind", b.kind).
+			Str("checker_id", b.id).
+			Int("consecutive_panics", b.consecutive).
+			Interface("panic", recovered).
+			Bytes("stack", debug.Stack()).
+			Msg("Checker panicked; marking run failed")
+
+		if b.consecutive >= b.limit && !b.disabled {
+			b.disabled = true
+			err = fmt.Errorf("%w (%d consecutive): %w", errCheckerDisabled, b.consecutive, err)
+
+			b.log.Error().
+				Str("checker_kind", b.kind).
+				Str("checker_id", b.id).
+				Int("consecutive_panics", b.consecutive).
+				Msg("Checker disabled after repeated panics")
+		}
+	}()
+
+	fn()
+
+	return nil
+}
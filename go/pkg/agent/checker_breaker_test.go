@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+func TestCheckerBreakerResetsOnCleanRun(t *testing.T) {
+	breaker := newCheckerBreaker("plugin", "p1", logger.NewTestLogger())
+
+	for i := 0; i < checkerPanicLimit-1; i++ {
+		err := breaker.run(func() { panic("boom") })
+		require.ErrorIs(t, err, errCheckerPanicked)
+	}
+
+	require.NoError(t, breaker.run(func() {}))
+
+	err := breaker.run(func() { panic("boom") })
+	require.ErrorIs(t, err, errCheckerPanicked)
+	assert.False(t, errors.Is(err, errCheckerDisabled))
+	assert.False(t, breaker.Disabled())
+}
+
+func TestPushLoopICMPPanicDisablesOnlyThatCheck(t *testing.T) {
+	loop := &PushLoop{
+		logger: logger.NewTestLogger(),
+		icmpChecks: map[string]*icmpCheckConfig{
+			"bad":  {ID: "bad", Name: "bad", Target: "10.0.0.1", Enabled: true},
+			"good": {ID: "good", Name: "good", Target: "10.0.0.2", Enabled: true},
+		},
+		icmpLastRun: make(map[string]time.Time),
+	}
+
+	var badCalls atomic.Int32
+
+	loop.icmpCheckRunner = func(_ context.Context, check *icmpCheckConfig) icmpCheckResult {
+		if check.ID == "bad" {
+			badCalls.Add(1)
+			panic("nil probe")
+		}
+
+		return icmpCheckResult{CheckID: check.ID, Target: check.Target, Available: true}
+	}
+
+	for cycle := 0; cycle < checkerPanicLimit+2; cycle++ {
+		loop.icmpMu.Lock()
+		loop.icmpLastRun = make(map[string]time.Time)
+		loop.icmpMu.Unlock()
+
+		byID := make(map[string]icmpCheckResult)
+		for _, result := range loop.collectDueICMPResults(context.Background()) {
+			byID[result.CheckID] = result
+		}
+
+		require.Contains(t, byID, "good")
+		assert.True(t, byID["good"].Available)
+
+		if cycle < checkerPanicLimit {
+			require.Contains(t, byID, "bad")
+			assert.False(t, byID["bad"].Available)
+			assert.Contains(t, byID["bad"].Error, "nil probe")
+		} else {
+			assert.NotContains(t, byID, "bad")
+		}
+	}
+
+	assert.Equal(t, int32(checkerPanicLimit), badCalls.Load())
+	assert.True(t, loop.icmpBreaker("bad").Disabled())
+	assert.False(t, loop.icmpBreaker("good").Disabled())
+}
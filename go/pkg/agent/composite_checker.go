@@ -0,0 +1,87 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/carverauto/serviceradar/go/pkg/checker"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/proto"
+)
+
+const compositeCheckType = "composite"
+
+// NewCompositeChecker builds a composite check whose sub-checks are the
+// agent's own checkers. Supported sub-check types are icmp (alias ping); the
+// sub-check target is the host and settings["device_id"] the device it
+// reports for.
+func NewCompositeChecker(cfg checker.CompositeConfig, log logger.Logger) (*checker.Composite, error) {
+	return checker.NewComposite(cfg, func(sub checker.SubCheckConfig) (checker.Checker, error) {
+		return newSubChecker(sub, log)
+	})
+}
+
+func newSubChecker(sub checker.SubCheckConfig, log logger.Logger) (checker.Checker, error) {
+	checkType := strings.ToLower(strings.TrimSpace(sub.Type))
+	target := strings.TrimSpace(sub.Target)
+
+	switch checkType {
+	case "icmp", "ping":
+		if target == "" {
+			return nil, errSubCheckTarget
+		}
+
+		return NewICMPCheckerWithDeviceID(target, strings.TrimSpace(sub.Settings["device_id"]), log)
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnsupportedSubCheck, sub.Type)
+	}
+}
+
+// newCompositeCheckFromConfig builds a composite check from a gateway check
+// config. settings["checks"] holds the sub-checks as a JSON array of
+// checker.SubCheckConfig; settings["policy"] and settings["quorum"] set the
+// aggregation policy.
+func newCompositeCheckFromConfig(check *proto.AgentCheckConfig, log logger.Logger) (checker.Checker, error) {
+	cfg := checker.CompositeConfig{
+		Name:   strings.TrimSpace(check.Name),
+		Policy: checker.Policy(strings.ToLower(strings.TrimSpace(check.Settings["policy"]))),
+	}
+
+	rawChecks := strings.TrimSpace(check.Settings["checks"])
+	if rawChecks == "" {
+		return nil, errCompositeSubChecks
+	}
+
+	if err := json.Unmarshal([]byte(rawChecks), &cfg.Checks); err != nil {
+		return nil, fmt.Errorf("%w: %w", errCompositeSubChecks, err)
+	}
+
+	if rawQuorum := strings.TrimSpace(check.Settings["quorum"]); rawQuorum != "" {
+		quorum, err := strconv.Atoi(rawQuorum)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", errCompositeQuorum, rawQuorum)
+		}
+
+		cfg.Quorum = quorum
+	}
+
+	return NewCompositeChecker(cfg, log)
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/checker"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+func TestNewCompositeCheckerRejectsUnbuildableSubChecks(t *testing.T) {
+	log := logger.NewTestLogger()
+
+	_, err := NewCompositeChecker(checker.CompositeConfig{
+		Checks: []checker.SubCheckConfig{{Name: "web", Type: "http", Target: "https://example.com"}},
+	}, log)
+	require.ErrorIs(t, err, errUnsupportedSubCheck)
+
+	_, err = NewCompositeChecker(checker.CompositeConfig{
+		Checks: []checker.SubCheckConfig{{Name: "ping", Type: "icmp"}},
+	}, log)
+	require.ErrorIs(t, err, errSubCheckTarget)
+}
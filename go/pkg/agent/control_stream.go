@@ -45,6 +45,7 @@ const (
 	commandTypeCameraRelayOpen = "camera.open_relay"
 	commandTypeCameraRelayStop = "camera.close_relay"
 	commandTypeAgentUpdate     = "agent.update_release"
+	commandTypeSelfTest        = "agent.self_test"
 )
 
 const defaultOnDemandMtrDeadline = 45 * time.Second
@@ -338,6 +339,8 @@ func (p *PushLoop) handleCommand(ctx context.Context, cmd *proto.CommandRequest,
 			p.handleCameraRelayStop(ctx, cmd, sender)
 		case commandTypeAgentUpdate:
 			p.handleAgentUpdateRelease(ctx, cmd, sender)
+		case commandTypeSelfTest:
+			p.handleSelfTest(ctx, cmd, sender)
 		default:
 			_ = sender.Send(commandResult(cmd, false, "unsupported command", nil))
 		}
@@ -29,4 +29,21 @@ var (
 
 	// KV.
 	errDataServiceUnavailable = errors.New("data service unavailable")
+	errKVNotConfigured        = errors.New("kv address not configured")
+
+	// Sysmon.
+	errSysmonStreamNotAcknowledged = errors.New("gateway did not acknowledge sysmon metrics stream")
+
+	// Self-test.
+	errServiceUnavailable = errors.New("service reported unavailable")
+	errCheckFailed        = errors.New("check failed")
+
+	// Composite checks.
+	errUnsupportedSubCheck = errors.New("unsupported sub-check type")
+	errSubCheckTarget      = errors.New("sub-check target is required")
+	errCompositeSubChecks  = errors.New("composite check needs settings.checks as a JSON array of sub-checks")
+	errCompositeQuorum     = errors.New("composite check quorum must be an integer")
+
+	// Transaction checks.
+	errTransactionSteps = errors.New("transaction check needs settings.steps as a JSON array of steps")
 )
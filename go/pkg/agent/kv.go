@@ -25,6 +25,7 @@ import (
 
 	"github.com/carverauto/serviceradar/go/pkg/config/kv"
 	"github.com/carverauto/serviceradar/go/pkg/grpc"
+	"github.com/carverauto/serviceradar/go/pkg/models"
 	"github.com/carverauto/serviceradar/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -42,6 +43,48 @@ var (
 	_ ObjectStore = (*grpcRemoteStore)(nil)
 )
 
+// dialKVStore connects to the configured KV service. KVSecurity is used when
+// set, otherwise the agent's general security config.
+func (s *Server) dialKVStore(ctx context.Context) (KVStore, error) {
+	s.mu.RLock()
+	addr := s.config.KVAddress
+	security := s.config.KVSecurity
+	if security == nil {
+		security = s.config.Security
+	}
+	s.mu.RUnlock()
+
+	if addr == "" {
+		return nil, errKVNotConfigured
+	}
+
+	if security == nil {
+		security = &models.SecurityConfig{Mode: grpc.SecurityModeNone}
+	}
+
+	provider, err := grpc.NewSecurityProvider(ctx, security, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := grpc.NewClient(ctx, grpc.ClientConfig{
+		Address:          addr,
+		SecurityProvider: provider,
+		Logger:           s.logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conn := client.GetConnection()
+
+	return &grpcRemoteStore{
+		configClient: proto.NewKVServiceClient(conn),
+		objectClient: proto.NewDataServiceClient(conn),
+		conn:         client,
+	}, nil
+}
+
 func (s *grpcRemoteStore) Get(ctx context.Context, key string) (value []byte, found bool, err error) {
 	resp, err := s.configClient.Get(ctx, &proto.GetRequest{Key: key})
 	if err != nil {
@@ -47,6 +47,7 @@ type MapperService struct {
 	config       *mapper.Config
 	engine       mapper.Mapper
 	publisher    *MapperResultPublisher
+	stream       *mapper.StreamPublisher
 	configHash   string
 	engineCtx    context.Context
 	engineCancel context.CancelFunc
@@ -57,29 +58,67 @@ func NewMapperService(cfg *mapper.Config, log logger.Logger) (*MapperService, er
 		return nil, errMapperConfigRequired
 	}
 
-	publisher := NewMapperResultPublisher()
-	engine, err := mapper.NewDiscoveryEngine(cfg, publisher, log)
+	publisher, stream, err := newMapperPublishers(cfg, log)
 	if err != nil {
 		return nil, err
 	}
 
+	engine, err := mapper.NewDiscoveryEngine(cfg, mapper.NewMultiPublisher(publisher, streamPublisher(stream)), log)
+	if err != nil {
+		closeMapperStream(stream)
+		return nil, err
+	}
+
 	return &MapperService{
 		logger:    log,
 		config:    cfg,
 		engine:    engine,
 		publisher: publisher,
+		stream:    stream,
 	}, nil
 }
 
+// newMapperPublishers builds the pull buffer drained by the push loop and,
+// when stream_config selects one, the stream publisher results are also
+// pushed to.
+func newMapperPublishers(cfg *mapper.Config, log logger.Logger) (*MapperResultPublisher, *mapper.StreamPublisher, error) {
+	stream, err := mapper.NewStreamPublisher(context.Background(), cfg.StreamConfig, log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mapper stream publisher: %w", err)
+	}
+
+	return NewMapperResultPublisher(), stream, nil
+}
+
+// streamPublisher avoids handing a typed nil to NewMultiPublisher.
+func streamPublisher(stream *mapper.StreamPublisher) mapper.Publisher {
+	if stream == nil {
+		return nil
+	}
+
+	return stream
+}
+
+func closeMapperStream(stream *mapper.StreamPublisher) {
+	if stream != nil {
+		stream.Close()
+	}
+}
+
 func (s *MapperService) Start(ctx context.Context) error {
 	s.mu.RLock()
 	engine := s.engine
+	stream := s.stream
 	s.mu.RUnlock()
 
 	if engine == nil {
 		return errMapperEngineNotRunning
 	}
 
+	if stream != nil {
+		go stream.Run(ctx)
+	}
+
 	return engine.Start(ctx)
 }
 
@@ -87,6 +126,7 @@ func (s *MapperService) Stop(ctx context.Context) error {
 	s.mu.Lock()
 	engine := s.engine
 	engineCancel := s.engineCancel
+	stream := s.stream
 	s.mu.Unlock()
 
 	if engine == nil {
@@ -98,7 +138,14 @@ func (s *MapperService) Stop(ctx context.Context) error {
 		engineCancel()
 	}
 
-	return engine.Stop(ctx)
+	err := engine.Stop(ctx)
+
+	if stream != nil {
+		_ = stream.Flush(ctx)
+		stream.Close()
+	}
+
+	return err
 }
 
 func (*MapperService) Name() string {
@@ -144,12 +191,20 @@ func (s *MapperService) applyConfigWithHash(cfg *mapper.Config, hash string) err
 		s.engineCancel()
 	}
 
-	publisher := NewMapperResultPublisher()
-	engine, err := mapper.NewDiscoveryEngine(cfg, publisher, s.logger)
+	closeMapperStream(s.stream)
+	s.stream = nil
+
+	publisher, stream, err := newMapperPublishers(cfg, s.logger)
 	if err != nil {
 		return err
 	}
 
+	engine, err := mapper.NewDiscoveryEngine(cfg, mapper.NewMultiPublisher(publisher, streamPublisher(stream)), s.logger)
+	if err != nil {
+		closeMapperStream(stream)
+		return err
+	}
+
 	// Create a long-lived context for the engine that won't be canceled
 	// when this function returns. It will only be canceled when Stop is called
 	// or when a new config is applied.
@@ -157,12 +212,18 @@ func (s *MapperService) applyConfigWithHash(cfg *mapper.Config, hash string) err
 
 	if err := engine.Start(engineCtx); err != nil {
 		engineCancel()
+		closeMapperStream(stream)
 		return err
 	}
 
+	if stream != nil {
+		go stream.Run(engineCtx)
+	}
+
 	s.config = cfg
 	s.engine = engine
 	s.publisher = publisher
+	s.stream = stream
 	s.engineCtx = engineCtx
 	s.engineCancel = engineCancel
 	if hash != "" {
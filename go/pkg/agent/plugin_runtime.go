@@ -727,6 +727,7 @@ func (m *PluginManager) enqueueResult(result PluginResult) {
 type pluginRunner struct {
 	manager    *PluginManager
 	assignment *pluginAssignment
+	breaker    *checkerBreaker
 	cancel     context.CancelFunc
 	done       chan struct{}
 }
@@ -735,6 +736,7 @@ func newPluginRunner(manager *PluginManager, assignment *pluginAssignment) *plug
 	return &pluginRunner{
 		manager:    manager,
 		assignment: assignment,
+		breaker:    newCheckerBreaker("plugin", assignment.AssignmentID, manager.logger),
 		done:       make(chan struct{}),
 	}
 }
@@ -774,7 +776,21 @@ func (r *pluginRunner) stop() {
 	<-r.done
 }
 
+// runOnce executes the plugin with panic isolation. A panicking run is
+// reported as a failed result; once the breaker opens, the result announcing
+// it is the last one until the assignment is reconfigured.
 func (r *pluginRunner) runOnce(ctx context.Context) {
+	if r.breaker.Disabled() {
+		return
+	}
+
+	if err := r.breaker.run(func() { r.execute(ctx) }); err != nil {
+		r.manager.recordExecution(false)
+		r.manager.enqueueResult(buildPluginErrorResult(r.assignment, err.Error()))
+	}
+}
+
+func (r *pluginRunner) execute(ctx context.Context) {
 	timeout := r.assignment.Timeout
 	if timeout <= 0 {
 		timeout = pluginDefaultTimeout
@@ -29,6 +29,7 @@ import (
 	"net"
 	"os"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -92,6 +93,7 @@ type snmpMetricResult struct {
 	Timestamp    time.Time   `json:"timestamp"`
 	DataType     string      `json:"data_type,omitempty"`
 	Scale        float64     `json:"scale,omitempty"`
+	Unit         string      `json:"unit,omitempty"`
 	Delta        bool        `json:"delta,omitempty"`
 	IfIndex      *int        `json:"if_index,omitempty"`
 	InterfaceUID string      `json:"interface_uid,omitempty"`
@@ -116,9 +118,12 @@ type PushLoop struct {
 	sweepResultsSeq           string
 	icmpChecks                map[string]*icmpCheckConfig
 	icmpLastRun               map[string]time.Time
+	icmpBreakers              map[string]*checkerBreaker
 	icmpMu                    sync.RWMutex
 	sysmonLastSent            time.Time
 	sysmonMu                  sync.RWMutex
+	sysmonBuffer              *sysmonFlushBuffer
+	selfMonitor               *selfMonitor
 	statusDebounce            time.Duration
 	statusHeartbeat           time.Duration
 	statusDebounceConfigured  bool
@@ -126,12 +131,16 @@ type PushLoop struct {
 	lastStatusPush            time.Time
 	lastStatusSignature       string
 	syncRuntime               *SyncRuntime
+	resultSchemaVersion       int // Highest checker result schema version core accepts (0: not advertised)
 	mtrState                  *mtrCheckerState
+	scheduledChecks           *scheduledCheckState
 	mtrOnDemandSem            chan struct{}
 	mtrBulkJobSem             chan struct{}
 	cameraRelayManager        *cameraRelayManager
+	icmpCheckRunner           func(context.Context, *icmpCheckConfig) icmpCheckResult // overrides runICMPCheck in tests
+	kvDialer                  func(context.Context) (KVStore, error)                  // overrides Server.dialKVStore in tests
 
-	stateMu  sync.RWMutex // Protects interval, configPollInterval, enrolled, configVersion, started
+	stateMu  sync.RWMutex // Protects interval, configPollInterval, enrolled, configVersion, started, resultSchemaVersion
 	cancelMu sync.Mutex
 	cancel   context.CancelFunc
 }
@@ -304,6 +313,23 @@ func NewPushLoop(server *Server, gateway *agentgateway.GatewayClient, interval t
 	}
 	debounce, heartbeat := clampStatusIntervals(interval, defaultStatusHeartbeatInterval, interval)
 	cameraRelayManager := newCameraRelayManager(gateway, log)
+
+	var sysmonFlush *SysmonFlushConfig
+
+	var self *selfMonitor
+
+	if server != nil && server.config != nil {
+		sysmonFlush = server.config.SysmonFlush
+
+		if cfg := server.config.SelfMonitor; cfg != nil && cfg.Enabled {
+			monitor, err := newSelfMonitor()
+			if err != nil {
+				log.Warn().Err(err).Msg("Agent self-monitoring disabled")
+			} else {
+				self = monitor
+			}
+		}
+	}
 	cameraRelayManager.pluginSourceFactory = func(ctx context.Context, spec cameraRelaySessionSpec) (cameraRelayChunkStream, error) {
 		server.mu.RLock()
 		pluginManager := server.pluginManager
@@ -326,13 +352,17 @@ func NewPushLoop(server *Server, gateway *agentgateway.GatewayClient, interval t
 		configPollInterval: defaultConfigPollInterval,
 		icmpChecks:         make(map[string]*icmpCheckConfig),
 		icmpLastRun:        make(map[string]time.Time),
+		icmpBreakers:       make(map[string]*checkerBreaker),
 		statusDebounce:     debounce,
 		statusHeartbeat:    heartbeat,
 		syncRuntime:        NewSyncRuntime(server, gateway, log),
 		mtrState:           newMtrCheckerState(),
+		scheduledChecks:    newScheduledCheckState(),
 		mtrOnDemandSem:     make(chan struct{}, defaultMaxConcurrentOnDemandMtr),
 		mtrBulkJobSem:      make(chan struct{}, 1),
 		cameraRelayManager: cameraRelayManager,
+		sysmonBuffer:       newSysmonFlushBuffer(sysmonFlush),
+		selfMonitor:        self,
 	}
 }
 
@@ -363,6 +393,8 @@ func (p *PushLoop) Start(ctx context.Context) error {
 
 	if p.syncRuntime != nil {
 		p.syncRuntime.SetContext(runCtx)
+
+		go p.syncRuntime.ServeWebhooks(runCtx)
 	}
 
 	// Start config polling in a separate goroutine
@@ -378,10 +410,12 @@ func (p *PushLoop) Start(ctx context.Context) error {
 		select {
 		case <-runCtx.Done():
 			p.logger.Info().Msg("Push loop stopping due to context cancellation")
+			p.flushSysmonBuffer()
 			return runCtx.Err()
 
 		case <-p.stopCh:
 			p.logger.Info().Msg("Push loop stopping due to Stop()")
+			p.flushSysmonBuffer()
 			return context.Canceled
 
 		case <-timer.C:
@@ -749,6 +783,7 @@ func (p *PushLoop) pushStatus(ctx context.Context) {
 	sentSNMPMetrics := p.pushSNMPMetrics(ctx)
 	sentPluginResults := p.pushPluginResults(ctx)
 	sentPluginTelemetry := p.pushPluginTelemetry(ctx)
+	sentSelfMetrics := p.pushSelfMetrics(ctx)
 
 	if len(statuses) == 0 &&
 		sysmonStatus == nil &&
@@ -760,7 +795,8 @@ func (p *PushLoop) pushStatus(ctx context.Context) {
 		!sentMapperTopology &&
 		!sentSNMPMetrics &&
 		!sentPluginResults &&
-		!sentPluginTelemetry {
+		!sentPluginTelemetry &&
+		!sentSelfMetrics {
 		p.logger.Debug().Msg("No statuses to push")
 	}
 }
@@ -846,93 +882,158 @@ func (p *PushLoop) pushRegularStatuses(ctx context.Context, statuses []*proto.Ga
 }
 
 // pushSysmonStatus sends sysmon metrics via StreamStatus for large payloads.
+// Drained samples pass through the sysmon flush buffer, so with batching
+// configured they may be held back until the size or max-age trigger fires.
+// Samples from a flush the gateway does not accept are requeued.
 func (p *PushLoop) pushSysmonStatus(ctx context.Context, status *proto.GatewayServiceStatus) {
 	p.server.mu.RLock()
-	agentID := p.server.config.AgentID
-	partition := p.server.config.Partition
 	sysmonSvc := p.server.sysmonService
 	p.server.mu.RUnlock()
-	gatewayID := p.gateway.GetGatewayID()
-	runtimeMetadata := currentRuntimeMetadata()
 
-	var chunks []*proto.GatewayStatusChunk
+	now := time.Now()
 
 	// If service is available, drain buffered metrics for transmission
-	if sysmonSvc != nil {
-		if samples := sysmonSvc.DrainMetrics(); len(samples) > 0 {
-			// Convert each sample into a separate status message to ensure
-			// full-fidelity time-series ingestion at the gateway.
-			for _, sample := range samples {
-				s := p.convertToSysmonGatewayStatusFromSample(sample)
-				if s == nil {
-					continue
-				}
-				// Append a new chunk for each sample.
-				chunks = append(chunks, &proto.GatewayStatusChunk{
-					Services:  []*proto.GatewayServiceStatus{s},
-					GatewayId: gatewayID,
-					AgentId:   agentID,
-					Timestamp: time.Now().UnixNano(),
-					Partition: partition,
-					SourceIp:  p.getSourceIP(),
-					Version:   runtimeMetadata.Version,
-					Hostname:  runtimeMetadata.Hostname,
-					Os:        runtimeMetadata.Os,
-					Arch:      runtimeMetadata.Arch,
-				})
-			}
+	if sysmonSvc != nil && p.sysmonBuffer != nil {
+		p.logDroppedSysmonSamples(p.sysmonBuffer.add(now, sysmonSvc.DrainMetrics()))
+	}
+
+	var (
+		samples []*sysmon.MetricSample
+		since   time.Time
+	)
+
+	if p.sysmonBuffer != nil {
+		samples, since = p.sysmonBuffer.take(now, false)
+		if len(samples) == 0 && p.sysmonBuffer.buffered() > 0 {
+			// Samples are accumulating toward the next batch; skip the heartbeat.
+			return
 		}
 	}
 
-	// If no buffered metrics (or service nil), fall back to sending the current status (heartbeat)
-	if len(chunks) == 0 && status != nil {
-		chunks = append(chunks, &proto.GatewayStatusChunk{
-			Services:    []*proto.GatewayServiceStatus{status},
-			GatewayId:   gatewayID,
-			AgentId:     agentID,
-			Timestamp:   time.Now().UnixNano(),
-			Partition:   partition,
-			SourceIp:    p.getSourceIP(),
-			IsFinal:     true,
-			ChunkIndex:  0,
-			TotalChunks: 1,
-			Version:     runtimeMetadata.Version,
-			Hostname:    runtimeMetadata.Hostname,
-			Os:          runtimeMetadata.Os,
-			Arch:        runtimeMetadata.Arch,
-		})
+	chunks := p.buildSysmonChunks(samples, status)
+	if len(chunks) == 0 {
+		return
+	}
+
+	err := p.streamSysmonChunks(ctx, chunks, len(samples))
+	if err == nil || len(samples) == 0 {
+		return
+	}
+
+	if isRejectedSysmonPayload(err) {
+		p.logger.Warn().Int("dropped", len(samples)).Msg("Gateway rejected sysmon payload; dropping samples")
+		return
+	}
+
+	p.logDroppedSysmonSamples(p.sysmonBuffer.requeue(since, samples))
+}
+
+// isRejectedSysmonPayload reports whether the gateway refused the payload
+// itself, in which case resending the same samples cannot succeed.
+func isRejectedSysmonPayload(err error) bool {
+	switch status.Code(err) {
+	case codes.ResourceExhausted, codes.InvalidArgument:
+		return true
+	default:
+		return false
+	}
+}
+
+// flushSysmonBuffer streams any samples still held by the flush buffer. It is
+// called on shutdown so batching never drops collected metrics.
+func (p *PushLoop) flushSysmonBuffer() {
+	if p.sysmonBuffer == nil || p.gateway == nil || p.server == nil {
+		return
+	}
+
+	samples, _ := p.sysmonBuffer.take(time.Now(), true)
+	if len(samples) == 0 {
+		return
 	}
 
+	chunks := p.buildSysmonChunks(samples, nil)
 	if len(chunks) == 0 {
 		return
 	}
 
-	// Update chunk metadata
-	totalChunks := int32(len(chunks))
-	for i, chunk := range chunks {
-		chunk.ChunkIndex = int32(i)
-		chunk.TotalChunks = totalChunks
-		chunk.IsFinal = int32(i) == totalChunks-1
+	p.logger.Info().Int("sample_count", len(samples)).Msg("Flushing buffered sysmon metrics before shutdown")
+
+	_ = p.streamSysmonChunks(context.Background(), chunks, len(samples))
+}
+
+func (p *PushLoop) logDroppedSysmonSamples(dropped int) {
+	if dropped > 0 {
+		p.logger.Warn().Int("dropped", dropped).Msg("Sysmon flush buffer full; dropped oldest samples")
+	}
+}
+
+// buildSysmonChunks aggregates samples into a single sysmon status, falling
+// back to the current status (heartbeat) when there are no samples.
+func (p *PushLoop) buildSysmonChunks(samples []*sysmon.MetricSample, status *proto.GatewayServiceStatus) []*proto.GatewayStatusChunk {
+	p.server.mu.RLock()
+	agentID := p.server.config.AgentID
+	partition := p.server.config.Partition
+	p.server.mu.RUnlock()
+	gatewayID := p.gateway.GetGatewayID()
+	runtimeMetadata := currentRuntimeMetadata()
+
+	// If no buffered metrics (or service nil), fall back to sending the current status (heartbeat)
+	if s := p.convertToSysmonGatewayStatusFromSamples(samples); s != nil {
+		status = s
 	}
 
+	if status == nil {
+		return nil
+	}
+
+	return []*proto.GatewayStatusChunk{{
+		Services:    []*proto.GatewayServiceStatus{status},
+		GatewayId:   gatewayID,
+		AgentId:     agentID,
+		Timestamp:   time.Now().UnixNano(),
+		Partition:   partition,
+		SourceIp:    p.getSourceIP(),
+		IsFinal:     true,
+		ChunkIndex:  0,
+		TotalChunks: 1,
+		Version:     runtimeMetadata.Version,
+		Hostname:    runtimeMetadata.Hostname,
+		Os:          runtimeMetadata.Os,
+		Arch:        runtimeMetadata.Arch,
+	}}
+}
+
+// streamSysmonChunks returns nil once the gateway has accepted the stream.
+func (p *PushLoop) streamSysmonChunks(ctx context.Context, chunks []*proto.GatewayStatusChunk, sampleCount int) error {
 	pushCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	resp, err := p.gateway.StreamStatus(pushCtx, chunks)
 	if err != nil {
 		p.logger.Error().Err(err).Msg("Failed to stream sysmon metrics to gateway")
-		return
+		return err
 	}
 
-	if resp.Received {
-		p.logger.Info().Int("sample_count", len(chunks)).Msg("Successfully streamed sysmon metrics to gateway")
-	} else {
+	if !resp.Received {
 		p.logger.Warn().Msg("Gateway did not acknowledge sysmon metrics stream")
+		return errSysmonStreamNotAcknowledged
 	}
+
+	p.logger.Info().Int("sample_count", sampleCount).Msg("Successfully streamed sysmon metrics to gateway")
+
+	return nil
 }
 
-func (p *PushLoop) convertToSysmonGatewayStatusFromSample(sample *sysmon.MetricSample) *proto.GatewayServiceStatus {
-	if sample == nil {
+// convertToSysmonGatewayStatusFromSamples wraps samples in one sysmon status.
+// The newest sample goes in "status", as for a single sample, and any older
+// ones in "samples", oldest first, so consumers that only read "status" still
+// see the latest metrics.
+func (p *PushLoop) convertToSysmonGatewayStatusFromSamples(samples []*sysmon.MetricSample) *proto.GatewayServiceStatus {
+	samples = slices.DeleteFunc(slices.Clone(samples), func(sample *sysmon.MetricSample) bool {
+		return sample == nil
+	})
+
+	if len(samples) == 0 {
 		return nil
 	}
 
@@ -945,13 +1046,15 @@ func (p *PushLoop) convertToSysmonGatewayStatusFromSample(sample *sysmon.MetricS
 
 	// Build the response payload
 	payload := struct {
-		Available    bool                 `json:"available"`
-		ResponseTime int64                `json:"response_time"`
-		Status       *sysmon.MetricSample `json:"status"`
+		Available    bool                   `json:"available"`
+		ResponseTime int64                  `json:"response_time"`
+		Status       *sysmon.MetricSample   `json:"status"`
+		Samples      []*sysmon.MetricSample `json:"samples,omitempty"`
 	}{
 		Available:    true,
 		ResponseTime: 0, // Drained metrics don't have response time tracking easily available
-		Status:       sample,
+		Status:       samples[len(samples)-1],
+		Samples:      samples[:len(samples)-1],
 	}
 
 	messageBytes, err := json.Marshal(payload)
@@ -1244,11 +1347,13 @@ func (p *PushLoop) buildSNMPDrainedResults(
 		oidValue := ""
 		dataType := ""
 		scale := 1.0
+		unit := ""
 		delta := false
 		if ok {
 			oidValue = oidConfig.OID
 			dataType = string(oidConfig.DataType)
 			scale = oidConfig.Scale
+			unit = oidConfig.Unit
 			delta = oidConfig.Delta
 		}
 
@@ -1265,6 +1370,7 @@ func (p *PushLoop) buildSNMPDrainedResults(
 				Timestamp:    point.Timestamp,
 				DataType:     dataType,
 				Scale:        scale,
+				Unit:         unit,
 				Delta:        delta,
 				InterfaceUID: interfaceUID,
 			}
@@ -1519,8 +1625,10 @@ func (p *PushLoop) collectDueICMPResults(ctx context.Context) []icmpCheckResult
 			continue
 		}
 
-		result := p.runICMPCheck(ctx, check)
-		results = append(results, result)
+		result, ran := p.checkICMP(ctx, check)
+		if ran {
+			results = append(results, result)
+		}
 
 		p.icmpMu.Lock()
 		p.icmpLastRun[check.ID] = now
@@ -1530,6 +1638,57 @@ func (p *PushLoop) collectDueICMPResults(ctx context.Context) []icmpCheckResult
 	return results
 }
 
+// checkICMP runs one ICMP check with panic isolation. A panicking check
+// reports an unavailable result for the cycle and is skipped once its breaker
+// opens.
+func (p *PushLoop) checkICMP(ctx context.Context, check *icmpCheckConfig) (icmpCheckResult, bool) {
+	breaker := p.icmpBreaker(check.ID)
+	if breaker.Disabled() {
+		return icmpCheckResult{}, false
+	}
+
+	var result icmpCheckResult
+
+	err := breaker.run(func() {
+		if p.icmpCheckRunner != nil {
+			result = p.icmpCheckRunner(ctx, check)
+			return
+		}
+
+		result = p.runICMPCheck(ctx, check)
+	})
+	if err != nil {
+		result = icmpCheckResult{
+			CheckID:   check.ID,
+			CheckName: check.Name,
+			Target:    check.Target,
+			DeviceID:  check.DeviceID,
+			Available: false,
+			Timestamp: time.Now().UnixNano(),
+			Error:     err.Error(),
+		}
+	}
+
+	return result, true
+}
+
+func (p *PushLoop) icmpBreaker(checkID string) *checkerBreaker {
+	p.icmpMu.Lock()
+	defer p.icmpMu.Unlock()
+
+	if p.icmpBreakers == nil {
+		p.icmpBreakers = make(map[string]*checkerBreaker)
+	}
+
+	breaker, ok := p.icmpBreakers[checkID]
+	if !ok {
+		breaker = newCheckerBreaker("icmp", checkID, p.logger)
+		p.icmpBreakers[checkID] = breaker
+	}
+
+	return breaker
+}
+
 func (p *PushLoop) runICMPCheck(ctx context.Context, check *icmpCheckConfig) icmpCheckResult {
 	timeout := check.Timeout
 	if timeout <= 0 {
@@ -1787,6 +1946,8 @@ func (p *PushLoop) collectAllStatusesSeparated(ctx context.Context) ([]*proto.Ga
 		p.logger.Warn().Err(err).Msg("Failed to get SNMP status")
 	}
 
+	statuses = append(statuses, p.collectScheduledCheckStatuses(ctx)...)
+
 	return statuses, sysmonStatus
 }
 
@@ -1820,7 +1981,7 @@ func (p *PushLoop) convertToGatewayStatus(resp *proto.StatusResponse, serviceNam
 	return &proto.GatewayServiceStatus{
 		ServiceName:  serviceName,
 		Available:    resp.Available,
-		Message:      resp.Message,
+		Message:      p.stampResult(resp.Message),
 		ServiceType:  serviceType,
 		ResponseTime: resp.ResponseTime,
 		AgentId:      agentID,
@@ -1848,7 +2009,7 @@ func (p *PushLoop) convertToSysmonGatewayStatus(resp *proto.StatusResponse) *pro
 	return &proto.GatewayServiceStatus{
 		ServiceName:  SysmonServiceName,
 		Available:    resp.Available,
-		Message:      resp.Message,
+		Message:      p.stampResult(resp.Message),
 		ServiceType:  SysmonServiceType,
 		ResponseTime: resp.ResponseTime,
 		AgentId:      agentID,
@@ -1877,7 +2038,7 @@ func (p *PushLoop) buildPluginGatewayStatus(
 	return &proto.GatewayServiceStatus{
 		ServiceName:  serviceName,
 		Available:    available,
-		Message:      payload,
+		Message:      p.stampResult(payload),
 		ServiceType:  "plugin",
 		ResponseTime: 0,
 		AgentId:      agentID,
@@ -2642,6 +2803,7 @@ func (p *PushLoop) applyConfigResponse(configResp *proto.AgentConfigResponse, so
 		}
 	}
 
+	p.applyResultSchemaVersion(configResp.ConfigJson)
 	p.applySweepConfig(configResp.ConfigJson)
 	p.applyMapperConfig(configResp.ConfigJson)
 	if p.syncRuntime != nil {
@@ -2983,6 +3145,7 @@ func (p *PushLoop) applySysmonConfig(protoConfig *proto.SysmonConfig) {
 		Bool("cpu", cfg.CollectCPU).
 		Bool("memory", cfg.CollectMemory).
 		Bool("disk", cfg.CollectDisk).
+		Bool("disk_io", cfg.CollectDiskIO).
 		Bool("network", cfg.CollectNetwork).
 		Bool("processes", cfg.CollectProcesses).
 		Msg("Applied sysmon config from gateway")
@@ -3000,6 +3163,7 @@ func protoToSysmonConfig(proto *proto.SysmonConfig) sysmon.Config {
 		CollectCPU:       proto.CollectCpu,
 		CollectMemory:    proto.CollectMemory,
 		CollectDisk:      proto.CollectDisk,
+		CollectDiskIO:    proto.CollectDiskIo,
 		CollectNetwork:   proto.CollectNetwork,
 		CollectProcesses: proto.CollectProcesses,
 		DiskPaths:        proto.DiskPaths,
@@ -3042,12 +3206,19 @@ func (p *PushLoop) applyCheckConfigs(checks []*proto.AgentCheckConfig) {
 	}
 
 	p.icmpMu.Lock()
+	previous := p.icmpChecks
 	p.icmpChecks = parsed
 	for id := range p.icmpLastRun {
 		if _, ok := parsed[id]; !ok {
 			delete(p.icmpLastRun, id)
 		}
 	}
+	// Removing or changing a check re-enables it after its breaker opened.
+	for id := range p.icmpBreakers {
+		if cfg, ok := parsed[id]; !ok || previous[id] == nil || *previous[id] != *cfg {
+			delete(p.icmpBreakers, id)
+		}
+	}
 	p.icmpMu.Unlock()
 
 	if len(parsed) > 0 {
@@ -3058,6 +3229,9 @@ func (p *PushLoop) applyCheckConfigs(checks []*proto.AgentCheckConfig) {
 
 	// Apply MTR check configs from the same check list.
 	p.applyMtrCheckConfigs(checks)
+
+	// Apply checker-backed check configs (composite, transaction) from the same check list.
+	p.applyScheduledCheckConfigs(checks)
 }
 
 func parseICMPCheckConfig(check *proto.AgentCheckConfig) *icmpCheckConfig {
@@ -3156,10 +3330,13 @@ func getAgentCapabilities() []string {
 	return []string{
 		"icmp",
 		"mtr",
+		compositeCheckType,
+		transactionCheckType,
 		sweepType,
 		"snmp",
 		"mapper",
 		"sync",
 		"sysmon",
+		"self_test",
 	}
 }
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"encoding/json"
+
+	"github.com/carverauto/serviceradar/go/pkg/checkerresult"
+)
+
+// parseResultSchemaVersion returns the checker result schema version core
+// advertises in config_json, or 0 when it does not advertise one.
+func parseResultSchemaVersion(configJSON []byte) int {
+	if len(configJSON) == 0 {
+		return 0
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(configJSON, &payload); err != nil {
+		return 0
+	}
+
+	var version int
+	if err := json.Unmarshal(payload[checkerresult.ConfigKey], &version); err != nil {
+		return 0
+	}
+
+	return version
+}
+
+func (p *PushLoop) applyResultSchemaVersion(configJSON []byte) {
+	version := parseResultSchemaVersion(configJSON)
+
+	p.stateMu.Lock()
+	changed := version != p.resultSchemaVersion
+	p.resultSchemaVersion = version
+	p.stateMu.Unlock()
+
+	if changed {
+		p.logger.Info().
+			Int("core_max_version", version).
+			Int("negotiated_version", checkerresult.Negotiate(checkerresult.CurrentVersion, version)).
+			Msg("Negotiated checker result schema version")
+	}
+}
+
+// stampResult marks a checker payload with the schema version negotiated
+// with core. Cores that never advertised a version receive legacy payloads.
+func (p *PushLoop) stampResult(payload []byte) []byte {
+	p.stateMu.RLock()
+	peerMax := p.resultSchemaVersion
+	p.stateMu.RUnlock()
+
+	return checkerresult.Stamp(payload, checkerresult.Negotiate(checkerresult.CurrentVersion, peerMax))
+}
@@ -0,0 +1,22 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+func TestStampResultFollowsCoreAdvertisedVersion(t *testing.T) {
+	p := &PushLoop{logger: logger.NewTestLogger()}
+	payload := []byte(`{"available":true}`)
+
+	assert.Equal(t, payload, p.stampResult(payload), "legacy cores receive unversioned payloads")
+
+	p.applyResultSchemaVersion([]byte(`{"sweep":{},"result_schema_version":2}`))
+	assert.JSONEq(t, `{"available":true,"schema_version":2}`, string(p.stampResult(payload)))
+
+	p.applyResultSchemaVersion([]byte(`{"sweep":{}}`))
+	assert.Equal(t, payload, p.stampResult(payload))
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carverauto/serviceradar/go/pkg/checker"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/proto"
+)
+
+const defaultScheduledCheckTimeout = 30 * time.Second
+
+// scheduledCheckBuilder builds the checker for one gateway check config.
+type scheduledCheckBuilder func(check *proto.AgentCheckConfig, log logger.Logger) (checker.Checker, error)
+
+// scheduledCheckBuilders maps the gateway check types that run through a
+// checker.Checker to their builders. Their latest results are reported as
+// regular service statuses.
+var scheduledCheckBuilders = map[string]scheduledCheckBuilder{
+	compositeCheckType:   newCompositeCheckFromConfig,
+	transactionCheckType: newTransactionCheckFromConfig,
+}
+
+type scheduledCheck struct {
+	id          string
+	name        string
+	checkType   string
+	interval    time.Duration
+	timeout     time.Duration
+	fingerprint string
+	checker     checker.Checker
+
+	lastRun time.Time
+	result  *proto.StatusResponse
+}
+
+// scheduledCheckState holds the gateway-configured checks built by
+// scheduledCheckBuilders.
+type scheduledCheckState struct {
+	mu     sync.Mutex
+	checks map[string]*scheduledCheck
+}
+
+func newScheduledCheckState() *scheduledCheckState {
+	return &scheduledCheckState{checks: make(map[string]*scheduledCheck)}
+}
+
+// applyScheduledCheckConfigs rebuilds the scheduled checks from the gateway
+// check list. Unchanged checks keep their checker and last result; removed or
+// changed ones are closed.
+func (p *PushLoop) applyScheduledCheckConfigs(checks []*proto.AgentCheckConfig) {
+	state := p.scheduledChecks
+	if state == nil {
+		return
+	}
+
+	state.mu.Lock()
+	previous := state.checks
+	state.mu.Unlock()
+
+	parsed := make(map[string]*scheduledCheck)
+
+	for _, check := range checks {
+		id, build, ok := scheduledCheckBuilderFor(check)
+		if !ok {
+			continue
+		}
+
+		fingerprint := scheduledCheckFingerprint(check)
+		if existing, ok := previous[id]; ok && existing.fingerprint == fingerprint {
+			parsed[id] = existing
+
+			continue
+		}
+
+		built, err := build(check, p.logger)
+		if err != nil {
+			p.logger.Warn().Err(err).Str("check_id", id).Str("check_type", check.CheckType).
+				Msg("Skipping invalid check config from gateway")
+
+			continue
+		}
+
+		parsed[id] = &scheduledCheck{
+			id:          id,
+			name:        strings.TrimSpace(check.Name),
+			checkType:   strings.ToLower(strings.TrimSpace(check.CheckType)),
+			interval:    time.Duration(check.IntervalSec) * time.Second,
+			timeout:     time.Duration(check.TimeoutSec) * time.Second,
+			fingerprint: fingerprint,
+			checker:     built,
+		}
+	}
+
+	state.mu.Lock()
+	state.checks = parsed
+	state.mu.Unlock()
+
+	for id, check := range previous {
+		if parsed[id] != check {
+			closeScheduledCheck(check, p.logger)
+		}
+	}
+
+	if len(parsed) > 0 {
+		p.logger.Info().Int("scheduled_checks", len(parsed)).Msg("Applied scheduled check config from gateway")
+	}
+}
+
+func scheduledCheckBuilderFor(check *proto.AgentCheckConfig) (string, scheduledCheckBuilder, bool) {
+	if check == nil || !check.Enabled {
+		return "", nil, false
+	}
+
+	build, ok := scheduledCheckBuilders[strings.ToLower(strings.TrimSpace(check.CheckType))]
+	if !ok {
+		return "", nil, false
+	}
+
+	id := strings.TrimSpace(check.CheckId)
+	if id == "" {
+		return "", nil, false
+	}
+
+	return id, build, true
+}
+
+// scheduledCheckFingerprint identifies a check config so an unchanged config
+// keeps its checker across config polls.
+func scheduledCheckFingerprint(check *proto.AgentCheckConfig) string {
+	data, err := json.Marshal(check)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+func closeScheduledCheck(check *scheduledCheck, log logger.Logger) {
+	closer, ok := check.checker.(interface{ Close(context.Context) error })
+	if !ok {
+		return
+	}
+
+	if err := closer.Close(context.Background()); err != nil {
+		log.Warn().Err(err).Str("check_id", check.id).Msg("Failed to close scheduled check")
+	}
+}
+
+// collectScheduledCheckStatuses runs the scheduled checks that are due and
+// returns the latest result of every check that has run as a service status.
+func (p *PushLoop) collectScheduledCheckStatuses(ctx context.Context) []*proto.GatewayServiceStatus {
+	state := p.scheduledChecks
+	if state == nil {
+		return nil
+	}
+
+	state.mu.Lock()
+	checks := make([]*scheduledCheck, 0, len(state.checks))
+	for _, check := range state.checks {
+		checks = append(checks, check)
+	}
+	state.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]*proto.GatewayServiceStatus, 0, len(checks))
+
+	for _, check := range checks {
+		interval := check.interval
+		if interval <= 0 {
+			interval = p.getInterval()
+		}
+
+		state.mu.Lock()
+		due := check.lastRun.IsZero() || now.Sub(check.lastRun) >= interval
+		state.mu.Unlock()
+
+		if due {
+			result := p.runScheduledCheck(ctx, check)
+
+			state.mu.Lock()
+			check.lastRun = now
+			check.result = result
+			state.mu.Unlock()
+		}
+
+		state.mu.Lock()
+		result := check.result
+		state.mu.Unlock()
+
+		if result == nil {
+			continue
+		}
+
+		name := check.name
+		if name == "" {
+			name = check.id
+		}
+
+		if status := p.convertToGatewayStatus(result, name, check.checkType); status != nil {
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses
+}
+
+func (p *PushLoop) runScheduledCheck(ctx context.Context, check *scheduledCheck) *proto.StatusResponse {
+	timeout := check.timeout
+	if timeout <= 0 {
+		timeout = defaultScheduledCheckTimeout
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	available, message := check.checker.Check(checkCtx, &proto.StatusRequest{
+		ServiceName: check.name,
+		ServiceType: check.checkType,
+	})
+
+	return &proto.StatusResponse{
+		Available:    available,
+		Message:      message,
+		ServiceName:  check.name,
+		ServiceType:  check.checkType,
+		ResponseTime: time.Since(start).Nanoseconds(),
+	}
+}
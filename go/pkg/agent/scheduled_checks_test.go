@@ -0,0 +1,219 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/agentgateway"
+	"github.com/carverauto/serviceradar/go/pkg/checker"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/proto"
+)
+
+type countingChecker struct {
+	calls  atomic.Int32
+	closed atomic.Bool
+}
+
+func (c *countingChecker) Check(_ context.Context, _ *proto.StatusRequest) (bool, json.RawMessage) {
+	c.calls.Add(1)
+
+	return true, json.RawMessage(`{"passed":1,"total":1,"available":true}`)
+}
+
+func (c *countingChecker) Close(_ context.Context) error {
+	c.closed.Store(true)
+
+	return nil
+}
+
+func withScheduledCheckBuilder(t *testing.T, checkType string, build scheduledCheckBuilder) {
+	t.Helper()
+
+	previous, had := scheduledCheckBuilders[checkType]
+	scheduledCheckBuilders[checkType] = build
+
+	t.Cleanup(func() {
+		if had {
+			scheduledCheckBuilders[checkType] = previous
+		} else {
+			delete(scheduledCheckBuilders, checkType)
+		}
+	})
+}
+
+func TestApplyScheduledCheckConfigsReusesUnchangedChecks(t *testing.T) {
+	var built []*countingChecker
+
+	withScheduledCheckBuilder(t, "fake", func(*proto.AgentCheckConfig, logger.Logger) (checker.Checker, error) {
+		c := &countingChecker{}
+		built = append(built, c)
+
+		return c, nil
+	})
+
+	pl := NewPushLoop(nil, nil, 30*time.Second, logger.NewTestLogger())
+	check := &proto.AgentCheckConfig{CheckId: "c1", CheckType: "fake", Name: "Fake", Enabled: true}
+
+	pl.applyScheduledCheckConfigs([]*proto.AgentCheckConfig{check})
+	pl.applyScheduledCheckConfigs([]*proto.AgentCheckConfig{check})
+	require.Len(t, built, 1)
+	assert.False(t, built[0].closed.Load())
+
+	changed := &proto.AgentCheckConfig{CheckId: "c1", CheckType: "fake", Name: "Fake", Enabled: true, IntervalSec: 10}
+	pl.applyScheduledCheckConfigs([]*proto.AgentCheckConfig{changed})
+	require.Len(t, built, 2)
+	assert.True(t, built[0].closed.Load())
+
+	pl.applyScheduledCheckConfigs(nil)
+	assert.True(t, built[1].closed.Load())
+	assert.Empty(t, pl.scheduledChecks.checks)
+}
+
+func TestCollectScheduledCheckStatusesHonorsInterval(t *testing.T) {
+	fake := &countingChecker{}
+
+	withScheduledCheckBuilder(t, "fake", func(*proto.AgentCheckConfig, logger.Logger) (checker.Checker, error) {
+		return fake, nil
+	})
+
+	pl := NewPushLoop(&Server{config: &ServerConfig{AgentID: "agent-1"}}, &agentgateway.GatewayClient{},
+		30*time.Second, logger.NewTestLogger())
+	pl.applyScheduledCheckConfigs([]*proto.AgentCheckConfig{
+		{CheckId: "c1", CheckType: "fake", Name: "Edge path", Enabled: true, IntervalSec: 3600},
+		{CheckId: "off", CheckType: "fake", Name: "Disabled", Enabled: false},
+	})
+
+	statuses := pl.collectScheduledCheckStatuses(context.Background())
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "Edge path", statuses[0].ServiceName)
+	assert.Equal(t, "fake", statuses[0].ServiceType)
+	assert.True(t, statuses[0].Available)
+	assert.JSONEq(t, `{"passed":1,"total":1,"available":true}`, string(statuses[0].Message))
+
+	// Not due again yet: the last result is reported without re-running.
+	statuses = pl.collectScheduledCheckStatuses(context.Background())
+	require.Len(t, statuses, 1)
+	assert.Equal(t, int32(1), fake.calls.Load())
+}
+
+func TestNewCompositeCheckFromConfigRejectsBadSettings(t *testing.T) {
+	t.Parallel()
+
+	log := logger.NewTestLogger()
+
+	_, err := newCompositeCheckFromConfig(&proto.AgentCheckConfig{CheckId: "c", CheckType: compositeCheckType}, log)
+	require.ErrorIs(t, err, errCompositeSubChecks)
+
+	_, err = newCompositeCheckFromConfig(&proto.AgentCheckConfig{
+		CheckId:   "c",
+		CheckType: compositeCheckType,
+		Settings:  map[string]string{"checks": `{"name":"a"}`},
+	}, log)
+	require.ErrorIs(t, err, errCompositeSubChecks)
+
+	_, err = newCompositeCheckFromConfig(&proto.AgentCheckConfig{
+		CheckId:   "c",
+		CheckType: compositeCheckType,
+		Settings: map[string]string{
+			"checks": `[{"name":"a","type":"icmp","target":"10.0.0.1"}]`,
+			"policy": "quorum",
+			"quorum": "most",
+		},
+	}, log)
+	require.ErrorIs(t, err, errCompositeQuorum)
+
+	_, err = newCompositeCheckFromConfig(&proto.AgentCheckConfig{
+		CheckId:   "c",
+		CheckType: compositeCheckType,
+		Settings:  map[string]string{"checks": `[{"name":"web","type":"http","target":"https://example.com"}]`},
+	}, log)
+	require.ErrorIs(t, err, errUnsupportedSubCheck)
+}
+
+func TestTransactionCheckReportsStepResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"token":"abc"}`))
+	}))
+	defer server.Close()
+
+	steps := `[{"name":"login","url":"` + server.URL + `/login","expect_status":200,` +
+		`"extract":[{"name":"token","json":"token"}]},` +
+		`{"name":"profile","url":"` + server.URL + `/profile?token=${token}","expect_status":200}]`
+
+	pl := NewPushLoop(&Server{config: &ServerConfig{AgentID: "agent-1"}}, &agentgateway.GatewayClient{},
+		30*time.Second, logger.NewTestLogger())
+	pl.applyScheduledCheckConfigs([]*proto.AgentCheckConfig{{
+		CheckId:    "txn-1",
+		CheckType:  transactionCheckType,
+		Name:       "Login flow",
+		Enabled:    true,
+		TimeoutSec: 5,
+		Settings:   map[string]string{"steps": steps},
+	}})
+
+	statuses := pl.collectScheduledCheckStatuses(context.Background())
+	require.Len(t, statuses, 1)
+	assert.Equal(t, transactionCheckType, statuses[0].ServiceType)
+	assert.True(t, statuses[0].Available)
+
+	var status checker.TransactionStatus
+	require.NoError(t, json.Unmarshal(statuses[0].Message, &status))
+	require.Len(t, status.Steps, 2)
+	assert.Equal(t, []string{"token"}, status.Steps[0].Extracted)
+	assert.True(t, status.Steps[1].Success)
+}
+
+func TestNewTransactionCheckFromConfigRejectsBadSettings(t *testing.T) {
+	t.Parallel()
+
+	log := logger.NewTestLogger()
+
+	_, err := newTransactionCheckFromConfig(&proto.AgentCheckConfig{CheckId: "t", CheckType: transactionCheckType}, log)
+	require.ErrorIs(t, err, errTransactionSteps)
+
+	_, err = newTransactionCheckFromConfig(&proto.AgentCheckConfig{
+		CheckId:   "t",
+		CheckType: transactionCheckType,
+		Settings:  map[string]string{"steps": `{"name":"a"}`},
+	}, log)
+	require.ErrorIs(t, err, errTransactionSteps)
+
+	_, err = newTransactionCheckFromConfig(&proto.AgentCheckConfig{
+		CheckId:   "t",
+		CheckType: transactionCheckType,
+		Settings:  map[string]string{"steps": `[{"name":"a","type":"tcp"}]`},
+	}, log)
+	require.Error(t, err)
+}
+
+func TestAgentCapabilitiesIncludeScheduledCheckTypes(t *testing.T) {
+	t.Parallel()
+
+	assert.Contains(t, getAgentCapabilities(), compositeCheckType)
+	assert.Contains(t, getAgentCapabilities(), transactionCheckType)
+}
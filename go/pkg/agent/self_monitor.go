@@ -0,0 +1,157 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/carverauto/serviceradar/proto"
+)
+
+const (
+	selfMonitorServiceName = "agent_self"
+	selfMonitorServiceType = "agent-self"
+	selfMonitorSource      = "agent-self-metrics"
+)
+
+// SelfMonitorConfig toggles the built-in checker that reports the agent's own
+// resource usage on every push cycle.
+type SelfMonitorConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+// SelfMetrics is the payload reported by the self-monitoring checker.
+type SelfMetrics struct {
+	AgentID    string  `json:"agent_id"`
+	Timestamp  string  `json:"timestamp"`
+	CPUPercent float64 `json:"cpu_percent"` // Process CPU usage since the previous sample
+	RSSBytes   uint64  `json:"rss_bytes"`
+	Goroutines int     `json:"goroutines"`
+	OpenFDs    int32   `json:"open_fds"` // -1 when the platform cannot report it
+}
+
+// selfMonitor samples the agent process. It keeps a single process handle so
+// CPU usage is computed as a delta between consecutive samples.
+type selfMonitor struct {
+	mu   sync.Mutex
+	proc *process.Process
+}
+
+func newSelfMonitor() (*selfMonitor, error) {
+	proc, err := process.NewProcess(int32(os.Getpid())) //nolint:gosec // pid fits in int32
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent process: %w", err)
+	}
+
+	return &selfMonitor{proc: proc}, nil
+}
+
+func (m *selfMonitor) collect(ctx context.Context, agentID string) (*SelfMetrics, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cpuPercent, err := m.proc.PercentWithContext(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent cpu usage: %w", err)
+	}
+
+	memInfo, err := m.proc.MemoryInfoWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent memory usage: %w", err)
+	}
+
+	openFDs, err := m.proc.NumFDsWithContext(ctx)
+	if err != nil {
+		openFDs = -1
+	}
+
+	return &SelfMetrics{
+		AgentID:    agentID,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339Nano),
+		CPUPercent: cpuPercent,
+		RSSBytes:   memInfo.RSS,
+		Goroutines: runtime.NumGoroutine(),
+		OpenFDs:    openFDs,
+	}, nil
+}
+
+// pushSelfMetrics reports the agent's own resource usage when self-monitoring
+// is enabled. It is pushed separately from regular statuses so the changing
+// values do not defeat status change detection.
+func (p *PushLoop) pushSelfMetrics(ctx context.Context) bool {
+	if p.selfMonitor == nil {
+		return false
+	}
+
+	p.server.mu.RLock()
+	agentID := p.server.config.AgentID
+	partition := p.server.config.Partition
+	kvStoreID := p.server.config.KVAddress
+	p.server.mu.RUnlock()
+	gatewayID := p.gateway.GetGatewayID()
+
+	metrics, err := p.selfMonitor.collect(ctx, agentID)
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("Failed to collect agent self metrics")
+		return false
+	}
+
+	payload, err := json.Marshal(metrics)
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("Failed to marshal agent self metrics")
+		return false
+	}
+
+	req := &proto.GatewayStatusRequest{
+		Services: []*proto.GatewayServiceStatus{{
+			ServiceName: selfMonitorServiceName,
+			Available:   true,
+			Message:     payload,
+			ServiceType: selfMonitorServiceType,
+			AgentId:     agentID,
+			GatewayId:   gatewayID,
+			Partition:   partition,
+			Source:      selfMonitorSource,
+			KvStoreId:   kvStoreID,
+		}},
+		GatewayId: gatewayID,
+		AgentId:   agentID,
+		Timestamp: time.Now().UnixNano(),
+		Partition: partition,
+		SourceIp:  p.getSourceIP(),
+		KvStoreId: kvStoreID,
+	}
+
+	pushCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	resp, err := p.gateway.PushStatus(pushCtx, req)
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("Failed to push agent self metrics")
+		return false
+	}
+
+	return resp.Received
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+func TestSelfMonitorCollectsPlausibleMetrics(t *testing.T) {
+	monitor, err := newSelfMonitor()
+	require.NoError(t, err)
+
+	metrics, err := monitor.collect(context.Background(), "agent-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "agent-1", metrics.AgentID)
+	assert.NotEmpty(t, metrics.Timestamp)
+	assert.GreaterOrEqual(t, metrics.CPUPercent, 0.0)
+	assert.Positive(t, metrics.RSSBytes)
+	assert.Positive(t, metrics.Goroutines)
+
+	if runtime.GOOS == "linux" {
+		// stdin/stdout/stderr at minimum.
+		assert.GreaterOrEqual(t, metrics.OpenFDs, int32(3))
+	}
+
+	// A second sample measures CPU since the first one.
+	again, err := monitor.collect(context.Background(), "agent-1")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, again.CPUPercent, 0.0)
+}
+
+func TestNewPushLoopSelfMonitorToggle(t *testing.T) {
+	disabled := NewPushLoop(&Server{config: &ServerConfig{}}, nil, 0, logger.NewTestLogger())
+	assert.Nil(t, disabled.selfMonitor)
+
+	enabled := NewPushLoop(&Server{config: &ServerConfig{
+		SelfMonitor: &SelfMonitorConfig{Enabled: true},
+	}}, nil, 0, logger.NewTestLogger())
+	assert.NotNil(t, enabled.selfMonitor)
+}
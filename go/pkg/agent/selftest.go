@@ -0,0 +1,301 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/carverauto/serviceradar/proto"
+)
+
+const (
+	selfTestComponentTimeout = 10 * time.Second
+
+	SelfTestComponentICMP    = "icmp"
+	SelfTestComponentService = "service"
+	SelfTestComponentKV      = "kv"
+	SelfTestComponentGateway = "gateway"
+)
+
+// SelfTestResult is the outcome of exercising one configured component.
+type SelfTestResult struct {
+	Component string `json:"component"`
+	Name      string `json:"name"`
+	Target    string `json:"target,omitempty"`
+	Passed    bool   `json:"passed"`
+	Skipped   bool   `json:"skipped,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SelfTestReport summarises a self-test run. Passed is true only when every
+// non-skipped component passed.
+type SelfTestReport struct {
+	AgentID     string           `json:"agent_id"`
+	Passed      bool             `json:"passed"`
+	StartedAt   time.Time        `json:"started_at"`
+	CompletedAt time.Time        `json:"completed_at"`
+	Results     []SelfTestResult `json:"results"`
+}
+
+// Failed returns the results that did not pass.
+func (r *SelfTestReport) Failed() []SelfTestResult {
+	var failed []SelfTestResult
+
+	for _, result := range r.Results {
+		if !result.Passed && !result.Skipped {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
+type selfTestProbe struct {
+	component string
+	name      string
+	target    string
+	run       func(ctx context.Context) error
+}
+
+// RunSelfTest runs every configured ICMP check once, queries each embedded
+// service for its status and attempts a KV read, returning a per-component
+// report. It does not touch the polling schedule or push any results.
+func (p *PushLoop) RunSelfTest(ctx context.Context) *SelfTestReport {
+	p.server.mu.RLock()
+	agentID := p.server.config.AgentID
+	p.server.mu.RUnlock()
+
+	report := &SelfTestReport{
+		AgentID:   agentID,
+		StartedAt: time.Now().UTC(),
+	}
+
+	probes := p.selfTestProbes()
+	report.Results = make([]SelfTestResult, len(probes))
+
+	var wg sync.WaitGroup
+
+	for i, probe := range probes {
+		wg.Add(1)
+
+		go func(i int, probe selfTestProbe) {
+			defer wg.Done()
+
+			report.Results[i] = runSelfTestProbe(ctx, probe)
+		}(i, probe)
+	}
+
+	wg.Wait()
+
+	report.Results = append(report.Results, p.selfTestKV(ctx))
+	report.CompletedAt = time.Now().UTC()
+	report.Passed = len(report.Failed()) == 0
+
+	return report
+}
+
+// RefreshChecks fetches the agent config from the gateway and applies only its
+// check definitions, so a standalone self-test knows which checks to run
+// without starting any other service.
+func (p *PushLoop) RefreshChecks(ctx context.Context) error {
+	p.server.mu.RLock()
+	agentID := p.server.config.AgentID
+	p.server.mu.RUnlock()
+
+	resp, err := p.gateway.GetConfig(ctx, &proto.AgentConfigRequest{AgentId: agentID})
+	if err != nil {
+		return fmt.Errorf("failed to fetch config from gateway: %w", err)
+	}
+
+	p.applyCheckConfigs(resp.GetChecks())
+
+	return nil
+}
+
+func (p *PushLoop) selfTestProbes() []selfTestProbe {
+	p.icmpMu.RLock()
+	checks := make([]*icmpCheckConfig, 0, len(p.icmpChecks))
+	for _, check := range p.icmpChecks {
+		checks = append(checks, check)
+	}
+	p.icmpMu.RUnlock()
+
+	sort.Slice(checks, func(i, j int) bool { return checks[i].ID < checks[j].ID })
+
+	probes := make([]selfTestProbe, 0, len(checks))
+
+	for _, check := range checks {
+		if check == nil || !check.Enabled || check.Target == "" {
+			continue
+		}
+
+		name := check.Name
+		if name == "" {
+			name = check.ID
+		}
+
+		probes = append(probes, selfTestProbe{
+			component: SelfTestComponentICMP,
+			name:      name,
+			target:    check.Target,
+			run: func(ctx context.Context) error {
+				result, ran := p.checkICMP(ctx, check)
+				if !ran {
+					return fmt.Errorf("%w: %w", errCheckFailed, errCheckerDisabled)
+				}
+
+				if result.Error != "" {
+					return fmt.Errorf("%w: %s", errCheckFailed, result.Error)
+				}
+
+				if !result.Available {
+					return fmt.Errorf("%w: %s unreachable", errCheckFailed, check.Target)
+				}
+
+				return nil
+			},
+		})
+	}
+
+	p.server.mu.RLock()
+	services := append([]Service(nil), p.server.services...)
+	p.server.mu.RUnlock()
+
+	for _, svc := range services {
+		provider, ok := svc.(SweepStatusProvider)
+		if !ok {
+			continue
+		}
+
+		probes = append(probes, selfTestProbe{
+			component: SelfTestComponentService,
+			name:      svc.Name(),
+			run: func(ctx context.Context) error {
+				resp, err := provider.GetStatus(ctx)
+				if err != nil {
+					return err
+				}
+
+				if resp == nil || !resp.Available {
+					return errServiceUnavailable
+				}
+
+				return nil
+			},
+		})
+	}
+
+	return probes
+}
+
+func (p *PushLoop) selfTestKV(ctx context.Context) SelfTestResult {
+	p.server.mu.RLock()
+	addr := p.server.config.KVAddress
+	agentID := p.server.config.AgentID
+	p.server.mu.RUnlock()
+
+	if addr == "" {
+		return SelfTestResult{
+			Component: SelfTestComponentKV,
+			Name:      "kv",
+			Passed:    true,
+			Skipped:   true,
+			Error:     errKVNotConfigured.Error(),
+		}
+	}
+
+	dial := p.kvDialer
+	if dial == nil {
+		dial = p.server.dialKVStore
+	}
+
+	return runSelfTestProbe(ctx, selfTestProbe{
+		component: SelfTestComponentKV,
+		name:      "kv",
+		target:    addr,
+		run: func(ctx context.Context) error {
+			store, err := dial(ctx)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = store.Close() }()
+
+			// A missing key still proves the store is reachable.
+			_, _, err = store.Get(ctx, "agents/"+agentID+"/self_test")
+
+			return err
+		},
+	})
+}
+
+func runSelfTestProbe(ctx context.Context, probe selfTestProbe) SelfTestResult {
+	probeCtx, cancel := context.WithTimeout(ctx, selfTestComponentTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := probe.run(probeCtx)
+
+	result := SelfTestResult{
+		Component: probe.component,
+		Name:      probe.name,
+		Target:    probe.target,
+		Passed:    err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+
+	if err != nil {
+		if errors.Is(probeCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", selfTestComponentTimeout, err)
+		}
+
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func (p *PushLoop) handleSelfTest(ctx context.Context, cmd *proto.CommandRequest, sender *controlStreamSender) {
+	runTimeout := commandTimeoutCap(cmd)
+	if runTimeout <= 0 {
+		_ = sender.Send(commandResult(cmd, false, "command deadline exceeded", nil))
+		return
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+
+	report := p.RunSelfTest(runCtx)
+
+	message := "self-test passed"
+	if !report.Passed {
+		message = fmt.Sprintf("self-test failed: %d of %d components failed", len(report.Failed()), len(report.Results))
+	}
+
+	p.logger.Info().
+		Str("command_id", cmd.CommandId).
+		Bool("passed", report.Passed).
+		Int("components", len(report.Results)).
+		Msg("Agent self-test completed")
+
+	_ = sender.Send(commandResult(cmd, report.Passed, message, report))
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+	"github.com/carverauto/serviceradar/proto"
+)
+
+// statusService is an embedded service that reports a fixed availability.
+type statusService struct {
+	name      string
+	available bool
+}
+
+func (s *statusService) Start(context.Context) error       { return nil }
+func (s *statusService) Stop(context.Context) error        { return nil }
+func (s *statusService) Name() string                      { return s.name }
+func (s *statusService) UpdateConfig(*models.Config) error { return nil }
+func (s *statusService) GetStatus(context.Context) (*proto.StatusResponse, error) {
+	return &proto.StatusResponse{Available: s.available, ServiceName: s.name}, nil
+}
+
+func newSelfTestLoop(t *testing.T, kvAddress string) *PushLoop {
+	t.Helper()
+
+	loop := &PushLoop{
+		server: &Server{
+			config: &ServerConfig{AgentID: "agent-1", KVAddress: kvAddress},
+			services: []Service{
+				&statusService{name: "network_sweep", available: true},
+				&statusService{name: "rperf", available: false},
+			},
+		},
+		logger: logger.NewTestLogger(),
+		icmpChecks: map[string]*icmpCheckConfig{
+			"gw":   {ID: "gw", Name: "gateway-ping", Target: "10.0.0.1", Enabled: true},
+			"dead": {ID: "dead", Name: "dead-host", Target: "10.0.0.99", Enabled: true},
+			"off":  {ID: "off", Name: "disabled", Target: "10.0.0.2"},
+		},
+		icmpLastRun: make(map[string]time.Time),
+	}
+
+	loop.icmpCheckRunner = func(_ context.Context, check *icmpCheckConfig) icmpCheckResult {
+		return icmpCheckResult{CheckID: check.ID, Target: check.Target, Available: check.ID == "gw"}
+	}
+
+	return loop
+}
+
+func resultsByName(report *SelfTestReport) map[string]SelfTestResult {
+	out := make(map[string]SelfTestResult, len(report.Results))
+	for _, result := range report.Results {
+		out[result.Name] = result
+	}
+
+	return out
+}
+
+func TestRunSelfTestReportsMixedComponents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := NewMockKVStore(ctrl)
+	store.EXPECT().Get(gomock.Any(), "agents/agent-1/self_test").Return(nil, false, nil)
+	store.EXPECT().Close().Return(nil)
+
+	loop := newSelfTestLoop(t, "kv:50057")
+	loop.kvDialer = func(context.Context) (KVStore, error) { return store, nil }
+
+	report := loop.RunSelfTest(context.Background())
+
+	assert.False(t, report.Passed)
+	assert.Equal(t, "agent-1", report.AgentID)
+	require.Len(t, report.Results, 5)
+
+	byName := resultsByName(report)
+	assert.True(t, byName["gateway-ping"].Passed)
+	assert.False(t, byName["dead-host"].Passed)
+	assert.Contains(t, byName["dead-host"].Error, "10.0.0.99 unreachable")
+	assert.True(t, byName["network_sweep"].Passed)
+	assert.False(t, byName["rperf"].Passed)
+	assert.True(t, byName["kv"].Passed)
+	assert.NotContains(t, byName, "disabled")
+
+	assert.Len(t, report.Failed(), 2)
+	assert.Empty(t, loop.icmpLastRun, "self-test must not affect the polling schedule")
+}
+
+func TestRunSelfTestKV(t *testing.T) {
+	loop := newSelfTestLoop(t, "")
+	loop.icmpChecks = nil
+	loop.server.services = nil
+
+	report := loop.RunSelfTest(context.Background())
+	require.Len(t, report.Results, 1)
+	assert.True(t, report.Results[0].Skipped)
+	assert.True(t, report.Passed)
+
+	loop.server.config.KVAddress = "kv:50057"
+	loop.kvDialer = func(context.Context) (KVStore, error) { return nil, errDataServiceUnavailable }
+
+	report = loop.RunSelfTest(context.Background())
+	require.Len(t, report.Results, 1)
+	assert.False(t, report.Results[0].Passed)
+	assert.Equal(t, errDataServiceUnavailable.Error(), report.Results[0].Error)
+	assert.False(t, report.Passed)
+}
+
+func TestHandleSelfTestSendsReport(t *testing.T) {
+	loop := newSelfTestLoop(t, "")
+	stream := &fakeControlStreamClient{}
+
+	loop.handleSelfTest(context.Background(), &proto.CommandRequest{
+		CommandId:   "cmd-1",
+		CommandType: commandTypeSelfTest,
+	}, newControlStreamSender(stream))
+
+	require.Len(t, stream.sent, 1)
+
+	result := stream.sent[0].GetCommandResult()
+	require.NotNil(t, result)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Message, "2 of 5 components failed")
+
+	var report SelfTestReport
+	require.NoError(t, json.Unmarshal(result.PayloadJson, &report))
+	assert.Len(t, report.Results, 5)
+}
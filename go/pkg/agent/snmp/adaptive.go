@@ -0,0 +1,201 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package snmp pkg/agent/snmp/adaptive.go
+
+package snmp
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	defaultAdaptiveChangeThreshold = 0.05
+	defaultAdaptiveMaxMultiplier   = 10
+	adaptiveBackoffFactor          = 2
+)
+
+// AdaptivePolling lets an OID's poll interval follow how much its value
+// changes. A value that stays within ChangeThreshold of the previous sample
+// is polled half as often, up to MaxInterval; a value that moves by more is
+// polled twice as often again, down to MinInterval.
+type AdaptivePolling struct {
+	// MinInterval is the shortest interval (default: the target interval).
+	// It cannot be shorter than the target interval.
+	MinInterval Duration `json:"min_interval,omitempty"`
+	// MaxInterval is the longest interval (default: 10x MinInterval).
+	MaxInterval Duration `json:"max_interval,omitempty"`
+	// ChangeThreshold is the relative change between samples that counts as
+	// significant, e.g. 0.05 for 5% (default: 0.05). Non-numeric values are
+	// significant whenever they change.
+	ChangeThreshold float64 `json:"change_threshold,omitempty"`
+}
+
+// applyDefaults fills unset bounds and validates them against the target
+// interval.
+func (a *AdaptivePolling) applyDefaults(targetInterval time.Duration) error {
+	if a.MinInterval == 0 {
+		a.MinInterval = Duration(targetInterval)
+	}
+
+	if a.MaxInterval == 0 {
+		a.MaxInterval = a.MinInterval * defaultAdaptiveMaxMultiplier
+	}
+
+	if a.ChangeThreshold == 0 {
+		a.ChangeThreshold = defaultAdaptiveChangeThreshold
+	}
+
+	switch {
+	case time.Duration(a.MinInterval) < targetInterval:
+		return errAdaptiveMinInterval
+	case a.MaxInterval < a.MinInterval:
+		return errAdaptiveMaxInterval
+	case a.ChangeThreshold < 0 || math.IsNaN(a.ChangeThreshold) || math.IsInf(a.ChangeThreshold, 0):
+		return errAdaptiveThreshold
+	}
+
+	return nil
+}
+
+// applyAdaptiveDefaults resolves the adaptive polling bounds of every OID of
+// the target.
+func applyAdaptiveDefaults(target *Target) error {
+	for i := range target.OIDs {
+		oid := &target.OIDs[i]
+		if oid.Adaptive == nil {
+			continue
+		}
+
+		if err := oid.Adaptive.applyDefaults(time.Duration(target.Interval)); err != nil {
+			return fmt.Errorf("OID %s: %w", oid.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// adaptiveSchedule tracks when an adaptive OID is next due and the sample its
+// volatility is judged against.
+type adaptiveSchedule struct {
+	policy   AdaptivePolling
+	interval time.Duration
+	nextPoll time.Time
+	last     interface{}
+}
+
+func newAdaptiveSchedule(policy AdaptivePolling) *adaptiveSchedule {
+	return &adaptiveSchedule{policy: policy, interval: time.Duration(policy.MinInterval)}
+}
+
+// due reports whether the OID should be polled at now. slack absorbs ticker
+// jitter so an OID due a few milliseconds after a tick is not pushed back a
+// whole tick.
+func (s *adaptiveSchedule) due(now time.Time, slack time.Duration) bool {
+	return !now.Add(slack).Before(s.nextPoll)
+}
+
+// observe records a sample taken at now and adapts the interval to it.
+func (s *adaptiveSchedule) observe(value interface{}, now time.Time) {
+	if s.last != nil {
+		if significantChange(s.last, value, s.policy.ChangeThreshold) {
+			s.interval = max(s.interval/adaptiveBackoffFactor, time.Duration(s.policy.MinInterval))
+		} else {
+			s.interval = min(s.interval*adaptiveBackoffFactor, time.Duration(s.policy.MaxInterval))
+		}
+	}
+
+	s.last = value
+	s.nextPoll = now.Add(s.interval)
+}
+
+// significantChange reports whether current differs from prev by more than
+// threshold, relative to prev.
+func significantChange(prev, current interface{}, threshold float64) bool {
+	p, okP := toFloat64(prev)
+	c, okC := toFloat64(current)
+
+	if !okP || !okC {
+		return prev != current
+	}
+
+	diff := math.Abs(c - p)
+	if p == 0 {
+		return diff != 0
+	}
+
+	return diff/math.Abs(p) > threshold
+}
+
+// dueOIDs returns the OIDs to poll at now: every non-adaptive OID, plus the
+// adaptive ones whose interval has elapsed.
+func (c *SNMPCollector) dueOIDs(now time.Time) []string {
+	slack := time.Duration(c.target.Interval) / 2
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	oids := make([]string, 0, len(c.target.OIDs))
+
+	for i := range c.target.OIDs {
+		oid := c.target.OIDs[i].OID
+
+		if schedule, ok := c.adaptive[oid]; ok && !schedule.due(now, slack) {
+			continue
+		}
+
+		oids = append(oids, oid)
+	}
+
+	return oids
+}
+
+// observeAdaptive feeds a sample of oid to its schedule, if it has one.
+func (c *SNMPCollector) observeAdaptive(oid string, value interface{}, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if schedule, ok := c.adaptive[oid]; ok {
+		schedule.observe(value, now)
+	}
+}
+
+// pollInterval returns the current interval of an adaptive OID.
+func (c *SNMPCollector) pollInterval(oid string) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	schedule, ok := c.adaptive[oid]
+	if !ok {
+		return 0, false
+	}
+
+	return schedule.interval, true
+}
+
+func newAdaptiveSchedules(oids []OIDConfig) map[string]*adaptiveSchedule {
+	schedules := make(map[string]*adaptiveSchedule)
+
+	for i := range oids {
+		if oids[i].Adaptive != nil {
+			schedules[oids[i].OID] = newAdaptiveSchedule(*oids[i].Adaptive)
+		}
+	}
+
+	return schedules
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snmp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+const (
+	tempOID    = ".1.3.6.1.4.1.9.9.13.1.3.1.3.1"
+	counterOID = ".1.3.6.1.2.1.2.2.1.10.1"
+)
+
+func adaptiveTarget(policy *AdaptivePolling) *Target {
+	return &Target{
+		Name:     "router-1",
+		Host:     "192.0.2.1",
+		Interval: Duration(10 * time.Second),
+		OIDs: []OIDConfig{
+			{OID: tempOID, Name: "temperature", DataType: TypeGauge, Adaptive: policy},
+			{OID: counterOID, Name: "ifInOctets", DataType: TypeGauge},
+		},
+	}
+}
+
+func adaptiveCollector(t *testing.T, target *Target) *SNMPCollector {
+	t.Helper()
+
+	require.NoError(t, applyAdaptiveDefaults(target))
+
+	return &SNMPCollector{
+		target:   target,
+		dataChan: make(chan DataPoint, 16),
+		done:     make(chan struct{}),
+		status:   TargetStatus{OIDStatus: make(map[string]OIDStatus)},
+		logger:   logger.NewTestLogger(),
+		adaptive: newAdaptiveSchedules(target.OIDs),
+	}
+}
+
+// simulate runs the collector's schedule for ticks ticks of the target
+// interval, sampling each due OID from sample, and returns how often each OID
+// was polled and the adaptive OID's interval after every tick.
+func simulate(
+	c *SNMPCollector, ticks int, sample func(oid string, tick int) interface{},
+) (polls map[string]int, intervals []time.Duration) {
+	polls = make(map[string]int)
+	start := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	for tick := 0; tick < ticks; tick++ {
+		now := start.Add(time.Duration(tick) * time.Duration(c.target.Interval))
+
+		for _, oid := range c.dueOIDs(now) {
+			polls[oid]++
+			c.observeAdaptive(oid, sample(oid, tick), now)
+		}
+
+		interval, _ := c.pollInterval(tempOID)
+		intervals = append(intervals, interval)
+	}
+
+	return polls, intervals
+}
+
+func TestAdaptivePolling_StableValueBacksOffToMax(t *testing.T) {
+	c := adaptiveCollector(t, adaptiveTarget(&AdaptivePolling{
+		MaxInterval:     Duration(80 * time.Second),
+		ChangeThreshold: 0.05,
+	}))
+
+	polls, intervals := simulate(c, 60, func(string, int) interface{} { return 23.5 })
+
+	assert.Equal(t, 60, polls[counterOID], "non-adaptive OIDs are polled every tick")
+	assert.Less(t, polls[tempOID], 15, "a stable OID is polled far less often")
+	assert.Equal(t, 80*time.Second, intervals[len(intervals)-1])
+
+	for _, interval := range intervals {
+		assert.GreaterOrEqual(t, interval, 10*time.Second)
+		assert.LessOrEqual(t, interval, 80*time.Second)
+	}
+}
+
+func TestAdaptivePolling_VolatileValueShortensToMin(t *testing.T) {
+	c := adaptiveCollector(t, adaptiveTarget(&AdaptivePolling{
+		MinInterval: Duration(20 * time.Second),
+		MaxInterval: Duration(160 * time.Second),
+	}))
+
+	// Stable for the first 60 ticks, then swinging by 50% on every sample.
+	value := 40.0
+	sample := func(oid string, tick int) interface{} {
+		if oid == tempOID && tick >= 60 {
+			value = 100 - value
+		}
+
+		return value
+	}
+
+	polls, intervals := simulate(c, 100, sample)
+
+	assert.Equal(t, 160*time.Second, intervals[59], "stable phase reaches the ceiling")
+	assert.Equal(t, 20*time.Second, intervals[99], "volatile phase returns to the floor")
+	assert.Equal(t, 100, polls[counterOID])
+
+	for _, interval := range intervals {
+		assert.GreaterOrEqual(t, interval, 20*time.Second)
+		assert.LessOrEqual(t, interval, 160*time.Second)
+	}
+}
+
+func TestAdaptivePolling_SmallChangesCountAsStable(t *testing.T) {
+	schedule := newAdaptiveSchedule(AdaptivePolling{
+		MinInterval:     Duration(10 * time.Second),
+		MaxInterval:     Duration(40 * time.Second),
+		ChangeThreshold: 0.1,
+	})
+	now := time.Now()
+
+	schedule.observe(uint64(1000), now)
+	schedule.observe(uint64(1050), now)
+	assert.Equal(t, 20*time.Second, schedule.interval, "5% is below the 10% threshold")
+
+	schedule.observe(uint64(1500), now)
+	assert.Equal(t, 10*time.Second, schedule.interval, "~43% is significant")
+
+	schedule.observe("up", now)
+	assert.Equal(t, 10*time.Second, schedule.interval, "a type change is significant")
+
+	schedule.observe("up", now)
+	assert.Equal(t, 20*time.Second, schedule.interval, "an unchanged string is stable")
+}
+
+func TestPollTarget_SkipsAdaptiveOIDsNotDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockSNMPClient(ctrl)
+
+	c := adaptiveCollector(t, adaptiveTarget(&AdaptivePolling{}))
+	c.client = client
+
+	client.EXPECT().
+		Get([]string{tempOID, counterOID}).
+		Return(map[string]interface{}{tempOID: 23.5, counterOID: 100.0}, nil)
+	require.NoError(t, c.pollTarget(context.Background()))
+
+	// The temperature sample scheduled the next poll a full interval out.
+	client.EXPECT().
+		Get([]string{counterOID}).
+		Return(map[string]interface{}{counterOID: 150.0}, nil)
+	require.NoError(t, c.pollTarget(context.Background()))
+}
+
+func TestApplyAdaptiveDefaults(t *testing.T) {
+	target := adaptiveTarget(&AdaptivePolling{})
+	require.NoError(t, applyAdaptiveDefaults(target))
+
+	policy := target.OIDs[0].Adaptive
+	assert.Equal(t, Duration(10*time.Second), policy.MinInterval)
+	assert.Equal(t, Duration(100*time.Second), policy.MaxInterval)
+	assert.InDelta(t, defaultAdaptiveChangeThreshold, policy.ChangeThreshold, 0)
+
+	target = adaptiveTarget(&AdaptivePolling{MinInterval: Duration(5 * time.Second)})
+	require.ErrorIs(t, applyAdaptiveDefaults(target), errAdaptiveMinInterval)
+
+	target = adaptiveTarget(&AdaptivePolling{
+		MinInterval: Duration(30 * time.Second),
+		MaxInterval: Duration(20 * time.Second),
+	})
+	require.ErrorIs(t, applyAdaptiveDefaults(target), errAdaptiveMaxInterval)
+
+	target = adaptiveTarget(&AdaptivePolling{ChangeThreshold: -1})
+	require.ErrorIs(t, applyAdaptiveDefaults(target), errAdaptiveThreshold)
+}
@@ -414,7 +414,7 @@ func validateTarget(target *Target) error {
 		target.Retries = defaultRetries
 	}
 
-	return nil
+	return applyAdaptiveDefaults(target)
 }
 
 // securityLevelToMsgFlags converts SecurityLevel to gosnmp.SnmpV3MsgFlags.
@@ -58,7 +58,8 @@ func NewCollector(target *Target, log logger.Logger) (Collector, error) {
 				return make([]byte, 0, defaultByteBuffer)
 			},
 		},
-		logger: log,
+		logger:   log,
+		adaptive: newAdaptiveSchedules(target.OIDs),
 	}
 
 	return collector, nil
@@ -126,17 +127,17 @@ func (c *SNMPCollector) collect(ctx context.Context) {
 
 // pollTarget performs a single poll of all OIDs for the target.
 func (c *SNMPCollector) pollTarget(ctx context.Context) error {
+	oids := c.dueOIDs(time.Now())
+	if len(oids) == 0 {
+		return nil
+	}
+
 	c.logger.Debug().
 		Str("target_name", c.target.Name).
 		Str("target_host", c.target.Host).
-		Int("oid_count", len(c.target.OIDs)).
+		Int("oid_count", len(oids)).
 		Msg("Polling target")
 
-	oids := make([]string, len(c.target.OIDs))
-	for i, oid := range c.target.OIDs {
-		oids[i] = oid.OID
-	}
-
 	// Get SNMP data
 	results, err := c.client.Get(oids)
 	if err != nil {
@@ -204,12 +205,9 @@ func (c *SNMPCollector) processResult(ctx context.Context, oid string, value int
 		}
 	}
 
-	// Apply scaling if configured
-	if oidConfig.Scale != 0 && oidConfig.Scale != 1.0 {
-		if val, ok := toFloat64(finalValue); ok {
-			finalValue = val * oidConfig.Scale
-		}
-	}
+	c.observeAdaptive(oid, finalValue, now)
+
+	finalValue = applyScaling(finalValue, oidConfig)
 
 	// Create data point
 	// If we performed delta calculation, the resulting value is a Rate (Gauge/Float)
@@ -228,6 +226,7 @@ func (c *SNMPCollector) processResult(ctx context.Context, oid string, value int
 		Timestamp: now,
 		DataType:  dataType,
 		Scale:     oidConfig.Scale,
+		Unit:      oidConfig.Unit,
 		Delta:     isDelta,
 	}
 
@@ -244,6 +243,32 @@ func (c *SNMPCollector) processResult(ctx context.Context, oid string, value int
 	}
 }
 
+// applyScaling multiplies a numeric value by the configured scale and divides
+// it by the configured divisor. Non-numeric values are returned unchanged.
+func applyScaling(value interface{}, oidConfig *OIDConfig) interface{} {
+	scale := oidConfig.Scale
+	divisor := oidConfig.Divisor
+
+	if (scale == 0 || scale == 1.0) && (divisor == 0 || divisor == 1.0) {
+		return value
+	}
+
+	val, ok := toFloat64(value)
+	if !ok {
+		return value
+	}
+
+	if scale != 0 {
+		val *= scale
+	}
+
+	if divisor != 0 {
+		val /= divisor
+	}
+
+	return val
+}
+
 func calculateDelta(prev, current interface{}) float64 {
 	p, okP := toFloat64(prev)
 	c, okC := toFloat64(current)
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
@@ -86,3 +87,66 @@ func TestCollector_WithMocks(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessResult_AppliesScalingAndUnit(t *testing.T) {
+	tests := []struct {
+		name      string
+		oidConfig OIDConfig
+		raw       interface{}
+		wantValue interface{}
+	}{
+		{
+			name: "tenths of a degree to celsius",
+			oidConfig: OIDConfig{
+				OID: ".1.3.6.1.4.1.9.9.13.1.3.1.3.1", Name: "temperature",
+				DataType: TypeGauge, Scale: 1.0, Divisor: 10, Unit: "celsius",
+			},
+			raw:       int64(235),
+			wantValue: 23.5,
+		},
+		{
+			name: "bytes to bits",
+			oidConfig: OIDConfig{
+				OID: ".1.3.6.1.2.1.2.2.1.10.1", Name: "ifInOctets",
+				DataType: TypeGauge, Scale: 8, Unit: "bits",
+			},
+			raw:       uint64(1500),
+			wantValue: 12000.0,
+		},
+		{
+			name: "unscaled values pass through",
+			oidConfig: OIDConfig{
+				OID: ".1.3.6.1.2.1.1.5.0", Name: "sysName",
+				DataType: TypeString, Scale: 1.0, Divisor: 1.0,
+			},
+			raw:       "router-1",
+			wantValue: "router-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &SNMPCollector{
+				target:   &Target{Name: "router-1", OIDs: []OIDConfig{tt.oidConfig}},
+				dataChan: make(chan DataPoint, 1),
+				done:     make(chan struct{}),
+				status:   TargetStatus{OIDStatus: make(map[string]OIDStatus)},
+			}
+
+			require.NoError(t, c.processResult(context.Background(), tt.oidConfig.OID, tt.raw))
+
+			point := <-c.dataChan
+			assert.Equal(t, tt.wantValue, point.Value)
+			assert.Equal(t, tt.oidConfig.Unit, point.Unit)
+		})
+	}
+}
+
+func TestValidateOIDConfig_Divisor(t *testing.T) {
+	oid := OIDConfig{OID: ".1.3.6.1.2.1.1.3.0", Name: "sysUpTime", DataType: TypeGauge}
+	require.NoError(t, validateOIDConfig(&oid, map[string]bool{}))
+	assert.InDelta(t, 1.0, oid.Divisor, 0)
+
+	oid.Divisor = -10
+	require.ErrorIs(t, validateOIDConfig(&oid, map[string]bool{}), errInvalidDivisor)
+}
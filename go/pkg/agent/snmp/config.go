@@ -22,6 +22,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"strings"
@@ -41,6 +42,9 @@ type SNMPConfig struct {
 	Targets     []Target               `json:"targets"`
 	Partition   string                 `json:"partition"`
 	Logger      *logger.Config         `json:"logger,omitempty"`
+	// TrapCorrelationWindow bounds how far apart a link trap and a matching
+	// ifOperStatus poll may be to be correlated (default: 5m).
+	TrapCorrelationWindow models.Duration `json:"trap_correlation_window,omitempty"`
 }
 
 const (
@@ -135,7 +139,7 @@ func (*SNMPConfig) validateTarget(target *Target, targetNames map[string]bool) e
 		}
 	}
 
-	return nil
+	return applyAdaptiveDefaults(target)
 }
 
 func validateTargetName(name string, targetNames map[string]bool) error {
@@ -211,6 +215,14 @@ func validateOIDConfig(oid *OIDConfig, oidNames map[string]bool) error {
 		oid.Scale = 1.0 // Set default scale
 	}
 
+	if oid.Divisor < 0 || math.IsNaN(oid.Divisor) || math.IsInf(oid.Divisor, 0) {
+		return errInvalidDivisor
+	}
+
+	if oid.Divisor == 0 {
+		oid.Divisor = 1.0 // Set default divisor
+	}
+
 	return nil
 }
 
@@ -39,7 +39,11 @@ var (
 	errInvalidHostAddress  = fmt.Errorf("invalid host address")
 	errInvalidDataType     = fmt.Errorf("invalid data type")
 	errInvalidScale        = fmt.Errorf("scale factor must be greater than 0")
+	errInvalidDivisor      = fmt.Errorf("divisor must be greater than 0")
 	errEmptyOIDName        = fmt.Errorf("OID name cannot be empty")
+	errAdaptiveMinInterval = errors.New("adaptive min_interval cannot be shorter than the target interval")
+	errAdaptiveMaxInterval = errors.New("adaptive max_interval cannot be shorter than min_interval")
+	errAdaptiveThreshold   = errors.New("adaptive change_threshold must be a non-negative number")
 
 	// Service error types.
 
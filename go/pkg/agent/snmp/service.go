@@ -91,6 +91,8 @@ func newSNMPServiceInternal(config *SNMPConfig, log logger.Logger) *SNMPService
 		logger:      log,
 	}
 
+	service.trapCorrelator = NewTrapCorrelator(time.Duration(config.TrapCorrelationWindow))
+
 	// Create collector factory with database service
 	service.collectorFactory = &defaultCollectorFactory{}
 	service.aggregatorFactory = &defaultAggregatorFactory{}
@@ -430,16 +432,27 @@ func (s *SNMPService) processResults(ctx context.Context, targetName string, res
 	}
 }
 
+// RecordTrap feeds a linkUp/linkDown trap from the trap stream into interface
+// status correlation. Host must match the target host being polled.
+func (s *SNMPService) RecordTrap(event TrapEvent) {
+	s.trapCorrelator.RecordTrap(event)
+}
+
 // handleDataPoint processes a single data point.
 func (s *SNMPService) handleDataPoint(targetName string, point *DataPoint, aggregator Aggregator) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	status, exists := s.status[targetName]
+	if exists && s.trapCorrelator != nil {
+		point.Trap = s.trapCorrelator.Correlate(status.HostIP, status.GetOID(point.OIDName), point.Value, point.Timestamp)
+	}
+
 	// Update aggregator
 	aggregator.AddPoint(point)
 
 	// Update status
-	if status, exists := s.status[targetName]; exists {
+	if exists {
 		if status.OIDStatus == nil {
 			status.OIDStatus = make(map[string]OIDStatus)
 		}
@@ -447,6 +460,7 @@ func (s *SNMPService) handleDataPoint(targetName string, point *DataPoint, aggre
 		status.OIDStatus[point.OIDName] = OIDStatus{
 			LastValue:  point.Value,
 			LastUpdate: point.Timestamp,
+			Trap:       point.Trap,
 		}
 
 		// Update hostname if this is the sysName OID
@@ -475,6 +489,14 @@ func (s *SNMPService) handleDataPoint(targetName string, point *DataPoint, aggre
 			"delta":     point.Delta,
 		}
 
+		if point.Unit != "" {
+			message["unit"] = point.Unit
+		}
+
+		if point.Trap != nil {
+			message["trap"] = point.Trap
+		}
+
 		messageJSON, err := json.Marshal(message)
 		if err != nil {
 			s.logger.Error().Err(err).Msg("Error marshaling data point")
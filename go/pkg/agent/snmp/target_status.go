@@ -36,6 +36,21 @@ func (ts *TargetStatus) GetDataType(oidName string) string {
 	return defaultUnknown
 }
 
+// GetOID returns the configured OID for the given OID name, or "" if unknown.
+func (ts *TargetStatus) GetOID(oidName string) string {
+	if ts.Target == nil {
+		return ""
+	}
+
+	for _, oid := range ts.Target.OIDs {
+		if oid.Name == oidName {
+			return oid.OID
+		}
+	}
+
+	return ""
+}
+
 // GetScale returns the scale factor for the given OID.
 func (ts *TargetStatus) GetScale(oidName string) float64 {
 	if ts.Target == nil {
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package snmp pkg/agent/snmp/trap_correlation.go
+package snmp
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TrapOIDLinkDown is the SNMPv2 linkDown notification OID.
+	TrapOIDLinkDown = ".1.3.6.1.6.3.1.1.5.3"
+	// TrapOIDLinkUp is the SNMPv2 linkUp notification OID.
+	TrapOIDLinkUp = ".1.3.6.1.6.3.1.1.5.4"
+
+	oidIfOperStatusPrefix = ".1.3.6.1.2.1.2.2.1.8."
+
+	defaultTrapCorrelationWindow = 5 * time.Minute
+
+	// ifOperStatus values (RFC 2863).
+	ifOperStatusUp             = 1
+	ifOperStatusDown           = 2
+	ifOperStatusLowerLayerDown = 7
+)
+
+// TrapEvent is a linkUp/linkDown notification received for a device.
+type TrapEvent struct {
+	Host    string    `json:"host"`
+	IfIndex int       `json:"if_index"`
+	TrapOID string    `json:"trap_oid"`
+	Time    time.Time `json:"time"`
+}
+
+// TrapContext annotates a polled interface sample with the trap that agrees with it.
+type TrapContext struct {
+	TrapOID  string    `json:"trap_oid"`
+	TrapTime time.Time `json:"trap_time"`
+	IfIndex  int       `json:"if_index"`
+}
+
+// TrapCorrelator remembers recent link traps per device interface so polled
+// ifOperStatus samples can be matched against them.
+type TrapCorrelator struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	traps map[string]TrapEvent
+}
+
+// NewTrapCorrelator creates a correlator that matches traps seen within window
+// of a poll. A non-positive window uses the default of five minutes.
+func NewTrapCorrelator(window time.Duration) *TrapCorrelator {
+	if window <= 0 {
+		window = defaultTrapCorrelationWindow
+	}
+
+	return &TrapCorrelator{
+		window: window,
+		traps:  make(map[string]TrapEvent),
+	}
+}
+
+// RecordTrap stores the most recent link trap for the event's device interface.
+// Non-link traps are ignored.
+func (c *TrapCorrelator) RecordTrap(event TrapEvent) {
+	event.TrapOID = normalizeOID(event.TrapOID)
+	if event.TrapOID != TrapOIDLinkDown && event.TrapOID != TrapOIDLinkUp {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := trapKey(event.Host, event.IfIndex)
+	if existing, ok := c.traps[key]; ok && existing.Time.After(event.Time) {
+		return
+	}
+
+	c.traps[key] = event
+
+	cutoff := event.Time.Add(-c.window)
+	for k, trap := range c.traps {
+		if trap.Time.Before(cutoff) {
+			delete(c.traps, k)
+		}
+	}
+}
+
+// Correlate returns the trap context for a polled sample of oid on host when
+// the sample is an ifOperStatus reading whose state matches a trap received
+// within the correlation window. It returns nil otherwise.
+func (c *TrapCorrelator) Correlate(host, oid string, value interface{}, at time.Time) *TrapContext {
+	ifIndex, ok := ifOperStatusIndex(oid)
+	if !ok {
+		return nil
+	}
+
+	wantTrap, ok := trapForOperStatus(value)
+	if !ok {
+		return nil
+	}
+
+	c.mu.Lock()
+	trap, found := c.traps[trapKey(host, ifIndex)]
+	c.mu.Unlock()
+
+	if !found || trap.TrapOID != wantTrap {
+		return nil
+	}
+
+	if delta := at.Sub(trap.Time).Abs(); delta > c.window {
+		return nil
+	}
+
+	return &TrapContext{
+		TrapOID:  trap.TrapOID,
+		TrapTime: trap.Time,
+		IfIndex:  ifIndex,
+	}
+}
+
+func trapKey(host string, ifIndex int) string {
+	return host + "|" + strconv.Itoa(ifIndex)
+}
+
+func normalizeOID(oid string) string {
+	oid = strings.TrimSpace(oid)
+	if oid != "" && !strings.HasPrefix(oid, ".") {
+		oid = "." + oid
+	}
+
+	return oid
+}
+
+// ifOperStatusIndex extracts the ifIndex from an ifOperStatus instance OID.
+func ifOperStatusIndex(oid string) (int, bool) {
+	suffix, ok := strings.CutPrefix(normalizeOID(oid), oidIfOperStatusPrefix)
+	if !ok {
+		return 0, false
+	}
+
+	ifIndex, err := strconv.Atoi(suffix)
+	if err != nil || ifIndex <= 0 {
+		return 0, false
+	}
+
+	return ifIndex, true
+}
+
+// trapForOperStatus maps a polled ifOperStatus value to the trap that reports it.
+func trapForOperStatus(value interface{}) (string, bool) {
+	var status int64
+
+	switch v := value.(type) {
+	case int:
+		status = int64(v)
+	case int32:
+		status = int64(v)
+	case int64:
+		status = v
+	case uint:
+		status = int64(v) //nolint:gosec // ifOperStatus is a small enum
+	case uint32:
+		status = int64(v)
+	case uint64:
+		status = int64(v) //nolint:gosec // ifOperStatus is a small enum
+	case float64:
+		status = int64(v)
+	default:
+		return "", false
+	}
+
+	switch status {
+	case ifOperStatusUp:
+		return TrapOIDLinkUp, true
+	case ifOperStatusDown, ifOperStatusLowerLayerDown:
+		return TrapOIDLinkDown, true
+	default:
+		return "", false
+	}
+}
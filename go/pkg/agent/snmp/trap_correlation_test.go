@@ -0,0 +1,102 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snmp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+const testIfOperStatus3 = ".1.3.6.1.2.1.2.2.1.8.3"
+
+func TestTrapCorrelatorMatchesLinkDown(t *testing.T) {
+	correlator := NewTrapCorrelator(time.Minute)
+	trapTime := time.Now()
+
+	correlator.RecordTrap(TrapEvent{Host: "10.0.0.1", IfIndex: 3, TrapOID: "1.3.6.1.6.3.1.1.5.3", Time: trapTime})
+
+	trap := correlator.Correlate("10.0.0.1", testIfOperStatus3, int64(ifOperStatusDown), trapTime.Add(20*time.Second))
+	require.NotNil(t, trap)
+	assert.Equal(t, TrapOIDLinkDown, trap.TrapOID)
+	assert.Equal(t, trapTime, trap.TrapTime)
+	assert.Equal(t, 3, trap.IfIndex)
+}
+
+func TestTrapCorrelatorRejectsMismatches(t *testing.T) {
+	correlator := NewTrapCorrelator(time.Minute)
+	trapTime := time.Now()
+
+	correlator.RecordTrap(TrapEvent{Host: "10.0.0.1", IfIndex: 3, TrapOID: TrapOIDLinkDown, Time: trapTime})
+
+	tests := []struct {
+		name  string
+		host  string
+		oid   string
+		value interface{}
+		at    time.Time
+	}{
+		{name: "state disagrees", host: "10.0.0.1", oid: testIfOperStatus3, value: int64(ifOperStatusUp), at: trapTime},
+		{name: "outside window", host: "10.0.0.1", oid: testIfOperStatus3, value: int64(ifOperStatusDown), at: trapTime.Add(2 * time.Minute)},
+		{name: "other interface", host: "10.0.0.1", oid: ".1.3.6.1.2.1.2.2.1.8.4", value: int64(ifOperStatusDown), at: trapTime},
+		{name: "other device", host: "10.0.0.2", oid: testIfOperStatus3, value: int64(ifOperStatusDown), at: trapTime},
+		{name: "not ifOperStatus", host: "10.0.0.1", oid: ".1.3.6.1.2.1.1.3.0", value: int64(ifOperStatusDown), at: trapTime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Nil(t, correlator.Correlate(tt.host, tt.oid, tt.value, tt.at))
+		})
+	}
+}
+
+func TestTrapCorrelatorIgnoresOtherTraps(t *testing.T) {
+	correlator := NewTrapCorrelator(0)
+
+	correlator.RecordTrap(TrapEvent{Host: "10.0.0.1", IfIndex: 3, TrapOID: ".1.3.6.1.6.3.1.1.5.1", Time: time.Now()})
+
+	assert.Empty(t, correlator.traps)
+	assert.Equal(t, defaultTrapCorrelationWindow, correlator.window)
+}
+
+func TestHandleDataPointAnnotatesTrapContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	aggregator := NewMockAggregator(ctrl)
+
+	service := newSNMPServiceInternal(&SNMPConfig{}, logger.NewTestLogger())
+	service.status["edge-router"] = TargetStatus{
+		HostIP: "10.0.0.1",
+		Target: &Target{OIDs: []OIDConfig{{OID: testIfOperStatus3, Name: "ge-0/0/3_status"}}},
+	}
+
+	trapTime := time.Now()
+	service.RecordTrap(TrapEvent{Host: "10.0.0.1", IfIndex: 3, TrapOID: TrapOIDLinkDown, Time: trapTime})
+
+	point := &DataPoint{OIDName: "ge-0/0/3_status", Value: int64(ifOperStatusDown), Timestamp: trapTime.Add(5 * time.Second)}
+	aggregator.EXPECT().AddPoint(point)
+
+	service.handleDataPoint("edge-router", point, aggregator)
+
+	require.NotNil(t, point.Trap)
+	assert.Equal(t, TrapOIDLinkDown, point.Trap.TrapOID)
+	assert.Equal(t, point.Trap, service.status["edge-router"].OIDStatus["ge-0/0/3_status"].Trap)
+}
@@ -50,6 +50,8 @@ type SNMPCollector struct {
 	status     TargetStatus
 	bufferPool *sync.Pool
 	logger     logger.Logger
+	// adaptive holds the schedules of OIDs with adaptive polling, by OID.
+	adaptive map[string]*adaptiveSchedule
 }
 
 // SNMPVersion represents supported SNMP versions.
@@ -113,7 +115,10 @@ type DataPoint struct {
 	Timestamp time.Time   `json:"timestamp"`
 	DataType  DataType    `json:"data_type"`
 	Scale     float64     `json:"scale"`
+	Unit      string      `json:"unit,omitempty"`
 	Delta     bool        `json:"delta"`
+	// Trap is set when an interface status sample agrees with a recent link trap.
+	Trap *TrapContext `json:"trap,omitempty"`
 }
 
 // SecurityLevel represents SNMPv3 security levels.
@@ -190,8 +195,12 @@ type OIDConfig struct {
 	OID      string   `json:"oid"`
 	Name     string   `json:"name"`
 	DataType DataType `json:"type"`
-	Scale    float64  `json:"scale,omitempty"` // For scaling values (e.g., bytes to megabytes)
-	Delta    bool     `json:"delta,omitempty"` // Calculate change between samples
+	Scale    float64  `json:"scale,omitempty"`   // For scaling values (e.g., bytes to bits)
+	Divisor  float64  `json:"divisor,omitempty"` // Divides values (e.g., tenths of a degree to degrees)
+	Unit     string   `json:"unit,omitempty"`    // Unit of the scaled value (e.g., "celsius", "bits")
+	Delta    bool     `json:"delta,omitempty"`   // Calculate change between samples
+	// Adaptive, when set, polls the OID less often while its value is stable.
+	Adaptive *AdaptivePolling `json:"adaptive,omitempty"`
 }
 
 // SNMPService implements both the Service interface and proto.AgentServiceServer.
@@ -208,15 +217,17 @@ type SNMPService struct {
 	collectorFactory  CollectorFactory
 	aggregatorFactory AggregatorFactory
 	status            map[string]TargetStatus
+	trapCorrelator    *TrapCorrelator
 	logger            logger.Logger
 }
 
 // OIDStatus represents the current status of an OID.
 type OIDStatus struct {
-	LastValue  interface{} `json:"last_value"`
-	LastUpdate time.Time   `json:"last_update"`
-	ErrorCount int         `json:"error_count"`
-	LastError  string      `json:"last_error,omitempty"`
+	LastValue  interface{}  `json:"last_value"`
+	LastUpdate time.Time    `json:"last_update"`
+	ErrorCount int          `json:"error_count"`
+	LastError  string       `json:"last_error,omitempty"`
+	Trap       *TrapContext `json:"trap,omitempty"`
 }
 
 // TargetStatus represents the current status of an SNMP target.
@@ -734,7 +734,10 @@ func protoToSNMPConfig(p *proto.SNMPConfig) *snmp.SNMPConfig {
 				Name:     oid.Name,
 				DataType: protoToSNMPDataType(oid.DataType),
 				Scale:    float64(oid.Scale),
+				Divisor:  oid.Divisor,
+				Unit:     oid.Unit,
 				Delta:    oid.Delta,
+				Adaptive: protoToAdaptivePolling(oid),
 			})
 		}
 
@@ -744,6 +747,21 @@ func protoToSNMPConfig(p *proto.SNMPConfig) *snmp.SNMPConfig {
 	return config
 }
 
+// protoToAdaptivePolling converts the adaptive polling fields of a proto OID
+// config, returning nil when adaptive polling is off. Zero bounds are left for
+// the collector to default from the target interval.
+func protoToAdaptivePolling(oid *proto.SNMPOIDConfig) *snmp.AdaptivePolling {
+	if !oid.GetAdaptive() {
+		return nil
+	}
+
+	return &snmp.AdaptivePolling{
+		MinInterval:     snmp.Duration(time.Duration(oid.GetAdaptiveMinIntervalSeconds()) * time.Second),
+		MaxInterval:     snmp.Duration(time.Duration(oid.GetAdaptiveMaxIntervalSeconds()) * time.Second),
+		ChangeThreshold: oid.GetAdaptiveChangeThreshold(),
+	}
+}
+
 // protoToSNMPVersion converts proto SNMPVersion to snmp.SNMPVersion.
 func protoToSNMPVersion(v proto.SNMPVersion) snmp.SNMPVersion {
 	switch v {
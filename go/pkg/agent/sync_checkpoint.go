@@ -0,0 +1,72 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import "time"
+
+// armisCheckpointTTL bounds how long an interrupted fetch can be resumed.
+// Older progress is discarded so a resumed run never ships stale devices.
+const armisCheckpointTTL = time.Hour
+
+// armisCheckpoint records how far an interrupted Armis fetch got, so the next
+// run in the same session continues from the next page instead of starting
+// over. Results are only sent once every page has been fetched, so the
+// checkpoint also carries the updates built from the pages already fetched.
+type armisCheckpoint struct {
+	// query is the index of the query being paged; len(queries) means every
+	// page was fetched and only sending the results is outstanding.
+	query   int
+	from    int
+	updates []map[string]interface{}
+	savedAt time.Time
+}
+
+// saveCheckpoint records that pages before from (for query) were processed.
+func (r *syncSourceRunner) saveCheckpoint(query, from int, updates []map[string]interface{}, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checkpoint = &armisCheckpoint{query: query, from: from, updates: updates, savedAt: now}
+}
+
+// resumeCheckpoint returns the checkpoint to resume from, or nil when there is
+// none or it has expired.
+func (r *syncSourceRunner) resumeCheckpoint(now time.Time) *armisCheckpoint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.checkpoint == nil {
+		return nil
+	}
+
+	if now.Sub(r.checkpoint.savedAt) > armisCheckpointTTL {
+		r.checkpoint = nil
+
+		return nil
+	}
+
+	checkpoint := *r.checkpoint
+
+	return &checkpoint
+}
+
+// clearCheckpoint drops the checkpoint after a clean, complete run.
+func (r *syncSourceRunner) clearCheckpoint() {
+	r.mu.Lock()
+	r.checkpoint = nil
+	r.mu.Unlock()
+}
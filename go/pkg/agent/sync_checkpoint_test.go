@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+// fakeArmis serves three pages of two devices each and can fail a page once.
+type fakeArmis struct {
+	mu        sync.Mutex
+	requested []int
+	failFrom  int
+}
+
+func (f *fakeArmis) handler(t *testing.T) http.Handler {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(armisAccessTokenPath, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"access_token":"token"},"success":true}`))
+	})
+	mux.HandleFunc(armisSearchPath, func(w http.ResponseWriter, r *http.Request) {
+		from, err := strconv.Atoi(r.URL.Query().Get("from"))
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		f.mu.Lock()
+		f.requested = append(f.requested, from)
+		fail := f.failFrom == from
+		if fail {
+			f.failFrom = -1
+		}
+		f.mu.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+
+			return
+		}
+
+		var resp armisSearchResponse
+		for i := from; i < from+2; i++ {
+			resp.Data.Results = append(resp.Data.Results, armisDevice{ID: i, IPAddress: fmt.Sprintf("10.0.0.%d", i+1)})
+		}
+
+		if from+2 < 6 {
+			resp.Data.Next = from + 2
+		}
+
+		resp.Success = true
+
+		assert.NoError(t, json.NewEncoder(w).Encode(resp))
+	})
+
+	return mux
+}
+
+func (f *fakeArmis) takeRequested() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	requested := f.requested
+	f.requested = nil
+
+	return requested
+}
+
+func newCheckpointTestRuntime(t *testing.T, armis *fakeArmis) (*SyncRuntime, *syncSourceRunner) {
+	t.Helper()
+
+	srv := httptest.NewServer(armis.handler(t))
+	t.Cleanup(srv.Close)
+
+	runtime := NewSyncRuntime(&Server{config: &ServerConfig{AgentID: "agent-1"}}, nil, logger.NewTestLogger())
+	runner := &syncSourceRunner{
+		key: "armis",
+		config: models.SourceConfig{
+			Type:        armisSourceType,
+			Endpoint:    srv.URL,
+			Credentials: map[string]string{"page_size": "2"},
+		},
+	}
+
+	return runtime, runner
+}
+
+func deviceIPs(updates []map[string]interface{}) []string {
+	ips := make([]string, 0, len(updates))
+	for _, update := range updates {
+		ips = append(ips, update["ip"].(string))
+	}
+
+	return ips
+}
+
+func TestFetchArmisUpdatesResumesAfterInterruption(t *testing.T) {
+	t.Parallel()
+
+	armis := &fakeArmis{failFrom: 4}
+	runtime, runner := newCheckpointTestRuntime(t, armis)
+
+	updates, err := runtime.fetchArmisUpdates(t.Context(), runner)
+	require.ErrorIs(t, err, errArmisSearchFailed)
+	assert.Len(t, updates, 4)
+	assert.Equal(t, []int{0, 2, 4}, armis.takeRequested())
+
+	updates, err = runtime.fetchArmisUpdates(t.Context(), runner)
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{4}, armis.takeRequested(), "only the remaining page is fetched")
+	assert.Equal(t,
+		[]string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"},
+		deviceIPs(updates))
+}
+
+func TestFetchArmisUpdatesSkipsFetchWhenOnlySendIsOutstanding(t *testing.T) {
+	t.Parallel()
+
+	armis := &fakeArmis{failFrom: -1}
+	runtime, runner := newCheckpointTestRuntime(t, armis)
+
+	updates, err := runtime.fetchArmisUpdates(t.Context(), runner)
+	require.NoError(t, err)
+	require.Len(t, updates, 6)
+	armis.takeRequested()
+
+	// The send failed, so the checkpoint still covers every page.
+	resent, err := runtime.fetchArmisUpdates(t.Context(), runner)
+	require.NoError(t, err)
+	assert.Len(t, resent, 6)
+	assert.Empty(t, armis.takeRequested())
+
+	runner.clearCheckpoint()
+
+	_, err = runtime.fetchArmisUpdates(t.Context(), runner)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 2, 4}, armis.takeRequested(), "a cleared checkpoint starts over")
+}
+
+func TestResumeCheckpointExpires(t *testing.T) {
+	t.Parallel()
+
+	runner := &syncSourceRunner{}
+	savedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	runner.saveCheckpoint(0, 200, nil, savedAt)
+
+	checkpoint := runner.resumeCheckpoint(savedAt.Add(time.Minute))
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, 200, checkpoint.from)
+
+	assert.Nil(t, runner.resumeCheckpoint(savedAt.Add(armisCheckpointTTL+time.Second)))
+	assert.Nil(t, runner.resumeCheckpoint(savedAt.Add(time.Minute)), "expired checkpoints are dropped")
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	syncMeterName = "github.com/carverauto/serviceradar/go/pkg/agent/sync"
+
+	syncStageFetch     = "fetch"
+	syncStageReconcile = "reconcile"
+	syncStageRun       = "run"
+)
+
+// syncRunStats describes one sync run for the per-source metrics.
+type syncRunStats struct {
+	fetchDuration     time.Duration
+	reconcileDuration time.Duration
+	devicesFetched    int
+	devicesChanged    int
+
+	// failedStage names the step that failed (fetch, reconcile or run).
+	failedStage string
+}
+
+// syncMetrics holds the per-source sync instruments. Every measurement is
+// labeled with the source name so integrations can be dashboarded separately.
+type syncMetrics struct {
+	fetchDuration       metric.Float64Histogram
+	reconcileDuration   metric.Float64Histogram
+	devicesFetched      metric.Int64Counter
+	devicesChanged      metric.Int64Counter
+	errors              metric.Int64Counter
+	consecutiveFailures metric.Int64Gauge
+}
+
+func newSyncMetrics(meter metric.Meter) (*syncMetrics, error) {
+	var (
+		m   syncMetrics
+		err error
+	)
+
+	if m.fetchDuration, err = meter.Float64Histogram(
+		"serviceradar.sync.fetch.duration",
+		metric.WithDescription("Time spent fetching devices from a sync source"),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, fmt.Errorf("create fetch duration histogram: %w", err)
+	}
+
+	if m.reconcileDuration, err = meter.Float64Histogram(
+		"serviceradar.sync.reconcile.duration",
+		metric.WithDescription("Time spent sending fetched devices to the gateway for reconciliation"),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, fmt.Errorf("create reconcile duration histogram: %w", err)
+	}
+
+	if m.devicesFetched, err = meter.Int64Counter(
+		"serviceradar.sync.devices.fetched",
+		metric.WithDescription("Devices fetched from a sync source"),
+		metric.WithUnit("{device}"),
+	); err != nil {
+		return nil, fmt.Errorf("create devices fetched counter: %w", err)
+	}
+
+	if m.devicesChanged, err = meter.Int64Counter(
+		"serviceradar.sync.devices.changed",
+		metric.WithDescription("Fetched devices that are new or differ from the previous successful run"),
+		metric.WithUnit("{device}"),
+	); err != nil {
+		return nil, fmt.Errorf("create devices changed counter: %w", err)
+	}
+
+	if m.errors, err = meter.Int64Counter(
+		"serviceradar.sync.errors",
+		metric.WithDescription("Failed sync runs"),
+		metric.WithUnit("{error}"),
+	); err != nil {
+		return nil, fmt.Errorf("create errors counter: %w", err)
+	}
+
+	if m.consecutiveFailures, err = meter.Int64Gauge(
+		"serviceradar.sync.consecutive_failures",
+		metric.WithDescription("Sync runs that failed in a row; 0 after a successful run"),
+		metric.WithUnit("{run}"),
+	); err != nil {
+		return nil, fmt.Errorf("create consecutive failures gauge: %w", err)
+	}
+
+	return &m, nil
+}
+
+// recordRun emits the metrics for a finished run. It is a no-op when the
+// instruments could not be created.
+func (m *syncMetrics) recordRun(ctx context.Context, source string, stats syncRunStats, runErr error, failures int) {
+	if m == nil {
+		return
+	}
+
+	labels := metric.WithAttributes(attribute.String("source", source))
+
+	m.fetchDuration.Record(ctx, stats.fetchDuration.Seconds(), labels)
+	m.devicesFetched.Add(ctx, int64(stats.devicesFetched), labels)
+	m.devicesChanged.Add(ctx, int64(stats.devicesChanged), labels)
+
+	if stats.reconcileDuration > 0 {
+		m.reconcileDuration.Record(ctx, stats.reconcileDuration.Seconds(), labels)
+	}
+
+	if runErr != nil {
+		stage := stats.failedStage
+		if stage == "" {
+			stage = syncStageRun
+		}
+
+		m.errors.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("source", source),
+			attribute.String("stage", stage),
+		))
+	}
+
+	m.consecutiveFailures.Record(ctx, int64(failures), labels)
+}
+
+// recordOutcome updates the consecutive failure count and returns it.
+func (r *syncSourceRunner) recordOutcome(err error) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.consecutiveFailures++
+	} else {
+		r.consecutiveFailures = 0
+	}
+
+	return r.consecutiveFailures
+}
+
+// diffDevices counts the updates that are new or changed since the last
+// successful run and returns the fingerprints to keep once this run succeeds.
+func (r *syncSourceRunner) diffDevices(updates []map[string]interface{}) (int, map[string]string) {
+	r.mu.Lock()
+	previous := r.fingerprints
+	r.mu.Unlock()
+
+	changed := 0
+	fingerprints := make(map[string]string, len(updates))
+
+	for _, update := range updates {
+		deviceID, _ := update["device_id"].(string)
+		if deviceID == "" {
+			continue
+		}
+
+		fingerprint := syncUpdateFingerprint(update)
+		if previous[deviceID] != fingerprint {
+			changed++
+		}
+
+		fingerprints[deviceID] = fingerprint
+	}
+
+	return changed, fingerprints
+}
+
+func (r *syncSourceRunner) saveFingerprints(fingerprints map[string]string) {
+	r.mu.Lock()
+	r.fingerprints = fingerprints
+	r.mu.Unlock()
+}
+
+// syncUpdateFingerprint hashes an update without the fields that change on
+// every run (the fetch timestamp and chunk metadata).
+func syncUpdateFingerprint(update map[string]interface{}) string {
+	stable := make(map[string]interface{}, len(update))
+	for key, value := range update {
+		if key == "timestamp" || key == syncMetaKey {
+			continue
+		}
+		stable[key] = value
+	}
+
+	data, err := json.Marshal(stable)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
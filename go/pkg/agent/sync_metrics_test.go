@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func newMetricsTestRuntime(t *testing.T, armis *fakeArmis) (*SyncRuntime, *syncSourceRunner, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	runtime, runner := newCheckpointTestRuntime(t, armis)
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+
+	metrics, err := newSyncMetrics(provider.Meter(syncMeterName))
+	require.NoError(t, err)
+	runtime.metrics = metrics
+
+	return runtime, runner, reader
+}
+
+func collectSyncMetrics(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(t.Context(), &rm))
+
+	collected := make(map[string]metricdata.Aggregation)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			collected[m.Name] = m.Data
+		}
+	}
+
+	return collected
+}
+
+func sourceLabels(source string, extra ...attribute.KeyValue) attribute.Set {
+	return attribute.NewSet(append([]attribute.KeyValue{attribute.String("source", source)}, extra...)...)
+}
+
+func sumValue(t *testing.T, data metricdata.Aggregation, attrs attribute.Set) int64 {
+	t.Helper()
+
+	sum, ok := data.(metricdata.Sum[int64])
+	require.True(t, ok, "expected an int64 sum, got %T", data)
+
+	for _, point := range sum.DataPoints {
+		if point.Attributes.Equals(&attrs) {
+			return point.Value
+		}
+	}
+
+	require.Failf(t, "missing data point", "no point labeled %v", attrs.ToSlice())
+
+	return 0
+}
+
+func gaugeValue(t *testing.T, data metricdata.Aggregation, attrs attribute.Set) int64 {
+	t.Helper()
+
+	gauge, ok := data.(metricdata.Gauge[int64])
+	require.True(t, ok, "expected an int64 gauge, got %T", data)
+
+	for _, point := range gauge.DataPoints {
+		if point.Attributes.Equals(&attrs) {
+			return point.Value
+		}
+	}
+
+	require.Failf(t, "missing data point", "no point labeled %v", attrs.ToSlice())
+
+	return 0
+}
+
+func histogramCount(t *testing.T, data metricdata.Aggregation, attrs attribute.Set) uint64 {
+	t.Helper()
+
+	histogram, ok := data.(metricdata.Histogram[float64])
+	require.True(t, ok, "expected a float64 histogram, got %T", data)
+
+	for _, point := range histogram.DataPoints {
+		if point.Attributes.Equals(&attrs) {
+			return point.Count
+		}
+	}
+
+	return 0
+}
+
+func TestExecuteRunRecordsMetricsForFailedRun(t *testing.T) {
+	t.Parallel()
+
+	runtime, runner, reader := newMetricsTestRuntime(t, &fakeArmis{failFrom: 2})
+
+	runtime.executeRun(t.Context(), runner, "discovery")
+
+	metrics := collectSyncMetrics(t, reader)
+	labels := sourceLabels("armis")
+
+	assert.Equal(t, uint64(1), histogramCount(t, metrics["serviceradar.sync.fetch.duration"], labels))
+	assert.Equal(t, int64(2), sumValue(t, metrics["serviceradar.sync.devices.fetched"], labels),
+		"devices from the pages fetched before the failure")
+	assert.Equal(t, int64(0), sumValue(t, metrics["serviceradar.sync.devices.changed"], labels))
+	assert.Equal(t, int64(1), sumValue(t, metrics["serviceradar.sync.errors"],
+		sourceLabels("armis", attribute.String("stage", syncStageFetch))))
+	assert.Equal(t, int64(1), gaugeValue(t, metrics["serviceradar.sync.consecutive_failures"], labels))
+	assert.NotContains(t, metrics, "serviceradar.sync.reconcile.duration", "nothing was sent")
+}
+
+func TestExecuteRunRecordsMetricsForSuccessfulRun(t *testing.T) {
+	t.Parallel()
+
+	runtime, runner, reader := newMetricsTestRuntime(t, &fakeArmis{failFrom: 0})
+	// Filter every device out so the run succeeds without a gateway.
+	runner.config.NetworkBlacklist = []string{"10.0.0.0/24"}
+
+	runtime.executeRun(t.Context(), runner, "discovery")
+	runtime.executeRun(t.Context(), runner, "discovery")
+
+	metrics := collectSyncMetrics(t, reader)
+	labels := sourceLabels("armis")
+
+	assert.Equal(t, uint64(2), histogramCount(t, metrics["serviceradar.sync.fetch.duration"], labels))
+	assert.Equal(t, int64(0), sumValue(t, metrics["serviceradar.sync.devices.fetched"], labels))
+	assert.Equal(t, int64(1), sumValue(t, metrics["serviceradar.sync.errors"],
+		sourceLabels("armis", attribute.String("stage", syncStageFetch))), "only the first run failed")
+	assert.Equal(t, int64(0), gaugeValue(t, metrics["serviceradar.sync.consecutive_failures"], labels),
+		"a successful run resets the failure count")
+}
+
+func TestDiffDevicesCountsNewAndChangedDevices(t *testing.T) {
+	t.Parallel()
+
+	runner := &syncSourceRunner{}
+	first := []map[string]interface{}{
+		{"device_id": "default:10.0.0.1", "ip": "10.0.0.1", "hostname": "a", "timestamp": "t1"},
+		{"device_id": "default:10.0.0.2", "ip": "10.0.0.2", "hostname": "b", "timestamp": "t1"},
+	}
+
+	changed, fingerprints := runner.diffDevices(first)
+	assert.Equal(t, 2, changed, "every device is new on the first run")
+	runner.saveFingerprints(fingerprints)
+
+	second := []map[string]interface{}{
+		{"device_id": "default:10.0.0.1", "ip": "10.0.0.1", "hostname": "a", "timestamp": "t2"},
+		{"device_id": "default:10.0.0.2", "ip": "10.0.0.2", "hostname": "b-renamed", "timestamp": "t2"},
+		{"device_id": "default:10.0.0.3", "ip": "10.0.0.3", "timestamp": "t2"},
+	}
+
+	changed, _ = runner.diffDevices(second)
+	assert.Equal(t, 2, changed, "timestamps alone do not count as a change")
+}
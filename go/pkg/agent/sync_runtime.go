@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
 
 	"github.com/carverauto/serviceradar/go/pkg/agentgateway"
 	"github.com/carverauto/serviceradar/go/pkg/logger"
@@ -49,6 +50,7 @@ type SyncRuntime struct {
 	server  *Server
 	gateway *agentgateway.GatewayClient
 	logger  logger.Logger
+	metrics *syncMetrics
 
 	mu      sync.Mutex
 	ctx     context.Context
@@ -59,10 +61,22 @@ type syncSourceRunner struct {
 	key    string
 	hash   string
 	config models.SourceConfig
+	ctx    context.Context
 	cancel context.CancelFunc
 
+	// schedule is set when the source is driven by a cron expression.
+	schedule *syncSchedule
+
 	mu       sync.Mutex
 	inflight bool
+
+	// checkpoint lets an interrupted fetch resume where it stopped.
+	checkpoint *armisCheckpoint
+
+	// consecutiveFailures and fingerprints (device ID to update hash from the
+	// last successful run) feed the per-source metrics.
+	consecutiveFailures int
+	fingerprints        map[string]string
 }
 
 type syncConfigPayload struct {
@@ -107,10 +121,16 @@ type armisTokenResponse struct {
 
 // NewSyncRuntime builds the integration sync runtime for an agent.
 func NewSyncRuntime(server *Server, gateway *agentgateway.GatewayClient, log logger.Logger) *SyncRuntime {
+	metrics, err := newSyncMetrics(otel.Meter(syncMeterName))
+	if err != nil {
+		log.Warn().Err(err).Msg("Sync metrics disabled")
+	}
+
 	return &SyncRuntime{
 		server:  server,
 		gateway: gateway,
 		logger:  log,
+		metrics: metrics,
 		sources: make(map[string]*syncSourceRunner),
 	}
 }
@@ -168,6 +188,12 @@ func (r *SyncRuntime) ApplyConfig(configJSON []byte) {
 			continue
 		}
 
+		schedule, err := parseSyncSchedule(source)
+		if err != nil {
+			r.logger.Warn().Err(err).Str("source", key).Msg("Skipping sync source with invalid schedule")
+			continue
+		}
+
 		hash := syncSourceHash(source)
 		if existing, ok := r.sources[key]; ok {
 			if existing.hash == hash {
@@ -177,7 +203,7 @@ func (r *SyncRuntime) ApplyConfig(configJSON []byte) {
 			delete(r.sources, key)
 		}
 
-		r.sources[key] = r.startSourceLocked(key, source, hash)
+		r.sources[key] = r.startSourceLocked(key, source, hash, schedule)
 	}
 }
 
@@ -188,13 +214,20 @@ func (r *SyncRuntime) stopAllLocked() {
 	}
 }
 
-func (r *SyncRuntime) startSourceLocked(key string, source models.SourceConfig, hash string) *syncSourceRunner {
+func (r *SyncRuntime) startSourceLocked(
+	key string,
+	source models.SourceConfig,
+	hash string,
+	schedule *syncSchedule,
+) *syncSourceRunner {
 	ctx, cancel := context.WithCancel(r.ctx)
 	runner := &syncSourceRunner{
-		key:    key,
-		hash:   hash,
-		config: source,
-		cancel: cancel,
+		key:      key,
+		hash:     hash,
+		config:   source,
+		ctx:      ctx,
+		cancel:   cancel,
+		schedule: schedule,
 	}
 
 	go r.runSource(ctx, runner)
@@ -205,15 +238,21 @@ func (r *SyncRuntime) runSource(ctx context.Context, runner *syncSourceRunner) {
 	pollInterval := time.Duration(runner.config.PollInterval)
 	discoveryInterval := time.Duration(runner.config.DiscoveryInterval)
 
-	if pollInterval <= 0 && discoveryInterval <= 0 {
+	if runner.schedule == nil && pollInterval <= 0 && discoveryInterval <= 0 {
 		r.logger.Warn().Str("source", runner.key).Msg("Sync source has no intervals configured")
 		return
 	}
 
-	// Run an initial discovery immediately.
-	r.executeRun(ctx, runner, "discovery")
+	if runner.schedule != nil {
+		// The schedule replaces the discovery interval, including the
+		// initial run, so sources stay inside their configured windows.
+		go r.runScheduledDiscovery(ctx, runner)
+	} else {
+		// Run an initial discovery immediately.
+		r.executeRun(ctx, runner, "discovery")
+	}
 
-	if discoveryInterval > 0 {
+	if runner.schedule == nil && discoveryInterval > 0 {
 		ticker := time.NewTicker(discoveryInterval)
 		defer ticker.Stop()
 
@@ -246,6 +285,29 @@ func (r *SyncRuntime) runSource(ctx context.Context, runner *syncSourceRunner) {
 	<-ctx.Done()
 }
 
+func (r *SyncRuntime) runScheduledDiscovery(ctx context.Context, runner *syncSourceRunner) {
+	for {
+		next := runner.schedule.Next(time.Now())
+		if next.IsZero() {
+			r.logger.Warn().Str("source", runner.key).Str("schedule", runner.schedule.String()).
+				Msg("Sync schedule never fires; discovery disabled")
+			return
+		}
+
+		r.logger.Debug().Str("source", runner.key).Time("next_run", next).Msg("Next scheduled sync run")
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			r.executeRun(ctx, runner, "discovery")
+		}
+	}
+}
+
 func (r *SyncRuntime) executeRun(ctx context.Context, runner *syncSourceRunner, runKind string) {
 	if !runner.tryStart() {
 		r.logger.Debug().Str("source", runner.key).Msg("Sync run skipped (in progress)")
@@ -258,9 +320,12 @@ func (r *SyncRuntime) executeRun(ctx context.Context, runner *syncSourceRunner,
 	defer cancel()
 
 	start := time.Now()
-	count, err := r.runSourceOnce(runCtx, runner, runKind, runID)
+	stats, err := r.runSourceOnce(runCtx, runner, runKind, runID)
 	duration := time.Since(start)
 
+	failures := runner.recordOutcome(err)
+	r.metrics.recordRun(ctx, runner.key, stats, err, failures)
+
 	logEvent := r.logger.Info()
 	if err != nil {
 		logEvent = r.logger.Error().Err(err)
@@ -270,7 +335,9 @@ func (r *SyncRuntime) executeRun(ctx context.Context, runner *syncSourceRunner,
 		Str("type", runner.config.Type).
 		Str("run_id", runID).
 		Str("kind", runKind).
-		Int("device_count", count).
+		Int("device_count", stats.devicesFetched).
+		Int("changed_count", stats.devicesChanged).
+		Int("consecutive_failures", failures).
 		Dur("duration", duration).
 		Msg("Sync run completed")
 }
@@ -280,13 +347,13 @@ func (r *SyncRuntime) runSourceOnce(
 	runner *syncSourceRunner,
 	_ string,
 	runID string,
-) (int, error) {
+) (syncRunStats, error) {
 	sourceType := strings.ToLower(strings.TrimSpace(runner.config.Type))
 	switch sourceType {
 	case armisSourceType:
 		return r.runArmisSync(ctx, runner, runID)
 	default:
-		return 0, fmt.Errorf("%w: %s", errUnsupportedSyncSourceType, sourceType)
+		return syncRunStats{failedStage: syncStageRun}, fmt.Errorf("%w: %s", errUnsupportedSyncSourceType, sourceType)
 	}
 }
 
@@ -294,35 +361,95 @@ func (r *SyncRuntime) runArmisSync(
 	ctx context.Context,
 	runner *syncSourceRunner,
 	runID string,
-) (int, error) {
+) (syncRunStats, error) {
+	var stats syncRunStats
+
+	fetchStart := time.Now()
+	updates, err := r.fetchArmisUpdates(ctx, runner)
+	stats.fetchDuration = time.Since(fetchStart)
+	stats.devicesFetched = len(updates)
+
+	if err != nil {
+		stats.failedStage = syncStageFetch
+		return stats, err
+	}
+
+	changed, fingerprints := runner.diffDevices(updates)
+	stats.devicesChanged = changed
+
+	if len(updates) > 0 {
+		reconcileStart := time.Now()
+		err := r.sendSyncUpdates(ctx, runner, updates, runID)
+		stats.reconcileDuration = time.Since(reconcileStart)
+
+		if err != nil {
+			stats.failedStage = syncStageReconcile
+			return stats, err
+		}
+	}
+
+	runner.clearCheckpoint()
+	runner.saveFingerprints(fingerprints)
+
+	return stats, nil
+}
+
+// fetchArmisUpdates pages through every configured query and builds the
+// device updates. Progress is checkpointed after each page, so when a run is
+// interrupted (API error, timeout, shutdown) the next run resumes from the
+// page that failed rather than refetching everything.
+func (r *SyncRuntime) fetchArmisUpdates(ctx context.Context, runner *syncSourceRunner) ([]map[string]interface{}, error) {
 	client := newArmisClient(runner.config)
 	queries := runner.config.Queries
 	if len(queries) == 0 {
 		queries = []models.QueryConfig{{}}
 	}
 
+	pageSize := armisPageSize(runner.config)
+	updates := make([]map[string]interface{}, 0, pageSize*len(queries))
+
+	resume := runner.resumeCheckpoint(time.Now())
+	if resume != nil {
+		updates = append(updates, resume.updates...)
+
+		r.logger.Info().Str("source", runner.key).
+			Int("query", resume.query).
+			Int("from", resume.from).
+			Int("device_count", len(updates)).
+			Msg("Resuming interrupted sync fetch")
+
+		if resume.query >= len(queries) {
+			return updates, nil
+		}
+	}
+
 	token, err := client.accessToken(ctx, runner.config.Credentials)
 	if err != nil {
-		return 0, err
+		return updates, err
 	}
 
-	pageSize := armisPageSize(runner.config)
-	updates := make([]map[string]interface{}, 0, pageSize*len(queries))
+	firstQuery := 0
+	if resume != nil {
+		firstQuery = resume.query
+	}
 
-	for _, query := range queries {
-		queryString := query.Query
-		queryLabel := query.Label
+	for queryIndex := firstQuery; queryIndex < len(queries); queryIndex++ {
+		query := queries[queryIndex]
 
 		from := 0
+		if resume != nil && queryIndex == resume.query {
+			from = resume.from
+		}
+
 		for {
-			resp, err := client.search(ctx, token, queryString, from, pageSize)
+			resp, err := client.search(ctx, token, query.Query, from, pageSize)
 			if err != nil {
-				return len(updates), err
+				return updates, err
 			}
 
 			filtered := filterArmisDevices(resp.Data.Results, runner.config.NetworkBlacklist)
 			for _, device := range filtered {
-				update := buildArmisUpdate(r.server, runner, device, queryLabel)
+				update := buildArmisUpdate(r.server, runner, device, query.Label)
 				if update == nil {
 					continue
 				}
@@ -330,22 +457,16 @@ func (r *SyncRuntime) runArmisSync(
 			}
 
 			if resp.Data.Next <= 0 || resp.Data.Next <= from {
+				runner.saveCheckpoint(queryIndex+1, 0, updates, time.Now())
 				break
 			}
 
 			from = resp.Data.Next
+			runner.saveCheckpoint(queryIndex, from, updates, time.Now())
 		}
 	}
 
-	if len(updates) == 0 {
-		return 0, nil
-	}
-
-	if err := r.sendSyncUpdates(ctx, runner, updates, runID); err != nil {
-		return len(updates), err
-	}
-
-	return len(updates), nil
+	return updates, nil
 }
 
 func (r *SyncRuntime) sendSyncUpdates(
@@ -354,7 +475,7 @@ func (r *SyncRuntime) sendSyncUpdates(
 	updates []map[string]interface{},
 	runID string,
 ) error {
-	chunks, err := buildSyncResultsChunks(updates, runner.config, runID)
+	chunks, err := buildSyncResultsChunks(updates, runner.config, runner.schedule, runID)
 	if err != nil {
 		return err
 	}
@@ -712,9 +833,19 @@ type syncChunkMeta struct {
 	syncServiceID string
 	runID         string
 	totalDevices  int
+	dryRun        bool
+
+	// schedule and nextRun let core detect missed runs for cron-driven sources.
+	schedule string
+	nextRun  time.Time
 }
 
-func buildSyncResultsChunks(updates []map[string]interface{}, source models.SourceConfig, runID string) ([]*proto.ResultsChunk, error) {
+func buildSyncResultsChunks(
+	updates []map[string]interface{},
+	source models.SourceConfig,
+	schedule *syncSchedule,
+	runID string,
+) ([]*proto.ResultsChunk, error) {
 	if len(updates) == 0 {
 		return nil, nil
 	}
@@ -723,6 +854,12 @@ func buildSyncResultsChunks(updates []map[string]interface{}, source models.Sour
 		syncServiceID: source.SyncServiceID,
 		runID:         runID,
 		totalDevices:  len(updates),
+		dryRun:        source.DryRun,
+	}
+
+	if schedule != nil {
+		meta.schedule = schedule.String()
+		meta.nextRun = schedule.Next(time.Now())
 	}
 
 	maxChunkSize, maxHosts := sweepResultsChunkLimits()
@@ -821,7 +958,7 @@ func applySyncMeta(
 }
 
 func buildSyncMeta(meta syncChunkMeta, chunkIndex int, totalChunks int, isFinal bool) map[string]interface{} {
-	return map[string]interface{}{
+	syncMeta := map[string]interface{}{
 		"sync_service_id": meta.syncServiceID,
 		"sync_run_id":     meta.runID,
 		"chunk_index":     chunkIndex,
@@ -829,4 +966,17 @@ func buildSyncMeta(meta syncChunkMeta, chunkIndex int, totalChunks int, isFinal
 		"total_devices":   meta.totalDevices,
 		"is_final":        isFinal,
 	}
+
+	if meta.dryRun {
+		syncMeta["dry_run"] = true
+	}
+
+	if meta.schedule != "" {
+		syncMeta["schedule"] = meta.schedule
+		if !meta.nextRun.IsZero() {
+			syncMeta["next_run"] = meta.nextRun.UTC().Format(time.RFC3339)
+		}
+	}
+
+	return syncMeta
 }
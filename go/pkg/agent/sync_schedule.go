@@ -0,0 +1,284 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+// maxScheduleSearchYears bounds the next-run search so impossible expressions
+// such as "0 0 31 2 *" terminate.
+const maxScheduleSearchYears = 5
+
+var (
+	errInvalidCronExpression = errors.New("invalid cron expression")
+	errInvalidCronField      = errors.New("invalid cron field")
+	errInvalidScheduleZone   = errors.New("invalid schedule timezone")
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronDayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day-of-month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: cronMonthNames},
+	{name: "day-of-week", min: 0, max: 7, names: cronDayNames},
+}
+
+// syncSchedule is a parsed five-field cron expression evaluated in a fixed zone.
+type syncSchedule struct {
+	expr     string
+	location *time.Location
+
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+
+	// Standard cron semantics: when both day fields are restricted a day
+	// matches if either does.
+	daysRestricted     bool
+	weekdaysRestricted bool
+}
+
+// parseSyncSchedule returns the source's cron schedule, or nil when the source
+// is interval driven.
+func parseSyncSchedule(source models.SourceConfig) (*syncSchedule, error) {
+	expr := strings.TrimSpace(source.Schedule)
+	if expr == "" {
+		return nil, nil
+	}
+
+	location := time.UTC
+
+	if zone := strings.TrimSpace(source.ScheduleTimezone); zone != "" {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", errInvalidScheduleZone, zone, err)
+		}
+
+		location = loc
+	}
+
+	return parseCronExpression(expr, location)
+}
+
+func parseCronExpression(expr string, location *time.Location) (*syncSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "@") {
+		resolved, ok := cronDescriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("%w %q: unknown descriptor", errInvalidCronExpression, expr)
+		}
+
+		spec = resolved
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("%w %q: expected %d fields, got %d",
+			errInvalidCronExpression, expr, len(cronFields), len(parts))
+	}
+
+	var masks [5]uint64
+
+	for i, part := range parts {
+		mask, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", errInvalidCronExpression, expr, err)
+		}
+
+		masks[i] = mask
+	}
+
+	// Both 0 and 7 mean Sunday.
+	if masks[4]&(1<<7) != 0 {
+		masks[4] = (masks[4] | 1) &^ (1 << 7)
+	}
+
+	return &syncSchedule{
+		expr:               expr,
+		location:           location,
+		minutes:            masks[0],
+		hours:              masks[1],
+		days:               masks[2],
+		months:             masks[3],
+		weekdays:           masks[4],
+		daysRestricted:     parts[2] != "*" && parts[2] != "?",
+		weekdaysRestricted: parts[4] != "*" && parts[4] != "?",
+	}, nil
+}
+
+func parseCronField(raw string, field cronField) (uint64, error) {
+	var mask uint64
+
+	for _, item := range strings.Split(raw, ",") {
+		rangePart, step := item, 1
+
+		if idx := strings.Index(item, "/"); idx >= 0 {
+			rangePart = item[:idx]
+
+			parsed, err := strconv.Atoi(item[idx+1:])
+			if err != nil || parsed <= 0 {
+				return 0, fmt.Errorf("%w: %s step %q", errInvalidCronField, field.name, item)
+			}
+
+			step = parsed
+		}
+
+		low, high, err := parseCronRange(rangePart, field, step > 1)
+		if err != nil {
+			return 0, err
+		}
+
+		for v := low; v <= high; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+
+	return mask, nil
+}
+
+func parseCronRange(raw string, field cronField, stepped bool) (int, int, error) {
+	if raw == "*" || raw == "?" {
+		return field.min, field.max, nil
+	}
+
+	lowRaw, highRaw, isRange := strings.Cut(raw, "-")
+
+	low, err := parseCronValue(lowRaw, field)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	high := low
+
+	switch {
+	case isRange:
+		if high, err = parseCronValue(highRaw, field); err != nil {
+			return 0, 0, err
+		}
+	case stepped:
+		// "5/15" means every 15 starting at 5.
+		high = field.max
+	}
+
+	if low > high {
+		return 0, 0, fmt.Errorf("%w: %s range %q is reversed", errInvalidCronField, field.name, raw)
+	}
+
+	return low, high, nil
+}
+
+func parseCronValue(raw string, field cronField) (int, error) {
+	if v, ok := field.names[strings.ToLower(raw)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s value %q", errInvalidCronField, field.name, raw)
+	}
+
+	if v < field.min || v > field.max {
+		return 0, fmt.Errorf("%w: %s value %d outside %d-%d",
+			errInvalidCronField, field.name, v, field.min, field.max)
+	}
+
+	return v, nil
+}
+
+// Next returns the first scheduled time strictly after after, or the zero time
+// when the expression never fires.
+func (s *syncSchedule) Next(after time.Time) time.Time {
+	t := after.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxScheduleSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+			continue
+		}
+
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+			continue
+		}
+
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+			continue
+		}
+
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *syncSchedule) matchesDay(t time.Time) bool {
+	dayMatch := s.days&(1<<uint(t.Day())) != 0
+	weekdayMatch := s.weekdays&(1<<uint(t.Weekday())) != 0
+
+	if s.daysRestricted && s.weekdaysRestricted {
+		return dayMatch || weekdayMatch
+	}
+
+	return dayMatch && weekdayMatch
+}
+
+// String returns the expression as configured.
+func (s *syncSchedule) String() string {
+	return s.expr
+}
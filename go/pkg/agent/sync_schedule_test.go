@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+func mustSchedule(t *testing.T, expr string) *syncSchedule {
+	t.Helper()
+
+	schedule, err := parseSyncSchedule(models.SourceConfig{Schedule: expr})
+	require.NoError(t, err)
+	require.NotNil(t, schedule)
+
+	return schedule
+}
+
+func TestSyncScheduleNext(t *testing.T) {
+	// 2026-03-06 is a Friday.
+	base := time.Date(2026, time.March, 6, 16, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "every fifteen minutes",
+			expr:  "*/15 * * * *",
+			after: base,
+			want:  time.Date(2026, time.March, 6, 16, 45, 0, 0, time.UTC),
+		},
+		{
+			name:  "business hours rolls into the weekend gap",
+			expr:  "0 9-17 * * mon-fri",
+			after: time.Date(2026, time.March, 6, 17, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, time.March, 9, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "descriptor",
+			expr:  "@daily",
+			after: base,
+			want:  time.Date(2026, time.March, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "sunday as seven",
+			expr:  "30 2 * * 7",
+			after: base,
+			want:  time.Date(2026, time.March, 8, 2, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "day of month or weekday",
+			expr:  "0 0 1 * 6",
+			after: base,
+			want:  time.Date(2026, time.March, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "month rollover",
+			expr:  "0 12 15 jan,jul *",
+			after: base,
+			want:  time.Date(2026, time.July, 15, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "strictly after current minute",
+			expr:  "30 16 * * *",
+			after: base,
+			want:  time.Date(2026, time.March, 7, 16, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mustSchedule(t, tt.expr).Next(tt.after))
+		})
+	}
+}
+
+func TestSyncScheduleNextNeverFires(t *testing.T) {
+	assert.True(t, mustSchedule(t, "0 0 31 2 *").Next(time.Now()).IsZero())
+}
+
+func TestSyncScheduleNextHonorsTimezone(t *testing.T) {
+	schedule, err := parseCronExpression("0 9 * * *", time.FixedZone("UTC-5", -5*60*60))
+	require.NoError(t, err)
+
+	next := schedule.Next(time.Date(2026, time.March, 6, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2026, time.March, 6, 14, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestParseSyncScheduleErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source models.SourceConfig
+		want   error
+	}{
+		{name: "too few fields", source: models.SourceConfig{Schedule: "* * *"}, want: errInvalidCronExpression},
+		{name: "unknown descriptor", source: models.SourceConfig{Schedule: "@sometimes"}, want: errInvalidCronExpression},
+		{name: "minute out of range", source: models.SourceConfig{Schedule: "60 * * * *"}, want: errInvalidCronField},
+		{name: "reversed range", source: models.SourceConfig{Schedule: "0 17-9 * * *"}, want: errInvalidCronField},
+		{name: "zero step", source: models.SourceConfig{Schedule: "*/0 * * * *"}, want: errInvalidCronField},
+		{name: "bad name", source: models.SourceConfig{Schedule: "0 0 * * funday"}, want: errInvalidCronField},
+		{
+			name:   "unknown timezone",
+			source: models.SourceConfig{Schedule: "@hourly", ScheduleTimezone: "Mars/Olympus_Mons"},
+			want:   errInvalidScheduleZone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSyncSchedule(tt.source)
+			require.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestParseSyncScheduleEmpty(t *testing.T) {
+	schedule, err := parseSyncSchedule(models.SourceConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, schedule)
+}
+
+func TestBuildSyncMetaIncludesSchedule(t *testing.T) {
+	next := time.Date(2026, time.March, 9, 9, 0, 0, 0, time.UTC)
+	meta := buildSyncMeta(syncChunkMeta{runID: "run", schedule: "0 9-17 * * 1-5", nextRun: next}, 0, 1, true)
+
+	assert.Equal(t, "0 9-17 * * 1-5", meta["schedule"])
+	assert.Equal(t, "2026-03-09T09:00:00Z", meta["next_run"])
+
+	unscheduled := buildSyncMeta(syncChunkMeta{runID: "run"}, 0, 1, true)
+	assert.NotContains(t, unscheduled, "schedule")
+}
+
+func TestBuildSyncMetaMarksDryRun(t *testing.T) {
+	meta := buildSyncMeta(syncChunkMeta{runID: "run", dryRun: true}, 0, 1, true)
+	assert.Equal(t, true, meta["dry_run"])
+
+	live := buildSyncMeta(syncChunkMeta{runID: "run"}, 0, 1, true)
+	assert.NotContains(t, live, "dry_run")
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSyncWebhookPath     = "/sync/webhook/"
+	defaultSyncWebhookDebounce = 10 * time.Second
+	maxSyncWebhookBody         = 1 << 20
+	syncWebhookRunKind         = "webhook"
+
+	// Generic HMAC-SHA256 signature, "sha256=<hex>" (GitHub style).
+	syncWebhookSignatureHeader = "X-Signature-256"
+	// NetBox signs webhook bodies with HMAC-SHA512 as plain hex.
+	netboxSignatureHeader = "X-Hook-Signature"
+	// Senders that cannot sign (such as Armis notification rules) send the
+	// shared secret itself.
+	syncWebhookSecretHeader = "X-Webhook-Secret"
+)
+
+var errSyncWebhookSignature = errors.New("sync webhook signature invalid")
+
+// SyncWebhookConfig enables the receiver for change notifications from sync
+// sources. Each notification is posted to Path + <source key> and must be
+// authenticated with the source's webhook_secret.
+type SyncWebhookConfig struct {
+	Enabled    bool     `json:"enabled"`
+	ListenAddr string   `json:"listen_addr"`        // e.g. ":8089"
+	Path       string   `json:"path,omitempty"`     // default: /sync/webhook/
+	Debounce   Duration `json:"debounce,omitempty"` // notifications within this window share one run (default: 10s)
+}
+
+// syncWebhookReceiver validates notifications and coalesces bursts so each
+// source syncs at most once per debounce window.
+type syncWebhookReceiver struct {
+	path     string
+	debounce time.Duration
+	lookup   func(key string) (secret string, ok bool)
+	trigger  func(key string) bool
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer
+}
+
+func newSyncWebhookReceiver(
+	cfg *SyncWebhookConfig,
+	lookup func(string) (string, bool),
+	trigger func(string) bool,
+) *syncWebhookReceiver {
+	path := defaultSyncWebhookPath
+	debounce := defaultSyncWebhookDebounce
+
+	if cfg != nil {
+		if p := strings.TrimSpace(cfg.Path); p != "" {
+			path = "/" + strings.Trim(p, "/") + "/"
+		}
+
+		if cfg.Debounce > 0 {
+			debounce = time.Duration(cfg.Debounce)
+		}
+	}
+
+	return &syncWebhookReceiver{
+		path:     path,
+		debounce: debounce,
+		lookup:   lookup,
+		trigger:  trigger,
+		pending:  make(map[string]*time.Timer),
+	}
+}
+
+func (w *syncWebhookReceiver) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	key := strings.Trim(strings.TrimPrefix(req.URL.Path, w.path), "/")
+
+	// Unknown sources and sources without a secret look the same to callers,
+	// so the endpoint cannot be used to enumerate configured sources.
+	secret, ok := w.lookup(key)
+	if key == "" || !ok || secret == "" {
+		http.NotFound(rw, req)
+
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(rw, req.Body, maxSyncWebhookBody))
+	if err != nil {
+		http.Error(rw, "invalid request body", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := verifySyncWebhook(req.Header, body, secret); err != nil {
+		http.Error(rw, "invalid signature", http.StatusUnauthorized)
+
+		return
+	}
+
+	w.schedule(key)
+
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+// schedule triggers a run for key once the debounce window closes. Further
+// notifications inside the window are folded into that run.
+func (w *syncWebhookReceiver) schedule(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.pending[key]; ok {
+		return
+	}
+
+	w.pending[key] = time.AfterFunc(w.debounce, func() {
+		w.mu.Lock()
+		delete(w.pending, key)
+		w.mu.Unlock()
+
+		w.trigger(key)
+	})
+}
+
+func (w *syncWebhookReceiver) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key, timer := range w.pending {
+		timer.Stop()
+		delete(w.pending, key)
+	}
+}
+
+// verifySyncWebhook accepts an HMAC-SHA256 or NetBox HMAC-SHA512 signature of
+// the body, or the shared secret itself.
+func verifySyncWebhook(header http.Header, body []byte, secret string) error {
+	if sig := header.Get(syncWebhookSignatureHeader); sig != "" {
+		return checkHMAC(sha256.New, strings.TrimPrefix(sig, "sha256="), body, secret)
+	}
+
+	if sig := header.Get(netboxSignatureHeader); sig != "" {
+		return checkHMAC(sha512.New, sig, body, secret)
+	}
+
+	if provided := header.Get(syncWebhookSecretHeader); provided != "" {
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(secret)) == 1 {
+			return nil
+		}
+	}
+
+	return errSyncWebhookSignature
+}
+
+func checkHMAC(newHash func() hash.Hash, signature string, body []byte, secret string) error {
+	provided, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return errSyncWebhookSignature
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+
+	if !hmac.Equal(provided, mac.Sum(nil)) {
+		return errSyncWebhookSignature
+	}
+
+	return nil
+}
+
+// ServeWebhooks runs the sync webhook receiver until ctx is done. It returns
+// immediately when the receiver is not enabled.
+func (r *SyncRuntime) ServeWebhooks(ctx context.Context) {
+	r.server.mu.RLock()
+	cfg := r.server.config.SyncWebhook
+	r.server.mu.RUnlock()
+
+	if cfg == nil || !cfg.Enabled || strings.TrimSpace(cfg.ListenAddr) == "" {
+		return
+	}
+
+	receiver := newSyncWebhookReceiver(cfg, r.webhookSecret, r.TriggerSource)
+	defer receiver.stop()
+
+	mux := http.NewServeMux()
+	mux.Handle(receiver.path, receiver)
+
+	srv := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	r.logger.Info().Str("addr", cfg.ListenAddr).Str("path", receiver.path).Msg("Sync webhook receiver listening")
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		r.logger.Error().Err(err).Msg("Sync webhook receiver stopped")
+	}
+}
+
+func (r *SyncRuntime) webhookSecret(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runner, ok := r.sources[key]
+	if !ok {
+		return "", false
+	}
+
+	return runner.config.WebhookSecret, true
+}
+
+// TriggerSource starts an out-of-schedule run of the named source. It
+// reports false if the source is not running. A run already in progress
+// absorbs the trigger.
+func (r *SyncRuntime) TriggerSource(key string) bool {
+	r.mu.Lock()
+	runner, ok := r.sources[key]
+	r.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	r.logger.Info().Str("source", key).Msg("Sync run triggered by webhook")
+
+	go r.executeRun(runner.ctx, runner, syncWebhookRunKind)
+
+	return true
+}
@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+const testWebhookSecret = "s3cret"
+
+func hmacHex(newHash func() hash.Hash, body []byte) string {
+	mac := hmac.New(newHash, []byte(testWebhookSecret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySyncWebhook(t *testing.T) {
+	body := []byte(`{"event":"updated","model":"dcim.device"}`)
+
+	sha256Sig := hmacHex(sha256.New, body)
+	sha512Sig := hmacHex(sha512.New, body)
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		valid  bool
+	}{
+		{name: "hmac sha256", header: map[string]string{syncWebhookSignatureHeader: "sha256=" + sha256Sig}, valid: true},
+		{name: "netbox sha512", header: map[string]string{netboxSignatureHeader: sha512Sig}, valid: true},
+		{name: "shared secret", header: map[string]string{syncWebhookSecretHeader: testWebhookSecret}, valid: true},
+		{name: "wrong secret", header: map[string]string{syncWebhookSecretHeader: "guess"}},
+		{name: "tampered signature", header: map[string]string{syncWebhookSignatureHeader: "sha256=" + sha512Sig[:64]}},
+		{name: "malformed signature", header: map[string]string{netboxSignatureHeader: "not-hex"}},
+		{name: "unsigned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.header {
+				header.Set(k, v)
+			}
+
+			err := verifySyncWebhook(header, body, testWebhookSecret)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, errSyncWebhookSignature)
+			}
+		})
+	}
+}
+
+type triggerRecorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *triggerRecorder) trigger(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, key)
+
+	return true
+}
+
+func (r *triggerRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.calls)
+}
+
+func newTestReceiver(debounce time.Duration, recorder *triggerRecorder) *syncWebhookReceiver {
+	secrets := map[string]string{"armis-prod": testWebhookSecret, "netbox": ""}
+
+	return newSyncWebhookReceiver(
+		&SyncWebhookConfig{Debounce: Duration(debounce)},
+		func(key string) (string, bool) {
+			secret, ok := secrets[key]
+
+			return secret, ok
+		},
+		recorder.trigger,
+	)
+}
+
+func postWebhook(receiver http.Handler, source, secret string) int {
+	req := httptest.NewRequest(http.MethodPost, defaultSyncWebhookPath+source, strings.NewReader(`{}`))
+	req.Header.Set(syncWebhookSecretHeader, secret)
+
+	rec := httptest.NewRecorder()
+	receiver.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+func TestSyncWebhookTriggersSource(t *testing.T) {
+	recorder := &triggerRecorder{}
+	receiver := newTestReceiver(10*time.Millisecond, recorder)
+	defer receiver.stop()
+
+	assert.Equal(t, http.StatusUnauthorized, postWebhook(receiver, "armis-prod", "wrong"))
+	assert.Equal(t, http.StatusNotFound, postWebhook(receiver, "unknown", testWebhookSecret))
+	assert.Equal(t, http.StatusNotFound, postWebhook(receiver, "netbox", ""), "sources without a secret are not triggerable")
+	assert.Equal(t, http.StatusAccepted, postWebhook(receiver, "armis-prod", testWebhookSecret))
+
+	require.Eventually(t, func() bool { return recorder.count() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"armis-prod"}, recorder.calls)
+}
+
+func TestSyncWebhookDebouncesBursts(t *testing.T) {
+	recorder := &triggerRecorder{}
+	receiver := newTestReceiver(50*time.Millisecond, recorder)
+	defer receiver.stop()
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, http.StatusAccepted, postWebhook(receiver, "armis-prod", testWebhookSecret))
+	}
+
+	require.Eventually(t, func() bool { return recorder.count() == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, recorder.count(), "a burst triggers a single run")
+
+	require.Equal(t, http.StatusAccepted, postWebhook(receiver, "armis-prod", testWebhookSecret))
+	require.Eventually(t, func() bool { return recorder.count() == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestTriggerSourceUnknown(t *testing.T) {
+	runtime := NewSyncRuntime(nil, nil, logger.NewTestLogger())
+
+	assert.False(t, runtime.TriggerSource("missing"))
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/carverauto/serviceradar/go/pkg/sysmon"
+)
+
+// SysmonFlushConfig controls how drained sysmon samples are batched before they
+// are streamed to the gateway. The zero value flushes on every push cycle.
+type SysmonFlushConfig struct {
+	MaxBatchSize  int      `json:"max_batch_size,omitempty"` // Flush once this many samples are buffered
+	FlushInterval Duration `json:"flush_interval,omitempty"` // Flush once the oldest buffered sample is this old
+}
+
+const (
+	// defaultSysmonFlushMaxAge bounds how long samples wait for a size-only
+	// batch to fill, so a slow trickle is still delivered.
+	defaultSysmonFlushMaxAge = time.Minute
+
+	// sysmonFlushMaxPending caps the samples held while the gateway rejects
+	// flushes; the oldest are dropped beyond it.
+	sysmonFlushMaxPending = 1000
+)
+
+// sysmonFlushBuffer accumulates sysmon samples across push cycles and releases
+// them when the size or max-age trigger fires. Samples from a failed flush are
+// requeued ahead of newer ones.
+type sysmonFlushBuffer struct {
+	mu         sync.Mutex
+	pending    []*sysmon.MetricSample
+	oldest     time.Time
+	maxBatch   int
+	maxAge     time.Duration
+	maxPending int
+}
+
+func newSysmonFlushBuffer(cfg *SysmonFlushConfig) *sysmonFlushBuffer {
+	buf := &sysmonFlushBuffer{maxPending: sysmonFlushMaxPending}
+	if cfg != nil {
+		buf.maxBatch = cfg.MaxBatchSize
+		buf.maxAge = time.Duration(cfg.FlushInterval)
+	}
+
+	if buf.maxBatch > 0 && buf.maxAge <= 0 {
+		buf.maxAge = defaultSysmonFlushMaxAge
+	}
+
+	buf.maxPending = max(buf.maxPending, buf.maxBatch)
+
+	return buf
+}
+
+// add appends samples drained at now, ignoring empty input. It returns how
+// many of the oldest samples were dropped to stay within the pending cap.
+func (b *sysmonFlushBuffer) add(now time.Time, samples []*sysmon.MetricSample) int {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) == 0 {
+		b.oldest = now
+	}
+
+	b.pending = append(b.pending, samples...)
+
+	return b.capLocked()
+}
+
+// requeue puts samples from a failed flush, first buffered at since, back
+// ahead of anything buffered meanwhile. It returns how many of the oldest
+// samples were dropped to stay within the pending cap.
+func (b *sysmonFlushBuffer) requeue(since time.Time, samples []*sysmon.MetricSample) int {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) == 0 || since.Before(b.oldest) {
+		b.oldest = since
+	}
+
+	b.pending = append(append([]*sysmon.MetricSample{}, samples...), b.pending...)
+
+	return b.capLocked()
+}
+
+func (b *sysmonFlushBuffer) capLocked() int {
+	dropped := len(b.pending) - b.maxPending
+	if dropped <= 0 {
+		return 0
+	}
+
+	b.pending = append([]*sysmon.MetricSample(nil), b.pending[dropped:]...)
+
+	return dropped
+}
+
+// take returns the buffered samples, and when the oldest of them was
+// buffered, if a flush is due (or force is set), and resets the buffer. It
+// returns nil when samples should keep accumulating.
+func (b *sysmonFlushBuffer) take(now time.Time, force bool) ([]*sysmon.MetricSample, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) == 0 {
+		return nil, time.Time{}
+	}
+
+	if !force && b.batching() {
+		sizeDue := b.maxBatch > 0 && len(b.pending) >= b.maxBatch
+		ageDue := now.Sub(b.oldest) >= b.maxAge
+
+		if !sizeDue && !ageDue {
+			return nil, time.Time{}
+		}
+	}
+
+	samples, since := b.pending, b.oldest
+	b.pending = nil
+	b.oldest = time.Time{}
+
+	return samples, since
+}
+
+func (b *sysmonFlushBuffer) batching() bool {
+	return b.maxAge > 0
+}
+
+// buffered reports how many samples are waiting for a flush.
+func (b *sysmonFlushBuffer) buffered() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.pending)
+}
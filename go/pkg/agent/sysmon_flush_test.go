@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/carverauto/serviceradar/go/pkg/agentgateway"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/sysmon"
+)
+
+func sysmonSamples(n int) []*sysmon.MetricSample {
+	samples := make([]*sysmon.MetricSample, n)
+	for i := range samples {
+		samples[i] = &sysmon.MetricSample{}
+	}
+
+	return samples
+}
+
+func TestSysmonFlushBuffer_DefaultFlushesEveryCycle(t *testing.T) {
+	now := time.Now()
+	buf := newSysmonFlushBuffer(nil)
+
+	flushed, _ := buf.take(now, false)
+	assert.Nil(t, flushed, "empty buffer must not produce a flush")
+
+	buf.add(now, sysmonSamples(1))
+	flushed, _ = buf.take(now, false)
+	assert.Len(t, flushed, 1)
+	assert.Zero(t, buf.buffered())
+}
+
+func TestSysmonFlushBuffer_BatchOnSize(t *testing.T) {
+	now := time.Now()
+	buf := newSysmonFlushBuffer(&SysmonFlushConfig{MaxBatchSize: 5})
+
+	buf.add(now, sysmonSamples(3))
+	flushed, _ := buf.take(now, false)
+	assert.Nil(t, flushed)
+	assert.Equal(t, 3, buf.buffered())
+
+	buf.add(now, sysmonSamples(2))
+	flushed, since := buf.take(now, false)
+	require.Len(t, flushed, 5)
+	assert.Equal(t, now, since)
+	assert.Zero(t, buf.buffered())
+}
+
+func TestSysmonFlushBuffer_BatchOnInterval(t *testing.T) {
+	start := time.Now()
+	buf := newSysmonFlushBuffer(&SysmonFlushConfig{
+		MaxBatchSize:  100,
+		FlushInterval: Duration(time.Minute),
+	})
+
+	buf.add(start, sysmonSamples(2))
+	flushed, _ := buf.take(start.Add(30*time.Second), false)
+	assert.Nil(t, flushed)
+
+	buf.add(start.Add(30*time.Second), sysmonSamples(1))
+	flushed, _ = buf.take(start.Add(time.Minute), false)
+	require.Len(t, flushed, 3)
+
+	// The age is measured from the oldest sample buffered since the flush.
+	buf.add(start.Add(time.Minute), sysmonSamples(1))
+	flushed, _ = buf.take(start.Add(90*time.Second), false)
+	assert.Nil(t, flushed)
+	flushed, _ = buf.take(start.Add(2*time.Minute), false)
+	assert.Len(t, flushed, 1)
+}
+
+func TestSysmonFlushBuffer_SizeOnlyBatchStillAgesOut(t *testing.T) {
+	start := time.Now()
+	buf := newSysmonFlushBuffer(&SysmonFlushConfig{MaxBatchSize: 100})
+
+	buf.add(start, sysmonSamples(1))
+	flushed, _ := buf.take(start.Add(defaultSysmonFlushMaxAge-time.Second), false)
+	assert.Nil(t, flushed)
+
+	flushed, _ = buf.take(start.Add(defaultSysmonFlushMaxAge), false)
+	assert.Len(t, flushed, 1)
+}
+
+func TestSysmonFlushBuffer_RequeueKeepsOrderAndAge(t *testing.T) {
+	start := time.Now()
+	buf := newSysmonFlushBuffer(&SysmonFlushConfig{
+		MaxBatchSize:  100,
+		FlushInterval: Duration(time.Minute),
+	})
+
+	failed := sysmonSamples(2)
+	buf.add(start, failed)
+	flushed, since := buf.take(start.Add(time.Minute), false)
+	require.Len(t, flushed, 2)
+
+	newer := sysmonSamples(1)
+	buf.add(start.Add(time.Minute), newer)
+	assert.Zero(t, buf.requeue(since, flushed))
+
+	// The requeued samples keep their original age, so they are due again.
+	flushed, _ = buf.take(start.Add(time.Minute), false)
+	assert.Equal(t, append(failed, newer...), flushed)
+}
+
+func TestSysmonFlushBuffer_DropsOldestBeyondPendingCap(t *testing.T) {
+	now := time.Now()
+	buf := newSysmonFlushBuffer(&SysmonFlushConfig{MaxBatchSize: sysmonFlushMaxPending * 2})
+	buf.maxPending = 3
+
+	samples := sysmonSamples(4)
+	assert.Equal(t, 1, buf.add(now, samples))
+
+	flushed, _ := buf.take(now, true)
+	assert.Equal(t, samples[1:], flushed)
+}
+
+func TestSysmonFlushBuffer_ForceFlushOnShutdown(t *testing.T) {
+	now := time.Now()
+	buf := newSysmonFlushBuffer(&SysmonFlushConfig{
+		MaxBatchSize:  100,
+		FlushInterval: Duration(time.Hour),
+	})
+
+	buf.add(now, sysmonSamples(4))
+	buf.add(now, nil)
+	flushed, _ := buf.take(now, false)
+	assert.Nil(t, flushed)
+
+	flushed, _ = buf.take(now, true)
+	require.Len(t, flushed, 4)
+	flushed, _ = buf.take(now, true)
+	assert.Nil(t, flushed)
+}
+
+func TestBuildSysmonChunksAggregatesSamples(t *testing.T) {
+	p := &PushLoop{
+		server:  &Server{config: &ServerConfig{AgentID: "agent-1", HostIP: "10.0.0.1"}},
+		gateway: &agentgateway.GatewayClient{},
+		logger:  logger.NewTestLogger(),
+	}
+
+	samples := []*sysmon.MetricSample{
+		{Timestamp: "2026-10-17T12:00:00Z"},
+		nil,
+		{Timestamp: "2026-10-17T12:00:05Z"},
+		{Timestamp: "2026-10-17T12:00:10Z"},
+	}
+
+	chunks := p.buildSysmonChunks(samples, nil)
+	require.Len(t, chunks, 1)
+	require.Len(t, chunks[0].Services, 1)
+	assert.True(t, chunks[0].IsFinal)
+
+	var payload struct {
+		Status  sysmon.MetricSample   `json:"status"`
+		Samples []sysmon.MetricSample `json:"samples"`
+	}
+	require.NoError(t, json.Unmarshal(chunks[0].Services[0].Message, &payload))
+
+	assert.Equal(t, "2026-10-17T12:00:10Z", payload.Status.Timestamp)
+	require.Len(t, payload.Samples, 2)
+	assert.Equal(t, "2026-10-17T12:00:00Z", payload.Samples[0].Timestamp)
+	assert.Equal(t, "2026-10-17T12:00:05Z", payload.Samples[1].Timestamp)
+}
+
+func TestIsRejectedSysmonPayload(t *testing.T) {
+	assert.True(t, isRejectedSysmonPayload(status.Error(codes.ResourceExhausted, "payload exceeds max size")))
+	assert.False(t, isRejectedSysmonPayload(status.Error(codes.Unavailable, "gateway down")))
+	assert.False(t, isRejectedSysmonPayload(errSysmonStreamNotAcknowledged))
+}
@@ -207,6 +207,7 @@ func (s *SysmonService) Start(ctx context.Context) error {
 		Bool("cpu", parsed.CollectCPU).
 		Bool("memory", parsed.CollectMemory).
 		Bool("disk", parsed.CollectDisk).
+		Bool("disk_io", parsed.CollectDiskIO).
 		Bool("network", parsed.CollectNetwork).
 		Bool("processes", parsed.CollectProcesses).
 		Msg("Sysmon service started")
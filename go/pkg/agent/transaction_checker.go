@@ -0,0 +1,52 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/carverauto/serviceradar/go/pkg/checker"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+	"github.com/carverauto/serviceradar/proto"
+)
+
+const transactionCheckType = "transaction"
+
+// newTransactionCheckFromConfig builds a synthetic transaction check from a
+// gateway check config. settings["steps"] holds the steps as a JSON array of
+// checker.TransactionStep; the check timeout bounds the whole transaction.
+func newTransactionCheckFromConfig(check *proto.AgentCheckConfig, _ logger.Logger) (checker.Checker, error) {
+	cfg := checker.TransactionConfig{
+		Name:    strings.TrimSpace(check.Name),
+		Timeout: models.Duration(time.Duration(check.TimeoutSec) * time.Second),
+	}
+
+	rawSteps := strings.TrimSpace(check.Settings["steps"])
+	if rawSteps == "" {
+		return nil, errTransactionSteps
+	}
+
+	if err := json.Unmarshal([]byte(rawSteps), &cfg.Steps); err != nil {
+		return nil, fmt.Errorf("%w: %w", errTransactionSteps, err)
+	}
+
+	return checker.NewTransaction(cfg)
+}
@@ -23,6 +23,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/carverauto/serviceradar/go/pkg/agentgateway"
 	"github.com/carverauto/serviceradar/go/pkg/logger"
 	"github.com/carverauto/serviceradar/go/pkg/models"
 	"github.com/carverauto/serviceradar/go/pkg/scan"
@@ -115,6 +116,15 @@ type ServerConfig struct {
 
 	// Embedded sync runtime
 	SyncRuntimeEnabled *bool `json:"sync_runtime_enabled,omitempty"` // Enable embedded integration sync runtime
+	// SyncWebhook accepts change notifications that trigger sync runs (default: disabled)
+	SyncWebhook *SyncWebhookConfig `json:"sync_webhook,omitempty"`
+
+	// SysmonFlush batches sysmon samples across push cycles (default: flush every push)
+	SysmonFlush *SysmonFlushConfig `json:"sysmon_flush,omitempty"`
+	// SelfMonitor reports the agent's own CPU, RSS, goroutine and FD usage (default: disabled)
+	SelfMonitor *SelfMonitorConfig `json:"self_monitor,omitempty"`
+	// GatewayDiagnostics probes the gateway connection layer by layer after repeated failures (default: disabled)
+	GatewayDiagnostics *agentgateway.DiagnosticsConfig `json:"gateway_diagnostics,omitempty"`
 }
 
 // ServiceError represents an error that occurred in a specific service.
@@ -0,0 +1,466 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentgateway
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	srgrpc "github.com/carverauto/serviceradar/go/pkg/grpc"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+// Connection layers, in the order they are probed.
+const (
+	LayerDNS  = "dns"
+	LayerTCP  = "tcp"
+	LayerTLS  = "tls"
+	LayerAuth = "auth"
+)
+
+const (
+	defaultDiagnosticsThreshold = 3
+	defaultProbeTimeout         = 5 * time.Second
+	// tlsRejectWait is how long the TLS probe waits for the gateway to reject
+	// the client certificate, which TLS 1.3 reports after the handshake.
+	tlsRejectWait = time.Second
+)
+
+var (
+	errProbeSkipped        = errors.New("probe skipped")
+	errCertificateRejected = errors.New("gateway rejected the client certificate")
+)
+
+// DiagnosticsConfig enables connection diagnostics. After FailureThreshold
+// consecutive failed attempts to connect or enroll, the client probes DNS,
+// TCP, TLS and authentication in turn and logs a hint naming the layer that
+// fails. Probing repeats every FailureThreshold failures until it recovers.
+type DiagnosticsConfig struct {
+	Enabled          bool   `json:"enabled"`
+	FailureThreshold int    `json:"failure_threshold,omitempty"` // Failed attempts before probing (default: 3)
+	HealthFile       string `json:"health_file,omitempty"`       // Optional file the latest diagnosis is written to as JSON
+}
+
+// ConnectionProbe is the outcome of probing one layer.
+type ConnectionProbe struct {
+	Layer   string `json:"layer"`
+	OK      bool   `json:"ok"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// ConnectionDiagnosis is the local health signal for the gateway connection.
+// FailedLayer and Hint are empty when every probe passed.
+type ConnectionDiagnosis struct {
+	Addr                string            `json:"addr"`
+	Healthy             bool              `json:"healthy"`
+	ConsecutiveFailures int               `json:"consecutive_failures"`
+	LastError           string            `json:"last_error,omitempty"`
+	FailedLayer         string            `json:"failed_layer,omitempty"`
+	Hint                string            `json:"hint"`
+	Probes              []ConnectionProbe `json:"probes,omitempty"`
+	CheckedAt           time.Time         `json:"checked_at"`
+}
+
+type connectionDiagnostics struct {
+	mu          sync.Mutex
+	threshold   int
+	healthFile  string
+	prober      *connectionProber
+	failures    int
+	authFailure bool // the last failure was a rejected enrollment
+	diagnosis   *ConnectionDiagnosis
+}
+
+// connectionProber checks each layer of the gateway connection on its own.
+type connectionProber struct {
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	dial       func(ctx context.Context, network, addr string) (net.Conn, error)
+	// tlsConfig is nil when the connection does not use mTLS; tlsSkip says why.
+	tlsConfig func() (*tls.Config, error)
+	tlsSkip   string
+	timeout   time.Duration
+}
+
+// EnableDiagnostics turns on connection diagnostics. Call it before Connect.
+func (g *GatewayClient) EnableDiagnostics(cfg DiagnosticsConfig) {
+	threshold := cfg.FailureThreshold
+	if threshold <= 0 {
+		threshold = defaultDiagnosticsThreshold
+	}
+
+	g.mu.Lock()
+	g.diagnostics = &connectionDiagnostics{
+		threshold:  threshold,
+		healthFile: cfg.HealthFile,
+		prober:     newConnectionProber(g.security, g),
+	}
+	g.mu.Unlock()
+}
+
+// Diagnosis returns the latest connection diagnosis, or nil when diagnostics
+// are disabled or no diagnosis has been made yet.
+func (g *GatewayClient) Diagnosis() *ConnectionDiagnosis {
+	d := g.getDiagnostics()
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.diagnosis == nil {
+		return nil
+	}
+
+	diagnosis := *d.diagnosis
+	diagnosis.Probes = append([]ConnectionProbe(nil), d.diagnosis.Probes...)
+
+	return &diagnosis
+}
+
+func (g *GatewayClient) getDiagnostics() *connectionDiagnostics {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.diagnostics
+}
+
+func newConnectionProber(security *models.SecurityConfig, g *GatewayClient) *connectionProber {
+	var dialer net.Dialer
+
+	prober := &connectionProber{
+		lookupHost: net.DefaultResolver.LookupHost,
+		dial:       dialer.DialContext,
+		timeout:    defaultProbeTimeout,
+	}
+
+	switch {
+	case security == nil || security.Mode == "" || security.Mode == models.SecurityModeNone:
+		prober.tlsSkip = "insecure connection"
+	case security.Mode == models.SecurityModeMTLS:
+		prober.tlsConfig = func() (*tls.Config, error) {
+			return srgrpc.ClientTLSConfig(security, g.logger)
+		}
+	default:
+		prober.tlsSkip = fmt.Sprintf("not probed in %s mode", security.Mode)
+	}
+
+	return prober
+}
+
+// recordFailure counts a failed connection or enrollment attempt and
+// diagnoses the connection every threshold failures.
+func (g *GatewayClient) recordFailure(ctx context.Context, err error) {
+	d := g.getDiagnostics()
+	if d == nil || err == nil || ctx.Err() != nil {
+		return
+	}
+
+	// Configuration errors already say what to fix.
+	if errors.Is(err, ErrGatewayAddrRequired) || errors.Is(err, ErrSecurityRequired) {
+		return
+	}
+
+	d.mu.Lock()
+	d.failures++
+	d.authFailure = isAuthRejection(err)
+	failures := d.failures
+	d.mu.Unlock()
+
+	if failures%d.threshold != 0 {
+		return
+	}
+
+	diagnosis := d.prober.diagnose(ctx, g.addr, err)
+	diagnosis.ConsecutiveFailures = failures
+
+	g.logger.Warn().
+		Str("addr", g.addr).
+		Int("consecutive_failures", failures).
+		Str("failed_layer", diagnosis.FailedLayer).
+		Str("hint", diagnosis.Hint).
+		Err(err).
+		Msg("Gateway connection keeps failing")
+
+	g.storeDiagnosis(d, diagnosis)
+}
+
+// recordSuccess resets the failure count. A connection alone does not clear a
+// rejected enrollment; only an accepted one does.
+func (g *GatewayClient) recordSuccess(enrolled bool) {
+	d := g.getDiagnostics()
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	if d.failures == 0 || (d.authFailure && !enrolled) {
+		d.mu.Unlock()
+		return
+	}
+
+	d.failures = 0
+	d.authFailure = false
+	diagnosed := d.diagnosis != nil && !d.diagnosis.Healthy
+	d.mu.Unlock()
+
+	if !diagnosed {
+		return
+	}
+
+	g.logger.Info().Str("addr", g.addr).Msg("Gateway connection recovered")
+
+	g.storeDiagnosis(d, &ConnectionDiagnosis{
+		Addr:      g.addr,
+		Healthy:   true,
+		CheckedAt: time.Now().UTC(),
+	})
+}
+
+func (g *GatewayClient) storeDiagnosis(d *connectionDiagnostics, diagnosis *ConnectionDiagnosis) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.diagnosis = diagnosis
+
+	if d.healthFile == "" {
+		return
+	}
+
+	if err := writeDiagnosisFile(d.healthFile, diagnosis); err != nil {
+		g.logger.Warn().Err(err).Str("path", d.healthFile).Msg("Failed to write gateway connection health file")
+	}
+}
+
+func writeDiagnosisFile(path string, diagnosis *ConnectionDiagnosis) error {
+	data, err := json.MarshalIndent(diagnosis, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil { //nolint:gosec // meant to be read by operators
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// diagnose probes DNS, TCP and TLS in turn and stops at the first layer that
+// fails. Authentication has no probe of its own: it is judged from the
+// gateway's answer to the last attempt and from the TLS probe.
+func (p *connectionProber) diagnose(ctx context.Context, addr string, lastErr error) *ConnectionDiagnosis {
+	diagnosis := &ConnectionDiagnosis{
+		Addr:      addr,
+		CheckedAt: time.Now().UTC(),
+	}
+	if lastErr != nil {
+		diagnosis.LastError = lastErr.Error()
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		diagnosis.FailedLayer = LayerDNS
+		diagnosis.Hint = fmt.Sprintf("gateway_addr %q is not a host:port address", addr)
+
+		return diagnosis
+	}
+
+	probes := []struct {
+		layer string
+		run   func(ctx context.Context) (string, error)
+	}{
+		{LayerDNS, func(ctx context.Context) (string, error) { return p.probeDNS(ctx, host) }},
+		{LayerTCP, func(ctx context.Context) (string, error) { return p.probeTCP(ctx, addr) }},
+		{LayerTLS, func(ctx context.Context) (string, error) { return p.probeTLS(ctx, host, addr) }},
+	}
+
+	for _, probe := range probes {
+		probeCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		detail, err := probe.run(probeCtx)
+		cancel()
+
+		switch {
+		case errors.Is(err, errProbeSkipped):
+			diagnosis.Probes = append(diagnosis.Probes, ConnectionProbe{
+				Layer: probe.layer, OK: true, Skipped: true, Detail: detail,
+			})
+		case err != nil:
+			diagnosis.Probes = append(diagnosis.Probes, ConnectionProbe{Layer: probe.layer, Detail: err.Error()})
+
+			layer := probe.layer
+			if errors.Is(err, errCertificateRejected) {
+				layer = LayerAuth
+			}
+
+			diagnosis.FailedLayer = layer
+			diagnosis.Hint = connectionHint(layer, host, port, addr)
+
+			return diagnosis
+		default:
+			diagnosis.Probes = append(diagnosis.Probes, ConnectionProbe{Layer: probe.layer, OK: true, Detail: detail})
+		}
+	}
+
+	if isAuthRejection(lastErr) {
+		diagnosis.Probes = append(diagnosis.Probes, ConnectionProbe{Layer: LayerAuth, Detail: lastErr.Error()})
+		diagnosis.FailedLayer = LayerAuth
+		diagnosis.Hint = connectionHint(LayerAuth, host, port, addr)
+
+		return diagnosis
+	}
+
+	diagnosis.Probes = append(diagnosis.Probes, ConnectionProbe{Layer: LayerAuth, OK: true})
+	diagnosis.Hint = fmt.Sprintf(
+		"%s is reachable and accepts the agent's TLS credentials; check the agent-gateway logs for why gRPC requests fail",
+		addr,
+	)
+
+	return diagnosis
+}
+
+func (p *connectionProber) probeDNS(ctx context.Context, host string) (string, error) {
+	if net.ParseIP(host) != nil {
+		return "address is an IP, no lookup needed", errProbeSkipped
+	}
+
+	addrs, err := p.lookupHost(ctx, host)
+	if err != nil {
+		return "", err
+	}
+
+	return "resolved to " + strings.Join(addrs, ", "), nil
+}
+
+func (p *connectionProber) probeTCP(ctx context.Context, addr string) (string, error) {
+	start := time.Now()
+
+	conn, err := p.dial(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	_ = conn.Close()
+
+	return fmt.Sprintf("connected in %s", time.Since(start).Round(time.Millisecond)), nil
+}
+
+func (p *connectionProber) probeTLS(ctx context.Context, host, addr string) (string, error) {
+	if p.tlsConfig == nil {
+		return p.tlsSkip, errProbeSkipped
+	}
+
+	cfg, err := p.tlsConfig()
+	if err != nil {
+		return "", fmt.Errorf("load client TLS credentials: %w", err)
+	}
+
+	cfg = cfg.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+
+	rawConn, err := p.dial(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+
+	conn := tls.Client(rawConn, cfg)
+	defer func() { _ = conn.Close() }()
+
+	if err := conn.HandshakeContext(ctx); err != nil {
+		if isCertificateAlert(err) {
+			return "", fmt.Errorf("%w: %w", errCertificateRejected, err)
+		}
+
+		return "", err
+	}
+
+	// With TLS 1.3 the gateway verifies the client certificate after the
+	// handshake completes and reports a rejection as an alert on first read.
+	_ = conn.SetReadDeadline(time.Now().Add(tlsRejectWait))
+
+	var buf [1]byte
+	if _, err := conn.Read(buf[:]); err != nil && isCertificateAlert(err) {
+		return "", fmt.Errorf("%w: %w", errCertificateRejected, err)
+	}
+
+	return "handshake completed with " + tls.VersionName(conn.ConnectionState().Version), nil
+}
+
+// isCertificateAlert reports whether the gateway answered with a TLS alert
+// about the client certificate.
+func isCertificateAlert(err error) bool {
+	msg := err.Error()
+
+	return strings.Contains(msg, "remote error: tls:") &&
+		(strings.Contains(msg, "certificate") || strings.Contains(msg, "access denied"))
+}
+
+// isAuthRejection reports whether the gateway refused the agent's credentials.
+func isAuthRejection(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, ErrEnrollmentRejected) || errors.Is(err, errCertificateRejected) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return true
+	default:
+		return isCertificateAlert(err)
+	}
+}
+
+func connectionHint(layer, host, port, addr string) string {
+	switch layer {
+	case LayerDNS:
+		return fmt.Sprintf("cannot resolve %q: check gateway_addr and the DNS configuration of this host", host)
+	case LayerTCP:
+		return fmt.Sprintf(
+			"cannot open a TCP connection to %s: check that agent-gateway is running and listening on port %s "+
+				"and that no firewall blocks it", addr, port)
+	case LayerTLS:
+		return fmt.Sprintf(
+			"TLS handshake with %s failed: check the ca_file, cert_file, key_file and server_name in "+
+				"gateway_security and that the gateway serves mTLS on this port", addr)
+	case LayerAuth:
+		return "the gateway rejected the agent's credentials: check that the client certificate is issued by " +
+			"the CA the gateway trusts, has not expired, and that the agent is allowed to enroll"
+	default:
+		return ""
+	}
+}
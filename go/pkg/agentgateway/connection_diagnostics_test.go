@@ -0,0 +1,300 @@
+package agentgateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+var errNoSuchHost = errors.New("no such host")
+
+func newTestProber() *connectionProber {
+	var dialer net.Dialer
+
+	return &connectionProber{
+		lookupHost: func(context.Context, string) ([]string, error) { return []string{"127.0.0.1"}, nil },
+		dial:       dialer.DialContext,
+		tlsSkip:    "insecure connection",
+		timeout:    2 * time.Second,
+	}
+}
+
+// serve accepts connections on a local listener and hands each to handle.
+func serve(t *testing.T, listener net.Listener, handle func(net.Conn)) string {
+	t.Helper()
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer func() { _ = conn.Close() }()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func listenTCP(t *testing.T) net.Listener {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	return listener
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and a pool trusting it.
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gateway"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestDiagnoseDNSFailure(t *testing.T) {
+	t.Parallel()
+
+	prober := newTestProber()
+	prober.lookupHost = func(context.Context, string) ([]string, error) { return nil, errNoSuchHost }
+
+	diagnosis := prober.diagnose(t.Context(), "gateway.example.invalid:50052", errors.New("connect timeout"))
+
+	assert.Equal(t, LayerDNS, diagnosis.FailedLayer)
+	assert.Contains(t, diagnosis.Hint, `cannot resolve "gateway.example.invalid"`)
+	require.Len(t, diagnosis.Probes, 1)
+	assert.False(t, diagnosis.Probes[0].OK)
+	assert.Equal(t, "connect timeout", diagnosis.LastError)
+}
+
+func TestDiagnoseTCPFailure(t *testing.T) {
+	t.Parallel()
+
+	listener := listenTCP(t)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	diagnosis := newTestProber().diagnose(t.Context(), addr, errors.New("connect timeout"))
+
+	assert.Equal(t, LayerTCP, diagnosis.FailedLayer)
+	assert.Contains(t, diagnosis.Hint, "cannot open a TCP connection to "+addr)
+	require.Len(t, diagnosis.Probes, 2)
+	assert.True(t, diagnosis.Probes[0].Skipped, "IP addresses need no lookup")
+}
+
+func TestDiagnoseTLSFailure(t *testing.T) {
+	t.Parallel()
+
+	// A plaintext server where the gateway's TLS port should be.
+	addr := serve(t, listenTCP(t), func(conn net.Conn) {
+		_, _ = conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+	})
+
+	_, pool := selfSignedCert(t)
+	prober := newTestProber()
+	prober.tlsConfig = func() (*tls.Config, error) {
+		return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS13}, nil
+	}
+
+	diagnosis := prober.diagnose(t.Context(), addr, errors.New("connect timeout"))
+
+	assert.Equal(t, LayerTLS, diagnosis.FailedLayer)
+	assert.Contains(t, diagnosis.Hint, "TLS handshake with "+addr+" failed")
+}
+
+func TestDiagnoseUnloadableClientCertificate(t *testing.T) {
+	t.Parallel()
+
+	addr := serve(t, listenTCP(t), func(net.Conn) {})
+
+	prober := newTestProber()
+	prober.tlsConfig = func() (*tls.Config, error) { return nil, os.ErrNotExist }
+
+	diagnosis := prober.diagnose(t.Context(), addr, errors.New("connect timeout"))
+
+	assert.Equal(t, LayerTLS, diagnosis.FailedLayer)
+	assert.Contains(t, diagnosis.Probes[len(diagnosis.Probes)-1].Detail, "load client TLS credentials")
+}
+
+func TestDiagnoseRejectedClientCertificate(t *testing.T) {
+	t.Parallel()
+
+	cert, pool := selfSignedCert(t)
+	listener := tls.NewListener(listenTCP(t), &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	})
+	addr := serve(t, listener, func(conn net.Conn) {
+		_ = conn.(*tls.Conn).Handshake()
+	})
+
+	prober := newTestProber()
+	prober.tlsConfig = func() (*tls.Config, error) {
+		// No client certificate, so the gateway rejects the agent.
+		return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS13}, nil
+	}
+
+	diagnosis := prober.diagnose(t.Context(), addr, errors.New("connect timeout"))
+
+	assert.Equal(t, LayerAuth, diagnosis.FailedLayer)
+	assert.Contains(t, diagnosis.Hint, "rejected the agent's credentials")
+}
+
+func TestDiagnoseRejectedEnrollment(t *testing.T) {
+	t.Parallel()
+
+	addr := serve(t, listenTCP(t), func(net.Conn) {})
+
+	for name, lastErr := range map[string]error{
+		"unauthenticated":   fmt.Errorf("failed to send Hello: %w", status.Error(codes.Unauthenticated, "unknown agent")),
+		"permission denied": status.Error(codes.PermissionDenied, "agent not allowed"),
+		"rejected":          fmt.Errorf("%w: agent revoked", ErrEnrollmentRejected),
+	} {
+		diagnosis := newTestProber().diagnose(t.Context(), addr, lastErr)
+
+		assert.Equal(t, LayerAuth, diagnosis.FailedLayer, name)
+		assert.Len(t, diagnosis.Probes, 4, name)
+	}
+}
+
+func TestDiagnoseAllLayersReachable(t *testing.T) {
+	t.Parallel()
+
+	addr := serve(t, listenTCP(t), func(net.Conn) {})
+
+	diagnosis := newTestProber().diagnose(t.Context(), addr, status.Error(codes.Unavailable, "overloaded"))
+
+	assert.Empty(t, diagnosis.FailedLayer)
+	assert.Contains(t, diagnosis.Hint, "check the agent-gateway logs")
+
+	for _, probe := range diagnosis.Probes {
+		assert.True(t, probe.OK, probe.Layer)
+	}
+}
+
+func TestGatewayClientDiagnosesRepeatedFailures(t *testing.T) {
+	t.Parallel()
+
+	healthFile := filepath.Join(t.TempDir(), "gateway-connection.json")
+
+	client := NewGatewayClient("gateway.example.invalid:50052", nil, logger.NewTestLogger())
+	client.EnableDiagnostics(DiagnosticsConfig{Enabled: true, FailureThreshold: 2, HealthFile: healthFile})
+
+	prober := newTestProber()
+	prober.lookupHost = func(context.Context, string) ([]string, error) { return nil, errNoSuchHost }
+	client.diagnostics.prober = prober
+
+	connectErr := errors.New("failed to connect to gateway: context deadline exceeded")
+
+	client.recordFailure(t.Context(), connectErr)
+	assert.Nil(t, client.Diagnosis(), "below the threshold")
+	assert.NoFileExists(t, healthFile)
+
+	client.recordFailure(t.Context(), connectErr)
+
+	diagnosis := client.Diagnosis()
+	require.NotNil(t, diagnosis)
+	assert.False(t, diagnosis.Healthy)
+	assert.Equal(t, LayerDNS, diagnosis.FailedLayer)
+	assert.Equal(t, 2, diagnosis.ConsecutiveFailures)
+
+	data, err := os.ReadFile(healthFile)
+	require.NoError(t, err)
+
+	var written ConnectionDiagnosis
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, LayerDNS, written.FailedLayer)
+
+	client.recordSuccess(false)
+
+	diagnosis = client.Diagnosis()
+	require.NotNil(t, diagnosis)
+	assert.True(t, diagnosis.Healthy, "a successful connection clears the diagnosis")
+
+	data, err = os.ReadFile(healthFile)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.True(t, written.Healthy)
+}
+
+func TestGatewayClientKeepsEnrollmentRejectionUntilEnrolled(t *testing.T) {
+	t.Parallel()
+
+	addr := serve(t, listenTCP(t), func(net.Conn) {})
+
+	client := NewGatewayClient(addr, nil, logger.NewTestLogger())
+	client.EnableDiagnostics(DiagnosticsConfig{Enabled: true, FailureThreshold: 1})
+	client.diagnostics.prober = newTestProber()
+
+	client.recordFailure(t.Context(), fmt.Errorf("%w: unknown agent", ErrEnrollmentRejected))
+	client.recordSuccess(false)
+
+	diagnosis := client.Diagnosis()
+	require.NotNil(t, diagnosis)
+	assert.Equal(t, LayerAuth, diagnosis.FailedLayer, "connecting again does not clear a rejection")
+
+	client.recordSuccess(true)
+	assert.True(t, client.Diagnosis().Healthy)
+}
+
+func TestGatewayClientIgnoresFailuresWithoutDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	client := NewGatewayClient("127.0.0.1:1", nil, logger.NewTestLogger())
+	client.recordFailure(t.Context(), errors.New("boom"))
+	client.recordSuccess(true)
+
+	assert.Nil(t, client.Diagnosis())
+}
@@ -73,6 +73,7 @@ type GatewayClient struct {
 	connected        bool
 	reconnectDelay   time.Duration
 	gatewayID        string
+	diagnostics      *connectionDiagnostics
 	logger           logger.Logger
 }
 
@@ -88,6 +89,17 @@ func NewGatewayClient(addr string, security *models.SecurityConfig, log logger.L
 
 // Connect establishes a connection to the gateway.
 func (g *GatewayClient) Connect(ctx context.Context) error {
+	if err := g.connect(ctx); err != nil {
+		g.recordFailure(ctx, err)
+		return err
+	}
+
+	g.recordSuccess(false)
+
+	return nil
+}
+
+func (g *GatewayClient) connect(ctx context.Context) error {
 	var (
 		staleConn     *grpc.ClientConn
 		staleProvider srgrpc.SecurityProvider
@@ -428,13 +440,18 @@ func (g *GatewayClient) Hello(ctx context.Context, req *proto.AgentHelloRequest)
 	if err != nil {
 		g.logger.Error().Err(err).Msg("Failed to send Hello to gateway")
 		g.markDisconnected()
+		g.recordFailure(ctx, err)
 		return nil, fmt.Errorf("failed to send Hello: %w", err)
 	}
 
 	if !resp.Accepted {
-		return nil, fmt.Errorf("%w: %s", ErrEnrollmentRejected, resp.Message)
+		err := fmt.Errorf("%w: %s", ErrEnrollmentRejected, resp.Message)
+		g.recordFailure(ctx, err)
+		return nil, err
 	}
 
+	g.recordSuccess(true)
+
 	g.logger.Info().
 		Str("agent_id", resp.AgentId).
 		Str("gateway_id", resp.GatewayId).
@@ -0,0 +1,289 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package checker combines service checks. A Composite runs several
+// sub-checks and reports a single health result, so a service that needs
+// e.g. an open port, an HTTP 200 and a valid certificate is only healthy when
+// its aggregation policy is met. A Transaction runs a scripted multi-step
+// HTTP/TCP interaction (login, fetch, logout) as one check with per-step
+// timing.
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carverauto/serviceradar/proto"
+)
+
+// Checker is a single service check. It matches the Check method of the
+// agent's checkers, so they can be used as sub-checks unchanged.
+type Checker interface {
+	Check(ctx context.Context, req *proto.StatusRequest) (bool, json.RawMessage)
+}
+
+// Policy decides how sub-check outcomes combine into the composite result.
+type Policy string
+
+const (
+	// PolicyAll requires every sub-check to pass. It is the default.
+	PolicyAll Policy = "all"
+	// PolicyAny requires at least one sub-check to pass.
+	PolicyAny Policy = "any"
+	// PolicyQuorum requires at least Quorum sub-checks to pass.
+	PolicyQuorum Policy = "quorum"
+)
+
+var (
+	errNoSubChecks         = errors.New("composite check needs at least one sub-check")
+	errSubCheckName        = errors.New("sub-check name is required")
+	errSubCheckType        = errors.New("sub-check type is required")
+	errDuplicateSubCheck   = errors.New("duplicate sub-check name")
+	errUnknownPolicy       = errors.New("unknown aggregation policy")
+	errQuorumOutOfRange    = errors.New("quorum must be between 1 and the number of sub-checks")
+	errQuorumWithoutPolicy = errors.New("quorum is only valid with the quorum policy")
+	errNilFactory          = errors.New("sub-check factory is required")
+	errNilSubCheck         = errors.New("sub-check factory returned no checker")
+)
+
+// SubCheckConfig configures one sub-check of a composite check.
+type SubCheckConfig struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Target   string            `json:"target"`
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// CompositeConfig configures a composite check.
+type CompositeConfig struct {
+	Name   string           `json:"name"`
+	Policy Policy           `json:"policy,omitempty"`
+	Quorum int              `json:"quorum,omitempty"`
+	Checks []SubCheckConfig `json:"checks"`
+}
+
+// Validate checks the configuration and fills in the default policy.
+func (c *CompositeConfig) Validate() error {
+	if len(c.Checks) == 0 {
+		return errNoSubChecks
+	}
+
+	seen := make(map[string]struct{}, len(c.Checks))
+
+	for i, sub := range c.Checks {
+		name := strings.TrimSpace(sub.Name)
+		if name == "" {
+			return fmt.Errorf("checks[%d]: %w", i, errSubCheckName)
+		}
+
+		if strings.TrimSpace(sub.Type) == "" {
+			return fmt.Errorf("checks[%d] %q: %w", i, name, errSubCheckType)
+		}
+
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("%w: %q", errDuplicateSubCheck, name)
+		}
+
+		seen[name] = struct{}{}
+	}
+
+	if c.Policy == "" {
+		c.Policy = PolicyAll
+	}
+
+	switch c.Policy {
+	case PolicyAll, PolicyAny:
+		if c.Quorum != 0 {
+			return fmt.Errorf("%w (policy %q)", errQuorumWithoutPolicy, c.Policy)
+		}
+	case PolicyQuorum:
+		if c.Quorum < 1 || c.Quorum > len(c.Checks) {
+			return fmt.Errorf("%w (quorum %d, %d sub-checks)", errQuorumOutOfRange, c.Quorum, len(c.Checks))
+		}
+	default:
+		return fmt.Errorf("%w: %q", errUnknownPolicy, c.Policy)
+	}
+
+	return nil
+}
+
+// Factory builds the checker for one sub-check.
+type Factory func(SubCheckConfig) (Checker, error)
+
+// SubCheckResult is the outcome of one sub-check in a composite status.
+type SubCheckResult struct {
+	Name      string          `json:"name"`
+	Type      string          `json:"type"`
+	Available bool            `json:"available"`
+	Duration  int64           `json:"duration_ns"`
+	Status    json.RawMessage `json:"status,omitempty"`
+}
+
+// CompositeStatus is the status message reported by a composite check.
+type CompositeStatus struct {
+	Name      string           `json:"name,omitempty"`
+	Policy    Policy           `json:"policy"`
+	Quorum    int              `json:"quorum,omitempty"`
+	Passed    int              `json:"passed"`
+	Total     int              `json:"total"`
+	Available bool             `json:"available"`
+	Checks    []SubCheckResult `json:"checks"`
+}
+
+type subCheck struct {
+	config  SubCheckConfig
+	checker Checker
+}
+
+// Composite runs its sub-checks concurrently and aggregates their results
+// according to its policy.
+type Composite struct {
+	config CompositeConfig
+	checks []subCheck
+	now    func() time.Time
+}
+
+// NewComposite validates cfg and builds each sub-check with factory.
+func NewComposite(cfg CompositeConfig, factory Factory) (*Composite, error) {
+	if factory == nil {
+		return nil, errNilFactory
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	composite := &Composite{config: cfg, now: time.Now}
+
+	for _, sub := range cfg.Checks {
+		checker, err := factory(sub)
+		if err != nil {
+			_ = composite.Close(context.Background())
+
+			return nil, fmt.Errorf("sub-check %q: %w", sub.Name, err)
+		}
+
+		if checker == nil {
+			_ = composite.Close(context.Background())
+
+			return nil, fmt.Errorf("sub-check %q: %w", sub.Name, errNilSubCheck)
+		}
+
+		composite.checks = append(composite.checks, subCheck{config: sub, checker: checker})
+	}
+
+	return composite, nil
+}
+
+// Check runs every sub-check and reports whether the policy is met. The
+// status message is a CompositeStatus with each sub-check's own result.
+func (c *Composite) Check(ctx context.Context, req *proto.StatusRequest) (bool, json.RawMessage) {
+	results := make([]SubCheckResult, len(c.checks))
+
+	var wg sync.WaitGroup
+
+	for i, sub := range c.checks {
+		wg.Add(1)
+
+		go func(i int, sub subCheck) {
+			defer wg.Done()
+
+			start := c.now()
+			available, status := sub.checker.Check(ctx, req)
+
+			if len(status) > 0 && !json.Valid(status) {
+				status = nil
+			}
+
+			results[i] = SubCheckResult{
+				Name:      sub.config.Name,
+				Type:      sub.config.Type,
+				Available: available,
+				Duration:  c.now().Sub(start).Nanoseconds(),
+				Status:    status,
+			}
+		}(i, sub)
+	}
+
+	wg.Wait()
+
+	passed := 0
+
+	for _, result := range results {
+		if result.Available {
+			passed++
+		}
+	}
+
+	status := CompositeStatus{
+		Name:      c.config.Name,
+		Policy:    c.config.Policy,
+		Quorum:    c.config.Quorum,
+		Passed:    passed,
+		Total:     len(results),
+		Available: c.healthy(passed, len(results)),
+		Checks:    results,
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		return false, jsonError(fmt.Sprintf("failed to marshal composite status: %v", err))
+	}
+
+	return status.Available, data
+}
+
+func (c *Composite) healthy(passed, total int) bool {
+	switch c.config.Policy {
+	case PolicyAny:
+		return passed > 0
+	case PolicyQuorum:
+		return passed >= c.config.Quorum
+	case PolicyAll:
+		return passed == total
+	default:
+		return false
+	}
+}
+
+// Close closes every sub-check that holds resources.
+func (c *Composite) Close(ctx context.Context) error {
+	var errs []error
+
+	for _, sub := range c.checks {
+		if closer, ok := sub.checker.(interface{ Close(context.Context) error }); ok {
+			if err := closer.Close(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("sub-check %q: %w", sub.config.Name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func jsonError(msg string) json.RawMessage {
+	payload, err := json.Marshal(map[string]string{"error": msg})
+	if err != nil {
+		return []byte(`{"error":"failed to marshal error"}`)
+	}
+
+	return payload
+}
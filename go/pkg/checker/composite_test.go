@@ -0,0 +1,180 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/proto"
+)
+
+var errFactoryFailed = errors.New("factory failed")
+
+type fakeChecker struct {
+	available bool
+	status    json.RawMessage
+	closed    bool
+}
+
+func (f *fakeChecker) Check(context.Context, *proto.StatusRequest) (bool, json.RawMessage) {
+	return f.available, f.status
+}
+
+func (f *fakeChecker) Close(context.Context) error {
+	f.closed = true
+
+	return nil
+}
+
+// outcomeFactory builds fake sub-checks that pass when their target is "up".
+func outcomeFactory(built map[string]*fakeChecker) Factory {
+	return func(cfg SubCheckConfig) (Checker, error) {
+		fake := &fakeChecker{
+			available: cfg.Target == "up",
+			status:    json.RawMessage(`{"target":"` + cfg.Target + `"}`),
+		}
+
+		if built != nil {
+			built[cfg.Name] = fake
+		}
+
+		return fake, nil
+	}
+}
+
+func subChecks(targets ...string) []SubCheckConfig {
+	names := []string{"port", "http", "cert", "dns"}
+	checks := make([]SubCheckConfig, len(targets))
+
+	for i, target := range targets {
+		checks[i] = SubCheckConfig{Name: names[i], Type: "fake", Target: target}
+	}
+
+	return checks
+}
+
+func TestCompositePolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    Policy
+		quorum    int
+		targets   []string
+		available bool
+	}{
+		{name: "all passes when every sub-check passes", policy: PolicyAll, targets: []string{"up", "up", "up"}, available: true},
+		{name: "all fails on one failed sub-check", policy: PolicyAll, targets: []string{"up", "down", "up"}, available: false},
+		{name: "default policy is all", targets: []string{"up", "down"}, available: false},
+		{name: "any passes on one passing sub-check", policy: PolicyAny, targets: []string{"down", "up", "down"}, available: true},
+		{name: "any fails when every sub-check fails", policy: PolicyAny, targets: []string{"down", "down"}, available: false},
+		{name: "quorum passes when met", policy: PolicyQuorum, quorum: 2, targets: []string{"up", "down", "up"}, available: true},
+		{name: "quorum passes above threshold", policy: PolicyQuorum, quorum: 3, targets: []string{"up", "down", "up", "up"}, available: true},
+		{name: "quorum fails below threshold", policy: PolicyQuorum, quorum: 3, targets: []string{"up", "down", "up", "down"}, available: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			composite, err := NewComposite(CompositeConfig{
+				Name:   "web",
+				Policy: tt.policy,
+				Quorum: tt.quorum,
+				Checks: subChecks(tt.targets...),
+			}, outcomeFactory(nil))
+			require.NoError(t, err)
+
+			available, raw := composite.Check(context.Background(), &proto.StatusRequest{})
+			assert.Equal(t, tt.available, available)
+
+			var status CompositeStatus
+			require.NoError(t, json.Unmarshal(raw, &status))
+			assert.Equal(t, tt.available, status.Available)
+			assert.Equal(t, len(tt.targets), status.Total)
+			require.Len(t, status.Checks, len(tt.targets))
+
+			passed := 0
+
+			for i, result := range status.Checks {
+				assert.Equal(t, subChecks(tt.targets...)[i].Name, result.Name)
+				assert.Equal(t, tt.targets[i] == "up", result.Available)
+				assert.JSONEq(t, `{"target":"`+tt.targets[i]+`"}`, string(result.Status))
+
+				if result.Available {
+					passed++
+				}
+			}
+
+			assert.Equal(t, passed, status.Passed)
+		})
+	}
+}
+
+func TestCompositeConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  CompositeConfig
+		err  error
+	}{
+		{name: "no sub-checks", cfg: CompositeConfig{}, err: errNoSubChecks},
+		{name: "missing name", cfg: CompositeConfig{Checks: []SubCheckConfig{{Type: "icmp"}}}, err: errSubCheckName},
+		{name: "missing type", cfg: CompositeConfig{Checks: []SubCheckConfig{{Name: "ping"}}}, err: errSubCheckType},
+		{
+			name: "duplicate names",
+			cfg:  CompositeConfig{Checks: []SubCheckConfig{{Name: "ping", Type: "icmp"}, {Name: "ping", Type: "icmp"}}},
+			err:  errDuplicateSubCheck,
+		},
+		{name: "unknown policy", cfg: CompositeConfig{Policy: "most", Checks: subChecks("up")}, err: errUnknownPolicy},
+		{name: "quorum too high", cfg: CompositeConfig{Policy: PolicyQuorum, Quorum: 3, Checks: subChecks("up", "up")}, err: errQuorumOutOfRange},
+		{name: "quorum missing", cfg: CompositeConfig{Policy: PolicyQuorum, Checks: subChecks("up", "up")}, err: errQuorumOutOfRange},
+		{name: "quorum with all", cfg: CompositeConfig{Policy: PolicyAll, Quorum: 1, Checks: subChecks("up")}, err: errQuorumWithoutPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, tt.cfg.Validate(), tt.err)
+		})
+	}
+
+	cfg := CompositeConfig{Checks: subChecks("up")}
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, PolicyAll, cfg.Policy)
+}
+
+func TestNewCompositeClosesBuiltSubChecksOnFactoryError(t *testing.T) {
+	built := map[string]*fakeChecker{}
+	build := outcomeFactory(built)
+
+	_, err := NewComposite(CompositeConfig{Checks: subChecks("up", "up", "up")}, func(cfg SubCheckConfig) (Checker, error) {
+		if cfg.Name == "cert" {
+			return nil, errFactoryFailed
+		}
+
+		return build(cfg)
+	})
+	require.ErrorIs(t, err, errFactoryFailed)
+	assert.ErrorContains(t, err, `"cert"`)
+
+	require.Len(t, built, 2)
+
+	for _, fake := range built {
+		assert.True(t, fake.closed)
+	}
+}
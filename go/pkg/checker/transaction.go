@@ -0,0 +1,616 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+	"github.com/carverauto/serviceradar/proto"
+)
+
+// StepType is the protocol a transaction step speaks.
+type StepType string
+
+const (
+	// StepHTTP sends an HTTP request. It is the default.
+	StepHTTP StepType = "http"
+	// StepTCP opens a TCP connection, optionally sends a payload and reads
+	// the reply.
+	StepTCP StepType = "tcp"
+)
+
+const (
+	defaultStepTimeout  = 10 * time.Second
+	maxTransactionSteps = 20
+	maxStepResponseSize = 1 << 20
+)
+
+var (
+	errNoSteps             = errors.New("transaction needs at least one step")
+	errTooManySteps        = errors.New("transaction has too many steps")
+	errStepName            = errors.New("step name is required")
+	errDuplicateStep       = errors.New("duplicate step name")
+	errUnknownStepType     = errors.New("unknown step type")
+	errStepURL             = errors.New("http step needs a url")
+	errStepAddress         = errors.New("tcp step needs an address")
+	errExtractionName      = errors.New("extraction name is required")
+	errDuplicateExtraction = errors.New("duplicate extraction name")
+	errExtractionSource    = errors.New("extraction needs exactly one of json, regex or header")
+	errHeaderOnTCP         = errors.New("header extraction is only valid for http steps")
+	errUndefinedVariable   = errors.New("variable is not extracted by an earlier step")
+	errUnexpectedStatus    = errors.New("unexpected status code")
+	errMissingContent      = errors.New("response does not contain expected content")
+	errNoExtractMatch      = errors.New("no value to extract")
+	errStepSkipped         = errors.New("skipped after an earlier step failed")
+)
+
+// variablePattern matches ${name} references to values extracted by earlier
+// steps.
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`) //nolint:gochecknoglobals // compiled once
+
+// Extraction captures a value from a step's response. Later steps reference
+// it as ${name} in their url, address, headers, body or payload.
+type Extraction struct {
+	Name string `json:"name"`
+	// JSON is a dotted path into a JSON response body, e.g. "data.token" or
+	// "items.0.id".
+	JSON string `json:"json,omitempty"`
+	// Regex is matched against the response body; the first capture group
+	// is extracted, or the whole match if there is none.
+	Regex string `json:"regex,omitempty"`
+	// Header is an HTTP response header name.
+	Header string `json:"header,omitempty"`
+}
+
+// TransactionStep is one step of a synthetic transaction.
+type TransactionStep struct {
+	Name    string          `json:"name"`
+	Type    StepType        `json:"type,omitempty"`
+	Timeout models.Duration `json:"timeout,omitempty"`
+
+	// HTTP steps.
+	Method       string            `json:"method,omitempty"`
+	URL          string            `json:"url,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	ExpectStatus int               `json:"expect_status,omitempty"`
+
+	// TCP steps.
+	Address string `json:"address,omitempty"`
+	Send    string `json:"send,omitempty"`
+
+	// ExpectContains must appear in the response body (HTTP) or reply (TCP).
+	ExpectContains string       `json:"expect_contains,omitempty"`
+	Extract        []Extraction `json:"extract,omitempty"`
+}
+
+// TransactionConfig configures a synthetic transaction: steps that run in
+// order, each able to use values extracted by the steps before it.
+type TransactionConfig struct {
+	Name    string            `json:"name"`
+	Timeout models.Duration   `json:"timeout,omitempty"`
+	Steps   []TransactionStep `json:"steps"`
+}
+
+// Validate checks the configuration, fills in default step types and makes
+// sure every ${variable} is extracted by an earlier step.
+func (c *TransactionConfig) Validate() error {
+	if len(c.Steps) == 0 {
+		return errNoSteps
+	}
+
+	if len(c.Steps) > maxTransactionSteps {
+		return fmt.Errorf("%w (%d, max %d)", errTooManySteps, len(c.Steps), maxTransactionSteps)
+	}
+
+	steps := make(map[string]struct{}, len(c.Steps))
+	variables := make(map[string]struct{})
+
+	for i := range c.Steps {
+		step := &c.Steps[i]
+
+		name := strings.TrimSpace(step.Name)
+		if name == "" {
+			return fmt.Errorf("steps[%d]: %w", i, errStepName)
+		}
+
+		if _, ok := steps[name]; ok {
+			return fmt.Errorf("%w: %q", errDuplicateStep, name)
+		}
+
+		steps[name] = struct{}{}
+
+		if err := step.validate(variables); err != nil {
+			return fmt.Errorf("step %q: %w", name, err)
+		}
+
+		for _, extraction := range step.Extract {
+			variables[extraction.Name] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+func (s *TransactionStep) validate(variables map[string]struct{}) error {
+	if s.Type == "" {
+		s.Type = StepHTTP
+	}
+
+	switch s.Type {
+	case StepHTTP:
+		if strings.TrimSpace(s.URL) == "" {
+			return errStepURL
+		}
+	case StepTCP:
+		if strings.TrimSpace(s.Address) == "" {
+			return errStepAddress
+		}
+	default:
+		return fmt.Errorf("%w: %q", errUnknownStepType, s.Type)
+	}
+
+	for _, text := range s.templated() {
+		for _, match := range variablePattern.FindAllStringSubmatch(text, -1) {
+			if _, ok := variables[match[1]]; !ok {
+				return fmt.Errorf("%w: ${%s}", errUndefinedVariable, match[1])
+			}
+		}
+	}
+
+	seen := make(map[string]struct{}, len(s.Extract))
+
+	for _, extraction := range s.Extract {
+		if strings.TrimSpace(extraction.Name) == "" {
+			return errExtractionName
+		}
+
+		if _, ok := seen[extraction.Name]; ok {
+			return fmt.Errorf("%w: %q", errDuplicateExtraction, extraction.Name)
+		}
+
+		seen[extraction.Name] = struct{}{}
+
+		if err := extraction.validate(s.Type); err != nil {
+			return fmt.Errorf("extraction %q: %w", extraction.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// templated returns every field that may reference ${variables}.
+func (s *TransactionStep) templated() []string {
+	fields := []string{s.URL, s.Body, s.Address, s.Send, s.ExpectContains}
+	for _, value := range s.Headers {
+		fields = append(fields, value)
+	}
+
+	return fields
+}
+
+func (e *Extraction) validate(stepType StepType) error {
+	sources := 0
+
+	for _, source := range []string{e.JSON, e.Regex, e.Header} {
+		if source != "" {
+			sources++
+		}
+	}
+
+	if sources != 1 {
+		return errExtractionSource
+	}
+
+	if e.Header != "" && stepType != StepHTTP {
+		return errHeaderOnTCP
+	}
+
+	if e.Regex != "" {
+		if _, err := regexp.Compile(e.Regex); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// StepResult is the outcome of one transaction step. Extracted lists the
+// names of the values the step captured; the values themselves are not
+// reported since they are often credentials or session tokens.
+type StepResult struct {
+	Name       string   `json:"name"`
+	Type       StepType `json:"type"`
+	Success    bool     `json:"success"`
+	Skipped    bool     `json:"skipped,omitempty"`
+	Duration   int64    `json:"duration_ns"`
+	StatusCode int      `json:"status_code,omitempty"`
+	Extracted  []string `json:"extracted,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	// CertNotAfter is when the server's leaf certificate expires, for HTTP
+	// steps answered over TLS.
+	CertNotAfter *time.Time `json:"cert_not_after,omitempty"`
+}
+
+// TransactionStatus is the status message reported by a transaction check.
+type TransactionStatus struct {
+	Name       string       `json:"name,omitempty"`
+	Available  bool         `json:"available"`
+	Duration   int64        `json:"duration_ns"`
+	FailedStep string       `json:"failed_step,omitempty"`
+	Steps      []StepResult `json:"steps"`
+}
+
+// Transaction runs a synthetic transaction as a single check. Steps run in
+// order and the transaction stops at the first failing step; the remaining
+// steps are reported as skipped.
+type Transaction struct {
+	config  TransactionConfig
+	regexes map[string]*regexp.Regexp
+	client  *http.Client
+	dialer  *net.Dialer
+	now     func() time.Time
+}
+
+// NewTransaction validates cfg and returns a check that runs it.
+func NewTransaction(cfg TransactionConfig) (*Transaction, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	regexes := make(map[string]*regexp.Regexp)
+
+	for _, step := range cfg.Steps {
+		for _, extraction := range step.Extract {
+			if extraction.Regex != "" {
+				regexes[extraction.Regex] = regexp.MustCompile(extraction.Regex)
+			}
+		}
+	}
+
+	return &Transaction{
+		config:  cfg,
+		regexes: regexes,
+		// Each step's timeout comes from its context; redirects are followed.
+		client: &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+		dialer: &net.Dialer{},
+		now:    time.Now,
+	}, nil
+}
+
+// Check runs the transaction and reports whether every step succeeded. The
+// status message is a TransactionStatus with per-step timing.
+func (t *Transaction) Check(ctx context.Context, _ *proto.StatusRequest) (bool, json.RawMessage) {
+	if timeout := time.Duration(t.config.Timeout); timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := t.now()
+	variables := make(map[string]string)
+	status := TransactionStatus{Name: t.config.Name, Available: true}
+
+	for _, step := range t.config.Steps {
+		if !status.Available {
+			status.Steps = append(status.Steps, StepResult{
+				Name:    step.Name,
+				Type:    step.Type,
+				Skipped: true,
+				Error:   errStepSkipped.Error(),
+			})
+
+			continue
+		}
+
+		result := t.runStep(ctx, step, variables)
+		if !result.Success {
+			status.Available = false
+			status.FailedStep = step.Name
+		}
+
+		status.Steps = append(status.Steps, result)
+	}
+
+	status.Duration = t.now().Sub(start).Nanoseconds()
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		return false, jsonError(fmt.Sprintf("failed to marshal transaction status: %v", err))
+	}
+
+	return status.Available, data
+}
+
+// Close releases idle HTTP connections.
+func (t *Transaction) Close(context.Context) error {
+	t.client.CloseIdleConnections()
+
+	return nil
+}
+
+// stepResponse is what a step received, for expectations and extraction.
+type stepResponse struct {
+	statusCode   int
+	header       http.Header
+	body         []byte
+	certNotAfter *time.Time
+}
+
+func (t *Transaction) runStep(ctx context.Context, step TransactionStep, variables map[string]string) StepResult {
+	timeout := time.Duration(step.Timeout)
+	if timeout <= 0 {
+		timeout = defaultStepTimeout
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := StepResult{Name: step.Name, Type: step.Type}
+	start := t.now()
+
+	var (
+		resp *stepResponse
+		err  error
+	)
+
+	switch step.Type {
+	case StepTCP:
+		resp, err = t.runTCP(stepCtx, step, variables)
+	case StepHTTP:
+		resp, err = t.runHTTP(stepCtx, step, variables)
+	default:
+		err = fmt.Errorf("%w: %q", errUnknownStepType, step.Type)
+	}
+
+	if err == nil {
+		result.StatusCode = resp.statusCode
+		result.CertNotAfter = resp.certNotAfter
+		err = t.evaluate(step, resp, variables, &result)
+	}
+
+	result.Duration = t.now().Sub(start).Nanoseconds()
+
+	if err != nil {
+		result.Error = err.Error()
+
+		return result
+	}
+
+	result.Success = true
+
+	return result
+}
+
+func (t *Transaction) runHTTP(ctx context.Context, step TransactionStep, variables map[string]string) (*stepResponse, error) {
+	method := strings.ToUpper(strings.TrimSpace(step.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(expand(step.Body, variables))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, expand(step.URL, variables), body)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+
+	for name, value := range step.Headers {
+		req.Header.Set(name, expand(value, variables))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxStepResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	response := &stepResponse{statusCode: resp.StatusCode, header: resp.Header, body: data}
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		notAfter := resp.TLS.PeerCertificates[0].NotAfter.UTC()
+		response.certNotAfter = &notAfter
+	}
+
+	return response, nil
+}
+
+// runTCP dials the address and writes the payload. If the step expects or
+// extracts content, the reply is read until the expected content appears,
+// the server closes the connection or the step times out.
+func (t *Transaction) runTCP(ctx context.Context, step TransactionStep, variables map[string]string) (*stepResponse, error) {
+	conn, err := t.dialer.DialContext(ctx, "tcp", expand(step.Address, variables))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if step.Send != "" {
+		if _, err := io.WriteString(conn, expand(step.Send, variables)); err != nil {
+			return nil, fmt.Errorf("send: %w", err)
+		}
+	}
+
+	if step.ExpectContains == "" && len(step.Extract) == 0 {
+		return &stepResponse{}, nil
+	}
+
+	want := []byte(expand(step.ExpectContains, variables))
+	reader := bufio.NewReader(io.LimitReader(conn, maxStepResponseSize))
+
+	var reply []byte
+
+	buf := make([]byte, 4096)
+
+	for {
+		n, readErr := reader.Read(buf)
+		reply = append(reply, buf[:n]...)
+
+		if len(want) > 0 && bytes.Contains(reply, want) {
+			break
+		}
+
+		if readErr != nil {
+			if len(reply) == 0 || len(want) > 0 {
+				return nil, fmt.Errorf("read reply: %w", readErr)
+			}
+
+			break
+		}
+	}
+
+	return &stepResponse{body: reply}, nil
+}
+
+func (t *Transaction) evaluate(step TransactionStep, resp *stepResponse, variables map[string]string, result *StepResult) error {
+	if step.Type == StepHTTP {
+		if step.ExpectStatus != 0 && resp.statusCode != step.ExpectStatus {
+			return fmt.Errorf("%w: got %d, want %d", errUnexpectedStatus, resp.statusCode, step.ExpectStatus)
+		}
+
+		if step.ExpectStatus == 0 && (resp.statusCode < 200 || resp.statusCode > 299) {
+			return fmt.Errorf("%w: got %d, want 2xx", errUnexpectedStatus, resp.statusCode)
+		}
+	}
+
+	if step.ExpectContains != "" && !bytes.Contains(resp.body, []byte(expand(step.ExpectContains, variables))) {
+		return errMissingContent
+	}
+
+	for _, extraction := range step.Extract {
+		value, err := t.extract(extraction, resp)
+		if err != nil {
+			return fmt.Errorf("extract %q: %w", extraction.Name, err)
+		}
+
+		variables[extraction.Name] = value
+		result.Extracted = append(result.Extracted, extraction.Name)
+	}
+
+	return nil
+}
+
+func (t *Transaction) extract(extraction Extraction, resp *stepResponse) (string, error) {
+	switch {
+	case extraction.Header != "":
+		value := resp.header.Get(extraction.Header)
+		if value == "" {
+			return "", errNoExtractMatch
+		}
+
+		return value, nil
+	case extraction.Regex != "":
+		match := t.regexes[extraction.Regex].FindSubmatch(resp.body)
+		if match == nil {
+			return "", errNoExtractMatch
+		}
+
+		if len(match) > 1 {
+			return string(match[1]), nil
+		}
+
+		return string(match[0]), nil
+	default:
+		return extractJSON(resp.body, extraction.JSON)
+	}
+}
+
+// extractJSON walks a dotted path through a JSON document. Numeric segments
+// index into arrays. Strings are returned as-is, other values as JSON.
+func extractJSON(body []byte, path string) (string, error) {
+	var current any
+	if err := json.Unmarshal(body, &current); err != nil {
+		return "", fmt.Errorf("response is not JSON: %w", err)
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[segment]
+			if !ok {
+				return "", errNoExtractMatch
+			}
+
+			current = value
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", errNoExtractMatch
+			}
+
+			current = node[index]
+		default:
+			return "", errNoExtractMatch
+		}
+	}
+
+	switch value := current.(type) {
+	case nil:
+		return "", errNoExtractMatch
+	case string:
+		return value, nil
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+
+		return string(data), nil
+	}
+}
+
+// expand replaces ${name} references with extracted values.
+func expand(text string, variables map[string]string) string {
+	if !strings.Contains(text, "${") {
+		return text
+	}
+
+	return variablePattern.ReplaceAllStringFunc(text, func(ref string) string {
+		name := variablePattern.FindStringSubmatch(ref)[1]
+		if value, ok := variables[name]; ok {
+			return value
+		}
+
+		return ref
+	})
+}
@@ -0,0 +1,323 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+func newLoginServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		w.Header().Set("X-Session", "session-42")
+		_, _ = w.Write([]byte(`{"data":{"token":"abc123","user":{"id":7}}}`))
+	})
+	mux.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc123" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"name":"alice","id":` + r.URL.Query().Get("id") + `}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func runTransaction(t *testing.T, cfg TransactionConfig) (bool, TransactionStatus) {
+	t.Helper()
+
+	transaction, err := NewTransaction(cfg)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = transaction.Close(context.Background()) })
+
+	available, raw := transaction.Check(context.Background(), nil)
+
+	var status TransactionStatus
+	require.NoError(t, json.Unmarshal(raw, &status))
+
+	return available, status
+}
+
+func TestTransactionPassesExtractedValuesBetweenSteps(t *testing.T) {
+	server := newLoginServer(t)
+
+	available, status := runTransaction(t, TransactionConfig{
+		Name: "login-flow",
+		Steps: []TransactionStep{
+			{
+				Name:   "login",
+				Method: http.MethodPost,
+				URL:    server.URL + "/login",
+				Extract: []Extraction{
+					{Name: "token", JSON: "data.token"},
+					{Name: "user_id", JSON: "data.user.id"},
+					{Name: "session", Header: "X-Session"},
+				},
+			},
+			{
+				Name:           "profile",
+				URL:            server.URL + "/profile?id=${user_id}",
+				Headers:        map[string]string{"Authorization": "Bearer ${token}"},
+				ExpectContains: `"name":"alice"`,
+			},
+		},
+	})
+
+	require.True(t, available)
+	assert.Equal(t, "login-flow", status.Name)
+	assert.Empty(t, status.FailedStep)
+	require.Len(t, status.Steps, 2)
+
+	login := status.Steps[0]
+	assert.True(t, login.Success)
+	assert.Equal(t, StepHTTP, login.Type)
+	assert.Equal(t, http.StatusOK, login.StatusCode)
+	assert.Equal(t, []string{"token", "user_id", "session"}, login.Extracted)
+	assert.Positive(t, login.Duration)
+
+	profile := status.Steps[1]
+	assert.True(t, profile.Success)
+	assert.Equal(t, http.StatusOK, profile.StatusCode)
+	assert.NotContains(t, string(mustJSON(t, status)), "abc123", "extracted values must not be reported")
+}
+
+func TestTransactionStopsAtFirstFailingStep(t *testing.T) {
+	server := newLoginServer(t)
+
+	available, status := runTransaction(t, TransactionConfig{
+		Steps: []TransactionStep{
+			{Name: "profile", URL: server.URL + "/profile"},
+			{Name: "logout", URL: server.URL + "/logout"},
+		},
+	})
+
+	assert.False(t, available)
+	assert.Equal(t, "profile", status.FailedStep)
+	require.Len(t, status.Steps, 2)
+	assert.Equal(t, http.StatusUnauthorized, status.Steps[0].StatusCode)
+	assert.Contains(t, status.Steps[0].Error, "unexpected status code")
+	assert.True(t, status.Steps[1].Skipped)
+	assert.False(t, status.Steps[1].Success)
+}
+
+func TestTransactionFailsWhenExtractionMisses(t *testing.T) {
+	server := newLoginServer(t)
+
+	available, status := runTransaction(t, TransactionConfig{
+		Steps: []TransactionStep{{
+			Name:    "login",
+			Method:  http.MethodPost,
+			URL:     server.URL + "/login",
+			Extract: []Extraction{{Name: "token", Regex: `"csrf":"([^"]+)"`}},
+		}},
+	})
+
+	assert.False(t, available)
+	assert.Contains(t, status.Steps[0].Error, `extract "token"`)
+}
+
+func TestTransactionReportsCertificateExpiry(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	transaction, err := NewTransaction(TransactionConfig{
+		Steps: []TransactionStep{{Name: "home", URL: server.URL}},
+	})
+	require.NoError(t, err)
+
+	transaction.client = server.Client()
+
+	available, raw := transaction.Check(context.Background(), nil)
+	require.True(t, available)
+
+	var status TransactionStatus
+	require.NoError(t, json.Unmarshal(raw, &status))
+	require.Len(t, status.Steps, 1)
+	require.NotNil(t, status.Steps[0].CertNotAfter)
+	assert.True(t, server.Certificate().NotAfter.Equal(*status.Steps[0].CertNotAfter))
+
+	_, plain := runTransaction(t, TransactionConfig{
+		Steps: []TransactionStep{{Name: "login", Method: http.MethodPost, URL: newLoginServer(t).URL + "/login"}},
+	})
+	assert.Nil(t, plain.Steps[0].CertNotAfter, "plain HTTP has no certificate")
+}
+
+func TestTransactionTCPSteps(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer func() { _ = conn.Close() }()
+
+				_, _ = conn.Write([]byte("220 ready id=srv-9\r\n"))
+
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+
+				_, _ = conn.Write([]byte("250 hello " + strings.TrimSpace(line) + "\r\n"))
+			}(conn)
+		}
+	}()
+
+	available, status := runTransaction(t, TransactionConfig{
+		Steps: []TransactionStep{
+			{
+				Name:           "banner",
+				Type:           StepTCP,
+				Address:        listener.Addr().String(),
+				ExpectContains: "220",
+				Extract:        []Extraction{{Name: "server_id", Regex: `id=(\S+)`}},
+			},
+			{
+				Name:           "greet",
+				Type:           StepTCP,
+				Address:        listener.Addr().String(),
+				Send:           "HELO ${server_id}\r\n",
+				ExpectContains: "250 hello HELO srv-9",
+				Timeout:        models.Duration(2 * time.Second),
+			},
+		},
+	})
+
+	require.True(t, available, "steps: %+v", status.Steps)
+	assert.Equal(t, []string{"server_id"}, status.Steps[0].Extracted)
+	assert.True(t, status.Steps[1].Success)
+}
+
+func TestTransactionConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config TransactionConfig
+		err    error
+	}{
+		{name: "no steps", config: TransactionConfig{}, err: errNoSteps},
+		{
+			name:   "missing step name",
+			config: TransactionConfig{Steps: []TransactionStep{{URL: "http://x"}}},
+			err:    errStepName,
+		},
+		{
+			name: "duplicate step",
+			config: TransactionConfig{Steps: []TransactionStep{
+				{Name: "a", URL: "http://x"}, {Name: "a", URL: "http://y"},
+			}},
+			err: errDuplicateStep,
+		},
+		{
+			name:   "http without url",
+			config: TransactionConfig{Steps: []TransactionStep{{Name: "a"}}},
+			err:    errStepURL,
+		},
+		{
+			name:   "tcp without address",
+			config: TransactionConfig{Steps: []TransactionStep{{Name: "a", Type: StepTCP}}},
+			err:    errStepAddress,
+		},
+		{
+			name:   "unknown type",
+			config: TransactionConfig{Steps: []TransactionStep{{Name: "a", Type: "udp"}}},
+			err:    errUnknownStepType,
+		},
+		{
+			name: "variable from a later step",
+			config: TransactionConfig{Steps: []TransactionStep{
+				{Name: "a", URL: "http://x/${token}"},
+				{Name: "b", URL: "http://x", Extract: []Extraction{{Name: "token", JSON: "token"}}},
+			}},
+			err: errUndefinedVariable,
+		},
+		{
+			name: "extraction with two sources",
+			config: TransactionConfig{Steps: []TransactionStep{{
+				Name: "a", URL: "http://x",
+				Extract: []Extraction{{Name: "t", JSON: "token", Header: "X-Token"}},
+			}}},
+			err: errExtractionSource,
+		},
+		{
+			name: "header extraction on tcp",
+			config: TransactionConfig{Steps: []TransactionStep{{
+				Name: "a", Type: StepTCP, Address: "x:1",
+				Extract: []Extraction{{Name: "t", Header: "X-Token"}},
+			}}},
+			err: errHeaderOnTCP,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, tt.config.Validate(), tt.err)
+		})
+	}
+
+	cfg := TransactionConfig{Steps: []TransactionStep{{Name: "a", URL: "http://x"}}}
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, StepHTTP, cfg.Steps[0].Type)
+
+	bad := TransactionConfig{Steps: []TransactionStep{{
+		Name: "a", URL: "http://x", Extract: []Extraction{{Name: "t", Regex: "("}},
+	}}}
+	require.Error(t, bad.Validate())
+}
+
+func mustJSON(t *testing.T, value any) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+
+	return data
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package checkerresult versions the JSON payloads checkers report in
+// StatusResponse.message and ResultsResponse.data. Core reads and upgrades
+// them in ServiceRadar.CheckerResultSchema.
+//
+// Version 1 is the legacy, unversioned payload. From version 2 on, object
+// payloads carry a top-level "schema_version" field. Agents only stamp a
+// version once core has advertised support for it (see Negotiate), so cores
+// that predate versioning keep receiving the payloads they expect.
+package checkerresult
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// VersionKey is the payload field carrying the schema version.
+	VersionKey = "schema_version"
+	// ConfigKey is the agent config_json field in which core advertises the
+	// highest schema version it accepts.
+	ConfigKey = "result_schema_version"
+
+	// LegacyVersion is assumed for payloads without a schema_version.
+	LegacyVersion = 1
+	// CurrentVersion is the version this build produces.
+	CurrentVersion = 2
+)
+
+// Negotiate returns the version to produce for a peer accepting at most
+// peerMax. A peer that never advertised a version (peerMax <= 0) only
+// understands legacy payloads.
+func Negotiate(produced, peerMax int) int {
+	if peerMax < LegacyVersion {
+		return LegacyVersion
+	}
+
+	return min(produced, peerMax)
+}
+
+// Stamp returns payload marked with version. Legacy payloads, and payloads
+// that are not JSON objects, are returned unchanged.
+func Stamp(payload []byte, version int) []byte {
+	if version <= LegacyVersion {
+		return payload
+	}
+
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) < 2 || trimmed[0] != '{' || !json.Valid(trimmed) {
+		return payload
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return payload
+	}
+
+	fields[VersionKey] = json.RawMessage(fmt.Sprintf("%d", version))
+
+	stamped, err := json.Marshal(fields)
+	if err != nil {
+		return payload
+	}
+
+	return stamped
+}
@@ -0,0 +1,31 @@
+package checkerresult
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStampAddsVersionToObjects(t *testing.T) {
+	legacy := []byte(`{"available":true,"response_time":1200,"host":"10.0.0.1"}`)
+
+	require.JSONEq(t, `{"available":true,"response_time":1200,"host":"10.0.0.1","schema_version":2}`,
+		string(Stamp(legacy, 2)))
+}
+
+func TestNegotiate(t *testing.T) {
+	assert.Equal(t, LegacyVersion, Negotiate(CurrentVersion, 0), "cores that never advertised get legacy payloads")
+	assert.Equal(t, LegacyVersion, Negotiate(CurrentVersion, 1))
+	assert.Equal(t, CurrentVersion, Negotiate(CurrentVersion, CurrentVersion))
+	assert.Equal(t, CurrentVersion, Negotiate(CurrentVersion, CurrentVersion+5), "never produce a version this build does not know")
+}
+
+func TestStampLeavesNonObjectPayloadsAlone(t *testing.T) {
+	for _, payload := range []string{`[1,2]`, `plain text`, ``, `{"broken":`} {
+		assert.Equal(t, payload, string(Stamp([]byte(payload), CurrentVersion)))
+	}
+
+	legacy := []byte(`{"a":1}`)
+	assert.Equal(t, legacy, Stamp(legacy, LegacyVersion))
+}
@@ -893,6 +893,10 @@ func ParseFlags() (*CmdConfig, error) {
 		"edge":                  EdgeHandler{},
 		"nats-bootstrap":        NatsBootstrapHandler{},
 		"admin":                 AdminHandler{},
+		"doctor":                DoctorHandler{},
+		"config-export":         ConfigExportHandler{},
+		"config-import":         ConfigImportHandler{},
+		"tail":                  TailHandler{},
 	}
 
 	// Parse subcommand flags if present
@@ -0,0 +1,272 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/carverauto/serviceradar/go/pkg/config/bundle"
+	"github.com/carverauto/serviceradar/go/pkg/config/kvgrpc"
+	"github.com/carverauto/serviceradar/go/pkg/grpc"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+	"github.com/carverauto/serviceradar/proto"
+)
+
+const (
+	defaultBundleKVAddress  = "localhost:50057"
+	defaultBundleServerName = "datasvc.serviceradar"
+	defaultBundleCertName   = "core"
+	defaultBundleTimeout    = 30 * time.Second
+	defaultBundleCertDir    = "/etc/serviceradar/certs"
+)
+
+// addBundleConnectionFlags registers the flags shared by config-export and
+// config-import for reaching the KV service.
+func addBundleConnectionFlags(fs *flag.FlagSet, cfg *CmdConfig) {
+	fs.StringVar(&cfg.BundleKVAddress, "kv-address", defaultBundleKVAddress, "datasvc KV gRPC address")
+	fs.StringVar(&cfg.BundleSecurityMode, "security", string(models.SecurityModeMTLS), "connection security: mtls or none")
+	fs.StringVar(&cfg.CertDir, "cert-dir", defaultBundleCertDir, "directory holding the client certificate and root.pem")
+	fs.StringVar(&cfg.BundleCertName, "cert-name", defaultBundleCertName, "client certificate name (<name>.pem and <name>-key.pem)")
+	fs.StringVar(&cfg.BundleServerName, "server-name", defaultBundleServerName, "expected server name of the KV service")
+	fs.DurationVar(&cfg.BundleTimeout, "timeout", defaultBundleTimeout, "timeout for the whole operation")
+	fs.Var((*stringSliceFlag)(&cfg.BundlePrefixes), "prefix", "KV key prefix to include (repeatable, defaults to all config prefixes)")
+}
+
+// ConfigExportHandler handles flags for the config-export subcommand.
+type ConfigExportHandler struct{}
+
+// Parse processes the command-line arguments for the config-export subcommand.
+func (ConfigExportHandler) Parse(args []string, cfg *CmdConfig) error {
+	fs := flag.NewFlagSet("config-export", flag.ExitOnError)
+	addBundleConnectionFlags(fs, cfg)
+	fs.StringVar(&cfg.BundleFile, "output", "", "file to write the bundle to (defaults to stdout)")
+	fs.StringVar(&cfg.BundleSource, "source", "", "label recorded in the bundle, e.g. the environment name")
+	fs.BoolVar(&cfg.BundleIncludeSecrets, "include-secrets", false, "export secret values instead of redacting them")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("parsing config-export flags: %w", err)
+	}
+
+	return nil
+}
+
+// ConfigImportHandler handles flags for the config-import subcommand.
+type ConfigImportHandler struct{}
+
+// Parse processes the command-line arguments for the config-import subcommand.
+func (ConfigImportHandler) Parse(args []string, cfg *CmdConfig) error {
+	fs := flag.NewFlagSet("config-import", flag.ExitOnError)
+	addBundleConnectionFlags(fs, cfg)
+	fs.StringVar(&cfg.BundleFile, "file", "", "bundle file to import (required, - for stdin)")
+	fs.BoolVar(&cfg.BundleDryRun, "dry-run", false, "report the changes without writing them")
+	fs.StringVar(&cfg.BundleOnConflict, "on-conflict", string(bundle.ConflictSkip),
+		"what to do when a key already holds a different value: skip, overwrite or fail")
+	fs.BoolVar(&cfg.BundleAllowMissingSecrets, "allow-missing-secrets", false,
+		"import entries whose redacted secrets cannot be filled from the target")
+	fs.StringVar(&cfg.BundleOutputFormat, "output", "text", "report format: text or json")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("parsing config-import flags: %w", err)
+	}
+
+	cfg.BundleOutputFormat = strings.ToLower(strings.TrimSpace(cfg.BundleOutputFormat))
+
+	return nil
+}
+
+// RunConfigExport handles the config-export subcommand.
+func RunConfigExport(cfg *CmdConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), bundleTimeout(cfg))
+	defer cancel()
+
+	store, err := dialBundleStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	b, err := bundle.Export(ctx, store, bundle.ExportOptions{
+		Prefixes:       cfg.BundlePrefixes,
+		IncludeSecrets: cfg.BundleIncludeSecrets,
+		Source:         cfg.BundleSource,
+	})
+	if err != nil {
+		return err
+	}
+
+	if cfg.BundleFile == "" || cfg.BundleFile == "-" {
+		return b.Write(os.Stdout)
+	}
+
+	f, err := os.OpenFile(cfg.BundleFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaultKeyPerms)
+	if err != nil {
+		return fmt.Errorf("creating bundle file: %w", err)
+	}
+
+	if err := b.Write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing bundle file: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d key(s) to %s\n", len(b.Entries), cfg.BundleFile)
+
+	return nil
+}
+
+// RunConfigImport handles the config-import subcommand.
+func RunConfigImport(cfg *CmdConfig) error {
+	if strings.TrimSpace(cfg.BundleFile) == "" {
+		return errBundleFileRequired
+	}
+
+	b, err := readBundleFile(cfg.BundleFile)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), bundleTimeout(cfg))
+	defer cancel()
+
+	store, err := dialBundleStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	report, importErr := bundle.Import(ctx, store, b, bundle.ImportOptions{
+		DryRun:              cfg.BundleDryRun,
+		OnConflict:          bundle.ConflictPolicy(cfg.BundleOnConflict),
+		Prefixes:            cfg.BundlePrefixes,
+		AllowMissingSecrets: cfg.BundleAllowMissingSecrets,
+	})
+
+	if report != nil {
+		if err := writeImportReport(os.Stdout, report, cfg.BundleOutputFormat); err != nil {
+			return err
+		}
+	}
+
+	return importErr
+}
+
+func bundleTimeout(cfg *CmdConfig) time.Duration {
+	if cfg.BundleTimeout <= 0 {
+		return defaultBundleTimeout
+	}
+
+	return cfg.BundleTimeout
+}
+
+func readBundleFile(path string) (*bundle.Bundle, error) {
+	if path == "-" {
+		return bundle.Read(os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening bundle file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return bundle.Read(f)
+}
+
+// dialBundleStore connects to the KV service named by the config flags.
+func dialBundleStore(ctx context.Context, cfg *CmdConfig) (*kvgrpc.Client, error) {
+	security := &models.SecurityConfig{Mode: models.SecurityMode(cfg.BundleSecurityMode)}
+
+	switch security.Mode {
+	case models.SecurityModeNone:
+	case models.SecurityModeMTLS:
+		security.CertDir = cfg.CertDir
+		security.ServerName = cfg.BundleServerName
+		security.Role = models.RoleCore
+		security.TLS = models.TLSConfig{
+			CertFile: cfg.BundleCertName + ".pem",
+			KeyFile:  cfg.BundleCertName + "-key.pem",
+			CAFile:   "root.pem",
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", errBundleSecurityMode, cfg.BundleSecurityMode)
+	}
+
+	log := logger.NewTestLogger()
+
+	provider, err := grpc.NewSecurityProvider(ctx, security, log)
+	if err != nil {
+		return nil, fmt.Errorf("configuring KV security: %w", err)
+	}
+
+	client, err := grpc.NewClient(ctx, grpc.ClientConfig{
+		Address:          cfg.BundleKVAddress,
+		SecurityProvider: provider,
+		Logger:           log,
+		DisableTelemetry: true,
+	})
+	if err != nil {
+		_ = provider.Close()
+		return nil, fmt.Errorf("connecting to KV at %s: %w", cfg.BundleKVAddress, err)
+	}
+
+	return kvgrpc.New(proto.NewKVServiceClient(client.GetConnection()), client.Close), nil
+}
+
+func writeImportReport(w io.Writer, report *bundle.ImportReport, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(report)
+	}
+
+	for _, change := range report.Changes {
+		if change.Action == bundle.ActionUnchanged {
+			continue
+		}
+
+		line := fmt.Sprintf("%-14s %s", change.Action, change.Key)
+		if len(change.MissingSecrets) > 0 {
+			line += " (missing " + strings.Join(change.MissingSecrets, ", ") + ")"
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	verb := "Imported"
+	if report.DryRun {
+		verb = "Dry run"
+	}
+
+	_, err := fmt.Fprintf(w, "%s: %d created, %d updated, %d unchanged, %d skipped\n",
+		verb, report.Created, report.Updated, report.Unchanged, report.Skipped)
+
+	return err
+}
@@ -0,0 +1,462 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDoctorTimeout   = 5 * time.Second
+	defaultClockSkewWarn   = 5 * time.Second
+	defaultClockSkewCrit   = 60 * time.Second
+	defaultPlaceholderID   = "default-agent"
+	cnpgPasswordFileEnvVar = "CNPG_PASSWORD_FILE"
+)
+
+// DoctorSeverity ranks a doctor finding.
+type DoctorSeverity string
+
+const (
+	DoctorCritical DoctorSeverity = "critical"
+	DoctorWarning  DoctorSeverity = "warning"
+)
+
+// DoctorFinding is a single problem detected by the doctor, with a suggested fix.
+type DoctorFinding struct {
+	Check    string         `json:"check"`
+	Severity DoctorSeverity `json:"severity"`
+	Problem  string         `json:"problem"`
+	Fix      string         `json:"fix"`
+}
+
+// DoctorHandler handles flags for the doctor subcommand.
+type DoctorHandler struct{}
+
+// Parse processes the command-line arguments for the doctor subcommand.
+func (DoctorHandler) Parse(args []string, cfg *CmdConfig) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configFile := fs.String("config", "", "path to the service JSON config to inspect")
+	coreURL := fs.String("core-url", "", "ServiceRadar core base URL used for the clock skew check (skipped when empty)")
+	passwordFile := fs.String("cnpg-password-file", "",
+		"CNPG password file to verify (defaults to $"+cnpgPasswordFileEnvVar+")")
+	timeout := fs.Duration("timeout", defaultDoctorTimeout, "timeout for network checks")
+	output := fs.String("output", "text", "Output format: text or json")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("parsing doctor flags: %w", err)
+	}
+
+	cfg.ConfigFile = *configFile
+	cfg.CoreAPIURL = *coreURL
+	cfg.DBPasswordFile = *passwordFile
+	cfg.DoctorTimeout = *timeout
+	cfg.DoctorOutputFormat = strings.ToLower(strings.TrimSpace(*output))
+
+	return nil
+}
+
+// doctor runs the diagnostic checks. Its dependencies are swappable so the
+// checks can be exercised without real network endpoints.
+type doctor struct {
+	configFile   string
+	coreURL      string
+	passwordFile string
+	timeout      time.Duration
+	dial         func(ctx context.Context, network, address string) (net.Conn, error)
+	httpClient   *http.Client
+	now          func() time.Time
+}
+
+func newDoctor(cfg *CmdConfig) *doctor {
+	timeout := cfg.DoctorTimeout
+	if timeout <= 0 {
+		timeout = defaultDoctorTimeout
+	}
+
+	passwordFile := strings.TrimSpace(cfg.DBPasswordFile)
+	if passwordFile == "" {
+		passwordFile = strings.TrimSpace(os.Getenv(cnpgPasswordFileEnvVar))
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+
+	return &doctor{
+		configFile:   strings.TrimSpace(cfg.ConfigFile),
+		coreURL:      strings.TrimSpace(cfg.CoreAPIURL),
+		passwordFile: passwordFile,
+		timeout:      timeout,
+		dial:         dialer.DialContext,
+		httpClient:   &http.Client{Timeout: timeout},
+		now:          time.Now,
+	}
+}
+
+// RunDoctor handles the doctor subcommand. It prints every finding and fails
+// when any of them is critical.
+func RunDoctor(cfg *CmdConfig) error {
+	if strings.TrimSpace(cfg.ConfigFile) == "" {
+		return errDoctorConfigRequired
+	}
+
+	findings, err := newDoctor(cfg).run(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if err := writeDoctorReport(os.Stdout, findings, cfg.DoctorOutputFormat); err != nil {
+		return err
+	}
+
+	if critical := countCritical(findings); critical > 0 {
+		return fmt.Errorf("%w: %d critical", errDoctorFoundProblems, critical)
+	}
+
+	return nil
+}
+
+func (d *doctor) run(ctx context.Context) ([]DoctorFinding, error) {
+	data, err := os.ReadFile(d.configFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errConfigReadFailed, err)
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidConfigJSON, err)
+	}
+
+	var findings []DoctorFinding
+
+	findings = append(findings, checkIdentity(config)...)
+	findings = append(findings, checkCertificates(config)...)
+	findings = append(findings, d.checkKV(ctx, config)...)
+	findings = append(findings, d.checkCNPGPassword(config)...)
+	findings = append(findings, d.checkClockSkew(ctx)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity == DoctorCritical && findings[j].Severity != DoctorCritical
+	})
+
+	return findings, nil
+}
+
+// checkIdentity flags placeholder or missing agent identifiers, which make
+// several agents report as the same device.
+func checkIdentity(config map[string]interface{}) []DoctorFinding {
+	raw, present := config["agent_id"]
+	if !present {
+		return nil
+	}
+
+	agentID, _ := raw.(string)
+	agentID = strings.TrimSpace(agentID)
+
+	switch agentID {
+	case "":
+		return []DoctorFinding{{
+			Check:    "agent_id",
+			Severity: DoctorCritical,
+			Problem:  "agent_id is empty",
+			Fix:      "set agent_id to a unique name for this host, e.g. the hostname",
+		}}
+	case defaultPlaceholderID:
+		return []DoctorFinding{{
+			Check:    "agent_id",
+			Severity: DoctorCritical,
+			Problem:  fmt.Sprintf("agent_id is still the placeholder %q; every agent using it reports as the same agent", agentID),
+			Fix:      "set agent_id to a unique name for this host, e.g. the hostname",
+		}}
+	default:
+		return nil
+	}
+}
+
+// checkCertificates verifies that every certificate referenced by an mTLS
+// security block exists and is readable.
+func checkCertificates(config map[string]interface{}) []DoctorFinding {
+	var findings []DoctorFinding
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key != "security" && !strings.HasSuffix(key, "_security") {
+			continue
+		}
+
+		block, ok := config[key].(map[string]interface{})
+		if !ok || !strings.EqualFold(stringField(block, "mode"), "mtls") {
+			continue
+		}
+
+		certDir := stringField(block, "cert_dir")
+		tls, _ := block["tls"].(map[string]interface{})
+
+		for _, field := range []string{"cert_file", "key_file", "ca_file"} {
+			name := stringField(tls, field)
+			if name == "" {
+				findings = append(findings, DoctorFinding{
+					Check:    key + ".tls." + field,
+					Severity: DoctorCritical,
+					Problem:  fmt.Sprintf("%s uses mtls but tls.%s is not set", key, field),
+					Fix:      fmt.Sprintf("set %s.tls.%s or run `serviceradar generate-tls`", key, field),
+				})
+
+				continue
+			}
+
+			path := name
+			if !filepath.IsAbs(path) && certDir != "" {
+				path = filepath.Join(certDir, path)
+			}
+
+			if err := checkReadable(path); err != nil {
+				findings = append(findings, DoctorFinding{
+					Check:    key + ".tls." + field,
+					Severity: DoctorCritical,
+					Problem:  fmt.Sprintf("certificate file %s is not readable: %v", path, err),
+					Fix:      "generate the certificate with `serviceradar generate-tls` or fix the path and file permissions",
+				})
+			}
+		}
+	}
+
+	return findings
+}
+
+// checkKV dials the configured KV address.
+func (d *doctor) checkKV(ctx context.Context, config map[string]interface{}) []DoctorFinding {
+	address := stringField(config, "kv_address")
+	if address == "" {
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return []DoctorFinding{{
+			Check:    "kv_address",
+			Severity: DoctorCritical,
+			Problem:  fmt.Sprintf("kv_address %q is not a host:port address", address),
+			Fix:      "set kv_address to the datasvc host and port, e.g. datasvc:50057",
+		}}
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	conn, err := d.dial(dialCtx, "tcp", address)
+	if err != nil {
+		return []DoctorFinding{{
+			Check:    "kv_address",
+			Severity: DoctorCritical,
+			Problem:  fmt.Sprintf("KV store at %s is unreachable: %v", address, err),
+			Fix:      "check that datasvc is running and that kv_address points at it from this host",
+		}}
+	}
+
+	_ = conn.Close()
+
+	return nil
+}
+
+// checkCNPGPassword verifies that services with a CNPG block can obtain a
+// password, either inline or from the mounted password file.
+func (d *doctor) checkCNPGPassword(config map[string]interface{}) []DoctorFinding {
+	cnpg, ok := config["cnpg"].(map[string]interface{})
+	if !ok {
+		if d.passwordFile == "" {
+			return nil
+		}
+
+		cnpg = map[string]interface{}{}
+	}
+
+	if d.passwordFile == "" {
+		if stringField(cnpg, "password") != "" {
+			return nil
+		}
+
+		return []DoctorFinding{{
+			Check:    "cnpg.password",
+			Severity: DoctorCritical,
+			Problem:  "no CNPG password is configured and " + cnpgPasswordFileEnvVar + " is not set",
+			Fix:      "mount the CNPG secret and set " + cnpgPasswordFileEnvVar + " to its password file",
+		}}
+	}
+
+	data, err := os.ReadFile(d.passwordFile)
+	if err != nil {
+		return []DoctorFinding{{
+			Check:    "cnpg.password_file",
+			Severity: DoctorCritical,
+			Problem:  fmt.Sprintf("CNPG password file %s is not readable: %v", d.passwordFile, err),
+			Fix:      "check that the CNPG secret is mounted at that path and readable by the service user",
+		}}
+	}
+
+	if strings.TrimSpace(string(data)) == "" {
+		return []DoctorFinding{{
+			Check:    "cnpg.password_file",
+			Severity: DoctorCritical,
+			Problem:  fmt.Sprintf("CNPG password file %s is empty", d.passwordFile),
+			Fix:      "regenerate the CNPG credentials secret",
+		}}
+	}
+
+	return nil
+}
+
+// checkClockSkew compares the local clock with the Date header returned by core.
+func (d *doctor) checkClockSkew(ctx context.Context) []DoctorFinding {
+	if d.coreURL == "" {
+		return nil
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodHead, normaliseCoreURL(d.coreURL), http.NoBody)
+	if err != nil {
+		return []DoctorFinding{coreUnreachable(d.coreURL, err)}
+	}
+
+	sent := d.now()
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return []DoctorFinding{coreUnreachable(d.coreURL, err)}
+	}
+
+	_ = resp.Body.Close()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return []DoctorFinding{{
+			Check:    "clock_skew",
+			Severity: DoctorWarning,
+			Problem:  "core did not return a usable Date header; clock skew could not be measured",
+			Fix:      "check that -core-url points at the ServiceRadar core API",
+		}}
+	}
+
+	// Compare against the midpoint of the request to discount latency.
+	received := d.now()
+	local := sent.Add(received.Sub(sent) / 2)
+
+	skew := local.Sub(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+
+	// The Date header has one second resolution.
+	skew = skew.Truncate(time.Second)
+
+	if skew < defaultClockSkewWarn {
+		return nil
+	}
+
+	severity := DoctorWarning
+	if skew >= defaultClockSkewCrit {
+		severity = DoctorCritical
+	}
+
+	return []DoctorFinding{{
+		Check:    "clock_skew",
+		Severity: severity,
+		Problem:  fmt.Sprintf("local clock differs from core by %s", skew),
+		Fix:      "enable NTP (e.g. chrony or systemd-timesyncd) on this host and on the core host",
+	}}
+}
+
+func coreUnreachable(coreURL string, err error) DoctorFinding {
+	return DoctorFinding{
+		Check:    "clock_skew",
+		Severity: DoctorWarning,
+		Problem:  fmt.Sprintf("core at %s is unreachable, clock skew not checked: %v", coreURL, err),
+		Fix:      "check -core-url and network access to the core API",
+	}
+}
+
+func checkReadable(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	value, _ := m[key].(string)
+	return strings.TrimSpace(value)
+}
+
+func countCritical(findings []DoctorFinding) int {
+	count := 0
+
+	for _, finding := range findings {
+		if finding.Severity == DoctorCritical {
+			count++
+		}
+	}
+
+	return count
+}
+
+func writeDoctorReport(w io.Writer, findings []DoctorFinding, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		if findings == nil {
+			findings = []DoctorFinding{}
+		}
+
+		return encoder.Encode(findings)
+	}
+
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "No problems found.")
+		return err
+	}
+
+	for _, finding := range findings {
+		if _, err := fmt.Fprintf(w, "[%s] %s: %s\n    fix: %s\n",
+			strings.ToUpper(string(finding.Severity)), finding.Check, finding.Problem, finding.Fix); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d problem(s), %d critical\n", len(findings), countCritical(findings))
+
+	return err
+}
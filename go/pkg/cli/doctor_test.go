@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errDialRefused = errors.New("connection refused")
+
+func writeDoctorConfig(t *testing.T, config map[string]interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("marshal config: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "agent.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	return path
+}
+
+func testDoctor(configFile string) *doctor {
+	d := newDoctor(&CmdConfig{ConfigFile: configFile, DoctorTimeout: time.Second})
+	d.passwordFile = ""
+	d.dial = func(context.Context, string, string) (net.Conn, error) {
+		client, server := net.Pipe()
+		_ = server.Close()
+
+		return client, nil
+	}
+
+	return d
+}
+
+func findingFor(findings []DoctorFinding, check string) *DoctorFinding {
+	for i := range findings {
+		if findings[i].Check == check {
+			return &findings[i]
+		}
+	}
+
+	return nil
+}
+
+func TestDoctorReportsMisconfigurations(t *testing.T) {
+	certDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(certDir, "root.pem"), []byte("ca"), 0o600); err != nil {
+		t.Fatalf("write ca: %v", err)
+	}
+
+	configFile := writeDoctorConfig(t, map[string]interface{}{
+		"agent_id":   "default-agent",
+		"kv_address": "datasvc:50057",
+		"security": map[string]interface{}{
+			"mode":     "mtls",
+			"cert_dir": certDir,
+			"tls": map[string]interface{}{
+				"cert_file": "agent.pem",
+				"key_file":  "agent-key.pem",
+				"ca_file":   "root.pem",
+			},
+		},
+		"cnpg": map[string]interface{}{"host": "cnpg-rw", "database": "serviceradar"},
+	})
+
+	d := testDoctor(configFile)
+	d.dial = func(context.Context, string, string) (net.Conn, error) {
+		return nil, errDialRefused
+	}
+
+	findings, err := d.run(context.Background())
+	if err != nil {
+		t.Fatalf("run doctor: %v", err)
+	}
+
+	for _, check := range []string{
+		"agent_id",
+		"security.tls.cert_file",
+		"security.tls.key_file",
+		"kv_address",
+		"cnpg.password",
+	} {
+		finding := findingFor(findings, check)
+		if finding == nil {
+			t.Fatalf("expected a %s finding, got %+v", check, findings)
+		}
+
+		if finding.Severity != DoctorCritical {
+			t.Errorf("expected %s to be critical, got %s", check, finding.Severity)
+		}
+
+		if finding.Fix == "" {
+			t.Errorf("expected %s to suggest a fix", check)
+		}
+	}
+
+	if finding := findingFor(findings, "security.tls.ca_file"); finding != nil {
+		t.Errorf("readable CA must not be reported: %+v", finding)
+	}
+}
+
+func TestDoctorPassesHealthyConfig(t *testing.T) {
+	certDir := t.TempDir()
+	for _, name := range []string{"agent.pem", "agent-key.pem", "root.pem"} {
+		if err := os.WriteFile(filepath.Join(certDir, name), []byte("pem"), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatalf("write password file: %v", err)
+	}
+
+	configFile := writeDoctorConfig(t, map[string]interface{}{
+		"agent_id":   "edge-01",
+		"kv_address": "datasvc:50057",
+		"kv_security": map[string]interface{}{
+			"mode":     "mtls",
+			"cert_dir": certDir,
+			"tls": map[string]interface{}{
+				"cert_file": "agent.pem",
+				"key_file":  "agent-key.pem",
+				"ca_file":   filepath.Join(certDir, "root.pem"),
+			},
+		},
+		"cnpg": map[string]interface{}{"host": "cnpg-rw"},
+	})
+
+	d := testDoctor(configFile)
+	d.passwordFile = passwordFile
+
+	findings, err := d.run(context.Background())
+	if err != nil {
+		t.Fatalf("run doctor: %v", err)
+	}
+
+	if len(findings) != 0 {
+		t.Fatalf("expected no findings, got %+v", findings)
+	}
+}
+
+func TestDoctorReportsEmptyCNPGPasswordFile(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("\n"), 0o600); err != nil {
+		t.Fatalf("write password file: %v", err)
+	}
+
+	d := testDoctor(writeDoctorConfig(t, map[string]interface{}{"cnpg": map[string]interface{}{}}))
+	d.passwordFile = passwordFile
+
+	findings, err := d.run(context.Background())
+	if err != nil {
+		t.Fatalf("run doctor: %v", err)
+	}
+
+	if finding := findingFor(findings, "cnpg.password_file"); finding == nil || finding.Severity != DoctorCritical {
+		t.Fatalf("expected critical cnpg.password_file finding, got %+v", findings)
+	}
+}
+
+func TestDoctorMeasuresClockSkewAgainstCore(t *testing.T) {
+	coreTime := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Date", coreTime.Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	d := testDoctor(writeDoctorConfig(t, map[string]interface{}{}))
+	d.coreURL = server.URL
+
+	d.now = func() time.Time { return coreTime.Add(2 * time.Minute) }
+
+	findings, err := d.run(context.Background())
+	if err != nil {
+		t.Fatalf("run doctor: %v", err)
+	}
+
+	finding := findingFor(findings, "clock_skew")
+	if finding == nil || finding.Severity != DoctorCritical || !strings.Contains(finding.Problem, "2m0s") {
+		t.Fatalf("expected critical 2m clock skew finding, got %+v", findings)
+	}
+
+	d.now = func() time.Time { return coreTime.Add(500 * time.Millisecond) }
+
+	findings, err = d.run(context.Background())
+	if err != nil {
+		t.Fatalf("run doctor: %v", err)
+	}
+
+	if finding := findingFor(findings, "clock_skew"); finding != nil {
+		t.Fatalf("sub-second skew must not be reported: %+v", finding)
+	}
+}
+
+func TestRunDoctorFailsOnCriticalFindings(t *testing.T) {
+	configFile := writeDoctorConfig(t, map[string]interface{}{"agent_id": "default-agent"})
+	t.Setenv(cnpgPasswordFileEnvVar, "")
+
+	var err error
+
+	output := captureStdout(t, func() {
+		err = RunDoctor(&CmdConfig{ConfigFile: configFile})
+	})
+
+	if !errors.Is(err, errDoctorFoundProblems) {
+		t.Fatalf("expected errDoctorFoundProblems, got %v", err)
+	}
+
+	if !strings.Contains(output, "[CRITICAL] agent_id") {
+		t.Fatalf("expected the agent_id finding in the report, got %q", output)
+	}
+
+	if err := RunDoctor(&CmdConfig{}); !errors.Is(err, errDoctorConfigRequired) {
+		t.Fatalf("expected errDoctorConfigRequired, got %v", err)
+	}
+}
@@ -75,6 +75,13 @@ var (
 	errSystemAccountSeedReq     = errors.New("system account seed is required")
 	errNATSConfigNotFound       = errors.New("NATS config not found")
 	errNATSVerifyFailed         = errors.New("NATS bootstrap verification failed")
+	errDoctorConfigRequired     = errors.New("doctor requires -config")
+	errInvalidConfigJSON        = errors.New("config file is not valid JSON")
+	errDoctorFoundProblems      = errors.New("doctor found critical problems")
+	errBundleFileRequired       = errors.New("config-import requires -file")
+	errBundleSecurityMode       = errors.New("unsupported -security mode (use mtls or none)")
+	errTailManagedToken         = errors.New("tail sets time:, sort: and limit: itself; use -since and -limit instead")
+	errTailEntityRequired       = errors.New("tail query must select an entity with in:")
 )
 
 // ErrUnknownAdminResource returns a wrapped error for unknown admin resources.
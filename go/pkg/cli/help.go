@@ -13,6 +13,10 @@ Usage:
   serviceradar generate-jwt-keys [options]
   serviceradar spire-join-token [options]
   serviceradar enroll [options]
+  serviceradar doctor [options]
+  serviceradar config-export [options]
+  serviceradar config-import [options]
+  serviceradar tail [options]
 
 Commands:
   (default)        Generate bcrypt hash from password
@@ -22,6 +26,10 @@ Commands:
   generate-jwt-keys Generate RS256 keypair and update core.json
   spire-join-token  Request a join token from Core and optionally register a downstream entry
   enroll           Enroll an edge agent or collector using an onboarding token
+  doctor           Check a service config and environment for common misconfigurations
+  config-export    Export agent, gateway and template configuration from the KV store as a bundle
+  config-import    Import a configuration bundle into the KV store
+  tail             Print recent logs or events and optionally follow new ones
   edge package create  Issue a new onboarding package and emit the structured token
   edge package list    List onboarding packages with optional filters
   edge package show    Display detailed information for a package
@@ -55,6 +63,48 @@ Options for generate-tls:
   -add-ips            add IPs to existing certificates
   -non-interactive    run in non-interactive mode (use 127.0.0.1)
 
+Options for doctor:
+  -config string              path to the service JSON config to inspect
+  -core-url string            core URL used to measure clock skew (skipped when empty)
+  -cnpg-password-file string  CNPG password file to verify (defaults to $CNPG_PASSWORD_FILE)
+  -timeout duration           timeout for network checks (default 5s)
+  -output string              output format: text or json (default "text")
+
+Options for config-export and config-import:
+  -kv-address string   datasvc KV gRPC address (default "localhost:50057")
+  -security string     connection security: mtls or none (default "mtls")
+  -cert-dir string     directory holding the client certificate and root.pem (default "/etc/serviceradar/certs")
+  -cert-name string    client certificate name (default "core")
+  -server-name string  expected server name of the KV service (default "datasvc.serviceradar")
+  -prefix value        KV key prefix to include (repeatable, default agents/, config/, gateways/, templates/)
+  -timeout duration    timeout for the whole operation (default 30s)
+
+Options for config-export:
+  -output string       file to write the bundle to (defaults to stdout)
+  -source string       label recorded in the bundle, e.g. the environment name
+  -include-secrets     export secret values instead of redacting them
+
+Options for config-import:
+  -file string             bundle file to import (required, - for stdin)
+  -dry-run                 report the changes without writing them
+  -on-conflict string      skip, overwrite or fail when a key holds a different value (default "skip")
+  -allow-missing-secrets   import entries whose redacted secrets cannot be filled from the target
+  -output string           report format: text or json (default "text")
+
+Options for tail:
+  -core-url string      ServiceRadar core base URL (default "http://localhost:8090")
+  -api-key string       API key for core (defaults to $SERVICERADAR_API_KEY)
+  -api-key-file string  file containing the API key for core
+  -bearer string        Bearer token for core
+  -query string         SRQL query to tail, without time:, sort: or limit: (default "in:logs")
+  -since duration       how far back to backfill before tailing (default 5m0s)
+  -follow               keep polling for new rows after the backfill
+  -interval duration    poll interval while following (default 2s)
+  -limit int            maximum rows fetched per request (default 500)
+  -severity value       only show rows with this severity (repeatable)
+  -service value        only show rows from this service (repeatable)
+  -output string        output format: text or json, one row per line (default "text")
+
 Examples:
   # Generate bcrypt hash
   serviceradar mypassword
@@ -73,7 +123,14 @@ Examples:
   # Enable all standard checkers
   serviceradar update-gateway -file /etc/serviceradar/gateway.json -enable-all
 
-  # Generate mTLS certificates
+  # Copy configuration from staging to production
+  serviceradar config-export -kv-address staging-datasvc:50057 -source staging -output staging.json
+  serviceradar config-import -kv-address prod-datasvc:50057 -file staging.json -dry-run
+  serviceradar config-import -kv-address prod-datasvc:50057 -file staging.json -on-conflict overwrite
+
+  # Follow error logs from the flowgger service
+  serviceradar tail -follow -since 15m -severity error -service flowgger
+
   serviceradar generate-tls -ip 192.168.1.10,10.0.0.5
   serviceradar generate-tls --non-interactive
   serviceradar generate-tls --add-ips -ip 10.0.0.5
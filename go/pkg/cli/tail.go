@@ -0,0 +1,459 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultTailQuery    = "in:logs"
+	defaultTailSince    = 5 * time.Minute
+	defaultTailInterval = 2 * time.Second
+	defaultTailLimit    = 500
+	tailAPIKeyEnvVar    = "SERVICERADAR_API_KEY"
+	tailEntityEvents    = "events"
+)
+
+// tailManagedTokens are the SRQL keys tail sets itself to page through new rows.
+var tailManagedTokens = []string{"time:", "sort:", "limit:"}
+
+// TailHandler handles flags for the tail subcommand.
+type TailHandler struct{}
+
+// Parse processes the command-line arguments for the tail subcommand.
+func (TailHandler) Parse(args []string, cfg *CmdConfig) error {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	coreURL := fs.String("core-url", defaultCoreURL, "ServiceRadar core base URL")
+	apiKey := fs.String("api-key", "", "API key for authenticating with core (defaults to $"+tailAPIKeyEnvVar+")")
+	apiKeyFile := fs.String("api-key-file", "", "file containing the API key for authenticating with core")
+	bearer := fs.String("bearer", "", "Bearer token for authenticating with core")
+	query := fs.String("query", defaultTailQuery, "SRQL query selecting the rows to tail (without time:, sort: or limit:)")
+	since := fs.Duration("since", defaultTailSince, "how far back to backfill before tailing (0 starts at now)")
+	follow := fs.Bool("follow", false, "keep polling for new rows after the backfill")
+	interval := fs.Duration("interval", defaultTailInterval, "poll interval while following")
+	limit := fs.Int("limit", defaultTailLimit, "maximum rows fetched per request")
+	output := fs.String("output", "text", "Output format: text or json (one row per line)")
+
+	var severities stringSliceFlag
+	fs.Var(&severities, "severity", "only show rows with this severity (repeatable)")
+
+	var services stringSliceFlag
+	fs.Var(&services, "service", "only show rows from this service (repeatable)")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("parsing tail flags: %w", err)
+	}
+
+	key, err := resolveAPIKey(*apiKey, *apiKeyFile)
+	if err != nil {
+		return err
+	}
+
+	cfg.CoreAPIURL = *coreURL
+	cfg.APIKey = key
+	cfg.BearerToken = *bearer
+	cfg.TailQuery = *query
+	cfg.TailSince = *since
+	cfg.TailFollow = *follow
+	cfg.TailInterval = *interval
+	cfg.TailLimit = *limit
+	cfg.TailSeverities = trimValues(severities)
+	cfg.TailServices = trimValues(services)
+	cfg.TailOutputFormat = strings.ToLower(strings.TrimSpace(*output))
+
+	return nil
+}
+
+// resolveAPIKey prefers an explicit key, then a key file, then the environment.
+func resolveAPIKey(flagValue, keyFile string) (string, error) {
+	if key := strings.TrimSpace(flagValue); key != "" {
+		return key, nil
+	}
+
+	if path := strings.TrimSpace(keyFile); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read api key file: %w", err)
+		}
+
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	return strings.TrimSpace(os.Getenv(tailAPIKeyEnvVar)), nil
+}
+
+// tailQuery is the user's SRQL query with the tail filters applied; the time
+// window, sort and limit are added per request by build.
+type tailQuery struct {
+	base   string
+	entity string
+	limit  int
+}
+
+func newTailQuery(base string, severities, services []string, limit int) (*tailQuery, error) {
+	base = strings.TrimSpace(base)
+	if base == "" {
+		base = defaultTailQuery
+	}
+
+	entity := ""
+
+	for _, token := range strings.Fields(base) {
+		lower := strings.ToLower(token)
+		for _, managed := range tailManagedTokens {
+			if strings.HasPrefix(lower, managed) {
+				return nil, fmt.Errorf("%w: %s", errTailManagedToken, token)
+			}
+		}
+
+		if entity == "" && strings.HasPrefix(lower, "in:") {
+			entity = strings.TrimPrefix(lower, "in:")
+		}
+	}
+
+	if entity == "" {
+		return nil, errTailEntityRequired
+	}
+
+	parts := []string{base}
+	if filter := srqlListFilter("severity", severities); filter != "" {
+		parts = append(parts, filter)
+	}
+
+	if filter := srqlListFilter(tailServiceField(entity), services); filter != "" {
+		parts = append(parts, filter)
+	}
+
+	if limit <= 0 {
+		limit = defaultTailLimit
+	}
+
+	return &tailQuery{base: strings.Join(parts, " "), entity: entity, limit: limit}, nil
+}
+
+// tailServiceField names the field that identifies the emitting service for an entity.
+func tailServiceField(entity string) string {
+	if entity == tailEntityEvents {
+		return "log_provider"
+	}
+
+	return "service_name"
+}
+
+// build returns the SRQL for rows at or after start, oldest first.
+func (q *tailQuery) build(start time.Time) string {
+	return fmt.Sprintf("%s time:[%s,] sort:timestamp:asc limit:%d",
+		q.base, start.UTC().Format(time.RFC3339Nano), q.limit)
+}
+
+// srqlListFilter renders field:value or field:(a,b), quoting values with spaces.
+func srqlListFilter(field string, values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		if strings.ContainsAny(value, " ,()") {
+			value = `"` + strings.ReplaceAll(value, `"`, "") + `"`
+		}
+
+		quoted = append(quoted, value)
+	}
+
+	if len(quoted) == 1 {
+		return field + ":" + quoted[0]
+	}
+
+	return field + ":(" + strings.Join(quoted, ",") + ")"
+}
+
+// tailer polls the core query API and prints rows it has not printed yet.
+type tailer struct {
+	cfg        *CmdConfig
+	query      *tailQuery
+	endpoint   string
+	format     string
+	interval   time.Duration
+	httpClient *http.Client
+	out        io.Writer
+	now        func() time.Time
+
+	// cursor is the newest row timestamp printed so far; seen holds the keys
+	// of rows at that timestamp, since the time window is inclusive.
+	cursor time.Time
+	seen   map[string]struct{}
+}
+
+func newTailer(cfg *CmdConfig) (*tailer, error) {
+	format, err := normalizeOutputFormat(cfg.TailOutputFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := newTailQuery(cfg.TailQuery, cfg.TailSeverities, cfg.TailServices, cfg.TailLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := cfg.TailInterval
+	if interval <= 0 {
+		interval = defaultTailInterval
+	}
+
+	return &tailer{
+		cfg:        cfg,
+		query:      query,
+		endpoint:   normaliseCoreURL(cfg.CoreAPIURL) + "/api/query",
+		format:     format,
+		interval:   interval,
+		httpClient: newHTTPClient(),
+		out:        os.Stdout,
+		now:        time.Now,
+		seen:       make(map[string]struct{}),
+	}, nil
+}
+
+// RunTail handles the tail subcommand. It prints the backfill window and, with
+// -follow, keeps printing new rows until interrupted.
+func RunTail(cfg *CmdConfig) error {
+	t, err := newTailer(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return t.run(ctx)
+}
+
+func (t *tailer) run(ctx context.Context) error {
+	since := t.cfg.TailSince
+	if since < 0 {
+		since = 0
+	}
+
+	t.cursor = t.now().Add(-since)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.drain(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		if !t.cfg.TailFollow {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain fetches pages until the server returns fewer rows than the limit.
+func (t *tailer) drain(ctx context.Context) error {
+	for {
+		rows, err := t.fetch(ctx, t.query.build(t.cursor))
+		if err != nil {
+			return err
+		}
+
+		printed := 0
+
+		for _, row := range rows {
+			ok, err := t.emit(row)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				printed++
+			}
+		}
+
+		// A full page made only of rows already printed means every row left
+		// shares the cursor timestamp; stop rather than refetch it forever.
+		if len(rows) < t.query.limit || printed == 0 {
+			return nil
+		}
+	}
+}
+
+func (t *tailer) fetch(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "limit": t.query.limit})
+	if err != nil {
+		return nil, fmt.Errorf("encode query request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create query request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	applyAuthHeaders(req, t.cfg)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request query: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		message := readErrorBody(resp.Body)
+		if message == "" {
+			message = resp.Status
+		}
+
+		return nil, fmt.Errorf("%w: %s", errCoreAPIError, message)
+	}
+
+	var payload struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("decode query response: %w", err)
+	}
+
+	return payload.Results, nil
+}
+
+// emit prints a row unless it was already printed, and advances the cursor.
+func (t *tailer) emit(row map[string]interface{}) (bool, error) {
+	ts, hasTS := rowTimestamp(row)
+	key := rowKey(row)
+
+	if hasTS {
+		if ts.Before(t.cursor) {
+			return false, nil
+		}
+
+		if ts.After(t.cursor) {
+			t.cursor = ts
+			t.seen = make(map[string]struct{})
+		}
+	}
+
+	if _, dup := t.seen[key]; dup {
+		return false, nil
+	}
+
+	t.seen[key] = struct{}{}
+
+	if t.format == outputFormatJSON {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return false, fmt.Errorf("encode row: %w", err)
+		}
+
+		_, err = fmt.Fprintln(t.out, string(data))
+
+		return err == nil, err
+	}
+
+	_, err := fmt.Fprintln(t.out, formatTailRow(row))
+
+	return err == nil, err
+}
+
+// rowTimestamp reads the row time from the fields logs and events use.
+func rowTimestamp(row map[string]interface{}) (time.Time, bool) {
+	for _, field := range []string{"timestamp", "time", "event_timestamp"} {
+		raw, ok := row[field].(string)
+		if !ok {
+			continue
+		}
+
+		if ts, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			return ts, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// rowKey identifies a row for de-duplication, using its id when it has one.
+func rowKey(row map[string]interface{}) string {
+	if id, ok := row["id"]; ok && id != nil {
+		return fmt.Sprint(id)
+	}
+
+	data, _ := json.Marshal(row)
+
+	return string(data)
+}
+
+// formatTailRow renders "<time> <SEVERITY> [service] message" for text output.
+func formatTailRow(row map[string]interface{}) string {
+	ts := firstRowString(row, "timestamp", "time", "event_timestamp")
+	severity := strings.ToUpper(firstRowString(row, "severity_text", "severity", "level"))
+	service := firstRowString(row, "service_name", "log_provider", "source")
+	message := firstRowString(row, "body", "message", "short_message")
+
+	var line strings.Builder
+
+	line.WriteString(ts)
+
+	if severity != "" {
+		line.WriteString(" " + severity)
+	}
+
+	if service != "" {
+		line.WriteString(" [" + service + "]")
+	}
+
+	if message != "" {
+		line.WriteString(" " + message)
+	}
+
+	return line.String()
+}
+
+func firstRowString(row map[string]interface{}, fields ...string) string {
+	for _, field := range fields {
+		if value, ok := row[field]; ok && value != nil {
+			if text := strings.TrimSpace(fmt.Sprint(value)); text != "" {
+				return text
+			}
+		}
+	}
+
+	return ""
+}
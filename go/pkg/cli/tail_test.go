@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTailQueryAppliesFilters(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		base       string
+		severities []string
+		services   []string
+		want       string
+	}{
+		{
+			name: "default logs query",
+			want: "in:logs time:[2026-01-02T03:04:05Z,] sort:timestamp:asc limit:100",
+		},
+		{
+			name:       "single severity and service",
+			base:       "in:logs",
+			severities: []string{"error"},
+			services:   []string{"flowgger"},
+			want:       "in:logs severity:error service_name:flowgger time:[2026-01-02T03:04:05Z,] sort:timestamp:asc limit:100",
+		},
+		{
+			name:       "lists and quoting",
+			base:       "in:logs body:%timeout%",
+			severities: []string{"error", "fatal"},
+			services:   []string{"core", "web ng"},
+			want: `in:logs body:%timeout% severity:(error,fatal) service_name:(core,"web ng") ` +
+				"time:[2026-01-02T03:04:05Z,] sort:timestamp:asc limit:100",
+		},
+		{
+			name:     "events filter services by provider",
+			base:     "in:events",
+			services: []string{"trapd"},
+			want:     "in:events log_provider:trapd time:[2026-01-02T03:04:05Z,] sort:timestamp:asc limit:100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := newTailQuery(tt.base, tt.severities, tt.services, 100)
+			if err != nil {
+				t.Fatalf("newTailQuery: %v", err)
+			}
+
+			if got := q.build(start); got != tt.want {
+				t.Fatalf("query = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTailQueryRejectsInvalidQueries(t *testing.T) {
+	for _, base := range []string{"in:logs time:last_1h", "in:logs sort:timestamp:desc", "in:logs LIMIT:5"} {
+		if _, err := newTailQuery(base, nil, nil, 0); !errors.Is(err, errTailManagedToken) {
+			t.Fatalf("newTailQuery(%q) error = %v, want errTailManagedToken", base, err)
+		}
+	}
+
+	if _, err := newTailQuery("severity:error", nil, nil, 0); !errors.Is(err, errTailEntityRequired) {
+		t.Fatalf("expected errTailEntityRequired, got %v", err)
+	}
+}
+
+func TestResolveAPIKeyPrecedence(t *testing.T) {
+	t.Setenv(tailAPIKeyEnvVar, "env-key")
+
+	keyFile := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatalf("write key file: %v", err)
+	}
+
+	if key, _ := resolveAPIKey("flag-key", keyFile); key != "flag-key" {
+		t.Fatalf("flag key not preferred, got %q", key)
+	}
+
+	if key, _ := resolveAPIKey("", keyFile); key != "file-key" {
+		t.Fatalf("file key not used, got %q", key)
+	}
+
+	if key, _ := resolveAPIKey("", ""); key != "env-key" {
+		t.Fatalf("env key not used, got %q", key)
+	}
+
+	if _, err := resolveAPIKey("", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("expected error for missing key file")
+	}
+}
+
+func TestTailerPrintsNewRowsOnce(t *testing.T) {
+	pages := [][]map[string]interface{}{
+		{
+			{"id": "1", "timestamp": "2026-01-02T03:04:05Z", "severity_text": "error", "service_name": "core", "body": "first"},
+			{"id": "2", "timestamp": "2026-01-02T03:04:06Z", "severity_text": "warn", "service_name": "core", "body": "second"},
+		},
+		{
+			// The inclusive window returns the last printed row again.
+			{"id": "2", "timestamp": "2026-01-02T03:04:06Z", "severity_text": "warn", "service_name": "core", "body": "second"},
+			{"id": "3", "timestamp": "2026-01-02T03:04:07Z", "severity_text": "info", "service_name": "agent", "body": "third"},
+		},
+	}
+
+	var queries []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/query" || r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		var req struct {
+			Query string `json:"query"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		queries = append(queries, req.Query)
+
+		var page []map[string]interface{}
+		if len(queries) <= len(pages) {
+			page = pages[len(queries)-1]
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": page})
+	}))
+	defer server.Close()
+
+	tl, err := newTailer(&CmdConfig{
+		CoreAPIURL: server.URL,
+		APIKey:     "secret",
+		TailLimit:  2,
+		TailSince:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("newTailer: %v", err)
+	}
+
+	var out bytes.Buffer
+	tl.out = &out
+	tl.now = func() time.Time { return time.Date(2026, 1, 2, 3, 5, 0, 0, time.UTC) }
+
+	if err := tl.run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"2026-01-02T03:04:05Z ERROR [core] first",
+		"2026-01-02T03:04:06Z WARN [core] second",
+		"2026-01-02T03:04:07Z INFO [agent] third",
+	}
+
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("output:\n%s\nwant:\n%s", out.String(), strings.Join(want, "\n"))
+	}
+
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 requests, got %d: %v", len(queries), queries)
+	}
+
+	if !strings.Contains(queries[0], "time:[2026-01-02T03:04:00Z,]") {
+		t.Fatalf("backfill query missing since window: %s", queries[0])
+	}
+
+	if !strings.Contains(queries[1], "time:[2026-01-02T03:04:06Z,]") {
+		t.Fatalf("second page did not advance cursor: %s", queries[1])
+	}
+}
+
+func TestTailerReportsAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"unsupported time token"}`))
+	}))
+	defer server.Close()
+
+	tl, err := newTailer(&CmdConfig{CoreAPIURL: server.URL})
+	if err != nil {
+		t.Fatalf("newTailer: %v", err)
+	}
+
+	tl.out = &bytes.Buffer{}
+
+	if err := tl.run(context.Background()); !errors.Is(err, errCoreAPIError) {
+		t.Fatalf("expected errCoreAPIError, got %v", err)
+	}
+}
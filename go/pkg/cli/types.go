@@ -2,6 +2,7 @@ package cli
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
@@ -114,6 +115,32 @@ type CmdConfig struct {
 	NATSAccountLimit     int
 	AdminNatsAction      string
 	AdminCommand         string
+	// Doctor configuration
+	DoctorTimeout      time.Duration
+	DoctorOutputFormat string
+	// Config bundle export/import configuration
+	BundleKVAddress           string
+	BundleSecurityMode        string
+	BundleCertName            string
+	BundleServerName          string
+	BundleTimeout             time.Duration
+	BundlePrefixes            []string
+	BundleFile                string
+	BundleSource              string
+	BundleIncludeSecrets      bool
+	BundleDryRun              bool
+	BundleOnConflict          string
+	BundleAllowMissingSecrets bool
+	BundleOutputFormat        string
+	// Tail configuration
+	TailQuery        string
+	TailSince        time.Duration
+	TailFollow       bool
+	TailInterval     time.Duration
+	TailLimit        int
+	TailSeverities   []string
+	TailServices     []string
+	TailOutputFormat string
 }
 
 // logStyles defines styles for logging messages
@@ -0,0 +1,269 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bundle exports the configuration held in the KV store to a portable
+// bundle and restores it into another instance.
+//
+// Values that parse as JSON are stored inline so bundles stay readable and
+// diffable; anything else is base64 encoded. Secret fields are redacted on
+// export unless explicitly requested, and re-referenced from the destination on
+// import so a bundle can be shared without carrying credentials.
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FormatVersion is the bundle format written by Export and accepted by Import.
+const FormatVersion = 1
+
+var (
+	errNilBundle          = errors.New("bundle is nil")
+	errUnsupportedVersion = errors.New("unsupported bundle version")
+	errEmptyKey           = errors.New("bundle entry has an empty key")
+	errDuplicateKey       = errors.New("bundle contains a duplicate key")
+	errUnknownPolicy      = errors.New("unknown conflict policy")
+	errInvalidJSON        = errors.New("value is not valid JSON")
+
+	// ErrConflict is returned by Import under ConflictFail when any destination
+	// key already holds a different value. Nothing is written in that case.
+	ErrConflict = errors.New("bundle conflicts with existing configuration")
+)
+
+// DefaultPrefixes returns the KV prefixes exported when none are given.
+func DefaultPrefixes() []string {
+	return []string{"agents/", "config/", "gateways/", "templates/"}
+}
+
+// Store is the subset of the KV API needed to export and import bundles.
+type Store interface {
+	ListKeys(ctx context.Context, prefix string) ([]string, error)
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Bundle is a portable snapshot of configuration state.
+type Bundle struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Source     string    `json:"source,omitempty"`
+	Prefixes   []string  `json:"prefixes"`
+	Entries    []Entry   `json:"entries"`
+}
+
+// Entry is a single KV key and its value.
+type Entry struct {
+	Key string `json:"key"`
+	// Value holds values that are valid, compact JSON.
+	Value json.RawMessage `json:"value,omitempty"`
+	// Data holds any other value, base64 encoded.
+	Data []byte `json:"data,omitempty"`
+	// Redacted lists JSON pointers to secret fields removed from Value.
+	Redacted []string `json:"redacted,omitempty"`
+}
+
+// ExportOptions controls Export.
+type ExportOptions struct {
+	// Prefixes to export. Defaults to DefaultPrefixes().
+	Prefixes []string
+	// IncludeSecrets keeps secret fields in the bundle instead of redacting them.
+	IncludeSecrets bool
+	// Source is recorded in the bundle to identify where it came from.
+	Source string
+	// Now overrides the export timestamp (for tests).
+	Now func() time.Time
+}
+
+// Export reads every key under the configured prefixes and returns a bundle
+// with entries sorted by key.
+func Export(ctx context.Context, store Store, opts ExportOptions) (*Bundle, error) {
+	prefixes := opts.Prefixes
+	if len(prefixes) == 0 {
+		prefixes = DefaultPrefixes()
+	}
+
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+
+	keys, err := listKeys(ctx, store, prefixes)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{
+		Version:    FormatVersion,
+		ExportedAt: now().UTC(),
+		Source:     opts.Source,
+		Prefixes:   append([]string(nil), prefixes...),
+		Entries:    make([]Entry, 0, len(keys)),
+	}
+
+	for _, key := range keys {
+		value, found, err := store.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", key, err)
+		}
+
+		if !found {
+			continue // deleted between list and read
+		}
+
+		b.Entries = append(b.Entries, newEntry(key, value, opts.IncludeSecrets))
+	}
+
+	return b, nil
+}
+
+func listKeys(ctx context.Context, store Store, prefixes []string) ([]string, error) {
+	seen := make(map[string]struct{})
+
+	for _, prefix := range prefixes {
+		keys, err := store.ListKeys(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys under %q: %w", prefix, err)
+		}
+
+		for _, key := range keys {
+			if strings.HasPrefix(key, prefix) {
+				seen[key] = struct{}{}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func newEntry(key string, value []byte, includeSecrets bool) Entry {
+	entry := Entry{Key: key}
+
+	var (
+		redacted []byte
+		paths    []string
+	)
+
+	if !includeSecrets {
+		redacted, paths = redactSecrets(value)
+	}
+
+	if len(paths) > 0 {
+		value = redacted
+		entry.Redacted = paths
+	}
+
+	if isCompactJSON(value) {
+		entry.Value = value
+	} else {
+		entry.Data = value
+	}
+
+	return entry
+}
+
+// isCompactJSON reports whether value is JSON that encoding/json will emit
+// byte for byte, so storing it inline does not change the restored value.
+func isCompactJSON(value []byte) bool {
+	if len(value) == 0 || !json.Valid(value) {
+		return false
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, value); err != nil {
+		return false
+	}
+
+	return bytes.Equal(buf.Bytes(), value)
+}
+
+// Bytes returns the value stored in the entry. Inline JSON values were
+// compact when exported, so they are compacted again to undo the indentation
+// added by Write.
+func (e Entry) Bytes() []byte {
+	if e.Value != nil {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, e.Value); err != nil {
+			return e.Value
+		}
+
+		return buf.Bytes()
+	}
+
+	return e.Data
+}
+
+// Write encodes the bundle as indented JSON.
+func (b *Bundle) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(b)
+}
+
+// Read decodes and validates a bundle.
+func Read(r io.Reader) (*Bundle, error) {
+	var b Bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("failed to decode bundle: %w", err)
+	}
+
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// Validate checks the bundle version and that keys are present and unique.
+func (b *Bundle) Validate() error {
+	if b == nil {
+		return errNilBundle
+	}
+
+	if b.Version != FormatVersion {
+		return fmt.Errorf("%w: %d", errUnsupportedVersion, b.Version)
+	}
+
+	seen := make(map[string]struct{}, len(b.Entries))
+	for _, entry := range b.Entries {
+		if entry.Key == "" {
+			return errEmptyKey
+		}
+
+		if _, ok := seen[entry.Key]; ok {
+			return fmt.Errorf("%w: %s", errDuplicateKey, entry.Key)
+		}
+
+		seen[entry.Key] = struct{}{}
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	values map[string][]byte
+	puts   int
+}
+
+func newMemStore(values map[string]string) *memStore {
+	s := &memStore{values: make(map[string][]byte, len(values))}
+	for k, v := range values {
+		s.values[k] = []byte(v)
+	}
+
+	return s
+}
+
+func (s *memStore) ListKeys(_ context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (s *memStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	v, ok := s.values[key]
+	return v, ok, nil
+}
+
+func (s *memStore) Put(_ context.Context, key string, value []byte, _ time.Duration) error {
+	s.values[key] = append([]byte(nil), value...)
+	s.puts++
+
+	return nil
+}
+
+const (
+	agentJSON   = `{"agent_id":"agent-1","checks":[{"name":"snmp","community":"s3cret","cert_file":"/etc/certs/a.pem"}]}`
+	gatewayJSON = "{\n  \"gateway_id\": \"gw-1\",\n  \"listen_addr\": \":50052\"\n}"
+	dbTOML      = "[database]\nhost = \"db\"\npassword = \"hunter2\"\n\n[log]\nlevel = \"info\""
+)
+
+func sourceStore() *memStore {
+	return newMemStore(map[string]string{
+		"config/agent.json":                   agentJSON,
+		"config/gateways/gw-1.json":           gatewayJSON,
+		"config/db.toml":                      dbTOML,
+		"templates/checkers/mtls/sysmon.json": `{"listen_addr":":50083"}`,
+		"other/ignored":                       "not exported",
+	})
+}
+
+func roundTrip(t *testing.T, b *Bundle) *Bundle {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, b.Write(&buf))
+
+	out, err := Read(&buf)
+	require.NoError(t, err)
+
+	return out
+}
+
+func TestExportImportRoundTripWithSecrets(t *testing.T) {
+	ctx := context.Background()
+	src := sourceStore()
+
+	b, err := Export(ctx, src, ExportOptions{IncludeSecrets: true, Source: "prod"})
+	require.NoError(t, err)
+	require.Len(t, b.Entries, 4)
+	assert.Equal(t, "config/agent.json", b.Entries[0].Key, "entries are sorted")
+
+	dst := newMemStore(nil)
+	report, err := Import(ctx, dst, roundTrip(t, b), ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Created)
+
+	for key, want := range src.values {
+		if strings.HasPrefix(key, "other/") {
+			assert.NotContains(t, dst.values, key)
+			continue
+		}
+
+		assert.Equal(t, string(want), string(dst.values[key]), key)
+	}
+
+	report, err = Import(ctx, dst, roundTrip(t, b), ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Unchanged, "re-importing is a no-op")
+}
+
+func TestExportRedactsSecrets(t *testing.T) {
+	b, err := Export(context.Background(), sourceStore(), ExportOptions{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.Write(&buf))
+	assert.NotContains(t, buf.String(), "s3cret")
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.Contains(t, buf.String(), "/etc/certs/a.pem", "file references are kept")
+
+	entries := make(map[string]Entry)
+	for _, e := range b.Entries {
+		entries[e.Key] = e
+	}
+
+	assert.Equal(t, []string{"/checks/0/community"}, entries["config/agent.json"].Redacted)
+	assert.Equal(t, []string{"toml:database.password"}, entries["config/db.toml"].Redacted)
+	assert.Empty(t, entries["config/gateways/gw-1.json"].Redacted)
+}
+
+func TestImportReReferencesSecretsFromDestination(t *testing.T) {
+	ctx := context.Background()
+
+	b, err := Export(ctx, sourceStore(), ExportOptions{})
+	require.NoError(t, err)
+
+	dst := newMemStore(map[string]string{
+		"config/agent.json": `{"agent_id":"old","checks":[{"name":"snmp","community":"dest-secret"}]}`,
+		"config/db.toml":    "[database]\nhost = \"old\"\npassword = \"dest-pass\"",
+	})
+
+	report, err := Import(ctx, dst, roundTrip(t, b), ImportOptions{OnConflict: ConflictOverwrite})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Updated)
+	assert.Equal(t, 2, report.Created)
+
+	var agent map[string]any
+	require.NoError(t, json.Unmarshal(dst.values["config/agent.json"], &agent))
+	assert.Equal(t, "agent-1", agent["agent_id"])
+	check := agent["checks"].([]any)[0].(map[string]any)
+	assert.Equal(t, "dest-secret", check["community"])
+	assert.Equal(t, "/etc/certs/a.pem", check["cert_file"])
+
+	assert.Equal(t, strings.Replace(dbTOML, "hunter2", "dest-pass", 1), string(dst.values["config/db.toml"]))
+}
+
+func TestImportSkipsEntriesWithUnresolvedSecrets(t *testing.T) {
+	ctx := context.Background()
+
+	b, err := Export(ctx, sourceStore(), ExportOptions{})
+	require.NoError(t, err)
+
+	dst := newMemStore(nil)
+	report, err := Import(ctx, dst, b, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Skipped)
+	assert.NotContains(t, dst.values, "config/agent.json")
+
+	for _, change := range report.Changes {
+		if change.Key == "config/agent.json" {
+			assert.Equal(t, ActionMissingSecret, change.Action)
+			assert.Equal(t, []string{"/checks/0/community"}, change.MissingSecrets)
+		}
+	}
+
+	report, err = Import(ctx, dst, b, ImportOptions{AllowMissingSecrets: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Created)
+	assert.Contains(t, string(dst.values["config/agent.json"]), RedactedValue)
+}
+
+func TestImportConflictPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	b, err := Export(ctx, sourceStore(), ExportOptions{IncludeSecrets: true})
+	require.NoError(t, err)
+
+	existing := map[string]string{
+		"config/gateways/gw-1.json":           `{"gateway_id":"gw-1","listen_addr":":9999"}`,
+		"templates/checkers/mtls/sysmon.json": `{ "listen_addr": ":50083" }`,
+	}
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		dst := newMemStore(existing)
+		report, err := Import(ctx, dst, b, ImportOptions{DryRun: true, OnConflict: ConflictOverwrite})
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 1, report.Updated)
+		assert.Equal(t, 1, report.Unchanged, "formatting differences are not conflicts")
+		assert.Zero(t, dst.puts)
+	})
+
+	t.Run("skip keeps conflicting keys", func(t *testing.T) {
+		dst := newMemStore(existing)
+		report, err := Import(ctx, dst, b, ImportOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Skipped)
+		assert.Equal(t, existing["config/gateways/gw-1.json"], string(dst.values["config/gateways/gw-1.json"]))
+	})
+
+	t.Run("fail aborts before writing", func(t *testing.T) {
+		dst := newMemStore(existing)
+		_, err := Import(ctx, dst, b, ImportOptions{OnConflict: ConflictFail})
+		require.ErrorIs(t, err, ErrConflict)
+		assert.Zero(t, dst.puts)
+	})
+
+	t.Run("prefix filter", func(t *testing.T) {
+		dst := newMemStore(nil)
+		report, err := Import(ctx, dst, b, ImportOptions{Prefixes: []string{"templates/"}})
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Created)
+		assert.Len(t, dst.values, 1)
+	})
+}
+
+func TestReadRejectsInvalidBundles(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"version":2,"entries":[]}`))
+	require.ErrorIs(t, err, errUnsupportedVersion)
+
+	_, err = Read(strings.NewReader(`{"version":1,"entries":[{"key":"a","data":"eA=="},{"key":"a","data":"eQ=="}]}`))
+	require.ErrorIs(t, err, errDuplicateKey)
+
+	_, err = Import(context.Background(), newMemStore(nil), &Bundle{Version: 1}, ImportOptions{OnConflict: "merge"})
+	require.ErrorIs(t, err, errUnknownPolicy)
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConflictPolicy decides what Import does with keys that already hold a
+// different value in the destination.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip leaves conflicting keys untouched.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces conflicting keys with the bundle value.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictFail aborts the import, before any write, if any key conflicts.
+	ConflictFail ConflictPolicy = "fail"
+)
+
+// Action is the outcome of importing a single entry.
+type Action string
+
+const (
+	ActionCreate        Action = "create"
+	ActionUpdate        Action = "update"
+	ActionUnchanged     Action = "unchanged"
+	ActionConflict      Action = "conflict"
+	ActionMissingSecret Action = "missing_secret"
+)
+
+// ImportOptions controls Import.
+type ImportOptions struct {
+	// DryRun plans the import and reports it without writing anything.
+	DryRun bool
+	// OnConflict defaults to ConflictSkip.
+	OnConflict ConflictPolicy
+	// Prefixes restricts the import to matching keys. Empty imports everything.
+	Prefixes []string
+	// AllowMissingSecrets writes entries whose redacted secrets cannot be
+	// re-referenced from the destination, leaving RedactedValue in place.
+	// Otherwise such entries are skipped.
+	AllowMissingSecrets bool
+}
+
+// Change describes what Import did, or would do, for one key.
+type Change struct {
+	Key            string   `json:"key"`
+	Action         Action   `json:"action"`
+	MissingSecrets []string `json:"missing_secrets,omitempty"`
+}
+
+// ImportReport summarizes an import.
+type ImportReport struct {
+	DryRun    bool     `json:"dry_run"`
+	Changes   []Change `json:"changes"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Skipped   int      `json:"skipped"`
+}
+
+type plannedWrite struct {
+	key   string
+	value []byte
+}
+
+// Import restores a bundle into store. Every entry is planned against the
+// destination first, so a ConflictFail import or a dry run writes nothing.
+func Import(ctx context.Context, store Store, b *Bundle, opts ImportOptions) (*ImportReport, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	policy := opts.OnConflict
+	if policy == "" {
+		policy = ConflictSkip
+	}
+
+	switch policy {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownPolicy, policy)
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun, Changes: make([]Change, 0, len(b.Entries))}
+	writes := make([]plannedWrite, 0, len(b.Entries))
+	conflicts := 0
+
+	for _, entry := range b.Entries {
+		if !matchesPrefix(entry.Key, opts.Prefixes) {
+			continue
+		}
+
+		change, value, err := planEntry(ctx, store, entry, policy, opts.AllowMissingSecrets)
+		if err != nil {
+			return nil, err
+		}
+
+		report.record(change)
+
+		if change.Action == ActionConflict && policy == ConflictFail {
+			conflicts++
+		}
+
+		if change.Action == ActionCreate || change.Action == ActionUpdate {
+			writes = append(writes, plannedWrite{key: entry.Key, value: value})
+		}
+	}
+
+	if conflicts > 0 {
+		return report, fmt.Errorf("%w: %d key(s) differ", ErrConflict, conflicts)
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, w := range writes {
+		if err := store.Put(ctx, w.key, w.value, 0); err != nil {
+			return report, fmt.Errorf("failed to write %s: %w", w.key, err)
+		}
+	}
+
+	return report, nil
+}
+
+func planEntry(
+	ctx context.Context, store Store, entry Entry, policy ConflictPolicy, allowMissingSecrets bool,
+) (Change, []byte, error) {
+	change := Change{Key: entry.Key}
+
+	current, found, err := store.Get(ctx, entry.Key)
+	if err != nil {
+		return change, nil, fmt.Errorf("failed to read %s: %w", entry.Key, err)
+	}
+
+	if !found {
+		current = nil
+	}
+
+	value := entry.Bytes()
+
+	if len(entry.Redacted) > 0 {
+		value, change.MissingSecrets = restoreSecrets(value, current, entry.Redacted)
+		if len(change.MissingSecrets) > 0 && !allowMissingSecrets {
+			change.Action = ActionMissingSecret
+			return change, nil, nil
+		}
+	}
+
+	switch {
+	case !found:
+		change.Action = ActionCreate
+	case sameValue(current, value):
+		change.Action = ActionUnchanged
+	case policy == ConflictOverwrite:
+		change.Action = ActionUpdate
+	default:
+		change.Action = ActionConflict
+	}
+
+	return change, value, nil
+}
+
+func restoreSecrets(value, current []byte, paths []string) ([]byte, []string) {
+	if strings.HasPrefix(paths[0], tomlPathPrefix) {
+		return restoreTOML(value, current, paths)
+	}
+
+	return restoreJSON(value, current, paths)
+}
+
+// sameValue compares JSON values structurally so formatting and key order
+// differences are not reported as conflicts.
+func sameValue(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+
+	docA, errA := decodeJSON(a)
+	docB, errB := decodeJSON(b)
+
+	return errA == nil && errB == nil && reflect.DeepEqual(docA, docB)
+}
+
+func matchesPrefix(key string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *ImportReport) record(change Change) {
+	r.Changes = append(r.Changes, change)
+
+	switch change.Action {
+	case ActionCreate:
+		r.Created++
+	case ActionUpdate:
+		r.Updated++
+	case ActionUnchanged:
+		r.Unchanged++
+	case ActionConflict, ActionMissingSecret:
+		r.Skipped++
+	}
+}
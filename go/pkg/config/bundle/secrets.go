@@ -0,0 +1,346 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/carverauto/serviceradar/go/pkg/config"
+)
+
+// RedactedValue replaces secret values in exported bundles.
+const RedactedValue = "__SERVICERADAR_REDACTED__"
+
+const tomlPathPrefix = "toml:"
+
+// redactSecrets replaces secret string fields in a JSON or TOML value with
+// RedactedValue and returns the rewritten value with the redacted paths:
+// JSON pointers for JSON, "toml:table.key" for TOML. Values that are neither,
+// or that hold no secrets, yield no paths.
+func redactSecrets(value []byte) ([]byte, []string) {
+	if json.Valid(value) {
+		return redactJSON(value)
+	}
+
+	return redactTOML(value)
+}
+
+func redactJSON(value []byte) ([]byte, []string) {
+	doc, err := decodeJSON(value)
+	if err != nil {
+		return nil, nil
+	}
+
+	var paths []string
+
+	doc = walkJSON(doc, "", func(pointer, key string, v any) any {
+		if s, ok := v.(string); ok && s != "" && s != RedactedValue && config.IsSecretKey(key) {
+			paths = append(paths, pointer)
+			return RedactedValue
+		}
+
+		return v
+	})
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil
+	}
+
+	sort.Strings(paths)
+
+	return out, paths
+}
+
+// restoreJSON copies the redacted fields of value from current. It returns
+// the restored value and the paths that current could not supply.
+func restoreJSON(value, current []byte, paths []string) ([]byte, []string) {
+	doc, err := decodeJSON(value)
+	if err != nil {
+		return nil, paths
+	}
+
+	var existing any
+	if current != nil {
+		existing, _ = decodeJSON(current)
+	}
+
+	wanted := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		wanted[p] = struct{}{}
+	}
+
+	var missing []string
+
+	doc = walkJSON(doc, "", func(pointer, _ string, v any) any {
+		if _, ok := wanted[pointer]; !ok {
+			return v
+		}
+
+		delete(wanted, pointer)
+
+		if secret, ok := lookupPointer(existing, pointer); ok {
+			return secret
+		}
+
+		missing = append(missing, pointer)
+
+		return v
+	})
+
+	for p := range wanted {
+		missing = append(missing, p)
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, paths
+	}
+
+	sort.Strings(missing)
+
+	return out, missing
+}
+
+func decodeJSON(value []byte) (any, error) {
+	if !json.Valid(value) {
+		return nil, errInvalidJSON
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// walkJSON visits every object member and array element, replacing each with
+// the result of fn. Containers are walked after fn returns them unchanged.
+func walkJSON(node any, pointer string, fn func(pointer, key string, v any) any) any {
+	switch n := node.(type) {
+	case map[string]any:
+		for key, child := range n {
+			childPointer := pointer + "/" + escapePointer(key)
+			child = fn(childPointer, key, child)
+			n[key] = walkJSON(child, childPointer, fn)
+		}
+	case []any:
+		for i, child := range n {
+			childPointer := pointer + "/" + strconv.Itoa(i)
+			child = fn(childPointer, "", child)
+			n[i] = walkJSON(child, childPointer, fn)
+		}
+	}
+
+	return node
+}
+
+func lookupPointer(doc any, pointer string) (any, bool) {
+	if doc == nil {
+		return nil, false
+	}
+
+	node := doc
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = unescapePointer(token)
+
+		switch n := node.(type) {
+		case map[string]any:
+			child, ok := n[token]
+			if !ok {
+				return nil, false
+			}
+
+			node = child
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, false
+			}
+
+			node = n[i]
+		default:
+			return nil, false
+		}
+	}
+
+	if s, ok := node.(string); !ok || s == "" || s == RedactedValue {
+		return nil, false
+	}
+
+	return node, true
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func unescapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// redactTOML rewrites `key = "value"` lines whose key is a secret. TOML is
+// handled line by line, which covers the flat service configs kept in KV.
+func redactTOML(value []byte) ([]byte, []string) {
+	var paths []string
+
+	out := rewriteTOML(value, func(path, line string) string {
+		key, val, ok := splitTOMLAssignment(line)
+		if !ok || !config.IsSecretKey(lastSegment(key)) || !isTOMLString(val) || val == strconv.Quote(RedactedValue) {
+			return line
+		}
+
+		paths = append(paths, tomlPathPrefix+path)
+
+		return leadingSpace(line) + key + " = " + strconv.Quote(RedactedValue)
+	})
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(paths)
+
+	return out, paths
+}
+
+// restoreTOML copies the redacted lines of value from current.
+func restoreTOML(value, current []byte, paths []string) ([]byte, []string) {
+	existing := make(map[string]string)
+
+	rewriteTOML(current, func(path, line string) string {
+		if _, val, ok := splitTOMLAssignment(line); ok && isTOMLString(val) && val != strconv.Quote(RedactedValue) {
+			existing[tomlPathPrefix+path] = val
+		}
+
+		return line
+	})
+
+	wanted := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		wanted[p] = struct{}{}
+	}
+
+	var missing []string
+
+	out := rewriteTOML(value, func(path, line string) string {
+		path = tomlPathPrefix + path
+		if _, ok := wanted[path]; !ok {
+			return line
+		}
+
+		delete(wanted, path)
+
+		secret, ok := existing[path]
+		if !ok {
+			missing = append(missing, path)
+			return line
+		}
+
+		key, _, _ := splitTOMLAssignment(line)
+
+		return leadingSpace(line) + key + " = " + secret
+	})
+
+	for p := range wanted {
+		missing = append(missing, p)
+	}
+
+	sort.Strings(missing)
+
+	return out, missing
+}
+
+// rewriteTOML calls fn for every key assignment with its dotted path and
+// replaces the line with the result. Other lines are kept as they are.
+func rewriteTOML(value []byte, fn func(path, line string) string) []byte {
+	if len(value) == 0 {
+		return value
+	}
+
+	lines := strings.Split(string(value), "\n")
+	table := ""
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case strings.HasPrefix(trimmed, "["):
+			table = strings.Trim(trimmed, "[] ")
+			continue
+		}
+
+		key, _, ok := splitTOMLAssignment(line)
+		if !ok {
+			continue
+		}
+
+		path := key
+		if table != "" {
+			path = table + "." + key
+		}
+
+		lines[i] = fn(path, line)
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func splitTOMLAssignment(line string) (string, string, bool) {
+	key, val, ok := strings.Cut(strings.TrimSpace(line), "=")
+	if !ok {
+		return "", "", false
+	}
+
+	key = strings.Trim(strings.TrimSpace(key), `"`)
+	if key == "" {
+		return "", "", false
+	}
+
+	return key, strings.TrimSpace(val), true
+}
+
+func isTOMLString(val string) bool {
+	return len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0]
+}
+
+func lastSegment(key string) string {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[i+1:]
+	}
+
+	return key
+}
+
+func leadingSpace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+// RedactedDiffValue replaces secret values in config diffs.
+const RedactedDiffValue = "[REDACTED]"
+
+// ErrConfigNotJSON is returned by DiffJSON for values that are not JSON.
+var ErrConfigNotJSON = errors.New("config value is not JSON")
+
+// DiffJSON compares two JSON config values. Objects are compared member by
+// member; arrays and scalars are compared as a whole. An empty value stands
+// for a missing key, so a newly created config reports all of its members as
+// added. Values of secret fields (see IsSecretKey), and everything nested
+// under them, are replaced with RedactedDiffValue: the diff records that a
+// secret changed, never what it was or became.
+func DiffJSON(oldValue, newValue []byte) (*models.ConfigDiff, error) {
+	oldDoc, err := decodeConfigJSON(oldValue)
+	if err != nil {
+		return nil, err
+	}
+
+	newDoc, err := decodeConfigJSON(newValue)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.ConfigDiff{
+		Added:   make(map[string]interface{}),
+		Removed: make(map[string]interface{}),
+		Changed: make(map[string]models.ConfigValueChange),
+	}
+
+	_, oldIsObject := oldDoc.(map[string]interface{})
+	_, newIsObject := newDoc.(map[string]interface{})
+
+	switch {
+	case oldDoc == nil && newDoc == nil:
+	case oldDoc == nil && newIsObject:
+		diffValues(diff, "", false, map[string]interface{}{}, newDoc)
+	case newDoc == nil && oldIsObject:
+		diffValues(diff, "", false, oldDoc, map[string]interface{}{})
+	case oldDoc == nil:
+		diff.Added[""] = newDoc
+	case newDoc == nil:
+		diff.Removed[""] = oldDoc
+	default:
+		diffValues(diff, "", false, oldDoc, newDoc)
+	}
+
+	return diff, nil
+}
+
+func decodeConfigJSON(value []byte) (interface{}, error) {
+	if len(bytes.TrimSpace(value)) == 0 {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfigNotJSON, err)
+	}
+
+	return doc, nil
+}
+
+func diffValues(diff *models.ConfigDiff, pointer string, secret bool, oldValue, newValue interface{}) {
+	oldObject, oldIsObject := oldValue.(map[string]interface{})
+	newObject, newIsObject := newValue.(map[string]interface{})
+
+	if oldIsObject && newIsObject {
+		diffObjects(diff, pointer, secret, oldObject, newObject)
+		return
+	}
+
+	if reflect.DeepEqual(oldValue, newValue) {
+		return
+	}
+
+	diff.Changed[pointer] = models.ConfigValueChange{
+		Old: redactValue(oldValue, secret),
+		New: redactValue(newValue, secret),
+	}
+}
+
+func diffObjects(diff *models.ConfigDiff, pointer string, secret bool, oldObject, newObject map[string]interface{}) {
+	for key, oldChild := range oldObject {
+		childPointer := pointer + "/" + escapeJSONPointer(key)
+		childSecret := secret || IsSecretKey(key)
+
+		newChild, ok := newObject[key]
+		if !ok {
+			diff.Removed[childPointer] = redactValue(oldChild, childSecret)
+			continue
+		}
+
+		diffValues(diff, childPointer, childSecret, oldChild, newChild)
+	}
+
+	for key, newChild := range newObject {
+		if _, ok := oldObject[key]; ok {
+			continue
+		}
+
+		childPointer := pointer + "/" + escapeJSONPointer(key)
+		diff.Added[childPointer] = redactValue(newChild, secret || IsSecretKey(key))
+	}
+}
+
+// redactValue returns a copy of value with the secrets in it redacted. When
+// secret is set, every scalar in value is redacted.
+func redactValue(value interface{}, secret bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			redacted[key] = redactValue(child, secret || IsSecretKey(key))
+		}
+
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, child := range v {
+			redacted[i] = redactValue(child, secret)
+		}
+
+		return redacted
+	case nil:
+		return nil
+	default:
+		if secret {
+			return RedactedDiffValue
+		}
+
+		return v
+	}
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+func TestDiffJSON_NestedChange(t *testing.T) {
+	oldValue := []byte(`{
+		"interval": "30s",
+		"outputs": {"nats": {"url": "nats://a:4222", "subjects": ["a"]}},
+		"targets": {"router-1": {"host": "10.0.0.1", "port": 161}},
+		"debug": true
+	}`)
+	newValue := []byte(`{
+		"interval": "60s",
+		"outputs": {"nats": {"url": "nats://a:4222", "subjects": ["a", "b"]}},
+		"targets": {"router-1": {"host": "10.0.0.2"}, "router/2": {"host": "10.0.0.3"}}
+	}`)
+
+	diff, err := DiffJSON(oldValue, newValue)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"/targets/router~12": map[string]interface{}{"host": "10.0.0.3"},
+	}, diff.Added)
+	assert.Equal(t, map[string]interface{}{
+		"/debug":                 true,
+		"/targets/router-1/port": json.Number("161"),
+	}, diff.Removed)
+	assert.Equal(t, map[string]models.ConfigValueChange{
+		"/interval":              {Old: "30s", New: "60s"},
+		"/outputs/nats/subjects": {Old: []interface{}{"a"}, New: []interface{}{"a", "b"}},
+		"/targets/router-1/host": {Old: "10.0.0.1", New: "10.0.0.2"},
+	}, diff.Changed)
+}
+
+func TestDiffJSON_RedactsSecrets(t *testing.T) {
+	oldValue := []byte(`{
+		"name": "armis",
+		"api_key": "old-key",
+		"snmp": {"community": "public", "version": "v2c"},
+		"credentials": {"username": "admin", "hosts": ["a"]}
+	}`)
+	newValue := []byte(`{
+		"name": "armis",
+		"api_key": "new-key",
+		"snmp": {"version": "v3"},
+		"credentials": {"username": "root", "hosts": ["a", "b"]},
+		"auth": {"password": "hunter2", "user": "svc"},
+		"tls": {"key_file": "/etc/tls/key.pem"}
+	}`)
+
+	diff, err := DiffJSON(oldValue, newValue)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"/auth": map[string]interface{}{"password": RedactedDiffValue, "user": "svc"},
+		"/tls":  map[string]interface{}{"key_file": "/etc/tls/key.pem"},
+	}, diff.Added)
+	assert.Equal(t, map[string]interface{}{
+		"/snmp/community": RedactedDiffValue,
+	}, diff.Removed)
+	assert.Equal(t, map[string]models.ConfigValueChange{
+		"/api_key":              {Old: RedactedDiffValue, New: RedactedDiffValue},
+		"/snmp/version":         {Old: "v2c", New: "v3"},
+		"/credentials/username": {Old: RedactedDiffValue, New: RedactedDiffValue},
+		"/credentials/hosts": {
+			Old: []interface{}{RedactedDiffValue},
+			New: []interface{}{RedactedDiffValue, RedactedDiffValue},
+		},
+	}, diff.Changed)
+
+	encoded, err := json.Marshal(diff)
+	require.NoError(t, err)
+
+	for _, secret := range []string{"old-key", "new-key", "public", "hunter2", "admin", "root"} {
+		assert.NotContains(t, string(encoded), secret)
+	}
+}
+
+func TestDiffJSON_CreateAndDelete(t *testing.T) {
+	value := []byte(`{"enabled": true, "token": "abc"}`)
+
+	created, err := DiffJSON(nil, value)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"/enabled": true, "/token": RedactedDiffValue}, created.Added)
+	assert.Empty(t, created.Removed)
+	assert.Empty(t, created.Changed)
+
+	deleted, err := DiffJSON(value, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"/enabled": true, "/token": RedactedDiffValue}, deleted.Removed)
+
+	unchanged, err := DiffJSON(value, []byte(`{"token":"abc","enabled":true}`))
+	require.NoError(t, err)
+	assert.True(t, unchanged.Empty())
+
+	_, err = DiffJSON(value, []byte(`interval = "30s"`))
+	require.ErrorIs(t, err, ErrConfigNotJSON)
+}
@@ -26,6 +26,21 @@ import (
 // ErrKeyExists indicates that a create/put-if-absent operation found an existing value.
 var ErrKeyExists = errors.New("kv: key already exists")
 
+// Revision operations.
+const (
+	RevisionOpPut    = "put"
+	RevisionOpDelete = "delete"
+	RevisionOpPurge  = "purge"
+)
+
+// Revision describes a single historical value of a key.
+type Revision struct {
+	Value     []byte
+	Revision  uint64
+	Created   time.Time
+	Operation string // RevisionOpPut, RevisionOpDelete or RevisionOpPurge
+}
+
 // KVStore defines the interface for a key-value store used in ServiceRadar configuration management.
 type KVStore interface {
 	// Get retrieves the value associated with the given key.
@@ -61,6 +61,56 @@ func (k *Client) Delete(ctx context.Context, key string) error {
 	return err
 }
 
+// ListKeys returns the keys under prefix.
+func (k *Client) ListKeys(ctx context.Context, prefix string) ([]string, error) {
+	resp, err := k.c.ListKeys(ctx, &proto.ListKeysRequest{Prefix: prefix})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetKeys(), nil
+}
+
+// History lists up to limit recent revisions of key, newest first. A limit of
+// zero uses the server default; the server caps the depth it returns. The
+// boolean reports whether older revisions were omitted.
+func (k *Client) History(ctx context.Context, key string, limit uint32) ([]kv.Revision, bool, error) {
+	resp, err := k.c.History(ctx, &proto.HistoryRequest{Key: key, Limit: limit})
+	if err != nil {
+		return nil, false, err
+	}
+
+	revisions := make([]kv.Revision, 0, len(resp.GetRevisions()))
+	for _, rev := range resp.GetRevisions() {
+		revisions = append(revisions, kv.Revision{
+			Value:     rev.GetValue(),
+			Revision:  rev.GetRevision(),
+			Created:   time.Unix(0, rev.GetCreatedUnixNano()),
+			Operation: rev.GetOperation(),
+		})
+	}
+
+	return revisions, resp.GetTruncated(), nil
+}
+
+// GetRevision fetches the value key held at revision. The boolean is false if
+// the revision no longer exists or was a delete.
+func (k *Client) GetRevision(ctx context.Context, key string, revision uint64) (kv.Revision, bool, error) {
+	resp, err := k.c.GetRevision(ctx, &proto.GetRevisionRequest{Key: key, Revision: revision})
+	if err != nil {
+		return kv.Revision{}, false, err
+	}
+	if !resp.GetFound() {
+		return kv.Revision{}, false, nil
+	}
+
+	return kv.Revision{
+		Value:     resp.GetValue(),
+		Revision:  resp.GetRevision(),
+		Created:   time.Unix(0, resp.GetCreatedUnixNano()),
+		Operation: kv.RevisionOpPut,
+	}, true, nil
+}
+
 func (k *Client) Watch(ctx context.Context, key string) (<-chan []byte, error) {
 	stream, err := k.c.Watch(ctx, &proto.WatchRequest{Key: key})
 	if err != nil {
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import "strings"
+
+// IsSecretKey reports whether a config field name holds a credential. Fields
+// that point at files (cert_file, creds_path, ...) are references, not secrets.
+func IsSecretKey(name string) bool {
+	name = strings.ToLower(name)
+
+	for _, suffix := range []string{"_file", "_path", "_dir", "_url", "_ttl"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+
+	for _, marker := range []string{
+		"password", "passwd", "passphrase", "secret", "token",
+		"private_key", "api_key", "apikey", "community", "credential",
+	} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+
+	return false
+}
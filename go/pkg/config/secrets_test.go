@@ -0,0 +1,33 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSecretKey(t *testing.T) {
+	for _, key := range []string{"password", "db_password", "api_key", "apiKey", "community", "jwt_secret", "token"} {
+		assert.True(t, IsSecretKey(key), key)
+	}
+
+	for _, key := range []string{"cert_file", "creds_file", "token_ttl", "name", "listen_addr", "key_file"} {
+		assert.False(t, IsSecretKey(key), key)
+	}
+}
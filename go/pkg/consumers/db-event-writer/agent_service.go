@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/carverauto/serviceradar/go/pkg/db"
 	"github.com/carverauto/serviceradar/go/pkg/logger"
 	"github.com/carverauto/serviceradar/proto"
 )
@@ -40,6 +41,14 @@ func (s *AgentService) GetStatus(_ context.Context, req *proto.StatusRequest) (*
 		msg["message"] = "db-event-writer is operational"
 	}
 
+	if s.svc != nil && s.svc.otel != nil {
+		msg["otel_buffer"] = s.svc.otel.buffer.Stats()
+	}
+
+	if queries := s.slowQueries(); len(queries) > 0 {
+		msg["slow_queries"] = queries
+	}
+
 	data, err := json.Marshal(msg)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("Failed to marshal status message")
@@ -55,6 +64,21 @@ func (s *AgentService) GetStatus(_ context.Context, req *proto.StatusRequest) (*
 	}, nil
 }
 
+// slowQueries returns the slow queries, with plans when capture_query_plans is
+// set, recorded on the CNPG pool the writer uses.
+func (s *AgentService) slowQueries() []db.SlowQuery {
+	if s.svc == nil {
+		return nil
+	}
+
+	recorder, ok := s.svc.db.(interface{ SlowQueries() []db.SlowQuery })
+	if !ok {
+		return nil
+	}
+
+	return recorder.SlowQueries()
+}
+
 // GetResults implements the AgentService GetResults method.
 // DB event writer service doesn't support GetResults, so return a "not supported" response.
 func (s *AgentService) GetResults(_ context.Context, req *proto.ResultsRequest) (*proto.ResultsResponse, error) {
@@ -0,0 +1,59 @@
+package dbeventwriter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/db"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/proto"
+)
+
+type slowQueryDB struct {
+	db.Service
+	queries []db.SlowQuery
+}
+
+func (d *slowQueryDB) SlowQueries() []db.SlowQuery {
+	return d.queries
+}
+
+func TestGetStatusReportsSlowQueries(t *testing.T) {
+	t.Parallel()
+
+	svc := &Service{
+		db: &slowQueryDB{queries: []db.SlowQuery{{
+			SQL:      "SELECT * FROM ocsf_devices WHERE uid = $1",
+			Duration: 2 * time.Second,
+			Plan:     json.RawMessage(`[{"Plan":{"Node Type":"Seq Scan"}}]`),
+		}}},
+		logger: logger.NewTestLogger(),
+	}
+
+	resp, err := NewAgentService(svc).GetStatus(context.Background(), &proto.StatusRequest{})
+	require.NoError(t, err)
+
+	var msg struct {
+		SlowQueries []db.SlowQuery `json:"slow_queries"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Message, &msg))
+
+	require.Len(t, msg.SlowQueries, 1)
+	assert.Equal(t, "SELECT * FROM ocsf_devices WHERE uid = $1", msg.SlowQueries[0].SQL)
+	assert.JSONEq(t, `[{"Plan":{"Node Type":"Seq Scan"}}]`, string(msg.SlowQueries[0].Plan))
+}
+
+func TestGetStatusOmitsSlowQueriesWhenNoneRecorded(t *testing.T) {
+	t.Parallel()
+
+	svc := &Service{db: &slowQueryDB{}, logger: logger.NewTestLogger()}
+
+	resp, err := NewAgentService(svc).GetStatus(context.Background(), &proto.StatusRequest{})
+	require.NoError(t, err)
+	assert.NotContains(t, string(resp.Message), "slow_queries")
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/carverauto/serviceradar/go/pkg/db"
 	"github.com/carverauto/serviceradar/go/pkg/logger"
 	"github.com/carverauto/serviceradar/go/pkg/models"
 )
@@ -19,6 +20,10 @@ var (
 	ErrInvalidJSON           = errors.New("failed to unmarshal JSON configuration")
 	ErrStreamSubjectRequired = errors.New("stream subject is required")
 	ErrStreamTableRequired   = errors.New("stream table is required")
+
+	ErrInvalidOTELBufferCompression = errors.New("otel_buffer compression must be none or gzip")
+	ErrInvalidOTELBufferOnFull      = errors.New("otel_buffer on_full must be shed or spill")
+	ErrOTELBufferSpillUnavailable   = errors.New("otel_buffer on_full spill requires write_buffer")
 )
 
 // StreamConfig holds configuration for a specific stream/table pair
@@ -44,6 +49,15 @@ type DBEventWriterConfig struct {
 	NATSSecurity  *models.SecurityConfig `json:"nats_security"`
 	CNPG          *models.CNPGDatabase   `json:"cnpg"`
 	Logging       *logger.Config         `json:"logging"` // Logger configuration including OTEL settings
+	// WriteBuffer spills event and flow inserts to local disk while CNPG is
+	// unreachable. It is read at startup; changes need a restart.
+	WriteBuffer *db.SpillConfig `json:"write_buffer,omitempty"`
+	// OTELBuffer batches OTEL trace and metric inserts in memory. It is read
+	// at startup; changes need a restart.
+	OTELBuffer *OTELBufferConfig `json:"otel_buffer,omitempty"`
+	// LogEnrichment tags logs with the device their source host resolves
+	// to. It is read at startup; changes need a restart.
+	LogEnrichment *LogEnrichmentConfig `json:"log_enrichment,omitempty"`
 }
 
 // Validate checks the configuration for required fields.
@@ -95,6 +109,10 @@ func (c *DBEventWriterConfig) Validate() error {
 		errs = append(errs, ErrMissingCNPGConfig)
 	}
 
+	if err := c.OTELBuffer.Validate(c.WriteBuffer != nil && c.WriteBuffer.Enabled); err != nil {
+		errs = append(errs, err)
+	}
+
 	if len(errs) > 0 {
 		return errors.Join(errs...)
 	}
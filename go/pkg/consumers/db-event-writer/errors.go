@@ -7,4 +7,5 @@ var (
 	errCNPGEventsNotConfigured = errors.New("cnpg storage is not configured for events ingestion")
 	errUnsupportedTable        = errors.New("unsupported table")
 	errCNPGOCSFNotConfigured   = errors.New("cnpg storage is not configured for OCSF network activity")
+	errOTELBufferKindUnknown   = errors.New("otel buffer kind not registered")
 )
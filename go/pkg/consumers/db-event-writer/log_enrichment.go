@@ -0,0 +1,239 @@
+package dbeventwriter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+const (
+	defaultLogEnrichmentCacheTTL  = 5 * time.Minute
+	defaultLogEnrichmentCacheSize = 10000
+)
+
+// logHostKeys are the resource attributes naming a log's source host, in the
+// order they are tried. Addresses come before names since they identify a
+// device more reliably.
+//
+//nolint:gochecknoglobals // package-level lookup table
+var logHostKeys = []string{"host.ip", "ip", "ip_address", "remote_addr", "host.name", "host", "hostname"}
+
+// LogEnrichmentConfig configures enrichment of logs with the identity of the
+// device that sent them. A log's source host or IP is resolved to an
+// inventory device (platform.ocsf_devices), and the device ID, partition and
+// the device metadata keys listed in Tags are written alongside the log. Logs
+// from hosts without a device are stored unchanged. It is read at startup;
+// changes need a restart.
+type LogEnrichmentConfig struct {
+	Enabled   bool            `json:"enabled"`
+	Tags      []string        `json:"tags,omitempty"`       // device metadata keys copied to device_tags
+	CacheTTL  models.Duration `json:"cache_ttl,omitempty"`  // defaults to 5m
+	CacheSize int             `json:"cache_size,omitempty"` // defaults to 10000 hosts
+}
+
+// logDeviceResolver resolves hosts to devices; *db.DB implements it.
+type logDeviceResolver interface {
+	DevicesByHost(ctx context.Context, hosts []string) (map[string]*models.Device, error)
+}
+
+type logDeviceEntry struct {
+	device  *models.Device // nil when no device matched
+	expires time.Time
+}
+
+// logEnricher attaches device context to log rows. Host resolutions,
+// including misses, are cached for the TTL. Like writeSpill it outlives
+// processors, so the cache survives config and database reloads.
+type logEnricher struct {
+	tags    []string
+	ttl     time.Duration
+	maxSize int
+	log     logger.Logger
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]logDeviceEntry
+}
+
+func newLogEnricher(cfg *LogEnrichmentConfig, log logger.Logger) *logEnricher {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	ttl := time.Duration(cfg.CacheTTL)
+	if ttl <= 0 {
+		ttl = defaultLogEnrichmentCacheTTL
+	}
+
+	maxSize := cfg.CacheSize
+	if maxSize <= 0 {
+		maxSize = defaultLogEnrichmentCacheSize
+	}
+
+	return &logEnricher{
+		tags:    cfg.Tags,
+		ttl:     ttl,
+		maxSize: maxSize,
+		log:     log,
+		now:     time.Now,
+		cache:   make(map[string]logDeviceEntry),
+	}
+}
+
+// Enrich sets the device fields of rows whose source host resolves to a
+// device. A failed lookup leaves the affected rows unenriched rather than
+// holding up ingestion.
+func (e *logEnricher) Enrich(ctx context.Context, resolver logDeviceResolver, rows []models.OTELLogRow) {
+	if e == nil || resolver == nil || len(rows) == 0 {
+		return
+	}
+
+	hosts := make([][]string, len(rows))
+	for i := range rows {
+		hosts[i] = logHosts(&rows[i])
+	}
+
+	devices, err := e.resolve(ctx, resolver, hosts)
+	if err != nil && e.log != nil {
+		e.log.Warn().Err(err).Int("rows", len(rows)).Msg("Log device enrichment lookup failed")
+	}
+
+	for i := range rows {
+		for _, host := range hosts[i] {
+			if device := devices[host]; device != nil {
+				e.apply(&rows[i], device)
+				break
+			}
+		}
+	}
+}
+
+// resolve returns the device for each host, from the cache where possible.
+// Hosts with no device map to nil.
+func (e *logEnricher) resolve(
+	ctx context.Context, resolver logDeviceResolver, hosts [][]string,
+) (map[string]*models.Device, error) {
+	now := e.now()
+	devices := make(map[string]*models.Device)
+
+	var missing []string
+
+	e.mu.Lock()
+	for _, candidates := range hosts {
+		for _, host := range candidates {
+			if _, done := devices[host]; done {
+				continue
+			}
+
+			if entry, ok := e.cache[host]; ok && now.Before(entry.expires) {
+				devices[host] = entry.device
+				continue
+			}
+
+			devices[host] = nil
+			missing = append(missing, host)
+		}
+	}
+	e.mu.Unlock()
+
+	if len(missing) == 0 {
+		return devices, nil
+	}
+
+	found, err := resolver.DevicesByHost(ctx, missing)
+	if err != nil {
+		return devices, fmt.Errorf("resolve %d log hosts: %w", len(missing), err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.cache)+len(missing) > e.maxSize {
+		e.evict(now)
+	}
+
+	for _, host := range missing {
+		device := found[host]
+		devices[host] = device
+		e.cache[host] = logDeviceEntry{device: device, expires: now.Add(e.ttl)}
+	}
+
+	return devices, nil
+}
+
+// evict drops expired entries, or the whole cache if it is still full.
+// Callers hold e.mu.
+func (e *logEnricher) evict(now time.Time) {
+	for host, entry := range e.cache {
+		if !now.Before(entry.expires) {
+			delete(e.cache, host)
+		}
+	}
+
+	if len(e.cache) >= e.maxSize {
+		e.cache = make(map[string]logDeviceEntry)
+	}
+}
+
+func (e *logEnricher) apply(row *models.OTELLogRow, device *models.Device) {
+	row.DeviceID = device.DeviceID
+	row.DevicePartition = devicePartition(device.DeviceID)
+
+	for _, key := range e.tags {
+		value, ok := device.Metadata[key]
+		if !ok || isEmptyAttributeValue(value) {
+			continue
+		}
+
+		if row.DeviceTags == nil {
+			row.DeviceTags = make(map[string]string, len(e.tags))
+		}
+
+		row.DeviceTags[key] = stringFromValue(value)
+	}
+}
+
+// logHosts returns the candidate source hosts named by a log's resource
+// attributes, without duplicates.
+func logHosts(row *models.OTELLogRow) []string {
+	attributes := parseAttributeValue(row.ResourceAttributes)
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	var hosts []string
+
+	for _, key := range logHostKeys {
+		host := strings.TrimSpace(stringFromValue(attributes[key]))
+		if key == "remote_addr" {
+			if addr, _, err := net.SplitHostPort(host); err == nil {
+				host = addr
+			}
+		}
+
+		if host == "" || slices.Contains(hosts, host) {
+			continue
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	return hosts
+}
+
+// devicePartition returns the partition prefix of a "partition:ip" device ID.
+func devicePartition(deviceID string) string {
+	partition, _, found := strings.Cut(deviceID, ":")
+	if !found {
+		return ""
+	}
+
+	return partition
+}
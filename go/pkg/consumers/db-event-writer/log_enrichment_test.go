@@ -0,0 +1,115 @@
+package dbeventwriter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+type fakeDeviceResolver struct {
+	devices map[string]*models.Device
+	lookups [][]string
+	err     error
+}
+
+func (r *fakeDeviceResolver) DevicesByHost(_ context.Context, hosts []string) (map[string]*models.Device, error) {
+	r.lookups = append(r.lookups, hosts)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	found := make(map[string]*models.Device)
+
+	for _, host := range hosts {
+		if device, ok := r.devices[host]; ok {
+			found[host] = device
+		}
+	}
+
+	return found, nil
+}
+
+func newTestLogEnricher(t *testing.T) (*logEnricher, *fakeDeviceResolver, *time.Time) {
+	t.Helper()
+
+	enricher := newLogEnricher(&LogEnrichmentConfig{Enabled: true, Tags: []string{"criticality", "site"}}, logger.NewTestLogger())
+	require.NotNil(t, enricher)
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	enricher.now = func() time.Time { return now }
+
+	resolver := &fakeDeviceResolver{devices: map[string]*models.Device{
+		"10.0.0.5": {
+			DeviceID: "edge:10.0.0.5",
+			IP:       "10.0.0.5",
+			Metadata: map[string]interface{}{"criticality": "critical", "site": "", "owner": "netops"},
+		},
+	}}
+
+	return enricher, resolver, &now
+}
+
+func TestLogEnrichmentAttachesDeviceContext(t *testing.T) {
+	enricher, resolver, _ := newTestLogEnricher(t)
+
+	rows, ok := parseJSONLogs([]byte(`[
+		{"message": "link down", "host": "core-sw-1", "ip": "10.0.0.5"},
+		{"message": "disk full", "host": "unknown-host", "ip": "192.0.2.9"}
+	]`), "logs.syslog")
+	require.True(t, ok)
+	require.Len(t, rows, 2)
+
+	enricher.Enrich(context.Background(), resolver, rows)
+
+	assert.Equal(t, "edge:10.0.0.5", rows[0].DeviceID)
+	assert.Equal(t, "edge", rows[0].DevicePartition)
+	assert.Equal(t, map[string]string{"criticality": "critical"}, rows[0].DeviceTags, "only selected, non-empty tags")
+
+	assert.Empty(t, rows[1].DeviceID, "logs from unknown hosts pass through")
+	assert.Empty(t, rows[1].DevicePartition)
+	assert.Nil(t, rows[1].DeviceTags)
+	assert.Equal(t, "disk full", rows[1].Body)
+}
+
+func TestLogEnrichmentCachesResolutions(t *testing.T) {
+	enricher, resolver, now := newTestLogEnricher(t)
+	ctx := context.Background()
+
+	row := models.OTELLogRow{ResourceAttributes: "host.name=core-sw-1,remote_addr=10.0.0.5:514"}
+	miss := models.OTELLogRow{ResourceAttributes: `{"ip":"192.0.2.9"}`}
+
+	enricher.Enrich(ctx, resolver, []models.OTELLogRow{row, miss})
+	require.Len(t, resolver.lookups, 1)
+	assert.ElementsMatch(t, []string{"10.0.0.5", "core-sw-1", "192.0.2.9"}, resolver.lookups[0])
+
+	rows := []models.OTELLogRow{row, miss}
+	enricher.Enrich(ctx, resolver, rows)
+	assert.Len(t, resolver.lookups, 1, "hits and misses are both cached")
+	assert.Equal(t, "edge:10.0.0.5", rows[0].DeviceID)
+	assert.Empty(t, rows[1].DeviceID)
+
+	*now = now.Add(10 * time.Minute)
+	enricher.Enrich(ctx, resolver, []models.OTELLogRow{miss})
+	assert.Len(t, resolver.lookups, 2, "expired entries are looked up again")
+}
+
+func TestLogEnrichmentLookupFailureLeavesRowsUnchanged(t *testing.T) {
+	enricher, resolver, _ := newTestLogEnricher(t)
+	resolver.err = errTestDatabaseDown
+
+	rows := []models.OTELLogRow{{ResourceAttributes: `{"ip":"10.0.0.5"}`}}
+	enricher.Enrich(context.Background(), resolver, rows)
+	assert.Empty(t, rows[0].DeviceID)
+
+	resolver.err = nil
+	enricher.Enrich(context.Background(), resolver, rows)
+	assert.Equal(t, "edge:10.0.0.5", rows[0].DeviceID, "failures are not cached")
+
+	assert.Nil(t, newLogEnricher(&LogEnrichmentConfig{}, nil), "disabled by default")
+}
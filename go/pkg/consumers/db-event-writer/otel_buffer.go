@@ -0,0 +1,504 @@
+package dbeventwriter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/carverauto/serviceradar/go/pkg/db"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+const (
+	otelBufferKindTraces  = "otel_traces"
+	otelBufferKindMetrics = "otel_metrics"
+
+	otelBufferCompressionNone = "none"
+	otelBufferCompressionGzip = "gzip"
+
+	otelBufferOnFullShed  = "shed"
+	otelBufferOnFullSpill = "spill"
+
+	defaultOTELBufferMaxBytes      = 64 << 20
+	defaultOTELBufferFlushRows     = 5000
+	defaultOTELBufferFlushInterval = 2 * time.Second
+)
+
+// OTELBufferConfig configures the in-memory buffer that batches OTEL trace
+// and metric rows before they are inserted into CNPG. Buffered rows are held
+// encoded, gzip-compressed when Compression is "gzip", and flushed once
+// FlushRows rows are pending or every FlushInterval. Once MaxBytes are
+// buffered, new rows are shed, or with OnFull "spill" written through the
+// write_buffer spill log instead. It is read at startup; changes need a
+// restart.
+type OTELBufferConfig struct {
+	Enabled       bool            `json:"enabled"`
+	MaxBytes      int64           `json:"max_bytes,omitempty"`      // defaults to 64 MiB
+	FlushRows     int             `json:"flush_rows,omitempty"`     // defaults to 5000
+	FlushInterval models.Duration `json:"flush_interval,omitempty"` // defaults to 2s
+	Compression   string          `json:"compression,omitempty"`    // "none" (default) or "gzip"
+	OnFull        string          `json:"on_full,omitempty"`        // "shed" (default) or "spill"
+}
+
+// Validate checks the compression and overflow settings. Spilling needs the
+// write_buffer spill log, which spillEnabled reports.
+func (c *OTELBufferConfig) Validate(spillEnabled bool) error {
+	if c == nil || !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+
+	switch c.Compression {
+	case "", otelBufferCompressionNone, otelBufferCompressionGzip:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidOTELBufferCompression, c.Compression))
+	}
+
+	switch c.OnFull {
+	case "", otelBufferOnFullShed:
+	case otelBufferOnFullSpill:
+		if !spillEnabled {
+			errs = append(errs, ErrOTELBufferSpillUnavailable)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidOTELBufferOnFull, c.OnFull))
+	}
+
+	return errors.Join(errs...)
+}
+
+// OTELBufferStats reports the buffer's utilization and lifetime counters.
+// Utilization is the buffered bytes, including rows being flushed, as a
+// fraction of MaxBytes.
+type OTELBufferStats struct {
+	BufferedRows  int     `json:"buffered_rows"`
+	BufferedBytes int64   `json:"buffered_bytes"`
+	MaxBytes      int64   `json:"max_bytes"`
+	Utilization   float64 `json:"utilization"`
+	Flushes       uint64  `json:"flushes"`
+	FlushFailures uint64  `json:"flush_failures"`
+	FlushedRows   uint64  `json:"flushed_rows"`
+	ShedRows      uint64  `json:"shed_rows"`
+	SpilledRows   uint64  `json:"spilled_rows"`
+}
+
+type otelSegment struct {
+	kind    string
+	table   string
+	rows    int
+	payload []byte
+}
+
+type otelFlushFunc func(ctx context.Context, table string, payloads [][]byte) error
+
+// otelBuffer batches OTEL rows in memory between consumer fetches. Rows are
+// acknowledged once admitted, so a crash loses at most what is buffered; a
+// failed flush keeps the rows for the next attempt. Like writeSpill it
+// outlives processors and flushes into whichever database is current.
+type otelBuffer struct {
+	maxBytes      int64
+	flushRows     int
+	flushInterval time.Duration
+	compress      bool
+	spillOnFull   bool
+	log           logger.Logger
+	target        atomic.Pointer[db.DB]
+
+	flushMu       sync.Mutex
+	mu            sync.Mutex
+	kinds         map[string]otelFlushFunc
+	segments      []otelSegment
+	pendingRows   int
+	pendingBytes  int64
+	inflightRows  int
+	inflightBytes int64
+
+	flushes       atomic.Uint64
+	flushFailures atomic.Uint64
+	flushedRows   atomic.Uint64
+	shedRows      atomic.Uint64
+	spilledRows   atomic.Uint64
+}
+
+func newOTELBuffer(cfg *OTELBufferConfig, log logger.Logger) *otelBuffer {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	b := &otelBuffer{
+		maxBytes:      cfg.MaxBytes,
+		flushRows:     cfg.FlushRows,
+		flushInterval: time.Duration(cfg.FlushInterval),
+		compress:      cfg.Compression == otelBufferCompressionGzip,
+		spillOnFull:   cfg.OnFull == otelBufferOnFullSpill,
+		log:           log,
+		kinds:         make(map[string]otelFlushFunc),
+	}
+
+	if b.maxBytes <= 0 {
+		b.maxBytes = defaultOTELBufferMaxBytes
+	}
+
+	if b.flushRows <= 0 {
+		b.flushRows = defaultOTELBufferFlushRows
+	}
+
+	if b.flushInterval <= 0 {
+		b.flushInterval = defaultOTELBufferFlushInterval
+	}
+
+	return b
+}
+
+// otelBufferWriter buffers rows of one kind. Rows that do not fit go to spill
+// when the buffer spills on overflow, and are shed otherwise.
+type otelBufferWriter[T any] struct {
+	buffer *otelBuffer
+	kind   string
+	spill  func(ctx context.Context, table string, rows []T) error
+}
+
+// registerOTELBufferWriter registers how buffered rows of kind are inserted.
+// spill may be nil when the buffer sheds on overflow.
+func registerOTELBufferWriter[T any](
+	b *otelBuffer,
+	kind string,
+	insert func(ctx context.Context, table string, rows []T) error,
+	spill func(ctx context.Context, table string, rows []T) error,
+) *otelBufferWriter[T] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.kinds[kind] = func(ctx context.Context, table string, payloads [][]byte) error {
+		var rows []T
+
+		for _, payload := range payloads {
+			var segment []T
+			if err := json.Unmarshal(payload, &segment); err != nil {
+				return fmt.Errorf("decode buffered %s rows: %w", kind, err)
+			}
+
+			rows = append(rows, segment...)
+		}
+
+		return insert(ctx, table, rows)
+	}
+
+	return &otelBufferWriter[T]{buffer: b, kind: kind, spill: spill}
+}
+
+// Write buffers rows for table, flushing when enough rows are pending. A
+// failed flush is not returned: the rows stay buffered and are retried.
+func (w *otelBufferWriter[T]) Write(ctx context.Context, table string, rows []T) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	b := w.buffer
+
+	payload, err := json.Marshal(rows)
+	if err != nil {
+		return fmt.Errorf("encode %s rows for buffer: %w", w.kind, err)
+	}
+
+	if b.compress {
+		if payload, err = gzipBytes(payload); err != nil {
+			return fmt.Errorf("compress %s rows for buffer: %w", w.kind, err)
+		}
+	}
+
+	if !b.admit(otelSegment{kind: w.kind, table: table, rows: len(rows), payload: payload}) {
+		return w.overflow(ctx, table, rows)
+	}
+
+	if b.flushDue() {
+		if err := b.Flush(ctx); err != nil {
+			b.log.Warn().Err(err).Msg("Failed to flush OTEL buffer; rows kept for retry")
+		}
+	}
+
+	return nil
+}
+
+func (w *otelBufferWriter[T]) overflow(ctx context.Context, table string, rows []T) error {
+	b := w.buffer
+
+	if b.spillOnFull && w.spill != nil {
+		if err := w.spill(ctx, table, rows); err != nil {
+			return err
+		}
+
+		b.spilledRows.Add(uint64(len(rows)))
+
+		return nil
+	}
+
+	b.shedRows.Add(uint64(len(rows)))
+	b.log.Warn().
+		Str("kind", w.kind).
+		Str("table", table).
+		Int("rows", len(rows)).
+		Msg("OTEL buffer full; shedding rows")
+
+	return nil
+}
+
+// admit adds seg unless it would take the buffer past maxBytes.
+func (b *otelBuffer) admit(seg otelSegment) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	size := int64(len(seg.payload))
+	if b.pendingBytes+b.inflightBytes+size > b.maxBytes {
+		return false
+	}
+
+	b.segments = append(b.segments, seg)
+	b.pendingRows += seg.rows
+	b.pendingBytes += size
+
+	return true
+}
+
+func (b *otelBuffer) flushDue() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.pendingRows >= b.flushRows
+}
+
+// Flush inserts every buffered row, grouped by kind and table. Groups that
+// fail to insert are put back at the front of the buffer.
+func (b *otelBuffer) Flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	segments := b.segments
+	b.segments = nil
+	b.inflightRows, b.inflightBytes = b.pendingRows, b.pendingBytes
+	b.pendingRows, b.pendingBytes = 0, 0
+	kinds := b.kinds
+	b.mu.Unlock()
+
+	if len(segments) == 0 {
+		return nil
+	}
+
+	var (
+		failed []otelSegment
+		errs   []error
+	)
+
+	for _, group := range groupOTELSegments(segments) {
+		if err := b.flushGroup(ctx, kinds[group[0].kind], group); err != nil {
+			failed = append(failed, group...)
+			errs = append(errs, err)
+
+			continue
+		}
+
+		for _, seg := range group {
+			b.flushedRows.Add(uint64(seg.rows))
+		}
+	}
+
+	b.mu.Lock()
+	b.segments = append(failed, b.segments...)
+	b.inflightRows, b.inflightBytes = 0, 0
+
+	for _, seg := range failed {
+		b.pendingRows += seg.rows
+		b.pendingBytes += int64(len(seg.payload))
+	}
+	b.mu.Unlock()
+
+	b.flushes.Add(1)
+
+	if len(errs) > 0 {
+		b.flushFailures.Add(1)
+
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (b *otelBuffer) flushGroup(ctx context.Context, flush otelFlushFunc, group []otelSegment) error {
+	kind, table := group[0].kind, group[0].table
+	if flush == nil {
+		return fmt.Errorf("%w: %s", errOTELBufferKindUnknown, kind)
+	}
+
+	payloads := make([][]byte, 0, len(group))
+
+	for _, seg := range group {
+		payload := seg.payload
+		if b.compress {
+			var err error
+			if payload, err = gunzipBytes(payload); err != nil {
+				return fmt.Errorf("decompress buffered %s rows: %w", kind, err)
+			}
+		}
+
+		payloads = append(payloads, payload)
+	}
+
+	return flush(ctx, table, payloads)
+}
+
+// groupOTELSegments groups segments by kind and table, keeping arrival order
+// within each group.
+func groupOTELSegments(segments []otelSegment) [][]otelSegment {
+	type groupKey struct{ kind, table string }
+
+	index := make(map[groupKey]int)
+	groups := make([][]otelSegment, 0)
+
+	for _, seg := range segments {
+		key := groupKey{seg.kind, seg.table}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+
+		groups[i] = append(groups[i], seg)
+	}
+
+	return groups
+}
+
+// Run flushes the buffer every flush interval until ctx is done.
+func (b *otelBuffer) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.Flush(ctx); err != nil && ctx.Err() == nil {
+				b.log.Warn().Err(err).Msg("Failed to flush OTEL buffer; rows kept for retry")
+			}
+		}
+	}
+}
+
+// Stats reports the buffer's utilization and counters.
+func (b *otelBuffer) Stats() OTELBufferStats {
+	b.mu.Lock()
+	rows := b.pendingRows + b.inflightRows
+	size := b.pendingBytes + b.inflightBytes
+	b.mu.Unlock()
+
+	return OTELBufferStats{
+		BufferedRows:  rows,
+		BufferedBytes: size,
+		MaxBytes:      b.maxBytes,
+		Utilization:   float64(size) / float64(b.maxBytes),
+		Flushes:       b.flushes.Load(),
+		FlushFailures: b.flushFailures.Load(),
+		FlushedRows:   b.flushedRows.Load(),
+		ShedRows:      b.shedRows.Load(),
+		SpilledRows:   b.spilledRows.Load(),
+	}
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+
+	return io.ReadAll(zr)
+}
+
+type otelTracesWrite struct {
+	Table string                `json:"table"`
+	Rows  []models.OTELTraceRow `json:"rows"`
+}
+
+type otelMetricsWrite struct {
+	Table string                 `json:"table"`
+	Rows  []models.OTELMetricRow `json:"rows"`
+}
+
+// otelIngest routes OTEL trace and metric inserts through an otelBuffer,
+// overflowing into the write spill when the buffer is configured to spill.
+type otelIngest struct {
+	buffer  *otelBuffer
+	traces  *otelBufferWriter[models.OTELTraceRow]
+	metrics *otelBufferWriter[models.OTELMetricRow]
+}
+
+func newOTELIngest(cfg *OTELBufferConfig, spill *writeSpill, log logger.Logger) *otelIngest {
+	buffer := newOTELBuffer(cfg, log)
+	if buffer == nil {
+		return nil
+	}
+
+	var (
+		spillTraces  func(context.Context, string, []models.OTELTraceRow) error
+		spillMetrics func(context.Context, string, []models.OTELMetricRow) error
+	)
+
+	if spill != nil {
+		spillTraces = func(ctx context.Context, table string, rows []models.OTELTraceRow) error {
+			return spill.traces.Write(ctx, otelTracesWrite{Table: table, Rows: rows})
+		}
+		spillMetrics = func(ctx context.Context, table string, rows []models.OTELMetricRow) error {
+			return spill.metrics.Write(ctx, otelMetricsWrite{Table: table, Rows: rows})
+		}
+	}
+
+	return &otelIngest{
+		buffer: buffer,
+		traces: registerOTELBufferWriter(buffer, otelBufferKindTraces,
+			func(ctx context.Context, table string, rows []models.OTELTraceRow) error {
+				target := buffer.target.Load()
+				if target == nil {
+					return db.ErrDatabaseNotInitialized
+				}
+
+				return target.InsertOTELTraces(ctx, table, rows)
+			}, spillTraces),
+		metrics: registerOTELBufferWriter(buffer, otelBufferKindMetrics,
+			func(ctx context.Context, table string, rows []models.OTELMetricRow) error {
+				target := buffer.target.Load()
+				if target == nil {
+					return db.ErrDatabaseNotInitialized
+				}
+
+				return target.InsertOTELMetrics(ctx, table, rows)
+			}, spillMetrics),
+	}
+}
@@ -0,0 +1,217 @@
+package dbeventwriter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+var errTestDatabaseDown = errors.New("database down")
+
+type bufferedRow struct {
+	ID   int
+	Span string
+}
+
+// recordingSink collects inserted rows and fails while down is set.
+type recordingSink struct {
+	mu      sync.Mutex
+	down    bool
+	inserts [][]bufferedRow
+}
+
+func (s *recordingSink) insert(_ context.Context, table string, rows []bufferedRow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.down {
+		return errTestDatabaseDown
+	}
+
+	if table != "otel_traces" {
+		return errUnsupportedTable
+	}
+
+	s.inserts = append(s.inserts, rows)
+
+	return nil
+}
+
+func (s *recordingSink) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.down = down
+}
+
+func (s *recordingSink) insertCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.inserts)
+}
+
+func rowsFrom(start, count int) []bufferedRow {
+	rows := make([]bufferedRow, 0, count)
+	for i := start; i < start+count; i++ {
+		rows = append(rows, bufferedRow{ID: i, Span: "GET /api/devices"})
+	}
+
+	return rows
+}
+
+func segmentSize(t *testing.T, rows []bufferedRow) int64 {
+	t.Helper()
+
+	payload, err := json.Marshal(rows)
+	require.NoError(t, err)
+
+	return int64(len(payload))
+}
+
+func TestOTELBufferFlushesOnSize(t *testing.T) {
+	t.Parallel()
+
+	buffer := newOTELBuffer(&OTELBufferConfig{
+		Enabled:       true,
+		FlushRows:     4,
+		FlushInterval: models.Duration(time.Hour),
+		Compression:   otelBufferCompressionGzip,
+	}, logger.NewTestLogger())
+
+	sink := &recordingSink{}
+	writer := registerOTELBufferWriter(buffer, otelBufferKindTraces, sink.insert, nil)
+	ctx := context.Background()
+
+	require.NoError(t, writer.Write(ctx, "otel_traces", rowsFrom(0, 2)))
+	assert.Zero(t, sink.insertCount(), "below flush_rows nothing is inserted")
+	assert.Equal(t, 2, buffer.Stats().BufferedRows)
+
+	require.NoError(t, writer.Write(ctx, "otel_traces", rowsFrom(2, 2)))
+	require.Equal(t, 1, sink.insertCount())
+	assert.Equal(t, rowsFrom(0, 4), sink.inserts[0], "one insert, in arrival order")
+
+	stats := buffer.Stats()
+	assert.Zero(t, stats.BufferedRows)
+	assert.Zero(t, stats.BufferedBytes)
+	assert.Equal(t, uint64(4), stats.FlushedRows)
+	assert.Equal(t, uint64(1), stats.Flushes)
+}
+
+func TestOTELBufferFlushesOnInterval(t *testing.T) {
+	t.Parallel()
+
+	buffer := newOTELBuffer(&OTELBufferConfig{
+		Enabled:       true,
+		FlushRows:     1000,
+		FlushInterval: models.Duration(10 * time.Millisecond),
+	}, logger.NewTestLogger())
+
+	sink := &recordingSink{}
+	writer := registerOTELBufferWriter(buffer, otelBufferKindTraces, sink.insert, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go buffer.Run(ctx)
+
+	require.NoError(t, writer.Write(ctx, "otel_traces", rowsFrom(0, 3)))
+
+	require.Eventually(t, func() bool { return sink.insertCount() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, uint64(3), buffer.Stats().FlushedRows)
+}
+
+func TestOTELBufferShedsWhenSaturated(t *testing.T) {
+	t.Parallel()
+
+	first := rowsFrom(0, 5)
+
+	buffer := newOTELBuffer(&OTELBufferConfig{
+		Enabled:       true,
+		MaxBytes:      segmentSize(t, first) + 10,
+		FlushRows:     1,
+		FlushInterval: models.Duration(time.Hour),
+	}, logger.NewTestLogger())
+
+	sink := &recordingSink{down: true}
+	writer := registerOTELBufferWriter(buffer, otelBufferKindTraces, sink.insert, nil)
+	ctx := context.Background()
+
+	// The flush fails, so the rows stay buffered and fill it.
+	require.NoError(t, writer.Write(ctx, "otel_traces", first))
+	require.NoError(t, writer.Write(ctx, "otel_traces", rowsFrom(5, 5)))
+
+	stats := buffer.Stats()
+	assert.Equal(t, 5, stats.BufferedRows)
+	assert.Equal(t, uint64(5), stats.ShedRows)
+	assert.Equal(t, uint64(1), stats.FlushFailures)
+	assert.Greater(t, stats.Utilization, 0.9)
+
+	// Once the database is back the buffered rows are flushed; the shed
+	// rows are gone.
+	sink.setDown(false)
+	require.NoError(t, buffer.Flush(ctx))
+	require.Equal(t, 1, sink.insertCount())
+	assert.Equal(t, first, sink.inserts[0])
+	assert.Zero(t, buffer.Stats().BufferedRows)
+}
+
+func TestOTELBufferSpillsWhenSaturated(t *testing.T) {
+	t.Parallel()
+
+	first := rowsFrom(0, 5)
+
+	buffer := newOTELBuffer(&OTELBufferConfig{
+		Enabled:       true,
+		MaxBytes:      segmentSize(t, first) + 10,
+		FlushRows:     1,
+		FlushInterval: models.Duration(time.Hour),
+		OnFull:        otelBufferOnFullSpill,
+	}, logger.NewTestLogger())
+
+	var spilled []bufferedRow
+
+	spill := func(_ context.Context, _ string, rows []bufferedRow) error {
+		spilled = append(spilled, rows...)
+		return nil
+	}
+
+	sink := &recordingSink{down: true}
+	writer := registerOTELBufferWriter(buffer, otelBufferKindTraces, sink.insert, spill)
+	ctx := context.Background()
+
+	require.NoError(t, writer.Write(ctx, "otel_traces", first))
+	require.NoError(t, writer.Write(ctx, "otel_traces", rowsFrom(5, 5)))
+
+	assert.Equal(t, rowsFrom(5, 5), spilled)
+
+	stats := buffer.Stats()
+	assert.Equal(t, 5, stats.BufferedRows)
+	assert.Equal(t, uint64(5), stats.SpilledRows)
+	assert.Zero(t, stats.ShedRows)
+}
+
+func TestOTELBufferConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	cfg := &OTELBufferConfig{Enabled: true, Compression: "lz4", OnFull: "block"}
+	err := cfg.Validate(true)
+	require.ErrorIs(t, err, ErrInvalidOTELBufferCompression)
+	require.ErrorIs(t, err, ErrInvalidOTELBufferOnFull)
+
+	cfg = &OTELBufferConfig{Enabled: true, OnFull: otelBufferOnFullSpill}
+	require.ErrorIs(t, cfg.Validate(false), ErrOTELBufferSpillUnavailable)
+	require.NoError(t, cfg.Validate(true))
+
+	var disabled *OTELBufferConfig
+	require.NoError(t, disabled.Validate(false))
+}
@@ -42,6 +42,9 @@ type Processor struct {
 	db      *db.DB
 	table   string         // Legacy single table
 	streams []StreamConfig // Multi-stream configuration
+	spill   *writeSpill    // Optional outage buffer for event and flow inserts
+	otel    *otelIngest    // Optional in-memory batching for OTEL trace and metric inserts
+	devices *logEnricher   // Optional device context for log rows
 	logger  logger.Logger
 }
 
@@ -395,7 +398,7 @@ func (p *Processor) processOCSFEventsTable(ctx context.Context, table string, ms
 		return processed, nil
 	}
 
-	if err := p.db.InsertOCSFEvents(ctx, table, eventRows); err != nil {
+	if err := p.insertOCSFEvents(ctx, table, eventRows); err != nil {
 		return processed, err
 	}
 
@@ -407,6 +410,14 @@ func (p *Processor) processOCSFEventsTable(ctx context.Context, table string, ms
 	return processed, nil
 }
 
+func (p *Processor) insertOCSFEvents(ctx context.Context, table string, rows []models.OCSFEventRow) error {
+	if p.spill != nil {
+		return p.spill.events.Write(ctx, ocsfEventsWrite{Table: table, Rows: rows})
+	}
+
+	return p.db.InsertOCSFEvents(ctx, table, rows)
+}
+
 func processOTELTable[T any](
 	ctx context.Context,
 	log logger.Logger,
@@ -482,6 +493,8 @@ func (p *Processor) processLogsTable(ctx context.Context, table string, msgs []j
 		return processed, nil
 	}
 
+	p.devices.Enrich(ctx, p.db, rows)
+
 	if err := p.db.InsertOTELLogs(ctx, table, rows); err != nil {
 		return processed, err
 	}
@@ -535,7 +548,7 @@ func (p *Processor) processMetricsTable(ctx context.Context, table string, msgs
 		return processed, nil
 	}
 
-	if err := p.db.InsertOTELMetrics(ctx, table, metricRows); err != nil {
+	if err := p.insertOTELMetrics(ctx, table, metricRows); err != nil {
 		return processed, err
 	}
 
@@ -555,12 +568,28 @@ func (p *Processor) processTracesTable(ctx context.Context, table string, msgs [
 		table,
 		msgs,
 		p.parseOTELTraces,
-		p.db.InsertOTELTraces,
+		p.insertOTELTraces,
 		"Skipping malformed OTEL trace message",
 		"Inserted OTEL traces into CNPG",
 	)
 }
 
+func (p *Processor) insertOTELTraces(ctx context.Context, table string, rows []models.OTELTraceRow) error {
+	if p.otel != nil {
+		return p.otel.traces.Write(ctx, table, rows)
+	}
+
+	return p.db.InsertOTELTraces(ctx, table, rows)
+}
+
+func (p *Processor) insertOTELMetrics(ctx context.Context, table string, rows []models.OTELMetricRow) error {
+	if p.otel != nil {
+		return p.otel.metrics.Write(ctx, table, rows)
+	}
+
+	return p.db.InsertOTELMetrics(ctx, table, rows)
+}
+
 // parseOTELMessage attempts to parse an OTEL message and returns log rows
 // It returns the parsed log rows and a boolean indicating success
 func (p *Processor) parseOTELMessage(msg jetstream.Msg) ([]models.OTELLogRow, bool) {
@@ -1262,6 +1291,10 @@ func (p *Processor) insertOCSFNetworkActivityBatch(ctx context.Context, table st
 		return nil
 	}
 
+	if p.spill != nil {
+		return p.spill.flows.Write(ctx, networkActivityWrite{Table: table, Rows: rows})
+	}
+
 	return p.db.InsertOCSFNetworkActivity(ctx, table, rows)
 }
 
@@ -24,6 +24,9 @@ type Service struct {
 	js             jetstream.JetStream
 	consumer       *Consumer
 	processor      *Processor
+	spill          *writeSpill
+	otel           *otelIngest
+	devices        *logEnricher
 	wg             sync.WaitGroup
 	db             db.Service
 	logger         logger.Logger
@@ -38,7 +41,14 @@ var (
 	ErrNilDBService = errors.New("db-event-writer: db service is nil")
 )
 
-func buildProcessor(cfg *DBEventWriterConfig, dbService db.Service, log logger.Logger) (*Processor, error) {
+func buildProcessor(
+	cfg *DBEventWriterConfig,
+	dbService db.Service,
+	spill *writeSpill,
+	otel *otelIngest,
+	devices *logEnricher,
+	log logger.Logger,
+) (*Processor, error) {
 	if cfg == nil {
 		return nil, ErrNilConfig
 	}
@@ -46,12 +56,34 @@ func buildProcessor(cfg *DBEventWriterConfig, dbService db.Service, log logger.L
 		return nil, ErrNilDBService
 	}
 
-	streams := cfg.GetStreams()
-	if len(streams) > 0 {
-		return NewProcessorWithStreams(dbService, streams, log)
+	var (
+		proc *Processor
+		err  error
+	)
+
+	if streams := cfg.GetStreams(); len(streams) > 0 {
+		proc, err = NewProcessorWithStreams(dbService, streams, log)
+	} else {
+		proc, err = NewProcessor(dbService, cfg.Table, log)
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
-	return NewProcessor(dbService, cfg.Table, log)
+	if spill != nil {
+		proc.spill = spill
+		spill.target.Store(proc.db)
+	}
+
+	if otel != nil {
+		proc.otel = otel
+		otel.buffer.target.Store(proc.db)
+	}
+
+	proc.devices = devices
+
+	return proc, nil
 }
 
 // NewService initializes the service.
@@ -60,12 +92,28 @@ func NewService(cfg *DBEventWriterConfig, dbService db.Service, log logger.Logge
 		return nil, err
 	}
 
-	proc, err := buildProcessor(cfg, dbService, log)
+	spill, err := newWriteSpill(cfg.WriteBuffer, log)
 	if err != nil {
 		return nil, err
 	}
 
-	svc := &Service{cfg: cfg, processor: proc, db: dbService, logger: log}
+	otel := newOTELIngest(cfg.OTELBuffer, spill, log)
+	devices := newLogEnricher(cfg.LogEnrichment, log)
+
+	proc, err := buildProcessor(cfg, dbService, spill, otel, devices, log)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := &Service{
+		cfg:       cfg,
+		processor: proc,
+		spill:     spill,
+		otel:      otel,
+		devices:   devices,
+		db:        dbService,
+		logger:    log,
+	}
 	svc.connectFactory = svc.createConnection
 	svc.retryDelay = connectionRetryDelay
 
@@ -80,6 +128,22 @@ func (s *Service) Start(ctx context.Context) error {
 	s.wg.Add(1)
 	go s.run(runCtx)
 
+	if s.spill != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.spill.buffer.Run(runCtx)
+		}()
+	}
+
+	if s.otel != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.otel.buffer.Run(runCtx)
+		}()
+	}
+
 	s.logger.Info().
 		Str("stream_name", s.cfg.StreamName).
 		Str("consumer_name", s.cfg.ConsumerName).
@@ -116,6 +180,20 @@ func (s *Service) Stop(ctx context.Context) error {
 
 	s.wg.Wait()
 
+	// Flush buffered OTEL rows before the spill log and database close.
+	if s.otel != nil {
+		flushCtx, cancelFlush := context.WithTimeout(ctx, shutdownTimeout)
+		if err := s.otel.buffer.Flush(flushCtx); err != nil {
+			s.logger.Warn().Err(err).Int("rows", s.otel.buffer.Stats().BufferedRows).
+				Msg("Failed to flush OTEL buffer on shutdown; buffered rows dropped")
+		}
+		cancelFlush()
+	}
+
+	if s.spill != nil {
+		_ = s.spill.buffer.Close()
+	}
+
 	if s.db != nil {
 		_ = s.db.Close()
 	}
@@ -144,7 +222,7 @@ func (s *Service) UpdateConfig(ctx context.Context, cfg *DBEventWriterConfig) er
 	s.wg.Wait()
 	s.resetConnection()
 
-	proc, err := buildProcessor(cfg, s.db, s.logger)
+	proc, err := buildProcessor(cfg, s.db, s.spill, s.otel, s.devices, s.logger)
 	if err != nil {
 		return err
 	}
@@ -169,7 +247,7 @@ func (s *Service) ReloadDatabase(ctx context.Context, dbService db.Service) erro
 	s.wg.Wait()
 	s.resetConnection()
 
-	proc, err := buildProcessor(s.cfg, dbService, s.logger)
+	proc, err := buildProcessor(s.cfg, dbService, s.spill, s.otel, s.devices, s.logger)
 	if err != nil {
 		return err
 	}
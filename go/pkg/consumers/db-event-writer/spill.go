@@ -0,0 +1,111 @@
+package dbeventwriter
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/carverauto/serviceradar/go/pkg/db"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+const (
+	spillKindOCSFEvents      = "ocsf_events"
+	spillKindNetworkActivity = "ocsf_network_activity"
+	spillKindOTELTraces      = "otel_traces"
+	spillKindOTELMetrics     = "otel_metrics"
+)
+
+type ocsfEventsWrite struct {
+	Table string                `json:"table"`
+	Rows  []models.OCSFEventRow `json:"rows"`
+}
+
+type networkActivityWrite struct {
+	Table string                       `json:"table"`
+	Rows  []models.OCSFNetworkActivity `json:"rows"`
+}
+
+// writeSpill routes event and flow inserts through a db.SpillBuffer so they
+// survive CNPG outages. OTEL rows that overflow the otelBuffer land here too.
+// It outlives processors: the buffer keeps replaying into whichever database
+// the service currently holds.
+type writeSpill struct {
+	buffer  *db.SpillBuffer
+	events  *db.SpillWriter[ocsfEventsWrite]
+	flows   *db.SpillWriter[networkActivityWrite]
+	traces  *db.SpillWriter[otelTracesWrite]
+	metrics *db.SpillWriter[otelMetricsWrite]
+	target  atomic.Pointer[db.DB]
+}
+
+func newWriteSpill(cfg *db.SpillConfig, log logger.Logger) (*writeSpill, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+
+	buffer, err := db.NewSpillBuffer(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &writeSpill{buffer: buffer}
+
+	w.events, err = db.RegisterSpillWriter(buffer, spillKindOCSFEvents, func(ctx context.Context, write ocsfEventsWrite) error {
+		target := w.target.Load()
+		if target == nil {
+			return db.ErrDatabaseNotInitialized
+		}
+
+		return target.InsertOCSFEvents(ctx, write.Table, write.Rows)
+	})
+	if err != nil {
+		_ = buffer.Close()
+
+		return nil, err
+	}
+
+	w.flows, err = db.RegisterSpillWriter(buffer, spillKindNetworkActivity, func(ctx context.Context, write networkActivityWrite) error {
+		target := w.target.Load()
+		if target == nil {
+			return db.ErrDatabaseNotInitialized
+		}
+
+		return target.InsertOCSFNetworkActivity(ctx, write.Table, write.Rows)
+	})
+	if err != nil {
+		_ = buffer.Close()
+
+		return nil, err
+	}
+
+	w.traces, err = db.RegisterSpillWriter(buffer, spillKindOTELTraces, func(ctx context.Context, write otelTracesWrite) error {
+		target := w.target.Load()
+		if target == nil {
+			return db.ErrDatabaseNotInitialized
+		}
+
+		return target.InsertOTELTraces(ctx, write.Table, write.Rows)
+	})
+	if err != nil {
+		_ = buffer.Close()
+
+		return nil, err
+	}
+
+	w.metrics, err = db.RegisterSpillWriter(buffer, spillKindOTELMetrics, func(ctx context.Context, write otelMetricsWrite) error {
+		target := w.target.Load()
+		if target == nil {
+			return db.ErrDatabaseNotInitialized
+		}
+
+		return target.InsertOTELMetrics(ctx, write.Table, write.Rows)
+	})
+	if err != nil {
+		_ = buffer.Close()
+
+		return nil, err
+	}
+
+	return w, nil
+}
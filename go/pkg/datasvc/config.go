@@ -42,6 +42,10 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if c.ConfigChanges != nil && c.ConfigChanges.Enabled && len(c.ConfigChanges.Keys) == 0 {
+		return errConfigChangeKeysRequired
+	}
+
 	c.normalizeSecurityCertPaths(c.Security)
 	c.normalizeSecurityCertPaths(c.NATSSecurity)
 	c.setDefaultBucket()
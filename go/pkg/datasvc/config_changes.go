@@ -0,0 +1,195 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datasvc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/carverauto/serviceradar/go/pkg/config"
+	configkv "github.com/carverauto/serviceradar/go/pkg/config/kv"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+// configChangePublisher publishes config change events.
+type configChangePublisher interface {
+	PublishConfigChangeEvent(ctx context.Context, data *models.ConfigChangeEventData) error
+}
+
+// configChangeDetector watches config keys and publishes the diff between
+// each new revision and the one before it, taken from the key's history.
+type configChangeDetector struct {
+	store     KVStore
+	publisher configChangePublisher
+	logger    logger.Logger
+
+	mu      sync.Mutex
+	handled map[string]uint64 // latest revision handled per key
+}
+
+func newConfigChangeDetector(store KVStore, publisher configChangePublisher, log logger.Logger) *configChangeDetector {
+	return &configChangeDetector{
+		store:     store,
+		publisher: publisher,
+		logger:    log,
+		handled:   make(map[string]uint64),
+	}
+}
+
+// Start records the current revision of each key and watches it for changes
+// until ctx is done. The revision is recorded first because a watch begins by
+// delivering the current value, which is not a change.
+func (d *configChangeDetector) Start(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		revisions, _, err := d.store.History(ctx, key, 1)
+		if err != nil {
+			return fmt.Errorf("failed to read history of config key %s: %w", key, err)
+		}
+
+		if len(revisions) > 0 {
+			d.markHandled(key, revisions[0].Revision)
+		}
+
+		updates, err := d.store.Watch(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to watch config key %s: %w", key, err)
+		}
+
+		go d.watch(ctx, key, updates)
+	}
+
+	return nil
+}
+
+func (d *configChangeDetector) watch(ctx context.Context, key string, updates <-chan []byte) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			if err := d.handleUpdate(ctx, key); err != nil {
+				d.logger.Warn().Err(err).Str("key", key).Msg("Failed to publish config change event")
+			}
+		}
+	}
+}
+
+// handleUpdate publishes the diff between the key's latest revision and the
+// one before it, unless that revision was already handled or changed nothing.
+func (d *configChangeDetector) handleUpdate(ctx context.Context, key string) error {
+	revisions, _, err := d.store.History(ctx, key, 2)
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+
+	if len(revisions) == 0 || !d.markHandled(key, revisions[0].Revision) {
+		return nil
+	}
+
+	current := revisions[0]
+	data := &models.ConfigChangeEventData{
+		Key:       key,
+		Operation: current.Operation,
+		Revision:  current.Revision,
+		Timestamp: current.Created,
+	}
+
+	var oldValue, newValue []byte
+
+	if len(revisions) > 1 {
+		data.PreviousRevision = revisions[1].Revision
+
+		if revisions[1].Operation == configkv.RevisionOpPut {
+			oldValue = revisions[1].Value
+		}
+	}
+
+	if current.Operation == configkv.RevisionOpPut {
+		newValue = current.Value
+	}
+
+	data.Diff, err = config.DiffJSON(oldValue, newValue)
+	if err != nil {
+		return fmt.Errorf("failed to diff revision %d: %w", current.Revision, err)
+	}
+
+	if data.Diff.Empty() && current.Operation == configkv.RevisionOpPut {
+		return nil
+	}
+
+	return d.publisher.PublishConfigChangeEvent(ctx, data)
+}
+
+// markHandled records revision as handled for key, reporting whether it is
+// newer than any revision handled before.
+func (d *configChangeDetector) markHandled(key string, revision uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if revision <= d.handled[key] {
+		return false
+	}
+
+	d.handled[key] = revision
+
+	return true
+}
+
+// storeEventPublisher publishes events on the NATS connection of the store,
+// following it across reconnects.
+type storeEventPublisher struct {
+	store  *NATSStore
+	events models.EventsConfig
+}
+
+func (p *storeEventPublisher) PublishConfigChangeEvent(ctx context.Context, data *models.ConfigChangeEventData) error {
+	publisher, err := p.store.eventPublisher(ctx, &p.events)
+	if err != nil {
+		return err
+	}
+
+	return publisher.PublishConfigChangeEvent(ctx, data)
+}
+
+// startConfigChangeEvents starts publishing config change events for the
+// configured keys.
+func (s *Server) startConfigChangeEvents(ctx context.Context, cfg *ConfigChangeConfig) error {
+	store, ok := s.store.(*NATSStore)
+	if !ok {
+		return errConfigChangesUnsupported
+	}
+
+	events := models.EventsConfig{Enabled: true, StreamName: cfg.StreamName}
+	if err := events.Validate(); err != nil {
+		return err
+	}
+
+	detector := newConfigChangeDetector(s.store, &storeEventPublisher{store: store, events: events}, s.logger)
+	if err := detector.Start(ctx, cfg.Keys); err != nil {
+		return err
+	}
+
+	s.logger.Info().Strs("keys", cfg.Keys).Msg("Publishing config change events")
+
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datasvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/carverauto/serviceradar/go/pkg/config"
+	configkv "github.com/carverauto/serviceradar/go/pkg/config/kv"
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+const syncConfigKey = "config/sync.json"
+
+type recordingPublisher struct {
+	events chan *models.ConfigChangeEventData
+}
+
+func (p *recordingPublisher) PublishConfigChangeEvent(_ context.Context, data *models.ConfigChangeEventData) error {
+	p.events <- data
+	return nil
+}
+
+func newTestDetector(t *testing.T) (*configChangeDetector, *MockKVStore, *recordingPublisher) {
+	t.Helper()
+
+	store := NewMockKVStore(gomock.NewController(t))
+	publisher := &recordingPublisher{events: make(chan *models.ConfigChangeEventData, 4)}
+
+	return newConfigChangeDetector(store, publisher, logger.NewTestLogger()), store, publisher
+}
+
+func putRevision(revision uint64, value string) Revision {
+	return Revision{
+		Value:     []byte(value),
+		Revision:  revision,
+		Created:   time.Date(2026, 10, 16, 12, 0, int(revision), 0, time.UTC),
+		Operation: configkv.RevisionOpPut,
+	}
+}
+
+func TestConfigChangeDetector_PublishesRedactedDiff(t *testing.T) {
+	ctx := context.Background()
+	detector, store, publisher := newTestDetector(t)
+
+	oldValue := `{"sources":{"armis":{"endpoint":"https://a","api_key":"k1","interval":"5m"}}}`
+	newValue := `{"sources":{"armis":{"endpoint":"https://b","api_key":"k2","interval":"5m"}}}`
+
+	store.EXPECT().History(ctx, syncConfigKey, 2).
+		Return([]Revision{putRevision(8, newValue), putRevision(7, oldValue)}, true, nil)
+
+	require.NoError(t, detector.handleUpdate(ctx, syncConfigKey))
+
+	event := <-publisher.events
+	assert.Equal(t, syncConfigKey, event.Key)
+	assert.Equal(t, configkv.RevisionOpPut, event.Operation)
+	assert.Equal(t, uint64(8), event.Revision)
+	assert.Equal(t, uint64(7), event.PreviousRevision)
+	assert.Equal(t, map[string]models.ConfigValueChange{
+		"/sources/armis/endpoint": {Old: "https://a", New: "https://b"},
+		"/sources/armis/api_key":  {Old: config.RedactedDiffValue, New: config.RedactedDiffValue},
+	}, event.Diff.Changed)
+	assert.Empty(t, event.Diff.Added)
+	assert.Empty(t, event.Diff.Removed)
+}
+
+func TestConfigChangeDetector_SkipsHandledAndNoOpRevisions(t *testing.T) {
+	ctx := context.Background()
+	detector, store, publisher := newTestDetector(t)
+
+	value := `{"interval":"5m"}`
+	detector.markHandled(syncConfigKey, 7)
+
+	// The current value, as delivered when a watch starts.
+	store.EXPECT().History(ctx, syncConfigKey, 2).
+		Return([]Revision{putRevision(7, value), putRevision(6, `{}`)}, true, nil)
+	require.NoError(t, detector.handleUpdate(ctx, syncConfigKey))
+
+	// A rewrite of the same config.
+	store.EXPECT().History(ctx, syncConfigKey, 2).
+		Return([]Revision{putRevision(8, value), putRevision(7, value)}, true, nil)
+	require.NoError(t, detector.handleUpdate(ctx, syncConfigKey))
+
+	assert.Empty(t, publisher.events)
+}
+
+func TestConfigChangeDetector_PublishesDeletes(t *testing.T) {
+	ctx := context.Background()
+	detector, store, publisher := newTestDetector(t)
+
+	deleted := Revision{Revision: 9, Operation: configkv.RevisionOpDelete}
+	store.EXPECT().History(ctx, syncConfigKey, 2).
+		Return([]Revision{deleted, putRevision(8, `{"enabled":true}`)}, true, nil)
+
+	require.NoError(t, detector.handleUpdate(ctx, syncConfigKey))
+
+	event := <-publisher.events
+	assert.Equal(t, configkv.RevisionOpDelete, event.Operation)
+	assert.Equal(t, map[string]interface{}{"/enabled": true}, event.Diff.Removed)
+}
+
+func TestConfigChangeDetector_WatchesConfiguredKeys(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	detector, store, publisher := newTestDetector(t)
+	updates := make(chan []byte, 2)
+
+	gomock.InOrder(
+		store.EXPECT().History(ctx, syncConfigKey, 1).
+			Return([]Revision{putRevision(3, `{"interval":"5m"}`)}, true, nil),
+		store.EXPECT().Watch(ctx, syncConfigKey).Return((<-chan []byte)(updates), nil),
+		store.EXPECT().History(ctx, syncConfigKey, 2).
+			Return([]Revision{putRevision(3, `{"interval":"5m"}`)}, true, nil),
+		store.EXPECT().History(ctx, syncConfigKey, 2).
+			Return([]Revision{putRevision(4, `{"interval":"1m"}`), putRevision(3, `{"interval":"5m"}`)}, true, nil),
+	)
+
+	require.NoError(t, detector.Start(ctx, []string{syncConfigKey}))
+
+	updates <- []byte(`{"interval":"5m"}`)
+	updates <- []byte(`{"interval":"1m"}`)
+
+	select {
+	case event := <-publisher.events:
+		assert.Equal(t, uint64(4), event.Revision)
+		assert.Equal(t, map[string]models.ConfigValueChange{
+			"/interval": {Old: "5m", New: "1m"},
+		}, event.Diff.Changed)
+	case <-time.After(time.Second):
+		t.Fatal("no config change event published")
+	}
+}
@@ -48,6 +48,8 @@ var (
 	errResolverPathNotSet       = errors.New("resolver path not configured")
 	errAccountKeyJWTRequired    = errors.New("account public key and JWT are required")
 	errObjectTooLarge           = errors.New("object exceeds configured maximum size")
+	errConfigChangeKeysRequired = errors.New("config_changes.keys is required when config_changes is enabled")
+	errConfigChangesUnsupported = errors.New("config change events require the NATS store")
 )
 
 // ErrCASMismatch indicates a compare-and-swap failure due to a stale revision.
@@ -23,6 +23,8 @@ import (
 	"context"
 	"io"
 	"time"
+
+	configkv "github.com/carverauto/serviceradar/go/pkg/config/kv"
 )
 
 // KVStore defines the interface for a key-value store used in ServiceRadar configuration management.
@@ -61,6 +63,15 @@ type KVStore interface {
 	// If prefix is empty, all keys are returned.
 	ListKeys(ctx context.Context, prefix string) ([]string, error)
 
+	// History returns up to limit revisions of the key, newest first, including deletes.
+	// The boolean reports whether older revisions were omitted. A limit <= 0 selects
+	// DefaultHistoryLimit and limits above MaxHistoryLimit are capped.
+	History(ctx context.Context, key string, limit int) ([]Revision, bool, error)
+
+	// GetRevision retrieves the key as it was at the given revision.
+	// The boolean is false if the revision does not exist or no longer holds a value.
+	GetRevision(ctx context.Context, key string, revision uint64) (Revision, bool, error)
+
 	// PutObject streams an object payload into the JetStream object store.
 	PutObject(ctx context.Context, key string, reader io.Reader, meta ObjectMetadata) (*ObjectInfo, error)
 
@@ -90,6 +101,16 @@ type Entry struct {
 	Found    bool
 }
 
+// History depth bounds. JetStream only retains as many revisions as the
+// bucket's history setting allows, so fewer may be returned.
+const (
+	DefaultHistoryLimit = 20
+	MaxHistoryLimit     = 64
+)
+
+// Revision describes a single historical value of a key.
+type Revision = configkv.Revision
+
 // ObjectMetadata captures descriptive attributes for JetStream objects.
 type ObjectMetadata struct {
 	Domain      string
@@ -146,6 +146,38 @@ func (mr *MockKVStoreMockRecorder) GetObjectInfo(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObjectInfo", reflect.TypeOf((*MockKVStore)(nil).GetObjectInfo), arg0, arg1)
 }
 
+// GetRevision mocks base method.
+func (m *MockKVStore) GetRevision(arg0 context.Context, arg1 string, arg2 uint64) (Revision, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevision", arg0, arg1, arg2)
+	ret0, _ := ret[0].(Revision)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetRevision indicates an expected call of GetRevision.
+func (mr *MockKVStoreMockRecorder) GetRevision(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevision", reflect.TypeOf((*MockKVStore)(nil).GetRevision), arg0, arg1, arg2)
+}
+
+// History mocks base method.
+func (m *MockKVStore) History(arg0 context.Context, arg1 string, arg2 int) ([]Revision, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]Revision)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// History indicates an expected call of History.
+func (mr *MockKVStoreMockRecorder) History(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockKVStore)(nil).History), arg0, arg1, arg2)
+}
+
 // Put mocks base method.
 func (m *MockKVStore) Put(arg0 context.Context, arg1 string, arg2 []byte, arg3 time.Duration) error {
 	m.ctrl.T.Helper()
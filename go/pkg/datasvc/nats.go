@@ -36,6 +36,7 @@ import (
 
 	configkv "github.com/carverauto/serviceradar/go/pkg/config/kv"
 	"github.com/carverauto/serviceradar/go/pkg/models"
+	"github.com/carverauto/serviceradar/go/pkg/natsutil"
 )
 
 type NATSStore struct {
@@ -510,6 +511,90 @@ func (n *NATSStore) ListKeys(ctx context.Context, prefix string) ([]string, erro
 	return keys, nil
 }
 
+// History returns the retained revisions of a key, newest first, bounded by limit.
+func (n *NATSStore) History(ctx context.Context, key string, limit int) ([]Revision, bool, error) {
+	domain, realKey := n.extractDomain(key)
+	kv, err := n.getKVForDomain(ctx, domain)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entries, err := kv.History(ctx, realKey)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return []Revision{}, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read history for key %s: %w", realKey, err)
+	}
+
+	revisions, truncated := newestRevisions(entries, limit)
+
+	return revisions, truncated, nil
+}
+
+// GetRevision retrieves the value a key held at the given revision.
+func (n *NATSStore) GetRevision(ctx context.Context, key string, revision uint64) (Revision, bool, error) {
+	domain, realKey := n.extractDomain(key)
+	kv, err := n.getKVForDomain(ctx, domain)
+	if err != nil {
+		return Revision{}, false, err
+	}
+
+	entry, err := kv.GetRevision(ctx, realKey, revision)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return Revision{}, false, nil
+	}
+	if err != nil {
+		return Revision{}, false, fmt.Errorf("failed to get revision %d of key %s: %w", revision, realKey, err)
+	}
+
+	return revisionFromEntry(entry), true, nil
+}
+
+// newestRevisions converts JetStream history (oldest first) into at most limit
+// revisions ordered newest first, reporting whether older ones were dropped.
+func newestRevisions(entries []jetstream.KeyValueEntry, limit int) ([]Revision, bool) {
+	limit = clampHistoryLimit(limit)
+
+	count := min(len(entries), limit)
+	revisions := make([]Revision, 0, count)
+	for i := len(entries) - 1; i >= len(entries)-count; i-- {
+		revisions = append(revisions, revisionFromEntry(entries[i]))
+	}
+
+	return revisions, len(entries) > count
+}
+
+func clampHistoryLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return DefaultHistoryLimit
+	case limit > MaxHistoryLimit:
+		return MaxHistoryLimit
+	default:
+		return limit
+	}
+}
+
+func revisionFromEntry(entry jetstream.KeyValueEntry) Revision {
+	rev := Revision{
+		Revision: entry.Revision(),
+		Created:  entry.Created(),
+	}
+
+	switch entry.Operation() {
+	case jetstream.KeyValueDelete:
+		rev.Operation = configkv.RevisionOpDelete
+	case jetstream.KeyValuePurge:
+		rev.Operation = configkv.RevisionOpPurge
+	case jetstream.KeyValuePut:
+		rev.Operation = configkv.RevisionOpPut
+		rev.Value = entry.Value()
+	}
+
+	return rev
+}
+
 func (n *NATSStore) PutObject(ctx context.Context, key string, reader io.Reader, meta ObjectMetadata) (*ObjectInfo, error) {
 	domain, realKey := n.extractDomain(key)
 	store, err := n.getObjectStoreForDomain(ctx, domain)
@@ -735,6 +820,19 @@ func (n *NATSStore) getKVForDomain(ctx context.Context, domain string) (jetstrea
 	return n.ensureDomainLocked(ctx, domain)
 }
 
+// eventPublisher returns a publisher for OCSF events on the store's current
+// NATS connection, using the default domain's JetStream.
+func (n *NATSStore) eventPublisher(ctx context.Context, events *models.EventsConfig) (*natsutil.EventPublisher, error) {
+	if _, err := n.getKVForDomain(ctx, n.defaultDomain); err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return natsutil.NewEventPublisher(n.jsByDomain[n.defaultDomain], events.StreamName, events.Subjects), nil
+}
+
 func (n *NATSStore) getObjectStoreForDomain(ctx context.Context, domain string) (jetstream.ObjectStore, error) {
 	if _, err := n.getKVForDomain(ctx, domain); err != nil {
 		return nil, err
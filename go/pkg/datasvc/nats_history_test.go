@@ -0,0 +1,137 @@
+package datasvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configkv "github.com/carverauto/serviceradar/go/pkg/config/kv"
+)
+
+func newHistoryTestStore(t *testing.T, history uint32) *NATSStore {
+	t.Helper()
+
+	srv := runJetStreamServer(t, &server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	t.Cleanup(srv.Shutdown)
+
+	store := &NATSStore{
+		ctx:           context.Background(),
+		natsURL:       srv.ClientURL(),
+		bucket:        "history-kv",
+		bucketHistory: history,
+		jsByDomain:    make(map[string]jetstream.JetStream),
+		kvByDomain:    make(map[string]jetstream.KeyValue),
+	}
+	store.connectFn = store.connect
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func TestNATSStoreHistoryListsRevisionsNewestFirst(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip("skipping JetStream history test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	store := newHistoryTestStore(t, 10)
+
+	for _, value := range []string{"v1", "v2", "v3"} {
+		require.NoError(t, store.Put(ctx, "config/agent.json", []byte(value), 0))
+	}
+	require.NoError(t, store.Delete(ctx, "config/agent.json"))
+	require.NoError(t, store.Put(ctx, "config/agent.json", []byte("v4"), 0))
+
+	revisions, truncated, err := store.History(ctx, "config/agent.json", 0)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	require.Len(t, revisions, 5)
+
+	ops := make([]string, 0, len(revisions))
+	for i, rev := range revisions {
+		ops = append(ops, rev.Operation)
+		assert.False(t, rev.Created.IsZero())
+		if i > 0 {
+			assert.Greater(t, revisions[i-1].Revision, rev.Revision)
+		}
+	}
+	assert.Equal(t, []string{
+		configkv.RevisionOpPut,
+		configkv.RevisionOpDelete,
+		configkv.RevisionOpPut,
+		configkv.RevisionOpPut,
+		configkv.RevisionOpPut,
+	}, ops)
+	assert.Equal(t, []byte("v4"), revisions[0].Value)
+	assert.Empty(t, revisions[1].Value)
+	assert.Equal(t, []byte("v1"), revisions[4].Value)
+
+	limited, truncated, err := store.History(ctx, "config/agent.json", 2)
+	require.NoError(t, err)
+	assert.True(t, truncated)
+	require.Len(t, limited, 2)
+	assert.Equal(t, revisions[:2], limited)
+
+	missing, truncated, err := store.History(ctx, "config/missing.json", 0)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	assert.Empty(t, missing)
+}
+
+func TestNATSStoreGetRevisionReturnsPriorValue(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip("skipping JetStream history test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	store := newHistoryTestStore(t, 10)
+
+	require.NoError(t, store.Put(ctx, "config/agent.json", []byte("original"), 0))
+	first, err := store.GetEntry(ctx, "config/agent.json")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "config/agent.json", []byte("broken"), 0))
+	require.NoError(t, store.Delete(ctx, "config/agent.json"))
+
+	rev, found, err := store.GetRevision(ctx, "config/agent.json", first.Revision)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, []byte("original"), rev.Value)
+	assert.Equal(t, first.Revision, rev.Revision)
+	assert.Equal(t, configkv.RevisionOpPut, rev.Operation)
+
+	_, found, err = store.GetRevision(ctx, "config/agent.json", first.Revision+2)
+	require.NoError(t, err)
+	assert.False(t, found, "deleted revision should not be found")
+
+	_, found, err = store.GetRevision(ctx, "config/agent.json", 1000)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestClampHistoryLimit(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, DefaultHistoryLimit, clampHistoryLimit(0))
+	assert.Equal(t, DefaultHistoryLimit, clampHistoryLimit(-3))
+	assert.Equal(t, 5, clampHistoryLimit(5))
+	assert.Equal(t, MaxHistoryLimit, clampHistoryLimit(MaxHistoryLimit+1))
+}
@@ -130,6 +130,8 @@ func (*Server) authorizeMethod(method string, role Role) error {
 		"/proto.KVService/BatchGet":         {RoleReader, RoleWriter},
 		"/proto.KVService/Watch":            {RoleReader, RoleWriter},
 		"/proto.KVService/Info":             {RoleReader, RoleWriter},
+		"/proto.KVService/History":          {RoleReader, RoleWriter},
+		"/proto.KVService/GetRevision":      {RoleReader, RoleWriter},
 		"/proto.KVService/Put":              {RoleWriter},
 		"/proto.KVService/PutIfAbsent":      {RoleWriter},
 		"/proto.KVService/PutMany":          {RoleWriter},
@@ -106,6 +106,12 @@ func (s *Server) Start(ctx context.Context) error {
 		s.StartCoreRegistration(ctx, s.config.CoreRegistration, s.config.ListenAddr, s.config.Security)
 	}
 
+	if cfg := s.config.ConfigChanges; cfg != nil && cfg.Enabled {
+		if err := s.startConfigChangeEvents(ctx, cfg); err != nil {
+			return fmt.Errorf("failed to start config change events: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -264,6 +270,59 @@ func (s *Server) ListKeys(ctx context.Context, req *proto.ListKeysRequest) (*pro
 	}, nil
 }
 
+// History implements the History RPC, listing a key's recent revisions newest first.
+func (s *Server) History(ctx context.Context, req *proto.HistoryRequest) (*proto.HistoryResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	revisions, truncated, err := s.store.History(ctx, req.GetKey(), int(req.GetLimit()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read history for key %s: %v", req.GetKey(), err)
+	}
+
+	resp := &proto.HistoryResponse{
+		Revisions: make([]*proto.KeyRevision, 0, len(revisions)),
+		Truncated: truncated,
+	}
+	for _, rev := range revisions {
+		resp.Revisions = append(resp.Revisions, &proto.KeyRevision{
+			Revision:        rev.Revision,
+			CreatedUnixNano: rev.Created.UnixNano(),
+			Operation:       rev.Operation,
+			Value:           rev.Value,
+		})
+	}
+
+	return resp, nil
+}
+
+// GetRevision implements the GetRevision RPC, returning a key's value at a specific revision.
+func (s *Server) GetRevision(ctx context.Context, req *proto.GetRevisionRequest) (*proto.GetRevisionResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	if req.GetRevision() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "revision is required")
+	}
+
+	rev, found, err := s.store.GetRevision(ctx, req.GetKey(), req.GetRevision())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get revision %d of key %s: %v", req.GetRevision(), req.GetKey(), err)
+	}
+
+	if !found {
+		return &proto.GetRevisionResponse{Found: false}, nil
+	}
+
+	return &proto.GetRevisionResponse{
+		Value:           rev.Value,
+		Found:           true,
+		Revision:        rev.Revision,
+		CreatedUnixNano: rev.Created.UnixNano(),
+	}, nil
+}
+
 // UploadObject implements the client-streaming object upload RPC.
 func (s *Server) UploadObject(stream proto.DataService_UploadObjectServer) error {
 	firstChunk, err := stream.Recv()
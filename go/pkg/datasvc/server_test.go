@@ -25,6 +25,7 @@ import (
 	"io"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -190,3 +191,69 @@ func TestUploadObjectRejectsOversizeStream(t *testing.T) {
 	require.Equal(t, codes.ResourceExhausted, status.Code(err))
 	require.Nil(t, stream.resp)
 }
+
+func TestHistoryReturnsRevisionsNewestFirst(t *testing.T) {
+	s, mockStore := setupServer(t)
+
+	created := time.Unix(1_700_000_000, 0)
+	mockStore.EXPECT().
+		History(gomock.Any(), "config/agent.json", 2).
+		Return([]Revision{
+			{Value: []byte(`{"v":3}`), Revision: 7, Created: created.Add(time.Minute), Operation: "put"},
+			{Revision: 6, Created: created, Operation: "delete"},
+		}, true, nil)
+
+	resp, err := s.History(context.Background(), &proto.HistoryRequest{Key: "config/agent.json", Limit: 2})
+	require.NoError(t, err)
+	require.True(t, resp.GetTruncated())
+	require.Len(t, resp.GetRevisions(), 2)
+
+	assert.Equal(t, uint64(7), resp.GetRevisions()[0].GetRevision())
+	assert.Equal(t, []byte(`{"v":3}`), resp.GetRevisions()[0].GetValue())
+	assert.Equal(t, created.Add(time.Minute).UnixNano(), resp.GetRevisions()[0].GetCreatedUnixNano())
+	assert.Equal(t, "delete", resp.GetRevisions()[1].GetOperation())
+	assert.Empty(t, resp.GetRevisions()[1].GetValue())
+}
+
+func TestHistoryRequiresKey(t *testing.T) {
+	s, _ := setupServer(t)
+
+	_, err := s.History(context.Background(), &proto.HistoryRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetRevisionReturnsPriorValue(t *testing.T) {
+	s, mockStore := setupServer(t)
+
+	created := time.Unix(1_700_000_000, 0)
+	mockStore.EXPECT().
+		GetRevision(gomock.Any(), "config/agent.json", uint64(3)).
+		Return(Revision{Value: []byte(`{"v":1}`), Revision: 3, Created: created, Operation: "put"}, true, nil)
+
+	resp, err := s.GetRevision(context.Background(), &proto.GetRevisionRequest{Key: "config/agent.json", Revision: 3})
+	require.NoError(t, err)
+	assert.True(t, resp.GetFound())
+	assert.Equal(t, []byte(`{"v":1}`), resp.GetValue())
+	assert.Equal(t, uint64(3), resp.GetRevision())
+	assert.Equal(t, created.UnixNano(), resp.GetCreatedUnixNano())
+}
+
+func TestGetRevisionNotFound(t *testing.T) {
+	s, mockStore := setupServer(t)
+
+	mockStore.EXPECT().
+		GetRevision(gomock.Any(), "config/agent.json", uint64(99)).
+		Return(Revision{}, false, nil)
+
+	resp, err := s.GetRevision(context.Background(), &proto.GetRevisionRequest{Key: "config/agent.json", Revision: 99})
+	require.NoError(t, err)
+	assert.False(t, resp.GetFound())
+	assert.Empty(t, resp.GetValue())
+}
+
+func TestGetRevisionRequiresRevision(t *testing.T) {
+	s, _ := setupServer(t)
+
+	_, err := s.GetRevision(context.Background(), &proto.GetRevisionRequest{Key: "config/agent.json"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -43,6 +43,15 @@ type CoreRegistration struct {
 	HeartbeatInterval models.Duration `json:"heartbeat_interval,omitempty"` // Heartbeat interval (default: 30s)
 }
 
+// ConfigChangeConfig enables config change events. Whenever one of Keys
+// changes, datasvc publishes an OCSF event to events.ocsf.processed holding
+// the JSON diff between the previous and new value, with secrets redacted.
+type ConfigChangeConfig struct {
+	Enabled    bool     `json:"enabled"`
+	Keys       []string `json:"keys"`                  // Exact KV keys to watch (no wildcards)
+	StreamName string   `json:"stream_name,omitempty"` // JetStream stream for the events (default "events")
+}
+
 // Config holds the configuration for the KV service.
 type Config struct {
 	ListenAddr    string                 `json:"listen_addr"`
@@ -64,6 +73,9 @@ type Config struct {
 	BucketHistory     uint32            `json:"bucket_history,omitempty"`     // History depth per key
 	CoreRegistration  *CoreRegistration `json:"core_registration,omitempty"`  // Core service registration settings
 
+	// ConfigChanges publishes an event with the diff of each change to the listed config keys.
+	ConfigChanges *ConfigChangeConfig `json:"config_changes,omitempty"`
+
 	// NATSOperator configures the NATS account management service for namespace isolation.
 	// When configured, datasvc will expose the NATSAccountService gRPC endpoint.
 	NATSOperator *accounts.OperatorConfig `json:"nats_operator,omitempty"`
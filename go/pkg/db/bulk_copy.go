@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrBulkLoadMissingTable   = errors.New("bulk load: table required")
+	ErrBulkLoadMissingColumns = errors.New("bulk load: columns required")
+	ErrBulkLoadUnknownColumn  = errors.New("bulk load: conflict or update column not in columns")
+	ErrBulkCopyUnsupported    = errors.New("bulk load: executor does not support COPY")
+)
+
+// copyExecutor is the COPY side of *pgxpool.Pool and pgx.Tx.
+type copyExecutor interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// BulkLoad describes a COPY-based load into a CNPG table.
+type BulkLoad struct {
+	// Table is the target table, optionally schema qualified.
+	Table string
+	// Columns lists the target columns in the order of each row's values.
+	Columns []string
+	// ConflictColumns makes the load an upsert keyed on these columns. COPY
+	// cannot resolve conflicts, so rows are copied into a temporary staging
+	// table and merged into Table with INSERT ... ON CONFLICT.
+	ConflictColumns []string
+	// UpdateColumns are overwritten from the loaded row when it conflicts with
+	// an existing one. When empty, conflicting rows are skipped.
+	UpdateColumns []string
+}
+
+func (l BulkLoad) validate() error {
+	if len(splitTableName(l.Table)) == 0 {
+		return ErrBulkLoadMissingTable
+	}
+
+	if len(l.Columns) == 0 {
+		return ErrBulkLoadMissingColumns
+	}
+
+	known := make(map[string]bool, len(l.Columns))
+	for _, column := range l.Columns {
+		known[column] = true
+	}
+
+	for _, column := range append(append([]string{}, l.ConflictColumns...), l.UpdateColumns...) {
+		if !known[column] {
+			return fmt.Errorf("%w: %s", ErrBulkLoadUnknownColumn, column)
+		}
+	}
+
+	return nil
+}
+
+// CopyRows bulk loads rows with PostgreSQL COPY, which is much faster than
+// batched INSERTs for backfills and imports of millions of rows.
+//
+// Without ConflictColumns the rows are copied straight into the table and a
+// single conflicting row aborts the whole load. With ConflictColumns the load
+// runs in one transaction: rows are copied into a staging table and merged
+// into the table, and when a key repeats within the load the last row wins.
+//
+// It returns the number of rows written to the table; rows skipped on
+// conflict are not counted.
+func (db *DB) CopyRows(ctx context.Context, load BulkLoad, rows pgx.CopyFromSource) (int64, error) {
+	if err := load.validate(); err != nil {
+		return 0, err
+	}
+
+	if !db.cnpgConfigured() {
+		return 0, ErrDatabaseNotInitialized
+	}
+
+	table := pgx.Identifier(splitTableName(load.Table))
+
+	if len(load.ConflictColumns) == 0 {
+		copier, ok := db.conn().(copyExecutor)
+		if !ok {
+			return 0, ErrBulkCopyUnsupported
+		}
+
+		copied, err := copier.CopyFrom(ctx, table, load.Columns, rows)
+		if err != nil {
+			return 0, fmt.Errorf("copy into %s: %w", load.Table, err)
+		}
+
+		return copied, nil
+	}
+
+	var written int64
+
+	err := db.withExecutorTx(ctx, func(exec PgxExecutor) error {
+		var err error
+		written, err = copyAndMerge(ctx, exec, table, load, rows)
+
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("bulk upsert into %s: %w", load.Table, err)
+	}
+
+	return written, nil
+}
+
+// bulkLoadStagingTable is dropped when the loading transaction ends.
+const bulkLoadStagingTable = "bulk_load_staging"
+
+func copyAndMerge(
+	ctx context.Context,
+	exec PgxExecutor,
+	table pgx.Identifier,
+	load BulkLoad,
+	rows pgx.CopyFromSource,
+) (int64, error) {
+	copier, ok := exec.(copyExecutor)
+	if !ok {
+		return 0, ErrBulkCopyUnsupported
+	}
+
+	staging := pgx.Identifier{bulkLoadStagingTable}
+
+	for _, stmt := range buildStagingTableStatements(table, load.Columns) {
+		if _, err := exec.Exec(ctx, stmt); err != nil {
+			return 0, fmt.Errorf("create staging table: %w", err)
+		}
+	}
+
+	if _, err := copier.CopyFrom(ctx, staging, load.Columns, rows); err != nil {
+		return 0, fmt.Errorf("copy into staging table: %w", err)
+	}
+
+	tag, err := exec.Exec(ctx, buildBulkMergeQuery(table, load))
+	if err != nil {
+		return 0, fmt.Errorf("merge staging table: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// buildStagingTableStatements creates an empty temporary table holding only
+// the loaded columns, with their types but none of the target's constraints.
+func buildStagingTableStatements(table pgx.Identifier, columns []string) []string {
+	staging := pgx.Identifier{bulkLoadStagingTable}.Sanitize()
+
+	return []string{
+		"DROP TABLE IF EXISTS " + staging,
+		fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA",
+			staging, sanitizeColumns(columns), table.Sanitize()),
+	}
+}
+
+// buildBulkMergeQuery moves the staging rows into the target table. DISTINCT
+// ON keeps one row per key, since ON CONFLICT DO UPDATE rejects a statement
+// that touches the same row twice; ctid order is the order rows were copied.
+func buildBulkMergeQuery(table pgx.Identifier, load BulkLoad) string {
+	columns := sanitizeColumns(load.Columns)
+	keys := sanitizeColumns(load.ConflictColumns)
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "INSERT INTO %s (%s)\nSELECT DISTINCT ON (%s) %s FROM %s\nORDER BY %s, ctid DESC\nON CONFLICT (%s) ",
+		table.Sanitize(), columns, keys, columns, pgx.Identifier{bulkLoadStagingTable}.Sanitize(), keys, keys)
+
+	if len(load.UpdateColumns) == 0 {
+		b.WriteString("DO NOTHING")
+		return b.String()
+	}
+
+	b.WriteString("DO UPDATE SET ")
+
+	for i, column := range load.UpdateColumns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		quoted := pgx.Identifier{column}.Sanitize()
+		fmt.Fprintf(&b, "%s = EXCLUDED.%s", quoted, quoted)
+	}
+
+	return b.String()
+}
+
+func sanitizeColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+func splitTableName(table string) []string {
+	var parts []string
+
+	for _, part := range strings.Split(table, ".") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+// fakeCopyExecutor records COPY loads and statements. Batched INSERTs fail the
+// test so the COPY path is the only way rows can be written.
+type fakeCopyExecutor struct {
+	t *testing.T
+
+	copies   []pgx.Identifier
+	columns  [][]string
+	rows     [][]any
+	execs    []string
+	affected int64
+}
+
+func (f *fakeCopyExecutor) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	f.execs = append(f.execs, sql)
+	return pgconn.NewCommandTag(fmt.Sprintf("INSERT 0 %d", f.affected)), nil
+}
+
+func (f *fakeCopyExecutor) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errFakeExecutorQueryNotImplemented
+}
+
+func (f *fakeCopyExecutor) QueryRow(context.Context, string, ...any) pgx.Row {
+	return fakeBatchRow{}
+}
+
+func (f *fakeCopyExecutor) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
+	f.t.Fatalf("bulk loads must not fall back to batched inserts")
+	return nil
+}
+
+func (f *fakeCopyExecutor) CopyFrom(
+	_ context.Context,
+	table pgx.Identifier,
+	columns []string,
+	src pgx.CopyFromSource,
+) (int64, error) {
+	f.copies = append(f.copies, table)
+	f.columns = append(f.columns, columns)
+
+	var copied int64
+
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			return copied, err
+		}
+
+		f.rows = append(f.rows, values)
+		copied++
+	}
+
+	return copied, src.Err()
+}
+
+func TestCopyOCSFNetworkActivity_CopiesLargeBatch(t *testing.T) {
+	const total = 200_000
+
+	exec := &fakeCopyExecutor{t: t}
+	db := &DB{executor: exec}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := make([]models.OCSFNetworkActivity, total)
+
+	for i := range rows {
+		rows[i] = models.OCSFNetworkActivity{
+			Time:          start.Add(time.Duration(i) * time.Millisecond),
+			SrcEndpointIP: fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff),
+			BytesTotal:    int64(i),
+		}
+	}
+
+	written, err := db.CopyOCSFNetworkActivity(context.Background(), "", rows)
+	require.NoError(t, err)
+	assert.Equal(t, int64(total), written)
+
+	require.Len(t, exec.copies, 1, "the whole batch should be a single COPY")
+	assert.Equal(t, pgx.Identifier{"ocsf_network_activity"}, exec.copies[0])
+	assert.Equal(t, ocsfNetworkActivityColumns, exec.columns[0])
+	assert.Empty(t, exec.execs)
+	require.Len(t, exec.rows, total)
+
+	for _, i := range []int{0, total / 2, total - 1} {
+		values := exec.rows[i]
+		require.Len(t, values, len(ocsfNetworkActivityColumns))
+		assert.Equal(t, rows[i].Time, values[0])
+		assert.Equal(t, 4001, values[1], "class_uid default")
+		assert.Equal(t, rows[i].SrcEndpointIP, values[8])
+		assert.Equal(t, int64(i), values[17])
+		assert.Equal(t, "default", values[23], "partition default")
+	}
+}
+
+func TestCopyOCSFEvents_UpsertsThroughStagingTable(t *testing.T) {
+	const total = 50_000
+
+	exec := &fakeCopyExecutor{t: t, affected: total}
+	db := &DB{executor: exec}
+
+	now := time.Now().UTC()
+	rows := make([]models.OCSFEventRow, 0, total+1)
+
+	for i := 0; i < total; i++ {
+		rows = append(rows, models.OCSFEventRow{ID: fmt.Sprintf("evt-%d", i), Time: now, ClassUID: 1008})
+	}
+
+	rows = append(rows, models.OCSFEventRow{Time: now})
+
+	written, err := db.CopyOCSFEvents(context.Background(), "public.ocsf_events", rows)
+	require.NoError(t, err)
+	assert.Equal(t, int64(total), written)
+
+	require.Len(t, exec.copies, 1)
+	assert.Equal(t, pgx.Identifier{bulkLoadStagingTable}, exec.copies[0])
+	require.Len(t, exec.rows, total, "rows without an id are not copied")
+	assert.Equal(t, "evt-0", exec.rows[0][0])
+	assert.Equal(t, int32(1), exec.rows[0][7], "severity_id default")
+
+	require.Len(t, exec.execs, 3)
+	assert.Equal(t, `DROP TABLE IF EXISTS "bulk_load_staging"`, exec.execs[0])
+	assert.Contains(t, exec.execs[1], `CREATE TEMP TABLE "bulk_load_staging" ON COMMIT DROP`)
+	assert.Contains(t, exec.execs[1], `FROM "public"."ocsf_events" WITH NO DATA`)
+	assert.Contains(t, exec.execs[2], `INSERT INTO "public"."ocsf_events"`)
+	assert.Contains(t, exec.execs[2], `ON CONFLICT ("id", "time") DO NOTHING`)
+}
+
+func TestBuildBulkMergeQuery_UpdatesColumnsAndKeepsLastRowPerKey(t *testing.T) {
+	query := buildBulkMergeQuery(pgx.Identifier{"device_metrics"}, BulkLoad{
+		Columns:         []string{"device_id", "ts", "value"},
+		ConflictColumns: []string{"device_id", "ts"},
+		UpdateColumns:   []string{"value"},
+	})
+
+	assert.Equal(t, `INSERT INTO "device_metrics" ("device_id", "ts", "value")
+SELECT DISTINCT ON ("device_id", "ts") "device_id", "ts", "value" FROM "bulk_load_staging"
+ORDER BY "device_id", "ts", ctid DESC
+ON CONFLICT ("device_id", "ts") DO UPDATE SET "value" = EXCLUDED."value"`, query)
+}
+
+func TestCopyRows_ValidatesLoad(t *testing.T) {
+	db := &DB{executor: &fakeCopyExecutor{t: t}}
+	empty := pgx.CopyFromRows(nil)
+
+	_, err := db.CopyRows(context.Background(), BulkLoad{Columns: []string{"id"}}, empty)
+	require.ErrorIs(t, err, ErrBulkLoadMissingTable)
+
+	_, err = db.CopyRows(context.Background(), BulkLoad{Table: "t"}, empty)
+	require.ErrorIs(t, err, ErrBulkLoadMissingColumns)
+
+	_, err = db.CopyRows(context.Background(), BulkLoad{
+		Table:           "t",
+		Columns:         []string{"id"},
+		ConflictColumns: []string{"time"},
+	}, empty)
+	require.ErrorIs(t, err, ErrBulkLoadUnknownColumn)
+
+	_, err = (&DB{executor: &fakePgxExecutor{}}).CopyRows(context.Background(), BulkLoad{
+		Table:   "t",
+		Columns: []string{"id"},
+	}, empty)
+	require.ErrorIs(t, err, ErrBulkCopyUnsupported)
+}
+
+// The COPY column lists must match the INSERT statements they mirror.
+func TestBulkCopyColumnsMatchInsertQueries(t *testing.T) {
+	columnList := regexp.MustCompile(`(?s)INSERT INTO \S+ \((.*?)\) VALUES`)
+
+	for _, tc := range []struct {
+		query   string
+		columns []string
+	}{
+		{buildOCSFEventsInsertQuery("t"), ocsfEventColumns},
+		{buildOCSFNetworkActivityInsertQuery("t"), ocsfNetworkActivityColumns},
+	} {
+		match := columnList.FindStringSubmatch(tc.query)
+		require.Len(t, match, 2)
+
+		var columns []string
+		for _, column := range strings.Split(match[1], ",") {
+			columns = append(columns, strings.TrimSpace(column))
+		}
+
+		assert.Equal(t, columns, tc.columns)
+	}
+}
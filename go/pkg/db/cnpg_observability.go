@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -36,12 +37,16 @@ const (
 		scope_version,
 		scope_attributes,
 		attributes,
-		resource_attributes
+		resource_attributes,
+		device_id,
+		device_partition,
+		device_tags
 	) VALUES (
 		$1,$2,$3,$4,$5,
 		$6,$7,$8,$9,$10,
 		$11,$12,$13,$14,$15,
-		$16,$17,$18
+		$16,$17,$18,NULLIF($19, ''),NULLIF($20, ''),
+		$21
 	) ON CONFLICT DO NOTHING`
 
 	otelMetricsInsertSQL = `INSERT INTO %s (
@@ -136,9 +141,27 @@ func (inserter otelLogInserter) QueueRow(batch *pgx.Batch, query string, rowInde
 		row.ScopeAttributes,
 		row.Attributes,
 		row.ResourceAttributes,
+		row.DeviceID,
+		row.DevicePartition,
+		deviceTagsJSON(row.DeviceTags),
 	)
 }
 
+// deviceTagsJSON encodes enriched device tags for the device_tags JSONB
+// column, or NULL when the log was not enriched.
+func deviceTagsJSON(tags map[string]string) []byte {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	encoded, err := json.Marshal(tags)
+	if err != nil {
+		return nil
+	}
+
+	return encoded
+}
+
 type otelMetricInserter struct {
 	rows []models.OTELMetricRow
 }
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/carverauto/serviceradar/go/pkg/logger"
@@ -147,19 +148,29 @@ func buildCNPGConnURL(cfg *models.CNPGDatabase) (url.URL, error) {
 	return connURL, nil
 }
 
-// NewCNPGPool dials the configured CNPG cluster and returns a pgx pool that can
-// be used for Timescale-backed reads/writes.
-func NewCNPGPool(ctx context.Context, cfg *models.CNPGDatabase, log logger.Logger) (*pgxpool.Pool, error) {
-	if cfg == nil {
-		return nil, nil
+// buildSearchPathStatement renders a SET search_path statement with each
+// schema quoted as an identifier. It returns "" when no search path is set.
+func buildSearchPathStatement(paths []string) (string, error) {
+	if len(paths) == 0 {
+		return "", nil
 	}
 
-	cnpg := *cfg
-	if cnpg.Port == 0 {
-		cnpg.Port = 5432
+	quoted := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		path = strings.Trim(strings.TrimSpace(path), `"`)
+		if path == "" {
+			return "", ErrCNPGInvalidSearchPath
+		}
+
+		quoted = append(quoted, pgx.Identifier{path}.Sanitize())
 	}
 
-	connURL, err := buildCNPGConnURL(&cnpg)
+	return "SET search_path TO " + strings.Join(quoted, ", "), nil
+}
+
+func buildCNPGPoolConfig(cnpg *models.CNPGDatabase) (*pgxpool.Config, error) {
+	connURL, err := buildCNPGConnURL(cnpg)
 	if err != nil {
 		return nil, err
 	}
@@ -208,16 +219,103 @@ func NewCNPGPool(ctx context.Context, cfg *models.CNPGDatabase, log logger.Logge
 		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = fmt.Sprintf("%d", timeout)
 	}
 
+	searchPath, err := buildSearchPathStatement(cnpg.SearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if searchPath != "" {
+		// The configured search path wins over any runtime_params override and is
+		// re-applied on every new connection so AGE/extension queries never run
+		// against a connection that lacks it.
+		delete(poolConfig.ConnConfig.RuntimeParams, "search_path")
+
+		poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			if _, err := conn.Exec(ctx, searchPath); err != nil {
+				return fmt.Errorf("cnpg: failed to set search_path: %w", err)
+			}
+
+			return nil
+		}
+	}
+
+	if cnpg.SlowQueryThreshold < 0 {
+		return nil, ErrCNPGInvalidSlowQuery
+	}
+
+	var tracers []pgx.QueryTracer
+
+	if cnpg.SlowQueryThreshold > 0 || cnpg.ForceQueryPlans {
+		tracers = append(tracers, newQueryPlanTracer(
+			time.Duration(cnpg.SlowQueryThreshold), cnpg.CaptureQueryPlans, cnpg.ForceQueryPlans))
+	}
+
+	if cnpg.LogStatements || cnpg.LogStatementParams {
+		tracers = append(tracers, newStatementTracer(cnpg.LogStatementParams))
+	}
+
+	cache, err := applyStatementCache(poolConfig, cnpg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cache != nil {
+		tracers = append(tracers, cache)
+	}
+
+	poolConfig.ConnConfig.Tracer = combineTracers(tracers)
+
+	return poolConfig, nil
+}
+
+// NewCNPGPool dials the configured CNPG cluster and returns a pgx pool that can
+// be used for Timescale-backed reads/writes.
+func NewCNPGPool(ctx context.Context, cfg *models.CNPGDatabase, log logger.Logger) (*pgxpool.Pool, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	cnpg := *cfg
+	if cnpg.Port == 0 {
+		cnpg.Port = 5432
+	}
+
+	applyStatementLogEnv(&cnpg)
+	applyStatementCacheEnv(&cnpg)
+
+	poolConfig, err := buildCNPGPoolConfig(&cnpg)
+	if err != nil {
+		return nil, err
+	}
+
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("cnpg: failed to initialize pool: %w", err)
 	}
 
+	if tracer, ok := findTracer[*queryPlanTracer](poolConfig.ConnConfig.Tracer); ok {
+		tracer.attach(pool, log)
+	}
+
+	if tracer, ok := findTracer[*statementTracer](poolConfig.ConnConfig.Tracer); ok {
+		tracer.attach(log)
+
+		if log != nil && tracer.logParams {
+			log.Warn().Msg("CNPG statement logging includes parameter values; logs may contain sensitive data")
+		}
+	}
+
+	if cache, ok := findTracer[*statementCache](poolConfig.ConnConfig.Tracer); ok {
+		cache.attach(log)
+	}
+
 	if log != nil {
 		log.Info().
 			Str("host", cnpg.Host).
 			Int("port", cnpg.Port).
 			Int32("max_conns", poolConfig.MaxConns).
+			Int("statement_cache_size", poolConfig.ConnConfig.StatementCacheCapacity).
+			Strs("search_path", cnpg.SearchPath).
 			Msg("connected to CNPG/Timescale cluster")
 	}
 
@@ -110,3 +110,61 @@ func TestResolveCNPGSSLMode_UsesRuntimeParamsFallback(t *testing.T) {
 		t.Fatalf("sslmode=%q, want %q", got, "verify-ca")
 	}
 }
+
+func TestBuildSearchPathStatement_QuotesSchemas(t *testing.T) {
+	t.Parallel()
+
+	stmt, err := buildSearchPathStatement([]string{"ag_catalog", `"$user"`, " public "})
+	if err != nil {
+		t.Fatalf("buildSearchPathStatement error: %v", err)
+	}
+
+	want := `SET search_path TO "ag_catalog", "$user", "public"`
+	if stmt != want {
+		t.Fatalf("statement=%q, want %q", stmt, want)
+	}
+
+	if _, err := buildSearchPathStatement([]string{"public", " "}); !errors.Is(err, ErrCNPGInvalidSearchPath) {
+		t.Fatalf("expected ErrCNPGInvalidSearchPath, got %v", err)
+	}
+}
+
+func TestBuildCNPGPoolConfig_AppliesSearchPathOnConnect(t *testing.T) {
+	t.Parallel()
+
+	poolConfig, err := buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host:               "cnpg-rw",
+		Port:               5432,
+		Database:           "serviceradar",
+		SearchPath:         []string{"ag_catalog", "$user", "public"},
+		ExtraRuntimeParams: map[string]string{"search_path": "public"},
+	})
+	if err != nil {
+		t.Fatalf("buildCNPGPoolConfig error: %v", err)
+	}
+
+	if poolConfig.AfterConnect == nil {
+		t.Fatal("expected AfterConnect hook to apply search_path")
+	}
+
+	if got, ok := poolConfig.ConnConfig.RuntimeParams["search_path"]; ok {
+		t.Fatalf("runtime search_path=%q should be superseded by SearchPath", got)
+	}
+}
+
+func TestBuildCNPGPoolConfig_NoSearchPathKeepsDefaults(t *testing.T) {
+	t.Parallel()
+
+	poolConfig, err := buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host:     "cnpg-rw",
+		Port:     5432,
+		Database: "serviceradar",
+	})
+	if err != nil {
+		t.Fatalf("buildCNPGPoolConfig error: %v", err)
+	}
+
+	if poolConfig.AfterConnect != nil {
+		t.Fatal("AfterConnect should be unset without a search path")
+	}
+}
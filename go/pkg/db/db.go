@@ -107,6 +107,15 @@ func (db *DB) Close() error {
 	return nil
 }
 
+// Ping verifies that the database is reachable.
+func (db *DB) Ping(ctx context.Context) error {
+	if db.pgPool == nil {
+		return ErrCNPGUnavailable
+	}
+
+	return db.pgPool.Ping(ctx)
+}
+
 // WithTx executes the given function within a transaction.
 func (db *DB) WithTx(ctx context.Context, fn func(tx Service) error) error {
 	if db.pgPool == nil {
@@ -162,3 +171,27 @@ func (db *DB) WithTx(ctx context.Context, fn func(tx Service) error) error {
 
 	return nil
 }
+
+// withExecutorTx runs fn inside a transaction when the DB is backed by the pool.
+// When the executor is already a transaction (or a test double), fn reuses it.
+func (db *DB) withExecutorTx(ctx context.Context, fn func(PgxExecutor) error) error {
+	if _, ok := db.executor.(*pgxpool.Pool); !ok || db.pgPool == nil {
+		return fn(db.conn())
+	}
+
+	tx, err := db.pgPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+const devicesByHostQuery = `
+SELECT uid, COALESCE(ip, ''), COALESCE(hostname, ''), COALESCE(gateway_id, ''),
+	COALESCE(metadata, '{}'::jsonb)
+FROM platform.ocsf_devices
+WHERE deleted_at IS NULL
+	AND (ip = ANY($1) OR lower(hostname) = ANY($1))
+ORDER BY last_seen_time DESC NULLS LAST`
+
+// DevicesByHost resolves IP addresses and hostnames to inventory devices. The
+// result is keyed by the host as given; hostnames match case-insensitively.
+// When several devices share a host the most recently seen wins, and hosts
+// without a device are omitted.
+func (db *DB) DevicesByHost(ctx context.Context, hosts []string) (map[string]*models.Device, error) {
+	devices := make(map[string]*models.Device, len(hosts))
+	if len(hosts) == 0 {
+		return devices, nil
+	}
+
+	if !db.cnpgConfigured() {
+		return nil, ErrDatabaseNotInitialized
+	}
+
+	lookup := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		lookup = append(lookup, host, strings.ToLower(host))
+	}
+
+	rows, err := db.conn().Query(ctx, devicesByHostQuery, lookup)
+	if err != nil {
+		return nil, fmt.Errorf("query devices by host: %w", err)
+	}
+	defer rows.Close()
+
+	byHost := make(map[string]*models.Device)
+
+	for rows.Next() {
+		var (
+			device   models.Device
+			metadata []byte
+		)
+
+		if err := rows.Scan(&device.DeviceID, &device.IP, &device.Hostname, &device.GatewayID, &metadata); err != nil {
+			return nil, fmt.Errorf("scan device by host: %w", err)
+		}
+
+		if len(metadata) > 0 {
+			if err := json.Unmarshal(metadata, &device.Metadata); err != nil {
+				return nil, fmt.Errorf("decode metadata for %s: %w", device.DeviceID, err)
+			}
+		}
+
+		for _, key := range []string{device.IP, strings.ToLower(device.Hostname)} {
+			if _, seen := byHost[key]; key != "" && !seen {
+				byHost[key] = &device
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate devices by host: %w", err)
+	}
+
+	for _, host := range hosts {
+		if device, ok := byHost[host]; ok {
+			devices[host] = device
+		} else if device, ok := byHost[strings.ToLower(host)]; ok {
+			devices[host] = device
+		}
+	}
+
+	return devices, nil
+}
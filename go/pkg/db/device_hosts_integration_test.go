@@ -0,0 +1,53 @@
+//go:build integration
+// +build integration
+
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDevicesByHost_CNPG runs the host lookup against a migrated CNPG
+// database. Set SERVICERADAR_TEST_CNPG_URL to a database with the platform
+// schema; the seeded devices are rolled back afterwards.
+func TestDevicesByHost_CNPG(t *testing.T) {
+	dsn := os.Getenv("SERVICERADAR_TEST_CNPG_URL")
+	if dsn == "" {
+		t.Skip("SERVICERADAR_TEST_CNPG_URL not set")
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	tx, err := pool.Begin(ctx)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, `
+INSERT INTO platform.ocsf_devices (uid, ip, hostname, gateway_id, metadata, last_seen_time, deleted_at)
+VALUES
+	('sr:it-hosts-new', '198.51.100.7', 'it-hosts-web', 'gw-it', '{"tag:env":"prod"}', now(), NULL),
+	('sr:it-hosts-old', '198.51.100.7', NULL, NULL, NULL, now() - interval '1 day', NULL),
+	('sr:it-hosts-gone', '198.51.100.8', NULL, NULL, NULL, now(), now())`)
+	require.NoError(t, err)
+
+	db := &DB{executor: tx}
+
+	devices, err := db.DevicesByHost(ctx, []string{"198.51.100.7", "IT-HOSTS-WEB", "198.51.100.8"})
+	require.NoError(t, err)
+
+	require.Contains(t, devices, "198.51.100.7")
+	assert.Equal(t, "sr:it-hosts-new", devices["198.51.100.7"].DeviceID)
+	assert.Equal(t, "gw-it", devices["198.51.100.7"].GatewayID)
+	assert.Equal(t, "prod", devices["IT-HOSTS-WEB"].Metadata["tag:env"])
+	assert.NotContains(t, devices, "198.51.100.8", "soft-deleted devices are not resolved")
+}
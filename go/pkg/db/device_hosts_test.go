@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hostRow is a (uid, ip, hostname, gateway_id, metadata) row of ocsf_devices.
+type hostRow struct {
+	uid, ip, hostname, gatewayID, metadata string
+}
+
+type fakeHostRows struct {
+	rows []hostRow
+	pos  int
+}
+
+func (r *fakeHostRows) Close()                                       {}
+func (r *fakeHostRows) Err() error                                   { return nil }
+func (r *fakeHostRows) CommandTag() pgconn.CommandTag                { return pgconn.NewCommandTag("SELECT 0") }
+func (r *fakeHostRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeHostRows) Values() ([]any, error)                       { return nil, nil }
+func (r *fakeHostRows) RawValues() [][]byte                          { return nil }
+func (r *fakeHostRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeHostRows) Next() bool {
+	if r.pos >= len(r.rows) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+func (r *fakeHostRows) Scan(dest ...any) error {
+	row := r.rows[r.pos-1]
+	*dest[0].(*string) = row.uid
+	*dest[1].(*string) = row.ip
+	*dest[2].(*string) = row.hostname
+	*dest[3].(*string) = row.gatewayID
+	*dest[4].(*[]byte) = []byte(row.metadata)
+	return nil
+}
+
+type fakeHostExecutor struct {
+	fakePgxExecutor
+	rows  []hostRow
+	query string
+	args  []any
+}
+
+func (f *fakeHostExecutor) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	f.query = sql
+	f.args = args
+
+	return &fakeHostRows{rows: f.rows}, nil
+}
+
+func TestDevicesByHost_ReadsOCSFDevices(t *testing.T) {
+	exec := &fakeHostExecutor{rows: []hostRow{
+		{uid: "sr:new", ip: "10.0.0.1", hostname: "Web-01", gatewayID: "gw-1", metadata: `{"tag:env":"prod"}`},
+		{uid: "sr:old", ip: "10.0.0.1", hostname: "web-01-old", metadata: `{}`},
+	}}
+	db := &DB{executor: exec}
+
+	devices, err := db.DevicesByHost(context.Background(), []string{"10.0.0.1", "WEB-01", "web-01-old", "10.9.9.9"})
+	require.NoError(t, err)
+
+	assert.Contains(t, exec.query, "FROM platform.ocsf_devices")
+	assert.Contains(t, exec.query, "deleted_at IS NULL")
+	assert.NotContains(t, exec.query, "unified_devices")
+	assert.Equal(t, []any{[]string{
+		"10.0.0.1", "10.0.0.1", "WEB-01", "web-01", "web-01-old", "web-01-old", "10.9.9.9", "10.9.9.9",
+	}}, exec.args)
+
+	require.Len(t, devices, 3)
+	assert.Equal(t, "sr:new", devices["10.0.0.1"].DeviceID, "the most recently seen device wins")
+	assert.Equal(t, "gw-1", devices["10.0.0.1"].GatewayID)
+	assert.Equal(t, "prod", devices["WEB-01"].Metadata["tag:env"])
+	assert.Equal(t, "sr:old", devices["web-01-old"].DeviceID)
+	assert.NotContains(t, devices, "10.9.9.9")
+}
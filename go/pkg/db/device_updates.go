@@ -0,0 +1,305 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+const (
+	ocsfDevicesTable = "platform.ocsf_devices"
+
+	// deviceUpsertColumns is the number of bind parameters queued per device row.
+	deviceUpsertColumns = 11
+	// maxDeviceUpsertRows keeps each statement well below the 65535 bind-parameter limit.
+	maxDeviceUpsertRows = 1000
+)
+
+var (
+	ErrDeviceUpdateMissingID         = errors.New("device update missing device_id")
+	ErrDeviceUpdateMissingIP         = errors.New("device update missing ip")
+	ErrDeviceUpdatePartitionMismatch = errors.New("device update partition does not match device_id")
+)
+
+// DeviceUpsertAction describes what happened to a single DeviceUpdate row.
+type DeviceUpsertAction string
+
+const (
+	DeviceUpsertInserted DeviceUpsertAction = "inserted"
+	DeviceUpsertUpdated  DeviceUpsertAction = "updated"
+	DeviceUpsertFailed   DeviceUpsertAction = "failed"
+)
+
+// DeviceUpsertResult reports the outcome for the update at the same index of the input slice.
+type DeviceUpsertResult struct {
+	DeviceID string
+	Action   DeviceUpsertAction
+	Err      error
+}
+
+const deviceUpsertConflictClause = `
+ON CONFLICT (uid) DO UPDATE SET
+	ip = EXCLUDED.ip,
+	gateway_id = COALESCE(NULLIF(EXCLUDED.gateway_id, ''), ocsf_devices.gateway_id),
+	agent_id = COALESCE(NULLIF(EXCLUDED.agent_id, ''), ocsf_devices.agent_id),
+	hostname = COALESCE(EXCLUDED.hostname, ocsf_devices.hostname),
+	mac = COALESCE(EXCLUDED.mac, ocsf_devices.mac),
+	discovery_sources = (
+		SELECT array_agg(DISTINCT s)
+		FROM unnest(COALESCE(ocsf_devices.discovery_sources, '{}'::text[]) || EXCLUDED.discovery_sources) AS s
+	),
+	is_available = EXCLUDED.is_available,
+	first_seen_time = COALESCE(ocsf_devices.first_seen_time, EXCLUDED.first_seen_time),
+	last_seen_time = GREATEST(ocsf_devices.last_seen_time, EXCLUDED.last_seen_time),
+	metadata = COALESCE(ocsf_devices.metadata, '{}'::jsonb) || EXCLUDED.metadata,
+	modified_time = EXCLUDED.modified_time
+RETURNING uid, (xmax = 0) AS inserted`
+
+// UpsertDeviceUpdates writes DeviceUpdates into the ocsf_devices inventory
+// using multi-row INSERT ... ON CONFLICT statements executed in a single
+// transaction. Soft-deleted devices are updated but stay deleted.
+//
+// Invalid rows are reported as failed and skipped without aborting the batch.
+// If the database rejects a statement the whole transaction is rolled back and
+// every remaining row is reported as failed with that error.
+func (db *DB) UpsertDeviceUpdates(ctx context.Context, updates []*models.DeviceUpdate) ([]DeviceUpsertResult, error) {
+	results := make([]DeviceUpsertResult, len(updates))
+	if len(updates) == 0 {
+		return results, nil
+	}
+
+	if !db.cnpgConfigured() {
+		return nil, ErrDatabaseNotInitialized
+	}
+
+	rows, indexes := prepareDeviceUpserts(updates, results)
+	if len(rows) == 0 {
+		return results, nil
+	}
+
+	outcome := make(map[string]bool, len(rows))
+
+	err := db.withExecutorTx(ctx, func(exec PgxExecutor) error {
+		for start := 0; start < len(rows); start += maxDeviceUpsertRows {
+			end := min(start+maxDeviceUpsertRows, len(rows))
+
+			if err := execDeviceUpsertChunk(ctx, exec, rows[start:end], outcome); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to upsert device updates: %w", err)
+
+		for deviceID, idxs := range indexes {
+			for _, idx := range idxs {
+				results[idx] = DeviceUpsertResult{DeviceID: deviceID, Action: DeviceUpsertFailed, Err: err}
+			}
+		}
+
+		return results, err
+	}
+
+	for deviceID, idxs := range indexes {
+		action := DeviceUpsertUpdated
+		if outcome[deviceID] {
+			action = DeviceUpsertInserted
+		}
+
+		for _, idx := range idxs {
+			results[idx] = DeviceUpsertResult{DeviceID: deviceID, Action: action}
+		}
+	}
+
+	return results, nil
+}
+
+// prepareDeviceUpserts validates updates, recording failures in results, and
+// coalesces repeated device IDs so a single statement never touches a row twice.
+func prepareDeviceUpserts(
+	updates []*models.DeviceUpdate,
+	results []DeviceUpsertResult,
+) ([]*models.DeviceUpdate, map[string][]int) {
+	rows := make([]*models.DeviceUpdate, 0, len(updates))
+	byID := make(map[string]*models.DeviceUpdate, len(updates))
+	indexes := make(map[string][]int, len(updates))
+
+	for i, update := range updates {
+		if err := validateDeviceUpsert(update); err != nil {
+			deviceID := ""
+			if update != nil {
+				deviceID = update.DeviceID
+			}
+
+			results[i] = DeviceUpsertResult{DeviceID: deviceID, Action: DeviceUpsertFailed, Err: err}
+
+			continue
+		}
+
+		indexes[update.DeviceID] = append(indexes[update.DeviceID], i)
+
+		existing, ok := byID[update.DeviceID]
+		if !ok {
+			merged := *update
+			merged.Metadata = maps.Clone(update.Metadata)
+			byID[update.DeviceID] = &merged
+			rows = append(rows, &merged)
+
+			continue
+		}
+
+		mergeDeviceUpdate(existing, update)
+	}
+
+	return rows, indexes
+}
+
+func validateDeviceUpsert(update *models.DeviceUpdate) error {
+	if update == nil || strings.TrimSpace(update.DeviceID) == "" {
+		return ErrDeviceUpdateMissingID
+	}
+
+	if strings.TrimSpace(update.IP) == "" {
+		return ErrDeviceUpdateMissingIP
+	}
+
+	if update.Partition != "" && !models.IsServiceDevice(update.DeviceID) {
+		if prefix, _, ok := strings.Cut(update.DeviceID, ":"); ok && prefix != update.Partition {
+			return fmt.Errorf("%w: %s not in partition %s", ErrDeviceUpdatePartitionMismatch, update.DeviceID, update.Partition)
+		}
+	}
+
+	return nil
+}
+
+// mergeDeviceUpdate folds a later update for the same device into dst.
+func mergeDeviceUpdate(dst, src *models.DeviceUpdate) {
+	if src.Timestamp.After(dst.Timestamp) {
+		dst.Timestamp = src.Timestamp
+		dst.IP = src.IP
+		dst.IsAvailable = src.IsAvailable
+		dst.Source = src.Source
+	}
+
+	if src.Hostname != nil {
+		dst.Hostname = src.Hostname
+	}
+
+	if src.MAC != nil {
+		dst.MAC = src.MAC
+	}
+
+	if src.GatewayID != "" {
+		dst.GatewayID = src.GatewayID
+	}
+
+	if src.AgentID != "" {
+		dst.AgentID = src.AgentID
+	}
+
+	if len(src.Metadata) > 0 && dst.Metadata == nil {
+		dst.Metadata = make(map[string]string, len(src.Metadata))
+	}
+
+	for k, v := range src.Metadata {
+		dst.Metadata[k] = v
+	}
+}
+
+func buildDeviceUpsertQuery(rowCount int) string {
+	var b strings.Builder
+
+	b.WriteString(`INSERT INTO ` + ocsfDevicesTable + ` AS ocsf_devices (
+	uid, ip, gateway_id, agent_id, hostname, mac, discovery_sources, is_available,
+	first_seen_time, last_seen_time, metadata, created_time, modified_time
+) VALUES `)
+
+	for row := 0; row < rowCount; row++ {
+		if row > 0 {
+			b.WriteString(",")
+		}
+
+		base := row * deviceUpsertColumns
+		fmt.Fprintf(&b, "($%d,$%d,$%d,$%d,$%d,$%d,ARRAY[$%d]::text[],$%d,$%d,$%d,$%d::jsonb,$%d,$%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6,
+			base+7, base+8, base+9, base+9, base+10, base+11, base+11)
+	}
+
+	b.WriteString(deviceUpsertConflictClause)
+
+	return b.String()
+}
+
+func deviceUpsertArgs(rows []*models.DeviceUpdate, now time.Time) ([]any, error) {
+	args := make([]any, 0, len(rows)*deviceUpsertColumns)
+
+	for _, row := range rows {
+		ts := row.Timestamp
+		if ts.IsZero() {
+			ts = now
+		}
+
+		metadata := []byte("{}")
+
+		if len(row.Metadata) > 0 {
+			encoded, err := json.Marshal(row.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("marshal metadata for %s: %w", row.DeviceID, err)
+			}
+
+			metadata = encoded
+		}
+
+		args = append(args,
+			row.DeviceID,
+			row.IP,
+			row.GatewayID,
+			row.AgentID,
+			row.Hostname,
+			row.MAC,
+			string(row.Source),
+			row.IsAvailable,
+			ts.UTC(),
+			metadata,
+			now,
+		)
+	}
+
+	return args, nil
+}
+
+func execDeviceUpsertChunk(ctx context.Context, exec PgxExecutor, rows []*models.DeviceUpdate, outcome map[string]bool) error {
+	args, err := deviceUpsertArgs(rows, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	result, err := exec.Query(ctx, buildDeviceUpsertQuery(len(rows)), args...)
+	if err != nil {
+		return err
+	}
+	defer result.Close()
+
+	for result.Next() {
+		var (
+			deviceID string
+			inserted bool
+		)
+
+		if err := result.Scan(&deviceID, &inserted); err != nil {
+			return fmt.Errorf("scan upsert result: %w", err)
+		}
+
+		outcome[deviceID] = inserted
+	}
+
+	return result.Err()
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+var errUpsertConstraint = errors.New("constraint violation")
+
+// upsertRow is a (device_id, inserted) pair returned by the fake upsert query.
+type upsertRow struct {
+	deviceID string
+	inserted bool
+}
+
+type fakeUpsertRows struct {
+	rows []upsertRow
+	pos  int
+}
+
+func (r *fakeUpsertRows) Close()                                       {}
+func (r *fakeUpsertRows) Err() error                                   { return nil }
+func (r *fakeUpsertRows) CommandTag() pgconn.CommandTag                { return pgconn.NewCommandTag("INSERT 0 0") }
+func (r *fakeUpsertRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeUpsertRows) Values() ([]any, error)                       { return nil, nil }
+func (r *fakeUpsertRows) RawValues() [][]byte                          { return nil }
+func (r *fakeUpsertRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeUpsertRows) Next() bool {
+	if r.pos >= len(r.rows) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+func (r *fakeUpsertRows) Scan(dest ...any) error {
+	row := r.rows[r.pos-1]
+	*dest[0].(*string) = row.deviceID
+	*dest[1].(*bool) = row.inserted
+	return nil
+}
+
+// fakeUpsertExecutor emulates ocsf_devices: device IDs in existing report
+// as updates, everything else as inserts.
+type fakeUpsertExecutor struct {
+	fakePgxExecutor
+	existing map[string]bool
+	queryErr error
+	queries  []string
+	args     [][]any
+}
+
+func (f *fakeUpsertExecutor) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	f.queries = append(f.queries, sql)
+	f.args = append(f.args, args)
+
+	if f.queryErr != nil {
+		return nil, f.queryErr
+	}
+
+	rows := &fakeUpsertRows{}
+	for i := 0; i < len(args); i += deviceUpsertColumns {
+		deviceID := args[i].(string)
+		rows.rows = append(rows.rows, upsertRow{deviceID: deviceID, inserted: !f.existing[deviceID]})
+		f.existing[deviceID] = true
+	}
+
+	return rows, nil
+}
+
+func testDeviceUpdate(id, ip string) *models.DeviceUpdate {
+	return &models.DeviceUpdate{
+		DeviceID:    id,
+		IP:          ip,
+		Partition:   "default",
+		Source:      models.DiscoverySourceArmis,
+		Timestamp:   time.Now().UTC(),
+		IsAvailable: true,
+		Metadata:    map[string]string{"integration_type": "armis"},
+	}
+}
+
+func TestUpsertDeviceUpdates_InsertsNewDevices(t *testing.T) {
+	exec := &fakeUpsertExecutor{existing: map[string]bool{}}
+	db := &DB{executor: exec}
+
+	results, err := db.UpsertDeviceUpdates(context.Background(), []*models.DeviceUpdate{
+		testDeviceUpdate("default:10.0.0.1", "10.0.0.1"),
+		testDeviceUpdate("default:10.0.0.2", "10.0.0.2"),
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	for _, result := range results {
+		assert.Equal(t, DeviceUpsertInserted, result.Action)
+		assert.NoError(t, result.Err)
+	}
+
+	require.Len(t, exec.queries, 1, "rows should be written with a single multi-row statement")
+	assert.Contains(t, exec.queries[0], "INSERT INTO platform.ocsf_devices AS ocsf_devices")
+	assert.Contains(t, exec.queries[0], "ON CONFLICT (uid) DO UPDATE")
+	assert.Len(t, exec.args[0], 2*deviceUpsertColumns)
+}
+
+func TestUpsertDeviceUpdates_UpdatesOnConflictAndCoalescesDuplicates(t *testing.T) {
+	exec := &fakeUpsertExecutor{existing: map[string]bool{"default:10.0.0.1": true}}
+	db := &DB{executor: exec}
+
+	first := testDeviceUpdate("default:10.0.0.1", "10.0.0.1")
+	second := testDeviceUpdate("default:10.0.0.1", "10.0.0.9")
+	second.Timestamp = first.Timestamp.Add(time.Minute)
+	second.Metadata = map[string]string{"armis_risk_level": "5"}
+
+	results, err := db.UpsertDeviceUpdates(context.Background(), []*models.DeviceUpdate{
+		first,
+		testDeviceUpdate("default:10.0.0.2", "10.0.0.2"),
+		second,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, DeviceUpsertUpdated, results[0].Action)
+	assert.Equal(t, DeviceUpsertInserted, results[1].Action)
+	assert.Equal(t, DeviceUpsertUpdated, results[2].Action)
+
+	// Duplicate device IDs are merged into a single row carrying the newest IP.
+	require.Len(t, exec.args[0], 2*deviceUpsertColumns)
+	assert.Equal(t, "10.0.0.9", exec.args[0][1])
+	assert.JSONEq(t, `{"integration_type":"armis","armis_risk_level":"5"}`, string(exec.args[0][9].([]byte)))
+}
+
+func TestUpsertDeviceUpdates_PartialBatchFailure(t *testing.T) {
+	exec := &fakeUpsertExecutor{existing: map[string]bool{}}
+	db := &DB{executor: exec}
+
+	wrongPartition := testDeviceUpdate("tenant-b:10.0.0.3", "10.0.0.3")
+
+	results, err := db.UpsertDeviceUpdates(context.Background(), []*models.DeviceUpdate{
+		testDeviceUpdate("default:10.0.0.1", "10.0.0.1"),
+		{IP: "10.0.0.2"},
+		wrongPartition,
+		nil,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, DeviceUpsertInserted, results[0].Action)
+	assert.ErrorIs(t, results[1].Err, ErrDeviceUpdateMissingID)
+	assert.ErrorIs(t, results[2].Err, ErrDeviceUpdatePartitionMismatch)
+	assert.ErrorIs(t, results[3].Err, ErrDeviceUpdateMissingID)
+	assert.Len(t, exec.args[0], deviceUpsertColumns)
+}
+
+func TestUpsertDeviceUpdates_StatementFailureFailsWholeBatch(t *testing.T) {
+	exec := &fakeUpsertExecutor{existing: map[string]bool{}, queryErr: errUpsertConstraint}
+	db := &DB{executor: exec}
+
+	results, err := db.UpsertDeviceUpdates(context.Background(), []*models.DeviceUpdate{
+		testDeviceUpdate("default:10.0.0.1", "10.0.0.1"),
+		testDeviceUpdate("default:10.0.0.2", "10.0.0.2"),
+	})
+	require.ErrorIs(t, err, errUpsertConstraint)
+
+	for _, result := range results {
+		assert.Equal(t, DeviceUpsertFailed, result.Action)
+		assert.ErrorIs(t, result.Err, errUpsertConstraint)
+	}
+}
+
+func TestBuildDeviceUpsertQuery_NumbersPlaceholders(t *testing.T) {
+	query := buildDeviceUpsertQuery(2)
+
+	assert.Contains(t, query, "($1,$2,$3,$4,$5,$6,ARRAY[$7]::text[],$8,$9,$9,$10::jsonb,$11,$11)")
+	assert.Contains(t, query, "($12,$13,$14,$15,$16,$17,ARRAY[$18]::text[],$19,$20,$20,$21::jsonb,$22,$22)")
+	assert.Equal(t, 1, strings.Count(query, "ON CONFLICT"))
+}
@@ -23,7 +23,10 @@ var (
 	ErrFailedOpenDB = errors.New("failed to open database")
 
 	// CNPG configuration helpers.
-	ErrCNPGConfigMissing   = errors.New("cnpg: missing configuration")
-	ErrCNPGLackingTLSFiles = errors.New("cnpg tls requires cert_file, key_file, and ca_file")
-	ErrCNPGTLSDisabled     = errors.New("cnpg tls configuration requires sslmode not be disable")
+	ErrCNPGConfigMissing     = errors.New("cnpg: missing configuration")
+	ErrCNPGLackingTLSFiles   = errors.New("cnpg tls requires cert_file, key_file, and ca_file")
+	ErrCNPGTLSDisabled       = errors.New("cnpg tls configuration requires sslmode not be disable")
+	ErrCNPGInvalidSearchPath = errors.New("cnpg: search_path entries must not be empty")
+	ErrCNPGInvalidSlowQuery  = errors.New("cnpg: slow_query_threshold must not be negative")
+	ErrCNPGInvalidStmtCache  = errors.New("cnpg: statement_cache_size must not be negative")
 )
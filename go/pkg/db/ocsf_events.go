@@ -12,6 +12,15 @@ import (
 
 const defaultOCSFEventsTable = "ocsf_events"
 
+// ocsfEventColumns are the ocsf_events columns in ocsfEventArgs order.
+var ocsfEventColumns = []string{
+	"id", "time", "class_uid", "category_uid", "type_uid", "activity_id", "activity_name",
+	"severity_id", "severity", "message", "status_id", "status", "status_code", "status_detail",
+	"metadata", "observables", "trace_id", "span_id", "actor", "device", "src_endpoint",
+	"dst_endpoint", "log_name", "log_provider", "log_level", "log_version", "unmapped",
+	"raw_data", "created_at",
+}
+
 func buildOCSFEventsInsertQuery(table string) string {
 	return fmt.Sprintf(`INSERT INTO %s (
 		id,
@@ -67,63 +76,98 @@ func (db *DB) InsertOCSFEvents(ctx context.Context, table string, rows []models.
 	now := time.Now().UTC()
 
 	for i := range rows {
-		row := rows[i]
-		if row.ID == "" {
+		if rows[i].ID == "" {
 			continue
 		}
 
-		ts := row.Time
-		if ts.IsZero() {
-			ts = now
-		}
+		batch.Queue(query, ocsfEventArgs(&rows[i], now)...)
+	}
 
-		createdAt := row.CreatedAt
-		if createdAt.IsZero() {
-			createdAt = now
-		}
+	if err := sendBatchExecAll(ctx, batch, db.conn().SendBatch, canonicalTable); err != nil {
+		return fmt.Errorf("failed to insert ocsf events: %w", err)
+	}
+
+	return nil
+}
 
-		severityID := row.SeverityID
-		if severityID == 0 {
-			severityID = 1
+// ocsfEventArgs returns the column values for row, defaulting unset times to now.
+func ocsfEventArgs(row *models.OCSFEventRow, now time.Time) []any {
+	ts := row.Time
+	if ts.IsZero() {
+		ts = now
+	}
+
+	createdAt := row.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+
+	severityID := row.SeverityID
+	if severityID == 0 {
+		severityID = 1
+	}
+
+	return []any{
+		row.ID,
+		ts,
+		row.ClassUID,
+		row.CategoryUID,
+		row.TypeUID,
+		row.ActivityID,
+		row.ActivityName,
+		severityID,
+		row.Severity,
+		row.Message,
+		row.StatusID,
+		row.Status,
+		row.StatusCode,
+		row.StatusDetail,
+		row.Metadata,
+		row.Observables,
+		row.TraceID,
+		row.SpanID,
+		row.Actor,
+		row.Device,
+		row.SrcEndpoint,
+		row.DstEndpoint,
+		row.LogName,
+		row.LogProvider,
+		row.LogLevel,
+		row.LogVersion,
+		row.Unmapped,
+		row.RawData,
+		createdAt,
+	}
+}
+
+// CopyOCSFEvents bulk loads OCSF event rows with COPY for backfills and
+// imports. Like InsertOCSFEvents it skips rows without an ID and rows whose
+// (id, time) already exists. It returns the number of rows written.
+func (db *DB) CopyOCSFEvents(ctx context.Context, table string, rows []models.OCSFEventRow) (int64, error) {
+	valid := make([]*models.OCSFEventRow, 0, len(rows))
+	for i := range rows {
+		if rows[i].ID != "" {
+			valid = append(valid, &rows[i])
 		}
+	}
 
-		batch.Queue(
-			query,
-			row.ID,
-			ts,
-			row.ClassUID,
-			row.CategoryUID,
-			row.TypeUID,
-			row.ActivityID,
-			row.ActivityName,
-			severityID,
-			row.Severity,
-			row.Message,
-			row.StatusID,
-			row.Status,
-			row.StatusCode,
-			row.StatusDetail,
-			row.Metadata,
-			row.Observables,
-			row.TraceID,
-			row.SpanID,
-			row.Actor,
-			row.Device,
-			row.SrcEndpoint,
-			row.DstEndpoint,
-			row.LogName,
-			row.LogProvider,
-			row.LogLevel,
-			row.LogVersion,
-			row.Unmapped,
-			row.RawData,
-			createdAt,
-		)
+	if len(valid) == 0 {
+		return 0, nil
 	}
 
-	if err := sendBatchExecAll(ctx, batch, db.conn().SendBatch, canonicalTable); err != nil {
-		return fmt.Errorf("failed to insert ocsf events: %w", err)
+	_, canonicalTable := sanitizeObservabilityTable(table, defaultOCSFEventsTable)
+	now := time.Now().UTC()
+
+	written, err := db.CopyRows(ctx, BulkLoad{
+		Table:           canonicalTable,
+		Columns:         ocsfEventColumns,
+		ConflictColumns: []string{"id", "time"},
+	}, pgx.CopyFromSlice(len(valid), func(i int) ([]any, error) {
+		return ocsfEventArgs(valid[i], now), nil
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy ocsf events: %w", err)
 	}
 
-	return nil
+	return written, nil
 }
@@ -14,6 +14,16 @@ import (
 
 const defaultOCSFNetworkActivityTable = "ocsf_network_activity"
 
+// ocsfNetworkActivityColumns are the ocsf_network_activity columns in
+// ocsfNetworkActivityArgs order.
+var ocsfNetworkActivityColumns = []string{
+	"time", "class_uid", "category_uid", "activity_id", "type_uid", "severity_id",
+	"start_time", "end_time", "src_endpoint_ip", "src_endpoint_port", "src_as_number",
+	"dst_endpoint_ip", "dst_endpoint_port", "dst_as_number", "protocol_num", "protocol_name",
+	"tcp_flags", "bytes_total", "packets_total", "bytes_in", "bytes_out", "sampler_address",
+	"ocsf_payload", "partition", "created_at",
+}
+
 func buildOCSFNetworkActivityInsertQuery(table string) string {
 	return fmt.Sprintf(`INSERT INTO %s (
 		time,
@@ -69,81 +79,7 @@ func (db *DB) InsertOCSFNetworkActivity(ctx context.Context, table string, rows
 	now := time.Now().UTC()
 
 	for i := range rows {
-		row := rows[i]
-
-		ts := row.Time
-		if ts.IsZero() {
-			ts = now
-		}
-
-		createdAt := row.CreatedAt
-		if createdAt.IsZero() {
-			createdAt = now
-		}
-
-		partition := strings.TrimSpace(row.Partition)
-		if partition == "" {
-			partition = "default"
-		}
-
-		classUID := row.ClassUID
-		if classUID == 0 {
-			classUID = 4001
-		}
-
-		categoryUID := row.CategoryUID
-		if categoryUID == 0 {
-			categoryUID = 4
-		}
-
-		activityID := row.ActivityID
-		if activityID == 0 {
-			activityID = 6
-		}
-
-		typeUID := row.TypeUID
-		if typeUID == 0 {
-			typeUID = 400106
-		}
-
-		severityID := row.SeverityID
-		if severityID == 0 {
-			severityID = 1
-		}
-
-		payload := row.OCSFPayload
-		if len(payload) == 0 {
-			payload = json.RawMessage("{}")
-		}
-
-		batch.Queue(
-			query,
-			ts,
-			classUID,
-			categoryUID,
-			activityID,
-			typeUID,
-			severityID,
-			row.StartTime,
-			row.EndTime,
-			row.SrcEndpointIP,
-			row.SrcEndpointPort,
-			row.SrcASNumber,
-			row.DstEndpointIP,
-			row.DstEndpointPort,
-			row.DstASNumber,
-			row.ProtocolNum,
-			row.ProtocolName,
-			row.TCPFlags,
-			row.BytesTotal,
-			row.PacketsTotal,
-			row.BytesIn,
-			row.BytesOut,
-			row.SamplerAddress,
-			payload,
-			partition,
-			createdAt,
-		)
+		batch.Queue(query, ocsfNetworkActivityArgs(&rows[i], now)...)
 	}
 
 	if err := sendBatchExecAll(ctx, batch, db.conn().SendBatch, canonicalTable); err != nil {
@@ -152,3 +88,103 @@ func (db *DB) InsertOCSFNetworkActivity(ctx context.Context, table string, rows
 
 	return nil
 }
+
+// ocsfNetworkActivityArgs returns the column values for row, filling in the
+// OCSF network activity defaults for unset fields.
+func ocsfNetworkActivityArgs(row *models.OCSFNetworkActivity, now time.Time) []any {
+	ts := row.Time
+	if ts.IsZero() {
+		ts = now
+	}
+
+	createdAt := row.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+
+	partition := strings.TrimSpace(row.Partition)
+	if partition == "" {
+		partition = "default"
+	}
+
+	classUID := row.ClassUID
+	if classUID == 0 {
+		classUID = 4001
+	}
+
+	categoryUID := row.CategoryUID
+	if categoryUID == 0 {
+		categoryUID = 4
+	}
+
+	activityID := row.ActivityID
+	if activityID == 0 {
+		activityID = 6
+	}
+
+	typeUID := row.TypeUID
+	if typeUID == 0 {
+		typeUID = 400106
+	}
+
+	severityID := row.SeverityID
+	if severityID == 0 {
+		severityID = 1
+	}
+
+	payload := row.OCSFPayload
+	if len(payload) == 0 {
+		payload = json.RawMessage("{}")
+	}
+
+	return []any{
+		ts,
+		classUID,
+		categoryUID,
+		activityID,
+		typeUID,
+		severityID,
+		row.StartTime,
+		row.EndTime,
+		row.SrcEndpointIP,
+		row.SrcEndpointPort,
+		row.SrcASNumber,
+		row.DstEndpointIP,
+		row.DstEndpointPort,
+		row.DstASNumber,
+		row.ProtocolNum,
+		row.ProtocolName,
+		row.TCPFlags,
+		row.BytesTotal,
+		row.PacketsTotal,
+		row.BytesIn,
+		row.BytesOut,
+		row.SamplerAddress,
+		payload,
+		partition,
+		createdAt,
+	}
+}
+
+// CopyOCSFNetworkActivity bulk loads OCSF network activity rows with COPY for
+// backfills and imports. It returns the number of rows written.
+func (db *DB) CopyOCSFNetworkActivity(ctx context.Context, table string, rows []models.OCSFNetworkActivity) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	_, canonicalTable := sanitizeObservabilityTable(table, defaultOCSFNetworkActivityTable)
+	now := time.Now().UTC()
+
+	written, err := db.CopyRows(ctx, BulkLoad{
+		Table:   canonicalTable,
+		Columns: ocsfNetworkActivityColumns,
+	}, pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+		return ocsfNetworkActivityArgs(&rows[i], now), nil
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy ocsf network activity: %w", err)
+	}
+
+	return written, nil
+}
@@ -0,0 +1,277 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+const (
+	slowQueryHistory       = 50
+	maxConcurrentExplains  = 2
+	explainTimeout         = 10 * time.Second
+	explainStatementPrefix = "EXPLAIN (FORMAT JSON) "
+)
+
+// SlowQuery records a statement that exceeded the slow-query threshold, or
+// any statement when plans are forced, and, when plan capture is enabled, its
+// EXPLAIN plan.
+type SlowQuery struct {
+	SQL       string          `json:"sql"`
+	Duration  time.Duration   `json:"duration"`
+	At        time.Time       `json:"at"`
+	Forced    bool            `json:"forced,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Plan      json.RawMessage `json:"plan,omitempty"`
+	PlanError string          `json:"plan_error,omitempty"`
+}
+
+type explainingKey struct{}
+
+type queryTraceKey struct{}
+
+type queryTrace struct {
+	sql   string
+	args  []any
+	start time.Time
+}
+
+type explainFunc func(ctx context.Context, sql string, args []any) (json.RawMessage, error)
+
+// queryPlanTracer is a pgx QueryTracer that logs slow statements and, when
+// enabled, captures their plans with EXPLAIN (FORMAT JSON). EXPLAIN runs on a
+// separate pooled connection after the statement finishes, without ANALYZE,
+// so the statement is planned but never executed a second time. With force
+// set, every statement is recorded and explained, for debugging.
+type queryPlanTracer struct {
+	threshold time.Duration
+	capture   bool
+	force     bool
+	log       logger.Logger
+	now       func() time.Time
+
+	mu      sync.Mutex
+	explain explainFunc
+	recent  []SlowQuery
+	wg      sync.WaitGroup
+	sem     chan struct{}
+}
+
+func newQueryPlanTracer(threshold time.Duration, capture, force bool) *queryPlanTracer {
+	return &queryPlanTracer{
+		threshold: threshold,
+		capture:   capture,
+		force:     force,
+		now:       time.Now,
+		sem:       make(chan struct{}, maxConcurrentExplains),
+	}
+}
+
+// attach wires the tracer to the pool its plans are captured on.
+func (t *queryPlanTracer) attach(pool interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}, log logger.Logger) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.log = log
+	t.explain = func(ctx context.Context, sql string, args []any) (json.RawMessage, error) {
+		var plan []byte
+		if err := pool.QueryRow(ctx, explainStatementPrefix+sql, args...).Scan(&plan); err != nil {
+			return nil, err
+		}
+
+		return plan, nil
+	}
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t *queryPlanTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if ctx.Value(explainingKey{}) != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{
+		sql:   data.SQL,
+		args:  data.Args,
+		start: t.now(),
+	})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (t *queryPlanTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+
+	elapsed := t.now().Sub(trace.start)
+
+	slow := t.threshold > 0 && elapsed >= t.threshold
+	if !slow && !t.force {
+		return
+	}
+
+	entry := SlowQuery{SQL: trace.sql, Duration: elapsed, At: trace.start, Forced: !slow}
+	if data.Err != nil {
+		entry.Error = data.Err.Error()
+	}
+
+	t.mu.Lock()
+	explain := t.explain
+	t.mu.Unlock()
+
+	if (!t.capture && !t.force) || explain == nil {
+		t.record(&entry)
+
+		return
+	}
+
+	if !explainable(trace.sql) {
+		entry.PlanError = "statement type is not explainable"
+		t.record(&entry)
+
+		return
+	}
+
+	select {
+	case t.sem <- struct{}{}:
+	default:
+		entry.PlanError = "plan capture skipped: too many plans in flight"
+		t.record(&entry)
+
+		return
+	}
+
+	t.wg.Add(1)
+
+	// The original connection is still busy finishing this call, so the plan
+	// is captured on another pooled connection once it has been released.
+	go func() {
+		defer t.wg.Done()
+		defer func() { <-t.sem }()
+
+		explainCtx, cancel := context.WithTimeout(
+			context.WithValue(context.WithoutCancel(ctx), explainingKey{}, true), explainTimeout)
+		defer cancel()
+
+		plan, err := explain(explainCtx, trace.sql, trace.args)
+		if err != nil {
+			entry.PlanError = err.Error()
+		} else {
+			entry.Plan = plan
+		}
+
+		t.record(&entry)
+	}()
+}
+
+func (t *queryPlanTracer) record(entry *SlowQuery) {
+	t.mu.Lock()
+
+	t.recent = append(t.recent, *entry)
+	if len(t.recent) > slowQueryHistory {
+		t.recent = t.recent[len(t.recent)-slowQueryHistory:]
+	}
+
+	log := t.log
+
+	t.mu.Unlock()
+
+	if log == nil {
+		return
+	}
+
+	event := log.Warn().
+		Str("sql", entry.SQL).
+		Dur("duration", entry.Duration)
+
+	if entry.Error != "" {
+		event = event.Str("error", entry.Error)
+	}
+
+	if len(entry.Plan) > 0 {
+		event = event.RawJSON("plan", entry.Plan)
+	} else if entry.PlanError != "" {
+		event = event.Str("plan_error", entry.PlanError)
+	}
+
+	if entry.Forced {
+		event.Msg("CNPG query plan")
+
+		return
+	}
+
+	event.Msg("Slow CNPG query")
+}
+
+// slowQueries returns the most recent slow queries, oldest first.
+func (t *queryPlanTracer) slowQueries() []SlowQuery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]SlowQuery(nil), t.recent...)
+}
+
+// wait blocks until in-flight plan captures finish.
+func (t *queryPlanTracer) wait() {
+	t.wg.Wait()
+}
+
+// explainable reports whether sql is a single DML/read statement EXPLAIN
+// accepts.
+func explainable(sql string) bool {
+	statement := strings.TrimSuffix(strings.TrimSpace(sql), ";")
+	if strings.Contains(statement, ";") {
+		return false
+	}
+
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH", "INSERT", "UPDATE", "DELETE", "MERGE", "VALUES", "TABLE":
+		return true
+	default:
+		return false
+	}
+}
+
+// SlowQueries returns the slow queries recorded on this database's pool,
+// oldest first, with captured plans when plan capture is enabled.
+func (db *DB) SlowQueries() []SlowQuery {
+	if db == nil || db.pgPool == nil {
+		return nil
+	}
+
+	tracer, ok := findTracer[*queryPlanTracer](db.pgPool.Config().ConnConfig.Tracer)
+	if !ok {
+		return nil
+	}
+
+	return tracer.slowQueries()
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+func runTracedQuery(t *testing.T, tracer *queryPlanTracer, ctx context.Context, sql string, took time.Duration) {
+	t.Helper()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracer.now = func() time.Time { return now }
+
+	ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql, Args: []any{"dev-1"}})
+	now = now.Add(took)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	tracer.wait()
+}
+
+func TestQueryPlanTracerCapturesPlanForSlowQuery(t *testing.T) {
+	t.Parallel()
+
+	tracer := newQueryPlanTracer(time.Second, true, false)
+
+	var explained []string
+
+	tracer.explain = func(ctx context.Context, sql string, args []any) (json.RawMessage, error) {
+		assert.NotNil(t, ctx.Value(explainingKey{}), "EXPLAIN must not be traced itself")
+		assert.Equal(t, []any{"dev-1"}, args)
+
+		explained = append(explained, sql)
+
+		return json.RawMessage(`[{"Plan":{"Node Type":"Seq Scan"}}]`), nil
+	}
+
+	const slowSQL = "SELECT * FROM ocsf_devices WHERE uid = $1"
+
+	runTracedQuery(t, tracer, context.Background(), "SELECT 1", 10*time.Millisecond)
+	runTracedQuery(t, tracer, context.Background(), slowSQL, 2*time.Second)
+
+	queries := tracer.slowQueries()
+	require.Len(t, queries, 1)
+	assert.Equal(t, slowSQL, queries[0].SQL)
+	assert.Equal(t, 2*time.Second, queries[0].Duration)
+	assert.JSONEq(t, `[{"Plan":{"Node Type":"Seq Scan"}}]`, string(queries[0].Plan))
+	assert.Equal(t, []string{slowSQL}, explained)
+}
+
+func TestQueryPlanTracerLogsWithoutPlanWhenCaptureDisabled(t *testing.T) {
+	t.Parallel()
+
+	tracer := newQueryPlanTracer(time.Second, false, false)
+	tracer.explain = func(context.Context, string, []any) (json.RawMessage, error) {
+		t.Fatal("EXPLAIN should not run when plan capture is disabled")
+
+		return nil, nil
+	}
+
+	runTracedQuery(t, tracer, context.Background(), "SELECT 1", 2*time.Second)
+
+	queries := tracer.slowQueries()
+	require.Len(t, queries, 1)
+	assert.Empty(t, queries[0].Plan)
+	assert.Empty(t, queries[0].PlanError)
+}
+
+func TestQueryPlanTracerSkipsUnexplainableStatements(t *testing.T) {
+	t.Parallel()
+
+	tracer := newQueryPlanTracer(time.Second, true, false)
+	tracer.explain = func(context.Context, string, []any) (json.RawMessage, error) {
+		t.Fatal("EXPLAIN should not run for DDL")
+
+		return nil, nil
+	}
+
+	runTracedQuery(t, tracer, context.Background(), "CREATE INDEX i ON t (c)", 2*time.Second)
+
+	queries := tracer.slowQueries()
+	require.Len(t, queries, 1)
+	assert.Empty(t, queries[0].Plan)
+	assert.NotEmpty(t, queries[0].PlanError)
+}
+
+func TestQueryPlanTracerForceCapturesEveryQuery(t *testing.T) {
+	t.Parallel()
+
+	tracer := newQueryPlanTracer(time.Second, false, true)
+	tracer.explain = func(context.Context, string, []any) (json.RawMessage, error) {
+		return json.RawMessage(`[{"Plan":{"Node Type":"Index Scan"}}]`), nil
+	}
+
+	runTracedQuery(t, tracer, context.Background(), "SELECT 1", time.Millisecond)
+	runTracedQuery(t, tracer, context.Background(), "SELECT 2", 2*time.Second)
+
+	queries := tracer.slowQueries()
+	require.Len(t, queries, 2)
+	assert.True(t, queries[0].Forced)
+	assert.NotEmpty(t, queries[0].Plan)
+	assert.False(t, queries[1].Forced, "queries over the threshold are still reported as slow")
+	assert.NotEmpty(t, queries[1].Plan)
+}
+
+func TestBuildCNPGPoolConfigInstallsQueryPlanTracer(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := buildCNPGPoolConfig(&models.CNPGDatabase{Host: "cnpg-rw", Port: 5432, Database: "serviceradar"})
+	require.NoError(t, err)
+
+	_, ok := findTracer[*queryPlanTracer](cfg.ConnConfig.Tracer)
+	assert.False(t, ok)
+
+	cfg, err = buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host: "cnpg-rw", Port: 5432, Database: "serviceradar",
+		CaptureQueryPlans: true,
+	})
+	require.NoError(t, err)
+
+	_, ok = findTracer[*queryPlanTracer](cfg.ConnConfig.Tracer)
+	assert.False(t, ok, "plans are only captured for queries over the slow threshold")
+
+	cfg, err = buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host: "cnpg-rw", Port: 5432, Database: "serviceradar",
+		ForceQueryPlans: true,
+	})
+	require.NoError(t, err)
+
+	tracer, ok := findTracer[*queryPlanTracer](cfg.ConnConfig.Tracer)
+	require.True(t, ok, "forced plans install the tracer without a slow threshold")
+	assert.True(t, tracer.force)
+
+	cfg, err = buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host: "cnpg-rw", Port: 5432, Database: "serviceradar",
+		SlowQueryThreshold: models.Duration(500 * time.Millisecond),
+		CaptureQueryPlans:  true,
+	})
+	require.NoError(t, err)
+
+	tracer, ok = findTracer[*queryPlanTracer](cfg.ConnConfig.Tracer)
+	require.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, tracer.threshold)
+	assert.True(t, tracer.capture)
+
+	_, err = buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host: "cnpg-rw", Port: 5432, Database: "serviceradar",
+		SlowQueryThreshold: models.Duration(-time.Second),
+	})
+	require.ErrorIs(t, err, ErrCNPGInvalidSlowQuery)
+}
@@ -0,0 +1,624 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package db
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+const (
+	spillWALFile          = "writes.wal"
+	spillQuarantineFile   = "writes.wal.corrupt"
+	spillRecordHeaderSize = 8 // uint32 length + uint32 CRC32
+
+	defaultSpillMaxBytes       = 256 << 20
+	defaultSpillReplayInterval = 5 * time.Second
+)
+
+var (
+	// ErrSpillBufferFull is returned when a write is shed because the on-disk
+	// buffer has reached its size limit.
+	ErrSpillBufferFull = errors.New("write spill buffer full")
+
+	errSpillDirRequired   = errors.New("write spill buffer requires a dir")
+	errSpillKindRequired  = errors.New("write spill kind is required")
+	errSpillKindExists    = errors.New("write spill kind already registered")
+	errSpillKindUnknown   = errors.New("write spill kind not registered")
+	errSpillCorruptRecord = errors.New("corrupt write spill record")
+)
+
+// SpillConfig configures the write buffer that spills inserts to a local
+// write-ahead log while CNPG is unreachable.
+type SpillConfig struct {
+	Enabled        bool            `json:"enabled"`
+	Dir            string          `json:"dir"`
+	MaxBytes       int64           `json:"max_bytes,omitempty"`       // defaults to 256 MiB
+	ReplayInterval models.Duration `json:"replay_interval,omitempty"` // defaults to 5s
+}
+
+// SpillStats reports the buffer's backlog and lifetime counters. Shed counts
+// writes dropped because the buffer was full.
+type SpillStats struct {
+	PendingRecords int64  `json:"pending_records"`
+	PendingBytes   int64  `json:"pending_bytes"`
+	Spilled        uint64 `json:"spilled"`
+	Replayed       uint64 `json:"replayed"`
+	Shed           uint64 `json:"shed"`
+	Discarded      uint64 `json:"discarded"`
+}
+
+type spillRecord struct {
+	Kind    string          `json:"kind"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type spillReplayFunc func(ctx context.Context, payload json.RawMessage) error
+
+// SpillBuffer orders writes through a local write-ahead log. While the
+// database accepts writes they go straight through; once a write fails
+// because the database is unreachable it, and every write after it, is
+// appended to the log until a replay drains the log in order. Replay is
+// at-least-once: a crash mid-replay re-applies the records not yet trimmed,
+// so spilled writes should be idempotent (ON CONFLICT DO NOTHING).
+//
+// mu guards the log file and its counters only; database writes, direct or
+// replayed, run without it. replayMu serializes replays.
+type SpillBuffer struct {
+	dir            string
+	maxBytes       int64
+	replayInterval time.Duration
+	log            logger.Logger
+	unavailable    func(error) bool
+
+	replayMu sync.Mutex
+	mu       sync.Mutex
+	kinds    map[string]spillReplayFunc
+	file     *os.File
+	pending  int64
+	size     int64
+	spilled  atomic.Uint64
+	replayed atomic.Uint64
+	shed     atomic.Uint64
+	discard  atomic.Uint64
+}
+
+// NewSpillBuffer opens (or recovers) the write-ahead log in cfg.Dir. A
+// partially written trailing record left by a crash is truncated.
+func NewSpillBuffer(cfg *SpillConfig, log logger.Logger) (*SpillBuffer, error) {
+	if cfg == nil || cfg.Dir == "" {
+		return nil, errSpillDirRequired
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create write spill dir: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(cfg.Dir, spillWALFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open write spill log: %w", err)
+	}
+
+	b := &SpillBuffer{
+		dir:            cfg.Dir,
+		maxBytes:       cfg.MaxBytes,
+		replayInterval: time.Duration(cfg.ReplayInterval),
+		log:            log,
+		unavailable:    IsUnavailable,
+		kinds:          make(map[string]spillReplayFunc),
+		file:           file,
+	}
+
+	if b.maxBytes <= 0 {
+		b.maxBytes = defaultSpillMaxBytes
+	}
+
+	if b.replayInterval <= 0 {
+		b.replayInterval = defaultSpillReplayInterval
+	}
+
+	if err := b.recover(); err != nil {
+		_ = file.Close()
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// recover counts the records in the log and trims a torn tail. Records that
+// are framed but corrupt are kept for Replay to quarantine.
+func (b *SpillBuffer) recover() error {
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek write spill log: %w", err)
+	}
+
+	info, err := b.file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat write spill log: %w", err)
+	}
+
+	reader := bufio.NewReader(b.file)
+
+	var good int64
+
+	for {
+		n, _, err := readSpillRecord(reader, info.Size()-good)
+		if err != nil && !errors.Is(err, errSpillCorruptRecord) {
+			break
+		}
+
+		good += n
+		b.pending++
+	}
+
+	if err := b.file.Truncate(good); err != nil {
+		return fmt.Errorf("truncate write spill log: %w", err)
+	}
+
+	if _, err := b.file.Seek(good, io.SeekStart); err != nil {
+		return fmt.Errorf("seek write spill log: %w", err)
+	}
+
+	b.size = good
+
+	if b.pending > 0 && b.log != nil {
+		b.log.Warn().
+			Int64("pending_records", b.pending).
+			Int64("pending_bytes", b.size).
+			Msg("Recovered spilled database writes awaiting replay")
+	}
+
+	return nil
+}
+
+// SpillWriter writes values of one kind through a SpillBuffer.
+type SpillWriter[T any] struct {
+	buffer *SpillBuffer
+	kind   string
+	write  func(ctx context.Context, value T) error
+}
+
+// RegisterSpillWriter registers write under kind. Spilled values are stored
+// as JSON, so T must round-trip through encoding/json, and kind must stay
+// stable across restarts for recovered records to replay.
+func RegisterSpillWriter[T any](
+	b *SpillBuffer, kind string, write func(ctx context.Context, value T) error,
+) (*SpillWriter[T], error) {
+	if kind == "" {
+		return nil, errSpillKindRequired
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.kinds[kind]; exists {
+		return nil, fmt.Errorf("%w: %s", errSpillKindExists, kind)
+	}
+
+	b.kinds[kind] = func(ctx context.Context, payload json.RawMessage) error {
+		var value T
+		if err := json.Unmarshal(payload, &value); err != nil {
+			return fmt.Errorf("decode spilled %s write: %w", kind, err)
+		}
+
+		return write(ctx, value)
+	}
+
+	return &SpillWriter[T]{buffer: b, kind: kind, write: write}, nil
+}
+
+// Write writes value directly unless earlier writes are still spilled, in
+// which case it is appended behind them to preserve order. A write that
+// fails because the database is unreachable is spilled and reported as
+// accepted; any other error is returned unchanged. Concurrent writes are
+// not ordered against each other.
+func (w *SpillWriter[T]) Write(ctx context.Context, value T) error {
+	b := w.buffer
+
+	b.mu.Lock()
+	direct := b.pending == 0
+	b.mu.Unlock()
+
+	if direct {
+		err := w.write(ctx, value)
+		if err == nil || !b.unavailable(err) {
+			return err
+		}
+
+		if b.log != nil {
+			b.log.Warn().Err(err).Str("kind", w.kind).Msg("Database unreachable; spilling writes to disk")
+		}
+	}
+
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encode %s write for spill: %w", w.kind, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.appendLocked(&spillRecord{Kind: w.kind, Payload: payload})
+}
+
+func (b *SpillBuffer) appendLocked(record *spillRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode write spill record: %w", err)
+	}
+
+	frame := make([]byte, spillRecordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(data))) //nolint:gosec // bounded by maxBytes
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(data))
+	copy(frame[spillRecordHeaderSize:], data)
+
+	if b.size+int64(len(frame)) > b.maxBytes {
+		shed := b.shed.Add(1)
+
+		if b.log != nil {
+			b.log.Warn().
+				Str("kind", record.Kind).
+				Int64("pending_bytes", b.size).
+				Int64("max_bytes", b.maxBytes).
+				Uint64("shed_total", shed).
+				Msg("Write spill buffer full; shedding write")
+		}
+
+		return ErrSpillBufferFull
+	}
+
+	if _, err := b.file.Write(frame); err != nil {
+		return fmt.Errorf("append write spill record: %w", err)
+	}
+
+	if err := b.file.Sync(); err != nil {
+		return fmt.Errorf("sync write spill log: %w", err)
+	}
+
+	b.size += int64(len(frame))
+	b.pending++
+	b.spilled.Add(1)
+
+	return nil
+}
+
+// Replay writes spilled records in order. It stops at the first record the
+// database is still unreachable for and keeps the rest for the next replay.
+// Records failing for any other reason, or of an unregistered kind, are
+// discarded so one bad record cannot wedge the log; records that fail their
+// checksum are moved to a quarantine file next to the log. Writes spilled
+// while a replay runs are left for the next one.
+func (b *SpillBuffer) Replay(ctx context.Context) error {
+	b.replayMu.Lock()
+	defer b.replayMu.Unlock()
+
+	b.mu.Lock()
+	limit, pending := b.size, b.pending
+	kinds := maps.Clone(b.kinds)
+	b.mu.Unlock()
+
+	if pending == 0 {
+		return nil
+	}
+
+	// Only replays trim the log, so a separate handle reads a stable prefix
+	// while writers keep appending behind it.
+	file, err := os.Open(filepath.Join(b.dir, spillWALFile))
+	if err != nil {
+		return fmt.Errorf("open write spill log: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := bufio.NewReader(io.LimitReader(file, limit))
+
+	var (
+		consumed int64
+		replayed int64
+		stopErr  error
+	)
+
+	for consumed < limit {
+		n, record, err := readSpillRecord(reader, limit-consumed)
+		if err != nil {
+			if !errors.Is(err, errSpillCorruptRecord) {
+				// The framing itself is broken, so nothing after this point
+				// can be trusted to line up with record boundaries.
+				n = limit - consumed
+			}
+
+			b.quarantine(file, consumed, n, err)
+
+			consumed += n
+			replayed++
+
+			if !errors.Is(err, errSpillCorruptRecord) {
+				break
+			}
+
+			continue
+		}
+
+		write, ok := kinds[record.Kind]
+		if !ok {
+			b.discardRecord(record, errSpillKindUnknown)
+		} else if err := write(ctx, record.Payload); err != nil {
+			if b.unavailable(err) || ctx.Err() != nil {
+				stopErr = err
+
+				break
+			}
+
+			b.discardRecord(record, err)
+		} else {
+			b.replayed.Add(1)
+		}
+
+		consumed += n
+		replayed++
+	}
+
+	if consumed == limit {
+		// Authoritative even when a quarantined tail held several records.
+		replayed = pending
+	}
+
+	b.mu.Lock()
+	err = b.trimLocked(consumed, replayed)
+	remaining := b.pending
+	b.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if b.log != nil && replayed > 0 {
+		b.log.Info().
+			Int64("replayed", replayed).
+			Int64("pending_records", remaining).
+			Msg("Replayed spilled database writes")
+	}
+
+	return stopErr
+}
+
+// quarantine appends the n bytes at offset off of the log to the quarantine
+// file so an unreadable record can be inspected instead of silently lost.
+func (b *SpillBuffer) quarantine(file *os.File, off, n int64, cause error) {
+	b.discard.Add(1)
+
+	if b.log != nil {
+		b.log.Error().
+			Err(cause).
+			Int64("offset", off).
+			Int64("bytes", n).
+			Msg("Quarantining corrupt spilled write")
+	}
+
+	out, err := os.OpenFile(filepath.Join(b.dir, spillQuarantineFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err == nil {
+		_, err = io.Copy(out, io.NewSectionReader(file, off, n))
+
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if err != nil && b.log != nil {
+		b.log.Error().Err(err).Msg("Failed to write spill quarantine file")
+	}
+}
+
+func (b *SpillBuffer) discardRecord(record *spillRecord, err error) {
+	b.discard.Add(1)
+
+	if b.log != nil {
+		b.log.Error().Err(err).Str("kind", record.Kind).Msg("Discarding spilled write that cannot be replayed")
+	}
+}
+
+// trimLocked drops the first consumed bytes of the log. The remainder is
+// rewritten to a temporary file and renamed over the log so a crash leaves
+// either the old or the new log intact.
+func (b *SpillBuffer) trimLocked(consumed, records int64) error {
+	if consumed == 0 {
+		return nil
+	}
+
+	if consumed >= b.size {
+		if err := b.file.Truncate(0); err != nil {
+			return fmt.Errorf("truncate write spill log: %w", err)
+		}
+
+		if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("seek write spill log: %w", err)
+		}
+
+		b.size = 0
+		b.pending = 0
+
+		return nil
+	}
+
+	path := filepath.Join(b.dir, spillWALFile)
+	tmpPath := path + ".tmp"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("create write spill log: %w", err)
+	}
+
+	if _, err := b.file.Seek(consumed, io.SeekStart); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("seek write spill log: %w", err)
+	}
+
+	if _, err := io.Copy(tmp, b.file); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("rewrite write spill log: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("sync write spill log: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("replace write spill log: %w", err)
+	}
+
+	_ = b.file.Close()
+	b.file = tmp
+	b.size -= consumed
+	b.pending -= records
+
+	return nil
+}
+
+// Run replays the log every replay interval until ctx is done.
+func (b *SpillBuffer) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.replayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.Replay(ctx); err != nil && b.log != nil && ctx.Err() == nil {
+				b.log.Debug().Err(err).Msg("Database still unreachable; spilled writes kept for replay")
+			}
+		}
+	}
+}
+
+// Stats returns the current backlog and counters.
+func (b *SpillBuffer) Stats() SpillStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return SpillStats{
+		PendingRecords: b.pending,
+		PendingBytes:   b.size,
+		Spilled:        b.spilled.Load(),
+		Replayed:       b.replayed.Load(),
+		Shed:           b.shed.Load(),
+		Discarded:      b.discard.Load(),
+	}
+}
+
+// Close closes the log file; spilled records remain on disk for the next
+// NewSpillBuffer.
+func (b *SpillBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.file.Close()
+}
+
+// readSpillRecord reads the next record from at most remaining bytes. A
+// record whose frame is intact but whose checksum or body is bad is reported
+// as errSpillCorruptRecord along with its frame size, so callers can skip it.
+func readSpillRecord(reader *bufio.Reader, remaining int64) (int64, *spillRecord, error) {
+	var header [spillRecordHeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	n := int64(spillRecordHeaderSize) + int64(length)
+
+	if n > remaining {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return 0, nil, err
+	}
+
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		return n, nil, errSpillCorruptRecord
+	}
+
+	var record spillRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return n, nil, fmt.Errorf("%w: %w", errSpillCorruptRecord, err)
+	}
+
+	return n, &record, nil
+}
+
+// IsUnavailable reports whether err means the database could not be reached,
+// as opposed to rejecting the statement: connection failures, timeouts,
+// dropped connections and server shutdown or startup states.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case len(pgErr.Code) >= 2 && pgErr.Code[:2] == "08": // connection_exception
+			return true
+		case pgErr.Code == "57P01", pgErr.Code == "57P02", pgErr.Code == "57P03", pgErr.Code == "53300":
+			// admin_shutdown, crash_shutdown, cannot_connect_now, too_many_connections
+			return true
+		default:
+			return false
+		}
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return pgconn.Timeout(err) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
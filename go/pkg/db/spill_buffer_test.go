@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+var errTestConstraint = errors.New("duplicate key")
+
+type fakeSpillTarget struct {
+	down    bool
+	written []int
+}
+
+func (f *fakeSpillTarget) write(_ context.Context, value int) error {
+	if f.down {
+		return &pgconn.ConnectError{}
+	}
+
+	if value < 0 {
+		return errTestConstraint
+	}
+
+	f.written = append(f.written, value)
+
+	return nil
+}
+
+func newTestSpill(t *testing.T, dir string, maxBytes int64) (*SpillBuffer, *SpillWriter[int], *fakeSpillTarget) {
+	t.Helper()
+
+	buffer, err := NewSpillBuffer(&SpillConfig{Enabled: true, Dir: dir, MaxBytes: maxBytes}, logger.NewTestLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = buffer.Close() })
+
+	target := &fakeSpillTarget{}
+
+	writer, err := RegisterSpillWriter(buffer, "numbers", target.write)
+	require.NoError(t, err)
+
+	return buffer, writer, target
+}
+
+func TestSpillBufferSpillsDuringOutageAndReplaysInOrder(t *testing.T) {
+	buffer, writer, target := newTestSpill(t, t.TempDir(), 0)
+	ctx := context.Background()
+
+	require.NoError(t, writer.Write(ctx, 1))
+
+	target.down = true
+
+	require.NoError(t, writer.Write(ctx, 2))
+	require.NoError(t, writer.Write(ctx, 3))
+
+	// The database is back, but 4 must queue behind the spilled writes.
+	target.down = false
+
+	require.NoError(t, writer.Write(ctx, 4))
+	assert.Equal(t, []int{1}, target.written)
+	assert.Equal(t, int64(3), buffer.Stats().PendingRecords)
+
+	require.NoError(t, buffer.Replay(ctx))
+	assert.Equal(t, []int{1, 2, 3, 4}, target.written)
+
+	stats := buffer.Stats()
+	assert.Zero(t, stats.PendingRecords)
+	assert.Zero(t, stats.PendingBytes)
+	assert.Equal(t, uint64(3), stats.Spilled)
+	assert.Equal(t, uint64(3), stats.Replayed)
+
+	require.NoError(t, writer.Write(ctx, 5))
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, target.written, "writes go direct once drained")
+}
+
+func TestSpillBufferReplayStopsWhileStillDown(t *testing.T) {
+	buffer, writer, target := newTestSpill(t, t.TempDir(), 0)
+	ctx := context.Background()
+
+	target.down = true
+
+	require.NoError(t, writer.Write(ctx, 1))
+	require.NoError(t, writer.Write(ctx, 2))
+
+	err := buffer.Replay(ctx)
+	require.Error(t, err)
+	assert.True(t, IsUnavailable(err))
+	assert.Equal(t, int64(2), buffer.Stats().PendingRecords)
+
+	target.down = false
+
+	require.NoError(t, buffer.Replay(ctx))
+	assert.Equal(t, []int{1, 2}, target.written)
+}
+
+func TestSpillBufferReturnsNonOutageErrors(t *testing.T) {
+	buffer, writer, _ := newTestSpill(t, t.TempDir(), 0)
+
+	err := writer.Write(context.Background(), -1)
+	require.ErrorIs(t, err, errTestConstraint)
+	assert.Zero(t, buffer.Stats().Spilled)
+}
+
+func TestSpillBufferShedsWhenFull(t *testing.T) {
+	buffer, writer, target := newTestSpill(t, t.TempDir(), 64)
+	ctx := context.Background()
+
+	target.down = true
+
+	require.NoError(t, writer.Write(ctx, 1))
+	require.ErrorIs(t, writer.Write(ctx, 2), ErrSpillBufferFull)
+
+	stats := buffer.Stats()
+	assert.Equal(t, int64(1), stats.PendingRecords)
+	assert.Equal(t, uint64(1), stats.Shed)
+}
+
+func TestSpillBufferRecoversLogAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	buffer, writer, target := newTestSpill(t, dir, 0)
+	target.down = true
+
+	require.NoError(t, writer.Write(ctx, 7))
+	require.NoError(t, writer.Write(ctx, 8))
+	require.NoError(t, buffer.Close())
+
+	// Simulate a torn write from a crash mid-append.
+	file, err := os.OpenFile(filepath.Join(dir, spillWALFile), os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = file.Write([]byte{0, 0, 0, 42, 1})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	restarted, _, replayTarget := newTestSpill(t, dir, 0)
+	assert.Equal(t, int64(2), restarted.Stats().PendingRecords)
+
+	require.NoError(t, restarted.Replay(ctx))
+	assert.Equal(t, []int{7, 8}, replayTarget.written)
+}
+
+func TestSpillBufferQuarantinesCorruptRecords(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	buffer, writer, target := newTestSpill(t, dir, 0)
+	target.down = true
+
+	require.NoError(t, writer.Write(ctx, 1))
+	require.NoError(t, writer.Write(ctx, 2))
+	require.NoError(t, writer.Write(ctx, 3))
+
+	// Flip a byte inside the second record's body; its checksum no longer
+	// matches but its frame still lines up.
+	path := filepath.Join(dir, spillWALFile)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	frameSize := len(data) / 3
+	data[frameSize+spillRecordHeaderSize+2] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	target.down = false
+
+	require.NoError(t, buffer.Replay(ctx))
+	assert.Equal(t, []int{1, 3}, target.written)
+
+	stats := buffer.Stats()
+	assert.Zero(t, stats.PendingRecords)
+	assert.Zero(t, stats.PendingBytes)
+	assert.Equal(t, uint64(1), stats.Discarded)
+
+	quarantined, err := os.ReadFile(filepath.Join(dir, spillQuarantineFile))
+	require.NoError(t, err)
+	assert.Equal(t, data[frameSize:2*frameSize], quarantined)
+}
+
+func TestSpillBufferReplaysWithoutHoldingTheLock(t *testing.T) {
+	buffer, writer, target := newTestSpill(t, t.TempDir(), 0)
+	ctx := context.Background()
+
+	target.down = true
+
+	require.NoError(t, writer.Write(ctx, 1))
+
+	// A replayed write that spills again must not deadlock against Replay.
+	reentrant, err := RegisterSpillWriter(buffer, "reentrant", func(ctx context.Context, value int) error {
+		if value == 0 {
+			return writer.Write(ctx, 2)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, reentrant.Write(ctx, 0))
+	assert.Equal(t, int64(2), buffer.Stats().PendingRecords)
+
+	target.down = false
+
+	require.NoError(t, buffer.Replay(ctx))
+	assert.Equal(t, []int{1}, target.written)
+	assert.Equal(t, int64(1), buffer.Stats().PendingRecords, "write spilled during replay waits for the next one")
+
+	require.NoError(t, buffer.Replay(ctx))
+	assert.Equal(t, []int{1, 2}, target.written)
+	assert.Zero(t, buffer.Stats().PendingRecords)
+}
+
+func TestIsUnavailable(t *testing.T) {
+	assert.True(t, IsUnavailable(&pgconn.PgError{Code: "08006"}))
+	assert.True(t, IsUnavailable(&pgconn.PgError{Code: "57P01"}))
+	assert.False(t, IsUnavailable(&pgconn.PgError{Code: "23505"}))
+	assert.False(t, IsUnavailable(errTestConstraint))
+	assert.False(t, IsUnavailable(nil))
+}
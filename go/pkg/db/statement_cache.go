@@ -0,0 +1,204 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+const (
+	envCNPGStatementCache     = "CNPG_STATEMENT_CACHE"
+	envCNPGStatementCacheSize = "CNPG_STATEMENT_CACHE_SIZE"
+
+	defaultStatementCacheSize = 512
+
+	sqlStateFeatureNotSupported   = "0A000"
+	sqlStateInvalidStatementName  = "26000"
+	cachedPlanResultTypeChangeMsg = "cached plan must not change result type"
+)
+
+// statementCache manages the prepared-statement caches of a pool's
+// connections.
+//
+// pgx keeps a per-connection LRU cache of prepared statements keyed by the
+// parameterized SQL, so hot SRQL queries are parsed and planned once per
+// connection instead of on every call. Postgres re-plans a cached statement on
+// its own when the tables it reads change, except when the change alters the
+// statement's result columns (or the statement was dropped server side). pgx
+// then evicts that one statement on the connection that failed, but every
+// other connection still holds the stale statement. The cache watches for
+// those errors as a pgx QueryTracer and bumps a generation counter; each
+// connection compares its generation when it is next acquired and, if it is
+// behind, deallocates its statements before running anything.
+type statementCache struct {
+	generation atomic.Uint64
+
+	mu    sync.Mutex
+	conns map[*pgx.Conn]uint64
+	log   logger.Logger
+}
+
+func newStatementCache() *statementCache {
+	return &statementCache{conns: make(map[*pgx.Conn]uint64)}
+}
+
+// attach sets the logger invalidations are reported to.
+func (c *statementCache) attach(log logger.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.log = log
+}
+
+// invalidate marks every connection's cached statements stale.
+func (c *statementCache) invalidate(reason string) {
+	generation := c.generation.Add(1)
+
+	c.mu.Lock()
+	log := c.log
+	c.mu.Unlock()
+
+	if log != nil {
+		log.Info().
+			Str("reason", reason).
+			Uint64("generation", generation).
+			Msg("CNPG prepared statement caches invalidated")
+	}
+}
+
+// prepareConn is installed as the pool's PrepareConn hook. A connection whose
+// statements predate the last invalidation deallocates them; if that fails
+// the connection is discarded and the query retried on another one.
+func (c *statementCache) prepareConn(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	generation := c.generation.Load()
+
+	c.mu.Lock()
+	seen, known := c.conns[conn]
+	c.conns[conn] = generation
+	c.mu.Unlock()
+
+	if !known || seen == generation {
+		return true, nil
+	}
+
+	if err := conn.DeallocateAll(ctx); err != nil {
+		c.forget(conn)
+
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// forget drops a closed connection; it is installed as the BeforeClose hook.
+func (c *statementCache) forget(conn *pgx.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.conns, conn)
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (c *statementCache) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (c *statementCache) TraceQueryEnd(_ context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	if isStaleStatementError(data.Err) {
+		c.invalidate(data.Err.Error())
+	}
+}
+
+// isStaleStatementError reports whether err means a cached prepared statement
+// no longer matches the schema or no longer exists on the server.
+func isStaleStatementError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	switch pgErr.Code {
+	case sqlStateInvalidStatementName:
+		return true
+	case sqlStateFeatureNotSupported:
+		return strings.Contains(pgErr.Message, cachedPlanResultTypeChangeMsg)
+	default:
+		return false
+	}
+}
+
+// applyStatementCache configures how the pool prepares statements and returns
+// the cache manager, or nil when caching is disabled. Disabled pools run every
+// query with the extended protocol but without a named prepared statement, so
+// they also work behind a transaction-mode connection pooler.
+func applyStatementCache(poolConfig *pgxpool.Config, cnpg *models.CNPGDatabase) (*statementCache, error) {
+	if cnpg.StatementCacheSize < 0 {
+		return nil, ErrCNPGInvalidStmtCache
+	}
+
+	if cnpg.NoStatementCache {
+		poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+		poolConfig.ConnConfig.StatementCacheCapacity = 0
+
+		return nil, nil
+	}
+
+	size := cnpg.StatementCacheSize
+	if size == 0 {
+		size = defaultStatementCacheSize
+	}
+
+	poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	poolConfig.ConnConfig.StatementCacheCapacity = size
+
+	cache := newStatementCache()
+	poolConfig.PrepareConn = cache.prepareConn
+	poolConfig.BeforeClose = cache.forget
+
+	return cache, nil
+}
+
+// applyStatementCacheEnv overrides statement caching from
+// CNPG_STATEMENT_CACHE and CNPG_STATEMENT_CACHE_SIZE.
+func applyStatementCacheEnv(cfg *models.CNPGDatabase) {
+	if enabled, ok := envBool(envCNPGStatementCache); ok {
+		cfg.NoStatementCache = !enabled
+	}
+
+	raw := strings.TrimSpace(os.Getenv(envCNPGStatementCacheSize))
+	if raw == "" {
+		return
+	}
+
+	if size, err := strconv.Atoi(raw); err == nil && size >= 0 {
+		cfg.StatementCacheSize = size
+	}
+}
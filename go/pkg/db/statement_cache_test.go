@@ -0,0 +1,359 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+const textOID = 25
+
+// fakePostgres speaks just enough of the Postgres wire protocol to count the
+// statements pgx parses, so statement caching can be tested without a server.
+type fakePostgres struct {
+	listener net.Listener
+
+	mu            sync.Mutex
+	parses        []string // statement name of every Parse; "" for unnamed
+	deallocations int
+	failNextBind  bool
+}
+
+func startFakePostgres(t *testing.T) *fakePostgres {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &fakePostgres{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (s *fakePostgres) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakePostgres) parseNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.parses...)
+}
+
+func (s *fakePostgres) deallocateCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deallocations
+}
+
+// failNextStatement makes the next Bind fail the way Postgres reports a
+// cached statement whose result columns changed.
+func (s *fakePostgres) failNextStatement() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failNextBind = true
+}
+
+func (s *fakePostgres) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	backend := pgproto3.NewBackend(conn, conn)
+
+	if _, err := backend.ReceiveStartupMessage(); err != nil {
+		return
+	}
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: []byte{0, 0, 0, 1}})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+
+	if err := backend.Flush(); err != nil {
+		return
+	}
+
+	rowDescription := &pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+		{Name: []byte("value"), DataTypeOID: textOID, DataTypeSize: -1, TypeModifier: -1},
+	}}
+
+	failed := false
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+
+		if failed {
+			if _, ok := msg.(*pgproto3.Sync); !ok {
+				continue
+			}
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.Parse:
+			s.mu.Lock()
+			s.parses = append(s.parses, msg.Name)
+			s.mu.Unlock()
+
+			backend.Send(&pgproto3.ParseComplete{})
+		case *pgproto3.Describe:
+			if msg.ObjectType == 'S' {
+				backend.Send(&pgproto3.ParameterDescription{ParameterOIDs: []uint32{textOID}})
+			}
+
+			backend.Send(rowDescription)
+		case *pgproto3.Bind:
+			s.mu.Lock()
+			fail := s.failNextBind
+			s.failNextBind = false
+			s.mu.Unlock()
+
+			if fail {
+				failed = true
+
+				backend.Send(&pgproto3.ErrorResponse{
+					Severity: "ERROR", Code: sqlStateFeatureNotSupported, Message: cachedPlanResultTypeChangeMsg,
+				})
+
+				continue
+			}
+
+			backend.Send(&pgproto3.BindComplete{})
+		case *pgproto3.Execute:
+			backend.Send(&pgproto3.DataRow{Values: [][]byte{[]byte("ok")}})
+			backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+		case *pgproto3.Close:
+			backend.Send(&pgproto3.CloseComplete{})
+		case *pgproto3.Sync:
+			failed = false
+
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		case *pgproto3.Query:
+			if strings.EqualFold(msg.String, "deallocate all") {
+				s.mu.Lock()
+				s.deallocations++
+				s.mu.Unlock()
+			}
+
+			backend.Send(&pgproto3.EmptyQueryResponse{})
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		case *pgproto3.Terminate:
+			return
+		}
+
+		if err := backend.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func newFakePostgresPool(t *testing.T, server *fakePostgres, cfg models.CNPGDatabase) *pgxpool.Pool {
+	t.Helper()
+
+	cfg.Host = "127.0.0.1"
+	cfg.Port = server.port()
+	cfg.Database = "serviceradar"
+	cfg.Username = "serviceradar"
+	cfg.MaxConnections = 1
+
+	poolConfig, err := buildCNPGPoolConfig(&cfg)
+	require.NoError(t, err)
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+func queryValue(ctx context.Context, pool *pgxpool.Pool, sql string) error {
+	var value string
+
+	return pool.QueryRow(ctx, sql, "device").Scan(&value)
+}
+
+func TestStatementCacheReusesPreparedStatements(t *testing.T) {
+	t.Parallel()
+
+	server := startFakePostgres(t)
+	pool := newFakePostgresPool(t, server, models.CNPGDatabase{})
+	ctx := context.Background()
+
+	for range 3 {
+		require.NoError(t, queryValue(ctx, pool, "SELECT $1::text"))
+	}
+
+	parses := server.parseNames()
+	require.Len(t, parses, 1, "repeated queries should reuse the prepared statement")
+	assert.NotEmpty(t, parses[0])
+}
+
+func TestStatementCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	server := startFakePostgres(t)
+	pool := newFakePostgresPool(t, server, models.CNPGDatabase{StatementCacheSize: 1})
+	ctx := context.Background()
+
+	for _, sql := range []string{"SELECT $1::text", "SELECT $1::text", "SELECT upper($1)", "SELECT $1::text"} {
+		require.NoError(t, queryValue(ctx, pool, sql))
+	}
+
+	assert.Len(t, server.parseNames(), 3)
+}
+
+func TestStatementCacheDisabled(t *testing.T) {
+	t.Parallel()
+
+	server := startFakePostgres(t)
+	pool := newFakePostgresPool(t, server, models.CNPGDatabase{NoStatementCache: true})
+	ctx := context.Background()
+
+	for range 3 {
+		require.NoError(t, queryValue(ctx, pool, "SELECT $1::text"))
+	}
+
+	assert.Equal(t, []string{"", "", ""}, server.parseNames())
+}
+
+func TestStatementCacheResetsAfterSchemaChange(t *testing.T) {
+	t.Parallel()
+
+	server := startFakePostgres(t)
+	pool := newFakePostgresPool(t, server, models.CNPGDatabase{})
+	ctx := context.Background()
+
+	require.NoError(t, queryValue(ctx, pool, "SELECT $1::text"))
+
+	server.failNextStatement()
+
+	err := queryValue(ctx, pool, "SELECT $1::text")
+	require.Error(t, err)
+	assert.True(t, isStaleStatementError(err))
+
+	require.NoError(t, queryValue(ctx, pool, "SELECT $1::text"))
+	require.NoError(t, queryValue(ctx, pool, "SELECT $1::text"))
+
+	assert.Len(t, server.parseNames(), 2)
+	assert.Equal(t, 1, server.deallocateCount())
+}
+
+func TestIsStaleStatementError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"result type changed", &pgconn.PgError{Code: "0A000", Message: "cached plan must not change result type"}, true},
+		{"statement missing", &pgconn.PgError{Code: "26000", Message: `prepared statement "stmtcache_1" does not exist`}, true},
+		{"wrapped", fmt.Errorf("query: %w", &pgconn.PgError{Code: "26000"}), true},
+		{"other feature not supported", &pgconn.PgError{Code: "0A000", Message: "cannot copy to view"}, false},
+		{"undefined column", &pgconn.PgError{Code: "42703"}, false},
+		{"not a postgres error", errors.New("connection reset"), false}, //nolint:err113 // test-only error
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, isStaleStatementError(tt.err))
+		})
+	}
+}
+
+func TestBuildCNPGPoolConfigStatementCache(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := buildCNPGPoolConfig(&models.CNPGDatabase{Host: "cnpg-rw", Port: 5432, Database: "serviceradar"})
+	require.NoError(t, err)
+	assert.Equal(t, pgx.QueryExecModeCacheStatement, cfg.ConnConfig.DefaultQueryExecMode)
+	assert.Equal(t, defaultStatementCacheSize, cfg.ConnConfig.StatementCacheCapacity)
+
+	_, ok := findTracer[*statementCache](cfg.ConnConfig.Tracer)
+	assert.True(t, ok)
+
+	cfg, err = buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host: "cnpg-rw", Port: 5432, Database: "serviceradar", StatementCacheSize: 64,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 64, cfg.ConnConfig.StatementCacheCapacity)
+
+	cfg, err = buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host: "cnpg-rw", Port: 5432, Database: "serviceradar", NoStatementCache: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, pgx.QueryExecModeExec, cfg.ConnConfig.DefaultQueryExecMode)
+	assert.Nil(t, cfg.PrepareConn)
+
+	_, err = buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host: "cnpg-rw", Port: 5432, Database: "serviceradar", StatementCacheSize: -1,
+	})
+	require.ErrorIs(t, err, ErrCNPGInvalidStmtCache)
+}
+
+func TestApplyStatementCacheEnv(t *testing.T) {
+	t.Setenv(envCNPGStatementCache, "false")
+	t.Setenv(envCNPGStatementCacheSize, "128")
+
+	cfg := models.CNPGDatabase{StatementCacheSize: 32}
+	applyStatementCacheEnv(&cfg)
+
+	assert.True(t, cfg.NoStatementCache)
+	assert.Equal(t, 128, cfg.StatementCacheSize)
+
+	t.Setenv(envCNPGStatementCache, "true")
+	t.Setenv(envCNPGStatementCacheSize, "-5")
+	applyStatementCacheEnv(&cfg)
+
+	assert.False(t, cfg.NoStatementCache)
+	assert.Equal(t, 128, cfg.StatementCacheSize)
+}
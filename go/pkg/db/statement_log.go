@@ -0,0 +1,188 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package db
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+const (
+	envCNPGLogStatements      = "CNPG_LOG_STATEMENTS"
+	envCNPGLogStatementParams = "CNPG_LOG_STATEMENT_PARAMS"
+)
+
+type statementTraceKey struct{}
+
+type statementTrace struct {
+	sql   string
+	args  []any
+	start time.Time
+}
+
+// statementTracer is a pgx QueryTracer that logs every statement run on the
+// pool, for debugging data issues. Statements are logged in their
+// parameterized form with the parameter count; parameter values are only
+// included when logParams is set.
+type statementTracer struct {
+	logParams bool
+	now       func() time.Time
+
+	mu  sync.Mutex
+	log logger.Logger
+}
+
+func newStatementTracer(logParams bool) *statementTracer {
+	return &statementTracer{logParams: logParams, now: time.Now}
+}
+
+// attach sets the logger statements are written to.
+func (t *statementTracer) attach(log logger.Logger) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.log = log
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t *statementTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, statementTraceKey{}, &statementTrace{
+		sql:   data.SQL,
+		args:  data.Args,
+		start: t.now(),
+	})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer. Statements are logged once they
+// finish so the entry carries the duration and any error.
+func (t *statementTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(statementTraceKey{}).(*statementTrace)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	log := t.log
+	t.mu.Unlock()
+
+	if log == nil {
+		return
+	}
+
+	event := log.Info().
+		Str("sql", trace.sql).
+		Int("params", len(trace.args)).
+		Dur("duration", t.now().Sub(trace.start)).
+		Int64("rows", data.CommandTag.RowsAffected())
+
+	if t.logParams {
+		event = event.Interface("param_values", trace.args)
+	}
+
+	if data.Err != nil {
+		event = event.Str("error", data.Err.Error())
+	}
+
+	event.Msg("CNPG statement")
+}
+
+// applyStatementLogEnv enables statement logging from CNPG_LOG_STATEMENTS and
+// CNPG_LOG_STATEMENT_PARAMS, so it can be switched on for a debug session
+// without editing the service config.
+func applyStatementLogEnv(cfg *models.CNPGDatabase) {
+	if enabled, ok := envBool(envCNPGLogStatements); ok {
+		cfg.LogStatements = enabled
+	}
+
+	if enabled, ok := envBool(envCNPGLogStatementParams); ok {
+		cfg.LogStatementParams = enabled
+	}
+}
+
+func envBool(name string) (value, ok bool) {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return false, false
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, false
+	}
+
+	return value, true
+}
+
+// queryTracers fans pgx query tracing out to several tracers, so statement
+// logging can run alongside the slow-query tracer.
+type queryTracers []pgx.QueryTracer
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t queryTracers) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	for _, tracer := range t {
+		ctx = tracer.TraceQueryStart(ctx, conn, data)
+	}
+
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (t queryTracers) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	for _, tracer := range t {
+		tracer.TraceQueryEnd(ctx, conn, data)
+	}
+}
+
+// combineTracers returns the only tracer, or all of them fanned out.
+func combineTracers(tracers []pgx.QueryTracer) pgx.QueryTracer {
+	switch len(tracers) {
+	case 0:
+		return nil
+	case 1:
+		return tracers[0]
+	default:
+		return queryTracers(tracers)
+	}
+}
+
+// findTracer returns the tracer of type T installed on a pool, if any.
+func findTracer[T pgx.QueryTracer](tracer pgx.QueryTracer) (T, bool) {
+	if found, ok := tracer.(T); ok {
+		return found, true
+	}
+
+	if tracers, ok := tracer.(queryTracers); ok {
+		for _, candidate := range tracers {
+			if found, ok := candidate.(T); ok {
+				return found, true
+			}
+		}
+	}
+
+	var zero T
+
+	return zero, false
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/models"
+)
+
+var errStatementFailed = errors.New("relation does not exist")
+
+func newCapturingLogger(t *testing.T, buf *bytes.Buffer) logger.Logger {
+	t.Helper()
+
+	zl := zerolog.New(buf)
+	log := logger.NewMockLogger(gomock.NewController(t))
+	log.EXPECT().Info().DoAndReturn(zl.Info).AnyTimes()
+
+	return log
+}
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var entries []map[string]any
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestStatementTracerLogsExecutedStatements(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	tracer := newStatementTracer(false)
+	tracer.attach(newCapturingLogger(t, &buf))
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{
+		SQL:  "UPDATE ocsf_devices SET hostname = $1 WHERE uid = $2",
+		Args: []any{"secret-host", "dev-1"},
+	})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 1")})
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT * FROM missing"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errStatementFailed})
+
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "UPDATE ocsf_devices SET hostname = $1 WHERE uid = $2", entries[0]["sql"])
+	assert.InDelta(t, 2, entries[0]["params"], 0)
+	assert.InDelta(t, 1, entries[0]["rows"], 0)
+	assert.NotContains(t, entries[0], "param_values")
+	assert.NotContains(t, buf.String(), "secret-host")
+
+	assert.Equal(t, "SELECT * FROM missing", entries[1]["sql"])
+	assert.Equal(t, errStatementFailed.Error(), entries[1]["error"])
+}
+
+func TestStatementTracerLogsParamValuesWhenEnabled(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	tracer := newStatementTracer(true)
+	tracer.attach(newCapturingLogger(t, &buf))
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{
+		SQL:  "SELECT * FROM ocsf_devices WHERE uid = $1",
+		Args: []any{"dev-1"},
+	})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, []any{"dev-1"}, entries[0]["param_values"])
+}
+
+func TestBuildCNPGPoolConfigCombinesStatementAndQueryPlanTracers(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host: "cnpg-rw", Port: 5432, Database: "serviceradar",
+		LogStatements: true,
+	})
+	require.NoError(t, err)
+
+	_, ok := findTracer[*statementTracer](cfg.ConnConfig.Tracer)
+	require.True(t, ok)
+
+	cfg, err = buildCNPGPoolConfig(&models.CNPGDatabase{
+		Host: "cnpg-rw", Port: 5432, Database: "serviceradar",
+		SlowQueryThreshold: models.Duration(time.Second),
+		LogStatementParams: true,
+	})
+	require.NoError(t, err)
+
+	statements, ok := findTracer[*statementTracer](cfg.ConnConfig.Tracer)
+	require.True(t, ok)
+	assert.True(t, statements.logParams)
+
+	plans, ok := findTracer[*queryPlanTracer](cfg.ConnConfig.Tracer)
+	require.True(t, ok)
+
+	// Both tracers see every statement through the combined tracer.
+	var buf bytes.Buffer
+
+	statements.attach(newCapturingLogger(t, &buf))
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	plans.now = func() time.Time { return now }
+
+	ctx := cfg.ConnConfig.Tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT pg_sleep(2)"})
+	now = now.Add(2 * time.Second)
+	cfg.ConnConfig.Tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	require.Len(t, decodeLogLines(t, &buf), 1)
+	require.Len(t, plans.slowQueries(), 1)
+}
+
+func TestApplyStatementLogEnv(t *testing.T) {
+	t.Setenv(envCNPGLogStatements, "true")
+	t.Setenv(envCNPGLogStatementParams, "not-a-bool")
+
+	cfg := models.CNPGDatabase{LogStatementParams: true}
+	applyStatementLogEnv(&cfg)
+
+	assert.True(t, cfg.LogStatements)
+	assert.True(t, cfg.LogStatementParams)
+
+	t.Setenv(envCNPGLogStatementParams, "0")
+	applyStatementLogEnv(&cfg)
+
+	assert.False(t, cfg.LogStatementParams)
+}
@@ -163,6 +163,17 @@ func (p *MTLSProvider) Close() error {
 
 // loadClientCredentials loads client TLS credentials using paths from config.TLS.
 func loadClientCredentials(config *models.SecurityConfig, log logger.Logger) (credentials.TransportCredentials, error) {
+	tlsConfig, err := ClientTLSConfig(config, log)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// ClientTLSConfig builds the client TLS configuration used in mTLS mode from
+// the paths in config.TLS, resolved against config.CertDir.
+func ClientTLSConfig(config *models.SecurityConfig, log logger.Logger) (*tls.Config, error) {
 	// Normalize paths with CertDir if they are relative
 	certPath := config.TLS.CertFile
 	keyPath := config.TLS.KeyFile
@@ -208,7 +219,7 @@ func loadClientCredentials(config *models.SecurityConfig, log logger.Logger) (cr
 		MinVersion:   tls.VersionTLS13,
 	}
 
-	return credentials.NewTLS(tlsConfig), nil
+	return tlsConfig, nil
 }
 
 func loadServerCredentials(config *models.SecurityConfig, log logger.Logger) (credentials.TransportCredentials, error) {
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+const (
+	// DefaultReadinessInterval is how often failing readiness checks are retried.
+	DefaultReadinessInterval = 2 * time.Second
+	// DefaultReadinessTimeout bounds a single readiness check attempt.
+	DefaultReadinessTimeout = 5 * time.Second
+)
+
+var errReadinessCheckMissing = errors.New("readiness check has no check function")
+
+// ReadinessCheck is a named dependency probe, such as a DB ping or a KV get,
+// that must succeed before a server reports itself ready.
+type ReadinessCheck struct {
+	Name  string
+	Check func(context.Context) error
+}
+
+// CheckReadiness runs every check once, each bounded by timeout, and returns
+// the joined errors of the checks that failed.
+func CheckReadiness(ctx context.Context, checks []ReadinessCheck, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+
+	var errs []error
+
+	for _, check := range checks {
+		if check.Check == nil {
+			errs = append(errs, fmt.Errorf("%s: %w", check.Name, errReadinessCheckMissing))
+
+			continue
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := check.Check(checkCtx)
+
+		cancel()
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", check.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// readinessGate holds a server's health status at NOT_SERVING until all of
+// its readiness checks pass.
+type readinessGate struct {
+	checks   []ReadinessCheck
+	interval time.Duration
+	timeout  time.Duration
+	health   *health.Server
+	services []string
+	log      logger.Logger
+}
+
+func newReadinessGate(opts *ServerOptions, healthServer *health.Server, log logger.Logger) *readinessGate {
+	interval := opts.ReadinessInterval
+	if interval <= 0 {
+		interval = DefaultReadinessInterval
+	}
+
+	return &readinessGate{
+		checks:   opts.ReadinessChecks,
+		interval: interval,
+		timeout:  opts.ReadinessTimeout,
+		health:   healthServer,
+		// The empty name is the overall server status used by plain health probes.
+		services: []string{"", opts.ServiceName},
+		log:      log,
+	}
+}
+
+// hold marks the gated services as not serving.
+func (g *readinessGate) hold() {
+	g.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// wait blocks until every readiness check passes, then marks the gated
+// services as serving. It returns the context error if ctx ends first.
+func (g *readinessGate) wait(ctx context.Context) error {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	attempt := 0
+
+	for {
+		attempt++
+
+		err := CheckReadiness(ctx, g.checks, g.timeout)
+		if err == nil {
+			g.setStatus(healthpb.HealthCheckResponse_SERVING)
+			g.log.Info().Int("attempts", attempt).Msg("Readiness checks passed, serving")
+
+			return nil
+		}
+
+		// Log the first failure loudly; retries are expected while dependencies start.
+		event := g.log.Debug()
+		if attempt == 1 {
+			event = g.log.Warn()
+		}
+
+		event.Err(err).Int("attempt", attempt).Msg("Waiting for dependencies to become ready")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *readinessGate) setStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	if g.health == nil {
+		return
+	}
+
+	for _, service := range g.services {
+		g.health.SetServingStatus(service, status)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+var errDatabaseDown = errors.New("database unavailable")
+
+func servingStatus(t *testing.T, server *health.Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+
+	resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+
+	return resp.GetStatus()
+}
+
+func TestReadinessGate_NotServingUntilDependencyRecovers(t *testing.T) {
+	var dbUp atomic.Bool
+
+	var attempts atomic.Int32
+
+	healthServer := health.NewServer()
+	gate := newReadinessGate(&ServerOptions{
+		ServiceName: "db-event-writer",
+		ReadinessChecks: []ReadinessCheck{
+			{
+				Name: "database",
+				Check: func(context.Context) error {
+					attempts.Add(1)
+
+					if !dbUp.Load() {
+						return errDatabaseDown
+					}
+
+					return nil
+				},
+			},
+		},
+		ReadinessInterval: 10 * time.Millisecond,
+	}, healthServer, logger.NewTestLogger())
+
+	gate.hold()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() { done <- gate.wait(ctx) }()
+
+	require.Eventually(t, func() bool { return attempts.Load() >= 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, healthServer, "db-event-writer"))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, healthServer, ""))
+
+	dbUp.Store(true)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("readiness gate did not open after the dependency recovered")
+	}
+
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, healthServer, "db-event-writer"))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, healthServer, ""))
+}
+
+func TestReadinessGate_StopsWhenContextEnds(t *testing.T) {
+	healthServer := health.NewServer()
+	gate := newReadinessGate(&ServerOptions{
+		ServiceName: "datasvc",
+		ReadinessChecks: []ReadinessCheck{
+			{Name: "kv", Check: func(context.Context) error { return errDatabaseDown }},
+		},
+		ReadinessInterval: 10 * time.Millisecond,
+	}, healthServer, logger.NewTestLogger())
+
+	gate.hold()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, gate.wait(ctx), context.DeadlineExceeded)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, healthServer, "datasvc"))
+}
+
+func TestCheckReadiness_ReportsEachFailingCheck(t *testing.T) {
+	err := CheckReadiness(context.Background(), []ReadinessCheck{
+		{Name: "database", Check: func(context.Context) error { return errDatabaseDown }},
+		{Name: "kv", Check: func(context.Context) error { return nil }},
+		{Name: "broken"},
+	}, time.Second)
+
+	require.ErrorIs(t, err, errDatabaseDown)
+	require.ErrorIs(t, err, errReadinessCheckMissing)
+	assert.Contains(t, err.Error(), "database")
+	assert.NotContains(t, err.Error(), "kv")
+}
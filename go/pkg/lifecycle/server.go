@@ -68,6 +68,16 @@ type ServerOptions struct {
 	Logger               logger.Logger // Optional: if provided, uses this logger instead of creating a new one
 	DisableTelemetry     bool
 	TelemetryFilter      grpc.TelemetryFilter
+
+	// ReadinessChecks must all pass before the health check reports SERVING.
+	// Until then the server (and the overall "" service) reports NOT_SERVING.
+	ReadinessChecks []ReadinessCheck
+	// ReadinessInterval is how often failing checks are retried (default 2s).
+	ReadinessInterval time.Duration
+	// ReadinessTimeout bounds each check attempt (default 5s).
+	ReadinessTimeout time.Duration
+	// WaitForReadiness delays binding the listen address until the checks pass.
+	WaitForReadiness bool
 }
 
 // RunServer starts a service with the provided options and handles lifecycle.
@@ -109,13 +119,34 @@ func RunServer(ctx context.Context, opts *ServerOptions) error {
 		}
 	}()
 
-	go func() {
+	startGRPC := func() {
 		log.Info().Str("address", opts.ListenAddr).Msg("Starting gRPC server")
 
 		if err := grpcServer.Start(); err != nil {
 			errChan <- fmt.Errorf("gRPC server failed: %w", err)
 		}
-	}()
+	}
+
+	gated := len(opts.ReadinessChecks) > 0
+
+	if gated {
+		gate := newReadinessGate(opts, grpcServer.GetHealthCheck(), log)
+		gate.hold()
+
+		go func() {
+			if err := gate.wait(ctx); err != nil {
+				return
+			}
+
+			if opts.WaitForReadiness {
+				startGRPC()
+			}
+		}()
+	}
+
+	if !gated || !opts.WaitForReadiness {
+		go startGRPC()
+	}
 
 	return handleShutdown(ctx, cancel, grpcServer, opts.Service, errChan, log)
 }
@@ -160,7 +191,7 @@ func setupGRPCServer(ctx context.Context, opts *ServerOptions, log logger.Logger
 	registerServices(underlyingServer, opts.RegisterGRPCServices, log)
 
 	if opts.EnableHealthCheck {
-		setupHealthCheck(grpcServer, opts.ServiceName, log)
+		setupHealthCheck(grpcServer, opts.ServiceName, len(opts.ReadinessChecks) > 0, log)
 	}
 
 	return grpcServer, nil
@@ -333,8 +364,9 @@ func registerServices(server *ggrpc.Server, services []GRPCServiceRegistrar, log
 	}
 }
 
-// setupHealthCheck configures the health check service if enabled.
-func setupHealthCheck(server *grpc.Server, serviceName string, log logger.Logger) {
+// setupHealthCheck configures the health check service if enabled. Gated
+// servers are left for the readiness gate to mark as serving.
+func setupHealthCheck(server *grpc.Server, serviceName string, gated bool, log logger.Logger) {
 	if err := server.RegisterHealthServer(); err != nil {
 		log.Warn().Err(err).Msg("Failed to register health server")
 
@@ -344,7 +376,7 @@ func setupHealthCheck(server *grpc.Server, serviceName string, log logger.Logger
 	log.Info().Msg("Successfully registered health server")
 
 	healthCheck := server.GetHealthCheck()
-	if healthCheck != nil {
+	if healthCheck != nil && !gated {
 		healthCheck.SetServingStatus(serviceName, healthpb.HealthCheckResponse_SERVING)
 
 		log.Info().Str("service", serviceName).Msg("Set health status to SERVING")
@@ -70,6 +70,11 @@ func NewDiscoveryEngine(config *Config, publisher Publisher, log logger.Logger)
 		return nil, fmt.Errorf("invalid discovery engine configuration: %w", err)
 	}
 
+	vendorMatcher, err := newVendorOIDMatcher(config.VendorOIDs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid discovery engine configuration: %w", err)
+	}
+
 	probeSvc, err := newSharedICMPProbeService(log)
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to initialize shared ICMP probe service; continuing without probes")
@@ -87,6 +92,8 @@ func NewDiscoveryEngine(config *Config, publisher Publisher, log logger.Logger)
 		schedulers:    make(map[string]*time.Ticker),
 		logger:        log,
 		hostProber:    probeSvc,
+		sessionPool:   newSNMPSessionPool(config.SessionPoolSize, config.SessionIdleTimeout),
+		vendorMatcher: vendorMatcher,
 	}
 
 	return engine, nil
@@ -173,6 +180,8 @@ func (e *DiscoveryEngine) Stop(ctx context.Context) error {
 	// Close jobChan after workers have stopped
 	close(e.jobChan)
 
+	e.sessionPool.close()
+
 	if e.hostProber != nil {
 		if err := e.hostProber.Close(); err != nil {
 			e.logger.Warn().Err(err).Msg("Error stopping host probe service")
@@ -32,6 +32,7 @@ var (
 	ErrInvalidWorkers           = errors.New("workers must be greater than 0")
 	ErrInvalidMaxActiveJobs     = errors.New("maxActiveJobs must be greater than 0")
 	ErrUnsupportedSNMPVersion   = errors.New("unsupported SNMP version")
+	ErrInvalidVendorOID         = errors.New("invalid vendor OID mapping")
 
 	ErrDatabaseServiceRequired = errors.New("database service is required")
 	ErrSNMPGetFailed           = errors.New("SNMP GET failed")
@@ -260,14 +260,36 @@ func (e *DiscoveryEngine) publishTopologyEvidence(
 	}
 }
 
-// setupSNMPClient creates and configures an SNMP client
-func (e *DiscoveryEngine) setupSNMPClient(job *DiscoveryJob, target string) (*gosnmp.GoSNMP, error) {
-	// Create SNMP client
-	client, err := e.createSNMPClient(target, job.Params.Credentials)
-	if err != nil {
-		return nil, err
+// setupSNMPClient checks out a pooled session for the target when one is idle
+// and otherwise creates a new, unconnected client. It returns the session key
+// and whether the client was reused.
+func (e *DiscoveryEngine) setupSNMPClient(job *DiscoveryJob, target string) (*gosnmp.GoSNMP, string, bool, error) {
+	credentials := targetCredentials(target, job.Params.Credentials)
+	key := snmpSessionKey(target, defaultSNMPPort, credentials)
+
+	client, idleFor, reused := e.sessionPool.acquire(key)
+	if !reused {
+		var err error
+
+		if client, err = e.createSNMPClient(target, credentials); err != nil {
+			return nil, "", false, err
+		}
+	} else if client.Version == gosnmp.Version3 && idleFor > snmpV3TimeWindow {
+		// The agent may have rebooted or its engine clock moved outside the
+		// USM time window while the session sat idle; reset the learned engine
+		// state so the next request rediscovers it.
+		if err := e.configureClientVersion(client, credentials); err != nil {
+			_ = closeSNMPClient(client)
+
+			return nil, "", false, err
+		}
+
+		client.ContextEngineID = ""
 	}
 
+	client.Timeout = e.config.Timeout
+	client.Retries = e.config.Retries
+
 	// Override timeout and retries if specified in job params
 	if job.Params.Timeout > 0 {
 		client.Timeout = job.Params.Timeout
@@ -277,12 +299,24 @@ func (e *DiscoveryEngine) setupSNMPClient(job *DiscoveryJob, target string) (*go
 		client.Retries = job.Params.Retries
 	}
 
-	// Connect to target
-	if err := client.Connect(); err != nil {
-		return nil, err
+	return client, key, reused, nil
+}
+
+// releaseSNMPClient returns a healthy client to the session pool and closes
+// any other in the background, since a timed-out handler may still hold it.
+func (e *DiscoveryEngine) releaseSNMPClient(
+	job *DiscoveryJob, client *gosnmp.GoSNMP, key, snmpTargetIP string, healthy bool) {
+	if healthy {
+		e.sessionPool.release(key, client)
+		return
 	}
 
-	return client, nil
+	go func() {
+		if err := closeSNMPClient(client); err != nil {
+			e.logger.Warn().Str("job_id", job.ID).Str("target_ip", snmpTargetIP).Err(err).
+				Msg("Error closing SNMP connection")
+		}
+	}()
 }
 
 // processSNMPVariables processes SNMP variables and populates the device object
@@ -526,6 +560,8 @@ func (e *DiscoveryEngine) querySysInfo(
 		return nil, ErrNoSNMPDataReturned
 	}
 
+	e.applyVendorFingerprint(device)
+
 	device.SNMPFingerprint = buildSNMPFingerprintFromDevice(device, extractionErrors)
 	e.enrichSNMPBridgeFingerprint(client, device.SNMPFingerprint, extractionErrors)
 	e.enrichSNMPVLANFingerprint(client, device.SNMPFingerprint, extractionErrors)
@@ -1716,7 +1752,8 @@ func (e *DiscoveryEngine) connectSNMPClient(
 	return nil
 }
 
-// performDiscoveryWithTimeout is a helper function to perform discovery operations with timeout
+// performDiscoveryWithTimeout is a helper function to perform discovery operations with timeout.
+// It returns false when the handler timed out or was canceled and may still be using the client.
 func (e *DiscoveryEngine) performDiscoveryWithTimeout(
 	ctx context.Context,
 	job *DiscoveryJob,
@@ -1725,31 +1762,36 @@ func (e *DiscoveryEngine) performDiscoveryWithTimeout(
 	discoveryTypeName string,
 	requiredType DiscoveryType,
 	handlerFunc func(*DiscoveryJob, *gosnmp.GoSNMP, string),
-) {
-	if job.Params.Type == DiscoveryTypeFull || job.Params.Type == requiredType {
-		done := make(chan struct{})
+) bool {
+	if job.Params.Type != DiscoveryTypeFull && job.Params.Type != requiredType {
+		return true
+	}
 
-		go func() {
-			handlerFunc(job, client, snmpTargetIP)
-			close(done)
-		}()
+	done := make(chan struct{})
 
-		select {
-		case <-done:
-		case <-time.After(30 * time.Second):
-			e.logger.Warn().Str("job_id", job.ID).Str("discovery_type", discoveryTypeName).
-				Str("target_ip", snmpTargetIP).Msg("Discovery timeout")
-		case <-ctx.Done():
-			e.logger.Info().Str("job_id", job.ID).Str("discovery_type", discoveryTypeName).
-				Str("target_ip", snmpTargetIP).Msg("Discovery canceled")
-		}
+	go func() {
+		handlerFunc(job, client, snmpTargetIP)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(30 * time.Second):
+		e.logger.Warn().Str("job_id", job.ID).Str("discovery_type", discoveryTypeName).
+			Str("target_ip", snmpTargetIP).Msg("Discovery timeout")
+	case <-ctx.Done():
+		e.logger.Info().Str("job_id", job.ID).Str("discovery_type", discoveryTypeName).
+			Str("target_ip", snmpTargetIP).Msg("Discovery canceled")
 	}
+
+	return false
 }
 
 // performInterfaceDiscovery performs interface discovery with timeout
 func (e *DiscoveryEngine) performInterfaceDiscovery(
-	ctx context.Context, job *DiscoveryJob, client *gosnmp.GoSNMP, snmpTargetIP string) {
-	e.performDiscoveryWithTimeout(
+	ctx context.Context, job *DiscoveryJob, client *gosnmp.GoSNMP, snmpTargetIP string) bool {
+	return e.performDiscoveryWithTimeout(
 		ctx,
 		job,
 		client,
@@ -1762,8 +1804,8 @@ func (e *DiscoveryEngine) performInterfaceDiscovery(
 
 // performTopologyDiscovery performs topology discovery with timeout
 func (e *DiscoveryEngine) performTopologyDiscovery(
-	ctx context.Context, job *DiscoveryJob, client *gosnmp.GoSNMP, snmpTargetIP string) {
-	e.performDiscoveryWithTimeout(
+	ctx context.Context, job *DiscoveryJob, client *gosnmp.GoSNMP, snmpTargetIP string) bool {
+	return e.performDiscoveryWithTimeout(
 		ctx,
 		job,
 		client,
@@ -1779,8 +1821,8 @@ func (e *DiscoveryEngine) scanTargetForSNMP(
 ) {
 	e.logger.Debug().Str("job_id", job.ID).Str("target_ip", snmpTargetIP).Msg("SNMP Scanning target")
 
-	// Setup SNMP client
-	client, err := e.setupSNMPClient(job, snmpTargetIP)
+	// Setup SNMP client, reusing an idle pooled session when available
+	client, sessionKey, reused, err := e.setupSNMPClient(job, snmpTargetIP)
 	if err != nil {
 		e.logger.Error().Str("job_id", job.ID).Str("target_ip", snmpTargetIP).Err(err).
 			Msg("Failed to setup SNMP client")
@@ -1789,17 +1831,17 @@ func (e *DiscoveryEngine) scanTargetForSNMP(
 	}
 
 	// Connect to SNMP client
-	if err = e.connectSNMPClient(ctx, client, job, snmpTargetIP); err != nil {
-		return
+	if !reused {
+		if err = e.connectSNMPClient(ctx, client, job, snmpTargetIP); err != nil {
+			return
+		}
 	}
 
+	// Only sessions that finished every request cleanly go back to the pool.
+	healthy := true
+
 	defer func() {
-		go func() {
-			if cErr := client.Conn.Close(); cErr != nil {
-				e.logger.Warn().Str("job_id", job.ID).Str("target_ip", snmpTargetIP).Err(cErr).
-					Msg("Error closing SNMP connection")
-			}
-		}()
+		e.releaseSNMPClient(job, client, sessionKey, snmpTargetIP, healthy && ctx.Err() == nil)
 	}()
 
 	// Query system information
@@ -1808,6 +1850,8 @@ func (e *DiscoveryEngine) scanTargetForSNMP(
 		e.logger.Warn().Str("job_id", job.ID).Str("target_ip", snmpTargetIP).Err(err).
 			Msg("Failed to query system info via SNMP, skipping")
 
+		healthy = false
+
 		// For topology mode, continue with LLDP/CDP/L2 polling when the target
 		// is already known from other evidence (e.g., UniFi inventory).
 		if mode == snmpPollingModeTopology {
@@ -1817,7 +1861,7 @@ func (e *DiscoveryEngine) scanTargetForSNMP(
 					Str("target_ip", snmpTargetIP).
 					Str("local_device_id", localDeviceID).
 					Msg("Continuing topology polling without sysinfo due to known device identity")
-				e.performTopologyDiscovery(ctx, job, client, snmpTargetIP)
+				_ = e.performTopologyDiscovery(ctx, job, client, snmpTargetIP)
 			}
 		}
 
@@ -1830,11 +1874,11 @@ func (e *DiscoveryEngine) scanTargetForSNMP(
 	job.mu.Unlock()
 
 	if mode == snmpPollingModeEnrichment {
-		e.performInterfaceDiscovery(ctx, job, client, snmpTargetIP)
+		healthy = e.performInterfaceDiscovery(ctx, job, client, snmpTargetIP)
 	}
 
 	if mode == snmpPollingModeTopology {
-		e.performTopologyDiscovery(ctx, job, client, snmpTargetIP)
+		healthy = e.performTopologyDiscovery(ctx, job, client, snmpTargetIP)
 	}
 }
 
@@ -0,0 +1,271 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mapper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	defaultSNMPPort             = 161
+	defaultSessionPoolSize      = 128
+	defaultSessionIdleTimeout   = 10 * time.Minute
+	sessionKeyFingerprintLength = 16
+	snmpV3TimeWindow            = 150 * time.Second // RFC 3414 section 2.2.3
+)
+
+// snmpSession is an idle, connected SNMP client held by the pool.
+type snmpSession struct {
+	client   *gosnmp.GoSNMP
+	lastUsed time.Time
+}
+
+// snmpSessionPool keeps connected SNMP clients between discovery cycles so
+// repeated polls of a target skip socket setup and, for SNMPv3, engine
+// discovery. Sessions are checked out exclusively and keyed by target and
+// credentials; idle sessions are evicted after idleTimeout or, once maxIdle
+// is reached, oldest first. A nil pool disables reuse.
+type snmpSessionPool struct {
+	mu          sync.Mutex
+	idle        map[string][]*snmpSession
+	count       int
+	maxIdle     int
+	idleTimeout time.Duration
+	now         func() time.Time
+	closeClient func(*gosnmp.GoSNMP) error
+}
+
+// newSNMPSessionPool returns nil when maxIdle is negative, disabling reuse.
+func newSNMPSessionPool(maxIdle int, idleTimeout time.Duration) *snmpSessionPool {
+	if maxIdle < 0 {
+		return nil
+	}
+
+	if maxIdle == 0 {
+		maxIdle = defaultSessionPoolSize
+	}
+
+	if idleTimeout <= 0 {
+		idleTimeout = defaultSessionIdleTimeout
+	}
+
+	return &snmpSessionPool{
+		idle:        make(map[string][]*snmpSession),
+		maxIdle:     maxIdle,
+		idleTimeout: idleTimeout,
+		now:         time.Now,
+		closeClient: closeSNMPClient,
+	}
+}
+
+// acquire checks out the most recently used idle session for key. It also
+// reports how long the session sat idle so callers can refresh state that
+// may have gone stale in the meantime.
+func (p *snmpSessionPool) acquire(key string) (*gosnmp.GoSNMP, time.Duration, bool) {
+	if p == nil {
+		return nil, 0, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.evictExpiredLocked()
+
+	sessions := p.idle[key]
+	if len(sessions) == 0 {
+		return nil, 0, false
+	}
+
+	session := sessions[len(sessions)-1]
+	p.removeLocked(key, len(sessions)-1)
+
+	return session.client, p.now().Sub(session.lastUsed), true
+}
+
+// release returns a healthy session to the pool, evicting the oldest idle
+// session when the pool is full. Without a pool the client is closed.
+func (p *snmpSessionPool) release(key string, client *gosnmp.GoSNMP) {
+	if client == nil {
+		return
+	}
+
+	if p == nil {
+		_ = closeSNMPClient(client)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.evictExpiredLocked()
+
+	for p.count >= p.maxIdle {
+		p.evictOldestLocked()
+	}
+
+	p.idle[key] = append(p.idle[key], &snmpSession{client: client, lastUsed: p.now()})
+	p.count++
+}
+
+// evictExpired closes sessions that have been idle longer than the idle
+// timeout and returns how many were closed.
+func (p *snmpSessionPool) evictExpired() int {
+	if p == nil {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.evictExpiredLocked()
+}
+
+// size returns the number of idle sessions.
+func (p *snmpSessionPool) size() int {
+	if p == nil {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.count
+}
+
+// close closes every idle session.
+func (p *snmpSessionPool) close() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, sessions := range p.idle {
+		for _, session := range sessions {
+			_ = p.closeClient(session.client)
+		}
+
+		delete(p.idle, key)
+	}
+
+	p.count = 0
+}
+
+func (p *snmpSessionPool) evictExpiredLocked() int {
+	cutoff := p.now().Add(-p.idleTimeout)
+	evicted := 0
+
+	for key, sessions := range p.idle {
+		kept := sessions[:0]
+
+		for _, session := range sessions {
+			if session.lastUsed.Before(cutoff) {
+				_ = p.closeClient(session.client)
+				evicted++
+
+				continue
+			}
+
+			kept = append(kept, session)
+		}
+
+		if len(kept) == 0 {
+			delete(p.idle, key)
+		} else {
+			p.idle[key] = kept
+		}
+	}
+
+	p.count -= evicted
+
+	return evicted
+}
+
+func (p *snmpSessionPool) evictOldestLocked() {
+	var (
+		oldestKey   string
+		oldestIndex = -1
+		oldest      time.Time
+	)
+
+	for key, sessions := range p.idle {
+		for i, session := range sessions {
+			if oldestIndex == -1 || session.lastUsed.Before(oldest) {
+				oldestKey, oldestIndex, oldest = key, i, session.lastUsed
+			}
+		}
+	}
+
+	if oldestIndex == -1 {
+		p.count = 0
+		return
+	}
+
+	_ = p.closeClient(p.idle[oldestKey][oldestIndex].client)
+	p.removeLocked(oldestKey, oldestIndex)
+}
+
+func (p *snmpSessionPool) removeLocked(key string, index int) {
+	sessions := p.idle[key]
+	sessions = append(sessions[:index], sessions[index+1:]...)
+
+	if len(sessions) == 0 {
+		delete(p.idle, key)
+	} else {
+		p.idle[key] = sessions
+	}
+
+	p.count--
+}
+
+func closeSNMPClient(client *gosnmp.GoSNMP) error {
+	if client == nil || client.Conn == nil {
+		return nil
+	}
+
+	return client.Conn.Close()
+}
+
+// snmpSessionKey identifies sessions that may be shared: the same target,
+// port, version and credentials. Secrets are hashed rather than embedded.
+func snmpSessionKey(target string, port uint16, credentials *SNMPCredentials) string {
+	hash := sha256.New()
+
+	for _, part := range []string{
+		credentials.Community,
+		credentials.Username,
+		strings.ToUpper(credentials.AuthProtocol),
+		credentials.AuthPassword,
+		strings.ToUpper(credentials.PrivacyProtocol),
+		credentials.PrivacyPassword,
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	fingerprint := hex.EncodeToString(hash.Sum(nil))[:sessionKeyFingerprintLength]
+
+	return fmt.Sprintf("%s|%d|%s|%s", target, port, credentials.Version, fingerprint)
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mapper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+type fakeSessionClock struct {
+	now time.Time
+}
+
+func (c *fakeSessionClock) Now() time.Time { return c.now }
+
+func newTestSessionPool(maxIdle int, idleTimeout time.Duration) (*snmpSessionPool, *fakeSessionClock, *[]*gosnmp.GoSNMP) {
+	clock := &fakeSessionClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	closed := &[]*gosnmp.GoSNMP{}
+
+	pool := newSNMPSessionPool(maxIdle, idleTimeout)
+	pool.now = clock.Now
+	pool.closeClient = func(client *gosnmp.GoSNMP) error {
+		*closed = append(*closed, client)
+		return nil
+	}
+
+	return pool, clock, closed
+}
+
+func TestSNMPSessionPoolReusesReleasedSession(t *testing.T) {
+	pool, clock, closed := newTestSessionPool(4, time.Minute)
+	client := &gosnmp.GoSNMP{Target: "192.0.2.1"}
+
+	_, _, ok := pool.acquire("a")
+	require.False(t, ok)
+
+	pool.release("a", client)
+	clock.now = clock.now.Add(20 * time.Second)
+
+	reused, idleFor, ok := pool.acquire("a")
+	require.True(t, ok)
+	assert.Same(t, client, reused)
+	assert.Equal(t, 20*time.Second, idleFor)
+
+	_, _, ok = pool.acquire("a")
+	assert.False(t, ok, "sessions are checked out exclusively")
+
+	_, _, ok = pool.acquire("b")
+	assert.False(t, ok)
+	assert.Empty(t, *closed)
+}
+
+func TestSNMPSessionPoolEvictsIdleSessions(t *testing.T) {
+	pool, clock, closed := newTestSessionPool(4, time.Minute)
+	stale := &gosnmp.GoSNMP{Target: "192.0.2.1"}
+	fresh := &gosnmp.GoSNMP{Target: "192.0.2.2"}
+
+	pool.release("a", stale)
+	clock.now = clock.now.Add(45 * time.Second)
+	pool.release("b", fresh)
+	clock.now = clock.now.Add(30 * time.Second)
+
+	assert.Equal(t, 1, pool.evictExpired())
+	assert.Equal(t, []*gosnmp.GoSNMP{stale}, *closed)
+	assert.Equal(t, 1, pool.size())
+
+	_, _, ok := pool.acquire("a")
+	assert.False(t, ok)
+
+	pool.close()
+	assert.Equal(t, []*gosnmp.GoSNMP{stale, fresh}, *closed)
+	assert.Zero(t, pool.size())
+}
+
+func TestSNMPSessionPoolEvictsOldestWhenFull(t *testing.T) {
+	pool, clock, closed := newTestSessionPool(2, time.Hour)
+	first := &gosnmp.GoSNMP{Target: "192.0.2.1"}
+	second := &gosnmp.GoSNMP{Target: "192.0.2.2"}
+	third := &gosnmp.GoSNMP{Target: "192.0.2.3"}
+
+	pool.release("a", first)
+	clock.now = clock.now.Add(time.Second)
+	pool.release("b", second)
+	clock.now = clock.now.Add(time.Second)
+	pool.release("c", third)
+
+	assert.Equal(t, []*gosnmp.GoSNMP{first}, *closed)
+	assert.Equal(t, 2, pool.size())
+}
+
+func TestSNMPSessionPoolDisabled(t *testing.T) {
+	pool := newSNMPSessionPool(-1, time.Minute)
+	require.Nil(t, pool)
+
+	pool.release("a", &gosnmp.GoSNMP{})
+
+	_, _, ok := pool.acquire("a")
+	assert.False(t, ok)
+	assert.Zero(t, pool.evictExpired())
+}
+
+func TestSNMPSessionKeySeparatesCredentials(t *testing.T) {
+	v2 := &SNMPCredentials{Version: SNMPVersion2c, Community: "public"}
+	other := &SNMPCredentials{Version: SNMPVersion2c, Community: "private"}
+
+	key := snmpSessionKey("192.0.2.1", defaultSNMPPort, v2)
+
+	assert.Equal(t, key, snmpSessionKey("192.0.2.1", defaultSNMPPort, &SNMPCredentials{
+		Version: SNMPVersion2c, Community: "public",
+	}))
+	assert.NotEqual(t, key, snmpSessionKey("192.0.2.1", defaultSNMPPort, other))
+	assert.NotEqual(t, key, snmpSessionKey("192.0.2.2", defaultSNMPPort, v2))
+	assert.NotContains(t, key, "public")
+}
+
+func TestSetupSNMPClientResetsStaleV3Session(t *testing.T) {
+	pool, clock, _ := newTestSessionPool(4, time.Hour)
+	engine := &DiscoveryEngine{
+		config:      &Config{Timeout: 5 * time.Second, Retries: 1},
+		logger:      logger.NewTestLogger(),
+		sessionPool: pool,
+	}
+
+	job := &DiscoveryJob{Params: &DiscoveryParams{Credentials: &SNMPCredentials{
+		Version:         SNMPVersion3,
+		Username:        "monitor",
+		AuthProtocol:    "SHA",
+		AuthPassword:    "auth-secret",
+		PrivacyProtocol: "AES",
+		PrivacyPassword: "priv-secret",
+	}}}
+
+	client, key, reused, err := engine.setupSNMPClient(job, "192.0.2.10")
+	require.NoError(t, err)
+	require.False(t, reused)
+
+	usm, ok := client.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	require.True(t, ok)
+
+	// Simulate engine discovery having completed on the first cycle.
+	usm.AuthoritativeEngineID = "engine-1"
+	usm.AuthoritativeEngineBoots = 3
+	client.ContextEngineID = "engine-1"
+
+	pool.release(key, client)
+	clock.now = clock.now.Add(time.Minute)
+
+	reusedClient, _, reused, err := engine.setupSNMPClient(job, "192.0.2.10")
+	require.NoError(t, err)
+	require.True(t, reused)
+	assert.Same(t, client, reusedClient)
+	assert.Equal(t, "engine-1", reusedClient.ContextEngineID, "recently used v3 sessions keep engine state")
+
+	pool.release(key, reusedClient)
+	clock.now = clock.now.Add(snmpV3TimeWindow + time.Second)
+
+	reusedClient, _, reused, err = engine.setupSNMPClient(job, "192.0.2.10")
+	require.NoError(t, err)
+	require.True(t, reused)
+	assert.Empty(t, reusedClient.ContextEngineID)
+
+	usm, ok = reusedClient.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	require.True(t, ok)
+	assert.Empty(t, usm.AuthoritativeEngineID, "stale v3 sessions rediscover the agent engine")
+	assert.Equal(t, "monitor", usm.UserName)
+}
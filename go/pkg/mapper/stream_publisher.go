@@ -0,0 +1,417 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+	"github.com/carverauto/serviceradar/go/pkg/natsutil"
+)
+
+const (
+	// StreamPublisherPull keeps discovery results pull-only.
+	StreamPublisherPull = "pull"
+	// StreamPublisherNATS publishes discovery results to NATS subjects.
+	StreamPublisherNATS = "nats"
+
+	StreamKindDevice       = "device"
+	StreamKindInterface    = "interface"
+	StreamKindTopologyLink = "topology_link"
+
+	defaultDeviceSubject    = "discovery.devices"
+	defaultInterfaceSubject = "discovery.interfaces"
+	defaultTopologySubject  = "discovery.topology"
+
+	defaultPublishBufferSize    = 10000
+	defaultPublishBatchSize     = 100
+	defaultPublishRetries       = 3
+	defaultPublishRetryInterval = 5 * time.Second
+)
+
+var (
+	ErrUnsupportedStreamPublisher = errors.New("unsupported stream publisher")
+	ErrStreamEndpointRequired     = errors.New("stream publisher endpoint is required")
+	ErrStreamBufferFull           = errors.New("stream publish buffer full")
+	errStreamDisconnected         = errors.New("stream connection unavailable")
+)
+
+// StreamMessage is the envelope written to the discovery subjects. Data holds
+// the DiscoveredDevice, DiscoveredInterface or TopologyLink named by Kind.
+type StreamMessage struct {
+	Kind      string    `json:"kind"`
+	AgentID   string    `json:"agent_id,omitempty"`
+	GatewayID string    `json:"gateway_id,omitempty"`
+	Partition string    `json:"partition,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+// StreamPublisherStats reports the publisher's buffer and delivery counters.
+type StreamPublisherStats struct {
+	Pending   int
+	Published uint64
+	Failed    uint64
+	Dropped   uint64
+}
+
+// streamSink delivers one encoded message to a subject.
+type streamSink interface {
+	Publish(ctx context.Context, subject string, payload []byte) error
+	Close()
+}
+
+type pendingStreamMessage struct {
+	subject string
+	payload []byte
+}
+
+// StreamPublisher pushes discovery results to a message stream. Publish
+// calls only encode and buffer the result so discovery never blocks on the
+// stream; Run drains the buffer in batches, retrying failed sends and keeping
+// undelivered messages buffered until the stream comes back. When the buffer
+// is full new results are dropped.
+type StreamPublisher struct {
+	cfg  StreamConfig
+	sink streamSink
+	log  logger.Logger
+	now  func() time.Time
+
+	mu        sync.Mutex
+	queue     []pendingStreamMessage
+	published uint64
+	failed    uint64
+	dropped   uint64
+
+	notify chan struct{}
+}
+
+var _ Publisher = (*StreamPublisher)(nil)
+
+// NewStreamPublisher builds the publisher selected by cfg.Publisher. It
+// returns nil for pull-only configurations.
+func NewStreamPublisher(ctx context.Context, cfg StreamConfig, log logger.Logger) (*StreamPublisher, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Publisher)) {
+	case "", StreamPublisherPull:
+		return nil, nil
+	case StreamPublisherNATS:
+		sink, err := newNATSStreamSink(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return newStreamPublisher(cfg, sink, log), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedStreamPublisher, cfg.Publisher)
+	}
+}
+
+func newStreamPublisher(cfg StreamConfig, sink streamSink, log logger.Logger) *StreamPublisher {
+	if cfg.DeviceStream == "" {
+		cfg.DeviceStream = defaultDeviceSubject
+	}
+	if cfg.InterfaceStream == "" {
+		cfg.InterfaceStream = defaultInterfaceSubject
+	}
+	if cfg.TopologyStream == "" {
+		cfg.TopologyStream = defaultTopologySubject
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultPublishBufferSize
+	}
+	if cfg.PublishBatchSize <= 0 {
+		cfg.PublishBatchSize = defaultPublishBatchSize
+	}
+	if cfg.PublishRetries < 0 {
+		cfg.PublishRetries = 0
+	} else if cfg.PublishRetries == 0 {
+		cfg.PublishRetries = defaultPublishRetries
+	}
+	if cfg.PublishRetryInterval <= 0 {
+		cfg.PublishRetryInterval = defaultPublishRetryInterval
+	}
+
+	return &StreamPublisher{
+		cfg:    cfg,
+		sink:   sink,
+		log:    log,
+		now:    time.Now,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// PublishDevice buffers a discovered device for the device subject.
+func (p *StreamPublisher) PublishDevice(_ context.Context, device *DiscoveredDevice) error {
+	if device == nil {
+		return nil
+	}
+
+	return p.enqueue(p.cfg.DeviceStream, StreamKindDevice, device)
+}
+
+// PublishInterface buffers a discovered interface for the interface subject.
+func (p *StreamPublisher) PublishInterface(_ context.Context, iface *DiscoveredInterface) error {
+	if iface == nil {
+		return nil
+	}
+
+	return p.enqueue(p.cfg.InterfaceStream, StreamKindInterface, iface)
+}
+
+// PublishTopologyLink buffers a topology link for the topology subject.
+func (p *StreamPublisher) PublishTopologyLink(_ context.Context, link *TopologyLink) error {
+	if link == nil {
+		return nil
+	}
+
+	return p.enqueue(p.cfg.TopologyStream, StreamKindTopologyLink, link)
+}
+
+func (p *StreamPublisher) enqueue(subject, kind string, data any) error {
+	payload, err := json.Marshal(StreamMessage{
+		Kind:      kind,
+		AgentID:   p.cfg.AgentID,
+		GatewayID: p.cfg.GatewayID,
+		Partition: p.cfg.Partition,
+		Timestamp: p.now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s for stream: %w", kind, err)
+	}
+
+	p.mu.Lock()
+	if len(p.queue) >= p.cfg.BufferSize {
+		p.dropped++
+		p.mu.Unlock()
+
+		p.log.Warn().
+			Str("kind", kind).
+			Str("subject", subject).
+			Int("buffer_size", p.cfg.BufferSize).
+			Msg("Discovery stream buffer full; dropping result")
+
+		return ErrStreamBufferFull
+	}
+
+	p.queue = append(p.queue, pendingStreamMessage{subject: subject, payload: payload})
+	p.mu.Unlock()
+
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run flushes buffered results until ctx is done. After a failed flush it
+// waits PublishRetryInterval before trying again.
+func (p *StreamPublisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.PublishRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.notify:
+		case <-ticker.C:
+		}
+
+		if err := p.Flush(ctx); err != nil && ctx.Err() == nil {
+			p.log.Warn().Err(err).Int("pending", p.Stats().Pending).Msg("Failed to publish discovery results; will retry")
+		}
+	}
+}
+
+// Flush publishes buffered results in order, a batch at a time. It stops at
+// the first message that still fails after PublishRetries attempts, leaving
+// it and everything after it buffered.
+func (p *StreamPublisher) Flush(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		n := min(len(p.queue), p.cfg.PublishBatchSize)
+		batch := append([]pendingStreamMessage(nil), p.queue[:n]...)
+		p.mu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for i, msg := range batch {
+			if err := p.publishWithRetry(ctx, msg); err != nil {
+				p.dequeue(i)
+				return err
+			}
+		}
+
+		p.dequeue(len(batch))
+	}
+}
+
+func (p *StreamPublisher) publishWithRetry(ctx context.Context, msg pendingStreamMessage) error {
+	var lastErr error
+
+	for attempt := 0; attempt <= p.cfg.PublishRetries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(p.cfg.PublishRetryInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		lastErr = p.sink.Publish(ctx, msg.subject, msg.payload)
+		if lastErr == nil {
+			return nil
+		}
+
+		p.mu.Lock()
+		p.failed++
+		p.mu.Unlock()
+	}
+
+	return fmt.Errorf("failed to publish to %s: %w", msg.subject, lastErr)
+}
+
+func (p *StreamPublisher) dequeue(n int) {
+	if n == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	p.queue = append(p.queue[:0], p.queue[n:]...)
+	p.published += uint64(n)
+	p.mu.Unlock()
+}
+
+// Stats returns a snapshot of the publisher's counters.
+func (p *StreamPublisher) Stats() StreamPublisherStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return StreamPublisherStats{
+		Pending:   len(p.queue),
+		Published: p.published,
+		Failed:    p.failed,
+		Dropped:   p.dropped,
+	}
+}
+
+// Close releases the stream connection. Results still buffered are lost.
+func (p *StreamPublisher) Close() {
+	p.sink.Close()
+}
+
+// multiPublisher fans results out to several publishers.
+type multiPublisher []Publisher
+
+// NewMultiPublisher returns a Publisher that forwards every result to each
+// non-nil publisher and joins their errors.
+func NewMultiPublisher(publishers ...Publisher) Publisher {
+	out := make(multiPublisher, 0, len(publishers))
+	for _, publisher := range publishers {
+		if publisher != nil {
+			out = append(out, publisher)
+		}
+	}
+
+	return out
+}
+
+func (m multiPublisher) PublishDevice(ctx context.Context, device *DiscoveredDevice) error {
+	var errs []error
+	for _, publisher := range m {
+		errs = append(errs, publisher.PublishDevice(ctx, device))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m multiPublisher) PublishInterface(ctx context.Context, iface *DiscoveredInterface) error {
+	var errs []error
+	for _, publisher := range m {
+		errs = append(errs, publisher.PublishInterface(ctx, iface))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m multiPublisher) PublishTopologyLink(ctx context.Context, link *TopologyLink) error {
+	var errs []error
+	for _, publisher := range m {
+		errs = append(errs, publisher.PublishTopologyLink(ctx, link))
+	}
+
+	return errors.Join(errs...)
+}
+
+// natsStreamSink publishes to core NATS subjects. Sends are refused while
+// the connection is down so results stay in the StreamPublisher buffer
+// instead of the client's reconnect buffer.
+type natsStreamSink struct {
+	nc *nats.Conn
+}
+
+func newNATSStreamSink(ctx context.Context, cfg StreamConfig) (*natsStreamSink, error) {
+	if strings.TrimSpace(cfg.Endpoint) == "" {
+		return nil, ErrStreamEndpointRequired
+	}
+
+	opts := []nats.Option{
+		nats.Name("serviceradar-mapper"),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true),
+	}
+	if cfg.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(cfg.CredsFile))
+	}
+
+	nc, err := natsutil.ConnectWithSecurity(ctx, cfg.Endpoint, cfg.Security, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &natsStreamSink{nc: nc}, nil
+}
+
+func (s *natsStreamSink) Publish(ctx context.Context, subject string, payload []byte) error {
+	if !s.nc.IsConnected() {
+		return errStreamDisconnected
+	}
+
+	if err := s.nc.Publish(subject, payload); err != nil {
+		return err
+	}
+
+	return s.nc.FlushWithContext(ctx)
+}
+
+func (s *natsStreamSink) Close() {
+	s.nc.Close()
+}
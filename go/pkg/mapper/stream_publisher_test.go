@@ -0,0 +1,163 @@
+package mapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carverauto/serviceradar/go/pkg/logger"
+)
+
+var errSinkDown = errors.New("sink down")
+
+type fakeStreamSink struct {
+	mu       sync.Mutex
+	fail     bool
+	messages map[string][][]byte
+}
+
+func (s *fakeStreamSink) Publish(_ context.Context, subject string, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail {
+		return errSinkDown
+	}
+
+	if s.messages == nil {
+		s.messages = make(map[string][][]byte)
+	}
+
+	s.messages[subject] = append(s.messages[subject], payload)
+
+	return nil
+}
+
+func (*fakeStreamSink) Close() {}
+
+func (s *fakeStreamSink) setFail(fail bool) {
+	s.mu.Lock()
+	s.fail = fail
+	s.mu.Unlock()
+}
+
+func decodeStreamMessage(t *testing.T, payload []byte) (StreamMessage, map[string]any) {
+	t.Helper()
+
+	var envelope struct {
+		StreamMessage
+		Data map[string]any `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &envelope))
+
+	return envelope.StreamMessage, envelope.Data
+}
+
+func TestStreamPublisherPublishesDiscoveryResults(t *testing.T) {
+	sink := &fakeStreamSink{}
+	publisher := newStreamPublisher(StreamConfig{
+		AgentID:   "agent-1",
+		GatewayID: "gw-1",
+		Partition: "default",
+	}, sink, logger.NewTestLogger())
+	publisher.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	ctx := context.Background()
+	require.NoError(t, publisher.PublishDevice(ctx, &DiscoveredDevice{DeviceID: "default:10.0.0.1", IP: "10.0.0.1", Hostname: "core-sw"}))
+	require.NoError(t, publisher.PublishInterface(ctx, &DiscoveredInterface{DeviceID: "default:10.0.0.1", IfIndex: 3, IfName: "ge-0/0/3"}))
+	require.NoError(t, publisher.PublishTopologyLink(ctx, &TopologyLink{Protocol: "LLDP", LocalDeviceIP: "10.0.0.1", LocalIfIndex: 3}))
+	require.NoError(t, publisher.Flush(ctx))
+
+	require.Len(t, sink.messages[defaultDeviceSubject], 1)
+	require.Len(t, sink.messages[defaultInterfaceSubject], 1)
+	require.Len(t, sink.messages[defaultTopologySubject], 1)
+
+	msg, data := decodeStreamMessage(t, sink.messages[defaultDeviceSubject][0])
+	assert.Equal(t, StreamKindDevice, msg.Kind)
+	assert.Equal(t, "agent-1", msg.AgentID)
+	assert.Equal(t, "gw-1", msg.GatewayID)
+	assert.Equal(t, "default", msg.Partition)
+	assert.Equal(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), msg.Timestamp)
+	assert.Equal(t, "10.0.0.1", data["IP"])
+	assert.Equal(t, "core-sw", data["Hostname"])
+
+	msg, data = decodeStreamMessage(t, sink.messages[defaultInterfaceSubject][0])
+	assert.Equal(t, StreamKindInterface, msg.Kind)
+	assert.Equal(t, "ge-0/0/3", data["IfName"])
+
+	msg, data = decodeStreamMessage(t, sink.messages[defaultTopologySubject][0])
+	assert.Equal(t, StreamKindTopologyLink, msg.Kind)
+	assert.Equal(t, "LLDP", data["Protocol"])
+
+	assert.Equal(t, StreamPublisherStats{Published: 3}, publisher.Stats())
+}
+
+func TestStreamPublisherBuffersUntilStreamRecovers(t *testing.T) {
+	sink := &fakeStreamSink{fail: true}
+	publisher := newStreamPublisher(StreamConfig{
+		DeviceStream:         "custom.devices",
+		PublishRetries:       -1,
+		PublishRetryInterval: time.Millisecond,
+		BufferSize:           2,
+	}, sink, logger.NewTestLogger())
+
+	ctx := context.Background()
+	require.NoError(t, publisher.PublishDevice(ctx, &DiscoveredDevice{IP: "10.0.0.1"}))
+	require.NoError(t, publisher.PublishDevice(ctx, &DiscoveredDevice{IP: "10.0.0.2"}))
+	require.ErrorIs(t, publisher.PublishDevice(ctx, &DiscoveredDevice{IP: "10.0.0.3"}), ErrStreamBufferFull)
+
+	require.ErrorIs(t, publisher.Flush(ctx), errSinkDown)
+	assert.Equal(t, StreamPublisherStats{Pending: 2, Failed: 1, Dropped: 1}, publisher.Stats())
+
+	sink.setFail(false)
+	require.NoError(t, publisher.Flush(ctx))
+
+	require.Len(t, sink.messages["custom.devices"], 2)
+	_, first := decodeStreamMessage(t, sink.messages["custom.devices"][0])
+	_, second := decodeStreamMessage(t, sink.messages["custom.devices"][1])
+	assert.Equal(t, "10.0.0.1", first["IP"])
+	assert.Equal(t, "10.0.0.2", second["IP"])
+	assert.Equal(t, 0, publisher.Stats().Pending)
+}
+
+func TestNewStreamPublisherSelection(t *testing.T) {
+	publisher, err := NewStreamPublisher(context.Background(), StreamConfig{}, logger.NewTestLogger())
+	require.NoError(t, err)
+	assert.Nil(t, publisher)
+
+	_, err = NewStreamPublisher(context.Background(), StreamConfig{Publisher: "kafka"}, logger.NewTestLogger())
+	require.ErrorIs(t, err, ErrUnsupportedStreamPublisher)
+
+	_, err = NewStreamPublisher(context.Background(), StreamConfig{Publisher: StreamPublisherNATS}, logger.NewTestLogger())
+	require.ErrorIs(t, err, ErrStreamEndpointRequired)
+}
+
+func TestConfigUnmarshalStreamConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"workers": 1,
+		"stream_config": {
+			"publisher": "nats",
+			"endpoint": "nats://nats:4222",
+			"creds_file": "/etc/serviceradar/creds/mapper.creds",
+			"device_stream": "discovery.devices",
+			"agent_id": "agent-1",
+			"publish_retries": 5,
+			"publish_retry_interval": "2s"
+		}
+	}`), &cfg))
+
+	assert.Equal(t, StreamPublisherNATS, cfg.StreamConfig.Publisher)
+	assert.Equal(t, "nats://nats:4222", cfg.StreamConfig.Endpoint)
+	assert.Equal(t, "/etc/serviceradar/creds/mapper.creds", cfg.StreamConfig.CredsFile)
+	assert.Equal(t, "discovery.devices", cfg.StreamConfig.DeviceStream)
+	assert.Equal(t, "agent-1", cfg.StreamConfig.AgentID)
+	assert.Equal(t, 5, cfg.StreamConfig.PublishRetries)
+	assert.Equal(t, 2*time.Second, cfg.StreamConfig.PublishRetryInterval)
+}
@@ -41,6 +41,8 @@ type DiscoveryEngine struct {
 	schedulers    map[string]*time.Ticker
 	logger        logger.Logger
 	hostProber    HostProber
+	sessionPool   *snmpSessionPool
+	vendorMatcher *vendorOIDMatcher
 }
 
 // HostProber provides advisory host reachability checks for worker scheduling.
@@ -425,8 +427,11 @@ type Config struct {
 	Retries            int                        `json:"retries"`
 	MaxActiveJobs      int                        `json:"max_active_jobs"`
 	ResultRetention    time.Duration              `json:"result_retention"`
+	SessionPoolSize    int                        `json:"session_pool_size"`    // Max idle SNMP sessions kept between cycles; negative disables reuse
+	SessionIdleTimeout time.Duration              `json:"session_idle_timeout"` // Idle SNMP sessions older than this are closed
 	DefaultCredentials SNMPCredentials            `json:"default_credentials"`
 	OIDs               map[DiscoveryType][]string `json:"oids"`
+	VendorOIDs         []VendorOIDMapping         `json:"vendor_oids"` // Extra sysObjectID prefix -> vendor/model mappings
 	StreamConfig       StreamConfig               `json:"stream_config"`
 	Credentials        []SNMPCredentialConfig     `json:"credentials"`
 	Seeds              []string                   `json:"seeds"`
@@ -472,9 +477,10 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 	type Alias Config
 
 	aux := &struct {
-		Timeout         string `json:"timeout"`
-		ResultRetention string `json:"result_retention"`
-		StreamConfig    struct {
+		Timeout            string `json:"timeout"`
+		ResultRetention    string `json:"result_retention"`
+		SessionIdleTimeout string `json:"session_idle_timeout"`
+		StreamConfig       struct {
 			DeviceStream         string `json:"device_stream"`
 			InterfaceStream      string `json:"interface_stream"`
 			TopologyStream       string `json:"topology_stream"`
@@ -484,6 +490,12 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 			PublishBatchSize     int    `json:"publish_batch_size"`
 			PublishRetries       int    `json:"publish_retries"`
 			PublishRetryInterval string `json:"publish_retry_interval"`
+			Publisher            string `json:"publisher"`
+			Endpoint             string `json:"endpoint"`
+			CredsFile            string `json:"creds_file"`
+			BufferSize           int    `json:"buffer_size"`
+
+			Security *models.SecurityConfig `json:"security"`
 		} `json:"stream_config"`
 		ScheduledJobs []struct {
 			Name          string            `json:"name"`
@@ -527,6 +539,32 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 		c.ResultRetention = duration
 	}
 
+	// Parse SessionIdleTimeout
+	if aux.SessionIdleTimeout != "" {
+		duration, err := time.ParseDuration(aux.SessionIdleTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid session_idle_timeout format: %w", err)
+		}
+
+		c.SessionIdleTimeout = duration
+	}
+
+	c.StreamConfig = StreamConfig{
+		DeviceStream:     aux.StreamConfig.DeviceStream,
+		InterfaceStream:  aux.StreamConfig.InterfaceStream,
+		TopologyStream:   aux.StreamConfig.TopologyStream,
+		AgentID:          aux.StreamConfig.AgentID,
+		GatewayID:        aux.StreamConfig.GatewayID,
+		Partition:        aux.StreamConfig.Partition,
+		PublishBatchSize: aux.StreamConfig.PublishBatchSize,
+		PublishRetries:   aux.StreamConfig.PublishRetries,
+		Publisher:        aux.StreamConfig.Publisher,
+		Endpoint:         aux.StreamConfig.Endpoint,
+		CredsFile:        aux.StreamConfig.CredsFile,
+		BufferSize:       aux.StreamConfig.BufferSize,
+		Security:         aux.StreamConfig.Security,
+	}
+
 	// Parse StreamConfig.PublishRetryInterval
 	if aux.StreamConfig.PublishRetryInterval != "" {
 		duration, err := time.ParseDuration(aux.StreamConfig.PublishRetryInterval)
@@ -572,6 +610,15 @@ type StreamConfig struct {
 	PublishBatchSize     int
 	PublishRetries       int
 	PublishRetryInterval time.Duration
+
+	// Publisher selects where results are pushed in addition to the pull
+	// API: "" or "pull" keeps results pull-only, "nats" publishes them to
+	// the Device/Interface/TopologyStream subjects on Endpoint.
+	Publisher  string
+	Endpoint   string
+	CredsFile  string
+	BufferSize int
+	Security   *models.SecurityConfig
 }
 
 // DeviceInterfaceMap represents a consolidated view of a device with all its interfaces and associated network identifiers.
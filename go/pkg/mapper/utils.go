@@ -50,6 +50,10 @@ func (e *DiscoveryEngine) cleanupRoutine(ctx context.Context) {
 			return
 		case <-ticker.C:
 			e.cleanupCompletedJobs()
+
+			if evicted := e.sessionPool.evictExpired(); evicted > 0 {
+				e.logger.Debug().Int("evicted_count", evicted).Msg("Closed idle SNMP sessions")
+			}
 		}
 	}
 }
@@ -77,16 +81,11 @@ func (e *DiscoveryEngine) cleanupCompletedJobs() {
 
 // createSNMPClient creates an SNMP client for the given target and credentials
 func (e *DiscoveryEngine) createSNMPClient(targetIP string, credentials *SNMPCredentials) (*gosnmp.GoSNMP, error) {
-	// Check if there are target-specific credentials
-	if credentials.TargetSpecific != nil {
-		if targetCreds, ok := credentials.TargetSpecific[targetIP]; ok {
-			credentials = targetCreds
-		}
-	}
+	credentials = targetCredentials(targetIP, credentials)
 
 	client := &gosnmp.GoSNMP{
 		Target:             targetIP,
-		Port:               161, // Default SNMP port
+		Port:               defaultSNMPPort,
 		Timeout:            e.config.Timeout,
 		Retries:            e.config.Retries,
 		MaxOids:            gosnmp.MaxOids,
@@ -103,6 +102,16 @@ func (e *DiscoveryEngine) createSNMPClient(targetIP string, credentials *SNMPCre
 	return client, nil
 }
 
+// targetCredentials returns the target-specific credentials for targetIP when
+// configured, otherwise the shared credentials.
+func targetCredentials(targetIP string, credentials *SNMPCredentials) *SNMPCredentials {
+	if targetCreds, ok := credentials.TargetSpecific[targetIP]; ok {
+		return targetCreds
+	}
+
+	return credentials
+}
+
 // configureClientVersion sets up the SNMP client based on the version in the credentials
 func (e *DiscoveryEngine) configureClientVersion(client *gosnmp.GoSNMP, credentials *SNMPCredentials) error {
 	switch credentials.Version {
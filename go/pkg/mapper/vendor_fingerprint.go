@@ -0,0 +1,166 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mapper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VendorOIDMapping maps a sysObjectID prefix to a vendor and, for product
+// OIDs, a model. Prefixes are matched on OID arc boundaries and the longest
+// matching prefix wins, so a model entry refines its vendor's enterprise entry.
+type VendorOIDMapping struct {
+	Prefix string `json:"prefix"`
+	Vendor string `json:"vendor"`
+	Model  string `json:"model,omitempty"`
+}
+
+// defaultVendorOIDs seeds fingerprinting with common enterprise numbers and a
+// few well-known product OIDs. Config entries with the same prefix override
+// these.
+//
+//nolint:gochecknoglobals // static seed table
+var defaultVendorOIDs = []VendorOIDMapping{
+	{Prefix: ".1.3.6.1.4.1.9", Vendor: "Cisco"},
+	{Prefix: ".1.3.6.1.4.1.9.1.516", Vendor: "Cisco", Model: "Catalyst 3750 Stack"},
+	{Prefix: ".1.3.6.1.4.1.9.1.1745", Vendor: "Cisco", Model: "Catalyst 3850 Stack"},
+	{Prefix: ".1.3.6.1.4.1.2636", Vendor: "Juniper"},
+	{Prefix: ".1.3.6.1.4.1.2636.1.1.1.2.21", Vendor: "Juniper", Model: "MX960"},
+	{Prefix: ".1.3.6.1.4.1.2636.1.1.1.2.25", Vendor: "Juniper", Model: "MX480"},
+	{Prefix: ".1.3.6.1.4.1.2636.1.1.1.2.29", Vendor: "Juniper", Model: "MX240"},
+	{Prefix: ".1.3.6.1.4.1.30065", Vendor: "Arista"},
+	{Prefix: ".1.3.6.1.4.1.11", Vendor: "HPE"},
+	{Prefix: ".1.3.6.1.4.1.14823", Vendor: "Aruba"},
+	{Prefix: ".1.3.6.1.4.1.12356", Vendor: "Fortinet"},
+	{Prefix: ".1.3.6.1.4.1.25461", Vendor: "Palo Alto Networks"},
+	{Prefix: ".1.3.6.1.4.1.14988", Vendor: "MikroTik"},
+	{Prefix: ".1.3.6.1.4.1.41112", Vendor: "Ubiquiti"},
+	{Prefix: ".1.3.6.1.4.1.8072", Vendor: "Net-SNMP"},
+}
+
+// vendorOIDMatcher resolves sysObjectIDs against mappings sorted by
+// descending prefix length.
+type vendorOIDMatcher struct {
+	mappings []VendorOIDMapping
+}
+
+// newVendorOIDMatcher merges the seed table with configured mappings.
+func newVendorOIDMatcher(extra []VendorOIDMapping) (*vendorOIDMatcher, error) {
+	byPrefix := make(map[string]VendorOIDMapping, len(defaultVendorOIDs)+len(extra))
+
+	for _, m := range defaultVendorOIDs {
+		byPrefix[m.Prefix] = m
+	}
+
+	for i, m := range extra {
+		prefix, ok := normalizeOID(m.Prefix)
+		if !ok {
+			return nil, fmt.Errorf("%w: vendor_oids[%d] prefix %q", ErrInvalidVendorOID, i, m.Prefix)
+		}
+
+		if strings.TrimSpace(m.Vendor) == "" {
+			return nil, fmt.Errorf("%w: vendor_oids[%d] has no vendor", ErrInvalidVendorOID, i)
+		}
+
+		m.Prefix = prefix
+		byPrefix[prefix] = m
+	}
+
+	matcher := &vendorOIDMatcher{mappings: make([]VendorOIDMapping, 0, len(byPrefix))}
+	for _, m := range byPrefix {
+		matcher.mappings = append(matcher.mappings, m)
+	}
+
+	sort.Slice(matcher.mappings, func(i, j int) bool {
+		if len(matcher.mappings[i].Prefix) != len(matcher.mappings[j].Prefix) {
+			return len(matcher.mappings[i].Prefix) > len(matcher.mappings[j].Prefix)
+		}
+
+		return matcher.mappings[i].Prefix < matcher.mappings[j].Prefix
+	})
+
+	return matcher, nil
+}
+
+// match returns the most specific mapping for sysObjectID.
+func (m *vendorOIDMatcher) match(sysObjectID string) (VendorOIDMapping, bool) {
+	oid, ok := normalizeOID(sysObjectID)
+	if !ok {
+		return VendorOIDMapping{}, false
+	}
+
+	for _, mapping := range m.mappings {
+		if oid == mapping.Prefix || strings.HasPrefix(oid, mapping.Prefix+".") {
+			return mapping, true
+		}
+	}
+
+	return VendorOIDMapping{}, false
+}
+
+// normalizeOID returns oid in dotted form with a leading dot, or false if it
+// is not a numeric OID.
+func normalizeOID(oid string) (string, bool) {
+	oid = strings.TrimPrefix(strings.TrimSpace(oid), ".")
+	if oid == "" {
+		return "", false
+	}
+
+	for _, arc := range strings.Split(oid, ".") {
+		if arc == "" || strings.Trim(arc, "0123456789") != "" {
+			return "", false
+		}
+	}
+
+	return "." + oid, true
+}
+
+// applyVendorFingerprint enriches the device with the vendor and model
+// mapped from its sysObjectID. Unmatched OIDs are kept verbatim so they can
+// be classified later. Vendor details reported by a richer source (such as a
+// controller API) are left untouched.
+func (e *DiscoveryEngine) applyVendorFingerprint(device *DiscoveredDevice) {
+	if device == nil || device.SysObjectID == "" {
+		return
+	}
+
+	if device.Metadata == nil {
+		device.Metadata = make(map[string]string)
+	}
+
+	matcher := e.vendorMatcher
+	if matcher == nil {
+		matcher, _ = newVendorOIDMatcher(nil)
+	}
+
+	mapping, ok := matcher.match(device.SysObjectID)
+	if !ok {
+		device.Metadata["sys_object_id"] = device.SysObjectID
+
+		return
+	}
+
+	if device.Metadata["vendor_name"] == "" {
+		device.Metadata["vendor_name"] = mapping.Vendor
+	}
+
+	if mapping.Model != "" && device.Metadata["model"] == "" {
+		device.Metadata["model"] = mapping.Model
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVendorFingerprintMatchesKnownEnterpriseOIDs(t *testing.T) {
+	engine := &DiscoveryEngine{}
+
+	tests := []struct {
+		sysObjectID string
+		vendor      string
+		model       string
+	}{
+		{sysObjectID: ".1.3.6.1.4.1.9.1.1745", vendor: "Cisco", model: "Catalyst 3850 Stack"},
+		{sysObjectID: "1.3.6.1.4.1.9.1.2494", vendor: "Cisco"},
+		{sysObjectID: ".1.3.6.1.4.1.2636.1.1.1.2.29", vendor: "Juniper", model: "MX240"},
+		{sysObjectID: ".1.3.6.1.4.1.30065.1.3011.7050.3741.48", vendor: "Arista"},
+	}
+
+	for _, tt := range tests {
+		device := &DiscoveredDevice{SysObjectID: tt.sysObjectID, Metadata: map[string]string{}}
+		engine.applyVendorFingerprint(device)
+
+		assert.Equal(t, tt.vendor, device.Metadata["vendor_name"], tt.sysObjectID)
+		assert.Equal(t, tt.model, device.Metadata["model"], tt.sysObjectID)
+		assert.NotContains(t, device.Metadata, "sys_object_id", tt.sysObjectID)
+	}
+}
+
+func TestVendorFingerprintKeepsUnknownOIDs(t *testing.T) {
+	engine := &DiscoveryEngine{}
+
+	for _, oid := range []string{".1.3.6.1.4.1.99999.1.2", ".1.3.6.1.4.1.90", "not-an-oid"} {
+		device := &DiscoveredDevice{SysObjectID: oid, Metadata: map[string]string{}}
+		engine.applyVendorFingerprint(device)
+
+		assert.Equal(t, oid, device.Metadata["sys_object_id"], "enterprise 9 must not match 90")
+		assert.NotContains(t, device.Metadata, "vendor_name")
+	}
+}
+
+func TestVendorFingerprintUsesConfiguredMappings(t *testing.T) {
+	matcher, err := newVendorOIDMatcher([]VendorOIDMapping{
+		{Prefix: "1.3.6.1.4.1.99999", Vendor: "Acme"},
+		{Prefix: ".1.3.6.1.4.1.30065.1.3011.7050", Vendor: "Arista", Model: "7050 Series"},
+	})
+	require.NoError(t, err)
+
+	engine := &DiscoveryEngine{vendorMatcher: matcher}
+
+	device := &DiscoveredDevice{SysObjectID: ".1.3.6.1.4.1.99999.1.2", Metadata: map[string]string{}}
+	engine.applyVendorFingerprint(device)
+	assert.Equal(t, "Acme", device.Metadata["vendor_name"])
+
+	device = &DiscoveredDevice{SysObjectID: ".1.3.6.1.4.1.30065.1.3011.7050.3741.48", Metadata: map[string]string{}}
+	engine.applyVendorFingerprint(device)
+	assert.Equal(t, "7050 Series", device.Metadata["model"])
+
+	device = &DiscoveredDevice{
+		SysObjectID: ".1.3.6.1.4.1.14988.1",
+		Metadata:    map[string]string{"vendor_name": "MikroTik", "model": "CCR2004"},
+	}
+	engine.applyVendorFingerprint(device)
+	assert.Equal(t, "CCR2004", device.Metadata["model"], "richer sources are not overwritten")
+
+	_, err = newVendorOIDMatcher([]VendorOIDMapping{{Prefix: "1.3.x", Vendor: "Bad"}})
+	require.ErrorIs(t, err, ErrInvalidVendorOID)
+
+	_, err = newVendorOIDMatcher([]VendorOIDMapping{{Prefix: "1.3.6.1.4.1.1"}})
+	require.ErrorIs(t, err, ErrInvalidVendorOID)
+}
@@ -17,6 +17,14 @@ type CNPGDatabase struct {
 	HealthCheckPeriod  Duration          `json:"health_check_period,omitempty"`
 	StatementTimeout   Duration          `json:"statement_timeout,omitempty"`
 	ExtraRuntimeParams map[string]string `json:"runtime_params,omitempty"`
+	SearchPath         []string          `json:"search_path,omitempty"`          // Applied to every pooled connection
+	SlowQueryThreshold Duration          `json:"slow_query_threshold,omitempty"` // Queries at or over this are logged (0 disables)
+	CaptureQueryPlans  bool              `json:"capture_query_plans,omitempty"`  // EXPLAIN queries over slow_query_threshold and log the plan
+	ForceQueryPlans    bool              `json:"force_query_plans,omitempty"`    // EXPLAIN and log every query regardless of slow_query_threshold (debugging only)
+	LogStatements      bool              `json:"log_statements,omitempty"`       // Log every statement (parameterized) for debugging
+	LogStatementParams bool              `json:"log_statement_params,omitempty"` // Also log parameter values; may expose sensitive data
+	StatementCacheSize int               `json:"statement_cache_size,omitempty"` // Prepared statements cached per connection (default: 512)
+	NoStatementCache   bool              `json:"no_statement_cache,omitempty"`   // Run every query unprepared (e.g. behind a transaction pooler)
 }
 
 type Metrics struct {
@@ -100,3 +100,32 @@ type DeviceLifecycleEventData struct {
 	RemoteAddr string            `json:"remote_addr,omitempty"`
 	Metadata   map[string]string `json:"metadata,omitempty"`
 }
+
+// ConfigValueChange is a config value before and after a change.
+type ConfigValueChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// ConfigDiff is the difference between two JSON config values. Each map is
+// keyed by the JSON pointer of the value within the config.
+type ConfigDiff struct {
+	Added   map[string]interface{}       `json:"added,omitempty"`
+	Removed map[string]interface{}       `json:"removed,omitempty"`
+	Changed map[string]ConfigValueChange `json:"changed,omitempty"`
+}
+
+// Empty reports whether the diff holds no changes.
+func (d *ConfigDiff) Empty() bool {
+	return d == nil || len(d.Added)+len(d.Removed)+len(d.Changed) == 0
+}
+
+// ConfigChangeEventData represents a change to a config value in the KV store.
+type ConfigChangeEventData struct {
+	Key              string      `json:"key"`
+	Operation        string      `json:"operation"` // put or delete
+	Revision         uint64      `json:"revision"`
+	PreviousRevision uint64      `json:"previous_revision,omitempty"`
+	Diff             *ConfigDiff `json:"diff"`
+	Timestamp        time.Time   `json:"timestamp"`
+}
@@ -22,6 +22,11 @@ type OTELLogRow struct {
 	ScopeAttributes    string
 	Attributes         string
 	ResourceAttributes string
+	// DeviceID, DevicePartition and DeviceTags identify the device the log's
+	// source host resolved to when log enrichment is enabled.
+	DeviceID        string
+	DevicePartition string
+	DeviceTags      map[string]string
 }
 
 // OTELMetricRow captures a single OTEL performance metric sample.
@@ -36,12 +36,29 @@ type SourceConfig struct {
 	// for this source. If empty, uses the global DiscoveryInterval from the sync config.
 	DiscoveryInterval Duration `json:"discovery_interval,omitempty"`
 
+	// Schedule is an optional five-field cron expression (or @hourly-style
+	// descriptor) that drives discovery runs instead of DiscoveryInterval,
+	// e.g. "0 9-17 * * 1-5" to sync hourly during business hours.
+	Schedule string `json:"schedule,omitempty"`
+
+	// ScheduleTimezone is the IANA zone Schedule is evaluated in. Defaults to UTC.
+	ScheduleTimezone string `json:"schedule_timezone,omitempty"`
+
+	// WebhookSecret enables push-triggered runs: notifications signed with
+	// this secret trigger an out-of-schedule sync of the source.
+	WebhookSecret string `json:"webhook_secret,omitempty" sensitive:"true"`
+
 	// NetworkBlacklist contains CIDR ranges to filter out from this specific source
 	NetworkBlacklist []string `json:"network_blacklist,omitempty"`
 
 	// BatchSize configures the number of items to process in each batch
 	// for bulk operations. If not specified, a default will be used.
 	BatchSize int `json:"batch_size,omitempty"`
+
+	// DryRun marks the source's results as a reconcile preview: core plans
+	// the inventory changes they would make and records the plan instead of
+	// writing devices.
+	DryRun bool `json:"dry_run,omitempty"`
 }
 
 // QueryConfig represents a single labeled query.
@@ -13,6 +13,7 @@ import (
 	"github.com/nats-io/nats.go/jetstream"
 	"github.com/rs/zerolog"
 
+	configkv "github.com/carverauto/serviceradar/go/pkg/config/kv"
 	"github.com/carverauto/serviceradar/go/pkg/logger"
 	"github.com/carverauto/serviceradar/go/pkg/models"
 )
@@ -188,6 +189,31 @@ func (p *EventPublisher) PublishDeviceLifecycleEvent(ctx context.Context, data *
 	return p.publishEvent(ctx, ocsfEventsSubject, event, event.ID)
 }
 
+// PublishConfigChangeEvent publishes the diff of a config value changed in the KV store.
+func (p *EventPublisher) PublishConfigChangeEvent(ctx context.Context, data *models.ConfigChangeEventData) error {
+	if data == nil {
+		return ErrEventPayloadNil
+	}
+
+	if data.Timestamp.IsZero() {
+		data.Timestamp = time.Now().UTC()
+	}
+	if data.Diff == nil {
+		data.Diff = &models.ConfigDiff{}
+	}
+
+	message := fmt.Sprintf("Config %s changed: %d added, %d removed, %d changed",
+		data.Key, len(data.Diff.Added), len(data.Diff.Removed), len(data.Diff.Changed))
+	if data.Operation == configkv.RevisionOpDelete {
+		message = fmt.Sprintf("Config %s deleted", data.Key)
+	}
+
+	event := buildOCSFEvent(message, data.Timestamp, 1, severityInformational)
+	event.Unmapped = configChangeUnmapped(data)
+
+	return p.publishEvent(ctx, ocsfEventsSubject, event, event.ID)
+}
+
 func (p *EventPublisher) publishEvent(ctx context.Context, subject string, event *ocsfEvent, eventID string) error {
 	if event == nil {
 		return ErrEventPayloadNil
@@ -646,3 +672,22 @@ func deviceLifecycleUnmapped(data *models.DeviceLifecycleEventData) map[string]a
 
 	return unmapped
 }
+
+func configChangeUnmapped(data *models.ConfigChangeEventData) map[string]any {
+	if data == nil {
+		return nil
+	}
+
+	unmapped := map[string]any{
+		"config_key": data.Key,
+		"operation":  data.Operation,
+		"revision":   data.Revision,
+		"diff":       data.Diff,
+	}
+
+	if data.PreviousRevision != 0 {
+		unmapped["previous_revision"] = data.PreviousRevision
+	}
+
+	return unmapped
+}
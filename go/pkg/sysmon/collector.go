@@ -65,6 +65,8 @@ type DefaultCollector struct {
 	stoppedCh    chan struct{}
 	cancel       context.CancelFunc
 	cpuCollector *CPUCollector
+	diskIORates  *counterRates
+	networkRates *counterRates
 }
 
 // CollectorOption configures a DefaultCollector.
@@ -99,7 +101,9 @@ func NewCollector(config *ParsedConfig, opts ...CollectorOption) (*DefaultCollec
 		stoppedCh: make(chan struct{}),
 		// Default buffer size 60 (e.g. 1 minute at 1s interval)
 		// TODO: Make configurable
-		buffer: core.NewRingBuffer[*MetricSample](60),
+		buffer:       core.NewRingBuffer[*MetricSample](60),
+		diskIORates:  newCounterRates(),
+		networkRates: newCounterRates(),
 	}
 
 	// Apply options
@@ -146,6 +150,7 @@ func (c *DefaultCollector) Start(ctx context.Context) error {
 		Bool("collect_cpu", c.config.CollectCPU).
 		Bool("collect_memory", c.config.CollectMemory).
 		Bool("collect_disk", c.config.CollectDisk).
+		Bool("collect_disk_io", c.config.CollectDiskIO).
 		Bool("collect_network", c.config.CollectNetwork).
 		Bool("collect_processes", c.config.CollectProcesses).
 		Msg("sysmon collector started")
@@ -219,6 +224,12 @@ func (c *DefaultCollector) Collect(ctx context.Context) (*MetricSample, error) {
 		if err != nil {
 			c.log.Warn().Err(err).Msg("disk collection failed")
 		} else {
+			if config.CollectDiskIO {
+				if err := attachDiskIO(ctx, disks, c.diskIORates, time.Now()); err != nil {
+					c.log.Debug().Err(err).Msg("disk I/O counters unavailable")
+				}
+			}
+
 			sample.Disks = disks
 		}
 	}
@@ -229,6 +240,7 @@ func (c *DefaultCollector) Collect(ctx context.Context) (*MetricSample, error) {
 		if err != nil {
 			c.log.Warn().Err(err).Msg("network collection failed")
 		} else {
+			applyNetworkRates(network, c.networkRates, time.Now())
 			sample.Network = network
 		}
 	}
@@ -273,6 +285,7 @@ func (c *DefaultCollector) Reconfigure(config *ParsedConfig) error {
 		Bool("collect_cpu", config.CollectCPU).
 		Bool("collect_memory", config.CollectMemory).
 		Bool("collect_disk", config.CollectDisk).
+		Bool("collect_disk_io", config.CollectDiskIO).
 		Bool("collect_network", config.CollectNetwork).
 		Bool("collect_processes", config.CollectProcesses).
 		Msg("sysmon collector reconfigured")
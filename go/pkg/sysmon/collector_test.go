@@ -388,3 +388,128 @@ func TestMetricSampleJSONCompatibility(t *testing.T) {
 
 	t.Logf("JSON output:\n%s", string(data))
 }
+
+func TestCounterRates(t *testing.T) {
+	rates := newCounterRates()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first := rates.observe("sda", t0, 1000, 50)
+	for i, r := range first {
+		if r != nil {
+			t.Errorf("first reading: expected nil rate for counter %d, got %v", i, *r)
+		}
+	}
+
+	second := rates.observe("sda", t0.Add(10*time.Second), 6000, 50)
+	if second[0] == nil || *second[0] != 500 {
+		t.Errorf("expected 500/s, got %v", second[0])
+	}
+
+	if second[1] == nil || *second[1] != 0 {
+		t.Errorf("expected 0/s for an idle counter, got %v", second[1])
+	}
+
+	// A counter that goes backwards was reset; its rate is skipped for one
+	// sample and resumes from the new baseline.
+	reset := rates.observe("sda", t0.Add(20*time.Second), 100, 150)
+	if reset[0] != nil {
+		t.Errorf("expected nil rate after counter reset, got %v", *reset[0])
+	}
+
+	if reset[1] == nil || *reset[1] != 10 {
+		t.Errorf("expected 10/s for the unaffected counter, got %v", reset[1])
+	}
+
+	resumed := rates.observe("sda", t0.Add(25*time.Second), 600, 150)
+	if resumed[0] == nil || *resumed[0] != 100 {
+		t.Errorf("expected 100/s after reset, got %v", resumed[0])
+	}
+
+	same := rates.observe("sda", t0.Add(25*time.Second), 700, 150)
+	if same[0] != nil {
+		t.Errorf("expected nil rate when no time elapsed, got %v", *same[0])
+	}
+}
+
+func TestCounterRatesRetain(t *testing.T) {
+	rates := newCounterRates()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rates.observe("eth0", t0, 1)
+	rates.observe("eth1", t0, 1)
+	rates.retain(map[string]struct{}{"eth0": {}})
+
+	if r := rates.observe("eth0", t0.Add(time.Second), 2); r[0] == nil {
+		t.Error("expected retained interface to keep its baseline")
+	}
+
+	if r := rates.observe("eth1", t0.Add(time.Second), 2); r[0] != nil {
+		t.Error("expected dropped interface to start from a new baseline")
+	}
+}
+
+func TestApplyNetworkRates(t *testing.T) {
+	rates := newCounterRates()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	applyNetworkRates([]NetworkMetric{{Interface: "eth0", BytesSent: 1000, BytesRecv: 2000}}, rates, t0)
+
+	metrics := []NetworkMetric{{Interface: "eth0", BytesSent: 3000, BytesRecv: 2500, PacketsSent: 20, PacketsRecv: 40}}
+	applyNetworkRates(metrics, rates, t0.Add(2*time.Second))
+
+	m := metrics[0]
+	if m.BytesSentPerSec == nil || *m.BytesSentPerSec != 1000 {
+		t.Errorf("expected 1000 B/s sent, got %v", m.BytesSentPerSec)
+	}
+
+	if m.BytesRecvPerSec == nil || *m.BytesRecvPerSec != 250 {
+		t.Errorf("expected 250 B/s received, got %v", m.BytesRecvPerSec)
+	}
+
+	if m.PacketsRecvPerSec == nil || *m.PacketsRecvPerSec != 20 {
+		t.Errorf("expected 20 pkt/s received, got %v", m.PacketsRecvPerSec)
+	}
+}
+
+func TestWholeDiskName(t *testing.T) {
+	tests := map[string]string{
+		"disk3s1s1": "disk3",
+		"disk0s2":   "disk0",
+		"disk0":     "",
+		"sda1":      "",
+		"diskette":  "",
+	}
+
+	for name, want := range tests {
+		got, ok := wholeDiskName(name)
+		if ok != (want != "") || got != want {
+			t.Errorf("wholeDiskName(%q) = %q, %v; want %q", name, got, ok, want)
+		}
+	}
+}
+
+func TestCollectorCollectDiskIO(t *testing.T) {
+	config := &ParsedConfig{
+		Enabled:        true,
+		SampleInterval: time.Second,
+		CollectDisk:    true,
+		CollectDiskIO:  true,
+	}
+
+	collector, err := NewCollector(config)
+	if err != nil {
+		t.Fatalf("NewCollector failed: %v", err)
+	}
+
+	// I/O counters are platform dependent; collection must succeed either way.
+	sample, err := collector.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	for _, d := range sample.Disks {
+		if d.IO != nil && d.IO.ReadBytesPerSec != nil {
+			t.Errorf("expected no I/O rates on the first sample for %s", d.MountPoint)
+		}
+	}
+}
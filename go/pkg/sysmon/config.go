@@ -56,6 +56,10 @@ type Config struct {
 	// CollectDisk enables disk metrics collection.
 	CollectDisk bool `json:"collect_disk"`
 
+	// CollectDiskIO attaches block device I/O counters and rates to disk
+	// metrics. It has no effect unless CollectDisk is also enabled.
+	CollectDiskIO bool `json:"collect_disk_io"`
+
 	// CollectNetwork enables network interface metrics collection.
 	CollectNetwork bool `json:"collect_network"`
 
@@ -81,6 +85,7 @@ func DefaultConfig() Config {
 		CollectCPU:       true,
 		CollectMemory:    true,
 		CollectDisk:      true,
+		CollectDiskIO:    true,
 		CollectNetwork:   false, // Opt-in due to verbosity
 		CollectProcesses: false, // Opt-in due to resource usage
 		DiskPaths:        []string{},
@@ -96,6 +101,7 @@ type ParsedConfig struct {
 	CollectCPU       bool
 	CollectMemory    bool
 	CollectDisk      bool
+	CollectDiskIO    bool
 	CollectNetwork   bool
 	CollectProcesses bool
 	DiskPaths        []string
@@ -110,6 +116,7 @@ func (c *Config) Parse() (*ParsedConfig, error) {
 		CollectCPU:       c.CollectCPU,
 		CollectMemory:    c.CollectMemory,
 		CollectDisk:      c.CollectDisk,
+		CollectDiskIO:    c.CollectDiskIO,
 		CollectNetwork:   c.CollectNetwork,
 		CollectProcesses: c.CollectProcesses,
 		DiskPaths:        c.DiskPaths,
@@ -176,6 +183,7 @@ func (c *Config) MergeWithDefaults() Config {
 	merged.CollectCPU = c.CollectCPU
 	merged.CollectMemory = c.CollectMemory
 	merged.CollectDisk = c.CollectDisk
+	merged.CollectDiskIO = c.CollectDiskIO
 	merged.CollectNetwork = c.CollectNetwork
 	merged.CollectProcesses = c.CollectProcesses
 
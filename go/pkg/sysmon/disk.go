@@ -89,6 +89,7 @@ func CollectDisks(ctx context.Context, paths []string, excludePaths []string) ([
 			MountPoint: mountpoint,
 			UsedBytes:  usage.Used,
 			TotalBytes: usage.Total,
+			DeviceName: deviceName(partition.Device),
 		})
 	}
 
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysmon
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// attachDiskIO fills in the I/O counters and rates of each disk from the
+// block device backing its mount. Mounts whose device has no counters on this
+// platform are left without IO. Mounts that share a device report the same
+// device-level figures.
+func attachDiskIO(ctx context.Context, disks []DiskMetric, rates *counterRates, now time.Time) error {
+	if len(disks) == 0 {
+		return nil
+	}
+
+	counters, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	fillDeviceNames(ctx, disks)
+
+	observed := make(map[string]*DiskIOMetric, len(counters))
+
+	for i := range disks {
+		name, ok := ioCounterName(disks[i].DeviceName, counters)
+		if !ok {
+			continue
+		}
+
+		io, seen := observed[name]
+		if !seen {
+			io = diskIOFromCounters(counters[name], rates.observe(name, now,
+				counters[name].ReadBytes, counters[name].WriteBytes,
+				counters[name].ReadCount, counters[name].WriteCount))
+			observed[name] = io
+		}
+
+		copied := *io
+		disks[i].IO = &copied
+	}
+
+	keep := make(map[string]struct{}, len(observed))
+	for name := range observed {
+		keep[name] = struct{}{}
+	}
+
+	rates.retain(keep)
+
+	return nil
+}
+
+func diskIOFromCounters(c disk.IOCountersStat, rates []*float64) *DiskIOMetric {
+	return &DiskIOMetric{
+		ReadBytes:        c.ReadBytes,
+		WriteBytes:       c.WriteBytes,
+		ReadOps:          c.ReadCount,
+		WriteOps:         c.WriteCount,
+		ReadBytesPerSec:  rates[0],
+		WriteBytesPerSec: rates[1],
+		ReadOpsPerSec:    rates[2],
+		WriteOpsPerSec:   rates[3],
+	}
+}
+
+// fillDeviceNames looks up the backing device of disks collected by path,
+// where disk usage does not report it.
+func fillDeviceNames(ctx context.Context, disks []DiskMetric) {
+	missing := false
+
+	for i := range disks {
+		if disks[i].DeviceName == "" {
+			missing = true
+			break
+		}
+	}
+
+	if !missing {
+		return
+	}
+
+	partitions, err := disk.PartitionsWithContext(ctx, true)
+	if err != nil {
+		return
+	}
+
+	devices := make(map[string]string, len(partitions))
+	for _, partition := range partitions {
+		devices[partition.Mountpoint] = deviceName(partition.Device)
+	}
+
+	for i := range disks {
+		if disks[i].DeviceName == "" {
+			disks[i].DeviceName = devices[disks[i].MountPoint]
+		}
+	}
+}
+
+// deviceName trims the /dev/ prefix from a partition device path.
+func deviceName(device string) string {
+	return strings.TrimPrefix(device, "/dev/")
+}
+
+// ioCounterName finds the I/O counter entry for a mount's device. Counters
+// are keyed by kernel device name, so device-mapper links are resolved
+// (/dev/mapper/vg-root -> dm-0), and APFS volumes fall back to their whole
+// disk (disk3s1s1 -> disk3) because macOS only reports whole-disk counters.
+func ioCounterName(device string, counters map[string]disk.IOCountersStat) (string, bool) {
+	if device == "" {
+		return "", false
+	}
+
+	candidates := []string{filepath.Base(device)}
+
+	if resolved, err := filepath.EvalSymlinks(filepath.Join("/dev", device)); err == nil {
+		candidates = append(candidates, filepath.Base(resolved))
+	}
+
+	if whole, ok := wholeDiskName(filepath.Base(device)); ok {
+		candidates = append(candidates, whole)
+	}
+
+	for _, name := range candidates {
+		if _, ok := counters[name]; ok {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// wholeDiskName returns the whole-disk name of a macOS slice such as
+// disk3s1s1, or false when name is not a slice.
+func wholeDiskName(name string) (string, bool) {
+	rest, ok := strings.CutPrefix(name, "disk")
+	if !ok {
+		return "", false
+	}
+
+	digits := 0
+	for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+
+	if digits == 0 || digits == len(rest) || rest[digits] != 's' {
+		return "", false
+	}
+
+	return "disk" + rest[:digits], true
+}
@@ -94,6 +94,41 @@ type DiskMetric struct {
 
 	// TotalBytes is the total capacity in bytes.
 	TotalBytes uint64 `json:"total_bytes"`
+
+	// DeviceName is the block device backing the mount (e.g., sda1, disk3s1).
+	DeviceName string `json:"device_name,omitempty"`
+
+	// IO holds the block device I/O counters, when disk I/O collection is
+	// enabled and the platform reports counters for the device.
+	IO *DiskIOMetric `json:"io,omitempty"`
+}
+
+// DiskIOMetric represents block device I/O counters and the rates derived
+// from them. Rates are nil until a previous sample of the device is available.
+type DiskIOMetric struct {
+	// ReadBytes is the total bytes read from the device.
+	ReadBytes uint64 `json:"read_bytes"`
+
+	// WriteBytes is the total bytes written to the device.
+	WriteBytes uint64 `json:"write_bytes"`
+
+	// ReadOps is the total number of completed reads.
+	ReadOps uint64 `json:"read_ops"`
+
+	// WriteOps is the total number of completed writes.
+	WriteOps uint64 `json:"write_ops"`
+
+	// ReadBytesPerSec is the read throughput since the previous sample.
+	ReadBytesPerSec *float64 `json:"read_bytes_per_sec,omitempty"`
+
+	// WriteBytesPerSec is the write throughput since the previous sample.
+	WriteBytesPerSec *float64 `json:"write_bytes_per_sec,omitempty"`
+
+	// ReadOpsPerSec is the read operation rate since the previous sample.
+	ReadOpsPerSec *float64 `json:"read_ops_per_sec,omitempty"`
+
+	// WriteOpsPerSec is the write operation rate since the previous sample.
+	WriteOpsPerSec *float64 `json:"write_ops_per_sec,omitempty"`
 }
 
 // MemoryMetric represents system memory usage.
@@ -139,6 +174,18 @@ type NetworkMetric struct {
 
 	// DropsOut is the count of dropped outgoing packets.
 	DropsOut uint64 `json:"drops_out"`
+
+	// BytesSentPerSec is the transmit throughput since the previous sample.
+	BytesSentPerSec *float64 `json:"bytes_sent_per_sec,omitempty"`
+
+	// BytesRecvPerSec is the receive throughput since the previous sample.
+	BytesRecvPerSec *float64 `json:"bytes_recv_per_sec,omitempty"`
+
+	// PacketsSentPerSec is the transmit packet rate since the previous sample.
+	PacketsSentPerSec *float64 `json:"packets_sent_per_sec,omitempty"`
+
+	// PacketsRecvPerSec is the receive packet rate since the previous sample.
+	PacketsRecvPerSec *float64 `json:"packets_recv_per_sec,omitempty"`
 }
 
 // ProcessMetric represents metrics for a single process.
@@ -19,6 +19,7 @@ package sysmon
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/shirou/gopsutil/v3/net"
 )
@@ -54,6 +55,26 @@ func CollectNetwork(ctx context.Context) ([]NetworkMetric, error) {
 	return metrics, nil
 }
 
+// applyNetworkRates sets the per-second throughput of each interface from the
+// change in its counters since the previous sample.
+func applyNetworkRates(metrics []NetworkMetric, rates *counterRates, now time.Time) {
+	keep := make(map[string]struct{}, len(metrics))
+
+	for i := range metrics {
+		m := &metrics[i]
+		r := rates.observe(m.Interface, now, m.BytesSent, m.BytesRecv, m.PacketsSent, m.PacketsRecv)
+
+		m.BytesSentPerSec = r[0]
+		m.BytesRecvPerSec = r[1]
+		m.PacketsSentPerSec = r[2]
+		m.PacketsRecvPerSec = r[3]
+
+		keep[m.Interface] = struct{}{}
+	}
+
+	rates.retain(keep)
+}
+
 // shouldSkipInterface returns true for interfaces that should be excluded from monitoring.
 func shouldSkipInterface(name string) bool {
 	name = strings.ToLower(name)
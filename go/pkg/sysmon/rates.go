@@ -0,0 +1,87 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysmon
+
+import (
+	"sync"
+	"time"
+)
+
+// counterRates converts cumulative OS counters into per-second rates by
+// remembering the previous reading of each counter set, in the same way the
+// SNMP collector turns delta OIDs into rates at the edge.
+type counterRates struct {
+	mu       sync.Mutex
+	previous map[string]counterReading
+}
+
+type counterReading struct {
+	at     time.Time
+	values []uint64
+}
+
+func newCounterRates() *counterRates {
+	return &counterRates{previous: make(map[string]counterReading)}
+}
+
+// observe records the counters for key and returns the per-second rate of
+// each one since the previous reading. A rate is nil on the first reading,
+// when no time has elapsed, when the number of counters changed, or when the
+// counter went backwards. OS counters are 64-bit, so a decrease means the
+// device was reset or re-created rather than that the counter wrapped.
+func (r *counterRates) observe(key string, at time.Time, values ...uint64) []*float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rates := make([]*float64, len(values))
+
+	prev, ok := r.previous[key]
+	r.previous[key] = counterReading{at: at, values: append([]uint64(nil), values...)}
+
+	if !ok || len(prev.values) != len(values) {
+		return rates
+	}
+
+	elapsed := at.Sub(prev.at).Seconds()
+	if elapsed <= 0 {
+		return rates
+	}
+
+	for i, value := range values {
+		if value < prev.values[i] {
+			continue
+		}
+
+		rate := float64(value-prev.values[i]) / elapsed
+		rates[i] = &rate
+	}
+
+	return rates
+}
+
+// retain forgets readings for keys not in keep, so devices and interfaces
+// that disappear do not accumulate.
+func (r *counterRates) retain(keep map[string]struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.previous {
+		if _, ok := keep[key]; !ok {
+			delete(r.previous, key)
+		}
+	}
+}
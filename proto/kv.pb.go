@@ -1016,6 +1016,303 @@ func (x *ListKeysResponse) GetKeys() []string {
 	return nil
 }
 
+// HistoryRequest is the request message for the History operation.
+type HistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Limit         uint32                 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"` // Maximum revisions to return, 0 for the server default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryRequest) Reset() {
+	*x = HistoryRequest{}
+	mi := &file_kv_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryRequest) ProtoMessage() {}
+
+func (x *HistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kv_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryRequest.ProtoReflect.Descriptor instead.
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return file_kv_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *HistoryRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HistoryRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// KeyRevision describes a single historical revision of a key.
+type KeyRevision struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Revision        uint64                 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	CreatedUnixNano int64                  `protobuf:"varint,2,opt,name=created_unix_nano,json=createdUnixNano,proto3" json:"created_unix_nano,omitempty"` // Time the revision was written
+	Operation       string                 `protobuf:"bytes,3,opt,name=operation,proto3" json:"operation,omitempty"`                                       // One of "put", "delete", or "purge"
+	Value           []byte                 `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`                                               // Value at this revision, empty for deletes and purges
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *KeyRevision) Reset() {
+	*x = KeyRevision{}
+	mi := &file_kv_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyRevision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyRevision) ProtoMessage() {}
+
+func (x *KeyRevision) ProtoReflect() protoreflect.Message {
+	mi := &file_kv_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyRevision.ProtoReflect.Descriptor instead.
+func (*KeyRevision) Descriptor() ([]byte, []int) {
+	return file_kv_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *KeyRevision) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *KeyRevision) GetCreatedUnixNano() int64 {
+	if x != nil {
+		return x.CreatedUnixNano
+	}
+	return 0
+}
+
+func (x *KeyRevision) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *KeyRevision) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+// HistoryResponse is the response message for the History operation.
+type HistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Revisions     []*KeyRevision         `protobuf:"bytes,1,rep,name=revisions,proto3" json:"revisions,omitempty"`  // Revisions ordered newest first
+	Truncated     bool                   `protobuf:"varint,2,opt,name=truncated,proto3" json:"truncated,omitempty"` // True if older revisions were omitted
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryResponse) Reset() {
+	*x = HistoryResponse{}
+	mi := &file_kv_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryResponse) ProtoMessage() {}
+
+func (x *HistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_kv_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryResponse.ProtoReflect.Descriptor instead.
+func (*HistoryResponse) Descriptor() ([]byte, []int) {
+	return file_kv_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *HistoryResponse) GetRevisions() []*KeyRevision {
+	if x != nil {
+		return x.Revisions
+	}
+	return nil
+}
+
+func (x *HistoryResponse) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+// GetRevisionRequest is the request message for the GetRevision operation.
+type GetRevisionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Revision      uint64                 `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRevisionRequest) Reset() {
+	*x = GetRevisionRequest{}
+	mi := &file_kv_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRevisionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRevisionRequest) ProtoMessage() {}
+
+func (x *GetRevisionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kv_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRevisionRequest.ProtoReflect.Descriptor instead.
+func (*GetRevisionRequest) Descriptor() ([]byte, []int) {
+	return file_kv_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetRevisionRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *GetRevisionRequest) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+// GetRevisionResponse is the response message for the GetRevision operation.
+type GetRevisionResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Value           []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`  // The value at the requested revision, empty if not found
+	Found           bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"` // Indicates if the revision exists and holds a value
+	Revision        uint64                 `protobuf:"varint,3,opt,name=revision,proto3" json:"revision,omitempty"`
+	CreatedUnixNano int64                  `protobuf:"varint,4,opt,name=created_unix_nano,json=createdUnixNano,proto3" json:"created_unix_nano,omitempty"` // Time the revision was written
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GetRevisionResponse) Reset() {
+	*x = GetRevisionResponse{}
+	mi := &file_kv_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRevisionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRevisionResponse) ProtoMessage() {}
+
+func (x *GetRevisionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_kv_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRevisionResponse.ProtoReflect.Descriptor instead.
+func (*GetRevisionResponse) Descriptor() ([]byte, []int) {
+	return file_kv_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GetRevisionResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *GetRevisionResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *GetRevisionResponse) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *GetRevisionResponse) GetCreatedUnixNano() int64 {
+	if x != nil {
+		return x.CreatedUnixNano
+	}
+	return 0
+}
+
 var File_kv_proto protoreflect.FileDescriptor
 
 const file_kv_proto_rawDesc = "" +
@@ -1075,7 +1372,26 @@ const file_kv_proto_rawDesc = "" +
 	"\x0fListKeysRequest\x12\x16\n" +
 	"\x06prefix\x18\x01 \x01(\tR\x06prefix\"&\n" +
 	"\x10ListKeysResponse\x12\x12\n" +
-	"\x04keys\x18\x01 \x03(\tR\x04keys2\xba\x04\n" +
+	"\x04keys\x18\x01 \x03(\tR\x04keys\"8\n" +
+	"\x0eHistoryRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\rR\x05limit\"\x89\x01\n" +
+	"\vKeyRevision\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x04R\brevision\x12*\n" +
+	"\x11created_unix_nano\x18\x02 \x01(\x03R\x0fcreatedUnixNano\x12\x1c\n" +
+	"\toperation\x18\x03 \x01(\tR\toperation\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\fR\x05value\"a\n" +
+	"\x0fHistoryResponse\x120\n" +
+	"\trevisions\x18\x01 \x03(\v2\x12.proto.KeyRevisionR\trevisions\x12\x1c\n" +
+	"\ttruncated\x18\x02 \x01(\bR\ttruncated\"B\n" +
+	"\x12GetRevisionRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x04R\brevision\"\x89\x01\n" +
+	"\x13GetRevisionResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\x14\n" +
+	"\x05found\x18\x02 \x01(\bR\x05found\x12\x1a\n" +
+	"\brevision\x18\x03 \x01(\x04R\brevision\x12*\n" +
+	"\x11created_unix_nano\x18\x04 \x01(\x03R\x0fcreatedUnixNano2\xbe\x05\n" +
 	"\tKVService\x12.\n" +
 	"\x03Get\x12\x11.proto.GetRequest\x1a\x12.proto.GetResponse\"\x00\x12=\n" +
 	"\bBatchGet\x12\x16.proto.BatchGetRequest\x1a\x17.proto.BatchGetResponse\"\x00\x12.\n" +
@@ -1086,7 +1402,9 @@ const file_kv_proto_rawDesc = "" +
 	"\x06Delete\x12\x14.proto.DeleteRequest\x1a\x15.proto.DeleteResponse\"\x00\x126\n" +
 	"\x05Watch\x12\x13.proto.WatchRequest\x1a\x14.proto.WatchResponse\"\x000\x01\x121\n" +
 	"\x04Info\x12\x12.proto.InfoRequest\x1a\x13.proto.InfoResponse\"\x00\x12=\n" +
-	"\bListKeys\x12\x16.proto.ListKeysRequest\x1a\x17.proto.ListKeysResponse\"\x00B*Z(github.com/carverauto/serviceradar/protob\x06proto3"
+	"\bListKeys\x12\x16.proto.ListKeysRequest\x1a\x17.proto.ListKeysResponse\"\x00\x12:\n" +
+	"\aHistory\x12\x15.proto.HistoryRequest\x1a\x16.proto.HistoryResponse\"\x00\x12F\n" +
+	"\vGetRevision\x12\x19.proto.GetRevisionRequest\x1a\x1a.proto.GetRevisionResponse\"\x00B*Z(github.com/carverauto/serviceradar/protob\x06proto3"
 
 var (
 	file_kv_proto_rawDescOnce sync.Once
@@ -1100,57 +1418,67 @@ func file_kv_proto_rawDescGZIP() []byte {
 	return file_kv_proto_rawDescData
 }
 
-var file_kv_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_kv_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
 var file_kv_proto_goTypes = []any{
-	(*GetRequest)(nil),       // 0: proto.GetRequest
-	(*GetResponse)(nil),      // 1: proto.GetResponse
-	(*BatchGetRequest)(nil),  // 2: proto.BatchGetRequest
-	(*BatchGetEntry)(nil),    // 3: proto.BatchGetEntry
-	(*BatchGetResponse)(nil), // 4: proto.BatchGetResponse
-	(*PutRequest)(nil),       // 5: proto.PutRequest
-	(*PutResponse)(nil),      // 6: proto.PutResponse
-	(*KeyValueEntry)(nil),    // 7: proto.KeyValueEntry
-	(*PutManyRequest)(nil),   // 8: proto.PutManyRequest
-	(*PutManyResponse)(nil),  // 9: proto.PutManyResponse
-	(*UpdateRequest)(nil),    // 10: proto.UpdateRequest
-	(*UpdateResponse)(nil),   // 11: proto.UpdateResponse
-	(*DeleteRequest)(nil),    // 12: proto.DeleteRequest
-	(*DeleteResponse)(nil),   // 13: proto.DeleteResponse
-	(*WatchRequest)(nil),     // 14: proto.WatchRequest
-	(*WatchResponse)(nil),    // 15: proto.WatchResponse
-	(*InfoRequest)(nil),      // 16: proto.InfoRequest
-	(*InfoResponse)(nil),     // 17: proto.InfoResponse
-	(*ListKeysRequest)(nil),  // 18: proto.ListKeysRequest
-	(*ListKeysResponse)(nil), // 19: proto.ListKeysResponse
+	(*GetRequest)(nil),          // 0: proto.GetRequest
+	(*GetResponse)(nil),         // 1: proto.GetResponse
+	(*BatchGetRequest)(nil),     // 2: proto.BatchGetRequest
+	(*BatchGetEntry)(nil),       // 3: proto.BatchGetEntry
+	(*BatchGetResponse)(nil),    // 4: proto.BatchGetResponse
+	(*PutRequest)(nil),          // 5: proto.PutRequest
+	(*PutResponse)(nil),         // 6: proto.PutResponse
+	(*KeyValueEntry)(nil),       // 7: proto.KeyValueEntry
+	(*PutManyRequest)(nil),      // 8: proto.PutManyRequest
+	(*PutManyResponse)(nil),     // 9: proto.PutManyResponse
+	(*UpdateRequest)(nil),       // 10: proto.UpdateRequest
+	(*UpdateResponse)(nil),      // 11: proto.UpdateResponse
+	(*DeleteRequest)(nil),       // 12: proto.DeleteRequest
+	(*DeleteResponse)(nil),      // 13: proto.DeleteResponse
+	(*WatchRequest)(nil),        // 14: proto.WatchRequest
+	(*WatchResponse)(nil),       // 15: proto.WatchResponse
+	(*InfoRequest)(nil),         // 16: proto.InfoRequest
+	(*InfoResponse)(nil),        // 17: proto.InfoResponse
+	(*ListKeysRequest)(nil),     // 18: proto.ListKeysRequest
+	(*ListKeysResponse)(nil),    // 19: proto.ListKeysResponse
+	(*HistoryRequest)(nil),      // 20: proto.HistoryRequest
+	(*KeyRevision)(nil),         // 21: proto.KeyRevision
+	(*HistoryResponse)(nil),     // 22: proto.HistoryResponse
+	(*GetRevisionRequest)(nil),  // 23: proto.GetRevisionRequest
+	(*GetRevisionResponse)(nil), // 24: proto.GetRevisionResponse
 }
 var file_kv_proto_depIdxs = []int32{
 	3,  // 0: proto.BatchGetResponse.results:type_name -> proto.BatchGetEntry
 	7,  // 1: proto.PutManyRequest.entries:type_name -> proto.KeyValueEntry
-	0,  // 2: proto.KVService.Get:input_type -> proto.GetRequest
-	2,  // 3: proto.KVService.BatchGet:input_type -> proto.BatchGetRequest
-	5,  // 4: proto.KVService.Put:input_type -> proto.PutRequest
-	5,  // 5: proto.KVService.PutIfAbsent:input_type -> proto.PutRequest
-	8,  // 6: proto.KVService.PutMany:input_type -> proto.PutManyRequest
-	10, // 7: proto.KVService.Update:input_type -> proto.UpdateRequest
-	12, // 8: proto.KVService.Delete:input_type -> proto.DeleteRequest
-	14, // 9: proto.KVService.Watch:input_type -> proto.WatchRequest
-	16, // 10: proto.KVService.Info:input_type -> proto.InfoRequest
-	18, // 11: proto.KVService.ListKeys:input_type -> proto.ListKeysRequest
-	1,  // 12: proto.KVService.Get:output_type -> proto.GetResponse
-	4,  // 13: proto.KVService.BatchGet:output_type -> proto.BatchGetResponse
-	6,  // 14: proto.KVService.Put:output_type -> proto.PutResponse
-	6,  // 15: proto.KVService.PutIfAbsent:output_type -> proto.PutResponse
-	9,  // 16: proto.KVService.PutMany:output_type -> proto.PutManyResponse
-	11, // 17: proto.KVService.Update:output_type -> proto.UpdateResponse
-	13, // 18: proto.KVService.Delete:output_type -> proto.DeleteResponse
-	15, // 19: proto.KVService.Watch:output_type -> proto.WatchResponse
-	17, // 20: proto.KVService.Info:output_type -> proto.InfoResponse
-	19, // 21: proto.KVService.ListKeys:output_type -> proto.ListKeysResponse
-	12, // [12:22] is the sub-list for method output_type
-	2,  // [2:12] is the sub-list for method input_type
-	2,  // [2:2] is the sub-list for extension type_name
-	2,  // [2:2] is the sub-list for extension extendee
-	0,  // [0:2] is the sub-list for field type_name
+	21, // 2: proto.HistoryResponse.revisions:type_name -> proto.KeyRevision
+	0,  // 3: proto.KVService.Get:input_type -> proto.GetRequest
+	2,  // 4: proto.KVService.BatchGet:input_type -> proto.BatchGetRequest
+	5,  // 5: proto.KVService.Put:input_type -> proto.PutRequest
+	5,  // 6: proto.KVService.PutIfAbsent:input_type -> proto.PutRequest
+	8,  // 7: proto.KVService.PutMany:input_type -> proto.PutManyRequest
+	10, // 8: proto.KVService.Update:input_type -> proto.UpdateRequest
+	12, // 9: proto.KVService.Delete:input_type -> proto.DeleteRequest
+	14, // 10: proto.KVService.Watch:input_type -> proto.WatchRequest
+	16, // 11: proto.KVService.Info:input_type -> proto.InfoRequest
+	18, // 12: proto.KVService.ListKeys:input_type -> proto.ListKeysRequest
+	20, // 13: proto.KVService.History:input_type -> proto.HistoryRequest
+	23, // 14: proto.KVService.GetRevision:input_type -> proto.GetRevisionRequest
+	1,  // 15: proto.KVService.Get:output_type -> proto.GetResponse
+	4,  // 16: proto.KVService.BatchGet:output_type -> proto.BatchGetResponse
+	6,  // 17: proto.KVService.Put:output_type -> proto.PutResponse
+	6,  // 18: proto.KVService.PutIfAbsent:output_type -> proto.PutResponse
+	9,  // 19: proto.KVService.PutMany:output_type -> proto.PutManyResponse
+	11, // 20: proto.KVService.Update:output_type -> proto.UpdateResponse
+	13, // 21: proto.KVService.Delete:output_type -> proto.DeleteResponse
+	15, // 22: proto.KVService.Watch:output_type -> proto.WatchResponse
+	17, // 23: proto.KVService.Info:output_type -> proto.InfoResponse
+	19, // 24: proto.KVService.ListKeys:output_type -> proto.ListKeysResponse
+	22, // 25: proto.KVService.History:output_type -> proto.HistoryResponse
+	24, // 26: proto.KVService.GetRevision:output_type -> proto.GetRevisionResponse
+	15, // [15:27] is the sub-list for method output_type
+	3,  // [3:15] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_kv_proto_init() }
@@ -1164,7 +1492,7 @@ func file_kv_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_kv_proto_rawDesc), len(file_kv_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   20,
+			NumMessages:   25,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -44,6 +44,8 @@ const (
 	KVService_Watch_FullMethodName       = "/proto.KVService/Watch"
 	KVService_Info_FullMethodName        = "/proto.KVService/Info"
 	KVService_ListKeys_FullMethodName    = "/proto.KVService/ListKeys"
+	KVService_History_FullMethodName     = "/proto.KVService/History"
+	KVService_GetRevision_FullMethodName = "/proto.KVService/GetRevision"
 )
 
 // KVServiceClient is the client API for KVService service.
@@ -74,6 +76,11 @@ type KVServiceClient interface {
 	Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
 	// ListKeys returns all keys matching a prefix filter.
 	ListKeys(ctx context.Context, in *ListKeysRequest, opts ...grpc.CallOption) (*ListKeysResponse, error)
+	// History lists the most recent revisions of a key, newest first.
+	// The depth is bounded by the bucket's history setting and a server-side cap.
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
+	// GetRevision retrieves the value a key held at a specific revision.
+	GetRevision(ctx context.Context, in *GetRevisionRequest, opts ...grpc.CallOption) (*GetRevisionResponse, error)
 }
 
 type kVServiceClient struct {
@@ -193,6 +200,26 @@ func (c *kVServiceClient) ListKeys(ctx context.Context, in *ListKeysRequest, opt
 	return out, nil
 }
 
+func (c *kVServiceClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, KVService_History_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) GetRevision(ctx context.Context, in *GetRevisionRequest, opts ...grpc.CallOption) (*GetRevisionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRevisionResponse)
+	err := c.cc.Invoke(ctx, KVService_GetRevision_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // KVServiceServer is the server API for KVService service.
 // All implementations must embed UnimplementedKVServiceServer
 // for forward compatibility.
@@ -221,6 +248,11 @@ type KVServiceServer interface {
 	Info(context.Context, *InfoRequest) (*InfoResponse, error)
 	// ListKeys returns all keys matching a prefix filter.
 	ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error)
+	// History lists the most recent revisions of a key, newest first.
+	// The depth is bounded by the bucket's history setting and a server-side cap.
+	History(context.Context, *HistoryRequest) (*HistoryResponse, error)
+	// GetRevision retrieves the value a key held at a specific revision.
+	GetRevision(context.Context, *GetRevisionRequest) (*GetRevisionResponse, error)
 	mustEmbedUnimplementedKVServiceServer()
 }
 
@@ -261,6 +293,12 @@ func (UnimplementedKVServiceServer) Info(context.Context, *InfoRequest) (*InfoRe
 func (UnimplementedKVServiceServer) ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListKeys not implemented")
 }
+func (UnimplementedKVServiceServer) History(context.Context, *HistoryRequest) (*HistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
+}
+func (UnimplementedKVServiceServer) GetRevision(context.Context, *GetRevisionRequest) (*GetRevisionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRevision not implemented")
+}
 func (UnimplementedKVServiceServer) mustEmbedUnimplementedKVServiceServer() {}
 func (UnimplementedKVServiceServer) testEmbeddedByValue()                   {}
 
@@ -455,6 +493,42 @@ func _KVService_ListKeys_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KVService_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_History_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).History(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_GetRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRevisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).GetRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KVService_GetRevision_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).GetRevision(ctx, req.(*GetRevisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // KVService_ServiceDesc is the grpc.ServiceDesc for KVService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -498,6 +572,14 @@ var KVService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListKeys",
 			Handler:    _KVService_ListKeys_Handler,
 		},
+		{
+			MethodName: "History",
+			Handler:    _KVService_History_Handler,
+		},
+		{
+			MethodName: "GetRevision",
+			Handler:    _KVService_GetRevision_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -3189,6 +3189,8 @@ type SysmonConfig struct {
 	DiskPaths []string `protobuf:"bytes,8,rep,name=disk_paths,json=diskPaths,proto3" json:"disk_paths,omitempty"`
 	// Disk paths to exclude when collecting disk metrics
 	DiskExcludePaths []string `protobuf:"bytes,14,rep,name=disk_exclude_paths,json=diskExcludePaths,proto3" json:"disk_exclude_paths,omitempty"`
+	// Whether to attach block device I/O counters and rates to disk metrics
+	CollectDiskIo bool `protobuf:"varint,15,opt,name=collect_disk_io,json=collectDiskIo,proto3" json:"collect_disk_io,omitempty"`
 	// Alert thresholds as key-value pairs
 	// Keys: cpu_warning, cpu_critical, memory_warning, memory_critical,
 	//
@@ -3297,6 +3299,13 @@ func (x *SysmonConfig) GetDiskExcludePaths() []string {
 	return nil
 }
 
+func (x *SysmonConfig) GetCollectDiskIo() bool {
+	if x != nil {
+		return x.CollectDiskIo
+	}
+	return false
+}
+
 func (x *SysmonConfig) GetThresholds() map[string]string {
 	if x != nil {
 		return x.Thresholds
@@ -3740,14 +3749,20 @@ func (x *SNMPv3Auth) GetPrivPassword() string {
 
 // SNMPOIDConfig defines an OID to poll from an SNMP target.
 type SNMPOIDConfig struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Oid           string                 `protobuf:"bytes,1,opt,name=oid,proto3" json:"oid,omitempty"`                                                         // OID string (e.g., ".1.3.6.1.2.1.1.1.0")
-	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                                                       // Human-readable name (e.g., "sysDescr")
-	DataType      SNMPDataType           `protobuf:"varint,3,opt,name=data_type,json=dataType,proto3,enum=monitoring.SNMPDataType" json:"data_type,omitempty"` // Expected data type
-	Scale         float64                `protobuf:"fixed64,4,opt,name=scale,proto3" json:"scale,omitempty"`                                                   // Scale factor (default 1.0)
-	Delta         bool                   `protobuf:"varint,5,opt,name=delta,proto3" json:"delta,omitempty"`                                                    // Calculate rate of change
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state                      protoimpl.MessageState `protogen:"open.v1"`
+	Oid                        string                 `protobuf:"bytes,1,opt,name=oid,proto3" json:"oid,omitempty"`                                                                                       // OID string (e.g., ".1.3.6.1.2.1.1.1.0")
+	Name                       string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                                                                                     // Human-readable name (e.g., "sysDescr")
+	DataType                   SNMPDataType           `protobuf:"varint,3,opt,name=data_type,json=dataType,proto3,enum=monitoring.SNMPDataType" json:"data_type,omitempty"`                               // Expected data type
+	Scale                      float64                `protobuf:"fixed64,4,opt,name=scale,proto3" json:"scale,omitempty"`                                                                                 // Scale factor (default 1.0)
+	Delta                      bool                   `protobuf:"varint,5,opt,name=delta,proto3" json:"delta,omitempty"`                                                                                  // Calculate rate of change
+	Divisor                    float64                `protobuf:"fixed64,6,opt,name=divisor,proto3" json:"divisor,omitempty"`                                                                             // Divide the value by this factor (default 1.0)
+	Unit                       string                 `protobuf:"bytes,7,opt,name=unit,proto3" json:"unit,omitempty"`                                                                                     // Unit of the emitted value (e.g., "celsius", "bits")
+	Adaptive                   bool                   `protobuf:"varint,8,opt,name=adaptive,proto3" json:"adaptive,omitempty"`                                                                            // Poll less often while the value is stable
+	AdaptiveMinIntervalSeconds uint32                 `protobuf:"varint,9,opt,name=adaptive_min_interval_seconds,json=adaptiveMinIntervalSeconds,proto3" json:"adaptive_min_interval_seconds,omitempty"`  // Shortest adaptive interval (default: target interval)
+	AdaptiveMaxIntervalSeconds uint32                 `protobuf:"varint,10,opt,name=adaptive_max_interval_seconds,json=adaptiveMaxIntervalSeconds,proto3" json:"adaptive_max_interval_seconds,omitempty"` // Longest adaptive interval (default: 10x the minimum)
+	AdaptiveChangeThreshold    float64                `protobuf:"fixed64,11,opt,name=adaptive_change_threshold,json=adaptiveChangeThreshold,proto3" json:"adaptive_change_threshold,omitempty"`           // Relative change counted as significant (default 0.05)
+	unknownFields              protoimpl.UnknownFields
+	sizeCache                  protoimpl.SizeCache
 }
 
 func (x *SNMPOIDConfig) Reset() {
@@ -3815,6 +3830,48 @@ func (x *SNMPOIDConfig) GetDelta() bool {
 	return false
 }
 
+func (x *SNMPOIDConfig) GetDivisor() float64 {
+	if x != nil {
+		return x.Divisor
+	}
+	return 0
+}
+
+func (x *SNMPOIDConfig) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *SNMPOIDConfig) GetAdaptive() bool {
+	if x != nil {
+		return x.Adaptive
+	}
+	return false
+}
+
+func (x *SNMPOIDConfig) GetAdaptiveMinIntervalSeconds() uint32 {
+	if x != nil {
+		return x.AdaptiveMinIntervalSeconds
+	}
+	return 0
+}
+
+func (x *SNMPOIDConfig) GetAdaptiveMaxIntervalSeconds() uint32 {
+	if x != nil {
+		return x.AdaptiveMaxIntervalSeconds
+	}
+	return 0
+}
+
+func (x *SNMPOIDConfig) GetAdaptiveChangeThreshold() float64 {
+	if x != nil {
+		return x.AdaptiveChangeThreshold
+	}
+	return 0
+}
+
 // MtrMplsLabel represents a single MPLS label stack entry extracted from
 // RFC 4884 ICMP extension objects.
 type MtrMplsLabel struct {
@@ -4633,7 +4690,7 @@ const file_monitoring_proto_rawDesc = "" +
 	"\x0fsource_repo_url\x18\x13 \x01(\tR\rsourceRepoUrl\x12#\n" +
 	"\rsource_commit\x18\x14 \x01(\tR\fsourceCommit\x12!\n" +
 	"\fdownload_url\x18\x15 \x01(\tR\vdownloadUrl\x12%\n" +
-	"\x0edownload_token\x18\x16 \x01(\tR\rdownloadToken\"\xd5\x04\n" +
+	"\x0edownload_token\x18\x16 \x01(\tR\rdownloadToken\"\xfd\x04\n" +
 	"\fSysmonConfig\x12\x18\n" +
 	"\aenabled\x18\x01 \x01(\bR\aenabled\x12'\n" +
 	"\x0fsample_interval\x18\x02 \x01(\tR\x0esampleInterval\x12\x1f\n" +
@@ -4645,7 +4702,8 @@ const file_monitoring_proto_rawDesc = "" +
 	"\x11collect_processes\x18\a \x01(\bR\x10collectProcesses\x12\x1d\n" +
 	"\n" +
 	"disk_paths\x18\b \x03(\tR\tdiskPaths\x12,\n" +
-	"\x12disk_exclude_paths\x18\x0e \x03(\tR\x10diskExcludePaths\x12H\n" +
+	"\x12disk_exclude_paths\x18\x0e \x03(\tR\x10diskExcludePaths\x12&\n" +
+	"\x0fcollect_disk_io\x18\x0f \x01(\bR\rcollectDiskIo\x12H\n" +
 	"\n" +
 	"thresholds\x18\n" +
 	" \x03(\v2(.monitoring.SysmonConfig.ThresholdsEntryR\n" +
@@ -4703,13 +4761,20 @@ const file_monitoring_proto_rawDesc = "" +
 	"\rauth_protocol\x18\x03 \x01(\x0e2\x1c.monitoring.SNMPAuthProtocolR\fauthProtocol\x12#\n" +
 	"\rauth_password\x18\x04 \x01(\tR\fauthPassword\x12A\n" +
 	"\rpriv_protocol\x18\x05 \x01(\x0e2\x1c.monitoring.SNMPPrivProtocolR\fprivProtocol\x12#\n" +
-	"\rpriv_password\x18\x06 \x01(\tR\fprivPassword\"\x98\x01\n" +
+	"\rpriv_password\x18\x06 \x01(\tR\fprivPassword\"\xa4\x03\n" +
 	"\rSNMPOIDConfig\x12\x10\n" +
 	"\x03oid\x18\x01 \x01(\tR\x03oid\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x125\n" +
 	"\tdata_type\x18\x03 \x01(\x0e2\x18.monitoring.SNMPDataTypeR\bdataType\x12\x14\n" +
 	"\x05scale\x18\x04 \x01(\x01R\x05scale\x12\x14\n" +
-	"\x05delta\x18\x05 \x01(\bR\x05delta\"V\n" +
+	"\x05delta\x18\x05 \x01(\bR\x05delta\x12\x18\n" +
+	"\adivisor\x18\x06 \x01(\x01R\adivisor\x12\x12\n" +
+	"\x04unit\x18\a \x01(\tR\x04unit\x12\x1a\n" +
+	"\badaptive\x18\b \x01(\bR\badaptive\x12A\n" +
+	"\x1dadaptive_min_interval_seconds\x18\t \x01(\rR\x1aadaptiveMinIntervalSeconds\x12A\n" +
+	"\x1dadaptive_max_interval_seconds\x18\n" +
+	" \x01(\rR\x1aadaptiveMaxIntervalSeconds\x12:\n" +
+	"\x19adaptive_change_threshold\x18\v \x01(\x01R\x17adaptiveChangeThreshold\"V\n" +
 	"\fMtrMplsLabel\x12\x14\n" +
 	"\x05label\x18\x01 \x01(\x05R\x05label\x12\x10\n" +
 	"\x03exp\x18\x02 \x01(\x05R\x03exp\x12\f\n" +
@@ -1,6 +1,8 @@
-use anyhow::{Context, Result};
+use crate::parser::{self, Entity, OrderClause};
+use anyhow::{bail, Context, Result};
 use serde::Deserialize;
 use std::{
+    collections::HashMap,
     env,
     net::{SocketAddr, ToSocketAddrs},
     time::Duration,
@@ -23,6 +25,18 @@ pub struct AppConfig {
     pub request_timeout: Duration,
     pub rate_limit_max_requests: u64,
     pub rate_limit_window: Duration,
+    /// Metric queries spanning more than this are downsampled automatically.
+    /// Zero disables automatic downsampling.
+    pub downsample_threshold: Duration,
+    /// Upper bound on buckets per series for automatic downsampling.
+    pub downsample_target_points: i64,
+    /// Hard cap on rows returned by one query. Zero (the default) disables the cap.
+    pub max_result_rows: i64,
+    /// Requests presenting this key in `x-srql-admin-key` bypass the result cap.
+    pub admin_api_key: Option<String>,
+    /// Per-entity overrides of the order applied when a query has no `sort:`.
+    /// An empty order disables the default for that entity.
+    pub default_sort: HashMap<Entity, Vec<OrderClause>>,
 }
 
 #[derive(Debug, Deserialize)]
@@ -57,6 +71,16 @@ struct RawConfig {
     srql_rate_limit_max: u64,
     #[serde(default = "default_rate_limit_window_secs")]
     srql_rate_limit_window_secs: u64,
+    #[serde(default = "default_downsample_threshold_hours")]
+    srql_downsample_threshold_hours: u64,
+    #[serde(default = "default_downsample_target_points")]
+    srql_downsample_target_points: i64,
+    #[serde(default = "default_max_result_rows")]
+    srql_max_result_rows: i64,
+    #[serde(default)]
+    srql_admin_api_key: Option<String>,
+    #[serde(default)]
+    srql_default_sort: Option<String>,
 }
 
 const fn default_pool_size() -> u32 {
@@ -83,6 +107,20 @@ const fn default_rate_limit_window_secs() -> u64 {
     60
 }
 
+const fn default_downsample_threshold_hours() -> u64 {
+    7 * 24
+}
+
+const fn default_downsample_target_points() -> i64 {
+    1000
+}
+
+const fn default_max_result_rows() -> i64 {
+    0
+}
+
+const MIN_DOWNSAMPLE_TARGET_POINTS: i64 = 2;
+
 impl AppConfig {
     pub fn from_env() -> Result<Self> {
         let raw: RawConfig =
@@ -144,6 +182,23 @@ impl AppConfig {
             request_timeout: Duration::from_secs(raw.srql_request_timeout_secs.max(1)),
             rate_limit_max_requests: raw.srql_rate_limit_max.max(1),
             rate_limit_window: Duration::from_secs(raw.srql_rate_limit_window_secs.max(1)),
+            downsample_threshold: Duration::from_secs(
+                raw.srql_downsample_threshold_hours.saturating_mul(60 * 60),
+            ),
+            downsample_target_points: raw
+                .srql_downsample_target_points
+                .max(MIN_DOWNSAMPLE_TARGET_POINTS),
+            max_result_rows: raw.srql_max_result_rows.max(0),
+            admin_api_key: raw
+                .srql_admin_api_key
+                .map(|key| key.trim().to_string())
+                .filter(|key| !key.is_empty()),
+            default_sort: raw
+                .srql_default_sort
+                .as_deref()
+                .map(parse_default_sort)
+                .transpose()?
+                .unwrap_or_default(),
         })
     }
 
@@ -164,8 +219,40 @@ impl AppConfig {
             request_timeout: Duration::from_secs(default_timeout_secs()),
             rate_limit_max_requests: default_rate_limit_requests(),
             rate_limit_window: Duration::from_secs(default_rate_limit_window_secs()),
+            downsample_threshold: Duration::from_secs(
+                default_downsample_threshold_hours() * 60 * 60,
+            ),
+            downsample_target_points: default_downsample_target_points(),
+            max_result_rows: default_max_result_rows(),
+            admin_api_key: None,
+            default_sort: HashMap::new(),
         }
     }
+
+    /// The row cap applied to ordinary query requests.
+    pub fn result_cap(&self) -> Option<i64> {
+        (self.max_result_rows > 0).then_some(self.max_result_rows)
+    }
+}
+
+/// Parses `SRQL_DEFAULT_SORT`: `;`-separated `entity=sort` entries where
+/// `sort` uses the query syntax, e.g.
+/// `devices=last_seen:desc;logs=timestamp:desc,severity_number:desc`.
+fn parse_default_sort(raw: &str) -> Result<HashMap<Entity, Vec<OrderClause>>> {
+    let mut overrides = HashMap::new();
+    for entry in raw
+        .split(';')
+        .map(str::trim)
+        .filter(|entry| !entry.is_empty())
+    {
+        let Some((entity, sort)) = entry.split_once('=') else {
+            bail!("invalid SRQL_DEFAULT_SORT entry '{entry}': expected entity=field:dir");
+        };
+        let entity = parser::parse_entity(entity.trim())
+            .with_context(|| format!("invalid SRQL_DEFAULT_SORT entity in '{entry}'"))?;
+        overrides.insert(entity, parser::parse_order(sort));
+    }
+    Ok(overrides)
 }
 
 fn resolve_addr(
@@ -190,3 +277,33 @@ fn resolve_addr(
         .next()
         .context("listen address resolved to no targets")
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::parser::OrderDirection;
+
+    #[test]
+    fn parses_default_sort_overrides() {
+        let overrides =
+            parse_default_sort("devices=uid:asc ; logs=timestamp:desc,severity_number:asc;flows=")
+                .expect("valid overrides");
+
+        let devices = &overrides[&Entity::Devices];
+        assert_eq!(devices.len(), 1);
+        assert_eq!(devices[0].field, "uid");
+        assert!(matches!(devices[0].direction, OrderDirection::Asc));
+
+        let logs = &overrides[&Entity::Logs];
+        assert_eq!(logs.len(), 2);
+        assert!(matches!(logs[1].direction, OrderDirection::Asc));
+
+        assert!(overrides[&Entity::Flows].is_empty());
+    }
+
+    #[test]
+    fn rejects_malformed_default_sort() {
+        assert!(parse_default_sort("devices").is_err());
+        assert!(parse_default_sort("widgets=name:asc").is_err());
+    }
+}
@@ -0,0 +1,322 @@
+//! Response encodings for the query API.
+//!
+//! JSON is the default. Clients that send `Accept: application/x-protobuf`
+//! receive the messages defined in `proto/query.proto` instead, which are
+//! smaller and cheaper to parse for high-throughput consumers. The web-ng
+//! `/api/query` endpoint encodes its JSON responses through
+//! [`encode_protobuf_json`] via the SRQL NIF, so both servers send the same
+//! wire message.
+
+use crate::query::{DownsampleMeta, PaginationMeta, QueryResponse};
+use http::{header::ACCEPT, HeaderMap};
+use prost::Message;
+use prost_types::{value::Kind, ListValue, Struct, Value as ProtoValue};
+use serde_json::{Map, Number, Value};
+
+pub const PROTOBUF_CONTENT_TYPE: &str = "application/x-protobuf";
+
+/// Wire types for `proto/query.proto`.
+pub mod pb {
+    #[derive(Clone, PartialEq, ::prost::Message)]
+    pub struct QueryResponse {
+        #[prost(message, repeated, tag = "1")]
+        pub results: ::prost::alloc::vec::Vec<::prost_types::Value>,
+        #[prost(message, optional, tag = "2")]
+        pub pagination: ::core::option::Option<Pagination>,
+        #[prost(string, optional, tag = "3")]
+        pub error: ::core::option::Option<::prost::alloc::string::String>,
+        #[prost(message, optional, tag = "4")]
+        pub downsample: ::core::option::Option<Downsample>,
+        #[prost(bool, tag = "5")]
+        pub truncated: bool,
+        #[prost(int64, optional, tag = "6")]
+        pub total_available: ::core::option::Option<i64>,
+        #[prost(message, optional, tag = "7")]
+        pub explain: ::core::option::Option<::prost_types::Value>,
+    }
+
+    #[derive(Clone, PartialEq, ::prost::Message)]
+    pub struct Pagination {
+        #[prost(string, optional, tag = "1")]
+        pub next_cursor: ::core::option::Option<::prost::alloc::string::String>,
+        #[prost(string, optional, tag = "2")]
+        pub prev_cursor: ::core::option::Option<::prost::alloc::string::String>,
+        #[prost(int64, optional, tag = "3")]
+        pub limit: ::core::option::Option<i64>,
+    }
+
+    #[derive(Clone, PartialEq, ::prost::Message)]
+    pub struct Downsample {
+        #[prost(int64, tag = "1")]
+        pub bucket_seconds: i64,
+        #[prost(string, tag = "2")]
+        pub bucket: ::prost::alloc::string::String,
+        #[prost(string, tag = "3")]
+        pub agg: ::prost::alloc::string::String,
+        #[prost(bool, tag = "4")]
+        pub auto: bool,
+    }
+}
+
+/// Reports whether the request asked for protobuf. Unlisted or wildcard
+/// Accept headers keep the JSON default.
+pub fn wants_protobuf(headers: &HeaderMap) -> bool {
+    headers
+        .get_all(ACCEPT)
+        .iter()
+        .filter_map(|value| value.to_str().ok())
+        .flat_map(|value| value.split(','))
+        .any(|media| {
+            media
+                .split(';')
+                .next()
+                .map(str::trim)
+                .is_some_and(|media| media.eq_ignore_ascii_case(PROTOBUF_CONTENT_TYPE))
+        })
+}
+
+pub fn encode_protobuf(response: &QueryResponse) -> Vec<u8> {
+    to_proto(response).encode_to_vec()
+}
+
+pub fn to_proto(response: &QueryResponse) -> pb::QueryResponse {
+    pb::QueryResponse {
+        results: response.results.iter().map(json_to_proto).collect(),
+        pagination: Some(pagination_to_proto(&response.pagination)),
+        error: response.error.clone(),
+        downsample: response.downsample.as_ref().map(downsample_to_proto),
+        truncated: response.truncated,
+        total_available: response.total_available,
+        explain: response
+            .explain
+            .as_ref()
+            .and_then(|meta| serde_json::to_value(meta).ok())
+            .map(|meta| json_to_proto(&meta)),
+    }
+}
+
+/// Encodes a query response that was already rendered as JSON, such as the
+/// web query API's response, as the `QueryResponse` message. Fields follow
+/// the JSON response shape; missing fields take their protobuf defaults.
+pub fn encode_protobuf_json(response: &Value) -> Result<Vec<u8>, String> {
+    let Value::Object(fields) = response else {
+        return Err("query response must be a JSON object".to_string());
+    };
+
+    let results = match fields.get("results") {
+        Some(Value::Array(rows)) => rows.iter().map(json_to_proto).collect(),
+        None | Some(Value::Null) => Vec::new(),
+        Some(_) => return Err("query response results must be an array".to_string()),
+    };
+
+    let pagination = fields.get("pagination").and_then(Value::as_object);
+    let downsample = fields.get("downsample").and_then(Value::as_object);
+
+    let message = pb::QueryResponse {
+        results,
+        pagination: Some(pb::Pagination {
+            next_cursor: json_string(pagination, "next_cursor"),
+            prev_cursor: json_string(pagination, "prev_cursor"),
+            limit: pagination.and_then(|page| page.get("limit")?.as_i64()),
+        }),
+        error: json_string(Some(fields), "error"),
+        downsample: downsample.map(|meta| pb::Downsample {
+            bucket_seconds: meta
+                .get("bucket_seconds")
+                .and_then(Value::as_i64)
+                .unwrap_or_default(),
+            bucket: json_string(Some(meta), "bucket").unwrap_or_default(),
+            agg: json_string(Some(meta), "agg").unwrap_or_default(),
+            auto: meta
+                .get("auto")
+                .and_then(Value::as_bool)
+                .unwrap_or_default(),
+        }),
+        truncated: fields
+            .get("truncated")
+            .and_then(Value::as_bool)
+            .unwrap_or_default(),
+        total_available: fields.get("total_available").and_then(Value::as_i64),
+        explain: fields
+            .get("explain")
+            .filter(|explain| !explain.is_null())
+            .map(json_to_proto),
+    };
+
+    Ok(message.encode_to_vec())
+}
+
+fn json_string(fields: Option<&Map<String, Value>>, key: &str) -> Option<String> {
+    fields?.get(key)?.as_str().map(str::to_string)
+}
+
+fn downsample_to_proto(meta: &DownsampleMeta) -> pb::Downsample {
+    pb::Downsample {
+        bucket_seconds: meta.bucket_seconds,
+        bucket: meta.bucket.clone(),
+        agg: meta.agg.as_str().to_string(),
+        auto: meta.auto,
+    }
+}
+
+fn pagination_to_proto(meta: &PaginationMeta) -> pb::Pagination {
+    pb::Pagination {
+        next_cursor: meta.next_cursor.clone(),
+        prev_cursor: meta.prev_cursor.clone(),
+        limit: meta.limit,
+    }
+}
+
+fn json_to_proto(value: &Value) -> ProtoValue {
+    let kind = match value {
+        Value::Null => Kind::NullValue(0),
+        Value::Bool(flag) => Kind::BoolValue(*flag),
+        Value::Number(number) => Kind::NumberValue(number.as_f64().unwrap_or_default()),
+        Value::String(text) => Kind::StringValue(text.clone()),
+        Value::Array(items) => Kind::ListValue(ListValue {
+            values: items.iter().map(json_to_proto).collect(),
+        }),
+        Value::Object(fields) => Kind::StructValue(Struct {
+            fields: fields
+                .iter()
+                .map(|(key, value)| (key.clone(), json_to_proto(value)))
+                .collect(),
+        }),
+    };
+
+    ProtoValue { kind: Some(kind) }
+}
+
+/// Converts a decoded protobuf value back to JSON. Integral numbers that fit
+/// in an i64 come back as integers so rows compare equal to the JSON path.
+pub fn proto_to_json(value: &ProtoValue) -> Value {
+    match &value.kind {
+        None | Some(Kind::NullValue(_)) => Value::Null,
+        Some(Kind::BoolValue(flag)) => Value::Bool(*flag),
+        Some(Kind::NumberValue(number)) => number_to_json(*number),
+        Some(Kind::StringValue(text)) => Value::String(text.clone()),
+        Some(Kind::ListValue(list)) => {
+            Value::Array(list.values.iter().map(proto_to_json).collect())
+        }
+        Some(Kind::StructValue(object)) => Value::Object(
+            object
+                .fields
+                .iter()
+                .map(|(key, value)| (key.clone(), proto_to_json(value)))
+                .collect::<Map<_, _>>(),
+        ),
+    }
+}
+
+fn number_to_json(number: f64) -> Value {
+    if number.fract() == 0.0 && number >= i64::MIN as f64 && number < i64::MAX as f64 {
+        return Value::Number(Number::from(number as i64));
+    }
+
+    Number::from_f64(number)
+        .map(Value::Number)
+        .unwrap_or(Value::Null)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use http::HeaderValue;
+    use serde_json::json;
+
+    fn sample_response() -> QueryResponse {
+        QueryResponse {
+            results: vec![
+                json!({
+                    "uid": "sr:device-1",
+                    "ip": "10.0.0.1",
+                    "is_available": true,
+                    "risk_score": 4.5,
+                    "open_ports": [22, 443],
+                    "metadata": {"site": "nyc", "rack": null},
+                }),
+                json!({
+                    "uid": "sr:device-2",
+                    "ip": "10.0.0.2",
+                    "is_available": false,
+                    "risk_score": 0,
+                    "open_ports": [],
+                    "metadata": {},
+                }),
+            ],
+            pagination: PaginationMeta {
+                next_cursor: Some("cursor-2".to_string()),
+                prev_cursor: None,
+                limit: Some(2),
+            },
+            downsample: None,
+            truncated: true,
+            total_available: Some(5),
+            explain: None,
+            error: None,
+        }
+    }
+
+    #[test]
+    fn protobuf_and_json_decode_to_the_same_rows() {
+        let response = sample_response();
+
+        let json_body = serde_json::to_vec(&response).unwrap();
+        let json_decoded: Value = serde_json::from_slice(&json_body).unwrap();
+
+        let proto_body = encode_protobuf(&response);
+        let proto_decoded = pb::QueryResponse::decode(proto_body.as_slice()).unwrap();
+        let proto_rows: Vec<Value> = proto_decoded.results.iter().map(proto_to_json).collect();
+
+        assert_eq!(json_decoded["results"], Value::Array(proto_rows));
+
+        let pagination = proto_decoded.pagination.unwrap();
+        assert_eq!(pagination.next_cursor.as_deref(), Some("cursor-2"));
+        assert_eq!(pagination.prev_cursor, None);
+        assert_eq!(pagination.limit, Some(2));
+        assert_eq!(proto_decoded.error, None);
+        assert_eq!(json_decoded["truncated"], Value::Bool(true));
+        assert!(proto_decoded.truncated);
+        assert_eq!(json_decoded["total_available"], Value::from(5));
+        assert_eq!(proto_decoded.total_available, Some(5));
+    }
+
+    #[test]
+    fn negotiates_protobuf_only_when_requested() {
+        let mut headers = HeaderMap::new();
+        assert!(!wants_protobuf(&headers));
+
+        headers.insert(ACCEPT, HeaderValue::from_static("application/json, */*"));
+        assert!(!wants_protobuf(&headers));
+
+        headers.insert(
+            ACCEPT,
+            HeaderValue::from_static("application/json;q=0.5, application/x-protobuf"),
+        );
+        assert!(wants_protobuf(&headers));
+    }
+
+    #[test]
+    fn json_response_encodes_like_the_server_response() {
+        let response = sample_response();
+        let json = serde_json::to_value(&response).unwrap();
+
+        let from_json = encode_protobuf_json(&json).expect("json response should encode");
+        assert_eq!(from_json, encode_protobuf(&response));
+    }
+
+    #[test]
+    fn json_response_defaults_missing_fields() {
+        let encoded = encode_protobuf_json(&json!({"results": [{"uid": "sr:device-1"}]}))
+            .expect("minimal response should encode");
+        let decoded = pb::QueryResponse::decode(encoded.as_slice()).unwrap();
+
+        assert_eq!(decoded.results.len(), 1);
+        assert!(!decoded.truncated);
+        assert_eq!(decoded.total_available, None);
+        assert_eq!(decoded.pagination.unwrap().limit, None);
+
+        assert!(encode_protobuf_json(&json!([])).is_err());
+        assert!(encode_protobuf_json(&json!({"results": "rows"})).is_err());
+    }
+}
@@ -2,6 +2,7 @@
 
 pub mod config;
 pub mod db;
+pub mod encoding;
 pub mod error;
 pub mod jsonb;
 pub mod models;
@@ -17,7 +18,8 @@ pub mod time;
 use crate::{config::AppConfig, server::Server};
 
 pub use crate::query::{
-    QueryDirection, QueryEngine, QueryRequest, QueryResponse, TranslateRequest, TranslateResponse,
+    DownsampleMeta, QueryDirection, QueryEngine, QueryRequest, QueryResponse, TranslateRequest,
+    TranslateResponse,
 };
 
 /// Bootstraps the SRQL service using environment configuration.
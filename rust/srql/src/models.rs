@@ -10,6 +10,9 @@ use diesel::sql_types::{
 use serde::Serialize;
 use uuid::Uuid;
 
+/// Lifecycle state reported for devices without an explicit one.
+pub const DEFAULT_DEVICE_LIFECYCLE_STATE: &str = "active";
+
 /// OCSF-aligned agent row (OCSF v1.7.0 Agent object)
 #[derive(Debug, Clone, Queryable, Selectable, Serialize)]
 #[diesel(table_name = crate::schema::ocsf_agents, check_for_backend(diesel::pg::Pg))]
@@ -124,10 +127,38 @@ pub struct DeviceRow {
     pub deleted_at: Option<DateTime<Utc>>,
     pub deleted_by: Option<String>,
     pub deleted_reason: Option<String>,
+
+    // Ownership assignment
+    pub owner_uid: Option<String>,
+    pub owner_type: Option<String>,
+
+    // Archival
+    pub archived_at: Option<DateTime<Utc>>,
+    pub archived_by: Option<String>,
+    pub archived_reason: Option<String>,
 }
 
 impl DeviceRow {
     pub fn into_json(self) -> serde_json::Value {
+        let lifecycle_state = self
+            .metadata
+            .as_ref()
+            .and_then(|metadata| metadata.get("lifecycle_state"))
+            .and_then(|value| value.as_str())
+            .filter(|value| !value.is_empty())
+            .unwrap_or(DEFAULT_DEVICE_LIFECYCLE_STATE)
+            .to_string();
+
+        let is_available = self.is_available.unwrap_or(false);
+        let health_rollup = self
+            .metadata
+            .as_ref()
+            .and_then(|metadata| metadata.get("health_rollup"))
+            .and_then(|value| value.as_str())
+            .filter(|value| !value.is_empty())
+            .unwrap_or(if is_available { "healthy" } else { "unhealthy" })
+            .to_string();
+
         serde_json::json!({
             // OCSF Core Identity
             "uid": self.uid,
@@ -178,13 +209,20 @@ impl DeviceRow {
             "gateway_id": self.gateway_id,
             "agent_id": self.agent_id,
             "discovery_sources": self.discovery_sources.unwrap_or_default(),
-            "is_available": self.is_available.unwrap_or(false),
+            "is_available": is_available,
+            "lifecycle_state": lifecycle_state,
+            "health_rollup": health_rollup,
             "metadata": self
                 .metadata
                 .map_or(serde_json::json!({}), serde_json::Value::from),
             "deleted_at": self.deleted_at,
             "deleted_by": self.deleted_by,
             "deleted_reason": self.deleted_reason,
+            "owner_uid": self.owner_uid,
+            "owner_type": self.owner_type,
+            "archived_at": self.archived_at,
+            "archived_by": self.archived_by,
+            "archived_reason": self.archived_reason,
         })
     }
 }
@@ -409,7 +447,7 @@ impl LogRow {
     }
 }
 
-#[derive(Debug, Clone, Queryable, Selectable, Serialize)]
+#[derive(Debug, Clone, Queryable, QueryableByName, Selectable, Serialize)]
 #[diesel(table_name = crate::schema::otel_traces, check_for_backend(diesel::pg::Pg))]
 pub struct TraceSpanRow {
     pub timestamp: DateTime<Utc>,
@@ -741,6 +779,14 @@ pub struct DiskMetricRow {
     pub device_id: Option<String>,
     pub partition: Option<String>,
     pub created_at: DateTime<Utc>,
+    pub read_bytes: Option<i64>,
+    pub write_bytes: Option<i64>,
+    pub read_ops: Option<i64>,
+    pub write_ops: Option<i64>,
+    pub read_bytes_per_sec: Option<f64>,
+    pub write_bytes_per_sec: Option<f64>,
+    pub read_ops_per_sec: Option<f64>,
+    pub write_ops_per_sec: Option<f64>,
 }
 
 impl DiskMetricRow {
@@ -756,6 +802,65 @@ impl DiskMetricRow {
             "used_bytes": self.used_bytes,
             "available_bytes": self.available_bytes,
             "usage_percent": self.usage_percent,
+            "read_bytes": self.read_bytes,
+            "write_bytes": self.write_bytes,
+            "read_ops": self.read_ops,
+            "write_ops": self.write_ops,
+            "read_bytes_per_sec": self.read_bytes_per_sec,
+            "write_bytes_per_sec": self.write_bytes_per_sec,
+            "read_ops_per_sec": self.read_ops_per_sec,
+            "write_ops_per_sec": self.write_ops_per_sec,
+            "uid": self.device_id,
+            "partition": self.partition,
+        })
+    }
+}
+
+#[derive(Debug, Clone, Queryable, Selectable, Serialize)]
+#[diesel(table_name = crate::schema::network_metrics, check_for_backend(diesel::pg::Pg))]
+pub struct NetworkMetricRow {
+    pub timestamp: DateTime<Utc>,
+    pub gateway_id: Option<String>,
+    pub agent_id: Option<String>,
+    pub host_id: Option<String>,
+    pub interface_name: Option<String>,
+    pub bytes_sent: Option<i64>,
+    pub bytes_recv: Option<i64>,
+    pub packets_sent: Option<i64>,
+    pub packets_recv: Option<i64>,
+    pub errors_in: Option<i64>,
+    pub errors_out: Option<i64>,
+    pub drops_in: Option<i64>,
+    pub drops_out: Option<i64>,
+    pub bytes_sent_per_sec: Option<f64>,
+    pub bytes_recv_per_sec: Option<f64>,
+    pub packets_sent_per_sec: Option<f64>,
+    pub packets_recv_per_sec: Option<f64>,
+    pub device_id: Option<String>,
+    pub partition: Option<String>,
+    pub created_at: DateTime<Utc>,
+}
+
+impl NetworkMetricRow {
+    pub fn into_json(self) -> serde_json::Value {
+        serde_json::json!({
+            "timestamp": self.timestamp,
+            "gateway_id": self.gateway_id,
+            "agent_id": self.agent_id,
+            "host_id": self.host_id,
+            "interface_name": self.interface_name,
+            "bytes_sent": self.bytes_sent,
+            "bytes_recv": self.bytes_recv,
+            "packets_sent": self.packets_sent,
+            "packets_recv": self.packets_recv,
+            "errors_in": self.errors_in,
+            "errors_out": self.errors_out,
+            "drops_in": self.drops_in,
+            "drops_out": self.drops_out,
+            "bytes_sent_per_sec": self.bytes_sent_per_sec,
+            "bytes_recv_per_sec": self.bytes_recv_per_sec,
+            "packets_sent_per_sec": self.packets_sent_per_sec,
+            "packets_recv_per_sec": self.packets_recv_per_sec,
             "uid": self.device_id,
             "partition": self.partition,
         })
@@ -932,3 +1037,46 @@ impl AlertRow {
         })
     }
 }
+
+/// A TLS certificate tracked for expiry
+#[derive(Debug, Clone, Queryable, Selectable, Serialize)]
+#[diesel(table_name = crate::schema::monitored_certificates, check_for_backend(diesel::pg::Pg))]
+pub struct CertificateRow {
+    pub id: Uuid,
+    pub source: String,
+    pub target: String,
+    pub name: Option<String>,
+    pub subject: Option<String>,
+    pub issuer: Option<String>,
+    pub serial_number: Option<String>,
+    pub not_before: Option<DateTime<Utc>>,
+    pub not_after: DateTime<Utc>,
+    pub last_checked_at: DateTime<Utc>,
+    pub inserted_at: DateTime<Utc>,
+    pub updated_at: DateTime<Utc>,
+}
+
+impl CertificateRow {
+    /// Serializes the row, adding `days_remaining` (whole days until
+    /// `not_after` as of `now`; negative once expired).
+    pub fn into_json(self, now: DateTime<Utc>) -> serde_json::Value {
+        let days_remaining = (self.not_after - now).num_seconds().div_euclid(86_400);
+
+        serde_json::json!({
+            "id": self.id.to_string(),
+            "source": self.source,
+            "target": self.target,
+            "name": self.name,
+            "subject": self.subject,
+            "issuer": self.issuer,
+            "serial_number": self.serial_number,
+            "not_before": self.not_before,
+            "not_after": self.not_after,
+            "days_remaining": days_remaining,
+            "last_checked_at": self.last_checked_at,
+            "timestamp": self.last_checked_at,
+            "inserted_at": self.inserted_at,
+            "updated_at": self.updated_at,
+        })
+    }
+}
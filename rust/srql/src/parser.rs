@@ -2,11 +2,12 @@
 
 use crate::{
     error::{Result, ServiceError},
-    time::{parse_time_value, TimeFilterSpec},
+    time::{parse_datetime, parse_time_value, TimeFilterSpec},
 };
+use chrono::{DateTime, Utc};
 use serde::Serialize;
 
-#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+#[derive(Debug, Clone, PartialEq, Eq, Hash, Serialize)]
 #[serde(rename_all = "snake_case")]
 pub enum Entity {
     Agents,
@@ -39,6 +40,7 @@ pub enum Entity {
     CpuMetrics,
     MemoryMetrics,
     DiskMetrics,
+    NetworkMetrics,
     ProcessMetrics,
     TimeseriesMetrics,
     SnmpMetrics,
@@ -46,6 +48,7 @@ pub enum Entity {
     Traces,
     Flows,
     Alerts,
+    Certificates,
 }
 
 #[derive(Debug, Clone, Serialize)]
@@ -59,6 +62,8 @@ pub struct QueryAst {
     pub downsample: Option<DownsampleSpec>,
     /// Rollup stats type for querying pre-computed CAGGs (e.g., "severity", "summary", "availability")
     pub rollup_stats: Option<String>,
+    /// Set by a leading `EXPLAIN`: return the translated SQL instead of rows.
+    pub explain: bool,
 }
 
 /// Parsed stats specification with structured aggregation info
@@ -82,6 +87,34 @@ impl StatsSpec {
     pub fn from_raw(raw: &str) -> Self {
         parse_stats_expr(raw)
     }
+
+    /// Returns the `by` clause of the stats expression, if any.
+    pub fn group_by(&self) -> Option<String> {
+        split_stats_group_by(&self.raw).1
+    }
+
+    /// Resolves a `sort:` field against the select list: either an alias
+    /// (`total`) or an aggregate written out in full (`sum(bytes_total)`)
+    /// resolves to that aggregation's alias.
+    pub fn resolve_order_field(&self, field: &str) -> Option<&str> {
+        if let Some(aggregation) = self
+            .aggregations
+            .iter()
+            .find(|aggregation| aggregation.alias.eq_ignore_ascii_case(field))
+        {
+            return Some(&aggregation.alias);
+        }
+
+        let expr = parse_single_stats_agg(&format!("{field} as {field}"))?;
+        self.aggregations
+            .iter()
+            .find(|aggregation| {
+                aggregation.agg_type == expr.agg_type
+                    && aggregation.field == expr.field
+                    && aggregation.percentile == expr.percentile
+            })
+            .map(|aggregation| aggregation.alias.as_str())
+    }
 }
 
 /// A single stats aggregation like count(), sum(field), etc.
@@ -94,21 +127,29 @@ pub struct StatsAggregation {
     pub field: Option<String>,
     /// The alias for the result
     pub alias: String,
+    /// Fraction (0-1) for percentile aggregations
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub percentile: Option<f64>,
 }
 
 /// Stats aggregation function types
-#[derive(Debug, Clone, Serialize)]
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
 #[serde(rename_all = "snake_case")]
 pub enum StatsAggType {
     Count,
+    CountDistinct,
     Sum,
     Avg,
     Min,
     Max,
+    /// `percentile(field, 99)`, translated to `percentile_cont`.
+    Percentile,
 }
 
 const MAX_STATS_EXPR_LEN: usize = 1024;
 const MAX_FILTER_LIST_VALUES: usize = 200;
+const MAX_SUBQUERY_FILTERS: usize = 8;
+const MAX_SUBQUERY_ROWS: i64 = 10_000;
 const MAX_DOWNSAMPLE_BUCKET_SECS: i64 = 31 * 24 * 60 * 60;
 
 #[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
@@ -125,9 +166,26 @@ pub enum DownsampleAgg {
     Rate,
 }
 
+impl DownsampleAgg {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            DownsampleAgg::Avg => "avg",
+            DownsampleAgg::Min => "min",
+            DownsampleAgg::Max => "max",
+            DownsampleAgg::Sum => "sum",
+            DownsampleAgg::Count => "count",
+            DownsampleAgg::Rate => "rate",
+        }
+    }
+}
+
 #[derive(Debug, Clone, PartialEq, Eq, Serialize)]
 pub struct DownsampleSpec {
+    /// Bucket width. Zero until an automatic bucket is resolved against the
+    /// query's time range.
     pub bucket_seconds: i64,
+    /// True when the bucket was (or will be) sized automatically.
+    pub auto: bool,
     pub agg: DownsampleAgg,
     pub series: Option<String>,
     pub value_field: Option<String>,
@@ -160,6 +218,22 @@ pub enum FilterOp {
 pub enum FilterValue {
     Scalar(String),
     List(Vec<String>),
+    /// Nested `field:(in:<entity> ... select:<field>)` query, translated to
+    /// `field IN (SELECT ...)`.
+    Subquery(Box<Subquery>),
+}
+
+/// A bounded query nested inside a list filter. Subqueries only carry
+/// filters, a time window and a row limit; they cannot nest further or use
+/// stats, downsampling, ordering or EXPLAIN.
+#[derive(Debug, Clone, Serialize)]
+pub struct Subquery {
+    pub entity: Entity,
+    /// Field of `entity` whose values feed the outer IN list.
+    pub select: String,
+    pub filters: Vec<Filter>,
+    pub time_filter: Option<TimeFilterSpec>,
+    pub limit: i64,
 }
 
 impl FilterValue {
@@ -169,6 +243,7 @@ impl FilterValue {
             FilterValue::List(_) => {
                 Err(ServiceError::InvalidRequest("expected scalar value".into()))
             }
+            FilterValue::Subquery(_) => Err(subquery_unsupported()),
         }
     }
 
@@ -178,8 +253,29 @@ impl FilterValue {
             FilterValue::Scalar(_) => {
                 Err(ServiceError::InvalidRequest("expected list value".into()))
             }
+            FilterValue::Subquery(_) => Err(subquery_unsupported()),
         }
     }
+
+    /// Returns the value as a list, treating a scalar as a single-item list.
+    pub fn to_values(&self) -> Result<Vec<String>> {
+        match self {
+            FilterValue::Scalar(v) => Ok(vec![v.clone()]),
+            FilterValue::List(items) => Ok(items.clone()),
+            FilterValue::Subquery(_) => Err(subquery_unsupported()),
+        }
+    }
+
+    pub fn as_subquery(&self) -> Option<&Subquery> {
+        match self {
+            FilterValue::Subquery(subquery) => Some(subquery),
+            _ => None,
+        }
+    }
+}
+
+fn subquery_unsupported() -> ServiceError {
+    ServiceError::InvalidRequest("subqueries are not supported for this filter".into())
 }
 
 #[derive(Debug, Clone, Serialize)]
@@ -209,7 +305,48 @@ pub fn parse(input: &str) -> Result<QueryAst> {
     let mut rollup_stats: Option<String> = None;
 
     let mut tokens = tokenize(input).into_iter().peekable();
+    let explain = tokens
+        .next_if(|token| token.eq_ignore_ascii_case("explain"))
+        .is_some();
     while let Some(token) = tokens.next() {
+        if !token.contains(':')
+            && tokens
+                .peek()
+                .is_some_and(|next| next.eq_ignore_ascii_case("between"))
+        {
+            let _ = tokens.next();
+            let lower = tokens.next();
+            let and = tokens.next();
+            let upper = tokens.next();
+            let (Some(lower), Some(upper)) = (lower, upper) else {
+                return Err(ServiceError::InvalidRequest(BETWEEN_SYNTAX_ERROR.into()));
+            };
+            if !and.is_some_and(|and| and.eq_ignore_ascii_case("and")) {
+                return Err(ServiceError::InvalidRequest(BETWEEN_SYNTAX_ERROR.into()));
+            }
+
+            let field = token.trim().to_lowercase();
+            match parse_between_bounds(&lower, &upper)? {
+                BetweenBounds::Timestamp(start, end) if field == "time" || field == "timestamp" => {
+                    time_filter = Some(TimeFilterSpec::Absolute { start, end });
+                }
+                bounds => {
+                    let (lower, upper) = bounds.into_strings();
+                    filters.push(Filter {
+                        field: field.clone(),
+                        op: FilterOp::Gte,
+                        value: FilterValue::Scalar(lower),
+                    });
+                    filters.push(Filter {
+                        field,
+                        op: FilterOp::Lte,
+                        value: FilterValue::Scalar(upper),
+                    });
+                }
+            }
+            continue;
+        }
+
         let (raw_key, raw_value) = split_token(&token)?;
         let key = raw_key.trim().to_lowercase();
         let value = parse_value(raw_value);
@@ -240,7 +377,12 @@ pub fn parse(input: &str) -> Result<QueryAst> {
                 time_filter = Some(parse_time_value(raw_value)?);
             }
             "bucket" | "downsample" => {
-                downsample_bucket_seconds = Some(parse_bucket_seconds(value.as_scalar()?)?);
+                let raw = value.as_scalar()?;
+                downsample_bucket_seconds = if raw.trim().eq_ignore_ascii_case("auto") {
+                    Some(0)
+                } else {
+                    Some(parse_bucket_seconds(raw)?)
+                };
             }
             "agg" => {
                 downsample_agg = parse_downsample_agg(value.as_scalar()?)?;
@@ -347,6 +489,14 @@ pub fn parse(input: &str) -> Result<QueryAst> {
                 continue;
             }
             _ => {
+                if let Some(body) = subquery_body(raw_value) {
+                    let subquery = parse_subquery(body)?;
+                    filters.push(build_filter(
+                        raw_key,
+                        FilterValue::Subquery(Box::new(subquery)),
+                    ));
+                    continue;
+                }
                 if let FilterValue::List(ref items) = value {
                     if items.len() > MAX_FILTER_LIST_VALUES {
                         return Err(ServiceError::InvalidRequest(format!(
@@ -365,6 +515,7 @@ pub fn parse(input: &str) -> Result<QueryAst> {
 
     let downsample = downsample_bucket_seconds.map(|bucket_seconds| DownsampleSpec {
         bucket_seconds,
+        auto: bucket_seconds == 0,
         agg: downsample_agg,
         series: downsample_series,
         value_field: downsample_value_field,
@@ -379,10 +530,11 @@ pub fn parse(input: &str) -> Result<QueryAst> {
         stats,
         downsample,
         rollup_stats,
+        explain,
     })
 }
 
-fn parse_entity(raw: &str) -> Result<Entity> {
+pub(crate) fn parse_entity(raw: &str) -> Result<Entity> {
     let normalized = raw.trim_matches('"').trim_matches('\'').to_lowercase();
     match normalized.as_str() {
         "agents" | "agent" | "ocsf_agents" => Ok(Entity::Agents),
@@ -435,6 +587,7 @@ fn parse_entity(raw: &str) -> Result<Entity> {
         "cpu_metrics" | "cpu" => Ok(Entity::CpuMetrics),
         "memory_metrics" | "memory" => Ok(Entity::MemoryMetrics),
         "disk_metrics" | "disk" => Ok(Entity::DiskMetrics),
+        "network_metrics" | "network" => Ok(Entity::NetworkMetrics),
         "process_metrics" | "processes" => Ok(Entity::ProcessMetrics),
         "timeseries_metrics" | "timeseries" => Ok(Entity::TimeseriesMetrics),
         "snmp_metrics" | "snmp" => Ok(Entity::SnmpMetrics),
@@ -444,6 +597,7 @@ fn parse_entity(raw: &str) -> Result<Entity> {
         "otel_traces" | "traces" | "trace_spans" => Ok(Entity::Traces),
         "flows" | "flow" | "network_activity" => Ok(Entity::Flows),
         "alerts" | "alert" => Ok(Entity::Alerts),
+        "certificates" | "certificate" | "certs" | "cert_expiry" => Ok(Entity::Certificates),
         other => Err(ServiceError::InvalidRequest(format!(
             "unsupported entity '{other}'"
         ))),
@@ -566,8 +720,8 @@ fn merge_stats_exprs(existing: &str, next: &str) -> Result<String> {
 fn parse_stats_expr(raw: &str) -> StatsSpec {
     let raw = raw.trim().trim_matches('"').trim_matches('\'');
     let (agg_expr, _group_by) = split_stats_group_by(raw);
-    let aggregations = agg_expr
-        .split(',')
+    let aggregations = split_top_level(&agg_expr)
+        .into_iter()
         .filter_map(|part| parse_single_stats_agg(part.trim()))
         .collect();
 
@@ -577,6 +731,27 @@ fn parse_stats_expr(raw: &str) -> StatsSpec {
     }
 }
 
+/// Splits on commas outside parentheses, so `percentile(duration_ms, 99)`
+/// stays one aggregation.
+pub(crate) fn split_top_level(raw: &str) -> Vec<&str> {
+    let mut parts = Vec::new();
+    let mut depth = 0usize;
+    let mut start = 0;
+    for (idx, ch) in raw.char_indices() {
+        match ch {
+            '(' => depth += 1,
+            ')' => depth = depth.saturating_sub(1),
+            ',' if depth == 0 => {
+                parts.push(&raw[start..idx]);
+                start = idx + 1;
+            }
+            _ => {}
+        }
+    }
+    parts.push(&raw[start..]);
+    parts
+}
+
 /// Parse a single stats aggregation like "count() as total" or "sum(field) as total"
 fn parse_single_stats_agg(expr: &str) -> Option<StatsAggregation> {
     let expr = expr.trim().to_lowercase();
@@ -595,7 +770,23 @@ fn parse_single_stats_agg(expr: &str) -> Option<StatsAggregation> {
         return None;
     }
 
-    // Parse the function: count(), sum(field), avg(field), min(field), max(field)
+    // Parse the function: count(), count(distinct field), sum(field), avg(field), min(field), max(field)
+    if let Some(inner) = func_part
+        .strip_prefix("count(distinct ")
+        .and_then(|s| s.strip_suffix(')'))
+    {
+        let field = inner.trim();
+        if field.is_empty() {
+            return None;
+        }
+        return Some(StatsAggregation {
+            agg_type: StatsAggType::CountDistinct,
+            field: Some(field.to_string()),
+            alias: alias.to_string(),
+            percentile: None,
+        });
+    }
+
     if let Some(inner) = func_part
         .strip_prefix("count(")
         .and_then(|s| s.strip_suffix(')'))
@@ -606,6 +797,7 @@ fn parse_single_stats_agg(expr: &str) -> Option<StatsAggregation> {
             agg_type: StatsAggType::Count,
             field: None,
             alias: alias.to_string(),
+            percentile: None,
         });
     }
 
@@ -619,6 +811,7 @@ fn parse_single_stats_agg(expr: &str) -> Option<StatsAggregation> {
                 agg_type: StatsAggType::Sum,
                 field: Some(field.to_string()),
                 alias: alias.to_string(),
+                percentile: None,
             });
         }
     }
@@ -633,6 +826,7 @@ fn parse_single_stats_agg(expr: &str) -> Option<StatsAggregation> {
                 agg_type: StatsAggType::Avg,
                 field: Some(field.to_string()),
                 alias: alias.to_string(),
+                percentile: None,
             });
         }
     }
@@ -647,6 +841,24 @@ fn parse_single_stats_agg(expr: &str) -> Option<StatsAggregation> {
                 agg_type: StatsAggType::Min,
                 field: Some(field.to_string()),
                 alias: alias.to_string(),
+                percentile: None,
+            });
+        }
+    }
+
+    if let Some(inner) = func_part
+        .strip_prefix("percentile(")
+        .and_then(|s| s.strip_suffix(')'))
+    {
+        let (field, percentile) = inner.split_once(',')?;
+        let field = field.trim();
+        let percentile = percentile_fraction(percentile).ok()?;
+        if !field.is_empty() {
+            return Some(StatsAggregation {
+                agg_type: StatsAggType::Percentile,
+                field: Some(field.to_string()),
+                alias: alias.to_string(),
+                percentile: Some(percentile),
             });
         }
     }
@@ -661,6 +873,7 @@ fn parse_single_stats_agg(expr: &str) -> Option<StatsAggregation> {
                 agg_type: StatsAggType::Max,
                 field: Some(field.to_string()),
                 alias: alias.to_string(),
+                percentile: None,
             });
         }
     }
@@ -668,6 +881,32 @@ fn parse_single_stats_agg(expr: &str) -> Option<StatsAggregation> {
     None
 }
 
+/// Parses a percentile such as `99` or `99.9` into the fraction
+/// `percentile_cont` takes.
+pub(crate) fn percentile_fraction(raw: &str) -> Result<f64> {
+    let raw = raw.trim();
+    let value = raw
+        .parse::<f64>()
+        .map_err(|_| ServiceError::InvalidRequest(format!("invalid percentile '{raw}'")))?;
+    if !(value > 0.0 && value < 100.0) {
+        return Err(ServiceError::InvalidRequest(
+            "percentiles must be between 0 and 100 (exclusive)".into(),
+        ));
+    }
+    Ok(value / 100.0)
+}
+
+/// Parses a `pNN` filter threshold such as `p99` or `p99.9`. Returns None when
+/// `raw` is not a percentile threshold.
+pub(crate) fn percentile_threshold(raw: &str) -> Option<Result<f64>> {
+    let raw = raw.trim().trim_matches('"').trim_matches('\'');
+    let rest = raw.strip_prefix('p').or_else(|| raw.strip_prefix('P'))?;
+    if rest.is_empty() || !rest.chars().all(|ch| ch.is_ascii_digit() || ch == '.') {
+        return None;
+    }
+    Some(percentile_fraction(rest))
+}
+
 fn build_filter(key: &str, value: FilterValue) -> Filter {
     let mut field = key.trim();
     let mut negated = false;
@@ -698,7 +937,7 @@ fn build_filter(key: &str, value: FilterValue) -> Filter {
                 (FilterOp::Eq, FilterValue::Scalar(v))
             }
         }
-        FilterValue::List(_) => {
+        FilterValue::List(_) | FilterValue::Subquery(_) => {
             if negated {
                 (FilterOp::NotIn, value)
             } else {
@@ -714,7 +953,60 @@ fn build_filter(key: &str, value: FilterValue) -> Filter {
     }
 }
 
-fn parse_order(raw: &str) -> Vec<OrderClause> {
+const BETWEEN_SYNTAX_ERROR: &str = "BETWEEN must be of the form 'field BETWEEN low AND high'";
+
+/// Inclusive bounds of a `field BETWEEN low AND high` condition.
+enum BetweenBounds {
+    Number(String, String),
+    Timestamp(DateTime<Utc>, DateTime<Utc>),
+}
+
+impl BetweenBounds {
+    fn into_strings(self) -> (String, String) {
+        match self {
+            BetweenBounds::Number(lower, upper) => (lower, upper),
+            BetweenBounds::Timestamp(lower, upper) => (lower.to_rfc3339(), upper.to_rfc3339()),
+        }
+    }
+}
+
+/// Requires both bounds to be numbers or both to be timestamps, with the
+/// lower bound not after the upper one.
+fn parse_between_bounds(lower: &str, upper: &str) -> Result<BetweenBounds> {
+    let lower = lower.trim().trim_matches('"').trim_matches('\'');
+    let upper = upper.trim().trim_matches('"').trim_matches('\'');
+
+    let numbers = (lower.parse::<f64>(), upper.parse::<f64>());
+    if let (Ok(low), Ok(high)) = numbers {
+        if !low.is_finite() || !high.is_finite() {
+            return Err(ServiceError::InvalidRequest(
+                "BETWEEN bounds must be finite numbers".into(),
+            ));
+        }
+        if low > high {
+            return Err(ServiceError::InvalidRequest(format!(
+                "BETWEEN lower bound {lower} is greater than upper bound {upper}"
+            )));
+        }
+        return Ok(BetweenBounds::Number(lower.to_string(), upper.to_string()));
+    }
+
+    let timestamps = (parse_datetime(lower), parse_datetime(upper));
+    if let (Ok(start), Ok(end)) = timestamps {
+        if start > end {
+            return Err(ServiceError::InvalidRequest(format!(
+                "BETWEEN lower bound {lower} is after upper bound {upper}"
+            )));
+        }
+        return Ok(BetweenBounds::Timestamp(start, end));
+    }
+
+    Err(ServiceError::InvalidRequest(format!(
+        "BETWEEN bounds must both be numbers or both be timestamps (got '{lower}' and '{upper}')"
+    )))
+}
+
+pub(crate) fn parse_order(raw: &str) -> Vec<OrderClause> {
     raw.split(',')
         .filter_map(|segment| {
             let trimmed = segment.trim();
@@ -738,6 +1030,74 @@ fn parse_order(raw: &str) -> Vec<OrderClause> {
         .collect()
 }
 
+/// Returns the inner query of a `(in:<entity> ...)` filter value.
+fn subquery_body(raw: &str) -> Option<&str> {
+    let inner = raw.trim().strip_prefix('(')?.strip_suffix(')')?.trim();
+    let prefix = inner.get(..3)?;
+    prefix.eq_ignore_ascii_case("in:").then_some(inner)
+}
+
+fn parse_subquery(body: &str) -> Result<Subquery> {
+    let mut select = None;
+    let mut rest = Vec::new();
+    for token in tokenize(body) {
+        match token.split_once(':') {
+            Some((key, value)) if key.trim().eq_ignore_ascii_case("select") => {
+                if select.is_some() {
+                    return Err(ServiceError::InvalidRequest(
+                        "subqueries select exactly one field".into(),
+                    ));
+                }
+                select = normalize_optional_string(value);
+            }
+            Some((_, value)) if subquery_body(value).is_some() => {
+                return Err(ServiceError::InvalidRequest(
+                    "subqueries cannot be nested".into(),
+                ));
+            }
+            _ => rest.push(token),
+        }
+    }
+
+    let select = select.ok_or_else(|| {
+        ServiceError::InvalidRequest(
+            "subqueries must select a field (e.g., select:device_id)".into(),
+        )
+    })?;
+
+    let ast = parse(&rest.join(" "))?;
+    if ast.explain
+        || ast.stats.is_some()
+        || ast.downsample.is_some()
+        || ast.rollup_stats.is_some()
+        || !ast.order.is_empty()
+    {
+        return Err(ServiceError::InvalidRequest(
+            "subqueries only support filters, time, limit and select".into(),
+        ));
+    }
+    if ast.filters.len() > MAX_SUBQUERY_FILTERS {
+        return Err(ServiceError::InvalidRequest(format!(
+            "subqueries support at most {MAX_SUBQUERY_FILTERS} filters"
+        )));
+    }
+
+    let limit = ast.limit.unwrap_or(MAX_SUBQUERY_ROWS);
+    if limit > MAX_SUBQUERY_ROWS {
+        return Err(ServiceError::InvalidRequest(format!(
+            "subquery limit must be <= {MAX_SUBQUERY_ROWS}"
+        )));
+    }
+
+    Ok(Subquery {
+        entity: ast.entity,
+        select: select.to_lowercase(),
+        filters: ast.filters,
+        time_filter: ast.time_filter,
+        limit,
+    })
+}
+
 fn tokenize(input: &str) -> Vec<String> {
     let mut tokens = Vec::new();
     let mut current = String::new();
@@ -958,6 +1318,28 @@ mod tests {
         }
     }
 
+    #[test]
+    fn parses_aggregate_sort_fields() {
+        let ast = parse(
+            "in:devices stats:\"count(distinct vendor_name) as vendors by type\" sort:count(distinct vendor_name):desc,type:asc",
+        )
+        .unwrap();
+        assert_eq!(ast.order.len(), 2);
+        assert_eq!(ast.order[0].field, "count(distinct vendor_name)");
+        assert!(matches!(ast.order[0].direction, OrderDirection::Desc));
+        assert_eq!(ast.order[1].field, "type");
+
+        let stats = ast.stats.as_ref().unwrap();
+        assert_eq!(stats.group_by().as_deref(), Some("type"));
+        assert_eq!(
+            stats.resolve_order_field(&ast.order[0].field),
+            Some("vendors")
+        );
+        assert_eq!(stats.resolve_order_field("VENDORS"), Some("vendors"));
+        assert_eq!(stats.resolve_order_field("count()"), None);
+        assert_eq!(stats.resolve_order_field("type"), None);
+    }
+
     #[test]
     fn parses_stats_expression() {
         let ast = parse("in:logs stats:\"count() as total\" time:last_24h").unwrap();
@@ -971,6 +1353,42 @@ mod tests {
         assert_eq!(stats.aggregations[0].alias, "total");
     }
 
+    #[test]
+    fn parses_percentile_stats_aggregation() {
+        let ast = parse(
+            "in:otel_trace_summaries stats:\"percentile(duration_ms, 99) as p99, count() as total\"",
+        )
+        .unwrap();
+        let stats = ast.stats.as_ref().unwrap();
+        assert_eq!(stats.aggregations.len(), 2);
+        assert!(matches!(
+            stats.aggregations[0].agg_type,
+            StatsAggType::Percentile
+        ));
+        assert_eq!(stats.aggregations[0].field.as_deref(), Some("duration_ms"));
+        assert_eq!(stats.aggregations[0].percentile, Some(0.99));
+        assert_eq!(stats.aggregations[1].alias, "total");
+        assert_eq!(
+            stats.resolve_order_field("percentile(duration_ms, 99)"),
+            Some("p99")
+        );
+        assert_eq!(
+            stats.resolve_order_field("percentile(duration_ms, 95)"),
+            None
+        );
+    }
+
+    #[test]
+    fn parses_percentile_thresholds() {
+        assert_eq!(percentile_threshold("p99").unwrap().unwrap(), 0.99);
+        let fraction = percentile_threshold("P99.9").unwrap().unwrap();
+        assert!((fraction - 0.999).abs() < 1e-9);
+        assert!(percentile_threshold("250").is_none());
+        assert!(percentile_threshold("prod").is_none());
+        assert!(percentile_threshold("p100").unwrap().is_err());
+        assert!(percentile_fraction("0").is_err());
+    }
+
     #[test]
     fn parses_unquoted_stats_alias() {
         let ast = parse("in:devices stats:count() as total").unwrap();
@@ -1013,6 +1431,21 @@ mod tests {
         assert!(matches!(stats.aggregations[1].agg_type, StatsAggType::Sum));
     }
 
+    #[test]
+    fn parses_count_distinct_stats() {
+        let ast =
+            parse("in:devices stats:\"count(distinct vendor_name) as vendors\" by type").unwrap();
+        let stats = ast.stats.as_ref().unwrap();
+        assert_eq!(stats.raw, "count(distinct vendor_name) as vendors by type");
+        assert_eq!(stats.aggregations.len(), 1);
+        assert!(matches!(
+            stats.aggregations[0].agg_type,
+            StatsAggType::CountDistinct
+        ));
+        assert_eq!(stats.aggregations[0].field.as_deref(), Some("vendor_name"));
+        assert_eq!(stats.aggregations[0].alias, "vendors");
+    }
+
     #[test]
     fn parses_repeated_stats_tokens_by_merging_aggregations() {
         let ast = parse(
@@ -1098,4 +1531,130 @@ mod tests {
         let err = parse("in:logs rollup_stats:").unwrap_err();
         assert!(matches!(err, ServiceError::InvalidRequest(_)));
     }
+
+    #[test]
+    fn parses_numeric_between_as_inclusive_range() {
+        let ast = parse("in:cpu_metrics usage_percent BETWEEN 10 AND 90.5").unwrap();
+        assert_eq!(ast.filters.len(), 2);
+        assert_eq!(ast.filters[0].field, "usage_percent");
+        assert!(matches!(ast.filters[0].op, FilterOp::Gte));
+        assert_eq!(ast.filters[0].value.as_scalar().unwrap(), "10");
+        assert_eq!(ast.filters[1].field, "usage_percent");
+        assert!(matches!(ast.filters[1].op, FilterOp::Lte));
+        assert_eq!(ast.filters[1].value.as_scalar().unwrap(), "90.5");
+    }
+
+    #[test]
+    fn parses_time_between_as_absolute_range() {
+        let ast =
+            parse("in:logs time between 2025-01-01T00:00:00Z and 2025-01-02T00:00:00Z").unwrap();
+        assert!(ast.filters.is_empty());
+        match ast.time_filter {
+            Some(TimeFilterSpec::Absolute { start, end }) => {
+                assert_eq!(start.to_rfc3339(), "2025-01-01T00:00:00+00:00");
+                assert_eq!(end.to_rfc3339(), "2025-01-02T00:00:00+00:00");
+            }
+            other => panic!("expected absolute time range, got {other:?}"),
+        }
+    }
+
+    #[test]
+    fn parses_timestamp_between_on_other_fields() {
+        let ast = parse(
+            "in:devices last_seen BETWEEN '2025-01-01 00:00:00' AND 2025-01-01T06:00:00+02:00",
+        )
+        .unwrap();
+        assert!(ast.time_filter.is_none());
+        assert_eq!(
+            ast.filters[0].value.as_scalar().unwrap(),
+            "2025-01-01T00:00:00+00:00"
+        );
+        assert_eq!(
+            ast.filters[1].value.as_scalar().unwrap(),
+            "2025-01-01T04:00:00+00:00"
+        );
+    }
+
+    #[test]
+    fn parses_in_subquery() {
+        let ast = parse(
+            "in:devices device_id:(in:events severity:Critical time:last_24h select:device_id)",
+        )
+        .unwrap();
+        assert_eq!(ast.filters.len(), 1);
+        assert!(matches!(ast.filters[0].op, FilterOp::In));
+
+        let subquery = ast.filters[0]
+            .value
+            .as_subquery()
+            .expect("filter should carry a subquery");
+        assert!(matches!(subquery.entity, Entity::Events));
+        assert_eq!(subquery.select, "device_id");
+        assert_eq!(subquery.filters.len(), 1);
+        assert_eq!(subquery.filters[0].field, "severity");
+        assert!(subquery.time_filter.is_some());
+        assert_eq!(subquery.limit, MAX_SUBQUERY_ROWS);
+    }
+
+    #[test]
+    fn negated_subquery_uses_not_in() {
+        let ast =
+            parse("in:devices !uid:(in:events severity:Low select:device_id limit:50)").unwrap();
+        assert!(matches!(ast.filters[0].op, FilterOp::NotIn));
+        assert_eq!(ast.filters[0].value.as_subquery().unwrap().limit, 50);
+    }
+
+    #[test]
+    fn rejects_unbounded_subqueries() {
+        let too_many_filters = format!(
+            "in:devices uid:(in:events {} select:device_id)",
+            (0..=MAX_SUBQUERY_FILTERS)
+                .map(|i| format!("status_id:{i}"))
+                .collect::<Vec<_>>()
+                .join(" ")
+        );
+
+        for query in [
+            "in:devices uid:(in:events severity:Critical)".to_string(),
+            "in:devices uid:(in:events select:device_id select:device_ip)".to_string(),
+            "in:devices uid:(in:events uid:(in:events select:device_id) select:device_id)"
+                .to_string(),
+            "in:devices uid:(in:events stats:count() select:device_id)".to_string(),
+            "in:devices uid:(in:events sort:time:desc select:device_id)".to_string(),
+            format!(
+                "in:devices uid:(in:events limit:{} select:device_id)",
+                MAX_SUBQUERY_ROWS + 1
+            ),
+            too_many_filters,
+        ] {
+            assert!(
+                matches!(parse(&query), Err(ServiceError::InvalidRequest(_))),
+                "expected {query} to be rejected"
+            );
+        }
+    }
+
+    #[test]
+    fn parenthesized_values_without_in_remain_lists() {
+        let ast = parse("in:devices uid:(index:1,b)").unwrap();
+        assert!(matches!(ast.filters[0].value, FilterValue::List(_)));
+    }
+
+    #[test]
+    fn rejects_invalid_between_conditions() {
+        for query in [
+            "in:cpu_metrics usage_percent BETWEEN 10 AND 2025-01-01T00:00:00Z",
+            "in:cpu_metrics usage_percent BETWEEN 90 AND 10",
+            "in:cpu_metrics usage_percent BETWEEN low AND high",
+            "in:cpu_metrics usage_percent BETWEEN 10 90",
+            "in:cpu_metrics usage_percent BETWEEN 10",
+            "in:logs time BETWEEN 2025-01-02T00:00:00Z AND 2025-01-01T00:00:00Z",
+        ] {
+            let err = parse(query).unwrap_err();
+            assert!(
+                matches!(err, ServiceError::InvalidRequest(_)),
+                "expected {query} to be rejected"
+            );
+        }
+    }
 }
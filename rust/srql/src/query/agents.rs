@@ -138,10 +138,7 @@ fn apply_filter<'a>(mut query: AgentsQuery<'a>, filter: &Filter) -> Result<Agent
         }
         "capabilities" => {
             // Filter by capabilities using array overlap
-            let values = match &filter.value {
-                crate::parser::FilterValue::List(items) => items.clone(),
-                crate::parser::FilterValue::Scalar(v) => vec![v.clone()],
-            };
+            let values = filter.value.to_values()?;
             if !values.is_empty() {
                 match filter.op {
                     FilterOp::In | FilterOp::Eq => {
@@ -223,10 +220,7 @@ fn collect_filter_params(params: &mut Vec<BindParam>, filter: &Filter) -> Result
             Ok(())
         }
         "capabilities" => {
-            let values = match &filter.value {
-                crate::parser::FilterValue::List(items) => items.clone(),
-                crate::parser::FilterValue::Scalar(v) => vec![v.clone()],
-            };
+            let values = filter.value.to_values()?;
             if !values.is_empty() {
                 params.push(BindParam::TextArray(values));
             }
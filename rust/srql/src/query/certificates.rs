@@ -0,0 +1,367 @@
+//! Query support for `in:certificates`: TLS certificates tracked for expiry
+//! by core (configured endpoints and issued edge/collector package certs).
+//!
+//! `days_remaining` is computed from `not_after`, so `days_remaining:<30`
+//! filters on `not_after < now() + 30 days`.
+
+use super::{BindParam, QueryPlan};
+use crate::{
+    error::{Result, ServiceError},
+    models::CertificateRow,
+    parser::{Entity, Filter, FilterOp, OrderClause, OrderDirection},
+    schema::monitored_certificates::dsl::{
+        id as col_id, issuer as col_issuer, last_checked_at as col_last_checked_at,
+        monitored_certificates, name as col_name, not_after as col_not_after,
+        serial_number as col_serial_number, source as col_source, subject as col_subject,
+        target as col_target,
+    },
+    time::TimeRange,
+};
+use chrono::{DateTime, Duration as ChronoDuration, Utc};
+use diesel::pg::Pg;
+use diesel::prelude::*;
+use diesel::query_builder::{AsQuery, BoxedSelectStatement, FromClause};
+use diesel::PgTextExpressionMethods;
+use diesel_async::{AsyncPgConnection, RunQueryDsl};
+use uuid::Uuid;
+
+type CertificatesTable = crate::schema::monitored_certificates::table;
+type CertificatesFromClause = FromClause<CertificatesTable>;
+type CertificatesQuery<'a> =
+    BoxedSelectStatement<'a, <CertificatesTable as AsQuery>::SqlType, CertificatesFromClause, Pg>;
+
+pub(super) async fn execute(
+    conn: &mut AsyncPgConnection,
+    plan: &QueryPlan,
+) -> Result<Vec<serde_json::Value>> {
+    ensure_entity(plan)?;
+    let now = Utc::now();
+    let query = build_query(plan, now)?;
+    let rows: Vec<CertificateRow> = query
+        .select(CertificateRow::as_select())
+        .limit(plan.limit)
+        .offset(plan.offset)
+        .load::<CertificateRow>(conn)
+        .await
+        .map_err(|err| ServiceError::Internal(err.into()))?;
+
+    Ok(rows.into_iter().map(|row| row.into_json(now)).collect())
+}
+
+pub(super) fn to_sql_and_params(plan: &QueryPlan) -> Result<(String, Vec<BindParam>)> {
+    ensure_entity(plan)?;
+    let now = Utc::now();
+    let query = build_query(plan, now)?
+        .limit(plan.limit)
+        .offset(plan.offset);
+    let sql = super::diesel_sql(&query)?;
+
+    let mut params = Vec::new();
+    if let Some(TimeRange { start, end }) = &plan.time_range {
+        params.push(BindParam::timestamptz(*start));
+        params.push(BindParam::timestamptz(*end));
+    }
+
+    for filter in &plan.filters {
+        collect_filter_params(&mut params, filter, now)?;
+    }
+
+    super::reconcile_limit_offset_binds(&sql, &mut params, plan.limit, plan.offset)?;
+
+    #[cfg(any(test, debug_assertions))]
+    {
+        let bind_count = super::diesel_bind_count(&query)?;
+        if bind_count != params.len() {
+            return Err(ServiceError::Internal(anyhow::anyhow!(
+                "bind count mismatch (diesel {bind_count} vs params {})",
+                params.len()
+            )));
+        }
+    }
+
+    Ok((sql, params))
+}
+
+fn ensure_entity(plan: &QueryPlan) -> Result<()> {
+    match plan.entity {
+        Entity::Certificates => Ok(()),
+        _ => Err(ServiceError::InvalidRequest(
+            "entity not supported by certificates query".into(),
+        )),
+    }
+}
+
+fn build_query(plan: &QueryPlan, now: DateTime<Utc>) -> Result<CertificatesQuery<'static>> {
+    let mut query = monitored_certificates.into_boxed::<Pg>();
+
+    // Certificates are re-checked periodically, so the time range selects
+    // those seen recently.
+    if let Some(TimeRange { start, end }) = &plan.time_range {
+        query = query.filter(
+            col_last_checked_at
+                .ge(*start)
+                .and(col_last_checked_at.le(*end)),
+        );
+    }
+
+    for filter in &plan.filters {
+        query = apply_filter(query, filter, now)?;
+    }
+
+    query = apply_ordering(query, &plan.order);
+    Ok(query)
+}
+
+fn apply_filter<'a>(
+    mut query: CertificatesQuery<'a>,
+    filter: &Filter,
+    now: DateTime<Utc>,
+) -> Result<CertificatesQuery<'a>> {
+    match filter.field.as_str() {
+        "id" => {
+            let uuid = parse_uuid(filter.value.as_scalar()?)?;
+            query = match filter.op {
+                FilterOp::Eq => query.filter(col_id.eq(uuid)),
+                FilterOp::NotEq => query.filter(col_id.ne(uuid)),
+                _ => {
+                    return Err(ServiceError::InvalidRequest(
+                        "id filter only supports equality comparisons".into(),
+                    ))
+                }
+            };
+        }
+        "source" => {
+            query = apply_text_filter!(query, filter, col_source)?;
+        }
+        "target" => {
+            query = apply_text_filter!(query, filter, col_target)?;
+        }
+        "name" => {
+            query = apply_text_filter!(query, filter, col_name)?;
+        }
+        "subject" => {
+            query = apply_text_filter!(query, filter, col_subject)?;
+        }
+        "issuer" => {
+            query = apply_text_filter!(query, filter, col_issuer)?;
+        }
+        "serial_number" => {
+            query = apply_text_filter!(query, filter, col_serial_number)?;
+        }
+        "days_remaining" => {
+            let cutoff = days_remaining_cutoff(filter, now)?;
+            query = match filter.op {
+                FilterOp::Gt => query.filter(col_not_after.gt(cutoff)),
+                FilterOp::Gte => query.filter(col_not_after.ge(cutoff)),
+                FilterOp::Lt => query.filter(col_not_after.lt(cutoff)),
+                FilterOp::Lte => query.filter(col_not_after.le(cutoff)),
+                _ => unreachable!("days_remaining_cutoff rejects other operators"),
+            };
+        }
+        other => {
+            return Err(ServiceError::InvalidRequest(format!(
+                "unsupported filter field for certificates: '{other}'"
+            )));
+        }
+    }
+
+    Ok(query)
+}
+
+/// The `not_after` instant a `days_remaining` comparison translates to.
+fn days_remaining_cutoff(filter: &Filter, now: DateTime<Utc>) -> Result<DateTime<Utc>> {
+    if !matches!(
+        filter.op,
+        FilterOp::Gt | FilterOp::Gte | FilterOp::Lt | FilterOp::Lte
+    ) {
+        return Err(ServiceError::InvalidRequest(
+            "days_remaining filter only supports >, >=, < and <=".into(),
+        ));
+    }
+
+    let raw = filter.value.as_scalar()?;
+    let days = raw
+        .parse::<i64>()
+        .map_err(|_| ServiceError::InvalidRequest(format!("invalid integer '{raw}'")))?;
+
+    ChronoDuration::try_days(days)
+        .and_then(|offset| now.checked_add_signed(offset))
+        .ok_or_else(|| {
+            ServiceError::InvalidRequest(format!("days_remaining value '{raw}' is out of range"))
+        })
+}
+
+fn parse_uuid(raw: &str) -> Result<Uuid> {
+    Uuid::parse_str(raw).map_err(|_| ServiceError::InvalidRequest("id must be a valid UUID".into()))
+}
+
+fn collect_text_params(params: &mut Vec<BindParam>, filter: &Filter) -> Result<()> {
+    match filter.op {
+        FilterOp::Eq | FilterOp::NotEq | FilterOp::Like | FilterOp::NotLike => {
+            params.push(BindParam::Text(filter.value.as_scalar()?.to_string()));
+            Ok(())
+        }
+        FilterOp::In | FilterOp::NotIn => {
+            let values = filter.value.as_list()?.to_vec();
+            if values.is_empty() {
+                return Ok(());
+            }
+            params.push(BindParam::TextArray(values));
+            Ok(())
+        }
+        _ => Err(ServiceError::InvalidRequest(format!(
+            "unsupported operator for text filter: {:?}",
+            filter.op
+        ))),
+    }
+}
+
+fn collect_filter_params(
+    params: &mut Vec<BindParam>,
+    filter: &Filter,
+    now: DateTime<Utc>,
+) -> Result<()> {
+    match filter.field.as_str() {
+        "id" => {
+            params.push(BindParam::Uuid(parse_uuid(filter.value.as_scalar()?)?));
+            Ok(())
+        }
+        "source" | "target" | "name" | "subject" | "issuer" | "serial_number" => {
+            collect_text_params(params, filter)
+        }
+        "days_remaining" => {
+            params.push(BindParam::timestamptz(days_remaining_cutoff(filter, now)?));
+            Ok(())
+        }
+        other => Err(ServiceError::InvalidRequest(format!(
+            "unsupported filter field for certificates: '{other}'"
+        ))),
+    }
+}
+
+fn apply_ordering<'a>(
+    mut query: CertificatesQuery<'a>,
+    order: &[OrderClause],
+) -> CertificatesQuery<'a> {
+    let mut applied = false;
+    for clause in order {
+        query = if !applied {
+            applied = true;
+            match clause.field.as_str() {
+                "not_after" | "days_remaining" => match clause.direction {
+                    OrderDirection::Asc => query.order(col_not_after.asc()),
+                    OrderDirection::Desc => query.order(col_not_after.desc()),
+                },
+                "last_checked_at" | "timestamp" => match clause.direction {
+                    OrderDirection::Asc => query.order(col_last_checked_at.asc()),
+                    OrderDirection::Desc => query.order(col_last_checked_at.desc()),
+                },
+                "target" => match clause.direction {
+                    OrderDirection::Asc => query.order(col_target.asc()),
+                    OrderDirection::Desc => query.order(col_target.desc()),
+                },
+                "name" => match clause.direction {
+                    OrderDirection::Asc => query.order(col_name.asc()),
+                    OrderDirection::Desc => query.order(col_name.desc()),
+                },
+                _ => query,
+            }
+        } else {
+            match clause.field.as_str() {
+                "not_after" | "days_remaining" => match clause.direction {
+                    OrderDirection::Asc => query.then_order_by(col_not_after.asc()),
+                    OrderDirection::Desc => query.then_order_by(col_not_after.desc()),
+                },
+                "last_checked_at" | "timestamp" => match clause.direction {
+                    OrderDirection::Asc => query.then_order_by(col_last_checked_at.asc()),
+                    OrderDirection::Desc => query.then_order_by(col_last_checked_at.desc()),
+                },
+                "target" => match clause.direction {
+                    OrderDirection::Asc => query.then_order_by(col_target.asc()),
+                    OrderDirection::Desc => query.then_order_by(col_target.desc()),
+                },
+                "name" => match clause.direction {
+                    OrderDirection::Asc => query.then_order_by(col_name.asc()),
+                    OrderDirection::Desc => query.then_order_by(col_name.desc()),
+                },
+                _ => query,
+            }
+        };
+    }
+
+    // Default ordering: soonest to expire first
+    if !applied {
+        query = query.order(col_not_after.asc());
+    }
+
+    query
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::parser::{Entity, Filter, FilterOp, FilterValue};
+
+    fn plan(filters: Vec<Filter>) -> QueryPlan {
+        QueryPlan {
+            entity: Entity::Certificates,
+            filters,
+            order: Vec::new(),
+            limit: 50,
+            offset: 0,
+            time_range: None,
+            stats: None,
+            downsample: None,
+            rollup_stats: None,
+            include_deleted: false,
+        }
+    }
+
+    #[test]
+    fn days_remaining_filters_on_not_after() {
+        let plan = plan(vec![
+            Filter {
+                field: "source".into(),
+                op: FilterOp::Eq,
+                value: FilterValue::Scalar("endpoint".to_string()),
+            },
+            Filter {
+                field: "days_remaining".into(),
+                op: FilterOp::Lt,
+                value: FilterValue::Scalar("30".to_string()),
+            },
+        ]);
+
+        let (sql, params) = to_sql_and_params(&plan).expect("certificates query should translate");
+        assert!(sql.contains("\"monitored_certificates\""), "sql: {sql}");
+        assert!(sql.contains("\"not_after\" < $2"), "sql: {sql}");
+        assert!(
+            sql.contains("ORDER BY \"monitored_certificates\".\"not_after\" ASC"),
+            "sql: {sql}"
+        );
+
+        match &params[1] {
+            BindParam::Timestamptz(value) => {
+                let cutoff = DateTime::parse_from_rfc3339(value).expect("cutoff is a timestamp");
+                let days = (cutoff.with_timezone(&Utc) - Utc::now()).num_hours() / 24;
+                assert!(
+                    (29..=30).contains(&days),
+                    "cutoff {value} is not 30 days out"
+                );
+            }
+            other => panic!("expected a timestamptz bind, got {other:?}"),
+        }
+    }
+
+    #[test]
+    fn days_remaining_rejects_equality() {
+        let plan = plan(vec![Filter {
+            field: "days_remaining".into(),
+            op: FilterOp::Eq,
+            value: FilterValue::Scalar("30".to_string()),
+        }]);
+
+        let err = to_sql_and_params(&plan).expect_err("equality should be rejected");
+        assert!(err.to_string().contains("days_remaining"), "error: {err}");
+    }
+}
@@ -409,7 +409,7 @@ fn build_cagg_stats_query(plan: &QueryPlan, spec: &CpuStatsSpec) -> Result<CpuSt
     build_stats_query_with_source(
         plan,
         spec,
-        "cpu_metrics_hourly",
+        super::metric_rollup_table_for_plan(plan).unwrap_or("cpu_metrics_hourly"),
         "bucket",
         &format!(
             "CASE WHEN SUM(sample_count) = 0 THEN NULL ELSE SUM({avg_col} * sample_count)::float8 / SUM(sample_count)::float8 END"
@@ -436,7 +436,7 @@ fn build_stats_query_with_source(
 
     for filter in &plan.filters {
         if let Some((clause, mut values)) =
-            build_stats_filter_clause(filter, table == "cpu_metrics_hourly")?
+            build_stats_filter_clause(filter, table != "cpu_metrics")?
         {
             clauses.push(clause);
             binds.append(&mut values);
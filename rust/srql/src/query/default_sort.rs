@@ -0,0 +1,60 @@
+//! Default sort order for queries that omit `sort:`.
+//!
+//! Without an explicit order rows come back in whatever order the backend
+//! happens to scan them, so offset pagination can skip or repeat rows between
+//! pages. The translator fills in a per-entity default instead; operators can
+//! override it per entity with `SRQL_DEFAULT_SORT` (see [`AppConfig`]).
+
+use super::QueryPlan;
+use crate::{
+    config::AppConfig,
+    parser::{Entity, OrderClause, OrderDirection},
+};
+
+/// Sets the entity's default order on plans that have none. Stats, rollup and
+/// downsampled queries order their aggregate output themselves and are left
+/// alone.
+pub(super) fn apply(config: &AppConfig, plan: &mut QueryPlan) {
+    if !plan.order.is_empty()
+        || plan.stats.is_some()
+        || plan.rollup_stats.is_some()
+        || plan.downsample.is_some()
+    {
+        return;
+    }
+
+    plan.order = match config.default_sort.get(&plan.entity) {
+        Some(order) => order.clone(),
+        None => builtin(&plan.entity),
+    };
+}
+
+/// The built-in default order for `entity`. Entities without one keep the
+/// ordering their query module applies on its own.
+fn builtin(entity: &Entity) -> Vec<OrderClause> {
+    let fields: &[&str] = match entity {
+        Entity::Devices | Entity::Agents | Entity::Gateways => &["last_seen"],
+        Entity::Logs => &["timestamp", "severity_number"],
+        Entity::Events | Entity::BmpEvents | Entity::Flows => &["time"],
+        Entity::DeviceUpdates => &["observed_at"],
+        Entity::Alerts => &["triggered_at"],
+        Entity::Services
+        | Entity::OtelMetrics
+        | Entity::CpuMetrics
+        | Entity::MemoryMetrics
+        | Entity::DiskMetrics
+        | Entity::NetworkMetrics
+        | Entity::ProcessMetrics
+        | Entity::TimeseriesMetrics
+        | Entity::Traces => &["timestamp"],
+        _ => &[],
+    };
+
+    fields
+        .iter()
+        .map(|field| OrderClause {
+            field: (*field).to_string(),
+            direction: OrderDirection::Desc,
+        })
+        .collect()
+}
@@ -3,12 +3,13 @@ use crate::{
     error::{Result, ServiceError},
     jsonb::DbJson,
     models::DeviceRow,
-    parser::{Entity, Filter, FilterOp, FilterValue, OrderClause, OrderDirection},
+    parser::{Entity, Filter, FilterOp, OrderClause, OrderDirection, Subquery},
     schema::ocsf_devices::dsl::{
-        agent_id as col_agent_id, deleted_at as col_deleted_at, device_type as col_device_type,
-        first_seen_time as col_first_seen_time, gateway_id as col_gateway_id,
-        hostname as col_hostname, ip as col_ip, is_available as col_is_available,
-        last_seen_time as col_last_seen_time, model as col_model, ocsf_devices,
+        agent_id as col_agent_id, archived_at as col_archived_at, deleted_at as col_deleted_at,
+        device_type as col_device_type, first_seen_time as col_first_seen_time,
+        gateway_id as col_gateway_id, hostname as col_hostname, ip as col_ip,
+        is_available as col_is_available, last_seen_time as col_last_seen_time, model as col_model,
+        ocsf_devices, owner_type as col_owner_type, owner_uid as col_owner_uid,
         risk_level as col_risk_level, type_id as col_type_id, uid as col_uid,
         vendor_name as col_vendor_name,
     },
@@ -30,6 +31,14 @@ type DeviceQuery<'a> =
     BoxedSelectStatement<'a, <OcsfDevicesTable as AsQuery>::SqlType, DeviceFromClause, Pg>;
 type DeviceStatsQuery<'a> = BoxedSelectStatement<'a, BigInt, DeviceFromClause, Pg>;
 
+/// Lifecycle state lives in metadata; devices that predate lifecycle tracking are active.
+const DEVICE_LIFECYCLE_EXPR: &str = "coalesce(metadata->>'lifecycle_state', 'active')";
+
+/// Health rolled up from child devices lives in metadata; devices without
+/// children report their own availability.
+const DEVICE_HEALTH_ROLLUP_EXPR: &str = "coalesce(metadata->>'health_rollup', \
+     CASE WHEN coalesce(is_available, false) THEN 'healthy' ELSE 'unhealthy' END)";
+
 /// Groupable fields for device stats queries
 #[derive(Debug, Clone, Copy, PartialEq)]
 pub enum DeviceGroupField {
@@ -38,16 +47,18 @@ pub enum DeviceGroupField {
     RiskLevel,
     IsAvailable,
     GatewayId,
+    Owner,
 }
 
 impl DeviceGroupField {
     fn from_str(s: &str) -> Option<Self> {
         match s.to_lowercase().as_str() {
             "type" | "device_type" => Some(Self::Type),
-            "vendor_name" | "vendor" => Some(Self::VendorName),
+            "vendor_name" | "vendor" | "manufacturer" => Some(Self::VendorName),
             "risk_level" | "risk" => Some(Self::RiskLevel),
             "is_available" | "available" => Some(Self::IsAvailable),
             "gateway_id" | "gateway" => Some(Self::GatewayId),
+            "owner" | "owner_uid" => Some(Self::Owner),
             _ => None,
         }
     }
@@ -60,6 +71,7 @@ impl DeviceGroupField {
             Self::RiskLevel => "COALESCE(risk_level, 'Unknown')",
             Self::IsAvailable => "COALESCE(is_available, false)",
             Self::GatewayId => "gateway_id",
+            Self::Owner => "COALESCE(owner_uid, 'unassigned')",
         }
     }
 
@@ -70,6 +82,7 @@ impl DeviceGroupField {
             Self::RiskLevel => "risk_level",
             Self::IsAvailable => "is_available",
             Self::GatewayId => "gateway_id",
+            Self::Owner => "owner",
         }
     }
 }
@@ -142,8 +155,8 @@ pub(super) async fn execute(
     }
 
     if let Some(spec) = parse_stats_spec(plan.stats.as_ref().map(|s| s.as_raw()))? {
-        // Check if this is a grouped stats query
-        if spec.group_field.is_some() {
+        // Grouped stats, and aggregates that are not a BIGINT count, are built as raw SQL
+        if spec.needs_raw_sql() {
             let grouped_sql = build_grouped_stats_query(plan, &spec)?;
             let mut query = diesel::sql_query(&grouped_sql.sql).into_boxed();
             for bind in grouped_sql.binds {
@@ -189,8 +202,8 @@ pub(super) fn to_sql_and_params(plan: &QueryPlan) -> Result<(String, Vec<BindPar
     }
 
     if let Some(spec) = parse_stats_spec(plan.stats.as_ref().map(|s| s.as_raw()))? {
-        // Check if this is a grouped stats query
-        if spec.group_field.is_some() {
+        // Grouped stats, and aggregates that are not a BIGINT count, are built as raw SQL
+        if spec.needs_raw_sql() {
             let grouped_sql = build_grouped_stats_query(plan, &spec)?;
             let sql = rewrite_placeholders(&grouped_sql.sql);
             let params: Vec<BindParam> = grouped_sql
@@ -328,6 +341,10 @@ fn build_query(plan: &QueryPlan) -> Result<DeviceQuery<'static>> {
         query = query.filter(col_deleted_at.is_null());
     }
 
+    if !includes_archived(&plan.filters) {
+        query = query.filter(col_archived_at.is_null());
+    }
+
     if let Some(TimeRange { start, end }) = &plan.time_range {
         query = query.filter(
             col_last_seen_time
@@ -346,8 +363,126 @@ fn build_query(plan: &QueryPlan) -> Result<DeviceQuery<'static>> {
 
 #[derive(Debug, Clone)]
 struct DeviceStatsSpec {
+    aggregate: DeviceStatsAggregate,
     alias: String,
-    group_field: Option<DeviceGroupField>,
+    group_fields: Vec<DeviceGroupField>,
+}
+
+impl DeviceStatsSpec {
+    fn needs_raw_sql(&self) -> bool {
+        !self.group_fields.is_empty() || !self.aggregate.is_count()
+    }
+}
+
+/// Aggregate function supported by device stats queries.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum DeviceStatsAggregate {
+    Count,
+    CountDistinct(&'static str),
+    Sum(&'static str),
+    Avg(&'static str),
+    Min(&'static str),
+    Max(&'static str),
+}
+
+impl DeviceStatsAggregate {
+    fn sql(&self) -> String {
+        match self {
+            Self::Count => "COUNT(*)".to_string(),
+            Self::CountDistinct(column) => format!("COUNT(DISTINCT {column})"),
+            Self::Sum(column) => format!("SUM({column})"),
+            Self::Avg(column) => format!("AVG({column})"),
+            Self::Min(column) => format!("MIN({column})"),
+            Self::Max(column) => format!("MAX({column})"),
+        }
+    }
+
+    fn is_count(&self) -> bool {
+        matches!(self, Self::Count | Self::CountDistinct(_))
+    }
+}
+
+/// Maps a user-facing field to the ocsf_devices column used by COUNT(DISTINCT ...).
+fn distinct_column(raw: &str) -> Option<&'static str> {
+    match raw.trim().to_lowercase().as_str() {
+        "uid" | "device_id" => Some("uid"),
+        "hostname" => Some("hostname"),
+        "ip" => Some("ip"),
+        "mac" => Some("mac"),
+        "type" | "device_type" => Some("type"),
+        "vendor_name" | "vendor" => Some("vendor_name"),
+        "model" => Some("model"),
+        "risk_level" | "risk" => Some("risk_level"),
+        "gateway_id" | "gateway" => Some("gateway_id"),
+        "agent_id" | "agent" => Some("agent_id"),
+        "domain" => Some("domain"),
+        "zone" => Some("zone"),
+        "region" => Some("region"),
+        "subnet_uid" => Some("subnet_uid"),
+        "vlan_uid" => Some("vlan_uid"),
+        "owner" | "owner_uid" => Some("owner_uid"),
+        _ => None,
+    }
+}
+
+/// Maps a user-facing field to the numeric ocsf_devices column used by sum/avg/min/max.
+fn numeric_column(raw: &str) -> Option<&'static str> {
+    match raw.trim().to_lowercase().as_str() {
+        "risk_score" => Some("risk_score"),
+        "risk_level_id" => Some("risk_level_id"),
+        "type_id" => Some("type_id"),
+        _ => None,
+    }
+}
+
+fn parse_stats_aggregate(raw: &str) -> Result<DeviceStatsAggregate> {
+    let lower = raw.trim().to_lowercase();
+    if lower == "count()" {
+        return Ok(DeviceStatsAggregate::Count);
+    }
+
+    if let Some(field) = lower
+        .strip_prefix("count(distinct ")
+        .and_then(|rest| rest.strip_suffix(')'))
+    {
+        let column = distinct_column(field).ok_or_else(|| {
+            ServiceError::InvalidRequest(format!(
+                "unsupported count(distinct) field '{}'",
+                field.trim()
+            ))
+        })?;
+        return Ok(DeviceStatsAggregate::CountDistinct(column));
+    }
+
+    if let Some((func, field)) = lower
+        .split_once('(')
+        .and_then(|(func, rest)| Some((func.trim(), rest.strip_suffix(')')?)))
+    {
+        if matches!(func, "sum" | "avg" | "min" | "max") {
+            let column = numeric_column(field).ok_or_else(|| {
+                ServiceError::InvalidRequest(format!(
+                    "unsupported {func}() field '{}'. Supported fields: risk_score, risk_level_id, type_id",
+                    field.trim()
+                ))
+            })?;
+            return Ok(match func {
+                "sum" => DeviceStatsAggregate::Sum(column),
+                "avg" => DeviceStatsAggregate::Avg(column),
+                "min" => DeviceStatsAggregate::Min(column),
+                _ => DeviceStatsAggregate::Max(column),
+            });
+        }
+    }
+
+    Err(ServiceError::InvalidRequest(
+        "devices stats only support count(), count(distinct field), sum(field), avg(field), min(field) and max(field)".into(),
+    ))
+}
+
+fn ungrouped_field_error(field: &str) -> ServiceError {
+    ServiceError::InvalidRequest(format!(
+        "field '{field}' must be aggregated or listed after 'by'"
+    ))
 }
 
 fn parse_stats_spec(raw: Option<&str>) -> Result<Option<DeviceStatsSpec>> {
@@ -356,20 +491,42 @@ fn parse_stats_spec(raw: Option<&str>) -> Result<Option<DeviceStatsSpec>> {
         _ => return Ok(None),
     };
 
-    let tokens: Vec<&str> = raw.split_whitespace().collect();
-    if tokens.len() < 3 {
+    // The aggregate may contain whitespace (`count(distinct field)`), so split it
+    // off at the closing parenthesis before tokenizing the remainder.
+    let (aggregate_raw, rest) = match raw.find(')') {
+        Some(idx) => raw.split_at(idx + 1),
+        None => {
+            return Err(ServiceError::InvalidRequest(
+                "stats expressions must be of the form 'count() as alias'".into(),
+            ))
+        }
+    };
+
+    let tokens: Vec<&str> = rest.split_whitespace().collect();
+    if tokens.len() < 2 {
         return Err(ServiceError::InvalidRequest(
             "stats expressions must be of the form 'count() as alias'".into(),
         ));
     }
 
-    if !tokens[0].eq_ignore_ascii_case("count()") || !tokens[1].eq_ignore_ascii_case("as") {
+    // Fields may be selected ahead of the aggregate (`type, count() as total by type`),
+    // as long as they are also grouped.
+    let (selected_fields, aggregate_raw) = match aggregate_raw.rsplit_once(',') {
+        Some((fields, aggregate_raw)) => (
+            fields.split(',').map(str::trim).collect::<Vec<_>>(),
+            aggregate_raw,
+        ),
+        None => (Vec::new(), aggregate_raw),
+    };
+
+    let aggregate = parse_stats_aggregate(aggregate_raw)?;
+    if !tokens[0].eq_ignore_ascii_case("as") {
         return Err(ServiceError::InvalidRequest(
-            "devices stats only support count()".into(),
+            "stats expressions must be of the form 'count() as alias'".into(),
         ));
     }
 
-    let alias = tokens[2]
+    let alias = tokens[1]
         .trim_matches('"')
         .trim_matches('\'')
         .to_lowercase();
@@ -384,42 +541,55 @@ fn parse_stats_spec(raw: Option<&str>) -> Result<Option<DeviceStatsSpec>> {
         ));
     }
 
-    // Parse optional "by <field>" clause
-    let mut group_field = None;
-    if tokens.len() >= 5 {
-        if !tokens[3].eq_ignore_ascii_case("by") {
-            return Err(ServiceError::InvalidRequest(
-                "expected 'by <field>' after stats alias".into(),
-            ));
+    // Parse optional "by <field>[, <field>...]" clause
+    let mut group_fields = Vec::new();
+    if let Some(token) = tokens.get(2) {
+        if !token.eq_ignore_ascii_case("by") {
+            return Err(ungrouped_field_error(token.trim_end_matches(',')));
+        }
+        let fields = tokens[3..].join(" ");
+        for raw_field in fields.split(',').map(str::trim) {
+            if raw_field.is_empty() {
+                return Err(ServiceError::InvalidRequest(
+                    "expected 'by <field>' after stats alias".into(),
+                ));
+            }
+            let field = parse_group_field(raw_field)?;
+            if !group_fields.contains(&field) {
+                group_fields.push(field);
+            }
+        }
+    }
+
+    for field in selected_fields {
+        match DeviceGroupField::from_str(field) {
+            Some(field) if group_fields.contains(&field) => {}
+            _ => return Err(ungrouped_field_error(field)),
         }
-        group_field = Some(parse_group_field(tokens[4])?);
-    } else if tokens.len() > 3 {
-        return Err(ServiceError::InvalidRequest(
-            "expected 'by <field>' after stats alias".into(),
-        ));
     }
 
-    Ok(Some(DeviceStatsSpec { alias, group_field }))
+    Ok(Some(DeviceStatsSpec {
+        aggregate,
+        alias,
+        group_fields,
+    }))
 }
 
 fn parse_group_field(raw: &str) -> Result<DeviceGroupField> {
     DeviceGroupField::from_str(raw).ok_or_else(|| {
         ServiceError::InvalidRequest(format!(
-            "unsupported stats group field '{}'. Supported fields: type, vendor_name, risk_level, is_available, gateway_id",
+            "unsupported stats group field '{}'. Supported fields: type, vendor_name, risk_level, is_available, gateway_id, owner",
             raw
         ))
     })
 }
 
-/// Builds a grouped stats query using raw SQL (Diesel doesn't support GROUP BY well)
+/// Builds a grouped stats query using raw SQL (Diesel doesn't support GROUP BY well).
+/// Without group fields it returns the aggregate as a single row.
 fn build_grouped_stats_query(
     plan: &QueryPlan,
     spec: &DeviceStatsSpec,
 ) -> Result<DeviceGroupedStatsSql> {
-    let group_field = spec
-        .group_field
-        .ok_or_else(|| ServiceError::Internal(anyhow::anyhow!("group_field is required")))?;
-
     let mut binds = Vec::new();
     let mut clauses = Vec::new();
 
@@ -427,6 +597,10 @@ fn build_grouped_stats_query(
         clauses.push("deleted_at IS NULL".to_string());
     }
 
+    if !includes_archived(&plan.filters) {
+        clauses.push("archived_at IS NULL".to_string());
+    }
+
     // Time range filter
     if let Some(TimeRange { start, end }) = &plan.time_range {
         clauses.push("last_seen_time >= ?".to_string());
@@ -443,13 +617,17 @@ fn build_grouped_stats_query(
         }
     }
 
-    let column = group_field.column();
-    let response_key = group_field.response_key();
-
     // Build SELECT with jsonb_build_object
+    let aggregate = spec.aggregate.sql();
+    let mut fields: Vec<String> = spec
+        .group_fields
+        .iter()
+        .map(|field| format!("'{}', {}", field.response_key(), field.column()))
+        .collect();
+    fields.push(format!("'{}', {}", spec.alias, aggregate));
     let mut sql = format!(
-        "SELECT jsonb_build_object('{}', {}, '{}', COUNT(*)) AS payload",
-        response_key, column, spec.alias
+        "SELECT jsonb_build_object({}) AS payload",
+        fields.join(", ")
     );
     sql.push_str("\nFROM ocsf_devices");
 
@@ -458,10 +636,20 @@ fn build_grouped_stats_query(
         sql.push_str(&clauses.join(" AND "));
     }
 
-    sql.push_str(&format!("\nGROUP BY {column}"));
+    if spec.group_fields.is_empty() {
+        return Ok(DeviceGroupedStatsSql { sql, binds });
+    }
+
+    let columns: Vec<&str> = spec
+        .group_fields
+        .iter()
+        .map(|field| field.column())
+        .collect();
+    sql.push_str(&format!("\nGROUP BY {}", columns.join(", ")));
 
-    // Order by count descending by default
-    let order_sql = build_grouped_stats_order_clause(plan, &spec.alias, column);
+    // Order by the aggregate descending by default
+    let order_sql =
+        build_grouped_stats_order_clause(plan, &spec.alias, &aggregate, &spec.group_fields);
     sql.push_str(&order_sql);
 
     // Apply limit (default 20 for distributions)
@@ -479,20 +667,24 @@ fn build_grouped_stats_query(
     Ok(DeviceGroupedStatsSql { sql, binds })
 }
 
-fn build_grouped_stats_order_clause(plan: &QueryPlan, alias: &str, group_column: &str) -> String {
+fn build_grouped_stats_order_clause(
+    plan: &QueryPlan,
+    alias: &str,
+    aggregate: &str,
+    group_fields: &[DeviceGroupField],
+) -> String {
     if plan.order.is_empty() {
-        return "\nORDER BY COUNT(*) DESC".to_string();
+        return format!("\nORDER BY {aggregate} DESC");
     }
 
     let mut parts = Vec::new();
     for clause in &plan.order {
         let expr = if clause.field.eq_ignore_ascii_case(alias) || clause.field == "count" {
-            "COUNT(*)".to_string()
-        } else if clause
-            .field
-            .eq_ignore_ascii_case(group_column.split('(').next().unwrap_or(""))
+            aggregate.to_string()
+        } else if let Some(field) =
+            DeviceGroupField::from_str(&clause.field).filter(|field| group_fields.contains(field))
         {
-            group_column.to_string()
+            field.column().to_string()
         } else {
             continue;
         };
@@ -505,7 +697,7 @@ fn build_grouped_stats_order_clause(plan: &QueryPlan, alias: &str, group_column:
     }
 
     if parts.is_empty() {
-        "\nORDER BY COUNT(*) DESC".to_string()
+        format!("\nORDER BY {aggregate} DESC")
     } else {
         format!("\nORDER BY {}", parts.join(", "))
     }
@@ -516,7 +708,10 @@ fn build_grouped_stats_filter_clause(
 ) -> Result<Option<(String, Vec<DeviceSqlBindValue>)>> {
     let mut binds = Vec::new();
     let clause = match filter.field.as_str() {
-        "uid" => build_grouped_text_clause("uid", filter, &mut binds)?,
+        "uid" => match filter.value.as_subquery() {
+            Some(subquery) => build_grouped_subquery_clause(filter, subquery, &mut binds)?,
+            None => build_grouped_text_clause("uid", filter, &mut binds)?,
+        },
         "hostname" => build_grouped_text_clause("hostname", filter, &mut binds)?,
         "ip" => build_grouped_text_clause("ip", filter, &mut binds)?,
         "mac" => build_grouped_mac_clause(filter, &mut binds)?,
@@ -542,6 +737,12 @@ fn build_grouped_stats_filter_clause(
         "vendor_name" => build_grouped_text_clause("vendor_name", filter, &mut binds)?,
         "model" => build_grouped_text_clause("model", filter, &mut binds)?,
         "risk_level" => build_grouped_text_clause("risk_level", filter, &mut binds)?,
+        "owner" | "owner_uid" => build_grouped_text_clause("owner_uid", filter, &mut binds)?,
+        "owner_type" => build_grouped_text_clause("owner_type", filter, &mut binds)?,
+        "lifecycle_state" => build_grouped_text_clause(DEVICE_LIFECYCLE_EXPR, filter, &mut binds)?,
+        "health_rollup" => {
+            build_grouped_text_clause(DEVICE_HEALTH_ROLLUP_EXPR, filter, &mut binds)?
+        }
         "is_available" => {
             let value = parse_bool(filter.value.as_scalar()?)?;
             binds.push(DeviceSqlBindValue::Bool(value));
@@ -579,11 +780,29 @@ fn build_grouped_stats_filter_clause(
                 }
             }
         }
+        "archived" => {
+            let value = parse_bool(filter.value.as_scalar()?)?;
+            match filter.op {
+                FilterOp::Eq | FilterOp::NotEq => {
+                    if value == matches!(filter.op, FilterOp::Eq) {
+                        "archived_at IS NOT NULL".to_string()
+                    } else {
+                        "archived_at IS NULL".to_string()
+                    }
+                }
+                _ => {
+                    return Err(ServiceError::InvalidRequest(
+                        "archived filter only supports equality".into(),
+                    ))
+                }
+            }
+        }
+        "include_archived" => {
+            validate_include_archived(filter)?;
+            return Ok(None);
+        }
         "discovery_sources" => {
-            let values = match &filter.value {
-                FilterValue::Scalar(v) => vec![v.to_string()],
-                FilterValue::List(list) => list.clone(),
-            };
+            let values = filter.value.to_values()?;
             if values.is_empty() {
                 return Ok(None);
             }
@@ -665,6 +884,23 @@ fn build_grouped_text_clause(
     }
 }
 
+fn build_grouped_subquery_clause(
+    filter: &Filter,
+    subquery: &Subquery,
+    binds: &mut Vec<DeviceSqlBindValue>,
+) -> Result<String> {
+    let built = super::subquery::build(subquery)?;
+    binds.push(DeviceSqlBindValue::TextArray(built.values));
+    let clause = format!("uid IN ({}?{})", built.prefix, built.suffix);
+    match filter.op {
+        FilterOp::In => Ok(clause),
+        FilterOp::NotIn => Ok(format!("NOT ({clause})")),
+        _ => Err(ServiceError::InvalidRequest(
+            "subqueries only support IN and NOT IN".into(),
+        )),
+    }
+}
+
 /// Rewrites ? placeholders to $1, $2, etc. for PostgreSQL
 fn rewrite_placeholders(sql: &str) -> String {
     let mut result = String::with_capacity(sql.len());
@@ -691,6 +927,10 @@ fn build_stats_query(
         query = query.filter(col_deleted_at.is_null());
     }
 
+    if !includes_archived(&plan.filters) {
+        query = query.filter(col_archived_at.is_null());
+    }
+
     if let Some(TimeRange { start, end }) = &plan.time_range {
         query = query.filter(
             col_last_seen_time
@@ -703,14 +943,17 @@ fn build_stats_query(
         query = apply_filter(query, filter)?;
     }
 
-    let select_sql = format!("coalesce(COUNT(*), 0) as {}", spec.alias);
+    let select_sql = format!("coalesce({}, 0) as {}", spec.aggregate.sql(), spec.alias);
     Ok(query.select(sql::<BigInt>(&select_sql)))
 }
 
 fn apply_filter<'a>(mut query: DeviceQuery<'a>, filter: &Filter) -> Result<DeviceQuery<'a>> {
     match filter.field.as_str() {
         "uid" => {
-            query = apply_text_filter!(query, filter, col_uid)?;
+            query = match filter.value.as_subquery() {
+                Some(subquery) => apply_subquery_filter(query, filter, subquery)?,
+                None => apply_text_filter!(query, filter, col_uid)?,
+            };
         }
         "hostname" => {
             query = apply_text_filter_no_lists!(
@@ -807,6 +1050,25 @@ fn apply_filter<'a>(mut query: DeviceQuery<'a>, filter: &Filter) -> Result<Devic
                 "risk_level filter only supports equality"
             )?;
         }
+        // Assigned owner (user or team identifier)
+        "owner" | "owner_uid" => {
+            query = apply_text_filter!(query, filter, col_owner_uid)?;
+        }
+        "owner_type" => {
+            query = apply_eq_filter!(
+                query,
+                filter,
+                col_owner_type,
+                filter.value.as_scalar()?.to_string(),
+                "owner_type filter only supports equality"
+            )?;
+        }
+        "lifecycle_state" => {
+            query = apply_text_expr_filter(query, filter, DEVICE_LIFECYCLE_EXPR)?;
+        }
+        "health_rollup" => {
+            query = apply_text_expr_filter(query, filter, DEVICE_HEALTH_ROLLUP_EXPR)?;
+        }
         "deleted" => {
             let value = parse_bool(filter.value.as_scalar()?)?;
             let matches_deleted = col_deleted_at.is_not_null();
@@ -833,14 +1095,32 @@ fn apply_filter<'a>(mut query: DeviceQuery<'a>, filter: &Filter) -> Result<Devic
                 }
             };
         }
+        "archived" => {
+            let value = parse_bool(filter.value.as_scalar()?)?;
+            query = match filter.op {
+                FilterOp::Eq | FilterOp::NotEq => {
+                    if value == matches!(filter.op, FilterOp::Eq) {
+                        query.filter(col_archived_at.is_not_null())
+                    } else {
+                        query.filter(col_archived_at.is_null())
+                    }
+                }
+                _ => {
+                    return Err(ServiceError::InvalidRequest(
+                        "archived filter only supports equality".into(),
+                    ));
+                }
+            };
+        }
+        // Archived devices are excluded unless this is set (see build_query)
+        "include_archived" => {
+            validate_include_archived(filter)?;
+        }
         "tags" => {
             query = apply_tags_filter(query, filter)?;
         }
         "discovery_sources" => {
-            let values = match &filter.value {
-                FilterValue::Scalar(v) => vec![v.to_string()],
-                FilterValue::List(list) => list.clone(),
-            };
+            let values = filter.value.to_values()?;
             if values.is_empty() {
                 return Ok(query);
             }
@@ -902,12 +1182,98 @@ fn apply_filter<'a>(mut query: DeviceQuery<'a>, filter: &Filter) -> Result<Devic
     Ok(query)
 }
 
+/// `uid:(in:events ... select:device_id)` becomes `uid IN (SELECT ...)`.
+fn apply_subquery_filter<'a>(
+    query: DeviceQuery<'a>,
+    filter: &Filter,
+    subquery: &Subquery,
+) -> Result<DeviceQuery<'a>> {
+    let negate = match filter.op {
+        FilterOp::In => "",
+        FilterOp::NotIn => "NOT ",
+        _ => {
+            return Err(ServiceError::InvalidRequest(
+                "subqueries only support IN and NOT IN".into(),
+            ));
+        }
+    };
+    let built = super::subquery::build(subquery)?;
+    Ok(query.filter(
+        sql::<Bool>(&format!("{negate}(uid IN ({}", built.prefix))
+            .bind::<Array<Text>, _>(built.values)
+            .sql(&format!("{}))", built.suffix)),
+    ))
+}
+
+/// Filters on a derived text expression with equality and list operators.
+fn apply_text_expr_filter<'a>(
+    query: DeviceQuery<'a>,
+    filter: &Filter,
+    expr: &str,
+) -> Result<DeviceQuery<'a>> {
+    match filter.op {
+        FilterOp::Eq => Ok(query.filter(
+            sql::<Bool>(&format!("{expr} = "))
+                .bind::<Text, _>(filter.value.as_scalar()?.to_string()),
+        )),
+        FilterOp::NotEq => Ok(query.filter(
+            sql::<Bool>(&format!("{expr} <> "))
+                .bind::<Text, _>(filter.value.as_scalar()?.to_string()),
+        )),
+        FilterOp::In | FilterOp::NotIn => {
+            let values = filter.value.as_list()?.to_vec();
+            if values.is_empty() {
+                return Ok(query);
+            }
+            let negate = if matches!(filter.op, FilterOp::NotIn) {
+                "NOT "
+            } else {
+                ""
+            };
+            Ok(query.filter(
+                sql::<Bool>(&format!("{negate}({expr} = ANY("))
+                    .bind::<Array<Text>, _>(values)
+                    .sql("))"),
+            ))
+        }
+        _ => Err(ServiceError::InvalidRequest(format!(
+            "{} filter only supports equality and list filters",
+            filter.field
+        ))),
+    }
+}
+
 fn has_deleted_filter(filters: &[Filter]) -> bool {
     filters
         .iter()
         .any(|filter| filter.field.eq_ignore_ascii_case("deleted"))
 }
 
+/// Archived devices are hidden unless the query sets `include_archived:true`
+/// or filters on `archived` explicitly.
+fn includes_archived(filters: &[Filter]) -> bool {
+    filters.iter().any(|filter| {
+        filter.field.eq_ignore_ascii_case("archived")
+            || (filter.field.eq_ignore_ascii_case("include_archived")
+                && filter
+                    .value
+                    .as_scalar()
+                    .ok()
+                    .and_then(|raw| parse_bool(raw).ok())
+                    .unwrap_or(false))
+    })
+}
+
+fn validate_include_archived(filter: &Filter) -> Result<()> {
+    if !matches!(filter.op, FilterOp::Eq) {
+        return Err(ServiceError::InvalidRequest(
+            "include_archived only supports equality".into(),
+        ));
+    }
+
+    parse_bool(filter.value.as_scalar()?).map(|_| ())
+}
+
 fn apply_ip_filter<'a>(query: DeviceQuery<'a>, filter: &Filter) -> Result<DeviceQuery<'a>> {
     match filter.op {
         FilterOp::Eq | FilterOp::NotEq => {
@@ -1067,15 +1433,42 @@ fn collect_text_params(
 
 fn collect_filter_params(params: &mut Vec<BindParam>, filter: &Filter) -> Result<()> {
     match filter.field.as_str() {
-        "uid" => collect_text_params(params, filter, true),
+        "uid" => match filter.value.as_subquery() {
+            Some(subquery) => {
+                params.push(BindParam::TextArray(
+                    super::subquery::build(subquery)?.values,
+                ));
+                Ok(())
+            }
+            None => collect_text_params(params, filter, true),
+        },
+        "owner" | "owner_uid" => collect_text_params(params, filter, true),
         "hostname" => collect_text_params(params, filter, false),
         "mac" => collect_mac_params(params, filter),
         "ip" => collect_ip_params(params, filter),
         "gateway_id" | "agent_id" | "type" | "device_type" | "vendor_name" | "model"
-        | "risk_level" => {
+        | "risk_level" | "owner_type" => {
             params.push(BindParam::Text(filter.value.as_scalar()?.to_string()));
             Ok(())
         }
+        "lifecycle_state" | "health_rollup" => match filter.op {
+            FilterOp::Eq | FilterOp::NotEq => {
+                params.push(BindParam::Text(filter.value.as_scalar()?.to_string()));
+                Ok(())
+            }
+            FilterOp::In | FilterOp::NotIn => {
+                let values = filter.value.as_list()?.to_vec();
+                if values.is_empty() {
+                    return Ok(());
+                }
+                params.push(BindParam::TextArray(values));
+                Ok(())
+            }
+            _ => Err(ServiceError::InvalidRequest(format!(
+                "{} filter only supports equality and list filters",
+                filter.field
+            ))),
+        },
         "tags" => match filter.op {
             FilterOp::Eq | FilterOp::NotEq => {
                 params.push(BindParam::Text(filter.value.as_scalar()?.to_string()));
@@ -1105,15 +1498,12 @@ fn collect_filter_params(params: &mut Vec<BindParam>, filter: &Filter) -> Result
             params.push(BindParam::Bool(parse_bool(filter.value.as_scalar()?)?));
             Ok(())
         }
-        "deleted" => {
+        "deleted" | "archived" | "include_archived" => {
             let _ = parse_bool(filter.value.as_scalar()?)?;
             Ok(())
         }
         "discovery_sources" => {
-            let values = match &filter.value {
-                FilterValue::Scalar(v) => vec![v.to_string()],
-                FilterValue::List(list) => list.clone(),
-            };
+            let values = filter.value.to_values()?;
             if values.is_empty() {
                 return Ok(());
             }
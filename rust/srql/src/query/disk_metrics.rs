@@ -8,8 +8,10 @@ use crate::{
         agent_id as col_agent_id, available_bytes as col_available_bytes,
         device_id as col_device_id, device_name as col_device_name, disk_metrics,
         gateway_id as col_gateway_id, host_id as col_host_id, mount_point as col_mount_point,
-        partition as col_partition, timestamp as col_timestamp, total_bytes as col_total_bytes,
+        partition as col_partition, read_bytes_per_sec as col_read_bytes_per_sec,
+        timestamp as col_timestamp, total_bytes as col_total_bytes,
         usage_percent as col_usage_percent, used_bytes as col_used_bytes,
+        write_bytes_per_sec as col_write_bytes_per_sec,
     },
     time::TimeRange,
 };
@@ -311,6 +313,14 @@ fn apply_primary_order<'a>(query: DiskQuery<'a>, clause: &OrderClause) -> DiskQu
             OrderDirection::Asc => query.order(col_mount_point.asc()),
             OrderDirection::Desc => query.order(col_mount_point.desc()),
         },
+        "read_bytes_per_sec" => match clause.direction {
+            OrderDirection::Asc => query.order(col_read_bytes_per_sec.asc()),
+            OrderDirection::Desc => query.order(col_read_bytes_per_sec.desc()),
+        },
+        "write_bytes_per_sec" => match clause.direction {
+            OrderDirection::Asc => query.order(col_write_bytes_per_sec.asc()),
+            OrderDirection::Desc => query.order(col_write_bytes_per_sec.desc()),
+        },
         _ => query,
     }
 }
@@ -343,6 +353,22 @@ fn apply_secondary_order<'a>(query: DiskQuery<'a>, clause: &OrderClause) -> Disk
             OrderDirection::Asc => diesel::QueryDsl::then_order_by(query, col_mount_point.asc()),
             OrderDirection::Desc => diesel::QueryDsl::then_order_by(query, col_mount_point.desc()),
         },
+        "read_bytes_per_sec" => match clause.direction {
+            OrderDirection::Asc => {
+                diesel::QueryDsl::then_order_by(query, col_read_bytes_per_sec.asc())
+            }
+            OrderDirection::Desc => {
+                diesel::QueryDsl::then_order_by(query, col_read_bytes_per_sec.desc())
+            }
+        },
+        "write_bytes_per_sec" => match clause.direction {
+            OrderDirection::Asc => {
+                diesel::QueryDsl::then_order_by(query, col_write_bytes_per_sec.asc())
+            }
+            OrderDirection::Desc => {
+                diesel::QueryDsl::then_order_by(query, col_write_bytes_per_sec.desc())
+            }
+        },
         _ => query,
     }
 }
@@ -405,7 +431,8 @@ fn build_cagg_stats_query(plan: &QueryPlan, spec: &DiskStatsSpec) -> Result<Disk
     let agg_expr = format!(
         "CASE WHEN SUM(sample_count) = 0 THEN NULL ELSE SUM({mapped_col} * sample_count)::float8 / SUM(sample_count)::float8 END"
     );
-    build_stats_query_with_source(plan, spec, "disk_metrics_hourly", "bucket", &agg_expr, true)
+    let table = super::metric_rollup_table_for_plan(plan).unwrap_or("disk_metrics_hourly");
+    build_stats_query_with_source(plan, spec, table, "bucket", &agg_expr, true)
 }
 
 fn build_stats_query_with_source(
@@ -1,12 +1,13 @@
-use super::{BindParam, QueryPlan};
+use super::{BindParam, DownsampleMeta, QueryPlan};
 use crate::query::flows::{
     FLOW_APP_EXPR, FLOW_DIRECTION_EXPR, FLOW_EXPORTER_NAME_EXPR, FLOW_IN_IF_NAME_EXPR,
     FLOW_IN_IF_SPEED_BPS_EXPR, FLOW_OUT_IF_NAME_EXPR, FLOW_OUT_IF_SPEED_BPS_EXPR,
     FLOW_PROTOCOL_GROUP_EXPR,
 };
 use crate::{
+    config::AppConfig,
     error::{Result, ServiceError},
-    parser::{DownsampleAgg, Entity, Filter, FilterOp},
+    parser::{DownsampleAgg, DownsampleSpec, Entity, Filter, FilterOp},
     time::TimeRange,
 };
 use chrono::{DateTime, Utc};
@@ -48,6 +49,106 @@ pub(super) async fn execute(conn: &mut AsyncPgConnection, plan: &QueryPlan) -> R
         .collect())
 }
 
+/// Bucket widths automatic downsampling snaps to so chart axes land on round
+/// intervals. Wider spans fall back to whole days.
+const AUTO_BUCKET_LADDER_SECS: &[i64] = &[
+    60, 300, 600, 900, 1800, 3600, 7200, 10800, 21600, 43200, 86400, 604800,
+];
+const SECS_PER_DAY: i64 = 24 * 60 * 60;
+
+/// Resolves `bucket:auto` against the time range and, for metric queries whose
+/// range exceeds the configured threshold, adds an averaging downsample sized
+/// so each series returns at most `downsample_target_points` buckets.
+pub(super) fn apply_auto_downsample(config: &AppConfig, plan: &mut QueryPlan) -> Result<()> {
+    if let Some(spec) = plan.downsample.as_mut() {
+        if spec.auto {
+            let range = plan.time_range.as_ref().ok_or_else(|| {
+                ServiceError::InvalidRequest("bucket:auto requires time:<range>".into())
+            })?;
+            spec.bucket_seconds = auto_bucket_seconds(range, config.downsample_target_points);
+        }
+        return Ok(());
+    }
+
+    if plan.stats.is_some()
+        || plan.rollup_stats.is_some()
+        || !supports_auto_downsample(&plan.entity)
+    {
+        return Ok(());
+    }
+
+    let Some(range) = plan.time_range.as_ref() else {
+        return Ok(());
+    };
+
+    let threshold = config.downsample_threshold.as_secs() as i64;
+    if threshold == 0 || (range.end - range.start).num_seconds() <= threshold {
+        return Ok(());
+    }
+
+    plan.downsample = Some(DownsampleSpec {
+        bucket_seconds: auto_bucket_seconds(range, config.downsample_target_points),
+        auto: true,
+        agg: DownsampleAgg::Avg,
+        series: None,
+        value_field: None,
+    });
+
+    // Leave the query raw when its filters have no downsample equivalent.
+    if build_sql(plan).is_err() || build_bind_values(plan).is_err() {
+        plan.downsample = None;
+    }
+
+    Ok(())
+}
+
+fn supports_auto_downsample(entity: &Entity) -> bool {
+    matches!(
+        entity,
+        Entity::TimeseriesMetrics
+            | Entity::SnmpMetrics
+            | Entity::RperfMetrics
+            | Entity::CpuMetrics
+            | Entity::MemoryMetrics
+            | Entity::DiskMetrics
+            | Entity::NetworkMetrics
+            | Entity::ProcessMetrics
+    )
+}
+
+/// Picks the smallest ladder bucket that keeps the range within
+/// `target_points` buckets. Floor-based bucketing can touch one partial bucket
+/// at each edge, so the span is divided into `target_points - 1` slots.
+pub(super) fn auto_bucket_seconds(range: &TimeRange, target_points: i64) -> i64 {
+    let span = (range.end - range.start).num_seconds().max(1);
+    let slots = (target_points - 1).max(1);
+    let min_bucket = (span + slots - 1) / slots;
+
+    AUTO_BUCKET_LADDER_SECS
+        .iter()
+        .copied()
+        .find(|bucket| *bucket >= min_bucket)
+        .unwrap_or_else(|| (min_bucket + SECS_PER_DAY - 1) / SECS_PER_DAY * SECS_PER_DAY)
+}
+
+pub(super) fn meta_for_plan(plan: &QueryPlan) -> Option<DownsampleMeta> {
+    plan.downsample.as_ref().map(|spec| DownsampleMeta {
+        bucket_seconds: spec.bucket_seconds,
+        bucket: format_bucket(spec.bucket_seconds),
+        agg: spec.agg,
+        auto: spec.auto,
+    })
+}
+
+fn format_bucket(seconds: i64) -> String {
+    match seconds {
+        s if s > 0 && s % SECS_PER_DAY == 0 => format!("{}d", s / SECS_PER_DAY),
+        s if s > 0 && s % 3600 == 0 => format!("{}h", s / 3600),
+        s if s > 0 && s % 60 == 0 => format!("{}m", s / 60),
+        s => format!("{s}s"),
+    }
+}
+
 fn build_sql(plan: &QueryPlan) -> Result<String> {
     let downsample = plan.downsample.as_ref().ok_or_else(|| {
         ServiceError::InvalidRequest("downsample requires bucket:<duration>".into())
@@ -76,6 +177,7 @@ fn build_sql(plan: &QueryPlan) -> Result<String> {
         Entity::CpuMetrics => ("cpu_metrics", "timestamp", None),
         Entity::MemoryMetrics => ("memory_metrics", "timestamp", None),
         Entity::DiskMetrics => ("disk_metrics", "timestamp", None),
+        Entity::NetworkMetrics => ("network_metrics", "timestamp", None),
         Entity::ProcessMetrics => ("process_metrics", "timestamp", None),
         Entity::Flows => ("ocsf_network_activity", "time", None),
         _ => {
@@ -89,7 +191,7 @@ fn build_sql(plan: &QueryPlan) -> Result<String> {
         if matches!(plan.entity, Entity::Flows) {
             flow_cagg_for_bucket(downsample.bucket_seconds)
         } else {
-            super::cagg_table_for_entity(&plan.entity).unwrap_or(raw_table)
+            metric_rollup_for_bucket(&plan.entity, downsample.bucket_seconds).unwrap_or(raw_table)
         }
     } else {
         raw_table
@@ -321,10 +423,23 @@ fn resolve_value_column(
             Some("used_bytes") => Ok("used_bytes"),
             Some("available_bytes") => Ok("available_bytes"),
             Some("total_bytes") => Ok("total_bytes"),
+            Some("read_bytes_per_sec") => Ok("read_bytes_per_sec"),
+            Some("write_bytes_per_sec") => Ok("write_bytes_per_sec"),
+            Some("read_ops_per_sec") => Ok("read_ops_per_sec"),
+            Some("write_ops_per_sec") => Ok("write_ops_per_sec"),
             Some(other) => Err(ServiceError::InvalidRequest(format!(
                 "unsupported value_field '{other}' for disk_metrics"
             ))),
         },
+        Entity::NetworkMetrics => match field {
+            None | Some("bytes_recv_per_sec") => Ok("bytes_recv_per_sec"),
+            Some("bytes_sent_per_sec") => Ok("bytes_sent_per_sec"),
+            Some("packets_recv_per_sec") => Ok("packets_recv_per_sec"),
+            Some("packets_sent_per_sec") => Ok("packets_sent_per_sec"),
+            Some(other) => Err(ServiceError::InvalidRequest(format!(
+                "unsupported value_field '{other}' for network_metrics"
+            ))),
+        },
         Entity::ProcessMetrics => match field {
             None | Some("cpu_usage") => Ok("cpu_usage"),
             Some("memory_usage") => Ok("memory_usage"),
@@ -419,6 +534,19 @@ fn series_expr(plan: &QueryPlan, table: &str) -> Result<String> {
                 )))
             }
         },
+        Entity::NetworkMetrics => match series.as_str() {
+            "device_id" => "device_id".to_string(),
+            "host_id" => "host_id".to_string(),
+            "gateway_id" => "gateway_id".to_string(),
+            "agent_id" => "agent_id".to_string(),
+            "partition" => "partition".to_string(),
+            "interface_name" => "interface_name".to_string(),
+            other => {
+                return Err(ServiceError::InvalidRequest(format!(
+                    "unsupported series field '{other}' for {table}"
+                )))
+            }
+        },
         Entity::ProcessMetrics => match series.as_str() {
             "device_id" => "device_id".to_string(),
             "host_id" => "host_id".to_string(),
@@ -494,6 +622,7 @@ fn filter_clause(
         Entity::CpuMetrics => cpu_filter_clause(filter),
         Entity::MemoryMetrics => memory_filter_clause(filter),
         Entity::DiskMetrics => disk_filter_clause(filter),
+        Entity::NetworkMetrics => network_filter_clause(filter),
         Entity::ProcessMetrics => process_filter_clause(filter),
         Entity::Flows => flows_filter_clause(filter),
         _ => Err(ServiceError::InvalidRequest(
@@ -723,6 +852,21 @@ fn disk_filter_clause(filter: &Filter) -> Result<(String, Vec<SqlBindValue>)> {
     }
 }
 
+fn network_filter_clause(filter: &Filter) -> Result<(String, Vec<SqlBindValue>)> {
+    match filter.field.as_str() {
+        "gateway_id" | "agent_id" | "host_id" | "device_id" | "partition" | "interface_name" => {
+            text_clause(filter.field.as_str(), filter)
+        }
+        "bytes_sent_per_sec" => float_clause("bytes_sent_per_sec", filter, true),
+        "bytes_recv_per_sec" => float_clause("bytes_recv_per_sec", filter, true),
+        "bytes_sent" => int_clause("bytes_sent", filter, false),
+        "bytes_recv" => int_clause("bytes_recv", filter, false),
+        other => Err(ServiceError::InvalidRequest(format!(
+            "unsupported filter field for downsample network_metrics: '{other}'"
+        ))),
+    }
+}
+
 fn process_filter_clause(filter: &Filter) -> Result<(String, Vec<SqlBindValue>)> {
     match filter.field.as_str() {
         "gateway_id" | "agent_id" | "host_id" | "device_id" | "partition" | "name" | "status"
@@ -795,6 +939,18 @@ fn flow_cagg_for_bucket(bucket_seconds: i64) -> &'static str {
     }
 }
 
+/// Daily rollups cover whole UTC days, so they only serve buckets that are a
+/// whole number of days; anything finer reads the hourly CAGG.
+fn metric_rollup_for_bucket(entity: &Entity, bucket_seconds: i64) -> Option<&'static str> {
+    if bucket_seconds >= SECS_PER_DAY && bucket_seconds % SECS_PER_DAY == 0 {
+        if let Some(table) = super::daily_rollup_table_for_entity(entity) {
+            return Some(table);
+        }
+    }
+
+    super::cagg_table_for_entity(entity)
+}
+
 fn rewrite_placeholders(sql: &str) -> String {
     let mut result = String::with_capacity(sql.len());
     let mut index = 1;
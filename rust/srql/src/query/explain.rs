@@ -0,0 +1,234 @@
+//! `EXPLAIN` mode: returns the backend SQL a query translates to, with the
+//! resolved plan, instead of executing it.
+
+use super::{
+    downsample, plan_sql, result_cap, BindParam, PaginationMeta, QueryPlan, QueryResponse,
+};
+use crate::{
+    config::AppConfig,
+    error::Result,
+    parser::{Entity, Filter, OrderClause, StatsSpec},
+    time::TimeRange,
+};
+use serde::Serialize;
+
+/// The store a query is answered from.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum QueryBackend {
+    /// Relational tables and hypertables in CNPG/Timescale.
+    Postgres,
+    /// The Apache AGE property graph, queried through `cypher()`.
+    Age,
+}
+
+impl QueryBackend {
+    pub fn for_entity(entity: &Entity) -> Self {
+        match entity {
+            Entity::DeviceGraph | Entity::GraphCypher => Self::Age,
+            _ => Self::Postgres,
+        }
+    }
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ExplainMeta {
+    pub backend: QueryBackend,
+    /// Parameterized SQL exactly as the engine would send it.
+    pub sql: String,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub params: Vec<BindParam>,
+    pub plan: ExplainPlan,
+}
+
+/// The resolved query plan: relative times are resolved, aliases normalized
+/// and limits clamped.
+#[derive(Debug, Clone, Serialize)]
+pub struct ExplainPlan {
+    pub entity: Entity,
+    pub filters: Vec<Filter>,
+    pub order: Vec<OrderClause>,
+    pub limit: i64,
+    pub offset: i64,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub time_range: Option<TimeRange>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub stats: Option<StatsSpec>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub rollup_stats: Option<String>,
+    pub include_deleted: bool,
+    /// The requested limit when the result cap lowered it. The SQL then
+    /// fetches one lookahead row past the cap to detect truncation.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub capped_from: Option<i64>,
+}
+
+/// Translates `plan` under the result cap without touching the database.
+pub(super) fn explain(
+    config: &AppConfig,
+    mut plan: QueryPlan,
+    cap: Option<i64>,
+) -> Result<ExplainMeta> {
+    let capped_from = result_cap::apply(&mut plan, cap);
+    let (sql, params) = plan_sql(config, &plan)?;
+
+    Ok(ExplainMeta {
+        backend: QueryBackend::for_entity(&plan.entity),
+        sql,
+        params,
+        plan: ExplainPlan {
+            entity: plan.entity,
+            filters: plan.filters,
+            order: plan.order,
+            limit: plan.limit,
+            offset: plan.offset,
+            time_range: plan.time_range,
+            stats: plan.stats,
+            rollup_stats: plan.rollup_stats,
+            include_deleted: plan.include_deleted,
+            capped_from,
+        },
+    })
+}
+
+/// Builds the `/api/query` response for an `EXPLAIN` query: no rows, just
+/// the translation.
+pub(super) fn response(
+    config: &AppConfig,
+    plan: QueryPlan,
+    cap: Option<i64>,
+) -> Result<QueryResponse> {
+    let downsample = downsample::meta_for_plan(&plan);
+    let limit = plan.limit;
+    let meta = explain(config, plan, cap)?;
+
+    Ok(QueryResponse {
+        pagination: PaginationMeta {
+            limit: Some(limit),
+            ..PaginationMeta::default()
+        },
+        downsample,
+        explain: Some(meta),
+        ..QueryResponse::default()
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::parser;
+    use crate::query::{build_query_plan, QueryDirection, QueryRequest};
+
+    fn plan(query: &str) -> (AppConfig, QueryPlan) {
+        let config = AppConfig::embedded("postgres://unused/db".to_string());
+        let request = QueryRequest {
+            query: query.to_string(),
+            limit: None,
+            cursor: None,
+            direction: QueryDirection::Next,
+            mode: None,
+        };
+        let ast = parser::parse(query).expect("query should parse");
+        assert!(ast.explain, "expected EXPLAIN prefix to be recognised");
+        let plan = build_query_plan(&config, &request, ast).expect("plan should build");
+        (config, plan)
+    }
+
+    fn explain_query(query: &str, cap: Option<i64>) -> ExplainMeta {
+        let (config, plan) = plan(query);
+        explain(&config, plan, cap).expect("explain should succeed")
+    }
+
+    #[test]
+    fn prefix_is_case_insensitive_and_optional() {
+        assert!(parser::parse("explain in:devices").unwrap().explain);
+        assert!(parser::parse("EXPLAIN in:devices").unwrap().explain);
+        assert!(!parser::parse("in:devices").unwrap().explain);
+    }
+
+    #[test]
+    fn explains_relational_query_against_postgres() {
+        let meta = explain_query("EXPLAIN in:devices ip:10.0.0.0/8 limit:20", None);
+
+        assert_eq!(meta.backend, QueryBackend::Postgres);
+        assert!(matches!(meta.plan.entity, Entity::Devices));
+        assert_eq!(meta.plan.limit, 20);
+        assert_eq!(meta.plan.capped_from, None);
+
+        let sql = meta.sql.to_lowercase();
+        assert!(
+            sql.contains("ocsf_devices") && sql.contains("ip::inet") && sql.contains("<<="),
+            "expected CIDR predicate on ocsf_devices, got: {}",
+            meta.sql
+        );
+        assert!(meta
+            .params
+            .iter()
+            .any(|param| matches!(param, BindParam::Text(value) if value == "10.0.0.0/8")));
+    }
+
+    #[test]
+    fn explains_time_bounded_query_with_resolved_range() {
+        let meta = explain_query(
+            "EXPLAIN in:logs time:last_1h severity_text:error limit:10",
+            None,
+        );
+
+        assert_eq!(meta.backend, QueryBackend::Postgres);
+        assert!(meta.plan.time_range.is_some());
+        assert!(
+            meta.sql.to_lowercase().contains("logs"),
+            "expected logs table in SQL, got: {}",
+            meta.sql
+        );
+        assert!(meta
+            .params
+            .iter()
+            .any(|param| matches!(param, BindParam::Timestamptz(_))));
+    }
+
+    #[test]
+    fn explains_graph_query_against_age() {
+        let meta = explain_query(
+            "EXPLAIN in:graph_cypher cypher:\"MATCH (n) RETURN n\" limit:10",
+            None,
+        );
+
+        assert_eq!(meta.backend, QueryBackend::Age);
+        assert!(
+            meta.sql.contains("cypher(") && meta.sql.contains("platform_graph"),
+            "expected AGE cypher() call, got: {}",
+            meta.sql
+        );
+        assert!(matches!(
+            meta.params.as_slice(),
+            [BindParam::Int(10), BindParam::Int(0)]
+        ));
+    }
+
+    #[test]
+    fn explain_reports_result_cap() {
+        let meta = explain_query(
+            "EXPLAIN in:graph_cypher cypher:\"MATCH (n) RETURN n\" limit:50",
+            Some(10),
+        );
+
+        assert_eq!(meta.plan.capped_from, Some(50));
+        assert_eq!(meta.plan.limit, 11);
+        assert!(matches!(meta.params.first(), Some(BindParam::Int(11))));
+    }
+
+    #[test]
+    fn explain_response_has_no_rows() {
+        let (config, plan) = plan("EXPLAIN in:devices limit:5");
+        let response = response(&config, plan, None).expect("explain should succeed");
+
+        assert!(response.results.is_empty());
+        assert_eq!(response.pagination.limit, Some(5));
+
+        let body = serde_json::to_value(&response).unwrap();
+        assert_eq!(body["explain"]["backend"], "postgres");
+        assert_eq!(body["explain"]["plan"]["entity"], "devices");
+        assert!(body["explain"]["sql"].is_string());
+    }
+}
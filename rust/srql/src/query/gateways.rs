@@ -15,6 +15,7 @@ use crate::{
 };
 use chrono::{DateTime, Utc};
 use diesel::deserialize::QueryableByName;
+use diesel::dsl::sql;
 use diesel::pg::Pg;
 use diesel::prelude::*;
 use diesel::query_builder::{AsQuery, BoxedSelectStatement, BoxedSqlQuery, FromClause, SqlQuery};
@@ -29,6 +30,11 @@ type GatewaysFromClause = FromClause<GatewaysTable>;
 type GatewaysQuery<'a> =
     BoxedSelectStatement<'a, <GatewaysTable as AsQuery>::SqlType, GatewaysFromClause, Pg>;
 
+/// Gateways are assigned to a site/tier group through `metadata.group`; gateways
+/// without one fall into the same default group the core gateway rollup uses.
+const DEFAULT_GATEWAY_GROUP: &str = "ungrouped";
+const GATEWAY_GROUP_EXPR: &str = "coalesce(metadata->>'group', 'ungrouped')";
+
 pub(super) async fn execute(conn: &mut AsyncPgConnection, plan: &QueryPlan) -> Result<Vec<Value>> {
     ensure_entity(plan)?;
 
@@ -109,6 +115,16 @@ struct GatewayRowRow {
 
 impl GatewayRowRow {
     fn into_json(self) -> serde_json::Value {
+        let metadata = self
+            .metadata
+            .map_or(serde_json::json!({}), serde_json::Value::from);
+        let group = metadata
+            .get("group")
+            .and_then(|value| value.as_str())
+            .filter(|value| !value.is_empty())
+            .unwrap_or(DEFAULT_GATEWAY_GROUP)
+            .to_string();
+
         serde_json::json!({
             "gateway_id": self.gateway_id,
             "component_id": self.component_id,
@@ -118,9 +134,8 @@ impl GatewayRowRow {
             "first_registered": self.first_registered,
             "first_seen": self.first_seen,
             "last_seen": self.last_seen,
-            "metadata": self
-                .metadata
-                .map_or(serde_json::json!({}), serde_json::Value::from),
+            "metadata": metadata,
+            "group": group,
             "created_by": self.created_by,
             "is_healthy": self.is_healthy,
             "agent_count": self.agent_count.unwrap_or(0),
@@ -206,6 +221,9 @@ fn apply_filter<'a>(mut query: GatewaysQuery<'a>, filter: &Filter) -> Result<Gat
         "created_by" => {
             query = apply_text_filter!(query, filter, col_created_by)?;
         }
+        "group" => {
+            query = apply_group_filter(query, filter)?;
+        }
         "is_healthy" => {
             let value = parse_bool(filter.value.as_scalar()?)?;
             match filter.op {
@@ -228,6 +246,46 @@ fn apply_filter<'a>(mut query: GatewaysQuery<'a>, filter: &Filter) -> Result<Gat
     Ok(query)
 }
 
+fn apply_group_filter<'a>(query: GatewaysQuery<'a>, filter: &Filter) -> Result<GatewaysQuery<'a>> {
+    match filter.op {
+        FilterOp::Eq => Ok(query.filter(
+            sql::<Bool>(&format!("{GATEWAY_GROUP_EXPR} = "))
+                .bind::<Text, _>(filter.value.as_scalar()?.to_string()),
+        )),
+        FilterOp::NotEq => Ok(query.filter(
+            sql::<Bool>(&format!("{GATEWAY_GROUP_EXPR} <> "))
+                .bind::<Text, _>(filter.value.as_scalar()?.to_string()),
+        )),
+        FilterOp::Like => Ok(query.filter(
+            sql::<Bool>(&format!("{GATEWAY_GROUP_EXPR} ILIKE "))
+                .bind::<Text, _>(filter.value.as_scalar()?.to_string()),
+        )),
+        FilterOp::NotLike => Ok(query.filter(
+            sql::<Bool>(&format!("{GATEWAY_GROUP_EXPR} NOT ILIKE "))
+                .bind::<Text, _>(filter.value.as_scalar()?.to_string()),
+        )),
+        FilterOp::In | FilterOp::NotIn => {
+            let values = filter.value.as_list()?.to_vec();
+            if values.is_empty() {
+                return Ok(query);
+            }
+            let negate = if matches!(filter.op, FilterOp::NotIn) {
+                "NOT "
+            } else {
+                ""
+            };
+            Ok(query.filter(
+                sql::<Bool>(&format!("{negate}({GATEWAY_GROUP_EXPR} = ANY("))
+                    .bind::<Array<Text>, _>(values)
+                    .sql("))"),
+            ))
+        }
+        _ => Err(ServiceError::InvalidRequest(
+            "group filter only supports equality, LIKE, and list filters".into(),
+        )),
+    }
+}
+
 fn collect_text_params(params: &mut Vec<BindParam>, filter: &Filter) -> Result<()> {
     match filter.op {
         FilterOp::Eq | FilterOp::NotEq | FilterOp::Like | FilterOp::NotLike => {
@@ -256,7 +314,8 @@ fn collect_filter_params(params: &mut Vec<BindParam>, filter: &Filter) -> Result
         | "component_id"
         | "registration_source"
         | "spiffe_identity"
-        | "created_by" => collect_text_params(params, filter),
+        | "created_by"
+        | "group" => collect_text_params(params, filter),
         "is_healthy" => {
             let value = parse_bool(filter.value.as_scalar()?)?;
             params.push(BindParam::Bool(value));
@@ -419,4 +478,31 @@ mod tests {
             Ok(_) => panic!("expected error for unknown filter field"),
         }
     }
+
+    #[test]
+    fn group_filter_targets_metadata_group() {
+        let plan = QueryPlan {
+            entity: Entity::Gateways,
+            filters: vec![Filter {
+                field: "group".into(),
+                op: FilterOp::In,
+                value: FilterValue::List(vec!["site-nyc".into(), "site-sfo".into()]),
+            }],
+            order: Vec::new(),
+            limit: 50,
+            offset: 0,
+            time_range: None,
+            stats: None,
+            downsample: None,
+            rollup_stats: None,
+            include_deleted: false,
+        };
+
+        let (sql, params) = to_sql_and_params(&plan).expect("group filter should translate");
+        assert!(
+            sql.contains("coalesce(metadata->>'group', 'ungrouped') = ANY("),
+            "expected metadata group expression, got: {sql}"
+        );
+        assert!(matches!(params.first(), Some(BindParam::TextArray(values)) if values.len() == 2));
+    }
 }
@@ -2,7 +2,7 @@ use super::{BindParam, QueryPlan};
 use crate::{
     error::{Result, ServiceError},
     jsonb::DbJson,
-    parser::{Entity, Filter, FilterOp, FilterValue, OrderClause, OrderDirection},
+    parser::{Entity, Filter, FilterOp, OrderClause, OrderDirection},
     time::TimeRange,
 };
 use chrono::{DateTime, Utc};
@@ -606,10 +606,7 @@ fn build_ip_addresses_clause(
     binds: &mut Vec<BindParam>,
     bind_idx: &mut usize,
 ) -> Result<Option<String>> {
-    let values: Vec<String> = match &filter.value {
-        FilterValue::Scalar(value) => vec![value.to_string()],
-        FilterValue::List(list) => list.clone(),
-    };
+    let values = filter.value.to_values()?;
 
     if values.is_empty() {
         return Ok(None);
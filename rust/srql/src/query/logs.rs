@@ -15,13 +15,13 @@ use crate::{
 };
 use chrono::{DateTime, Utc};
 use diesel::deserialize::QueryableByName;
-use diesel::dsl::sql;
+use diesel::dsl::{not, sql};
 use diesel::expression::SqlLiteral;
 use diesel::pg::Pg;
 use diesel::prelude::*;
 use diesel::query_builder::{AsQuery, BoxedSelectStatement, BoxedSqlQuery, FromClause, SqlQuery};
 use diesel::sql_query;
-use diesel::sql_types::{Int4, Jsonb, Nullable, Text, Timestamptz};
+use diesel::sql_types::{Array, Bool, Int4, Jsonb, Nullable, Text, Timestamptz};
 use diesel::PgTextExpressionMethods;
 use diesel_async::{AsyncPgConnection, RunQueryDsl};
 use serde_json::Value;
@@ -231,7 +231,8 @@ fn collect_filter_params(params: &mut Vec<BindParam>, filter: &Filter) -> Result
         }
         "trace_id" | "span_id" | "service_name" | "service_version" | "service_instance"
         | "source" | "scope_name" | "scope_version" | "severity_text" | "severity" | "level"
-        | "body" | "message" => collect_text_params(params, filter),
+        | "body" | "message" | "device_id" | "device_partition" | "partition" | "device_tags"
+        | "device.tag" | "device.tags" => collect_text_params(params, filter),
         "severity_number" => match filter.op {
             FilterOp::Eq | FilterOp::NotEq => {
                 let value = filter.value.as_scalar()?.parse::<i32>().map_err(|_| {
@@ -481,6 +482,15 @@ fn apply_filter<'a>(mut query: LogsQuery<'a>, filter: &Filter) -> Result<LogsQue
         "body" | "message" => {
             query = apply_text_filter!(query, filter, col_body)?;
         }
+        "device_id" => {
+            query = apply_device_text_filter(query, filter, "device_id")?;
+        }
+        "device_partition" | "partition" => {
+            query = apply_device_text_filter(query, filter, "device_partition")?;
+        }
+        "device_tags" | "device.tag" | "device.tags" => {
+            query = apply_device_tags_filter(query, filter)?;
+        }
         "severity_number" => match filter.op {
             FilterOp::Eq | FilterOp::NotEq => {
                 let value = filter.value.as_scalar()?.parse::<i32>().map_err(|_| {
@@ -527,6 +537,82 @@ fn apply_filter<'a>(mut query: LogsQuery<'a>, filter: &Filter) -> Result<LogsQue
     Ok(query)
 }
 
+/// Filters on a device context column. These are set by db-event-writer log
+/// enrichment and are not part of the diesel schema, so the filter is written
+/// as SQL.
+fn apply_device_text_filter<'a>(
+    query: LogsQuery<'a>,
+    filter: &Filter,
+    column: &str,
+) -> Result<LogsQuery<'a>> {
+    let scalar = |op: &str| -> Result<_> {
+        let value = filter.value.as_scalar()?.to_string();
+        Ok(sql::<Bool>(&format!("{column} {op} ")).bind::<Text, _>(value))
+    };
+
+    let query = match filter.op {
+        FilterOp::Eq => query.filter(scalar("=")?),
+        FilterOp::NotEq => query.filter(scalar("<>")?),
+        FilterOp::Like => query.filter(scalar("ILIKE")?),
+        FilterOp::NotLike => query.filter(scalar("NOT ILIKE")?),
+        FilterOp::In | FilterOp::NotIn => {
+            let values = filter.value.as_list()?.to_vec();
+            if values.is_empty() {
+                return Ok(query);
+            }
+            let quantifier = if matches!(filter.op, FilterOp::In) {
+                "= ANY("
+            } else {
+                "<> ALL("
+            };
+            query.filter(
+                sql::<Bool>(&format!("{column} {quantifier}"))
+                    .bind::<Array<Text>, _>(values)
+                    .sql(")"),
+            )
+        }
+        _ => {
+            return Err(ServiceError::InvalidRequest(format!(
+                "unsupported operator for text filter: {:?}",
+                filter.op
+            )))
+        }
+    };
+
+    Ok(query)
+}
+
+/// `device.tag:critical` matches logs whose enriched device carries the tag.
+fn apply_device_tags_filter<'a>(query: LogsQuery<'a>, filter: &Filter) -> Result<LogsQuery<'a>> {
+    match filter.op {
+        FilterOp::Eq | FilterOp::NotEq => {
+            let tag = filter.value.as_scalar()?.to_string();
+            let expr = sql::<Bool>("coalesce(device_tags, '{}'::jsonb) ? ").bind::<Text, _>(tag);
+            if matches!(filter.op, FilterOp::NotEq) {
+                Ok(query.filter(not(expr)))
+            } else {
+                Ok(query.filter(expr))
+            }
+        }
+        FilterOp::In | FilterOp::NotIn => {
+            let tags = filter.value.as_list()?.to_vec();
+            if tags.is_empty() {
+                return Ok(query);
+            }
+            let expr =
+                sql::<Bool>("coalesce(device_tags, '{}'::jsonb) ?| ").bind::<Array<Text>, _>(tags);
+            if matches!(filter.op, FilterOp::NotIn) {
+                Ok(query.filter(not(expr)))
+            } else {
+                Ok(query.filter(expr))
+            }
+        }
+        _ => Err(ServiceError::InvalidRequest(
+            "device.tag filter only supports equality and list filters".into(),
+        )),
+    }
+}
+
 fn apply_ordering<'a>(mut query: LogsQuery<'a>, order: &[OrderClause]) -> LogsQuery<'a> {
     let mut applied = false;
     for clause in order {
@@ -725,6 +811,9 @@ fn build_stats_filter_clause(filter: &Filter) -> Result<Option<(String, Vec<SqlB
         "severity_text" | "severity" | "level" => build_text_clause("severity_text", filter),
         "body" | "message" => build_text_clause("body", filter),
         "severity_number" => build_numeric_clause("severity_number", filter),
+        "device_id" => build_text_clause("device_id", filter),
+        "device_partition" | "partition" => build_text_clause("device_partition", filter),
+        "device_tags" | "device.tag" | "device.tags" => build_device_tags_clause(filter),
         other => Err(ServiceError::InvalidRequest(format!(
             "unsupported filter field for logs stats: '{other}'"
         ))),
@@ -779,6 +868,36 @@ fn build_text_clause(column: &str, filter: &Filter) -> Result<Option<(String, Ve
     Ok(Some((clause, binds)))
 }
 
+fn build_device_tags_clause(filter: &Filter) -> Result<Option<(String, Vec<SqlBindValue>)>> {
+    let tags = match filter.op {
+        FilterOp::Eq | FilterOp::NotEq => vec![filter.value.as_scalar()?.to_string()],
+        FilterOp::In | FilterOp::NotIn => filter.value.as_list()?.to_vec(),
+        _ => {
+            return Err(ServiceError::InvalidRequest(
+                "device.tag filter only supports equality and list filters".into(),
+            ))
+        }
+    };
+    if tags.is_empty() {
+        return Ok(None);
+    }
+    enforce_list_limit(&filter.field, tags.len())?;
+
+    // jsonb_exists_any rather than ?| since ? marks bind placeholders here.
+    let placeholders = vec!["?"; tags.len()].join(", ");
+    let mut clause = format!(
+        "jsonb_exists_any(coalesce(device_tags, '{{}}'::jsonb), ARRAY[{placeholders}]::text[])"
+    );
+    if matches!(filter.op, FilterOp::NotEq | FilterOp::NotIn) {
+        clause = format!("NOT {clause}");
+    }
+
+    Ok(Some((
+        clause,
+        tags.into_iter().map(SqlBindValue::Text).collect(),
+    )))
+}
+
 fn build_numeric_clause(
     column: &str,
     filter: &Filter,
@@ -1001,6 +1120,69 @@ mod tests {
         );
     }
 
+    fn device_plan(filters: Vec<Filter>, stats: Option<&str>) -> QueryPlan {
+        let start = Utc.with_ymd_and_hms(2025, 1, 1, 0, 0, 0).unwrap();
+        QueryPlan {
+            entity: Entity::Logs,
+            filters,
+            order: Vec::new(),
+            limit: 100,
+            offset: 0,
+            time_range: Some(TimeRange {
+                start,
+                end: start + ChronoDuration::hours(24),
+            }),
+            stats: stats.map(crate::parser::StatsSpec::from_raw),
+            downsample: None,
+            rollup_stats: None,
+            include_deleted: false,
+        }
+    }
+
+    #[test]
+    fn device_context_filters_are_supported() {
+        let filters = vec![
+            Filter {
+                field: "device.tag".into(),
+                op: FilterOp::Eq,
+                value: FilterValue::Scalar("critical".to_string()),
+            },
+            Filter {
+                field: "partition".into(),
+                op: FilterOp::In,
+                value: FilterValue::List(vec!["edge".to_string(), "core".to_string()]),
+            },
+            Filter {
+                field: "device_id".into(),
+                op: FilterOp::Eq,
+                value: FilterValue::Scalar("edge:10.0.0.5".to_string()),
+            },
+        ];
+
+        let (sql, params) = to_sql_and_params(&device_plan(filters.clone(), None))
+            .expect("device filters should build");
+        assert!(
+            sql.contains("coalesce(device_tags, '{}'::jsonb) ?")
+                && sql.contains("device_partition = ANY(")
+                && sql.contains("device_id = "),
+            "unexpected device filter SQL: {sql}"
+        );
+        assert_eq!(params.len(), 5, "time range + one bind per filter expected");
+
+        let stats_sql = build_stats_query(&device_plan(filters, Some("count() as total")))
+            .expect("stats query should parse")
+            .expect("stats SQL expected");
+        assert!(
+            stats_sql
+                .sql
+                .contains("jsonb_exists_any(coalesce(device_tags")
+                && stats_sql.sql.contains("device_partition IN (?, ?)"),
+            "unexpected device filter stats SQL: {}",
+            stats_sql.sql
+        );
+        assert_eq!(stats_sql.binds.len(), 6);
+    }
+
     #[test]
     fn stats_query_uses_effective_timestamp() {
         let start = Utc.with_ymd_and_hms(2025, 1, 1, 0, 0, 0).unwrap();
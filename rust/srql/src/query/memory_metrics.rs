@@ -396,7 +396,7 @@ fn build_cagg_stats_query(plan: &QueryPlan, spec: &MemoryStatsSpec) -> Result<Me
     build_stats_query_with_source(
         plan,
         spec,
-        "memory_metrics_hourly",
+        super::metric_rollup_table_for_plan(plan).unwrap_or("memory_metrics_hourly"),
         "bucket",
         &agg_expr,
         true,
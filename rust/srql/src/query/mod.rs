@@ -118,13 +118,16 @@ pub(crate) fn normalize_mac_value(raw: &str, allow_wildcards: bool) -> Result<St
 mod agents;
 mod alerts;
 mod bmp_events;
+mod certificates;
 mod cpu_metrics;
+mod default_sort;
 mod device_graph;
 mod device_updates;
 mod devices;
 mod disk_metrics;
 mod downsample;
 mod events;
+mod explain;
 mod field_survey;
 mod flows;
 mod gateways;
@@ -132,9 +135,13 @@ mod graph_cypher;
 mod interfaces;
 mod logs;
 mod memory_metrics;
+mod network_metrics;
 mod otel_metrics;
 mod process_metrics;
+mod result_cap;
 mod services;
+mod stats_order;
+mod subquery;
 mod timeseries_metrics;
 mod trace_summaries;
 mod traces;
@@ -150,6 +157,7 @@ use crate::{
     time::TimeRange,
 };
 use chrono::{Duration as ChronoDuration, Utc};
+use diesel_async::AsyncPgConnection;
 use serde::{Deserialize, Serialize};
 use serde_json::Value;
 use std::sync::Arc;
@@ -157,6 +165,8 @@ use tracing::error;
 
 const CAGG_ROUTING_THRESHOLD_HOURS: i64 = 6;
 const CAGG_MAX_TIME_RANGE_DAYS: i64 = 395;
+const DAILY_ROLLUP_ROUTING_THRESHOLD_DAYS: i64 = 30;
+const DAILY_ROLLUP_MAX_TIME_RANGE_DAYS: i64 = 730;
 
 #[derive(Debug, Clone, Serialize)]
 #[serde(tag = "t", content = "v", rename_all = "snake_case")]
@@ -193,26 +203,75 @@ impl QueryEngine {
     }
 
     pub async fn execute_query(&self, request: QueryRequest) -> Result<QueryResponse> {
+        self.execute_query_with_cap(request, self.config.result_cap())
+            .await
+    }
+
+    /// Executes a query returning at most `cap` rows. When more rows match,
+    /// the response is truncated and flagged so clients know to paginate.
+    /// `None` lifts the cap (admin override).
+    pub async fn execute_query_with_cap(
+        &self,
+        request: QueryRequest,
+        cap: Option<i64>,
+    ) -> Result<QueryResponse> {
         let ast = parser::parse(&request.query)?;
-        let plan = build_query_plan(&self.config, &request, ast)?;
+        let explain = ast.explain;
+        let mut plan = build_query_plan(&self.config, &request, ast)?;
+        if explain {
+            return explain::response(&self.config, plan, cap);
+        }
+
+        let requested_limit = result_cap::apply(&mut plan, cap);
         let mut conn = self.pool.get().await.map_err(|err| {
             error!(error = ?err, "failed to acquire database connection");
             ServiceError::Internal(anyhow::anyhow!("{err:?}"))
         })?;
 
+        let mut results = self.execute_plan(&mut conn, &plan).await?;
+
+        let mut truncated = false;
+        let mut total_available = None;
+        if let Some(requested) = requested_limit {
+            let cap = result_cap::restore(&mut plan);
+            if results.len() as i64 > cap {
+                results.truncate(cap as usize);
+                truncated = true;
+                total_available =
+                    result_cap::count_available(&mut conn, &self.config, &plan, requested).await;
+            }
+        }
+
+        let pagination = self.build_pagination(&plan, results.len() as i64);
+        Ok(QueryResponse {
+            results,
+            pagination,
+            downsample: downsample::meta_for_plan(&plan),
+            truncated,
+            total_available,
+            explain: None,
+            error: None,
+        })
+    }
+
+    async fn execute_plan(
+        &self,
+        conn: &mut AsyncPgConnection,
+        plan: &QueryPlan,
+    ) -> Result<Vec<Value>> {
         let results = if plan.downsample.is_some() {
-            downsample::execute(&mut conn, &plan).await?
+            downsample::execute(conn, plan).await?
         } else {
             match plan.entity {
-                Entity::Agents => agents::execute(&mut conn, &plan).await?,
-                Entity::Devices => devices::execute(&mut conn, &plan).await?,
-                Entity::DeviceUpdates => device_updates::execute(&mut conn, &plan).await?,
-                Entity::DeviceGraph => device_graph::execute(&mut conn, &plan).await?,
+                Entity::Agents => agents::execute(conn, plan).await?,
+                Entity::Devices => devices::execute(conn, plan).await?,
+                Entity::DeviceUpdates => device_updates::execute(conn, plan).await?,
+                Entity::DeviceGraph => device_graph::execute(conn, plan).await?,
                 Entity::GraphCypher => {
-                    graph_cypher::execute(&mut conn, &plan, &self.config.age_graph_name).await?
+                    graph_cypher::execute(conn, plan, &self.config.age_graph_name).await?
                 }
-                Entity::Events => events::execute(&mut conn, &plan).await?,
-                Entity::BmpEvents => bmp_events::execute(&mut conn, &plan).await?,
+                Entity::Events => events::execute(conn, plan).await?,
+                Entity::BmpEvents => bmp_events::execute(conn, plan).await?,
                 Entity::FieldSurveySessions
                 | Entity::FieldSurveyRasters
                 | Entity::FieldSurveyArtifacts
@@ -220,7 +279,7 @@ impl QueryEngine {
                 | Entity::FieldSurveyPoseSamples
                 | Entity::FieldSurveyRfPoseMatches
                 | Entity::FieldSurveySpectrumObservations => {
-                    field_survey::execute(&mut conn, &plan).await?
+                    field_survey::execute(conn, plan).await?
                 }
                 Entity::WifiSites
                 | Entity::WifiSiteSnapshots
@@ -228,32 +287,29 @@ impl QueryEngine {
                 | Entity::WifiControllers
                 | Entity::WifiRadiusGroups
                 | Entity::WifiFleetHistory
-                | Entity::WifiSiteReferences => wifi_map::execute(&mut conn, &plan).await?,
-                Entity::Flows => flows::execute(&mut conn, &plan).await?,
-                Entity::Interfaces => interfaces::execute(&mut conn, &plan).await?,
-                Entity::Logs => logs::execute(&mut conn, &plan).await?,
-                Entity::Gateways => gateways::execute(&mut conn, &plan).await?,
-                Entity::OtelMetrics => otel_metrics::execute(&mut conn, &plan).await?,
+                | Entity::WifiSiteReferences => wifi_map::execute(conn, plan).await?,
+                Entity::Flows => flows::execute(conn, plan).await?,
+                Entity::Interfaces => interfaces::execute(conn, plan).await?,
+                Entity::Logs => logs::execute(conn, plan).await?,
+                Entity::Gateways => gateways::execute(conn, plan).await?,
+                Entity::OtelMetrics => otel_metrics::execute(conn, plan).await?,
                 Entity::RperfMetrics | Entity::TimeseriesMetrics | Entity::SnmpMetrics => {
-                    timeseries_metrics::execute(&mut conn, &plan).await?
+                    timeseries_metrics::execute(conn, plan).await?
                 }
-                Entity::CpuMetrics => cpu_metrics::execute(&mut conn, &plan).await?,
-                Entity::MemoryMetrics => memory_metrics::execute(&mut conn, &plan).await?,
-                Entity::DiskMetrics => disk_metrics::execute(&mut conn, &plan).await?,
-                Entity::ProcessMetrics => process_metrics::execute(&mut conn, &plan).await?,
-                Entity::Services => services::execute(&mut conn, &plan).await?,
-                Entity::TraceSummaries => trace_summaries::execute(&mut conn, &plan).await?,
-                Entity::Traces => traces::execute(&mut conn, &plan).await?,
-                Entity::Alerts => alerts::execute(&mut conn, &plan).await?,
+                Entity::CpuMetrics => cpu_metrics::execute(conn, plan).await?,
+                Entity::MemoryMetrics => memory_metrics::execute(conn, plan).await?,
+                Entity::DiskMetrics => disk_metrics::execute(conn, plan).await?,
+                Entity::NetworkMetrics => network_metrics::execute(conn, plan).await?,
+                Entity::ProcessMetrics => process_metrics::execute(conn, plan).await?,
+                Entity::Services => services::execute(conn, plan).await?,
+                Entity::TraceSummaries => trace_summaries::execute(conn, plan).await?,
+                Entity::Traces => traces::execute(conn, plan).await?,
+                Entity::Alerts => alerts::execute(conn, plan).await?,
+                Entity::Certificates => certificates::execute(conn, plan).await?,
             }
         };
 
-        let pagination = self.build_pagination(&plan, results.len() as i64);
-        Ok(QueryResponse {
-            results,
-            pagination,
-            error: None,
-        })
+        Ok(results)
     }
 
     pub async fn translate(&self, request: TranslateRequest) -> Result<TranslateResponse> {
@@ -305,7 +361,7 @@ fn build_query_plan(
         normalize_device_aliases(&ast.entity, ast.filters, ast.order, ast.downsample);
     let (filters, include_deleted) = extract_include_deleted(filters)?;
 
-    Ok(QueryPlan {
+    let mut plan = QueryPlan {
         entity: ast.entity,
         filters,
         order,
@@ -316,7 +372,12 @@ fn build_query_plan(
         downsample,
         rollup_stats: ast.rollup_stats,
         include_deleted,
-    })
+    };
+    downsample::apply_auto_downsample(config, &mut plan)?;
+    stats_order::apply(&mut plan)?;
+    default_sort::apply(config, &mut plan);
+
+    Ok(plan)
 }
 
 fn determine_limit(config: &AppConfig, candidate: Option<i64>) -> i64 {
@@ -400,6 +461,50 @@ pub(super) fn cagg_table_for_entity(entity: &Entity) -> Option<&'static str> {
     }
 }
 
+/// Daily rollup tables maintained by the core `MetricRollupWorker`. They share
+/// the column layout of the hourly CAGGs, so `cagg_column_for_entity` applies.
+pub(super) fn daily_rollup_table_for_entity(entity: &Entity) -> Option<&'static str> {
+    match entity {
+        Entity::CpuMetrics => Some("cpu_metrics_daily"),
+        Entity::MemoryMetrics => Some("memory_metrics_daily"),
+        Entity::DiskMetrics => Some("disk_metrics_daily"),
+        Entity::ProcessMetrics => Some("process_metrics_daily"),
+        Entity::TimeseriesMetrics | Entity::SnmpMetrics | Entity::RperfMetrics => {
+            Some("timeseries_metrics_daily")
+        }
+        _ => None,
+    }
+}
+
+/// Picks the summary table for a stats query already routed away from raw
+/// data: daily rollups for windows of `DAILY_ROLLUP_ROUTING_THRESHOLD_DAYS` or
+/// more, hourly CAGGs otherwise.
+pub(super) fn metric_rollup_table_for_plan(plan: &QueryPlan) -> Option<&'static str> {
+    if should_route_to_daily_rollup(&plan.entity, plan.time_range.as_ref()) {
+        daily_rollup_table_for_entity(&plan.entity)
+    } else {
+        cagg_table_for_entity(&plan.entity)
+    }
+}
+
+pub(super) fn should_route_to_daily_rollup(
+    entity: &Entity,
+    time_range: Option<&TimeRange>,
+) -> bool {
+    if daily_rollup_table_for_entity(entity).is_none() {
+        return false;
+    }
+
+    let Some(time_range) = time_range else {
+        return false;
+    };
+
+    time_range
+        .end
+        .signed_duration_since(time_range.start)
+        .ge(&ChronoDuration::days(DAILY_ROLLUP_ROUTING_THRESHOLD_DAYS))
+}
+
 pub(super) fn cagg_column_for_entity(
     entity: &Entity,
     agg_fn: &str,
@@ -458,10 +563,12 @@ fn is_hourly_cagg_eligible_query(entity: &Entity, has_stats: bool, has_downsampl
 }
 
 fn max_time_range_days_for_ast(ast: &QueryAst) -> i64 {
-    if is_hourly_cagg_eligible_query(&ast.entity, ast.stats.is_some(), ast.downsample.is_some()) {
-        CAGG_MAX_TIME_RANGE_DAYS
-    } else {
+    if !is_hourly_cagg_eligible_query(&ast.entity, ast.stats.is_some(), ast.downsample.is_some()) {
         90
+    } else if daily_rollup_table_for_entity(&ast.entity).is_some() {
+        DAILY_ROLLUP_MAX_TIME_RANGE_DAYS
+    } else {
+        CAGG_MAX_TIME_RANGE_DAYS
     }
 }
 
@@ -739,74 +846,85 @@ pub fn translate_request(config: &AppConfig, request: QueryRequest) -> Result<Tr
     let plan = build_query_plan(config, &request, ast)?;
     let viz = viz::meta_for_plan(&plan);
 
-    let (sql, params) = if plan.downsample.is_some() {
-        downsample::to_sql_and_params(&plan)?
+    let (sql, params) = plan_sql(config, &plan)?;
+
+    let next_cursor = Some(encode_cursor(plan.offset.saturating_add(plan.limit)));
+    let prev_cursor = if plan.offset > 0 {
+        Some(encode_cursor(plan.offset.saturating_sub(plan.limit)))
+    } else {
+        None
+    };
+
+    Ok(TranslateResponse {
+        backend: explain::QueryBackend::for_entity(&plan.entity),
+        sql,
+        params,
+        pagination: PaginationMeta {
+            next_cursor,
+            prev_cursor,
+            limit: Some(plan.limit),
+        },
+        downsample: downsample::meta_for_plan(&plan),
+        viz,
+    })
+}
+
+/// Builds the SQL and bind parameters the engine would run for `plan`.
+pub(super) fn plan_sql(config: &AppConfig, plan: &QueryPlan) -> Result<(String, Vec<BindParam>)> {
+    let sql_and_params = if plan.downsample.is_some() {
+        downsample::to_sql_and_params(plan)?
     } else {
         match plan.entity {
-            Entity::Agents => agents::to_sql_and_params(&plan)?,
-            Entity::Devices => devices::to_sql_and_params(&plan)?,
-            Entity::DeviceUpdates => device_updates::to_sql_and_params(&plan)?,
-            Entity::DeviceGraph => device_graph::to_sql_and_params(&plan)?,
-            Entity::GraphCypher => graph_cypher::to_sql_and_params(&plan, &config.age_graph_name)?,
-            Entity::Events => events::to_sql_and_params(&plan)?,
-            Entity::BmpEvents => bmp_events::to_sql_and_params(&plan)?,
+            Entity::Agents => agents::to_sql_and_params(plan)?,
+            Entity::Devices => devices::to_sql_and_params(plan)?,
+            Entity::DeviceUpdates => device_updates::to_sql_and_params(plan)?,
+            Entity::DeviceGraph => device_graph::to_sql_and_params(plan)?,
+            Entity::GraphCypher => graph_cypher::to_sql_and_params(plan, &config.age_graph_name)?,
+            Entity::Events => events::to_sql_and_params(plan)?,
+            Entity::BmpEvents => bmp_events::to_sql_and_params(plan)?,
             Entity::FieldSurveySessions
             | Entity::FieldSurveyRasters
             | Entity::FieldSurveyArtifacts
             | Entity::FieldSurveyRfObservations
             | Entity::FieldSurveyPoseSamples
             | Entity::FieldSurveyRfPoseMatches
-            | Entity::FieldSurveySpectrumObservations => field_survey::to_sql_and_params(&plan)?,
+            | Entity::FieldSurveySpectrumObservations => field_survey::to_sql_and_params(plan)?,
             Entity::WifiSites
             | Entity::WifiSiteSnapshots
             | Entity::WifiAccessPoints
             | Entity::WifiControllers
             | Entity::WifiRadiusGroups
             | Entity::WifiFleetHistory
-            | Entity::WifiSiteReferences => wifi_map::to_sql_and_params(&plan)?,
-            Entity::Flows => flows::to_sql_and_params(&plan)?,
-            Entity::Interfaces => interfaces::to_sql_and_params(&plan)?,
-            Entity::Logs => logs::to_sql_and_params(&plan)?,
-            Entity::Gateways => gateways::to_sql_and_params(&plan)?,
-            Entity::OtelMetrics => otel_metrics::to_sql_and_params(&plan)?,
+            | Entity::WifiSiteReferences => wifi_map::to_sql_and_params(plan)?,
+            Entity::Flows => flows::to_sql_and_params(plan)?,
+            Entity::Interfaces => interfaces::to_sql_and_params(plan)?,
+            Entity::Logs => logs::to_sql_and_params(plan)?,
+            Entity::Gateways => gateways::to_sql_and_params(plan)?,
+            Entity::OtelMetrics => otel_metrics::to_sql_and_params(plan)?,
             Entity::RperfMetrics | Entity::TimeseriesMetrics | Entity::SnmpMetrics => {
-                timeseries_metrics::to_sql_and_params(&plan)?
+                timeseries_metrics::to_sql_and_params(plan)?
             }
-            Entity::CpuMetrics => cpu_metrics::to_sql_and_params(&plan)?,
-            Entity::MemoryMetrics => memory_metrics::to_sql_and_params(&plan)?,
-            Entity::DiskMetrics => disk_metrics::to_sql_and_params(&plan)?,
-            Entity::ProcessMetrics => process_metrics::to_sql_and_params(&plan)?,
-            Entity::Services => services::to_sql_and_params(&plan)?,
-            Entity::TraceSummaries => trace_summaries::to_sql_and_params(&plan)?,
-            Entity::Traces => traces::to_sql_and_params(&plan)?,
-            Entity::Alerts => alerts::to_sql_and_params(&plan)?,
+            Entity::CpuMetrics => cpu_metrics::to_sql_and_params(plan)?,
+            Entity::MemoryMetrics => memory_metrics::to_sql_and_params(plan)?,
+            Entity::DiskMetrics => disk_metrics::to_sql_and_params(plan)?,
+            Entity::NetworkMetrics => network_metrics::to_sql_and_params(plan)?,
+            Entity::ProcessMetrics => process_metrics::to_sql_and_params(plan)?,
+            Entity::Services => services::to_sql_and_params(plan)?,
+            Entity::TraceSummaries => trace_summaries::to_sql_and_params(plan)?,
+            Entity::Traces => traces::to_sql_and_params(plan)?,
+            Entity::Alerts => alerts::to_sql_and_params(plan)?,
+            Entity::Certificates => certificates::to_sql_and_params(plan)?,
         }
     };
 
-    let next_cursor = Some(encode_cursor(plan.offset.saturating_add(plan.limit)));
-    let prev_cursor = if plan.offset > 0 {
-        Some(encode_cursor(plan.offset.saturating_sub(plan.limit)))
-    } else {
-        None
-    };
-
-    Ok(TranslateResponse {
-        sql,
-        params,
-        pagination: PaginationMeta {
-            next_cursor,
-            prev_cursor,
-            limit: Some(plan.limit),
-        },
-        viz,
-    })
+    Ok(sql_and_params)
 }
 
 #[cfg(test)]
 mod tests {
     use super::{devices, gateways, interfaces, *};
     use crate::parser::{self, FilterOp, FilterValue, OrderDirection};
-    use std::time::Duration as StdDuration;
+    use std::{collections::HashMap, time::Duration as StdDuration};
 
     #[test]
     fn devices_docs_example_available_true() {
@@ -925,6 +1043,153 @@ mod tests {
             .any(|param| { matches!(param, BindParam::Text(value) if value == "10.0.0.50") }));
     }
 
+    #[test]
+    fn devices_in_subquery_generates_subselect() {
+        let query = "in:devices device_id:(in:events severity:Critical select:device_id)";
+        let plan = plan_for(query);
+
+        let (sql, params) = devices::to_sql_and_params(&plan).expect("should build devices SQL");
+
+        assert!(
+            sql.contains("uid IN (SELECT s.device->>'uid' FROM ocsf_events s"),
+            "expected IN subquery, got: {sql}"
+        );
+        assert!(sql.contains("s.severity = p.v[1]"), "got: {sql}");
+        assert!(params.iter().any(|param| {
+            matches!(param, BindParam::TextArray(values) if values == &vec!["Critical".to_string()])
+        }));
+        assert_eq!(max_dollar_placeholder(&sql), params.len());
+    }
+
+    #[test]
+    fn devices_not_in_subquery_is_negated() {
+        let plan = plan_for("in:devices !uid:(in:events severity_id:>=4 select:device_id)");
+
+        let (sql, _params) = devices::to_sql_and_params(&plan).expect("should build devices SQL");
+
+        assert!(sql.contains("NOT (uid IN (SELECT"), "got: {sql}");
+        assert!(sql.contains("s.severity_id >= p.v[1]::int4"), "got: {sql}");
+    }
+
+    #[test]
+    fn devices_grouped_stats_support_subqueries() {
+        let plan = plan_for(
+            "in:devices uid:(in:events log_name:(syslog,snmp) select:device_id) stats:count() as total by type",
+        );
+
+        let (sql, params) = devices::to_sql_and_params(&plan).expect("should build stats SQL");
+
+        assert!(sql.contains("uid IN (SELECT"), "got: {sql}");
+        assert!(sql.contains("s.log_name = ANY(p.v[1:2])"), "got: {sql}");
+        assert_eq!(max_dollar_placeholder(&sql), params.len());
+    }
+
+    fn float_params(params: &[BindParam]) -> Vec<f64> {
+        params
+            .iter()
+            .filter_map(|param| match param {
+                BindParam::Float(value) => Some(*value),
+                _ => None,
+            })
+            .collect()
+    }
+
+    #[test]
+    fn trace_summaries_percentile_stats_use_percentile_cont() {
+        let plan = plan_for(
+            r#"in:otel_trace_summaries time:last_1h stats:"percentile(duration_ms, 99) as p99, count() as total""#,
+        );
+
+        let (sql, params) =
+            trace_summaries::to_sql_and_params(&plan).expect("should build stats SQL");
+
+        assert!(
+            sql.contains("'p99', percentile_cont($1) WITHIN GROUP (ORDER BY duration_ms)"),
+            "got: {sql}"
+        );
+        assert_eq!(float_params(&params), vec![0.99]);
+        assert_eq!(max_dollar_placeholder(&sql), params.len());
+    }
+
+    #[test]
+    fn trace_summaries_filter_above_percentile_threshold() {
+        let plan = plan_for(
+            "in:otel_trace_summaries time:last_1h root_service_name:checkout duration_ms:>p99",
+        );
+
+        let (sql, params) =
+            trace_summaries::to_sql_and_params(&plan).expect("should build threshold SQL");
+
+        assert!(
+            sql.contains("duration_ms > (SELECT percentile_cont($"),
+            "expected a percentile subquery, got: {sql}"
+        );
+        assert!(
+            sql.contains("FROM otel_trace_summaries WHERE timestamp >= $"),
+            "threshold should be scoped to the time range, got: {sql}"
+        );
+        assert_eq!(
+            sql.matches("root_service_name = $").count(),
+            2,
+            "threshold should be scoped to the other filters, got: {sql}"
+        );
+        assert_eq!(float_params(&params), vec![0.99]);
+        assert_eq!(max_dollar_placeholder(&sql), params.len());
+    }
+
+    #[test]
+    fn traces_filter_above_percentile_threshold() {
+        let plan = plan_for("in:traces time:last_1h service_name:checkout duration_ms:>=p95");
+
+        let (sql, params) = traces::to_sql_and_params(&plan).expect("should build threshold SQL");
+
+        assert!(sql.contains("FROM otel_traces"), "got: {sql}");
+        assert!(
+            sql.contains("::float8 >= (SELECT percentile_cont($"),
+            "expected a percentile subquery, got: {sql}"
+        );
+        assert_eq!(sql.matches("service_name = $").count(), 2, "got: {sql}");
+        assert!(
+            sql.ends_with("ORDER BY timestamp DESC\nLIMIT $8 OFFSET $9"),
+            "got: {sql}"
+        );
+        assert_eq!(float_params(&params), vec![0.95]);
+        assert_eq!(max_dollar_placeholder(&sql), params.len());
+    }
+
+    #[test]
+    fn traces_percentile_stats_use_span_duration() {
+        let plan = plan_for(
+            r#"in:traces time:last_1h stats:"percentile(duration_ms, 99) as p99, count() as spans""#,
+        );
+
+        let (sql, params) = traces::to_sql_and_params(&plan).expect("should build stats SQL");
+
+        assert!(
+            sql.starts_with("SELECT jsonb_build_object('p99', percentile_cont($1) WITHIN GROUP"),
+            "got: {sql}"
+        );
+        assert!(sql.contains("'spans', count(*)"), "got: {sql}");
+        assert_eq!(float_params(&params), vec![0.99]);
+        assert_eq!(max_dollar_placeholder(&sql), params.len());
+
+        let invalid = plan_for(r#"in:traces stats:"percentile(service_name, 99) as p99""#);
+        assert!(traces::to_sql_and_params(&invalid).is_err());
+    }
+
+    #[test]
+    fn traces_duration_filter_compares_span_duration() {
+        let plan = plan_for("in:traces time:last_1h duration_ms:>250");
+
+        let (sql, params) = traces::to_sql_and_params(&plan).expect("should build traces SQL");
+
+        assert!(
+            sql.contains("end_time_unix_nano - start_time_unix_nano"),
+            "got: {sql}"
+        );
+        assert_eq!(float_params(&params), vec![250.0]);
+    }
+
     #[test]
     fn services_docs_example_service_type_timeframe() {
         let query =
@@ -1031,6 +1296,11 @@ mod tests {
             request_timeout: StdDuration::from_secs(30),
             rate_limit_max_requests: 120,
             rate_limit_window: StdDuration::from_secs(60),
+            downsample_threshold: StdDuration::from_secs(7 * 24 * 60 * 60),
+            downsample_target_points: 1000,
+            max_result_rows: 0,
+            admin_api_key: None,
+            default_sort: HashMap::new(),
         }
     }
 
@@ -1208,6 +1478,147 @@ mod tests {
         );
     }
 
+    #[test]
+    fn wide_metric_ranges_are_downsampled_automatically() {
+        let plan = plan_for("in:cpu_metrics device_id:\"sr:device-1\" time:last_30d");
+        let spec = plan
+            .downsample
+            .as_ref()
+            .expect("30d cpu query should be downsampled");
+        assert!(spec.auto);
+        assert_eq!(spec.agg, crate::parser::DownsampleAgg::Avg);
+        assert_eq!(spec.bucket_seconds, 3600);
+
+        let meta = downsample::meta_for_plan(&plan).unwrap();
+        assert_eq!(meta.bucket, "1h");
+        assert!(meta.auto);
+
+        let narrow = plan_for("in:cpu_metrics time:last_24h");
+        assert!(narrow.downsample.is_none());
+
+        let stats = plan_for("in:cpu_metrics time:last_30d stats:\"avg(usage_percent) as cpu\"");
+        assert!(stats.downsample.is_none());
+    }
+
+    #[test]
+    fn explicit_auto_bucket_resolves_against_time_range() {
+        let plan = plan_for("in:memory_metrics time:last_24h bucket:auto agg:max");
+        let spec = plan.downsample.as_ref().unwrap();
+        assert!(spec.auto);
+        assert_eq!(spec.agg, crate::parser::DownsampleAgg::Max);
+        assert_eq!(spec.bucket_seconds, 300);
+
+        let explicit = plan_for("in:memory_metrics time:last_24h bucket:15m agg:min");
+        let meta = downsample::meta_for_plan(&explicit).unwrap();
+        assert_eq!(meta.bucket_seconds, 900);
+        assert_eq!(meta.bucket, "15m");
+        assert!(!meta.auto);
+
+        let config = test_config();
+        let query = "in:memory_metrics bucket:auto";
+        let ast = parser::parse(query).unwrap();
+        let request = QueryRequest {
+            query: query.to_string(),
+            limit: None,
+            cursor: None,
+            direction: QueryDirection::Next,
+            mode: None,
+        };
+        assert!(build_query_plan(&config, &request, ast).is_err());
+    }
+
+    #[test]
+    fn auto_bucket_keeps_point_count_bounded() {
+        let target = 1000;
+        let end = chrono::DateTime::parse_from_rfc3339("2026-03-14T15:09:26Z")
+            .unwrap()
+            .with_timezone(&Utc);
+
+        for days in [1, 7, 30, 90, 395] {
+            let range = TimeRange {
+                start: end - ChronoDuration::days(days),
+                end,
+            };
+            let bucket = downsample::auto_bucket_seconds(&range, target);
+
+            let first = range.start.timestamp().div_euclid(bucket);
+            let last = range.end.timestamp().div_euclid(bucket);
+            let buckets = last - first + 1;
+            assert!(
+                buckets <= target,
+                "{days}d range produced {buckets} buckets of {bucket}s"
+            );
+        }
+    }
+
+    #[test]
+    fn translate_reports_effective_bucket() {
+        let config = test_config();
+        let request = QueryRequest {
+            query: "in:timeseries_metrics time:last_30d".to_string(),
+            limit: None,
+            cursor: None,
+            direction: QueryDirection::Next,
+            mode: None,
+        };
+
+        let response = translate_request(&config, request).unwrap();
+        assert!(response.sql.contains("to_timestamp(floor("));
+        let meta = response.downsample.expect("downsample metadata");
+        assert_eq!(meta.bucket_seconds, 3600);
+        assert!(meta.auto);
+    }
+
+    #[test]
+    fn translate_between_emits_inclusive_ranges() {
+        let config = test_config();
+        let request = QueryRequest {
+            query: "in:cpu_metrics time BETWEEN 2025-01-01T00:00:00Z AND 2025-01-01T04:00:00Z \
+                    usage_percent BETWEEN 10 AND 90"
+                .to_string(),
+            limit: None,
+            cursor: None,
+            direction: QueryDirection::Next,
+            mode: None,
+        };
+
+        let response = translate_request(&config, request).unwrap();
+        let sql = response.sql.to_lowercase();
+        assert!(
+            sql.contains("usage_percent\" >= ") && sql.contains("usage_percent\" <= "),
+            "expected inclusive usage_percent bounds, got: {}",
+            response.sql
+        );
+        assert!(
+            sql.contains("timestamp\" >= ") && sql.contains("timestamp\" <= "),
+            "expected inclusive time bounds, got: {}",
+            response.sql
+        );
+
+        let floats: Vec<f64> = response
+            .params
+            .iter()
+            .filter_map(|param| match param {
+                BindParam::Float(value) => Some(*value),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(floats, vec![10.0, 90.0]);
+
+        let timestamps: Vec<&str> = response
+            .params
+            .iter()
+            .filter_map(|param| match param {
+                BindParam::Timestamptz(value) => Some(value.as_str()),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(
+            timestamps,
+            vec!["2025-01-01T00:00:00+00:00", "2025-01-01T04:00:00+00:00"]
+        );
+    }
+
     #[test]
     fn translate_flows_downsample_emits_time_bucket_query() {
         let config = crate::config::AppConfig::embedded("postgres://unused/db".to_string());
@@ -1357,6 +1768,99 @@ mod tests {
         );
     }
 
+    #[test]
+    fn devices_lifecycle_state_filter_uses_metadata() {
+        let plan = plan_for("in:devices lifecycle_state:decommissioned");
+
+        let (sql, params) =
+            devices::to_sql_and_params(&plan).expect("lifecycle filter should translate");
+        assert!(
+            sql.contains("coalesce(metadata->>'lifecycle_state', 'active') = "),
+            "expected lifecycle state expression, got: {sql}"
+        );
+        assert!(
+            matches!(params.first(), Some(BindParam::Text(value)) if value == "decommissioned"),
+            "expected lifecycle state bind, got: {params:?}"
+        );
+    }
+
+    #[test]
+    fn devices_health_rollup_filter_falls_back_to_availability() {
+        let plan = plan_for("in:devices health_rollup:(degraded,unhealthy)");
+
+        let (sql, params) =
+            devices::to_sql_and_params(&plan).expect("health rollup filter should translate");
+        assert!(
+            sql.contains("coalesce(metadata->>'health_rollup', CASE WHEN coalesce(is_available, false) THEN 'healthy' ELSE 'unhealthy' END) = ANY("),
+            "expected health rollup expression, got: {sql}"
+        );
+        assert!(
+            matches!(params.first(), Some(BindParam::TextArray(values)) if values == &["degraded", "unhealthy"]),
+            "expected health rollup binds, got: {params:?}"
+        );
+    }
+
+    #[test]
+    fn devices_stats_filter_by_lifecycle_state() {
+        let plan = plan_for(
+            "in:devices !lifecycle_state:(decommissioned,stale) stats:\"count() as total\" by type",
+        );
+
+        let (sql, _) =
+            devices::to_sql_and_params(&plan).expect("lifecycle stats filter should translate");
+        assert!(
+            sql.contains("NOT (coalesce(metadata->>'lifecycle_state', 'active') = ANY("),
+            "expected negated lifecycle list filter, got: {sql}"
+        );
+    }
+
+    #[test]
+    fn devices_stats_count_distinct() {
+        let query = "in:devices is_available:true stats:\"count(distinct vendor_name) as vendors\"";
+        let plan = plan_for(query);
+
+        let (sql, params) =
+            devices::to_sql_and_params(&plan).expect("should build count distinct SQL");
+        let lower = sql.to_lowercase();
+        assert!(
+            lower.contains("count(distinct vendor_name)"),
+            "expected COUNT(DISTINCT vendor_name) in SQL, got: {sql}"
+        );
+        assert!(
+            !lower.contains("count(*)"),
+            "count distinct must not fall back to COUNT(*), got: {sql}"
+        );
+        assert_eq!(
+            params.len(),
+            1,
+            "expected is_available bind, got: {params:?}"
+        );
+    }
+
+    #[test]
+    fn devices_stats_count_distinct_group_by_type() {
+        let query = "in:devices stats:\"count(distinct gateway_id) as gateways\" by type";
+        let plan = plan_for(query);
+
+        let (sql, _) = devices::to_sql_and_params(&plan).expect("should build grouped stats SQL");
+        let lower = sql.to_lowercase();
+        assert!(
+            lower.contains("'gateways', count(distinct gateway_id)"),
+            "expected distinct aggregate in payload, got: {sql}"
+        );
+        assert!(
+            lower.contains("order by count(distinct gateway_id) desc"),
+            "expected ORDER BY on distinct aggregate, got: {sql}"
+        );
+    }
+
+    #[test]
+    fn devices_stats_count_distinct_rejects_unknown_field() {
+        let plan = plan_for("in:devices stats:\"count(distinct metadata) as n\"");
+        let err = devices::to_sql_and_params(&plan).unwrap_err();
+        assert!(matches!(err, ServiceError::InvalidRequest(_)));
+    }
+
     #[test]
     fn devices_stats_group_by_availability() {
         let query = "in:devices stats:count() as count by is_available";
@@ -1393,6 +1897,82 @@ mod tests {
         );
     }
 
+    #[test]
+    fn devices_filter_by_owner() {
+        let plan = plan_for("in:devices owner:team-netops owner_type:team");
+
+        let (sql, params) =
+            devices::to_sql_and_params(&plan).expect("owner filter should translate");
+        let lower = sql.to_lowercase();
+        assert!(
+            lower.contains("\"ocsf_devices\".\"owner_uid\" = $1"),
+            "expected owner_uid predicate, got: {sql}"
+        );
+        assert!(
+            lower.contains("\"ocsf_devices\".\"owner_type\" = $2"),
+            "expected owner_type predicate, got: {sql}"
+        );
+        assert!(
+            matches!(params.first(), Some(BindParam::Text(value)) if value == "team-netops"),
+            "expected owner bind, got: {params:?}"
+        );
+    }
+
+    #[test]
+    fn devices_exclude_archived_by_default() {
+        let plan = plan_for("in:devices");
+
+        let (sql, _) = devices::to_sql_and_params(&plan).expect("devices query should translate");
+        assert!(
+            sql.to_lowercase()
+                .contains("\"ocsf_devices\".\"archived_at\" is null"),
+            "expected archived devices to be excluded, got: {sql}"
+        );
+    }
+
+    #[test]
+    fn devices_include_archived_modifier() {
+        let plan = plan_for("in:devices include_archived:true");
+
+        let (sql, params) =
+            devices::to_sql_and_params(&plan).expect("include_archived should translate");
+        assert!(
+            !sql.to_lowercase().contains("\"archived_at\" is"),
+            "expected no archived predicate, got: {sql}"
+        );
+        assert!(
+            !params
+                .iter()
+                .any(|param| matches!(param, BindParam::Bool(_))),
+            "include_archived should not bind a value, got: {params:?}"
+        );
+
+        let plan = plan_for("in:devices archived:true");
+        let (sql, _) = devices::to_sql_and_params(&plan).expect("archived filter should translate");
+        assert!(
+            sql.to_lowercase()
+                .contains("\"ocsf_devices\".\"archived_at\" is not null"),
+            "expected archived-only predicate, got: {sql}"
+        );
+    }
+
+    #[test]
+    fn devices_stats_group_by_owner() {
+        let plan = plan_for("in:devices owner:(alice,team-netops) stats:count() as count by owner");
+
+        let (sql, params) =
+            devices::to_sql_and_params(&plan).expect("owner grouped stats should translate");
+        assert!(
+            sql.contains("owner_uid = ANY("),
+            "expected owner list filter, got: {sql}"
+        );
+        assert!(
+            sql.contains("COALESCE(owner_uid, 'unassigned')"),
+            "expected owner grouping column, got: {sql}"
+        );
+        assert_eq!(params.len(), 1, "expected a single owner list bind");
+    }
+
     #[test]
     fn devices_stats_group_by_unsupported_field_returns_error() {
         let query = "in:devices stats:count() as count by hostname";
@@ -1407,6 +1987,80 @@ mod tests {
         );
     }
 
+    #[test]
+    fn devices_stats_group_by_multiple_fields() {
+        let plan =
+            plan_for("in:devices stats:\"count() as count by manufacturer, type\" sort:type:asc");
+
+        let (sql, params) =
+            devices::to_sql_and_params(&plan).expect("multi-field grouped stats should translate");
+        assert!(
+            sql.contains("jsonb_build_object('vendor_name', COALESCE(vendor_name, 'Unknown'), 'type', COALESCE(type, 'Unknown'), 'count', COUNT(*))"),
+            "expected one payload key per group field, got: {sql}"
+        );
+        assert!(
+            sql.contains("GROUP BY COALESCE(vendor_name, 'Unknown'), COALESCE(type, 'Unknown')"),
+            "expected GROUP BY over both fields, got: {sql}"
+        );
+        assert!(
+            sql.contains("ORDER BY COALESCE(type, 'Unknown') ASC"),
+            "expected sort by a group field, got: {sql}"
+        );
+        assert!(params.is_empty());
+    }
+
+    #[test]
+    fn devices_stats_numeric_aggregates() {
+        let plan = plan_for("in:devices stats:\"avg(risk_score) as avg_risk by type\"");
+        let (sql, _) = devices::to_sql_and_params(&plan).expect("grouped avg should translate");
+        assert!(
+            sql.contains("'avg_risk', AVG(risk_score)") && sql.contains("GROUP BY"),
+            "expected grouped AVG, got: {sql}"
+        );
+
+        // Without `by` the aggregate is a single row.
+        let plan = plan_for("in:devices is_available:true stats:\"max(risk_score) as max_risk\"");
+        let (sql, params) =
+            devices::to_sql_and_params(&plan).expect("ungrouped max should translate");
+        assert!(
+            sql.contains("jsonb_build_object('max_risk', MAX(risk_score))"),
+            "expected MAX payload, got: {sql}"
+        );
+        assert!(
+            !sql.contains("GROUP BY") && !sql.contains("LIMIT"),
+            "ungrouped aggregate should return a single row, got: {sql}"
+        );
+        assert_eq!(params.len(), 1, "expected the availability bind");
+
+        let plan = plan_for("in:devices stats:\"sum(hostname) as n\"");
+        let err = devices::to_sql_and_params(&plan).unwrap_err();
+        assert!(
+            err.to_string()
+                .contains("unsupported sum() field 'hostname'"),
+            "got: {err}"
+        );
+    }
+
+    #[test]
+    fn devices_stats_rejects_ungrouped_fields() {
+        for query in [
+            "in:devices stats:\"count() as total hostname\"",
+            "in:devices stats:\"hostname, count() as total by type\"",
+        ] {
+            let plan = plan_for(query);
+            let err = devices::to_sql_and_params(&plan).unwrap_err();
+            assert!(
+                err.to_string()
+                    .contains("field 'hostname' must be aggregated or listed after 'by'"),
+                "{query}: got {err}"
+            );
+        }
+
+        let plan = plan_for("in:devices stats:\"type, count() as total by type\"");
+        let (sql, _) = devices::to_sql_and_params(&plan).expect("grouped fields may be selected");
+        assert!(sql.contains("GROUP BY COALESCE(type, 'Unknown')"));
+    }
+
     #[test]
     fn cagg_routing_threshold_boundary() {
         let now = chrono::Utc::now();
@@ -1433,6 +2087,52 @@ mod tests {
         ));
     }
 
+    #[test]
+    fn daily_rollup_routing_threshold_boundary() {
+        let now = chrono::Utc::now();
+        let under = TimeRange {
+            start: now - ChronoDuration::days(29) - ChronoDuration::hours(23),
+            end: now,
+        };
+        let at = TimeRange {
+            start: now - ChronoDuration::days(30),
+            end: now,
+        };
+
+        assert!(!should_route_to_daily_rollup(
+            &Entity::CpuMetrics,
+            Some(&under)
+        ));
+        assert!(should_route_to_daily_rollup(&Entity::CpuMetrics, Some(&at)));
+        assert!(should_route_to_daily_rollup(
+            &Entity::SnmpMetrics,
+            Some(&at)
+        ));
+        assert!(!should_route_to_daily_rollup(&Entity::Flows, Some(&at)));
+        assert!(!should_route_to_daily_rollup(&Entity::CpuMetrics, None));
+    }
+
+    #[test]
+    fn aggregate_metric_query_allows_two_year_timeframe_with_daily_rollups() {
+        let config = test_config();
+        let query = "in:timeseries_metrics time:last_700d stats:avg(value) as avg_value";
+        let ast = parser::parse(query).expect("query should parse");
+        let request = QueryRequest {
+            query: query.to_string(),
+            limit: None,
+            cursor: None,
+            direction: QueryDirection::Next,
+            mode: None,
+        };
+
+        let plan = build_query_plan(&config, &request, ast)
+            .expect("stats metric query should allow daily rollup range");
+        assert_eq!(
+            metric_rollup_table_for_plan(&plan),
+            Some("timeseries_metrics_daily")
+        );
+    }
+
     #[test]
     fn aggregate_metric_query_allows_one_year_timeframe() {
         let config = test_config();
@@ -1531,6 +2231,63 @@ mod tests {
         );
     }
 
+    #[test]
+    fn cpu_stats_over_long_windows_route_to_daily_rollup() {
+        let config = test_config();
+        let request = QueryRequest {
+            query: "in:cpu_metrics time:last_90d stats:avg(usage_percent) as avg_usage".into(),
+            limit: None,
+            cursor: None,
+            direction: QueryDirection::Next,
+            mode: None,
+        };
+
+        let response =
+            translate_request(&config, request).expect("long-window cpu stats should translate");
+        let sql = response.sql.to_lowercase();
+        assert!(
+            sql.contains("from cpu_metrics_daily") && sql.contains("sample_count"),
+            "expected daily rollup source for 90d stats query, got: {}",
+            response.sql
+        );
+    }
+
+    #[test]
+    fn downsample_picks_rollup_resolution_from_bucket() {
+        let config = test_config();
+        let translate = |query: &str| {
+            let request = QueryRequest {
+                query: query.into(),
+                limit: None,
+                cursor: None,
+                direction: QueryDirection::Next,
+                mode: None,
+            };
+            translate_request(&config, request)
+                .expect("downsample query should translate")
+                .sql
+                .to_lowercase()
+        };
+
+        let daily = translate("in:cpu_metrics time:last_60d bucket:1d agg:avg");
+        assert!(
+            daily.contains("from cpu_metrics_daily"),
+            "expected daily rollup for 1d buckets, got: {daily}"
+        );
+
+        let hourly = translate("in:cpu_metrics time:last_60d bucket:6h agg:avg");
+        assert!(
+            hourly.contains("from cpu_metrics_hourly"),
+            "expected hourly CAGG for sub-day buckets, got: {hourly}"
+        );
+
+        let uneven = translate("in:cpu_metrics time:last_60d bucket:36h agg:avg");
+        assert!(
+            uneven.contains("from cpu_metrics_hourly"),
+            "expected hourly CAGG for buckets that are not whole days, got: {uneven}"
+        );
+    }
+
     #[test]
     fn cpu_stats_without_alias_translates_and_routes_to_cagg() {
         let config = test_config();
@@ -1556,6 +2313,142 @@ mod tests {
             response.sql
         );
     }
+
+    fn order_fields(plan: &QueryPlan) -> Vec<(&str, bool)> {
+        plan.order
+            .iter()
+            .map(|clause| {
+                let desc = matches!(clause.direction, OrderDirection::Desc);
+                (clause.field.as_str(), desc)
+            })
+            .collect()
+    }
+
+    #[test]
+    fn default_sort_is_injected_when_sort_is_absent() {
+        let devices = plan_for("in:devices is_available:true");
+        assert_eq!(order_fields(&devices), vec![("last_seen", true)]);
+
+        let logs = plan_for("in:logs time:last_24h");
+        assert_eq!(
+            order_fields(&logs),
+            vec![("timestamp", true), ("severity_number", true)]
+        );
+
+        let (sql, _) = devices::to_sql_and_params(&devices).expect("should build devices SQL");
+        assert!(
+            sql.to_lowercase().contains("\"last_seen_time\" desc"),
+            "expected default last_seen ordering, got: {sql}"
+        );
+    }
+
+    #[test]
+    fn default_sort_is_not_injected_when_sort_is_given() {
+        let plan = plan_for("in:devices sort:ip:asc");
+        assert_eq!(order_fields(&plan), vec![("ip", false)]);
+
+        let plan = plan_for("in:logs sort:severity_number:asc");
+        assert_eq!(order_fields(&plan), vec![("severity_number", false)]);
+    }
+
+    #[test]
+    fn default_sort_skips_stats_and_unconfigured_entities() {
+        let stats = plan_for("in:devices stats:count() as total");
+        assert!(stats.order.is_empty());
+
+        let graph = plan_for("in:device_graph device_id:sr:abc");
+        assert!(graph.order.is_empty());
+    }
+
+    #[test]
+    fn stats_sort_resolves_aliases_and_aggregate_expressions() {
+        let by_alias =
+            plan_for("in:devices stats:\"count() as total by vendor_name\" sort:total:asc");
+        let by_expr =
+            plan_for("in:devices stats:\"count() as total by vendor_name\" sort:count():asc");
+        assert_eq!(order_fields(&by_alias), vec![("total", false)]);
+        assert_eq!(order_fields(&by_expr), vec![("total", false)]);
+
+        for plan in [&by_alias, &by_expr] {
+            let (sql, _) = devices::to_sql_and_params(plan).expect("should build devices SQL");
+            assert!(
+                sql.contains("ORDER BY COUNT(*) ASC"),
+                "expected alias resolved to its aggregate, got: {sql}"
+            );
+        }
+
+        let flows = plan_for(
+            "in:flows time:last_1h stats:\"sum(bytes_total) as bytes, sum(packets_total) as packets by src_endpoint_ip\" sort:sum(packets_total):desc",
+        );
+        assert_eq!(order_fields(&flows), vec![("packets", true)]);
+        let (sql, _) = flows::to_sql_and_params(&flows).expect("should build flows SQL");
+        assert!(
+            sql.contains("ORDER BY agg_value_1 DESC"),
+            "expected aggregate expression ordered through its aliased column, got: {sql}"
+        );
+    }
+
+    #[test]
+    fn stats_sort_rejects_fields_outside_select_list() {
+        let config = test_config();
+        let build = |query: &str| {
+            let request = QueryRequest {
+                query: query.to_string(),
+                limit: None,
+                cursor: None,
+                direction: QueryDirection::Next,
+                mode: None,
+            };
+            build_query_plan(&config, &request, parser::parse(query).unwrap())
+        };
+
+        let err =
+            build("in:devices stats:\"count() as total by type\" sort:count(distinct ip):desc")
+                .expect_err("aggregate not in the select list");
+        assert!(err
+            .to_string()
+            .contains("does not match any stats aggregation"));
+
+        let err = build("in:devices stats:\"count() as total\" sort:totl:desc")
+            .expect_err("unknown alias on ungrouped stats");
+        assert!(err.to_string().contains("not a stats alias"));
+
+        let grouped = build("in:devices stats:\"count() as total by type\" sort:type:asc")
+            .expect("group columns are left to the query module");
+        assert_eq!(order_fields(&grouped), vec![("type", false)]);
+    }
+
+    #[test]
+    fn default_sort_uses_config_overrides() {
+        let mut config = test_config();
+        config.default_sort.insert(
+            Entity::Devices,
+            vec![OrderClause {
+                field: "uid".to_string(),
+                direction: OrderDirection::Asc,
+            }],
+        );
+        config.default_sort.insert(Entity::Logs, Vec::new());
+
+        let plan_with = |query: &str| {
+            let request = QueryRequest {
+                query: query.to_string(),
+                limit: None,
+                cursor: None,
+                direction: QueryDirection::Next,
+                mode: None,
+            };
+            let ast = parser::parse(query).expect("query should parse");
+            build_query_plan(&config, &request, ast).expect("should build plan")
+        };
+
+        assert_eq!(order_fields(&plan_with("in:devices")), vec![("uid", false)]);
+        assert!(plan_with("in:logs").order.is_empty());
+        assert_eq!(
+            order_fields(&plan_with("in:devices sort:last_seen:asc")),
+            vec![("last_seen", false)]
+        );
+    }
 }
 
 #[derive(Debug, Clone)]
@@ -1631,15 +2524,41 @@ pub struct QueryResponse {
     pub results: Vec<Value>,
     pub pagination: PaginationMeta,
     #[serde(skip_serializing_if = "Option::is_none")]
+    pub downsample: Option<DownsampleMeta>,
+    /// Set when more rows matched than the result cap allows.
+    #[serde(skip_serializing_if = "std::ops::Not::not")]
+    pub truncated: bool,
+    /// Rows the request would have returned without the cap, when known.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub total_available: Option<i64>,
+    /// Set instead of results for `EXPLAIN` queries.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub explain: Option<explain::ExplainMeta>,
+    #[serde(skip_serializing_if = "Option::is_none")]
     pub error: Option<String>,
 }
 
+/// Effective time bucketing of a downsampled response, so clients can label
+/// chart axes without re-deriving the bucket.
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+pub struct DownsampleMeta {
+    pub bucket_seconds: i64,
+    /// Human-readable bucket width such as `5m` or `1h`.
+    pub bucket: String,
+    pub agg: crate::parser::DownsampleAgg,
+    /// True when the bucket was chosen automatically.
+    pub auto: bool,
+}
+
 #[derive(Debug, Clone, Serialize)]
 pub struct TranslateResponse {
+    pub backend: explain::QueryBackend,
     pub sql: String,
     #[serde(skip_serializing_if = "Vec::is_empty", default)]
     pub params: Vec<BindParam>,
     pub pagination: PaginationMeta,
     #[serde(skip_serializing_if = "Option::is_none")]
+    pub downsample: Option<DownsampleMeta>,
+    #[serde(skip_serializing_if = "Option::is_none")]
     pub viz: Option<viz::VizMeta>,
 }
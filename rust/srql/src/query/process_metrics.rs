@@ -413,7 +413,7 @@ fn build_cagg_stats_query(plan: &QueryPlan, spec: &ProcessStatsSpec) -> Result<P
     build_stats_query_with_source(
         plan,
         spec,
-        "process_metrics_hourly",
+        super::metric_rollup_table_for_plan(plan).unwrap_or("process_metrics_hourly"),
         "bucket",
         &agg_expr,
         true,
@@ -0,0 +1,162 @@
+//! Hard cap on the rows a single query may return.
+//!
+//! Capped queries fetch one row past the cap so truncation can be detected
+//! without a second round trip; the total is only counted when the cap is hit.
+
+use super::{plan_sql, BindParam, QueryPlan};
+use crate::{
+    config::AppConfig,
+    error::{Result, ServiceError},
+};
+use diesel::deserialize::QueryableByName;
+use diesel::pg::Pg;
+use diesel::query_builder::{BoxedSqlQuery, SqlQuery};
+use diesel::sql_query;
+use diesel::sql_types::{Array, BigInt, Bool, Float8, Text, Timestamptz};
+use diesel_async::{AsyncPgConnection, RunQueryDsl};
+use tracing::warn;
+
+/// Lowers the plan limit to the cap plus one lookahead row when the request
+/// asks for more than `cap` rows. Returns the originally requested limit when
+/// the cap applies.
+pub(super) fn apply(plan: &mut QueryPlan, cap: Option<i64>) -> Option<i64> {
+    let cap = cap.filter(|cap| *cap > 0)?;
+    if plan.limit <= cap {
+        return None;
+    }
+
+    let requested = plan.limit;
+    plan.limit = cap.saturating_add(1);
+    Some(requested)
+}
+
+/// Drops the lookahead row from the plan limit and returns the cap.
+pub(super) fn restore(plan: &mut QueryPlan) -> i64 {
+    plan.limit = plan.limit.saturating_sub(1).max(1);
+    plan.limit
+}
+
+/// Counts the rows the request would have returned without the cap. Counting
+/// is best effort: failures are logged and reported as an unknown total.
+pub(super) async fn count_available(
+    conn: &mut AsyncPgConnection,
+    config: &AppConfig,
+    plan: &QueryPlan,
+    requested_limit: i64,
+) -> Option<i64> {
+    let mut count_plan = plan.clone();
+    count_plan.limit = requested_limit;
+
+    match count_rows(conn, config, &count_plan).await {
+        Ok(total) => Some(total),
+        Err(err) => {
+            warn!(error = ?err, entity = ?plan.entity, "failed to count truncated query results");
+            None
+        }
+    }
+}
+
+async fn count_rows(
+    conn: &mut AsyncPgConnection,
+    config: &AppConfig,
+    plan: &QueryPlan,
+) -> Result<i64> {
+    let (sql, params) = plan_sql(config, plan)?;
+    let mut query = sql_query(count_sql(&sql)).into_boxed::<Pg>();
+    for param in params {
+        query = bind_param(query, param)?;
+    }
+
+    let row: CountRow = query
+        .get_result(conn)
+        .await
+        .map_err(|err| ServiceError::Internal(err.into()))?;
+
+    Ok(row.total)
+}
+
+fn count_sql(sql: &str) -> String {
+    format!("SELECT COUNT(*) AS total FROM ({sql}) AS capped_query")
+}
+
+fn bind_param<'a>(
+    query: BoxedSqlQuery<'a, Pg, SqlQuery>,
+    param: BindParam,
+) -> Result<BoxedSqlQuery<'a, Pg, SqlQuery>> {
+    match param {
+        BindParam::Text(value) => Ok(query.bind::<Text, _>(value)),
+        BindParam::TextArray(values) => Ok(query.bind::<Array<Text>, _>(values)),
+        BindParam::IntArray(values) => Ok(query.bind::<Array<BigInt>, _>(values)),
+        BindParam::Bool(value) => Ok(query.bind::<Bool, _>(value)),
+        BindParam::Int(value) => Ok(query.bind::<BigInt, _>(value)),
+        BindParam::Float(value) => Ok(query.bind::<Float8, _>(value)),
+        BindParam::Timestamptz(value) => {
+            let timestamp = chrono::DateTime::parse_from_rfc3339(&value)
+                .map(|dt| dt.with_timezone(&chrono::Utc))
+                .map_err(|err| {
+                    ServiceError::Internal(anyhow::anyhow!(
+                        "invalid timestamptz bind {value:?}: {err}"
+                    ))
+                })?;
+            Ok(query.bind::<Timestamptz, _>(timestamp))
+        }
+        BindParam::Uuid(value) => Ok(query.bind::<diesel::sql_types::Uuid, _>(value)),
+    }
+}
+
+#[derive(Debug, QueryableByName)]
+#[diesel(check_for_backend(diesel::pg::Pg))]
+struct CountRow {
+    #[diesel(sql_type = BigInt)]
+    total: i64,
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::parser::Entity;
+
+    fn plan_with_limit(limit: i64) -> QueryPlan {
+        QueryPlan {
+            entity: Entity::Logs,
+            filters: Vec::new(),
+            order: Vec::new(),
+            limit,
+            offset: 0,
+            time_range: None,
+            stats: None,
+            downsample: None,
+            rollup_stats: None,
+            include_deleted: false,
+        }
+    }
+
+    #[test]
+    fn cap_only_applies_above_the_limit() {
+        let mut plan = plan_with_limit(100);
+        assert_eq!(apply(&mut plan, Some(100)), None);
+        assert_eq!(plan.limit, 100);
+
+        assert_eq!(apply(&mut plan, None), None);
+        assert_eq!(apply(&mut plan, Some(0)), None);
+        assert_eq!(plan.limit, 100);
+    }
+
+    #[test]
+    fn cap_fetches_one_lookahead_row() {
+        let mut plan = plan_with_limit(5_000);
+        assert_eq!(apply(&mut plan, Some(1_000)), Some(5_000));
+        assert_eq!(plan.limit, 1_001);
+
+        assert_eq!(restore(&mut plan), 1_000);
+        assert_eq!(plan.limit, 1_000);
+    }
+
+    #[test]
+    fn count_wraps_the_planned_query() {
+        assert_eq!(
+            count_sql("SELECT * FROM logs LIMIT $1"),
+            "SELECT COUNT(*) AS total FROM (SELECT * FROM logs LIMIT $1) AS capped_query"
+        );
+    }
+}
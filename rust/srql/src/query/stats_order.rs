@@ -0,0 +1,53 @@
+//! Resolves `sort:` fields of stats queries against the stats select list.
+//!
+//! `stats:"sum(bytes_total) as bytes by src_ip" sort:bytes:desc` orders by the
+//! alias; `sort:sum(bytes_total):desc` orders by the aggregate directly. Both
+//! are rewritten to the alias here, and each query module then maps the alias
+//! to what its SQL can order by: the aggregate expression itself where the
+//! result is built inline (`ORDER BY COUNT(*) DESC`), or the aliased column of
+//! an inner select (`ORDER BY agg_value_0 DESC`).
+
+use super::QueryPlan;
+use crate::error::{Result, ServiceError};
+
+/// Rewrites aggregate sort fields to their aliases and rejects fields that are
+/// not in the select list. Grouped stats may also order by group columns,
+/// which the query modules validate.
+pub(super) fn apply(plan: &mut QueryPlan) -> Result<()> {
+    let Some(stats) = plan.stats.as_ref() else {
+        return Ok(());
+    };
+    if stats.aggregations.is_empty() {
+        return Ok(());
+    }
+
+    let grouped = stats.group_by().is_some();
+    for clause in &mut plan.order {
+        if let Some(alias) = stats.resolve_order_field(&clause.field) {
+            clause.field = alias.to_string();
+            continue;
+        }
+
+        if clause.field.contains('(') {
+            return Err(ServiceError::InvalidRequest(format!(
+                "sort expression '{}' does not match any stats aggregation",
+                clause.field
+            )));
+        }
+
+        if !grouped {
+            return Err(ServiceError::InvalidRequest(format!(
+                "sort field '{}' is not a stats alias; available: {}",
+                clause.field,
+                stats
+                    .aggregations
+                    .iter()
+                    .map(|aggregation| aggregation.alias.as_str())
+                    .collect::<Vec<_>>()
+                    .join(", ")
+            )));
+        }
+    }
+
+    Ok(())
+}
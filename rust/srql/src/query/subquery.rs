@@ -0,0 +1,254 @@
+//! Translation of `field:(in:<entity> ... select:<field>)` subqueries into the
+//! body of an `IN (SELECT ...)` clause.
+//!
+//! All values used by a subquery travel in a single `text[]` bind exposed as
+//! `p.v`, so the outer query gains exactly one placeholder no matter how many
+//! inner filters there are. Inner conditions index into that array and cast
+//! back to the column type.
+
+use crate::{
+    error::{Result, ServiceError},
+    parser::{Entity, Filter, FilterOp, Subquery},
+};
+use chrono::Utc;
+
+const MAX_SUBQUERY_TIME_RANGE_DAYS: i64 = 90;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum ColumnKind {
+    Text,
+    Int,
+}
+
+struct SubquerySource {
+    table: &'static str,
+    time_column: &'static str,
+    select: fn(&str) -> Option<&'static str>,
+    column: fn(&str) -> Option<(&'static str, ColumnKind)>,
+}
+
+/// SQL for the inside of `IN (...)`, split around the `text[]` bind.
+#[derive(Debug, Clone)]
+pub(super) struct SubquerySql {
+    pub prefix: String,
+    pub values: Vec<String>,
+    pub suffix: String,
+}
+
+pub(super) fn build(subquery: &Subquery) -> Result<SubquerySql> {
+    let source = source_for(&subquery.entity)?;
+    let select = (source.select)(&subquery.select).ok_or_else(|| {
+        ServiceError::InvalidRequest(format!(
+            "unsupported subquery select field: '{}'",
+            subquery.select
+        ))
+    })?;
+
+    let mut values = Vec::new();
+    let mut conditions = vec![format!("{select} IS NOT NULL")];
+
+    if let Some(spec) = &subquery.time_filter {
+        let range = spec.resolve_with_max_days(Utc::now(), MAX_SUBQUERY_TIME_RANGE_DAYS)?;
+        let start = push_value(&mut values, range.start.to_rfc3339());
+        let end = push_value(&mut values, range.end.to_rfc3339());
+        conditions.push(format!(
+            "s.{column} >= p.v[{start}]::timestamptz AND s.{column} <= p.v[{end}]::timestamptz",
+            column = source.time_column
+        ));
+    }
+
+    for filter in &subquery.filters {
+        if let Some(condition) = filter_condition(&source, filter, &mut values)? {
+            conditions.push(condition);
+        }
+    }
+
+    Ok(SubquerySql {
+        prefix: format!("SELECT {select} FROM {} s, (SELECT ", source.table),
+        values,
+        suffix: format!(
+            "::text[] AS v) p WHERE {} LIMIT {}",
+            conditions.join(" AND "),
+            subquery.limit
+        ),
+    })
+}
+
+fn source_for(entity: &Entity) -> Result<SubquerySource> {
+    match entity {
+        Entity::Events => Ok(SubquerySource {
+            table: "ocsf_events",
+            time_column: "time",
+            select: events_select,
+            column: events_column,
+        }),
+        _ => Err(ServiceError::InvalidRequest(
+            "subqueries currently support in:events".into(),
+        )),
+    }
+}
+
+fn events_select(field: &str) -> Option<&'static str> {
+    match field {
+        "device_id" | "device_uid" | "device.uid" => Some("s.device->>'uid'"),
+        "device_ip" | "device.ip" => Some("s.device->>'ip'"),
+        _ => None,
+    }
+}
+
+fn events_column(field: &str) -> Option<(&'static str, ColumnKind)> {
+    let column = match field {
+        "severity" => ("severity", ColumnKind::Text),
+        "activity_name" => ("activity_name", ColumnKind::Text),
+        "message" | "short_message" => ("message", ColumnKind::Text),
+        "log_name" => ("log_name", ColumnKind::Text),
+        "log_provider" => ("log_provider", ColumnKind::Text),
+        "log_level" => ("log_level", ColumnKind::Text),
+        "status" => ("status", ColumnKind::Text),
+        "status_code" => ("status_code", ColumnKind::Text),
+        "class_uid" => ("class_uid", ColumnKind::Int),
+        "category_uid" => ("category_uid", ColumnKind::Int),
+        "type_uid" => ("type_uid", ColumnKind::Int),
+        "activity_id" => ("activity_id", ColumnKind::Int),
+        "severity_id" => ("severity_id", ColumnKind::Int),
+        "status_id" => ("status_id", ColumnKind::Int),
+        _ => return None,
+    };
+    Some(column)
+}
+
+fn filter_condition(
+    source: &SubquerySource,
+    filter: &Filter,
+    values: &mut Vec<String>,
+) -> Result<Option<String>> {
+    let (column, kind) = (source.column)(&filter.field).ok_or_else(|| {
+        ServiceError::InvalidRequest(format!(
+            "unsupported subquery filter field: '{}'",
+            filter.field
+        ))
+    })?;
+    let cast = match kind {
+        ColumnKind::Text => "",
+        ColumnKind::Int => "::int4",
+    };
+
+    let operator = match filter.op {
+        FilterOp::Eq => "=",
+        FilterOp::NotEq => "<>",
+        FilterOp::Like if kind == ColumnKind::Text => "ILIKE",
+        FilterOp::NotLike if kind == ColumnKind::Text => "NOT ILIKE",
+        FilterOp::Gt if kind == ColumnKind::Int => ">",
+        FilterOp::Gte if kind == ColumnKind::Int => ">=",
+        FilterOp::Lt if kind == ColumnKind::Int => "<",
+        FilterOp::Lte if kind == ColumnKind::Int => "<=",
+        FilterOp::In | FilterOp::NotIn => {
+            let items = filter.value.as_list()?;
+            if items.is_empty() {
+                return Ok(None);
+            }
+            let first = values.len() + 1;
+            for item in items {
+                push_value(values, checked_value(kind, &filter.field, item)?);
+            }
+            let last = values.len();
+            let slice = match kind {
+                ColumnKind::Text => format!("p.v[{first}:{last}]"),
+                ColumnKind::Int => format!("(p.v[{first}:{last}])::int4[]"),
+            };
+            return Ok(Some(if matches!(filter.op, FilterOp::NotIn) {
+                format!("s.{column} <> ALL({slice})")
+            } else {
+                format!("s.{column} = ANY({slice})")
+            }));
+        }
+        _ => {
+            return Err(ServiceError::InvalidRequest(format!(
+                "unsupported operator for subquery filter '{}': {:?}",
+                filter.field, filter.op
+            )));
+        }
+    };
+
+    let value = checked_value(kind, &filter.field, filter.value.as_scalar()?)?;
+    let index = push_value(values, value);
+    Ok(Some(format!("s.{column} {operator} p.v[{index}]{cast}")))
+}
+
+/// Validates integer values up front so bad input is a request error rather
+/// than a failed cast in the database.
+fn checked_value(kind: ColumnKind, field: &str, raw: &str) -> Result<String> {
+    if kind == ColumnKind::Int && raw.parse::<i32>().is_err() {
+        return Err(ServiceError::InvalidRequest(format!(
+            "{field} must be an integer"
+        )));
+    }
+    Ok(raw.to_string())
+}
+
+/// Appends a value and returns its 1-based array position.
+fn push_value(values: &mut Vec<String>, value: String) -> usize {
+    values.push(value);
+    values.len()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::parser::{parse, FilterValue};
+
+    fn subquery_for(query: &str) -> Subquery {
+        let ast = parse(query).expect("query should parse");
+        match &ast.filters[0].value {
+            FilterValue::Subquery(subquery) => (**subquery).clone(),
+            other => panic!("expected subquery, got {other:?}"),
+        }
+    }
+
+    #[test]
+    fn builds_events_subquery_with_indexed_values() {
+        let subquery = subquery_for(
+            "in:devices uid:(in:events severity:Critical type_uid:(100201,100202) select:device_id)",
+        );
+        let sql = build(&subquery).expect("subquery should translate");
+
+        assert_eq!(
+            sql.prefix,
+            "SELECT s.device->>'uid' FROM ocsf_events s, (SELECT "
+        );
+        assert_eq!(sql.values, vec!["Critical", "100201", "100202"]);
+        assert!(sql.suffix.contains("s.severity = p.v[1]"), "{}", sql.suffix);
+        assert!(
+            sql.suffix.contains("s.type_uid = ANY((p.v[2:3])::int4[])"),
+            "{}",
+            sql.suffix
+        );
+        assert!(sql.suffix.ends_with("LIMIT 10000"), "{}", sql.suffix);
+    }
+
+    #[test]
+    fn resolves_subquery_time_window() {
+        let subquery = subquery_for("in:devices uid:(in:events time:last_1h select:device_id)");
+        let sql = build(&subquery).expect("subquery should translate");
+
+        assert_eq!(sql.values.len(), 2);
+        assert!(sql
+            .suffix
+            .contains("s.time >= p.v[1]::timestamptz AND s.time <= p.v[2]::timestamptz"));
+    }
+
+    #[test]
+    fn rejects_unsupported_subquery_fields() {
+        let unknown_select = subquery_for("in:devices uid:(in:events select:hostname)");
+        assert!(build(&unknown_select).is_err());
+
+        let unknown_filter = subquery_for("in:devices uid:(in:events raw_data:x select:device_id)");
+        assert!(build(&unknown_filter).is_err());
+
+        let bad_int = subquery_for("in:devices uid:(in:events severity_id:high select:device_id)");
+        assert!(build(&bad_int).is_err());
+
+        let other_entity = subquery_for("in:devices uid:(in:logs select:device_id)");
+        assert!(build(&other_entity).is_err());
+    }
+}
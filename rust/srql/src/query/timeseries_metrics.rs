@@ -504,7 +504,7 @@ fn build_cagg_stats_query(
         plan,
         scope,
         spec,
-        "timeseries_metrics_hourly",
+        super::metric_rollup_table_for_plan(plan).unwrap_or("timeseries_metrics_hourly"),
         "bucket",
         &agg_expr,
         true,
@@ -3,7 +3,10 @@ use crate::{
     error::{Result, ServiceError},
     jsonb::DbJson,
     models::TraceSummaryRow,
-    parser::{Entity, Filter, FilterOp, OrderClause, OrderDirection},
+    parser::{
+        percentile_fraction, percentile_threshold, Entity, Filter, FilterOp, OrderClause,
+        OrderDirection,
+    },
     time::TimeRange,
 };
 use chrono::{DateTime, Utc};
@@ -237,10 +240,26 @@ fn build_summary_query(plan: &QueryPlan) -> Result<TraceSummarySql> {
 
 /// Build filter clauses as a list (without WHERE prefix)
 fn build_filters_clause_raw(plan: &QueryPlan) -> Result<(Vec<String>, Vec<SqlBindValue>)> {
+    build_filter_clauses(plan, true)
+}
+
+/// Percentile thresholds (`duration_ms:>p99`) are computed over the traces
+/// matching the other filters, so they are left out when building that scope.
+fn build_filter_clauses(
+    plan: &QueryPlan,
+    include_thresholds: bool,
+) -> Result<(Vec<String>, Vec<SqlBindValue>)> {
     let mut clauses = Vec::new();
     let mut binds = Vec::new();
 
     for filter in &plan.filters {
+        if let Some(fraction) = duration_threshold(filter)? {
+            if include_thresholds {
+                add_percentile_condition(&mut clauses, &mut binds, plan, filter, fraction)?;
+            }
+            continue;
+        }
+
         match filter.field.as_str() {
             "trace_id" => add_text_condition(&mut clauses, &mut binds, "trace_id", filter)?,
             "root_span_id" => add_text_condition(&mut clauses, &mut binds, "root_span_id", filter)?,
@@ -256,7 +275,9 @@ fn build_filters_clause_raw(plan: &QueryPlan) -> Result<(Vec<String>, Vec<SqlBin
             }
             "span_count" => add_i64_condition(&mut clauses, &mut binds, "span_count", filter)?,
             "error_count" => add_i64_condition(&mut clauses, &mut binds, "error_count", filter)?,
-            "duration_ms" => add_float_condition(&mut clauses, &mut binds, "duration_ms", filter)?,
+            "duration_ms" | "duration" => {
+                add_float_condition(&mut clauses, &mut binds, "duration_ms", filter)?
+            }
             other => {
                 return Err(ServiceError::InvalidRequest(format!(
                     "unsupported filter field '{other}'"
@@ -268,6 +289,64 @@ fn build_filters_clause_raw(plan: &QueryPlan) -> Result<(Vec<String>, Vec<SqlBin
     Ok((clauses, binds))
 }
 
+/// Returns the percentile fraction of a `duration_ms:>p99` style filter.
+fn duration_threshold(filter: &Filter) -> Result<Option<f64>> {
+    if !matches!(filter.field.as_str(), "duration_ms" | "duration") {
+        return Ok(None);
+    }
+    match filter.value.as_scalar().ok().and_then(percentile_threshold) {
+        Some(fraction) => fraction.map(Some),
+        None => Ok(None),
+    }
+}
+
+/// `duration_ms > (SELECT percentile_cont(0.99) ...)` over the traces in the
+/// same time range that match the query's other filters.
+fn add_percentile_condition(
+    clauses: &mut Vec<String>,
+    binds: &mut Vec<SqlBindValue>,
+    plan: &QueryPlan,
+    filter: &Filter,
+    fraction: f64,
+) -> Result<()> {
+    let op = match filter.op {
+        FilterOp::Gt => ">",
+        FilterOp::Gte => ">=",
+        FilterOp::Lt => "<",
+        FilterOp::Lte => "<=",
+        _ => {
+            return Err(ServiceError::InvalidRequest(
+                "percentile thresholds only support >, >=, < and <=".into(),
+            ))
+        }
+    };
+
+    let mut scope = Vec::new();
+    binds.push(SqlBindValue::Float(fraction));
+
+    if let Some(TimeRange { start, end }) = &plan.time_range {
+        scope.push("timestamp >= ?".to_string());
+        binds.push(SqlBindValue::Timestamp(*start));
+        scope.push("timestamp <= ?".to_string());
+        binds.push(SqlBindValue::Timestamp(*end));
+    }
+
+    let (scope_filters, mut scope_binds) = build_filter_clauses(plan, false)?;
+    scope.extend(scope_filters);
+    binds.append(&mut scope_binds);
+
+    let mut subquery = String::from(
+        "SELECT percentile_cont(?) WITHIN GROUP (ORDER BY duration_ms) FROM otel_trace_summaries",
+    );
+    if !scope.is_empty() {
+        subquery.push_str(" WHERE ");
+        subquery.push_str(&scope.join(" AND "));
+    }
+
+    clauses.push(format!("duration_ms {op} ({subquery})"));
+    Ok(())
+}
+
 fn rewrite_placeholders(sql: &str) -> String {
     let mut result = String::with_capacity(sql.len());
     let mut index = 1;
@@ -389,20 +468,23 @@ fn add_float_condition(
     column: &str,
     filter: &Filter,
 ) -> Result<()> {
-    match filter.op {
-        FilterOp::Eq | FilterOp::NotEq => {
-            let value = parse_f64(filter)?;
-            clauses.push(match filter.op {
-                FilterOp::Eq => format!("{column} = ?"),
-                _ => format!("{column} <> ?"),
-            });
-            binds.push(SqlBindValue::Float(value));
-            Ok(())
+    let op = match filter.op {
+        FilterOp::Eq => "=",
+        FilterOp::NotEq => "<>",
+        FilterOp::Gt => ">",
+        FilterOp::Gte => ">=",
+        FilterOp::Lt => "<",
+        FilterOp::Lte => "<=",
+        _ => {
+            return Err(ServiceError::InvalidRequest(format!(
+                "{column} filter only supports equality and comparisons"
+            )))
         }
-        _ => Err(ServiceError::InvalidRequest(format!(
-            "{column} filter only supports equality"
-        ))),
-    }
+    };
+    let value = parse_f64(filter)?;
+    clauses.push(format!("{column} {op} ?"));
+    binds.push(SqlBindValue::Float(value));
+    Ok(())
 }
 
 fn parse_i32(filter: &Filter) -> Result<i32> {
@@ -479,6 +561,10 @@ enum StatsExprKind {
         comparator: StatsComparator,
         threshold: f64,
     },
+    /// `percentile(duration_ms, 99)`
+    DurationPercentile {
+        fraction: f64,
+    },
 }
 
 #[derive(Debug, Clone, Copy)]
@@ -516,6 +602,10 @@ impl TraceStatsExpr {
                 );
                 (fragment, vec![SqlBindValue::Float(*threshold)])
             }
+            StatsExprKind::DurationPercentile { fraction } => (
+                "percentile_cont(?) WITHIN GROUP (ORDER BY duration_ms)".into(),
+                vec![SqlBindValue::Float(*fraction)],
+            ),
         }
     }
 }
@@ -591,10 +681,41 @@ fn parse_stats_expr(segment: &str) -> Result<TraceStatsExpr> {
         });
     }
 
+    if let Some(kind) = parse_percentile(normalized)? {
+        return Ok(TraceStatsExpr { alias, kind });
+    }
+
     let kind = parse_sum_if(normalized)?;
     Ok(TraceStatsExpr { alias, kind })
 }
 
+/// Parses `percentile(duration_ms, 99)`; returns None for other expressions.
+fn parse_percentile(expr: &str) -> Result<Option<StatsExprKind>> {
+    let lower = expr.trim().to_lowercase();
+    let Some(inner) = lower
+        .strip_prefix("percentile(")
+        .and_then(|rest| rest.strip_suffix(')'))
+    else {
+        return Ok(None);
+    };
+
+    let (field, percentile) = inner.split_once(',').ok_or_else(|| {
+        ServiceError::InvalidRequest(
+            "percentile requires a field and a percentile, e.g. percentile(duration_ms, 99)".into(),
+        )
+    })?;
+    if !matches!(field.trim(), "duration_ms" | "duration") {
+        return Err(ServiceError::InvalidRequest(format!(
+            "percentile is only supported on duration_ms, got '{}'",
+            field.trim()
+        )));
+    }
+
+    Ok(Some(StatsExprKind::DurationPercentile {
+        fraction: percentile_fraction(percentile)?,
+    }))
+}
+
 fn split_alias(segment: &str) -> Result<(String, String)> {
     let lower = segment.to_lowercase();
     if let Some(idx) = lower.rfind(" as ") {
@@ -3,7 +3,10 @@ use crate::{
     error::{Result, ServiceError},
     jsonb::DbJson,
     models::TraceSpanRow,
-    parser::{Entity, Filter, FilterOp, OrderClause, OrderDirection},
+    parser::{
+        percentile_threshold, split_top_level, Entity, Filter, FilterOp, OrderClause,
+        OrderDirection, StatsAggType, StatsSpec,
+    },
     schema::otel_traces::dsl::{
         end_time_unix_nano as col_end, kind as col_kind, name as col_name, otel_traces,
         parent_span_id as col_parent_span_id, scope_name as col_scope_name,
@@ -16,11 +19,12 @@ use crate::{
 };
 use chrono::{DateTime, Utc};
 use diesel::deserialize::QueryableByName;
+use diesel::dsl::sql;
 use diesel::pg::Pg;
 use diesel::prelude::*;
 use diesel::query_builder::{AsQuery, BoxedSelectStatement, BoxedSqlQuery, FromClause, SqlQuery};
 use diesel::sql_query;
-use diesel::sql_types::{Int4, Jsonb, Nullable, Text, Timestamptz};
+use diesel::sql_types::{Bool, Float8, Int4, Int8, Jsonb, Nullable, Text, Timestamptz};
 use diesel::PgTextExpressionMethods;
 use diesel_async::{AsyncPgConnection, RunQueryDsl};
 
@@ -29,6 +33,14 @@ type TracesFromClause = FromClause<TracesTable>;
 type TracesQuery<'a> =
     BoxedSelectStatement<'a, <TracesTable as AsQuery>::SqlType, TracesFromClause, Pg>;
 
+/// Spans have no duration column; it is derived from the span timestamps.
+const SPAN_DURATION_MS: &str = "((end_time_unix_nano - start_time_unix_nano) / 1e6)::float8";
+
+const SPAN_COLUMNS: &str = "timestamp, trace_id, span_id, parent_span_id, name, kind, \
+start_time_unix_nano, end_time_unix_nano, service_name, service_version, service_instance, \
+scope_name, scope_version, status_code, status_message, attributes, resource_attributes, \
+events, links, created_at";
+
 pub(super) async fn execute(
     conn: &mut AsyncPgConnection,
     plan: &QueryPlan,
@@ -48,6 +60,33 @@ pub(super) async fn execute(
             .collect());
     }
 
+    // Percentile thresholds and aggregations are translated to raw SQL
+    if let Some(percentile_query) = build_percentile_query(plan)? {
+        return match percentile_query {
+            PercentileQuery::Spans(spans_sql) => {
+                let rows: Vec<TraceSpanRow> = spans_sql
+                    .to_boxed_query()
+                    .load::<TraceSpanRow>(conn)
+                    .await
+                    .map_err(|err| ServiceError::Internal(err.into()))?;
+                Ok(rows.into_iter().map(TraceSpanRow::into_json).collect())
+            }
+            PercentileQuery::Stats(stats_sql) => {
+                let rows: Vec<TracesStatsPayload> = stats_sql
+                    .to_boxed_query()
+                    .load::<TracesStatsPayload>(conn)
+                    .await
+                    .map_err(|err| ServiceError::Internal(err.into()))?;
+                let payload = rows
+                    .into_iter()
+                    .next()
+                    .and_then(|row| row.payload.map(serde_json::Value::from))
+                    .unwrap_or_else(|| serde_json::Value::Object(serde_json::Map::new()));
+                Ok(vec![payload])
+            }
+        };
+    }
+
     let query = build_query(plan)?;
     let rows: Vec<TraceSpanRow> = query
         .select(TraceSpanRow::as_select())
@@ -74,6 +113,19 @@ pub(super) fn to_sql_and_params(plan: &QueryPlan) -> Result<(String, Vec<BindPar
         return Ok((sql, params));
     }
 
+    if let Some(percentile_query) = build_percentile_query(plan)? {
+        let raw_sql = match percentile_query {
+            PercentileQuery::Spans(raw_sql) | PercentileQuery::Stats(raw_sql) => raw_sql,
+        };
+        let sql = rewrite_placeholders(&raw_sql.sql);
+        let params = raw_sql
+            .binds
+            .into_iter()
+            .map(bind_param_from_stats)
+            .collect();
+        return Ok((sql, params));
+    }
+
     let query = build_query(plan)?.limit(plan.limit).offset(plan.offset);
     let sql = super::diesel_sql(&query)?;
 
@@ -144,6 +196,8 @@ struct TracesStatsPayload {
 enum SqlBindValue {
     Text(String),
     Int(i32),
+    BigInt(i64),
+    Float(f64),
     Timestamp(DateTime<Utc>),
 }
 
@@ -152,6 +206,8 @@ impl SqlBindValue {
         match self {
             SqlBindValue::Text(value) => query.bind::<Text, _>(value.clone()),
             SqlBindValue::Int(value) => query.bind::<Int4, _>(*value),
+            SqlBindValue::BigInt(value) => query.bind::<Int8, _>(*value),
+            SqlBindValue::Float(value) => query.bind::<Float8, _>(*value),
             SqlBindValue::Timestamp(value) => query.bind::<Timestamptz, _>(*value),
         }
     }
@@ -161,6 +217,8 @@ fn bind_param_from_stats(value: SqlBindValue) -> BindParam {
     match value {
         SqlBindValue::Text(value) => BindParam::Text(value),
         SqlBindValue::Int(value) => BindParam::Int(i64::from(value)),
+        SqlBindValue::BigInt(value) => BindParam::Int(value),
+        SqlBindValue::Float(value) => BindParam::Float(value),
         SqlBindValue::Timestamp(value) => BindParam::timestamptz(value),
     }
 }
@@ -213,9 +271,7 @@ fn build_summary_rollup_stats(plan: &QueryPlan) -> Result<Option<TracesStatsSql>
     for filter in &plan.filters {
         match filter.field.as_str() {
             "service_name" | "service.name" => {
-                if let Some((clause, mut values)) =
-                    build_rollup_text_clause("service_name", filter)?
-                {
+                if let Some((clause, mut values)) = build_raw_text_clause("service_name", filter)? {
                     clauses.push(clause);
                     binds.append(&mut values);
                 }
@@ -247,8 +303,8 @@ FROM traces_stats_5m"#,
     Ok(Some(TracesStatsSql { sql, binds }))
 }
 
-/// Build text filter clause for rollup_stats queries.
-fn build_rollup_text_clause(
+/// Build a text filter clause for the raw SQL (rollup_stats and percentile) queries.
+fn build_raw_text_clause(
     column: &str,
     filter: &Filter,
 ) -> Result<Option<(String, Vec<SqlBindValue>)>> {
@@ -289,7 +345,7 @@ fn build_rollup_text_clause(
         }
         _ => {
             return Err(ServiceError::InvalidRequest(format!(
-                "rollup_stats filter {column} does not support operator {:?}",
+                "filter {column} does not support operator {:?}",
                 filter.op
             )))
         }
@@ -298,6 +354,319 @@ fn build_rollup_text_clause(
     Ok(Some((clause, binds)))
 }
 
+// ============================================================================
+// Percentile support: `duration_ms:>p99` and `percentile(duration_ms, 99)`
+// ============================================================================
+
+enum PercentileQuery {
+    Spans(TracesStatsSql),
+    Stats(TracesStatsSql),
+}
+
+/// Builds a raw SQL query when the plan filters on a percentile threshold or
+/// aggregates percentiles. Returns None for plans the diesel query handles.
+fn build_percentile_query(plan: &QueryPlan) -> Result<Option<PercentileQuery>> {
+    let percentile_stats = plan
+        .stats
+        .as_ref()
+        .filter(|stats| stats.as_raw().to_lowercase().contains("percentile("));
+
+    if let Some(stats) = percentile_stats {
+        return build_percentile_stats(plan, stats).map(|sql| Some(PercentileQuery::Stats(sql)));
+    }
+
+    let mut has_threshold = false;
+    for filter in &plan.filters {
+        has_threshold |= duration_threshold(filter)?.is_some();
+    }
+    if !has_threshold {
+        return Ok(None);
+    }
+
+    let mut sql = format!("SELECT {SPAN_COLUMNS}\nFROM otel_traces");
+    let (clauses, mut binds) = build_raw_where(plan, true)?;
+    if !clauses.is_empty() {
+        sql.push_str("\nWHERE ");
+        sql.push_str(&clauses.join(" AND "));
+    }
+
+    sql.push_str(&build_raw_order_clause(&plan.order));
+    sql.push_str("\nLIMIT ? OFFSET ?");
+    binds.push(SqlBindValue::BigInt(plan.limit));
+    binds.push(SqlBindValue::BigInt(plan.offset));
+
+    Ok(Some(PercentileQuery::Spans(TracesStatsSql { sql, binds })))
+}
+
+/// `stats:"percentile(duration_ms, 99) as p99, count() as total"` over spans.
+fn build_percentile_stats(plan: &QueryPlan, stats: &StatsSpec) -> Result<TracesStatsSql> {
+    if stats.group_by().is_some() {
+        return Err(ServiceError::InvalidRequest(
+            "percentile stats on traces do not support grouping".into(),
+        ));
+    }
+
+    let segments = split_top_level(stats.as_raw().trim_matches('"').trim_matches('\''))
+        .into_iter()
+        .filter(|segment| !segment.trim().is_empty())
+        .count();
+    if segments != stats.aggregations.len() {
+        return Err(ServiceError::InvalidRequest(format!(
+            "unsupported stats expression for traces: '{}'",
+            stats.as_raw()
+        )));
+    }
+
+    let mut fields = Vec::new();
+    let mut binds = Vec::new();
+    for aggregation in &stats.aggregations {
+        if aggregation
+            .alias
+            .chars()
+            .any(|ch| !ch.is_ascii_alphanumeric() && ch != '_')
+        {
+            return Err(ServiceError::InvalidRequest(
+                "stats alias must be alphanumeric".into(),
+            ));
+        }
+
+        let expr = match (&aggregation.agg_type, aggregation.percentile) {
+            (StatsAggType::Count, _) => "count(*)".to_string(),
+            (StatsAggType::Percentile, Some(fraction))
+                if matches!(
+                    aggregation.field.as_deref(),
+                    Some("duration_ms" | "duration")
+                ) =>
+            {
+                binds.push(SqlBindValue::Float(fraction));
+                format!("percentile_cont(?) WITHIN GROUP (ORDER BY {SPAN_DURATION_MS})")
+            }
+            _ => {
+                return Err(ServiceError::InvalidRequest(
+                    "traces stats only support count() and percentile(duration_ms, N)".into(),
+                ))
+            }
+        };
+        fields.push(format!("'{}', {expr}", aggregation.alias));
+    }
+
+    let mut sql = format!(
+        "SELECT jsonb_build_object({}) AS payload\nFROM otel_traces",
+        fields.join(", ")
+    );
+    let (clauses, mut where_binds) = build_raw_where(plan, true)?;
+    if !clauses.is_empty() {
+        sql.push_str("\nWHERE ");
+        sql.push_str(&clauses.join(" AND "));
+    }
+    binds.append(&mut where_binds);
+
+    Ok(TracesStatsSql { sql, binds })
+}
+
+/// Returns the percentile fraction of a `duration_ms:>p99` style filter.
+fn duration_threshold(filter: &Filter) -> Result<Option<f64>> {
+    if !matches!(filter.field.as_str(), "duration_ms" | "duration") {
+        return Ok(None);
+    }
+    match filter.value.as_scalar().ok().and_then(percentile_threshold) {
+        Some(fraction) => fraction.map(Some),
+        None => Ok(None),
+    }
+}
+
+/// Time range and filter clauses for the raw SQL queries. Percentile
+/// thresholds are computed over the spans matching the other filters, so they
+/// are left out when building that scope.
+fn build_raw_where(
+    plan: &QueryPlan,
+    include_thresholds: bool,
+) -> Result<(Vec<String>, Vec<SqlBindValue>)> {
+    let mut clauses = Vec::new();
+    let mut binds = Vec::new();
+
+    if let Some(TimeRange { start, end }) = &plan.time_range {
+        clauses.push("timestamp >= ?".to_string());
+        binds.push(SqlBindValue::Timestamp(*start));
+        clauses.push("timestamp <= ?".to_string());
+        binds.push(SqlBindValue::Timestamp(*end));
+    }
+
+    for filter in &plan.filters {
+        if let Some(fraction) = duration_threshold(filter)? {
+            if include_thresholds {
+                let (clause, mut values) = build_threshold_clause(plan, filter, fraction)?;
+                clauses.push(clause);
+                binds.append(&mut values);
+            }
+            continue;
+        }
+
+        let built = match filter.field.as_str() {
+            "trace_id" => build_raw_text_clause("trace_id", filter)?,
+            "span_id" => build_raw_text_clause("span_id", filter)?,
+            "parent_span_id" => build_raw_text_clause("parent_span_id", filter)?,
+            "service_name" | "service.name" => build_raw_text_clause("service_name", filter)?,
+            "service_version" => build_raw_text_clause("service_version", filter)?,
+            "service_instance" => build_raw_text_clause("service_instance", filter)?,
+            "scope_name" => build_raw_text_clause("scope_name", filter)?,
+            "scope_version" => build_raw_text_clause("scope_version", filter)?,
+            "name" | "span_name" => build_raw_text_clause("name", filter)?,
+            "status_message" => build_raw_text_clause("status_message", filter)?,
+            "status_code" => build_raw_int_clause("status_code", filter)?,
+            "kind" | "span_kind" => build_raw_int_clause("kind", filter)?,
+            "duration_ms" | "duration" => {
+                let op = duration_operator(filter)?;
+                Some((
+                    format!("{SPAN_DURATION_MS} {op} ?"),
+                    vec![SqlBindValue::Float(parse_duration(filter)?)],
+                ))
+            }
+            other => {
+                return Err(ServiceError::InvalidRequest(format!(
+                    "unsupported filter field for traces: '{other}'"
+                )));
+            }
+        };
+
+        if let Some((clause, mut values)) = built {
+            clauses.push(clause);
+            binds.append(&mut values);
+        }
+    }
+
+    Ok((clauses, binds))
+}
+
+/// `duration > (SELECT percentile_cont(0.99) ...)` over the spans in the same
+/// time range that match the query's other filters.
+fn build_threshold_clause(
+    plan: &QueryPlan,
+    filter: &Filter,
+    fraction: f64,
+) -> Result<(String, Vec<SqlBindValue>)> {
+    let op = match filter.op {
+        FilterOp::Gt => ">",
+        FilterOp::Gte => ">=",
+        FilterOp::Lt => "<",
+        FilterOp::Lte => "<=",
+        _ => {
+            return Err(ServiceError::InvalidRequest(
+                "percentile thresholds only support >, >=, < and <=".into(),
+            ))
+        }
+    };
+
+    let mut binds = vec![SqlBindValue::Float(fraction)];
+    let mut subquery = format!(
+        "SELECT percentile_cont(?) WITHIN GROUP (ORDER BY {SPAN_DURATION_MS}) FROM otel_traces"
+    );
+    let (scope, mut scope_binds) = build_raw_where(plan, false)?;
+    if !scope.is_empty() {
+        subquery.push_str(" WHERE ");
+        subquery.push_str(&scope.join(" AND "));
+    }
+    binds.append(&mut scope_binds);
+
+    Ok((format!("{SPAN_DURATION_MS} {op} ({subquery})"), binds))
+}
+
+fn build_raw_int_clause(
+    column: &str,
+    filter: &Filter,
+) -> Result<Option<(String, Vec<SqlBindValue>)>> {
+    let parse = |raw: &str| {
+        raw.parse::<i32>()
+            .map_err(|_| ServiceError::InvalidRequest(format!("{column} must be an integer")))
+    };
+
+    match filter.op {
+        FilterOp::Eq | FilterOp::NotEq => {
+            let value = parse(filter.value.as_scalar()?)?;
+            let operator = if matches!(filter.op, FilterOp::Eq) {
+                "="
+            } else {
+                "<>"
+            };
+            Ok(Some((
+                format!("{column} {operator} ?"),
+                vec![SqlBindValue::Int(value)],
+            )))
+        }
+        FilterOp::In | FilterOp::NotIn => {
+            let values = filter
+                .value
+                .as_list()?
+                .iter()
+                .map(|value| parse(value))
+                .collect::<Result<Vec<_>>>()?;
+            if values.is_empty() {
+                return Ok(None);
+            }
+            let placeholders = vec!["?"; values.len()].join(", ");
+            let operator = if matches!(filter.op, FilterOp::In) {
+                "IN"
+            } else {
+                "NOT IN"
+            };
+            Ok(Some((
+                format!("{column} {operator} ({placeholders})"),
+                values.into_iter().map(SqlBindValue::Int).collect(),
+            )))
+        }
+        _ => Err(ServiceError::InvalidRequest(format!(
+            "{column} filter only supports equality or list comparisons"
+        ))),
+    }
+}
+
+fn build_raw_order_clause(order: &[OrderClause]) -> String {
+    let clauses: Vec<String> = order
+        .iter()
+        .filter(|clause| {
+            matches!(
+                clause.field.as_str(),
+                "timestamp" | "start_time_unix_nano" | "end_time_unix_nano" | "service_name"
+            )
+        })
+        .map(|clause| {
+            let direction = match clause.direction {
+                OrderDirection::Asc => "ASC",
+                OrderDirection::Desc => "DESC",
+            };
+            format!("{} {direction}", clause.field)
+        })
+        .collect();
+
+    if clauses.is_empty() {
+        "\nORDER BY timestamp DESC".to_string()
+    } else {
+        format!("\nORDER BY {}", clauses.join(", "))
+    }
+}
+
+fn duration_operator(filter: &Filter) -> Result<&'static str> {
+    match filter.op {
+        FilterOp::Eq => Ok("="),
+        FilterOp::NotEq => Ok("<>"),
+        FilterOp::Gt => Ok(">"),
+        FilterOp::Gte => Ok(">="),
+        FilterOp::Lt => Ok("<"),
+        FilterOp::Lte => Ok("<="),
+        _ => Err(ServiceError::InvalidRequest(
+            "duration_ms filter only supports equality and comparisons".into(),
+        )),
+    }
+}
+
+fn parse_duration(filter: &Filter) -> Result<f64> {
+    filter
+        .value
+        .as_scalar()?
+        .parse::<f64>()
+        .map_err(|_| ServiceError::InvalidRequest("duration_ms must be a number".into()))
+}
+
 fn build_query(plan: &QueryPlan) -> Result<TracesQuery<'static>> {
     let mut query = otel_traces.into_boxed::<Pg>();
 
@@ -351,6 +720,13 @@ fn apply_filter<'a>(mut query: TracesQuery<'a>, filter: &Filter) -> Result<Trace
         "kind" | "span_kind" => {
             query = apply_kind_filter(query, filter)?;
         }
+        "duration_ms" | "duration" => {
+            let op = duration_operator(filter)?;
+            query = query.filter(
+                sql::<Bool>(&format!("{SPAN_DURATION_MS} {op} "))
+                    .bind::<Float8, _>(parse_duration(filter)?),
+            );
+        }
         other => {
             return Err(ServiceError::InvalidRequest(format!(
                 "unsupported filter field for traces: '{other}'"
@@ -434,6 +810,11 @@ fn collect_filter_params(params: &mut Vec<BindParam>, filter: &Filter) -> Result
                 "kind filter only supports equality comparisons".into(),
             )),
         },
+        "duration_ms" | "duration" => {
+            duration_operator(filter)?;
+            params.push(BindParam::Float(parse_duration(filter)?));
+            Ok(())
+        }
         other => Err(ServiceError::InvalidRequest(format!(
             "unsupported filter field for traces: '{other}'"
         ))),
@@ -72,6 +72,7 @@ pub fn meta_for_plan(plan: &QueryPlan) -> Option<VizMeta> {
                 | Entity::CpuMetrics
                 | Entity::MemoryMetrics
                 | Entity::DiskMetrics
+                | Entity::NetworkMetrics
                 | Entity::ProcessMetrics
                 | Entity::Flows
         )
@@ -827,6 +828,56 @@ pub fn meta_for_plan(plan: &QueryPlan) -> Option<VizMeta> {
                 series: Some("mount_point".to_string()),
             }],
         },
+        Entity::NetworkMetrics => VizMeta {
+            columns: vec![
+                col(
+                    "timestamp",
+                    ColumnType::Timestamptz,
+                    Some(ColumnSemantic::Time),
+                ),
+                col("gateway_id", ColumnType::Text, Some(ColumnSemantic::Id)),
+                col("agent_id", ColumnType::Text, Some(ColumnSemantic::Id)),
+                col("host_id", ColumnType::Text, Some(ColumnSemantic::Id)),
+                col(
+                    "interface_name",
+                    ColumnType::Text,
+                    Some(ColumnSemantic::Label),
+                ),
+                col(
+                    "bytes_recv_per_sec",
+                    ColumnType::Float,
+                    Some(ColumnSemantic::Value),
+                )
+                .with_unit("bytes/s"),
+                col(
+                    "bytes_sent_per_sec",
+                    ColumnType::Float,
+                    Some(ColumnSemantic::Value),
+                )
+                .with_unit("bytes/s"),
+                col(
+                    "packets_recv_per_sec",
+                    ColumnType::Float,
+                    Some(ColumnSemantic::Value),
+                ),
+                col(
+                    "packets_sent_per_sec",
+                    ColumnType::Float,
+                    Some(ColumnSemantic::Value),
+                ),
+                col("bytes_recv", ColumnType::Int, None).with_unit("bytes"),
+                col("bytes_sent", ColumnType::Int, None).with_unit("bytes"),
+                col("errors_in", ColumnType::Int, None),
+                col("errors_out", ColumnType::Int, None),
+                col("device_id", ColumnType::Text, Some(ColumnSemantic::Id)),
+            ],
+            suggestions: vec![VizSuggestion {
+                kind: VizKind::Timeseries,
+                x: Some("timestamp".to_string()),
+                y: Some("bytes_recv_per_sec".to_string()),
+                series: Some("interface_name".to_string()),
+            }],
+        },
         Entity::ProcessMetrics => VizMeta {
             columns: vec![
                 col(
@@ -918,6 +969,35 @@ pub fn meta_for_plan(plan: &QueryPlan) -> Option<VizMeta> {
                 series: None,
             }],
         },
+        Entity::Certificates => VizMeta {
+            columns: vec![
+                col("id", ColumnType::Text, Some(ColumnSemantic::Id)),
+                col("source", ColumnType::Text, Some(ColumnSemantic::Label)),
+                col("target", ColumnType::Text, None),
+                col("name", ColumnType::Text, None),
+                col("subject", ColumnType::Text, None),
+                col("issuer", ColumnType::Text, None),
+                col("serial_number", ColumnType::Text, None),
+                col("not_before", ColumnType::Timestamptz, None),
+                col("not_after", ColumnType::Timestamptz, None),
+                col(
+                    "days_remaining",
+                    ColumnType::Int,
+                    Some(ColumnSemantic::Value),
+                ),
+                col(
+                    "last_checked_at",
+                    ColumnType::Timestamptz,
+                    Some(ColumnSemantic::Time),
+                ),
+            ],
+            suggestions: vec![VizSuggestion {
+                kind: VizKind::Table,
+                x: None,
+                y: None,
+                series: None,
+            }],
+        },
         Entity::DeviceGraph => VizMeta {
             columns: vec![col("result", ColumnType::Jsonb, None)],
             suggestions: vec![VizSuggestion {
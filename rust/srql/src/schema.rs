@@ -89,6 +89,15 @@ diesel::table! {
         deleted_at -> Nullable<Timestamptz>,
         deleted_by -> Nullable<Text>,
         deleted_reason -> Nullable<Text>,
+
+        // Ownership assignment
+        owner_uid -> Nullable<Text>,
+        owner_type -> Nullable<Text>,
+
+        // Archival
+        archived_at -> Nullable<Timestamptz>,
+        archived_by -> Nullable<Text>,
+        archived_reason -> Nullable<Text>,
     }
 }
 
@@ -385,6 +394,41 @@ diesel::table! {
         device_id -> Nullable<Text>,
         partition -> Nullable<Text>,
         created_at -> Timestamptz,
+        read_bytes -> Nullable<Int8>,
+        write_bytes -> Nullable<Int8>,
+        read_ops -> Nullable<Int8>,
+        write_ops -> Nullable<Int8>,
+        read_bytes_per_sec -> Nullable<Float8>,
+        write_bytes_per_sec -> Nullable<Float8>,
+        read_ops_per_sec -> Nullable<Float8>,
+        write_ops_per_sec -> Nullable<Float8>,
+    }
+}
+
+diesel::table! {
+    use diesel::sql_types::*;
+
+    network_metrics (timestamp, gateway_id, interface_name) {
+        timestamp -> Timestamptz,
+        gateway_id -> Nullable<Text>,
+        agent_id -> Nullable<Text>,
+        host_id -> Nullable<Text>,
+        interface_name -> Nullable<Text>,
+        bytes_sent -> Nullable<Int8>,
+        bytes_recv -> Nullable<Int8>,
+        packets_sent -> Nullable<Int8>,
+        packets_recv -> Nullable<Int8>,
+        errors_in -> Nullable<Int8>,
+        errors_out -> Nullable<Int8>,
+        drops_in -> Nullable<Int8>,
+        drops_out -> Nullable<Int8>,
+        bytes_sent_per_sec -> Nullable<Float8>,
+        bytes_recv_per_sec -> Nullable<Float8>,
+        packets_sent_per_sec -> Nullable<Float8>,
+        packets_recv_per_sec -> Nullable<Float8>,
+        device_id -> Nullable<Text>,
+        partition -> Nullable<Text>,
+        created_at -> Timestamptz,
     }
 }
 
@@ -466,6 +510,25 @@ diesel::table! {
     }
 }
 
+diesel::table! {
+    use diesel::sql_types::*;
+
+    monitored_certificates (id) {
+        id -> Uuid,
+        source -> Text,
+        target -> Text,
+        name -> Nullable<Text>,
+        subject -> Nullable<Text>,
+        issuer -> Nullable<Text>,
+        serial_number -> Nullable<Text>,
+        not_before -> Nullable<Timestamptz>,
+        not_after -> Timestamptz,
+        last_checked_at -> Timestamptz,
+        inserted_at -> Timestamptz,
+        updated_at -> Timestamptz,
+    }
+}
+
 diesel::table! {
     use diesel::sql_types::*;
 
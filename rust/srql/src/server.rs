@@ -1,13 +1,14 @@
 use crate::{
     config::AppConfig,
-    db,
+    db, encoding,
     error::{Result, ServiceError},
     query::{QueryEngine, QueryRequest, QueryResponse, TranslateRequest, TranslateResponse},
     state::{ApiKeyStore, AppState},
 };
 use axum::{
     extract::State,
-    http::HeaderMap,
+    http::{header::CONTENT_TYPE, HeaderMap},
+    response::{IntoResponse, Response},
     routing::{get, post},
     Json, Router,
 };
@@ -29,6 +30,8 @@ use tower::{Layer, Service};
 use tower_http::trace::TraceLayer;
 use tracing::{error, info, warn};
 
+const ADMIN_KEY_HEADER: &str = "x-srql-admin-key";
+
 pub struct Server {
     config: Arc<AppConfig>,
     state: AppState,
@@ -74,12 +77,17 @@ impl Server {
         State(state): State<AppState>,
         headers: HeaderMap,
         Json(request): Json<QueryRequest>,
-    ) -> Result<Json<QueryResponse>> {
+    ) -> Result<Response> {
         enforce_api_key(&headers, &state.api_keys)?;
-        let response = state.query.execute_query(request).await;
+        let cap = if is_admin_request(&headers, &state.config) {
+            None
+        } else {
+            state.config.result_cap()
+        };
+        let response = state.query.execute_query_with_cap(request, cap).await;
 
         match response {
-            Ok(rows) => Ok(Json(rows)),
+            Ok(rows) => Ok(encode_query_response(&headers, rows)),
             Err(err) => {
                 error!(error = ?err, "srql query failed");
                 Err(err)
@@ -98,6 +106,20 @@ impl Server {
     }
 }
 
+/// Encodes the query response as protobuf when the client asked for it via
+/// the Accept header, and as JSON otherwise.
+fn encode_query_response(headers: &HeaderMap, response: QueryResponse) -> Response {
+    if encoding::wants_protobuf(headers) {
+        return (
+            [(CONTENT_TYPE, encoding::PROTOBUF_CONTENT_TYPE)],
+            encoding::encode_protobuf(&response),
+        )
+            .into_response();
+    }
+
+    Json(response).into_response()
+}
+
 fn enforce_api_key(headers: &HeaderMap, api_keys: &ApiKeyStore) -> Result<()> {
     if let Some(expected) = api_keys.current() {
         let provided = headers
@@ -113,6 +135,33 @@ fn enforce_api_key(headers: &HeaderMap, api_keys: &ApiKeyStore) -> Result<()> {
     Ok(())
 }
 
+/// Reports whether the request carries the admin key that lifts the result cap.
+fn is_admin_request(headers: &HeaderMap, config: &AppConfig) -> bool {
+    let Some(expected) = config.admin_api_key.as_deref() else {
+        return false;
+    };
+
+    headers
+        .get(ADMIN_KEY_HEADER)
+        .and_then(|value| value.to_str().ok())
+        .is_some_and(|provided| constant_time_eq(provided.trim(), expected))
+}
+
+/// Compares two keys without short-circuiting on the first differing byte, so
+/// response timing does not reveal how much of a guessed key was right.
+fn constant_time_eq(provided: &str, expected: &str) -> bool {
+    let (provided, expected) = (provided.as_bytes(), expected.as_bytes());
+    if provided.len() != expected.len() {
+        return false;
+    }
+
+    provided
+        .iter()
+        .zip(expected)
+        .fold(0u8, |diff, (a, b)| diff | (a ^ b))
+        == 0
+}
+
 async fn initialize_api_keys(config: &AppConfig) -> anyhow::Result<ApiKeyStore> {
     let initial_api_key = config
         .api_key
@@ -320,6 +369,11 @@ mod tests {
             request_timeout: Duration::from_secs(30),
             rate_limit_max_requests: 120,
             rate_limit_window: Duration::from_secs(60),
+            downsample_threshold: Duration::from_secs(7 * 24 * 60 * 60),
+            downsample_target_points: 1000,
+            max_result_rows: 0,
+            admin_api_key: None,
+            default_sort: Default::default(),
         }
     }
 
@@ -375,4 +429,27 @@ mod tests {
 
         enforce_api_key(&headers, &store).expect("expected auth success");
     }
+
+    #[test]
+    fn admin_key_lifts_result_cap_only_when_configured() {
+        let mut config = test_config();
+        let mut headers = HeaderMap::new();
+        headers.insert("x-srql-admin-key", HeaderValue::from_static("admin-key"));
+        assert!(!is_admin_request(&headers, &config));
+
+        config.admin_api_key = Some("admin-key".to_string());
+        assert!(is_admin_request(&headers, &config));
+
+        headers.insert("x-srql-admin-key", HeaderValue::from_static("other-key"));
+        assert!(!is_admin_request(&headers, &config));
+        assert!(!is_admin_request(&HeaderMap::new(), &config));
+    }
+
+    #[test]
+    fn constant_time_eq_compares_whole_keys() {
+        assert!(constant_time_eq("admin-key", "admin-key"));
+        assert!(!constant_time_eq("admin-kez", "admin-key"));
+        assert!(!constant_time_eq("admin", "admin-key"));
+        assert!(!constant_time_eq("", "admin-key"));
+    }
 }
@@ -219,7 +219,7 @@ fn parse_absolute_range(value: &str) -> Result<TimeFilterSpec> {
     }
 }
 
-fn parse_datetime(value: &str) -> Result<DateTime<Utc>> {
+pub(crate) fn parse_datetime(value: &str) -> Result<DateTime<Utc>> {
     if let Ok(dt) = DateTime::parse_from_rfc3339(value) {
         return Ok(dt.with_timezone(&Utc));
     }
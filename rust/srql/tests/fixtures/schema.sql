@@ -49,7 +49,14 @@ CREATE TABLE ocsf_devices (
     metadata            JSONB,
     deleted_at          TIMESTAMPTZ,
     deleted_by          TEXT,
-    deleted_reason      TEXT
+    deleted_reason      TEXT,
+    -- Ownership assignment
+    owner_uid           TEXT,
+    owner_type          TEXT,
+    -- Archival
+    archived_at         TIMESTAMPTZ,
+    archived_by         TEXT,
+    archived_reason     TEXT
 );
 
 DROP TABLE IF EXISTS gateways;
@@ -125,6 +125,11 @@ fn test_config(database_url: String) -> AppConfig {
         request_timeout: Duration::from_secs(30),
         rate_limit_max_requests: 120,
         rate_limit_window: Duration::from_secs(60),
+        downsample_threshold: Duration::from_secs(7 * 24 * 60 * 60),
+        downsample_target_points: 1000,
+        max_result_rows: 0,
+        admin_api_key: None,
+        default_sort: Default::default(),
     }
 }
 
@@ -77,6 +77,65 @@ fn default_stream_replicas() -> usize {
     1
 }
 
+fn default_dedup_window_secs() -> u64 {
+    60
+}
+
+fn default_dedup_match_fields() -> Vec<DedupField> {
+    vec![
+        DedupField::Source,
+        DedupField::TrapOid,
+        DedupField::Varbinds,
+    ]
+}
+
+fn default_dedup_max_pending() -> usize {
+    10_000
+}
+
+/// Trap fields compared when deciding whether two traps are duplicates.
+#[derive(Debug, Clone, Copy, Serialize, Deserialize, PartialEq, Eq)]
+#[serde(rename_all = "snake_case")]
+pub enum DedupField {
+    /// The sending device's address (without the source port).
+    Source,
+    /// The `snmpTrapOID.0` varbind.
+    TrapOid,
+    /// Every varbind except `sysUpTime.0` and `snmpTrapOID.0`.
+    Varbinds,
+    Community,
+    Version,
+}
+
+/// Trap deduplication. When enabled, the first trap is published on arrival
+/// and traps matching it on `match_fields` within `window_secs` are absorbed;
+/// when the window closes, one summary with the occurrence count and the
+/// first and last occurrence times is published.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct DedupConfig {
+    #[serde(default)]
+    pub enabled: bool,
+    #[serde(default = "default_dedup_window_secs")]
+    pub window_secs: u64,
+    #[serde(default = "default_dedup_match_fields")]
+    pub match_fields: Vec<DedupField>,
+    /// Distinct traps tracked at once; traps beyond it are published without
+    /// absorbing their duplicates.
+    #[serde(default = "default_dedup_max_pending")]
+    pub max_pending: usize,
+}
+
+impl Default for DedupConfig {
+    fn default() -> Self {
+        Self {
+            enabled: false,
+            window_secs: default_dedup_window_secs(),
+            match_fields: default_dedup_match_fields(),
+            max_pending: default_dedup_max_pending(),
+        }
+    }
+}
+
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct Config {
     pub listen_addr: String,
@@ -94,6 +153,8 @@ pub struct Config {
     pub nats_security: Option<SecurityConfig>,
     pub grpc_listen_addr: Option<String>,
     pub grpc_security: Option<SecurityConfig>,
+    #[serde(default)]
+    pub dedup: DedupConfig,
 }
 
 impl Config {
@@ -113,6 +174,17 @@ impl Config {
         if self.subject.is_empty() {
             anyhow::bail!("subject is required");
         }
+        if self.dedup.enabled {
+            if self.dedup.window_secs == 0 {
+                anyhow::bail!("dedup.window_secs must be > 0 when dedup is enabled");
+            }
+            if self.dedup.match_fields.is_empty() {
+                anyhow::bail!("dedup.match_fields cannot be empty when dedup is enabled");
+            }
+            if self.dedup.max_pending == 0 {
+                anyhow::bail!("dedup.max_pending must be > 0 when dedup is enabled");
+            }
+        }
         if let Some(creds_file) = &self.nats_creds_file {
             if creds_file.trim().is_empty() {
                 anyhow::bail!("nats_creds_file cannot be empty");
@@ -207,6 +279,7 @@ mod tests {
                 workload_socket: Some("unix:/run/spire/sockets/agent.sock".into()),
                 ..Default::default()
             }),
+            dedup: DedupConfig::default(),
         }
     }
 
@@ -259,6 +332,38 @@ mod tests {
         );
     }
 
+    #[test]
+    fn dedup_defaults_when_omitted() {
+        let cfg: Config = serde_json::from_str(
+            r#"{"listen_addr": "0.0.0.0:162", "nats_url": "nats://127.0.0.1:4222", "subject": "logs.snmp"}"#,
+        )
+        .expect("expected config to parse");
+
+        assert!(!cfg.dedup.enabled);
+        assert_eq!(cfg.dedup.window_secs, 60);
+        assert_eq!(
+            cfg.dedup.match_fields,
+            vec![
+                DedupField::Source,
+                DedupField::TrapOid,
+                DedupField::Varbinds
+            ]
+        );
+    }
+
+    #[test]
+    fn dedup_requires_match_fields() {
+        let mut cfg = base_config();
+        cfg.dedup.enabled = true;
+        cfg.dedup.match_fields.clear();
+
+        let err = cfg.validate().expect_err("expected validation error");
+        assert!(
+            err.to_string().contains("dedup.match_fields"),
+            "error should mention dedup.match_fields: {err}"
+        );
+    }
+
     #[test]
     fn client_ca_path_prefers_client_ca_file() {
         let config = SecurityConfig {
@@ -0,0 +1,410 @@
+/*
+ * Copyright 2026 Carver Automation Corporation.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//! Trap deduplication. A flapping device can send thousands of identical
+//! traps; the coalescer publishes the first of each right away, absorbs the
+//! duplicates for the configured window and then publishes one summary with
+//! the number of traps the window saw.
+
+use std::collections::HashMap;
+use std::net::SocketAddr;
+use std::time::{Duration, SystemTime, UNIX_EPOCH};
+
+use crate::config::{DedupConfig, DedupField};
+use crate::{TrapMessage, TrapOccurrences};
+
+const SYS_UPTIME_OID: &str = "1.3.6.1.2.1.1.3.0";
+const SNMP_TRAP_OID: &str = "1.3.6.1.6.3.1.1.4.1.0";
+
+struct PendingTrap {
+    message: TrapMessage,
+    first_seen: SystemTime,
+    last_seen: SystemTime,
+    occurrences: u64,
+}
+
+pub struct TrapCoalescer {
+    window: Duration,
+    match_fields: Vec<DedupField>,
+    max_pending: usize,
+    pending: HashMap<String, PendingTrap>,
+}
+
+impl TrapCoalescer {
+    pub fn new(cfg: &DedupConfig) -> Self {
+        Self {
+            window: Duration::from_secs(cfg.window_secs),
+            match_fields: cfg.match_fields.clone(),
+            max_pending: cfg.max_pending,
+            pending: HashMap::new(),
+        }
+    }
+
+    /// Records a trap received at `now` and returns the messages to publish
+    /// right away. The first trap of a window is published immediately; a
+    /// duplicate within the window is only counted. A trap arriving after
+    /// its window has closed also releases that window's summary.
+    pub fn observe(&mut self, message: TrapMessage, now: SystemTime) -> Vec<TrapMessage> {
+        let key = self.key(&message);
+        let mut ready = Vec::new();
+
+        if let Some(pending) = self.pending.get_mut(&key) {
+            if now < pending.first_seen + self.window {
+                pending.occurrences += 1;
+                pending.last_seen = pending.last_seen.max(now);
+                return ready;
+            }
+
+            ready.extend(
+                self.pending
+                    .remove(&key)
+                    .and_then(PendingTrap::into_summary),
+            );
+        }
+
+        let first = PendingTrap {
+            message,
+            first_seen: now,
+            last_seen: now,
+            occurrences: 1,
+        };
+
+        // Without room to track the window the trap is still published, it
+        // just does not absorb duplicates.
+        if self.pending.len() < self.max_pending {
+            ready.push(first.first_message());
+            self.pending.insert(key, first);
+        } else {
+            ready.push(first.first_message());
+        }
+
+        ready
+    }
+
+    /// Closes the windows that ended by `now` and returns the summaries of
+    /// those that absorbed duplicates, oldest first.
+    pub fn flush_expired(&mut self, now: SystemTime) -> Vec<TrapMessage> {
+        let window = self.window;
+        let expired: Vec<String> = self
+            .pending
+            .iter()
+            .filter(|(_, pending)| now >= pending.first_seen + window)
+            .map(|(key, _)| key.clone())
+            .collect();
+
+        let closed: Vec<PendingTrap> = expired
+            .iter()
+            .filter_map(|key| self.pending.remove(key))
+            .collect();
+
+        summaries(closed)
+    }
+
+    /// Closes every open window, as on shutdown, and returns the summaries of
+    /// those that absorbed duplicates, oldest first.
+    pub fn drain(&mut self) -> Vec<TrapMessage> {
+        summaries(self.pending.drain().map(|(_, pending)| pending).collect())
+    }
+
+    #[cfg(test)]
+    fn pending(&self) -> usize {
+        self.pending.len()
+    }
+
+    /// The identity of a trap: the configured fields, unit-separated.
+    fn key(&self, message: &TrapMessage) -> String {
+        let parts: Vec<String> = self
+            .match_fields
+            .iter()
+            .map(|field| match field {
+                DedupField::Source => message
+                    .source
+                    .parse::<SocketAddr>()
+                    .map(|addr| addr.ip().to_string())
+                    .unwrap_or_else(|_| message.source.clone()),
+                DedupField::TrapOid => message
+                    .varbinds
+                    .iter()
+                    .find(|varbind| normalize_oid(&varbind.oid) == SNMP_TRAP_OID)
+                    .map(|varbind| varbind.value.clone())
+                    .unwrap_or_default(),
+                DedupField::Varbinds => message
+                    .varbinds
+                    .iter()
+                    .filter(|varbind| {
+                        let oid = normalize_oid(&varbind.oid);
+                        oid != SYS_UPTIME_OID && oid != SNMP_TRAP_OID
+                    })
+                    .map(|varbind| format!("{}={}", varbind.oid, varbind.value))
+                    .collect::<Vec<_>>()
+                    .join("\u{1e}"),
+                DedupField::Community => message.community.clone(),
+                DedupField::Version => message.version.clone(),
+            })
+            .collect();
+
+        parts.join("\u{1f}")
+    }
+}
+
+fn summaries(mut closed: Vec<PendingTrap>) -> Vec<TrapMessage> {
+    closed.sort_by_key(|pending| pending.first_seen);
+    closed
+        .into_iter()
+        .filter_map(PendingTrap::into_summary)
+        .collect()
+}
+
+impl PendingTrap {
+    /// The first trap of the window, as published on arrival.
+    fn first_message(&self) -> TrapMessage {
+        self.message_with(self.message.clone(), false)
+    }
+
+    /// The summary published when the window closes, or `None` when the
+    /// window saw no duplicates and the first message already said it all.
+    fn into_summary(self) -> Option<TrapMessage> {
+        if self.occurrences <= 1 {
+            return None;
+        }
+
+        let message = self.message.clone();
+        Some(self.message_with(message, true))
+    }
+
+    fn message_with(&self, mut message: TrapMessage, summary: bool) -> TrapMessage {
+        let first_seen = unix_nanos(self.first_seen);
+        message.timestamp = Some(first_seen);
+        message.attributes = Some(TrapOccurrences {
+            occurrences: self.occurrences,
+            first_seen_unix_nano: first_seen,
+            last_seen_unix_nano: unix_nanos(self.last_seen),
+            summary,
+        });
+        message
+    }
+}
+
+fn normalize_oid(oid: &str) -> &str {
+    oid.trim().trim_start_matches('.')
+}
+
+fn unix_nanos(time: SystemTime) -> u64 {
+    time.duration_since(UNIX_EPOCH)
+        .map(|elapsed| elapsed.as_nanos() as u64)
+        .unwrap_or_default()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::Varbind;
+
+    fn coalescer(window_secs: u64) -> TrapCoalescer {
+        TrapCoalescer::new(&DedupConfig {
+            enabled: true,
+            window_secs,
+            ..Default::default()
+        })
+    }
+
+    fn link_down(source: &str, uptime: &str, if_index: &str) -> TrapMessage {
+        TrapMessage {
+            source: source.to_string(),
+            version: "V2C".to_string(),
+            community: "public".to_string(),
+            varbinds: vec![
+                Varbind {
+                    oid: SYS_UPTIME_OID.to_string(),
+                    value: format!("TIMETICKS: {uptime}"),
+                },
+                Varbind {
+                    oid: SNMP_TRAP_OID.to_string(),
+                    value: "OBJECT IDENTIFIER: 1.3.6.1.6.3.1.1.5.3".to_string(),
+                },
+                Varbind {
+                    oid: "1.3.6.1.2.1.2.2.1.1".to_string(),
+                    value: format!("INTEGER: {if_index}"),
+                },
+            ],
+            timestamp: None,
+            attributes: None,
+        }
+    }
+
+    fn at(base: SystemTime, secs: u64) -> SystemTime {
+        base + Duration::from_secs(secs)
+    }
+
+    #[test]
+    fn coalesces_identical_traps_within_window() {
+        let mut coalescer = coalescer(60);
+        let base = UNIX_EPOCH + Duration::from_secs(1_800_000_000);
+
+        // The first trap goes out straight away.
+        let first = coalescer.observe(link_down("10.0.0.1:40000", "100", "3"), at(base, 0));
+        assert_eq!(first.len(), 1);
+        assert_eq!(first[0].timestamp, Some(unix_nanos(at(base, 0))));
+        let occurrences = first[0].attributes.as_ref().expect("occurrence attributes");
+        assert_eq!(occurrences.occurrences, 1);
+        assert!(!occurrences.summary);
+
+        // Same device (new source port), same trap; only sysUpTime differs.
+        assert!(coalescer
+            .observe(link_down("10.0.0.1:40001", "200", "3"), at(base, 10))
+            .is_empty());
+        assert!(coalescer
+            .observe(link_down("10.0.0.1:40002", "300", "3"), at(base, 45))
+            .is_empty());
+        assert_eq!(coalescer.pending(), 1);
+
+        assert!(coalescer.flush_expired(at(base, 59)).is_empty());
+
+        let flushed = coalescer.flush_expired(at(base, 60));
+        assert_eq!(flushed.len(), 1);
+        assert_eq!(coalescer.pending(), 0);
+
+        let summary = &flushed[0];
+        assert_eq!(
+            summary.source, "10.0.0.1:40000",
+            "the summary describes the first occurrence"
+        );
+        let occurrences = summary.attributes.as_ref().expect("occurrence attributes");
+        assert!(occurrences.summary);
+        assert_eq!(occurrences.occurrences, 3);
+        assert_eq!(occurrences.first_seen_unix_nano, unix_nanos(at(base, 0)));
+        assert_eq!(occurrences.last_seen_unix_nano, unix_nanos(at(base, 45)));
+        assert_eq!(summary.timestamp, Some(unix_nanos(at(base, 0))));
+    }
+
+    #[test]
+    fn windows_without_duplicates_close_silently() {
+        let mut coalescer = coalescer(60);
+        let base = UNIX_EPOCH + Duration::from_secs(1_800_000_000);
+
+        assert_eq!(
+            coalescer
+                .observe(link_down("10.0.0.1:40000", "100", "3"), at(base, 0))
+                .len(),
+            1
+        );
+
+        assert!(coalescer.flush_expired(at(base, 60)).is_empty());
+        assert_eq!(coalescer.pending(), 0);
+    }
+
+    #[test]
+    fn starts_a_new_window_after_the_last_one_closed() {
+        let mut coalescer = coalescer(60);
+        let base = UNIX_EPOCH + Duration::from_secs(1_800_000_000);
+
+        coalescer.observe(link_down("10.0.0.1:40000", "100", "3"), at(base, 0));
+        coalescer.observe(link_down("10.0.0.1:40000", "200", "3"), at(base, 30));
+
+        // The window closed before the flush tick: its summary is released
+        // together with the new trap, which starts its own window.
+        let released = coalescer.observe(link_down("10.0.0.1:40000", "300", "3"), at(base, 70));
+        assert_eq!(released.len(), 2);
+
+        let summary = released[0].attributes.as_ref().unwrap();
+        assert!(summary.summary);
+        assert_eq!(summary.occurrences, 2);
+
+        let first = released[1].attributes.as_ref().unwrap();
+        assert!(!first.summary);
+        assert_eq!(first.occurrences, 1);
+        assert_eq!(first.first_seen_unix_nano, unix_nanos(at(base, 70)));
+        assert_eq!(coalescer.pending(), 1);
+    }
+
+    #[test]
+    fn different_traps_are_kept_apart() {
+        let mut coalescer = coalescer(60);
+        let base = UNIX_EPOCH + Duration::from_secs(1_800_000_000);
+
+        for (secs, source, if_index) in [
+            (0, "10.0.0.1:40000", "3"),
+            (1, "10.0.0.1:40000", "4"),
+            (2, "10.0.0.2:40000", "3"),
+        ] {
+            let published = coalescer.observe(link_down(source, "100", if_index), at(base, secs));
+            assert_eq!(published.len(), 1, "each distinct trap is published");
+        }
+
+        assert_eq!(coalescer.pending(), 3);
+    }
+
+    #[test]
+    fn match_fields_are_configurable() {
+        let mut coalescer = TrapCoalescer::new(&DedupConfig {
+            enabled: true,
+            window_secs: 60,
+            match_fields: vec![DedupField::Source, DedupField::TrapOid],
+            ..Default::default()
+        });
+        let base = UNIX_EPOCH + Duration::from_secs(1_800_000_000);
+
+        // Varbinds are ignored, so linkDown for different interfaces collapse.
+        coalescer.observe(link_down("10.0.0.1:40000", "100", "3"), at(base, 0));
+        assert!(coalescer
+            .observe(link_down("10.0.0.1:40000", "100", "4"), at(base, 1))
+            .is_empty());
+
+        let flushed = coalescer.flush_expired(at(base, 60));
+        assert_eq!(flushed.len(), 1);
+        assert_eq!(flushed[0].attributes.as_ref().unwrap().occurrences, 2);
+    }
+
+    #[test]
+    fn publishes_immediately_when_full() {
+        let mut coalescer = TrapCoalescer::new(&DedupConfig {
+            enabled: true,
+            window_secs: 60,
+            max_pending: 1,
+            ..Default::default()
+        });
+        let base = UNIX_EPOCH + Duration::from_secs(1_800_000_000);
+
+        coalescer.observe(link_down("10.0.0.1:40000", "100", "3"), at(base, 0));
+
+        // No room for a second window: every copy is published untracked.
+        for secs in [1, 2] {
+            let overflow =
+                coalescer.observe(link_down("10.0.0.2:40000", "100", "3"), at(base, secs));
+            assert_eq!(overflow.len(), 1);
+            assert_eq!(overflow[0].source, "10.0.0.2:40000");
+            assert_eq!(overflow[0].attributes.as_ref().unwrap().occurrences, 1);
+        }
+        assert_eq!(coalescer.pending(), 1);
+    }
+
+    #[test]
+    fn drain_releases_open_windows() {
+        let mut coalescer = coalescer(60);
+        let base = UNIX_EPOCH + Duration::from_secs(1_800_000_000);
+
+        coalescer.observe(link_down("10.0.0.1:40000", "100", "3"), at(base, 0));
+        coalescer.observe(link_down("10.0.0.1:40000", "200", "3"), at(base, 5));
+        coalescer.observe(link_down("10.0.0.2:40000", "100", "3"), at(base, 10));
+
+        // Only the window that absorbed a duplicate has anything to report.
+        let drained = coalescer.drain();
+        assert_eq!(drained.len(), 1);
+        assert_eq!(drained[0].source, "10.0.0.1:40000");
+        assert_eq!(drained[0].attributes.as_ref().unwrap().occurrences, 2);
+        assert_eq!(coalescer.pending(), 0);
+    }
+}
@@ -23,8 +23,10 @@ use log::{info, warn};
 use serde::Serialize;
 use std::pin::Pin;
 use std::sync::Once;
+use std::time::{Duration, SystemTime};
 use std::{fs, net::SocketAddr, path::PathBuf};
 use tokio::net::UdpSocket;
+use tokio::time::MissedTickBehavior;
 
 use tonic::{
     transport::{Certificate, Identity, Server, ServerTlsConfig},
@@ -34,6 +36,7 @@ use tonic_health::server::health_reporter;
 use tonic_reflection::server::Builder as ReflectionBuilder;
 
 mod config;
+mod dedup;
 mod spiffe;
 use config::{Config, SecurityMode};
 
@@ -45,6 +48,7 @@ use monitoring::agent_service_server::{AgentService, AgentServiceServer};
 const FILE_DESCRIPTOR_SET_MONITORING: &[u8] =
     include_bytes!(concat!(env!("OUT_DIR"), "/monitoring_descriptor.bin"));
 const DEFAULT_WORKLOAD_SOCKET: &str = "unix:/run/spire/sockets/agent.sock";
+const DEDUP_FLUSH_INTERVAL: Duration = Duration::from_secs(1);
 
 #[derive(Parser, Debug)]
 #[command(name = "serviceradar-trapd")]
@@ -55,18 +59,35 @@ struct Cli {
     config: Option<String>,
 }
 
-#[derive(Serialize)]
+#[derive(Debug, Clone, Serialize)]
 struct Varbind {
     oid: String,
     value: String,
 }
 
-#[derive(Serialize)]
+#[derive(Debug, Clone, Serialize)]
 struct TrapMessage {
     source: String,
     version: String,
     community: String,
     varbinds: Vec<Varbind>,
+    /// First occurrence, in Unix nanoseconds; set when dedup is enabled.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    timestamp: Option<u64>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    attributes: Option<TrapOccurrences>,
+}
+
+/// How many identical traps a deduplicated message stands for. The first
+/// trap of a window is published with one occurrence; the window's summary,
+/// published when it closes, carries the total and `summary: true`.
+#[derive(Debug, Clone, Serialize)]
+struct TrapOccurrences {
+    occurrences: u64,
+    first_seen_unix_nano: u64,
+    last_seen_unix_nano: u64,
+    #[serde(skip_serializing_if = "std::ops::Not::not")]
+    summary: bool,
 }
 
 #[tokio::main]
@@ -154,25 +175,95 @@ async fn main() -> Result<()> {
         warn!("failed to ensure stream {}: {e}", cfg.stream_name);
     }
 
+    let mut coalescer = if cfg.dedup.enabled {
+        info!(
+            "Deduplicating traps over {}s windows on {:?}",
+            cfg.dedup.window_secs, cfg.dedup.match_fields
+        );
+        Some(dedup::TrapCoalescer::new(&cfg.dedup))
+    } else {
+        None
+    };
+    let mut flush_tick = tokio::time::interval(DEDUP_FLUSH_INTERVAL);
+    flush_tick.set_missed_tick_behavior(MissedTickBehavior::Delay);
+
+    let shutdown = shutdown_signal();
+    tokio::pin!(shutdown);
+
     let mut buf = vec![0u8; 65535];
     loop {
-        let (len, addr) = socket.recv_from(&mut buf).await?;
-        let data = &buf[..len];
-        match snmp2::Pdu::from_bytes(data) {
-            Ok(pdu) => {
-                let msg = build_message(&pdu, addr);
-                let payload = serde_json::to_vec(&msg)?;
-                if let Err(e) = js.publish(cfg.subject.clone(), payload.into()).await?.await {
-                    warn!("Failed to publish trap: {e}");
+        tokio::select! {
+            _ = &mut shutdown => {
+                info!("Shutting down trap receiver");
+                if let Some(coalescer) = coalescer.as_mut() {
+                    for msg in coalescer.drain() {
+                        publish_trap(&js, &cfg.subject, &msg).await?;
+                    }
+                }
+                return Ok(());
+            }
+            received = socket.recv_from(&mut buf) => {
+                let (len, addr) = received?;
+                let data = &buf[..len];
+                match snmp2::Pdu::from_bytes(data) {
+                    Ok(pdu) => {
+                        let msg = build_message(&pdu, addr);
+                        let ready = match coalescer.as_mut() {
+                            Some(coalescer) => coalescer.observe(msg, SystemTime::now()),
+                            None => vec![msg],
+                        };
+                        for msg in ready {
+                            publish_trap(&js, &cfg.subject, &msg).await?;
+                        }
+                    }
+                    Err(e) => {
+                        warn!("Failed to parse SNMP trap from {addr}: {e}");
+                    }
                 }
             }
-            Err(e) => {
-                warn!("Failed to parse SNMP trap from {addr}: {e}");
+            _ = flush_tick.tick(), if coalescer.is_some() => {
+                if let Some(coalescer) = coalescer.as_mut() {
+                    for msg in coalescer.flush_expired(SystemTime::now()) {
+                        publish_trap(&js, &cfg.subject, &msg).await?;
+                    }
+                }
             }
         }
     }
 }
 
+async fn publish_trap(
+    js: &async_nats::jetstream::Context,
+    subject: &str,
+    msg: &TrapMessage,
+) -> Result<()> {
+    let payload = serde_json::to_vec(msg)?;
+    if let Err(e) = js.publish(subject.to_string(), payload.into()).await?.await {
+        warn!("Failed to publish trap: {e}");
+    }
+
+    Ok(())
+}
+
+async fn shutdown_signal() {
+    #[cfg(unix)]
+    {
+        let mut terminate =
+            tokio::signal::unix::signal(tokio::signal::unix::SignalKind::terminate())
+                .expect("failed to install SIGTERM handler");
+
+        tokio::select! {
+            _ = tokio::signal::ctrl_c() => {}
+            _ = terminate.recv() => {}
+        }
+    }
+
+    #[cfg(not(unix))]
+    {
+        let _ = tokio::signal::ctrl_c().await;
+    }
+}
+
 fn ensure_rustls_provider_installed() {
     static ONCE: Once = Once::new();
     ONCE.call_once(|| {
@@ -233,6 +324,8 @@ fn build_message(pdu: &snmp2::Pdu<'_>, addr: SocketAddr) -> TrapMessage {
         version,
         community,
         varbinds,
+        timestamp: None,
+        attributes: None,
     }
 }
 
@@ -480,7 +573,7 @@ async fn serve_with_spiffe(
 #[cfg(test)]
 mod tests {
     use super::*;
-    use crate::config::SecurityConfig;
+    use crate::config::{DedupConfig, SecurityConfig};
     use std::path::Path;
 
     fn base_config() -> Config {
@@ -489,6 +582,7 @@ mod tests {
             nats_url: "tls://serviceradar-nats:4222".into(),
             nats_domain: None,
             stream_name: "events".into(),
+            stream_replicas: 1,
             subject: "logs.snmp".into(),
             nats_creds_file: None,
             nats_security: None,
@@ -499,6 +593,7 @@ mod tests {
                 workload_socket: None,
                 ..Default::default()
             }),
+            dedup: DedupConfig::default(),
         }
     }
 